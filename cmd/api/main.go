@@ -24,6 +24,11 @@
 // @in cookie
 // @name stronghold_access
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Operator token from ADMIN_API_TOKEN, sent as "Bearer <token>"
+
 // @tag.name health
 // @tag.description Health check endpoints for monitoring
 // @tag.name pricing
@@ -34,11 +39,16 @@
 // @tag.description Account management and billing
 // @tag.name scan
 // @tag.description AI security scanning endpoints (payment required)
+// @tag.name admin
+// @tag.description Operator endpoints (ADMIN_API_TOKEN required)
 
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -46,13 +56,21 @@ import (
 	"time"
 
 	"stronghold/internal/config"
+	"stronghold/internal/redact"
 	"stronghold/internal/server"
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-only", false, "validate configuration for the deployment profile and exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.Load()
 
+	if *validateOnly {
+		os.Exit(preflight(cfg))
+	}
+
 	// Setup structured logging - JSON for production, text for development
 	setupLogging(cfg)
 
@@ -107,6 +125,27 @@ func main() {
 	slog.Info("server exited")
 }
 
+// preflight reports every configuration problem for the deployment profile
+// and returns the exit code
+func preflight(cfg *config.Config) int {
+	err := cfg.Validate()
+	if err == nil {
+		fmt.Printf("configuration is valid for the %s profile\n", cfg.EffectiveProfile())
+		return 0
+	}
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "configuration is invalid for the %s profile:\n", verr.Profile)
+	for _, problem := range verr.Problems {
+		fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+	}
+	return 1
+}
+
 // setupLogging configures the global slog logger
 func setupLogging(cfg *config.Config) {
 	var handler slog.Handler
@@ -123,5 +162,10 @@ func setupLogging(cfg *config.Config) {
 		})
 	}
 
-	slog.SetDefault(slog.New(handler))
+	// Addresses, IPs, tokens and scanned content are scrubbed unless
+	// explicitly turned off for debugging
+	slog.SetDefault(slog.New(redact.Wrap(handler, cfg.Logging.Unredacted)))
+	if cfg.Logging.Unredacted {
+		slog.Warn("log redaction is off (LOG_UNREDACTED); logs contain personal data and secrets")
+	}
 }
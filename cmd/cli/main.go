@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"stronghold/internal/cli"
@@ -49,9 +50,16 @@ This command will:
   5. Install system service
   6. Start the proxy
 
+If installation fails partway, the steps already done are rolled back. Fix
+the problem, then run "stronghold init --resume" to retry from the failed
+step without setting up the account again.
+
 WARNING: This sets up a system-wide proxy that will route ALL traffic
 through Stronghold's scanning service. Intended for isolated machines only.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if resume, _ := cmd.Flags().GetBool("resume"); resume {
+				return cli.ResumeInit()
+			}
 			nonInteractive, _ := cmd.Flags().GetBool("yes")
 			privateKey, _ := cmd.Flags().GetString("private-key")
 			solanaPrivateKey, _ := cmd.Flags().GetString("solana-private-key")
@@ -75,6 +83,7 @@ through Stronghold's scanning service. Intended for isolated machines only.`,
 	initCmd.Flags().String("solana-private-key", "", "Import Solana wallet from private key (base58) - requires --yes")
 	initCmd.Flags().String("account-number", "", "Login to existing account - requires --yes")
 	initCmd.Flags().Bool("skip-service", false, "Skip proxy binary install, service setup, and transparent proxy enable")
+	initCmd.Flags().Bool("resume", false, "Retry a failed installation from the step that failed")
 
 	// Enable command
 	enableCmd := &cobra.Command{
@@ -109,6 +118,69 @@ This will:
 		},
 	}
 
+	// Supervise command
+	superviseCmd := &cobra.Command{
+		Use:   "supervise",
+		Short: "Run the proxy in the foreground, restarting it if it exits",
+		Long: `Run the Stronghold proxy in the foreground and restart it whenever it exits,
+waiting longer between restarts while it keeps failing.
+
+Use this as the entrypoint of containers and on other systems without an init
+system; 'stronghold doctor' reports when none was detected. On systemd,
+launchd, OpenRC and runit the installed service restarts the proxy instead.
+
+SIGINT and SIGTERM stop the proxy and exit.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.Supervise()
+		},
+	}
+
+	// Restart command
+	restartCmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the proxy on the installed binary without dropping connections",
+		Long: `Restart the Stronghold proxy, for example after upgrading its binary.
+
+The running proxy starts the installed binary on the sockets it is already
+listening on, and only stops accepting and drains its open connections once
+the new process is ready. Traffic redirected to the proxy is never refused,
+and if the new binary fails to start the old one keeps serving.
+
+A proxy from before this was supported is stopped and started again instead.
+On systemd, 'systemctl reload stronghold-proxy' does the same.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.Restart()
+		},
+	}
+
+	// Watchdog command
+	watchdogCmd := &cobra.Command{
+		Use:   "watchdog",
+		Short: "Handle a crash-looping proxy",
+	}
+
+	watchdogTripCmd := &cobra.Command{
+		Use:   "trip",
+		Short: "Apply the crash-loop fail-safe now",
+		Long: `Apply the watchdog policy as if the proxy had crash-looped.
+
+With fail_open the redirect rules are removed and traffic goes out unscanned;
+with fail_closed the kill switch replaces them and outbound web traffic is
+refused. Either way the change is logged to stderr and syslog, and shown by
+'stronghold status' and 'doctor' until 'stronghold enable' restores
+interception.
+
+The systemd service runs this once the proxy hits its start limit;
+'stronghold supervise' trips the watchdog itself.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason, _ := cmd.Flags().GetString("reason")
+			return cli.WatchdogTrip(reason)
+		},
+	}
+	watchdogTripCmd.Flags().String("reason", "tripped manually", "Why the fail-safe was applied (shown in alerts and status)")
+
+	watchdogCmd.AddCommand(watchdogTripCmd)
+
 	// Status command
 	statusCmd := &cobra.Command{
 		Use:   "status",
@@ -125,14 +197,25 @@ This will:
 		Short: "Check API and network RPC health",
 		Long: `Display health for:
   1. Stronghold API (/health)
-  2. Base RPC
-  3. Solana RPC
+  2. Base RPC endpoints
+  3. Solana RPC endpoints
+
+Every endpoint configured under rpc (see 'stronghold config get rpc') is
+checked, or the public RPC of each network when none are set.
 
-RPC statuses are reported as: up, down, or congested.`,
+RPC statuses are reported as: up, down, or congested.
+
+With --deep, each RPC is also called several times for its median latency
+and its block height is compared against the public RPC of its network, to
+catch stuck or lagging endpoints. The payment facilitator's verify and settle
+endpoints are checked, and the local clock is compared with the API's, since
+payments are only valid for 5 minutes from the time they are signed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return cli.Health()
+			deep, _ := cmd.Flags().GetBool("deep")
+			return cli.Health(deep)
 		},
 	}
+	healthCmd.Flags().Bool("deep", false, "Also check RPC block heights, the payment facilitator and clock skew")
 
 	// Uninstall command
 	uninstallCmd := &cobra.Command{
@@ -144,16 +227,23 @@ This will:
   1. Stop the proxy
   2. Remove system proxy configuration
   3. Remove system service
-  4. Remove binaries
-  5. Optionally remove configuration
+  4. Remove CA trust entries and the proxy system user
+  5. Remove binaries and logs
+  6. Optionally remove configuration
+
+Each removal is verified; anything that could not be removed is listed at
+the end. Use --dry-run to list what would be removed without changing
+anything.
 
 Your wallet balance will be preserved unless you explicitly delete your account.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			preserve, _ := cmd.Flags().GetBool("preserve-config")
-			return cli.Uninstall(preserve)
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return cli.Uninstall(preserve, dryRun)
 		},
 	}
 	uninstallCmd.Flags().BoolP("preserve-config", "p", true, "Preserve configuration files")
+	uninstallCmd.Flags().Bool("dry-run", false, "List what would be removed without removing anything")
 
 	// Logs command
 	logsCmd := &cobra.Command{
@@ -238,7 +328,74 @@ Available scanning keys:
 		},
 	}
 
-	configCmd.AddCommand(configGetCmd, configSetCmd)
+	configEncryptCmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt secrets in the config file",
+		Long: `Encrypt the auth tokens and wallet addresses in the config file at rest.
+
+Values are encrypted with AES-256-GCM using a random machine key. By default
+the key is written to ~/.stronghold/config.key (mode 0600); with --keychain it
+is stored in the OS keychain instead. The CLI and proxy decrypt transparently
+on load. Set STRONGHOLD_CONFIG_KEY to supply the key directly (base64), e.g.
+in containers.
+
+Examples:
+  stronghold config encrypt
+  stronghold config encrypt --keychain`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := "file"
+			if keychain, _ := cmd.Flags().GetBool("keychain"); keychain {
+				source = "keychain"
+			}
+			return cli.ConfigEncrypt(source)
+		},
+	}
+	configEncryptCmd.Flags().Bool("keychain", false, "Store the encryption key in the OS keychain")
+
+	configDecryptCmd := &cobra.Command{
+		Use:   "decrypt",
+		Short: "Store config secrets in plaintext again",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.ConfigDecrypt()
+		},
+	}
+
+	configSyncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Share settings between your machines, end-to-end encrypted",
+		Long: `Share scanning settings, policies and allowlists between the machines on
+your account through the Stronghold API.
+
+The synced sections (scanning, policies, block_response, payments.policy and
+wallet.autopay.hosts) are encrypted on this machine with AES-256-GCM, using a
+key derived from the account's EVM wallet. The API stores only ciphertext.
+Ports, paths, RPC endpoints and credentials are never synced.`,
+	}
+
+	configSyncPushCmd := &cobra.Command{
+		Use:   "push",
+		Short: "Upload this machine's synced settings",
+		Long: `Upload this machine's synced settings for the account's other machines.
+
+A push is refused if another machine pushed since this one last synced; pull
+first, or pass --force to overwrite.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			return cli.ConfigSyncPush(force)
+		},
+	}
+	configSyncPushCmd.Flags().Bool("force", false, "Overwrite changes pushed from other machines")
+
+	configSyncPullCmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Replace this machine's synced settings with the account's",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.ConfigSyncPull()
+		},
+	}
+	configSyncCmd.AddCommand(configSyncPushCmd, configSyncPullCmd)
+
+	configCmd.AddCommand(configGetCmd, configSetCmd, configEncryptCmd, configDecryptCmd, configSyncCmd)
 
 	// Account command
 	accountCmd := &cobra.Command{
@@ -250,7 +407,7 @@ Available scanning keys:
 	accountBalanceCmd := &cobra.Command{
 		Use:   "balance",
 		Short: "Check your account balance",
-		Long:  `Display your current balance and account status.`,
+		Long:  `Display your prepaid balance, how many days it lasts at your recent spend rate, and your wallet balances.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cli.AccountBalance()
 		},
@@ -376,6 +533,174 @@ but you can use this command to manually re-register if needed.`,
 
 	walletCmd.AddCommand(walletListCmd, walletBalanceCmd, walletExportCmd, walletReplaceCmd, walletLinkCmd)
 
+	// Device command
+	deviceCmd := &cobra.Command{
+		Use:   "device",
+		Short: "Manage trusted devices",
+	}
+
+	deviceListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List trusted devices",
+		Long: `List the devices trusted on your account with their name, hostname,
+operating system and when each was last seen, so you can tell them apart
+before revoking one.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceList()
+		},
+	}
+
+	deviceRenameCmd := &cobra.Command{
+		Use:   "rename <id> <name>",
+		Short: "Name a trusted device",
+		Long: `Give a trusted device a name shown in 'stronghold device list'.
+Pass an empty name to clear it.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceRename(args[0], args[1])
+		},
+	}
+
+	deviceRevokeCmd := &cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke a trusted device",
+		Long: `Remove trust from a device. It must verify with TOTP or be approved
+again before it can access server-stored wallet keys.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceRevoke(args[0])
+		},
+	}
+
+	deviceApprovalsCmd := &cobra.Command{
+		Use:   "approvals",
+		Short: "List devices waiting for approval",
+		Long: `List new machines that asked to be trusted instead of entering a TOTP code.
+
+Must be run on a trusted device.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceApprovals()
+		},
+	}
+
+	deviceApproveCmd := &cobra.Command{
+		Use:   "approve <id>",
+		Short: "Trust a device waiting for approval",
+		Long: `Trust the machine that made an approval request. The waiting
+'stronghold init' on that machine picks up the approval and continues.
+
+Must be run on a trusted device. Only approve requests you made yourself.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceApprove(args[0])
+		},
+	}
+
+	deviceDenyCmd := &cobra.Command{
+		Use:   "deny <id>",
+		Short: "Reject a device waiting for approval",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.DeviceDeny(args[0])
+		},
+	}
+
+	deviceCmd.AddCommand(deviceListCmd, deviceRenameCmd, deviceRevokeCmd, deviceApprovalsCmd, deviceApproveCmd, deviceDenyCmd)
+
+	// Bypass command
+	bypassCmd := &cobra.Command{
+		Use:   "bypass",
+		Short: "Manage scanning bypass tokens for trusted tooling",
+	}
+
+	bypassIssueCmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Issue a short-lived token that skips scanning",
+		Long: `Issue a signed, short-lived token that lets a trusted client skip
+scanning for specific hosts. The client attaches it as the
+X-Stronghold-Bypass header; the proxy strips it before forwarding.
+
+Every issued token is recorded in ~/.stronghold/logs/bypass-audit.log and
+every use is logged by the proxy. Tokens expire after at most 24h.
+
+The signing key is ~/.stronghold/bypass.key, so any process running as
+you can issue tokens. Run agents as a separate user if they must not be
+able to skip scanning.
+
+Examples:
+  stronghold bypass issue --ttl 10m --host api.internal
+  stronghold bypass issue --ttl 1h --host "*.corp.example" --client oncall-runbook`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hosts, _ := cmd.Flags().GetStringSlice("host")
+			ttl, _ := cmd.Flags().GetDuration("ttl")
+			client, _ := cmd.Flags().GetString("client")
+			return cli.BypassIssue(hosts, ttl, client)
+		},
+	}
+	bypassIssueCmd.Flags().StringSlice("host", nil, "Host allowed to bypass scanning (repeatable, supports *.domain)")
+	bypassIssueCmd.Flags().Duration("ttl", 10*time.Minute, "Token lifetime (max 24h)")
+	bypassIssueCmd.Flags().String("client", "", "Name of the client the token is issued to (recorded in audit logs)")
+	bypassIssueCmd.MarkFlagRequired("host")
+
+	bypassCmd.AddCommand(bypassIssueCmd)
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <request-id>",
+		Short: "Re-fetch a previously blocked or warned request",
+		Long: `Re-fetch a URL that the proxy blocked or warned on, using the request ID
+from the X-Stronghold-Request-ID header or the block response.
+
+By default the request is sent through the proxy and scanned again, so you
+can check whether the verdict still holds. With --force the block is
+bypassed for this single replay after confirmation; the override is recorded
+in ~/.stronghold/logs/bypass-audit.log.
+
+Only GET and HEAD requests can be replayed.
+
+Examples:
+  stronghold replay req-3f9c2a...
+  stronghold replay req-3f9c2a... --force --output page.html`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			yes, _ := cmd.Flags().GetBool("yes")
+			output, _ := cmd.Flags().GetString("output")
+			return cli.Replay(args[0], cli.ReplayOptions{Force: force, Yes: yes, Output: output})
+		},
+	}
+	replayCmd.Flags().Bool("force", false, "Bypass scanning for this replay (asks for confirmation)")
+	replayCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt for --force")
+	replayCmd.Flags().StringP("output", "o", "", "Write the response body to a file")
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the proxy's decision audit log",
+	}
+
+	auditVerifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the audit log for tampering",
+		Long: `Verify the hash chain of the proxy's decision audit log.
+
+Each audit record includes the hash of the record before it, so editing,
+reordering, or deleting a record breaks the chain from that point on. The
+command prints the head hash; keep a copy elsewhere to detect truncation.
+
+Usage logs kept by the API are chained the same way and can be checked at
+GET /v1/account/usage/verify.
+
+Examples:
+  stronghold audit verify
+  stronghold audit verify --file /var/log/stronghold/audit.log`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			return cli.AuditVerify(file)
+		},
+	}
+	auditVerifyCmd.Flags().String("file", "", "Audit log to verify (default: the configured audit log)")
+
+	auditCmd.AddCommand(auditVerifyCmd)
+
 	// Doctor command
 	doctorCmd := &cobra.Command{
 		Use:   "doctor",
@@ -402,6 +727,9 @@ Run this before 'stronghold init' to catch issues early.`,
 		initCmd,
 		enableCmd,
 		disableCmd,
+		superviseCmd,
+		restartCmd,
+		watchdogCmd,
 		statusCmd,
 		healthCmd,
 		uninstallCmd,
@@ -409,6 +737,10 @@ Run this before 'stronghold init' to catch issues early.`,
 		configCmd,
 		accountCmd,
 		walletCmd,
+		deviceCmd,
+		bypassCmd,
+		replayCmd,
+		auditCmd,
 		doctorCmd,
 	)
 
@@ -121,3 +121,13 @@ func TestDocs_DoNotUseDeprecatedWalletReplaceForms(t *testing.T) {
 		}
 	}
 }
+
+func TestBypassIssue_RequiresHost(t *testing.T) {
+	_, _, err := executeRoot(t, "bypass", "issue", "--ttl", "5m")
+	if err == nil {
+		t.Fatal("expected error when --host is omitted")
+	}
+	if !strings.Contains(err.Error(), `required flag(s) "host" not set`) {
+		t.Fatalf("expected required flag error, got: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Command facilitator-mock runs a stand-in x402 facilitator so the full
+// payment path can be exercised locally and in CI without the public
+// facilitator. Point the API at it with X402_FACILITATOR_URL.
+//
+// Failure modes and latency can be set with flags at startup and changed
+// while running with PUT /config. GET /stats reports counts and POST /reset
+// forgets settled nonces.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"stronghold/internal/facilitatormock"
+)
+
+func main() {
+	addr := flag.String("addr", ":8402", "listen address")
+	verifyLatency := flag.Duration("verify-latency", 0, "delay added to every /verify request")
+	settleLatency := flag.Duration("settle-latency", 0, "delay added to every /settle request")
+	verifyFailure := flag.String("verify-failure", "", "failure injected into /verify: reject, error or timeout")
+	settleFailure := flag.String("settle-failure", "", "failure injected into /settle: reject, error or timeout")
+	failureRate := flag.Float64("failure-rate", 0, "share of requests that fail when a failure mode is set (0 means all)")
+	flag.Parse()
+
+	cfg := facilitatormock.Config{
+		Verify: facilitatormock.EndpointConfig{
+			LatencyMs:   int(verifyLatency.Milliseconds()),
+			Failure:     *verifyFailure,
+			FailureRate: *failureRate,
+		},
+		Settle: facilitatormock.EndpointConfig{
+			LatencyMs:   int(settleLatency.Milliseconds()),
+			Failure:     *settleFailure,
+			FailureRate: *failureRate,
+		},
+	}
+
+	if err := run(*addr, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "facilitator-mock:", err)
+		os.Exit(1)
+	}
+}
+
+func run(addr string, cfg facilitatormock.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           facilitatormock.New(cfg).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("mock facilitator listening", "addr", addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,109 @@
+// Command loadtest drives synthetic scan traffic at a fixed rate through the
+// API or the proxy and reports latency percentiles, a latency histogram and
+// the error rate. Use it to validate sizing before a production rollout; it
+// exits non-zero when the run exceeds the error budget.
+//
+// API mode posts to a scan endpoint with an API key, so requests are billed
+// to that account. Proxy mode fetches synthetic pages from a local origin
+// through a running proxy.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"stronghold/internal/loadtest"
+)
+
+func main() {
+	mode := flag.String("target", "api", "where to send traffic: api or proxy")
+	apiURL := flag.String("api-url", "http://localhost:8080", "API base URL (api mode)")
+	endpoint := flag.String("endpoint", "/v1/scan/content", "scan endpoint (api mode)")
+	proxyURL := flag.String("proxy-url", "http://127.0.0.1:8402", "proxy URL (proxy mode)")
+	rps := flag.Int("rps", 10, "requests started per second")
+	duration := flag.Duration("duration", 30*time.Second, "how long to send traffic")
+	concurrency := flag.Int("concurrency", 0, "maximum requests in flight (default: rps)")
+	injectionRate := flag.Float64("injection-rate", 0.1, "share of requests carrying an injection payload")
+	pad := flag.Int("pad", 0, "pad payloads to this many bytes")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-request timeout")
+	maxErrorRate := flag.Float64("max-error-rate", 0.01, "error budget as a share of requests (0 disables)")
+	maxP99 := flag.Duration("max-p99", 0, "p99 latency budget (0 disables)")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	flag.Parse()
+
+	if err := run(*mode, *apiURL, *endpoint, *proxyURL, *rps, *duration, *concurrency, *injectionRate, *pad, *timeout, *maxErrorRate, *maxP99, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest:", err)
+		os.Exit(1)
+	}
+}
+
+func run(mode, apiURL, endpoint, proxyURL string, rps int, duration time.Duration, concurrency int, injectionRate float64, pad int, timeout time.Duration, maxErrorRate float64, maxP99 time.Duration, asJSON bool) error {
+	if rps <= 0 {
+		return fmt.Errorf("-rps must be positive")
+	}
+	if injectionRate < 0 || injectionRate > 1 {
+		return fmt.Errorf("-injection-rate must be between 0 and 1")
+	}
+
+	var target loadtest.Target
+	switch mode {
+	case "api":
+		apiKey := os.Getenv("STRONGHOLD_API_KEY")
+		if apiKey == "" {
+			return fmt.Errorf("STRONGHOLD_API_KEY must be set in api mode")
+		}
+		target = &loadtest.APITarget{
+			URL:    strings.TrimRight(apiURL, "/") + endpoint,
+			APIKey: apiKey,
+			Output: strings.HasSuffix(endpoint, "/output"),
+			Pad:    pad,
+			Client: &http.Client{
+				Timeout:   timeout,
+				Transport: &http.Transport{MaxIdleConnsPerHost: max(concurrency, rps)},
+			},
+		}
+	case "proxy":
+		t, err := loadtest.NewProxyTarget(proxyURL, timeout, pad)
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+		target = t
+	default:
+		return fmt.Errorf("unknown target %q (want api or proxy)", mode)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Sending %d req/s to %s for %s...\n", rps, mode, duration)
+	report := loadtest.Run(ctx, loadtest.Config{
+		RPS:           rps,
+		Duration:      duration,
+		Concurrency:   concurrency,
+		InjectionRate: injectionRate,
+	}, target)
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if violations := report.Check(loadtest.Budget{MaxErrorRate: maxErrorRate, MaxP99: maxP99}); len(violations) > 0 {
+		return fmt.Errorf("error budget exceeded: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"stronghold/internal/proxy"
+	"stronghold/internal/redact"
 )
 
 func main() {
@@ -17,7 +18,7 @@ func main() {
 		Level:     slog.LevelDebug,
 		AddSource: true,
 	})
-	slog.SetDefault(slog.New(handler))
+	slog.SetDefault(slog.New(redact.NewHandler(handler)))
 
 	// Load configuration from environment or config file
 	config, err := proxy.LoadConfig()
@@ -25,6 +26,9 @@ func main() {
 		slog.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
+	if config.Logging.Unredacted {
+		slog.SetDefault(slog.New(handler))
+	}
 
 	// Create and start the proxy server
 	server, err := proxy.NewServer(config)
@@ -37,8 +41,18 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Resolved now, before an upgrade replaces the file: a handoff starts
+	// whatever binary is at this path then
+	binary, err := os.Executable()
+	if err != nil {
+		slog.Warn("failed to resolve proxy binary, in-place upgrades disabled", "error", err)
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	if proxy.UpgradeSignal != nil && binary != "" {
+		signal.Notify(sigChan, proxy.UpgradeSignal)
+	}
 
 	// Start server in a goroutine
 	errChan := make(chan error, 1)
@@ -49,22 +63,75 @@ func main() {
 		}
 	}()
 
-	// Wait for shutdown signal or error
-	select {
-	case sig := <-sigChan:
-		slog.Info("received signal", "signal", sig)
-	case err := <-errChan:
-		slog.Error("server error", "error", err)
-		os.Exit(1)
+	// After a handoff this process stays as the one its service manager
+	// watches, following the successor serving in its place
+	var successor *proxy.Successor
+	var drained chan struct{}
+	var successorExited <-chan struct{}
+
+loop:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig != proxy.UpgradeSignal {
+				slog.Info("received signal", "signal", sig)
+				break loop
+			}
+			if server.IsSuccessor() {
+				// Handoffs are run by the process that started this one
+				if parent, err := os.FindProcess(os.Getppid()); err == nil {
+					parent.Signal(sig)
+				}
+				continue
+			}
+
+			slog.Info("upgrade requested, handing listeners to a new proxy", "binary", binary)
+			next, err := server.Handoff(binary)
+			if err != nil {
+				slog.Error("upgrade failed, still serving", "error", err)
+				continue
+			}
+			if successor != nil {
+				go successor.Stop(shutdownTimeout)
+			} else {
+				cancel()
+				drained = make(chan struct{})
+				go func() {
+					shutdown(server)
+					close(drained)
+				}()
+			}
+			successor, successorExited = next, next.Exited()
+		case <-successorExited:
+			// The service manager restarts the proxy, as if this process had failed
+			slog.Error("proxy successor exited", "pid", successor.Process.Pid, "error", successor.Err())
+			os.Exit(1)
+		case err := <-errChan:
+			slog.Error("server error", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	// Graceful shutdown with timeout
+	if successor == nil {
+		shutdown(server)
+	} else {
+		slog.Info("stopping proxy successor", "pid", successor.Process.Pid)
+		successor.Stop(shutdownTimeout)
+		<-drained
+	}
+
+	slog.Info("proxy stopped")
+}
+
+// shutdownTimeout bounds a graceful shutdown, connection drain included
+const shutdownTimeout = 30 * time.Second
+
+// shutdown stops the server, draining its connections
+func shutdown(server *proxy.Server) {
 	slog.Info("shutting down proxy")
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer shutdownCancel()
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("error during shutdown", "error", err)
 	}
-
-	slog.Info("proxy stopped")
 }
@@ -0,0 +1,103 @@
+// Command replay scans stored scan samples with the current detection
+// configuration and reports decisions that changed. Point it at the API
+// database and set the STRONGHOLD_* variables to the configuration under
+// evaluation.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/kms"
+	"stronghold/internal/sampling"
+	"stronghold/internal/stronghold"
+)
+
+func main() {
+	since := flag.Duration("since", 7*24*time.Hour, "replay samples taken within this period")
+	endpoint := flag.String("endpoint", "", "only replay samples from this endpoint (e.g. /v1/scan/content)")
+	limit := flag.Int("limit", 1000, "maximum number of samples to replay")
+	examples := flag.Int("examples", 20, "changed samples to list per kind in the text report")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	failOnRelaxed := flag.Bool("fail-on-relaxed", false, "exit 1 if any decision became more lenient")
+	flag.Parse()
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if err := run(*since, *endpoint, *limit, *examples, *asJSON, *failOnRelaxed); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(since time.Duration, endpoint string, limit, examples int, asJSON, failOnRelaxed bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.Load()
+	database, err := db.New(&db.Config{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		Name:     cfg.Database.Name,
+		SSLMode:  cfg.Database.SSLMode,
+		MaxConns: cfg.Database.MaxConns,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	scanner, err := stronghold.NewScanner(&cfg.Stronghold)
+	if err != nil {
+		return fmt.Errorf("failed to create scanner: %w", err)
+	}
+	defer scanner.Close()
+
+	samples, err := database.ListScanSamples(ctx, endpoint, time.Now().Add(-since), limit)
+	if err != nil {
+		return err
+	}
+
+	// Samples stored under customer-managed keys are opened with the
+	// customer's KMS key; those whose key was revoked can't be replayed
+	var sealer sampling.Sealer
+	for _, sample := range samples {
+		if sample.Sealed() {
+			if sealer, err = kms.NewDataKeys(ctx); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	samples, unreadable := sampling.Open(ctx, samples, sealer)
+
+	report := sampling.Replay(ctx, samples, scanner)
+	report.Total += unreadable
+	report.Unreadable = unreadable
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		report.WriteText(os.Stdout, examples)
+	}
+
+	if failOnRelaxed && len(report.Relaxed) > 0 {
+		return fmt.Errorf("%d decisions became more lenient", len(report.Relaxed))
+	}
+	return nil
+}
@@ -52,7 +52,13 @@ export default defineConfig({
             { label: 'Overview', slug: 'api' },
             { label: 'POST /v1/scan/content', slug: 'api/scan-content' },
             { label: 'POST /v1/scan/output', slug: 'api/scan-output' },
+            { label: 'POST /v1/scan/tool-call', slug: 'api/scan-tool-call' },
+            { label: 'POST /v1/scan/documents', slug: 'api/scan-documents' },
+            { label: 'POST /v1/ingest', slug: 'api/ingest' },
+            { label: 'POST /v1/scan/session', slug: 'api/scan-session' },
+            { label: 'POST /v1/scan/keys', slug: 'api/scan-keys' },
             { label: 'GET /v1/pricing', slug: 'api/pricing' },
+            { label: 'POST /v1/pricing/estimate', slug: 'api/pricing-estimate' },
             { label: 'Health Checks', slug: 'api/health' },
             { label: 'Errors', slug: 'api/errors' },
           ],
@@ -70,6 +76,7 @@ export default defineConfig({
           items: [
             { label: 'Threat Model', slug: 'security/threat-model' },
             { label: 'Detection Layers', slug: 'security/detection-layers' },
+            { label: 'Rule Packs', slug: 'security/rule-packs' },
           ],
         },
         {
@@ -88,6 +95,7 @@ export default defineConfig({
             { label: 'health', slug: 'cli/health' },
             { label: 'wallet', slug: 'cli/wallet' },
             { label: 'account', slug: 'cli/account' },
+            { label: 'device', slug: 'cli/device' },
             { label: 'config', slug: 'cli/config' },
             { label: 'doctor', slug: 'cli/doctor' },
           ],
@@ -24,7 +24,7 @@ const docTemplate = `{
     "paths": {
         "/docs": {
             "get": {
-                "description": "Interactive API documentation using Swagger UI",
+                "description": "Interactive API documentation",
                 "produces": [
                     "text/html"
                 ],
@@ -130,7 +130,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the authenticated user's account details including balance and deposit stats",
+                "description": "Returns the authenticated user's account details including balance and deposit stats, and a runway estimate: the average daily spend over the last 7 days and how many days the balance lasts at that rate. runway.low_balance is set when it is below the low balance alert threshold, 3 days by default. The runway is omitted if it could not be estimated.",
                 "produces": [
                     "application/json"
                 ],
@@ -167,49 +167,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/account/deposit": {
-            "post": {
+        "/v1/account/balances": {
+            "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Start a deposit via Stripe (requires linked wallet) or direct crypto transfer",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns on-chain USDC balances for the account's EVM and Solana wallets. Balances are cached for 30 seconds by default and served stale for up to 5 minutes more while they refresh in the background; updated_at tells when each was read on-chain. Pass fresh=true to bypass the cache, though a balance read within the last 5 seconds is still reused.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Initiate a deposit",
+                "summary": "Get wallet balances",
                 "parameters": [
                     {
-                        "description": "Deposit details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.InitiateDepositRequest"
-                        }
+                        "type": "boolean",
+                        "description": "Read balances on-chain instead of from the cache",
+                        "name": "fresh",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.InitiateDepositResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid request or no wallet linked",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.GetBalancesResponse"
                         }
                     },
                     "401": {
@@ -221,8 +206,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Server error",
+                    "404": {
+                        "description": "Account not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -233,41 +218,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/account/deposits": {
+        "/v1/account/balances/history": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns paginated deposit history for the authenticated account",
+                "description": "Returns one point per UTC day with the on-platform balance at the end of the day, and the on-chain USDC balances of the account's Base and Solana wallets when they were read that day. The current day's point is the latest snapshot, taken hourly by default. Deposits and withdrawals over the same period are listed as events for markers; withdrawals are balance debits that settle payments owed for scans served on credit. Days before the account's first snapshot are omitted.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Get deposit history",
+                "summary": "Get balance history",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Number of records to return (default 50)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of records to skip (default 0)",
-                        "name": "offset",
+                        "description": "Number of days to include, ending today (default 30, max 365)",
+                        "name": "days",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Deposits with pagination",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/db.BalanceHistory"
                         }
                     },
                     "401": {
@@ -291,41 +269,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/account/usage": {
+        "/v1/account/config-sync": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns paginated usage logs for the authenticated account",
+                "description": "Returns the latest encrypted CLI configuration uploaded by one of the account's machines, with its version.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Get usage logs",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Number of records to return (default 50)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of records to skip (default 0)",
-                        "name": "offset",
-                        "in": "query"
-                    }
-                ],
+                "summary": "Get synced CLI configuration",
                 "responses": {
                     "200": {
-                        "description": "Usage logs with pagination",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/db.ConfigSync"
                         }
                     },
                     "401": {
@@ -337,8 +300,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Server error",
+                    "404": {
+                        "description": "Nothing synced yet",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -347,37 +310,49 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/v1/account/usage/stats": {
-            "get": {
+            },
+            "put": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns aggregated usage statistics including daily breakdown and endpoint stats",
+                "description": "Stores a new version of the account's encrypted CLI configuration. The upload must name the version it was based on; if another machine uploaded since, it is refused with 409 and the current version so the caller can pull first.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Get usage statistics",
+                "summary": "Upload synced CLI configuration",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Number of days to include (default 30, max 365)",
-                        "name": "days",
-                        "in": "query"
+                        "description": "Base version and base64 ciphertext",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PutConfigSyncRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Usage statistics",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.ConfigSync"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid data",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -389,8 +364,24 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Server error",
+                    "403": {
+                        "description": "Trusted device required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Changed since base_version",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Configuration too large",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -401,14 +392,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/account/wallet": {
-            "put": {
+        "/v1/account/deposit": {
+            "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Links an Ethereum wallet address to the account for receiving crypto",
+                "description": "Start a deposit via Stripe (requires linked wallet) or direct crypto transfer",
                 "consumes": [
                     "application/json"
                 ],
@@ -418,21 +409,27 @@ const docTemplate = `{
                 "tags": [
                     "account"
                 ],
-                "summary": "Link a wallet address",
+                "summary": "Initiate a deposit",
                 "parameters": [
                     {
-                        "description": "Wallet address (0x...)",
+                        "description": "Deposit details",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LinkWalletRequest"
+                            "$ref": "#/definitions/handlers.InitiateDepositRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Wallet linked successfully",
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InitiateDepositResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or no wallet linked",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -440,8 +437,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid wallet address or already linked",
+                    "401": {
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -449,8 +446,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Not authenticated",
+                    "500": {
+                        "description": "Server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -461,38 +458,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/account": {
-            "post": {
-                "description": "Creates a new account with a generated account number and server-side wallet. Optionally accepts a private key to import an existing wallet.",
-                "consumes": [
-                    "application/json"
+        "/v1/account/deposits": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
                 ],
+                "description": "Returns paginated deposit history for the authenticated account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
                 ],
-                "summary": "Create a new account",
+                "summary": "Get deposit history",
                 "parameters": [
                     {
-                        "description": "Optional wallet address or private key",
-                        "name": "request",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CreateAccountRequest"
-                        }
+                        "type": "integer",
+                        "description": "Number of records to return (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of records to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "Deposits with pagination",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateAccountResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid request",
+                    "401": {
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -512,9 +516,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/login": {
+        "/v1/account/installs": {
             "post": {
-                "description": "Authenticates using account number and sets httpOnly auth cookies. Returns decrypted wallet key if KMS-encrypted key exists.",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Registers the Ed25519 public key a proxy install signs scan requests with. Signed scans are attributed to the install until it is revoked.",
                 "consumes": [
                     "application/json"
                 ],
@@ -522,29 +531,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
                 ],
-                "summary": "Login to an account",
+                "summary": "Register a proxy install",
                 "parameters": [
                     {
-                        "description": "Account number",
+                        "description": "Base64 public key and machine details",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/handlers.RegisterInstallRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginResponse"
+                            "$ref": "#/definitions/handlers.InstallResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid public key",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -553,7 +562,7 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "Invalid credentials",
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -561,8 +570,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Account not active",
+                    "409": {
+                        "description": "Public key already registered",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -573,24 +582,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/logout": {
-            "post": {
+        "/v1/account/installs/{id}": {
+            "delete": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Logs out from all sessions and clears auth cookies",
+                "description": "Revokes a proxy install, e.g. a lost or compromised machine. Scan requests it signs are rejected with 403 from then on.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
+                ],
+                "summary": "Revoke a proxy install",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Install ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Logout",
                 "responses": {
                     "200": {
-                        "description": "Logged out successfully",
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid install ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -606,34 +633,71 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "404": {
+                        "description": "Install not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/v1/auth/me": {
+        "/v1/account/machines": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the authenticated user's account information",
+                "description": "Lists the account's registered proxy installs with the version and protection status from their latest heartbeat. An active install that hasn't reported in 15 minutes is shown with state \"dark\". Each machine's usage counts the scans it signed over the last ` + "`" + `days` + "`" + ` days, or ` + "`" + `start` + "`" + ` to ` + "`" + `end` + "`" + ` when both are given; its spend is the price of its x402 scans.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
+                ],
+                "summary": "List machines",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of days of usage to include (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Usage window start (RFC 3339)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Usage window end (RFC 3339)",
+                        "name": "end",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get current user",
                 "responses": {
                     "200": {
-                        "description": "Account info with id, account_number, wallet_address, balance_usdc, status",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/handlers.ListMachinesResponse"
                         }
                     },
-                    "401": {
+                    "400": {
+                        "description": "Invalid window",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
                         "description": "Not authenticated",
                         "schema": {
                             "type": "object",
@@ -645,25 +709,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/refresh": {
-            "post": {
-                "description": "Uses the refresh token cookie to get a new access token",
+        "/v1/account/overview": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns everything the dashboard shows on load in one request: the balance, scans, blocks, block rate and spend over the last 24 hours, 7 days and 30 days, and the 10 most recent scans and deposits. Usage comes from hourly rollups, so windows are whole UTC hours ending with the current one. Responses may be cached for up to 30 seconds by default; generated_at tells when the overview was read.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
                 ],
-                "summary": "Refresh access token",
+                "summary": "Get account overview",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.RefreshTokenResponse"
+                            "$ref": "#/definitions/db.AccountOverview"
                         }
                     },
                     "401": {
-                        "description": "Invalid or expired refresh token",
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -671,8 +740,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Account not active",
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -683,14 +761,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/auth/wallet": {
+        "/v1/account/policy/jailbreak": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the account-level jailbreak policy and the effective policy after organization overrides",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Get jailbreak detection policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JailbreakPolicyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
             "put": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Replace the wallet for the authenticated account with a new private key",
+                "description": "Toggles jailbreak detection and chooses whether jailbreak-only detections warn or block",
                 "consumes": [
                     "application/json"
                 ],
@@ -698,17 +808,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "policy"
                 ],
-                "summary": "Update wallet",
+                "summary": "Update jailbreak detection policy",
                 "parameters": [
                     {
-                        "description": "New private key (hex-encoded)",
+                        "description": "Policy update",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.UpdateWalletRequest"
+                            "$ref": "#/definitions/handlers.JailbreakPolicyRequest"
                         }
                     }
                 ],
@@ -716,7 +826,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UpdateWalletResponse"
+                            "$ref": "#/definitions/handlers.JailbreakPolicyResponse"
                         }
                     },
                     "400": {
@@ -736,42 +846,50 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
-                    },
-                    "500": {
-                        "description": "Server error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
                     }
                 }
             }
         },
-        "/v1/pricing": {
+        "/v1/account/policy/prompts": {
             "get": {
-                "description": "Returns the pricing for all protected endpoints",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the system prompts registered for leak detection in output scans. Only their names and shingle counts are kept.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "pricing"
+                    "policy"
                 ],
-                "summary": "Get pricing information",
+                "summary": "List registered system prompts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.PricingResponse"
+                            "$ref": "#/definitions/handlers.PromptFingerprintsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/v1/scan/content": {
+            },
             "post": {
-                "description": "Scans content from external sources (websites, files, APIs) for prompt injection attacks before passing to LLM",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Registers a system prompt so output scans flag responses that repeat it. Send the prompt (hashed into shingles and discarded) or shingles computed client-side: truncated SHA-256 hashes (16 hex characters) of every 8-word run of the lowercased prompt split on non-alphanumerics. Prompts need at least 8 words.",
                 "consumes": [
                     "application/json"
                 ],
@@ -779,29 +897,93 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scan"
+                    "policy"
                 ],
-                "summary": "Scan external content for prompt injection",
+                "summary": "Register a system prompt",
                 "parameters": [
                     {
-                        "description": "Content scan request",
+                        "description": "Prompt name and text or shingles",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.ScanContentRequest"
+                            "$ref": "#/definitions/handlers.RegisterPromptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PromptFingerprintResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid prompt or shingles",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Name already registered or too many prompts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/v1/account/policy/prompts/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Stops checking output scans for leaks of the prompt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Delete a registered system prompt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Prompt ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/stronghold.ScanResult"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -809,49 +991,101 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "402": {
-                        "description": "Payment Required",
+                    "404": {
+                        "description": "Prompt not found",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/v1/scan/output": {
+        "/v1/account/policy/rule-packs": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns every rule pack version the account has imported, and which are enabled for its scans and listed in the public catalogue.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "List rule packs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RulePacksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Scans LLM output text for credential leaks and sensitive data exposure",
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Imports a YAML rule pack, as exported from any account or the public catalogue. The pack is validated and its tests are run; every problem found is returned. Each name and version can be imported once. Pass enable=true to enable it at once, replacing any enabled version of the same pack.",
                 "consumes": [
-                    "application/json"
+                    "application/yaml"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "scan"
+                    "policy"
                 ],
-                "summary": "Scan LLM output for credential leaks",
+                "summary": "Import a rule pack",
                 "parameters": [
                     {
-                        "description": "Output scan request",
+                        "type": "boolean",
+                        "description": "Enable the pack for scans",
+                        "name": "enable",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Rule pack YAML",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.ScanOutputRequest"
+                            "type": "string"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/stronghold.ScanResult"
+                            "$ref": "#/definitions/db.RulePack"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid rule pack, with problems",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -859,232 +1093,7176 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "402": {
-                        "description": "Payment Required",
+                    "409": {
+                        "description": "Version already imported or too many rule packs",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.CreateAccountRequest": {
+        },
+        "/v1/account/policy/rule-packs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the pack's YAML as imported, ready to import into another account.",
+                "produces": [
+                    "application/yaml"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Export a rule pack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule pack ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule pack YAML",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Rule pack not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Enables or disables the pack for the account's scans, and lists it in or removes it from the public catalogue. Enabling a version disables the pack's other versions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Update a rule pack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule pack ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Changes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateRulePackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.RulePack"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Rule pack not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Removes the pack version from the account; if it was enabled, its rules stop applying to scans.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Delete a rule pack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule pack ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Rule pack not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/policy/scores": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns how much scoring detail API key scan responses carry: exact scores, scores quantized to steps of 0.1, or none (decision and threat categories only). Shown for the account and for each active API key, which may override it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Get score exposure policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoreExposureResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Sets how much scoring detail API key scan responses carry, for keys without an override. Quantized rounds scores to steps of 0.1; omitted leaves only the decision and threat categories, so exact scores can't be used to tune payloads.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Update score exposure policy",
+                "parameters": [
+                    {
+                        "description": "Score exposure",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoreExposureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoreExposureResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/policy/scores/keys/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Sets how much scoring detail scans made with this key return, whatever the account setting. Send null to clear the override.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Override score exposure for an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Score exposure, or null",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoreExposureRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoreExposureResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/policy/scoring": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the account's scoring profiles by scanning mode. A profile replaces the engine's combination of layer scores with a weighted mean and maps it to a decision with a threshold curve. Scans in a mode without a profile use the default profile, or the engine's own scoring when there is none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "List scoring profiles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoringProfilesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/policy/scoring/{mode}": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Sets the layer weights (heuristic, semantic, ml, llm) and threshold curve applied to the account's content scans in a scanning mode, or in every mode without its own profile when mode is default. Weights must be non-negative with at least one positive; curve points must ascend through (0, 1] with WARN before BLOCK. Without a curve the scanner's warn and block thresholds are used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Set scoring profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "default, smart, strict or permissive",
+                        "name": "mode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Weights and threshold curve",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/stronghold.ScoringProfile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScoringProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid mode or profile",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Removes the account's scoring profile for a mode. Scans in that mode fall back to the default profile, or to the engine's own scoring.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Delete scoring profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "default, smart, strict or permissive",
+                        "name": "mode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No profile for this mode",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/redeem": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Credits the value of a single-use prepaid code to the account balance and records it as a deposit with provider prepaid_code. Codes may expire, and each batch limits how many of its codes one account may redeem. After too many failed attempts from the account or IP address, redemption is locked for a while.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Redeem a prepaid code",
+                "parameters": [
+                    {
+                        "description": "Prepaid code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RedeemCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RedeemCodeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Account not active",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Code not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Code already redeemed or batch limit reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Code expired",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/transfer": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Moves on-platform USDC balance to another account by account number and records a ledger entry on both accounts. Requires TOTP to be enabled and a current TOTP code or an unused recovery code with every transfer. An account may transfer out a limited amount per UTC day.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Transfer balance to another account",
+                "parameters": [
+                    {
+                        "description": "Recipient, amount and TOTP code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated or invalid code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Insufficient balance",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "TOTP not enabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Recipient not found or transfers disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Recipient account not active",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Daily transfer limit reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/transfers": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the account's ledger entries for transfers to and from other accounts, newest first, with the daily limit and how much of it has been used today.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "List balance transfers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to return (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ledger entries with pagination",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/usage": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns paginated usage logs for the authenticated account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Get usage logs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of records to return (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of records to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usage logs with pagination",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/usage/export": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Downloads the account's usage per UTC day as CSV, newest first: requests, cost, threats detected, blocked scans, spend, and p50, p95 and p99 latency in milliseconds. Amounts are in USDC. Finished days are read from daily rollups, so exports cost the same however much traffic the account sends.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Export daily usage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of days to include (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV of daily usage",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/usage/stats": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns aggregated usage statistics including daily breakdown and endpoint stats",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Get usage statistics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of days to include (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Usage statistics",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/usage/verify": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Recomputes the hash chain over the account's usage logs and reports the first record that does not match",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Verify usage log integrity",
+                "responses": {
+                    "200": {
+                        "description": "Verification result",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/wallets": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Update EVM and/or Solana wallet addresses for the authenticated account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Update wallet addresses",
+                "parameters": [
+                    {
+                        "description": "Wallet addresses to update (both optional)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWalletsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWalletsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Wallet address already linked to another account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/webhooks/settlement": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the URL notified when an x402 payment from one of the account's wallets settles. The signing secret is not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Get settlement webhook",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SettlementWebhookResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No settlement webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Sets the https URL notified when an x402 payment from one of the account's wallets settles. Returns the secret used to sign deliveries; it is kept when the URL changes and replaced only by deleting and recreating the webhook.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Set settlement webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook URL",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SettlementWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SettlementWebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid URL",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Stops settlement notifications and drops any not yet delivered",
+                "tags": [
+                    "account"
+                ],
+                "summary": "Delete settlement webhook",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No settlement webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/account/webhooks/settlement/test": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Immediately sends a signed webhook.test event to the account's settlement webhook and reports how the receiver answered. Test deliveries are signed like settlement deliveries but aren't retried.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Send test webhook",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/settlement.TestDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No settlement webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Webhook delivery unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/abuse": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the accounts and anonymous addresses this API instance has scored for probing the detection engine, highest score first, with the signals seen, the response level applied, and whether the account was suspended.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Abusive scan callers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AbuseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/abuse/{key}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Forgets a caller's abuse score and history on this API instance, lifting jitter and throttling. An account the detector suspended is reactivated.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Clear an abuse score",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller key: account:\u003cid\u003e or ip:\u003caddress\u003e",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Standing before the reset",
+                        "schema": {
+                            "$ref": "#/definitions/abuse.Caller"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Caller not scored",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to reactivate account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/accounts/{account_id}/encryption-key": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Seals the account's stored scan samples under a data key wrapped by the customer's AWS KMS key. The key must grant Stronghold kms:GenerateDataKey and kms:Decrypt, and is checked before it is saved. If the customer later disables or revokes the key, samples sealed under it become unreadable and new samples are not stored. Only business accounts can use customer-managed keys.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set customer-managed encryption key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "KMS key ARN",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EncryptionKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EncryptionKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, key unusable, or not a business account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "KMS unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Customer-managed keys not available",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the account's customer-managed key and deletes the scan samples sealed under it. New samples are stored like other accounts' samples.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Remove customer-managed encryption key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteEncryptionKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid account ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/accounts/{account_id}/region": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pins the account's scans to a region. Instances in other regions answer the account's API key requests with 421 and the regional endpoint, so scan content is only processed where the account is pinned. The region must be one of REGION_ENDPOINTS; an empty region removes the pin.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set account region",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Region",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AccountRegionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AccountRegionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or unknown region",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/accounts/{account_id}/scan-backend": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Routes the account's scans to a detection backend: the built-in engine (internal), the weighted ensemble (ensemble), an external service from SCAN_BACKENDS, or the account's own HTTPS endpoint (custom, with url and an optional bearer token). An empty backend returns the account to SCAN_DEFAULT_BACKEND.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set account scan backend",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Backend selection",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanBackendRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanBackendResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or unknown backend",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/canary": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the stable and canary detection versions, the default canary percentage, and accounts enrolled with their own percentage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get canary status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CanaryStatusResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/canary/accounts/{account_id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shadow-scores the given percentage of the account's scans with the canary detection configuration. The stable decision is always enforced.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Enroll account in canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Canary percentage",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CanaryEnrollmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.CanaryEnrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the account's canary enrollment; its scans fall back to the default canary percentage",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Remove account from canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid account ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/canary/results": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns how often the canary agreed with the stable decision, counts per decision transition, and the most recent disagreements",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get canary results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only results for this account",
+                        "name": "account_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 start time (default 7 days ago)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum disagreements returned (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CanaryResultsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/chaos": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the faults injected into facilitator calls and database connections. Fault injection is only available in API builds made with -tags chaos.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List injected faults",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChaosResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/chaos/{point}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Injects a fault at a point, replacing any already there. \"facilitator\" affects every facilitator request, including retries. \"db\" affects database connection checkouts; drop and error kill the connection and fail the query. Only available in API builds made with -tags chaos.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Inject a fault",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Injection point: facilitator or db",
+                        "name": "point",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fault",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChaosFaultRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/chaos.Fault"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid fault",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Fault injection not compiled in",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the fault injected at a point",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Remove a fault",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Injection point",
+                        "name": "point",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/detection/latency": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns scan count, error count, mean and maximum latency for each detection stage on this API instance since startup: engine (heuristic, semantic and LLM layers), ml (local ONNX classifier) and output (credential scan), plus how long the ml layer's warm-up took.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Detection stage latency",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DetectionLatencyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/flags": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all feature flags as stored. API instances pick up changes within FEATURE_FLAGS_REFRESH_INTERVAL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List feature flags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/db.FeatureFlag"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/flags/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates or replaces a feature flag. Well-known flags: \"maintenance:\u003cpath\u003e\" and \"maintenance:*\" answer 503 for an endpoint or all endpoints, and \"payments.log_only\" serves requests that would get 402 without charging them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set feature flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Flag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Flag settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FeatureFlagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.FeatureFlag"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a feature flag; a missing flag is off",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete feature flag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Flag name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid flag name",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Flag not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/payments/retention": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns payment transaction counts by status, the oldest prunable payment, on-disk sizes of the payment and archive tables, the retention settings, and the last pruning pass on this API instance.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Payment retention",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PaymentRetentionResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/prepaid-codes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists prepaid code batches, newest first, with how many of their codes have been redeemed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List prepaid code batches",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of batches to return (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of batches to skip (default 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Batches with pagination",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generates single-use codes that each credit a fixed USDC value to the account that redeems them, for trials and offline sales. Only hashes are stored, so the codes are returned once and can't be listed again. per_account_limit caps how many codes from the batch one account may redeem.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Generate prepaid codes",
+                "parameters": [
+                    {
+                        "description": "Batch terms",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePrepaidCodesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePrepaidCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Prepaid codes disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to generate codes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/prepaid-codes/{batch_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stops the batch's unredeemed codes from being redeemed, for example when codes leak. Balance already credited from the batch is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Void prepaid codes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "batch_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Voided"
+                    },
+                    "400": {
+                        "description": "Invalid batch ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/ratelimit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns allowed and limited request counts for each rate limiter on this API instance since startup, and how many requests were let through because the rate limit store was unavailable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Rate limiter stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RateLimitStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/replication/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns usage records created after the since/after_id cursor, oldest first, for replication to other regions. Only non-content metadata leaves the region: scanned text, threat reasons and payment payloads are never included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export usage for replication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 cursor time (default: beginning)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor record ID at since",
+                        "name": "after_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum records returned (default and max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReplicationUsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/account": {
+            "post": {
+                "description": "Creates a new account with a generated account number and server-side wallet. Optionally accepts a private key to import an existing wallet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Create a new account",
+                "parameters": [
+                    {
+                        "description": "Optional wallet address or private key",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAccountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/devices/approvals": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists requests from new machines waiting to be trusted. Must be called from a trusted device.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List pending device approvals",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/db.DeviceApproval"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not a trusted device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Starts trusting a new machine without a TOTP code. The response carries a device token that becomes trusted once an existing trusted device approves the request. The account's webhook, if set, receives a device.approval_requested event so the owner knows to decide. Poll GET /v1/auth/devices/approvals/{id} with the token in X-Stronghold-Device until the status is approved, denied or expired.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Request device approval",
+                "parameters": [
+                    {
+                        "description": "Device label and trust duration",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeviceApprovalRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeviceApprovalResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "No trusted device can approve the request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too many pending requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/devices/approvals/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the status of a device approval request: pending, approved, denied or expired. Only the requesting machine can poll, by sending the device token from the request in X-Stronghold-Device. Once approved, that token is a trusted device token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Poll device approval",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Approval ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeviceApprovalStatusResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Approval not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/devices/approvals/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Trusts the machine that made a pending request. Must be called from a trusted device; a machine can't approve its own request.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Approve a device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Approval ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.Device"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not a trusted device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No pending approval",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/devices/approvals/{id}/deny": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Rejects a pending request from a new machine. Must be called from a trusted device.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Deny a device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Approval ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not a trusted device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No pending approval",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/login": {
+            "post": {
+                "description": "Authenticates using account number and sets httpOnly auth cookies. Returns decrypted wallet key if KMS-encrypted key exists.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login to an account",
+                "parameters": [
+                    {
+                        "description": "Account number",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Account not active",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Logs out from all sessions and clears auth cookies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "Logged out successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/me": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the authenticated user's account information",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get current user",
+                "responses": {
+                    "200": {
+                        "description": "Account info with id, account_number, evm_wallet_address, solana_wallet_address, balance_usdc, status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/refresh": {
+            "post": {
+                "description": "Uses the refresh token cookie to get a new access token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired refresh token, or session bound to another device",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Account not active",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/sso/lookup": {
+            "get": {
+                "description": "Resolves a work email to the WorkOS organization that owns its verified domain. Pass the organization ID to AuthKit sign-in so the user is sent straight to their identity provider.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Find SSO organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Work email address",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SSOLookupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid email",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No organization uses SSO for this domain",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "SSO is not configured",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/wallet": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Replace the wallet for the authenticated account with a new private key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Update wallet",
+                "parameters": [
+                    {
+                        "description": "New private key (hex-encoded)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/wallet-key": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the KMS-decrypted wallet private key for the authenticated account",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get wallet private key",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.GetWalletKeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No encrypted key found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/detection/version": {
+            "get": {
+                "description": "Returns the detection rules version, engine version, enabled layers and thresholds, with a changelog of detection releases. The version field matches detection_version in scan results and usage logs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Get detection version",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DetectionVersionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/ingest/check": {
+            "post": {
+                "description": "Scans up to 64 documents bound for a vector store, like /v1/scan/documents, and records each document's SHA-256 fingerprint with its verdict so the documents can be rechecked with /v1/ingest/recheck when detection improves. Document text is never stored. Fingerprints are kept per account for API key requests and per payer wallet for x402 requests.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Check documents before ingestion",
+                "parameters": [
+                    {
+                        "description": "Documents to check",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanDocumentsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.IngestCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/ingest/recheck": {
+            "post": {
+                "description": "Rescans documents previously checked with /v1/ingest/check, for use when detection improves (see /v1/detection/version). Send the documents' text again, since only fingerprints are stored. Documents whose fingerprint the caller never checked are reported as unknown and not scanned. Each rescanned document's verdict is compared with the one it was last checked under, and the recorded verdict is updated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ingest"
+                ],
+                "summary": "Recheck ingested documents",
+                "parameters": [
+                    {
+                        "description": "Documents to recheck",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanDocumentsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.IngestRecheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/installs/heartbeat": {
+            "post": {
+                "description": "Called periodically by a registered proxy install, signed with its install key, to report its version and protection status.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Proxy install heartbeat",
+                "parameters": [
+                    {
+                        "description": "Version and protection status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HeartbeatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HeartbeatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid protection status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid install signature",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Install revoked",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "426": {
+                        "description": "Proxy version refused",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org/members": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the B2B accounts in the caller's WorkOS organization with their role and status, including accounts provisioned or deprovisioned by the organization's directory. Requires the admin role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "List organization members",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Not an organization admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org/policy/jailbreak": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the jailbreak policy override for the caller's WorkOS organization, or null if none is set",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Get organization jailbreak policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Not an organization session",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets a jailbreak policy that overrides the settings of every account in the organization. Requires the admin role.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Update organization jailbreak policy",
+                "parameters": [
+                    {
+                        "description": "Policy update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.JailbreakPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an organization admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the organization override so member accounts use their own settings. Requires the admin role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Remove organization jailbreak policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Not an organization admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/org/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Aggregates scans, spend, blocks and block rate across every account in the caller's WorkOS organization, broken down by member, by API key and by day (UTC). The window is the last ` + "`" + `days` + "`" + ` days, or ` + "`" + `start` + "`" + ` to ` + "`" + `end` + "`" + ` when both are given. Requires the admin role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Get organization usage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of days to include (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window start (RFC 3339)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window end (RFC 3339)",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/db.OrganizationUsage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid window",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an organization admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pricing": {
+            "get": {
+                "description": "Returns the pricing for all protected endpoints, with per-network payment parameters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pricing"
+                ],
+                "summary": "Get pricing information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PricingResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pricing/estimate": {
+            "post": {
+                "description": "Estimates the monthly cost of a planned workload under current pricing. Each workload names a priced endpoint and the documents it scans a month; documents larger than the per-request text limit, and batches for /v1/scan/documents and /v1/ingest, are converted into billed requests. Volume discounts are applied as the month's requests reach each tier. Requests with an API key start from the requests the account has already made this month.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pricing"
+                ],
+                "summary": "Estimate monthly cost",
+                "parameters": [
+                    {
+                        "description": "Planned workloads",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EstimateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EstimateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/rule-packs": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the rule packs accounts have shared publicly, by name with the newest version first. Export one with GET /v1/rule-packs/{id} and import it with POST /v1/account/policy/rule-packs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "List public rule packs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RulePacksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/rule-packs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns a publicly shared pack's YAML, ready to import.",
+                "produces": [
+                    "application/yaml"
+                ],
+                "tags": [
+                    "policy"
+                ],
+                "summary": "Export a public rule pack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rule pack ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rule pack YAML",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Rule pack not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/content": {
+            "post": {
+                "description": "Scans content from external sources (websites, files, APIs) for prompt injection attacks before passing to LLM",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data",
+                    "application/x-www-form-urlencoded",
+                    "text/plain",
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Scan external content for prompt injection",
+                "parameters": [
+                    {
+                        "description": "Content scan request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanContentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stronghold.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Raw body without a supported charset",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/documents": {
+            "post": {
+                "description": "Scans up to 64 named documents, such as retrieved RAG chunks, returning a verdict for each plus an overall decision and the worst offender, so pipelines can drop only the poisoned documents. The combined text is subject to the same size limit as /v1/scan/content.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Scan multiple documents for prompt injection",
+                "parameters": [
+                    {
+                        "description": "Documents to scan",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanDocumentsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanDocumentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/keys": {
+            "post": {
+                "description": "Issues a short-lived X25519 key. Scan requests sealed to it with the e2escan protocol and sent with Content-Type application/vnd.stronghold.e2e+json are only decrypted by the scanning backend, and their responses come back sealed. Any scan or ingest endpoint accepts them. The key is free and needs no authentication; fetch a new one when it expires or a scan answers 400 with code e2e_key_rejected.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Get a key for end-to-end encrypted scans",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanKeyResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/output": {
+            "post": {
+                "description": "Scans LLM output text for credential leaks and sensitive data exposure",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Scan LLM output for credential leaks",
+                "parameters": [
+                    {
+                        "description": "Output scan request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanOutputRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stronghold.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/session": {
+            "post": {
+                "description": "Opens a session for scanning a conversation's messages one at a time with POST /v1/scan/session/message. Each message is scanned on its own and together with the conversation's recent messages, so injection built up across turns is caught. Sessions expire after ttl_seconds without messages. Opening a session is free; sessions opened with an API key can only be used by that account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Start a conversation scan session",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanSessionResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid API key",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Session store unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/session/message": {
+            "post": {
+                "description": "Scans a message for prompt injection on its own and together with the session's recent messages. When the conversation reads as an injection but the message alone doesn't, the decision is raised with a multi_turn_injection threat; scores.context holds the conversation's score. The message is then added to the session and the session's TTL restarts. Charged per message.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Scan a conversation message",
+                "parameters": [
+                    {
+                        "description": "Session message scan request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanSessionMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stronghold.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found or expired",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/scan/tool-call": {
+            "post": {
+                "description": "Checks a tool call's arguments for dangerous shell commands, paths to credentials and startup files, and URLs pointing at internal networks, cloud metadata or exfiltration services. Rules follow the tool's kind (shell, file, http) and argument names. Each finding is located at its argument's JSON path. Critical and high findings block; medium findings warn. Arguments may be a JSON value or a string holding one, as LLM APIs return them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scan"
+                ],
+                "summary": "Scan a tool call before execution",
+                "parameters": [
+                    {
+                        "description": "Tool call scan request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ScanToolCallRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stronghold.ScanResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sub-accounts": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists the account's open sub-accounts by name, with their budget, spend and what is left of the budget.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "List sub-accounts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/handlers.SubAccountResponse"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not a business account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Creates a sub-account for a project or agent. It gets its own API keys and usage, and spends the account's balance up to budget_usdc when one is set. Business accounts only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Create a sub-account",
+                "parameters": [
+                    {
+                        "description": "Sub-account",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateSubAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SubAccountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or sub-account limit reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not a business account",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Name already in use",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sub-accounts/usage": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Aggregates scans, blocks, threats and spend by sub-account over the last ` + "`" + `days` + "`" + ` days, or ` + "`" + `start` + "`" + ` to ` + "`" + `end` + "`" + ` when both are given. Closed sub-accounts are included when they have usage in the window.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Get sub-account usage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of days to include (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window start (RFC 3339)",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Window end (RFC 3339)",
+                        "name": "end",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/db.SubAccountUsage"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid window",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sub-accounts/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Closes the sub-account and revokes its API keys. Its past usage stays attributed to it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Close a sub-account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sub-account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Sub-account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sub-accounts/{id}/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists the sub-account's active API keys. Keys are revoked with DELETE /v1/api-keys/{id}.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "List sub-account API keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sub-account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/handlers.APIKeyListItem"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Sub-account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Creates an API key that bills and attributes its usage to the sub-account. The full key is returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Create a sub-account API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sub-account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Key label",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing label or key limit reached",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Sub-account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/sub-accounts/{id}/budget": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Replaces the most the sub-account may spend in total, counting what it has already spent. A null budget_usdc removes the cap, leaving only the account balance as a limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Set a sub-account's budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sub-account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Budget",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SetSubAccountBudgetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SubAccountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid budget",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Sub-account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/signing-keys": {
+            "get": {
+                "description": "Returns the Ed25519 public keys, in JWK form, that verify the Stronghold-Signature-Ed25519 header of webhook deliveries. The active key signs new deliveries; retired keys stay listed while deliveries signed with them may still arrive. Match a signature to its key by kid, and reject deliveries whose timestamp is more than tolerance_seconds from your clock. The list is empty when the server has no signing key.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Webhook signing keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SigningKeysResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "abuse.Caller": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "string"
+                },
+                "refused": {
+                    "type": "integer"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "signals": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "suspended": {
+                    "description": "Account suspended by the detector",
+                    "type": "boolean"
+                }
+            }
+        },
+        "chaos.Fault": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "delay_ms": {
+                    "type": "integer"
+                },
+                "point": {
+                    "type": "string"
+                },
+                "rate": {
+                    "description": "Share of calls affected; 0 means all",
+                    "type": "number"
+                }
+            }
+        },
+        "db.APIKeyUsage": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "block_rate": {
+                    "type": "number"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "key_id": {
+                    "type": "string"
+                },
+                "key_prefix": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "spend_usdc": {
+                    "type": "integer"
+                },
+                "threats_detected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.AccountEvent": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "description": "net amount of deposits",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "decision": {
+                    "description": "scans only",
+                    "type": "string"
+                },
+                "endpoint": {
+                    "description": "scans only",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "deposits only",
+                    "type": "string"
+                },
+                "threat_type": {
+                    "description": "scans only",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "\"scan\" or \"deposit\"",
+                    "type": "string"
+                }
+            }
+        },
+        "db.AccountOverview": {
+            "type": "object",
+            "properties": {
+                "balance_usdc": {
+                    "type": "integer"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "last_24h": {
+                    "$ref": "#/definitions/db.UsageAggregate"
+                },
+                "last_30d": {
+                    "$ref": "#/definitions/db.UsageAggregate"
+                },
+                "last_7d": {
+                    "$ref": "#/definitions/db.UsageAggregate"
+                },
+                "recent_events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.AccountEvent"
+                    }
+                }
+            }
+        },
+        "db.BalanceEvent": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "description": "net amount of deposits",
+                    "type": "integer"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "description": "deposits only",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "\"deposit\", \"withdrawal\", \"transfer_out\" or \"transfer_in\"",
+                    "type": "string"
+                }
+            }
+        },
+        "db.BalanceHistory": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.BalanceSnapshot"
+                    }
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.BalanceEvent"
+                    }
+                }
+            }
+        },
+        "db.BalanceSnapshot": {
+            "type": "object",
+            "properties": {
+                "balance_usdc": {
+                    "type": "integer"
+                },
+                "date": {
+                    "description": "YYYY-MM-DD",
+                    "type": "string"
+                },
+                "evm_balance_usdc": {
+                    "type": "integer"
+                },
+                "solana_balance_usdc": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.CanaryEnrollment": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.CanaryResult": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "canary_decision": {
+                    "type": "string"
+                },
+                "canary_score": {
+                    "type": "number"
+                },
+                "canary_version": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "stable_decision": {
+                    "type": "string"
+                },
+                "stable_score": {
+                    "type": "number"
+                },
+                "stable_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.CanarySummary": {
+            "type": "object",
+            "properties": {
+                "agreed": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "transitions": {
+                    "description": "\"ALLOW-\u003eBLOCK\": count, stable decision first",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                }
+            }
+        },
+        "db.ConfigSync": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.DailyOrganizationUsage": {
+            "type": "object",
+            "properties": {
+                "block_rate": {
+                    "type": "number"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "spend_usdc": {
+                    "type": "integer"
+                },
+                "threats_detected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.Deposit": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "amount_usdc": {
+                    "type": "integer"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "fee_usdc": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "net_amount_usdc": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "$ref": "#/definitions/db.DepositProvider"
+                },
+                "provider_transaction_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/db.DepositStatus"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.DepositProvider": {
+            "type": "string",
+            "enum": [
+                "stripe",
+                "direct",
+                "prepaid_code"
+            ],
+            "x-enum-varnames": [
+                "DepositProviderStripe",
+                "DepositProviderDirect",
+                "DepositProviderPrepaidCode"
+            ]
+        },
+        "db.DepositStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "completed",
+                "failed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "DepositStatusPending",
+                "DepositStatusCompleted",
+                "DepositStatusFailed",
+                "DepositStatusCancelled"
+            ]
+        },
+        "db.Device": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "last_seen_ip": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.DeviceApproval": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "decided_at": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "requested_ip": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "ttl_days": {
+                    "type": "integer"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.FeatureFlag": {
+            "type": "object",
+            "properties": {
+                "account_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.JailbreakPolicy": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "\"warn\" or \"block\"",
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "source": {
+                    "description": "\"default\", \"account\", or \"organization\"",
+                    "type": "string"
+                }
+            }
+        },
+        "db.LedgerEntry": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "type": "integer"
+                },
+                "balance_after_usdc": {
+                    "type": "integer"
+                },
+                "counterparty_account_number": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "\"transfer_out\" or \"transfer_in\"",
+                    "type": "string"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "transfer_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.MemberUsage": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "block_rate": {
+                    "type": "number"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "spend_usdc": {
+                    "type": "integer"
+                },
+                "threats_detected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.OrganizationUsage": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.APIKeyUsage"
+                    }
+                },
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.DailyOrganizationUsage"
+                    }
+                },
+                "end": {
+                    "type": "string"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.MemberUsage"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "totals": {
+                    "$ref": "#/definitions/db.UsageAggregate"
+                }
+            }
+        },
+        "db.PaymentTableStats": {
+            "type": "object",
+            "properties": {
+                "archive_bytes": {
+                    "type": "integer"
+                },
+                "archive_rows": {
+                    "description": "planner estimate",
+                    "type": "integer"
+                },
+                "counts": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer",
+                        "format": "int64"
+                    }
+                },
+                "oldest": {
+                    "description": "oldest completed or expired payment still in the table",
+                    "type": "string"
+                },
+                "table_bytes": {
+                    "description": "including indexes",
+                    "type": "integer"
+                }
+            }
+        },
+        "db.PrepaidCodeBatch": {
+            "type": "object",
+            "properties": {
+                "code_count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "per_account_limit": {
+                    "type": "integer"
+                },
+                "redeemed_count": {
+                    "type": "integer"
+                },
+                "value_usdc": {
+                    "type": "integer"
+                },
+                "voided_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.ReplicatedUsage": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "cost_usdc": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detection_version": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "method": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "threat_detected": {
+                    "type": "boolean"
+                },
+                "threat_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.RulePack": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "rules": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.ScoreExposure": {
+            "type": "object",
+            "properties": {
+                "exposure": {
+                    "description": "\"exact\", \"quantized\" or \"omitted\"",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "\"default\", \"account\" or \"api_key\"",
+                    "type": "string"
+                }
+            }
+        },
+        "db.SubAccountUsage": {
+            "type": "object",
+            "properties": {
+                "block_rate": {
+                    "type": "number"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "closed_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "spend_usdc": {
+                    "type": "integer"
+                },
+                "sub_account_id": {
+                    "type": "string"
+                },
+                "threats_detected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.UsageAggregate": {
+            "type": "object",
+            "properties": {
+                "block_rate": {
+                    "type": "number"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "scans": {
+                    "type": "integer"
+                },
+                "spend_usdc": {
+                    "type": "integer"
+                },
+                "threats_detected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.APIKeyListItem": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key_prefix": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "score_exposure": {
+                    "description": "Set when the key overrides the account's score exposure",
+                    "type": "string"
+                },
+                "sub_account_id": {
+                    "description": "Set when the key belongs to a sub-account",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.APIKeyScoreExposure": {
+            "type": "object",
+            "properties": {
+                "effective": {
+                    "$ref": "#/definitions/db.ScoreExposure"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key_prefix": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "override": {
+                    "description": "null when the key follows the account",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AbuseResponse": {
+            "type": "object",
+            "properties": {
+                "callers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/abuse.Caller"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.AccountRegionRequest": {
+            "type": "object",
+            "properties": {
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AccountRegionResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CanaryEnrollmentRequest": {
+            "type": "object",
+            "properties": {
+                "percent": {
+                    "description": "0-100",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.CanaryResultsResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "agreement_rate": {
+                    "description": "0-1; 1 when there are no results",
+                    "type": "number"
+                },
+                "disagreements": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.CanaryResult"
+                    }
+                },
+                "since": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/db.CanarySummary"
+                }
+            }
+        },
+        "handlers.CanaryStatusResponse": {
+            "type": "object",
+            "properties": {
+                "canary_version": {
+                    "type": "string"
+                },
+                "default_percent": {
+                    "type": "number"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "enrollments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.CanaryEnrollment"
+                    }
+                },
+                "stable_version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ChaosFaultRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "\"delay\", \"error\" or \"drop\"",
+                    "type": "string"
+                },
+                "delay_ms": {
+                    "description": "Required for delay",
+                    "type": "integer"
+                },
+                "rate": {
+                    "description": "Share of calls affected, 0-1; 0 means all",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.ChaosResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "Whether this build can inject faults",
+                    "type": "boolean"
+                },
+                "faults": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/chaos.Fault"
+                    }
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "key_prefix": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateAccountRequest": {
+            "type": "object",
+            "properties": {
+                "private_key": {
+                    "description": "hex-encoded, used to import existing wallet",
+                    "type": "string"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateAccountResponse": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "evm_wallet_address": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "recovery_file": {
+                    "type": "string"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreatePrepaidCodesRequest": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "Conference trial"
+                },
+                "per_account_limit": {
+                    "description": "Codes from the batch one account may redeem; defaults to 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "value_usdc": {
+                    "type": "string",
+                    "example": "5.00"
+                }
+            }
+        },
+        "handlers.CreatePrepaidCodesResponse": {
+            "type": "object",
+            "properties": {
+                "batch": {
+                    "$ref": "#/definitions/db.PrepaidCodeBatch"
+                },
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.CreateSubAccountRequest": {
+            "type": "object",
+            "properties": {
+                "budget_usdc": {
+                    "description": "Omit for no cap beyond the account balance",
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "research-agent"
+                }
+            }
+        },
+        "handlers.DeleteEncryptionKeyResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "deleted_samples": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DetectionLatencyResponse": {
+            "type": "object",
+            "properties": {
+                "ml_warmup_ms": {
+                    "description": "0 when the ml layer isn't loaded",
+                    "type": "integer"
+                },
+                "stages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stronghold.StageLatency"
+                    }
+                }
+            }
+        },
+        "handlers.DetectionVersionResponse": {
+            "type": "object",
+            "properties": {
+                "block_threshold": {
+                    "type": "number"
+                },
+                "changelog": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stronghold.ChangelogEntry"
+                    }
+                },
+                "engine": {
+                    "type": "string"
+                },
+                "engine_version": {
+                    "type": "string"
+                },
+                "layers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rules_version": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "\"\u003crules\u003e+\u003cengine\u003e.\u003cconfig\u003e\", reported in every scan result",
+                    "type": "string"
+                },
+                "warn_threshold": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.DeviceApprovalRequest": {
+            "type": "object",
+            "properties": {
+                "device_hostname": {
+                    "type": "string"
+                },
+                "device_label": {
+                    "type": "string"
+                },
+                "device_os": {
+                    "type": "string"
+                },
+                "device_ttl_days": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeviceApprovalResponse": {
+            "type": "object",
+            "properties": {
+                "device_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "poll_interval_seconds": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeviceApprovalStatusResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DocumentVerdict": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "result": {
+                    "$ref": "#/definitions/stronghold.ScanResult"
+                }
+            }
+        },
+        "handlers.EncryptionKeyRequest": {
+            "type": "object",
+            "properties": {
+                "key_arn": {
+                    "description": "arn:aws:kms:\u003cregion\u003e:\u003caccount\u003e:key/\u003cid\u003e or alias/\u003cname\u003e",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EncryptionKeyResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "key_arn": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EstimateRequest": {
+            "type": "object",
+            "properties": {
+                "workloads": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.EstimateWorkload"
+                    }
+                }
+            }
+        },
+        "handlers.EstimateResponse": {
+            "type": "object",
+            "properties": {
+                "cost_micro_usdc": {
+                    "type": "integer"
+                },
+                "cost_usdc": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "current_monthly_requests": {
+                    "description": "Billed requests the caller's account made this month, counted toward tiers",
+                    "type": "integer"
+                },
+                "current_volume_tier": {
+                    "type": "integer"
+                },
+                "list_cost_micro_usdc": {
+                    "type": "integer"
+                },
+                "list_cost_usdc": {
+                    "type": "string"
+                },
+                "monthly_requests": {
+                    "type": "integer"
+                },
+                "volume_tier": {
+                    "description": "Tier reached by the end of the month; 0 is list price",
+                    "type": "integer"
+                },
+                "workloads": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.WorkloadEstimate"
+                    }
+                }
+            }
+        },
+        "handlers.EstimateWorkload": {
+            "type": "object",
+            "properties": {
+                "avg_document_bytes": {
+                    "description": "Documents over the per-request text limit take several requests",
+                    "type": "integer"
+                },
+                "documents": {
+                    "description": "Documents, messages or tool calls scanned per month",
+                    "type": "integer"
+                },
+                "documents_per_request": {
+                    "description": "Batch size for /v1/scan/documents and /v1/ingest (default and max 64)",
+                    "type": "integer"
+                },
+                "endpoint": {
+                    "description": "Priced path, e.g. \"/v1/scan/content\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.FeatureFlagRequest": {
+            "type": "object",
+            "properties": {
+                "account_ids": {
+                    "description": "Accounts the flag is always on for while enabled",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "message": {
+                    "description": "Shown to callers, e.g. during maintenance",
+                    "type": "string"
+                },
+                "percent": {
+                    "description": "Share of accounts the flag is on for, 0-100",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.GetBalancesResponse": {
+            "type": "object",
+            "properties": {
+                "evm": {
+                    "$ref": "#/definitions/handlers.WalletBalanceInfo"
+                },
+                "solana": {
+                    "$ref": "#/definitions/handlers.WalletBalanceInfo"
+                },
+                "total_usdc": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.GetWalletKeyResponse": {
+            "type": "object",
+            "properties": {
+                "private_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "services": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.HeartbeatRequest": {
+            "type": "object",
+            "properties": {
+                "protection_status": {
+                    "description": "\"protected\", \"shadow\" or \"degraded\"",
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.HeartbeatResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "update": {
+                    "$ref": "#/definitions/proxyversion.Update"
+                }
+            }
+        },
+        "handlers.IngestCheckResponse": {
+            "type": "object",
+            "properties": {
+                "decision": {
+                    "description": "Most severe decision across the documents",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/stronghold.Decision"
+                        }
+                    ]
+                },
+                "detection_version": {
+                    "type": "string"
+                },
+                "documents": {
+                    "description": "In request order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.IngestVerdict"
+                    }
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "worst_document": {
+                    "description": "ID of the highest-scoring document, unless all are allowed",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.IngestRecheckResponse": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "description": "Rescanned documents whose decision changed",
+                    "type": "integer"
+                },
+                "decision": {
+                    "description": "Most severe decision across the rescanned documents",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/stronghold.Decision"
+                        }
+                    ]
+                },
+                "detection_version": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RecheckVerdict"
+                    }
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "unknown": {
+                    "description": "Documents never checked, which weren't scanned",
+                    "type": "integer"
+                },
+                "worst_document": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.IngestVerdict": {
+            "type": "object",
+            "properties": {
+                "fingerprint": {
+                    "description": "Hex SHA-256 of the document text",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "result": {
+                    "$ref": "#/definitions/stronghold.ScanResult"
+                }
+            }
+        },
+        "handlers.InitiateDepositRequest": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "description": "Decimal string; JSON numbers are deprecated",
+                    "type": "string",
+                    "example": "10.50"
+                },
+                "network": {
+                    "description": "\"base\" (default) or \"solana\"",
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InitiateDepositResponse": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "type": "integer"
+                },
+                "checkout_url": {
+                    "type": "string"
+                },
+                "client_secret": {
+                    "type": "string"
+                },
+                "deposit_id": {
+                    "type": "string"
+                },
+                "instructions": {
+                    "type": "string"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "publishable_key": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "wallet_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InstallResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.JailbreakPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "\"warn\" or \"block\"",
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.JailbreakPolicyResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/db.JailbreakPolicy"
+                },
+                "effective": {
+                    "$ref": "#/definitions/db.JailbreakPolicy"
+                }
+            }
+        },
+        "handlers.ListMachinesResponse": {
+            "type": "object",
+            "properties": {
+                "dark": {
+                    "description": "Active installs whose protection went dark or never reported",
+                    "type": "integer"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "machines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MachineResponse"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "device_trusted": {
+                    "type": "boolean"
+                },
+                "evm_wallet_address": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "solana_wallet_address": {
+                    "type": "string"
+                },
+                "totp_required": {
+                    "type": "boolean"
+                },
+                "wallet_address": {
+                    "type": "string"
+                },
+                "wallet_escrow_enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.MachineResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "protection_status": {
+                    "description": "As last reported by the install",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "Reported status, or \"dark\", \"never_seen\" or \"revoked\"",
+                    "type": "string"
+                },
+                "usage": {
+                    "description": "Scans the install signed in the window",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.UsageAggregate"
+                        }
+                    ]
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PaymentRetentionResponse": {
+            "type": "object",
+            "properties": {
+                "archive": {
+                    "type": "boolean"
+                },
+                "last_run": {
+                    "$ref": "#/definitions/settlement.RetentionRun"
+                },
+                "retention_days": {
+                    "description": "0 when payments are kept forever",
+                    "type": "integer"
+                },
+                "tables": {
+                    "$ref": "#/definitions/db.PaymentTableStats"
+                }
+            }
+        },
+        "handlers.PricingResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "networks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "routes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RoutePrice"
+                    }
+                }
+            }
+        },
+        "handlers.PromptFingerprintResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "shingles": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PromptFingerprintsResponse": {
+            "type": "object",
+            "properties": {
+                "prompts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PromptFingerprintResponse"
+                    }
+                }
+            }
+        },
+        "handlers.PutConfigSyncRequest": {
+            "type": "object",
+            "properties": {
+                "base_version": {
+                    "description": "Version the change was made on; 0 for the first upload",
+                    "type": "integer"
+                },
+                "data": {
+                    "description": "Base64 ciphertext",
+                    "type": "string"
+                },
+                "hostname": {
+                    "description": "Machine uploading it",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RateLimitStatsResponse": {
+            "type": "object",
+            "properties": {
+                "limiters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ratelimit.Stats"
+                    }
+                }
+            }
+        },
+        "handlers.RecheckVerdict": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "description": "The decision differs from the previous one",
+                    "type": "boolean"
+                },
+                "fingerprint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "previous_decision": {
+                    "$ref": "#/definitions/stronghold.Decision"
+                },
+                "previous_detection_version": {
+                    "type": "string"
+                },
+                "result": {
+                    "$ref": "#/definitions/stronghold.ScanResult"
+                },
+                "status": {
+                    "description": "\"rescanned\" or \"unknown\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RedeemCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "ABCD-EFGH-IJKL-MNOP"
+                }
+            }
+        },
+        "handlers.RedeemCodeResponse": {
+            "type": "object",
+            "properties": {
+                "amount_usdc": {
+                    "type": "integer"
+                },
+                "balance_usdc": {
+                    "type": "integer"
+                },
+                "deposit": {
+                    "$ref": "#/definitions/db.Deposit"
+                }
+            }
+        },
+        "handlers.RefreshTokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RegisterInstallRequest": {
+            "type": "object",
+            "properties": {
+                "hostname": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RegisterPromptRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "shingles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.ReplicationUsageResponse": {
+            "type": "object",
+            "properties": {
+                "next_after_id": {
+                    "type": "string"
+                },
+                "next_since": {
+                    "type": "string"
+                },
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ReplicatedUsage"
+                    }
+                },
+                "region": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoutePrice": {
+            "type": "object",
+            "properties": {
+                "accepts": {
+                    "description": "Accepts lists every way to pay for the route, with the chain parameters\nneeded to build the payment",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/middleware.NetworkPrice"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "price_micro_usdc": {
+                    "type": "integer"
+                },
+                "price_usd": {
+                    "description": "Deprecated: float form of price_usdc",
+                    "type": "number"
+                },
+                "price_usdc": {
+                    "description": "Decimal string, e.g. \"0.001\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RulePacksResponse": {
+            "type": "object",
+            "properties": {
+                "rule_packs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.RulePack"
+                    }
+                }
+            }
+        },
+        "handlers.SSOLookupResponse": {
+            "type": "object",
+            "properties": {
+                "organization_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanBackendRequest": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "description": "internal, ensemble, custom or a SCAN_BACKENDS name; empty for the default",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Bearer token for the account's own endpoint",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Base URL of the account's own endpoint, for custom",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanBackendResponse": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "backend": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanContentRequest": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "\"html\", \"markdown\", \"json\", \"text\", \"code\"",
+                    "type": "string"
+                },
+                "file_path": {
+                    "description": "For file reads, e.g., \"README.md\"",
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "\"smart\" (default), \"strict\" or \"permissive\"; selects the account's scoring profile",
+                    "type": "string"
+                },
+                "source_type": {
+                    "description": "\"web_page\", \"file\", \"api_response\", \"code_repo\"",
+                    "type": "string"
+                },
+                "source_url": {
+                    "description": "Where content came from (e.g., https://github.com/...)",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanDocument": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "\"html\", \"markdown\", \"json\", \"text\", \"code\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Caller's name for the document; defaults to its index",
+                    "type": "string"
+                },
+                "source_type": {
+                    "description": "\"web_page\", \"file\", \"api_response\", \"code_repo\"",
+                    "type": "string"
+                },
+                "source_url": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanDocumentsRequest": {
+            "type": "object",
+            "properties": {
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ScanDocument"
+                    }
+                },
+                "mode": {
+                    "description": "\"smart\" (default), \"strict\" or \"permissive\"; selects the account's scoring profile",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanDocumentsResponse": {
+            "type": "object",
+            "properties": {
+                "decision": {
+                    "description": "Most severe decision across the documents",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/stronghold.Decision"
+                        }
+                    ]
+                },
+                "detection_version": {
+                    "type": "string"
+                },
+                "documents": {
+                    "description": "In request order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DocumentVerdict"
+                    }
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "worst_document": {
+                    "description": "ID of the highest-scoring document, unless all are allowed",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanKeyResponse": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "key_id": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "X25519, base64 in JSON",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "handlers.ScanOutputRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanSessionMessageRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "\"smart\" (default), \"strict\" or \"permissive\"; selects the account's scoring profile",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "\"user\" (default), \"assistant\" or \"tool\"",
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScanSessionResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "Idle time before the session expires; every message restarts it",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ScanToolCallRequest": {
+            "type": "object",
+            "properties": {
+                "arguments": {
+                    "description": "JSON arguments, or a string holding them",
+                    "type": "object"
+                },
+                "tool": {
+                    "description": "Tool name, e.g. \"bash\", \"read_file\", \"web_fetch\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScoreExposureRequest": {
+            "type": "object",
+            "properties": {
+                "exposure": {
+                    "description": "\"exact\", \"quantized\" or \"omitted\"",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ScoreExposureResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/db.ScoreExposure"
+                },
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIKeyScoreExposure"
+                    }
+                }
+            }
+        },
+        "handlers.ScoringProfileResponse": {
+            "type": "object",
+            "properties": {
+                "curve": {
+                    "description": "Ascending; the scanner's thresholds when empty",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stronghold.CurvePoint"
+                    }
+                },
+                "mode": {
+                    "description": "default, smart, strict or permissive",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "weights": {
+                    "description": "heuristic, semantic, ml, llm",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                }
+            }
+        },
+        "handlers.ScoringProfilesResponse": {
+            "type": "object",
+            "properties": {
+                "layers": {
+                    "description": "Layers that can be weighted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "profiles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ScoringProfileResponse"
+                    }
+                }
+            }
+        },
+        "handlers.SetSubAccountBudgetRequest": {
+            "type": "object",
+            "properties": {
+                "budget_usdc": {
+                    "description": "null removes the cap",
+                    "type": "string",
+                    "example": "100.00"
+                }
+            }
+        },
+        "handlers.SettlementWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "description": "must be https",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SettlementWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "signing secret, returned only by PUT",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SigningKeysResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/settlement.WebhookSigningKey"
+                    }
+                },
+                "tolerance_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SubAccountResponse": {
             "type": "object",
             "properties": {
-                "private_key": {
-                    "description": "hex-encoded, used to import existing wallet",
+                "account_id": {
                     "type": "string"
                 },
-                "wallet_address": {
+                "budget_usdc": {
+                    "description": "nil means no cap beyond the parent's balance",
+                    "type": "integer"
+                },
+                "closed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "remaining_usdc": {
+                    "type": "integer"
+                },
+                "spent_usdc": {
+                    "type": "integer"
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
         },
-        "handlers.CreateAccountResponse": {
+        "handlers.TransferRequest": {
             "type": "object",
             "properties": {
-                "account_number": {
+                "amount_usdc": {
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "code": {
                     "type": "string"
                 },
-                "expires_at": {
+                "memo": {
                     "type": "string"
                 },
-                "recovery_file": {
+                "recovery_code": {
                     "type": "string"
                 },
-                "wallet_address": {
+                "to_account_number": {
                     "type": "string"
                 }
             }
         },
-        "handlers.HealthResponse": {
+        "handlers.TransferResponse": {
             "type": "object",
             "properties": {
-                "services": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
+                "daily_limit_usdc": {
+                    "type": "integer"
                 },
-                "status": {
-                    "type": "string"
+                "entry": {
+                    "$ref": "#/definitions/db.LedgerEntry"
                 },
-                "timestamp": {
+                "remaining_today_usdc": {
                     "type": "integer"
+                }
+            }
+        },
+        "handlers.UpdateRulePackRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 },
-                "version": {
+                "public": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.UpdateWalletRequest": {
+            "type": "object",
+            "properties": {
+                "private_key": {
+                    "description": "hex-encoded",
                     "type": "string"
                 }
             }
         },
-        "handlers.InitiateDepositRequest": {
+        "handlers.UpdateWalletResponse": {
             "type": "object",
             "properties": {
-                "amount_usdc": {
-                    "type": "number"
+                "evm_wallet_address": {
+                    "type": "string"
                 },
-                "provider": {
+                "wallet_address": {
                     "type": "string"
                 }
             }
         },
-        "handlers.InitiateDepositResponse": {
+        "handlers.UpdateWalletsRequest": {
             "type": "object",
             "properties": {
-                "amount_usdc": {
-                    "type": "number"
-                },
-                "checkout_url": {
+                "evm_address": {
                     "type": "string"
                 },
-                "client_secret": {
+                "solana_address": {
                     "type": "string"
-                },
-                "deposit_id": {
+                }
+            }
+        },
+        "handlers.UpdateWalletsResponse": {
+            "type": "object",
+            "properties": {
+                "evm_wallet_address": {
                     "type": "string"
                 },
-                "instructions": {
+                "solana_wallet_address": {
                     "type": "string"
-                },
-                "provider": {
+                }
+            }
+        },
+        "handlers.WalletBalanceInfo": {
+            "type": "object",
+            "properties": {
+                "address": {
                     "type": "string"
                 },
-                "publishable_key": {
+                "balance_usdc": {
+                    "type": "integer"
+                },
+                "error": {
                     "type": "string"
                 },
-                "status": {
+                "network": {
                     "type": "string"
                 },
-                "wallet_address": {
+                "stale": {
+                    "description": "Served from cache while a refresh runs",
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "description": "When the balance was read on-chain",
                     "type": "string"
                 }
             }
         },
-        "handlers.LinkWalletRequest": {
+        "handlers.WorkloadEstimate": {
             "type": "object",
             "properties": {
-                "wallet_address": {
+                "cost_micro_usdc": {
+                    "type": "integer"
+                },
+                "cost_usdc": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "integer"
+                },
+                "endpoint": {
                     "type": "string"
+                },
+                "list_cost_micro_usdc": {
+                    "type": "integer"
+                },
+                "price_micro_usdc": {
+                    "description": "List price per request",
+                    "type": "integer"
+                },
+                "requests": {
+                    "description": "Billed requests the documents take",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "middleware.NetworkPrice": {
             "type": "object",
             "properties": {
-                "account_number": {
+                "amount": {
+                    "description": "Price in atomic token units",
+                    "type": "string"
+                },
+                "caip2": {
+                    "type": "string"
+                },
+                "chain_id": {
+                    "description": "EVM networks only",
+                    "type": "integer"
+                },
+                "facilitator_url": {
+                    "type": "string"
+                },
+                "fee_payer": {
+                    "description": "Solana only",
+                    "type": "string"
+                },
+                "network": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "scheme": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Token contract (EVM) or mint (Solana) address",
+                    "type": "string"
+                },
+                "token_decimals": {
+                    "description": "Atomic units per token: 10^decimals",
+                    "type": "integer"
+                },
+                "token_symbol": {
+                    "description": "Always \"USDC\"",
                     "type": "string"
                 }
             }
         },
-        "handlers.LoginResponse": {
+        "proxyversion.Update": {
             "type": "object",
             "properties": {
-                "account_number": {
+                "enforced": {
+                    "description": "Requests from this version are refused",
+                    "type": "boolean"
+                },
+                "minimum_version": {
                     "type": "string"
                 },
-                "expires_at": {
+                "reason": {
                     "type": "string"
                 },
-                "private_key": {
-                    "description": "Only returned over TLS when KMS-encrypted key exists",
+                "recommended_version": {
                     "type": "string"
                 },
-                "wallet_address": {
+                "status": {
+                    "type": "string"
+                },
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "handlers.PricingResponse": {
+        "ratelimit.Stats": {
             "type": "object",
             "properties": {
-                "currency": {
-                    "type": "string"
+                "allowed": {
+                    "type": "integer"
                 },
-                "network": {
+                "limited": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 },
-                "routes": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/handlers.RoutePrice"
-                    }
+                "store_errors": {
+                    "description": "Requests let through because the store failed",
+                    "type": "integer"
+                },
+                "strategy": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.RefreshTokenResponse": {
+        "settlement.RetentionRun": {
             "type": "object",
             "properties": {
-                "expires_at": {
+                "archived": {
+                    "type": "boolean"
+                },
+                "cutoff": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "pruned": {
+                    "type": "integer"
+                },
+                "started_at": {
                     "type": "string"
                 }
             }
         },
-        "handlers.RoutePrice": {
+        "settlement.TestDelivery": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "string"
+                "delivered": {
+                    "type": "boolean"
                 },
-                "method": {
+                "delivery_id": {
                     "type": "string"
                 },
-                "path": {
+                "error": {
                     "type": "string"
                 },
-                "price_usd": {
-                    "type": "number"
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "status_code": {
+                    "description": "omitted when the receiver couldn't be reached",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.ScanContentRequest": {
+        "settlement.WebhookSigningKey": {
             "type": "object",
             "properties": {
-                "content_type": {
-                    "description": "\"html\", \"markdown\", \"json\", \"text\", \"code\"",
+                "crv": {
+                    "description": "always \"Ed25519\"",
                     "type": "string"
                 },
-                "file_path": {
-                    "description": "For file reads, e.g., \"README.md\"",
+                "kid": {
                     "type": "string"
                 },
-                "source_type": {
-                    "description": "\"web_page\", \"file\", \"api_response\", \"code_repo\"",
+                "kty": {
+                    "description": "always \"OKP\"",
                     "type": "string"
                 },
-                "source_url": {
-                    "description": "Where content came from (e.g., https://github.com/...)",
+                "status": {
                     "type": "string"
                 },
-                "text": {
+                "use": {
+                    "description": "always \"sig\"",
                     "type": "string"
-                }
-            }
-        },
-        "handlers.ScanOutputRequest": {
-            "type": "object",
-            "properties": {
-                "text": {
+                },
+                "x": {
+                    "description": "base64url public key",
                     "type": "string"
                 }
             }
         },
-        "handlers.UpdateWalletRequest": {
+        "stronghold.ChangelogEntry": {
             "type": "object",
             "properties": {
-                "private_key": {
-                    "description": "hex-encoded",
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "date": {
+                    "type": "string"
+                },
+                "version": {
                     "type": "string"
                 }
             }
         },
-        "handlers.UpdateWalletResponse": {
+        "stronghold.CurvePoint": {
             "type": "object",
             "properties": {
-                "wallet_address": {
-                    "type": "string"
+                "decision": {
+                    "description": "WARN or BLOCK",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/stronghold.Decision"
+                        }
+                    ]
+                },
+                "min_score": {
+                    "type": "number"
                 }
             }
         },
@@ -1105,7 +8283,11 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "decision": {
-                    "$ref": "#/definitions/stronghold.Decision"
+                    "$ref": "#/definitions/types.Decision"
+                },
+                "detection_version": {
+                    "description": "Detection configuration that produced the verdict",
+                    "type": "string"
                 },
                 "latency_ms": {
                     "type": "integer"
@@ -1128,6 +8310,10 @@ const docTemplate = `{
                     "description": "Clean version with threats removed",
                     "type": "string"
                 },
+                "schema_version": {
+                    "description": "Set to SchemaVersion when marshaled, if empty",
+                    "type": "string"
+                },
                 "scores": {
                     "type": "object",
                     "additionalProperties": {
@@ -1139,12 +8325,65 @@ const docTemplate = `{
                     "description": "Detailed threat info",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/stronghold.Threat"
+                        "$ref": "#/definitions/types.Threat"
+                    }
+                }
+            }
+        },
+        "stronghold.ScoringProfile": {
+            "type": "object",
+            "properties": {
+                "curve": {
+                    "description": "Ascending; the scanner's thresholds when empty",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stronghold.CurvePoint"
+                    }
+                },
+                "weights": {
+                    "description": "heuristic, semantic, ml, llm",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
                     }
                 }
             }
         },
-        "stronghold.Threat": {
+        "stronghold.StageLatency": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "max_ms": {
+                    "type": "number"
+                },
+                "mean_ms": {
+                    "type": "number"
+                },
+                "stage": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.Decision": {
+            "type": "string",
+            "enum": [
+                "ALLOW",
+                "WARN",
+                "BLOCK"
+            ],
+            "x-enum-varnames": [
+                "DecisionAllow",
+                "DecisionWarn",
+                "DecisionBlock"
+            ]
+        },
+        "types.Threat": {
             "type": "object",
             "properties": {
                 "category": {
@@ -1171,6 +8410,12 @@ const docTemplate = `{
         }
     },
     "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Operator token from ADMIN_API_TOKEN, sent as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
         "CookieAuth": {
             "type": "apiKey",
             "name": "stronghold_access",
@@ -1197,6 +8442,10 @@ const docTemplate = `{
         {
             "description": "AI security scanning endpoints (payment required)",
             "name": "scan"
+        },
+        {
+            "description": "Operator endpoints (ADMIN_API_TOKEN required)",
+            "name": "admin"
         }
     ]
 }`
@@ -0,0 +1,376 @@
+// Package abuse scores scan callers for signs that they are probing the
+// detection engine rather than protecting an agent: resubmitting mutated
+// copies of blocked payloads, hunting for the edit that flips a decision,
+// or scanning little but attack payloads. Each signal adds to a caller's
+// score, which decays over time; as it rises the caller's responses are
+// jittered, then throttled, then the account is suspended. Scores are kept
+// in memory, per API instance.
+package abuse
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// recentScans is how many scans per caller new ones are compared with
+	recentScans = 20
+	// maxCallers bounds the callers tracked by one instance
+	maxCallers = 20000
+	// sweepInterval is how often idle callers are dropped
+	sweepInterval = time.Minute
+	// idleExpiry is how long a caller is remembered after its last scan
+	idleExpiry = time.Hour
+	// nearDuplicate is the estimated similarity from which two scans count
+	// as variants of one payload
+	nearDuplicate = 0.6
+	// flagRateMin is how many recent scans a caller needs before its share
+	// of flagged scans counts
+	flagRateMin = 10
+)
+
+// Signals of probing
+const (
+	SignalMutation = "mutation"  // A variant of a recently flagged scan
+	SignalBoundary = "boundary"  // A variant that got a different decision than the original
+	SignalFlagRate = "flag_rate" // A flagged scan while most recent scans were flagged
+)
+
+// signalWeights is the score each signal adds. A boundary hit is the
+// clearest sign of threshold fingerprinting.
+var signalWeights = map[string]float64{
+	SignalMutation: 1,
+	SignalBoundary: 5,
+	SignalFlagRate: 0.5,
+}
+
+// Level is the response to a caller's score
+type Level int
+
+// Levels, in increasing severity
+const (
+	LevelNone Level = iota
+	LevelJitter
+	LevelThrottle
+	LevelSuspend
+)
+
+// String returns the level's name
+func (l Level) String() string {
+	switch l {
+	case LevelJitter:
+		return "jitter"
+	case LevelThrottle:
+		return "throttle"
+	case LevelSuspend:
+		return "suspend"
+	default:
+		return "none"
+	}
+}
+
+// Action is what to do with a caller's next scan
+type Action struct {
+	Level      Level
+	Delay      time.Duration // Wait before scanning, so timing reveals less
+	Refuse     bool          // Over the throttle limit, or suspended
+	RetryAfter time.Duration // When a throttled caller may scan again
+}
+
+// Caller is a caller's standing, as reported to operators
+type Caller struct {
+	Key       string         `json:"key"`
+	Score     float64        `json:"score"`
+	Level     string         `json:"level"`
+	Signals   map[string]int `json:"signals"`
+	Scans     int64          `json:"scans"`
+	Refused   int64          `json:"refused"`
+	Suspended bool           `json:"suspended"` // Account suspended by the detector
+	LastSeen  time.Time      `json:"last_seen"`
+}
+
+// observed is a remembered scan
+type observed struct {
+	sig      signature
+	decision string
+	flagged  bool
+}
+
+type caller struct {
+	score       float64
+	scoredAt    time.Time
+	recent      []observed
+	next        int
+	signals     map[string]int
+	scans       int64
+	refused     int64
+	windowStart time.Time
+	windowCount int
+	suspended   bool
+	lastSeen    time.Time
+}
+
+// Detector scores scan callers and picks the response to each
+type Detector struct {
+	cfg     config.AbuseConfig
+	suspend func(ctx context.Context, accountID uuid.UUID) error
+	now     func() time.Time
+	jitter  func(max time.Duration) time.Duration
+
+	mu        sync.Mutex
+	callers   map[string]*caller
+	lastSweep time.Time
+}
+
+// New creates a detector, or returns nil when detection is disabled.
+// suspend is called once when an account reaches the suspend score.
+func New(cfg *config.AbuseConfig, suspend func(ctx context.Context, accountID uuid.UUID) error) *Detector {
+	if !cfg.Enabled || cfg.HalfLife <= 0 {
+		return nil
+	}
+	return &Detector{
+		cfg:     *cfg,
+		suspend: suspend,
+		now:     time.Now,
+		jitter:  func(max time.Duration) time.Duration { return rand.N(max) },
+		callers: make(map[string]*caller),
+	}
+}
+
+// AccountKey identifies an account's scans
+func AccountKey(id uuid.UUID) string {
+	return "account:" + id.String()
+}
+
+// AddressKey identifies the scans of an anonymous caller by address
+func AddressKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Admit returns the action for key's next scan and counts it against the
+// throttle limit
+func (d *Detector) Admit(key string) Action {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c, ok := d.callers[key]
+	if !ok {
+		return Action{}
+	}
+	d.decay(c, now)
+
+	a := Action{Level: d.level(c.score)}
+	switch a.Level {
+	case LevelSuspend:
+		a.Refuse = true
+	case LevelThrottle:
+		if now.Sub(c.windowStart) >= time.Minute {
+			c.windowStart, c.windowCount = now, 0
+		}
+		c.windowCount++
+		if c.windowCount > d.cfg.ThrottlePerMinute {
+			a.Refuse = true
+			a.RetryAfter = c.windowStart.Add(time.Minute).Sub(now)
+		}
+	}
+	if a.Refuse {
+		c.refused++
+		return a
+	}
+	if a.Level >= LevelJitter && d.cfg.JitterMax > 0 {
+		a.Delay = d.jitter(d.cfg.JitterMax)
+	}
+	return a
+}
+
+// Observe scores a completed scan of text by key. account is the caller's
+// account, or nil for anonymous callers, which are refused rather than
+// suspended.
+func (d *Detector) Observe(ctx context.Context, key string, account *uuid.UUID, text []byte, decision string) {
+	now := d.now()
+	sig := sign(text)
+	flagged := decision != "" && decision != "ALLOW"
+
+	d.mu.Lock()
+	c, ok := d.callers[key]
+	if !ok {
+		d.sweep(now, false)
+		if len(d.callers) >= maxCallers {
+			d.sweep(now, true)
+			if len(d.callers) >= maxCallers {
+				d.mu.Unlock()
+				return
+			}
+		}
+		c = &caller{signals: make(map[string]int)}
+		d.callers[key] = c
+	}
+	d.decay(c, now)
+	before := d.level(c.score)
+
+	var mutation, boundary, repeat bool
+	flaggedRecent := 0
+	for _, o := range c.recent {
+		if o.flagged {
+			flaggedRecent++
+		}
+		// An identical sketch is a retry of the same text, not a variant
+		if o.sig == sig {
+			repeat = true
+			continue
+		}
+		if o.sig.similarity(sig) < nearDuplicate {
+			continue
+		}
+		mutation = mutation || o.flagged
+		boundary = boundary || o.decision != decision
+	}
+	var signals []string
+	if mutation {
+		signals = append(signals, SignalMutation)
+	}
+	if boundary {
+		signals = append(signals, SignalBoundary)
+	}
+	if flagged && !repeat && len(c.recent) >= flagRateMin && flaggedRecent*2 >= len(c.recent) {
+		signals = append(signals, SignalFlagRate)
+	}
+	for _, s := range signals {
+		c.score += signalWeights[s]
+		c.signals[s]++
+	}
+
+	scan := observed{sig: sig, decision: decision, flagged: flagged}
+	if len(c.recent) < recentScans {
+		c.recent = append(c.recent, scan)
+	} else {
+		c.recent[c.next] = scan
+		c.next = (c.next + 1) % recentScans
+	}
+	c.scans++
+	c.lastSeen = now
+
+	after, score := d.level(c.score), c.score
+	suspend := after == LevelSuspend && account != nil && !c.suspended && d.suspend != nil
+	if suspend {
+		c.suspended = true
+	}
+	d.mu.Unlock()
+
+	if after > before {
+		slog.Warn("abusive scan pattern", "caller", key, "level", after.String(), "score", math.Round(score*100)/100, "signals", signals)
+	}
+	if !suspend {
+		return
+	}
+	if err := d.suspend(ctx, *account); err != nil {
+		slog.Error("failed to suspend abusive account", "account_id", account.String(), "error", err)
+		d.mu.Lock()
+		c.suspended = false
+		d.mu.Unlock()
+		return
+	}
+	slog.Warn("account suspended for abusive scanning", "account_id", account.String(), "score", math.Round(score*100)/100)
+}
+
+// Callers returns the callers with a score or a suspension, highest score
+// first
+func (d *Detector) Callers() []Caller {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := []Caller{}
+	for key, c := range d.callers {
+		d.decay(c, now)
+		if c.score < 1 && !c.suspended {
+			continue
+		}
+		out = append(out, d.snapshot(key, c))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// Reset forgets key's score and history, returning its standing before
+func (d *Detector) Reset(key string) (Caller, bool) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c, ok := d.callers[key]
+	if !ok {
+		return Caller{}, false
+	}
+	d.decay(c, now)
+	delete(d.callers, key)
+	return d.snapshot(key, c), true
+}
+
+// snapshot reports c. Callers hold d.mu.
+func (d *Detector) snapshot(key string, c *caller) Caller {
+	signals := make(map[string]int, len(c.signals))
+	for s, n := range c.signals {
+		signals[s] = n
+	}
+	return Caller{
+		Key:       key,
+		Score:     math.Round(c.score*100) / 100,
+		Level:     d.level(c.score).String(),
+		Signals:   signals,
+		Scans:     c.scans,
+		Refused:   c.refused,
+		Suspended: c.suspended,
+		LastSeen:  c.lastSeen,
+	}
+}
+
+// level maps a score to the response for it. Steps with a zero score are off.
+func (d *Detector) level(score float64) Level {
+	switch {
+	case d.cfg.SuspendScore > 0 && score >= d.cfg.SuspendScore:
+		return LevelSuspend
+	case d.cfg.ThrottleScore > 0 && score >= d.cfg.ThrottleScore:
+		return LevelThrottle
+	case d.cfg.JitterScore > 0 && score >= d.cfg.JitterScore:
+		return LevelJitter
+	}
+	return LevelNone
+}
+
+// decay brings c's score forward to now. Callers hold d.mu.
+func (d *Detector) decay(c *caller, now time.Time) {
+	if !c.scoredAt.IsZero() && now.After(c.scoredAt) {
+		c.score *= math.Exp2(-now.Sub(c.scoredAt).Seconds() / d.cfg.HalfLife.Seconds())
+	}
+	c.scoredAt = now
+}
+
+// sweep drops callers idle for idleExpiry, at most once per sweepInterval.
+// Forced, it also drops every caller whose score is below 1. Suspended
+// accounts are kept for operators. Callers hold d.mu.
+func (d *Detector) sweep(now time.Time, force bool) {
+	if !force && now.Sub(d.lastSweep) < sweepInterval {
+		return
+	}
+	d.lastSweep = now
+	for key, c := range d.callers {
+		if c.suspended {
+			continue
+		}
+		d.decay(c, now)
+		if now.Sub(c.lastSeen) >= idleExpiry || (force && c.score < 1) {
+			delete(d.callers, key)
+		}
+	}
+}
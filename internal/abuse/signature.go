@@ -0,0 +1,66 @@
+package abuse
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// signatureSize is the number of MinHash values kept per scan
+const signatureSize = 16
+
+// signature is a MinHash sketch of a scan's words and word pairs. Two
+// sketches agree in about as many positions as the texts share features, so
+// near-duplicates are found without keeping the text.
+type signature [signatureSize]uint32
+
+// sign sketches text. Empty texts get an all-ones sketch, which only
+// matches other empty texts.
+func sign(text []byte) signature {
+	var sig signature
+	for i := range sig {
+		sig[i] = ^uint32(0)
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(string(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		base := h.Sum64()
+		for i := range sig {
+			if v := uint32(mix(base + uint64(i)*0x9e3779b97f4a7c15)); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	for i, w := range words {
+		add(w)
+		if i > 0 {
+			add(words[i-1] + " " + w)
+		}
+	}
+	return sig
+}
+
+// similarity estimates the Jaccard similarity of the texts behind two sketches
+func (s signature) similarity(o signature) float64 {
+	same := 0
+	for i := range s {
+		if s[i] == o[i] {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
+
+// mix is the splitmix64 finalizer, deriving independent hashes from one
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,102 @@
+// Package backends routes scans to detection backends: the built-in engine,
+// external detection services, and customers' own endpoints, alone or as a
+// weighted ensemble.
+package backends
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+)
+
+// Backend is a detection engine scans can be routed to. *stronghold.Scanner
+// implements it.
+type Backend interface {
+	ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*stronghold.ScanResult, error)
+	ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error)
+}
+
+// AccountStore looks up accounts' backend selections
+type AccountStore interface {
+	GetAccountScanBackend(ctx context.Context, accountID uuid.UUID) (*db.ScanBackend, error)
+}
+
+// Router picks the backend for each scan
+type Router struct {
+	backends    map[string]Backend
+	defaultName string
+	store       AccountStore
+	client      *http.Client
+}
+
+// NewRouter creates a router over the built-in engine and the configured
+// external backends. The ensemble, when configured, decides with the
+// scanner's block and warn thresholds.
+func NewRouter(cfg *config.ScanBackendsConfig, internal Backend, thresholds *config.StrongholdConfig, store AccountStore) *Router {
+	r := &Router{
+		backends:    map[string]Backend{config.BackendInternal: internal},
+		defaultName: cfg.Default,
+		store:       store,
+		client:      &http.Client{Timeout: cfg.Timeout},
+	}
+	if r.defaultName == "" {
+		r.defaultName = config.BackendInternal
+	}
+	for name, url := range cfg.Endpoints {
+		r.backends[name] = NewHTTPBackend(name, url, cfg.Tokens[name], r.client)
+	}
+	if len(cfg.Ensemble) > 0 {
+		members := make([]Member, 0, len(cfg.Ensemble))
+		for name, weight := range cfg.Ensemble {
+			if b, ok := r.backends[name]; ok {
+				members = append(members, Member{Name: name, Backend: b, Weight: weight})
+			}
+		}
+		r.backends[config.BackendEnsemble] = NewEnsemble(members, thresholds.BlockThreshold, thresholds.WarnThreshold)
+	}
+	return r
+}
+
+// Has reports whether name is a backend accounts can be routed to. The
+// custom backend is always available.
+func (r *Router) Has(name string) bool {
+	_, ok := r.backends[name]
+	return ok || name == config.BackendCustom
+}
+
+// Default returns the name of the default backend
+func (r *Router) Default() string {
+	return r.defaultName
+}
+
+// For returns the backend for an account's scans and its name. x402 scans
+// (no account) and accounts without a selection use the default backend, as
+// do accounts whose selection is no longer configured.
+func (r *Router) For(ctx context.Context, accountID *uuid.UUID) (Backend, string) {
+	if accountID == nil || r.store == nil {
+		return r.backends[r.defaultName], r.defaultName
+	}
+
+	selection, err := r.store.GetAccountScanBackend(ctx, *accountID)
+	if err != nil {
+		slog.Warn("failed to get account scan backend, using default", "account_id", accountID.String(), "error", err)
+		return r.backends[r.defaultName], r.defaultName
+	}
+	switch {
+	case selection.Name == "":
+	case selection.Name == config.BackendCustom && selection.URL != "":
+		return NewHTTPBackend(config.BackendCustom, selection.URL, selection.Token, r.client), config.BackendCustom
+	default:
+		if b, ok := r.backends[selection.Name]; ok {
+			return b, selection.Name
+		}
+		slog.Warn("account scan backend not configured, using default", "account_id", accountID.String(), "backend", selection.Name)
+	}
+	return r.backends[r.defaultName], r.defaultName
+}
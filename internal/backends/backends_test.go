@@ -0,0 +1,156 @@
+package backends
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedBackend returns the same verdict for every scan
+type fixedBackend struct {
+	result *stronghold.ScanResult
+	err    error
+}
+
+func (f fixedBackend) ScanContent(context.Context, string, string, string, string) (*stronghold.ScanResult, error) {
+	return f.result, f.err
+}
+
+func (f fixedBackend) ScanOutput(context.Context, string) (*stronghold.ScanResult, error) {
+	return f.result, f.err
+}
+
+func verdict(decision stronghold.Decision, score float64) fixedBackend {
+	return fixedBackend{result: &stronghold.ScanResult{
+		Decision:         decision,
+		Scores:           map[string]float64{"combined": score},
+		Reason:           string(decision) + " reason",
+		DetectionVersion: "v1",
+	}}
+}
+
+type fakeStore map[uuid.UUID]*db.ScanBackend
+
+func (f fakeStore) GetAccountScanBackend(_ context.Context, id uuid.UUID) (*db.ScanBackend, error) {
+	if b, ok := f[id]; ok {
+		return b, nil
+	}
+	return nil, db.ErrAccountNotFound
+}
+
+func TestHTTPBackend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch r.URL.Path {
+		case "/scan/content":
+			assert.Equal(t, "web_page", req["source_type"])
+			w.Write([]byte(`{"decision":"BLOCK","scores":{"ml":0.97},"reason":"injection"}`))
+		case "/scan/output":
+			w.Write([]byte(`{"decision":"MAYBE"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewHTTPBackend("ml", srv.URL, "tok", srv.Client())
+	result, err := b.ScanContent(context.Background(), "text", "", "web_page", "")
+	require.NoError(t, err)
+	assert.Equal(t, stronghold.DecisionBlock, result.Decision)
+	assert.Equal(t, 0.97, result.Scores["ml"])
+	assert.Equal(t, "ml", result.DetectionVersion)
+
+	_, err = b.ScanOutput(context.Background(), "text")
+	assert.ErrorContains(t, err, "unknown decision")
+
+	_, err = NewHTTPBackend("ml", srv.URL+"/missing", "tok", srv.Client()).ScanOutput(context.Background(), "text")
+	assert.ErrorContains(t, err, "status 404")
+}
+
+func TestEnsemble_WeightedScore(t *testing.T) {
+	e := NewEnsemble([]Member{
+		{Name: "internal", Backend: verdict(stronghold.DecisionAllow, 0.2), Weight: 3},
+		{Name: "ml", Backend: verdict(stronghold.DecisionBlock, 1.0), Weight: 1},
+	}, 0.55, 0.35)
+
+	result, err := e.ScanContent(context.Background(), "text", "", "", "")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.4, result.Scores["combined"], 1e-9) // (0.2*3 + 1.0*1) / 4
+	assert.Equal(t, stronghold.DecisionWarn, result.Decision)
+	assert.Equal(t, "BLOCK reason", result.Reason, "reason comes from the highest-scoring member")
+	assert.Equal(t, "ensemble:internal@v1+ml@v1", result.DetectionVersion)
+	assert.Equal(t, map[string]string{"internal": "ALLOW", "ml": "BLOCK"}, result.Metadata["backend_decisions"])
+}
+
+func TestEnsemble_SkipsFailedMembers(t *testing.T) {
+	e := NewEnsemble([]Member{
+		{Name: "internal", Backend: verdict(stronghold.DecisionBlock, 0.9), Weight: 1},
+		{Name: "ml", Backend: fixedBackend{err: errors.New("timeout")}, Weight: 1},
+	}, 0.55, 0.35)
+
+	result, err := e.ScanOutput(context.Background(), "text")
+	require.NoError(t, err)
+	assert.Equal(t, stronghold.DecisionBlock, result.Decision)
+	assert.Equal(t, []string{"ml"}, result.Metadata["failed_backends"])
+
+	e = NewEnsemble([]Member{{Name: "ml", Backend: fixedBackend{err: errors.New("timeout")}, Weight: 1}}, 0.55, 0.35)
+	_, err = e.ScanOutput(context.Background(), "text")
+	assert.Error(t, err)
+}
+
+func TestEnsemble_ScoresFromDecision(t *testing.T) {
+	e := NewEnsemble([]Member{
+		{Name: "vendor", Backend: fixedBackend{result: &stronghold.ScanResult{Decision: stronghold.DecisionBlock}}, Weight: 1},
+	}, 0.55, 0.35)
+	result, err := e.ScanContent(context.Background(), "text", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, stronghold.DecisionBlock, result.Decision)
+}
+
+func TestRouter_For(t *testing.T) {
+	internal := verdict(stronghold.DecisionAllow, 0.1)
+	withML, withEnsemble, withCustom, withRemoved, unset := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := fakeStore{
+		withML:       {Name: "ml"},
+		withEnsemble: {Name: config.BackendEnsemble},
+		withCustom:   {Name: config.BackendCustom, URL: "https://detect.example.com"},
+		withRemoved:  {Name: "retired"},
+		unset:        {},
+	}
+	r := NewRouter(&config.ScanBackendsConfig{
+		Endpoints: map[string]string{"ml": "https://ml.internal"},
+		Ensemble:  map[string]float64{"internal": 0.5, "ml": 0.5},
+		Timeout:   time.Second,
+	}, internal, &config.StrongholdConfig{BlockThreshold: 0.55, WarnThreshold: 0.35}, store)
+
+	name := func(id *uuid.UUID) string {
+		_, n := r.For(context.Background(), id)
+		return n
+	}
+	assert.Equal(t, "internal", name(nil), "x402 scans use the default")
+	assert.Equal(t, "internal", name(&unset))
+	assert.Equal(t, "ml", name(&withML))
+	assert.Equal(t, "ensemble", name(&withEnsemble))
+	assert.Equal(t, "custom", name(&withCustom))
+	assert.Equal(t, "internal", name(&withRemoved), "unconfigured selections fall back to the default")
+	missing := uuid.New()
+	assert.Equal(t, "internal", name(&missing))
+
+	assert.True(t, r.Has("ml"))
+	assert.True(t, r.Has("custom"))
+	assert.False(t, r.Has("retired"))
+}
@@ -0,0 +1,139 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"stronghold/internal/stronghold"
+)
+
+// Member is a weighted backend in an ensemble
+type Member struct {
+	Name    string
+	Backend Backend
+	Weight  float64
+}
+
+// Ensemble scans with every member concurrently and decides on the weighted
+// mean of their scores. Members that fail are left out of the mean; the scan
+// fails only if all of them do.
+type Ensemble struct {
+	members        []Member
+	blockThreshold float64
+	warnThreshold  float64
+}
+
+// NewEnsemble creates an ensemble deciding with the given thresholds
+func NewEnsemble(members []Member, blockThreshold, warnThreshold float64) *Ensemble {
+	members = slices.Clone(members)
+	slices.SortFunc(members, func(a, b Member) int { return strings.Compare(a.Name, b.Name) })
+	return &Ensemble{members: members, blockThreshold: blockThreshold, warnThreshold: warnThreshold}
+}
+
+// ScanContent scans external content with every member
+func (e *Ensemble) ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*stronghold.ScanResult, error) {
+	return e.scan(ctx, func(b Backend) (*stronghold.ScanResult, error) {
+		return b.ScanContent(ctx, text, sourceURL, sourceType, contentType)
+	})
+}
+
+// ScanOutput scans agent output with every member
+func (e *Ensemble) ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error) {
+	return e.scan(ctx, func(b Backend) (*stronghold.ScanResult, error) {
+		return b.ScanOutput(ctx, text)
+	})
+}
+
+func (e *Ensemble) scan(ctx context.Context, run func(Backend) (*stronghold.ScanResult, error)) (*stronghold.ScanResult, error) {
+	results := make([]*stronghold.ScanResult, len(e.members))
+	errs := make([]error, len(e.members))
+	var wg sync.WaitGroup
+	for i, m := range e.members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = run(m.Backend)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	combined := &stronghold.ScanResult{
+		Scores:   make(map[string]float64),
+		Metadata: make(map[string]interface{}),
+	}
+	decisions := make(map[string]string)
+	var failed, versions []string
+	var top *stronghold.ScanResult
+	var topScore, sum, weights float64
+	for i, m := range e.members {
+		r := results[i]
+		if errs[i] != nil || r == nil {
+			failed = append(failed, m.Name)
+			continue
+		}
+		score := e.memberScore(r)
+		combined.Scores[m.Name] = score
+		sum += score * m.Weight
+		weights += m.Weight
+		decisions[m.Name] = string(r.Decision)
+		versions = append(versions, m.Name+"@"+r.DetectionVersion)
+		combined.ThreatsFound = append(combined.ThreatsFound, r.ThreatsFound...)
+		if top == nil || score > topScore {
+			top, topScore = r, score
+		}
+		if combined.SanitizedText == "" {
+			combined.SanitizedText = r.SanitizedText
+		}
+	}
+	if top == nil {
+		return nil, fmt.Errorf("all ensemble backends failed: %w", errors.Join(errs...))
+	}
+
+	score := sum / weights
+	combined.Scores["combined"] = score
+	combined.Decision, combined.RecommendedAction = e.decide(score)
+	combined.Reason = top.Reason
+	if combined.Decision == stronghold.DecisionAllow {
+		combined.Reason = "No threats detected"
+	}
+	combined.DetectionVersion = "ensemble:" + strings.Join(versions, "+")
+	combined.Metadata["backend_decisions"] = decisions
+	if len(failed) > 0 {
+		combined.Metadata["failed_backends"] = failed
+	}
+	return combined, nil
+}
+
+// memberScore is a member's primary score, or one implied by its decision
+// when it returned no scores
+func (e *Ensemble) memberScore(r *stronghold.ScanResult) float64 {
+	if len(r.Scores) > 0 {
+		return stronghold.PrimaryScore(r.Scores)
+	}
+	switch r.Decision {
+	case stronghold.DecisionBlock:
+		return 1
+	case stronghold.DecisionWarn:
+		return e.warnThreshold
+	default:
+		return 0
+	}
+}
+
+func (e *Ensemble) decide(score float64) (stronghold.Decision, string) {
+	switch {
+	case score >= e.blockThreshold:
+		return stronghold.DecisionBlock, "DO NOT PROCEED - Content contains active threats. Discard immediately."
+	case score >= e.warnThreshold:
+		return stronghold.DecisionWarn, "Caution advised - Review content manually before processing."
+	default:
+		return stronghold.DecisionAllow, "Content is safe to process"
+	}
+}
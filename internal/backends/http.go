@@ -0,0 +1,99 @@
+package backends
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"stronghold/internal/stronghold"
+	"stronghold/pkg/types"
+)
+
+// maxResponseBytes bounds a backend's response body
+const maxResponseBytes = 1 << 20
+
+// HTTPBackend calls an external detection service. The service accepts
+// POST {base}/scan/content and {base}/scan/output with the scan request body
+// and answers with a Stronghold scan result (at least decision and scores).
+type HTTPBackend struct {
+	name    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPBackend creates a backend for the service at baseURL. token, when
+// set, is sent as a bearer token.
+func NewHTTPBackend(name, baseURL, token string, client *http.Client) *HTTPBackend {
+	return &HTTPBackend{name: name, baseURL: baseURL, token: token, client: client}
+}
+
+type contentRequest struct {
+	Text        string `json:"text"`
+	SourceURL   string `json:"source_url,omitempty"`
+	SourceType  string `json:"source_type,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+type outputRequest struct {
+	Text string `json:"text"`
+}
+
+// ScanContent scans external content with the service
+func (b *HTTPBackend) ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*stronghold.ScanResult, error) {
+	return b.scan(ctx, "/scan/content", contentRequest{Text: text, SourceURL: sourceURL, SourceType: sourceType, ContentType: contentType})
+}
+
+// ScanOutput scans agent output with the service
+func (b *HTTPBackend) ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error) {
+	return b.scan(ctx, "/scan/output", outputRequest{Text: text})
+}
+
+func (b *HTTPBackend) scan(ctx context.Context, path string, body any) (*stronghold.ScanResult, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %w", b.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %w", b.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backend %s returned status %d", b.name, resp.StatusCode)
+	}
+
+	var result stronghold.ScanResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("backend %s returned an invalid result: %w", b.name, err)
+	}
+	if !types.Compatible(result.SchemaVersion) {
+		return nil, fmt.Errorf("backend %s returned unsupported result schema %s", b.name, result.SchemaVersion)
+	}
+	// Results leave the API in the API's own schema
+	result.SchemaVersion = ""
+	switch result.Decision {
+	case stronghold.DecisionAllow, stronghold.DecisionWarn, stronghold.DecisionBlock:
+	default:
+		return nil, fmt.Errorf("backend %s returned unknown decision %q", b.name, result.Decision)
+	}
+	if result.Scores == nil {
+		result.Scores = map[string]float64{}
+	}
+	if result.DetectionVersion == "" {
+		result.DetectionVersion = b.name
+	}
+	return &result, nil
+}
@@ -0,0 +1,194 @@
+// Package bypass issues and verifies short-lived signed tokens that let
+// trusted internal tooling skip proxy scanning for a fixed set of hosts.
+//
+// Tokens are HMAC-SHA256 signed with a key that lives next to the proxy
+// config, so a prompt injection that only controls request headers cannot
+// forge one. The key is readable by the user that owns the config, so a
+// process running as that user can read it or run "stronghold bypass issue"
+// and mint tokens; isolate agents under a separate user where that matters.
+package bypass
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HeaderName is the request header carrying a bypass token
+const HeaderName = "X-Stronghold-Bypass"
+
+// MaxTTL is the longest lifetime a bypass token may have
+const MaxTTL = 24 * time.Hour
+
+// tokenPrefix versions the token format
+const tokenPrefix = "shb1"
+
+// keySize is the HMAC key length in bytes
+const keySize = 32
+
+var (
+	// ErrInvalidToken is returned for malformed or incorrectly signed tokens
+	ErrInvalidToken = errors.New("invalid bypass token")
+	// ErrExpired is returned for tokens past their expiry
+	ErrExpired = errors.New("bypass token expired")
+)
+
+// Claims is the signed payload of a bypass token
+type Claims struct {
+	ID        string   `json:"jti"`
+	Hosts     []string `json:"hosts"`
+	Client    string   `json:"client,omitempty"` // Who the token was issued to, for audit
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// Expiry returns the token expiry time
+func (c *Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// AllowsHost reports whether the token covers host. Host patterns may use a
+// leading "*." to cover a domain and all of its subdomains.
+func (c *Claims) AllowsHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range c.Hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Issue creates a signed token for hosts that expires after ttl
+func Issue(key []byte, hosts []string, client string, ttl time.Duration) (string, *Claims, error) {
+	if len(hosts) == 0 {
+		return "", nil, fmt.Errorf("at least one host is required")
+	}
+	if ttl <= 0 || ttl > MaxTTL {
+		return "", nil, fmt.Errorf("ttl must be between 1s and %s", MaxTTL)
+	}
+
+	normalized := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" || h == "*" || h == "*." {
+			return "", nil, fmt.Errorf("invalid host: %q", h)
+		}
+		normalized = append(normalized, h)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		ID:        hex.EncodeToString(id),
+		Hosts:     normalized,
+		Client:    client,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signingInput := tokenPrefix + "." + encoded
+	return signingInput + "." + sign(key, signingInput), claims, nil
+}
+
+// Verify checks the token signature and expiry and returns its claims
+func Verify(key []byte, token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenPrefix {
+		return nil, ErrInvalidToken
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(sign(key, signingInput)), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Reject tokens whose lifetime exceeds MaxTTL even if correctly signed
+	if claims.ExpiresAt-claims.IssuedAt > int64(MaxTTL/time.Second) {
+		return nil, ErrInvalidToken
+	}
+	if !now.Before(claims.Expiry()) {
+		return nil, ErrExpired
+	}
+
+	return &claims, nil
+}
+
+func sign(key []byte, input string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LoadKey reads a hex-encoded signing key from path
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bypass key: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid bypass key in %s", path)
+	}
+
+	return key, nil
+}
+
+// LoadOrCreateKey reads the signing key from path, generating one if it does not exist
+func LoadOrCreateKey(path string) ([]byte, error) {
+	key, err := LoadKey(path)
+	if err == nil {
+		return key, nil
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		return nil, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate bypass key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write bypass key: %w", err)
+	}
+
+	return key, nil
+}
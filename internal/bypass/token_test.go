@@ -0,0 +1,93 @@
+package bypass
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	key := []byte(strings.Repeat("k", keySize))
+
+	token, issued, err := Issue(key, []string{"api.internal", "*.corp.example"}, "oncall", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	claims, err := Verify(key, token, time.Now())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.ID != issued.ID || claims.Client != "oncall" {
+		t.Errorf("claims mismatch: got %+v, want %+v", claims, issued)
+	}
+
+	for host, want := range map[string]bool{
+		"api.internal":      true,
+		"API.internal":      true,
+		"corp.example":      true,
+		"db.corp.example":   true,
+		"evil.internal":     false,
+		"corp.example.evil": false,
+	} {
+		if got := claims.AllowsHost(host); got != want {
+			t.Errorf("AllowsHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	key := []byte(strings.Repeat("k", keySize))
+	otherKey := []byte(strings.Repeat("x", keySize))
+
+	token, _, err := Issue(key, []string{"api.internal"}, "", time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if _, err := Verify(otherKey, token, time.Now()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong key: expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := Verify(key, token+"x", time.Now()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("tampered signature: expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := Verify(key, "not-a-token", time.Now()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("malformed: expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := Verify(key, token, time.Now().Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired: expected ErrExpired, got %v", err)
+	}
+}
+
+func TestIssue_ValidatesInput(t *testing.T) {
+	key := []byte(strings.Repeat("k", keySize))
+
+	if _, _, err := Issue(key, nil, "", time.Minute); err == nil {
+		t.Error("expected error for empty host list")
+	}
+	if _, _, err := Issue(key, []string{"*"}, "", time.Minute); err == nil {
+		t.Error("expected error for wildcard-all host")
+	}
+	if _, _, err := Issue(key, []string{"api.internal"}, "", MaxTTL+time.Second); err == nil {
+		t.Error("expected error for ttl above MaxTTL")
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bypass.key")
+
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+
+	again, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey (existing): %v", err)
+	}
+	if string(key) != string(again) {
+		t.Error("expected existing key to be reused")
+	}
+}
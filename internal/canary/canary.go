@@ -0,0 +1,186 @@
+// Package canary shadow-scores a share of scans with a candidate detection
+// configuration, so its decisions can be compared with the stable scanner's
+// on real traffic before it is rolled out. Canary decisions are recorded
+// only; the stable decision is always the one returned to the caller.
+package canary
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// percentTTL is how long an account's enrollment is cached
+	percentTTL = 30 * time.Second
+	// maxInFlight bounds concurrent shadow scans; further scans are skipped
+	maxInFlight = 16
+	// shadowTimeout bounds one shadow scan and its result write
+	shadowTimeout = 30 * time.Second
+)
+
+// Store reads enrollments and records comparisons
+type Store interface {
+	GetCanaryPercent(ctx context.Context, accountID uuid.UUID) (float64, bool, error)
+	CreateCanaryResult(ctx context.Context, r *db.CanaryResult) error
+}
+
+// Scanner is the candidate detection configuration
+type Scanner interface {
+	ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*stronghold.ScanResult, error)
+	ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error)
+	Version() stronghold.DetectionVersion
+}
+
+type cachedPercent struct {
+	percent float64
+	expires time.Time
+}
+
+// Canary routes scans to the candidate scanner. A nil Canary does nothing.
+type Canary struct {
+	scanner        Scanner
+	defaultPercent float64
+	store          Store
+	roll           func() float64
+	inFlight       chan struct{}
+
+	mu       sync.Mutex
+	percents map[uuid.UUID]cachedPercent
+}
+
+// New creates the candidate scanner. It returns nil when the canary is disabled.
+func New(cfg *config.CanaryConfig, store Store) (*Canary, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	scanner, err := stronghold.NewScanner(&cfg.Stronghold)
+	if err != nil {
+		return nil, err
+	}
+	return newCanary(scanner, cfg.DefaultPercent, store), nil
+}
+
+func newCanary(scanner Scanner, defaultPercent float64, store Store) *Canary {
+	return &Canary{
+		scanner:        scanner,
+		defaultPercent: defaultPercent,
+		store:          store,
+		roll:           func() float64 { return rand.Float64() * 100 },
+		inFlight:       make(chan struct{}, maxInFlight),
+		percents:       make(map[uuid.UUID]cachedPercent),
+	}
+}
+
+// Version returns the candidate's detection version
+func (c *Canary) Version() string {
+	if c == nil {
+		return ""
+	}
+	return c.scanner.Version().Version
+}
+
+// DefaultPercent returns the share of scans from accounts that are not enrolled
+func (c *Canary) DefaultPercent() float64 {
+	if c == nil {
+		return 0
+	}
+	return c.defaultPercent
+}
+
+// Forget drops a cached enrollment so a change applies immediately
+func (c *Canary) Forget(accountID uuid.UUID) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.percents, accountID)
+	c.mu.Unlock()
+}
+
+// Compare shadow-scores the scan in the background if it falls in the
+// account's canary share, and records both decisions. accountID is nil for
+// scans without an account, which use the default percentage.
+func (c *Canary) Compare(accountID *uuid.UUID, requestID, endpoint, text, sourceType, contentType string, stable *stronghold.ScanResult) {
+	if c == nil || stable == nil {
+		return
+	}
+	result := &db.CanaryResult{
+		AccountID:      accountID,
+		RequestID:      requestID,
+		Endpoint:       endpoint,
+		StableVersion:  stable.DetectionVersion,
+		StableDecision: string(stable.Decision),
+		StableScore:    stronghold.PrimaryScore(stable.Scores),
+	}
+
+	select {
+	case c.inFlight <- struct{}{}:
+	default:
+		return // Shadow scoring is best effort; never queue behind it
+	}
+	go func() {
+		defer func() { <-c.inFlight }()
+		ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+		defer cancel()
+
+		if c.roll() >= c.percent(ctx, accountID) {
+			return
+		}
+
+		var shadow *stronghold.ScanResult
+		var err error
+		if endpoint == "/v1/scan/output" {
+			shadow, err = c.scanner.ScanOutput(ctx, text)
+		} else {
+			shadow, err = c.scanner.ScanContent(ctx, text, "", sourceType, contentType)
+		}
+		if err != nil {
+			slog.Warn("canary scan failed", "request_id", requestID, "error", err)
+			return
+		}
+
+		result.CanaryVersion = shadow.DetectionVersion
+		result.CanaryDecision = string(shadow.Decision)
+		result.CanaryScore = stronghold.PrimaryScore(shadow.Scores)
+		if err := c.store.CreateCanaryResult(ctx, result); err != nil {
+			slog.Warn("failed to record canary result", "request_id", requestID, "error", err)
+		}
+	}()
+}
+
+// percent returns the canary share for an account, caching enrollments
+func (c *Canary) percent(ctx context.Context, accountID *uuid.UUID) float64 {
+	if accountID == nil {
+		return c.defaultPercent
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.percents[*accountID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.percent
+	}
+
+	percent, enrolled, err := c.store.GetCanaryPercent(ctx, *accountID)
+	if err != nil {
+		slog.Warn("failed to get canary enrollment", "account_id", accountID.String(), "error", err)
+		return 0
+	}
+	if !enrolled {
+		percent = c.defaultPercent
+	}
+	c.mu.Lock()
+	c.percents[*accountID] = cachedPercent{percent: percent, expires: now.Add(percentTTL)}
+	c.mu.Unlock()
+	return percent
+}
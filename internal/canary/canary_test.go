@@ -0,0 +1,169 @@
+package canary
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	percents map[uuid.UUID]float64
+	lookups  int
+	results  []*db.CanaryResult
+	done     chan struct{}
+}
+
+func (f *fakeStore) GetCanaryPercent(_ context.Context, accountID uuid.UUID) (float64, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	p, ok := f.percents[accountID]
+	return p, ok, nil
+}
+
+func (f *fakeStore) CreateCanaryResult(_ context.Context, r *db.CanaryResult) error {
+	f.mu.Lock()
+	f.results = append(f.results, r)
+	f.mu.Unlock()
+	f.done <- struct{}{}
+	return nil
+}
+
+type fakeScanner struct {
+	decision stronghold.Decision
+	outputs  int
+}
+
+func (f *fakeScanner) ScanContent(context.Context, string, string, string, string) (*stronghold.ScanResult, error) {
+	return &stronghold.ScanResult{Decision: f.decision, Scores: map[string]float64{"combined": 0.8}, DetectionVersion: "canary"}, nil
+}
+
+func (f *fakeScanner) ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error) {
+	f.outputs++
+	return f.ScanContent(ctx, text, "", "", "")
+}
+
+func (f *fakeScanner) Version() stronghold.DetectionVersion {
+	return stronghold.DetectionVersion{Version: "canary"}
+}
+
+func newTestCanary(store *fakeStore, scanner *fakeScanner, defaultPercent, roll float64) *Canary {
+	c := newCanary(scanner, defaultPercent, store)
+	c.roll = func() float64 { return roll }
+	return c
+}
+
+func waitResult(t *testing.T, store *fakeStore) *db.CanaryResult {
+	t.Helper()
+	select {
+	case <-store.done:
+	case <-time.After(time.Second):
+		t.Fatal("canary result was not recorded")
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.results[len(store.results)-1]
+}
+
+func expectNoResult(t *testing.T, store *fakeStore) {
+	t.Helper()
+	select {
+	case <-store.done:
+		t.Fatal("expected no canary result")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNew_DisabledReturnsNil(t *testing.T) {
+	c, err := New(&config.CanaryConfig{}, &fakeStore{})
+	if err != nil || c != nil {
+		t.Fatalf("expected nil canary when disabled, got %v, %v", c, err)
+	}
+	// A nil canary does nothing
+	c.Compare(nil, "req", "/v1/scan/content", "text", "", "", &stronghold.ScanResult{})
+	if c.Version() != "" || c.DefaultPercent() != 0 {
+		t.Error("expected zero values from a nil canary")
+	}
+}
+
+func TestCompare_RecordsBothDecisions(t *testing.T) {
+	account := uuid.New()
+	store := &fakeStore{percents: map[uuid.UUID]float64{account: 50}, done: make(chan struct{}, 1)}
+	c := newTestCanary(store, &fakeScanner{decision: stronghold.DecisionBlock}, 0, 10)
+
+	stable := &stronghold.ScanResult{Decision: stronghold.DecisionAllow, Scores: map[string]float64{"combined": 0.2}, DetectionVersion: "stable"}
+	c.Compare(&account, "req-1", "/v1/scan/content", "text", "web_page", "html", stable)
+
+	got := waitResult(t, store)
+	if got.StableDecision != "ALLOW" || got.CanaryDecision != "BLOCK" {
+		t.Errorf("unexpected decisions: %s -> %s", got.StableDecision, got.CanaryDecision)
+	}
+	if got.StableVersion != "stable" || got.CanaryVersion != "canary" {
+		t.Errorf("unexpected versions: %s, %s", got.StableVersion, got.CanaryVersion)
+	}
+	if got.StableScore != 0.2 || got.CanaryScore != 0.8 {
+		t.Errorf("unexpected scores: %v, %v", got.StableScore, got.CanaryScore)
+	}
+	if *got.AccountID != account || got.RequestID != "req-1" {
+		t.Errorf("unexpected result identity: %+v", got)
+	}
+}
+
+func TestCompare_OutputEndpointUsesOutputScan(t *testing.T) {
+	store := &fakeStore{done: make(chan struct{}, 1)}
+	scanner := &fakeScanner{decision: stronghold.DecisionAllow}
+	c := newTestCanary(store, scanner, 100, 0)
+
+	c.Compare(nil, "req-1", "/v1/scan/output", "text", "", "", &stronghold.ScanResult{Decision: stronghold.DecisionAllow})
+	waitResult(t, store)
+	if scanner.outputs != 1 {
+		t.Errorf("expected output scan, got %d", scanner.outputs)
+	}
+}
+
+func TestCompare_SkipsOutsideShare(t *testing.T) {
+	account := uuid.New()
+	store := &fakeStore{percents: map[uuid.UUID]float64{account: 5}, done: make(chan struct{}, 1)}
+	c := newTestCanary(store, &fakeScanner{}, 100, 5)
+
+	c.Compare(&account, "req-1", "/v1/scan/content", "text", "", "", &stronghold.ScanResult{})
+	expectNoResult(t, store)
+}
+
+func TestCompare_UnenrolledAccountUsesDefault(t *testing.T) {
+	store := &fakeStore{done: make(chan struct{}, 1)}
+	c := newTestCanary(store, &fakeScanner{}, 0, 0)
+
+	account := uuid.New()
+	c.Compare(&account, "req-1", "/v1/scan/content", "text", "", "", &stronghold.ScanResult{})
+	expectNoResult(t, store)
+}
+
+func TestPercent_CachesAndForgets(t *testing.T) {
+	account := uuid.New()
+	store := &fakeStore{percents: map[uuid.UUID]float64{account: 25}}
+	c := newTestCanary(store, &fakeScanner{}, 0, 0)
+
+	for range 3 {
+		if p := c.percent(context.Background(), &account); p != 25 {
+			t.Fatalf("expected 25, got %v", p)
+		}
+	}
+	if store.lookups != 1 {
+		t.Errorf("expected one lookup while cached, got %d", store.lookups)
+	}
+
+	store.percents[account] = 75
+	c.Forget(account)
+	if p := c.percent(context.Background(), &account); p != 75 {
+		t.Errorf("expected 75 after Forget, got %v", p)
+	}
+}
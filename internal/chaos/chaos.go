@@ -0,0 +1,74 @@
+// Package chaos injects faults into the calls the API and proxy depend on, for
+// resilience testing of fail_open/fail_closed handling and settlement retries.
+//
+// Faults only take effect in binaries built with -tags chaos. In other builds
+// Enabled is false, the hooks are no-ops and faults can't be set, so release
+// binaries carry no injection path. Never deploy a chaos build.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Points where faults can be injected
+const (
+	Scanner     = "scanner"     // Proxy requests to the scan API
+	Facilitator = "facilitator" // API requests to the x402 facilitator
+	DB          = "db"          // API database connection checkouts
+)
+
+// Points lists every injection point
+var Points = []string{Scanner, Facilitator, DB}
+
+// Fault actions
+const (
+	ActionDelay = "delay" // Wait DelayMs, then proceed normally
+	ActionError = "error" // Fail before the call is made
+	ActionDrop  = "drop"  // Make the call, then discard the response. Database connections are killed.
+)
+
+// Fault is a fault injected at a point
+type Fault struct {
+	Point   string  `json:"point"`
+	Action  string  `json:"action"`
+	DelayMs int64   `json:"delay_ms,omitempty"`
+	Rate    float64 `json:"rate,omitempty"` // Share of calls affected; 0 means all
+}
+
+var (
+	// ErrInjected is the error returned by calls an injected fault failed
+	ErrInjected = errors.New("chaos: injected fault")
+
+	// ErrDisabled is returned when setting a fault in a build without
+	// -tags chaos
+	ErrDisabled = errors.New("fault injection is not compiled into this build (rebuild with -tags chaos)")
+)
+
+// Validate checks the fault
+func (f *Fault) Validate() error {
+	if !slices.Contains(Points, f.Point) {
+		return fmt.Errorf("unknown injection point %q", f.Point)
+	}
+	switch f.Action {
+	case ActionDelay, ActionError, ActionDrop:
+	default:
+		return fmt.Errorf("unknown action %q (want delay, error or drop)", f.Action)
+	}
+	if f.Rate < 0 || f.Rate > 1 {
+		return errors.New("rate must be between 0 and 1")
+	}
+	if f.DelayMs < 0 {
+		return errors.New("delay_ms must not be negative")
+	}
+	if f.Action == ActionDelay && f.DelayMs == 0 {
+		return errors.New("delay faults need a positive delay_ms")
+	}
+	return nil
+}
+
+func (f *Fault) delay() time.Duration {
+	return time.Duration(f.DelayMs) * time.Millisecond
+}
@@ -0,0 +1,16 @@
+package chaos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultValidate(t *testing.T) {
+	assert.NoError(t, (&Fault{Point: Facilitator, Action: ActionDelay, DelayMs: 100}).Validate())
+	assert.NoError(t, (&Fault{Point: DB, Action: ActionDrop, Rate: 0.5}).Validate())
+	assert.Error(t, (&Fault{Point: "cache", Action: ActionError}).Validate())
+	assert.Error(t, (&Fault{Point: Scanner, Action: "explode"}).Validate())
+	assert.Error(t, (&Fault{Point: Scanner, Action: ActionDelay}).Validate(), "delays need a duration")
+	assert.Error(t, (&Fault{Point: Scanner, Action: ActionError, Rate: 2}).Validate())
+}
@@ -0,0 +1,34 @@
+//go:build !chaos
+
+package chaos
+
+import (
+	"context"
+	"net/http"
+)
+
+// Enabled reports whether this build can inject faults
+const Enabled = false
+
+// Set returns ErrDisabled: faults can't be injected without -tags chaos
+func Set(f Fault) error {
+	return ErrDisabled
+}
+
+// Clear does nothing without -tags chaos
+func Clear(point string) {}
+
+// Faults returns nil without -tags chaos
+func Faults() []Fault {
+	return nil
+}
+
+// Before does nothing without -tags chaos
+func Before(ctx context.Context, point string) error {
+	return nil
+}
+
+// Transport returns base unchanged without -tags chaos
+func Transport(point string, base http.RoundTripper) http.RoundTripper {
+	return base
+}
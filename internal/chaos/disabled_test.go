@@ -0,0 +1,20 @@
+//go:build !chaos
+
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisabled(t *testing.T) {
+	assert.False(t, Enabled)
+	assert.ErrorIs(t, Set(Fault{Point: DB, Action: ActionError}), ErrDisabled)
+	assert.Empty(t, Faults())
+	assert.NoError(t, Before(context.Background(), DB))
+	assert.Nil(t, Transport(Scanner, nil), "release builds keep the default transport")
+	assert.Equal(t, http.DefaultTransport, Transport(Scanner, http.DefaultTransport))
+}
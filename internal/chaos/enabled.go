@@ -0,0 +1,119 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Enabled reports whether this build can inject faults
+const Enabled = true
+
+var (
+	mu     sync.RWMutex
+	faults = make(map[string]Fault)
+)
+
+// Set installs a fault, replacing any at the same point
+func Set(f Fault) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	mu.Lock()
+	faults[f.Point] = f
+	mu.Unlock()
+	slog.Warn("chaos fault set", "point", f.Point, "action", f.Action, "delay_ms", f.DelayMs, "rate", f.Rate)
+	return nil
+}
+
+// Clear removes the fault at point
+func Clear(point string) {
+	mu.Lock()
+	delete(faults, point)
+	mu.Unlock()
+}
+
+// Faults returns the installed faults
+func Faults() []Fault {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Fault, 0, len(faults))
+	for _, p := range Points {
+		if f, ok := faults[p]; ok {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+// fire returns the fault at point if it applies to this call
+func fire(point string) (Fault, bool) {
+	mu.RLock()
+	f, ok := faults[point]
+	mu.RUnlock()
+	if !ok || (f.Rate > 0 && rand.Float64() >= f.Rate) {
+		return Fault{}, false
+	}
+	return f, true
+}
+
+// Before applies the fault at point ahead of a call: delays wait, and errors
+// and drops fail with ErrInjected
+func Before(ctx context.Context, point string) error {
+	f, ok := fire(point)
+	if !ok {
+		return nil
+	}
+	if f.Action == ActionDelay {
+		select {
+		case <-time.After(f.delay()):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ErrInjected
+}
+
+// Transport wraps base with the faults injected at point
+func Transport(point string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{point: point, base: base}
+}
+
+type transport struct {
+	point string
+	base  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, ok := fire(t.point)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	switch f.Action {
+	case ActionDelay:
+		select {
+		case <-time.After(f.delay()):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	case ActionError:
+		return nil, ErrInjected
+	case ActionDrop:
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return nil, ErrInjected
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,67 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportFaults(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(Scanner, nil)}
+	t.Cleanup(func() { Clear(Scanner) })
+
+	get := func() error {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get())
+	assert.Equal(t, int64(1), hits.Load())
+
+	require.NoError(t, Set(Fault{Point: Scanner, Action: ActionError}))
+	assert.ErrorIs(t, get(), ErrInjected)
+	assert.Equal(t, int64(1), hits.Load(), "errors fail before the request is sent")
+
+	require.NoError(t, Set(Fault{Point: Scanner, Action: ActionDrop}))
+	assert.ErrorIs(t, get(), ErrInjected)
+	assert.Equal(t, int64(2), hits.Load(), "drops send the request and discard the response")
+
+	require.NoError(t, Set(Fault{Point: Scanner, Action: ActionDelay, DelayMs: 30}))
+	start := time.Now()
+	require.NoError(t, get())
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	assert.Len(t, Faults(), 1)
+	Clear(Scanner)
+	assert.Empty(t, Faults())
+	require.NoError(t, get())
+}
+
+func TestBefore(t *testing.T) {
+	t.Cleanup(func() { Clear(DB) })
+	assert.NoError(t, Before(context.Background(), DB))
+
+	require.NoError(t, Set(Fault{Point: DB, Action: ActionDrop}))
+	assert.ErrorIs(t, Before(context.Background(), DB), ErrInjected)
+
+	require.NoError(t, Set(Fault{Point: DB, Action: ActionDelay, DelayMs: 5000}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Before(ctx, DB), context.DeadlineExceeded, "delays give up with the caller")
+}
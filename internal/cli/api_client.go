@@ -10,8 +10,12 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"strings"
 	"time"
+
+	"stronghold/internal/secure"
+	"stronghold/internal/usdc"
 )
 
 // APIClient handles communication with the Stronghold API
@@ -69,7 +73,7 @@ type LoginResponse struct {
 
 // GetWalletKeyResponse represents the response from the wallet-key endpoint
 type GetWalletKeyResponse struct {
-	PrivateKey string `json:"private_key"`
+	PrivateKey *secure.SecretString `json:"private_key"`
 }
 
 // ErrorResponse represents an API error response
@@ -176,28 +180,34 @@ func (c *APIClient) Login(accountNumber string) (*LoginResponse, error) {
 	return &result, nil
 }
 
-// GetWalletKey retrieves the decrypted wallet private key from the server
-func (c *APIClient) GetWalletKey() (string, error) {
+// GetWalletKey retrieves the decrypted wallet private key from the server.
+// The caller must Zero the returned secret when done.
+func (c *APIClient) GetWalletKey() (*secure.SecretString, error) {
 	var result GetWalletKeyResponse
 	if err := c.doRequest(http.MethodGet, "/v1/auth/wallet-key", http.StatusOK, nil, &result); err != nil {
-		return "", err
+		return nil, err
+	}
+	if result.PrivateKey.IsEmpty() {
+		return nil, fmt.Errorf("server returned an empty wallet key")
 	}
 	return result.PrivateKey, nil
 }
 
 // TOTPSetupResponse represents the response from TOTP setup.
 type TOTPSetupResponse struct {
-	Secret        string   `json:"secret"`
-	OTPAuthURL    string   `json:"otpauth_url"`
-	RecoveryCodes []string `json:"recovery_codes"`
+	Secret        *secure.SecretString `json:"secret"`
+	OTPAuthURL    string               `json:"otpauth_url"`
+	RecoveryCodes []string             `json:"recovery_codes"`
 }
 
 // TOTPVerifyRequest represents a TOTP verification request.
 type TOTPVerifyRequest struct {
-	Code          string `json:"code,omitempty"`
-	RecoveryCode  string `json:"recovery_code,omitempty"`
-	DeviceLabel   string `json:"device_label,omitempty"`
-	DeviceTTLDays int    `json:"device_ttl_days,omitempty"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+	DeviceLabel    string `json:"device_label,omitempty"`
+	DeviceTTLDays  int    `json:"device_ttl_days,omitempty"`
+	DeviceOS       string `json:"device_os,omitempty"`
+	DeviceHostname string `json:"device_hostname,omitempty"`
 }
 
 // TOTPVerifyResponse represents the response from TOTP verification.
@@ -225,6 +235,125 @@ func (c *APIClient) VerifyTOTP(req *TOTPVerifyRequest) (*TOTPVerifyResponse, err
 	return &result, nil
 }
 
+// DeviceApprovalRequest asks for this machine to be trusted by approval from
+// an existing trusted device.
+type DeviceApprovalRequest struct {
+	DeviceLabel    string `json:"device_label,omitempty"`
+	DeviceTTLDays  int    `json:"device_ttl_days,omitempty"`
+	DeviceOS       string `json:"device_os,omitempty"`
+	DeviceHostname string `json:"device_hostname,omitempty"`
+}
+
+// DeviceApprovalResponse is returned when requesting approval. DeviceToken
+// becomes trusted once the request is approved.
+type DeviceApprovalResponse struct {
+	ID                  string `json:"id"`
+	DeviceToken         string `json:"device_token"`
+	Status              string `json:"status"`
+	ExpiresAt           string `json:"expires_at"`
+	PollIntervalSeconds int    `json:"poll_interval_seconds"`
+}
+
+// DeviceApprovalStatus represents the status of an approval request.
+type DeviceApprovalStatus struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// PendingDeviceApproval represents a request waiting for a decision.
+type PendingDeviceApproval struct {
+	ID          string  `json:"id"`
+	Label       *string `json:"label,omitempty"`
+	OS          *string `json:"os,omitempty"`
+	Hostname    *string `json:"hostname,omitempty"`
+	TTLDays     int     `json:"ttl_days"`
+	RequestedIP *string `json:"requested_ip,omitempty"`
+	UserAgent   *string `json:"user_agent,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	ExpiresAt   string  `json:"expires_at"`
+}
+
+// RequestDeviceApproval asks an existing trusted device to trust this machine.
+func (c *APIClient) RequestDeviceApproval(req *DeviceApprovalRequest) (*DeviceApprovalResponse, error) {
+	var result DeviceApprovalResponse
+	if err := c.doRequest(http.MethodPost, "/v1/auth/devices/approvals", http.StatusCreated, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDeviceApproval polls an approval request. The device token from the
+// request must be set with SetDeviceToken.
+func (c *APIClient) GetDeviceApproval(id string) (*DeviceApprovalStatus, error) {
+	var result DeviceApprovalStatus
+	if err := c.doRequest(http.MethodGet, "/v1/auth/devices/approvals/"+url.PathEscape(id), http.StatusOK, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListDeviceApprovals lists requests waiting for this trusted device to decide.
+func (c *APIClient) ListDeviceApprovals() ([]PendingDeviceApproval, error) {
+	var result struct {
+		Approvals []PendingDeviceApproval `json:"approvals"`
+	}
+	if err := c.doRequest(http.MethodGet, "/v1/auth/devices/approvals", http.StatusOK, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Approvals, nil
+}
+
+// ApproveDevice trusts the machine that made an approval request.
+func (c *APIClient) ApproveDevice(id string) error {
+	return c.doRequest(http.MethodPost, "/v1/auth/devices/approvals/"+url.PathEscape(id)+"/approve", http.StatusOK, nil, nil)
+}
+
+// DenyDevice rejects an approval request.
+func (c *APIClient) DenyDevice(id string) error {
+	return c.doRequest(http.MethodPost, "/v1/auth/devices/approvals/"+url.PathEscape(id)+"/deny", http.StatusOK, nil, nil)
+}
+
+// TrustedDevice represents a trusted device on the account.
+type TrustedDevice struct {
+	ID         string  `json:"id"`
+	Label      *string `json:"label,omitempty"`
+	Name       *string `json:"name,omitempty"`
+	OS         *string `json:"os,omitempty"`
+	Hostname   *string `json:"hostname,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeenAt *string `json:"last_seen_at,omitempty"`
+	LastSeenIP *string `json:"last_seen_ip,omitempty"`
+	ExpiresAt  *string `json:"expires_at,omitempty"`
+}
+
+// ListDevicesResponse represents the trusted devices on the account.
+type ListDevicesResponse struct {
+	Devices         []TrustedDevice `json:"devices"`
+	CurrentDeviceID *string         `json:"current_device_id"`
+}
+
+// ListDevices lists the account's trusted devices.
+func (c *APIClient) ListDevices() (*ListDevicesResponse, error) {
+	var result ListDevicesResponse
+	if err := c.doRequest(http.MethodGet, "/v1/auth/devices", http.StatusOK, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RenameDevice names a trusted device. An empty name clears it.
+func (c *APIClient) RenameDevice(id, name string) error {
+	req := map[string]string{"device_id": id, "name": name}
+	return c.doRequest(http.MethodPost, "/v1/auth/devices/rename", http.StatusOK, req, nil)
+}
+
+// RevokeDevice removes trust from a device.
+func (c *APIClient) RevokeDevice(id string) error {
+	req := map[string]string{"device_id": id}
+	return c.doRequest(http.MethodPost, "/v1/auth/devices/revoke", http.StatusOK, req, nil)
+}
+
 // UpdateWalletRequest represents a request to update wallet
 type UpdateWalletRequest struct {
 	PrivateKey string `json:"private_key"`
@@ -275,6 +404,87 @@ func (c *APIClient) RegisterWalletAddresses(evmAddr, solanaAddr string) error {
 	return err
 }
 
+// RegisterInstallRequest registers this machine's proxy signing key
+type RegisterInstallRequest struct {
+	PublicKey string `json:"public_key"`
+	Hostname  string `json:"hostname,omitempty"`
+	OS        string `json:"os,omitempty"`
+}
+
+// RegisteredInstall represents a proxy install registered with the account
+type RegisteredInstall struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// RegisterInstall registers a proxy install's public key with the account
+func (c *APIClient) RegisterInstall(req *RegisterInstallRequest) (*RegisteredInstall, error) {
+	var result RegisteredInstall
+	if err := c.doRequest(http.MethodPost, "/v1/account/installs", http.StatusCreated, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AccountRunway estimates how long the account balance lasts at the recent
+// spend rate
+type AccountRunway struct {
+	DailySpendUSDC usdc.MicroUSDC `json:"daily_spend_usdc"`
+	DaysRemaining  *float64       `json:"days_remaining"` // nil when nothing was spent in the window
+	WindowDays     int            `json:"window_days"`
+	LowBalance     bool           `json:"low_balance"`
+}
+
+// AccountDetails is the part of the account details the CLI shows
+type AccountDetails struct {
+	BalanceUSDC usdc.MicroUSDC `json:"balance_usdc"`
+	Runway      *AccountRunway `json:"runway,omitempty"` // omitted when it could not be estimated
+}
+
+// GetAccount returns the account balance and runway estimate
+func (c *APIClient) GetAccount() (*AccountDetails, error) {
+	var result AccountDetails
+	if err := c.doRequest(http.MethodGet, "/v1/account", http.StatusOK, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ConfigSync is the account's encrypted CLI configuration
+type ConfigSync struct {
+	Version   int64   `json:"version"`
+	Data      string  `json:"data"`
+	UpdatedBy *string `json:"updated_by,omitempty"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// PutConfigSyncRequest uploads a new version of the encrypted configuration
+type PutConfigSyncRequest struct {
+	BaseVersion int64  `json:"base_version"`
+	Data        string `json:"data"`
+	Hostname    string `json:"hostname,omitempty"`
+}
+
+// GetConfigSync returns the account's synced configuration; a 404 APIError
+// means nothing has been synced
+func (c *APIClient) GetConfigSync() (*ConfigSync, error) {
+	var result ConfigSync
+	if err := c.doRequest(http.MethodGet, "/v1/account/config-sync", http.StatusOK, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PutConfigSync uploads the account's synced configuration; a 409 APIError
+// means another machine uploaded since req.BaseVersion
+func (c *APIClient) PutConfigSync(req *PutConfigSyncRequest) (*ConfigSync, error) {
+	var result ConfigSync
+	if err := c.doRequest(http.MethodPut, "/v1/account/config-sync", http.StatusOK, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // isNetworkError returns true if the error is a network-level failure
 // (DNS resolution, connection refused, timeout, etc.).
 func isNetworkError(err error) bool {
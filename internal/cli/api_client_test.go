@@ -221,3 +221,32 @@ func TestDoRequest_NetworkError(t *testing.T) {
 		t.Fatal("expected error for network failure, got nil")
 	}
 }
+
+func TestGetAccount_Runway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/account" {
+			t.Errorf("request = %s %s, want GET /v1/account", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"balance_usdc":"2500000","runway":{"daily_spend_usdc":"1000000","days_remaining":2.5,"window_days":7,"low_balance":true}}`))
+	}))
+	defer server.Close()
+
+	account, err := NewAPIClient(server.URL, "").GetAccount()
+	if err != nil {
+		t.Fatalf("GetAccount failed: %v", err)
+	}
+	if account.BalanceUSDC != 2_500_000 {
+		t.Errorf("BalanceUSDC = %d, want 2500000", account.BalanceUSDC)
+	}
+	if account.Runway == nil || !account.Runway.LowBalance {
+		t.Fatalf("Runway = %+v, want a low balance", account.Runway)
+	}
+	if got, want := formatRunway(account.Runway), "~2.5 days at 1.00 USDC/day (last 7 days)"; got != want {
+		t.Errorf("formatRunway = %q, want %q", got, want)
+	}
+
+	account.Runway.DaysRemaining = nil
+	if got, want := formatRunway(account.Runway), "no spend in the last 7 days"; got != want {
+		t.Errorf("formatRunway = %q, want %q", got, want)
+	}
+}
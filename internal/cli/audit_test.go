@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stronghold/internal/hashchain"
+)
+
+func TestAuditVerify(t *testing.T) {
+	head := hashchain.Genesis
+	var lines []string
+	for _, obj := range []string{`{"request_id":"req-1","action":"block"}`, `{"request_id":"req-2","action":"warn"}`} {
+		line, hash, err := hashchain.Seal(head, []byte(obj))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line))
+		head = hash
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AuditVerify(path); err != nil {
+		t.Errorf("expected intact log to verify, got %v", err)
+	}
+
+	tampered := strings.Replace(lines[0], `"block"`, `"allow"`, 1) + "\n" + lines[1] + "\n"
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AuditVerify(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected tampering at line 1 to be detected, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"stronghold/internal/bypass"
+)
+
+// bypassAuditEntry is one line of the bypass token audit log
+type bypassAuditEntry struct {
+	Event     string   `json:"event"`
+	TokenID   string   `json:"token_id"`
+	RequestID string   `json:"request_id,omitempty"` // Replayed request, for replay overrides
+	Hosts     []string `json:"hosts"`
+	Client    string   `json:"client,omitempty"`
+	IssuedBy  string   `json:"issued_by,omitempty"`
+	IssuedAt  string   `json:"issued_at"`
+	ExpiresAt string   `json:"expires_at"`
+}
+
+// BypassKeyPath returns the default location of the bypass signing key
+func BypassKeyPath() string {
+	return filepath.Join(ConfigDir(), "bypass.key")
+}
+
+// BypassAuditLogPath returns the path of the bypass token audit log
+func BypassAuditLogPath() string {
+	return filepath.Join(ConfigDir(), "logs", "bypass-audit.log")
+}
+
+// BypassIssue issues a short-lived token that lets a client skip scanning for hosts
+func BypassIssue(hosts []string, ttl time.Duration, client string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	key, restartNeeded, err := loadBypassKey(config)
+	if err != nil {
+		return err
+	}
+
+	token, claims, err := bypass.Issue(key, hosts, client, ttl)
+	if err != nil {
+		return err
+	}
+
+	if err := appendBypassAudit(bypassAuditEntry{
+		Event:     "issued",
+		TokenID:   claims.ID,
+		Hosts:     claims.Hosts,
+		Client:    claims.Client,
+		IssuedBy:  config.Auth.Email,
+		IssuedAt:  time.Unix(claims.IssuedAt, 0).UTC().Format(time.RFC3339),
+		ExpiresAt: claims.Expiry().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	fmt.Println(warningStyle.Render("⚠ Bypass token issued - scanning is skipped for requests that carry it"))
+	fmt.Println()
+	fmt.Printf("  Token ID:   %s\n", claims.ID)
+	fmt.Printf("  Hosts:      %v\n", claims.Hosts)
+	if claims.Client != "" {
+		fmt.Printf("  Client:     %s\n", claims.Client)
+	}
+	fmt.Printf("  Expires:    %s\n", claims.Expiry().Format(time.RFC3339))
+	fmt.Println()
+	fmt.Printf("Attach it to requests with:\n  %s: %s\n", bypass.HeaderName, token)
+	fmt.Println()
+	fmt.Println(infoStyle.Render("Usage is logged by the proxy. Issuance is recorded in " + BypassAuditLogPath()))
+	if restartNeeded {
+		fmt.Println(infoStyle.Render("Bypass tokens were just enabled; restart the proxy with 'stronghold disable && stronghold enable'"))
+	}
+
+	return nil
+}
+
+// loadBypassKey loads the bypass signing key, creating it and enabling
+// bypass tokens in the config on first use. The returned bool reports whether
+// the proxy must be restarted to pick up a newly enabled key.
+func loadBypassKey(config *CLIConfig) ([]byte, bool, error) {
+	keyPath := config.Bypass.KeyPath
+	if keyPath == "" {
+		keyPath = BypassKeyPath()
+	}
+
+	key, err := bypass.LoadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The proxy only loads the key at startup
+	if config.Bypass.KeyPath == "" {
+		config.Bypass.KeyPath = keyPath
+		if err := config.Save(); err != nil {
+			return nil, false, fmt.Errorf("failed to save config: %w", err)
+		}
+		return key, true, nil
+	}
+
+	return key, false, nil
+}
+
+// appendBypassAudit appends an entry to the bypass audit log
+func appendBypassAudit(entry bypassAuditEntry) error {
+	path := BypassAuditLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+// clockSkewWarning is the clock skew past which it is reported. Up to
+// wallet.MaxClockCorrection the proxy stamps payments with the API's time
+// instead; beyond it payments fall outside their validity window.
+const clockSkewWarning = 30 * time.Second
+
+var measureClockSkewFunc = measureClockSkew
+
+// measureClockSkew returns how far the local clock is ahead of (positive) or
+// behind (negative) the API's, from the Date header of its health endpoint,
+// and the request's round trip
+func measureClockSkew(baseURL string) (time.Duration, time.Duration, error) {
+	healthURL := strings.TrimRight(baseURL, "/") + "/health"
+	client := &http.Client{Timeout: apiHealthTimeout}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, healthURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sent := time.Now()
+	resp, err := client.Do(req)
+	received := time.Now()
+	if err != nil {
+		return 0, received.Sub(sent), err
+	}
+	resp.Body.Close()
+
+	if resp.Header.Get("Date") == "" {
+		return 0, received.Sub(sent), fmt.Errorf("no Date header in the API response")
+	}
+	skew, err := wallet.MeasureClockSkew(resp.Header.Get("Date"), sent, received)
+	return skew, received.Sub(sent), err
+}
+
+// describeClockSkew says how far the local clock is off from the API's
+func describeClockSkew(skew time.Duration) string {
+	switch {
+	case skew == 0:
+		return "local clock matches the API"
+	case skew > 0:
+		return fmt.Sprintf("local clock is %s ahead of the API", skew)
+	default:
+		return fmt.Sprintf("local clock is %s behind the API", -skew)
+	}
+}
+
+// checkClock verifies the local clock is close enough to the API's for
+// payments to fall in their validity window
+func checkClock() CheckResult {
+	result := CheckResult{Name: "System clock"}
+
+	config, err := LoadConfig()
+	if err != nil {
+		result.Status = CheckWarn
+		result.Message = "Could not load config to find the API"
+		return result
+	}
+	skew, _, err := measureClockSkewFunc(config.API.Endpoint.Primary())
+	if err != nil {
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("Could not compare with the API clock: %v", err)
+		return result
+	}
+
+	result.Message = describeClockSkew(skew)
+	switch abs := skew.Abs(); {
+	case abs < clockSkewWarning:
+		result.Status = CheckPass
+	case abs < wallet.MaxClockCorrection:
+		result.Status = CheckWarn
+		result.Message += "; the proxy corrects payment timestamps for it"
+	default:
+		result.Status = CheckFail
+		result.Message += fmt.Sprintf("; payments are valid for %s and will be rejected", wallet.PaymentValidity)
+		result.Fix = "Enable automatic time sync (NTP) for the system clock"
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckClock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := measureClockSkewFunc
+	defer func() { measureClockSkewFunc = orig }()
+
+	tests := []struct {
+		skew    time.Duration
+		want    CheckStatus
+		message string
+	}{
+		{0, CheckPass, "local clock matches the API"},
+		{10 * time.Second, CheckPass, "local clock is 10s ahead of the API"},
+		{-2 * time.Minute, CheckWarn, "corrects payment timestamps"},
+		{20 * time.Minute, CheckFail, "will be rejected"},
+	}
+	for _, tt := range tests {
+		measureClockSkewFunc = func(string) (time.Duration, time.Duration, error) {
+			return tt.skew, 0, nil
+		}
+		result := checkClock()
+		if result.Status != tt.want {
+			t.Errorf("skew %s: expected %s, got %s (%s)", tt.skew, tt.want, result.Status, result.Message)
+		}
+		if !strings.Contains(result.Message, tt.message) {
+			t.Errorf("skew %s: expected message to contain %q, got %q", tt.skew, tt.message, result.Message)
+		}
+	}
+}
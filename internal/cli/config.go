@@ -2,27 +2,69 @@ package cli
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"stronghold/internal/configschema"
+	"stronghold/internal/configsecret"
+	"stronghold/internal/wallet"
 )
 
-// ConfigVersion is the current config file version
-const ConfigVersion = "1.0"
+// ConfigVersion is the current config file schema version
+const ConfigVersion = configschema.Current
 
 // ProxyConfig holds proxy-specific configuration
 type ProxyConfig struct {
-	Port int    `yaml:"port"`
-	Bind string `yaml:"bind"`
+	Port int        `yaml:"port"`
+	Bind string     `yaml:"bind"`
+	IPv6 *bool      `yaml:"ipv6,omitempty"`
+	QUIC string     `yaml:"quic,omitempty"` // "block" (default), "log", or "allow"
+	Pool PoolConfig `yaml:"pool,omitempty"`
+
+	MaxInflightScans   int `yaml:"max_inflight_scans,omitempty"`    // Default 64
+	MaxInflightPerHost int `yaml:"max_inflight_per_host,omitempty"` // Default 8
+}
+
+// PoolConfig configures the proxy's reuse of upstream connections
+type PoolConfig struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns,omitempty"`          // Default 256
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host,omitempty"` // Default 16
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout,omitempty"`       // Default 90s
+}
+
+// IPv6Enabled reports whether IPv6 traffic is redirected to the proxy (default
+// true). When false, outbound IPv6 HTTP(S) is rejected instead.
+func (c ProxyConfig) IPv6Enabled() bool {
+	return c.IPv6 == nil || *c.IPv6
+}
+
+// QUIC handling modes for outbound UDP/443
+const (
+	QUICBlock = "block" // Reject so clients fall back to interceptable TCP
+	QUICLog   = "log"   // Count attempts but let them through unscanned
+	QUICAllow = "allow" // No QUIC rules
+)
+
+// QUICMode returns the configured QUIC handling mode, defaulting to block
+func (c ProxyConfig) QUICMode() string {
+	if c.QUIC == "" {
+		return QUICBlock
+	}
+	return c.QUIC
 }
 
 // APIConfig holds Stronghold API configuration
 type APIConfig struct {
-	Endpoint string        `yaml:"endpoint"`
-	Timeout  time.Duration `yaml:"timeout"`
+	Endpoint          configschema.Endpoints `yaml:"endpoint"` // One URL, or one per region to pick the fastest from
+	Timeout           time.Duration          `yaml:"timeout"`
+	HeartbeatInterval time.Duration          `yaml:"heartbeat_interval,omitempty"` // How often a registered install reports in (default 5m)
+	ProbeInterval     time.Duration          `yaml:"probe_interval,omitempty"`     // How often regional endpoints are probed for latency (default 5m)
+	Pins              map[string][]string    `yaml:"pins,omitempty"`               // SPKI SHA-256 hashes per API hostname; scans only go to pinned hosts
 }
 
 // AuthConfig holds authentication configuration
@@ -33,14 +75,32 @@ type AuthConfig struct {
 	AccountNumber string `yaml:"account_number"`
 	LoggedIn      bool   `yaml:"logged_in"`
 	DeviceToken   string `yaml:"device_token,omitempty"`
+	// Registered install that signs the proxy's scan requests
+	InstallID      string `yaml:"install_id,omitempty"`
+	InstallKeyPath string `yaml:"install_key_path,omitempty"`
 }
 
 // WalletConfig holds wallet configuration
 type WalletConfig struct {
-	Address       string `yaml:"address"`
-	Network       string `yaml:"network"`
-	SolanaAddress string `yaml:"solana_address,omitempty"`
-	SolanaNetwork string `yaml:"solana_network,omitempty"`
+	Address       string        `yaml:"address"`
+	Network       string        `yaml:"network"`
+	SolanaAddress string        `yaml:"solana_address,omitempty"`
+	SolanaNetwork string        `yaml:"solana_network,omitempty"`
+	AutoPay       AutoPayConfig `yaml:"autopay,omitempty"`
+	Presign       PresignConfig `yaml:"presign,omitempty"`
+}
+
+// PresignConfig keeps signed scan payments ready ahead of time
+type PresignConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	PoolSize int           `yaml:"pool_size,omitempty"`
+	MaxAge   time.Duration `yaml:"max_age,omitempty"`
+}
+
+// AutoPayConfig lets the proxy pay x402-protected upstream APIs with the local wallet
+type AutoPayConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Hosts   []string `yaml:"hosts,omitempty"`
 }
 
 // PaymentsConfig holds payment configuration
@@ -50,6 +110,25 @@ type PaymentsConfig struct {
 	TopupThreshold float64 `yaml:"topup_threshold"`
 	TopupAmount    float64 `yaml:"topup_amount"`
 	WalletAddress  string  `yaml:"wallet_address,omitempty"`
+
+	Policy SpendPolicyConfig `yaml:"policy,omitempty"`
+}
+
+// SpendPolicyConfig limits what the proxy may spend paying upstream APIs
+type SpendPolicyConfig struct {
+	MaxPerRequest float64            `yaml:"max_per_request,omitempty"`
+	MaxPerHour    float64            `yaml:"max_per_hour,omitempty"`
+	MaxPerDay     float64            `yaml:"max_per_day,omitempty"`
+	Domains       []DomainSpendLimit `yaml:"domains,omitempty"`
+	NotifyURL     string             `yaml:"notify_url,omitempty"`
+}
+
+// DomainSpendLimit applies tighter spend limits to matching hosts
+type DomainSpendLimit struct {
+	Host          string  `yaml:"host"`
+	MaxPerRequest float64 `yaml:"max_per_request,omitempty"`
+	MaxPerHour    float64 `yaml:"max_per_hour,omitempty"`
+	MaxPerDay     float64 `yaml:"max_per_day,omitempty"`
 }
 
 // ScanTypeConfig configures behavior for a specific scan type
@@ -61,17 +140,106 @@ type ScanTypeConfig struct {
 
 // ScanningConfig holds scanning behavior configuration
 type ScanningConfig struct {
-	Mode           string         `yaml:"mode"`
-	BlockThreshold float64        `yaml:"block_threshold"`
-	FailOpen       bool           `yaml:"fail_open"`
-	Content        ScanTypeConfig `yaml:"content"` // Prompt injection scanning (incoming)
-	Output         ScanTypeConfig `yaml:"output"`  // Credential leak scanning (outgoing)
+	Mode           string             `yaml:"mode"`
+	BlockThreshold float64            `yaml:"block_threshold"`
+	FailOpen       bool               `yaml:"fail_open"`
+	Encrypt        bool               `yaml:"encrypt,omitempty"` // Seal scans end to end so only the scanning backend reads content
+	Content        ScanTypeConfig     `yaml:"content"`           // Prompt injection scanning (incoming)
+	Output         ScanTypeConfig     `yaml:"output"`            // Credential leak scanning (outgoing)
+	Limits         ScanLimitsConfig   `yaml:"limits,omitempty"`
+	Headers        HeaderScanConfig   `yaml:"headers,omitempty"`
+	Request        RequestScanConfig  `yaml:"request,omitempty"` // Secrets in outbound request bodies and headers
+	Offline        OfflineQueueConfig `yaml:"offline,omitempty"`
+	Streaming      StreamScanConfig   `yaml:"streaming,omitempty"`  // Scan text/event-stream responses as they arrive
+	LocalOnly      bool               `yaml:"local_only,omitempty"` // Scan in-process with the proxy's bundled rules; the API is not contacted
+	Local          LocalScanConfig    `yaml:"local,omitempty"`
+}
+
+// LocalScanConfig configures the proxy's in-process scanning when
+// scanning.local_only is set
+type LocalScanConfig struct {
+	RulePacks   []string `yaml:"rule_packs,omitempty"`   // Rule pack YAML files matched alongside the bundled rules
+	APIFallback bool     `yaml:"api_fallback,omitempty"` // Send content no local rule flagged to the API
+}
+
+// OfflineQueueConfig configures retro-scanning of content that passed
+// unscanned while the API was unreachable with fail_open on
+type OfflineQueueConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxEntries    int           `yaml:"max_entries,omitempty"`
+	MaxBytes      int64         `yaml:"max_bytes,omitempty"`
+	RetryInterval time.Duration `yaml:"retry_interval,omitempty"`
+}
+
+// StreamScanConfig configures incremental scanning of server-sent event
+// streams
+type StreamScanConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Window   int           `yaml:"window,omitempty"`    // Default 4KB
+	Overlap  int           `yaml:"overlap,omitempty"`   // Default 512 bytes
+	MaxDelay time.Duration `yaml:"max_delay,omitempty"` // Default 500ms
+}
+
+// HeaderScanConfig configures the proxy's scanning of outbound request headers
+// and cookies for credentials
+type HeaderScanConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Action    string   `yaml:"action,omitempty"` // "block" (default), "redact", or "warn"
+	Allowlist []string `yaml:"allowlist,omitempty"`
+}
+
+// RequestScanConfig configures the proxy's scanning of outbound request bodies
+// and headers for secrets
+type RequestScanConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Action     string   `yaml:"action,omitempty"`      // "block" (default) or "warn"
+	MinEntropy float64  `yaml:"min_entropy,omitempty"` // Default 3.5 bits per character
+	Allowlist  []string `yaml:"allowlist,omitempty"`
+}
+
+// ScanLimitsConfig controls how much of a body the proxy scans
+type ScanLimitsConfig struct {
+	MaxBodySize  int  `yaml:"max_body_size,omitempty"` // Default 1MB
+	Chunked      bool `yaml:"chunked,omitempty"`
+	ChunkSize    int  `yaml:"chunk_size,omitempty"`    // Default 256KB
+	ChunkOverlap int  `yaml:"chunk_overlap,omitempty"` // Default 4KB
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level string `yaml:"level"`
-	File  string `yaml:"file"`
+	Level     string `yaml:"level"`
+	File      string `yaml:"file"`
+	AuditFile string `yaml:"audit_file,omitempty"` // Flagged proxy decisions; defaults to audit.log next to File
+	SpendFile string `yaml:"spend_file,omitempty"` // Upstream payments; defaults to spend.log next to File
+
+	OfflineFile string `yaml:"offline_file,omitempty"` // Offline scan queue; defaults to offline-queue.jsonl next to File
+
+	Unredacted bool `yaml:"unredacted,omitempty"` // Proxy logs personal data and secrets unmasked; debugging only
+
+	Rotation   LogRotationConfig `yaml:"rotation,omitempty"`    // Applies to the proxy log, audit log and spend ledger
+	DiskBudget int64             `yaml:"disk_budget,omitempty"` // Doctor warns when the data directory grows past this many bytes; defaults to 500MB
+}
+
+// LogRotationConfig bounds the disk used by the proxy's logs; zero values
+// take the proxy's defaults
+type LogRotationConfig struct {
+	MaxBytes   int64         `yaml:"max_bytes,omitempty"`   // Default 10MB
+	Interval   time.Duration `yaml:"interval,omitempty"`    // Also rotate every interval, e.g. 24h
+	MaxBackups int           `yaml:"max_backups,omitempty"` // Default 10
+	MaxAge     time.Duration `yaml:"max_age,omitempty"`
+	Compress   *bool         `yaml:"compress,omitempty"` // Default true
+}
+
+// AuditFilePath returns the proxy's decision audit log location, resolved the
+// same way the proxy does. Empty means the proxy keeps no audit log.
+func (c *LoggingConfig) AuditFilePath() string {
+	if c.AuditFile != "" {
+		return c.AuditFile
+	}
+	if c.File != "" {
+		return filepath.Join(filepath.Dir(c.File), "audit.log")
+	}
+	return ""
 }
 
 // UsageStats holds usage statistics
@@ -85,33 +253,134 @@ type UsageStats struct {
 
 // CAConfig holds CA certificate configuration for MITM
 type CAConfig struct {
-	CertPath string `yaml:"cert_path"`
-	KeyPath  string `yaml:"key_path"`
+	CertPath    string `yaml:"cert_path"`
+	KeyPath     string `yaml:"key_path"`
+	Pregenerate bool   `yaml:"pregenerate,omitempty"`
+}
+
+// BlockPageConfig controls the response sent when the proxy blocks content
+type BlockPageConfig struct {
+	Format       string        `yaml:"format,omitempty"`        // "json", "html", "auto", or "retry"
+	JSONTemplate string        `yaml:"json_template,omitempty"` // Go text/template producing the JSON body
+	HTMLFile     string        `yaml:"html_file,omitempty"`     // Path to a Go html/template file
+	RetryAfter   time.Duration `yaml:"retry_after,omitempty"`   // Retry-After for the "retry" format
+}
+
+// BlockResponseRule overrides the block response for matching destinations
+type BlockResponseRule struct {
+	Match           string `yaml:"match"` // "api.example.com" or "*.example.com"
+	BlockPageConfig `yaml:",inline"`
+}
+
+// BlockResponseConfig holds the default block response and per-destination overrides
+type BlockResponseConfig struct {
+	BlockPageConfig `yaml:",inline"`
+	Rules           []BlockResponseRule `yaml:"rules,omitempty"`
+}
+
+// BypassConfig configures signed bypass tokens for trusted internal tooling
+type BypassConfig struct {
+	KeyPath string `yaml:"key_path,omitempty"`
+}
+
+// PolicyRule restricts when and how often matching hosts may be contacted
+type PolicyRule struct {
+	Name        string        `yaml:"name"`
+	Hosts       []string      `yaml:"hosts,omitempty"`
+	Hours       string        `yaml:"hours,omitempty"`
+	Days        []string      `yaml:"days,omitempty"`
+	Timezone    string        `yaml:"timezone,omitempty"`
+	MaxRequests int           `yaml:"max_requests,omitempty"`
+	Per         time.Duration `yaml:"per,omitempty"`
+}
+
+// PolicyConfig holds local egress policy rules enforced by the proxy
+type PolicyConfig struct {
+	Rules         []PolicyRule                       `yaml:"rules,omitempty"`
+	ExpectedHosts []string                           `yaml:"expected_hosts,omitempty"`
+	Hosts         map[string]configschema.HostPolicy `yaml:"hosts,omitempty"`
+}
+
+// DNSConfig configures the proxy's optional local DNS forwarder
+type DNSConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Listen         string        `yaml:"listen,omitempty"`
+	Upstream       []string      `yaml:"upstream,omitempty"`
+	Denylist       []string      `yaml:"denylist,omitempty"`
+	BlockNewerThan time.Duration `yaml:"block_newer_than,omitempty"`
+}
+
+// ResourcesConfig caps the proxy's own memory and CPU use; over budget it
+// sheds scan work according to ShedPolicy
+type ResourcesConfig struct {
+	MaxMemory    int64    `yaml:"max_memory,omitempty"`
+	MaxCPU       float64  `yaml:"max_cpu,omitempty"`
+	ShedPolicy   string   `yaml:"shed_policy,omitempty"` // "low_risk" (default), "fail_open", or "fail_closed"
+	LowRiskTypes []string `yaml:"low_risk_types,omitempty"`
+}
+
+// WatchdogConfig decides what happens to the firewall when the proxy
+// crash-loops, leaving the redirect rules pointing at nothing
+type WatchdogConfig struct {
+	Policy      string        `yaml:"policy,omitempty"`       // "fail_open", "fail_closed", or "off"; empty follows scanning.fail_open
+	MaxRestarts int           `yaml:"max_restarts,omitempty"` // Crashes within Window that count as a crash loop (default 5)
+	Window      time.Duration `yaml:"window,omitempty"`       // Default 5m
+}
+
+// RPCConfig lists the RPC endpoints used for on-chain balance lookups and
+// payments, in order of preference. Calls fail over between them and back
+// off from endpoints that error or rate limit; an empty list uses the
+// network's public RPC.
+type RPCConfig struct {
+	Base         []string `yaml:"base,omitempty"`
+	BaseSepolia  []string `yaml:"base_sepolia,omitempty"`
+	Solana       []string `yaml:"solana,omitempty"`
+	SolanaDevnet []string `yaml:"solana_devnet,omitempty"`
+}
+
+// URLs returns the configured endpoints keyed by network name
+func (c RPCConfig) URLs() map[string][]string {
+	return map[string][]string{
+		"base":          c.Base,
+		"base-sepolia":  c.BaseSepolia,
+		"solana":        c.Solana,
+		"solana-devnet": c.SolanaDevnet,
+	}
 }
 
 // CLIConfig holds the complete CLI configuration
 type CLIConfig struct {
-	Version     string         `yaml:"version"`
-	Proxy       ProxyConfig    `yaml:"proxy"`
-	API         APIConfig      `yaml:"api"`
-	Auth        AuthConfig     `yaml:"auth"`
-	Wallet      WalletConfig   `yaml:"wallet"`
-	Payments    PaymentsConfig `yaml:"payments"`
-	Scanning    ScanningConfig `yaml:"scanning"`
-	Logging     LoggingConfig  `yaml:"logging"`
-	Stats       UsageStats     `yaml:"stats"`
-	CA          CAConfig       `yaml:"ca"`
-	Installed   bool           `yaml:"installed"`
-	InstallDate string         `yaml:"install_date,omitempty"`
+	Version       int                 `yaml:"version"`
+	Proxy         ProxyConfig         `yaml:"proxy"`
+	API           APIConfig           `yaml:"api"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Wallet        WalletConfig        `yaml:"wallet"`
+	Payments      PaymentsConfig      `yaml:"payments"`
+	Scanning      ScanningConfig      `yaml:"scanning"`
+	Logging       LoggingConfig       `yaml:"logging"`
+	Stats         UsageStats          `yaml:"stats"`
+	CA            CAConfig            `yaml:"ca"`
+	Installed     bool                `yaml:"installed"`
+	InstallDate   string              `yaml:"install_date,omitempty"`
+	InstallResume *InstallResume      `yaml:"install_resume,omitempty"`
+	BlockResponse BlockResponseConfig `yaml:"block_response,omitempty"`
+	Bypass        BypassConfig        `yaml:"bypass,omitempty"`
+	Policies      PolicyConfig        `yaml:"policies,omitempty"`
+	DNS           DNSConfig           `yaml:"dns,omitempty"`
+	RPC           RPCConfig           `yaml:"rpc,omitempty"`
+	Resources     ResourcesConfig     `yaml:"resources,omitempty"`
+	Watchdog      WatchdogConfig      `yaml:"watchdog,omitempty"`
+	Sync          ConfigSyncState     `yaml:"sync,omitempty"`       // Version of the account's synced config last pushed or pulled
+	Encryption    string              `yaml:"encryption,omitempty"` // Key source for encrypted secrets: "file" or "keychain"; empty stores them in plaintext
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() *CLIConfig {
 	homeDir, _ := os.UserHomeDir()
 
-	apiEndpoint := "https://api.getstronghold.xyz"
+	apiEndpoint := configschema.Endpoints{"https://api.getstronghold.xyz"}
 	if envURL := os.Getenv("STRONGHOLD_API_URL"); envURL != "" {
-		apiEndpoint = envURL
+		apiEndpoint = configschema.ParseEndpoints(envURL)
 	}
 
 	return &CLIConfig{
@@ -191,34 +460,37 @@ func LoadConfig() (*CLIConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Upgrade older layouts before decoding; the original is backed up
+	data, migrated, err := configschema.MigrateFile(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+	if migrated != nil && migrated.Backup != "" {
+		fmt.Fprintf(os.Stderr, "Migrated %s from config version %d to %d (original saved to %s)\n",
+			configPath, migrated.From, migrated.To, migrated.Backup)
+	}
+
+	data, err = configsecret.Decrypt(data, configPath)
+	if err != nil {
+		return nil, err
+	}
+
 	var config CLIConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
-
-	// Migrate if needed
-	if config.Version == "" {
+	if config.Version < ConfigVersion {
 		config.Version = ConfigVersion
 	}
 
-	// Apply defaults for new ScanTypeConfig fields if not set
-	applyDefaultScanTypeConfig(&config.Scanning.Content)
-	applyDefaultScanTypeConfig(&config.Scanning.Output)
+	// Configured RPC endpoints serve every command's on-chain lookups
+	for network, urls := range config.RPC.URLs() {
+		wallet.Providers().SetURLs(network, urls)
+	}
 
 	return &config, nil
 }
 
-// applyDefaultScanTypeConfig sets default values for ScanTypeConfig if not already set
-func applyDefaultScanTypeConfig(cfg *ScanTypeConfig) {
-	// If all fields are zero values, this is a new/uninitialized config
-	// We check ActionOnWarn since Enabled=false could be intentional
-	if cfg.ActionOnWarn == "" {
-		cfg.Enabled = true
-		cfg.ActionOnWarn = "warn"
-		cfg.ActionOnBlock = "block"
-	}
-}
-
 // Save saves the configuration to disk
 func (c *CLIConfig) Save() error {
 	configDir := ConfigDir()
@@ -240,16 +512,48 @@ func (c *CLIConfig) Save() error {
 	}
 
 	configPath := ConfigPath()
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
+	if c.Encryption != "" {
+		key, err := configsecret.LoadKey(c.Encryption, configPath)
+		if err != nil {
+			return err
+		}
+		if data, err = configsecret.Encrypt(data, key); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file beside path and renames it
+// into place, so readers never see a partly written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // GetProxyAddr returns the proxy address
 func (c *CLIConfig) GetProxyAddr() string {
-	return fmt.Sprintf("%s:%d", c.Proxy.Bind, c.Proxy.Port)
+	return net.JoinHostPort(c.Proxy.Bind, strconv.Itoa(c.Proxy.Port))
 }
 
 // GetProxyURL returns the proxy URL for environment variables
@@ -259,8 +563,7 @@ func (c *CLIConfig) GetProxyURL() string {
 
 // IsPortAvailable checks if the configured port is available
 func (c *CLIConfig) IsPortAvailable() bool {
-	addr := fmt.Sprintf("%s:%d", c.Proxy.Bind, c.Proxy.Port)
-	return IsPortAvailable(addr)
+	return IsPortAvailable(c.GetProxyAddr())
 }
 
 // ResetDailyStats resets daily statistics if needed
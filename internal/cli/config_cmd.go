@@ -2,10 +2,15 @@ package cli
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"stronghold/internal/configschema"
+	"stronghold/internal/configsecret"
+	"stronghold/internal/wallet"
 )
 
 // ConfigGet retrieves a configuration value by key using dot notation
@@ -48,6 +53,8 @@ func ConfigGet(key string) error {
 		fmt.Printf("mode: %s\n", v.Mode)
 		fmt.Printf("block_threshold: %.2f\n", v.BlockThreshold)
 		fmt.Printf("fail_open: %v\n", v.FailOpen)
+		fmt.Printf("encrypt: %v\n", v.Encrypt)
+		fmt.Printf("local_only: %v\n", v.LocalOnly)
 		fmt.Println("content:")
 		fmt.Printf("  enabled: %v\n", v.Content.Enabled)
 		fmt.Printf("  action_on_warn: %s\n", v.Content.ActionOnWarn)
@@ -82,6 +89,53 @@ func ConfigSet(key, value string) error {
 	return nil
 }
 
+// ConfigEncrypt encrypts the secret fields of the config file at rest,
+// keeping the key in a file next to the config or in the OS keychain
+func ConfigEncrypt(source string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Encryption != "" && config.Encryption != source {
+		return fmt.Errorf("config is already encrypted with a %s key; run 'stronghold config decrypt' first", config.Encryption)
+	}
+
+	if _, err := configsecret.CreateKey(source, ConfigPath()); err != nil {
+		return err
+	}
+	config.Encryption = source
+	if err := config.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("Encrypted %s in %s\n", strings.Join(configsecret.Fields, ", "), ConfigPath())
+	if source == configsecret.SourceFile {
+		fmt.Printf("Key: %s (keep a backup; the values can't be recovered without it)\n", configsecret.KeyPath(ConfigPath()))
+	} else {
+		fmt.Println("Key: OS keychain")
+	}
+	return nil
+}
+
+// ConfigDecrypt stores the config file's secret fields in plaintext again
+func ConfigDecrypt() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Encryption == "" {
+		fmt.Println("Config is not encrypted")
+		return nil
+	}
+
+	config.Encryption = ""
+	if err := config.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Decrypted secrets in %s\n", ConfigPath())
+	return nil
+}
+
 // getConfigValue retrieves a value from the config using dot notation
 func getConfigValue(config *CLIConfig, key string) (interface{}, error) {
 	parts := strings.Split(key, ".")
@@ -107,6 +161,44 @@ func getConfigValue(config *CLIConfig, key string) (interface{}, error) {
 			return config.Logging, nil
 		}
 		return getLoggingValue(&config.Logging, parts[1:])
+	case "block_response":
+		if len(parts) == 1 {
+			return config.BlockResponse, nil
+		}
+		return getBlockResponseValue(&config.BlockResponse, parts[1:])
+	case "dns":
+		if len(parts) == 1 {
+			return config.DNS, nil
+		}
+		return getDNSValue(&config.DNS, parts[1:])
+	case "resources":
+		if len(parts) == 1 {
+			return config.Resources, nil
+		}
+		return getResourcesValue(&config.Resources, parts[1])
+	case "watchdog":
+		if len(parts) == 1 {
+			return config.Watchdog, nil
+		}
+		return getWatchdogValue(config, parts[1])
+	case "rpc":
+		if len(parts) == 1 {
+			return config.RPC, nil
+		}
+		return getRPCValue(&config.RPC, parts[1:])
+	case "wallet":
+		if len(parts) >= 2 && parts[1] == "presign" {
+			return getPresignValue(&config.Wallet.Presign, parts[2:])
+		}
+		if len(parts) < 2 || parts[1] != "autopay" {
+			return nil, fmt.Errorf("only wallet.autopay and wallet.presign are available here; use 'stronghold wallet' for wallet settings")
+		}
+		return getAutoPayValue(&config.Wallet.AutoPay, parts[2:])
+	case "payments":
+		if len(parts) < 2 || parts[1] != "policy" {
+			return nil, fmt.Errorf("only payments.policy is available here")
+		}
+		return getSpendPolicyValue(&config.Payments.Policy, parts[2:])
 	default:
 		return nil, fmt.Errorf("unknown config key: %s", key)
 	}
@@ -124,6 +216,8 @@ func getScanningValue(scanning *ScanningConfig, parts []string) (interface{}, er
 		return scanning.BlockThreshold, nil
 	case "fail_open":
 		return scanning.FailOpen, nil
+	case "encrypt":
+		return scanning.Encrypt, nil
 	case "content":
 		if len(parts) == 1 {
 			return scanning.Content, nil
@@ -134,11 +228,87 @@ func getScanningValue(scanning *ScanningConfig, parts []string) (interface{}, er
 			return scanning.Output, nil
 		}
 		return getScanTypeValue(&scanning.Output, parts[1:])
+	case "limits":
+		if len(parts) == 1 {
+			return scanning.Limits, nil
+		}
+		return getScanLimitsValue(&scanning.Limits, parts[1:])
+	case "headers":
+		if len(parts) == 1 {
+			return scanning.Headers, nil
+		}
+		return getHeaderScanValue(&scanning.Headers, parts[1])
+	case "offline":
+		if len(parts) == 1 {
+			return scanning.Offline, nil
+		}
+		return getOfflineQueueValue(&scanning.Offline, parts[1])
+	case "local_only":
+		return scanning.LocalOnly, nil
+	case "local":
+		if len(parts) == 1 {
+			return scanning.Local, nil
+		}
+		return getLocalScanValue(&scanning.Local, parts[1])
 	default:
 		return nil, fmt.Errorf("unknown scanning key: %s", parts[0])
 	}
 }
 
+func getLocalScanValue(local *LocalScanConfig, key string) (interface{}, error) {
+	switch key {
+	case "api_fallback":
+		return local.APIFallback, nil
+	case "rule_packs":
+		return local.RulePacks, nil
+	default:
+		return nil, fmt.Errorf("unknown local key: %s", key)
+	}
+}
+
+func getOfflineQueueValue(offline *OfflineQueueConfig, key string) (interface{}, error) {
+	switch key {
+	case "enabled":
+		return offline.Enabled, nil
+	case "max_entries":
+		return offline.MaxEntries, nil
+	case "max_bytes":
+		return offline.MaxBytes, nil
+	case "retry_interval":
+		return offline.RetryInterval.String(), nil
+	default:
+		return nil, fmt.Errorf("unknown offline key: %s", key)
+	}
+}
+
+func getHeaderScanValue(headers *HeaderScanConfig, key string) (interface{}, error) {
+	switch key {
+	case "enabled":
+		return headers.Enabled, nil
+	case "action":
+		return headers.Action, nil
+	case "allowlist":
+		return headers.Allowlist, nil
+	default:
+		return nil, fmt.Errorf("unknown headers key: %s", key)
+	}
+}
+
+func getScanLimitsValue(limits *ScanLimitsConfig, parts []string) (interface{}, error) {
+	switch parts[0] {
+	case "max_body_size":
+		return limits.MaxBodySize, nil
+	case "chunked":
+		return limits.Chunked, nil
+	case "chunk_size":
+		return limits.ChunkSize, nil
+	case "chunk_overlap":
+		return limits.ChunkOverlap, nil
+	default:
+		return nil, fmt.Errorf("unknown limits key: %s", parts[0])
+	}
+}
+
 func getScanTypeValue(scanType *ScanTypeConfig, parts []string) (interface{}, error) {
 	if len(parts) == 0 {
 		return *scanType, nil
@@ -166,11 +336,37 @@ func getProxyValue(proxy *ProxyConfig, parts []string) (interface{}, error) {
 		return proxy.Port, nil
 	case "bind":
 		return proxy.Bind, nil
+	case "ipv6":
+		return proxy.IPv6Enabled(), nil
+	case "quic":
+		return proxy.QUICMode(), nil
+	case "pool":
+		if len(parts) == 1 {
+			return proxy.Pool, nil
+		}
+		return getPoolValue(&proxy.Pool, parts[1])
+	case "max_inflight_scans":
+		return proxy.MaxInflightScans, nil
+	case "max_inflight_per_host":
+		return proxy.MaxInflightPerHost, nil
 	default:
 		return nil, fmt.Errorf("unknown proxy key: %s", parts[0])
 	}
 }
 
+func getPoolValue(pool *PoolConfig, key string) (interface{}, error) {
+	switch key {
+	case "max_idle_conns":
+		return pool.MaxIdleConns, nil
+	case "max_idle_conns_per_host":
+		return pool.MaxIdleConnsPerHost, nil
+	case "idle_conn_timeout":
+		return pool.IdleConnTimeout.String(), nil
+	default:
+		return nil, fmt.Errorf("unknown pool key: %s", key)
+	}
+}
+
 func getAPIValue(api *APIConfig, parts []string) (interface{}, error) {
 	if len(parts) == 0 {
 		return *api, nil
@@ -178,7 +374,7 @@ func getAPIValue(api *APIConfig, parts []string) (interface{}, error) {
 
 	switch parts[0] {
 	case "endpoint":
-		return api.Endpoint, nil
+		return api.Endpoint.String(), nil
 	case "timeout":
 		return api.Timeout.String(), nil
 	default:
@@ -186,6 +382,59 @@ func getAPIValue(api *APIConfig, parts []string) (interface{}, error) {
 	}
 }
 
+func getAutoPayValue(autopay *AutoPayConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *autopay, nil
+	}
+
+	switch parts[0] {
+	case "enabled":
+		return autopay.Enabled, nil
+	case "hosts":
+		return autopay.Hosts, nil
+	default:
+		return nil, fmt.Errorf("unknown autopay key: %s", parts[0])
+	}
+}
+
+func getPresignValue(presign *PresignConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *presign, nil
+	}
+
+	switch parts[0] {
+	case "enabled":
+		return presign.Enabled, nil
+	case "pool_size":
+		return presign.PoolSize, nil
+	case "max_age":
+		return presign.MaxAge.String(), nil
+	default:
+		return nil, fmt.Errorf("unknown presign key: %s", parts[0])
+	}
+}
+
+func getSpendPolicyValue(policy *SpendPolicyConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *policy, nil
+	}
+
+	switch parts[0] {
+	case "max_per_request":
+		return policy.MaxPerRequest, nil
+	case "max_per_hour":
+		return policy.MaxPerHour, nil
+	case "max_per_day":
+		return policy.MaxPerDay, nil
+	case "domains":
+		return policy.Domains, nil
+	case "notify_url":
+		return policy.NotifyURL, nil
+	default:
+		return nil, fmt.Errorf("unknown payments.policy key: %s", parts[0])
+	}
+}
+
 func getLoggingValue(logging *LoggingConfig, parts []string) (interface{}, error) {
 	if len(parts) == 0 {
 		return *logging, nil
@@ -196,11 +445,128 @@ func getLoggingValue(logging *LoggingConfig, parts []string) (interface{}, error
 		return logging.Level, nil
 	case "file":
 		return logging.File, nil
+	case "disk_budget":
+		return logging.DiskBudget, nil
+	case "rotation":
+		if len(parts) == 1 {
+			return logging.Rotation, nil
+		}
+		return getLogRotationValue(&logging.Rotation, parts[1])
 	default:
 		return nil, fmt.Errorf("unknown logging key: %s", parts[0])
 	}
 }
 
+func getLogRotationValue(rotation *LogRotationConfig, key string) (interface{}, error) {
+	switch key {
+	case "max_bytes":
+		return rotation.MaxBytes, nil
+	case "interval":
+		return rotation.Interval.String(), nil
+	case "max_backups":
+		return rotation.MaxBackups, nil
+	case "max_age":
+		return rotation.MaxAge.String(), nil
+	case "compress":
+		return rotation.Compress == nil || *rotation.Compress, nil
+	default:
+		return nil, fmt.Errorf("unknown rotation key: %s", key)
+	}
+}
+
+func getBlockResponseValue(block *BlockResponseConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *block, nil
+	}
+
+	switch parts[0] {
+	case "format":
+		if block.Format == "" {
+			return "json", nil
+		}
+		return block.Format, nil
+	case "json_template":
+		return block.JSONTemplate, nil
+	case "html_file":
+		return block.HTMLFile, nil
+	case "retry_after":
+		return block.RetryAfter.String(), nil
+	default:
+		return nil, fmt.Errorf("unknown block_response key: %s", parts[0])
+	}
+}
+
+func getDNSValue(dns *DNSConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *dns, nil
+	}
+
+	switch parts[0] {
+	case "enabled":
+		return dns.Enabled, nil
+	case "listen":
+		if dns.Listen == "" {
+			return "127.0.0.1:5353", nil
+		}
+		return dns.Listen, nil
+	case "upstream":
+		return dns.Upstream, nil
+	case "denylist":
+		return dns.Denylist, nil
+	case "block_newer_than":
+		return dns.BlockNewerThan.String(), nil
+	default:
+		return nil, fmt.Errorf("unknown dns key: %s", parts[0])
+	}
+}
+
+func getResourcesValue(resources *ResourcesConfig, key string) (interface{}, error) {
+	switch key {
+	case "max_memory":
+		return resources.MaxMemory, nil
+	case "max_cpu":
+		return resources.MaxCPU, nil
+	case "shed_policy":
+		if resources.ShedPolicy == "" {
+			return "low_risk", nil
+		}
+		return resources.ShedPolicy, nil
+	case "low_risk_types":
+		return resources.LowRiskTypes, nil
+	default:
+		return nil, fmt.Errorf("unknown resources key: %s", key)
+	}
+}
+
+func getWatchdogValue(config *CLIConfig, key string) (interface{}, error) {
+	switch key {
+	case "policy":
+		return config.Watchdog.policy(config.Scanning.FailOpen), nil
+	case "max_restarts":
+		return config.Watchdog.maxRestarts(), nil
+	case "window":
+		return config.Watchdog.window().String(), nil
+	default:
+		return nil, fmt.Errorf("unknown watchdog key: %s", key)
+	}
+}
+
+func getRPCValue(rpc *RPCConfig, parts []string) (interface{}, error) {
+	if len(parts) == 0 {
+		return *rpc, nil
+	}
+
+	network, ok := rpcConfigNetworks[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown rpc key: %s", parts[0])
+	}
+	urls := rpc.URLs()[network]
+	if len(urls) == 0 {
+		return wallet.DefaultRPCURLs(network), nil
+	}
+	return urls, nil
+}
+
 // setConfigValue sets a value in the config using dot notation
 func setConfigValue(config *CLIConfig, key, value string) error {
 	parts := strings.Split(key, ".")
@@ -226,6 +592,44 @@ func setConfigValue(config *CLIConfig, key, value string) error {
 			return fmt.Errorf("cannot set entire logging section, specify a sub-key")
 		}
 		return setLoggingValue(&config.Logging, parts[1:], value)
+	case "block_response":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire block_response section, specify a sub-key")
+		}
+		return setBlockResponseValue(&config.BlockResponse, parts[1:], value)
+	case "dns":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire dns section, specify a sub-key")
+		}
+		return setDNSValue(&config.DNS, parts[1:], value)
+	case "resources":
+		if len(parts) != 2 {
+			return fmt.Errorf("cannot set entire resources section, specify a sub-key (max_memory, max_cpu, shed_policy)")
+		}
+		return setResourcesValue(&config.Resources, parts[1], value)
+	case "watchdog":
+		if len(parts) != 2 {
+			return fmt.Errorf("cannot set entire watchdog section, specify a sub-key (policy, max_restarts, window)")
+		}
+		return setWatchdogValue(&config.Watchdog, parts[1], value)
+	case "rpc":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire rpc section, specify a sub-key")
+		}
+		return setRPCValue(&config.RPC, parts[1:], value)
+	case "wallet":
+		if len(parts) == 3 && parts[1] == "presign" {
+			return setPresignValue(&config.Wallet.Presign, parts[2], value)
+		}
+		if len(parts) < 3 || parts[1] != "autopay" {
+			return fmt.Errorf("only wallet.autopay and wallet.presign sub-keys can be set here (enabled, pool_size, max_age)")
+		}
+		return setAutoPayValue(&config.Wallet.AutoPay, parts[2], value)
+	case "payments":
+		if len(parts) < 3 || parts[1] != "policy" {
+			return fmt.Errorf("only payments.policy sub-keys can be set here (max_per_request, max_per_hour, max_per_day, notify_url)")
+		}
+		return setSpendPolicyValue(&config.Payments.Policy, parts[2], value)
 	default:
 		return fmt.Errorf("unknown config key: %s", key)
 	}
@@ -238,8 +642,8 @@ func setScanningValue(scanning *ScanningConfig, parts []string, value string) er
 
 	switch parts[0] {
 	case "mode":
-		if value != "smart" && value != "strict" && value != "permissive" {
-			return fmt.Errorf("invalid mode: %s (must be smart, strict, or permissive)", value)
+		if value != "smart" && value != "strict" && value != "permissive" && value != "shadow" {
+			return fmt.Errorf("invalid mode: %s (must be smart, strict, permissive, or shadow)", value)
 		}
 		scanning.Mode = value
 	case "block_threshold":
@@ -257,6 +661,12 @@ func setScanningValue(scanning *ScanningConfig, parts []string, value string) er
 			return fmt.Errorf("invalid fail_open: %s (must be true or false)", value)
 		}
 		scanning.FailOpen = b
+	case "encrypt":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid encrypt: %s (must be true or false)", value)
+		}
+		scanning.Encrypt = b
 	case "content":
 		if len(parts) < 2 {
 			return fmt.Errorf("cannot set entire content section, specify a sub-key (enabled, action_on_warn, action_on_block)")
@@ -267,6 +677,32 @@ func setScanningValue(scanning *ScanningConfig, parts []string, value string) er
 			return fmt.Errorf("cannot set entire output section, specify a sub-key (enabled, action_on_warn, action_on_block)")
 		}
 		return setScanTypeValue(&scanning.Output, parts[1:], value)
+	case "limits":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire limits section, specify a sub-key (max_body_size, chunked, chunk_size, chunk_overlap)")
+		}
+		return setScanLimitsValue(&scanning.Limits, parts[1], value)
+	case "headers":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire headers section, specify a sub-key (enabled, action)")
+		}
+		return setHeaderScanValue(&scanning.Headers, parts[1], value)
+	case "offline":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire offline section, specify a sub-key (enabled, max_entries, max_bytes, retry_interval)")
+		}
+		return setOfflineQueueValue(&scanning.Offline, parts[1], value)
+	case "local_only":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid local_only: %s (must be true or false)", value)
+		}
+		scanning.LocalOnly = b
+	case "local":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire local section, specify a sub-key (api_fallback)")
+		}
+		return setLocalScanValue(&scanning.Local, parts[1], value)
 	default:
 		return fmt.Errorf("unknown scanning key: %s", parts[0])
 	}
@@ -274,6 +710,105 @@ func setScanningValue(scanning *ScanningConfig, parts []string, value string) er
 	return nil
 }
 
+func setHeaderScanValue(headers *HeaderScanConfig, key, value string) error {
+	switch key {
+	case "enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid enabled: %s (must be true or false)", value)
+		}
+		headers.Enabled = b
+	case "action":
+		if value != "block" && value != "redact" && value != "warn" {
+			return fmt.Errorf("invalid action: %s (must be block, redact, or warn)", value)
+		}
+		headers.Action = value
+	case "allowlist":
+		return fmt.Errorf("scanning.headers.allowlist is a list; edit it in %s", ConfigPath())
+	default:
+		return fmt.Errorf("unknown headers key: %s", key)
+	}
+	return nil
+}
+
+func setLocalScanValue(local *LocalScanConfig, key, value string) error {
+	switch key {
+	case "api_fallback":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid api_fallback: %s (must be true or false)", value)
+		}
+		local.APIFallback = b
+	case "rule_packs":
+		return fmt.Errorf("scanning.local.rule_packs is a list; edit it in %s", ConfigPath())
+	default:
+		return fmt.Errorf("unknown local key: %s", key)
+	}
+	return nil
+}
+
+func setOfflineQueueValue(offline *OfflineQueueConfig, key, value string) error {
+	switch key {
+	case "enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid enabled: %s (must be true or false)", value)
+		}
+		offline.Enabled = b
+	case "max_entries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_entries: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		offline.MaxEntries = n
+	case "max_bytes":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_bytes: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		offline.MaxBytes = n
+	case "retry_interval":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid retry_interval: %s (must be a duration like 30s)", value)
+		}
+		offline.RetryInterval = d
+	default:
+		return fmt.Errorf("unknown offline key: %s", key)
+	}
+	return nil
+}
+
+func setScanLimitsValue(limits *ScanLimitsConfig, key, value string) error {
+	if key == "chunked" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid chunked: %s (must be true or false)", value)
+		}
+		limits.Chunked = b
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid %s: %s (must be a size in bytes, 0 for the default)", key, value)
+	}
+	switch key {
+	case "max_body_size":
+		limits.MaxBodySize = n
+	case "chunk_size":
+		if n > 500*1024 {
+			return fmt.Errorf("invalid chunk_size: %s (the scan API accepts at most 512000 bytes)", value)
+		}
+		limits.ChunkSize = n
+	case "chunk_overlap":
+		limits.ChunkOverlap = n
+	default:
+		return fmt.Errorf("unknown limits key: %s", key)
+	}
+	return nil
+}
+
 func setScanTypeValue(scanType *ScanTypeConfig, parts []string, value string) error {
 	if len(parts) == 0 {
 		return fmt.Errorf("missing scan type sub-key")
@@ -320,6 +855,32 @@ func setProxyValue(proxy *ProxyConfig, parts []string, value string) error {
 		proxy.Port = p
 	case "bind":
 		proxy.Bind = value
+	case "ipv6":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid ipv6: %s (must be true or false)", value)
+		}
+		proxy.IPv6 = &b
+	case "quic":
+		if value != QUICBlock && value != QUICLog && value != QUICAllow {
+			return fmt.Errorf("invalid quic: %s (must be block, log, or allow)", value)
+		}
+		proxy.QUIC = value
+	case "pool":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire pool section, specify a sub-key (max_idle_conns, max_idle_conns_per_host, idle_conn_timeout)")
+		}
+		return setPoolValue(&proxy.Pool, parts[1], value)
+	case "max_inflight_scans", "max_inflight_per_host":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s: %s (must be a non-negative number, 0 for the default)", parts[0], value)
+		}
+		if parts[0] == "max_inflight_scans" {
+			proxy.MaxInflightScans = n
+		} else {
+			proxy.MaxInflightPerHost = n
+		}
 	default:
 		return fmt.Errorf("unknown proxy key: %s", parts[0])
 	}
@@ -327,6 +888,31 @@ func setProxyValue(proxy *ProxyConfig, parts []string, value string) error {
 	return nil
 }
 
+func setPoolValue(pool *PoolConfig, key, value string) error {
+	if key == "idle_conn_timeout" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid idle_conn_timeout: %s (must be a duration like 90s)", value)
+		}
+		pool.IdleConnTimeout = d
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid %s: %s (must be a non-negative number, 0 for the default)", key, value)
+	}
+	switch key {
+	case "max_idle_conns":
+		pool.MaxIdleConns = n
+	case "max_idle_conns_per_host":
+		pool.MaxIdleConnsPerHost = n
+	default:
+		return fmt.Errorf("unknown pool key: %s", key)
+	}
+	return nil
+}
+
 func setAPIValue(api *APIConfig, parts []string, value string) error {
 	if len(parts) == 0 {
 		return fmt.Errorf("missing api sub-key")
@@ -334,7 +920,7 @@ func setAPIValue(api *APIConfig, parts []string, value string) error {
 
 	switch parts[0] {
 	case "endpoint":
-		api.Endpoint = value
+		api.Endpoint = configschema.ParseEndpoints(value)
 	default:
 		return fmt.Errorf("unknown api key: %s", parts[0])
 	}
@@ -342,6 +928,76 @@ func setAPIValue(api *APIConfig, parts []string, value string) error {
 	return nil
 }
 
+func setAutoPayValue(autopay *AutoPayConfig, key, value string) error {
+	switch key {
+	case "enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid enabled: %s (must be true or false)", value)
+		}
+		autopay.Enabled = b
+	case "hosts":
+		return fmt.Errorf("wallet.autopay.hosts is a list; edit it in %s", ConfigPath())
+	default:
+		return fmt.Errorf("unknown autopay key: %s", key)
+	}
+	return nil
+}
+
+func setPresignValue(presign *PresignConfig, key, value string) error {
+	switch key {
+	case "enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid enabled: %s (must be true or false)", value)
+		}
+		presign.Enabled = b
+	case "pool_size":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid pool_size: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		presign.PoolSize = n
+	case "max_age":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || d > 4*time.Minute {
+			return fmt.Errorf("invalid max_age: %s (must be a duration up to 4m, e.g. 60s)", value)
+		}
+		presign.MaxAge = d
+	default:
+		return fmt.Errorf("unknown presign key: %s", key)
+	}
+	return nil
+}
+
+func setSpendPolicyValue(policy *SpendPolicyConfig, key, value string) error {
+	switch key {
+	case "max_per_request", "max_per_hour", "max_per_day":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid %s: %s (must be a USDC amount, 0 for the default)", key, value)
+		}
+		switch key {
+		case "max_per_request":
+			policy.MaxPerRequest = f
+		case "max_per_hour":
+			policy.MaxPerHour = f
+		default:
+			policy.MaxPerDay = f
+		}
+	case "domains":
+		return fmt.Errorf("payments.policy.domains is a list; edit it in %s", ConfigPath())
+	case "notify_url":
+		if value != "" && !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("invalid notify_url: %s (must be an http or https URL)", value)
+		}
+		policy.NotifyURL = value
+	default:
+		return fmt.Errorf("unknown payments.policy key: %s", key)
+	}
+	return nil
+}
+
 func setLoggingValue(logging *LoggingConfig, parts []string, value string) error {
 	if len(parts) == 0 {
 		return fmt.Errorf("missing logging sub-key")
@@ -355,9 +1011,216 @@ func setLoggingValue(logging *LoggingConfig, parts []string, value string) error
 		logging.Level = value
 	case "file":
 		logging.File = value
+	case "disk_budget":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid disk_budget: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		logging.DiskBudget = n
+	case "rotation":
+		if len(parts) < 2 {
+			return fmt.Errorf("cannot set entire rotation section, specify a sub-key (max_bytes, interval, max_backups, max_age, compress)")
+		}
+		return setLogRotationValue(&logging.Rotation, parts[1], value)
 	default:
 		return fmt.Errorf("unknown logging key: %s", parts[0])
 	}
 
 	return nil
 }
+
+func setLogRotationValue(rotation *LogRotationConfig, key, value string) error {
+	switch key {
+	case "max_bytes":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_bytes: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		rotation.MaxBytes = n
+	case "max_backups":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_backups: %s (must be a non-negative integer, 0 for the default)", value)
+		}
+		rotation.MaxBackups = n
+	case "interval", "max_age":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid %s: %s (must be a duration like 24h, 0 to turn it off)", key, value)
+		}
+		if key == "interval" {
+			rotation.Interval = d
+		} else {
+			rotation.MaxAge = d
+		}
+	case "compress":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid compress: %s (must be true or false)", value)
+		}
+		rotation.Compress = &b
+	default:
+		return fmt.Errorf("unknown rotation key: %s", key)
+	}
+	return nil
+}
+
+func setBlockResponseValue(block *BlockResponseConfig, parts []string, value string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("missing block_response sub-key")
+	}
+
+	switch parts[0] {
+	case "format":
+		if value != "json" && value != "html" && value != "auto" && value != "retry" {
+			return fmt.Errorf("invalid format: %s (must be json, html, auto, or retry)", value)
+		}
+		block.Format = value
+	case "json_template":
+		block.JSONTemplate = value
+	case "html_file":
+		block.HTMLFile = value
+	case "retry_after":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid retry_after: %s (must be a duration like 30s)", value)
+		}
+		block.RetryAfter = d
+	default:
+		return fmt.Errorf("unknown block_response key: %s", parts[0])
+	}
+
+	return nil
+}
+
+func setDNSValue(dns *DNSConfig, parts []string, value string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("missing dns sub-key")
+	}
+
+	switch parts[0] {
+	case "enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid enabled: %s (must be true or false)", value)
+		}
+		dns.Enabled = b
+	case "listen":
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return fmt.Errorf("invalid listen: %s (must be host:port)", value)
+		}
+		dns.Listen = value
+	case "block_newer_than":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid block_newer_than: %s (must be a duration like 720h)", value)
+		}
+		dns.BlockNewerThan = d
+	case "upstream", "denylist":
+		return fmt.Errorf("dns.%s is a list; edit it in %s", parts[0], ConfigPath())
+	default:
+		return fmt.Errorf("unknown dns key: %s", parts[0])
+	}
+
+	return nil
+}
+
+func setResourcesValue(resources *ResourcesConfig, key, value string) error {
+	switch key {
+	case "max_memory":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid max_memory: %s (must be a non-negative number of bytes, 0 for unlimited)", value)
+		}
+		resources.MaxMemory = n
+	case "max_cpu":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("invalid max_cpu: %s (must be a non-negative number of cores, 0 for unlimited)", value)
+		}
+		resources.MaxCPU = f
+	case "shed_policy":
+		switch value {
+		case "low_risk", "fail_open", "fail_closed":
+			resources.ShedPolicy = value
+		default:
+			return fmt.Errorf("invalid shed_policy: %s (must be low_risk, fail_open, or fail_closed)", value)
+		}
+	case "low_risk_types":
+		return fmt.Errorf("resources.low_risk_types is a list; edit it in %s", ConfigPath())
+	default:
+		return fmt.Errorf("unknown resources key: %s", key)
+	}
+
+	return nil
+}
+
+func setWatchdogValue(watchdog *WatchdogConfig, key, value string) error {
+	switch key {
+	case "policy":
+		switch value {
+		case WatchdogFailOpen, WatchdogFailClosed, WatchdogOff:
+			watchdog.Policy = value
+		default:
+			return fmt.Errorf("invalid policy: %s (must be fail_open, fail_closed, or off)", value)
+		}
+	case "max_restarts":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid max_restarts: %s (must be a positive number)", value)
+		}
+		watchdog.MaxRestarts = n
+	case "window":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid window: %s (must be a positive duration, e.g. 5m)", value)
+		}
+		watchdog.Window = d
+	default:
+		return fmt.Errorf("unknown watchdog key: %s", key)
+	}
+
+	return nil
+}
+
+// rpcConfigNetworks maps rpc config keys to network names
+var rpcConfigNetworks = map[string]string{
+	"base":          "base",
+	"base_sepolia":  "base-sepolia",
+	"solana":        "solana",
+	"solana_devnet": "solana-devnet",
+}
+
+func setRPCValue(rpc *RPCConfig, parts []string, value string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("missing rpc sub-key")
+	}
+	if _, ok := rpcConfigNetworks[parts[0]]; !ok {
+		return fmt.Errorf("unknown rpc key: %s", parts[0])
+	}
+
+	// A comma-separated list in order of preference; empty restores the default
+	var urls []string
+	for _, u := range strings.Split(value, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+			return fmt.Errorf("invalid rpc.%s URL: %s (must start with http:// or https://)", parts[0], u)
+		}
+		urls = append(urls, u)
+	}
+
+	switch parts[0] {
+	case "base":
+		rpc.Base = urls
+	case "base_sepolia":
+		rpc.BaseSepolia = urls
+	case "solana":
+		rpc.Solana = urls
+	case "solana_devnet":
+		rpc.SolanaDevnet = urls
+	}
+	return nil
+}
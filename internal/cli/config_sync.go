@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"stronghold/internal/configsecret"
+	"stronghold/internal/secure"
+	"stronghold/internal/wallet"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSyncKeyPurpose derives the key synced configuration is encrypted with
+// from the account's EVM wallet, which every machine on the account holds
+const configSyncKeyPurpose = "stronghold config sync v1"
+
+var errConfigSyncConflict = errors.New("the synced config was changed by another machine; run 'stronghold config sync pull' first, or 'stronghold config sync push --force' to overwrite it")
+
+// ConfigSyncState records the synced config version this machine last
+// pushed or pulled
+type ConfigSyncState struct {
+	Version int64 `yaml:"version,omitempty"`
+}
+
+// syncedConfig is the part of the config shared between an account's
+// machines: scanning preferences, policies and allowlists. Machine-specific
+// settings (ports, paths, RPCs) and credentials stay local.
+type syncedConfig struct {
+	Scanning      ScanningConfig      `yaml:"scanning"`
+	Policies      PolicyConfig        `yaml:"policies,omitempty"`
+	BlockResponse BlockResponseConfig `yaml:"block_response,omitempty"`
+	SpendPolicy   SpendPolicyConfig   `yaml:"spend_policy,omitempty"`
+	AutoPayHosts  []string            `yaml:"autopay_hosts,omitempty"`
+}
+
+func syncedFrom(c *CLIConfig) syncedConfig {
+	return syncedConfig{
+		Scanning:      c.Scanning,
+		Policies:      c.Policies,
+		BlockResponse: c.BlockResponse,
+		SpendPolicy:   c.Payments.Policy,
+		AutoPayHosts:  c.Wallet.AutoPay.Hosts,
+	}
+}
+
+func (s syncedConfig) applyTo(c *CLIConfig) {
+	c.Scanning = s.Scanning
+	c.Policies = s.Policies
+	c.BlockResponse = s.BlockResponse
+	c.Payments.Policy = s.SpendPolicy
+	c.Wallet.AutoPay.Hosts = s.AutoPayHosts
+}
+
+// sealSyncedConfig encrypts s for upload as base64
+func sealSyncedConfig(key []byte, s syncedConfig) (string, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode synced config: %w", err)
+	}
+	sealed, err := configsecret.Seal(key, string(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt synced config: %w", err)
+	}
+	return strings.TrimPrefix(sealed, configsecret.Prefix), nil
+}
+
+// openSyncedConfig decrypts a synced config downloaded from the API
+func openSyncedConfig(key []byte, data string) (syncedConfig, error) {
+	var s syncedConfig
+	plaintext, err := configsecret.Open(key, data)
+	if err != nil {
+		return s, fmt.Errorf("failed to decrypt synced config (was it pushed with another wallet?): %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(plaintext), &s); err != nil {
+		return s, fmt.Errorf("failed to decode synced config: %w", err)
+	}
+	return s, nil
+}
+
+// configSyncKey derives the sync key from this machine's EVM wallet
+func configSyncKey(config *CLIConfig) (*secure.SecretBytes, error) {
+	w, err := wallet.New(wallet.Config{UserID: config.Auth.UserID, Network: config.Wallet.Network})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wallet: %w", err)
+	}
+	if !w.Exists() {
+		return nil, fmt.Errorf("config sync is encrypted with the account's EVM wallet, which isn't on this machine; run 'stronghold init' to log in")
+	}
+	return w.DeriveKey(configSyncKeyPurpose)
+}
+
+// pushSyncedConfig uploads config's synced settings on top of the version
+// this machine last synced, or on top of whatever is stored with force
+func pushSyncedConfig(api *APIClient, config *CLIConfig, key []byte, force bool) (*ConfigSync, error) {
+	data, err := sealSyncedConfig(key, syncedFrom(config))
+	if err != nil {
+		return nil, err
+	}
+
+	base := config.Sync.Version
+	if force {
+		remote, err := api.GetConfigSync()
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			base = 0
+		case err != nil:
+			return nil, fmt.Errorf("failed to get synced config: %w", err)
+		default:
+			base = remote.Version
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	result, err := api.PutConfigSync(&PutConfigSyncRequest{BaseVersion: base, Data: data, Hostname: hostname})
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return nil, errConfigSyncConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload synced config: %w", err)
+	}
+	config.Sync.Version = result.Version
+	return result, nil
+}
+
+// pullSyncedConfig replaces config's synced settings with the account's. It
+// returns nil when nothing has been synced.
+func pullSyncedConfig(api *APIClient, config *CLIConfig, key []byte) (*ConfigSync, error) {
+	remote, err := api.GetConfigSync()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get synced config: %w", err)
+	}
+
+	s, err := openSyncedConfig(key, remote.Data)
+	if err != nil {
+		return nil, err
+	}
+	s.applyTo(config)
+	config.Sync.Version = remote.Version
+	return remote, nil
+}
+
+// ConfigSyncPush uploads this machine's scanning settings, policies and
+// allowlists, encrypted so only the account's machines can read them
+func ConfigSyncPush(force bool) error {
+	apiClient, config, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+	key, err := configSyncKey(config)
+	if err != nil {
+		return err
+	}
+	defer key.Zero()
+
+	result, err := pushSyncedConfig(apiClient, config, key.Bytes(), force)
+	if err != nil {
+		return err
+	}
+	if err := config.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Pushed synced config version %d\n", result.Version)
+	return nil
+}
+
+// ConfigSyncPull replaces this machine's scanning settings, policies and
+// allowlists with the ones last pushed from any of the account's machines
+func ConfigSyncPull() error {
+	apiClient, config, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+	key, err := configSyncKey(config)
+	if err != nil {
+		return err
+	}
+	defer key.Zero()
+
+	remote, err := pullSyncedConfig(apiClient, config, key.Bytes())
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		fmt.Println("Nothing has been synced yet; run 'stronghold config sync push' on the machine to copy from")
+		return nil
+	}
+	if err := config.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	from := ""
+	if remote.UpdatedBy != nil {
+		from = " from " + *remote.UpdatedBy
+	}
+	fmt.Printf("Pulled synced config version %d%s\n", remote.Version, from)
+	fmt.Println("Run 'stronghold restart' for the proxy to apply it")
+	return nil
+}
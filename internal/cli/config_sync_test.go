@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeConfigSyncAPI stores synced config like the API, refusing stale writes
+func fakeConfigSyncAPI(t *testing.T) *httptest.Server {
+	var stored *ConfigSync
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "No configuration has been synced"})
+				return
+			}
+			json.NewEncoder(w).Encode(stored)
+		case http.MethodPut:
+			var req PutConfigSyncRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("invalid upload: %v", err)
+				return
+			}
+			var current int64
+			if stored != nil {
+				current = stored.Version
+			}
+			if req.BaseVersion != current {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Configuration was changed by another machine"})
+				return
+			}
+			stored = &ConfigSync{Version: current + 1, Data: req.Data, UpdatedBy: &req.Hostname}
+			json.NewEncoder(w).Encode(stored)
+		}
+	}))
+}
+
+func TestConfigSync_PushAndPull(t *testing.T) {
+	server := fakeConfigSyncAPI(t)
+	defer server.Close()
+	api := NewAPIClient(server.URL, "")
+	key := make([]byte, 32)
+
+	laptop := DefaultConfig()
+	if remote, err := pullSyncedConfig(api, laptop, key); err != nil || remote != nil {
+		t.Fatalf("expected nothing to pull, got %v, %v", remote, err)
+	}
+
+	laptop.Scanning.Mode = "shadow"
+	laptop.Policies.ExpectedHosts = []string{"api.openai.com"}
+	laptop.Wallet.AutoPay.Hosts = []string{"*.example.com"}
+	laptop.Proxy.Port = 9000
+	if _, err := pushSyncedConfig(api, laptop, key, false); err != nil {
+		t.Fatalf("pushSyncedConfig() returned error: %v", err)
+	}
+	if laptop.Sync.Version != 1 {
+		t.Errorf("expected version 1 recorded, got %d", laptop.Sync.Version)
+	}
+
+	desktop := DefaultConfig()
+	if _, err := pullSyncedConfig(api, desktop, key); err != nil {
+		t.Fatalf("pullSyncedConfig() returned error: %v", err)
+	}
+	if desktop.Scanning.Mode != "shadow" || len(desktop.Policies.ExpectedHosts) != 1 || len(desktop.Wallet.AutoPay.Hosts) != 1 {
+		t.Errorf("synced settings not applied: %+v", syncedFrom(desktop))
+	}
+	if desktop.Proxy.Port == 9000 {
+		t.Error("machine-specific settings must not sync")
+	}
+
+	// The desktop pushes; the laptop's next push is based on a stale version
+	desktop.Scanning.BlockThreshold = 0.9
+	if _, err := pushSyncedConfig(api, desktop, key, false); err != nil {
+		t.Fatalf("pushSyncedConfig() returned error: %v", err)
+	}
+	if _, err := pushSyncedConfig(api, laptop, key, false); err != errConfigSyncConflict {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if result, err := pushSyncedConfig(api, laptop, key, true); err != nil || result.Version != 3 {
+		t.Fatalf("expected a forced push to succeed, got %v, %v", result, err)
+	}
+
+	other := make([]byte, 32)
+	other[0] = 1
+	if _, err := pullSyncedConfig(api, desktop, other); err == nil || !strings.Contains(err.Error(), "another wallet") {
+		t.Errorf("expected a decryption error with another key, got %v", err)
+	}
+}
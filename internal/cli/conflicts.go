@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// localProxy is a proxy or interception tool that may run alongside
+// Stronghold
+type localProxy struct {
+	Name      string
+	Processes []string // Lowercase executable names, matched as prefixes
+	MITM      bool     // Decrypts TLS with its own CA
+}
+
+// knownLocalProxies are the tools detected as conflicting with Stronghold
+var knownLocalProxies = []localProxy{
+	{Name: "mitmproxy", Processes: []string{"mitmproxy", "mitmdump", "mitmweb"}, MITM: true},
+	{Name: "Burp Suite", Processes: []string{"burpsuite", "burp suite"}, MITM: true},
+	{Name: "OWASP ZAP", Processes: []string{"zap.sh", "zaproxy", "owasp zap"}, MITM: true},
+	{Name: "Charles", Processes: []string{"charles"}, MITM: true},
+	{Name: "Fiddler", Processes: []string{"fiddler"}, MITM: true},
+	{Name: "Proxyman", Processes: []string{"proxyman"}, MITM: true},
+	{Name: "Squid", Processes: []string{"squid"}},
+	{Name: "Privoxy", Processes: []string{"privoxy"}},
+	{Name: "tinyproxy", Processes: []string{"tinyproxy"}},
+}
+
+// proxyEnvVars are the environment variables clients take a proxy from
+var proxyEnvVars = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy"}
+
+// proxyConflict is something on this machine that also proxies or
+// intercepts traffic
+type proxyConflict struct {
+	Source string // What was found
+	Detail string // Why it conflicts
+}
+
+var listProcessesFunc = listProcesses
+
+// listProcesses returns the executable names of running processes
+func listProcesses() ([]string, error) {
+	output, err := exec.Command("ps", "-A", "-o", "comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, filepath.Base(line))
+		}
+	}
+	return names, nil
+}
+
+// detectProxyConflicts finds known proxy tools among processes, and proxy
+// environment variables that send traffic somewhere other than Stronghold's
+// proxy at ownAddr. Either means traffic is proxied twice: the other tool's
+// upstream connections are intercepted again by Stronghold.
+func detectProxyConflicts(processes []string, getenv func(string) string, ownAddr string) []proxyConflict {
+	var conflicts []proxyConflict
+
+	for _, tool := range knownLocalProxies {
+		if !processRunning(processes, tool.Processes) {
+			continue
+		}
+		detail := "its upstream traffic is intercepted again by Stronghold"
+		if tool.MITM {
+			detail = "double TLS interception: it must trust the Stronghold CA, and Stronghold scans what it re-encrypts"
+		}
+		conflicts = append(conflicts, proxyConflict{Source: tool.Name + " is running", Detail: detail})
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range proxyEnvVars {
+		value := getenv(name)
+		if value == "" || seen[value] || isOwnProxy(value, ownAddr) {
+			continue
+		}
+		seen[value] = true
+		conflicts = append(conflicts, proxyConflict{
+			Source: fmt.Sprintf("%s=%s", name, value),
+			Detail: "clients send traffic through that proxy before Stronghold intercepts it",
+		})
+	}
+
+	return conflicts
+}
+
+// processRunning reports whether any process name starts with one of names
+func processRunning(processes, names []string) bool {
+	for _, process := range processes {
+		process = strings.ToLower(process)
+		for _, name := range names {
+			if strings.HasPrefix(process, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isOwnProxy reports whether a proxy URL points at Stronghold's proxy
+func isOwnProxy(proxyURL, ownAddr string) bool {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return false
+	}
+	_, ownPort, _ := net.SplitHostPort(ownAddr)
+	return u.Port() == ownPort && isLoopbackHost(u.Hostname())
+}
+
+// isLoopbackHost reports whether host names this machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// localProxyConflicts detects proxy conflicts on this machine
+func localProxyConflicts(config *CLIConfig) []proxyConflict {
+	processes, _ := listProcessesFunc()
+	return detectProxyConflicts(processes, os.Getenv, config.GetProxyAddr())
+}
+
+var portHolderFunc = portHolder
+
+// portHolder names the process listening on a TCP port, or returns "" if it
+// cannot be found
+func portHolder(port int) string {
+	switch runtime.GOOS {
+	case "linux":
+		output, err := exec.Command("ss", "-Hltnp", "sport = :"+strconv.Itoa(port)).Output()
+		if err != nil {
+			return ""
+		}
+		return parseSSProcess(string(output))
+	case "darwin":
+		output, err := exec.Command("lsof", "-nP", "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN", "-Fc").Output()
+		if err != nil {
+			return ""
+		}
+		return parseLsofCommand(string(output))
+	default:
+		return ""
+	}
+}
+
+// parseSSProcess extracts the process name from `ss -p` output, e.g.
+// users:(("mitmdump",pid=123,fd=5))
+func parseSSProcess(output string) string {
+	_, rest, ok := strings.Cut(output, `users:(("`)
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, `"`)
+	return name
+}
+
+// parseLsofCommand extracts the command name from `lsof -Fc` output
+func parseLsofCommand(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if name, ok := strings.CutPrefix(line, "c"); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// describePortHolder says what holds the proxy port
+func describePortHolder(port int) string {
+	if holder := portHolderFunc(port); holder != "" {
+		return fmt.Sprintf("Port %d is held by %s", port, holder)
+	}
+	return fmt.Sprintf("Port %d is in use", port)
+}
+
+// checkProxyConflicts warns about other proxies and interception tools that
+// would proxy traffic twice
+func checkProxyConflicts() CheckResult {
+	result := CheckResult{Name: "Proxy Conflicts"}
+
+	config, err := LoadConfig()
+	if err != nil {
+		config = DefaultConfig()
+	}
+
+	conflicts := localProxyConflicts(config)
+	if len(conflicts) == 0 {
+		result.Status = CheckPass
+		result.Message = "No other local proxies found"
+		return result
+	}
+
+	parts := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		parts[i] = fmt.Sprintf("%s (%s)", c.Source, c.Detail)
+	}
+	result.Status = CheckWarn
+	result.Message = strings.Join(parts, "; ")
+	result.Fix = "Stop the other proxy or unset the proxy variables while Stronghold is enabled"
+	return result
+}
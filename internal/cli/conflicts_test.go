@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDetectProxyConflicts(t *testing.T) {
+	env := map[string]string{
+		"HTTPS_PROXY": "http://127.0.0.1:8080",
+		"https_proxy": "http://127.0.0.1:8080", // Same proxy, reported once
+		"HTTP_PROXY":  "localhost:8402",        // Stronghold's own proxy
+	}
+	processes := []string{"sshd", "mitmdump", "Squid", "bash"}
+
+	conflicts := detectProxyConflicts(processes, func(k string) string { return env[k] }, "127.0.0.1:8402")
+	if len(conflicts) != 3 {
+		t.Fatalf("expected 3 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].Source != "mitmproxy is running" || !strings.Contains(conflicts[0].Detail, "double TLS interception") {
+		t.Errorf("unexpected mitmproxy conflict: %+v", conflicts[0])
+	}
+	if conflicts[1].Source != "Squid is running" || strings.Contains(conflicts[1].Detail, "TLS") {
+		t.Errorf("unexpected Squid conflict: %+v", conflicts[1])
+	}
+	if conflicts[2].Source != "HTTPS_PROXY=http://127.0.0.1:8080" {
+		t.Errorf("unexpected env conflict: %+v", conflicts[2])
+	}
+
+	if got := detectProxyConflicts([]string{"zsh"}, func(string) string { return "" }, "127.0.0.1:8402"); len(got) != 0 {
+		t.Errorf("expected no conflicts, got %+v", got)
+	}
+}
+
+func TestParsePortHolder(t *testing.T) {
+	ss := `LISTEN 0      4096   127.0.0.1:8402   0.0.0.0:*    users:(("mitmdump",pid=4242,fd=7))`
+	if got := parseSSProcess(ss); got != "mitmdump" {
+		t.Errorf("parseSSProcess() = %q", got)
+	}
+	if got := parseSSProcess("LISTEN 0 4096 127.0.0.1:8402 0.0.0.0:*"); got != "" {
+		t.Errorf("expected no process without -p details, got %q", got)
+	}
+	if got := parseLsofCommand("p4242\ncCharles\n"); got != "Charles" {
+		t.Errorf("parseLsofCommand() = %q", got)
+	}
+}
+
+func TestMergeEnvFile(t *testing.T) {
+	got := mergeEnvFile("STRONGHOLD_PROXY_PORT=8402\nHTTP_TIMEOUT=30\n", map[string]string{
+		"STRONGHOLD_PROXY_PORT": "8403",
+		"STRONGHOLD_PROXY_BIND": "127.0.0.1",
+	})
+	want := "STRONGHOLD_PROXY_PORT=8403\nHTTP_TIMEOUT=30\nSTRONGHOLD_PROXY_BIND=127.0.0.1\n"
+	if got != want {
+		t.Fatalf("mergeEnvFile() = %q, want %q", got, want)
+	}
+}
+
+func TestReserveProxyPort_MovesOffTakenPort(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	envPath := filepath.Join(t.TempDir(), "stronghold-proxy.env")
+	orig := serviceEnvPathFunc
+	defer func() { serviceEnvPathFunc = orig }()
+	serviceEnvPathFunc = func() string { return envPath }
+
+	// Another process took the port after init chose it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	taken := listener.Addr().(*net.TCPAddr).Port
+
+	config := DefaultConfig()
+	config.Proxy.Bind = "127.0.0.1"
+	config.Proxy.Port = taken
+
+	movedFrom, err := reserveProxyPort(config)
+	if err != nil {
+		t.Fatalf("reserveProxyPort() returned error: %v", err)
+	}
+	if movedFrom != taken || config.Proxy.Port == taken {
+		t.Fatalf("expected to move off port %d, got port %d (moved from %d)", taken, config.Proxy.Port, movedFrom)
+	}
+
+	env, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatalf("expected the port to be registered with the service: %v", err)
+	}
+	if !strings.Contains(string(env), "STRONGHOLD_PROXY_PORT="+strconv.Itoa(config.Proxy.Port)+"\n") {
+		t.Errorf("unexpected service environment:\n%s", env)
+	}
+
+	saved, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if saved.Proxy.Port != config.Proxy.Port {
+		t.Errorf("expected config to record port %d, got %d", config.Proxy.Port, saved.Proxy.Port)
+	}
+}
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// loginForDevices logs in for managing trusted devices, verifying this
+// machine with TOTP first if the account requires it.
+func loginForDevices() (*APIClient, *CLIConfig, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !config.Auth.LoggedIn || config.Auth.AccountNumber == "" {
+		fmt.Println(accountErrorStyle.Render("✗ Not logged in"))
+		fmt.Println(accountInfoStyle.Render("Run 'stronghold init' to set up your account"))
+		return nil, nil, nil
+	}
+
+	apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
+	loginResp, err := apiClient.Login(config.Auth.AccountNumber)
+	if err != nil {
+		return nil, nil, fmt.Errorf("login failed: %w", err)
+	}
+	if err := ensureTrustedDevice(apiClient, config, loginResp.TOTPRequired); err != nil {
+		return nil, nil, fmt.Errorf("TOTP verification failed: %w", err)
+	}
+	if err := config.Save(); err != nil {
+		return nil, nil, fmt.Errorf("failed to save config: %w", err)
+	}
+	return apiClient, config, nil
+}
+
+// DeviceList shows the account's trusted devices.
+func DeviceList() error {
+	apiClient, _, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+
+	resp, err := apiClient.ListDevices()
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if len(resp.Devices) == 0 {
+		fmt.Println(accountInfoStyle.Render("No trusted devices"))
+		return nil
+	}
+
+	fmt.Println(accountTitleStyle.Render("Trusted devices"))
+	for _, d := range resp.Devices {
+		name := deviceDisplayName(d.Name, d.Label)
+		if resp.CurrentDeviceID != nil && *resp.CurrentDeviceID == d.ID {
+			name += " (this device)"
+		}
+		fmt.Printf("  %s  %s\n", d.ID, name)
+
+		if machine := deviceMachine(d.Hostname, d.OS); machine != "" {
+			fmt.Printf("      %s\n", machine)
+		}
+		lastSeen := "never"
+		if d.LastSeenAt != nil {
+			lastSeen = formatDeviceTime(*d.LastSeenAt)
+			if d.LastSeenIP != nil {
+				lastSeen += " from " + *d.LastSeenIP
+			}
+		}
+		fmt.Printf("      last seen %s\n", lastSeen)
+		expires := "never expires"
+		if d.ExpiresAt != nil {
+			expires = "expires " + formatDeviceTime(*d.ExpiresAt)
+		}
+		fmt.Printf("      trusted %s, %s\n", formatDeviceTime(d.CreatedAt), expires)
+	}
+	fmt.Println()
+	fmt.Println(accountInfoStyle.Render("Name a device with 'stronghold device rename <id> <name>', revoke with 'stronghold device revoke <id>'"))
+	return nil
+}
+
+// DeviceRename names a trusted device. An empty name clears it.
+func DeviceRename(id, name string) error {
+	apiClient, _, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+	if err := apiClient.RenameDevice(id, name); err != nil {
+		return fmt.Errorf("failed to rename device: %w", err)
+	}
+	fmt.Println(successStyle.Render("✓ Device renamed"))
+	return nil
+}
+
+// DeviceRevoke removes trust from a device. Revoking this machine also
+// forgets its device token.
+func DeviceRevoke(id string) error {
+	apiClient, config, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+
+	resp, err := apiClient.ListDevices()
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if err := apiClient.RevokeDevice(id); err != nil {
+		return fmt.Errorf("failed to revoke device: %w", err)
+	}
+	fmt.Println(successStyle.Render("✓ Device revoked"))
+
+	if resp.CurrentDeviceID != nil && *resp.CurrentDeviceID == id {
+		config.Auth.DeviceToken = ""
+		if err := config.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println(accountWarningStyle.Render("⚠ This machine is no longer trusted"))
+	}
+	return nil
+}
+
+func deviceDisplayName(name, label *string) string {
+	switch {
+	case name != nil && *name != "":
+		return *name
+	case label != nil && *label != "":
+		return *label
+	default:
+		return "unnamed device"
+	}
+}
+
+func deviceMachine(hostname, os *string) string {
+	switch {
+	case hostname != nil && os != nil:
+		return *hostname + " (" + *os + ")"
+	case hostname != nil:
+		return *hostname
+	case os != nil:
+		return *os
+	default:
+		return ""
+	}
+}
+
+func formatDeviceTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// loginTrustedDevice logs in and checks this machine is a trusted device, the
+// only kind allowed to decide device approvals.
+func loginTrustedDevice() (*APIClient, bool, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !config.Auth.LoggedIn || config.Auth.AccountNumber == "" {
+		fmt.Println(accountErrorStyle.Render("✗ Not logged in"))
+		fmt.Println(accountInfoStyle.Render("Run 'stronghold init' to set up your account"))
+		return nil, false, nil
+	}
+
+	if config.Auth.DeviceToken == "" {
+		printNotTrustedDevice()
+		return nil, false, nil
+	}
+
+	apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
+	if _, err := apiClient.Login(config.Auth.AccountNumber); err != nil {
+		return nil, false, fmt.Errorf("login failed: %w", err)
+	}
+	return apiClient, true, nil
+}
+
+func printNotTrustedDevice() {
+	fmt.Println(accountErrorStyle.Render("✗ This machine is not a trusted device"))
+	fmt.Println(accountInfoStyle.Render("Only trusted devices can approve other devices"))
+}
+
+// isNotTrustedDevice reports whether the server rejected this machine's
+// device token
+func isNotTrustedDevice(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// DeviceApprovals lists machines waiting to be trusted.
+func DeviceApprovals() error {
+	apiClient, ok, err := loginTrustedDevice()
+	if err != nil || !ok {
+		return err
+	}
+
+	approvals, err := apiClient.ListDeviceApprovals()
+	if err != nil {
+		if isNotTrustedDevice(err) {
+			printNotTrustedDevice()
+			return nil
+		}
+		return fmt.Errorf("failed to list device approvals: %w", err)
+	}
+	if len(approvals) == 0 {
+		fmt.Println(accountInfoStyle.Render("No pending device approvals"))
+		return nil
+	}
+
+	fmt.Println(accountTitleStyle.Render("Pending device approvals"))
+	for _, a := range approvals {
+		fmt.Printf("  %s  %s\n", a.ID, deviceDisplayName(nil, a.Label))
+		if machine := deviceMachine(a.Hostname, a.OS); machine != "" {
+			fmt.Printf("      %s\n", machine)
+		}
+		if a.RequestedIP != nil {
+			fmt.Printf("      from %s, requested %s\n", *a.RequestedIP, formatDeviceTime(a.CreatedAt))
+		}
+	}
+	fmt.Println()
+	fmt.Println(accountInfoStyle.Render("Approve with 'stronghold device approve <id>' or deny with 'stronghold device deny <id>'"))
+	return nil
+}
+
+// DeviceApprove trusts the machine that made an approval request.
+func DeviceApprove(id string) error {
+	apiClient, ok, err := loginTrustedDevice()
+	if err != nil || !ok {
+		return err
+	}
+	if err := apiClient.ApproveDevice(id); err != nil {
+		if isNotTrustedDevice(err) {
+			printNotTrustedDevice()
+			return nil
+		}
+		return fmt.Errorf("failed to approve device: %w", err)
+	}
+	fmt.Println(successStyle.Render("✓ Device approved"))
+	return nil
+}
+
+// DeviceDeny rejects an approval request.
+func DeviceDeny(id string) error {
+	apiClient, ok, err := loginTrustedDevice()
+	if err != nil || !ok {
+		return err
+	}
+	if err := apiClient.DenyDevice(id); err != nil {
+		if isNotTrustedDevice(err) {
+			printNotTrustedDevice()
+			return nil
+		}
+		return fmt.Errorf("failed to deny device: %w", err)
+	}
+	fmt.Println(successStyle.Render("✓ Device denied"))
+	return nil
+}
@@ -24,6 +24,12 @@ func Disable() error {
 	}
 
 	if !status.Running {
+		// A watchdog fail-safe outlives the proxy it stood in for
+		if loadFailsafeState() != nil {
+			NewTransparentProxy(config).Disable()
+			clearFailsafeState()
+			fmt.Println("✓ Watchdog fail-safe rules removed")
+		}
 		fmt.Println("Stronghold proxy is not running")
 		return nil
 	}
@@ -36,6 +42,7 @@ func Disable() error {
 		fmt.Printf("Warning: failed to disable transparent proxy: %v\n", err)
 	} else {
 		fmt.Println("✓ Transparent proxy disabled")
+		clearFailsafeState()
 	}
 
 	// Stop the proxy
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// defaultDiskBudget is how much Stronghold's data directory may hold before
+// doctor warns, unless logging.disk_budget is set
+const defaultDiskBudget = 500 * 1024 * 1024
+
+// dirSize returns the total size of the regular files under dir, skipping
+// anything it is not allowed to read
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// formatBytes renders a size in bytes with a binary unit, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// checkDiskUsage warns when Stronghold's data directory has outgrown its
+// disk budget, which usually means log retention is set too generously
+func checkDiskUsage() CheckResult {
+	result := CheckResult{Name: "Disk Usage"}
+
+	config, err := LoadConfig()
+	if err != nil {
+		config = DefaultConfig()
+	}
+	budget := config.Logging.DiskBudget
+	if budget <= 0 {
+		budget = defaultDiskBudget
+	}
+
+	dir := ConfigDir()
+	used, err := dirSize(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Status = CheckPass
+		result.Message = "Nothing stored yet"
+		return result
+	}
+	if err != nil {
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("Could not measure %s: %v", dir, err)
+		return result
+	}
+
+	if used <= budget {
+		result.Status = CheckPass
+		result.Message = fmt.Sprintf("%s of %s used in %s", formatBytes(used), formatBytes(budget), dir)
+		return result
+	}
+
+	logs, _ := dirSize(filepath.Join(dir, "logs"))
+	result.Status = CheckWarn
+	result.Message = fmt.Sprintf("%s is using %s, over its %s budget (logs: %s)", dir, formatBytes(used), formatBytes(budget), formatBytes(logs))
+	result.Fix = "Lower logging.rotation.max_backups, max_bytes or max_age, or raise logging.disk_budget"
+	return result
+}
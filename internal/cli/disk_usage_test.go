@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:               "512 B",
+		1536:              "1.5 KB",
+		500 * 1024 * 1024: "500.0 MB",
+		3 << 30:           "3.0 GB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCheckDiskUsage_OverBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	config := DefaultConfig()
+	config.Logging.DiskBudget = 4096
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if result := checkDiskUsage(); result.Status != CheckPass {
+		t.Fatalf("expected a config alone to fit the budget, got %s: %s", result.Status, result.Message)
+	}
+
+	logs := filepath.Join(ConfigDir(), "logs")
+	if err := os.MkdirAll(logs, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logs, "proxy.log.20261017T120000.000Z.gz"), make([]byte, 8192), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result := checkDiskUsage()
+	if result.Status != CheckWarn {
+		t.Fatalf("expected a warning over budget, got %s: %s", result.Status, result.Message)
+	}
+	if !strings.Contains(result.Message, "logs: 8.0 KB") || !strings.Contains(result.Fix, "logging.disk_budget") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -2,10 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"time"
 )
 
 // CheckResult represents the result of a single check
@@ -55,9 +58,15 @@ func Doctor() error {
 	results = append(results, checkRoot())
 	results = append(results, checkFirewallTools())
 	results = append(results, checkPortAvailable())
+	results = append(results, checkProxyConflicts())
+	results = append(results, checkIPv6Leak())
 	results = append(results, checkConfig())
 	results = append(results, checkProxyBinary())
 	results = append(results, checkCLIBinary())
+	results = append(results, checkServiceManager())
+	results = append(results, checkClock())
+	results = append(results, checkDiskUsage())
+	results = append(results, checkWatchdog())
 
 	if runtime.GOOS == "linux" {
 		results = append(results, checkKernelModules())
@@ -178,25 +187,101 @@ func checkFirewallTools() CheckResult {
 	return result
 }
 
-// checkPortAvailable checks if the proxy port is available
+// checkPortAvailable checks if the proxy port is available, or held by the
+// Stronghold proxy itself
 func checkPortAvailable() CheckResult {
 	result := CheckResult{Name: "Proxy Port Available"}
 
-	config := DefaultConfig()
+	config, err := LoadConfig()
+	if err != nil {
+		config = DefaultConfig()
+	}
 	addr := config.GetProxyAddr()
 
 	if IsPortAvailable(addr) {
 		result.Status = CheckPass
 		result.Message = fmt.Sprintf("Port %d is available", config.Proxy.Port)
+	} else if _, err := fetchProxyHealth(config); err == nil {
+		result.Status = CheckPass
+		result.Message = fmt.Sprintf("Port %d is held by the Stronghold proxy", config.Proxy.Port)
 	} else {
 		result.Status = CheckWarn
-		result.Message = fmt.Sprintf("Port %d is in use", config.Proxy.Port)
+		result.Message = describePortHolder(config.Proxy.Port)
 		result.Fix = fmt.Sprintf("Run 'stronghold init' to use an alternative port, or stop the process using port %d", config.Proxy.Port)
 	}
 
 	return result
 }
 
+// checkIPv6Leak checks that IPv6 traffic cannot bypass the proxy on hosts
+// with IPv6 connectivity
+func checkIPv6Leak() CheckResult {
+	result := CheckResult{Name: "IPv6 Coverage"}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil || !hasGlobalIPv6(addrs) {
+		result.Status = CheckPass
+		result.Message = "No global IPv6 address (no IPv6 leak path)"
+		return result
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		config = DefaultConfig()
+	}
+	tp := NewTransparentProxy(config)
+
+	if runtime.GOOS == "linux" && !tp.hasNftables() && !tp.hasIp6tables() {
+		result.Status = CheckFail
+		result.Message = "IPv6 is configured but neither nftables nor ip6tables is available; IPv6 traffic would bypass the proxy"
+		result.Fix = "Install nftables or ip6tables, or disable IPv6 with 'sudo sysctl -w net.ipv6.conf.all.disable_ipv6=1'"
+		return result
+	}
+
+	if enabled, _ := tp.Status(); enabled && !tp.ipv6Covered() {
+		result.Status = CheckFail
+		result.Message = "Transparent proxy is active but IPv6 traffic is not redirected"
+		result.Fix = "Run 'sudo stronghold disable && sudo stronghold enable' to install IPv6 rules"
+		return result
+	}
+
+	if !config.Proxy.IPv6Enabled() {
+		result.Status = CheckPass
+		result.Message = "IPv6 interception disabled; outbound IPv6 HTTP(S) is rejected"
+		return result
+	}
+
+	addr6 := net.JoinHostPort("::1", strconv.Itoa(config.Proxy.Port))
+	if status, _ := NewServiceManager(config).IsRunning(); status != nil && status.Running {
+		conn, err := net.DialTimeout("tcp6", addr6, 2*time.Second)
+		if err != nil {
+			result.Status = CheckWarn
+			result.Message = fmt.Sprintf("Proxy is not listening on %s; redirected IPv6 connections will fail", addr6)
+			result.Fix = "Check the proxy log for IPv6 listener errors, or set proxy.ipv6: false to reject IPv6 HTTP(S)"
+			return result
+		}
+		conn.Close()
+	}
+
+	result.Status = CheckPass
+	result.Message = "IPv6 traffic is redirected to the proxy"
+	return result
+}
+
+// hasGlobalIPv6 reports whether any address is a globally routable IPv6 address
+func hasGlobalIPv6(addrs []net.Addr) bool {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil {
+			continue
+		}
+		if ipNet.IP.IsGlobalUnicast() && !ipNet.IP.IsPrivate() {
+			return true
+		}
+	}
+	return false
+}
+
 // checkConfig checks if config can be loaded/created
 func checkConfig() CheckResult {
 	result := CheckResult{Name: "Configuration"}
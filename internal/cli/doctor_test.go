@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"net"
+	"testing"
+)
+
+func TestHasGlobalIPv6(t *testing.T) {
+	mustNet := func(s string) net.Addr {
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q): %v", s, err)
+		}
+		ipNet.IP = ip
+		return ipNet
+	}
+
+	tests := []struct {
+		name  string
+		addrs []net.Addr
+		want  bool
+	}{
+		{"ipv4 only", []net.Addr{mustNet("192.168.1.5/24"), mustNet("127.0.0.1/8")}, false},
+		{"loopback and link-local", []net.Addr{mustNet("::1/128"), mustNet("fe80::1/64")}, false},
+		{"unique local", []net.Addr{mustNet("fd00::5/64")}, false},
+		{"global", []net.Addr{mustNet("10.0.0.2/8"), mustNet("2001:db8::5/64")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasGlobalIPv6(tt.addrs); got != tt.want {
+				t.Errorf("hasGlobalIPv6() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -31,6 +31,15 @@ func Enable() error {
 	}
 
 	if status.Running {
+		// The watchdog may have pulled interception from a proxy that has
+		// since come back
+		if loadFailsafeState() != nil {
+			if err := restoreInterception(config); err != nil {
+				return err
+			}
+			fmt.Println("✓ Interception restored after the watchdog fail-safe")
+			return nil
+		}
 		fmt.Printf("Stronghold proxy is already running on port %d (PID: %d)\n", status.Port, status.PID)
 		return nil
 	}
@@ -56,6 +65,7 @@ func Enable() error {
 		serviceManager.Stop()
 		return fmt.Errorf("failed to enable transparent proxy: %w", err)
 	}
+	clearFailsafeState()
 
 	fmt.Println()
 	fmt.Println("✓ Stronghold proxy started successfully")
@@ -38,8 +38,9 @@ var (
 	rpcStatusFromLatencyFunc = rpcStatusFromLatency
 )
 
-// Health checks API and network RPC health.
-func Health() error {
+// Health checks API and network RPC health. Deep mode also compares RPC block
+// heights, checks the payment facilitators and measures clock skew.
+func Health(deep bool) error {
 	config, err := LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -47,23 +48,80 @@ func Health() error {
 
 	var wg sync.WaitGroup
 
-	var apiStatus endpointHealth
-	var baseStatus endpointHealth
-	var solanaStatus endpointHealth
-
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		apiStatus = checkAPIHealthFunc(config.API.Endpoint)
-	}()
-	go func() {
-		defer wg.Done()
-		baseStatus = checkBaseRPCFunc()
-	}()
-	go func() {
-		defer wg.Done()
-		solanaStatus = checkSolanaRPCFunc()
-	}()
+	// Every configured endpoint of the wallets' networks is checked, in the
+	// order payments would try them
+	baseNetwork := config.Wallet.Network
+	if baseNetwork == "" {
+		baseNetwork = "base"
+	}
+	solanaNetwork := config.Wallet.SolanaNetwork
+	if solanaNetwork == "" {
+		solanaNetwork = DefaultSolanaNetwork
+	}
+	baseURLs := wallet.Providers().URLs(baseNetwork)
+	solanaURLs := wallet.Providers().URLs(solanaNetwork)
+
+	apiURLs := config.API.Endpoint
+	apiStatus := make([]endpointHealth, len(apiURLs))
+	baseStatus := make([]endpointHealth, len(baseURLs))
+	solanaStatus := make([]endpointHealth, len(solanaURLs))
+
+	wg.Add(len(apiURLs))
+	for i, url := range apiURLs {
+		go func() {
+			defer wg.Done()
+			apiStatus[i] = checkAPIHealthFunc(url)
+		}()
+	}
+	if deep {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			baseStatus = deepRPCHealth(baseNetwork, baseURLs, probeBaseRPCFunc)
+		}()
+		go func() {
+			defer wg.Done()
+			solanaStatus = deepRPCHealth(solanaNetwork, solanaURLs, probeSolanaRPCFunc)
+		}()
+	} else {
+		wg.Add(len(baseURLs) + len(solanaURLs))
+		for i, url := range baseURLs {
+			go func() {
+				defer wg.Done()
+				baseStatus[i] = checkBaseRPCFunc(url)
+			}()
+		}
+		for i, url := range solanaURLs {
+			go func() {
+				defer wg.Done()
+				solanaStatus[i] = checkSolanaRPCFunc(url)
+			}()
+		}
+	}
+
+	// Facilitators are checked on the endpoints payments are verified and
+	// settled through
+	var facilitatorTargets []string
+	var facilitatorStatus []endpointHealth
+	var clockStatus endpointHealth
+	if deep {
+		for _, url := range facilitatorURLs(baseNetwork, solanaNetwork) {
+			base := strings.TrimRight(url, "/")
+			facilitatorTargets = append(facilitatorTargets, base+"/verify", base+"/settle")
+		}
+		facilitatorStatus = make([]endpointHealth, len(facilitatorTargets))
+		wg.Add(1 + len(facilitatorTargets))
+		for i, url := range facilitatorTargets {
+			go func() {
+				defer wg.Done()
+				facilitatorStatus[i] = checkFacilitatorFunc(url)
+			}()
+		}
+		go func() {
+			defer wg.Done()
+			clockStatus = checkClockSkewFunc(apiURLs.Primary())
+		}()
+	}
 	wg.Wait()
 
 	fmt.Println()
@@ -73,17 +131,47 @@ func Health() error {
 	fmt.Println()
 
 	fmt.Println("API:")
-	printHealthLine("Stronghold API", config.API.Endpoint, apiStatus)
+	for i, url := range apiURLs {
+		printHealthLine("Stronghold API", url, apiStatus[i])
+	}
+	printActiveRegion(config)
 	fmt.Println()
 
 	fmt.Println("RPC Networks:")
-	printHealthLine("Base", wallet.BaseMainnetRPC, baseStatus)
-	printHealthLine("Solana", wallet.SolanaMainnetRPC, solanaStatus)
+	for i, url := range baseURLs {
+		printHealthLine("Base", url, baseStatus[i])
+	}
+	for i, url := range solanaURLs {
+		printHealthLine("Solana", url, solanaStatus[i])
+	}
 	fmt.Println()
+
+	if deep {
+		fmt.Println("Facilitator:")
+		for i, url := range facilitatorTargets {
+			name := "Verify"
+			if strings.HasSuffix(url, "/settle") {
+				name = "Settle"
+			}
+			printHealthLine(name, url, facilitatorStatus[i])
+		}
+		fmt.Println()
+
+		fmt.Println("Clock:")
+		printHealthLine("Skew", apiURLs.Primary(), clockStatus)
+		fmt.Println()
+	}
+
 	fmt.Println("Legend:")
 	fmt.Println("  up        - endpoint responded normally")
 	fmt.Println("  congested - endpoint responded but latency exceeded threshold")
 	fmt.Println("  down      - endpoint did not respond before timeout")
+	if deep {
+		fmt.Println()
+		fmt.Println("  RPCs trailing the highest block height seen by 30s are congested,")
+		fmt.Println("  and down once 5m behind. Clock skew of 30s or more is congested and")
+		fmt.Println("  corrected for by the proxy; at 5m payments are rejected and it is down.")
+	}
 	fmt.Println()
 
 	return nil
@@ -152,12 +240,12 @@ func checkAPIHealth(baseURL string) endpointHealth {
 	}
 }
 
-func checkBaseRPC() endpointHealth {
+func checkBaseRPC(rpcURL string) endpointHealth {
 	ctx, cancel := context.WithTimeout(context.Background(), rpcCheckTimeout)
 	defer cancel()
 
 	start := time.Now()
-	client, err := ethclient.DialContext(ctx, wallet.BaseMainnetRPC)
+	client, err := ethclient.DialContext(ctx, rpcURL)
 	if err != nil {
 		return endpointHealth{
 			Status:  "down",
@@ -182,12 +270,12 @@ func checkBaseRPC() endpointHealth {
 	}
 }
 
-func checkSolanaRPC() endpointHealth {
+func checkSolanaRPC(rpcURL string) endpointHealth {
 	ctx, cancel := context.WithTimeout(context.Background(), rpcCheckTimeout)
 	defer cancel()
 
 	start := time.Now()
-	client := rpc.New(wallet.SolanaMainnetRPC)
+	client := rpc.New(rpcURL)
 	if _, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized); err != nil {
 		return endpointHealth{
 			Status:  "down",
@@ -210,6 +298,20 @@ func rpcStatusFromLatency(latency time.Duration) string {
 	return "up"
 }
 
+// printActiveRegion shows which of several regional endpoints the running
+// proxy has pinned scans to
+func printActiveRegion(config *CLIConfig) {
+	if len(config.API.Endpoint) < 2 {
+		return
+	}
+	health, err := fetchProxyHealth(config)
+	if err != nil || health.Regions == nil {
+		fmt.Printf("  %-13s %s\n", "Active:", "unknown (proxy not running)")
+		return
+	}
+	fmt.Printf("  %-13s %s\n", "Active:", health.Regions.Active)
+}
+
 func printHealthLine(name, target string, health endpointHealth) {
 	status := healthStatusText(health.Status)
 	fmt.Printf("  %-13s %s (%s)\n", name+":", status, target)
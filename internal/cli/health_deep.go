@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"stronghold/internal/wallet"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// deepLatencySamples is how many calls each RPC gets in deep mode; the
+	// median latency is reported
+	deepLatencySamples = 3
+	// An RPC trailing the highest height seen by this long is reported
+	// congested, and down as stuck past blockLagStuck
+	blockLagCongested       = 30 * time.Second
+	blockLagStuck           = 5 * time.Minute
+	facilitatorCheckTimeout = 5 * time.Second
+)
+
+// rpcProbe is an endpoint's latest block height (the slot on Solana) and
+// its median latency across deepLatencySamples calls
+type rpcProbe struct {
+	Height  uint64
+	Latency time.Duration
+	Err     error
+}
+
+var (
+	probeBaseRPCFunc     = probeBaseRPC
+	probeSolanaRPCFunc   = probeSolanaRPC
+	checkFacilitatorFunc = checkFacilitator
+	checkClockSkewFunc   = checkClockSkew
+)
+
+// blockTime is roughly how often a network produces a block
+func blockTime(network string) time.Duration {
+	if wallet.IsSolanaNetwork(network) {
+		return 400 * time.Millisecond
+	}
+	return 2 * time.Second
+}
+
+// heightUnit names a network's block height in output
+func heightUnit(network string) string {
+	if wallet.IsSolanaNetwork(network) {
+		return "slot"
+	}
+	return "block"
+}
+
+// deepRPCHealth probes every endpoint of a network alongside the network's
+// public RPCs, and reports each endpoint's median latency and how far its
+// height trails the highest one seen. A lone endpoint that is also the
+// public RPC has nothing to be compared against.
+func deepRPCHealth(network string, urls []string, probe func(string) rpcProbe) []endpointHealth {
+	targets := slices.Clone(urls)
+	for _, ref := range wallet.DefaultRPCURLs(network) {
+		if !slices.Contains(targets, ref) {
+			targets = append(targets, ref)
+		}
+	}
+
+	probes := make([]rpcProbe, len(targets))
+	var wg sync.WaitGroup
+	for i, url := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i] = probe(url)
+		}()
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, p := range probes {
+		if p.Err == nil {
+			best = max(best, p.Height)
+		}
+	}
+
+	unit := heightUnit(network)
+	health := make([]endpointHealth, len(urls))
+	for i := range urls {
+		p := probes[i]
+		if p.Err != nil {
+			health[i] = endpointHealth{Status: "down", Latency: p.Latency, Detail: p.Err.Error()}
+			continue
+		}
+		h := endpointHealth{
+			Status:  rpcStatusFromLatencyFunc(p.Latency),
+			Latency: p.Latency,
+			Detail:  fmt.Sprintf("%s %d", unit, p.Height),
+		}
+		if lag := best - p.Height; lag > 0 {
+			h.Detail += fmt.Sprintf(", %d behind the highest seen", lag)
+			switch behind := time.Duration(lag) * blockTime(network); {
+			case behind >= blockLagStuck:
+				h.Status = "down"
+				h.Detail = "stuck: " + h.Detail
+			case behind >= blockLagCongested:
+				h.Status = "congested"
+			}
+		}
+		health[i] = h
+	}
+	return health
+}
+
+// sampleLatency runs call deepLatencySamples times and returns the height
+// of the last call and the median latency
+func sampleLatency(ctx context.Context, call func(context.Context) (uint64, error)) rpcProbe {
+	latencies := make([]time.Duration, 0, deepLatencySamples)
+	var height uint64
+	for range deepLatencySamples {
+		start := time.Now()
+		h, err := call(ctx)
+		latency := time.Since(start)
+		if err != nil {
+			return rpcProbe{Latency: latency, Err: err}
+		}
+		height = h
+		latencies = append(latencies, latency)
+	}
+	slices.Sort(latencies)
+	return rpcProbe{Height: height, Latency: latencies[len(latencies)/2]}
+}
+
+func probeBaseRPC(rpcURL string) rpcProbe {
+	ctx, cancel := context.WithTimeout(context.Background(), deepLatencySamples*rpcCheckTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return rpcProbe{Err: err}
+	}
+	defer client.Close()
+	return sampleLatency(ctx, client.BlockNumber)
+}
+
+func probeSolanaRPC(rpcURL string) rpcProbe {
+	ctx, cancel := context.WithTimeout(context.Background(), deepLatencySamples*rpcCheckTimeout)
+	defer cancel()
+
+	client := rpc.New(rpcURL)
+	return sampleLatency(ctx, func(ctx context.Context) (uint64, error) {
+		return client.GetSlot(ctx, rpc.CommitmentConfirmed)
+	})
+}
+
+// facilitatorURLs returns the facilitators of the wallets' networks,
+// without duplicates
+func facilitatorURLs(networks ...string) []string {
+	var urls []string
+	for _, network := range networks {
+		cfg, ok := wallet.NetworkConfig(network)
+		if ok && cfg.FacilitatorURL != "" && !slices.Contains(urls, cfg.FacilitatorURL) {
+			urls = append(urls, cfg.FacilitatorURL)
+		}
+	}
+	return urls
+}
+
+// checkFacilitator posts an empty request to a facilitator endpoint. Any
+// response short of a server error means it is reachable; the empty request
+// itself is always rejected.
+func checkFacilitator(endpointURL string) endpointHealth {
+	ctx, cancel := context.WithTimeout(context.Background(), facilitatorCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return endpointHealth{Status: "down", Detail: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return endpointHealth{Status: "down", Latency: latency, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return endpointHealth{Status: "down", Latency: latency, Detail: fmt.Sprintf("HTTP %d", resp.StatusCode)}
+	}
+	return endpointHealth{Status: rpcStatusFromLatencyFunc(latency), Latency: latency}
+}
+
+// checkClockSkew compares the local clock with the API's
+func checkClockSkew(baseURL string) endpointHealth {
+	skew, latency, err := measureClockSkew(baseURL)
+	if err != nil {
+		return endpointHealth{Status: "down", Latency: latency, Detail: err.Error()}
+	}
+	return clockSkewHealth(skew)
+}
+
+// clockSkewHealth reports how far the local clock is ahead of (positive) or
+// behind (negative) the API's
+func clockSkewHealth(skew time.Duration) endpointHealth {
+	detail := describeClockSkew(skew)
+	switch abs := skew.Abs(); {
+	case abs < clockSkewWarning:
+		return endpointHealth{Status: "up", Detail: detail}
+	case abs < wallet.MaxClockCorrection:
+		return endpointHealth{Status: "congested", Detail: detail + "; the proxy corrects payment timestamps for it"}
+	default:
+		return endpointHealth{Status: "down", Detail: detail + "; payments will be rejected"}
+	}
+}
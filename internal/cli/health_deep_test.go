@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+func TestDeepRPCHealth_ComparesHeights(t *testing.T) {
+	heights := map[string]uint64{
+		"https://fresh.example":  1000,
+		"https://slow.example":   980,
+		"https://stuck.example":  800,
+		wallet.BaseMainnetRPC:    1001,
+		"https://broken.example": 0,
+	}
+	probe := func(url string) rpcProbe {
+		if url == "https://broken.example" {
+			return rpcProbe{Err: errors.New("connection refused")}
+		}
+		return rpcProbe{Height: heights[url], Latency: 100 * time.Millisecond}
+	}
+
+	urls := []string{"https://fresh.example", "https://slow.example", "https://stuck.example", "https://broken.example"}
+	health := deepRPCHealth("base", urls, probe)
+	if len(health) != len(urls) {
+		t.Fatalf("expected %d results, got %d", len(urls), len(health))
+	}
+
+	// The public RPC is the reference even though it is not configured
+	for i, want := range []string{"up", "congested", "down", "down"} {
+		if health[i].Status != want {
+			t.Errorf("%s: expected %q, got %q (%s)", urls[i], want, health[i].Status, health[i].Detail)
+		}
+	}
+	if health[0].Detail != "block 1000, 1 behind the highest seen" {
+		t.Errorf("unexpected detail: %q", health[0].Detail)
+	}
+	if !strings.HasPrefix(health[2].Detail, "stuck:") {
+		t.Errorf("expected a stuck detail, got %q", health[2].Detail)
+	}
+	if health[3].Detail != "connection refused" {
+		t.Errorf("unexpected detail: %q", health[3].Detail)
+	}
+}
+
+func TestCheckFacilitator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/verify":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	if res := checkFacilitator(server.URL + "/verify"); res.Status != "up" {
+		t.Fatalf("expected a rejected request to count as up, got %q", res.Status)
+	}
+	if res := checkFacilitator(server.URL + "/settle"); res.Status != "down" || res.Detail != "HTTP 502" {
+		t.Fatalf("expected down with HTTP 502, got %q (%s)", res.Status, res.Detail)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	res := checkClockSkew(server.URL)
+	if res.Status != "down" || !strings.Contains(res.Detail, "ahead of the API") {
+		t.Fatalf("expected a clock far ahead to be down, got %q (%s)", res.Status, res.Detail)
+	}
+
+	for _, tc := range []struct {
+		skew time.Duration
+		want string
+	}{
+		{0, "up"},
+		{-5 * time.Second, "up"},
+		{time.Minute, "congested"},
+		{-wallet.PaymentValidity, "down"},
+	} {
+		if got := clockSkewHealth(tc.skew); got.Status != tc.want {
+			t.Errorf("skew %s: expected %q, got %q (%s)", tc.skew, tc.want, got.Status, got.Detail)
+		}
+	}
+}
+
+func TestHealth_Deep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	origAPI := checkAPIHealthFunc
+	origBase := probeBaseRPCFunc
+	origSol := probeSolanaRPCFunc
+	origFacilitator := checkFacilitatorFunc
+	origClock := checkClockSkewFunc
+	defer func() {
+		checkAPIHealthFunc = origAPI
+		probeBaseRPCFunc = origBase
+		probeSolanaRPCFunc = origSol
+		checkFacilitatorFunc = origFacilitator
+		checkClockSkewFunc = origClock
+	}()
+
+	checkAPIHealthFunc = func(string) endpointHealth {
+		return endpointHealth{Status: "up"}
+	}
+	probeBaseRPCFunc = func(string) rpcProbe {
+		return rpcProbe{Height: 42, Latency: 80 * time.Millisecond}
+	}
+	probeSolanaRPCFunc = func(string) rpcProbe {
+		return rpcProbe{Height: 7, Latency: 90 * time.Millisecond}
+	}
+	var mu sync.Mutex
+	var facilitatorChecks []string
+	checkFacilitatorFunc = func(url string) endpointHealth {
+		mu.Lock()
+		defer mu.Unlock()
+		facilitatorChecks = append(facilitatorChecks, url)
+		return endpointHealth{Status: "up"}
+	}
+	checkClockSkewFunc = func(string) endpointHealth {
+		return clockSkewHealth(2 * time.Second)
+	}
+
+	out, err := captureStdout(t, func() error { return Health(true) })
+	if err != nil {
+		t.Fatalf("Health() returned error: %v", err)
+	}
+
+	// The Base and Solana networks share a facilitator, checked once
+	if len(facilitatorChecks) != 2 {
+		t.Fatalf("expected verify and settle to be checked once, got %v", facilitatorChecks)
+	}
+	for _, want := range []string{
+		"block 42",
+		"slot 7",
+		"Facilitator:",
+		"https://x402.org/facilitator/verify",
+		"https://x402.org/facilitator/settle",
+		"Clock:",
+		"local clock is 2s ahead of the API",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("health output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -3,12 +3,16 @@ package cli
 import (
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"stronghold/internal/configschema"
+	"stronghold/internal/wallet"
 )
 
 func captureStdout(t *testing.T, fn func() error) (string, error) {
@@ -94,14 +98,14 @@ func TestHealth_OutputIncludesRPCStatuses(t *testing.T) {
 	checkAPIHealthFunc = func(string) endpointHealth {
 		return endpointHealth{Status: "up", Latency: 10 * time.Millisecond}
 	}
-	checkBaseRPCFunc = func() endpointHealth {
+	checkBaseRPCFunc = func(string) endpointHealth {
 		return endpointHealth{Status: "congested", Latency: 2 * time.Second, Detail: "high latency"}
 	}
-	checkSolanaRPCFunc = func() endpointHealth {
+	checkSolanaRPCFunc = func(string) endpointHealth {
 		return endpointHealth{Status: "down", Latency: 5 * time.Second, Detail: "timeout"}
 	}
 
-	out, err := captureStdout(t, Health)
+	out, err := captureStdout(t, func() error { return Health(false) })
 	if err != nil {
 		t.Fatalf("Health() returned error: %v", err)
 	}
@@ -121,3 +125,135 @@ func TestHealth_OutputIncludesRPCStatuses(t *testing.T) {
 		}
 	}
 }
+
+func TestHealth_ChecksEveryConfiguredRPC(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := DefaultConfig()
+	config.RPC.Base = []string{"https://base-a.example", "https://base-b.example"}
+	if err := config.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	defer wallet.Providers().SetURLs("base", nil)
+
+	origAPI := checkAPIHealthFunc
+	origBase := checkBaseRPCFunc
+	origSol := checkSolanaRPCFunc
+	defer func() {
+		checkAPIHealthFunc = origAPI
+		checkBaseRPCFunc = origBase
+		checkSolanaRPCFunc = origSol
+	}()
+
+	checkAPIHealthFunc = func(string) endpointHealth {
+		return endpointHealth{Status: "up"}
+	}
+	checkBaseRPCFunc = func(url string) endpointHealth {
+		if url == "https://base-b.example" {
+			return endpointHealth{Status: "down", Detail: "429 Too Many Requests"}
+		}
+		return endpointHealth{Status: "up"}
+	}
+	checkSolanaRPCFunc = func(string) endpointHealth {
+		return endpointHealth{Status: "up"}
+	}
+
+	out, err := captureStdout(t, func() error { return Health(false) })
+	if err != nil {
+		t.Fatalf("Health() returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"https://base-a.example",
+		"https://base-b.example",
+		"429 Too Many Requests",
+		wallet.SolanaMainnetRPC,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("health output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHealth_ShowsEveryRegionAndTheActiveOne(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"regions": {"active": "https://eu.example", "endpoints": []}}`)
+	}))
+	defer proxy.Close()
+	addr := proxy.Listener.Addr().(*net.TCPAddr)
+
+	t.Setenv("HOME", t.TempDir())
+	config := DefaultConfig()
+	config.API.Endpoint = configschema.Endpoints{"https://us.example", "https://eu.example"}
+	config.Proxy.Bind = addr.IP.String()
+	config.Proxy.Port = addr.Port
+	if err := config.Save(); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	origAPI := checkAPIHealthFunc
+	origBase := checkBaseRPCFunc
+	origSol := checkSolanaRPCFunc
+	defer func() {
+		checkAPIHealthFunc = origAPI
+		checkBaseRPCFunc = origBase
+		checkSolanaRPCFunc = origSol
+	}()
+
+	checkBaseRPCFunc = func(string) endpointHealth { return endpointHealth{Status: "up"} }
+	checkSolanaRPCFunc = func(string) endpointHealth { return endpointHealth{Status: "up"} }
+	checkAPIHealthFunc = func(url string) endpointHealth {
+		if url == "https://us.example" {
+			return endpointHealth{Status: "down", Detail: "connection refused"}
+		}
+		return endpointHealth{Status: "up"}
+	}
+
+	out, err := captureStdout(t, func() error { return Health(false) })
+	if err != nil {
+		t.Fatalf("Health() returned error: %v", err)
+	}
+	for _, want := range []string{
+		"(https://us.example)",
+		"connection refused",
+		"(https://eu.example)",
+		"Active:       https://eu.example",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("health output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSetRPCValue(t *testing.T) {
+	config := DefaultConfig()
+
+	if err := setConfigValue(config, "rpc.solana_devnet", "https://a.example, https://b.example"); err != nil {
+		t.Fatalf("setConfigValue() returned error: %v", err)
+	}
+	if len(config.RPC.SolanaDevnet) != 2 || config.RPC.SolanaDevnet[1] != "https://b.example" {
+		t.Fatalf("unexpected solana_devnet endpoints: %v", config.RPC.SolanaDevnet)
+	}
+	if err := setConfigValue(config, "rpc.base", "mainnet.base.org"); err == nil {
+		t.Fatal("expected an error for a URL without a scheme")
+	}
+	if err := setConfigValue(config, "rpc.ethereum", "https://a.example"); err == nil {
+		t.Fatal("expected an error for an unknown network")
+	}
+
+	// An unset network reports its public endpoint
+	value, err := getConfigValue(config, "rpc.base")
+	if err != nil {
+		t.Fatalf("getConfigValue() returned error: %v", err)
+	}
+	if urls, ok := value.([]string); !ok || len(urls) != 1 || urls[0] != wallet.BaseMainnetRPC {
+		t.Fatalf("unexpected rpc.base value: %v", value)
+	}
+
+	if err := setConfigValue(config, "rpc.solana_devnet", ""); err != nil {
+		t.Fatalf("setConfigValue() returned error: %v", err)
+	}
+	if config.RPC.SolanaDevnet != nil {
+		t.Fatalf("expected solana_devnet to be reset, got %v", config.RPC.SolanaDevnet)
+	}
+}
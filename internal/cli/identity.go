@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"stronghold/internal/identity"
+)
+
+// InstallKeyPath returns the default location of this install's signing key
+func InstallKeyPath() string {
+	return filepath.Join(ConfigDir(), "install.key")
+}
+
+// registerInstall registers this machine's signing key with the account so
+// the proxy's scan requests are attributed to it. The key is created on first
+// use and kept across re-inits; an install that is already registered is left
+// as is.
+func registerInstall(apiClient *APIClient, config *CLIConfig) error {
+	if config.Auth.InstallID != "" {
+		return nil
+	}
+
+	keyPath := config.Auth.InstallKeyPath
+	if keyPath == "" {
+		keyPath = InstallKeyPath()
+	}
+	key, err := identity.LoadOrCreateKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load install key: %w", err)
+	}
+
+	install, err := apiClient.RegisterInstall(&RegisterInstallRequest{
+		PublicKey: identity.EncodePublicKey(key.Public().(ed25519.PublicKey)),
+		Hostname:  deviceHostname(),
+		OS:        runtime.GOOS,
+	})
+	if err != nil {
+		return err
+	}
+
+	config.Auth.InstallID = install.ID
+	config.Auth.InstallKeyPath = keyPath
+	return nil
+}
@@ -13,6 +13,8 @@ import (
 	"strings"
 	"time"
 
+	"stronghold/internal/configschema"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -326,7 +328,7 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 
 			// Create account via API with the EVM wallet
 			address := m.config.Wallet.Address
-			apiClient := NewAPIClient(m.config.API.Endpoint, m.config.Auth.DeviceToken)
+			apiClient := NewAPIClient(m.config.API.Endpoint.Primary(), m.config.Auth.DeviceToken)
 			resp, err := apiClient.CreateAccount(&CreateAccountRequest{WalletAddress: &address})
 			if err != nil {
 				m.progress = append(m.progress, warningStyle.Render("⚠ "+friendlyAPIError("API unavailable", err)))
@@ -339,6 +341,9 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 				if err := apiClient.RegisterWalletAddresses(m.config.Wallet.Address, m.config.Wallet.SolanaAddress); err == nil {
 					m.progress = append(m.progress, successStyle.Render("✓ Wallet addresses registered with server"))
 				}
+				if err := registerInstall(apiClient, m.config); err == nil {
+					m.progress = append(m.progress, successStyle.Render("✓ Install registered for request signing"))
+				}
 			}
 			m.config.Auth.AccountNumber = m.accountNumber
 			m.config.Auth.LoggedIn = true
@@ -354,7 +359,7 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 			if accountNum == "" {
 				return m, nil
 			}
-			apiClient := NewAPIClient(m.config.API.Endpoint, m.config.Auth.DeviceToken)
+			apiClient := NewAPIClient(m.config.API.Endpoint.Primary(), m.config.Auth.DeviceToken)
 			loginResp, err := apiClient.Login(accountNum)
 			if err != nil {
 				m.progress = append(m.progress, errorStyle.Render("✗ "+friendlyLoginError(err)))
@@ -391,9 +396,9 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 					} else {
 						userID := generateUserID()
 						m.config.Auth.UserID = userID
-						address, err := ImportWallet(userID, DefaultBlockchain, privateKey)
+						address, err := ImportWallet(userID, DefaultBlockchain, privateKey.Reveal())
 						// Zero the private key after use
-						ZeroString(&privateKey)
+						privateKey.Zero()
 						if err != nil {
 							m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("⚠ Wallet import failed: %v", err)))
 						} else {
@@ -424,6 +429,11 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 			} else if m.config.Wallet.Address != "" || m.config.Wallet.SolanaAddress != "" {
 				m.progress = append(m.progress, successStyle.Render("✓ Wallet addresses registered with server"))
 			}
+			if err := registerInstall(apiClient, m.config); err != nil {
+				m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("⚠ Install registration: %v", err)))
+			} else {
+				m.progress = append(m.progress, successStyle.Render("✓ Install registered for request signing"))
+			}
 
 			m.awaitingLoginInput = false
 			m.state = StatePayment
@@ -432,7 +442,7 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 
 		if m.accountChoice == AccountChoiceCreate { // Create new account
 			// Create a local wallet first, then register its address with the API.
-			apiClient := NewAPIClient(m.config.API.Endpoint, m.config.Auth.DeviceToken)
+			apiClient := NewAPIClient(m.config.API.Endpoint.Primary(), m.config.Auth.DeviceToken)
 			walletAddress := m.config.Wallet.Address
 			if walletAddress == "" {
 				userID := m.config.Auth.UserID
@@ -492,6 +502,9 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 				if err := apiClient.RegisterWalletAddresses(m.config.Wallet.Address, m.config.Wallet.SolanaAddress); err == nil {
 					m.progress = append(m.progress, successStyle.Render("✓ Wallet addresses registered with server"))
 				}
+				if err := registerInstall(apiClient, m.config); err == nil {
+					m.progress = append(m.progress, successStyle.Render("✓ Install registered for request signing"))
+				}
 			}
 
 			m.state = StatePayment
@@ -523,7 +536,7 @@ func (m *InstallModel) handleEnter() (tea.Model, tea.Cmd) {
 		if m.apiInput.Value() != "" {
 			m.configAPI = m.apiInput.Value()
 		}
-		m.config.API.Endpoint = m.configAPI
+		m.config.API.Endpoint = configschema.ParseEndpoints(m.configAPI)
 
 		m.state = StateInstalling
 		return m, m.runInstallation()
@@ -837,11 +850,16 @@ func (m *InstallModel) runChecks() tea.Cmd {
 			}
 			oldPort := m.config.Proxy.Port
 			m.config.Proxy.Port = newPort
-			m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("⚠ Port %d in use, using port %d", oldPort, newPort)))
+			m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("⚠ %s, using port %d", describePortHolder(oldPort), newPort)))
 		} else {
 			m.progress = append(m.progress, successStyle.Render(fmt.Sprintf("✓ Port %d available", m.config.Proxy.Port)))
 		}
 
+		// Other proxies would see traffic twice
+		for _, c := range localProxyConflicts(m.config) {
+			m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("⚠ %s: %s", c.Source, c.Detail)))
+		}
+
 		m.currentStep = 2
 		return nil
 	}
@@ -850,32 +868,26 @@ func (m *InstallModel) runChecks() tea.Cmd {
 // runInstallation performs the installation
 func (m *InstallModel) runInstallation() tea.Cmd {
 	return func() tea.Msg {
-		steps := []struct {
-			name string
-			fn   func() error
-		}{
-			{"Creating stronghold user", m.createUser},
-			{"Saving configuration", m.saveConfig},
-			{"Installing proxy binary", m.installProxyBinary},
-			{"Installing CLI binary", m.installCLIBinary},
-			{"Generating CA certificate", m.generateCA},
-			{"Installing CA certificate", m.installCA},
-			{"Configuring system service", m.configureService},
-			{"Starting proxy", m.startProxy},
-			{"Enabling transparent proxy", m.enableTransparentProxy},
-		}
-
-		for _, step := range steps {
-			m.progress = append(m.progress, fmt.Sprintf("  → %s...", step.name))
-			if err := step.fn(); err != nil {
+		err := runInstallSteps(m.config, installSteps(m.config), func(event installEvent, name string, err error) {
+			switch event {
+			case installStepStarted:
+				m.progress = append(m.progress, fmt.Sprintf("  → %s...", name))
+			case installStepDone:
+				// Replace the "→" with "✓"
+				m.progress[len(m.progress)-1] = successStyle.Render(fmt.Sprintf("    ✓ %s", name))
+				time.Sleep(InstallStepDelay)
+			case installStepFailed:
 				m.progress = append(m.progress, errorStyle.Render(fmt.Sprintf("    ✗ %s", err.Error())))
-				m.state = StateError
-				m.errorMsg = err.Error()
-				return nil
+			case installStepRolledBack:
+				m.progress = append(m.progress, warningStyle.Render(fmt.Sprintf("    ↺ Rolled back: %s", name)))
+			case installRollbackFailed:
+				m.progress = append(m.progress, errorStyle.Render(fmt.Sprintf("    ✗ Rollback of %s failed: %v", name, err)))
 			}
-			// Replace the "→" with "✓"
-			m.progress[len(m.progress)-1] = successStyle.Render(fmt.Sprintf("    ✓ %s", step.name))
-			time.Sleep(InstallStepDelay)
+		})
+		if err != nil {
+			m.state = StateError
+			m.errorMsg = err.Error() + "\n\nFix the problem, then run 'stronghold init --resume' to retry from the failed step."
+			return nil
 		}
 
 		m.config.Installed = true
@@ -887,18 +899,8 @@ func (m *InstallModel) runInstallation() tea.Cmd {
 	}
 }
 
-// createUser creates the stronghold system user
-func (m *InstallModel) createUser() error {
-	return CreateStrongholdUser()
-}
-
-// saveConfig saves the configuration
-func (m *InstallModel) saveConfig() error {
-	return m.config.Save()
-}
-
 // generateCA generates the root CA certificate for MITM
-func (m *InstallModel) generateCA() error {
+func generateCA(config *CLIConfig) error {
 	caDir := filepath.Join(ConfigDir(), "ca")
 	if err := os.MkdirAll(caDir, 0700); err != nil {
 		return fmt.Errorf("failed to create CA directory: %w", err)
@@ -934,14 +936,14 @@ func (m *InstallModel) generateCA() error {
 	}
 
 	// Store CA path in config
-	m.config.CA.CertPath = certPath
-	m.config.CA.KeyPath = keyPath
+	config.CA.CertPath = certPath
+	config.CA.KeyPath = keyPath
 
 	return nil
 }
 
 // installCA installs the CA certificate to the system trust store
-func (m *InstallModel) installCA() error {
+func installCA() error {
 	certPath := filepath.Join(ConfigDir(), "ca", "ca.crt")
 	if _, err := os.Stat(certPath); os.IsNotExist(err) {
 		return fmt.Errorf("CA certificate not found at %s", certPath)
@@ -962,17 +964,21 @@ func InstallCAToTrustStore(certPath string) error {
 	}
 }
 
+// linuxCATrustDirs are the system CA anchor directories, by distro
+var linuxCATrustDirs = []string{
+	"/usr/local/share/ca-certificates",          // Debian/Ubuntu
+	"/etc/pki/ca-trust/source/anchors",          // RHEL/CentOS/Fedora
+	"/etc/ca-certificates/trust-source/anchors", // Arch Linux
+}
+
+// linuxCATrustFile is the file name the CA is installed under
+const linuxCATrustFile = "stronghold-ca.crt"
+
 // installCALinux installs CA to Linux system trust store
 func installCALinux(certPath string) error {
 	// Determine the correct CA directory based on distro
-	caDirs := []string{
-		"/usr/local/share/ca-certificates",          // Debian/Ubuntu
-		"/etc/pki/ca-trust/source/anchors",          // RHEL/CentOS/Fedora
-		"/etc/ca-certificates/trust-source/anchors", // Arch Linux
-	}
-
 	var destDir string
-	for _, dir := range caDirs {
+	for _, dir := range linuxCATrustDirs {
 		if _, err := os.Stat(filepath.Dir(dir)); err == nil {
 			destDir = dir
 			break
@@ -987,7 +993,7 @@ func installCALinux(certPath string) error {
 	os.MkdirAll(destDir, 0755)
 
 	// Copy certificate
-	destPath := filepath.Join(destDir, "stronghold-ca.crt")
+	destPath := filepath.Join(destDir, linuxCATrustFile)
 	input, err := os.ReadFile(certPath)
 	if err != nil {
 		return fmt.Errorf("failed to read CA certificate: %w", err)
@@ -997,7 +1003,13 @@ func installCALinux(certPath string) error {
 		return fmt.Errorf("failed to write CA certificate: %w", err)
 	}
 
-	// Update CA certificates
+	refreshLinuxTrustStore()
+	return nil
+}
+
+// refreshLinuxTrustStore rebuilds the system CA bundle from the anchor
+// directories with whichever tool the distro ships
+func refreshLinuxTrustStore() {
 	updateCommands := [][]string{
 		{"update-ca-certificates"},     // Debian/Ubuntu
 		{"update-ca-trust", "extract"}, // RHEL/CentOS/Fedora
@@ -1010,8 +1022,6 @@ func installCALinux(certPath string) error {
 			break
 		}
 	}
-
-	return nil
 }
 
 // installCADarwin installs CA to macOS system trust store
@@ -1032,7 +1042,7 @@ func installCADarwin(certPath string) error {
 }
 
 // installProxyBinary installs the proxy binary
-func (m *InstallModel) installProxyBinary() error {
+func installProxyBinary() error {
 	// In a real implementation, this would download or copy the binary
 	// For now, we'll just check if it exists or create a placeholder
 
@@ -1064,7 +1074,7 @@ func (m *InstallModel) installProxyBinary() error {
 }
 
 // installCLIBinary installs the CLI binary
-func (m *InstallModel) installCLIBinary() error {
+func installCLIBinary() error {
 	// Similar to proxy binary
 	destPath := "/usr/local/bin/stronghold"
 
@@ -1085,20 +1095,20 @@ func (m *InstallModel) installCLIBinary() error {
 }
 
 // configureService configures the system service
-func (m *InstallModel) configureService() error {
-	serviceManager := NewServiceManager(m.config)
+func configureService(config *CLIConfig) error {
+	serviceManager := NewServiceManager(config)
 	return serviceManager.InstallService()
 }
 
 // startProxy starts the proxy
-func (m *InstallModel) startProxy() error {
-	serviceManager := NewServiceManager(m.config)
+func startProxy(config *CLIConfig) error {
+	serviceManager := NewServiceManager(config)
 	return serviceManager.Start()
 }
 
 // enableTransparentProxy enables transparent proxying
-func (m *InstallModel) enableTransparentProxy() error {
-	tp := NewTransparentProxy(m.config)
+func enableTransparentProxy(config *CLIConfig) error {
+	tp := NewTransparentProxy(config)
 	if !tp.IsAvailable() {
 		return fmt.Errorf("transparent proxy not available on this system")
 	}
@@ -1133,11 +1143,16 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 		}
 		oldPort := config.Proxy.Port
 		config.Proxy.Port = newPort
-		fmt.Printf("Port %d in use, using port %d\n", oldPort, newPort)
+		fmt.Printf("%s, using port %d\n", describePortHolder(oldPort), newPort)
+	}
+
+	// Other proxies would see traffic twice
+	for _, c := range localProxyConflicts(config) {
+		fmt.Printf("⚠ %s: %s\n", c.Source, c.Detail)
 	}
 
 	// Handle account setup
-	apiClient := NewAPIClient(config.API.Endpoint, config.Auth.DeviceToken)
+	apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
 	userID := generateUserID()
 	config.Auth.UserID = userID
 
@@ -1149,7 +1164,10 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 			return fmt.Errorf("%s", friendlyLoginError(err))
 		}
 		if loginResp.TOTPRequired {
-			return fmt.Errorf("TOTP required for new device login. Run interactively to complete verification")
+			// No one can type a code here, so wait for a trusted device to approve
+			if err := awaitDeviceApproval(apiClient, config, 0); err != nil {
+				return fmt.Errorf("%w. Run interactively to verify with TOTP", err)
+			}
 		}
 		config.Auth.AccountNumber = loginResp.AccountNumber
 		config.Auth.LoggedIn = true
@@ -1168,9 +1186,9 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 					config.Wallet.Network = DefaultBlockchain
 					fmt.Printf("⚠ %s\n", friendlyAPIError("Wallet key fetch failed", err))
 				} else {
-					address, err := ImportWallet(userID, DefaultBlockchain, walletKey)
+					address, err := ImportWallet(userID, DefaultBlockchain, walletKey.Reveal())
 					// Zero the private key after use
-					ZeroString(&walletKey)
+					walletKey.Zero()
 					if err != nil {
 						return fmt.Errorf("failed to import wallet: %w", err)
 					}
@@ -1199,6 +1217,11 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 				fmt.Println("✓ Wallet addresses registered with server")
 			}
 		}
+		if err := registerInstall(apiClient, config); err != nil {
+			fmt.Printf("⚠ Install registration failed: %v\n", err)
+		} else {
+			fmt.Println("✓ Install registered for request signing")
+		}
 	} else {
 		// Create new account
 		fmt.Println("→ Creating account...")
@@ -1257,6 +1280,11 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 			if err := apiClient.RegisterWalletAddresses(config.Wallet.Address, config.Wallet.SolanaAddress); err == nil {
 				fmt.Println("✓ Wallet addresses registered with server")
 			}
+			if err := registerInstall(apiClient, config); err != nil {
+				fmt.Printf("⚠ Install registration failed: %v\n", err)
+			} else {
+				fmt.Println("✓ Install registered for request signing")
+			}
 		}
 		config.Auth.LoggedIn = true
 		fmt.Printf("✓ Account: %s\n", config.Auth.AccountNumber)
@@ -1268,39 +1296,10 @@ func RunInitNonInteractive(privateKey, solanaPrivateKey, accountNumber string, s
 	}
 
 	if !skipService {
-		// Install binaries
-		destPath := "/usr/local/bin/stronghold-proxy"
-		if _, err := os.Stat("./cmd/proxy/main.go"); err == nil {
-			cmd := exec.Command("go", "build", "-o", destPath, "./cmd/proxy")
-			if _, err := cmd.CombinedOutput(); err != nil {
-				userBin := filepath.Join(os.Getenv("HOME"), ".local", "bin")
-				os.MkdirAll(userBin, 0755)
-				destPath = filepath.Join(userBin, "stronghold-proxy")
-				cmd = exec.Command("go", "build", "-o", destPath, "./cmd/proxy")
-				if _, err := cmd.CombinedOutput(); err != nil {
-					return fmt.Errorf("failed to build proxy: %w", err)
-				}
-			}
-		}
-
-		// Install service
-		serviceManager := NewServiceManager(config)
-		if err := serviceManager.InstallService(); err != nil {
-			return fmt.Errorf("failed to install service: %w", err)
-		}
-
-		// Start proxy
-		if err := serviceManager.Start(); err != nil {
-			return fmt.Errorf("failed to start proxy: %w", err)
-		}
-
-		// Enable transparent proxy
-		tp := NewTransparentProxy(config)
-		if !tp.IsAvailable() {
-			return fmt.Errorf("transparent proxy not available on this system")
-		}
-		if err := tp.Enable(); err != nil {
-			return fmt.Errorf("failed to enable transparent proxy: %w", err)
+		// Failed steps are rolled back and recorded for --resume
+		steps := append([]installStep{buildProxyBinaryStep}, serviceInstallSteps(config)...)
+		if err := runInstallSteps(config, steps, printInstallEvent); err != nil {
+			return fmt.Errorf("%w\nFix the problem, then run 'stronghold init --resume' to retry from the failed step", err)
 		}
 	}
 
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// installStep is one step of installing Stronghold. undo reverses it; it is
+// also run for the step that failed, which may have been partly applied, so
+// it must tolerate the step never having run. Steps without one only leave
+// inert files behind and are kept on failure.
+type installStep struct {
+	name string
+	fn   func() error
+	undo func() error
+}
+
+// InstallResume records an installation that failed partway, so
+// `stronghold init --resume` can retry it
+type InstallResume struct {
+	Pending    []string `yaml:"pending"` // Steps still to run, in install order
+	FailedStep string   `yaml:"failed_step"`
+	Error      string   `yaml:"error"`
+	FailedAt   string   `yaml:"failed_at"`
+}
+
+// installEvent is reported as install steps run and roll back
+type installEvent int
+
+const (
+	installStepStarted installEvent = iota
+	installStepDone
+	installStepFailed
+	installStepRolledBack
+	installRollbackFailed
+)
+
+// installSteps returns the steps of a full installation, in order
+func installSteps(config *CLIConfig) []installStep {
+	steps := []installStep{
+		{name: "Creating stronghold user", fn: CreateStrongholdUser},
+		{name: "Saving configuration", fn: config.Save},
+		{name: "Installing proxy binary", fn: installProxyBinary},
+		{name: "Installing CLI binary", fn: installCLIBinary},
+		{name: "Generating CA certificate", fn: func() error { return generateCA(config) }},
+		{name: "Installing CA certificate", fn: installCA},
+	}
+	return append(steps, serviceInstallSteps(config)...)
+}
+
+// serviceInstallSteps returns the steps that put the proxy in the path of
+// traffic. These are rolled back on failure, so a failed install does not
+// leave traffic redirected to a proxy that is not running.
+func serviceInstallSteps(config *CLIConfig) []installStep {
+	serviceManager := NewServiceManager(config)
+	tp := NewTransparentProxy(config)
+
+	return []installStep{
+		{
+			// No undo: the port is only recorded, like the saved config
+			name: "Reserving proxy port",
+			fn: func() error {
+				_, err := reserveProxyPort(config)
+				return err
+			},
+		},
+		{
+			name: "Configuring system service",
+			fn:   func() error { return configureService(config) },
+			undo: serviceManager.UninstallService,
+		},
+		{
+			name: "Starting proxy",
+			fn:   func() error { return startProxy(config) },
+			undo: serviceManager.Stop,
+		},
+		{
+			name: "Enabling transparent proxy",
+			fn:   func() error { return enableTransparentProxy(config) },
+			undo: tp.Disable,
+		},
+	}
+}
+
+// buildProxyBinaryStep builds the proxy from source when run from a checkout,
+// as non-interactive init does; otherwise the installed binary is used
+var buildProxyBinaryStep = installStep{name: "Building proxy binary", fn: buildProxyBinary}
+
+// buildProxyBinary builds the proxy into /usr/local/bin, or ~/.local/bin when
+// that is not writable, if running from a source checkout
+func buildProxyBinary() error {
+	if _, err := os.Stat("./cmd/proxy/main.go"); err != nil {
+		return nil
+	}
+
+	destPath := "/usr/local/bin/stronghold-proxy"
+	cmd := exec.Command("go", "build", "-o", destPath, "./cmd/proxy")
+	if _, err := cmd.CombinedOutput(); err != nil {
+		userBin := filepath.Join(os.Getenv("HOME"), ".local", "bin")
+		os.MkdirAll(userBin, 0755)
+		destPath = filepath.Join(userBin, "stronghold-proxy")
+		cmd = exec.Command("go", "build", "-o", destPath, "./cmd/proxy")
+		if _, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to build proxy: %w", err)
+		}
+	}
+	return nil
+}
+
+// runInstallSteps runs steps in order. When one fails, it and the steps
+// before it are undone in reverse order, and the steps left to run are
+// recorded in config for `stronghold init --resume`. A successful run clears
+// any earlier record.
+func runInstallSteps(config *CLIConfig, steps []installStep, report func(event installEvent, name string, err error)) error {
+	for i, step := range steps {
+		report(installStepStarted, step.name, nil)
+		if err := step.fn(); err != nil {
+			report(installStepFailed, step.name, err)
+
+			rolledBack := rollbackInstallSteps(steps[:i+1], report)
+			var pending []string
+			for _, done := range steps[:i] {
+				if rolledBack[done.name] {
+					pending = append(pending, done.name)
+				}
+			}
+			for _, rest := range steps[i:] {
+				pending = append(pending, rest.name)
+			}
+
+			config.InstallResume = &InstallResume{
+				Pending:    pending,
+				FailedStep: step.name,
+				Error:      err.Error(),
+				FailedAt:   time.Now().Format(time.RFC3339),
+			}
+			config.Save()
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+		report(installStepDone, step.name, nil)
+	}
+
+	config.InstallResume = nil
+	return nil
+}
+
+// rollbackInstallSteps undoes steps in reverse order, returning the names of
+// those whose effects are gone and need to be redone. A step whose undo
+// fails is still redone on resume, since steps are safe to repeat.
+func rollbackInstallSteps(steps []installStep, report func(event installEvent, name string, err error)) map[string]bool {
+	rolledBack := make(map[string]bool)
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.undo == nil {
+			continue
+		}
+		rolledBack[step.name] = true
+		if err := step.undo(); err != nil {
+			report(installRollbackFailed, step.name, err)
+			continue
+		}
+		report(installStepRolledBack, step.name, nil)
+	}
+	return rolledBack
+}
+
+// printInstallEvent reports install progress on stdout
+func printInstallEvent(event installEvent, name string, err error) {
+	switch event {
+	case installStepStarted:
+		fmt.Printf("→ %s...\n", name)
+	case installStepDone:
+		fmt.Printf("✓ %s\n", name)
+	case installStepFailed:
+		fmt.Printf("✗ %s: %v\n", name, err)
+	case installStepRolledBack:
+		fmt.Printf("↺ Rolled back: %s\n", name)
+	case installRollbackFailed:
+		fmt.Printf("✗ Rollback of %s failed: %v\n", name, err)
+	}
+}
+
+// ResumeInit retries an installation that failed partway, from the step
+// that failed. Account setup is not repeated.
+func ResumeInit() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	resume := config.InstallResume
+	if resume == nil {
+		if config.Installed {
+			fmt.Println("Stronghold is already installed.")
+			return nil
+		}
+		return fmt.Errorf("no failed installation to resume: run 'stronghold init'")
+	}
+
+	known := make(map[string]installStep)
+	for _, step := range append(installSteps(config), buildProxyBinaryStep) {
+		known[step.name] = step
+	}
+	var steps []installStep
+	for _, name := range resume.Pending {
+		step, ok := known[name]
+		if !ok {
+			return fmt.Errorf("unknown install step %q: run 'stronghold init' again", name)
+		}
+		steps = append(steps, step)
+	}
+
+	fmt.Printf("Resuming installation from %q (failed at %s: %s)\n", resume.FailedStep, resume.FailedAt, resume.Error)
+	if err := runInstallSteps(config, steps, printInstallEvent); err != nil {
+		return fmt.Errorf("%w\nFix the problem, then run 'stronghold init --resume' again", err)
+	}
+
+	config.Installed = true
+	config.InstallDate = time.Now().Format(time.RFC3339)
+	if err := config.Save(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Println("✓ Installation complete!")
+	fmt.Printf("Proxy running on %s (transparent mode)\n", config.GetProxyAddr())
+	return nil
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunInstallSteps_RollsBackOnFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := DefaultConfig()
+
+	var calls []string
+	step := func(name string, fail bool, undo bool) installStep {
+		s := installStep{name: name, fn: func() error {
+			calls = append(calls, "run "+name)
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		}}
+		if undo {
+			s.undo = func() error {
+				calls = append(calls, "undo "+name)
+				return nil
+			}
+		}
+		return s
+	}
+
+	steps := []installStep{
+		step("binary", false, false),
+		step("service", false, true),
+		step("proxy", false, true),
+		step("firewall", true, true),
+		step("after", false, true),
+	}
+
+	var events []installEvent
+	err := runInstallSteps(config, steps, func(event installEvent, name string, err error) {
+		events = append(events, event)
+	})
+	if err == nil || err.Error() != "firewall: boom" {
+		t.Fatalf("expected the failed step's error, got %v", err)
+	}
+
+	// The failed step may be partly applied, so it is undone too
+	want := []string{"run binary", "run service", "run proxy", "run firewall", "undo firewall", "undo proxy", "undo service"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+	if events[len(events)-1] != installStepRolledBack {
+		t.Errorf("expected rollbacks to be reported, got %v", events)
+	}
+
+	resume := config.InstallResume
+	if resume == nil {
+		t.Fatal("expected the failure to be recorded for resume")
+	}
+	if resume.FailedStep != "firewall" || resume.Error != "boom" {
+		t.Errorf("unexpected resume record: %+v", resume)
+	}
+	// The binary step has no undo and stays done
+	if want := []string{"service", "proxy", "firewall", "after"}; !reflect.DeepEqual(resume.Pending, want) {
+		t.Errorf("expected pending %v, got %v", want, resume.Pending)
+	}
+
+	saved, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() returned error: %v", err)
+	}
+	if saved.InstallResume == nil || saved.InstallResume.FailedStep != "firewall" {
+		t.Fatalf("expected the resume record to be saved, got %+v", saved.InstallResume)
+	}
+
+	// A successful run clears it
+	if err := runInstallSteps(config, steps[:1], func(installEvent, string, error) {}); err != nil {
+		t.Fatalf("runInstallSteps() returned error: %v", err)
+	}
+	if config.InstallResume != nil {
+		t.Errorf("expected the resume record to be cleared, got %+v", config.InstallResume)
+	}
+}
+
+func TestResumeInit_NothingToResume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	err := ResumeInit()
+	if err == nil || !strings.Contains(err.Error(), "no failed installation to resume") {
+		t.Fatalf("expected nothing to resume, got %v", err)
+	}
+
+	config := DefaultConfig()
+	config.InstallResume = &InstallResume{Pending: []string{"Reticulating splines"}, FailedStep: "Reticulating splines"}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	err = ResumeInit()
+	if err == nil || !strings.Contains(err.Error(), `unknown install step "Reticulating splines"`) {
+		t.Fatalf("expected an unknown step error, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The kill switch rejects the traffic the transparent proxy would intercept
+// instead of redirecting it, so nothing reaches the agent unscanned while
+// the proxy is down. It is installed by the crash-loop watchdog under the
+// fail_closed policy and removed by 'stronghold enable' or 'disable'.
+
+// Names of the kill switch nftables table, iptables chain, and pf label
+const (
+	killSwitchTable = "stronghold_killswitch"
+	killSwitchChain = "STRONGHOLD_KILL"
+	killSwitchLabel = "stronghold_killswitch"
+)
+
+// EnableKillSwitch replaces the redirect rules with rules that reject
+// outbound HTTP, HTTPS and QUIC from everything but the proxy user
+func (t *TransparentProxy) EnableKillSwitch() error {
+	uid, err := GetStrongholdUID()
+	if err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		t.disableLinux()
+		if t.hasNftables() {
+			cmd := exec.Command("nft", "-f", "-")
+			cmd.Stdin = strings.NewReader(killSwitchNftScript(uid))
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("nftables failed: %s - %s", err, string(output))
+			}
+			return nil
+		}
+		if t.hasIptables() {
+			return t.enableKillSwitchIptables(uid)
+		}
+		return fmt.Errorf("neither iptables nor nftables found")
+	case "darwin":
+		return t.enableKillSwitchDarwin()
+	default:
+		return fmt.Errorf("kill switch not supported on %s", runtime.GOOS)
+	}
+}
+
+// KillSwitchActive reports whether the kill switch rules are installed
+func (t *TransparentProxy) KillSwitchActive() bool {
+	switch runtime.GOOS {
+	case "linux":
+		if t.hasNftables() && exec.Command("nft", "list", "table", "inet", killSwitchTable).Run() == nil {
+			return true
+		}
+		if t.hasIptables() {
+			output, err := exec.Command("iptables", "-L", "OUTPUT", "-n").Output()
+			return err == nil && strings.Contains(string(output), killSwitchChain)
+		}
+		return false
+	case "darwin":
+		output, err := exec.Command("pfctl", "-a", "stronghold", "-sr").Output()
+		return err == nil && strings.Contains(string(output), killSwitchLabel)
+	default:
+		return false
+	}
+}
+
+// disableKillSwitch removes the kill switch rules, ignoring rules that are
+// not there. On macOS they share the stronghold anchor with the redirect
+// rules, which disableDarwin flushes.
+func (t *TransparentProxy) disableKillSwitch() {
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if t.hasNftables() {
+		exec.Command("nft", "delete", "table", "inet", killSwitchTable).Run()
+	}
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			continue
+		}
+		exec.Command(bin, "-D", "OUTPUT", "-j", killSwitchChain).Run()
+		exec.Command(bin, "-F", killSwitchChain).Run()
+		exec.Command(bin, "-X", killSwitchChain).Run()
+	}
+}
+
+// killSwitchNftScript builds the inet-family kill switch table. Local and
+// private destinations stay reachable, as they are never redirected.
+func killSwitchNftScript(uid string) string {
+	return fmt.Sprintf(`table inet %s {
+    chain output {
+        type filter hook output priority 0; policy accept;
+
+        # Skip proxy's own traffic (runs as stronghold user)
+        meta skuid %s accept
+
+        ip daddr { 127.0.0.0/8, 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16 } accept
+        ip6 daddr { ::1, fc00::/7, fe80::/10 } accept
+
+        tcp dport { 80, 443 } reject with tcp reset
+        udp dport 443 reject
+    }
+}`, killSwitchTable, uid)
+}
+
+// enableKillSwitchIptables installs the kill switch chain with iptables,
+// and ip6tables when present
+func (t *TransparentProxy) enableKillSwitchIptables(uid string) error {
+	chains := map[string][]string{
+		"iptables":  {"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+		"ip6tables": {"::1/128", "fc00::/7", "fe80::/10"},
+	}
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		if bin == "ip6tables" && !t.hasIp6tables() {
+			continue
+		}
+		rules := [][]string{
+			{bin, "-N", killSwitchChain},
+			{bin, "-A", killSwitchChain, "-m", "owner", "--uid-owner", uid, "-j", "RETURN"},
+		}
+		for _, dest := range chains[bin] {
+			rules = append(rules, []string{bin, "-A", killSwitchChain, "-d", dest, "-j", "RETURN"})
+		}
+		rules = append(rules,
+			[]string{bin, "-A", killSwitchChain, "-p", "tcp", "--dport", "80", "-j", "REJECT", "--reject-with", "tcp-reset"},
+			[]string{bin, "-A", killSwitchChain, "-p", "tcp", "--dport", "443", "-j", "REJECT", "--reject-with", "tcp-reset"},
+			[]string{bin, "-A", killSwitchChain, "-p", "udp", "--dport", "443", "-j", "REJECT"},
+			[]string{bin, "-I", "OUTPUT", "-j", killSwitchChain},
+		)
+		for _, rule := range rules {
+			if output, err := exec.Command(rule[0], rule[1:]...).CombinedOutput(); err != nil {
+				if !strings.Contains(string(output), "Chain already exists") {
+					return fmt.Errorf("%s failed: %s - %s", rule[0], err, string(output))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// enableKillSwitchDarwin loads the kill switch into the stronghold anchor in
+// place of the redirect rules
+func (t *TransparentProxy) enableKillSwitchDarwin() error {
+	pfConf := fmt.Sprintf(`# Stronghold kill switch: the proxy is down, so web traffic is refused
+pass out quick proto { tcp, udp } user %s
+block return out quick proto tcp from any to ! { 127.0.0.0/8, 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16, ::1, fc00::/7, fe80::/10 } port { 80, 443 } label "%s"
+block return out quick proto udp from any to ! { 127.0.0.0/8, ::1 } port 443 label "%s"
+`, StrongholdUsername(), killSwitchLabel, killSwitchLabel)
+
+	configPath := "/etc/pf.stronghold.conf"
+	if err := os.WriteFile(configPath, []byte(pfConf), 0644); err != nil {
+		return fmt.Errorf("failed to write pf config: %w", err)
+	}
+	if output, err := exec.Command("pfctl", "-a", "stronghold", "-f", configPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("pfctl anchor load failed: %s - %s", err, string(output))
+	}
+	exec.Command("pfctl", "-e").Run()
+	return nil
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"stronghold/internal/bypass"
+	"stronghold/internal/logrotate"
+)
+
+// replayOverrideTTL is the lifetime of the bypass token minted for a forced replay
+const replayOverrideTTL = time.Minute
+
+// auditEntry is a flagged decision recorded by the proxy
+type auditEntry struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	Direction    string    `json:"direction"`
+	Decision     string    `json:"decision"`
+	Action       string    `json:"action"`
+	ShadowAction string    `json:"shadow_action,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+}
+
+// ReplayOptions configures a replay
+type ReplayOptions struct {
+	Force  bool   // Bypass scanning for this replay after confirmation
+	Yes    bool   // Skip the confirmation prompt
+	Output string // Write the response body to this file
+}
+
+// Replay re-fetches a previously flagged request through the proxy
+func Replay(requestID string, opts ReplayOptions) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	path := config.Logging.AuditFilePath()
+	if path == "" {
+		return fmt.Errorf("no audit log configured; set logging.file or logging.audit_file")
+	}
+
+	entry, err := findAuditEntry(path, requestID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Original request:")
+	fmt.Printf("  Time:       %s\n", entry.Time.Local().Format(time.RFC3339))
+	fmt.Printf("  Request:    %s %s\n", entry.Method, entry.URL)
+	fmt.Printf("  Decision:   %s (action: %s)\n", entry.Decision, entry.Action)
+	if entry.Reason != "" {
+		fmt.Printf("  Reason:     %s\n", entry.Reason)
+	}
+	fmt.Println()
+
+	if entry.Direction == "request" || (entry.Method != http.MethodGet && entry.Method != http.MethodHead) {
+		return fmt.Errorf("only GET and HEAD responses can be replayed (request bodies are not stored)")
+	}
+
+	target, err := url.Parse(entry.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL in audit log: %w", err)
+	}
+
+	req, err := http.NewRequest(entry.Method, entry.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if opts.Force {
+		if !opts.Yes && !Confirm(fmt.Sprintf("Fetch %s WITHOUT scanning? This override is recorded. [y/N]:", target.Hostname())) {
+			fmt.Println("Replay cancelled.")
+			return nil
+		}
+
+		key, restartNeeded, err := loadBypassKey(config)
+		if err != nil {
+			return err
+		}
+		if restartNeeded {
+			return fmt.Errorf("bypass tokens were just enabled; restart the proxy with 'stronghold disable && stronghold enable' and retry")
+		}
+
+		token, claims, err := bypass.Issue(key, []string{target.Hostname()}, "replay:"+requestID, replayOverrideTTL)
+		if err != nil {
+			return err
+		}
+
+		if err := appendBypassAudit(bypassAuditEntry{
+			Event:     "replay_override",
+			TokenID:   claims.ID,
+			RequestID: requestID,
+			Hosts:     claims.Hosts,
+			Client:    claims.Client,
+			IssuedBy:  config.Auth.Email,
+			IssuedAt:  time.Unix(claims.IssuedAt, 0).UTC().Format(time.RFC3339),
+			ExpiresAt: claims.Expiry().UTC().Format(time.RFC3339),
+		}); err != nil {
+			return fmt.Errorf("failed to record override: %w", err)
+		}
+
+		req.Header.Set(bypass.HeaderName, token)
+	}
+
+	client, err := newProxyClient(config)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fmt.Println("Replay:")
+	fmt.Printf("  Status:     %s\n", resp.Status)
+	if id := resp.Header.Get("X-Stronghold-Request-ID"); id != "" {
+		fmt.Printf("  Request ID: %s\n", id)
+	}
+	if scanType := resp.Header.Get("X-Stronghold-Scan-Type"); scanType == "bypassed" {
+		fmt.Printf("  Decision:   %s\n", warningStyle.Render("not scanned (override)"))
+	} else if decision := resp.Header.Get("X-Stronghold-Decision"); decision != "" {
+		fmt.Printf("  Decision:   %s\n", decision)
+		if reason := resp.Header.Get("X-Stronghold-Reason"); reason != "" {
+			fmt.Printf("  Reason:     %s\n", reason)
+		}
+	}
+
+	if opts.Output == "" {
+		n, _ := io.Copy(io.Discard, resp.Body)
+		fmt.Printf("  Body:       %d bytes (use --output to save)\n", n)
+		return nil
+	}
+
+	f, err := os.OpenFile(opts.Output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	fmt.Printf("  Body:       %d bytes written to %s\n", n, opts.Output)
+
+	return nil
+}
+
+// findAuditEntry returns the most recent audit entry for requestID
+func findAuditEntry(path, requestID string) (*auditEntry, error) {
+	segments := logrotate.Segments(path)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("audit log not found: %s", path)
+	}
+
+	// Search every file rotated out of the log too; the last match wins
+	var found *auditEntry
+	for _, segment := range segments {
+		r, err := logrotate.OpenSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var entry auditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if entry.RequestID == requestID {
+				found = &entry
+			}
+		}
+		err = scanner.Err()
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("request %s not found in %s", requestID, path)
+	}
+	return found, nil
+}
+
+// newProxyClient returns an HTTP client that sends requests through the local
+// proxy and trusts the Stronghold CA for intercepted HTTPS
+func newProxyClient(config *CLIConfig) (*http.Client, error) {
+	proxyURL, err := url.Parse(config.GetProxyURL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if config.CA.CertPath != "" {
+		if pem, err := os.ReadFile(config.CA.CertPath); err == nil {
+			pool.AppendCertsFromPEM(pem)
+		}
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+		// Replays show exactly what the proxy returned for this URL
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
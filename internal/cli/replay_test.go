@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindAuditEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	lines := strings.Join([]string{
+		`{"request_id":"req-1","method":"GET","url":"https://a.example/","decision":"WARN","action":"warn"}`,
+		`not json`,
+		`{"request_id":"req-2","method":"GET","url":"https://b.example/","decision":"BLOCK","action":"block"}`,
+		`{"request_id":"req-1","method":"GET","url":"https://a.example/","decision":"BLOCK","action":"block"}`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(lines+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := findAuditEntry(path, "req-1")
+	if err != nil {
+		t.Fatalf("findAuditEntry: %v", err)
+	}
+	if entry.Decision != "BLOCK" {
+		t.Errorf("expected most recent entry (BLOCK), got %s", entry.Decision)
+	}
+
+	if _, err := findAuditEntry(path, "req-missing"); err == nil {
+		t.Error("expected error for unknown request ID")
+	}
+	if _, err := findAuditEntry(filepath.Join(t.TempDir(), "none.log"), "req-1"); err == nil {
+		t.Error("expected error for missing audit log")
+	}
+}
+
+func TestLoggingConfig_AuditFilePath(t *testing.T) {
+	// Must resolve the same file the proxy writes
+	cfg := LoggingConfig{File: "/var/log/stronghold/proxy.log"}
+	if got := cfg.AuditFilePath(); got != "/var/log/stronghold/audit.log" {
+		t.Errorf("expected audit.log next to the proxy log, got %q", got)
+	}
+	cfg.AuditFile = "/tmp/custom.log"
+	if got := cfg.AuditFilePath(); got != "/tmp/custom.log" {
+		t.Errorf("expected logging.audit_file to win, got %q", got)
+	}
+	if got := (&LoggingConfig{}).AuditFilePath(); got != "" {
+		t.Errorf("expected no audit log without logging.file, got %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// errHandoffUnsupported means the running proxy cannot hand its sockets to
+// a new binary, so restarting it means stopping it first
+var errHandoffUnsupported = errors.New("running proxy does not support in-place restarts")
+
+var (
+	fetchProxyHealthFunc = fetchProxyHealth
+	signalProcessFunc    = func(pid int, sig os.Signal) error {
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		return process.Signal(sig)
+	}
+	handoffWait         = 35 * time.Second // Successor start-up plus margin
+	handoffPollInterval = 250 * time.Millisecond
+)
+
+// Restart restarts the proxy on the binary now installed, without a moment
+// where intercepted traffic is redirected to a port nobody listens on
+func Restart() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("Restarting Stronghold proxy...")
+	serviceManager := NewServiceManager(config)
+	if err := serviceManager.Restart(); err != nil {
+		return fmt.Errorf("failed to restart proxy: %w", err)
+	}
+
+	status, _ := serviceManager.IsRunning()
+	fmt.Println()
+	fmt.Println("✓ Stronghold proxy restarted")
+	fmt.Printf("  Address: %s\n", config.GetProxyAddr())
+	if status != nil && status.PID > 0 {
+		fmt.Printf("  PID:     %d\n", status.PID)
+	}
+	return nil
+}
+
+// handoff asks the running proxy to start the installed binary on its
+// listening sockets, and waits until the new process is the one serving.
+// The old process drains its connections and exits on its own.
+func (s *ServiceManager) handoff() error {
+	if proxyUpgradeSignal == nil {
+		return errHandoffUnsupported
+	}
+	// Proxies from before handoff support do not report their PID
+	before, err := fetchProxyHealthFunc(s.config)
+	if err != nil || before.PID == 0 {
+		return errHandoffUnsupported
+	}
+
+	if err := signalProcessFunc(before.PID, proxyUpgradeSignal); err != nil {
+		return fmt.Errorf("failed to signal proxy (PID: %d): %w", before.PID, err)
+	}
+
+	deadline := time.Now().Add(handoffWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(handoffPollInterval)
+		if after, err := fetchProxyHealthFunc(s.config); err == nil && after.PID != 0 && after.PID != before.PID {
+			return nil
+		}
+	}
+	return fmt.Errorf("new proxy did not take over within %s; the previous one is still serving (see 'stronghold logs')", handoffWait)
+}
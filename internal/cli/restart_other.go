@@ -0,0 +1,8 @@
+//go:build !unix
+
+package cli
+
+import "os"
+
+// proxyUpgradeSignal is nil where the proxy cannot hand over its sockets
+var proxyUpgradeSignal os.Signal
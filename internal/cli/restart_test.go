@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubHandoff replaces the proxy health lookup with one reporting the PIDs
+// in turn, the last one from then on, and records the signals sent
+func stubHandoff(t *testing.T, pids ...int) *[]int {
+	t.Helper()
+	origFetch, origSignal, origWait, origPoll := fetchProxyHealthFunc, signalProcessFunc, handoffWait, handoffPollInterval
+	t.Cleanup(func() {
+		fetchProxyHealthFunc, signalProcessFunc, handoffWait, handoffPollInterval = origFetch, origSignal, origWait, origPoll
+	})
+
+	calls := 0
+	fetchProxyHealthFunc = func(*CLIConfig) (*proxyHealth, error) {
+		pid := pids[min(calls, len(pids)-1)]
+		calls++
+		return &proxyHealth{Version: "test", PID: pid}, nil
+	}
+	var signalled []int
+	signalProcessFunc = func(pid int, sig os.Signal) error {
+		if sig != proxyUpgradeSignal {
+			t.Errorf("expected the upgrade signal, got %v", sig)
+		}
+		signalled = append(signalled, pid)
+		return nil
+	}
+	handoffWait = 200 * time.Millisecond
+	handoffPollInterval = time.Millisecond
+	return &signalled
+}
+
+func TestHandoff_WaitsForNewProcess(t *testing.T) {
+	if proxyUpgradeSignal == nil {
+		t.Skip("no listener handoff on this platform")
+	}
+	signalled := stubHandoff(t, 100, 100, 100, 200)
+
+	s := &ServiceManager{config: DefaultConfig()}
+	if err := s.handoff(); err != nil {
+		t.Fatalf("handoff: %v", err)
+	}
+	if len(*signalled) != 1 || (*signalled)[0] != 100 {
+		t.Errorf("expected the running proxy signalled once, got %v", *signalled)
+	}
+}
+
+func TestHandoff_OldProxyIsUnsupported(t *testing.T) {
+	signalled := stubHandoff(t, 0)
+
+	s := &ServiceManager{config: DefaultConfig()}
+	if err := s.handoff(); !errors.Is(err, errHandoffUnsupported) {
+		t.Fatalf("expected errHandoffUnsupported for a proxy without a PID, got %v", err)
+	}
+	if len(*signalled) != 0 {
+		t.Errorf("expected no signal, got %v", *signalled)
+	}
+}
+
+func TestHandoff_TimesOut(t *testing.T) {
+	if proxyUpgradeSignal == nil {
+		t.Skip("no listener handoff on this platform")
+	}
+	stubHandoff(t, 100)
+
+	s := &ServiceManager{config: DefaultConfig()}
+	err := s.handoff()
+	if err == nil || errors.Is(err, errHandoffUnsupported) || !strings.Contains(err.Error(), "still serving") {
+		t.Fatalf("expected a timeout leaving the old proxy serving, got %v", err)
+	}
+}
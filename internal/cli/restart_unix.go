@@ -0,0 +1,12 @@
+//go:build unix
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// proxyUpgradeSignal makes the proxy hand its sockets to a fresh start of
+// its binary
+var proxyUpgradeSignal os.Signal = syscall.SIGUSR2
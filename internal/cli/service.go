@@ -1,12 +1,14 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -15,12 +17,13 @@ import (
 
 // ServiceManager handles system service operations
 type ServiceManager struct {
-	config *CLIConfig
+	config     *CLIConfig
+	initSystem initSystem
 }
 
 // NewServiceManager creates a new service manager
 func NewServiceManager(config *CLIConfig) *ServiceManager {
-	return &ServiceManager{config: config}
+	return &ServiceManager{config: config, initSystem: detectInitSystemFunc()}
 }
 
 // ServiceStatus represents the status of the proxy service
@@ -92,12 +95,7 @@ func (s *ServiceManager) Start() error {
 	}
 
 	// Start the proxy
-	cmd := exec.Command(proxyBinary)
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_CONFIG=%s", ConfigPath()))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_PROXY_PORT=%d", s.config.Proxy.Port))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_PROXY_BIND=%s", s.config.Proxy.Bind))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_API_ENDPOINT=%s", s.config.API.Endpoint))
+	cmd := s.proxyCommand(proxyBinary)
 
 	// Set up logging
 	logFile := s.config.Logging.File
@@ -123,6 +121,18 @@ func (s *ServiceManager) Start() error {
 	return nil
 }
 
+// proxyCommand returns the command that runs the proxy binary with its
+// config, port and API endpoint in the environment
+func (s *ServiceManager) proxyCommand(proxyBinary string) *exec.Cmd {
+	cmd := exec.Command(proxyBinary)
+	cmd.Env = os.Environ()
+	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_CONFIG=%s", ConfigPath()))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_PROXY_PORT=%d", s.config.Proxy.Port))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_PROXY_BIND=%s", s.config.Proxy.Bind))
+	cmd.Env = append(cmd.Env, fmt.Sprintf("STRONGHOLD_API_ENDPOINT=%s", s.config.API.Endpoint))
+	return cmd
+}
+
 // Stop stops the proxy service
 func (s *ServiceManager) Stop() error {
 	status, err := s.IsRunning()
@@ -173,8 +183,13 @@ func (s *ServiceManager) Stop() error {
 	return fmt.Errorf("proxy did not stop in time")
 }
 
-// Restart restarts the proxy service
+// Restart restarts the proxy on the binary now installed. A running proxy
+// that supports it hands its sockets to the new binary before draining, so
+// redirected traffic is never refused; an older one is stopped and started.
 func (s *ServiceManager) Restart() error {
+	if err := s.handoff(); !errors.Is(err, errHandoffUnsupported) {
+		return err
+	}
 	if err := s.Stop(); err != nil {
 		return err
 	}
@@ -183,11 +198,18 @@ func (s *ServiceManager) Restart() error {
 
 // InstallService installs the proxy as a system service
 func (s *ServiceManager) InstallService() error {
-	switch runtime.GOOS {
-	case "linux":
+	switch s.initSystem {
+	case initSystemd:
 		return s.installLinuxService()
-	case "darwin":
+	case initLaunchd:
 		return s.installDarwinService()
+	case initOpenRC:
+		return s.installOpenRCService()
+	case initRunit:
+		return s.installRunitService()
+	case initNone:
+		// Nothing to install into; 'stronghold supervise' keeps the proxy running
+		return nil
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
@@ -195,11 +217,17 @@ func (s *ServiceManager) InstallService() error {
 
 // UninstallService removes the proxy system service
 func (s *ServiceManager) UninstallService() error {
-	switch runtime.GOOS {
-	case "linux":
+	switch s.initSystem {
+	case initSystemd:
 		return s.uninstallLinuxService()
-	case "darwin":
+	case initLaunchd:
 		return s.uninstallDarwinService()
+	case initOpenRC:
+		return s.uninstallOpenRCService()
+	case initRunit:
+		return s.uninstallRunitService()
+	case initNone:
+		return nil
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
@@ -212,26 +240,20 @@ func (s *ServiceManager) installLinuxService() error {
 
 	// Check if we can use system systemd or user systemd
 	serviceDir := "/etc/systemd/system"
-	useSystemd := false
-	if _, err := os.Stat("/run/systemd/system"); err == nil {
-		// Check if we have permission
-		if _, err := os.Stat(serviceDir); err == nil {
-			useSystemd = true
-		}
-	}
-
-	if useSystemd {
+	if useSystemSystemd() {
 		// System service runs as stronghold user for transparent proxy filtering
 		serviceContent := fmt.Sprintf(`[Unit]
 Description=Stronghold Proxy Service
 After=network.target
-
+%s
 [Service]
 Type=simple
 User=%s
 Group=%s
 EnvironmentFile=-/etc/systemd/system/stronghold-proxy.env
 ExecStart=%s
+# Hands the listening sockets to the installed binary without a restart
+ExecReload=/bin/kill -USR2 $MAINPID
 Restart=always
 RestartSec=5
 # Allow binding to privileged ports if needed
@@ -239,13 +261,17 @@ AmbientCapabilities=CAP_NET_BIND_SERVICE
 
 [Install]
 WantedBy=multi-user.target
-`, username, username, proxyBinary)
+`, s.systemdWatchdogLines(), username, username, proxyBinary)
 
 		servicePath := filepath.Join(serviceDir, "stronghold-proxy.service")
 		if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
 			return fmt.Errorf("failed to write service file: %w", err)
 		}
 
+		if err := s.installSystemdFailsafe(serviceDir); err != nil {
+			return err
+		}
+
 		// Reload systemd
 		exec.Command("systemctl", "daemon-reload").Run()
 	} else {
@@ -261,6 +287,8 @@ After=network.target
 Type=simple
 EnvironmentFile=-%%h/.config/systemd/user/stronghold-proxy.env
 ExecStart=%s
+# Hands the listening sockets to the installed binary without a restart
+ExecReload=/bin/kill -USR2 $MAINPID
 Restart=always
 RestartSec=5
 
@@ -280,6 +308,165 @@ WantedBy=default.target
 	return nil
 }
 
+// systemdFailsafeUnit is the oneshot unit the proxy service triggers when
+// it fails for good, which with Restart=always means it hit its start limit
+const systemdFailsafeUnit = "stronghold-failsafe.service"
+
+// systemdWatchdogLines returns the [Unit] lines that turn a crash loop into
+// a failed service and hand it to the fail-safe, or "" when the watchdog
+// policy is off
+func (s *ServiceManager) systemdWatchdogLines() string {
+	watchdog := s.config.Watchdog
+	if watchdog.policy(s.config.Scanning.FailOpen) == WatchdogOff {
+		return ""
+	}
+	return fmt.Sprintf("# Crash loops trip the watchdog fail-safe instead of restarting forever\nStartLimitIntervalSec=%d\nStartLimitBurst=%d\nOnFailure=%s\n",
+		int(watchdog.window().Seconds()), watchdog.maxRestarts(), systemdFailsafeUnit)
+}
+
+// installSystemdFailsafe writes the unit that runs 'stronghold watchdog trip'
+// as root, with the installing user's HOME so it reads their config
+func (s *ServiceManager) installSystemdFailsafe(serviceDir string) error {
+	cliBinary, err := os.Executable()
+	if err != nil {
+		cliBinary = "/usr/local/bin/stronghold"
+	}
+	unitContent := fmt.Sprintf(`[Unit]
+Description=Stronghold proxy crash-loop fail-safe
+
+[Service]
+Type=oneshot
+Environment=HOME=%s
+ExecStart=%s watchdog trip --reason "proxy service hit its start limit"
+`, os.Getenv("HOME"), cliBinary)
+
+	if err := os.WriteFile(filepath.Join(serviceDir, systemdFailsafeUnit), []byte(unitContent), 0644); err != nil {
+		return fmt.Errorf("failed to write fail-safe service file: %w", err)
+	}
+	return nil
+}
+
+// useSystemSystemd reports whether the proxy is installed as a system
+// systemd unit rather than a user one
+func useSystemSystemd() bool {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return false
+	}
+	_, err := os.Stat("/etc/systemd/system")
+	return err == nil
+}
+
+var serviceEnvPathFunc = serviceEnvPath
+
+// serviceEnvPath returns the environment file the service reads, or "" where
+// the service definition carries its environment itself (launchd) or there
+// is no service (containers, where the proxy is started with it)
+func serviceEnvPath() string {
+	switch detectInitSystemFunc() {
+	case initSystemd:
+		if useSystemSystemd() {
+			return "/etc/systemd/system/stronghold-proxy.env"
+		}
+		return filepath.Join(os.Getenv("HOME"), ".config", "systemd", "user", "stronghold-proxy.env")
+	case initOpenRC:
+		return openRCConfPath
+	case initRunit:
+		return filepath.Join(runitServiceDir, "conf")
+	default:
+		return ""
+	}
+}
+
+// reserveProxyPort makes sure the proxy port is still free, moving to the
+// next free one if another process took it since it was chosen, and
+// registers it with both the config and the service manager. The two are
+// written together: if either write fails, neither changes. Returns the
+// port the proxy moved from, or 0.
+func reserveProxyPort(config *CLIConfig) (int, error) {
+	oldPort := config.Proxy.Port
+	movedFrom := 0
+	if !config.IsPortAvailable() {
+		// A proxy already running there is ours from an earlier install
+		if _, err := fetchProxyHealth(config); err != nil {
+			newPort := FindAvailablePort(oldPort + 1)
+			if newPort == 0 {
+				return 0, fmt.Errorf("%s and no free port was found after it", describePortHolder(oldPort))
+			}
+			config.Proxy.Port = newPort
+			movedFrom = oldPort
+		}
+	}
+
+	envPath := serviceEnvPathFunc()
+	var previous []byte
+	hadPrevious := false
+	if envPath != "" {
+		data, err := os.ReadFile(envPath)
+		if err != nil && !os.IsNotExist(err) {
+			config.Proxy.Port = oldPort
+			return 0, fmt.Errorf("failed to read service environment: %w", err)
+		}
+		previous, hadPrevious = data, err == nil
+
+		env := mergeEnvFile(string(previous), map[string]string{
+			"STRONGHOLD_PROXY_PORT": strconv.Itoa(config.Proxy.Port),
+			"STRONGHOLD_PROXY_BIND": config.Proxy.Bind,
+		})
+		if err := os.MkdirAll(filepath.Dir(envPath), 0755); err != nil {
+			config.Proxy.Port = oldPort
+			return 0, fmt.Errorf("failed to create service directory: %w", err)
+		}
+		if err := writeFileAtomic(envPath, []byte(env), 0644); err != nil {
+			config.Proxy.Port = oldPort
+			return 0, fmt.Errorf("failed to register port with the service: %w", err)
+		}
+	}
+
+	if err := config.Save(); err != nil {
+		if envPath != "" {
+			if hadPrevious {
+				writeFileAtomic(envPath, previous, 0644)
+			} else {
+				os.Remove(envPath)
+			}
+		}
+		config.Proxy.Port = oldPort
+		return 0, err
+	}
+
+	return movedFrom, nil
+}
+
+// mergeEnvFile sets keys in the contents of a KEY=value environment file,
+// keeping every other line
+func mergeEnvFile(contents string, values map[string]string) string {
+	var b strings.Builder
+	written := make(map[string]bool)
+	for _, line := range strings.Split(contents, "\n") {
+		if line == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(line, "=")
+		if value, ok := values[strings.TrimSpace(key)]; ok {
+			line = key + "=" + value
+			written[strings.TrimSpace(key)] = true
+		}
+		b.WriteString(line + "\n")
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !written[key] {
+			b.WriteString(key + "=" + values[key] + "\n")
+		}
+	}
+	return b.String()
+}
+
 // uninstallLinuxService removes systemd service on Linux
 func (s *ServiceManager) uninstallLinuxService() error {
 	// Try system service first
@@ -288,6 +475,7 @@ func (s *ServiceManager) uninstallLinuxService() error {
 		exec.Command("systemctl", "stop", "stronghold-proxy").Run()
 		exec.Command("systemctl", "disable", "stronghold-proxy").Run()
 		os.Remove(servicePath)
+		os.Remove(filepath.Join("/etc/systemd/system", systemdFailsafeUnit))
 		exec.Command("systemctl", "daemon-reload").Run()
 		return nil
 	}
@@ -327,6 +515,10 @@ func (s *ServiceManager) installDarwinService() error {
     <dict>
         <key>STRONGHOLD_CONFIG</key>
         <string>%s</string>
+        <key>STRONGHOLD_PROXY_PORT</key>
+        <string>%d</string>
+        <key>STRONGHOLD_PROXY_BIND</key>
+        <string>%s</string>
     </dict>
     <key>RunAtLoad</key>
     <true/>
@@ -338,7 +530,7 @@ func (s *ServiceManager) installDarwinService() error {
     <string>/var/log/stronghold-proxy.log</string>
 </dict>
 </plist>
-`, proxyBinary, username, ConfigPath())
+`, proxyBinary, username, ConfigPath(), s.config.Proxy.Port, s.config.Proxy.Bind)
 
 	// Install as system daemon in /Library/LaunchDaemons (requires root)
 	launchDaemonsDir := "/Library/LaunchDaemons"
@@ -364,6 +556,10 @@ func (s *ServiceManager) installDarwinService() error {
     <dict>
         <key>STRONGHOLD_CONFIG</key>
         <string>%s</string>
+        <key>STRONGHOLD_PROXY_PORT</key>
+        <string>%d</string>
+        <key>STRONGHOLD_PROXY_BIND</key>
+        <string>%s</string>
     </dict>
     <key>RunAtLoad</key>
     <true/>
@@ -375,7 +571,7 @@ func (s *ServiceManager) installDarwinService() error {
     <string>%s/logs/proxy.log</string>
 </dict>
 </plist>
-`, proxyBinary, ConfigPath(), configDir, configDir)
+`, proxyBinary, ConfigPath(), s.config.Proxy.Port, s.config.Proxy.Bind, configDir, configDir)
 
 		plistPath = filepath.Join(launchAgentsDir, "com.stronghold.proxy.plist")
 		if err := os.WriteFile(plistPath, []byte(userPlistContent), 0644); err != nil {
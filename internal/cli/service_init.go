@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// initSystem is the service manager that supervises the proxy
+type initSystem string
+
+const (
+	initSystemd initSystem = "systemd"
+	initLaunchd initSystem = "launchd"
+	initOpenRC  initSystem = "openrc"
+	initRunit   initSystem = "runit"
+	initNone    initSystem = "none" // Containers and other systems without an init system
+)
+
+const (
+	openRCScriptPath = "/etc/init.d/stronghold-proxy"
+	openRCConfPath   = "/etc/conf.d/stronghold-proxy"
+	runitServiceDir  = "/etc/sv/stronghold-proxy"
+)
+
+// runitServiceDirs are where runsvdir looks for enabled services, by distro
+var runitServiceDirs = []string{
+	"/var/service",       // Void
+	"/etc/service",       // Debian, Ubuntu
+	"/run/runit/service", // Artix
+}
+
+var detectInitSystemFunc = func() initSystem {
+	pid1, _ := os.ReadFile("/proc/1/comm")
+	return detectInitSystem(runtime.GOOS, strings.TrimSpace(string(pid1)), pathExists)
+}
+
+// detectInitSystem works out which init system runs services, from the OS,
+// the name of PID 1 and the marker directories each init system creates at
+// boot. Having an init system installed is not enough: containers often
+// ship one that never runs.
+func detectInitSystem(goos, pid1 string, exists func(string) bool) initSystem {
+	if goos == "darwin" {
+		return initLaunchd
+	}
+	switch {
+	case exists("/run/systemd/system"):
+		return initSystemd
+	case exists("/run/openrc"):
+		return initOpenRC
+	case pid1 == "runit" || pid1 == "runsvdir" || exists("/run/runit"):
+		return initRunit
+	default:
+		return initNone
+	}
+}
+
+// pathExists reports whether anything exists at path
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// inContainer reports whether this process runs in a Docker or Podman
+// container
+func inContainer() bool {
+	return pathExists("/.dockerenv") || pathExists("/run/.containerenv")
+}
+
+// installOpenRCService installs an OpenRC service supervised by
+// supervise-daemon, which restarts the proxy if it exits
+func (s *ServiceManager) installOpenRCService() error {
+	username := StrongholdUsername()
+	script := fmt.Sprintf(`#!/sbin/openrc-run
+name="stronghold-proxy"
+description="Stronghold Proxy Service"
+command="%s"
+command_user="%s:%s"
+supervisor="supervise-daemon"
+respawn_delay=5
+output_log="/var/log/stronghold-proxy.log"
+error_log="/var/log/stronghold-proxy.log"
+
+# Port and bind address come from %s
+supervise_daemon_args="--env STRONGHOLD_CONFIG=%s --env STRONGHOLD_PROXY_PORT=${STRONGHOLD_PROXY_PORT} --env STRONGHOLD_PROXY_BIND=${STRONGHOLD_PROXY_BIND}"
+
+depend() {
+	need net
+}
+`, s.getProxyBinaryPath(), username, username, openRCConfPath, ConfigPath())
+
+	if err := os.WriteFile(openRCScriptPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write OpenRC service: %w", err)
+	}
+	if output, err := exec.Command("rc-update", "add", "stronghold-proxy", "default").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to enable OpenRC service: %s - %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// uninstallOpenRCService stops and removes the OpenRC service
+func (s *ServiceManager) uninstallOpenRCService() error {
+	if _, err := os.Stat(openRCScriptPath); err == nil {
+		exec.Command("rc-service", "stronghold-proxy", "stop").Run()
+		exec.Command("rc-update", "del", "stronghold-proxy", "default").Run()
+		os.Remove(openRCScriptPath)
+	}
+	os.Remove(openRCConfPath)
+	return nil
+}
+
+// installRunitService installs a runit service, which runsv restarts if
+// the proxy exits, and links it into the enabled services
+func (s *ServiceManager) installRunitService() error {
+	enabledDir := runitEnabledDir()
+	if enabledDir == "" {
+		return fmt.Errorf("no runit service directory found (looked in %s)", strings.Join(runitServiceDirs, ", "))
+	}
+
+	script := fmt.Sprintf(`#!/bin/sh
+exec 2>&1
+STRONGHOLD_CONFIG=%s
+# Port and bind address
+[ -r ./conf ] && . ./conf
+export STRONGHOLD_CONFIG STRONGHOLD_PROXY_PORT STRONGHOLD_PROXY_BIND
+exec chpst -u %s %s
+`, ConfigPath(), StrongholdUsername(), s.getProxyBinaryPath())
+
+	if err := os.MkdirAll(runitServiceDir, 0755); err != nil {
+		return fmt.Errorf("failed to create runit service: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runitServiceDir, "run"), []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write runit service: %w", err)
+	}
+
+	link := filepath.Join(enabledDir, "stronghold-proxy")
+	if _, err := os.Lstat(link); os.IsNotExist(err) {
+		if err := os.Symlink(runitServiceDir, link); err != nil {
+			return fmt.Errorf("failed to enable runit service: %w", err)
+		}
+	}
+	return nil
+}
+
+// uninstallRunitService stops the runit service and removes it
+func (s *ServiceManager) uninstallRunitService() error {
+	for _, dir := range runitServiceDirs {
+		link := filepath.Join(dir, "stronghold-proxy")
+		if _, err := os.Lstat(link); err == nil {
+			exec.Command("sv", "down", link).Run()
+			os.Remove(link)
+		}
+	}
+	os.RemoveAll(runitServiceDir)
+	return nil
+}
+
+// runitEnabledDir returns the directory runsvdir supervises on this system
+func runitEnabledDir() string {
+	for _, dir := range runitServiceDirs {
+		if pathExists(dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// checkServiceManager reports which init system will run the proxy
+func checkServiceManager() CheckResult {
+	result := CheckResult{Name: "Service Manager"}
+
+	switch detected := detectInitSystemFunc(); detected {
+	case initNone:
+		result.Status = CheckWarn
+		where := "No init system found"
+		if inContainer() {
+			where = "Running in a container without an init system"
+		}
+		result.Message = where + "; nothing restarts the proxy if it exits"
+		result.Fix = "Run 'stronghold supervise' as the container entrypoint, or under your own process supervisor"
+	case initRunit:
+		result.Status = CheckPass
+		result.Message = "runit"
+		if runitEnabledDir() == "" {
+			result.Status = CheckWarn
+			result.Message = "runit is running but no service directory was found"
+			result.Fix = fmt.Sprintf("Create one of %s for runsvdir to supervise", strings.Join(runitServiceDirs, ", "))
+		}
+	default:
+		result.Status = CheckPass
+		result.Message = string(detected)
+	}
+
+	return result
+}
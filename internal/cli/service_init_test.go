@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestDetectInitSystem(t *testing.T) {
+	tests := []struct {
+		name  string
+		goos  string
+		pid1  string
+		paths []string
+		want  initSystem
+	}{
+		{"macOS", "darwin", "launchd", nil, initLaunchd},
+		{"systemd", "linux", "systemd", []string{"/run/systemd/system"}, initSystemd},
+		{"OpenRC", "linux", "init", []string{"/run/openrc"}, initOpenRC},
+		{"runit as PID 1", "linux", "runit", nil, initRunit},
+		{"runit under another init", "linux", "init", []string{"/run/runit"}, initRunit},
+		{"container", "linux", "sh", []string{"/.dockerenv"}, initNone},
+		// An installed but not running init system does not count
+		{"container with OpenRC installed", "linux", "tini", []string{"/etc/init.d", "/sbin/openrc-run"}, initNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists := func(path string) bool {
+				for _, p := range tt.paths {
+					if p == path {
+						return true
+					}
+				}
+				return false
+			}
+			if got := detectInitSystem(tt.goos, tt.pid1, exists); got != tt.want {
+				t.Errorf("detectInitSystem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckServiceManager_NoInit(t *testing.T) {
+	orig := detectInitSystemFunc
+	defer func() { detectInitSystemFunc = orig }()
+	detectInitSystemFunc = func() initSystem { return initNone }
+
+	result := checkServiceManager()
+	if result.Status != CheckWarn {
+		t.Fatalf("expected a warning without an init system, got %s: %s", result.Status, result.Message)
+	}
+	if result.Fix == "" {
+		t.Error("expected a fix pointing at 'stronghold supervise'")
+	}
+
+	detectInitSystemFunc = func() initSystem { return initOpenRC }
+	if result := checkServiceManager(); result.Status != CheckPass || result.Message != "openrc" {
+		t.Errorf("expected OpenRC to pass, got %s: %s", result.Status, result.Message)
+	}
+}
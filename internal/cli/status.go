@@ -2,12 +2,16 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"stronghold/internal/proxyversion"
+	"stronghold/internal/usdc"
 	"stronghold/internal/wallet"
 )
 
@@ -46,6 +50,18 @@ func Status() error {
 	tp := NewTransparentProxy(config)
 	tpEnabled, _ := tp.Status()
 
+	// The running proxy's health, for its version and policy violations
+	var health *proxyHealth
+	var healthErr error
+	if proxyStatus.Running {
+		health, healthErr = fetchProxyHealth(config)
+	}
+
+	// A tripped watchdog matters more than anything below
+	if state := loadFailsafeState(); state != nil {
+		printFailsafeState(state)
+	}
+
 	// Proxy status
 	fmt.Println("Proxy:")
 	if proxyStatus.Running {
@@ -55,10 +71,22 @@ func Status() error {
 		fmt.Printf("  Address:    %s\n", config.GetProxyAddr())
 		if tpEnabled {
 			fmt.Printf("  Mode:       %s\n", successStyle.Render("Network-level (transparent)"))
+			printQUICStatus(tp, config.Proxy.QUICMode())
 		} else {
 			fmt.Printf("  Mode:       %s\n", warningStyle.Render("Not intercepting traffic"))
 		}
-		fmt.Printf("  Protection: %s\n", successStyle.Render("Enabled"))
+		if config.Scanning.Mode == "shadow" {
+			fmt.Printf("  Protection: %s\n", warningStyle.Render("Shadow (logging only, nothing blocked)"))
+		} else {
+			fmt.Printf("  Protection: %s\n", successStyle.Render("Enabled"))
+		}
+		if health != nil {
+			printProxyVersion(health)
+			printProxyScanning(health)
+			printProxyClock(health)
+			printProxyRegion(health)
+			printProxyPinning(health)
+		}
 	} else {
 		fmt.Printf("  Status:     %s\n", errorStyle.Render("Stopped"))
 		fmt.Printf("  Protection: %s\n", warningStyle.Render("Disabled"))
@@ -148,14 +176,37 @@ func Status() error {
 	fmt.Printf("  Warned:     %d (%.2f%%)\n",
 		config.Stats.WarnedToday,
 		percentage(config.Stats.WarnedToday, config.Stats.RequestsToday))
-	fmt.Printf("  Cost:       $%.2f\n", config.Stats.CostToday)
+	if health != nil && health.ScanSpend != nil {
+		printScanSpend(health)
+	} else {
+		fmt.Printf("  Cost:       $%.2f\n", config.Stats.CostToday)
+	}
 	fmt.Println()
 
+	// Policy violations reported by the running proxy
+	if proxyStatus.Running && len(config.Policies.Rules) > 0 {
+		fmt.Println("Policies:")
+		fmt.Printf("  Rules:      %d\n", len(config.Policies.Rules))
+		if healthErr != nil {
+			fmt.Printf("  Violations: %s\n", warningStyle.Render("unavailable ("+healthErr.Error()+")"))
+		} else {
+			fmt.Printf("  Violations: %d\n", health.PolicyViolations)
+			recent := health.RecentViolations
+			if len(recent) > 3 {
+				recent = recent[len(recent)-3:]
+			}
+			for _, v := range recent {
+				fmt.Printf("    %s  %s → %s (%s)\n", v.Time.Local().Format("15:04:05"), v.Rule, v.Host, v.Reason)
+			}
+		}
+		fmt.Println()
+	}
+
 	// Configuration
 	fmt.Println("Configuration:")
 	fmt.Printf("  Config:     %s\n", ConfigPath())
 	fmt.Printf("  Logs:       %s\n", config.Logging.File)
-	fmt.Printf("  API:        %s\n", config.API.Endpoint)
+	fmt.Printf("  API:        %s\n", config.API.Endpoint.String())
 	fmt.Println()
 
 	// Quick actions
@@ -171,6 +222,163 @@ func Status() error {
 	return nil
 }
 
+// proxyHealth is the subset of the proxy's /health response shown by status
+type proxyHealth struct {
+	Version          string               `json:"version"`
+	PID              int                  `json:"pid"`
+	Update           *proxyversion.Update `json:"update"`
+	LocalRules       string               `json:"local_rules"`
+	PolicyViolations int64                `json:"policy_violations"`
+	Clock            *struct {
+		SkewMs       int64 `json:"skew_ms"`
+		CorrectionMs int64 `json:"correction_ms"`
+	} `json:"clock"`
+	Regions *struct {
+		Active    string `json:"active"`
+		Endpoints []struct {
+			Endpoint    string `json:"endpoint"`
+			Healthy     bool   `json:"healthy"`
+			Misdirected bool   `json:"misdirected"`
+			LatencyMs   int64  `json:"latency_ms"`
+		} `json:"endpoints"`
+	} `json:"regions"`
+	Pinning *struct {
+		Mismatches int64  `json:"mismatches"`
+		LastHost   string `json:"last_host"`
+		LastPin    string `json:"last_pin"`
+	} `json:"pinning"`
+	ScanSpend *struct {
+		LastHour      usdc.MicroUSDC `json:"last_hour"`
+		LastDay       usdc.MicroUSDC `json:"last_day"`
+		ScansLastHour int64          `json:"scans_last_hour"`
+		ScansLastDay  int64          `json:"scans_last_day"`
+	} `json:"scan_spend"`
+	RecentViolations []struct {
+		Time   time.Time `json:"time"`
+		Rule   string    `json:"rule"`
+		Host   string    `json:"host"`
+		Reason string    `json:"reason"`
+	} `json:"recent_violations"`
+}
+
+// fetchProxyHealth queries the local proxy's /health endpoint
+func fetchProxyHealth(config *CLIConfig) (*proxyHealth, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(config.GetProxyURL() + "/health")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy returned %d", resp.StatusCode)
+	}
+
+	var health proxyHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("invalid health response: %w", err)
+	}
+	return &health, nil
+}
+
+// printProxyVersion shows the running proxy's version and whether the API
+// asked for it to be upgraded
+func printProxyVersion(health *proxyHealth) {
+	if health.Version == "" {
+		return
+	}
+	u := health.Update
+	switch {
+	case u == nil || u.Status == proxyversion.StatusCurrent:
+		fmt.Printf("  Version:    %s\n", health.Version)
+	case u.Status == proxyversion.StatusRequired:
+		fmt.Printf("  Version:    %s %s\n", health.Version, errorStyle.Render("(upgrade required)"))
+		if u.Enforced {
+			fmt.Printf("              %s\n", errorStyle.Render("Scans are refused until you upgrade"))
+		}
+	default:
+		fmt.Printf("  Version:    %s %s\n", health.Version, warningStyle.Render("(upgrade recommended)"))
+	}
+	if u != nil && u.Reason != "" {
+		fmt.Printf("              %s\n", u.Reason)
+	}
+}
+
+// printProxyClock warns when the proxy measured the local clock off from the
+// API's, and whether its payment timestamps are corrected for it
+func printProxyClock(health *proxyHealth) {
+	if health.Clock == nil {
+		return
+	}
+	skew := time.Duration(health.Clock.SkewMs) * time.Millisecond
+	switch abs := skew.Abs(); {
+	case abs < clockSkewWarning:
+		return
+	case abs < wallet.MaxClockCorrection:
+		fmt.Printf("  Clock:      %s\n", warningStyle.Render(describeClockSkew(skew)))
+		fmt.Printf("              Payment timestamps are shifted by %s to compensate\n", time.Duration(health.Clock.CorrectionMs)*time.Millisecond)
+	default:
+		fmt.Printf("  Clock:      %s\n", errorStyle.Render(describeClockSkew(skew)))
+		fmt.Printf("              Payments will be rejected until the system clock is synced\n")
+	}
+}
+
+// printProxyRegion shows the regional endpoint the proxy pinned scans to,
+// when it was given more than one
+func printProxyRegion(health *proxyHealth) {
+	r := health.Regions
+	if r == nil {
+		return
+	}
+	healthy := 0
+	var latency int64
+	for _, e := range r.Endpoints {
+		if e.Healthy && !e.Misdirected {
+			healthy++
+		}
+		if e.Endpoint == r.Active {
+			latency = e.LatencyMs
+		}
+	}
+	summary := fmt.Sprintf("%d of %d healthy", healthy, len(r.Endpoints))
+	if latency > 0 {
+		summary = fmt.Sprintf("%dms, %s", latency, summary)
+	}
+	if healthy == 0 {
+		fmt.Printf("  Region:     %s %s\n", r.Active, errorStyle.Render("("+summary+")"))
+		return
+	}
+	fmt.Printf("  Region:     %s (%s)\n", r.Active, summary)
+}
+
+// printProxyPinning alerts when the proxy refused API connections that
+// presented a certificate key it doesn't have pinned
+func printProxyPinning(health *proxyHealth) {
+	p := health.Pinning
+	if p == nil || p.Mismatches == 0 {
+		return
+	}
+	fmt.Printf("  API pin:    %s\n", errorStyle.Render(fmt.Sprintf("%d connections refused, possible interception", p.Mismatches)))
+	fmt.Printf("              %s presented %s\n", p.LastHost, p.LastPin)
+}
+
+// printProxyScanning notes when the proxy scans with its local rules instead
+// of the API
+func printProxyScanning(health *proxyHealth) {
+	if health.LocalRules == "" {
+		return
+	}
+	fmt.Printf("  Scanning:   %s\n", warningStyle.Render("Local rules ("+health.LocalRules+")"))
+}
+
+// printScanSpend shows what the running proxy has paid for scans over the
+// last day and hour, counted as the scans are made
+func printScanSpend(health *proxyHealth) {
+	s := health.ScanSpend
+	fmt.Printf("  Cost:       $%s (%d scans)\n", s.LastDay, s.ScansLastDay)
+	fmt.Printf("  Last hour:  $%s (%d scans)\n", s.LastHour, s.ScansLastHour)
+}
+
 // percentage calculates a percentage safely
 func percentage(part, total int64) float64 {
 	if total == 0 {
@@ -241,3 +449,37 @@ func Logs(follow bool, lines int) error {
 
 	return nil
 }
+
+// printFailsafeState warns that the watchdog changed the firewall after the
+// proxy crash-looped
+func printFailsafeState(state *FailsafeState) {
+	fmt.Println(errorStyle.Render("Watchdog: proxy crash-looped at " + state.Time.Local().Format(time.RFC3339) + " (" + state.Reason + ")"))
+	if state.Policy == WatchdogFailClosed {
+		fmt.Println(errorStyle.Render("  Kill switch is on: outbound web traffic is refused"))
+	} else {
+		fmt.Println(errorStyle.Render("  Interception removed: web traffic is going out unscanned"))
+	}
+	fmt.Println("  Check 'stronghold logs', then run 'stronghold enable' to restore protection")
+	fmt.Println()
+}
+
+// printQUICStatus reports how outbound QUIC (HTTP/3) is handled and how many
+// attempts the firewall has seen
+func printQUICStatus(tp *TransparentProxy, mode string) {
+	if mode == QUICAllow {
+		fmt.Printf("  QUIC/HTTP3: %s\n", warningStyle.Render("Allowed (not scanned)"))
+		return
+	}
+
+	packets, ok := tp.QUICAttempts()
+	observed := ""
+	if ok {
+		observed = fmt.Sprintf(" - %d packets observed", packets)
+	}
+
+	if mode == QUICLog {
+		fmt.Printf("  QUIC/HTTP3: %s%s\n", warningStyle.Render("Logged (not scanned)"), observed)
+	} else {
+		fmt.Printf("  QUIC/HTTP3: %s%s\n", successStyle.Render("Blocked (falls back to TCP)"), observed)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	superviseMinBackoff  = time.Second
+	superviseMaxBackoff  = 30 * time.Second
+	superviseStableAfter = time.Minute // A run this long resets the backoff
+	superviseStopTimeout = 10 * time.Second
+)
+
+// Supervise runs the proxy in the foreground and restarts it whenever it
+// exits, for containers and other systems without an init system to do so.
+// SIGINT and SIGTERM stop the proxy and return.
+func Supervise() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	serviceManager := NewServiceManager(config)
+	proxyBinary := serviceManager.getProxyBinaryPath()
+	if _, err := os.Stat(proxyBinary); os.IsNotExist(err) {
+		return fmt.Errorf("proxy binary not found at %s", proxyBinary)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Supervising %s on %s\n", proxyBinary, config.GetProxyAddr())
+	superviseLoop(ctx, newCrashWatchdog(config), func(ctx context.Context) error {
+		return runSupervised(ctx, serviceManager.proxyCommand(proxyBinary))
+	})
+	fmt.Println("Proxy stopped")
+	return nil
+}
+
+// superviseLoop calls run until ctx is cancelled, waiting between runs for a
+// backoff that grows while the proxy keeps exiting soon after starting.
+// Exits and stable runs are reported to watchdog.
+func superviseLoop(ctx context.Context, watchdog *crashWatchdog, run func(context.Context) error) {
+	var backoff time.Duration
+	for {
+		started := time.Now()
+		stable := time.AfterFunc(superviseStableAfter, watchdog.stable)
+		err := run(ctx)
+		stable.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+
+		ranFor := time.Since(started)
+		backoff = nextSuperviseBackoff(backoff, ranFor)
+		reason := "exited"
+		if err != nil {
+			reason = err.Error()
+		}
+		fmt.Printf("Proxy stopped after %s (%s); restarting in %s\n", ranFor.Round(time.Second), reason, backoff)
+		watchdog.exited(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// nextSuperviseBackoff doubles the wait before restarting a proxy that
+// exited soon after starting, up to superviseMaxBackoff, and starts over
+// once a run lasted superviseStableAfter
+func nextSuperviseBackoff(previous, ranFor time.Duration) time.Duration {
+	if previous == 0 || ranFor >= superviseStableAfter {
+		return superviseMinBackoff
+	}
+	return min(previous*2, superviseMaxBackoff)
+}
+
+// runSupervised runs cmd with its output on ours until it exits. When ctx is
+// cancelled first, cmd is sent SIGTERM, and killed if it has not exited
+// within superviseStopTimeout.
+func runSupervised(ctx context.Context, cmd *exec.Cmd) error {
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(superviseStopTimeout):
+			cmd.Process.Kill()
+			<-done
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNextSuperviseBackoff(t *testing.T) {
+	tests := []struct {
+		previous, ranFor, want time.Duration
+	}{
+		{0, time.Second, superviseMinBackoff},
+		{superviseMinBackoff, time.Second, 2 * superviseMinBackoff},
+		{20 * time.Second, time.Second, superviseMaxBackoff},
+		{superviseMaxBackoff, time.Second, superviseMaxBackoff},
+		// A proxy that ran for a while before exiting starts over
+		{superviseMaxBackoff, 2 * superviseStableAfter, superviseMinBackoff},
+	}
+	for _, tt := range tests {
+		if got := nextSuperviseBackoff(tt.previous, tt.ranFor); got != tt.want {
+			t.Errorf("nextSuperviseBackoff(%s, %s) = %s, want %s", tt.previous, tt.ranFor, got, tt.want)
+		}
+	}
+}
+
+func TestSuperviseLoop_RestartsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	out, _ := captureStdout(t, func() error {
+		superviseLoop(ctx, nil, func(ctx context.Context) error {
+			runs++
+			if runs == 2 {
+				cancel()
+				return ctx.Err()
+			}
+			return errors.New("exit status 1")
+		})
+		return nil
+	})
+
+	if runs != 2 {
+		t.Fatalf("expected the proxy to be restarted once, got %d runs", runs)
+	}
+	if want := "restarting in 1s"; !strings.Contains(out, want) {
+		t.Errorf("expected %q in output:\n%s", want, out)
+	}
+}
+
+func TestRunSupervised_StopsOnCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	started := time.Now()
+	err := runSupervised(ctx, exec.Command("sleep", "30"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the run to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("expected the proxy to stop on SIGTERM, took %s", elapsed)
+	}
+}
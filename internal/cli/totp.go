@@ -2,20 +2,28 @@ package cli
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 )
 
 func defaultDeviceLabel() string {
-	host, err := os.Hostname()
-	if err != nil || host == "" {
+	host := deviceHostname()
+	if host == "" {
 		host = "unknown"
 	}
 	return fmt.Sprintf("%s (%s)", host, runtime.GOOS)
 }
 
+func deviceHostname() string {
+	host, _ := os.Hostname()
+	return host
+}
+
 func promptDeviceTTL() int {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Println("Trust this device for:")
@@ -73,7 +81,8 @@ func isValidTOTPCode(code string) bool {
 func printTOTPSetup(setup *TOTPSetupResponse) {
 	fmt.Println()
 	fmt.Println(successStyle.Render("✓ TOTP setup required for server wallet storage"))
-	fmt.Println("Secret:", setup.Secret)
+	fmt.Println("Secret:", setup.Secret.Reveal())
+	setup.Secret.Zero()
 	fmt.Println("OTPAuth URL:", setup.OTPAuthURL)
 	if len(setup.RecoveryCodes) > 0 {
 		fmt.Println()
@@ -90,14 +99,19 @@ func ensureTrustedDevice(apiClient *APIClient, config *CLIConfig, totpRequired b
 		return nil
 	}
 	fmt.Println(warningStyle.Render("TOTP required to trust this device"))
+	if promptApproveFromDevice() {
+		return awaitDeviceApproval(apiClient, config, promptDeviceTTL())
+	}
 	code, isRecovery, err := promptTOTPCode()
 	if err != nil {
 		return fmt.Errorf("failed to read TOTP: %w", err)
 	}
 	ttlDays := promptDeviceTTL()
 	req := &TOTPVerifyRequest{
-		DeviceLabel:   defaultDeviceLabel(),
-		DeviceTTLDays: ttlDays,
+		DeviceLabel:    defaultDeviceLabel(),
+		DeviceTTLDays:  ttlDays,
+		DeviceOS:       runtime.GOOS,
+		DeviceHostname: deviceHostname(),
 	}
 	if isRecovery {
 		req.RecoveryCode = code
@@ -113,3 +127,61 @@ func ensureTrustedDevice(apiClient *APIClient, config *CLIConfig, totpRequired b
 	apiClient.SetDeviceToken(resp.DeviceToken)
 	return nil
 }
+
+func promptApproveFromDevice() bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Approve from another trusted device instead? [y/N]: ")
+	resp, _ := reader.ReadString('\n')
+	resp = strings.ToLower(strings.TrimSpace(resp))
+	return resp == "y" || resp == "yes"
+}
+
+// awaitDeviceApproval requests trust for this machine and polls until an
+// existing trusted device approves or denies it, or the request expires.
+// On approval the device token is stored in config.
+func awaitDeviceApproval(apiClient *APIClient, config *CLIConfig, ttlDays int) error {
+	approval, err := apiClient.RequestDeviceApproval(&DeviceApprovalRequest{
+		DeviceLabel:    defaultDeviceLabel(),
+		DeviceTTLDays:  ttlDays,
+		DeviceOS:       runtime.GOOS,
+		DeviceHostname: deviceHostname(),
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+			return fmt.Errorf("no trusted device can approve this machine, verify with TOTP instead")
+		}
+		return fmt.Errorf("failed to request device approval: %w", err)
+	}
+
+	fmt.Println(infoStyle.Render("Waiting for approval from a trusted device..."))
+	fmt.Printf("  Request ID: %s\n", approval.ID)
+	fmt.Printf("  On a trusted device, run: stronghold device approve %s\n", approval.ID)
+
+	interval := time.Duration(approval.PollIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+
+	// Poll with the pending token; it becomes trusted once approved
+	apiClient.SetDeviceToken(approval.DeviceToken)
+	for {
+		time.Sleep(interval)
+		status, err := apiClient.GetDeviceApproval(approval.ID)
+		if err != nil {
+			apiClient.SetDeviceToken(config.Auth.DeviceToken)
+			return fmt.Errorf("failed to check device approval: %w", err)
+		}
+		switch status.Status {
+		case "pending":
+			continue
+		case "approved":
+			config.Auth.DeviceToken = approval.DeviceToken
+			fmt.Println(successStyle.Render("✓ Device approved"))
+			return nil
+		default:
+			apiClient.SetDeviceToken(config.Auth.DeviceToken)
+			return fmt.Errorf("device approval %s", status.Status)
+		}
+	}
+}
@@ -9,6 +9,10 @@ import (
 	"strings"
 )
 
+// quicCounterName names the nftables counter, iptables rule comment, and pf
+// label that track outbound QUIC attempts
+const quicCounterName = "stronghold_quic"
+
 // TransparentProxy manages transparent proxying via iptables/nftables/pf
 type TransparentProxy struct {
 	config *CLIConfig
@@ -74,12 +78,20 @@ func (t *TransparentProxy) hasIptables() bool {
 	return err == nil
 }
 
+func (t *TransparentProxy) hasIp6tables() bool {
+	_, err := exec.LookPath("ip6tables")
+	return err == nil
+}
+
 func (t *TransparentProxy) hasNftables() bool {
 	_, err := exec.LookPath("nft")
 	return err == nil
 }
 
 func (t *TransparentProxy) enableLinux() error {
+	// Interception replaces a kill switch left by the watchdog
+	t.disableKillSwitch()
+
 	// Determine which tool to use
 	if t.hasNftables() {
 		return t.enableNftables()
@@ -92,6 +104,7 @@ func (t *TransparentProxy) enableLinux() error {
 
 func (t *TransparentProxy) disableLinux() error {
 	// Try both, ignore errors
+	t.disableKillSwitch()
 	if t.hasNftables() {
 		t.disableNftables()
 	}
@@ -120,7 +133,39 @@ func (t *TransparentProxy) statusLinux() (bool, error) {
 	return false, nil
 }
 
-// enableIptables sets up iptables rules for transparent proxying
+// ipv6Covered reports whether active rules also redirect (or reject) IPv6 traffic
+func (t *TransparentProxy) ipv6Covered() bool {
+	switch runtime.GOOS {
+	case "linux":
+		// The inet-family nftables table covers both IPv4 and IPv6
+		if t.hasNftables() && exec.Command("nft", "list", "table", "inet", "stronghold").Run() == nil {
+			return true
+		}
+		if t.hasIp6tables() {
+			for _, table := range []string{"nat", "filter"} {
+				output, err := exec.Command("ip6tables", "-t", table, "-L", "OUTPUT", "-n").Output()
+				if err == nil && strings.Contains(string(output), "STRONGHOLD") {
+					return true
+				}
+			}
+		}
+		return false
+	case "darwin":
+		// rdr rules are listed with -sn, the IPv6 block rule with -sr
+		for _, flag := range []string{"-sn", "-sr"} {
+			output, err := exec.Command("pfctl", "-a", "stronghold", flag).Output()
+			if err == nil && strings.Contains(string(output), "inet6") {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// enableIptables sets up iptables (and ip6tables, when present) rules for
+// transparent proxying
 func (t *TransparentProxy) enableIptables() error {
 	proxyPort := strconv.Itoa(t.config.Proxy.Port)
 
@@ -151,12 +196,18 @@ func (t *TransparentProxy) enableIptables() error {
 		{"iptables", "-t", "nat", "-A", "OUTPUT", "-p", "tcp", "-j", "STRONGHOLD"},
 	}
 
+	rules = append(rules, t.iptablesQUICRules("iptables", uid, "127.0.0.0/8")...)
+	if t.hasIp6tables() {
+		rules = append(rules, t.ip6tablesRules(uid, proxyPort)...)
+		rules = append(rules, t.iptablesQUICRules("ip6tables", uid, "::1/128")...)
+	}
+
 	for _, rule := range rules {
 		cmd := exec.Command(rule[0], rule[1:]...)
 		if output, err := cmd.CombinedOutput(); err != nil {
 			// Ignore "chain already exists" errors
 			if !strings.Contains(string(output), "Chain already exists") {
-				return fmt.Errorf("iptables failed: %s - %s", err, string(output))
+				return fmt.Errorf("%s failed: %s - %s", rule[0], err, string(output))
 			}
 		}
 	}
@@ -167,11 +218,83 @@ func (t *TransparentProxy) enableIptables() error {
 	return nil
 }
 
+// ip6tablesRules returns the IPv6 rules. With IPv6 interception enabled,
+// traffic is redirected to the proxy's [::1] listener; otherwise outbound
+// IPv6 HTTP(S) is rejected so clients fall back to IPv4 instead of leaking
+// past the proxy.
+func (t *TransparentProxy) ip6tablesRules(uid, proxyPort string) [][]string {
+	if !t.config.Proxy.IPv6Enabled() {
+		return [][]string{
+			{"ip6tables", "-N", "STRONGHOLD", "-m", "comment", "--comment", "Stronghold IPv6 leak guard"},
+			{"ip6tables", "-A", "STRONGHOLD", "-m", "owner", "--uid-owner", uid, "-j", "RETURN"},
+			{"ip6tables", "-A", "STRONGHOLD", "-d", "::1/128", "-j", "RETURN"},
+			{"ip6tables", "-A", "STRONGHOLD", "-d", "fc00::/7", "-j", "RETURN"},
+			{"ip6tables", "-A", "STRONGHOLD", "-d", "fe80::/10", "-j", "RETURN"},
+			{"ip6tables", "-A", "STRONGHOLD", "-p", "tcp", "--dport", "80", "-j", "REJECT", "--reject-with", "tcp-reset"},
+			{"ip6tables", "-A", "STRONGHOLD", "-p", "tcp", "--dport", "443", "-j", "REJECT", "--reject-with", "tcp-reset"},
+			{"ip6tables", "-A", "OUTPUT", "-p", "tcp", "-j", "STRONGHOLD"},
+		}
+	}
+
+	return [][]string{
+		{"ip6tables", "-t", "nat", "-N", "STRONGHOLD", "-m", "comment", "--comment", "Stronghold transparent proxy"},
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-m", "owner", "--uid-owner", uid, "-j", "RETURN"},
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-d", "::1/128", "-j", "RETURN"},
+		// Unique local and link-local addresses (IPv6 equivalents of private networks)
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-d", "fc00::/7", "-j", "RETURN"},
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-d", "fe80::/10", "-j", "RETURN"},
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-p", "tcp", "--dport", "80", "-j", "REDIRECT", "--to-port", proxyPort},
+		{"ip6tables", "-t", "nat", "-A", "STRONGHOLD", "-p", "tcp", "--dport", "443", "-j", "REDIRECT", "--to-port", proxyPort},
+		{"ip6tables", "-t", "nat", "-A", "OUTPUT", "-p", "tcp", "-j", "STRONGHOLD"},
+	}
+}
+
+// iptablesQUICRules returns the filter rules that count (and in block mode
+// reject) outbound QUIC, which would otherwise bypass the TCP proxy
+func (t *TransparentProxy) iptablesQUICRules(bin, uid, loopback string) [][]string {
+	mode := t.config.Proxy.QUICMode()
+	if mode == QUICAllow {
+		return nil
+	}
+
+	verdict := []string{"-j", "RETURN"}
+	if mode == QUICBlock {
+		verdict = []string{"-j", "REJECT"}
+	}
+
+	return [][]string{
+		{bin, "-N", "STRONGHOLD_QUIC"},
+		{bin, "-A", "STRONGHOLD_QUIC", "-m", "owner", "--uid-owner", uid, "-j", "RETURN"},
+		{bin, "-A", "STRONGHOLD_QUIC", "-d", loopback, "-j", "RETURN"},
+		append([]string{bin, "-A", "STRONGHOLD_QUIC", "-m", "comment", "--comment", quicCounterName}, verdict...),
+		{bin, "-A", "OUTPUT", "-p", "udp", "--dport", "443", "-j", "STRONGHOLD_QUIC"},
+	}
+}
+
 func (t *TransparentProxy) disableIptables() error {
 	// Remove rules (ignore errors if they don't exist)
 	exec.Command("iptables", "-t", "nat", "-D", "OUTPUT", "-p", "tcp", "-j", "STRONGHOLD").Run()
 	exec.Command("iptables", "-t", "nat", "-F", "STRONGHOLD").Run()
 	exec.Command("iptables", "-t", "nat", "-X", "STRONGHOLD").Run()
+
+	bins := []string{"iptables"}
+	if t.hasIp6tables() {
+		bins = append(bins, "ip6tables")
+	}
+	for _, bin := range bins {
+		exec.Command(bin, "-D", "OUTPUT", "-p", "udp", "--dport", "443", "-j", "STRONGHOLD_QUIC").Run()
+		exec.Command(bin, "-F", "STRONGHOLD_QUIC").Run()
+		exec.Command(bin, "-X", "STRONGHOLD_QUIC").Run()
+	}
+
+	if t.hasIp6tables() {
+		// Remove both the redirect (nat) and leak guard (filter) variants
+		for _, table := range []string{"nat", "filter"} {
+			exec.Command("ip6tables", "-t", table, "-D", "OUTPUT", "-p", "tcp", "-j", "STRONGHOLD").Run()
+			exec.Command("ip6tables", "-t", table, "-F", "STRONGHOLD").Run()
+			exec.Command("ip6tables", "-t", table, "-X", "STRONGHOLD").Run()
+		}
+	}
 	return nil
 }
 
@@ -185,9 +308,63 @@ func (t *TransparentProxy) enableNftables() error {
 		return fmt.Errorf("stronghold user not found: run 'stronghold init' first: %w", err)
 	}
 
-	// Create nftables script
-	// Use UID-based filtering (meta skuid) to skip proxy's own traffic
-	nftScript := fmt.Sprintf(`table inet stronghold {
+	// Apply nftables config
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(nftablesScript(uid, proxyPort, t.config.Proxy))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nftables failed: %s - %s", err, string(output))
+	}
+
+	return nil
+}
+
+// nftablesScript builds the inet-family ruleset, which covers IPv4 and IPv6.
+// Use UID-based filtering (meta skuid) to skip proxy's own traffic. When IPv6
+// interception is disabled, IPv6 HTTP(S) is rejected rather than left unscanned.
+func nftablesScript(uid, proxyPort string, proxy ProxyConfig) string {
+	ipv6Redirect := ""
+	ipv6Guard := ""
+	if !proxy.IPv6Enabled() {
+		ipv6Redirect = `
+        # IPv6 interception disabled - rejected by the guard chain below
+        meta nfproto ipv6 return
+`
+		ipv6Guard = fmt.Sprintf(`
+
+    chain ipv6_guard {
+        type filter hook output priority 0; policy accept;
+
+        meta skuid %s return
+        ip6 daddr { ::1, fc00::/7, fe80::/10 } return
+        meta nfproto ipv6 tcp dport { 80, 443 } reject with tcp reset
+    }`, uid)
+	}
+
+	// QUIC (HTTP/3) runs over UDP/443 and cannot be redirected to the TCP
+	// proxy. Count attempts, and reject them in block mode so clients fall
+	// back to HTTP/2 over TCP.
+	quicChain := ""
+	if mode := proxy.QUICMode(); mode != QUICAllow {
+		verdict := ""
+		if mode == QUICBlock {
+			verdict = " reject"
+		}
+		quicChain = fmt.Sprintf(`
+
+    counter %s {
+    }
+
+    chain quic {
+        type filter hook output priority 0; policy accept;
+
+        meta skuid %s return
+        ip daddr 127.0.0.0/8 return
+        ip6 daddr ::1/128 return
+        udp dport 443 counter name %s%s
+    }`, quicCounterName, uid, quicCounterName, verdict)
+	}
+
+	return fmt.Sprintf(`table inet stronghold {
     chain output {
         type nat hook output priority 0; policy accept;
 
@@ -202,23 +379,16 @@ func (t *TransparentProxy) enableNftables() error {
         ip daddr 10.0.0.0/8 return
         ip daddr 172.16.0.0/12 return
         ip daddr 192.168.0.0/16 return
-
+        ip6 daddr fc00::/7 return
+        ip6 daddr fe80::/10 return
+%s
         # Redirect HTTP to proxy
         tcp dport 80 redirect to :%s
 
         # Redirect HTTPS to proxy (MITM interception)
         tcp dport 443 redirect to :%s
-    }
-}`, uid, proxyPort, proxyPort)
-
-	// Apply nftables config
-	cmd := exec.Command("nft", "-f", "-")
-	cmd.Stdin = strings.NewReader(nftScript)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nftables failed: %s - %s", err, string(output))
-	}
-
-	return nil
+    }%s%s
+}`, uid, ipv6Redirect, proxyPort, proxyPort, ipv6Guard, quicChain)
 }
 
 func (t *TransparentProxy) disableNftables() error {
@@ -265,6 +435,36 @@ rdr pass on lo0 inet proto tcp from any to any port 443 -> 127.0.0.1 port %s
 pass out quick on lo0 inet proto tcp from any to 127.0.0.1 port %s
 `, username, activeIface, proxyPort, activeIface, proxyPort, proxyPort, proxyPort, proxyPort)
 
+	if t.config.Proxy.IPv6Enabled() {
+		pfConf += fmt.Sprintf(`
+# Redirect IPv6 HTTP/HTTPS to the proxy's IPv6 loopback listener
+rdr pass on %s inet6 proto tcp from any to any port 80 -> ::1 port %s
+rdr pass on %s inet6 proto tcp from any to any port 443 -> ::1 port %s
+pass out quick on lo0 inet6 proto tcp from any to ::1 port %s
+`, activeIface, proxyPort, activeIface, proxyPort, proxyPort)
+	} else {
+		pfConf += `
+# IPv6 interception disabled - reject IPv6 HTTP/HTTPS so clients fall back to IPv4
+block return out quick inet6 proto tcp from any to ! fe80::/10 port { 80, 443 }
+`
+	}
+
+	// QUIC (HTTP/3) uses UDP/443, which rdr cannot send to the TCP proxy
+	switch t.config.Proxy.QUICMode() {
+	case QUICBlock:
+		pfConf += fmt.Sprintf(`
+# Reject QUIC so clients fall back to interceptable HTTP/2 over TCP
+pass out quick proto udp from any to any port 443 user %s
+block return out quick proto udp from any to ! { 127.0.0.1, ::1 } port 443 label "%s"
+`, username, quicCounterName)
+	case QUICLog:
+		pfConf += fmt.Sprintf(`
+# Count QUIC attempts (not scanned)
+pass out quick proto udp from any to any port 443 user %s
+pass out quick proto udp from any to ! { 127.0.0.1, ::1 } port 443 label "%s"
+`, username, quicCounterName)
+	}
+
 	// Write config file for the anchor
 	configPath := "/etc/pf.stronghold.conf"
 	if err := os.WriteFile(configPath, []byte(pfConf), 0644); err != nil {
@@ -300,8 +500,88 @@ func (t *TransparentProxy) statusDarwin() (bool, error) {
 	if err != nil {
 		return false, nil // Anchor doesn't exist = not enabled
 	}
-	// If anchor has rules, proxy is enabled
-	return len(strings.TrimSpace(string(output))) > 0, nil
+	// If anchor has rules other than the kill switch, proxy is enabled
+	rules := strings.TrimSpace(string(output))
+	return len(rules) > 0 && !strings.Contains(rules, killSwitchLabel), nil
+}
+
+// QUICAttempts returns the number of outbound QUIC packets seen by the
+// firewall rules since they were installed. ok is false when QUIC is not
+// being tracked.
+func (t *TransparentProxy) QUICAttempts() (packets int64, ok bool) {
+	if t.config.Proxy.QUICMode() == QUICAllow {
+		return 0, false
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		if t.hasNftables() {
+			if output, err := exec.Command("nft", "list", "counter", "inet", "stronghold", quicCounterName).Output(); err == nil {
+				return parseNftCounterPackets(string(output)), true
+			}
+		}
+		for _, bin := range []string{"iptables", "ip6tables"} {
+			if _, err := exec.LookPath(bin); err != nil {
+				continue
+			}
+			if output, err := exec.Command(bin, "-L", "STRONGHOLD_QUIC", "-v", "-n", "-x").Output(); err == nil {
+				packets += parseIptablesCounter(string(output), quicCounterName)
+				ok = true
+			}
+		}
+		return packets, ok
+	case "darwin":
+		output, err := exec.Command("pfctl", "-a", "stronghold", "-sl").Output()
+		if err != nil {
+			return 0, false
+		}
+		return parsePfLabelPackets(string(output), quicCounterName), true
+	default:
+		return 0, false
+	}
+}
+
+// parseNftCounterPackets extracts the packet count from `nft list counter` output
+func parseNftCounterPackets(output string) int64 {
+	fields := strings.Fields(output)
+	for i, f := range fields {
+		if f == "packets" && i+1 < len(fields) {
+			n, _ := strconv.ParseInt(fields[i+1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// parseIptablesCounter sums the packet counts (first column of
+// `iptables -L -v -x`) of rules tagged with comment
+func parseIptablesCounter(output, comment string) int64 {
+	var total int64
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "/* "+comment+" */") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			n, _ := strconv.ParseInt(fields[0], 10, 64)
+			total += n
+		}
+	}
+	return total
+}
+
+// parsePfLabelPackets extracts the packet count for label from `pfctl -sl`
+// output ("label evaluations packets bytes ...")
+func parsePfLabelPackets(output, label string) int64 {
+	var total int64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == label {
+			n, _ := strconv.ParseInt(fields[2], 10, 64)
+			total += n
+		}
+	}
+	return total
 }
 
 // IsTransparentProxyEnabled checks if transparent proxying is currently active
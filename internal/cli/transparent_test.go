@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNftablesScript_IPv6(t *testing.T) {
+	script := nftablesScript("999", "8402", ProxyConfig{})
+	if !strings.Contains(script, "table inet stronghold") {
+		t.Error("expected inet-family table covering IPv4 and IPv6")
+	}
+	if !strings.Contains(script, "ip6 daddr fc00::/7 return") {
+		t.Error("expected IPv6 unique local addresses to be excluded")
+	}
+	if strings.Contains(script, "ipv6_guard") {
+		t.Error("guard chain must not be installed when IPv6 is intercepted")
+	}
+}
+
+func TestNftablesScript_IPv6Disabled(t *testing.T) {
+	disabled := false
+	script := nftablesScript("999", "8402", ProxyConfig{IPv6: &disabled})
+	if !strings.Contains(script, "meta nfproto ipv6 return") {
+		t.Error("expected IPv6 to skip redirection")
+	}
+	if !strings.Contains(script, "meta nfproto ipv6 tcp dport { 80, 443 } reject with tcp reset") {
+		t.Error("expected IPv6 HTTP(S) to be rejected")
+	}
+	if !strings.Contains(script, "meta skuid 999 return") {
+		t.Error("expected proxy user to be exempt")
+	}
+}
+
+func TestNftablesScript_QUIC(t *testing.T) {
+	tests := []struct {
+		mode    string
+		chain   bool
+		rejects bool
+	}{
+		{"", true, true}, // block is the default
+		{QUICBlock, true, true},
+		{QUICLog, true, false},
+		{QUICAllow, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			script := nftablesScript("999", "8402", ProxyConfig{QUIC: tt.mode})
+			if got := strings.Contains(script, "chain quic"); got != tt.chain {
+				t.Errorf("quic chain present = %v, want %v", got, tt.chain)
+			}
+			if got := strings.Contains(script, "counter name stronghold_quic reject"); got != tt.rejects {
+				t.Errorf("quic reject present = %v, want %v", got, tt.rejects)
+			}
+		})
+	}
+}
+
+func TestParseQUICCounters(t *testing.T) {
+	nft := `table inet stronghold {
+	counter stronghold_quic {
+		packets 17 bytes 21400
+	}
+}`
+	if got := parseNftCounterPackets(nft); got != 17 {
+		t.Errorf("nft: expected 17, got %d", got)
+	}
+
+	iptables := `Chain STRONGHOLD_QUIC (1 references)
+    pkts      bytes target     prot opt in     out     source               destination
+       3      180 RETURN     all  --  *      *       0.0.0.0/0            0.0.0.0/0            owner UID match 999
+       0        0 RETURN     all  --  *      *       0.0.0.0/0            127.0.0.0/8
+      42    52000 REJECT     all  --  *      *       0.0.0.0/0            0.0.0.0/0            /* stronghold_quic */ reject-with icmp-port-unreachable`
+	if got := parseIptablesCounter(iptables, quicCounterName); got != 42 {
+		t.Errorf("iptables: expected 42, got %d", got)
+	}
+
+	pf := "stronghold_quic 120 9 11000 0 0 9 11000\n"
+	if got := parsePfLabelPackets(pf, quicCounterName); got != 9 {
+		t.Errorf("pf: expected 9, got %d", got)
+	}
+}
+
+func TestKillSwitchNftScript(t *testing.T) {
+	script := killSwitchNftScript("999")
+	if !strings.Contains(script, "table inet stronghold_killswitch") {
+		t.Error("expected a table separate from the redirect rules")
+	}
+	if !strings.Contains(script, "meta skuid 999 accept") {
+		t.Error("expected proxy user to be exempt")
+	}
+	if !strings.Contains(script, "tcp dport { 80, 443 } reject with tcp reset") || !strings.Contains(script, "udp dport 443 reject") {
+		t.Error("expected HTTP, HTTPS and QUIC to be rejected")
+	}
+	if strings.Contains(script, "redirect") {
+		t.Error("kill switch must not redirect")
+	}
+}
@@ -2,22 +2,44 @@ package cli
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 )
 
-// Uninstall removes Stronghold from the system
-func Uninstall(preserveConfig bool) error {
+// Uninstall removes Stronghold from the system: every artifact install may
+// have left behind is removed and checked to be gone. With dryRun it only
+// lists what would be removed.
+func Uninstall(preserveConfig, dryRun bool) error {
 	config, err := LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if !config.Installed {
+	present := presentArtifacts(uninstallInventoryFunc(config, preserveConfig))
+	if !config.Installed && len(present) == 0 {
 		fmt.Println("Stronghold is not installed.")
 		return nil
 	}
 
+	if dryRun {
+		fmt.Println()
+		if len(present) == 0 {
+			fmt.Println("Nothing to remove.")
+		} else {
+			fmt.Println("Would remove:")
+			kind := ""
+			for _, a := range present {
+				if a.Kind != kind {
+					kind = a.Kind
+					fmt.Printf("  %s:\n", kind)
+				}
+				fmt.Printf("    - %s\n", a.Name)
+			}
+		}
+		if preserveConfig {
+			fmt.Printf("\nConfiguration at %s would be preserved.\n", ConfigDir())
+		}
+		return nil
+	}
+
 	// Confirm uninstallation
 	fmt.Println()
 	fmt.Println("This will remove Stronghold proxy and configuration.")
@@ -34,9 +56,8 @@ func Uninstall(preserveConfig bool) error {
 	fmt.Println()
 	fmt.Println("Uninstalling Stronghold...")
 
-	serviceManager := NewServiceManager(config)
-
 	// Stop the proxy
+	serviceManager := NewServiceManager(config)
 	if status, _ := serviceManager.IsRunning(); status.Running {
 		fmt.Println("  → Stopping proxy...")
 		if err := serviceManager.Stop(); err != nil {
@@ -46,54 +67,26 @@ func Uninstall(preserveConfig bool) error {
 		}
 	}
 
-	// Disable transparent proxy
-	fmt.Println("  → Disabling transparent proxy...")
-	tp := NewTransparentProxy(config)
-	if err := tp.Disable(); err != nil {
-		fmt.Printf("    Warning: failed to disable transparent proxy: %v\n", err)
-	} else {
-		fmt.Println("    ✓ Transparent proxy disabled")
-	}
+	failures := removeArtifacts(present)
 
-	// Uninstall service
-	fmt.Println("  → Removing system service...")
-	if err := serviceManager.UninstallService(); err != nil {
-		fmt.Printf("    Warning: failed to uninstall service: %v\n", err)
-	} else {
-		fmt.Println("    ✓ Service removed")
-	}
-
-	// Remove binaries
-	fmt.Println("  → Removing binaries...")
-	binaries := []string{
-		"/usr/local/bin/stronghold",
-		"/usr/local/bin/stronghold-proxy",
-		filepath.Join(os.Getenv("HOME"), ".local", "bin", "stronghold"),
-		filepath.Join(os.Getenv("HOME"), ".local", "bin", "stronghold-proxy"),
-	}
-	for _, binary := range binaries {
-		if err := os.Remove(binary); err == nil {
-			fmt.Printf("    ✓ Removed %s\n", filepath.Base(binary))
-		}
-	}
-
-	// Handle configuration
 	if preserveConfig {
-		fmt.Println("  → Preserving configuration at ~/.stronghold/")
+		fmt.Printf("  → Preserving configuration at %s\n", ConfigDir())
 		// Just mark as uninstalled
 		config.Installed = false
 		config.Save()
-	} else {
-		fmt.Println("  → Removing configuration...")
-		configDir := ConfigDir()
-		if err := os.RemoveAll(configDir); err != nil {
-			fmt.Printf("    Warning: failed to remove config directory: %v\n", err)
-		} else {
-			fmt.Println("    ✓ Configuration removed")
-		}
 	}
 
 	fmt.Println()
+	if len(failures) > 0 {
+		fmt.Println("Could not remove:")
+		for _, f := range failures {
+			fmt.Printf("  ✗ [%s] %s: %v\n", f.Artifact.Kind, f.Artifact.Name, f.Err)
+		}
+		fmt.Println()
+		fmt.Println("Remove these by hand, or re-run with sudo.")
+		return fmt.Errorf("%d artifact(s) could not be removed", len(failures))
+	}
+
 	fmt.Println("✓ Stronghold has been uninstalled.")
 
 	if config.Auth.LoggedIn && preserveConfig {
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Kinds of artifact, in the order they are removed: firewall rules first so
+// traffic is not redirected to a proxy that is gone, the service before the
+// user it runs as, and logs before the config directory that holds them
+const (
+	artifactFirewall = "firewall rules"
+	artifactService  = "system service"
+	artifactCA       = "CA trust entries"
+	artifactUser     = "system user"
+	artifactBinary   = "binaries"
+	artifactLogs     = "logs"
+	artifactConfig   = "configuration"
+)
+
+// darwinSystemKeychain is the keychain the CA is trusted in on macOS
+const darwinSystemKeychain = "/Library/Keychains/System.keychain"
+
+// caCommonName is the subject CN of the generated Stronghold CA
+const caCommonName = "Stronghold Root CA"
+
+// artifact is something installing Stronghold leaves on the system
+type artifact struct {
+	Kind    string
+	Name    string // File path, or what the artifact is
+	Present func() bool
+	Remove  func() error
+}
+
+// artifactFailure is an artifact uninstall could not remove
+type artifactFailure struct {
+	Artifact artifact
+	Err      error
+}
+
+var uninstallInventoryFunc = uninstallInventory
+
+// uninstallInventory lists everything installing Stronghold may have left
+// on this system, present or not. The config directory is only included
+// when it is not being preserved.
+func uninstallInventory(config *CLIConfig, preserveConfig bool) []artifact {
+	tp := NewTransparentProxy(config)
+	home := os.Getenv("HOME")
+	var inventory []artifact
+
+	switch runtime.GOOS {
+	case "linux":
+		inventory = append(inventory, artifact{
+			Kind:    artifactFirewall,
+			Name:    "iptables/nftables redirect and kill switch rules",
+			Present: func() bool { enabled, _ := tp.Status(); return enabled || tp.KillSwitchActive() },
+			Remove:  tp.Disable,
+		})
+
+		userUnitDir := filepath.Join(home, ".config", "systemd", "user")
+		inventory = append(inventory,
+			fileArtifact(artifactService, "/etc/systemd/system/stronghold-proxy.service", func(path string) error {
+				return removeSystemdUnit(path, false)
+			}),
+			fileArtifact(artifactService, "/etc/systemd/system/stronghold-proxy.env", os.Remove),
+			fileArtifact(artifactService, filepath.Join("/etc/systemd/system", systemdFailsafeUnit), os.Remove),
+			fileArtifact(artifactService, filepath.Join(userUnitDir, "stronghold-proxy.service"), func(path string) error {
+				return removeSystemdUnit(path, true)
+			}),
+			fileArtifact(artifactService, filepath.Join(userUnitDir, "stronghold-proxy.env"), os.Remove),
+			fileArtifact(artifactService, openRCScriptPath, func(path string) error {
+				exec.Command("rc-service", "stronghold-proxy", "stop").Run()
+				exec.Command("rc-update", "del", "stronghold-proxy", "default").Run()
+				return os.Remove(path)
+			}),
+			fileArtifact(artifactService, openRCConfPath, os.Remove),
+		)
+		for _, dir := range runitServiceDirs {
+			inventory = append(inventory, fileArtifact(artifactService, filepath.Join(dir, "stronghold-proxy"), func(path string) error {
+				exec.Command("sv", "down", path).Run()
+				return os.Remove(path)
+			}))
+		}
+		inventory = append(inventory, fileArtifact(artifactService, runitServiceDir, os.RemoveAll))
+
+		for _, dir := range linuxCATrustDirs {
+			inventory = append(inventory, fileArtifact(artifactCA, filepath.Join(dir, linuxCATrustFile), func(path string) error {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				refreshLinuxTrustStore()
+				return nil
+			}))
+		}
+
+		inventory = append(inventory, userArtifact(func(username string) *exec.Cmd {
+			return exec.Command("userdel", username)
+		}))
+
+	case "darwin":
+		inventory = append(inventory,
+			artifact{
+				Kind:    artifactFirewall,
+				Name:    "pf anchor \"stronghold\"",
+				Present: func() bool { enabled, _ := tp.Status(); return enabled || tp.KillSwitchActive() },
+				Remove:  tp.Disable,
+			},
+			fileArtifact(artifactFirewall, "/etc/pf.stronghold.conf", os.Remove),
+			fileArtifact(artifactService, "/Library/LaunchDaemons/com.stronghold.proxy.plist", removeLaunchdPlist),
+			fileArtifact(artifactService, filepath.Join(home, "Library", "LaunchAgents", "com.stronghold.proxy.plist"), removeLaunchdPlist),
+			artifact{
+				Kind:    artifactCA,
+				Name:    fmt.Sprintf("%q in %s", caCommonName, darwinSystemKeychain),
+				Present: func() bool { return len(keychainCAHashes()) > 0 },
+				Remove:  removeKeychainCA,
+			},
+			userArtifact(func(username string) *exec.Cmd {
+				return exec.Command("dscl", ".", "-delete", "/Users/"+username)
+			}),
+		)
+	}
+
+	for _, binary := range []string{
+		"/usr/local/bin/stronghold",
+		"/usr/local/bin/stronghold-proxy",
+		filepath.Join(home, ".local", "bin", "stronghold"),
+		filepath.Join(home, ".local", "bin", "stronghold-proxy"),
+	} {
+		inventory = append(inventory, fileArtifact(artifactBinary, binary, os.Remove))
+	}
+
+	if runtime.GOOS == "darwin" {
+		inventory = append(inventory, fileArtifact(artifactLogs, "/var/log/stronghold-proxy.log", os.Remove))
+	}
+	inventory = append(inventory, fileArtifact(artifactLogs, filepath.Join(ConfigDir(), "logs"), os.RemoveAll))
+
+	if !preserveConfig {
+		inventory = append(inventory, fileArtifact(artifactConfig, ConfigDir(), os.RemoveAll))
+	}
+
+	return inventory
+}
+
+// fileArtifact is an artifact at path, present while anything exists there.
+// A path that cannot be checked counts as present, so it is reported rather
+// than silently left behind.
+func fileArtifact(kind, path string, remove func(string) error) artifact {
+	return artifact{
+		Kind: kind,
+		Name: path,
+		Present: func() bool {
+			_, err := os.Lstat(path)
+			return !errors.Is(err, fs.ErrNotExist)
+		},
+		Remove: func() error { return remove(path) },
+	}
+}
+
+// userArtifact is the system user the proxy runs as, deleted with the
+// command from del
+func userArtifact(del func(username string) *exec.Cmd) artifact {
+	username := StrongholdUsername()
+	return artifact{
+		Kind: artifactUser,
+		Name: username,
+		Present: func() bool {
+			_, err := user.Lookup(username)
+			return err == nil
+		},
+		Remove: func() error {
+			if output, err := del(username).CombinedOutput(); err != nil {
+				return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+			}
+			return nil
+		},
+	}
+}
+
+// removeSystemdUnit stops, disables and removes a systemd unit file
+func removeSystemdUnit(path string, userScope bool) error {
+	systemctl := func(args ...string) {
+		if userScope {
+			args = append([]string{"--user"}, args...)
+		}
+		exec.Command("systemctl", args...).Run()
+	}
+
+	systemctl("stop", "stronghold-proxy")
+	systemctl("disable", "stronghold-proxy")
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	systemctl("daemon-reload")
+	return nil
+}
+
+// removeLaunchdPlist unloads and removes a launchd plist
+func removeLaunchdPlist(path string) error {
+	exec.Command("launchctl", "unload", path).Run()
+	return os.Remove(path)
+}
+
+// keychainCAHashes returns the SHA-1 hashes of every Stronghold CA in the
+// system keychain; reinstalling adds another rather than replacing it
+func keychainCAHashes() []string {
+	output, err := exec.Command("security", "find-certificate", "-a", "-Z", "-c", caCommonName, darwinSystemKeychain).Output()
+	if err != nil {
+		return nil
+	}
+
+	var hashes []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if hash, ok := strings.CutPrefix(strings.TrimSpace(line), "SHA-1 hash:"); ok {
+			hashes = append(hashes, strings.TrimSpace(hash))
+		}
+	}
+	return hashes
+}
+
+// removeKeychainCA deletes every Stronghold CA from the system keychain,
+// along with its trust settings
+func removeKeychainCA() error {
+	for _, hash := range keychainCAHashes() {
+		cmd := exec.Command("security", "delete-certificate", "-Z", hash, "-t", darwinSystemKeychain)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// presentArtifacts filters the inventory down to what is on the system
+func presentArtifacts(inventory []artifact) []artifact {
+	var present []artifact
+	for _, a := range inventory {
+		if a.Present() {
+			present = append(present, a)
+		}
+	}
+	return present
+}
+
+// removeArtifacts removes each artifact and checks it is gone, returning
+// those that remain
+func removeArtifacts(artifacts []artifact) []artifactFailure {
+	var failures []artifactFailure
+	kind := ""
+	for _, a := range artifacts {
+		if a.Kind != kind {
+			kind = a.Kind
+			fmt.Printf("  → Removing %s...\n", kind)
+		}
+
+		err := a.Remove()
+		if err == nil && a.Present() {
+			err = fmt.Errorf("still present after removal")
+		}
+		if err != nil {
+			fmt.Printf("    ✗ %s: %v\n", a.Name, err)
+			failures = append(failures, artifactFailure{Artifact: a, Err: err})
+			continue
+		}
+		fmt.Printf("    ✓ Removed %s\n", a.Name)
+	}
+	return failures
+}
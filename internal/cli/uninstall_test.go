@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveArtifacts_VerifiesRemoval(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "stronghold")
+	if err := os.WriteFile(binary, []byte("bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts := []artifact{
+		fileArtifact(artifactBinary, binary, os.Remove),
+		{
+			Kind:    artifactUser,
+			Name:    "stronghold",
+			Present: func() bool { return true },
+			Remove:  func() error { return nil }, // Claims success but leaves it
+		},
+		{
+			Kind:    artifactCA,
+			Name:    "keychain entry",
+			Present: func() bool { return true },
+			Remove:  func() error { return errors.New("permission denied") },
+		},
+	}
+
+	var failures []artifactFailure
+	out, _ := captureStdout(t, func() error {
+		failures = removeArtifacts(artifacts)
+		return nil
+	})
+
+	if _, err := os.Stat(binary); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed, got %v", binary, err)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %d:\n%s", len(failures), out)
+	}
+	if failures[0].Artifact.Name != "stronghold" || failures[0].Err.Error() != "still present after removal" {
+		t.Errorf("unexpected first failure: %s: %v", failures[0].Artifact.Name, failures[0].Err)
+	}
+	if failures[1].Artifact.Name != "keychain entry" || failures[1].Err.Error() != "permission denied" {
+		t.Errorf("unexpected second failure: %s: %v", failures[1].Artifact.Name, failures[1].Err)
+	}
+	if !strings.Contains(out, "✓ Removed "+binary) {
+		t.Errorf("expected removal of %s to be reported:\n%s", binary, out)
+	}
+}
+
+func TestFileArtifact_Present(t *testing.T) {
+	dir := t.TempDir()
+	logs := fileArtifact(artifactLogs, filepath.Join(dir, "logs"), os.RemoveAll)
+	if logs.Present() {
+		t.Fatal("expected a missing path not to be present")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logs", "proxy.log"), []byte("log"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !logs.Present() {
+		t.Fatal("expected the logs directory to be present")
+	}
+	if err := logs.Remove(); err != nil || logs.Present() {
+		t.Fatalf("expected the logs directory to be removed, got %v", err)
+	}
+}
+
+func TestUninstall_DryRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	orig := uninstallInventoryFunc
+	defer func() { uninstallInventoryFunc = orig }()
+
+	// Left over from an earlier install even though config says otherwise
+	leftover := filepath.Join(t.TempDir(), "stronghold-proxy.service")
+	if err := os.WriteFile(leftover, []byte("[Unit]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uninstallInventoryFunc = func(*CLIConfig, bool) []artifact {
+		return []artifact{
+			fileArtifact(artifactService, leftover, os.Remove),
+			fileArtifact(artifactBinary, filepath.Join(t.TempDir(), "stronghold"), os.Remove),
+		}
+	}
+
+	out, err := captureStdout(t, func() error { return Uninstall(true, true) })
+	if err != nil {
+		t.Fatalf("Uninstall() returned error: %v", err)
+	}
+	if _, err := os.Stat(leftover); err != nil {
+		t.Fatalf("dry run removed %s: %v", leftover, err)
+	}
+	if !strings.Contains(out, "system service:") || !strings.Contains(out, leftover) {
+		t.Fatalf("expected the leftover unit to be listed:\n%s", out)
+	}
+	if strings.Contains(out, "binaries:") {
+		t.Fatalf("expected absent artifacts not to be listed:\n%s", out)
+	}
+}
+
+func TestUninstall_NotInstalled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	orig := uninstallInventoryFunc
+	defer func() { uninstallInventoryFunc = orig }()
+	uninstallInventoryFunc = func(*CLIConfig, bool) []artifact { return nil }
+
+	out, err := captureStdout(t, func() error { return Uninstall(true, false) })
+	if err != nil {
+		t.Fatalf("Uninstall() returned error: %v", err)
+	}
+	if !strings.Contains(out, "Stronghold is not installed.") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
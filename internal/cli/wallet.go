@@ -34,11 +34,56 @@ var (
 				Foreground(lipgloss.Color("#FF4444"))
 )
 
-// AccountBalance displays account balances (legacy alias for wallet balance).
+// AccountBalance displays the prepaid account balance with its runway
+// estimate, followed by wallet balances.
 func AccountBalance() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Auth.LoggedIn && config.Auth.AccountNumber != "" {
+		if err := showAccountBalance(); err != nil {
+			fmt.Println(accountWarningStyle.Render(fmt.Sprintf("⚠ Could not fetch account balance: %v", err)))
+			fmt.Println()
+		}
+	}
 	return WalletBalance()
 }
 
+// showAccountBalance prints the account balance and how long it lasts
+func showAccountBalance() error {
+	apiClient, _, err := loginForDevices()
+	if err != nil || apiClient == nil {
+		return err
+	}
+	account, err := apiClient.GetAccount()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(accountTitleStyle.Render("💰 Account Balance"))
+	fmt.Println()
+	fmt.Printf("  Balance: %s\n", accountBalanceStyle.Render(account.BalanceUSDC.String()+" USDC"))
+	if account.Runway != nil {
+		fmt.Printf("  Runway:  %s\n", formatRunway(account.Runway))
+		if account.Runway.LowBalance {
+			fmt.Println(accountWarningStyle.Render("  ⚠ Balance is running low"))
+			fmt.Println(accountInfoStyle.Render("  Run 'stronghold account deposit' to add funds"))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// formatRunway describes a runway estimate, e.g. "~2.5 days at 1.00 USDC/day
+// (last 7 days)"
+func formatRunway(r *AccountRunway) string {
+	if r.DaysRemaining == nil {
+		return fmt.Sprintf("no spend in the last %d days", r.WindowDays)
+	}
+	return fmt.Sprintf("~%.1f days at %s USDC/day (last %d days)", *r.DaysRemaining, r.DailySpendUSDC, r.WindowDays)
+}
+
 // WalletBalance displays wallet balances and status by chain.
 func WalletBalance() error {
 	config, err := LoadConfig()
@@ -314,7 +359,7 @@ func WalletLink() error {
 	}
 
 	// Login to API
-	apiClient := NewAPIClient(config.API.Endpoint, config.Auth.DeviceToken)
+	apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
 	loginResp, err := apiClient.Login(config.Auth.AccountNumber)
 	if err != nil {
 		return fmt.Errorf("login failed: %w", err)
@@ -423,6 +468,7 @@ func ExportWallet(outputPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to export wallet: %w", err)
 	}
+	defer privateKey.Zero()
 
 	// Ensure parent directory exists
 	dir := filepath.Dir(outputPath)
@@ -431,7 +477,7 @@ func ExportWallet(outputPath string) error {
 	}
 
 	// Write EVM key to file with secure permissions
-	if err := os.WriteFile(outputPath, []byte(privateKey), 0600); err != nil {
+	if err := os.WriteFile(outputPath, privateKey.Bytes(), 0600); err != nil {
 		return fmt.Errorf("failed to write backup file: %w", err)
 	}
 
@@ -452,8 +498,9 @@ func ExportWallet(outputPath string) error {
 		if err == nil && sw.Exists() {
 			solanaKey, err := sw.Export()
 			if err == nil {
+				defer solanaKey.Zero()
 				solanaPath := outputPath + "-solana"
-				if err := os.WriteFile(solanaPath, []byte(solanaKey), 0600); err == nil {
+				if err := os.WriteFile(solanaPath, solanaKey.Bytes(), 0600); err == nil {
 					fmt.Println()
 					fmt.Println(accountTitleStyle.Render("✓ Solana wallet exported"))
 					fmt.Println(accountInfoStyle.Render("  Backup saved to: " + solanaPath))
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
 
+	"stronghold/internal/secure"
 	"stronghold/internal/wallet"
 
 	"golang.org/x/term"
@@ -49,7 +51,7 @@ func WalletReplace(chainFlag, fileFlag string, yesFlag bool) error {
 
 	if isSolana {
 		// Validate and import Solana key
-		cleanedKey, err := ValidatePrivateKeyBase58(privateKey.String())
+		cleanedKey, err := ValidatePrivateKeyBase58(privateKey.Reveal())
 		if err != nil {
 			return fmt.Errorf("invalid Solana private key: %w", err)
 		}
@@ -85,7 +87,7 @@ func WalletReplace(chainFlag, fileFlag string, yesFlag bool) error {
 
 		// Register the new Solana address with the server (best-effort)
 		if config.Auth.AccountNumber != "" {
-			apiClient := NewAPIClient(config.API.Endpoint, config.Auth.DeviceToken)
+			apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
 			if _, err := apiClient.Login(config.Auth.AccountNumber); err == nil {
 				if err := apiClient.RegisterWalletAddresses("", address); err == nil {
 					fmt.Println(successStyle.Render("✓ Solana address registered with server"))
@@ -94,7 +96,7 @@ func WalletReplace(chainFlag, fileFlag string, yesFlag bool) error {
 		}
 	} else {
 		// Validate EVM key
-		cleanedKey, err := ValidatePrivateKeyHex(privateKey.String())
+		cleanedKey, err := ValidatePrivateKeyHex(privateKey.Reveal())
 		if err != nil {
 			return fmt.Errorf("invalid EVM private key: %w", err)
 		}
@@ -144,7 +146,7 @@ func WalletReplace(chainFlag, fileFlag string, yesFlag bool) error {
 				return fmt.Errorf("account number missing. Run 'stronghold init' first")
 			}
 
-			apiClient := NewAPIClient(config.API.Endpoint, config.Auth.DeviceToken)
+			apiClient := NewAPIClient(config.API.Endpoint.Primary(), config.Auth.DeviceToken)
 			if _, err := apiClient.Login(config.Auth.AccountNumber); err != nil {
 				return fmt.Errorf("login failed: %w", err)
 			}
@@ -170,8 +172,10 @@ func WalletReplace(chainFlag, fileFlag string, yesFlag bool) error {
 			}
 			ttlDays := promptDeviceTTL()
 			verifyReq := &TOTPVerifyRequest{
-				DeviceLabel:   defaultDeviceLabel(),
-				DeviceTTLDays: ttlDays,
+				DeviceLabel:    defaultDeviceLabel(),
+				DeviceTTLDays:  ttlDays,
+				DeviceOS:       runtime.GOOS,
+				DeviceHostname: deviceHostname(),
 			}
 			if isRecovery {
 				verifyReq.RecoveryCode = code
@@ -232,8 +236,8 @@ func normalizeWalletChain(chain string) (string, error) {
 // 3. stdin (if piped)
 // 4. interactive prompt (if terminal)
 //
-// Returns a SecureBytes that must be zeroed when done (caller should use defer key.Zero()).
-func readPrivateKey(fileFlag string, isSolana bool) (*SecureBytes, error) {
+// Returns a SecretString that must be zeroed when done (caller should use defer key.Zero()).
+func readPrivateKey(fileFlag string, isSolana bool) (*secure.SecretString, error) {
 	envVar := "STRONGHOLD_PRIVATE_KEY"
 	promptLabel := "Enter EVM private key (hex): "
 	if isSolana {
@@ -263,18 +267,18 @@ func readPrivateKey(fileFlag string, isSolana bool) (*SecureBytes, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to read key file: %w", err)
 		}
-		// Create SecureBytes from file data (trimmed)
+		// Create SecretString from file data (trimmed)
 		trimmed := strings.TrimSpace(string(data))
 		// Zero the original data slice
 		for i := range data {
 			data[i] = 0
 		}
-		return NewSecureBytes([]byte(trimmed)), nil
+		return secure.NewSecretStringFromBytes([]byte(trimmed)), nil
 	}
 
 	// 2. Check environment variable
 	if envKey := os.Getenv(envVar); envKey != "" {
-		return NewSecureBytes([]byte(strings.TrimSpace(envKey))), nil
+		return secure.NewSecretStringFromBytes([]byte(strings.TrimSpace(envKey))), nil
 	}
 
 	// 3. Check stdin (if piped)
@@ -286,7 +290,7 @@ func readPrivateKey(fileFlag string, isSolana bool) (*SecureBytes, error) {
 		if err != nil && key == "" {
 			return nil, fmt.Errorf("failed to read from stdin: %w", err)
 		}
-		return NewSecureBytes([]byte(strings.TrimSpace(key))), nil
+		return secure.NewSecretStringFromBytes([]byte(strings.TrimSpace(key))), nil
 	}
 
 	// 4. Interactive prompt (only if terminal)
@@ -297,12 +301,12 @@ func readPrivateKey(fileFlag string, isSolana bool) (*SecureBytes, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to read private key: %w", err)
 		}
-		// Create SecureBytes and zero original
+		// Create SecretString and zero original
 		trimmed := strings.TrimSpace(string(key))
 		for i := range key {
 			key[i] = 0
 		}
-		return NewSecureBytes([]byte(trimmed)), nil
+		return secure.NewSecretStringFromBytes([]byte(trimmed)), nil
 	}
 
 	return nil, fmt.Errorf("no private key provided. Use stdin, %s env var, --file flag, or run interactively", envVar)
@@ -58,8 +58,8 @@ func TestReadPrivateKey_ValidFile(t *testing.T) {
 	}
 	defer key.Zero()
 
-	if key.String() != validKey {
-		t.Errorf("got key %q, want %q", key.String(), validKey)
+	if key.Reveal() != validKey {
+		t.Errorf("got key %q, want %q", key.Reveal(), validKey)
 	}
 }
 
@@ -128,8 +128,8 @@ func TestReadPrivateKey_WithWhitespace(t *testing.T) {
 	}
 	defer key.Zero()
 
-	if key.String() != validKey {
-		t.Errorf("got key %q, want %q (whitespace should be trimmed)", key.String(), validKey)
+	if key.Reveal() != validKey {
+		t.Errorf("got key %q, want %q (whitespace should be trimmed)", key.Reveal(), validKey)
 	}
 }
 
@@ -149,8 +149,8 @@ func TestReadPrivateKey_With0xPrefix(t *testing.T) {
 	defer key.Zero()
 
 	// Note: readPrivateKey returns the raw key; 0x prefix is stripped by ValidatePrivateKeyHex
-	if key.String() != validKey {
-		t.Errorf("got key %q, want %q", key.String(), validKey)
+	if key.Reveal() != validKey {
+		t.Errorf("got key %q, want %q", key.Reveal(), validKey)
 	}
 }
 
@@ -164,8 +164,8 @@ func TestReadPrivateKey_EnvVar(t *testing.T) {
 	}
 	defer key.Zero()
 
-	if key.String() != validKey {
-		t.Errorf("got key %q, want %q", key.String(), validKey)
+	if key.Reveal() != validKey {
+		t.Errorf("got key %q, want %q", key.Reveal(), validKey)
 	}
 }
 
@@ -184,8 +184,8 @@ func TestReadPrivateKey_ZerosFileData(t *testing.T) {
 	}
 
 	// Verify key is correct before zeroing
-	if key.String() != validKey {
-		t.Errorf("got key %q, want %q", key.String(), validKey)
+	if key.Reveal() != validKey {
+		t.Errorf("got key %q, want %q", key.Reveal(), validKey)
 	}
 
 	// Zero the key
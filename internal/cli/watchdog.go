@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The watchdog keeps a crash-looping proxy from leaving the machine
+// blackholed: the redirect rules outlive the proxy, so every web request
+// goes to a port nobody listens on. Once the proxy has crashed MaxRestarts
+// times within Window, the watchdog either removes the redirect rules
+// (fail_open) or swaps them for the kill switch (fail_closed), and alerts on
+// stderr, in syslog, and in 'stronghold status' and 'doctor'.
+
+// Watchdog policies
+const (
+	WatchdogFailOpen   = "fail_open"   // Remove interception; traffic goes out unscanned
+	WatchdogFailClosed = "fail_closed" // Enable the kill switch; web traffic is refused
+	WatchdogOff        = "off"         // Leave the firewall alone
+)
+
+const (
+	defaultWatchdogMaxRestarts = 5
+	defaultWatchdogWindow      = 5 * time.Minute
+	failsafeStateFile          = "failsafe.json"
+)
+
+// policy returns the configured policy, or the one matching the scanning
+// fail_open setting when none is set
+func (c WatchdogConfig) policy(scanningFailOpen bool) string {
+	if c.Policy != "" {
+		return c.Policy
+	}
+	if scanningFailOpen {
+		return WatchdogFailOpen
+	}
+	return WatchdogFailClosed
+}
+
+func (c WatchdogConfig) maxRestarts() int {
+	if c.MaxRestarts <= 0 {
+		return defaultWatchdogMaxRestarts
+	}
+	return c.MaxRestarts
+}
+
+func (c WatchdogConfig) window() time.Duration {
+	if c.Window <= 0 {
+		return defaultWatchdogWindow
+	}
+	return c.Window
+}
+
+// FailsafeState records that the watchdog changed the firewall
+type FailsafeState struct {
+	Time   time.Time `json:"time"`
+	Policy string    `json:"policy"`
+	Reason string    `json:"reason"`
+}
+
+func failsafeStatePath() string {
+	return filepath.Join(ConfigDir(), failsafeStateFile)
+}
+
+// loadFailsafeState returns the watchdog's record of a crash loop, or nil
+// when it has not tripped
+func loadFailsafeState() *FailsafeState {
+	data, err := os.ReadFile(failsafeStatePath())
+	if err != nil {
+		return nil
+	}
+	var state FailsafeState
+	if json.Unmarshal(data, &state) != nil {
+		return nil
+	}
+	return &state
+}
+
+// clearFailsafeState forgets a tripped watchdog once interception is back
+// or has been turned off deliberately
+func clearFailsafeState() {
+	if err := os.Remove(failsafeStatePath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Warning: failed to clear %s: %v\n", failsafeStatePath(), err)
+	}
+}
+
+// The watchdog's firewall changes, replaced in tests
+var (
+	interceptionActiveFunc = func(tp *TransparentProxy) bool {
+		enabled, _ := tp.Status()
+		return enabled
+	}
+	disableInterceptionFunc = func(tp *TransparentProxy) error { return tp.Disable() }
+	enableKillSwitchFunc    = func(tp *TransparentProxy) error { return tp.EnableKillSwitch() }
+	enableInterceptionFunc  = func(tp *TransparentProxy) error { return tp.Enable() }
+	syslogAlertFunc         = syslogAlert
+)
+
+// tripFailsafe applies the watchdog policy to a crash-looping proxy,
+// records it and alerts. It does nothing when the policy is off or no
+// traffic is being redirected.
+func tripFailsafe(config *CLIConfig, reason string) error {
+	policy := config.Watchdog.policy(config.Scanning.FailOpen)
+	if policy == WatchdogOff {
+		alert(fmt.Sprintf("Stronghold proxy is crash-looping (%s); watchdog policy is off, firewall left as is", reason))
+		return nil
+	}
+
+	tp := NewTransparentProxy(config)
+	if !interceptionActiveFunc(tp) {
+		fmt.Fprintf(os.Stderr, "Proxy is crash-looping (%s); traffic is not being intercepted, so the firewall is left as is\n", reason)
+		return nil
+	}
+
+	var err error
+	var consequence string
+	switch policy {
+	case WatchdogFailClosed:
+		err = enableKillSwitchFunc(tp)
+		consequence = "kill switch enabled, outbound web traffic is refused"
+	default:
+		err = disableInterceptionFunc(tp)
+		consequence = "interception removed, web traffic is going out UNSCANNED"
+	}
+	if err != nil {
+		alert(fmt.Sprintf("Stronghold proxy is crash-looping (%s) and the %s fail-safe failed: %v", reason, policy, err))
+		return fmt.Errorf("failed to apply watchdog policy %s: %w", policy, err)
+	}
+
+	state := FailsafeState{Time: time.Now().UTC(), Policy: policy, Reason: reason}
+	if data, err := json.MarshalIndent(state, "", "  "); err == nil {
+		os.MkdirAll(ConfigDir(), 0700)
+		// Readable by the user when the fail-safe runs as root
+		if err := os.WriteFile(failsafeStatePath(), data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record fail-safe state: %v\n", err)
+		}
+	}
+	alert(fmt.Sprintf("Stronghold proxy is crash-looping (%s): %s. Run 'stronghold enable' once it is fixed.", reason, consequence))
+	return nil
+}
+
+// restoreInterception puts the redirect rules back after a tripped watchdog
+func restoreInterception(config *CLIConfig) error {
+	if err := enableInterceptionFunc(NewTransparentProxy(config)); err != nil {
+		return fmt.Errorf("failed to restore interception: %w", err)
+	}
+	clearFailsafeState()
+	alert("Stronghold watchdog fail-safe lifted: interception restored")
+	return nil
+}
+
+// alert reports a fail-safe change where it cannot be missed: a banner on
+// stderr, which service managers log, and a critical syslog message
+func alert(message string) {
+	line := strings.Repeat("!", 72)
+	fmt.Fprintf(os.Stderr, "%s\n!! %s\n%s\n", line, message, line)
+	syslogAlertFunc(message)
+}
+
+// syslogAlert logs message to syslog at auth.crit on platforms with logger(1)
+func syslogAlert(message string) {
+	if _, err := exec.LookPath("logger"); err != nil {
+		return
+	}
+	exec.Command("logger", "-p", "auth.crit", "-t", "stronghold", message).Run()
+}
+
+// crashWatchdog counts proxy exits under 'stronghold supervise' and trips
+// the fail-safe once they make a crash loop. A nil watchdog does nothing.
+type crashWatchdog struct {
+	config *CLIConfig
+
+	mu      sync.Mutex
+	exits   []time.Time
+	tripped bool
+}
+
+// newCrashWatchdog returns a watchdog for config, or nil when its policy
+// is off
+func newCrashWatchdog(config *CLIConfig) *crashWatchdog {
+	if config.Watchdog.policy(config.Scanning.FailOpen) == WatchdogOff {
+		return nil
+	}
+	return &crashWatchdog{config: config}
+}
+
+// exited records a proxy exit at now and trips the fail-safe when it is the
+// MaxRestarts-th within Window
+func (w *crashWatchdog) exited(now time.Time) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cutoff := now.Add(-w.config.Watchdog.window())
+	recent := w.exits[:0]
+	for _, t := range w.exits {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	w.exits = append(recent, now)
+
+	if w.tripped || len(w.exits) < w.config.Watchdog.maxRestarts() {
+		return
+	}
+	reason := fmt.Sprintf("%d exits in %s", len(w.exits), w.config.Watchdog.window())
+	if err := tripFailsafe(w.config, reason); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	w.tripped = true
+}
+
+// stable is called once a proxy run has lasted superviseStableAfter, and
+// restores interception if the watchdog had tripped
+func (w *crashWatchdog) stable() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.exits = nil
+	if !w.tripped || loadFailsafeState() == nil {
+		// Cleared by 'stronghold enable' or 'disable' in the meantime
+		w.tripped = false
+		return
+	}
+	if err := restoreInterception(w.config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	w.tripped = false
+}
+
+// WatchdogTrip applies the watchdog policy now. The systemd unit runs it
+// once the proxy service hits its start limit.
+func WatchdogTrip(reason string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	return tripFailsafe(config, reason)
+}
+
+// checkWatchdog fails while the watchdog's fail-safe is in place, since
+// the proxy is down and traffic is either unscanned or refused
+func checkWatchdog() CheckResult {
+	result := CheckResult{Name: "Watchdog"}
+
+	state := loadFailsafeState()
+	if state == nil {
+		config, err := LoadConfig()
+		if err != nil {
+			config = DefaultConfig()
+		}
+		result.Status = CheckPass
+		result.Message = fmt.Sprintf("No crash loop detected (policy: %s)", config.Watchdog.policy(config.Scanning.FailOpen))
+		return result
+	}
+
+	result.Status = CheckFail
+	switch state.Policy {
+	case WatchdogFailClosed:
+		result.Message = fmt.Sprintf("Proxy crash-looped at %s (%s); kill switch is refusing web traffic", state.Time.Local().Format(time.RFC3339), state.Reason)
+	default:
+		result.Message = fmt.Sprintf("Proxy crash-looped at %s (%s); web traffic is going out unscanned", state.Time.Local().Format(time.RFC3339), state.Reason)
+	}
+	result.Fix = "Check 'stronghold logs' for why the proxy exits, then run 'stronghold enable'"
+	return result
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+// stubFirewall replaces the watchdog's firewall changes for the duration of
+// a test and counts them
+type stubFirewall struct {
+	intercepting bool
+	disabled     int
+	killSwitch   int
+	enabled      int
+	alerts       []string
+}
+
+func newStubFirewall(t *testing.T) *stubFirewall {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	f := &stubFirewall{intercepting: true}
+	origActive, origDisable, origKill, origEnable, origAlert := interceptionActiveFunc, disableInterceptionFunc, enableKillSwitchFunc, enableInterceptionFunc, syslogAlertFunc
+	t.Cleanup(func() {
+		interceptionActiveFunc, disableInterceptionFunc, enableKillSwitchFunc, enableInterceptionFunc, syslogAlertFunc = origActive, origDisable, origKill, origEnable, origAlert
+	})
+	interceptionActiveFunc = func(*TransparentProxy) bool { return f.intercepting }
+	disableInterceptionFunc = func(*TransparentProxy) error { f.disabled++; f.intercepting = false; return nil }
+	enableKillSwitchFunc = func(*TransparentProxy) error { f.killSwitch++; f.intercepting = false; return nil }
+	enableInterceptionFunc = func(*TransparentProxy) error { f.enabled++; f.intercepting = true; return nil }
+	syslogAlertFunc = func(message string) { f.alerts = append(f.alerts, message) }
+	return f
+}
+
+func TestWatchdogConfig_Policy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		failOpen bool
+		want     string
+	}{
+		{"", true, WatchdogFailOpen},
+		{"", false, WatchdogFailClosed},
+		{WatchdogFailClosed, true, WatchdogFailClosed},
+		{WatchdogOff, false, WatchdogOff},
+	}
+	for _, tt := range tests {
+		if got := (WatchdogConfig{Policy: tt.policy}).policy(tt.failOpen); got != tt.want {
+			t.Errorf("policy(%q, fail_open=%v) = %q, want %q", tt.policy, tt.failOpen, got, tt.want)
+		}
+	}
+}
+
+func TestCrashWatchdog_FailClosedTripsAndRecovers(t *testing.T) {
+	f := newStubFirewall(t)
+	config := DefaultConfig()
+	config.Scanning.FailOpen = false
+	config.Watchdog = WatchdogConfig{MaxRestarts: 3, Window: time.Minute}
+	w := newCrashWatchdog(config)
+
+	now := time.Now()
+	w.exited(now)
+	w.exited(now.Add(10 * time.Second))
+	if f.killSwitch != 0 || loadFailsafeState() != nil {
+		t.Fatal("expected no fail-safe before max_restarts exits")
+	}
+
+	w.exited(now.Add(20 * time.Second))
+	if f.killSwitch != 1 || f.disabled != 0 {
+		t.Fatalf("expected the kill switch enabled once, got %d (disabled %d)", f.killSwitch, f.disabled)
+	}
+	state := loadFailsafeState()
+	if state == nil || state.Policy != WatchdogFailClosed {
+		t.Fatalf("expected fail_closed state recorded, got %+v", state)
+	}
+	if len(f.alerts) != 1 {
+		t.Errorf("expected one syslog alert, got %v", f.alerts)
+	}
+
+	// Further crashes do not trip it again
+	w.exited(now.Add(30 * time.Second))
+	if f.killSwitch != 1 {
+		t.Errorf("expected the fail-safe applied once, got %d", f.killSwitch)
+	}
+
+	w.stable()
+	if f.enabled != 1 || !f.intercepting {
+		t.Fatal("expected interception restored once the proxy is stable")
+	}
+	if loadFailsafeState() != nil {
+		t.Error("expected the fail-safe state cleared after recovery")
+	}
+}
+
+func TestCrashWatchdog_OldExitsExpire(t *testing.T) {
+	f := newStubFirewall(t)
+	config := DefaultConfig()
+	config.Watchdog = WatchdogConfig{MaxRestarts: 3, Window: time.Minute}
+	w := newCrashWatchdog(config)
+
+	now := time.Now()
+	for i := range 6 {
+		w.exited(now.Add(time.Duration(i) * 45 * time.Second))
+	}
+	if f.disabled != 0 || f.killSwitch != 0 {
+		t.Fatal("expected exits spread beyond the window not to trip the fail-safe")
+	}
+}
+
+func TestTripFailsafe_FailOpen(t *testing.T) {
+	f := newStubFirewall(t)
+	config := DefaultConfig()
+	config.Scanning.FailOpen = true
+
+	if err := tripFailsafe(config, "test"); err != nil {
+		t.Fatalf("tripFailsafe: %v", err)
+	}
+	if f.disabled != 1 || f.killSwitch != 0 {
+		t.Fatalf("expected interception removed, got disabled=%d killSwitch=%d", f.disabled, f.killSwitch)
+	}
+	if result := checkWatchdog(); result.Status != CheckFail {
+		t.Errorf("expected doctor to fail while the fail-safe is in place, got %+v", result)
+	}
+}
+
+func TestTripFailsafe_NothingToChange(t *testing.T) {
+	f := newStubFirewall(t)
+	f.intercepting = false
+	config := DefaultConfig()
+
+	if err := tripFailsafe(config, "test"); err != nil {
+		t.Fatalf("tripFailsafe: %v", err)
+	}
+	if f.disabled != 0 || f.killSwitch != 0 || loadFailsafeState() != nil {
+		t.Error("expected the firewall left alone when traffic is not intercepted")
+	}
+
+	config.Watchdog.Policy = WatchdogOff
+	if newCrashWatchdog(config) != nil {
+		t.Error("expected no watchdog with policy off")
+	}
+}
@@ -1,9 +1,9 @@
 package config
 
 import (
-	"errors"
 	"log/slog"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -22,30 +22,67 @@ const (
 
 // Config holds all service configuration
 type Config struct {
-	Environment Environment
-	Server      ServerConfig
-	Database    DatabaseConfig
-	Auth        AuthConfig
-	Cookie      CookieConfig
-	Dashboard   DashboardConfig
-	X402        X402Config
-	Stripe      StripeConfig
-	Stronghold  StrongholdConfig
-	Pricing     PricingConfig
-	RateLimit   RateLimitConfig
-	KMS         KMSConfig
-	WorkOS      WorkOSConfig
-}
-
-// ServerConfig holds HTTP server configuration
+	Environment  Environment
+	Profile      Profile // DEPLOY_PROFILE; see EffectiveProfile
+	Server       ServerConfig
+	Limits       LimitsConfig
+	Database     DatabaseConfig
+	Auth         AuthConfig
+	Cookie       CookieConfig
+	Dashboard    DashboardConfig
+	X402         X402Config
+	Payments     PaymentRetentionConfig
+	Rollups      UsageRollupConfig
+	Snapshots    BalanceSnapshotConfig
+	Runway       RunwayConfig
+	Transfers    TransferConfig
+	PrepaidCodes PrepaidCodeConfig
+	RPC          RPCConfig
+	Webhooks     WebhookSigningConfig
+	Stripe       StripeConfig
+	Stronghold   StrongholdConfig
+	Pricing      PricingConfig
+	Sampling     SamplingConfig
+	Canary       CanaryConfig
+	Admin        AdminConfig
+	Flags        FlagsConfig
+	RateLimit    RateLimitConfig
+	KMS          KMSConfig
+	WorkOS       WorkOSConfig
+	Region       RegionConfig
+	Backends     ScanBackendsConfig
+	Sessions     ScanSessionsConfig
+	Idempotency  ScanIdempotencyConfig
+	Abuse        AbuseConfig
+	E2EScan      E2EScanConfig
+	Proxy        ProxyVersionConfig
+	Logging      LoggingConfig
+}
+
+// ServerConfig holds HTTP server configuration. The server speaks HTTP/1.1;
+// TLS and HTTP/2 are terminated by the reverse proxy in front of it.
 type ServerConfig struct {
 	Port           string
-	ReadTimeout    time.Duration
+	ReadTimeout    time.Duration // Reading a whole request, headers and body, so slow clients can't hold a connection
 	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration // Keep-alive connections idle this long are closed
+	KeepAlive      bool
+	MaxConns       int // Connections served at once; more are refused with 503
+	MaxConnsPerIP  int // Connections from one client address; more are refused with 429. 0 is unlimited
 	ProxyHeader    string
 	TrustedProxies []string
 }
 
+// LimitsConfig bounds request sizes and the memory scan requests may hold.
+// Zero uses 4MB for BodyLimit and 500KB for the scanned text, and turns the
+// scan body and in-flight limits off.
+type LimitsConfig struct {
+	BodyLimit         int // Any request body, rejected with 413 before routing
+	ScanMaxBodyBytes  int // Scan request bodies, after decompression
+	ScanMaxTextBytes  int // Text scanned by a single request
+	ScanInFlightBytes int // Scan body bytes processed at once across requests
+}
+
 // DatabaseConfig holds PostgreSQL database configuration
 type DatabaseConfig struct {
 	Host     string
@@ -62,6 +99,9 @@ type AuthConfig struct {
 	JWTSecret       string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	// SessionBinding controls how refreshes from a different device are
+	// handled: off, log, enforce or strict
+	SessionBinding string
 }
 
 // CookieConfig holds httpOnly cookie configuration
@@ -73,8 +113,11 @@ type CookieConfig struct {
 
 // DashboardConfig holds dashboard configuration
 type DashboardConfig struct {
-	URL            string
-	AllowedOrigins []string
+	URL              string
+	AllowedOrigins   []string
+	OverviewCacheTTL time.Duration // How long an account overview is served from memory; 0 disables caching
+	BalanceCacheTTL  time.Duration // How long on-chain wallet balances are served from memory; 0 disables caching
+	BalanceStaleTTL  time.Duration // How much longer a cached balance is served while it is refreshed
 }
 
 // X402Config holds x402 payment configuration
@@ -84,6 +127,26 @@ type X402Config struct {
 	FacilitatorURL      string   // x402 facilitator URL
 	Networks            []string // Supported payment networks (e.g. ["base", "solana"])
 	SolanaFeePayer      string   // Facilitator's Solana pubkey for paying tx fees
+	// OverpaymentTolerance is how far above the price a payment may be and
+	// still be accepted; the excess is credited to the payer's account. Zero
+	// accepts exact payments only.
+	OverpaymentTolerance usdc.MicroUSDC
+	// OutageCredit lets trusted accounts be served on credit while the
+	// facilitator is unreachable
+	OutageCredit OutageCreditConfig
+}
+
+// OutageCreditConfig controls serving paid requests on credit during
+// facilitator outages. A payer qualifies when its wallet is linked to an
+// account with at least MinSettledPayments settled x402 payments, or whose
+// balance covers everything it owes; outstanding credit is capped at
+// MaxOutstanding either way. Credit is settled by the payment itself once the
+// facilitator recovers, or from the account balance if the authorization
+// lapses first.
+type OutageCreditConfig struct {
+	Enabled            bool
+	MinSettledPayments int            // Settled payments that earn credit without a balance
+	MaxOutstanding     usdc.MicroUSDC // Credit an account may owe at once
 }
 
 // WalletForNetwork returns the wallet address for the given network.
@@ -124,15 +187,128 @@ type StrongholdConfig struct {
 	WarnThreshold   float64
 	EnableHugot     bool
 	EnableSemantics bool
-	HugotModelPath  string
+	HugotModelPath  string // Directory holding the ONNX model and tokenizer for the ML layer
+	ONNXRuntimePath string // libonnxruntime path; the pure Go backend is used when empty
+	MLWarmupRuns    int    // Classifications run at startup before serving scans
 	LLMProvider     string
 	LLMAPIKey       string
 }
 
 // PricingConfig holds endpoint pricing in microUSDC
 type PricingConfig struct {
-	ScanContent usdc.MicroUSDC
-	ScanOutput  usdc.MicroUSDC
+	ScanContent   usdc.MicroUSDC
+	ScanOutput    usdc.MicroUSDC
+	ScanToolCall  usdc.MicroUSDC
+	ScanSession   usdc.MicroUSDC // Per message scanned in a conversation session
+	ScanDocuments usdc.MicroUSDC // Per multi-document scan request
+	Ingest        usdc.MicroUSDC // Per RAG ingestion check or recheck request
+	VolumeTiers   []VolumeTier   // Discounts for account-billed requests, ascending by MinRequests
+}
+
+// VolumeTier discounts requests for accounts that have made at least
+// MinRequests billed requests in the current calendar month
+type VolumeTier struct {
+	MinRequests     int64
+	DiscountPercent int
+}
+
+// PriceForVolume returns the price after the discount of the highest tier
+// reached by monthlyRequests, and that tier's index (-1 for list price)
+func (p *PricingConfig) PriceForVolume(price usdc.MicroUSDC, monthlyRequests int64) (usdc.MicroUSDC, int) {
+	tier := -1
+	for i, t := range p.VolumeTiers {
+		if monthlyRequests >= t.MinRequests {
+			tier = i
+		}
+	}
+	if tier < 0 {
+		return price, -1
+	}
+	return price * usdc.MicroUSDC(100-p.VolumeTiers[tier].DiscountPercent) / 100, tier
+}
+
+// PaymentRetentionConfig controls pruning settled payment transactions.
+// Payments older than RetentionDays are archived, or deleted outright when
+// Archive is off; zero keeps them forever.
+type PaymentRetentionConfig struct {
+	RetentionDays int  // Completed and expired payments older than this are pruned
+	Archive       bool // Move pruned payments to payment_transactions_archive
+	BatchSize     int  // Payments pruned per statement
+}
+
+// UsageRollupConfig controls the worker that materializes per-account daily
+// usage totals, which usage stats and exports read instead of the usage log
+type UsageRollupConfig struct {
+	Interval     time.Duration // How often pending days are rolled up; 0 disables the worker
+	BackfillDays int           // How many past days the worker rolls up when they are missing
+}
+
+// BalanceSnapshotConfig controls the worker that records each account's daily
+// on-platform and on-chain balances for balance history
+type BalanceSnapshotConfig struct {
+	Interval     time.Duration // How often balances are snapshot; 0 disables the worker
+	OnchainBatch int           // Accounts whose wallets are read on-chain per pass
+}
+
+// RunwayConfig controls the balance runway estimate shown with account
+// details, and the webhook sent when it runs low
+type RunwayConfig struct {
+	AlertDays float64 // Runway in days below which the account webhook is notified; 0 disables alerts
+}
+
+// TransferConfig bounds balance transfers between accounts
+type TransferConfig struct {
+	DailyLimit usdc.MicroUSDC // Most an account may transfer out per UTC day; 0 disables transfers
+}
+
+// PrepaidCodeConfig bounds prepaid code generation and redemption
+type PrepaidCodeConfig struct {
+	MaxValue      usdc.MicroUSDC // Most a single code may carry; 0 disables generation
+	MaxFailures   int            // Failed redemptions per account or IP within FailureWindow before redemption is locked; 0 disables the lockout
+	FailureWindow time.Duration  // Window failed redemptions are counted in
+}
+
+// RPCConfig lists the RPC endpoints used for on-chain balance lookups, in
+// order of preference per network. Calls fail over between them and back
+// off from endpoints that error or rate limit. A network without endpoints
+// uses its public RPC.
+type RPCConfig struct {
+	URLs map[string][]string // Keyed by network: base, base-sepolia, solana, solana-devnet
+}
+
+// WebhookSigningConfig holds the Ed25519 keys that sign settlement webhook
+// deliveries alongside each webhook's HMAC secret. SigningKey signs; the
+// RetiredKeys stay published so deliveries signed before a rotation still
+// verify. Without a SigningKey, deliveries carry only the HMAC signature.
+type WebhookSigningConfig struct {
+	SigningKey  string   // Base64 32-byte Ed25519 seed
+	RetiredKeys []string // Base64 Ed25519 public keys of earlier signing keys
+}
+
+// SamplingConfig controls storing a sample of scanned payloads for replay
+// against new detection versions. Sampling is off unless Percent is set.
+type SamplingConfig struct {
+	Percent       float64 // Share of scans to store, 0-100
+	MaxBytes      int     // Longer payloads are truncated before storing
+	RetentionDays int     // Samples older than this are deleted
+}
+
+// CanaryConfig configures a candidate detection configuration that shadow
+// scores a share of scans. The stable decision is always the one returned.
+type CanaryConfig struct {
+	Enabled        bool
+	DefaultPercent float64          // Share of scans from accounts that are not enrolled, 0-100
+	Stronghold     StrongholdConfig // Candidate scanner settings; unset values follow the stable scanner
+}
+
+// AdminConfig holds operator API configuration
+type AdminConfig struct {
+	APIToken string // Bearer token for /v1/admin; the admin API is disabled when empty
+}
+
+// FlagsConfig controls how feature flags are cached
+type FlagsConfig struct {
+	RefreshInterval time.Duration // How often each instance reloads flags from the database
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -143,6 +319,12 @@ type RateLimitConfig struct {
 	LoginMax      int
 	AccountMax    int
 	RefreshMax    int
+	ScanMax       int    // Scan requests per window per account; 0 disables the scan limiter
+	Strategy      string // "fixed_window" or "token_bucket"
+	Burst         int    // Token bucket capacity for the general limiter (default MaxRequests)
+	ScanBurst     int    // Token bucket capacity for the scan limiter (default ScanMax)
+	Store         string // "memory" or "redis"
+	RedisURL      string // redis:// URL, required with the redis store
 }
 
 // KMSConfig holds AWS KMS configuration for wallet key encryption
@@ -153,8 +335,96 @@ type KMSConfig struct {
 
 // WorkOSConfig holds WorkOS AuthKit configuration for B2B SSO
 type WorkOSConfig struct {
-	APIKey   string // WorkOS API key (sk_live_... or sk_test_...)
-	ClientID string // WorkOS client ID (client_01...)
+	APIKey        string // WorkOS API key (sk_live_... or sk_test_...)
+	ClientID      string // WorkOS client ID (client_01...)
+	WebhookSecret string // WorkOS webhook signing secret for Directory Sync events
+}
+
+// RegionConfig holds data residency configuration. Each region runs its own
+// API and database; accounts pinned to a region only have scans processed
+// by that region's API.
+type RegionConfig struct {
+	Name      string            // Region this instance serves (e.g. "us", "eu"); empty disables pinning
+	Endpoints map[string]string // Public API URL of every region, keyed by region name
+}
+
+// Scan backend names with a fixed meaning; they can't name an external backend
+const (
+	BackendInternal = "internal" // The built-in detection engine
+	BackendEnsemble = "ensemble" // Weighted combination of SCAN_ENSEMBLE members
+	BackendCustom   = "custom"   // An account's own endpoint
+)
+
+// ScanBackendsConfig configures the detection backends scans can be routed
+// to besides the built-in engine
+type ScanBackendsConfig struct {
+	Endpoints map[string]string  // External backend base URLs, keyed by name
+	Tokens    map[string]string  // Bearer tokens for external backends, keyed by name
+	Default   string             // Backend for accounts without a selection and for x402 scans
+	Ensemble  map[string]float64 // Ensemble member weights, keyed by backend name
+	Timeout   time.Duration      // Per-request timeout for external and custom backends
+}
+
+// LoggingConfig controls log output
+type LoggingConfig struct {
+	// Unredacted logs wallet addresses, IPs, account numbers, tokens and
+	// scanned content as they are. Debugging only; redaction is on by default.
+	Unredacted bool
+}
+
+// ScanSessionsConfig configures conversation scan sessions, which scan each
+// message together with the conversation's recent messages
+type ScanSessionsConfig struct {
+	TTL             time.Duration // Idle time before a session expires; every message restarts it
+	MaxMessages     int           // Earlier messages kept as context
+	MaxContextBytes int           // Bound on the earlier messages scanned with a new one
+	Store           string        // "memory" or "redis"
+	RedisURL        string        // redis:// URL, required with the redis store
+}
+
+// ScanIdempotencyConfig configures scan deduplication for API-key requests
+// sent with "Idempotent-Scan: true": a retry of the same scan by the same
+// account within Window gets the first verdict back without a second charge.
+type ScanIdempotencyConfig struct {
+	Window   time.Duration // How long a verdict is replayed; 0 turns deduplication off
+	Store    string        // "memory" or "redis"
+	RedisURL string        // redis:// URL, required with the redis store
+}
+
+// AbuseConfig configures detection of callers probing the detection engine:
+// mutating blocked payloads, hunting for the edge where a decision flips,
+// or scanning mostly attack payloads. Each caller's score decays with
+// HalfLife; as it rises, responses are jittered, then throttled, then the
+// account is suspended.
+type AbuseConfig struct {
+	Enabled           bool
+	HalfLife          time.Duration // Time for a caller's score to halve
+	JitterScore       float64       // Score from which responses are delayed by up to JitterMax
+	JitterMax         time.Duration
+	ThrottleScore     float64 // Score from which scans are limited to ThrottlePerMinute
+	ThrottlePerMinute int
+	SuspendScore      float64 // Score at which accounts are suspended; 0 never suspends
+}
+
+// E2EScanConfig configures end-to-end encrypted scans: clients fetch a
+// short-lived key from /v1/scan/keys and encrypt scan bodies to it, so only
+// the API sees their plaintext. Keys are derived from Secret, so every
+// instance sharing it can decrypt requests for keys any of them issued.
+type E2EScanConfig struct {
+	Enabled bool
+	Secret  string        // Derives the scan keys; random per process when empty
+	KeyTTL  time.Duration // How long an issued key is accepted
+}
+
+// ProxyVersionConfig is the supported proxy version policy. Proxies older
+// than Recommended are nudged to upgrade, older than Minimum or listed in
+// Vulnerable are told they must, and with Enforce vulnerable versions are
+// refused.
+type ProxyVersionConfig struct {
+	MinimumVersion     string
+	RecommendedVersion string
+	VulnerableVersions []string
+	EnforceVulnerable  bool
 }
 
 // Load loads configuration from environment variables
@@ -165,14 +435,45 @@ func Load() *Config {
 		env = EnvProduction
 	}
 
+	scanner := StrongholdConfig{
+		BlockThreshold:  getFloat("STRONGHOLD_BLOCK_THRESHOLD", 0.55),
+		WarnThreshold:   getFloat("STRONGHOLD_WARN_THRESHOLD", 0.35),
+		EnableHugot:     getBool("STRONGHOLD_ENABLE_HUGOT", true),
+		EnableSemantics: getBool("STRONGHOLD_ENABLE_SEMANTICS", true),
+		HugotModelPath:  getEnv("HUGOT_MODEL_PATH", "./models"),
+		ONNXRuntimePath: getEnv("ONNX_RUNTIME_PATH", ""),
+		MLWarmupRuns:    getInt("STRONGHOLD_ML_WARMUP_RUNS", 3),
+		LLMProvider:     getEnv("STRONGHOLD_LLM_PROVIDER", ""),
+		LLMAPIKey:       getEnv("STRONGHOLD_LLM_API_KEY", ""),
+	}
+
+	// Behind trusted proxies every connection comes from a proxy, so a
+	// per-address cap would throttle all clients at once
+	trustedProxies := getEnvSlice("TRUSTED_PROXIES", nil)
+	maxConnsPerIP := 256
+	if len(trustedProxies) > 0 {
+		maxConnsPerIP = 0
+	}
+
 	return &Config{
 		Environment: env,
+		Profile:     Profile(getEnv("DEPLOY_PROFILE", "")),
 		Server: ServerConfig{
 			Port:           getEnv("PORT", "8080"),
 			ReadTimeout:    getDuration("SERVER_READ_TIMEOUT", 10*time.Second),
 			WriteTimeout:   getDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:    getDuration("SERVER_IDLE_TIMEOUT", 120*time.Second),
+			KeepAlive:      getBool("SERVER_KEEPALIVE", true),
+			MaxConns:       getInt("SERVER_MAX_CONNS", 10000),
+			MaxConnsPerIP:  getInt("SERVER_MAX_CONNS_PER_IP", maxConnsPerIP),
 			ProxyHeader:    getEnv("PROXY_HEADER", "X-Forwarded-For"),
-			TrustedProxies: getEnvSlice("TRUSTED_PROXIES", nil),
+			TrustedProxies: trustedProxies,
+		},
+		Limits: LimitsConfig{
+			BodyLimit:         getInt("SERVER_BODY_LIMIT", 4*1024*1024),
+			ScanMaxBodyBytes:  getInt("SCAN_MAX_BODY_BYTES", 1024*1024),
+			ScanMaxTextBytes:  getInt("SCAN_MAX_TEXT_BYTES", 500*1024),
+			ScanInFlightBytes: getInt("SCAN_MAX_INFLIGHT_BYTES", 64*1024*1024),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -187,6 +488,7 @@ func Load() *Config {
 			JWTSecret:       getEnv("JWT_SECRET", ""),
 			AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
 			RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 90*24*time.Hour),
+			SessionBinding:  getEnv("SESSION_BINDING", "enforce"),
 		},
 		Cookie: CookieConfig{
 			Domain:   getEnv("COOKIE_DOMAIN", ""),
@@ -194,15 +496,55 @@ func Load() *Config {
 			SameSite: getEnv("COOKIE_SAMESITE", "Strict"),
 		},
 		Dashboard: DashboardConfig{
-			URL:            getEnv("DASHBOARD_URL", "http://localhost:3000"),
-			AllowedOrigins: getEnvSlice("DASHBOARD_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+			URL:              getEnv("DASHBOARD_URL", "http://localhost:3000"),
+			AllowedOrigins:   getEnvSlice("DASHBOARD_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+			OverviewCacheTTL: getDuration("DASHBOARD_OVERVIEW_CACHE_TTL", 30*time.Second),
+			BalanceCacheTTL:  getDuration("DASHBOARD_BALANCE_CACHE_TTL", 30*time.Second),
+			BalanceStaleTTL:  getDuration("DASHBOARD_BALANCE_STALE_TTL", 5*time.Minute),
 		},
 		X402: X402Config{
-			EVMWalletAddress:    getEnvWithFallback("X402_EVM_WALLET_ADDRESS", "X402_WALLET_ADDRESS", ""),
-			SolanaWalletAddress: getEnv("X402_SOLANA_WALLET_ADDRESS", ""),
-			FacilitatorURL:      getEnv("X402_FACILITATOR_URL", "https://x402.org/facilitator"),
-			Networks:            loadX402Networks(),
-			SolanaFeePayer:      getEnv("X402_SOLANA_FEE_PAYER", ""),
+			EVMWalletAddress:     getEnvWithFallback("X402_EVM_WALLET_ADDRESS", "X402_WALLET_ADDRESS", ""),
+			SolanaWalletAddress:  getEnv("X402_SOLANA_WALLET_ADDRESS", ""),
+			FacilitatorURL:       getEnv("X402_FACILITATOR_URL", "https://x402.org/facilitator"),
+			Networks:             loadX402Networks(),
+			SolanaFeePayer:       getEnv("X402_SOLANA_FEE_PAYER", ""),
+			OverpaymentTolerance: getMicroUSDC("X402_OVERPAYMENT_TOLERANCE", 0.01),
+			OutageCredit: OutageCreditConfig{
+				Enabled:            getBool("OUTAGE_CREDIT_ENABLED", false),
+				MinSettledPayments: getInt("OUTAGE_CREDIT_MIN_PAYMENTS", 100),
+				MaxOutstanding:     getMicroUSDC("OUTAGE_CREDIT_MAX_OUTSTANDING", 1.00),
+			},
+		},
+		Payments: PaymentRetentionConfig{
+			RetentionDays: getInt("PAYMENT_RETENTION_DAYS", 90),
+			Archive:       getBool("PAYMENT_RETENTION_ARCHIVE", true),
+			BatchSize:     getInt("PAYMENT_RETENTION_BATCH_SIZE", 1000),
+		},
+		Rollups: UsageRollupConfig{
+			Interval:     getDuration("USAGE_ROLLUP_INTERVAL", 5*time.Minute),
+			BackfillDays: getInt("USAGE_ROLLUP_BACKFILL_DAYS", 365),
+		},
+		Snapshots: BalanceSnapshotConfig{
+			Interval:     getDuration("BALANCE_SNAPSHOT_INTERVAL", time.Hour),
+			OnchainBatch: getInt("BALANCE_SNAPSHOT_ONCHAIN_BATCH", 100),
+		},
+		Runway: RunwayConfig{
+			AlertDays: getFloat("LOW_BALANCE_RUNWAY_DAYS", 3),
+		},
+		Transfers: TransferConfig{
+			DailyLimit: getMicroUSDC("TRANSFER_DAILY_LIMIT", 1000.00),
+		},
+		PrepaidCodes: PrepaidCodeConfig{
+			MaxValue:      getMicroUSDC("PREPAID_CODE_MAX_VALUE", 500.00),
+			MaxFailures:   getInt("PREPAID_CODE_MAX_FAILURES", 5),
+			FailureWindow: getDuration("PREPAID_CODE_FAILURE_WINDOW", time.Hour),
+		},
+		RPC: RPCConfig{
+			URLs: loadRPCURLs(),
+		},
+		Webhooks: WebhookSigningConfig{
+			SigningKey:  getEnv("WEBHOOK_SIGNING_KEY", ""),
+			RetiredKeys: getEnvSlice("WEBHOOK_SIGNING_KEYS_RETIRED", nil),
 		},
 		Stripe: StripeConfig{
 			SecretKey:      getEnv("STRIPE_SECRET_KEY", ""),
@@ -210,18 +552,41 @@ func Load() *Config {
 			PublishableKey: getEnv("STRIPE_PUBLISHABLE_KEY", ""),
 			MeterEventName: getEnv("STRIPE_METER_EVENT_NAME", ""),
 		},
-		Stronghold: StrongholdConfig{
-			BlockThreshold:  getFloat("STRONGHOLD_BLOCK_THRESHOLD", 0.55),
-			WarnThreshold:   getFloat("STRONGHOLD_WARN_THRESHOLD", 0.35),
-			EnableHugot:     getBool("STRONGHOLD_ENABLE_HUGOT", true),
-			EnableSemantics: getBool("STRONGHOLD_ENABLE_SEMANTICS", true),
-			HugotModelPath:  getEnv("HUGOT_MODEL_PATH", "./models"),
-			LLMProvider:     getEnv("STRONGHOLD_LLM_PROVIDER", ""),
-			LLMAPIKey:       getEnv("STRONGHOLD_LLM_API_KEY", ""),
-		},
+		Stronghold: scanner,
 		Pricing: PricingConfig{
-			ScanContent: getMicroUSDC("PRICE_SCAN_CONTENT", 0.001),
-			ScanOutput:  getMicroUSDC("PRICE_SCAN_OUTPUT", 0.001),
+			ScanContent:   getMicroUSDC("PRICE_SCAN_CONTENT", 0.001),
+			ScanOutput:    getMicroUSDC("PRICE_SCAN_OUTPUT", 0.001),
+			ScanToolCall:  getMicroUSDC("PRICE_SCAN_TOOL_CALL", 0.001),
+			ScanSession:   getMicroUSDC("PRICE_SCAN_SESSION_MESSAGE", 0.002),
+			ScanDocuments: getMicroUSDC("PRICE_SCAN_DOCUMENTS", 0.005),
+			Ingest:        getMicroUSDC("PRICE_INGEST", 0.005),
+			VolumeTiers:   loadVolumeTiers(),
+		},
+		Sampling: SamplingConfig{
+			Percent:       getFloat("SCAN_SAMPLE_PERCENT", 0),
+			MaxBytes:      getInt("SCAN_SAMPLE_MAX_BYTES", 16*1024),
+			RetentionDays: getInt("SCAN_SAMPLE_RETENTION_DAYS", 30),
+		},
+		Canary: CanaryConfig{
+			Enabled:        getBool("CANARY_ENABLED", false),
+			DefaultPercent: getFloat("CANARY_DEFAULT_PERCENT", 0),
+			Stronghold: StrongholdConfig{
+				BlockThreshold:  getFloat("CANARY_BLOCK_THRESHOLD", scanner.BlockThreshold),
+				WarnThreshold:   getFloat("CANARY_WARN_THRESHOLD", scanner.WarnThreshold),
+				EnableHugot:     getBool("CANARY_ENABLE_HUGOT", scanner.EnableHugot),
+				EnableSemantics: getBool("CANARY_ENABLE_SEMANTICS", scanner.EnableSemantics),
+				HugotModelPath:  getEnv("CANARY_HUGOT_MODEL_PATH", scanner.HugotModelPath),
+				ONNXRuntimePath: scanner.ONNXRuntimePath,
+				MLWarmupRuns:    scanner.MLWarmupRuns,
+				LLMProvider:     getEnv("CANARY_LLM_PROVIDER", scanner.LLMProvider),
+				LLMAPIKey:       getEnv("CANARY_LLM_API_KEY", scanner.LLMAPIKey),
+			},
+		},
+		Admin: AdminConfig{
+			APIToken: getEnv("ADMIN_API_TOKEN", ""),
+		},
+		Flags: FlagsConfig{
+			RefreshInterval: getDuration("FEATURE_FLAGS_REFRESH_INTERVAL", 15*time.Second),
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:       getBool("RATE_LIMIT_ENABLED", true),
@@ -230,14 +595,61 @@ func Load() *Config {
 			LoginMax:      getInt("RATE_LIMIT_LOGIN_MAX", 5),
 			AccountMax:    getInt("RATE_LIMIT_ACCOUNT_MAX", 3),
 			RefreshMax:    getInt("RATE_LIMIT_REFRESH_MAX", 10),
+			ScanMax:       getInt("RATE_LIMIT_SCAN_MAX", 300),
+			Strategy:      getEnv("RATE_LIMIT_STRATEGY", "fixed_window"),
+			Burst:         getInt("RATE_LIMIT_BURST", 0),
+			ScanBurst:     getInt("RATE_LIMIT_SCAN_BURST", 0),
+			Store:         getEnv("RATE_LIMIT_STORE", "memory"),
+			RedisURL:      getEnv("RATE_LIMIT_REDIS_URL", ""),
 		},
 		KMS: KMSConfig{
 			Region: getEnv("KMS_REGION", ""),
 			KeyID:  getEnv("KMS_KEY_ID", ""),
 		},
 		WorkOS: WorkOSConfig{
-			APIKey:   getEnv("WORKOS_API_KEY", ""),
-			ClientID: getEnv("WORKOS_CLIENT_ID", ""),
+			APIKey:        getEnv("WORKOS_API_KEY", ""),
+			ClientID:      getEnv("WORKOS_CLIENT_ID", ""),
+			WebhookSecret: getEnv("WORKOS_WEBHOOK_SECRET", ""),
+		},
+		Region: RegionConfig{
+			Name:      strings.ToLower(strings.TrimSpace(getEnv("REGION", ""))),
+			Endpoints: loadRegionEndpoints(),
+		},
+		Backends: loadScanBackends(),
+		Sessions: ScanSessionsConfig{
+			TTL:             getDuration("SCAN_SESSION_TTL", 30*time.Minute),
+			MaxMessages:     getInt("SCAN_SESSION_MAX_MESSAGES", 20),
+			MaxContextBytes: getInt("SCAN_SESSION_MAX_CONTEXT_BYTES", 32*1024),
+			Store:           getEnv("SCAN_SESSION_STORE", "memory"),
+			RedisURL:        getEnvWithFallback("SCAN_SESSION_REDIS_URL", "RATE_LIMIT_REDIS_URL", ""),
+		},
+		Idempotency: ScanIdempotencyConfig{
+			Window:   getDuration("SCAN_IDEMPOTENCY_WINDOW", time.Minute),
+			Store:    getEnv("SCAN_IDEMPOTENCY_STORE", "memory"),
+			RedisURL: getEnvWithFallback("SCAN_IDEMPOTENCY_REDIS_URL", "RATE_LIMIT_REDIS_URL", ""),
+		},
+		Abuse: AbuseConfig{
+			Enabled:           getBool("ABUSE_DETECTION_ENABLED", true),
+			HalfLife:          getDuration("ABUSE_SCORE_HALF_LIFE", 10*time.Minute),
+			JitterScore:       getFloat("ABUSE_JITTER_SCORE", 20),
+			JitterMax:         getDuration("ABUSE_JITTER_MAX", 2*time.Second),
+			ThrottleScore:     getFloat("ABUSE_THROTTLE_SCORE", 50),
+			ThrottlePerMinute: getInt("ABUSE_THROTTLE_PER_MINUTE", 10),
+			SuspendScore:      getFloat("ABUSE_SUSPEND_SCORE", 0),
+		},
+		E2EScan: E2EScanConfig{
+			Enabled: getBool("E2E_SCAN_ENABLED", false),
+			Secret:  getEnv("E2E_SCAN_SECRET", ""),
+			KeyTTL:  getDuration("E2E_SCAN_KEY_TTL", 10*time.Minute),
+		},
+		Proxy: ProxyVersionConfig{
+			MinimumVersion:     getEnv("PROXY_MIN_VERSION", ""),
+			RecommendedVersion: getEnv("PROXY_RECOMMENDED_VERSION", ""),
+			VulnerableVersions: getEnvSlice("PROXY_VULNERABLE_VERSIONS", nil),
+			EnforceVulnerable:  getBool("PROXY_ENFORCE_VULNERABLE", false),
+		},
+		Logging: LoggingConfig{
+			Unredacted: getBool("LOG_UNREDACTED", false),
 		},
 	}
 }
@@ -278,10 +690,16 @@ func getFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
-// getMicroUSDC parses a human-readable float env var (e.g. "0.001") into MicroUSDC.
+// getMicroUSDC parses a human-readable decimal env var (e.g. "0.001") into
+// MicroUSDC exactly. Values only a float parser accepts, such as "1e-3", are
+// still honoured with a deprecation warning.
 func getMicroUSDC(key string, defaultFloat float64) usdc.MicroUSDC {
 	if value := os.Getenv(key); value != "" {
+		if m, err := usdc.ParseDecimal(value); err == nil {
+			return m
+		}
 		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			slog.Warn("non-decimal USDC env value is deprecated, write it like \"0.001\"", "key", key, "value", value)
 			return usdc.FromFloat(f)
 		}
 		slog.Warn("invalid microUSDC env value, using default", "key", key, "value", value, "default_usdc", defaultFloat)
@@ -314,6 +732,27 @@ func getEnvSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// rpcURLEnv names the env var listing each network's RPC endpoints
+var rpcURLEnv = map[string]string{
+	"base":          "BASE_RPC_URLS",
+	"base-sepolia":  "BASE_SEPOLIA_RPC_URLS",
+	"solana":        "SOLANA_RPC_URLS",
+	"solana-devnet": "SOLANA_DEVNET_RPC_URLS",
+}
+
+// loadRPCURLs reads each network's comma-separated RPC endpoints
+func loadRPCURLs() map[string][]string {
+	urls := make(map[string][]string)
+	for network, key := range rpcURLEnv {
+		for _, u := range getEnvSlice(key, nil) {
+			if u = strings.TrimSpace(u); u != "" {
+				urls[network] = append(urls[network], u)
+			}
+		}
+	}
+	return urls
+}
+
 // loadX402Networks loads the list of supported payment networks.
 // Reads from X402_NETWORKS (comma-separated) first, falls back to
 // the legacy X402_NETWORK (singular) env var, then auto-detects
@@ -345,88 +784,114 @@ func loadX402Networks() []string {
 	return networks
 }
 
-// Validate checks that all required configuration is present.
-// In production, missing critical values will return an error.
-// In development, it will use insecure defaults and log warnings.
-func (c *Config) Validate() error {
-	var errs []string
-
-	// JWT_SECRET is critical for authentication security
-	if c.Auth.JWTSecret == "" {
-		if c.Environment == EnvProduction {
-			errs = append(errs, "JWT_SECRET is required in production")
-		}
-	} else if c.Environment == EnvProduction && len(c.Auth.JWTSecret) < 32 {
-		errs = append(errs, "JWT_SECRET must be at least 32 characters in production")
+// loadVolumeTiers parses PRICE_VOLUME_TIERS, a comma-separated list of
+// min_requests:discount_percent pairs (e.g. "10000:10,100000:25"). Invalid
+// entries are skipped with a warning; tiers are returned sorted.
+func loadVolumeTiers() []VolumeTier {
+	value := os.Getenv("PRICE_VOLUME_TIERS")
+	if value == "" {
+		return nil
 	}
 
-	// Database password should be set in production
-	if c.Database.Password == "" {
-		if c.Environment == EnvProduction {
-			errs = append(errs, "DB_PASSWORD is required in production")
-		}
-	}
-
-	// Stripe keys are required in production
-	if c.Environment == EnvProduction {
-		if c.Stripe.SecretKey == "" {
-			errs = append(errs, "STRIPE_SECRET_KEY is required in production")
-		}
-		if c.Stripe.WebhookSecret == "" {
-			errs = append(errs, "STRIPE_WEBHOOK_SECRET is required in production")
+	var tiers []VolumeTier
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
 		}
-		if c.Stripe.PublishableKey == "" {
-			errs = append(errs, "STRIPE_PUBLISHABLE_KEY is required in production")
+		minStr, discountStr, ok := strings.Cut(entry, ":")
+		minRequests, minErr := strconv.ParseInt(strings.TrimSpace(minStr), 10, 64)
+		discount, discountErr := strconv.Atoi(strings.TrimSpace(discountStr))
+		if !ok || minErr != nil || discountErr != nil || minRequests < 0 || discount < 0 || discount > 100 {
+			slog.Warn("invalid volume tier, skipping", "key", "PRICE_VOLUME_TIERS", "entry", entry)
+			continue
 		}
+		tiers = append(tiers, VolumeTier{MinRequests: minRequests, DiscountPercent: discount})
 	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinRequests < tiers[j].MinRequests })
+	return tiers
+}
 
-	// x402 wallet addresses are required in production so paid endpoints cannot silently bypass payment.
-	if c.Environment == EnvProduction && !c.X402.HasPayments() {
-		errs = append(errs, "at least one X402 wallet address (X402_EVM_WALLET_ADDRESS or X402_SOLANA_WALLET_ADDRESS) is required in production")
+// loadScanBackends reads SCAN_BACKENDS (name=url pairs), each backend's
+// SCAN_BACKEND_<NAME>_TOKEN, SCAN_DEFAULT_BACKEND and SCAN_ENSEMBLE
+func loadScanBackends() ScanBackendsConfig {
+	cfg := ScanBackendsConfig{
+		Endpoints: loadNamedURLs("SCAN_BACKENDS"),
+		Default:   strings.ToLower(strings.TrimSpace(getEnv("SCAN_DEFAULT_BACKEND", BackendInternal))),
+		Ensemble:  loadEnsembleWeights(),
+		Timeout:   getDuration("SCAN_BACKEND_TIMEOUT", 5*time.Second),
 	}
-
-	// CORS validation: wildcard origins are insecure when credentials are allowed
-	// The server uses AllowCredentials: true, so wildcards must be rejected
-	for _, origin := range c.Dashboard.AllowedOrigins {
-		if origin == "*" {
-			errs = append(errs, "DASHBOARD_ALLOWED_ORIGINS cannot contain wildcard '*' (credentials are enabled)")
-			break
+	for name := range cfg.Endpoints {
+		key := "SCAN_BACKEND_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_TOKEN"
+		if token := os.Getenv(key); token != "" {
+			if cfg.Tokens == nil {
+				cfg.Tokens = make(map[string]string)
+			}
+			cfg.Tokens[name] = token
 		}
 	}
+	return cfg
+}
 
-	// KMS is required in production for wallet key encryption
-	if c.Environment == EnvProduction {
-		if c.KMS.Region == "" {
-			errs = append(errs, "KMS_REGION is required in production")
-		}
-		if c.KMS.KeyID == "" {
-			errs = append(errs, "KMS_KEY_ID is required in production")
-		}
+// loadEnsembleWeights parses SCAN_ENSEMBLE, a comma-separated list of
+// backend:weight pairs (e.g. "internal:0.7,ml:0.3"). Invalid entries are
+// skipped with a warning.
+func loadEnsembleWeights() map[string]float64 {
+	value := os.Getenv("SCAN_ENSEMBLE")
+	if value == "" {
+		return nil
 	}
 
-	// WorkOS is required in production for B2B SSO
-	if c.Environment == EnvProduction {
-		if c.WorkOS.APIKey == "" {
-			errs = append(errs, "WORKOS_API_KEY is required in production")
+	weights := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
 		}
-		if c.WorkOS.ClientID == "" {
-			errs = append(errs, "WORKOS_CLIENT_ID is required in production")
+		name, weightStr, ok := strings.Cut(entry, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if !ok || name == "" || err != nil {
+			slog.Warn("invalid ensemble weight, skipping", "key", "SCAN_ENSEMBLE", "entry", entry)
+			continue
 		}
+		weights[name] = weight
 	}
+	return weights
+}
 
-	// Validate scanner thresholds are within valid range
-	if c.Stronghold.BlockThreshold < 0.0 || c.Stronghold.BlockThreshold > 1.0 {
-		errs = append(errs, "STRONGHOLD_BLOCK_THRESHOLD must be between 0.0 and 1.0")
-	}
-	if c.Stronghold.WarnThreshold < 0.0 || c.Stronghold.WarnThreshold > 1.0 {
-		errs = append(errs, "STRONGHOLD_WARN_THRESHOLD must be between 0.0 and 1.0")
-	}
+// loadRegionEndpoints parses REGION_ENDPOINTS, a comma-separated list of
+// region=url pairs (e.g. "us=https://api.example.com,eu=https://eu.api.example.com").
+// Invalid entries are skipped with a warning.
+func loadRegionEndpoints() map[string]string {
+	return loadNamedURLs("REGION_ENDPOINTS")
+}
 
-	if len(errs) > 0 {
-		return errors.New("configuration errors: " + strings.Join(errs, "; "))
+// loadNamedURLs parses a comma-separated list of name=url pairs. Names are
+// lowercased and trailing slashes trimmed; invalid entries are skipped with
+// a warning.
+func loadNamedURLs(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
 	}
 
-	return nil
+	endpoints := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, endpoint, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if !ok || name == "" || endpoint == "" {
+			slog.Warn("invalid endpoint, skipping", "key", key, "entry", entry)
+			continue
+		}
+		endpoints[name] = endpoint
+	}
+	return endpoints
 }
 
 // IsDevelopment returns true if running in development mode
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"strings"
 	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
 )
 
 func TestValidateProductionRequiresAtLeastOneX402Wallet(t *testing.T) {
@@ -132,3 +137,458 @@ func validProductionConfig() *Config {
 		},
 	}
 }
+
+func TestLoadVolumeTiers(t *testing.T) {
+	t.Setenv("PRICE_VOLUME_TIERS", "100000:25, 10000:10,bad,5000:150")
+
+	tiers := loadVolumeTiers()
+	if len(tiers) != 2 {
+		t.Fatalf("expected 2 valid tiers, got %d: %+v", len(tiers), tiers)
+	}
+	if tiers[0].MinRequests != 10000 || tiers[1].MinRequests != 100000 {
+		t.Errorf("expected tiers sorted by min requests, got %+v", tiers)
+	}
+}
+
+func TestPriceForVolume(t *testing.T) {
+	p := PricingConfig{VolumeTiers: []VolumeTier{
+		{MinRequests: 10000, DiscountPercent: 10},
+		{MinRequests: 100000, DiscountPercent: 25},
+	}}
+
+	tests := []struct {
+		requests int64
+		price    usdc.MicroUSDC
+		tier     int
+	}{
+		{0, 1000, -1},
+		{9999, 1000, -1},
+		{10000, 900, 0},
+		{250000, 750, 1},
+	}
+	for _, tt := range tests {
+		price, tier := p.PriceForVolume(1000, tt.requests)
+		if price != tt.price || tier != tt.tier {
+			t.Errorf("PriceForVolume(1000, %d) = %d, %d; want %d, %d", tt.requests, price, tier, tt.price, tt.tier)
+		}
+	}
+}
+
+func TestEffectiveProfile(t *testing.T) {
+	tests := []struct {
+		env     Environment
+		profile Profile
+		want    Profile
+	}{
+		{EnvProduction, "", ProfileProduction},
+		{EnvDevelopment, "", ProfileDevelopment},
+		{EnvTest, "", ProfileDevelopment},
+		{EnvProduction, ProfileSelfHost, ProfileSelfHost},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Environment: tt.env, Profile: tt.profile}
+		if got := cfg.EffectiveProfile(); got != tt.want {
+			t.Errorf("EffectiveProfile(%s, %q) = %s, want %s", tt.env, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSelfHostNeedsOnlySecrets(t *testing.T) {
+	cfg := &Config{
+		Environment: EnvProduction,
+		Profile:     ProfileSelfHost,
+		Database:    DatabaseConfig{Password: "db-password"},
+		Auth:        AuthConfig{JWTSecret: strings.Repeat("a", 32)},
+		Stronghold:  StrongholdConfig{BlockThreshold: 0.55, WarnThreshold: 0.35},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected selfhost to pass without Stripe, KMS, WorkOS or x402, got: %v", err)
+	}
+
+	cfg.Stripe.SecretKey = "sk_test_123"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "STRIPE_WEBHOOK_SECRET is required when STRIPE_SECRET_KEY is set") {
+		t.Fatalf("expected Stripe cross-field error, got: %v", err)
+	}
+}
+
+func TestValidateStagingRejectsRealMoney(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Profile = ProfileStaging
+	cfg.Stripe.SecretKey = "sk_live_123"
+	cfg.X402 = X402Config{
+		EVMWalletAddress: "0x1234567890123456789012345678901234567890",
+		Networks:         []string{"base"},
+	}
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got: %v", err)
+	}
+	if verr.Profile != ProfileStaging || len(verr.Problems) != 2 {
+		t.Fatalf("expected live Stripe key and mainnet problems in staging, got: %v", verr.Problems)
+	}
+
+	cfg.Stripe.SecretKey = "sk_test_123"
+	cfg.X402.Networks = []string{"base-sepolia"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected staging with test money to pass, got: %v", err)
+	}
+}
+
+func TestValidateNetworkWithoutWallet(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.X402 = X402Config{
+		EVMWalletAddress: "0x1234567890123456789012345678901234567890",
+		Networks:         []string{"base", "solana"},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "X402_SOLANA_WALLET_ADDRESS is not set") {
+		t.Fatalf("expected missing Solana wallet error, got: %v", err)
+	}
+}
+
+func TestValidateUnknownProfile(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Profile = "prod"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown DEPLOY_PROFILE "prod"`) {
+		t.Fatalf("expected unknown profile error, got: %v", err)
+	}
+}
+
+func TestValidateRedisRateLimitNeedsURL(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.RateLimit.Store = "redis"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_REDIS_URL is required") {
+		t.Fatalf("expected missing Redis URL error, got: %v", err)
+	}
+
+	cfg.RateLimit.RedisURL = "redis://localhost:6379/0"
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "RATE_LIMIT_") {
+		t.Fatalf("expected no rate limit error, got: %v", err)
+	}
+}
+
+func TestValidateRedisScanSessionsNeedURL(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Sessions.Store = "redis"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "SCAN_SESSION_REDIS_URL or RATE_LIMIT_REDIS_URL is required") {
+		t.Fatalf("expected missing Redis URL error, got: %v", err)
+	}
+
+	cfg.Sessions.RedisURL = "redis://localhost:6379/0"
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "SCAN_SESSION_") {
+		t.Fatalf("expected no scan session error, got: %v", err)
+	}
+}
+
+func TestValidateScanIdempotency(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Idempotency.Store = "redis"
+	cfg.Idempotency.Window = -time.Second
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "SCAN_IDEMPOTENCY_REDIS_URL or RATE_LIMIT_REDIS_URL is required") {
+		t.Fatalf("expected missing Redis URL error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "SCAN_IDEMPOTENCY_WINDOW must not be negative") {
+		t.Fatalf("expected negative window error, got: %v", err)
+	}
+
+	cfg.Idempotency.RedisURL = "redis://localhost:6379/0"
+	cfg.Idempotency.Window = time.Minute
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "SCAN_IDEMPOTENCY_") {
+		t.Fatalf("expected no scan idempotency error, got: %v", err)
+	}
+}
+
+func TestValidateAbuse(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Abuse = AbuseConfig{Enabled: true, HalfLife: time.Minute, JitterScore: 50, ThrottleScore: 20, SuspendScore: 100}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "ABUSE_THROTTLE_SCORE must be above ABUSE_JITTER_SCORE") {
+		t.Fatalf("expected out-of-order score error, got: %v", err)
+	}
+
+	// Steps that are off are skipped
+	cfg.Abuse.ThrottleScore = 0
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "ABUSE_") {
+		t.Fatalf("expected no abuse error, got: %v", err)
+	}
+
+	cfg.Abuse.HalfLife = 0
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "ABUSE_SCORE_HALF_LIFE must be positive") {
+		t.Fatalf("expected half-life error, got: %v", err)
+	}
+}
+
+func TestValidateE2EScan(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.E2EScan = E2EScanConfig{Enabled: true, KeyTTL: 10 * time.Minute}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "E2E_SCAN_SECRET is required") {
+		t.Fatalf("expected missing secret error, got: %v", err)
+	}
+
+	cfg.E2EScan.Secret = "short"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "E2E_SCAN_SECRET must be at least 32 characters") {
+		t.Fatalf("expected weak secret error, got: %v", err)
+	}
+
+	cfg.E2EScan.Secret = strings.Repeat("s", 32)
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "E2E_SCAN_") {
+		t.Fatalf("expected no e2e scan error, got: %v", err)
+	}
+}
+
+func TestValidateProxyVersions(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Proxy.MinimumVersion = "latest"
+	cfg.Proxy.VulnerableVersions = []string{"1.4.1", "1.4.x"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `PROXY_MIN_VERSION "latest"`) || !strings.Contains(err.Error(), `"1.4.x"`) {
+		t.Fatalf("expected invalid proxy version errors, got: %v", err)
+	}
+
+	cfg.Proxy.MinimumVersion = "v1.2.0"
+	cfg.Proxy.VulnerableVersions = []string{"1.4.1"}
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "PROXY_") {
+		t.Fatalf("expected no proxy version error, got: %v", err)
+	}
+}
+
+func TestRegisterCheckRunsForItsProfiles(t *testing.T) {
+	saved := checks
+	t.Cleanup(func() { checks = saved })
+
+	RegisterCheck(Check{
+		Name:     "test",
+		Profiles: []Profile{ProfileProduction},
+		Run:      func(*Config) []string { return []string{"custom problem"} },
+	})
+
+	cfg := validProductionConfig()
+	cfg.X402 = X402Config{EVMWalletAddress: "0x1234567890123456789012345678901234567890", Networks: []string{"base"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "custom problem") {
+		t.Fatalf("expected registered check to run in production, got: %v", err)
+	}
+
+	cfg.Profile = ProfileSelfHost
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected registered check to be skipped in selfhost, got: %v", err)
+	}
+}
+
+func TestValidateSessionBinding(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Auth.SessionBinding = "paranoid"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "SESSION_BINDING") {
+		t.Fatalf("expected session binding error, got: %v", err)
+	}
+
+	cfg.Auth.SessionBinding = "strict"
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "SESSION_BINDING") {
+		t.Fatalf("expected no session binding error, got: %v", err)
+	}
+}
+
+func TestLoadRegionEndpoints(t *testing.T) {
+	t.Setenv("REGION_ENDPOINTS", "US=https://api.example.com/, eu=https://eu.api.example.com,bad,=https://x")
+
+	endpoints := loadRegionEndpoints()
+	if len(endpoints) != 2 {
+		t.Fatalf("expected 2 valid endpoints, got %d: %v", len(endpoints), endpoints)
+	}
+	if endpoints["us"] != "https://api.example.com" || endpoints["eu"] != "https://eu.api.example.com" {
+		t.Errorf("unexpected endpoints: %v", endpoints)
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Region = RegionConfig{Name: "eu", Endpoints: map[string]string{"us": "https://api.example.com"}}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "REGION_ENDPOINTS must include") {
+		t.Fatalf("expected missing region endpoint error, got: %v", err)
+	}
+
+	cfg.Region.Endpoints["eu"] = "eu.api.example.com"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `URL for "eu"`) {
+		t.Fatalf("expected invalid region URL error, got: %v", err)
+	}
+
+	cfg.Region.Endpoints["eu"] = "https://eu.api.example.com"
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "REGION") {
+		t.Fatalf("expected no region error, got: %v", err)
+	}
+}
+
+func TestLoadScanBackends(t *testing.T) {
+	t.Setenv("SCAN_BACKENDS", "ML=https://ml.internal/,vendor-x=https://x.example.com")
+	t.Setenv("SCAN_BACKEND_VENDOR_X_TOKEN", "secret")
+	t.Setenv("SCAN_ENSEMBLE", "internal:0.7, ml:0.3, bad")
+	t.Setenv("SCAN_DEFAULT_BACKEND", "Ensemble")
+
+	b := loadScanBackends()
+	if b.Endpoints["ml"] != "https://ml.internal" || b.Endpoints["vendor-x"] != "https://x.example.com" {
+		t.Errorf("unexpected endpoints: %v", b.Endpoints)
+	}
+	if b.Tokens["vendor-x"] != "secret" || len(b.Tokens) != 1 {
+		t.Errorf("unexpected tokens: %v", b.Tokens)
+	}
+	if len(b.Ensemble) != 2 || b.Ensemble["internal"] != 0.7 || b.Ensemble["ml"] != 0.3 {
+		t.Errorf("unexpected ensemble: %v", b.Ensemble)
+	}
+	if b.Default != BackendEnsemble || b.Timeout != 5*time.Second {
+		t.Errorf("unexpected default %q or timeout %v", b.Default, b.Timeout)
+	}
+}
+
+func TestValidateScanBackends(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Backends = ScanBackendsConfig{
+		Endpoints: map[string]string{"custom": "https://x.example.com", "ml": "ml.internal"},
+		Default:   "vendor",
+		Ensemble:  map[string]float64{"internal": 1, "other": 0},
+		Timeout:   time.Second,
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected scan backend errors")
+	}
+	for _, want := range []string{`reserved name "custom"`, `URL for "ml"`, `SCAN_DEFAULT_BACKEND "vendor"`, `member "other"`, `weight for "other"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in: %v", want, err)
+		}
+	}
+
+	cfg.Backends = ScanBackendsConfig{
+		Endpoints: map[string]string{"ml": "https://ml.internal"},
+		Default:   BackendEnsemble,
+		Ensemble:  map[string]float64{"internal": 0.7, "ml": 0.3},
+		Timeout:   time.Second,
+	}
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "SCAN_") {
+		t.Fatalf("expected no scan backend error, got: %v", err)
+	}
+}
+
+func TestValidateWebhookSigning(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Webhooks = WebhookSigningConfig{
+		SigningKey:  "c2hvcnQ=",
+		RetiredKeys: []string{"not base64!"},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected webhook signing errors")
+	}
+	for _, want := range []string{"WEBHOOK_SIGNING_KEY must be", `entry "not base64!"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in: %v", want, err)
+		}
+	}
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	cfg.Webhooks = WebhookSigningConfig{SigningKey: key, RetiredKeys: []string{key}}
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "WEBHOOK_SIGNING") {
+		t.Fatalf("expected no webhook signing error, got: %v", err)
+	}
+}
+
+func TestLoadRPCURLs(t *testing.T) {
+	t.Setenv("BASE_RPC_URLS", "https://base.example.com, https://mainnet.base.org,")
+	t.Setenv("SOLANA_DEVNET_RPC_URLS", "https://devnet.example.com")
+
+	urls := loadRPCURLs()
+	if len(urls) != 2 {
+		t.Fatalf("expected endpoints for 2 networks, got %v", urls)
+	}
+	if len(urls["base"]) != 2 || urls["base"][0] != "https://base.example.com" || urls["base"][1] != "https://mainnet.base.org" {
+		t.Errorf("unexpected base endpoints: %v", urls["base"])
+	}
+	if len(urls["solana-devnet"]) != 1 {
+		t.Errorf("unexpected solana-devnet endpoints: %v", urls["solana-devnet"])
+	}
+}
+
+func TestValidateRPCURLs(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.RPC.URLs = map[string][]string{"solana": {"https://solana.example.com", "solana.example.com"}}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `SOLANA_RPC_URLS URL "solana.example.com"`) {
+		t.Fatalf("expected invalid RPC URL error, got: %v", err)
+	}
+
+	cfg.RPC.URLs["solana"] = []string{"https://solana.example.com"}
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "RPC_URLS") {
+		t.Fatalf("expected no RPC URL error, got: %v", err)
+	}
+}
+
+func TestLoadServerConnectionLimits(t *testing.T) {
+	cfg := Load()
+	if cfg.Server.MaxConnsPerIP != 256 || cfg.Server.MaxConns != 10000 || !cfg.Server.KeepAlive {
+		t.Fatalf("unexpected defaults: %+v", cfg.Server)
+	}
+
+	// Behind trusted proxies the peer is the proxy, so the cap is off
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1")
+	if cfg := Load(); cfg.Server.MaxConnsPerIP != 0 {
+		t.Errorf("expected no per-IP cap behind trusted proxies, got %d", cfg.Server.MaxConnsPerIP)
+	}
+
+	t.Setenv("SERVER_MAX_CONNS_PER_IP", "32")
+	if cfg := Load(); cfg.Server.MaxConnsPerIP != 32 {
+		t.Errorf("expected SERVER_MAX_CONNS_PER_IP to win, got %d", cfg.Server.MaxConnsPerIP)
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	cfg := validProductionConfig()
+	cfg.Server.IdleTimeout = -time.Second
+	cfg.Server.MaxConns = 10
+	cfg.Server.MaxConnsPerIP = 20
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "SERVER_IDLE_TIMEOUT cannot be negative") || !strings.Contains(err.Error(), "SERVER_MAX_CONNS_PER_IP cannot exceed SERVER_MAX_CONNS") {
+		t.Fatalf("expected server config errors, got: %v", err)
+	}
+
+	cfg.Server.IdleTimeout = time.Minute
+	cfg.Server.MaxConnsPerIP = 5
+	err = cfg.Validate()
+	if err != nil && strings.Contains(err.Error(), "SERVER_") {
+		t.Fatalf("expected no server error, got: %v", err)
+	}
+}
@@ -0,0 +1,686 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"stronghold/internal/proxyversion"
+)
+
+// Profile names a deployment profile. The profile decides which settings are
+// required and which checks run; Environment still controls runtime behavior
+// such as log format.
+type Profile string
+
+const (
+	// ProfileProduction is the hosted service: payments, billing, SSO and KMS
+	ProfileProduction Profile = "production"
+	// ProfileStaging mirrors production with test money: Stripe test keys and
+	// x402 testnets only
+	ProfileStaging Profile = "staging"
+	// ProfileSelfHost is a private deployment: secrets are required, but
+	// Stripe, KMS, WorkOS and x402 are optional
+	ProfileSelfHost Profile = "selfhost"
+	// ProfileDevelopment requires nothing and allows insecure defaults
+	ProfileDevelopment Profile = "development"
+)
+
+// Profiles lists the known deployment profiles
+var Profiles = []Profile{ProfileProduction, ProfileStaging, ProfileSelfHost, ProfileDevelopment}
+
+// EffectiveProfile returns DEPLOY_PROFILE if set, otherwise the profile
+// matching ENV: production for production, development for anything else
+func (c *Config) EffectiveProfile() Profile {
+	if c.Profile != "" {
+		return c.Profile
+	}
+	if c.Environment == EnvProduction {
+		return ProfileProduction
+	}
+	return ProfileDevelopment
+}
+
+// requiredKey is a setting that must be non-empty
+type requiredKey struct {
+	env   string
+	value func(c *Config) string
+}
+
+var (
+	keyJWTSecret      = requiredKey{"JWT_SECRET", func(c *Config) string { return c.Auth.JWTSecret }}
+	keyDBPassword     = requiredKey{"DB_PASSWORD", func(c *Config) string { return c.Database.Password }}
+	keyStripeSecret   = requiredKey{"STRIPE_SECRET_KEY", func(c *Config) string { return c.Stripe.SecretKey }}
+	keyStripeWebhook  = requiredKey{"STRIPE_WEBHOOK_SECRET", func(c *Config) string { return c.Stripe.WebhookSecret }}
+	keyStripePublic   = requiredKey{"STRIPE_PUBLISHABLE_KEY", func(c *Config) string { return c.Stripe.PublishableKey }}
+	keyKMSRegion      = requiredKey{"KMS_REGION", func(c *Config) string { return c.KMS.Region }}
+	keyKMSKeyID       = requiredKey{"KMS_KEY_ID", func(c *Config) string { return c.KMS.KeyID }}
+	keyWorkOSAPIKey   = requiredKey{"WORKOS_API_KEY", func(c *Config) string { return c.WorkOS.APIKey }}
+	keyWorkOSClientID = requiredKey{"WORKOS_CLIENT_ID", func(c *Config) string { return c.WorkOS.ClientID }}
+)
+
+// requiredKeys lists the settings each profile cannot run without
+var requiredKeys = map[Profile][]requiredKey{
+	ProfileProduction: {
+		keyJWTSecret, keyDBPassword,
+		keyStripeSecret, keyStripeWebhook, keyStripePublic,
+		keyKMSRegion, keyKMSKeyID,
+		keyWorkOSAPIKey, keyWorkOSClientID,
+	},
+	ProfileStaging: {
+		keyJWTSecret, keyDBPassword,
+		keyStripeSecret, keyStripeWebhook, keyStripePublic,
+		keyKMSRegion, keyKMSKeyID,
+	},
+	ProfileSelfHost: {
+		keyJWTSecret, keyDBPassword,
+	},
+	ProfileDevelopment: {},
+}
+
+// Check validates one aspect of the configuration and returns a message for
+// each problem found
+type Check struct {
+	Name     string
+	Profiles []Profile // Profiles the check runs for; all profiles if empty
+	Run      func(c *Config) []string
+}
+
+func (ch Check) appliesTo(p Profile) bool {
+	return len(ch.Profiles) == 0 || slices.Contains(ch.Profiles, p)
+}
+
+// checks run in registration order after the required keys
+var checks []Check
+
+// RegisterCheck adds a validation check. Packages that add settings register
+// their checks from init so Validate and --validate-only cover them.
+func RegisterCheck(ch Check) {
+	checks = append(checks, ch)
+}
+
+// ValidationError lists every problem found for a profile
+type ValidationError struct {
+	Profile  Profile
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "configuration errors: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the configuration against its deployment profile: every
+// required key must be set and every applicable check must pass. It returns
+// a *ValidationError listing all problems.
+func (c *Config) Validate() error {
+	profile := c.EffectiveProfile()
+	required, ok := requiredKeys[profile]
+	if !ok {
+		return &ValidationError{Profile: profile, Problems: []string{
+			fmt.Sprintf("unknown DEPLOY_PROFILE %q (want production, staging, selfhost or development)", profile),
+		}}
+	}
+
+	var problems []string
+	for _, key := range required {
+		if key.value(c) == "" {
+			problems = append(problems, fmt.Sprintf("%s is required in %s", key.env, profile))
+		}
+	}
+	for _, ch := range checks {
+		if ch.appliesTo(profile) {
+			problems = append(problems, ch.Run(c)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Profile: profile, Problems: problems}
+	}
+	return nil
+}
+
+// deployed are the profiles reachable by real users
+var deployed = []Profile{ProfileProduction, ProfileStaging, ProfileSelfHost}
+
+func init() {
+	RegisterCheck(Check{
+		Name:     "secret-strength",
+		Profiles: deployed,
+		Run: func(c *Config) []string {
+			var errs []string
+			if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+				errs = append(errs, fmt.Sprintf("JWT_SECRET must be at least 32 characters in %s", c.EffectiveProfile()))
+			}
+			// A weak admin token would expose operator endpoints
+			if c.Admin.APIToken != "" && len(c.Admin.APIToken) < 32 {
+				errs = append(errs, fmt.Sprintf("ADMIN_API_TOKEN must be at least 32 characters in %s", c.EffectiveProfile()))
+			}
+			return errs
+		},
+	})
+
+	// x402 wallet addresses are required where paid endpoints must not
+	// silently bypass payment
+	RegisterCheck(Check{
+		Name:     "x402-required",
+		Profiles: []Profile{ProfileProduction, ProfileStaging},
+		Run: func(c *Config) []string {
+			if !c.X402.HasPayments() {
+				return []string{fmt.Sprintf("at least one X402 wallet address (X402_EVM_WALLET_ADDRESS or X402_SOLANA_WALLET_ADDRESS) is required in %s", c.EffectiveProfile())}
+			}
+			return nil
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "x402-networks",
+		Run: func(c *Config) []string {
+			var errs []string
+			for _, network := range c.X402.Networks {
+				switch {
+				case !slices.Contains([]string{"base", "base-sepolia", "solana", "solana-devnet"}, network):
+					errs = append(errs, fmt.Sprintf("X402_NETWORKS contains unknown network %q", network))
+				case c.X402.HasPayments() && c.X402.WalletForNetwork(network) == "":
+					wallet := "X402_EVM_WALLET_ADDRESS"
+					if strings.HasPrefix(network, "solana") {
+						wallet = "X402_SOLANA_WALLET_ADDRESS"
+					}
+					errs = append(errs, fmt.Sprintf("X402_NETWORKS includes %s but %s is not set", network, wallet))
+				}
+			}
+			return errs
+		},
+	})
+
+	// Staging must never move real money
+	RegisterCheck(Check{
+		Name:     "staging-test-money",
+		Profiles: []Profile{ProfileStaging},
+		Run: func(c *Config) []string {
+			var errs []string
+			if strings.HasPrefix(c.Stripe.SecretKey, "sk_live_") || strings.HasPrefix(c.Stripe.SecretKey, "rk_live_") {
+				errs = append(errs, "STRIPE_SECRET_KEY must be a test key in staging")
+			}
+			for _, network := range c.X402.Networks {
+				if network == "base" || network == "solana" {
+					errs = append(errs, fmt.Sprintf("X402_NETWORKS must only contain testnets in staging, got %s", network))
+				}
+			}
+			return errs
+		},
+	})
+
+	// Optional integrations must be configured completely or not at all.
+	// Profiles that require them already report each missing key.
+	RegisterCheck(Check{
+		Name:     "stripe-pair",
+		Profiles: []Profile{ProfileSelfHost, ProfileDevelopment},
+		Run: func(c *Config) []string {
+			var errs []string
+			if c.Stripe.SecretKey != "" && c.Stripe.WebhookSecret == "" {
+				errs = append(errs, "STRIPE_WEBHOOK_SECRET is required when STRIPE_SECRET_KEY is set")
+			}
+			if c.Stripe.SecretKey != "" && c.Stripe.PublishableKey == "" {
+				errs = append(errs, "STRIPE_PUBLISHABLE_KEY is required when STRIPE_SECRET_KEY is set")
+			}
+			if (c.Stripe.WebhookSecret != "" || c.Stripe.PublishableKey != "") && c.Stripe.SecretKey == "" {
+				errs = append(errs, "STRIPE_SECRET_KEY is required when other Stripe keys are set")
+			}
+			return errs
+		},
+	})
+	RegisterCheck(Check{
+		Name:     "kms-pair",
+		Profiles: []Profile{ProfileSelfHost, ProfileDevelopment},
+		Run: func(c *Config) []string {
+			if (c.KMS.Region == "") != (c.KMS.KeyID == "") {
+				return []string{"KMS_REGION and KMS_KEY_ID must be set together"}
+			}
+			return nil
+		},
+	})
+	RegisterCheck(Check{
+		Name:     "workos-pair",
+		Profiles: []Profile{ProfileStaging, ProfileSelfHost, ProfileDevelopment},
+		Run: func(c *Config) []string {
+			if (c.WorkOS.APIKey == "") != (c.WorkOS.ClientID == "") {
+				return []string{"WORKOS_API_KEY and WORKOS_CLIENT_ID must be set together"}
+			}
+			return nil
+		},
+	})
+
+	// CORS validation: wildcard origins are insecure when credentials are allowed
+	// The server uses AllowCredentials: true, so wildcards must be rejected
+	RegisterCheck(Check{
+		Name: "cors",
+		Run: func(c *Config) []string {
+			if slices.Contains(c.Dashboard.AllowedOrigins, "*") {
+				return []string{"DASHBOARD_ALLOWED_ORIGINS cannot contain wildcard '*' (credentials are enabled)"}
+			}
+			return nil
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "ranges",
+		Run: func(c *Config) []string {
+			var errs []string
+			threshold := func(env string, v float64) {
+				if v < 0.0 || v > 1.0 {
+					errs = append(errs, env+" must be between 0.0 and 1.0")
+				}
+			}
+			percent := func(env string, v float64) {
+				if v < 0 || v > 100 {
+					errs = append(errs, env+" must be between 0 and 100")
+				}
+			}
+			threshold("STRONGHOLD_BLOCK_THRESHOLD", c.Stronghold.BlockThreshold)
+			threshold("STRONGHOLD_WARN_THRESHOLD", c.Stronghold.WarnThreshold)
+			if c.Stronghold.WarnThreshold > c.Stronghold.BlockThreshold {
+				errs = append(errs, "STRONGHOLD_WARN_THRESHOLD must not be above STRONGHOLD_BLOCK_THRESHOLD")
+			}
+			if c.Stronghold.MLWarmupRuns < 0 {
+				errs = append(errs, "STRONGHOLD_ML_WARMUP_RUNS must not be negative")
+			}
+			percent("SCAN_SAMPLE_PERCENT", c.Sampling.Percent)
+			percent("CANARY_DEFAULT_PERCENT", c.Canary.DefaultPercent)
+			threshold("CANARY_BLOCK_THRESHOLD", c.Canary.Stronghold.BlockThreshold)
+			threshold("CANARY_WARN_THRESHOLD", c.Canary.Stronghold.WarnThreshold)
+			if c.Flags.RefreshInterval != 0 && c.Flags.RefreshInterval < time.Second {
+				errs = append(errs, "FEATURE_FLAGS_REFRESH_INTERVAL must be at least 1s")
+			}
+			if c.Dashboard.OverviewCacheTTL < 0 {
+				errs = append(errs, "DASHBOARD_OVERVIEW_CACHE_TTL must not be negative")
+			}
+			if c.Dashboard.BalanceCacheTTL < 0 {
+				errs = append(errs, "DASHBOARD_BALANCE_CACHE_TTL must not be negative")
+			}
+			if c.Dashboard.BalanceStaleTTL < 0 {
+				errs = append(errs, "DASHBOARD_BALANCE_STALE_TTL must not be negative")
+			}
+			if c.Rollups.Interval < 0 {
+				errs = append(errs, "USAGE_ROLLUP_INTERVAL must not be negative")
+			}
+			if c.Rollups.BackfillDays < 0 {
+				errs = append(errs, "USAGE_ROLLUP_BACKFILL_DAYS must not be negative")
+			}
+			if c.Snapshots.Interval < 0 {
+				errs = append(errs, "BALANCE_SNAPSHOT_INTERVAL must not be negative")
+			}
+			if c.Snapshots.OnchainBatch < 0 {
+				errs = append(errs, "BALANCE_SNAPSHOT_ONCHAIN_BATCH must not be negative")
+			}
+			if c.Runway.AlertDays < 0 {
+				errs = append(errs, "LOW_BALANCE_RUNWAY_DAYS must not be negative")
+			}
+			if c.Transfers.DailyLimit < 0 {
+				errs = append(errs, "TRANSFER_DAILY_LIMIT must not be negative")
+			}
+			if c.PrepaidCodes.MaxValue < 0 {
+				errs = append(errs, "PREPAID_CODE_MAX_VALUE must not be negative")
+			}
+			if c.PrepaidCodes.MaxFailures < 0 {
+				errs = append(errs, "PREPAID_CODE_MAX_FAILURES must not be negative")
+			}
+			if c.PrepaidCodes.FailureWindow < 0 {
+				errs = append(errs, "PREPAID_CODE_FAILURE_WINDOW must not be negative")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "rpc-urls",
+		Run: func(c *Config) []string {
+			var errs []string
+			networks := make([]string, 0, len(c.RPC.URLs))
+			for network := range c.RPC.URLs {
+				networks = append(networks, network)
+			}
+			slices.Sort(networks)
+			for _, network := range networks {
+				for _, u := range c.RPC.URLs[network] {
+					if !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+						errs = append(errs, fmt.Sprintf("%s URL %q must start with http:// or https://", rpcURLEnv[network], u))
+					}
+				}
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "rate-limit",
+		Run: func(c *Config) []string {
+			rl := c.RateLimit
+			var errs []string
+			switch rl.Strategy {
+			case "", "fixed_window", "token_bucket":
+			default:
+				errs = append(errs, fmt.Sprintf("RATE_LIMIT_STRATEGY %q is not fixed_window or token_bucket", rl.Strategy))
+			}
+			switch rl.Store {
+			case "", "memory":
+			case "redis":
+				if rl.RedisURL == "" {
+					errs = append(errs, "RATE_LIMIT_REDIS_URL is required when RATE_LIMIT_STORE is redis")
+				}
+			default:
+				errs = append(errs, fmt.Sprintf("RATE_LIMIT_STORE %q is not memory or redis", rl.Store))
+			}
+			if rl.Enabled && rl.WindowSeconds <= 0 {
+				errs = append(errs, "RATE_LIMIT_WINDOW_SECONDS must be positive")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "scan-sessions",
+		Run: func(c *Config) []string {
+			ss := c.Sessions
+			var errs []string
+			switch ss.Store {
+			case "", "memory":
+			case "redis":
+				if ss.RedisURL == "" {
+					errs = append(errs, "SCAN_SESSION_REDIS_URL or RATE_LIMIT_REDIS_URL is required when SCAN_SESSION_STORE is redis")
+				}
+			default:
+				errs = append(errs, fmt.Sprintf("SCAN_SESSION_STORE %q is not memory or redis", ss.Store))
+			}
+			if ss.TTL < 0 || ss.MaxMessages < 0 || ss.MaxContextBytes < 0 {
+				errs = append(errs, "SCAN_SESSION_TTL, SCAN_SESSION_MAX_MESSAGES and SCAN_SESSION_MAX_CONTEXT_BYTES must not be negative")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "scan-idempotency",
+		Run: func(c *Config) []string {
+			si := c.Idempotency
+			var errs []string
+			switch si.Store {
+			case "", "memory":
+			case "redis":
+				if si.RedisURL == "" {
+					errs = append(errs, "SCAN_IDEMPOTENCY_REDIS_URL or RATE_LIMIT_REDIS_URL is required when SCAN_IDEMPOTENCY_STORE is redis")
+				}
+			default:
+				errs = append(errs, fmt.Sprintf("SCAN_IDEMPOTENCY_STORE %q is not memory or redis", si.Store))
+			}
+			if si.Window < 0 {
+				errs = append(errs, "SCAN_IDEMPOTENCY_WINDOW must not be negative")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "abuse",
+		Run: func(c *Config) []string {
+			a := c.Abuse
+			if !a.Enabled {
+				return nil
+			}
+			var errs []string
+			if a.HalfLife <= 0 {
+				errs = append(errs, "ABUSE_SCORE_HALF_LIFE must be positive")
+			}
+			if a.JitterScore < 0 || a.ThrottleScore < 0 || a.SuspendScore < 0 || a.JitterMax < 0 || a.ThrottlePerMinute < 0 {
+				errs = append(errs, "ABUSE_* scores and limits must not be negative")
+			}
+			// Each step that is on must start above the ones before it
+			steps := []struct {
+				name  string
+				score float64
+			}{{"ABUSE_JITTER_SCORE", a.JitterScore}, {"ABUSE_THROTTLE_SCORE", a.ThrottleScore}, {"ABUSE_SUSPEND_SCORE", a.SuspendScore}}
+			for i, prev := 1, steps[0]; i < len(steps); i++ {
+				if steps[i].score <= 0 {
+					continue
+				}
+				if prev.score > 0 && steps[i].score <= prev.score {
+					errs = append(errs, fmt.Sprintf("%s must be above %s", steps[i].name, prev.name))
+				}
+				prev = steps[i]
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "e2e-scan",
+		Run: func(c *Config) []string {
+			e := c.E2EScan
+			if !e.Enabled {
+				return nil
+			}
+			var errs []string
+			if e.KeyTTL <= 0 {
+				errs = append(errs, "E2E_SCAN_KEY_TTL must be positive")
+			}
+			if e.Secret != "" && len(e.Secret) < 32 {
+				errs = append(errs, "E2E_SCAN_SECRET must be at least 32 characters")
+			}
+			// A per-process secret only works with a single instance, and
+			// invalidates every issued key on restart
+			if e.Secret == "" && slices.Contains(deployed, c.EffectiveProfile()) {
+				errs = append(errs, fmt.Sprintf("E2E_SCAN_SECRET is required in %s when E2E_SCAN_ENABLED is true", c.EffectiveProfile()))
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "proxy-versions",
+		Run: func(c *Config) []string {
+			pv := c.Proxy
+			var errs []string
+			if v := pv.MinimumVersion; v != "" && !proxyversion.Valid(v) {
+				errs = append(errs, fmt.Sprintf("PROXY_MIN_VERSION %q is not a MAJOR.MINOR.PATCH version", v))
+			}
+			if v := pv.RecommendedVersion; v != "" && !proxyversion.Valid(v) {
+				errs = append(errs, fmt.Sprintf("PROXY_RECOMMENDED_VERSION %q is not a MAJOR.MINOR.PATCH version", v))
+			}
+			for _, v := range pv.VulnerableVersions {
+				if !proxyversion.Valid(v) {
+					errs = append(errs, fmt.Sprintf("PROXY_VULNERABLE_VERSIONS entry %q is not a MAJOR.MINOR.PATCH version", v))
+				}
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "session-binding",
+		Run: func(c *Config) []string {
+			switch c.Auth.SessionBinding {
+			case "", "off", "log", "enforce", "strict":
+				return nil
+			default:
+				return []string{fmt.Sprintf("SESSION_BINDING %q is not off, log, enforce or strict", c.Auth.SessionBinding)}
+			}
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "server",
+		Run: func(c *Config) []string {
+			s := c.Server
+			var errs []string
+			if s.ReadTimeout < 0 || s.WriteTimeout < 0 || s.IdleTimeout < 0 {
+				errs = append(errs, "SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT and SERVER_IDLE_TIMEOUT cannot be negative")
+			}
+			if s.MaxConns < 0 || s.MaxConnsPerIP < 0 {
+				errs = append(errs, "SERVER_MAX_CONNS and SERVER_MAX_CONNS_PER_IP cannot be negative")
+			}
+			if s.MaxConns > 0 && s.MaxConnsPerIP > s.MaxConns {
+				errs = append(errs, "SERVER_MAX_CONNS_PER_IP cannot exceed SERVER_MAX_CONNS")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "limits",
+		Run: func(c *Config) []string {
+			l := c.Limits
+			var errs []string
+			if l.BodyLimit < 0 || l.ScanMaxBodyBytes < 0 || l.ScanMaxTextBytes < 0 || l.ScanInFlightBytes < 0 {
+				errs = append(errs, "SERVER_BODY_LIMIT, SCAN_MAX_BODY_BYTES, SCAN_MAX_TEXT_BYTES and SCAN_MAX_INFLIGHT_BYTES cannot be negative")
+			}
+			if l.ScanMaxTextBytes > 0 && l.ScanMaxBodyBytes > 0 && l.ScanMaxTextBytes > l.ScanMaxBodyBytes {
+				errs = append(errs, "SCAN_MAX_TEXT_BYTES cannot exceed SCAN_MAX_BODY_BYTES")
+			}
+			if l.ScanMaxBodyBytes > 0 && l.ScanInFlightBytes > 0 && l.ScanMaxBodyBytes > l.ScanInFlightBytes {
+				errs = append(errs, "SCAN_MAX_BODY_BYTES cannot exceed SCAN_MAX_INFLIGHT_BYTES")
+			}
+			return errs
+		},
+	})
+
+	// A pinned account is redirected to its region's endpoint, so every
+	// region, including this one, needs a usable URL
+	RegisterCheck(Check{
+		Name: "region",
+		Run: func(c *Config) []string {
+			r := c.Region
+			var errs []string
+			if len(r.Endpoints) > 0 && r.Name == "" {
+				errs = append(errs, "REGION is required when REGION_ENDPOINTS is set")
+			}
+			if r.Name != "" {
+				if _, ok := r.Endpoints[r.Name]; !ok {
+					errs = append(errs, fmt.Sprintf("REGION_ENDPOINTS must include this instance's region %q", r.Name))
+				}
+			}
+			regions := make([]string, 0, len(r.Endpoints))
+			for region := range r.Endpoints {
+				regions = append(regions, region)
+			}
+			slices.Sort(regions)
+			for _, region := range regions {
+				if !strings.HasPrefix(r.Endpoints[region], "https://") && !strings.HasPrefix(r.Endpoints[region], "http://") {
+					errs = append(errs, fmt.Sprintf("REGION_ENDPOINTS URL for %q must start with http:// or https://", region))
+				}
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "scan_backends",
+		Run: func(c *Config) []string {
+			b := c.Backends
+			var errs []string
+			names := make([]string, 0, len(b.Endpoints))
+			for name := range b.Endpoints {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				if name == BackendInternal || name == BackendEnsemble || name == BackendCustom {
+					errs = append(errs, fmt.Sprintf("SCAN_BACKENDS can't use the reserved name %q", name))
+				}
+				if !strings.HasPrefix(b.Endpoints[name], "https://") && !strings.HasPrefix(b.Endpoints[name], "http://") {
+					errs = append(errs, fmt.Sprintf("SCAN_BACKENDS URL for %q must start with http:// or https://", name))
+				}
+			}
+
+			known := func(name string) bool {
+				_, ok := b.Endpoints[name]
+				return ok || name == BackendInternal
+			}
+			switch {
+			case b.Default == BackendEnsemble:
+				if len(b.Ensemble) == 0 {
+					errs = append(errs, "SCAN_DEFAULT_BACKEND=ensemble requires SCAN_ENSEMBLE")
+				}
+			case b.Default != "" && !known(b.Default):
+				errs = append(errs, fmt.Sprintf("SCAN_DEFAULT_BACKEND %q is not internal, ensemble or a SCAN_BACKENDS name", b.Default))
+			}
+
+			members := make([]string, 0, len(b.Ensemble))
+			for name := range b.Ensemble {
+				members = append(members, name)
+			}
+			slices.Sort(members)
+			for _, name := range members {
+				if !known(name) {
+					errs = append(errs, fmt.Sprintf("SCAN_ENSEMBLE member %q is not internal or a SCAN_BACKENDS name", name))
+				}
+				if b.Ensemble[name] <= 0 {
+					errs = append(errs, fmt.Sprintf("SCAN_ENSEMBLE weight for %q must be positive", name))
+				}
+			}
+			if len(b.Endpoints) > 0 && b.Timeout <= 0 {
+				errs = append(errs, "SCAN_BACKEND_TIMEOUT must be positive")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "payment-retention",
+		Run: func(c *Config) []string {
+			p := c.Payments
+			var errs []string
+			if p.RetentionDays < 0 {
+				errs = append(errs, "PAYMENT_RETENTION_DAYS cannot be negative")
+			}
+			if p.RetentionDays > 0 && p.BatchSize <= 0 {
+				errs = append(errs, "PAYMENT_RETENTION_BATCH_SIZE must be positive when PAYMENT_RETENTION_DAYS is set")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "outage-credit",
+		Run: func(c *Config) []string {
+			o := c.X402.OutageCredit
+			if !o.Enabled {
+				return nil
+			}
+			var errs []string
+			if o.MinSettledPayments < 0 {
+				errs = append(errs, "OUTAGE_CREDIT_MIN_PAYMENTS cannot be negative")
+			}
+			if o.MaxOutstanding <= 0 {
+				errs = append(errs, "OUTAGE_CREDIT_MAX_OUTSTANDING must be positive when OUTAGE_CREDIT_ENABLED is set")
+			}
+			return errs
+		},
+	})
+
+	RegisterCheck(Check{
+		Name: "webhook-signing",
+		Run: func(c *Config) []string {
+			w := c.Webhooks
+			var errs []string
+			if w.SigningKey != "" && decodedLen(w.SigningKey) != 32 {
+				errs = append(errs, "WEBHOOK_SIGNING_KEY must be a base64 32-byte Ed25519 seed")
+			}
+			for _, key := range w.RetiredKeys {
+				if decodedLen(key) != 32 {
+					errs = append(errs, fmt.Sprintf("WEBHOOK_SIGNING_KEYS_RETIRED entry %q is not a base64 32-byte Ed25519 public key", key))
+				}
+			}
+			return errs
+		},
+	})
+}
+
+// decodedLen returns the length of base64 s decoded, or -1 if it isn't base64
+func decodedLen(s string) int {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return -1
+	}
+	return len(b)
+}
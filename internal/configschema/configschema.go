@@ -0,0 +1,201 @@
+// Package configschema versions the config file shared by the CLI and the
+// proxy (~/.stronghold/config.yaml) and migrates older layouts on load.
+//
+// Migrations edit the YAML document tree before it is decoded, so a renamed
+// key can be moved to its new place instead of being silently dropped.
+// Comments and key order in the user's file are preserved. When a file is
+// migrated, the original is kept next to it as config.yaml.v<N>.bak.
+package configschema
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Current is the schema version written by this release. Bump it and append
+// a migration whenever a key is renamed, moved, or changes meaning.
+const Current = 2
+
+// Migration upgrades a document to version To. Apply receives the root
+// mapping node and edits it in place.
+type Migration struct {
+	To          int
+	Description string
+	Apply       func(root *yaml.Node) error
+}
+
+// migrations are applied in order to documents older than their To version
+var migrations = []Migration{
+	{
+		To:          2,
+		Description: "schema version is an integer; scanning.content and scanning.output get explicit actions",
+		Apply: func(root *yaml.Node) error {
+			scanning := ensureMapping(root, "scanning")
+			for _, key := range []string{"content", "output"} {
+				section := ensureMapping(scanning, key)
+				// Files written before these sections had actions scanned
+				// everything and enforced the default actions
+				if lookup(section, "action_on_warn") == nil {
+					set(section, "enabled", "true", "!!bool")
+					set(section, "action_on_warn", "warn", "!!str")
+					set(section, "action_on_block", "block", "!!str")
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// Result describes a migration applied by Migrate or MigrateFile
+type Result struct {
+	From    int
+	To      int
+	Applied []string // Descriptions of the migrations applied
+	Backup  string   // Path of the saved original; empty if the file was not rewritten
+}
+
+// Version returns a document's schema version. Files without a version
+// predate versioning (0); the CLI's original "1.0" string is version 1.
+func Version(root *yaml.Node) (int, error) {
+	v := lookup(root, "version")
+	if v == nil || v.Value == "" {
+		return 0, nil
+	}
+	if v.Value == "1.0" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v.Value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid config version %q", v.Value)
+	}
+	return n, nil
+}
+
+// Migrate upgrades a config document to Current. It returns the migrated
+// document, or nil with a nil Result when data is already current. Files
+// from a newer release are returned unchanged so an older binary can still
+// read the keys it knows.
+func Migrate(data []byte) ([]byte, *Result, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty file: nothing to migrate, defaults apply
+		return nil, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("failed to parse config file: top level is not a mapping")
+	}
+
+	from, err := Version(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from >= Current {
+		return nil, nil, nil
+	}
+
+	res := &Result{From: from, To: Current}
+	for _, m := range migrations {
+		if from >= m.To {
+			continue
+		}
+		if err := m.Apply(root); err != nil {
+			return nil, nil, fmt.Errorf("config migration to version %d failed: %w", m.To, err)
+		}
+		res.Applied = append(res.Applied, m.Description)
+	}
+	setFirst(root, "version", strconv.Itoa(Current), "!!int")
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode migrated config: %w", err)
+	}
+	enc.Close()
+	return buf.Bytes(), res, nil
+}
+
+// MigrateFile migrates the config at path, whose contents are data. When a
+// migration applies, the original is copied to <path>.v<N>.bak and the file
+// is rewritten. If the file can't be written (a proxy running as another
+// user, a read-only mount), the migrated document is still returned and the
+// file is left as it was. It returns the data to decode.
+func MigrateFile(path string, data []byte) ([]byte, *Result, error) {
+	migrated, res, err := Migrate(data)
+	if err != nil || migrated == nil {
+		return data, nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return migrated, res, nil
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, res.From)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+			return migrated, res, nil
+		}
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		return migrated, res, nil
+	}
+	res.Backup = backup
+	return migrated, res, nil
+}
+
+// lookup returns the value node for key in a mapping, or nil
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ensureMapping returns the mapping under key, creating it (or replacing an
+// empty value) if needed
+func ensureMapping(m *yaml.Node, key string) *yaml.Node {
+	if v := lookup(m, key); v != nil {
+		if v.Kind != yaml.MappingNode && v.Tag == "!!null" {
+			v.Kind, v.Tag, v.Value = yaml.MappingNode, "!!map", ""
+		}
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	m.Content = append(m.Content, scalar(key, "!!str"), v)
+	return v
+}
+
+// set sets a scalar value, appending the key if it is missing
+func set(m *yaml.Node, key, value, tag string) {
+	if v := lookup(m, key); v != nil {
+		*v = *scalar(value, tag)
+		return
+	}
+	m.Content = append(m.Content, scalar(key, "!!str"), scalar(value, tag))
+}
+
+// setFirst sets a scalar value, inserting the key at the top if it is missing
+func setFirst(m *yaml.Node, key, value, tag string) {
+	if v := lookup(m, key); v != nil {
+		*v = *scalar(value, tag)
+		return
+	}
+	m.Content = append([]*yaml.Node{scalar(key, "!!str"), scalar(value, tag)}, m.Content...)
+}
+
+func scalar(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
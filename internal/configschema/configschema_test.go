@@ -0,0 +1,133 @@
+package configschema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type scanTypes struct {
+	Version  int `yaml:"version"`
+	Scanning struct {
+		Mode    string `yaml:"mode"`
+		Content struct {
+			Enabled       bool   `yaml:"enabled"`
+			ActionOnWarn  string `yaml:"action_on_warn"`
+			ActionOnBlock string `yaml:"action_on_block"`
+		} `yaml:"content"`
+		Output struct {
+			ActionOnWarn string `yaml:"action_on_warn"`
+		} `yaml:"output"`
+	} `yaml:"scanning"`
+}
+
+func decode(t *testing.T, data []byte) scanTypes {
+	t.Helper()
+	var cfg scanTypes
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("migrated config does not parse: %v\n%s", err, data)
+	}
+	return cfg
+}
+
+func TestMigrate_Unversioned(t *testing.T) {
+	data := []byte("# my proxy\nproxy:\n  port: 9000 # custom port\nscanning:\n  mode: strict\n")
+
+	migrated, res, err := Migrate(data)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if res == nil || res.From != 0 || res.To != Current || len(res.Applied) == 0 {
+		t.Fatalf("result = %+v", res)
+	}
+
+	cfg := decode(t, migrated)
+	if cfg.Version != Current {
+		t.Errorf("version = %d, want %d", cfg.Version, Current)
+	}
+	if cfg.Scanning.Mode != "strict" {
+		t.Errorf("scanning.mode = %q, existing values should be kept", cfg.Scanning.Mode)
+	}
+	if !cfg.Scanning.Content.Enabled || cfg.Scanning.Content.ActionOnWarn != "warn" || cfg.Scanning.Content.ActionOnBlock != "block" {
+		t.Errorf("scanning.content = %+v, want defaults", cfg.Scanning.Content)
+	}
+	if cfg.Scanning.Output.ActionOnWarn != "warn" {
+		t.Errorf("scanning.output.action_on_warn = %q, want warn", cfg.Scanning.Output.ActionOnWarn)
+	}
+	for _, comment := range []string{"# my proxy", "# custom port"} {
+		if !strings.Contains(string(migrated), comment) {
+			t.Errorf("comment %q was lost:\n%s", comment, migrated)
+		}
+	}
+}
+
+func TestMigrate_LegacyStringVersion(t *testing.T) {
+	data := []byte("version: \"1.0\"\nscanning:\n  content:\n    enabled: false\n    action_on_warn: allow\n    action_on_block: warn\n")
+
+	migrated, res, err := Migrate(data)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if res == nil || res.From != 1 {
+		t.Fatalf("result = %+v, want migration from 1", res)
+	}
+	cfg := decode(t, migrated)
+	if cfg.Version != Current {
+		t.Errorf("version = %d, want %d", cfg.Version, Current)
+	}
+	if cfg.Scanning.Content.Enabled || cfg.Scanning.Content.ActionOnWarn != "allow" || cfg.Scanning.Content.ActionOnBlock != "warn" {
+		t.Errorf("scanning.content = %+v, explicit settings should be kept", cfg.Scanning.Content)
+	}
+}
+
+func TestMigrate_CurrentAndNewer(t *testing.T) {
+	for _, data := range []string{"", "version: 2\n", "version: 99\nfuture_key: true\n"} {
+		migrated, res, err := Migrate([]byte(data))
+		if err != nil || migrated != nil || res != nil {
+			t.Errorf("Migrate(%q) = %q, %+v, %v; want no change", data, migrated, res, err)
+		}
+	}
+}
+
+func TestMigrate_InvalidVersion(t *testing.T) {
+	if _, _, err := Migrate([]byte("version: latest\n")); err == nil {
+		t.Error("expected an error for a non-numeric version")
+	}
+}
+
+func TestMigrateFile_BacksUpOriginal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := []byte("scanning:\n  mode: smart\n")
+	if err := os.WriteFile(path, original, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, res, err := MigrateFile(path, original)
+	if err != nil {
+		t.Fatalf("MigrateFile: %v", err)
+	}
+	if res == nil || res.Backup != path+".v0.bak" {
+		t.Fatalf("result = %+v", res)
+	}
+
+	backup, err := os.ReadFile(res.Backup)
+	if err != nil || string(backup) != string(original) {
+		t.Errorf("backup = %q, %v; want the original file", backup, err)
+	}
+	onDisk, _ := os.ReadFile(path)
+	if string(onDisk) != string(data) {
+		t.Error("config file was not rewritten with the migrated document")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("config mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A second load finds nothing to do
+	again, res, err := MigrateFile(path, onDisk)
+	if err != nil || res != nil || string(again) != string(onDisk) {
+		t.Errorf("second MigrateFile = %+v, %v; want no change", res, err)
+	}
+}
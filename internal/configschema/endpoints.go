@@ -0,0 +1,62 @@
+package configschema
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Endpoints is a list of API base URLs, one per region. It is written as a
+// single URL when there is one, so files from before regional endpoints
+// read and write the same.
+type Endpoints []string
+
+// ParseEndpoints splits a comma-separated list of URLs
+func ParseEndpoints(s string) Endpoints {
+	var e Endpoints
+	for _, url := range strings.Split(s, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			e = append(e, url)
+		}
+	}
+	return e
+}
+
+// Primary is the first endpoint, used where only one API can be called
+func (e Endpoints) Primary() string {
+	if len(e) == 0 {
+		return ""
+	}
+	return e[0]
+}
+
+// String lists the endpoints separated by commas
+func (e Endpoints) String() string {
+	return strings.Join(e, ",")
+}
+
+// UnmarshalYAML reads a single URL or a list of them
+func (e *Endpoints) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*e = ParseEndpoints(node.Value)
+		return nil
+	case yaml.SequenceNode:
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*e = list
+		return nil
+	}
+	return fmt.Errorf("line %d: endpoint must be a URL or a list of URLs", node.Line)
+}
+
+// MarshalYAML writes a single endpoint as a plain URL
+func (e Endpoints) MarshalYAML() (any, error) {
+	if len(e) == 1 {
+		return e[0], nil
+	}
+	return []string(e), nil
+}
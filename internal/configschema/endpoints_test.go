@@ -0,0 +1,54 @@
+package configschema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEndpoints_YAML(t *testing.T) {
+	var cfg struct {
+		Endpoint Endpoints `yaml:"endpoint"`
+	}
+	for _, doc := range []string{
+		"endpoint: https://us.example.com",
+		"endpoint: [https://us.example.com, https://eu.example.com]",
+		"endpoint:\n  - https://us.example.com\n  - https://eu.example.com\n",
+	} {
+		if err := yaml.Unmarshal([]byte(doc), &cfg); err != nil {
+			t.Fatalf("%q: %v", doc, err)
+		}
+		if cfg.Endpoint.Primary() != "https://us.example.com" {
+			t.Errorf("%q: primary is %q", doc, cfg.Endpoint.Primary())
+		}
+	}
+	if !slices.Equal(cfg.Endpoint, Endpoints{"https://us.example.com", "https://eu.example.com"}) {
+		t.Errorf("unexpected endpoints %v", cfg.Endpoint)
+	}
+
+	if err := yaml.Unmarshal([]byte("endpoint: {url: x}"), &cfg); err == nil {
+		t.Error("expected a mapping to be rejected")
+	}
+
+	// A single endpoint is written as before
+	cfg.Endpoint = Endpoints{"https://us.example.com"}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(out)) != "endpoint: https://us.example.com" {
+		t.Errorf("unexpected YAML %q", out)
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	got := ParseEndpoints(" https://us.example.com, ,https://eu.example.com ")
+	if !slices.Equal(got, Endpoints{"https://us.example.com", "https://eu.example.com"}) {
+		t.Errorf("unexpected endpoints %v", got)
+	}
+	if got.String() != "https://us.example.com,https://eu.example.com" {
+		t.Errorf("unexpected string %q", got.String())
+	}
+}
@@ -0,0 +1,55 @@
+package configschema
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HostPolicySkip is the shorthand for a host whose content is not scanned
+const HostPolicySkip = "skip"
+
+// HostPolicy changes how the proxy scans traffic to matching hosts. It is
+// written as a single word when only skip or mode is set, so
+// "*.github.com: skip" and "api.openai.com: strict" read as they mean.
+type HostPolicy struct {
+	Skip          bool   `yaml:"skip,omitempty"`            // Don't scan content to or from the host
+	Mode          string `yaml:"mode,omitempty"`            // Scan mode for the host: "smart", "strict", "permissive", or "shadow"
+	ActionOnBlock string `yaml:"action_on_block,omitempty"` // "allow", "warn", or "block" in place of scanning.content.action_on_block
+}
+
+// UnmarshalYAML reads "skip", a scan mode, or the full mapping
+func (p *HostPolicy) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*p = HostPolicy{}
+		if node.Value == HostPolicySkip {
+			p.Skip = true
+		} else {
+			p.Mode = node.Value
+		}
+		return nil
+	case yaml.MappingNode:
+		type plain HostPolicy
+		var v plain
+		if err := node.Decode(&v); err != nil {
+			return err
+		}
+		*p = HostPolicy(v)
+		return nil
+	}
+	return fmt.Errorf("line %d: host policy must be skip, a scan mode, or a mapping", node.Line)
+}
+
+// MarshalYAML writes a policy that only skips or only sets a mode as a
+// single word
+func (p HostPolicy) MarshalYAML() (any, error) {
+	switch {
+	case p.Skip && p.Mode == "" && p.ActionOnBlock == "":
+		return HostPolicySkip, nil
+	case !p.Skip && p.Mode != "" && p.ActionOnBlock == "":
+		return p.Mode, nil
+	}
+	type plain HostPolicy
+	return plain(p), nil
+}
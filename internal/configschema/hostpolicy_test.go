@@ -0,0 +1,51 @@
+package configschema
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHostPolicy_YAML(t *testing.T) {
+	doc := `
+hosts:
+  "*.github.com": skip
+  api.openai.com: strict
+  example.com:
+    mode: permissive
+    action_on_block: warn
+`
+	var cfg struct {
+		Hosts map[string]HostPolicy `yaml:"hosts"`
+	}
+	if err := yaml.Unmarshal([]byte(doc), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]HostPolicy{
+		"*.github.com":   {Skip: true},
+		"api.openai.com": {Mode: "strict"},
+		"example.com":    {Mode: "permissive", ActionOnBlock: "warn"},
+	}
+	for host, p := range want {
+		if cfg.Hosts[host] != p {
+			t.Errorf("%s: got %+v, want %+v", host, cfg.Hosts[host], p)
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte("hosts: {example.com: [strict]}"), &cfg); err == nil {
+		t.Error("expected a list to be rejected")
+	}
+
+	// Policies that fit in one word are written as one
+	out, err := yaml.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"'*.github.com': skip", "api.openai.com: strict", "    action_on_block: warn"} {
+		if !strings.Contains(string(out), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, out)
+		}
+	}
+}
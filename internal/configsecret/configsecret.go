@@ -0,0 +1,280 @@
+// Package configsecret encrypts secret values in the local config file.
+//
+// Encrypted values are stored in place as "enc:v1:" followed by the base64
+// AES-256-GCM nonce and ciphertext, so the file stays readable YAML and
+// unencrypted fields can still be edited by hand. The key is a random
+// machine key kept either in a file next to the config (0600) or in the OS
+// keychain; STRONGHOLD_CONFIG_KEY supplies it directly for containers.
+package configsecret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/99designs/keyring"
+	"gopkg.in/yaml.v3"
+	"stronghold/internal/wallet"
+)
+
+// Prefix marks an encrypted value
+const Prefix = "enc:v1:"
+
+// KeyEnv supplies the key (base64) without a key file or keychain
+const KeyEnv = "STRONGHOLD_CONFIG_KEY"
+
+// Key sources, as stored in the config's top-level "encryption" key
+const (
+	SourceFile     = "file"
+	SourceKeychain = "keychain"
+)
+
+// keychainItem names the key in the OS keychain
+const keychainItem = "config-encryption-key"
+
+// Fields are the config paths encrypted when encryption is on
+var Fields = []string{
+	"auth.token",
+	"auth.device_token",
+	"wallet.address",
+	"wallet.solana_address",
+	"payments.wallet_address",
+}
+
+// openKeyring is replaced in tests
+var openKeyring = wallet.OpenKeyring
+
+// KeyPath returns the key file used for the config at configPath
+func KeyPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "config.key")
+}
+
+// LoadKey returns the key for the config at configPath from KeyEnv, or from
+// source if the variable is unset
+func LoadKey(source, configPath string) ([]byte, error) {
+	if v := os.Getenv(KeyEnv); v != "" {
+		return decodeKey(v, KeyEnv)
+	}
+	switch source {
+	case SourceFile:
+		data, err := os.ReadFile(KeyPath(configPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config key: %w", err)
+		}
+		return decodeKey(string(data), KeyPath(configPath))
+	case SourceKeychain:
+		ring, err := openKeyring()
+		if err != nil {
+			return nil, err
+		}
+		item, err := ring.Get(keychainItem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config key from keychain: %w", err)
+		}
+		return decodeKey(string(item.Data), "keychain")
+	default:
+		return nil, fmt.Errorf("unknown config encryption key source %q (use %q or %q)", source, SourceFile, SourceKeychain)
+	}
+}
+
+// CreateKey returns the existing key for source, generating and storing a
+// new one if there is none
+func CreateKey(source, configPath string) ([]byte, error) {
+	if key, err := LoadKey(source, configPath); err == nil {
+		return key, nil
+	} else if os.Getenv(KeyEnv) != "" {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate config key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	switch source {
+	case SourceFile:
+		if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := os.WriteFile(KeyPath(configPath), []byte(encoded+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write config key: %w", err)
+		}
+	case SourceKeychain:
+		ring, err := openKeyring()
+		if err != nil {
+			return nil, err
+		}
+		if err := ring.Set(keyring.Item{Key: keychainItem, Data: []byte(encoded)}); err != nil {
+			return nil, fmt.Errorf("failed to store config key in keychain: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config encryption key source %q (use %q or %q)", source, SourceFile, SourceKeychain)
+	}
+	return key, nil
+}
+
+func decodeKey(encoded, from string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid config key in %s: want 32 bytes, base64-encoded", from)
+	}
+	return key, nil
+}
+
+// Seal encrypts a value
+func Seal(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func Open(key []byte, value string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", errors.New("malformed encrypted value")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("encrypted value does not match the config key")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Decrypt replaces encrypted values in a config document with plaintext.
+// Documents without encrypted values are returned unchanged. The key source
+// is read from the document's top-level "encryption" key.
+func Decrypt(data []byte, configPath string) ([]byte, error) {
+	if !bytes.Contains(data, []byte(Prefix)) {
+		return data, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	root := doc.Content[0]
+
+	source := SourceFile
+	if v := lookup(root, "encryption"); v != nil && v.Value != "" {
+		source = v.Value
+	}
+	key, err := LoadKey(source, configPath)
+	if err != nil {
+		return nil, fmt.Errorf("config file has encrypted values: %w", err)
+	}
+
+	var errs []string
+	walkScalars(root, "", func(path string, n *yaml.Node) {
+		if !strings.HasPrefix(n.Value, Prefix) {
+			return
+		}
+		plaintext, err := Open(key, n.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			return
+		}
+		n.Value, n.Tag, n.Style = plaintext, "!!str", yaml.DoubleQuotedStyle
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to decrypt config: %s", strings.Join(errs, "; "))
+	}
+	return yaml.Marshal(&doc)
+}
+
+// Encrypt seals the non-empty values at Fields in a config document
+func Encrypt(data []byte, key []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	for _, path := range Fields {
+		n := at(doc.Content[0], path)
+		if n == nil || n.Kind != yaml.ScalarNode || n.Value == "" || strings.HasPrefix(n.Value, Prefix) {
+			continue
+		}
+		sealed, err := Seal(key, n.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", path, err)
+		}
+		n.Value, n.Tag, n.Style = sealed, "!!str", 0
+	}
+	return yaml.Marshal(&doc)
+}
+
+// at follows a dotted path from the root mapping
+func at(m *yaml.Node, path string) *yaml.Node {
+	for _, key := range strings.Split(path, ".") {
+		if m = lookup(m, key); m == nil {
+			return nil
+		}
+	}
+	return m
+}
+
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// walkScalars calls fn for every scalar value under n with its dotted path
+func walkScalars(n *yaml.Node, path string, fn func(path string, n *yaml.Node)) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		fn(path, n)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			child := n.Content[i].Value
+			if path != "" {
+				child = path + "." + child
+			}
+			walkScalars(n.Content[i+1], child, fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			walkScalars(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
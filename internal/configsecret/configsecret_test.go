@@ -0,0 +1,149 @@
+package configsecret
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/keyring"
+	"gopkg.in/yaml.v3"
+)
+
+const plainConfig = `version: 2
+encryption: file
+auth:
+  token: secret-token
+  email: user@example.com
+wallet:
+  address: "0x1234"
+scanning:
+  mode: smart
+`
+
+type decoded struct {
+	Auth struct {
+		Token string `yaml:"token"`
+		Email string `yaml:"email"`
+	} `yaml:"auth"`
+	Wallet struct {
+		Address string `yaml:"address"`
+	} `yaml:"wallet"`
+}
+
+func TestSealOpen(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := Seal(key, "hello")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !strings.HasPrefix(sealed, Prefix) || strings.Contains(sealed, "hello") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	if got, err := Open(key, sealed); err != nil || got != "hello" {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+
+	other := make([]byte, 32)
+	other[0] = 1
+	if _, err := Open(other, sealed); err == nil {
+		t.Error("expected an error opening with a different key")
+	}
+}
+
+func TestEncryptDecrypt_FileKey(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	key, err := CreateKey(SourceFile, configPath)
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if info, err := os.Stat(KeyPath(configPath)); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file = %v, %v; want mode 0600", info, err)
+	}
+	again, err := CreateKey(SourceFile, configPath)
+	if err != nil || string(again) != string(key) {
+		t.Fatal("CreateKey should reuse the existing key")
+	}
+
+	encrypted, err := Encrypt([]byte(plainConfig), key)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	for _, secret := range []string{"secret-token", "0x1234"} {
+		if strings.Contains(string(encrypted), secret) {
+			t.Errorf("%q is stored in plaintext:\n%s", secret, encrypted)
+		}
+	}
+	if !strings.Contains(string(encrypted), "user@example.com") {
+		t.Error("fields outside Fields should be left alone")
+	}
+
+	decrypted, err := Decrypt(encrypted, configPath)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	var cfg decoded
+	if err := yaml.Unmarshal(decrypted, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.Token != "secret-token" || cfg.Wallet.Address != "0x1234" || cfg.Auth.Email != "user@example.com" {
+		t.Errorf("decrypted = %+v", cfg)
+	}
+}
+
+func TestDecrypt_PlaintextUnchanged(t *testing.T) {
+	data := []byte(plainConfig)
+	got, err := Decrypt(data, filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil || string(got) != plainConfig {
+		t.Errorf("Decrypt of a plaintext config = %q, %v; want it unchanged", got, err)
+	}
+}
+
+func TestDecrypt_MissingKey(t *testing.T) {
+	key := make([]byte, 32)
+	encrypted, err := Encrypt([]byte(plainConfig), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Decrypt(encrypted, filepath.Join(t.TempDir(), "config.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "encrypted values") {
+		t.Errorf("err = %v, want a missing key error", err)
+	}
+}
+
+func TestLoadKey_EnvOverride(t *testing.T) {
+	key := make([]byte, 32)
+	key[31] = 7
+	t.Setenv(KeyEnv, base64.StdEncoding.EncodeToString(key))
+
+	got, err := LoadKey(SourceKeychain, filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil || string(got) != string(key) {
+		t.Errorf("LoadKey = %v, %v; want the key from %s", got, err, KeyEnv)
+	}
+
+	t.Setenv(KeyEnv, "too-short")
+	if _, err := LoadKey(SourceFile, "config.yaml"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestCreateKey_Keychain(t *testing.T) {
+	ring := keyring.NewArrayKeyring(nil)
+	orig := openKeyring
+	openKeyring = func() (keyring.Keyring, error) { return ring, nil }
+	t.Cleanup(func() { openKeyring = orig })
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	key, err := CreateKey(SourceKeychain, configPath)
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if _, err := os.Stat(KeyPath(configPath)); !os.IsNotExist(err) {
+		t.Error("keychain keys should not be written to a file")
+	}
+	got, err := LoadKey(SourceKeychain, configPath)
+	if err != nil || string(got) != string(key) {
+		t.Errorf("LoadKey = %v, %v; want the stored key", got, err)
+	}
+}
@@ -50,7 +50,7 @@ var (
 const accountSelectColumns = `id, account_number, account_type, evm_wallet_address, solana_wallet_address,
        balance_usdc, status, wallet_escrow_enabled, totp_enabled,
        created_at, updated_at, last_login_at, metadata,
-       email, company_name, workos_user_id, stripe_customer_id`
+       email, company_name, workos_user_id, stripe_customer_id, region`
 
 // scanAccount scans a row into an Account struct matching accountSelectColumns.
 func scanAccount(row interface{ Scan(dest ...any) error }) (*Account, error) {
@@ -63,6 +63,7 @@ func scanAccount(row interface{ Scan(dest ...any) error }) (*Account, error) {
 		&account.WalletEscrow, &account.TOTPEnabled,
 		&account.CreatedAt, &account.UpdatedAt, &account.LastLoginAt, &account.Metadata,
 		&account.Email, &account.CompanyName, &account.WorkOSUserID, &account.StripeCustomerID,
+		&account.Region,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -96,6 +97,8 @@ type Account struct {
 	CompanyName      *string `json:"company_name,omitempty"`
 	WorkOSUserID     *string `json:"-"`
 	StripeCustomerID *string `json:"stripe_customer_id,omitempty"`
+	// Region the account's scans must be processed in; nil means any region
+	Region *string `json:"region,omitempty"`
 	// Encrypted wallet key fields - never exposed via JSON
 	EncryptedPrivateKey *string    `json:"-"`
 	KMSKeyID            *string    `json:"-"`
@@ -433,6 +436,22 @@ func (db *DB) SuspendAccount(ctx context.Context, accountID uuid.UUID) error {
 	return nil
 }
 
+// ReactivateAccount makes a suspended account active again. Closed accounts
+// stay closed.
+func (db *DB) ReactivateAccount(ctx context.Context, accountID uuid.UUID) error {
+	_, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`, AccountStatusActive, time.Now().UTC(), accountID, AccountStatusSuspended)
+
+	if err != nil {
+		return fmt.Errorf("failed to reactivate account: %w", err)
+	}
+
+	return nil
+}
+
 // CloseAccount closes an account
 func (db *DB) CloseAccount(ctx context.Context, accountID uuid.UUID) error {
 	_, err := db.pool.Exec(ctx, `
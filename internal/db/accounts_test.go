@@ -436,6 +436,17 @@ func TestAccountStatus(t *testing.T) {
 		assert.Equal(t, AccountStatusSuspended, found.Status)
 	})
 
+	t.Run("reactivate suspended account", func(t *testing.T) {
+		err := db.ReactivateAccount(ctx, account.ID)
+		require.NoError(t, err)
+
+		found, err := db.GetAccountByID(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, AccountStatusActive, found.Status)
+
+		require.NoError(t, db.SuspendAccount(ctx, account.ID))
+	})
+
 	t.Run("close account", func(t *testing.T) {
 		err := db.CloseAccount(ctx, account.ID)
 		require.NoError(t, err)
@@ -443,6 +454,12 @@ func TestAccountStatus(t *testing.T) {
 		found, err := db.GetAccountByID(ctx, account.ID)
 		require.NoError(t, err)
 		assert.Equal(t, AccountStatusClosed, found.Status)
+
+		// Closed accounts are not reopened
+		require.NoError(t, db.ReactivateAccount(ctx, account.ID))
+		found, err = db.GetAccountByID(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, AccountStatusClosed, found.Status)
 	})
 }
 
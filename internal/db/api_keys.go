@@ -12,14 +12,16 @@ import (
 
 // APIKey represents an API key for B2B authentication
 type APIKey struct {
-	ID         uuid.UUID  `json:"id"`
-	AccountID  uuid.UUID  `json:"account_id"`
-	KeyPrefix  string     `json:"key_prefix"`
-	KeyHash    string     `json:"-"`
-	Name       string     `json:"name"`
-	CreatedAt  time.Time  `json:"created_at"`
-	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
-	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ID            uuid.UUID  `json:"id"`
+	AccountID     uuid.UUID  `json:"account_id"`
+	KeyPrefix     string     `json:"key_prefix"`
+	KeyHash       string     `json:"-"`
+	Name          string     `json:"name"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	ScoreExposure *string    `json:"score_exposure,omitempty"` // Overrides the account's score exposure; nil inherits it
+	SubAccountID  *uuid.UUID `json:"sub_account_id,omitempty"` // Sub-account the key bills to; nil for the account itself
 }
 
 // ErrAPIKeyLimitReached is returned when the account has reached the maximum number of active API keys.
@@ -32,13 +34,24 @@ var ErrAPIKeyNotFound = errors.New("API key not found or already revoked")
 // keys per account. The cap is checked atomically under a row lock on the account
 // to prevent concurrent requests from exceeding the limit.
 func (db *DB) CreateAPIKey(ctx context.Context, accountID uuid.UUID, keyPrefix, keyHash, name string, maxKeys int) (*APIKey, error) {
+	return db.createAPIKey(ctx, accountID, nil, keyPrefix, keyHash, name, maxKeys)
+}
+
+// CreateSubAccountAPIKey creates an API key that bills to an open
+// sub-account of accountID. Each sub-account has its own cap of maxKeys.
+func (db *DB) CreateSubAccountAPIKey(ctx context.Context, accountID, subAccountID uuid.UUID, keyPrefix, keyHash, name string, maxKeys int) (*APIKey, error) {
+	return db.createAPIKey(ctx, accountID, &subAccountID, keyPrefix, keyHash, name, maxKeys)
+}
+
+func (db *DB) createAPIKey(ctx context.Context, accountID uuid.UUID, subAccountID *uuid.UUID, keyPrefix, keyHash, name string, maxKeys int) (*APIKey, error) {
 	key := &APIKey{
-		ID:        uuid.New(),
-		AccountID: accountID,
-		KeyPrefix: keyPrefix,
-		KeyHash:   keyHash,
-		Name:      name,
-		CreatedAt: time.Now().UTC(),
+		ID:           uuid.New(),
+		AccountID:    accountID,
+		KeyPrefix:    keyPrefix,
+		KeyHash:      keyHash,
+		Name:         name,
+		CreatedAt:    time.Now().UTC(),
+		SubAccountID: subAccountID,
 	}
 
 	tx, err := db.pool.Begin(ctx)
@@ -53,9 +66,24 @@ func (db *DB) CreateAPIKey(ctx context.Context, accountID uuid.UUID, keyPrefix,
 		return nil, fmt.Errorf("failed to lock account: %w", err)
 	}
 
+	if subAccountID != nil {
+		var open bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM sub_accounts WHERE id = $1 AND account_id = $2 AND closed_at IS NULL)
+		`, *subAccountID, accountID).Scan(&open); err != nil {
+			return nil, fmt.Errorf("failed to check sub-account: %w", err)
+		}
+		if !open {
+			return nil, ErrSubAccountNotFound
+		}
+	}
+
 	// Count active keys under the lock
 	var count int
-	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE account_id = $1 AND revoked_at IS NULL`, accountID).Scan(&count); err != nil {
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM api_keys
+		WHERE account_id = $1 AND sub_account_id IS NOT DISTINCT FROM $2 AND revoked_at IS NULL
+	`, accountID, subAccountID).Scan(&count); err != nil {
 		return nil, fmt.Errorf("failed to count API keys: %w", err)
 	}
 	if count >= maxKeys {
@@ -64,9 +92,9 @@ func (db *DB) CreateAPIKey(ctx context.Context, accountID uuid.UUID, keyPrefix,
 
 	// Insert the key
 	if _, err := tx.Exec(ctx, `
-		INSERT INTO api_keys (id, account_id, key_prefix, key_hash, name, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, key.ID, key.AccountID, key.KeyPrefix, key.KeyHash, key.Name, key.CreatedAt); err != nil {
+		INSERT INTO api_keys (id, account_id, key_prefix, key_hash, name, created_at, sub_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, key.ID, key.AccountID, key.KeyPrefix, key.KeyHash, key.Name, key.CreatedAt, key.SubAccountID); err != nil {
 		return nil, fmt.Errorf("failed to create API key: %w", err)
 	}
 
@@ -81,12 +109,12 @@ func (db *DB) CreateAPIKey(ctx context.Context, accountID uuid.UUID, keyPrefix,
 func (db *DB) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
 	key := &APIKey{}
 	err := db.QueryRow(ctx, `
-		SELECT id, account_id, key_prefix, key_hash, name, created_at, last_used_at, revoked_at
+		SELECT id, account_id, key_prefix, key_hash, name, created_at, last_used_at, revoked_at, score_exposure, sub_account_id
 		FROM api_keys
 		WHERE key_hash = $1 AND revoked_at IS NULL
 	`, keyHash).Scan(
 		&key.ID, &key.AccountID, &key.KeyPrefix, &key.KeyHash, &key.Name,
-		&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt,
+		&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt, &key.ScoreExposure, &key.SubAccountID,
 	)
 
 	if err != nil {
@@ -102,7 +130,7 @@ func (db *DB) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, err
 // ListAPIKeys lists all non-revoked API keys for an account
 func (db *DB) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]APIKey, error) {
 	rows, err := db.pool.Query(ctx, `
-		SELECT id, account_id, key_prefix, key_hash, name, created_at, last_used_at, revoked_at
+		SELECT id, account_id, key_prefix, key_hash, name, created_at, last_used_at, revoked_at, score_exposure, sub_account_id
 		FROM api_keys
 		WHERE account_id = $1 AND revoked_at IS NULL
 		ORDER BY created_at DESC
@@ -117,7 +145,7 @@ func (db *DB) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]APIKey, e
 		var key APIKey
 		if err := rows.Scan(
 			&key.ID, &key.AccountID, &key.KeyPrefix, &key.KeyHash, &key.Name,
-			&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt,
+			&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt, &key.ScoreExposure, &key.SubAccountID,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
 		}
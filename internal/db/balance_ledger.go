@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// Balance ledger entry kinds
+const (
+	LedgerTransferOut = "transfer_out"
+	LedgerTransferIn  = "transfer_in"
+)
+
+var (
+	// ErrTransferToSelf is returned when an account transfers to itself
+	ErrTransferToSelf = errors.New("cannot transfer to the same account")
+	// ErrTransferRecipientInactive is returned when the receiving account is
+	// suspended or closed
+	ErrTransferRecipientInactive = errors.New("recipient account is not active")
+	// ErrTransferInsufficientBalance is returned when the sender's balance
+	// doesn't cover the transfer
+	ErrTransferInsufficientBalance = errors.New("insufficient balance")
+	// ErrTransferLimitExceeded is returned when the transfer would take the
+	// sender past its daily limit
+	ErrTransferLimitExceeded = errors.New("daily transfer limit exceeded")
+)
+
+// LedgerEntry is one side of a balance transfer between two accounts.
+// AmountUSDC is negative for the sender and positive for the recipient.
+type LedgerEntry struct {
+	ID                        uuid.UUID      `json:"id"`
+	TransferID                uuid.UUID      `json:"transfer_id"`
+	AccountID                 uuid.UUID      `json:"-"`
+	Kind                      string         `json:"kind"` // "transfer_out" or "transfer_in"
+	AmountUSDC                usdc.MicroUSDC `json:"amount_usdc"`
+	BalanceAfterUSDC          usdc.MicroUSDC `json:"balance_after_usdc"`
+	CounterpartyAccountNumber string         `json:"counterparty_account_number"`
+	Memo                      *string        `json:"memo,omitempty"`
+	CreatedAt                 time.Time      `json:"created_at"`
+}
+
+// transferDayStart is the start of the UTC day daily limits are counted from
+func transferDayStart(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// TransferBalance moves amount from one account's balance to another's and
+// records a ledger entry on each side, returning the sender's. The sender
+// may transfer at most dailyLimit per UTC day; 0 leaves it unlimited.
+func (db *DB) TransferBalance(ctx context.Context, fromID, toID uuid.UUID, amount, dailyLimit usdc.MicroUSDC, memo string) (*LedgerEntry, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if fromID == toID {
+		return nil, ErrTransferToSelf
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both accounts in id order, so transfers in opposite directions
+	// can't deadlock, and serialize the sender's transfers against its limit
+	type locked struct {
+		number  string
+		balance usdc.MicroUSDC
+		status  AccountStatus
+	}
+	accounts := make(map[uuid.UUID]*locked, 2)
+	rows, err := tx.Query(ctx, `
+		SELECT id, COALESCE(account_number, ''), balance_usdc, status
+		FROM accounts
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE
+	`, fromID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		a := &locked{}
+		if err := rows.Scan(&id, &a.number, &a.balance, &a.status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts[id] = a
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+
+	from, to := accounts[fromID], accounts[toID]
+	if from == nil || to == nil {
+		return nil, ErrAccountNotFound
+	}
+	if to.status != AccountStatusActive {
+		return nil, ErrTransferRecipientInactive
+	}
+	if from.balance < amount {
+		return nil, ErrTransferInsufficientBalance
+	}
+
+	now := time.Now().UTC()
+	if dailyLimit > 0 {
+		var sent usdc.MicroUSDC
+		if err := tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(-amount_usdc), 0)
+			FROM balance_ledger
+			WHERE account_id = $1 AND kind = $2 AND created_at >= $3
+		`, fromID, LedgerTransferOut, transferDayStart(now)).Scan(&sent); err != nil {
+			return nil, fmt.Errorf("failed to sum today's transfers: %w", err)
+		}
+		if sent+amount > dailyLimit {
+			return nil, ErrTransferLimitExceeded
+		}
+	}
+
+	for _, update := range []struct {
+		id    uuid.UUID
+		delta usdc.MicroUSDC
+	}{{fromID, -amount}, {toID, amount}} {
+		if _, err := tx.Exec(ctx, `
+			UPDATE accounts SET balance_usdc = balance_usdc + $1, updated_at = $2
+			WHERE id = $3
+		`, update.delta, now, update.id); err != nil {
+			return nil, fmt.Errorf("failed to update balance: %w", err)
+		}
+	}
+
+	transferID := uuid.New()
+	out := &LedgerEntry{
+		ID:                        uuid.New(),
+		TransferID:                transferID,
+		AccountID:                 fromID,
+		Kind:                      LedgerTransferOut,
+		AmountUSDC:                -amount,
+		BalanceAfterUSDC:          from.balance - amount,
+		CounterpartyAccountNumber: to.number,
+		Memo:                      labelOrNull(memo),
+		CreatedAt:                 now,
+	}
+	in := &LedgerEntry{
+		ID:                        uuid.New(),
+		TransferID:                transferID,
+		AccountID:                 toID,
+		Kind:                      LedgerTransferIn,
+		AmountUSDC:                amount,
+		BalanceAfterUSDC:          to.balance + amount,
+		CounterpartyAccountNumber: from.number,
+		Memo:                      out.Memo,
+		CreatedAt:                 now,
+	}
+	for _, e := range []*LedgerEntry{out, in} {
+		counterparty := toID
+		if e == in {
+			counterparty = fromID
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO balance_ledger (id, transfer_id, account_id, kind, amount_usdc, balance_after_usdc,
+			                            counterparty_account_id, counterparty_account_number, memo, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, e.ID, e.TransferID, e.AccountID, e.Kind, e.AmountUSDC, e.BalanceAfterUSDC,
+			counterparty, e.CounterpartyAccountNumber, e.Memo, e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to record ledger entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return out, nil
+}
+
+// TransferredToday returns how much an account has transferred out since the
+// start of the current UTC day
+func (db *DB) TransferredToday(ctx context.Context, accountID uuid.UUID) (usdc.MicroUSDC, error) {
+	var sent usdc.MicroUSDC
+	err := db.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(-amount_usdc), 0)
+		FROM balance_ledger
+		WHERE account_id = $1 AND kind = $2 AND created_at >= $3
+	`, accountID, LedgerTransferOut, transferDayStart(time.Now())).Scan(&sent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum today's transfers: %w", err)
+	}
+	return sent, nil
+}
+
+// GetLedgerEntries returns an account's ledger entries, newest first
+func (db *DB) GetLedgerEntries(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*LedgerEntry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, transfer_id, account_id, kind, amount_usdc, balance_after_usdc,
+		       counterparty_account_number, memo, created_at
+		FROM balance_ledger
+		WHERE account_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`, accountID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*LedgerEntry{}
+	for rows.Next() {
+		e := &LedgerEntry{}
+		if err := rows.Scan(&e.ID, &e.TransferID, &e.AccountID, &e.Kind, &e.AmountUSDC, &e.BalanceAfterUSDC,
+			&e.CounterpartyAccountNumber, &e.Memo, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ledger entries: %w", err)
+	}
+	return entries, nil
+}
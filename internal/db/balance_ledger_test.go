@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferBalance(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	treasury, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	member, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateBalance(ctx, treasury.ID, 100_000_000))
+
+	out, err := db.TransferBalance(ctx, treasury.ID, member.ID, 30_000_000, 50_000_000, "March budget")
+	require.NoError(t, err)
+	assert.Equal(t, LedgerTransferOut, out.Kind)
+	assert.Equal(t, usdc.MicroUSDC(-30_000_000), out.AmountUSDC)
+	assert.Equal(t, usdc.MicroUSDC(70_000_000), out.BalanceAfterUSDC)
+	assert.Equal(t, member.AccountNumber, out.CounterpartyAccountNumber)
+
+	// Both sides are in the ledger under the same transfer
+	in, err := db.GetLedgerEntries(ctx, member.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, in, 1)
+	assert.Equal(t, out.TransferID, in[0].TransferID)
+	assert.Equal(t, LedgerTransferIn, in[0].Kind)
+	assert.Equal(t, usdc.MicroUSDC(30_000_000), in[0].AmountUSDC)
+	assert.Equal(t, usdc.MicroUSDC(30_000_000), in[0].BalanceAfterUSDC)
+	assert.Equal(t, treasury.AccountNumber, in[0].CounterpartyAccountNumber)
+	require.NotNil(t, in[0].Memo)
+	assert.Equal(t, "March budget", *in[0].Memo)
+
+	got, err := db.GetAccountByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(30_000_000), got.BalanceUSDC)
+
+	// The daily limit counts what was already sent today
+	_, err = db.TransferBalance(ctx, treasury.ID, member.ID, 25_000_000, 50_000_000, "")
+	assert.ErrorIs(t, err, ErrTransferLimitExceeded)
+	sent, err := db.TransferredToday(ctx, treasury.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(30_000_000), sent)
+
+	_, err = db.TransferBalance(ctx, member.ID, treasury.ID, 40_000_000, 0, "")
+	assert.ErrorIs(t, err, ErrTransferInsufficientBalance)
+	_, err = db.TransferBalance(ctx, member.ID, member.ID, 1, 0, "")
+	assert.ErrorIs(t, err, ErrTransferToSelf)
+
+	require.NoError(t, db.SuspendAccount(ctx, member.ID))
+	_, err = db.TransferBalance(ctx, treasury.ID, member.ID, 1_000_000, 0, "")
+	assert.ErrorIs(t, err, ErrTransferRecipientInactive)
+
+	// Refused transfers leave balances and the ledger untouched
+	got, err = db.GetAccountByID(ctx, treasury.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(70_000_000), got.BalanceUSDC)
+	entries, err := db.GetLedgerEntries(ctx, treasury.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
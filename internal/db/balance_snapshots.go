@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// Balance event types
+const (
+	BalanceEventDeposit     = "deposit"
+	BalanceEventWithdrawal  = "withdrawal"
+	BalanceEventTransferOut = LedgerTransferOut
+	BalanceEventTransferIn  = LedgerTransferIn
+)
+
+// maxBalanceHistoryDays bounds how far back balance history is read
+const maxBalanceHistoryDays = 365
+
+// BalanceSnapshot is an account's balances at the end of a UTC day. On-chain
+// balances are omitted for days they could not be read.
+type BalanceSnapshot struct {
+	Date              string          `json:"date"` // YYYY-MM-DD
+	BalanceUSDC       usdc.MicroUSDC  `json:"balance_usdc"`
+	EVMBalanceUSDC    *usdc.MicroUSDC `json:"evm_balance_usdc,omitempty"`
+	SolanaBalanceUSDC *usdc.MicroUSDC `json:"solana_balance_usdc,omitempty"`
+}
+
+// BalanceEvent marks money added to or taken from an account's balance
+// outside of metered scans
+type BalanceEvent struct {
+	Type       string         `json:"type"`               // "deposit", "withdrawal", "transfer_out" or "transfer_in"
+	AmountUSDC usdc.MicroUSDC `json:"amount_usdc"`        // net amount of deposits
+	Provider   string         `json:"provider,omitempty"` // deposits only
+	At         time.Time      `json:"at"`
+}
+
+// BalanceHistory is an account's daily balances and the deposits and
+// withdrawals between them, oldest first
+type BalanceHistory struct {
+	Days   []*BalanceSnapshot `json:"days"`
+	Events []*BalanceEvent    `json:"events"`
+}
+
+// OnchainSnapshot is an account whose wallets' balances have not been
+// snapshot for a day
+type OnchainSnapshot struct {
+	AccountID           uuid.UUID
+	EVMWalletAddress    *string
+	SolanaWalletAddress *string
+}
+
+// SnapshotBalances records every active account's on-platform balance for a
+// UTC day, replacing the day's earlier snapshot so the last one of the day
+// holds its closing balance. Returns how many accounts were snapshot.
+func (db *DB) SnapshotBalances(ctx context.Context, day time.Time) (int64, error) {
+	tag, err := db.pool.Exec(ctx, `
+		INSERT INTO balance_snapshots (account_id, day, balance_usdc)
+		SELECT id, $1::date, balance_usdc
+		FROM accounts
+		WHERE status = $2
+		ON CONFLICT (account_id, day) DO UPDATE
+		SET balance_usdc = EXCLUDED.balance_usdc, updated_at = NOW()
+	`, day.UTC(), AccountStatusActive)
+	if err != nil {
+		return 0, fmt.Errorf("failed to snapshot balances: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PendingOnchainSnapshots returns up to limit accounts with wallets whose
+// on-chain balances have not been recorded for a UTC day
+func (db *DB) PendingOnchainSnapshots(ctx context.Context, day time.Time, limit int) ([]*OnchainSnapshot, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT a.id, NULLIF(a.evm_wallet_address, ''), NULLIF(a.solana_wallet_address, '')
+		FROM balance_snapshots s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE s.day = $1::date AND s.onchain_at IS NULL
+		  AND (NULLIF(a.evm_wallet_address, '') IS NOT NULL OR NULLIF(a.solana_wallet_address, '') IS NOT NULL)
+		ORDER BY s.updated_at
+		LIMIT $2
+	`, day.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending on-chain snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	pending := []*OnchainSnapshot{}
+	for rows.Next() {
+		s := &OnchainSnapshot{}
+		if err := rows.Scan(&s.AccountID, &s.EVMWalletAddress, &s.SolanaWalletAddress); err != nil {
+			return nil, fmt.Errorf("failed to scan pending on-chain snapshot: %w", err)
+		}
+		pending = append(pending, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending on-chain snapshots: %w", err)
+	}
+	return pending, nil
+}
+
+// RecordOnchainBalances stores the wallet balances read for an account's
+// snapshot of a UTC day. Nil balances keep what was recorded before; the
+// snapshot stops being pending once complete is set.
+func (db *DB) RecordOnchainBalances(ctx context.Context, accountID uuid.UUID, day time.Time, evm, solana *usdc.MicroUSDC, complete bool) error {
+	_, err := db.pool.Exec(ctx, `
+		UPDATE balance_snapshots
+		SET evm_balance_usdc = COALESCE($3, evm_balance_usdc),
+		    solana_balance_usdc = COALESCE($4, solana_balance_usdc),
+		    onchain_at = CASE WHEN $5 THEN NOW() END,
+		    updated_at = NOW()
+		WHERE account_id = $1 AND day = $2::date
+	`, accountID, day.UTC(), evm, solana, complete)
+	if err != nil {
+		return fmt.Errorf("failed to record on-chain balances: %w", err)
+	}
+	return nil
+}
+
+// GetBalanceHistory returns an account's daily balance snapshots over the
+// last days UTC days, including the current one, with the deposits that
+// completed, the balance debits and the transfers made over the same period.
+// Balance debits settle payments owed for scans served on credit, and are
+// reported as withdrawals.
+func (db *DB) GetBalanceHistory(ctx context.Context, accountID uuid.UUID, days int, now time.Time) (*BalanceHistory, error) {
+	if days <= 0 {
+		days = 30
+	}
+	days = min(days, maxBalanceHistoryDays)
+	from := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	history := &BalanceHistory{
+		Days:   []*BalanceSnapshot{},
+		Events: []*BalanceEvent{},
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), balance_usdc, evm_balance_usdc, solana_balance_usdc
+		FROM balance_snapshots
+		WHERE account_id = $1 AND day >= $2::date
+		ORDER BY day
+	`, accountID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance snapshots: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := &BalanceSnapshot{}
+		if err := rows.Scan(&s.Date, &s.BalanceUSDC, &s.EVMBalanceUSDC, &s.SolanaBalanceUSDC); err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+		}
+		history.Days = append(history.Days, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate balance snapshots: %w", err)
+	}
+
+	rows, err = db.pool.Query(ctx, `
+		SELECT 'deposit', net_amount_usdc, provider::text, completed_at
+		FROM deposits
+		WHERE account_id = $1 AND status = 'completed' AND completed_at >= $2
+		UNION ALL
+		SELECT 'withdrawal', amount_usdc, '', settled_at
+		FROM payment_receivables
+		WHERE account_id = $1 AND settled_via = 'balance' AND settled_at >= $2
+		UNION ALL
+		SELECT kind, ABS(amount_usdc), '', created_at
+		FROM balance_ledger
+		WHERE account_id = $1 AND created_at >= $2
+		ORDER BY 4
+	`, accountID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := &BalanceEvent{}
+		if err := rows.Scan(&e.Type, &e.AmountUSDC, &e.Provider, &e.At); err != nil {
+			return nil, fmt.Errorf("failed to scan balance event: %w", err)
+		}
+		history.Events = append(history.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate balance events: %w", err)
+	}
+
+	return history, nil
+}
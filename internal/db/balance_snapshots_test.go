@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceSnapshots(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+	fixtures := NewFixtures(t, db)
+
+	account := fixtures.CreateTestAccountWithWallet().Account
+	bare := fixtures.CreateTestAccount(nil).Account
+	fixtures.CreateCompletedDeposit(account.ID, 5_000_000)
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+
+	_, err := db.SnapshotBalances(ctx, yesterday)
+	require.NoError(t, err)
+
+	// Later snapshots of a day replace its balance
+	_, err = testDB.Pool.Exec(ctx, `UPDATE accounts SET balance_usdc = 4000000 WHERE id = $1`, account.ID)
+	require.NoError(t, err)
+	_, err = db.SnapshotBalances(ctx, today)
+	require.NoError(t, err)
+	_, err = testDB.Pool.Exec(ctx, `UPDATE accounts SET balance_usdc = 3500000 WHERE id = $1`, account.ID)
+	require.NoError(t, err)
+	_, err = db.SnapshotBalances(ctx, today)
+	require.NoError(t, err)
+
+	// Only accounts with wallets wait on on-chain balances
+	pending, err := db.PendingOnchainSnapshots(ctx, today, 100)
+	require.NoError(t, err)
+	ids := []uuid.UUID{}
+	for _, p := range pending {
+		ids = append(ids, p.AccountID)
+	}
+	assert.Contains(t, ids, account.ID)
+	assert.NotContains(t, ids, bare.ID)
+
+	evm := usdc.MicroUSDC(2_500_000)
+	require.NoError(t, db.RecordOnchainBalances(ctx, account.ID, today, &evm, nil, false))
+	require.NoError(t, db.RecordOnchainBalances(ctx, account.ID, today, nil, nil, true))
+	pending, err = db.PendingOnchainSnapshots(ctx, today, 100)
+	require.NoError(t, err)
+	for _, p := range pending {
+		assert.NotEqual(t, account.ID, p.AccountID)
+	}
+
+	// A balance debit settling a receivable is a withdrawal
+	_, err = testDB.Pool.Exec(ctx, `
+		INSERT INTO payment_receivables (account_id, payment_transaction_id, amount_usdc, status, settled_via, settled_at)
+		VALUES ($1, $2, 500000, 'settled', 'balance', NOW())
+	`, account.ID, uuid.New())
+	require.NoError(t, err)
+
+	history, err := db.GetBalanceHistory(ctx, account.ID, 7, now)
+	require.NoError(t, err)
+	require.Len(t, history.Days, 2)
+	assert.Equal(t, yesterday.Format("2006-01-02"), history.Days[0].Date)
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), history.Days[0].BalanceUSDC)
+	assert.Nil(t, history.Days[0].EVMBalanceUSDC)
+	assert.Equal(t, usdc.MicroUSDC(3_500_000), history.Days[1].BalanceUSDC)
+	assert.Equal(t, &evm, history.Days[1].EVMBalanceUSDC)
+	assert.Nil(t, history.Days[1].SolanaBalanceUSDC)
+
+	require.Len(t, history.Events, 2)
+	assert.Equal(t, BalanceEventDeposit, history.Events[0].Type)
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), history.Events[0].AmountUSDC)
+	assert.Equal(t, BalanceEventWithdrawal, history.Events[1].Type)
+	assert.Equal(t, usdc.MicroUSDC(500_000), history.Events[1].AmountUSDC)
+
+	// The window ends today
+	history, err = db.GetBalanceHistory(ctx, account.ID, 1, now)
+	require.NoError(t, err)
+	require.Len(t, history.Days, 1)
+	assert.Equal(t, today.Format("2006-01-02"), history.Days[0].Date)
+}
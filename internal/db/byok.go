@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetAccountEncryptionKey returns the customer-managed KMS key ARN the
+// account's scan samples are sealed under, or "" when it has none
+func (db *DB) GetAccountEncryptionKey(ctx context.Context, accountID uuid.UUID) (string, error) {
+	var keyARN *string
+	err := db.pool.QueryRow(ctx, `
+		SELECT encryption_key_arn FROM accounts WHERE id = $1
+	`, accountID).Scan(&keyARN)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrAccountNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get account encryption key: %w", err)
+	}
+	if keyARN == nil {
+		return "", nil
+	}
+	return *keyARN, nil
+}
+
+// SetAccountEncryptionKey seals the account's future scan samples under
+// keyARN. Samples already sealed under a previous key keep that key.
+func (db *DB) SetAccountEncryptionKey(ctx context.Context, accountID uuid.UUID, keyARN string) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE accounts SET encryption_key_arn = $1, updated_at = $2 WHERE id = $3
+	`, keyARN, time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to set account encryption key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// RemoveAccountEncryptionKey removes the account's customer-managed key and
+// deletes the samples sealed under any of its keys, which could not be read
+// without them. It returns the number of samples deleted.
+func (db *DB) RemoveAccountEncryptionKey(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE accounts SET encryption_key_arn = NULL, updated_at = $1 WHERE id = $2
+	`, time.Now().UTC(), accountID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove account encryption key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, ErrAccountNotFound
+	}
+
+	result, err = tx.Exec(ctx, `
+		DELETE FROM scan_samples WHERE account_id = $1 AND encryption_key_arn IS NOT NULL
+	`, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sealed scan samples: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
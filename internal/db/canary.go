@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CanaryEnrollment routes a percentage of an account's scans through the
+// canary detection configuration
+type CanaryEnrollment struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Percent   float64   `json:"percent"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CanaryResult records the stable and canary decisions for one scan
+type CanaryResult struct {
+	ID             uuid.UUID  `json:"id"`
+	AccountID      *uuid.UUID `json:"account_id,omitempty"`
+	RequestID      string     `json:"request_id"`
+	Endpoint       string     `json:"endpoint"`
+	StableVersion  string     `json:"stable_version"`
+	CanaryVersion  string     `json:"canary_version"`
+	StableDecision string     `json:"stable_decision"`
+	CanaryDecision string     `json:"canary_decision"`
+	StableScore    float64    `json:"stable_score"`
+	CanaryScore    float64    `json:"canary_score"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CanarySummary aggregates canary results
+type CanarySummary struct {
+	Total       int64            `json:"total"`
+	Agreed      int64            `json:"agreed"`
+	Transitions map[string]int64 `json:"transitions"` // "ALLOW->BLOCK": count, stable decision first
+}
+
+// SetCanaryEnrollment enrolls an account in the canary, or updates its percentage
+func (db *DB) SetCanaryEnrollment(ctx context.Context, accountID uuid.UUID, percent float64) (*CanaryEnrollment, error) {
+	e := &CanaryEnrollment{}
+	err := db.pool.QueryRow(ctx, `
+		INSERT INTO canary_enrollments (account_id, percent)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE SET percent = EXCLUDED.percent, updated_at = NOW()
+		RETURNING account_id, percent::float8, created_at, updated_at
+	`, accountID, percent).Scan(&e.AccountID, &e.Percent, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set canary enrollment: %w", err)
+	}
+	return e, nil
+}
+
+// DeleteCanaryEnrollment removes an account from the canary
+func (db *DB) DeleteCanaryEnrollment(ctx context.Context, accountID uuid.UUID) error {
+	if _, err := db.pool.Exec(ctx, `DELETE FROM canary_enrollments WHERE account_id = $1`, accountID); err != nil {
+		return fmt.Errorf("failed to delete canary enrollment: %w", err)
+	}
+	return nil
+}
+
+// GetCanaryPercent returns an account's canary percentage, and false if the
+// account is not enrolled
+func (db *DB) GetCanaryPercent(ctx context.Context, accountID uuid.UUID) (float64, bool, error) {
+	var percent float64
+	err := db.pool.QueryRow(ctx, `
+		SELECT percent::float8 FROM canary_enrollments WHERE account_id = $1
+	`, accountID).Scan(&percent)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to get canary enrollment: %w", err)
+	}
+	return percent, true, nil
+}
+
+// ListCanaryEnrollments returns all enrolled accounts
+func (db *DB) ListCanaryEnrollments(ctx context.Context) ([]*CanaryEnrollment, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT account_id, percent::float8, created_at, updated_at
+		FROM canary_enrollments
+		ORDER BY created_at ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list canary enrollments: %w", err)
+	}
+	defer rows.Close()
+
+	enrollments := []*CanaryEnrollment{}
+	for rows.Next() {
+		e := &CanaryEnrollment{}
+		if err := rows.Scan(&e.AccountID, &e.Percent, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan canary enrollment: %w", err)
+		}
+		enrollments = append(enrollments, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating canary enrollments: %w", err)
+	}
+	return enrollments, nil
+}
+
+// CreateCanaryResult records a canary comparison
+func (db *DB) CreateCanaryResult(ctx context.Context, r *CanaryResult) error {
+	r.ID = uuid.New()
+	r.CreatedAt = time.Now().UTC()
+	_, err := db.pool.Exec(ctx, `
+		INSERT INTO canary_results (
+			id, account_id, request_id, endpoint, stable_version, canary_version,
+			stable_decision, canary_decision, stable_score, canary_score, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, r.ID, r.AccountID, r.RequestID, r.Endpoint, r.StableVersion, r.CanaryVersion,
+		r.StableDecision, r.CanaryDecision, r.StableScore, r.CanaryScore, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create canary result: %w", err)
+	}
+	return nil
+}
+
+// GetCanarySummary aggregates canary results since a time, for one account
+// or, with a nil accountID, for all traffic
+func (db *DB) GetCanarySummary(ctx context.Context, accountID *uuid.UUID, since time.Time) (*CanarySummary, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT stable_decision, canary_decision, COUNT(*)
+		FROM canary_results
+		WHERE created_at >= $1 AND ($2::uuid IS NULL OR account_id = $2)
+		GROUP BY stable_decision, canary_decision
+	`, since, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get canary summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &CanarySummary{Transitions: make(map[string]int64)}
+	for rows.Next() {
+		var stable, canary string
+		var count int64
+		if err := rows.Scan(&stable, &canary, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan canary summary: %w", err)
+		}
+		summary.Total += count
+		if stable == canary {
+			summary.Agreed += count
+		}
+		summary.Transitions[stable+"->"+canary] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating canary summary: %w", err)
+	}
+	return summary, nil
+}
+
+// ListCanaryDisagreements returns the most recent results where the canary
+// decision differed from the stable one
+func (db *DB) ListCanaryDisagreements(ctx context.Context, accountID *uuid.UUID, since time.Time, limit int) ([]*CanaryResult, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, account_id, request_id, endpoint, stable_version, canary_version,
+		       stable_decision, canary_decision, stable_score, canary_score, created_at
+		FROM canary_results
+		WHERE created_at >= $1 AND ($2::uuid IS NULL OR account_id = $2)
+		  AND stable_decision <> canary_decision
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, since, accountID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list canary disagreements: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*CanaryResult{}
+	for rows.Next() {
+		r := &CanaryResult{}
+		if err := rows.Scan(
+			&r.ID, &r.AccountID, &r.RequestID, &r.Endpoint, &r.StableVersion, &r.CanaryVersion,
+			&r.StableDecision, &r.CanaryDecision, &r.StableScore, &r.CanaryScore, &r.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan canary result: %w", err)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating canary results: %w", err)
+	}
+	return results, nil
+}
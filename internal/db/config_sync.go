@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrConfigSyncNotFound is returned when the account has never synced
+	// its configuration
+	ErrConfigSyncNotFound = errors.New("no synced configuration")
+	// ErrConfigSyncConflict is returned when the synced configuration was
+	// written since the version the caller based its change on
+	ErrConfigSyncConflict = errors.New("synced configuration has changed")
+)
+
+// ConfigSync is an account's encrypted CLI configuration. Data is opaque to
+// the API.
+type ConfigSync struct {
+	AccountID uuid.UUID `json:"-"`
+	Version   int64     `json:"version"`
+	Data      string    `json:"data"`
+	UpdatedBy *string   `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetConfigSync returns the account's synced configuration
+func (db *DB) GetConfigSync(ctx context.Context, accountID uuid.UUID) (*ConfigSync, error) {
+	s := ConfigSync{AccountID: accountID}
+	err := db.pool.QueryRow(ctx, `
+		SELECT version, data, updated_by, updated_at
+		FROM config_sync
+		WHERE account_id = $1
+	`, accountID).Scan(&s.Version, &s.Data, &s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrConfigSyncNotFound
+		}
+		return nil, fmt.Errorf("failed to get synced configuration: %w", err)
+	}
+	return &s, nil
+}
+
+// PutConfigSync stores data as the account's synced configuration if the
+// stored version is still baseVersion (0 when nothing has been synced), and
+// returns the new version. Otherwise nothing is written and
+// ErrConfigSyncConflict is returned.
+func (db *DB) PutConfigSync(ctx context.Context, accountID uuid.UUID, baseVersion int64, data, updatedBy string) (*ConfigSync, error) {
+	s := ConfigSync{AccountID: accountID, Data: data}
+	query := `
+		UPDATE config_sync
+		SET version = version + 1, data = $3, updated_by = $4, updated_at = $5
+		WHERE account_id = $1 AND version = $2
+		RETURNING version, updated_by, updated_at`
+	if baseVersion == 0 {
+		query = `
+		INSERT INTO config_sync (account_id, version, data, updated_by, updated_at)
+		SELECT $1, $2::BIGINT + 1, $3, $4, $5
+		ON CONFLICT (account_id) DO NOTHING
+		RETURNING version, updated_by, updated_at`
+	}
+	err := db.pool.QueryRow(ctx, query, accountID, baseVersion, data, labelOrNull(updatedBy), time.Now().UTC()).
+		Scan(&s.Version, &s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrConfigSyncConflict
+		}
+		return nil, fmt.Errorf("failed to store synced configuration: %w", err)
+	}
+	return &s, nil
+}
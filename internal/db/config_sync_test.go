@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSync(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	_, err = db.GetConfigSync(ctx, account.ID)
+	assert.ErrorIs(t, err, ErrConfigSyncNotFound)
+	_, err = db.PutConfigSync(ctx, account.ID, 3, "c2VhbGVk", "laptop")
+	assert.ErrorIs(t, err, ErrConfigSyncConflict, "nothing to base a change on")
+
+	first, err := db.PutConfigSync(ctx, account.ID, 0, "djE=", "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), first.Version)
+	_, err = db.PutConfigSync(ctx, account.ID, 0, "djE=", "desktop")
+	assert.ErrorIs(t, err, ErrConfigSyncConflict, "a second first write conflicts")
+
+	second, err := db.PutConfigSync(ctx, account.ID, 1, "djI=", "desktop")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), second.Version)
+	_, err = db.PutConfigSync(ctx, account.ID, 1, "c3RhbGU=", "laptop")
+	assert.ErrorIs(t, err, ErrConfigSyncConflict, "a stale write conflicts")
+
+	got, err := db.GetConfigSync(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Version)
+	assert.Equal(t, "djI=", got.Data)
+	require.NotNil(t, got.UpdatedBy)
+	assert.Equal(t, "desktop", *got.UpdatedBy)
+
+	_, err = db.GetConfigSync(ctx, other.ID)
+	assert.ErrorIs(t, err, ErrConfigSyncNotFound, "configuration is scoped to the account")
+}
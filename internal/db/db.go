@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"time"
 
+	"stronghold/internal/chaos"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -86,6 +88,16 @@ func New(cfg *Config) (*DB, error) {
 	poolConfig.MaxConnLifetime = time.Hour
 	poolConfig.MaxConnIdleTime = 30 * time.Minute
 	poolConfig.HealthCheckPeriod = 5 * time.Minute
+	if chaos.Enabled {
+		// Injected db faults delay checkouts or kill the connection and fail
+		// the query
+		poolConfig.PrepareConn = func(ctx context.Context, _ *pgx.Conn) (bool, error) {
+			if err := chaos.Before(ctx, chaos.DB); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
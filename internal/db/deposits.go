@@ -28,6 +28,7 @@ type DepositProvider string
 const (
 	DepositProviderStripe  DepositProvider = "stripe"
 	DepositProviderDirect  DepositProvider = "direct"
+	DepositProviderPrepaidCode DepositProvider = "prepaid_code"
 )
 
 // Deposit represents a payment deposit
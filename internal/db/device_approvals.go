@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Device approval statuses. Expired is reported for pending requests past
+// their expiry and is never stored.
+const (
+	DeviceApprovalPending  = "pending"
+	DeviceApprovalApproved = "approved"
+	DeviceApprovalDenied   = "denied"
+	DeviceApprovalExpired  = "expired"
+)
+
+// DeviceApprovalEventType is the event name of device approval webhook payloads
+const DeviceApprovalEventType = "device.approval_requested"
+
+var (
+	// ErrDeviceApprovalNotPending is returned when deciding a request that
+	// was already decided or has expired
+	ErrDeviceApprovalNotPending = errors.New("device approval is not pending")
+	// ErrTooManyDeviceApprovals is returned when an account already has the
+	// maximum number of pending requests
+	ErrTooManyDeviceApprovals = errors.New("too many pending device approvals")
+)
+
+// DeviceApproval is a new machine's request to become a trusted device
+type DeviceApproval struct {
+	ID          uuid.UUID  `json:"id"`
+	AccountID   uuid.UUID  `json:"account_id"`
+	Label       *string    `json:"label,omitempty"`
+	OS          *string    `json:"os,omitempty"`
+	Hostname    *string    `json:"hostname,omitempty"`
+	TTLDays     int        `json:"ttl_days"`
+	RequestedIP *string    `json:"requested_ip,omitempty"`
+	UserAgent   *string    `json:"user_agent,omitempty"`
+	Status      string     `json:"status"`
+	DeviceID    *uuid.UUID `json:"device_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+}
+
+// DeviceApprovalEvent is the body of a device approval webhook delivery, sent
+// so the account's owner can approve or deny the request from a trusted device
+type DeviceApprovalEvent struct {
+	Event       string    `json:"event"` // always "device.approval_requested"
+	AccountID   uuid.UUID `json:"account_id"`
+	ApprovalID  uuid.UUID `json:"approval_id"`
+	Label       *string   `json:"label,omitempty"`
+	OS          *string   `json:"os,omitempty"`
+	Hostname    *string   `json:"hostname,omitempty"`
+	RequestedIP *string   `json:"requested_ip,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+const deviceApprovalColumns = `id, account_id, label, os, hostname, ttl_days, requested_ip, user_agent, status, device_id, created_at, expires_at, decided_at`
+
+func scanDeviceApproval(row pgx.Row) (*DeviceApproval, error) {
+	var a DeviceApproval
+	if err := row.Scan(&a.ID, &a.AccountID, &a.Label, &a.OS, &a.Hostname, &a.TTLDays, &a.RequestedIP, &a.UserAgent,
+		&a.Status, &a.DeviceID, &a.CreatedAt, &a.ExpiresAt, &a.DecidedAt); err != nil {
+		return nil, err
+	}
+	if a.Status == DeviceApprovalPending && !a.ExpiresAt.After(time.Now().UTC()) {
+		a.Status = DeviceApprovalExpired
+	}
+	return &a, nil
+}
+
+// CreateDeviceApproval stores a pending request. token is the device token
+// already issued to the requesting machine; only its hash is stored, and it
+// becomes a trusted device token if the request is approved. At most
+// maxPending unexpired requests may be pending per account. The account's
+// webhook, if it has one, is sent a device.approval_requested event.
+func (db *DB) CreateDeviceApproval(ctx context.Context, accountID uuid.UUID, token, label string, meta DeviceMetadata, ttlDays int, requestedIP, userAgent string, expiresAt time.Time, maxPending int) (*DeviceApproval, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the account row to serialize concurrent requests
+	var lockedID uuid.UUID
+	if err := tx.QueryRow(ctx, `SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, accountID).Scan(&lockedID); err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	var pending int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM device_approvals
+		WHERE account_id = $1 AND status = 'pending' AND expires_at > NOW()
+	`, accountID).Scan(&pending); err != nil {
+		return nil, fmt.Errorf("failed to count device approvals: %w", err)
+	}
+	if pending >= maxPending {
+		return nil, ErrTooManyDeviceApprovals
+	}
+
+	approval, err := scanDeviceApproval(tx.QueryRow(ctx, `
+		INSERT INTO device_approvals (account_id, device_token_hash, label, os, hostname, ttl_days, requested_ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+deviceApprovalColumns,
+		accountID, HashToken(token), labelOrNull(label), labelOrNull(meta.OS), labelOrNull(meta.Hostname),
+		ttlDays, labelOrNull(requestedIP), labelOrNull(userAgent), expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create device approval: %w", err)
+	}
+
+	// Tell the account's webhook, if it has one, so someone can decide
+	payload, err := json.Marshal(&DeviceApprovalEvent{
+		Event:       DeviceApprovalEventType,
+		AccountID:   accountID,
+		ApprovalID:  approval.ID,
+		Label:       approval.Label,
+		OS:          approval.OS,
+		Hostname:    approval.Hostname,
+		RequestedIP: approval.RequestedIP,
+		RequestedAt: approval.CreatedAt,
+		ExpiresAt:   approval.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode device approval event: %w", err)
+	}
+	if err := queueAccountEvent(ctx, tx, accountID, DeviceApprovalEventType, payload); err != nil {
+		return nil, fmt.Errorf("failed to queue device approval webhook: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return approval, nil
+}
+
+// GetDeviceApprovalByToken returns a request if token is the device token it
+// was created with, so only the requesting machine can poll it
+func (db *DB) GetDeviceApprovalByToken(ctx context.Context, accountID, id uuid.UUID, token string) (*DeviceApproval, error) {
+	approval, err := scanDeviceApproval(db.pool.QueryRow(ctx, `
+		SELECT `+deviceApprovalColumns+`
+		FROM device_approvals
+		WHERE id = $1 AND account_id = $2 AND device_token_hash = $3
+	`, id, accountID, HashToken(token)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, pgx.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get device approval: %w", err)
+	}
+	return approval, nil
+}
+
+// ListPendingDeviceApprovals returns an account's unexpired pending requests, newest first
+func (db *DB) ListPendingDeviceApprovals(ctx context.Context, accountID uuid.UUID) ([]*DeviceApproval, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+deviceApprovalColumns+`
+		FROM device_approvals
+		WHERE account_id = $1 AND status = 'pending' AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []*DeviceApproval{}
+	for rows.Next() {
+		approval, err := scanDeviceApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan device approval: %w", err)
+		}
+		approvals = append(approvals, approval)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate device approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+// ApproveDeviceApproval trusts the requesting machine. The new device gets
+// the token hash from the request and an expiry from its ttl_days.
+// decidedBy is the trusted device that approved it.
+func (db *DB) ApproveDeviceApproval(ctx context.Context, accountID, id, decidedBy uuid.UUID) (*Device, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	device, err := scanDevice(tx.QueryRow(ctx, `
+		INSERT INTO account_devices (account_id, device_token_hash, label, os, hostname, expires_at)
+		SELECT account_id, device_token_hash, label, os, hostname,
+			CASE WHEN ttl_days > 0 THEN NOW() + make_interval(days => ttl_days) END
+		FROM device_approvals
+		WHERE id = $1 AND account_id = $2 AND status = 'pending' AND expires_at > NOW()
+		FOR UPDATE
+		RETURNING `+deviceColumns, id, accountID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeviceApprovalNotPending
+		}
+		return nil, fmt.Errorf("failed to trust device: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE device_approvals
+		SET status = 'approved', device_id = $3, decided_by = $4, decided_at = NOW()
+		WHERE id = $1 AND account_id = $2
+	`, id, accountID, device.ID, decidedBy); err != nil {
+		return nil, fmt.Errorf("failed to update device approval: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return device, nil
+}
+
+// DenyDeviceApproval rejects a pending request
+func (db *DB) DenyDeviceApproval(ctx context.Context, accountID, id, decidedBy uuid.UUID) error {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE device_approvals
+		SET status = 'denied', decided_by = $3, decided_at = NOW()
+		WHERE id = $1 AND account_id = $2 AND status = 'pending' AND expires_at > NOW()
+	`, id, accountID, decidedBy)
+	if err != nil {
+		return fmt.Errorf("failed to deny device approval: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeviceApprovalNotPending
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,44 +10,64 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// ErrDeviceNotFound is returned when a device doesn't exist or belongs to
+// another account
+var ErrDeviceNotFound = errors.New("device not found")
+
 // Device represents a trusted device for an account.
 type Device struct {
 	ID         uuid.UUID  `json:"id"`
 	AccountID  uuid.UUID  `json:"account_id"`
 	Label      *string    `json:"label,omitempty"`
+	Name       *string    `json:"name,omitempty"`
+	OS         *string    `json:"os,omitempty"`
+	Hostname   *string    `json:"hostname,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	LastSeenIP *string    `json:"last_seen_ip,omitempty"`
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
 
+// DeviceMetadata describes the machine behind a device, as reported by the
+// client when it is trusted.
+type DeviceMetadata struct {
+	OS       string
+	Hostname string
+}
+
+const deviceColumns = `id, account_id, label, name, os, hostname, created_at, last_seen_at, last_seen_ip, expires_at`
+
+func scanDevice(row pgx.Row) (*Device, error) {
+	var d Device
+	if err := row.Scan(&d.ID, &d.AccountID, &d.Label, &d.Name, &d.OS, &d.Hostname,
+		&d.CreatedAt, &d.LastSeenAt, &d.LastSeenIP, &d.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
 // CreateDeviceToken stores a new trusted device token.
-func (db *DB) CreateDeviceToken(ctx context.Context, accountID uuid.UUID, token, label string, expiresAt *time.Time) (*Device, error) {
+func (db *DB) CreateDeviceToken(ctx context.Context, accountID uuid.UUID, token, label string, meta DeviceMetadata, expiresAt *time.Time) (*Device, error) {
 	hashed := HashToken(token)
-	var device Device
-	err := db.pool.QueryRow(ctx, `
-		INSERT INTO account_devices (account_id, device_token_hash, label, expires_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, account_id, label, created_at, last_seen_at, expires_at
-	`, accountID, hashed, labelOrNull(label), expiresAt).Scan(
-		&device.ID, &device.AccountID, &device.Label, &device.CreatedAt, &device.LastSeenAt, &device.ExpiresAt,
-	)
+	device, err := scanDevice(db.pool.QueryRow(ctx, `
+		INSERT INTO account_devices (account_id, device_token_hash, label, os, hostname, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+deviceColumns,
+		accountID, hashed, labelOrNull(label), labelOrNull(meta.OS), labelOrNull(meta.Hostname), expiresAt))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create device token: %w", err)
 	}
-	return &device, nil
+	return device, nil
 }
 
 // GetDeviceByToken returns the device if the token is valid and not expired.
 func (db *DB) GetDeviceByToken(ctx context.Context, accountID uuid.UUID, token string) (*Device, error) {
 	hashed := HashToken(token)
-	var device Device
-	err := db.pool.QueryRow(ctx, `
-		SELECT id, account_id, label, created_at, last_seen_at, expires_at
+	device, err := scanDevice(db.pool.QueryRow(ctx, `
+		SELECT `+deviceColumns+`
 		FROM account_devices
 		WHERE account_id = $1 AND device_token_hash = $2
-	`, accountID, hashed).Scan(
-		&device.ID, &device.AccountID, &device.Label, &device.CreatedAt, &device.LastSeenAt, &device.ExpiresAt,
-	)
+	`, accountID, hashed))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, pgx.ErrNoRows
@@ -59,17 +80,17 @@ func (db *DB) GetDeviceByToken(ctx context.Context, accountID uuid.UUID, token s
 		return nil, pgx.ErrNoRows
 	}
 
-	return &device, nil
+	return device, nil
 }
 
-// TouchDevice updates last_seen_at for a device token.
-func (db *DB) TouchDevice(ctx context.Context, accountID uuid.UUID, token string) error {
+// TouchDevice updates last_seen_at and last_seen_ip for a device token.
+func (db *DB) TouchDevice(ctx context.Context, accountID uuid.UUID, token, ip string) error {
 	hashed := HashToken(token)
 	_, err := db.pool.Exec(ctx, `
 		UPDATE account_devices
-		SET last_seen_at = $1
+		SET last_seen_at = $1, last_seen_ip = $4
 		WHERE account_id = $2 AND device_token_hash = $3
-	`, time.Now().UTC(), accountID, hashed)
+	`, time.Now().UTC(), accountID, hashed, labelOrNull(ip))
 	if err != nil {
 		return fmt.Errorf("failed to update device last_seen_at: %w", err)
 	}
@@ -79,7 +100,7 @@ func (db *DB) TouchDevice(ctx context.Context, accountID uuid.UUID, token string
 // ListDevices returns trusted devices for an account.
 func (db *DB) ListDevices(ctx context.Context, accountID uuid.UUID) ([]*Device, error) {
 	rows, err := db.pool.Query(ctx, `
-		SELECT id, account_id, label, created_at, last_seen_at, expires_at
+		SELECT `+deviceColumns+`
 		FROM account_devices
 		WHERE account_id = $1
 		ORDER BY created_at DESC
@@ -91,11 +112,11 @@ func (db *DB) ListDevices(ctx context.Context, accountID uuid.UUID) ([]*Device,
 
 	var devices []*Device
 	for rows.Next() {
-		var device Device
-		if err := rows.Scan(&device.ID, &device.AccountID, &device.Label, &device.CreatedAt, &device.LastSeenAt, &device.ExpiresAt); err != nil {
+		device, err := scanDevice(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan device: %w", err)
 		}
-		devices = append(devices, &device)
+		devices = append(devices, device)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("failed to iterate devices: %w", err)
@@ -103,6 +124,23 @@ func (db *DB) ListDevices(ctx context.Context, accountID uuid.UUID) ([]*Device,
 	return devices, nil
 }
 
+// RenameDevice sets the user-assigned name of a device. An empty name clears
+// it. Returns ErrDeviceNotFound if the device doesn't belong to the account.
+func (db *DB) RenameDevice(ctx context.Context, accountID uuid.UUID, deviceID uuid.UUID, name string) error {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE account_devices
+		SET name = $3
+		WHERE account_id = $1 AND id = $2
+	`, accountID, deviceID, labelOrNull(name))
+	if err != nil {
+		return fmt.Errorf("failed to rename device: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
 // RevokeDevice deletes a specific trusted device.
 func (db *DB) RevokeDevice(ctx context.Context, accountID uuid.UUID, deviceID uuid.UUID) error {
 	_, err := db.pool.Exec(ctx, `
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeatureFlag is an operator-controlled switch. While enabled it is on for
+// the listed accounts and for Percent of all other accounts.
+type FeatureFlag struct {
+	Name       string      `json:"name"`
+	Enabled    bool        `json:"enabled"`
+	Percent    float64     `json:"percent"`
+	AccountIDs []uuid.UUID `json:"account_ids"`
+	Message    string      `json:"message,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// ListFeatureFlags returns all feature flags
+func (db *DB) ListFeatureFlags(ctx context.Context) ([]*FeatureFlag, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT name, enabled, percent::float8, account_ids, message, created_at, updated_at
+		FROM feature_flags
+		ORDER BY name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []*FeatureFlag{}
+	for rows.Next() {
+		f := &FeatureFlag{}
+		if err := rows.Scan(&f.Name, &f.Enabled, &f.Percent, &f.AccountIDs, &f.Message, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// UpsertFeatureFlag creates or replaces a feature flag
+func (db *DB) UpsertFeatureFlag(ctx context.Context, f *FeatureFlag) error {
+	if f.AccountIDs == nil {
+		f.AccountIDs = []uuid.UUID{}
+	}
+	err := db.pool.QueryRow(ctx, `
+		INSERT INTO feature_flags (name, enabled, percent, account_ids, message)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			percent = EXCLUDED.percent,
+			account_ids = EXCLUDED.account_ids,
+			message = EXCLUDED.message,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, f.Name, f.Enabled, f.Percent, f.AccountIDs, f.Message).Scan(&f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeatureFlag removes a feature flag, reporting whether it existed
+func (db *DB) DeleteFeatureFlag(ctx context.Context, name string) (bool, error) {
+	tag, err := db.pool.Exec(ctx, `DELETE FROM feature_flags WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
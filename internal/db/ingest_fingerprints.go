@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IngestFingerprint is a document checked by the RAG ingestion gateway,
+// kept only as a hash of its text with the verdict it was last checked under
+type IngestFingerprint struct {
+	Fingerprint      string    `json:"fingerprint"`
+	DocumentID       string    `json:"document_id"`
+	Decision         string    `json:"decision"`
+	DetectionVersion string    `json:"detection_version,omitempty"`
+	FirstCheckedAt   time.Time `json:"first_checked_at"`
+	CheckedAt        time.Time `json:"checked_at"`
+}
+
+// SaveIngestFingerprints records the owner's latest verdicts, keeping when
+// each fingerprint was first checked
+func (db *DB) SaveIngestFingerprints(ctx context.Context, owner string, prints []*IngestFingerprint) error {
+	if len(prints) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	batch := &pgx.Batch{}
+	for _, fp := range prints {
+		batch.Queue(`
+			INSERT INTO ingest_fingerprints (
+				owner, fingerprint, document_id, decision, detection_version, first_checked_at, checked_at
+			) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $6)
+			ON CONFLICT (owner, fingerprint) DO UPDATE SET
+				document_id = EXCLUDED.document_id,
+				decision = EXCLUDED.decision,
+				detection_version = EXCLUDED.detection_version,
+				checked_at = EXCLUDED.checked_at
+		`, owner, fp.Fingerprint, fp.DocumentID, fp.Decision, fp.DetectionVersion, now)
+	}
+
+	br := db.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range prints {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to save ingest fingerprint: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetIngestFingerprints returns the owner's records for the given
+// fingerprints, keyed by fingerprint. Fingerprints never checked are absent.
+func (db *DB) GetIngestFingerprints(ctx context.Context, owner string, fingerprints []string) (map[string]*IngestFingerprint, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT fingerprint, document_id, decision, COALESCE(detection_version, ''),
+			first_checked_at, checked_at
+		FROM ingest_fingerprints
+		WHERE owner = $1 AND fingerprint = ANY($2)
+	`, owner, fingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	prints := make(map[string]*IngestFingerprint, len(fingerprints))
+	for rows.Next() {
+		fp := &IngestFingerprint{}
+		if err := rows.Scan(&fp.Fingerprint, &fp.DocumentID, &fp.Decision, &fp.DetectionVersion,
+			&fp.FirstCheckedAt, &fp.CheckedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest fingerprint: %w", err)
+		}
+		prints[fp.Fingerprint] = fp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ingest fingerprints: %w", err)
+	}
+	return prints, nil
+}
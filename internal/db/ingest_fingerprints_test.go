@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestFingerprints(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	clean := strings.Repeat("a", 64)
+	poisoned := strings.Repeat("b", 64)
+	require.NoError(t, db.SaveIngestFingerprints(ctx, "owner-1", []*IngestFingerprint{
+		{Fingerprint: clean, DocumentID: "kb-1", Decision: "ALLOW", DetectionVersion: "v1"},
+		{Fingerprint: poisoned, DocumentID: "kb-2", Decision: "BLOCK", DetectionVersion: "v1"},
+	}))
+
+	prints, err := db.GetIngestFingerprints(ctx, "owner-1", []string{clean, poisoned, strings.Repeat("c", 64)})
+	require.NoError(t, err)
+	require.Len(t, prints, 2)
+	assert.Equal(t, "kb-1", prints[clean].DocumentID)
+	assert.Equal(t, "BLOCK", prints[poisoned].Decision)
+	first := prints[clean].FirstCheckedAt
+
+	// A recheck updates the verdict but keeps the first check time
+	require.NoError(t, db.SaveIngestFingerprints(ctx, "owner-1", []*IngestFingerprint{
+		{Fingerprint: clean, DocumentID: "kb-1", Decision: "WARN", DetectionVersion: "v2"},
+	}))
+	prints, err = db.GetIngestFingerprints(ctx, "owner-1", []string{clean})
+	require.NoError(t, err)
+	assert.Equal(t, "WARN", prints[clean].Decision)
+	assert.Equal(t, "v2", prints[clean].DetectionVersion)
+	assert.True(t, prints[clean].FirstCheckedAt.Equal(first))
+	assert.False(t, prints[clean].CheckedAt.Before(first))
+
+	// Fingerprints are scoped to their owner
+	prints, err = db.GetIngestFingerprints(ctx, "owner-2", []string{clean})
+	require.NoError(t, err)
+	assert.Empty(t, prints)
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrProxyInstallNotFound is returned when an install doesn't exist or
+	// belongs to another account
+	ErrProxyInstallNotFound = errors.New("proxy install not found")
+	// ErrProxyInstallKeyInUse is returned when the public key is already
+	// registered to an install
+	ErrProxyInstallKeyInUse = errors.New("public key already registered")
+)
+
+// ProxyInstall is a proxy installation identified by the Ed25519 key it signs
+// scan requests with
+type ProxyInstall struct {
+	ID        uuid.UUID  `json:"id"`
+	AccountID uuid.UUID  `json:"account_id"`
+	PublicKey string     `json:"public_key"`
+	Hostname  *string    `json:"hostname,omitempty"`
+	OS        *string    `json:"os,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Reported by the install's latest heartbeat
+	Version          *string    `json:"version,omitempty"`
+	ProtectionStatus *string    `json:"protection_status,omitempty"`
+	LastSeenAt       *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// Revoked reports whether the install's signed requests are refused
+func (i *ProxyInstall) Revoked() bool {
+	return i.RevokedAt != nil
+}
+
+const proxyInstallColumns = `id, account_id, public_key, hostname, os, created_at, revoked_at,
+	version, protection_status, last_seen_at`
+
+func scanProxyInstall(row pgx.Row) (*ProxyInstall, error) {
+	var i ProxyInstall
+	if err := row.Scan(&i.ID, &i.AccountID, &i.PublicKey, &i.Hostname, &i.OS, &i.CreatedAt, &i.RevokedAt,
+		&i.Version, &i.ProtectionStatus, &i.LastSeenAt); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// CreateProxyInstall registers an install's public key with the account
+func (db *DB) CreateProxyInstall(ctx context.Context, accountID uuid.UUID, publicKey string, meta DeviceMetadata) (*ProxyInstall, error) {
+	install, err := scanProxyInstall(db.pool.QueryRow(ctx, `
+		INSERT INTO proxy_installs (id, account_id, public_key, hostname, os, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+proxyInstallColumns,
+		uuid.New(), accountID, publicKey, labelOrNull(meta.Hostname), labelOrNull(meta.OS), time.Now().UTC()))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrProxyInstallKeyInUse
+		}
+		return nil, fmt.Errorf("failed to create proxy install: %w", err)
+	}
+	return install, nil
+}
+
+// GetProxyInstall returns an install by ID, including revoked installs
+func (db *DB) GetProxyInstall(ctx context.Context, id uuid.UUID) (*ProxyInstall, error) {
+	install, err := scanProxyInstall(db.pool.QueryRow(ctx, `
+		SELECT `+proxyInstallColumns+`
+		FROM proxy_installs
+		WHERE id = $1
+	`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProxyInstallNotFound
+		}
+		return nil, fmt.Errorf("failed to get proxy install: %w", err)
+	}
+	return install, nil
+}
+
+// RevokeProxyInstall revokes one of the account's installs. Revoking an
+// already revoked install keeps the original revocation time.
+func (db *DB) RevokeProxyInstall(ctx context.Context, accountID, id uuid.UUID) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE proxy_installs
+		SET revoked_at = COALESCE(revoked_at, $3)
+		WHERE account_id = $1 AND id = $2
+	`, accountID, id, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to revoke proxy install: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrProxyInstallNotFound
+	}
+	return nil
+}
+
+// ListProxyInstalls returns the account's installs, including revoked ones,
+// oldest first
+func (db *DB) ListProxyInstalls(ctx context.Context, accountID uuid.UUID) ([]ProxyInstall, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+proxyInstallColumns+`
+		FROM proxy_installs
+		WHERE account_id = $1
+		ORDER BY created_at, id
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proxy installs: %w", err)
+	}
+	defer rows.Close()
+
+	var installs []ProxyInstall
+	for rows.Next() {
+		install, err := scanProxyInstall(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan proxy install: %w", err)
+		}
+		installs = append(installs, *install)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list proxy installs: %w", err)
+	}
+	return installs, nil
+}
+
+// RecordProxyInstallHeartbeat stores the version and protection status an
+// install reported and marks it seen now. Revoked installs are not updated.
+func (db *DB) RecordProxyInstallHeartbeat(ctx context.Context, id uuid.UUID, version, protectionStatus string) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE proxy_installs
+		SET version = $2, protection_status = $3, last_seen_at = $4
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, labelOrNull(version), protectionStatus, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record proxy install heartbeat: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrProxyInstallNotFound
+	}
+	return nil
+}
+
+// InstallUsage is one proxy install's usage over a window
+type InstallUsage struct {
+	InstallID uuid.UUID `json:"install_id"`
+	UsageAggregate
+}
+
+// GetInstallUsage aggregates scans, blocks and spend by proxy install for the
+// account between start and end. Install-signed x402 scans are paid on-chain
+// and logged at no cost, so spend is read from metadata.actual_cost. Installs
+// without usage in the window are omitted.
+func (db *DB) GetInstallUsage(ctx context.Context, accountID uuid.UUID, start, end time.Time) ([]*InstallUsage, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT install_id,
+		       COUNT(*) FILTER (WHERE metadata ? 'auth_method'),
+		       COUNT(*) FILTER (WHERE metadata ? 'auth_method' AND metadata->>'decision' = 'BLOCK'),
+		       COUNT(*) FILTER (WHERE metadata ? 'auth_method' AND threat_detected),
+		       COALESCE(SUM(COALESCE((metadata->>'actual_cost')::bigint, 0)), 0)
+		FROM usage_logs
+		WHERE account_id = $1 AND install_id IS NOT NULL
+		  AND created_at >= $2 AND created_at <= $3
+		GROUP BY install_id
+	`, accountID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get install usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*InstallUsage{}
+	for rows.Next() {
+		u := &InstallUsage{}
+		if err := rows.Scan(append([]any{&u.InstallID}, u.scanDest()...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan install usage: %w", err)
+		}
+		u.computeBlockRate()
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate install usage: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyInstalls(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	install, err := db.CreateProxyInstall(ctx, account.ID, "cHVibGljLWtleQ==", DeviceMetadata{Hostname: "build-01", OS: "linux"})
+	require.NoError(t, err)
+	require.NotNil(t, install.Hostname)
+	assert.Equal(t, "build-01", *install.Hostname)
+	assert.False(t, install.Revoked())
+
+	_, err = db.CreateProxyInstall(ctx, other.ID, "cHVibGljLWtleQ==", DeviceMetadata{})
+	assert.ErrorIs(t, err, ErrProxyInstallKeyInUse)
+
+	got, err := db.GetProxyInstall(ctx, install.ID)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, got.AccountID)
+	assert.Equal(t, "cHVibGljLWtleQ==", got.PublicKey)
+
+	_, err = db.GetProxyInstall(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrProxyInstallNotFound)
+
+	assert.Nil(t, got.LastSeenAt)
+	require.NoError(t, db.RecordProxyInstallHeartbeat(ctx, install.ID, "1.4.0", "protected"))
+	got, err = db.GetProxyInstall(ctx, install.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastSeenAt)
+	assert.Equal(t, "1.4.0", *got.Version)
+	assert.Equal(t, "protected", *got.ProtectionStatus)
+
+	installs, err := db.ListProxyInstalls(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, installs, 1)
+	assert.Equal(t, install.ID, installs[0].ID)
+	installs, err = db.ListProxyInstalls(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, installs)
+
+	assert.ErrorIs(t, db.RevokeProxyInstall(ctx, other.ID, install.ID), ErrProxyInstallNotFound, "installs are scoped to their account")
+	require.NoError(t, db.RevokeProxyInstall(ctx, account.ID, install.ID))
+
+	got, err = db.GetProxyInstall(ctx, install.ID)
+	require.NoError(t, err)
+	require.True(t, got.Revoked())
+	revokedAt := *got.RevokedAt
+
+	require.NoError(t, db.RevokeProxyInstall(ctx, account.ID, install.ID))
+	got, err = db.GetProxyInstall(ctx, install.ID)
+	require.NoError(t, err)
+	assert.True(t, revokedAt.Equal(*got.RevokedAt), "revoking twice keeps the first revocation time")
+
+	assert.ErrorIs(t, db.RecordProxyInstallHeartbeat(ctx, install.ID, "1.4.0", "protected"), ErrProxyInstallNotFound, "revoked installs can't report in")
+}
+
+func TestGetInstallUsage(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	laptop, err := db.CreateProxyInstall(ctx, account.ID, "bGFwdG9w", DeviceMetadata{Hostname: "laptop"})
+	require.NoError(t, err)
+	_, err = db.CreateProxyInstall(ctx, account.ID, "c2VydmVy", DeviceMetadata{Hostname: "server"})
+	require.NoError(t, err)
+
+	logScan := func(installID *uuid.UUID, decision string, threat bool) {
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: account.ID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+			Status: "success", ThreatDetected: threat, InstallID: installID,
+			Metadata: map[string]any{"auth_method": "x402", "decision": decision, "actual_cost": usdc.MicroUSDC(1000)},
+		}))
+	}
+	logScan(&laptop.ID, "BLOCK", true)
+	logScan(&laptop.ID, "ALLOW", false)
+	logScan(nil, "ALLOW", false) // not signed by an install
+
+	usage, err := db.GetInstallUsage(ctx, account.ID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, usage, 1, "installs without usage are omitted")
+	assert.Equal(t, laptop.ID, usage[0].InstallID)
+	assert.Equal(t, int64(2), usage[0].Scans)
+	assert.Equal(t, int64(1), usage[0].Blocked)
+	assert.Equal(t, int64(1), usage[0].ThreatsDetected)
+	assert.Equal(t, usdc.MicroUSDC(2000), usage[0].SpendUSDC, "spend comes from actual_cost, not cost_usdc")
+	assert.InDelta(t, 0.5, usage[0].BlockRate, 0.001)
+
+	usage, err = db.GetInstallUsage(ctx, account.ID, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, usage)
+}
@@ -39,6 +39,7 @@ type Database interface {
 	LinkSolanaWallet(ctx context.Context, accountID uuid.UUID, solanaAddress string) error
 	UpdateBalance(ctx context.Context, accountID uuid.UUID, newBalance usdc.MicroUSDC) error
 	SuspendAccount(ctx context.Context, accountID uuid.UUID) error
+	ReactivateAccount(ctx context.Context, accountID uuid.UUID) error
 	CloseAccount(ctx context.Context, accountID uuid.UUID) error
 	DeleteAccount(ctx context.Context, accountID uuid.UUID) error
 	AccountExists(ctx context.Context, accountNumber string) (bool, error)
@@ -91,6 +92,35 @@ type Database interface {
 	GetUsageStats(ctx context.Context, accountID uuid.UUID, start, end time.Time) (*UsageStats, error)
 	GetDailyUsageStats(ctx context.Context, accountID uuid.UUID, days int) ([]*DailyUsageStats, error)
 	GetEndpointUsageStats(ctx context.Context, accountID uuid.UUID, start, end time.Time) ([]*EndpointUsageStats, error)
+	VerifyUsageLogChain(ctx context.Context, accountID uuid.UUID) (*UsageChainResult, error)
+	GetAccountOverview(ctx context.Context, accountID uuid.UUID, now time.Time) (*AccountOverview, error)
+	GetAccountRunway(ctx context.Context, accountID uuid.UUID, balance usdc.MicroUSDC, alertDays float64, now time.Time) (*AccountRunway, error)
+	QueueLowBalanceNotifications(ctx context.Context, alertDays float64, now time.Time) (int, error)
+	RollupDailyUsage(ctx context.Context, day time.Time) (int64, error)
+	PendingUsageRollupDays(ctx context.Context, from, to time.Time) ([]time.Time, error)
+	SnapshotBalances(ctx context.Context, day time.Time) (int64, error)
+	PendingOnchainSnapshots(ctx context.Context, day time.Time, limit int) ([]*OnchainSnapshot, error)
+	RecordOnchainBalances(ctx context.Context, accountID uuid.UUID, day time.Time, evm, solana *usdc.MicroUSDC, complete bool) error
+	GetBalanceHistory(ctx context.Context, accountID uuid.UUID, days int, now time.Time) (*BalanceHistory, error)
+
+	// Scan sample operations
+	CreateScanSample(ctx context.Context, sample *ScanSample) error
+	ListScanSamples(ctx context.Context, endpoint string, since time.Time, limit int) ([]*ScanSample, error)
+	CleanupOldScanSamples(ctx context.Context, retentionDays int) (int64, error)
+
+	// Canary operations
+	SetCanaryEnrollment(ctx context.Context, accountID uuid.UUID, percent float64) (*CanaryEnrollment, error)
+	DeleteCanaryEnrollment(ctx context.Context, accountID uuid.UUID) error
+	GetCanaryPercent(ctx context.Context, accountID uuid.UUID) (float64, bool, error)
+	ListCanaryEnrollments(ctx context.Context) ([]*CanaryEnrollment, error)
+	CreateCanaryResult(ctx context.Context, r *CanaryResult) error
+	GetCanarySummary(ctx context.Context, accountID *uuid.UUID, since time.Time) (*CanarySummary, error)
+	ListCanaryDisagreements(ctx context.Context, accountID *uuid.UUID, since time.Time, limit int) ([]*CanaryResult, error)
+
+	// Feature flag operations
+	ListFeatureFlags(ctx context.Context) ([]*FeatureFlag, error)
+	UpsertFeatureFlag(ctx context.Context, f *FeatureFlag) error
+	DeleteFeatureFlag(ctx context.Context, name string) (bool, error)
 
 	// Transaction support
 	BeginTx(ctx context.Context) (pgx.Tx, error)
@@ -110,6 +140,26 @@ type Database interface {
 	ExpireStaleReservations(ctx context.Context) (int64, error)
 	MarkSettling(ctx context.Context, id uuid.UUID) error
 	LinkUsageLog(ctx context.Context, usageLogID, paymentTxID uuid.UUID) error
+	PrunePayments(ctx context.Context, before time.Time, archive bool, limit int) (int64, error)
+	GetPaymentTableStats(ctx context.Context) (*PaymentTableStats, error)
+
+	// Settlement webhooks
+	SetSettlementWebhook(ctx context.Context, accountID uuid.UUID, url string) (*SettlementWebhook, error)
+	GetSettlementWebhook(ctx context.Context, accountID uuid.UUID) (*SettlementWebhook, error)
+	DeleteSettlementWebhook(ctx context.Context, accountID uuid.UUID) (bool, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	MarkWebhookDelivered(ctx context.Context, id uuid.UUID) error
+	FailWebhookDelivery(ctx context.Context, id uuid.UUID, errorMsg string, retryAt *time.Time) error
+
+	// Outage credit
+	GetCreditStanding(ctx context.Context, payer string) (*CreditStanding, error)
+	CreateReceivable(ctx context.Context, accountID, paymentID uuid.UUID, amount usdc.MicroUSDC) error
+	GetLapsedReceivables(ctx context.Context, before time.Time, limit int) ([]*Receivable, error)
+	SettleReceivableFromBalance(ctx context.Context, id uuid.UUID) (bool, error)
+
+	// RAG ingestion gateway
+	SaveIngestFingerprints(ctx context.Context, owner string, prints []*IngestFingerprint) error
+	GetIngestFingerprints(ctx context.Context, owner string, fingerprints []string) (map[string]*IngestFingerprint, error)
 
 	// Webhook event idempotency
 	ClaimWebhookEvent(ctx context.Context, eventID, eventType string) (bool, error)
@@ -118,6 +168,20 @@ type Database interface {
 	// Account settings
 	GetJailbreakDetectionEnabled(ctx context.Context, accountID uuid.UUID, defaultValue bool) (bool, error)
 	SetJailbreakDetectionEnabled(ctx context.Context, accountID uuid.UUID, enabled bool) error
+
+	// Scan policies
+	GetJailbreakPolicy(ctx context.Context, accountID uuid.UUID, defaultEnabled bool) (*JailbreakPolicy, error)
+	SetJailbreakPolicy(ctx context.Context, accountID uuid.UUID, enabled bool, action string) error
+	GetOrganizationJailbreakPolicy(ctx context.Context, organizationID string) (*JailbreakPolicy, error)
+	SetOrganizationJailbreakPolicy(ctx context.Context, organizationID string, enabled bool, action string, updatedBy uuid.UUID) error
+	DeleteOrganizationJailbreakPolicy(ctx context.Context, organizationID string) error
+	GetEffectiveJailbreakPolicy(ctx context.Context, accountID uuid.UUID, defaultEnabled bool) (*JailbreakPolicy, error)
+	GetScoreExposure(ctx context.Context, accountID uuid.UUID) (*ScoreExposure, error)
+	SetScoreExposure(ctx context.Context, accountID uuid.UUID, exposure string) error
+	SetAPIKeyScoreExposure(ctx context.Context, keyID, accountID uuid.UUID, exposure *string) error
+	GetEffectiveScoreExposure(ctx context.Context, accountID, keyID uuid.UUID) (*ScoreExposure, error)
+	GetWorkOSOrganizationID(ctx context.Context, accountID uuid.UUID) (string, error)
+	SetWorkOSOrganizationID(ctx context.Context, accountID uuid.UUID, organizationID string) error
 }
 
 // Ensure DB implements Database interface
@@ -0,0 +1,31 @@
+-- Migration: 006_jailbreak_policy
+-- Add configurable jailbreak detection policy for B2B accounts and
+-- organization-level overrides keyed by WorkOS organization.
+
+-- ============================================================
+-- accounts table: WorkOS organization membership
+-- ============================================================
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS workos_organization_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_workos_organization_id
+    ON accounts(workos_organization_id) WHERE workos_organization_id IS NOT NULL;
+
+-- ============================================================
+-- organization_policies table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS organization_policies (
+    workos_organization_id TEXT PRIMARY KEY,
+    jailbreak_detection_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    jailbreak_action TEXT NOT NULL DEFAULT 'block',
+    updated_by UUID REFERENCES accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_jailbreak_action CHECK (jailbreak_action IN ('warn', 'block'))
+);
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON COLUMN accounts.workos_organization_id IS 'WorkOS organization ID the B2B account belongs to (from the org_id JWT claim)';
+COMMENT ON TABLE organization_policies IS 'Organization-level scan policy overrides applied to every member account';
+COMMENT ON COLUMN organization_policies.jailbreak_action IS 'Action for jailbreak-only detections: warn (downgrade BLOCK to WARN) or block';
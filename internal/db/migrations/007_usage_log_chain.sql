@@ -0,0 +1,52 @@
+-- Migration: 007_usage_log_chain
+-- Hash-chain usage logs per account so edits and deletions are detectable,
+-- and reject updates to the chained columns.
+
+-- ============================================================
+-- usage_logs table: chain position and hashes
+-- ============================================================
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS chain_seq BIGINT;
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_logs_account_chain
+    ON usage_logs(account_id, chain_seq) WHERE chain_seq IS NOT NULL;
+
+-- ============================================================
+-- Write-once enforcement for chained rows
+-- ============================================================
+CREATE OR REPLACE FUNCTION reject_usage_log_rewrite()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.hash IS NOT NULL AND (
+        NEW.account_id IS DISTINCT FROM OLD.account_id OR
+        NEW.request_id IS DISTINCT FROM OLD.request_id OR
+        NEW.endpoint IS DISTINCT FROM OLD.endpoint OR
+        NEW.method IS DISTINCT FROM OLD.method OR
+        NEW.cost_usdc IS DISTINCT FROM OLD.cost_usdc OR
+        NEW.status IS DISTINCT FROM OLD.status OR
+        NEW.threat_detected IS DISTINCT FROM OLD.threat_detected OR
+        NEW.threat_type IS DISTINCT FROM OLD.threat_type OR
+        NEW.created_at IS DISTINCT FROM OLD.created_at OR
+        NEW.chain_seq IS DISTINCT FROM OLD.chain_seq OR
+        NEW.prev_hash IS DISTINCT FROM OLD.prev_hash OR
+        NEW.hash IS DISTINCT FROM OLD.hash
+    ) THEN
+        RAISE EXCEPTION 'usage log % is write-once', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS reject_usage_log_rewrite_trigger ON usage_logs;
+CREATE TRIGGER reject_usage_log_rewrite_trigger
+    BEFORE UPDATE ON usage_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_usage_log_rewrite();
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON COLUMN usage_logs.chain_seq IS 'Position in the account''s usage log hash chain, starting at 1';
+COMMENT ON COLUMN usage_logs.prev_hash IS 'Hash of the previous record in the account''s chain';
+COMMENT ON COLUMN usage_logs.hash IS 'SHA-256 over prev_hash and the record''s billing fields';
@@ -0,0 +1,29 @@
+-- Migration: 008_scan_samples
+-- Store a sample of scanned payloads (PII-scrubbed) so detection changes can
+-- be replayed against real traffic and compared with the original verdicts.
+
+-- ============================================================
+-- scan_samples table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS scan_samples (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    request_id VARCHAR(255) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    text TEXT NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    source_type VARCHAR(50),
+    content_type VARCHAR(50),
+    decision VARCHAR(10) NOT NULL,
+    scores JSONB NOT NULL DEFAULT '{}',
+    threat_categories TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_samples_created_at ON scan_samples(created_at);
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON TABLE scan_samples IS 'Sampled scan payloads with PII scrubbed, for replaying against new detection versions';
+COMMENT ON COLUMN scan_samples.decision IS 'Decision the scanner returned when the sample was taken';
+COMMENT ON COLUMN scan_samples.truncated IS 'Text was cut to SCAN_SAMPLE_MAX_BYTES before storing';
@@ -0,0 +1,9 @@
+-- Migration: 009_detection_version
+-- Record which detection version produced each billed scan and each sample,
+-- so verdict drift can be correlated with detection releases.
+
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS detection_version VARCHAR(100);
+ALTER TABLE scan_samples ADD COLUMN IF NOT EXISTS detection_version VARCHAR(100);
+
+COMMENT ON COLUMN usage_logs.detection_version IS 'Detection version that produced the scan verdict (see GET /v1/detection/version)';
+COMMENT ON COLUMN scan_samples.detection_version IS 'Detection version that produced the recorded decision';
@@ -0,0 +1,41 @@
+-- Migration: 010_canary
+-- Canary rollout of detection changes: accounts enrolled to have a share of
+-- their scans shadow-scored by a candidate detection configuration, and the
+-- recorded comparisons.
+
+-- ============================================================
+-- canary_enrollments table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS canary_enrollments (
+    account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    percent NUMERIC(5, 2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_canary_percent CHECK (percent >= 0 AND percent <= 100)
+);
+
+-- ============================================================
+-- canary_results table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS canary_results (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE,
+    request_id VARCHAR(255) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    stable_version VARCHAR(100) NOT NULL,
+    canary_version VARCHAR(100) NOT NULL,
+    stable_decision VARCHAR(10) NOT NULL,
+    canary_decision VARCHAR(10) NOT NULL,
+    stable_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    canary_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_canary_results_created_at ON canary_results(created_at);
+CREATE INDEX IF NOT EXISTS idx_canary_results_account_created ON canary_results(account_id, created_at);
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON TABLE canary_enrollments IS 'Accounts whose scans are partly shadow-scored by the canary detection configuration';
+COMMENT ON TABLE canary_results IS 'Stable vs canary decisions for shadow-scored scans; the stable decision is always the one enforced';
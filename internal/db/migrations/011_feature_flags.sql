@@ -0,0 +1,25 @@
+-- Migration: 011_feature_flags
+-- Operator feature flags: endpoint maintenance mode, payment enforcement
+-- level, and features gated per account cohort, changed without a redeploy.
+
+-- ============================================================
+-- feature_flags table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name VARCHAR(255) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    percent NUMERIC(5, 2) NOT NULL DEFAULT 100,
+    account_ids UUID[] NOT NULL DEFAULT '{}',
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT valid_feature_flag_percent CHECK (percent >= 0 AND percent <= 100)
+);
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON TABLE feature_flags IS 'Operator feature flags, cached by each API instance';
+COMMENT ON COLUMN feature_flags.percent IS 'Share of accounts (0-100) the flag is on for, by a stable hash of flag name and account';
+COMMENT ON COLUMN feature_flags.account_ids IS 'Accounts the flag is always on for while enabled';
+COMMENT ON COLUMN feature_flags.message IS 'Shown to callers, e.g. the reason for maintenance';
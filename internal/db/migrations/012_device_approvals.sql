@@ -0,0 +1,34 @@
+-- Migration: 012_device_approvals
+-- Lets a new machine become trusted by approval from an existing trusted
+-- device, instead of entering a TOTP code on it.
+
+-- ============================================================
+-- device_approvals table
+-- ============================================================
+CREATE TABLE IF NOT EXISTS device_approvals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    device_token_hash VARCHAR(64) NOT NULL,
+    label TEXT,
+    ttl_days INTEGER NOT NULL DEFAULT 0,
+    requested_ip TEXT,
+    user_agent TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    device_id UUID REFERENCES account_devices(id) ON DELETE SET NULL,
+    decided_by UUID REFERENCES account_devices(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    decided_at TIMESTAMPTZ,
+    CONSTRAINT valid_device_approval_status CHECK (status IN ('pending', 'approved', 'denied'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_approvals_token_hash ON device_approvals(device_token_hash);
+CREATE INDEX IF NOT EXISTS idx_device_approvals_pending ON device_approvals(account_id, created_at)
+    WHERE status = 'pending';
+
+-- ============================================================
+-- Comments
+-- ============================================================
+COMMENT ON TABLE device_approvals IS 'Requests from new machines to become trusted devices';
+COMMENT ON COLUMN device_approvals.device_token_hash IS 'Hash of the device token issued to the requesting machine; becomes the trusted device token on approval';
+COMMENT ON COLUMN device_approvals.decided_by IS 'Trusted device that approved or denied the request';
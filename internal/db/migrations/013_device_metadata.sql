@@ -0,0 +1,16 @@
+-- Migration: 013_device_metadata
+-- Let users name trusted devices and record which machine each one is, so
+-- the right device can be identified before revoking.
+
+ALTER TABLE account_devices ADD COLUMN IF NOT EXISTS name TEXT;
+ALTER TABLE account_devices ADD COLUMN IF NOT EXISTS os TEXT;
+ALTER TABLE account_devices ADD COLUMN IF NOT EXISTS hostname TEXT;
+ALTER TABLE account_devices ADD COLUMN IF NOT EXISTS last_seen_ip TEXT;
+
+ALTER TABLE device_approvals ADD COLUMN IF NOT EXISTS os TEXT;
+ALTER TABLE device_approvals ADD COLUMN IF NOT EXISTS hostname TEXT;
+
+COMMENT ON COLUMN account_devices.name IS 'Name assigned by the user; shown instead of label when set';
+COMMENT ON COLUMN account_devices.os IS 'Operating system reported by the device when it was trusted';
+COMMENT ON COLUMN account_devices.hostname IS 'Hostname reported by the device when it was trusted';
+COMMENT ON COLUMN account_devices.last_seen_ip IS 'Client IP of the most recent request using the device token';
@@ -0,0 +1,7 @@
+-- Migration: 014_session_binding
+-- Bind sessions to the trusted device they were created on, so a stolen
+-- refresh cookie can't be replayed from another machine.
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_token_hash VARCHAR(64);
+
+COMMENT ON COLUMN sessions.device_token_hash IS 'Hash of the trusted device token the session is bound to; refreshes must present the same token';
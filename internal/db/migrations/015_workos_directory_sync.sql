@@ -0,0 +1,15 @@
+-- Migration: 015_workos_directory_sync
+-- Provision and deprovision B2B accounts from an organization's identity
+-- provider (SCIM via WorkOS Directory Sync) and record each member's
+-- organization role.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS workos_role TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS workos_directory_user_id TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS deprovisioned_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_workos_directory_user_id_unique
+    ON accounts(workos_directory_user_id) WHERE workos_directory_user_id IS NOT NULL;
+
+COMMENT ON COLUMN accounts.workos_role IS 'Organization role slug from the WorkOS session or directory (e.g. admin, member)';
+COMMENT ON COLUMN accounts.workos_directory_user_id IS 'WorkOS Directory Sync user ID for accounts provisioned by the organization''s identity provider';
+COMMENT ON COLUMN accounts.deprovisioned_at IS 'When the identity provider deprovisioned the user; the account stays suspended until reprovisioned';
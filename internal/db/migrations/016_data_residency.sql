@@ -0,0 +1,7 @@
+-- Migration: 016_data_residency
+-- Pin accounts to a region so their scan content is only processed by that
+-- region's API instance.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS region TEXT;
+
+COMMENT ON COLUMN accounts.region IS 'Region whose API instance must process the account''s scans (e.g. eu); NULL means any region';
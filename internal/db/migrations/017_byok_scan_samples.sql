@@ -0,0 +1,17 @@
+-- Migration: 017_byok_scan_samples
+-- Customer-managed keys (BYOK) for stored scan samples. Samples of an account
+-- with a key are stored sealed under a data key wrapped by the customer's KMS
+-- key, so revoking that key makes them unreadable.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS encryption_key_arn TEXT;
+
+ALTER TABLE scan_samples ADD COLUMN IF NOT EXISTS account_id UUID REFERENCES accounts(id) ON DELETE CASCADE;
+ALTER TABLE scan_samples ADD COLUMN IF NOT EXISTS encryption_key_arn TEXT;
+ALTER TABLE scan_samples ADD COLUMN IF NOT EXISTS wrapped_key BYTEA;
+ALTER TABLE scan_samples ADD COLUMN IF NOT EXISTS ciphertext BYTEA;
+
+CREATE INDEX IF NOT EXISTS idx_scan_samples_account_id ON scan_samples(account_id) WHERE account_id IS NOT NULL;
+
+COMMENT ON COLUMN accounts.encryption_key_arn IS 'Customer-managed KMS key ARN that stored scan samples are sealed under; NULL means platform storage';
+COMMENT ON COLUMN scan_samples.wrapped_key IS 'Per-sample data key wrapped by encryption_key_arn';
+COMMENT ON COLUMN scan_samples.ciphertext IS 'Text, scores and threat categories sealed with the data key; those columns are empty when set';
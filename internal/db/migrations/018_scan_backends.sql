@@ -0,0 +1,10 @@
+-- Migration: 018_scan_backends
+-- Per-account selection of the detection backend scans are routed to.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS scan_backend TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS scan_backend_url TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS scan_backend_token TEXT;
+
+COMMENT ON COLUMN accounts.scan_backend IS 'Detection backend for the account''s scans: internal, ensemble, custom or a SCAN_BACKENDS name; NULL uses SCAN_DEFAULT_BACKEND';
+COMMENT ON COLUMN accounts.scan_backend_url IS 'Base URL of the account''s own detection endpoint when scan_backend is custom';
+COMMENT ON COLUMN accounts.scan_backend_token IS 'Bearer token sent to the account''s own detection endpoint';
@@ -0,0 +1,17 @@
+-- Migration: 019_scoring_profiles
+-- Per-account layer weights and threshold curves, per scanning mode, that
+-- replace the engine's built-in score combination.
+
+CREATE TABLE IF NOT EXISTS scoring_profiles (
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL,
+    profile JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_id, mode),
+    CONSTRAINT valid_scoring_mode CHECK (mode IN ('default', 'smart', 'strict', 'permissive'))
+);
+
+COMMENT ON TABLE scoring_profiles IS 'Layer weights and threshold curve applied to an account''s content scans in a scanning mode';
+COMMENT ON COLUMN scoring_profiles.mode IS 'Scanning mode the profile applies to; default applies to modes without their own profile';
+COMMENT ON COLUMN scoring_profiles.profile IS 'JSON {"weights": {layer: weight}, "curve": [{"min_score", "decision"}]}';
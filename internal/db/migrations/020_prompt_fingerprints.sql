@@ -0,0 +1,15 @@
+-- Migration: 020_prompt_fingerprints
+-- Hashed fingerprints of customers' system prompts, checked against output
+-- scans to catch responses that leak them. Prompt text is never stored.
+
+CREATE TABLE IF NOT EXISTS prompt_fingerprints (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    shingles TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_prompt_fingerprint_name UNIQUE (account_id, name)
+);
+
+COMMENT ON TABLE prompt_fingerprints IS 'Hashed system prompt fingerprints; output scans that repeat enough of one are flagged as system prompt leaks';
+COMMENT ON COLUMN prompt_fingerprints.shingles IS 'Truncated SHA-256 hashes of every 8-word run of the normalized prompt';
@@ -0,0 +1,24 @@
+-- Migration: 021_proxy_installs
+-- Each proxy install registers an Ed25519 public key and signs its scan
+-- requests with the matching private key, so scans can be attributed to the
+-- machine that sent them and a compromised machine can be revoked.
+
+CREATE TABLE IF NOT EXISTS proxy_installs (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    public_key TEXT NOT NULL UNIQUE,
+    hostname TEXT,
+    os TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_proxy_installs_account_id ON proxy_installs(account_id);
+
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS install_id UUID REFERENCES proxy_installs(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_usage_logs_install_id ON usage_logs(install_id) WHERE install_id IS NOT NULL;
+
+COMMENT ON TABLE proxy_installs IS 'Proxy installs registered by accounts; scan requests signed by an install are attributed to it';
+COMMENT ON COLUMN proxy_installs.public_key IS 'Base64 Ed25519 public key the install signs scan requests with';
+COMMENT ON COLUMN proxy_installs.revoked_at IS 'When the install was revoked; its signed requests are rejected from then on';
+COMMENT ON COLUMN usage_logs.install_id IS 'Proxy install that signed the scan request, if any';
@@ -0,0 +1,12 @@
+-- Migration: 022_proxy_install_heartbeats
+-- Registered proxy installs report in periodically with their version and
+-- protection status, so fleet operators can spot machines whose protection
+-- went dark.
+
+ALTER TABLE proxy_installs ADD COLUMN IF NOT EXISTS version TEXT;
+ALTER TABLE proxy_installs ADD COLUMN IF NOT EXISTS protection_status TEXT;
+ALTER TABLE proxy_installs ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN proxy_installs.version IS 'Proxy version reported by the latest heartbeat';
+COMMENT ON COLUMN proxy_installs.protection_status IS 'Protection status reported by the latest heartbeat: protected, shadow or degraded';
+COMMENT ON COLUMN proxy_installs.last_seen_at IS 'When the install last sent a heartbeat';
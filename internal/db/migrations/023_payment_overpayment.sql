@@ -0,0 +1,8 @@
+-- Migration: 023_payment_overpayment
+-- x402 payments may exceed the price by a configured tolerance (clients
+-- sometimes pay a cached, higher price). The excess is recorded on the
+-- payment and credited to the payer's account when settlement completes.
+
+ALTER TABLE payment_transactions ADD COLUMN IF NOT EXISTS overpayment_usdc BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN payment_transactions.overpayment_usdc IS 'MicroUSDC paid above amount_usdc, credited to the payer''s account as a deposit on settlement';
@@ -0,0 +1,33 @@
+-- Migration: 024_payment_retention
+-- Payment transactions are kept for replay protection only while their
+-- signed authorization is valid (five minutes). Completed and expired
+-- payments past the retention period are moved to a slim archive table
+-- without the signed header and cached response, or deleted outright.
+
+CREATE TABLE IF NOT EXISTS payment_transactions_archive (
+    id UUID PRIMARY KEY,
+    payment_nonce VARCHAR(128) NOT NULL,
+    payer_address VARCHAR(64) NOT NULL,
+    receiver_address VARCHAR(64) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    amount_usdc BIGINT NOT NULL,
+    overpayment_usdc BIGINT NOT NULL DEFAULT 0,
+    network VARCHAR(32) NOT NULL,
+    status payment_status NOT NULL,
+    facilitator_payment_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL,
+    settled_at TIMESTAMPTZ,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_tx_archive_payer ON payment_transactions_archive(payer_address, created_at);
+
+-- Index for the retention worker
+CREATE INDEX IF NOT EXISTS idx_payment_tx_retention ON payment_transactions(created_at)
+    WHERE status IN ('completed', 'expired');
+
+-- Usage logs keep the payment id after the payment is archived
+ALTER TABLE usage_logs DROP CONSTRAINT IF EXISTS usage_logs_payment_transaction_id_fkey;
+
+COMMENT ON TABLE payment_transactions_archive IS 'Completed and expired x402 payments moved out of payment_transactions by the retention worker';
+COMMENT ON COLUMN usage_logs.payment_transaction_id IS 'Payment in payment_transactions, or payment_transactions_archive once archived';
@@ -0,0 +1,35 @@
+-- Migration: 025_settlement_webhooks
+-- Accounts may register a URL notified when an x402 payment from one of
+-- their wallets settles. Deliveries are queued in the same transaction that
+-- completes the payment and sent by a background worker with retries.
+
+CREATE TABLE IF NOT EXISTS settlement_webhooks (
+    account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS settlement_webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES settlement_webhooks(account_id) ON DELETE CASCADE,
+    payment_transaction_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ,
+    failed_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (account_id, payment_transaction_id)
+);
+
+-- Partial index for the delivery worker
+CREATE INDEX IF NOT EXISTS idx_settlement_webhook_deliveries_pending
+    ON settlement_webhook_deliveries(next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+
+COMMENT ON TABLE settlement_webhooks IS 'Per-account URL notified when an x402 payment from the account''s wallets settles';
+COMMENT ON COLUMN settlement_webhooks.secret IS 'HMAC-SHA256 key for the Stronghold-Signature header';
+COMMENT ON TABLE settlement_webhook_deliveries IS 'Outbox of settlement notifications; failed_at is set once retries are exhausted';
@@ -0,0 +1,26 @@
+-- Migration: 026_payment_receivables
+-- While the facilitator is down, trusted accounts may be served on credit.
+-- Each credited payment records a receivable, settled by the payment itself
+-- when the facilitator recovers or from the account balance if the signed
+-- authorization lapses first.
+
+CREATE TABLE IF NOT EXISTS payment_receivables (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    payment_transaction_id UUID NOT NULL UNIQUE,
+    amount_usdc BIGINT NOT NULL CHECK (amount_usdc > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'settled')),
+    settled_via VARCHAR(20)
+        CHECK (settled_via IN ('x402', 'balance')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    settled_at TIMESTAMPTZ
+);
+
+-- Outstanding credit per account
+CREATE INDEX IF NOT EXISTS idx_payment_receivables_open
+    ON payment_receivables(account_id)
+    WHERE status = 'open';
+
+COMMENT ON TABLE payment_receivables IS 'Scans served on credit during facilitator outages, owed until the payment settles';
+COMMENT ON COLUMN payment_receivables.settled_via IS 'x402 when the payment settled on-chain, balance when it was debited from the account';
@@ -0,0 +1,19 @@
+-- Migration: 027_ingest_fingerprints
+-- SHA-256 fingerprints of documents checked by the RAG ingestion gateway,
+-- with the verdict they were ingested under, so callers can recheck them
+-- when detection improves. Document text is never stored.
+
+CREATE TABLE IF NOT EXISTS ingest_fingerprints (
+    owner TEXT NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    document_id TEXT NOT NULL,
+    decision VARCHAR(10) NOT NULL,
+    detection_version TEXT,
+    first_checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (owner, fingerprint)
+);
+
+COMMENT ON TABLE ingest_fingerprints IS 'Documents checked by /v1/ingest/check, kept only as hashes with their latest verdict';
+COMMENT ON COLUMN ingest_fingerprints.owner IS 'Account ID for API key requests, payer wallet address for x402 requests';
+COMMENT ON COLUMN ingest_fingerprints.fingerprint IS 'Hex SHA-256 of the document text';
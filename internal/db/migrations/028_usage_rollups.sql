@@ -0,0 +1,61 @@
+-- Migration: 028_usage_rollups
+-- Hourly per-account usage totals, kept up to date by a trigger on
+-- usage_logs, so the dashboard overview reads at most 720 rows per account
+-- instead of aggregating the usage log on every load. Scans, blocks and
+-- spend follow the organization usage report: scan rows carry auth_method,
+-- and B2B billing rows record their cost in metadata.actual_cost.
+
+CREATE TABLE IF NOT EXISTS usage_rollups_hourly (
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    hour TIMESTAMPTZ NOT NULL,
+    scans BIGINT NOT NULL DEFAULT 0,
+    blocked BIGINT NOT NULL DEFAULT 0,
+    threats_detected BIGINT NOT NULL DEFAULT 0,
+    spend_usdc BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (account_id, hour)
+);
+
+COMMENT ON TABLE usage_rollups_hourly IS 'Per-account usage totals by UTC hour, maintained from usage_logs';
+
+CREATE OR REPLACE FUNCTION rollup_usage_log()
+RETURNS TRIGGER AS $$
+DECLARE
+    is_scan BOOLEAN := COALESCE(NEW.metadata ? 'auth_method', FALSE);
+BEGIN
+    INSERT INTO usage_rollups_hourly (account_id, hour, scans, blocked, threats_detected, spend_usdc)
+    VALUES (
+        NEW.account_id,
+        date_trunc('hour', NEW.created_at, 'UTC'),
+        CASE WHEN is_scan THEN 1 ELSE 0 END,
+        CASE WHEN is_scan AND NEW.metadata->>'decision' = 'BLOCK' THEN 1 ELSE 0 END,
+        CASE WHEN is_scan AND NEW.threat_detected THEN 1 ELSE 0 END,
+        COALESCE((NEW.metadata->>'actual_cost')::bigint, NEW.cost_usdc::bigint)
+    )
+    ON CONFLICT (account_id, hour) DO UPDATE SET
+        scans = usage_rollups_hourly.scans + EXCLUDED.scans,
+        blocked = usage_rollups_hourly.blocked + EXCLUDED.blocked,
+        threats_detected = usage_rollups_hourly.threats_detected + EXCLUDED.threats_detected,
+        spend_usdc = usage_rollups_hourly.spend_usdc + EXCLUDED.spend_usdc;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS rollup_usage_log_trigger ON usage_logs;
+CREATE TRIGGER rollup_usage_log_trigger
+    AFTER INSERT ON usage_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION rollup_usage_log();
+
+-- Backfill the last 30 days, the longest window the overview reports. The
+-- trigger's lock keeps new usage out until this migration commits.
+INSERT INTO usage_rollups_hourly (account_id, hour, scans, blocked, threats_detected, spend_usdc)
+SELECT account_id,
+       date_trunc('hour', created_at, 'UTC'),
+       COUNT(*) FILTER (WHERE metadata ? 'auth_method'),
+       COUNT(*) FILTER (WHERE metadata ? 'auth_method' AND metadata->>'decision' = 'BLOCK'),
+       COUNT(*) FILTER (WHERE metadata ? 'auth_method' AND threat_detected),
+       COALESCE(SUM(COALESCE((metadata->>'actual_cost')::bigint, cost_usdc::bigint)), 0)
+FROM usage_logs
+WHERE created_at >= NOW() - INTERVAL '31 days'
+GROUP BY 1, 2
+ON CONFLICT (account_id, hour) DO NOTHING;
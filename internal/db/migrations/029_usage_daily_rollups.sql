@@ -0,0 +1,29 @@
+-- Migration: 029_usage_daily_rollups
+-- Per-account usage totals by UTC day, written by the usage rollup worker,
+-- so daily usage stats and exports read one row per day instead of
+-- aggregating every usage log in the window. The worker rolls up a day once
+-- it has ended and records it in usage_rollup_days; days not recorded there,
+-- including the current one, are still read from usage_logs.
+
+CREATE TABLE IF NOT EXISTS usage_rollups_daily (
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    cost_usdc BIGINT NOT NULL DEFAULT 0,
+    threats_detected BIGINT NOT NULL DEFAULT 0,
+    blocked BIGINT NOT NULL DEFAULT 0,
+    spend_usdc BIGINT NOT NULL DEFAULT 0,
+    latency_p50_ms INTEGER NOT NULL DEFAULT 0,
+    latency_p95_ms INTEGER NOT NULL DEFAULT 0,
+    latency_p99_ms INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (account_id, day)
+);
+
+COMMENT ON TABLE usage_rollups_daily IS 'Per-account usage totals and latency percentiles by UTC day, written by the usage rollup worker';
+
+CREATE TABLE IF NOT EXISTS usage_rollup_days (
+    day DATE PRIMARY KEY,
+    rolled_up_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE usage_rollup_days IS 'UTC days rolled up into usage_rollups_daily, which are no longer read from usage_logs';
@@ -0,0 +1,16 @@
+-- Migration: 030_low_balance_alerts
+-- Account webhooks are also notified when an account's balance will run out
+-- within the configured number of days at its recent spend rate. Deliveries
+-- carry their event name, and only settlement deliveries reference a payment.
+-- low_balance_notified_at keeps an account from being notified again until
+-- its runway recovers.
+
+ALTER TABLE settlement_webhook_deliveries
+    ADD COLUMN IF NOT EXISTS event TEXT NOT NULL DEFAULT 'payment.settled',
+    ALTER COLUMN payment_transaction_id DROP NOT NULL;
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS low_balance_notified_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN settlement_webhook_deliveries.event IS 'Event name sent in the Stronghold-Event header';
+COMMENT ON COLUMN accounts.low_balance_notified_at IS 'When the account webhook was last told the balance is running low; cleared once the runway recovers';
@@ -0,0 +1,26 @@
+-- Migration: 031_balance_snapshots
+-- One row per account per UTC day with its on-platform balance at the end of
+-- the day and its wallets' on-chain USDC balances, for balance history charts.
+-- balance_usdc is rewritten on every snapshot during the day, so it holds the
+-- closing balance once the day ends. On-chain balances are read once a day;
+-- onchain_at stays NULL until every wallet has been read.
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    balance_usdc BIGINT NOT NULL,
+    evm_balance_usdc BIGINT,
+    solana_balance_usdc BIGINT,
+    onchain_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_id, day)
+);
+
+-- Snapshots still waiting on their on-chain balances
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_onchain_pending
+    ON balance_snapshots(day)
+    WHERE onchain_at IS NULL;
+
+COMMENT ON TABLE balance_snapshots IS 'Daily per-account balances for the dashboard balance history chart';
+COMMENT ON COLUMN balance_snapshots.balance_usdc IS 'On-platform balance as of the latest snapshot of the day';
+COMMENT ON COLUMN balance_snapshots.onchain_at IS 'When the on-chain wallet balances were read; NULL until every wallet has been';
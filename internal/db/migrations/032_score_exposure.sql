@@ -0,0 +1,9 @@
+-- Migration: 032_score_exposure
+-- Let an API key override how much of a scan's scoring its responses reveal.
+-- The account-wide setting lives in accounts.metadata (score_exposure), next
+-- to the other scan policies.
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS score_exposure TEXT
+    CHECK (score_exposure IN ('exact', 'quantized', 'omitted'));
+
+COMMENT ON COLUMN api_keys.score_exposure IS 'Score exposure for scans made with this key (exact, quantized or omitted); NULL uses the account setting';
@@ -0,0 +1,33 @@
+-- Migration: 033_rule_packs
+-- Rule packs imported by accounts: portable YAML sets of detection rules with
+-- their own tests. The YAML is kept as imported so it can be exported again.
+-- Several versions of a pack may be stored, but at most one is enabled.
+-- Public packs are listed in a catalogue any account can import from.
+
+CREATE TABLE IF NOT EXISTS rule_packs (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rules INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_rule_pack_version UNIQUE (account_id, name, version)
+);
+
+-- One enabled version per pack name
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rule_packs_enabled
+    ON rule_packs(account_id, name)
+    WHERE enabled;
+
+CREATE INDEX IF NOT EXISTS idx_rule_packs_public
+    ON rule_packs(name, created_at DESC)
+    WHERE public;
+
+COMMENT ON TABLE rule_packs IS 'Account rule packs; enabled packs add their matches to the account''s content and output scans';
+COMMENT ON COLUMN rule_packs.source IS 'Pack YAML as imported and validated';
+COMMENT ON COLUMN rule_packs.public IS 'Listed in the public rule pack catalogue';
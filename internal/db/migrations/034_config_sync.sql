@@ -0,0 +1,17 @@
+-- Migration: 034_config_sync
+-- CLI configuration synced between an account's machines. The CLI encrypts
+-- it with a key derived from the account's wallet before upload, so the API
+-- only stores ciphertext it cannot read. Version increments on every write
+-- so a machine can't overwrite changes it hasn't pulled.
+
+CREATE TABLE IF NOT EXISTS config_sync (
+    account_id UUID PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    data TEXT NOT NULL,
+    updated_by TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE config_sync IS 'End-to-end encrypted CLI configuration shared by an account''s machines';
+COMMENT ON COLUMN config_sync.data IS 'Base64 ciphertext sealed by the CLI; never decrypted server side';
+COMMENT ON COLUMN config_sync.updated_by IS 'Hostname of the machine that wrote this version, as it reported';
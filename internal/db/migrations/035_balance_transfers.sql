@@ -0,0 +1,28 @@
+-- Migration: 035_balance_transfers
+-- Balance ledger: every change to an account's on-platform balance made by a
+-- transfer between accounts, one entry per side. Both entries of a transfer
+-- share its transfer_id and are written in the same transaction as the
+-- balance updates. Outgoing entries are summed per UTC day to enforce the
+-- daily transfer limit.
+
+CREATE TABLE IF NOT EXISTS balance_ledger (
+    id UUID PRIMARY KEY,
+    transfer_id UUID NOT NULL,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('transfer_out', 'transfer_in')),
+    amount_usdc BIGINT NOT NULL,
+    balance_after_usdc BIGINT NOT NULL,
+    counterparty_account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+    counterparty_account_number TEXT NOT NULL,
+    memo TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_balance_ledger_side UNIQUE (transfer_id, account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_ledger_account
+    ON balance_ledger(account_id, created_at DESC);
+
+COMMENT ON TABLE balance_ledger IS 'Per-account entries for balance transfers between accounts';
+COMMENT ON COLUMN balance_ledger.amount_usdc IS 'Signed change to the balance: negative for transfer_out, positive for transfer_in';
+COMMENT ON COLUMN balance_ledger.balance_after_usdc IS 'Account balance once the entry was applied';
+COMMENT ON COLUMN balance_ledger.counterparty_account_number IS 'Kept so the entry still names the other account after it is deleted';
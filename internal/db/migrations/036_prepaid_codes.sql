@@ -0,0 +1,51 @@
+-- Migration: 036_prepaid_codes
+-- Prepaid codes: single-use codes of a fixed USDC value, generated by an
+-- operator in batches for trials and offline sales. Only a hash of each code
+-- is stored. Redeeming one records a completed deposit with provider
+-- 'prepaid_code', which credits the balance through the deposit trigger.
+-- Failed redemptions are recorded per account and IP to lock out guessing.
+
+ALTER TYPE deposit_provider ADD VALUE IF NOT EXISTS 'prepaid_code';
+
+CREATE TABLE IF NOT EXISTS prepaid_code_batches (
+    id UUID PRIMARY KEY,
+    label TEXT NOT NULL,
+    value_usdc BIGINT NOT NULL CHECK (value_usdc > 0),
+    code_count INTEGER NOT NULL CHECK (code_count > 0),
+    per_account_limit INTEGER NOT NULL DEFAULT 1 CHECK (per_account_limit > 0),
+    expires_at TIMESTAMPTZ,
+    voided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS prepaid_codes (
+    id UUID PRIMARY KEY,
+    batch_id UUID NOT NULL REFERENCES prepaid_code_batches(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL UNIQUE,
+    code_hint TEXT NOT NULL,
+    redeemed_at TIMESTAMPTZ,
+    redeemed_by UUID REFERENCES accounts(id) ON DELETE SET NULL,
+    deposit_id UUID REFERENCES deposits(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_prepaid_codes_batch_redeemer
+    ON prepaid_codes(batch_id, redeemed_by);
+
+CREATE TABLE IF NOT EXISTS prepaid_code_failures (
+    id BIGSERIAL PRIMARY KEY,
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_prepaid_code_failures_account
+    ON prepaid_code_failures(account_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_prepaid_code_failures_ip
+    ON prepaid_code_failures(ip, created_at);
+
+COMMENT ON TABLE prepaid_code_batches IS 'Batches of prepaid codes generated together with the same value and terms';
+COMMENT ON COLUMN prepaid_code_batches.per_account_limit IS 'Codes from the batch a single account may redeem, 1 for trial codes';
+COMMENT ON COLUMN prepaid_code_batches.voided_at IS 'When the batch was voided; its unredeemed codes can no longer be redeemed';
+COMMENT ON COLUMN prepaid_codes.code_hash IS 'SHA-256 of the normalized code; the code itself is only shown when generated';
+COMMENT ON COLUMN prepaid_codes.code_hint IS 'Last four characters of the code, for support';
+COMMENT ON TABLE prepaid_code_failures IS 'Failed prepaid code redemptions, counted per account and IP to lock out guessing';
@@ -0,0 +1,34 @@
+-- Migration: 037_sub_accounts
+-- Sub-accounts: per-project or per-agent slices of a business account. Each
+-- has its own API keys and an optional budget; scans made with its keys are
+-- paid from the parent account's balance and counted against the budget.
+-- Usage rows carry the sub-account in metadata, like api_key_id.
+
+CREATE TABLE IF NOT EXISTS sub_accounts (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    budget_usdc BIGINT CHECK (budget_usdc IS NULL OR budget_usdc >= 0),
+    spent_usdc BIGINT NOT NULL DEFAULT 0 CHECK (spent_usdc >= 0),
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sub_accounts_account_name
+    ON sub_accounts(account_id, LOWER(name)) WHERE closed_at IS NULL;
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS sub_account_id UUID REFERENCES sub_accounts(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_sub_account
+    ON api_keys(sub_account_id) WHERE sub_account_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_usage_logs_sub_account
+    ON usage_logs(account_id, (metadata->>'sub_account_id'), created_at)
+    WHERE metadata ? 'sub_account_id';
+
+COMMENT ON TABLE sub_accounts IS 'Per-project or per-agent slices of an account, paid from its balance';
+COMMENT ON COLUMN sub_accounts.budget_usdc IS 'Most the sub-account may spend of the parent balance; NULL for no cap';
+COMMENT ON COLUMN sub_accounts.spent_usdc IS 'Spent so far by the sub-account''s API keys, credits and metered billing alike';
+COMMENT ON COLUMN sub_accounts.closed_at IS 'When the sub-account was closed; its API keys are revoked';
+COMMENT ON COLUMN api_keys.sub_account_id IS 'Sub-account the key bills and attributes usage to; NULL for the account itself';
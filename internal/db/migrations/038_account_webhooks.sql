@@ -0,0 +1,12 @@
+-- Migration: 038_account_webhooks
+-- The settlement webhook tables also carry low balance and device approval
+-- events, so they are renamed for what they hold: each account's webhook and
+-- the outbox of events queued for it. Deliveries are still identified by
+-- their event name; only settlements reference a payment.
+
+ALTER TABLE IF EXISTS settlement_webhooks RENAME TO account_webhooks;
+ALTER TABLE IF EXISTS settlement_webhook_deliveries RENAME TO account_webhook_deliveries;
+ALTER INDEX IF EXISTS idx_settlement_webhook_deliveries_pending RENAME TO idx_account_webhook_deliveries_pending;
+
+COMMENT ON TABLE account_webhooks IS 'Per-account URL notified of account events: x402 settlements, low balance and device approval requests';
+COMMENT ON TABLE account_webhook_deliveries IS 'Outbox of account webhook events; failed_at is set once retries are exhausted';
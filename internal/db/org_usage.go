@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// UsageAggregate summarizes B2B scan activity. Scans and blocks come from
+// the scan handler's usage rows and spend from the billing rows, which the
+// payment router records separately with the actual cost in metadata.
+type UsageAggregate struct {
+	Scans           int64          `json:"scans"`
+	Blocked         int64          `json:"blocked"`
+	ThreatsDetected int64          `json:"threats_detected"`
+	SpendUSDC       usdc.MicroUSDC `json:"spend_usdc"`
+	BlockRate       float64        `json:"block_rate"`
+}
+
+// MemberUsage is an organization member's usage
+type MemberUsage struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Email     *string   `json:"email,omitempty"`
+	Role      *string   `json:"role,omitempty"`
+	UsageAggregate
+}
+
+// APIKeyUsage is the usage of one API key
+type APIKeyUsage struct {
+	KeyID     uuid.UUID  `json:"key_id"`
+	AccountID uuid.UUID  `json:"account_id"`
+	Name      string     `json:"name"`
+	KeyPrefix string     `json:"key_prefix"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	UsageAggregate
+}
+
+// DailyOrganizationUsage is an organization's usage on one day (UTC)
+type DailyOrganizationUsage struct {
+	Date string `json:"date"`
+	UsageAggregate
+}
+
+// OrganizationUsage aggregates an organization's usage over a window
+type OrganizationUsage struct {
+	Start   time.Time                 `json:"start"`
+	End     time.Time                 `json:"end"`
+	Totals  UsageAggregate            `json:"totals"`
+	Members []*MemberUsage            `json:"members"`
+	APIKeys []*APIKeyUsage            `json:"api_keys"`
+	Daily   []*DailyOrganizationUsage `json:"daily"`
+}
+
+// orgUsageCTE selects the organization's usage rows in [$2, $3]
+const orgUsageCTE = `
+	WITH org_usage AS (
+		SELECT u.account_id,
+		       NULLIF(u.metadata->>'api_key_id', '')::uuid AS api_key_id,
+		       u.created_at,
+		       (u.metadata ? 'auth_method') AS is_scan,
+		       u.threat_detected,
+		       u.metadata->>'decision' = 'BLOCK' AS blocked,
+		       COALESCE((u.metadata->>'actual_cost')::bigint, 0) AS spend
+		FROM usage_logs u
+		JOIN accounts a ON a.id = u.account_id
+		WHERE a.workos_organization_id = $1 AND u.created_at >= $2 AND u.created_at <= $3
+	)`
+
+// orgUsageAggregates are the aggregate columns read by UsageAggregate.scanDest
+const orgUsageAggregates = `
+	COUNT(*) FILTER (WHERE is_scan),
+	COUNT(*) FILTER (WHERE is_scan AND blocked),
+	COUNT(*) FILTER (WHERE is_scan AND threat_detected),
+	COALESCE(SUM(spend), 0)`
+
+func (a *UsageAggregate) scanDest() []any {
+	return []any{&a.Scans, &a.Blocked, &a.ThreatsDetected, &a.SpendUSDC}
+}
+
+func (a *UsageAggregate) computeBlockRate() {
+	if a.Scans > 0 {
+		a.BlockRate = float64(a.Blocked) / float64(a.Scans)
+	}
+}
+
+func (a *UsageAggregate) add(b UsageAggregate) {
+	a.Scans += b.Scans
+	a.Blocked += b.Blocked
+	a.ThreatsDetected += b.ThreatsDetected
+	a.SpendUSDC += b.SpendUSDC
+}
+
+// GetOrganizationUsage aggregates scans, spend and blocks for every account
+// in a WorkOS organization between start and end, by member, by API key and
+// by day. Members without usage are included with zero counts.
+func (db *DB) GetOrganizationUsage(ctx context.Context, organizationID string, start, end time.Time) (*OrganizationUsage, error) {
+	usage := &OrganizationUsage{
+		Start:   start,
+		End:     end,
+		Members: []*MemberUsage{},
+		APIKeys: []*APIKeyUsage{},
+		Daily:   []*DailyOrganizationUsage{},
+	}
+
+	rows, err := db.pool.Query(ctx, orgUsageCTE+`
+		SELECT a.id, a.email, a.workos_role,
+		       COUNT(u.account_id) FILTER (WHERE u.is_scan),
+		       COUNT(u.account_id) FILTER (WHERE u.is_scan AND u.blocked),
+		       COUNT(u.account_id) FILTER (WHERE u.is_scan AND u.threat_detected),
+		       COALESCE(SUM(u.spend), 0)
+		FROM accounts a
+		LEFT JOIN org_usage u ON u.account_id = a.id
+		WHERE a.workos_organization_id = $1
+		GROUP BY a.id, a.email, a.workos_role
+		ORDER BY 4 DESC, a.email
+	`, organizationID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member usage: %w", err)
+	}
+	for rows.Next() {
+		m := &MemberUsage{}
+		if err := rows.Scan(append([]any{&m.AccountID, &m.Email, &m.Role}, m.scanDest()...)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan member usage: %w", err)
+		}
+		m.computeBlockRate()
+		usage.Totals.add(m.UsageAggregate)
+		usage.Members = append(usage.Members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate member usage: %w", err)
+	}
+	usage.Totals.computeBlockRate()
+
+	rows, err = db.pool.Query(ctx, orgUsageCTE+`
+		SELECT k.id, k.account_id, k.name, k.key_prefix, k.revoked_at,`+orgUsageAggregates+`
+		FROM org_usage u
+		JOIN api_keys k ON k.id = u.api_key_id
+		GROUP BY k.id, k.account_id, k.name, k.key_prefix, k.revoked_at
+		ORDER BY 6 DESC, k.name
+	`, organizationID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key usage: %w", err)
+	}
+	for rows.Next() {
+		k := &APIKeyUsage{}
+		if err := rows.Scan(append([]any{&k.KeyID, &k.AccountID, &k.Name, &k.KeyPrefix, &k.RevokedAt}, k.scanDest()...)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan API key usage: %w", err)
+		}
+		k.computeBlockRate()
+		usage.APIKeys = append(usage.APIKeys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate API key usage: %w", err)
+	}
+
+	rows, err = db.pool.Query(ctx, orgUsageCTE+`
+		SELECT DATE(created_at AT TIME ZONE 'UTC'),`+orgUsageAggregates+`
+		FROM org_usage
+		GROUP BY 1
+		ORDER BY 1 DESC
+	`, organizationID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily organization usage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d := &DailyOrganizationUsage{}
+		var date time.Time
+		if err := rows.Scan(append([]any{&date}, d.scanDest()...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan daily organization usage: %w", err)
+		}
+		d.Date = date.Format("2006-01-02")
+		d.computeBlockRate()
+		usage.Daily = append(usage.Daily, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate daily organization usage: %w", err)
+	}
+
+	return usage, nil
+}
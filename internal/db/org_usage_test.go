@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrganizationUsage(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	alice, _, err := db.ProvisionDirectoryUser(ctx, DirectoryUser{DirectoryUserID: "du_alice", OrganizationID: "org_01", Email: "alice@example.com", Role: "admin"})
+	require.NoError(t, err)
+	bob, _, err := db.ProvisionDirectoryUser(ctx, DirectoryUser{DirectoryUserID: "du_bob", OrganizationID: "org_01", Email: "bob@example.com"})
+	require.NoError(t, err)
+	outsider, _, err := db.ProvisionDirectoryUser(ctx, DirectoryUser{DirectoryUserID: "du_eve", OrganizationID: "org_02", Email: "eve@example.com"})
+	require.NoError(t, err)
+
+	key, err := db.CreateAPIKey(ctx, alice, "sk_live_org", "hash-org-usage", "prod", 10)
+	require.NoError(t, err)
+
+	logScan := func(accountID uuid.UUID, decision string, threat bool) {
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: accountID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+			Status: "success", ThreatDetected: threat,
+			Metadata: map[string]any{"auth_method": "api_key", "api_key_id": key.ID.String(), "decision": decision},
+		}))
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: accountID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+			Status:   "success",
+			Metadata: map[string]any{"payment_method": "credits", "account_type": "b2b", "actual_cost": usdc.MicroUSDC(1000), "api_key_id": key.ID.String()},
+		}))
+	}
+	logScan(alice, "BLOCK", true)
+	logScan(alice, "ALLOW", false)
+	logScan(outsider, "BLOCK", true)
+
+	usage, err := db.GetOrganizationUsage(ctx, "org_01", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), usage.Totals.Scans)
+	assert.Equal(t, int64(1), usage.Totals.Blocked)
+	assert.Equal(t, usdc.MicroUSDC(2000), usage.Totals.SpendUSDC)
+	assert.InDelta(t, 0.5, usage.Totals.BlockRate, 0.001)
+
+	require.Len(t, usage.Members, 2, "members without usage are listed too")
+	assert.Equal(t, alice, usage.Members[0].AccountID)
+	assert.Equal(t, bob, usage.Members[1].AccountID)
+	assert.Equal(t, int64(0), usage.Members[1].Scans)
+
+	require.Len(t, usage.APIKeys, 1)
+	assert.Equal(t, key.ID, usage.APIKeys[0].KeyID)
+	assert.Equal(t, int64(2), usage.APIKeys[0].Scans)
+
+	require.Len(t, usage.Daily, 1)
+	assert.Equal(t, int64(2), usage.Daily[0].Scans)
+}
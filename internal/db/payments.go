@@ -35,6 +35,7 @@ type PaymentTransaction struct {
 	ReceiverAddress        string                 `json:"receiver_address"`
 	Endpoint               string                 `json:"endpoint"`
 	AmountUSDC             usdc.MicroUSDC         `json:"amount_usdc"`
+	OverpaymentUSDC        usdc.MicroUSDC         `json:"overpayment_usdc"` // Paid above the price, credited to the payer's account on settlement
 	Network                string                 `json:"network"`
 	Status                 PaymentStatus          `json:"status"`
 	FacilitatorPaymentID   *string                `json:"facilitator_payment_id,omitempty"`
@@ -52,8 +53,8 @@ func (db *DB) CreatePaymentTransaction(ctx context.Context, tx *PaymentTransacti
 	query := `
 		INSERT INTO payment_transactions (
 			payment_nonce, payment_header, payer_address, receiver_address,
-			endpoint, amount_usdc, network, status, expires_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			endpoint, amount_usdc, overpayment_usdc, network, status, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at
 	`
 
@@ -64,6 +65,7 @@ func (db *DB) CreatePaymentTransaction(ctx context.Context, tx *PaymentTransacti
 		tx.ReceiverAddress,
 		tx.Endpoint,
 		tx.AmountUSDC,
+		tx.OverpaymentUSDC,
 		tx.Network,
 		PaymentStatusReserved,
 		tx.ExpiresAt,
@@ -87,8 +89,8 @@ func (db *DB) CreateOrGetPaymentTransaction(ctx context.Context, tx *PaymentTran
 	query := `
 		INSERT INTO payment_transactions (
 			payment_nonce, payment_header, payer_address, receiver_address,
-			endpoint, amount_usdc, network, status, expires_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			endpoint, amount_usdc, overpayment_usdc, network, status, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		ON CONFLICT (payment_nonce) DO NOTHING
 		RETURNING id, created_at
 	`
@@ -100,6 +102,7 @@ func (db *DB) CreateOrGetPaymentTransaction(ctx context.Context, tx *PaymentTran
 		tx.ReceiverAddress,
 		tx.Endpoint,
 		tx.AmountUSDC,
+		tx.OverpaymentUSDC,
 		tx.Network,
 		PaymentStatusReserved,
 		tx.ExpiresAt,
@@ -126,7 +129,7 @@ func (db *DB) CreateOrGetPaymentTransaction(ctx context.Context, tx *PaymentTran
 func (db *DB) GetPaymentByNonce(ctx context.Context, nonce string) (*PaymentTransaction, error) {
 	query := `
 		SELECT id, payment_nonce, payment_header, payer_address, receiver_address,
-			   endpoint, amount_usdc, network, status, facilitator_payment_id,
+			   endpoint, amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
 			   settlement_attempts, last_error, service_result,
 			   created_at, executed_at, settled_at, expires_at
 		FROM payment_transactions
@@ -143,6 +146,7 @@ func (db *DB) GetPaymentByNonce(ctx context.Context, nonce string) (*PaymentTran
 		&tx.ReceiverAddress,
 		&tx.Endpoint,
 		&tx.AmountUSDC,
+		&tx.OverpaymentUSDC,
 		&tx.Network,
 		&tx.Status,
 		&tx.FacilitatorPaymentID,
@@ -172,7 +176,7 @@ func (db *DB) GetPaymentByNonce(ctx context.Context, nonce string) (*PaymentTran
 func (db *DB) GetPaymentByID(ctx context.Context, id uuid.UUID) (*PaymentTransaction, error) {
 	query := `
 		SELECT id, payment_nonce, payment_header, payer_address, receiver_address,
-			   endpoint, amount_usdc, network, status, facilitator_payment_id,
+			   endpoint, amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
 			   settlement_attempts, last_error, service_result,
 			   created_at, executed_at, settled_at, expires_at
 		FROM payment_transactions
@@ -189,6 +193,7 @@ func (db *DB) GetPaymentByID(ctx context.Context, id uuid.UUID) (*PaymentTransac
 		&tx.ReceiverAddress,
 		&tx.Endpoint,
 		&tx.AmountUSDC,
+		&tx.OverpaymentUSDC,
 		&tx.Network,
 		&tx.Status,
 		&tx.FacilitatorPaymentID,
@@ -263,23 +268,83 @@ func (db *DB) RecordExecution(ctx context.Context, id uuid.UUID, result map[stri
 	return nil
 }
 
-// CompleteSettlement marks a payment as successfully settled
+// CompleteSettlement marks a payment as successfully settled. An overpayment
+// recorded on the payment is credited to the account linked to the payer's
+// wallet in the same transaction, and a notification is queued for that
+// account's settlement webhook. A receivable for credit extended on the
+// payment is settled too.
 func (db *DB) CompleteSettlement(ctx context.Context, id uuid.UUID, facilitatorPaymentID string) error {
-	query := `
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	event := &SettlementEvent{Event: SettlementEventType, PaymentID: id, TxHash: facilitatorPaymentID}
+	err = tx.QueryRow(ctx, `
 		UPDATE payment_transactions
 		SET status = $2, facilitator_payment_id = $3, settled_at = NOW()
 		WHERE id = $1 AND status IN ($4, $5)
-	`
-
-	result, err := db.ExecResult(ctx, query, id, PaymentStatusCompleted, facilitatorPaymentID, PaymentStatusSettling, PaymentStatusFailed)
+		RETURNING payment_nonce, network, payer_address, receiver_address, endpoint,
+			amount_usdc, overpayment_usdc, settled_at
+	`, id, PaymentStatusCompleted, facilitatorPaymentID, PaymentStatusSettling, PaymentStatusFailed).Scan(
+		&event.Nonce, &event.Network, &event.Payer, &event.Receiver, &event.Endpoint,
+		&event.AmountUSDC, &event.OverpaymentUSDC, &event.SettledAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("complete settlement failed: payment not in settling or failed state")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to complete settlement: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("complete settlement failed: payment not in settling or failed state")
+	if event.OverpaymentUSDC > 0 {
+		if err := creditOverpayment(ctx, tx, id, event.Payer, event.OverpaymentUSDC); err != nil {
+			return err
+		}
+	}
+	if err := settleReceivable(ctx, tx, id, ReceivableSettledViaX402); err != nil {
+		return err
+	}
+	if err := queueSettlementEvent(ctx, tx, event); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// creditOverpayment adds a completed deposit for an overpayment, which the
+// deposit trigger credits to the balance. Payers without an account keep the
+// overpayment on the payment record only.
+func creditOverpayment(ctx context.Context, tx pgx.Tx, paymentID uuid.UUID, payer string, amount usdc.MicroUSDC) error {
+	var accountID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM accounts
+		WHERE LOWER(evm_wallet_address) = LOWER($1) OR solana_wallet_address = $1
+		LIMIT 1
+	`, payer).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find payer account: %w", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO deposits (
+			id, account_id, provider, amount_usdc, fee_usdc, net_amount_usdc,
+			status, provider_transaction_id, wallet_address, metadata, created_at, completed_at
+		) VALUES ($1, $2, $3, $4, 0, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (provider_transaction_id) DO NOTHING
+	`, uuid.New(), accountID, DepositProviderDirect, amount, DepositStatusCompleted,
+		"x402-overpayment:"+paymentID.String(), payer,
+		map[string]any{"type": "x402_overpayment", "payment_id": paymentID.String()}, now)
+	if err != nil {
+		return fmt.Errorf("failed to credit overpayment: %w", err)
+	}
 	return nil
 }
 
@@ -314,7 +379,7 @@ func (db *DB) GetPendingSettlements(ctx context.Context, maxAttempts int, limit
 
 	query := `
 		SELECT id, payment_nonce, payment_header, payer_address, receiver_address,
-			   endpoint, amount_usdc, network, status, facilitator_payment_id,
+			   endpoint, amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
 			   settlement_attempts, last_error, service_result,
 			   created_at, executed_at, settled_at, expires_at
 		FROM payment_transactions
@@ -344,6 +409,7 @@ func (db *DB) GetPendingSettlements(ctx context.Context, maxAttempts int, limit
 			&ptx.ReceiverAddress,
 			&ptx.Endpoint,
 			&ptx.AmountUSDC,
+			&ptx.OverpaymentUSDC,
 			&ptx.Network,
 			&ptx.Status,
 			&ptx.FacilitatorPaymentID,
@@ -398,7 +464,7 @@ func (db *DB) ExpireStaleReservations(ctx context.Context) (int64, error) {
 func (db *DB) GetSettlementCandidates(ctx context.Context, maxAttempts int, limit int) ([]*PaymentTransaction, error) {
 	query := `
 		SELECT id, payment_nonce, payment_header, payer_address, receiver_address,
-			   endpoint, amount_usdc, network, status, facilitator_payment_id,
+			   endpoint, amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
 			   settlement_attempts, last_error, service_result,
 			   created_at, executed_at, settled_at, expires_at
 		FROM payment_transactions
@@ -426,6 +492,7 @@ func (db *DB) GetSettlementCandidates(ctx context.Context, maxAttempts int, limi
 			&ptx.ReceiverAddress,
 			&ptx.Endpoint,
 			&ptx.AmountUSDC,
+			&ptx.OverpaymentUSDC,
 			&ptx.Network,
 			&ptx.Status,
 			&ptx.FacilitatorPaymentID,
@@ -491,3 +558,88 @@ func (db *DB) LinkUsageLog(ctx context.Context, usageLogID, paymentTxID uuid.UUI
 
 	return nil
 }
+
+// PaymentTableStats reports the size of the payment tables
+type PaymentTableStats struct {
+	Counts       map[PaymentStatus]int64 `json:"counts"`
+	Oldest       *time.Time              `json:"oldest,omitempty"` // oldest completed or expired payment still in the table
+	TableBytes   int64                   `json:"table_bytes"`      // including indexes
+	ArchiveRows  int64                   `json:"archive_rows"`     // planner estimate
+	ArchiveBytes int64                   `json:"archive_bytes"`
+}
+
+// PrunePayments removes up to limit completed and expired payments created
+// before the cutoff, oldest first, copying them to
+// payment_transactions_archive when archive is set. Usage logs keep the
+// payment id. Returns the number of payments removed.
+func (db *DB) PrunePayments(ctx context.Context, before time.Time, archive bool, limit int) (int64, error) {
+	query := `
+		DELETE FROM payment_transactions
+		WHERE id IN (
+			SELECT id FROM payment_transactions
+			WHERE status IN ($1, $2) AND created_at < $3
+			ORDER BY created_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	if archive {
+		query = `
+			WITH pruned AS (` + query + `
+				RETURNING id, payment_nonce, payer_address, receiver_address, endpoint,
+					amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
+					created_at, settled_at
+			)
+			INSERT INTO payment_transactions_archive (
+				id, payment_nonce, payer_address, receiver_address, endpoint,
+				amount_usdc, overpayment_usdc, network, status, facilitator_payment_id,
+				created_at, settled_at
+			)
+			SELECT * FROM pruned
+			ON CONFLICT (id) DO NOTHING
+		`
+	}
+
+	result, err := db.ExecResult(ctx, query, PaymentStatusCompleted, PaymentStatusExpired, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune payments: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetPaymentTableStats returns row counts by status and on-disk sizes of the
+// payment and archive tables
+func (db *DB) GetPaymentTableStats(ctx context.Context) (*PaymentTableStats, error) {
+	stats := &PaymentTableStats{Counts: make(map[PaymentStatus]int64)}
+
+	rows, err := db.Query(ctx, `SELECT status, COUNT(*) FROM payment_transactions GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count payments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status PaymentStatus
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan payment count: %w", err)
+		}
+		stats.Counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count payments: %w", err)
+	}
+
+	err = db.QueryRow(ctx, `
+		SELECT
+			(SELECT MIN(created_at) FROM payment_transactions WHERE status IN ($1, $2)),
+			pg_total_relation_size('payment_transactions'),
+			GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'payment_transactions_archive'::regclass), 0)::BIGINT,
+			pg_total_relation_size('payment_transactions_archive')
+	`, PaymentStatusCompleted, PaymentStatusExpired).Scan(&stats.Oldest, &stats.TableBytes, &stats.ArchiveRows, &stats.ArchiveBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment table sizes: %w", err)
+	}
+
+	return stats, nil
+}
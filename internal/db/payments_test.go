@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -322,6 +323,144 @@ func TestInvalidStatusTransition(t *testing.T) {
 	_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions WHERE id = $1", tx.ID)
 }
 
+// TestCompleteSettlementCreditsOverpayment credits the excess of an
+// overpayment to the payer's account once, on settlement
+func TestCompleteSettlementCreditsOverpayment(t *testing.T) {
+	pool := getTestPool(t)
+	if pool == nil {
+		t.Skip("No database connection available")
+	}
+	db := &DB{pool: pool}
+	ctx := context.Background()
+
+	payer := "0xAbCdEf" + uuid.New().String()[:8] + "00000000000000000000000000"
+	account, err := db.CreateAccount(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	if err := db.LinkEVMWallet(ctx, account.ID, strings.ToLower(payer)); err != nil {
+		t.Fatalf("Failed to link wallet: %v", err)
+	}
+
+	tx := &PaymentTransaction{
+		PaymentNonce:    "overpayment-" + uuid.New().String(),
+		PaymentHeader:   "x402;test-header",
+		PayerAddress:    payer,
+		ReceiverAddress: "0x0987654321098765432109876543210987654321",
+		Endpoint:        "/v1/scan/content",
+		AmountUSDC:      usdc.MicroUSDC(1000),
+		OverpaymentUSDC: usdc.MicroUSDC(500),
+		Network:         "base-sepolia",
+		ExpiresAt:       time.Now().Add(5 * time.Minute),
+	}
+	if err := db.CreatePaymentTransaction(ctx, tx); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	if err := db.TransitionStatus(ctx, tx.ID, PaymentStatusReserved, PaymentStatusSettling); err != nil {
+		t.Fatalf("Failed to transition to settling: %v", err)
+	}
+
+	if err := db.CompleteSettlement(ctx, tx.ID, "payment-id-overpaid"); err != nil {
+		t.Fatalf("Failed to complete settlement: %v", err)
+	}
+	// A second completion is refused and must not credit twice
+	if err := db.CompleteSettlement(ctx, tx.ID, "payment-id-overpaid"); err == nil {
+		t.Error("Expected second completion to fail")
+	}
+
+	fetched, err := db.GetPaymentByID(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("Failed to get payment: %v", err)
+	}
+	if fetched.OverpaymentUSDC != 500 {
+		t.Errorf("Expected overpayment 500, got %d", fetched.OverpaymentUSDC)
+	}
+
+	credited, err := db.GetAccountByID(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Failed to get account: %v", err)
+	}
+	if credited.BalanceUSDC != account.BalanceUSDC+500 {
+		t.Errorf("Expected balance %d, got %d", account.BalanceUSDC+500, credited.BalanceUSDC)
+	}
+
+	// Cleanup
+	_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions WHERE id = $1", tx.ID)
+	_, _ = db.pool.Exec(ctx, "DELETE FROM deposits WHERE account_id = $1", account.ID)
+	_, _ = db.pool.Exec(ctx, "DELETE FROM accounts WHERE id = $1", account.ID)
+}
+
+// TestPrunePayments tests that old completed payments are archived and
+// recent ones kept
+func TestPrunePayments(t *testing.T) {
+	pool := getTestPool(t)
+	if pool == nil {
+		t.Skip("No database connection available")
+	}
+	db := &DB{pool: pool}
+	ctx := context.Background()
+
+	create := func(age time.Duration) *PaymentTransaction {
+		tx := &PaymentTransaction{
+			PaymentNonce:    "prune-" + uuid.New().String(),
+			PaymentHeader:   "x402;test-header",
+			PayerAddress:    "0x1234567890123456789012345678901234567890",
+			ReceiverAddress: "0x0987654321098765432109876543210987654321",
+			Endpoint:        "/v1/scan/content",
+			AmountUSDC:      usdc.MicroUSDC(1000),
+			Network:         "base-sepolia",
+			ExpiresAt:       time.Now().Add(5 * time.Minute),
+		}
+		if err := db.CreatePaymentTransaction(ctx, tx); err != nil {
+			t.Fatalf("Failed to create payment: %v", err)
+		}
+		_, err := db.pool.Exec(ctx, "UPDATE payment_transactions SET status = $2, created_at = NOW() - make_interval(hours => $3) WHERE id = $1",
+			tx.ID, PaymentStatusCompleted, int(age.Hours()))
+		if err != nil {
+			t.Fatalf("Failed to age payment: %v", err)
+		}
+		return tx
+	}
+	old := create(400 * 24 * time.Hour)
+	recent := create(time.Hour)
+
+	pruned, err := db.PrunePayments(ctx, time.Now().AddDate(0, 0, -365), true, 1000)
+	if err != nil {
+		t.Fatalf("Failed to prune payments: %v", err)
+	}
+	if pruned < 1 {
+		t.Errorf("Expected at least 1 pruned payment, got %d", pruned)
+	}
+
+	if _, err := db.GetPaymentByNonce(ctx, old.PaymentNonce); err == nil {
+		t.Error("Expected old payment to be pruned")
+	}
+	if _, err := db.GetPaymentByNonce(ctx, recent.PaymentNonce); err != nil {
+		t.Errorf("Expected recent payment to be kept: %v", err)
+	}
+
+	var archived string
+	err = db.pool.QueryRow(ctx, "SELECT payment_nonce FROM payment_transactions_archive WHERE id = $1", old.ID).Scan(&archived)
+	if err != nil {
+		t.Fatalf("Expected old payment in archive: %v", err)
+	}
+	if archived != old.PaymentNonce {
+		t.Errorf("Expected archived nonce %s, got %s", old.PaymentNonce, archived)
+	}
+
+	stats, err := db.GetPaymentTableStats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get payment table stats: %v", err)
+	}
+	if stats.Counts[PaymentStatusCompleted] < 1 || stats.TableBytes <= 0 {
+		t.Errorf("Unexpected payment table stats: %+v", stats)
+	}
+
+	// Cleanup
+	_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions WHERE id = $1", recent.ID)
+	_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions_archive WHERE id = $1", old.ID)
+}
+
 // getTestPool returns a connection pool for testing, or nil if unavailable
 func getTestPool(t *testing.T) *pgxpool.Pool {
 	cfg := LoadConfig()
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Jailbreak policy actions
+const (
+	JailbreakActionWarn  = "warn"
+	JailbreakActionBlock = "block"
+)
+
+// Jailbreak policy sources, reported so callers can tell which level applied
+const (
+	PolicySourceDefault      = "default"
+	PolicySourceAccount      = "account"
+	PolicySourceOrganization = "organization"
+)
+
+// ErrInvalidJailbreakAction is returned when a policy action is not warn or block
+var ErrInvalidJailbreakAction = errors.New("invalid jailbreak action")
+
+// JailbreakPolicy controls how jailbreak-category threats are treated in scan results.
+type JailbreakPolicy struct {
+	Enabled bool   `json:"enabled"`
+	Action  string `json:"action"` // "warn" or "block"
+	Source  string `json:"source"` // "default", "account", or "organization"
+}
+
+// IsValidJailbreakAction reports whether action is a supported jailbreak policy action.
+func IsValidJailbreakAction(action string) bool {
+	return action == JailbreakActionWarn || action == JailbreakActionBlock
+}
+
+// GetJailbreakPolicy reads the account-level jailbreak policy from the account's metadata JSONB.
+// Missing keys fall back to defaultEnabled and the block action.
+func (db *DB) GetJailbreakPolicy(ctx context.Context, accountID uuid.UUID, defaultEnabled bool) (*JailbreakPolicy, error) {
+	var metadata map[string]any
+	err := db.QueryRow(ctx, `
+		SELECT metadata FROM accounts WHERE id = $1
+	`, accountID).Scan(&metadata)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get account metadata: %w", err)
+	}
+
+	policy := &JailbreakPolicy{
+		Enabled: defaultEnabled,
+		Action:  JailbreakActionBlock,
+		Source:  PolicySourceDefault,
+	}
+
+	if enabled, ok := metadata["jailbreak_detection_enabled"].(bool); ok {
+		policy.Enabled = enabled
+		policy.Source = PolicySourceAccount
+	}
+	if action, ok := metadata["jailbreak_action"].(string); ok && IsValidJailbreakAction(action) {
+		policy.Action = action
+		policy.Source = PolicySourceAccount
+	}
+
+	return policy, nil
+}
+
+// SetJailbreakPolicy stores the account-level jailbreak policy in the account's metadata JSONB.
+func (db *DB) SetJailbreakPolicy(ctx context.Context, accountID uuid.UUID, enabled bool, action string) error {
+	if !IsValidJailbreakAction(action) {
+		return ErrInvalidJailbreakAction
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+		        'jailbreak_detection_enabled', $1::boolean,
+		        'jailbreak_action', $2::text),
+		    updated_at = $3
+		WHERE id = $4
+	`, enabled, action, time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update jailbreak policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// GetOrganizationJailbreakPolicy returns the organization-level override for the given
+// WorkOS organization, or nil if the organization has not set one.
+func (db *DB) GetOrganizationJailbreakPolicy(ctx context.Context, organizationID string) (*JailbreakPolicy, error) {
+	policy := &JailbreakPolicy{Source: PolicySourceOrganization}
+	err := db.QueryRow(ctx, `
+		SELECT jailbreak_detection_enabled, jailbreak_action
+		FROM organization_policies
+		WHERE workos_organization_id = $1
+	`, organizationID).Scan(&policy.Enabled, &policy.Action)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get organization policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetOrganizationJailbreakPolicy creates or replaces the organization-level jailbreak override.
+func (db *DB) SetOrganizationJailbreakPolicy(ctx context.Context, organizationID string, enabled bool, action string, updatedBy uuid.UUID) error {
+	if !IsValidJailbreakAction(action) {
+		return ErrInvalidJailbreakAction
+	}
+
+	_, err := db.pool.Exec(ctx, `
+		INSERT INTO organization_policies (workos_organization_id, jailbreak_detection_enabled, jailbreak_action, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workos_organization_id) DO UPDATE
+		SET jailbreak_detection_enabled = EXCLUDED.jailbreak_detection_enabled,
+		    jailbreak_action = EXCLUDED.jailbreak_action,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, organizationID, enabled, action, updatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to set organization policy: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrganizationJailbreakPolicy removes the organization-level override so
+// member accounts fall back to their own settings.
+func (db *DB) DeleteOrganizationJailbreakPolicy(ctx context.Context, organizationID string) error {
+	_, err := db.pool.Exec(ctx, `
+		DELETE FROM organization_policies WHERE workos_organization_id = $1
+	`, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization policy: %w", err)
+	}
+	return nil
+}
+
+// GetEffectiveJailbreakPolicy resolves the jailbreak policy that applies to an account.
+// An organization override takes precedence over the account's own settings.
+func (db *DB) GetEffectiveJailbreakPolicy(ctx context.Context, accountID uuid.UUID, defaultEnabled bool) (*JailbreakPolicy, error) {
+	orgID, err := db.GetWorkOSOrganizationID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if orgID != "" {
+		orgPolicy, err := db.GetOrganizationJailbreakPolicy(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if orgPolicy != nil {
+			return orgPolicy, nil
+		}
+	}
+
+	return db.GetJailbreakPolicy(ctx, accountID, defaultEnabled)
+}
+
+// GetWorkOSOrganizationID returns the WorkOS organization the account belongs to,
+// or an empty string if it is not a member of one.
+func (db *DB) GetWorkOSOrganizationID(ctx context.Context, accountID uuid.UUID) (string, error) {
+	var orgID *string
+	err := db.QueryRow(ctx, `
+		SELECT workos_organization_id FROM accounts WHERE id = $1
+	`, accountID).Scan(&orgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrAccountNotFound
+		}
+		return "", fmt.Errorf("failed to get organization ID: %w", err)
+	}
+	if orgID == nil {
+		return "", nil
+	}
+	return *orgID, nil
+}
+
+// SetWorkOSOrganizationID records the WorkOS organization an account belongs to.
+// An empty organizationID clears the membership. No-op when unchanged.
+func (db *DB) SetWorkOSOrganizationID(ctx context.Context, accountID uuid.UUID, organizationID string) error {
+	var orgID *string
+	if organizationID != "" {
+		orgID = &organizationID
+	}
+
+	_, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET workos_organization_id = $1, updated_at = $2
+		WHERE id = $3 AND workos_organization_id IS DISTINCT FROM $1
+	`, orgID, time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update organization ID: %w", err)
+	}
+	return nil
+}
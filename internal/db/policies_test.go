@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJailbreakPolicy_Defaults(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	policy, err := db.GetJailbreakPolicy(ctx, account.ID, true)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	assert.Equal(t, JailbreakActionBlock, policy.Action)
+	assert.Equal(t, PolicySourceDefault, policy.Source)
+}
+
+func TestSetJailbreakPolicy_RoundTrip(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, db.SetJailbreakPolicy(ctx, account.ID, true, JailbreakActionWarn))
+
+	policy, err := db.GetJailbreakPolicy(ctx, account.ID, false)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	assert.Equal(t, JailbreakActionWarn, policy.Action)
+	assert.Equal(t, PolicySourceAccount, policy.Source)
+
+	// Legacy settings endpoint writes the same key
+	enabled, err := db.GetJailbreakDetectionEnabled(ctx, account.ID, false)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	assert.ErrorIs(t, db.SetJailbreakPolicy(ctx, account.ID, true, "ignore"), ErrInvalidJailbreakAction)
+}
+
+func TestGetEffectiveJailbreakPolicy_OrganizationOverride(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.SetJailbreakPolicy(ctx, account.ID, false, JailbreakActionBlock))
+
+	// No organization: account policy applies
+	policy, err := db.GetEffectiveJailbreakPolicy(ctx, account.ID, true)
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled)
+	assert.Equal(t, PolicySourceAccount, policy.Source)
+
+	// Organization without a policy row: account policy still applies
+	require.NoError(t, db.SetWorkOSOrganizationID(ctx, account.ID, "org_123"))
+	policy, err = db.GetEffectiveJailbreakPolicy(ctx, account.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, PolicySourceAccount, policy.Source)
+
+	// Organization override wins
+	require.NoError(t, db.SetOrganizationJailbreakPolicy(ctx, "org_123", true, JailbreakActionWarn, account.ID))
+	policy, err = db.GetEffectiveJailbreakPolicy(ctx, account.ID, true)
+	require.NoError(t, err)
+	assert.True(t, policy.Enabled)
+	assert.Equal(t, JailbreakActionWarn, policy.Action)
+	assert.Equal(t, PolicySourceOrganization, policy.Source)
+
+	// Removing the override falls back to the account
+	require.NoError(t, db.DeleteOrganizationJailbreakPolicy(ctx, "org_123"))
+	policy, err = db.GetEffectiveJailbreakPolicy(ctx, account.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, PolicySourceAccount, policy.Source)
+}
@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrPrepaidCodeNotFound is returned when no code matches
+	ErrPrepaidCodeNotFound = errors.New("prepaid code not found")
+	// ErrPrepaidCodeRedeemed is returned when the code was already redeemed
+	ErrPrepaidCodeRedeemed = errors.New("prepaid code already redeemed")
+	// ErrPrepaidCodeExpired is returned when the code's batch expired or was
+	// voided
+	ErrPrepaidCodeExpired = errors.New("prepaid code expired")
+	// ErrPrepaidCodeLimit is returned when the account already redeemed as
+	// many codes from the batch as it may
+	ErrPrepaidCodeLimit = errors.New("prepaid code limit reached for this batch")
+	// ErrPrepaidCodeAccountInactive is returned when the redeeming account is
+	// suspended or closed
+	ErrPrepaidCodeAccountInactive = errors.New("account is not active")
+	// ErrPrepaidCodeBatchNotFound is returned when no batch matches
+	ErrPrepaidCodeBatchNotFound = errors.New("prepaid code batch not found")
+)
+
+// PrepaidCodeBatch is a set of prepaid codes generated together with the
+// same value and terms
+type PrepaidCodeBatch struct {
+	ID              uuid.UUID      `json:"id"`
+	Label           string         `json:"label"`
+	ValueUSDC       usdc.MicroUSDC `json:"value_usdc"`
+	CodeCount       int            `json:"code_count"`
+	RedeemedCount   int            `json:"redeemed_count"`
+	PerAccountLimit int            `json:"per_account_limit"`
+	ExpiresAt       *time.Time     `json:"expires_at,omitempty"`
+	VoidedAt        *time.Time     `json:"voided_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// CreatePrepaidCodeBatch stores a batch and the hashes of its codes. hints
+// holds the last characters of each code, in the same order as codeHashes.
+func (db *DB) CreatePrepaidCodeBatch(ctx context.Context, batch *PrepaidCodeBatch, codeHashes, hints []string) error {
+	if len(codeHashes) == 0 || len(codeHashes) != len(hints) {
+		return errors.New("a batch needs one hint per code")
+	}
+	batch.ID = uuid.New()
+	batch.CodeCount = len(codeHashes)
+	batch.RedeemedCount = 0
+	batch.CreatedAt = time.Now().UTC()
+	if batch.PerAccountLimit <= 0 {
+		batch.PerAccountLimit = 1
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO prepaid_code_batches (id, label, value_usdc, code_count, per_account_limit, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, batch.ID, batch.Label, batch.ValueUSDC, batch.CodeCount, batch.PerAccountLimit,
+		batch.ExpiresAt, batch.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create prepaid code batch: %w", err)
+	}
+
+	rows := make([][]any, len(codeHashes))
+	for i, hash := range codeHashes {
+		rows[i] = []any{uuid.New(), batch.ID, hash, hints[i]}
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"prepaid_codes"},
+		[]string{"id", "batch_id", "code_hash", "code_hint"}, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to store prepaid codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListPrepaidCodeBatches returns batches with how many of their codes were
+// redeemed, newest first
+func (db *DB) ListPrepaidCodeBatches(ctx context.Context, limit, offset int) ([]*PrepaidCodeBatch, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	rows, err := db.pool.Query(ctx, `
+		SELECT b.id, b.label, b.value_usdc, b.code_count, b.per_account_limit, b.expires_at, b.voided_at, b.created_at,
+		       (SELECT COUNT(*) FROM prepaid_codes c WHERE c.batch_id = b.id AND c.redeemed_at IS NOT NULL)
+		FROM prepaid_code_batches b
+		ORDER BY b.created_at DESC, b.id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prepaid code batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []*PrepaidCodeBatch{}
+	for rows.Next() {
+		b := &PrepaidCodeBatch{}
+		if err := rows.Scan(&b.ID, &b.Label, &b.ValueUSDC, &b.CodeCount, &b.PerAccountLimit,
+			&b.ExpiresAt, &b.VoidedAt, &b.CreatedAt, &b.RedeemedCount); err != nil {
+			return nil, fmt.Errorf("failed to scan prepaid code batch: %w", err)
+		}
+		batches = append(batches, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate prepaid code batches: %w", err)
+	}
+	return batches, nil
+}
+
+// VoidPrepaidCodeBatch stops a batch's unredeemed codes from being redeemed.
+// Codes already redeemed keep their deposits.
+func (db *DB) VoidPrepaidCodeBatch(ctx context.Context, batchID uuid.UUID) error {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE prepaid_code_batches
+		SET voided_at = COALESCE(voided_at, $1)
+		WHERE id = $2
+	`, time.Now().UTC(), batchID)
+	if err != nil {
+		return fmt.Errorf("failed to void prepaid code batch: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPrepaidCodeBatchNotFound
+	}
+	return nil
+}
+
+// RedeemPrepaidCode credits the code's value to the account as a completed
+// deposit and marks the code redeemed, returning the deposit
+func (db *DB) RedeemPrepaidCode(ctx context.Context, accountID uuid.UUID, codeHash string) (*Deposit, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the account so its concurrent redemptions are counted against the
+	// batch limit one at a time
+	var status AccountStatus
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM accounts WHERE id = $1 FOR UPDATE
+	`, accountID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+	if status != AccountStatusActive {
+		return nil, ErrPrepaidCodeAccountInactive
+	}
+
+	var (
+		codeID, batchID uuid.UUID
+		redeemedAt      *time.Time
+		batch           PrepaidCodeBatch
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT c.id, c.redeemed_at, b.id, b.label, b.value_usdc, b.per_account_limit, b.expires_at, b.voided_at
+		FROM prepaid_codes c
+		JOIN prepaid_code_batches b ON b.id = c.batch_id
+		WHERE c.code_hash = $1
+		FOR UPDATE OF c
+	`, codeHash).Scan(&codeID, &redeemedAt, &batchID, &batch.Label, &batch.ValueUSDC,
+		&batch.PerAccountLimit, &batch.ExpiresAt, &batch.VoidedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPrepaidCodeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prepaid code: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if redeemedAt != nil {
+		return nil, ErrPrepaidCodeRedeemed
+	}
+	if batch.VoidedAt != nil || (batch.ExpiresAt != nil && !now.Before(*batch.ExpiresAt)) {
+		return nil, ErrPrepaidCodeExpired
+	}
+
+	var redeemed int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM prepaid_codes WHERE batch_id = $1 AND redeemed_by = $2
+	`, batchID, accountID).Scan(&redeemed); err != nil {
+		return nil, fmt.Errorf("failed to count redeemed codes: %w", err)
+	}
+	if redeemed >= batch.PerAccountLimit {
+		return nil, ErrPrepaidCodeLimit
+	}
+
+	txID := "prepaid:" + codeID.String()
+	deposit := &Deposit{
+		ID:                    uuid.New(),
+		AccountID:             accountID,
+		Provider:              DepositProviderPrepaidCode,
+		AmountUSDC:            batch.ValueUSDC,
+		NetAmountUSDC:         batch.ValueUSDC,
+		Status:                DepositStatusCompleted,
+		ProviderTransactionID: &txID,
+		Metadata:              map[string]any{"batch_id": batchID.String(), "batch_label": batch.Label},
+		CreatedAt:             now,
+		CompletedAt:           &now,
+	}
+	// The deposit trigger credits the balance for a completed deposit
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO deposits (
+			id, account_id, provider, amount_usdc, fee_usdc, net_amount_usdc,
+			status, provider_transaction_id, metadata, created_at, completed_at
+		) VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $8, $9, $10)
+	`, deposit.ID, deposit.AccountID, deposit.Provider, deposit.AmountUSDC, deposit.NetAmountUSDC,
+		deposit.Status, deposit.ProviderTransactionID, deposit.Metadata, deposit.CreatedAt, deposit.CompletedAt); err != nil {
+		return nil, fmt.Errorf("failed to record deposit: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE prepaid_codes SET redeemed_at = $1, redeemed_by = $2, deposit_id = $3
+		WHERE id = $4
+	`, now, accountID, deposit.ID, codeID); err != nil {
+		return nil, fmt.Errorf("failed to mark prepaid code redeemed: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deposit, nil
+}
+
+// RecordPrepaidCodeFailure records a failed redemption by an account from
+// ip. Failures older than keep are pruned.
+func (db *DB) RecordPrepaidCodeFailure(ctx context.Context, accountID uuid.UUID, ip string, keep time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := db.pool.Exec(ctx, `
+		INSERT INTO prepaid_code_failures (account_id, ip, created_at) VALUES ($1, $2, $3)
+	`, accountID, ip, now); err != nil {
+		return fmt.Errorf("failed to record prepaid code failure: %w", err)
+	}
+	if _, err := db.pool.Exec(ctx, `
+		DELETE FROM prepaid_code_failures WHERE created_at < $1
+	`, now.Add(-keep)); err != nil {
+		return fmt.Errorf("failed to prune prepaid code failures: %w", err)
+	}
+	return nil
+}
+
+// CountPrepaidCodeFailures returns the failed redemptions since since by the
+// account and from ip
+func (db *DB) CountPrepaidCodeFailures(ctx context.Context, accountID uuid.UUID, ip string, since time.Time) (byAccount, byIP int, err error) {
+	err = db.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE account_id = $1), COUNT(*) FILTER (WHERE ip = $2)
+		FROM prepaid_code_failures
+		WHERE created_at >= $3 AND (account_id = $1 OR ip = $2)
+	`, accountID, ip, since).Scan(&byAccount, &byIP)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count prepaid code failures: %w", err)
+	}
+	return byAccount, byIP, nil
+}
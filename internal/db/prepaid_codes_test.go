@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedeemPrepaidCode(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	batch := &PrepaidCodeBatch{Label: "Trial", ValueUSDC: 5_000_000}
+	codes := []string{"AAAA-BBBB-CCCC-DDDD", "EEEE-FFFF-GGGG-HHHH"}
+	require.NoError(t, db.CreatePrepaidCodeBatch(ctx, batch,
+		[]string{HashToken(codes[0]), HashToken(codes[1])}, []string{"DDDD", "HHHH"}))
+	assert.Equal(t, 1, batch.PerAccountLimit)
+
+	deposit, err := db.RedeemPrepaidCode(ctx, account.ID, HashToken(codes[0]))
+	require.NoError(t, err)
+	assert.Equal(t, DepositProviderPrepaidCode, deposit.Provider)
+	assert.Equal(t, DepositStatusCompleted, deposit.Status)
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), deposit.AmountUSDC)
+
+	got, err := db.GetAccountByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), got.BalanceUSDC)
+
+	// Codes are single use, and the batch allows one code per account
+	_, err = db.RedeemPrepaidCode(ctx, other.ID, HashToken(codes[0]))
+	assert.ErrorIs(t, err, ErrPrepaidCodeRedeemed)
+	_, err = db.RedeemPrepaidCode(ctx, account.ID, HashToken(codes[1]))
+	assert.ErrorIs(t, err, ErrPrepaidCodeLimit)
+	_, err = db.RedeemPrepaidCode(ctx, account.ID, HashToken("ZZZZ-ZZZZ-ZZZZ-ZZZZ"))
+	assert.ErrorIs(t, err, ErrPrepaidCodeNotFound)
+
+	// A voided batch's remaining codes can't be redeemed
+	require.NoError(t, db.VoidPrepaidCodeBatch(ctx, batch.ID))
+	_, err = db.RedeemPrepaidCode(ctx, other.ID, HashToken(codes[1]))
+	assert.ErrorIs(t, err, ErrPrepaidCodeExpired)
+
+	batches, err := db.ListPrepaidCodeBatches(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, 2, batches[0].CodeCount)
+	assert.Equal(t, 1, batches[0].RedeemedCount)
+	assert.NotNil(t, batches[0].VoidedAt)
+
+	got, err = db.GetAccountByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(0), got.BalanceUSDC)
+}
+
+func TestPrepaidCodeFailures(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	since := time.Now().Add(-time.Minute)
+	require.NoError(t, db.RecordPrepaidCodeFailure(ctx, account.ID, "203.0.113.7", time.Hour))
+	require.NoError(t, db.RecordPrepaidCodeFailure(ctx, account.ID, "203.0.113.7", time.Hour))
+	require.NoError(t, db.RecordPrepaidCodeFailure(ctx, other.ID, "203.0.113.7", time.Hour))
+
+	byAccount, byIP, err := db.CountPrepaidCodeFailures(ctx, account.ID, "198.51.100.1", since)
+	require.NoError(t, err)
+	assert.Equal(t, 2, byAccount)
+	assert.Equal(t, 0, byIP)
+
+	byAccount, byIP, err = db.CountPrepaidCodeFailures(ctx, other.ID, "203.0.113.7", since)
+	require.NoError(t, err)
+	assert.Equal(t, 1, byAccount)
+	assert.Equal(t, 3, byIP)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MaxPromptFingerprints is the most system prompts an account can register
+const MaxPromptFingerprints = 20
+
+var (
+	// ErrPromptFingerprintNotFound is returned when an account has no
+	// fingerprint with the given ID
+	ErrPromptFingerprintNotFound = errors.New("prompt fingerprint not found")
+	// ErrPromptFingerprintExists is returned when the account already has a
+	// fingerprint with the name
+	ErrPromptFingerprintExists = errors.New("prompt fingerprint name already in use")
+	// ErrTooManyPromptFingerprints is returned when the account has
+	// MaxPromptFingerprints already
+	ErrTooManyPromptFingerprints = errors.New("too many prompt fingerprints")
+)
+
+// PromptFingerprint is a registered system prompt, kept only as hashed
+// shingles
+type PromptFingerprint struct {
+	ID        uuid.UUID `json:"id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Name      string    `json:"name"`
+	Shingles  []string  `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatePromptFingerprint registers a system prompt fingerprint for the account
+func (db *DB) CreatePromptFingerprint(ctx context.Context, accountID uuid.UUID, name string, shingles []string) (*PromptFingerprint, error) {
+	fp := &PromptFingerprint{
+		ID:        uuid.New(),
+		AccountID: accountID,
+		Name:      name,
+		Shingles:  shingles,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		INSERT INTO prompt_fingerprints (id, account_id, name, shingles, created_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (SELECT COUNT(*) FROM prompt_fingerprints WHERE account_id = $2) < $6
+	`, fp.ID, accountID, name, shingles, fp.CreatedAt, MaxPromptFingerprints)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrPromptFingerprintExists
+		}
+		return nil, fmt.Errorf("failed to create prompt fingerprint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrTooManyPromptFingerprints
+	}
+	return fp, nil
+}
+
+// ListPromptFingerprints returns the account's fingerprints, oldest first
+func (db *DB) ListPromptFingerprints(ctx context.Context, accountID uuid.UUID) ([]*PromptFingerprint, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, name, shingles, created_at
+		FROM prompt_fingerprints
+		WHERE account_id = $1
+		ORDER BY created_at, name
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	prints := []*PromptFingerprint{}
+	for rows.Next() {
+		fp := &PromptFingerprint{AccountID: accountID}
+		if err := rows.Scan(&fp.ID, &fp.Name, &fp.Shingles, &fp.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt fingerprint: %w", err)
+		}
+		prints = append(prints, fp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate prompt fingerprints: %w", err)
+	}
+	return prints, nil
+}
+
+// DeletePromptFingerprint removes one of the account's fingerprints
+func (db *DB) DeletePromptFingerprint(ctx context.Context, accountID, id uuid.UUID) error {
+	result, err := db.pool.Exec(ctx, `
+		DELETE FROM prompt_fingerprints WHERE account_id = $1 AND id = $2
+	`, accountID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete prompt fingerprint: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPromptFingerprintNotFound
+	}
+	return nil
+}
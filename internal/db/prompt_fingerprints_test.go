@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptFingerprints(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	fp, err := db.CreatePromptFingerprint(ctx, account.ID, "support", []string{"0123456789abcdef", "fedcba9876543210"})
+	require.NoError(t, err)
+
+	_, err = db.CreatePromptFingerprint(ctx, account.ID, "support", []string{"0123456789abcdef"})
+	assert.ErrorIs(t, err, ErrPromptFingerprintExists)
+
+	prints, err := db.ListPromptFingerprints(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, prints, 1)
+	assert.Equal(t, "support", prints[0].Name)
+	assert.Equal(t, []string{"0123456789abcdef", "fedcba9876543210"}, prints[0].Shingles)
+
+	for i := 1; i < MaxPromptFingerprints; i++ {
+		_, err := db.CreatePromptFingerprint(ctx, account.ID, fmt.Sprintf("prompt-%d", i), []string{"0123456789abcdef"})
+		require.NoError(t, err)
+	}
+	_, err = db.CreatePromptFingerprint(ctx, account.ID, "one-too-many", []string{"0123456789abcdef"})
+	assert.ErrorIs(t, err, ErrTooManyPromptFingerprints)
+
+	require.NoError(t, db.DeletePromptFingerprint(ctx, account.ID, fp.ID))
+	assert.ErrorIs(t, db.DeletePromptFingerprint(ctx, account.ID, fp.ID), ErrPromptFingerprintNotFound)
+	assert.ErrorIs(t, db.DeletePromptFingerprint(ctx, account.ID, uuid.New()), ErrPromptFingerprintNotFound)
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReceivableStatus is the state of credit extended during a facilitator outage
+type ReceivableStatus string
+
+const (
+	ReceivableStatusOpen    ReceivableStatus = "open"
+	ReceivableStatusSettled ReceivableStatus = "settled"
+)
+
+// How a receivable was settled
+const (
+	ReceivableSettledViaX402    = "x402"
+	ReceivableSettledViaBalance = "balance"
+)
+
+// Receivable is a scan served on credit while the facilitator was down
+type Receivable struct {
+	ID                   uuid.UUID        `json:"id"`
+	AccountID            uuid.UUID        `json:"account_id"`
+	PaymentTransactionID uuid.UUID        `json:"payment_transaction_id"`
+	AmountUSDC           usdc.MicroUSDC   `json:"amount_usdc"`
+	Status               ReceivableStatus `json:"status"`
+	SettledVia           *string          `json:"settled_via,omitempty"`
+	CreatedAt            time.Time        `json:"created_at"`
+	SettledAt            *time.Time       `json:"settled_at,omitempty"`
+}
+
+// CreditStanding is what decides whether a payer may be served on credit
+type CreditStanding struct {
+	AccountID       uuid.UUID
+	Status          AccountStatus
+	Balance         usdc.MicroUSDC
+	SettledPayments int            // Completed x402 payments from the account's wallets
+	Outstanding     usdc.MicroUSDC // Sum of open receivables
+}
+
+// GetCreditStanding returns the standing of the account linked to a payer
+// wallet, or ErrAccountNotFound if the wallet isn't linked to one
+func (db *DB) GetCreditStanding(ctx context.Context, payer string) (*CreditStanding, error) {
+	s := &CreditStanding{}
+	err := db.QueryRow(ctx, `
+		SELECT a.id, a.status, a.balance_usdc,
+			(SELECT COUNT(*) FROM payment_transactions p
+			 WHERE p.status = $2
+			   AND (LOWER(p.payer_address) = LOWER(a.evm_wallet_address) OR p.payer_address = a.solana_wallet_address)),
+			(SELECT COALESCE(SUM(r.amount_usdc), 0) FROM payment_receivables r
+			 WHERE r.account_id = a.id AND r.status = $3)
+		FROM accounts a
+		WHERE LOWER(a.evm_wallet_address) = LOWER($1) OR a.solana_wallet_address = $1
+		LIMIT 1
+	`, payer, PaymentStatusCompleted, ReceivableStatusOpen).Scan(
+		&s.AccountID, &s.Status, &s.Balance, &s.SettledPayments, &s.Outstanding)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit standing: %w", err)
+	}
+	return s, nil
+}
+
+// CreateReceivable records that a payment was served on credit
+func (db *DB) CreateReceivable(ctx context.Context, accountID, paymentID uuid.UUID, amount usdc.MicroUSDC) error {
+	err := db.Exec(ctx, `
+		INSERT INTO payment_receivables (account_id, payment_transaction_id, amount_usdc)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (payment_transaction_id) DO NOTHING
+	`, accountID, paymentID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to create receivable: %w", err)
+	}
+	return nil
+}
+
+// settleReceivable marks the receivable for a payment settled, if it has one
+func settleReceivable(ctx context.Context, tx pgx.Tx, paymentID uuid.UUID, via string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE payment_receivables SET status = $2, settled_via = $3, settled_at = NOW()
+		WHERE payment_transaction_id = $1 AND status = $4
+	`, paymentID, ReceivableStatusSettled, via, ReceivableStatusOpen)
+	if err != nil {
+		return fmt.Errorf("failed to settle receivable: %w", err)
+	}
+	return nil
+}
+
+// GetLapsedReceivables returns open receivables whose payment failed to
+// settle and can no longer be, as its authorization expired before the
+// given time
+func (db *DB) GetLapsedReceivables(ctx context.Context, before time.Time, limit int) ([]*Receivable, error) {
+	rows, err := db.Query(ctx, `
+		SELECT r.id, r.account_id, r.payment_transaction_id, r.amount_usdc, r.status,
+			r.settled_via, r.created_at, r.settled_at
+		FROM payment_receivables r
+		JOIN payment_transactions p ON p.id = r.payment_transaction_id
+		WHERE r.status = $1 AND p.status = $2 AND p.expires_at < $3
+		ORDER BY r.created_at
+		LIMIT $4
+	`, ReceivableStatusOpen, PaymentStatusFailed, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lapsed receivables: %w", err)
+	}
+	defer rows.Close()
+
+	var receivables []*Receivable
+	for rows.Next() {
+		r := &Receivable{}
+		if err := rows.Scan(&r.ID, &r.AccountID, &r.PaymentTransactionID, &r.AmountUSDC, &r.Status,
+			&r.SettledVia, &r.CreatedAt, &r.SettledAt); err != nil {
+			return nil, fmt.Errorf("failed to scan receivable: %w", err)
+		}
+		receivables = append(receivables, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query lapsed receivables: %w", err)
+	}
+	return receivables, nil
+}
+
+// SettleReceivableFromBalance debits a lapsed receivable from the account
+// balance and gives up on settling its payment. Returns false, leaving the
+// receivable open, if the balance is short or the payment is being settled.
+func (db *DB) SettleReceivableFromBalance(ctx context.Context, id uuid.UUID) (bool, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var accountID, paymentID uuid.UUID
+	var amount usdc.MicroUSDC
+	// Locking the payment keeps the settlement worker from claiming it meanwhile
+	err = tx.QueryRow(ctx, `
+		SELECT r.account_id, r.payment_transaction_id, r.amount_usdc
+		FROM payment_receivables r
+		JOIN payment_transactions p ON p.id = r.payment_transaction_id
+		WHERE r.id = $1 AND r.status = $2 AND p.status = $3
+		FOR UPDATE OF r, p
+	`, id, ReceivableStatusOpen, PaymentStatusFailed).Scan(&accountID, &paymentID, &amount)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock receivable: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE accounts SET balance_usdc = balance_usdc - $1, updated_at = NOW()
+		WHERE id = $2 AND balance_usdc >= $1
+	`, amount, accountID)
+	if err != nil {
+		return false, fmt.Errorf("failed to deduct balance: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE payment_transactions SET status = $2, last_error = $3 WHERE id = $1
+	`, paymentID, PaymentStatusExpired, "authorization lapsed; debited from account balance"); err != nil {
+		return false, fmt.Errorf("failed to expire payment: %w", err)
+	}
+	if err := settleReceivable(ctx, tx, paymentID, ReceivableSettledViaBalance); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
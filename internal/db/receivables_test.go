@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// TestSettleReceivableFromBalance tests that a receivable whose payment can
+// no longer settle is debited from the balance, and only when it covers it
+func TestSettleReceivableFromBalance(t *testing.T) {
+	pool := getTestPool(t)
+	if pool == nil {
+		t.Skip("No database connection available")
+	}
+	db := &DB{pool: pool}
+	ctx := context.Background()
+
+	payer := "0xfeed" + uuid.New().String()[:8] + "0000000000000000000000000000"
+	account, err := db.CreateAccount(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	defer func() {
+		_, _ = db.pool.Exec(ctx, "DELETE FROM accounts WHERE id = $1", account.ID)
+	}()
+	if err := db.LinkEVMWallet(ctx, account.ID, strings.ToLower(payer)); err != nil {
+		t.Fatalf("Failed to link wallet: %v", err)
+	}
+	if _, err := db.pool.Exec(ctx, "UPDATE accounts SET balance_usdc = 1500 WHERE id = $1", account.ID); err != nil {
+		t.Fatalf("Failed to set balance: %v", err)
+	}
+
+	// Two payments served on credit whose authorizations have lapsed
+	var receivables []uuid.UUID
+	for i := 0; i < 2; i++ {
+		tx := &PaymentTransaction{
+			PaymentNonce:    "credit-" + uuid.New().String(),
+			PaymentHeader:   "x402;test-header",
+			PayerAddress:    payer,
+			ReceiverAddress: "0x0987654321098765432109876543210987654321",
+			Endpoint:        "/v1/scan/content",
+			AmountUSDC:      usdc.MicroUSDC(1000),
+			Network:         "base-sepolia",
+			ExpiresAt:       time.Now().Add(-time.Hour),
+		}
+		if err := db.CreatePaymentTransaction(ctx, tx); err != nil {
+			t.Fatalf("Failed to create payment: %v", err)
+		}
+		defer func() {
+			_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions WHERE id = $1", tx.ID)
+		}()
+		if err := db.TransitionStatus(ctx, tx.ID, PaymentStatusReserved, PaymentStatusSettling); err != nil {
+			t.Fatalf("Failed to transition to settling: %v", err)
+		}
+		if err := db.FailSettlement(ctx, tx.ID, "facilitator unavailable"); err != nil {
+			t.Fatalf("Failed to fail settlement: %v", err)
+		}
+		if err := db.CreateReceivable(ctx, account.ID, tx.ID, tx.AmountUSDC); err != nil {
+			t.Fatalf("Failed to create receivable: %v", err)
+		}
+	}
+
+	standing, err := db.GetCreditStanding(ctx, payer)
+	if err != nil {
+		t.Fatalf("Failed to get credit standing: %v", err)
+	}
+	if standing.AccountID != account.ID || standing.Outstanding != 2000 || standing.Balance != 1500 {
+		t.Errorf("Unexpected standing: %+v", standing)
+	}
+
+	lapsed, err := db.GetLapsedReceivables(ctx, time.Now(), 100)
+	if err != nil {
+		t.Fatalf("Failed to get lapsed receivables: %v", err)
+	}
+	for _, r := range lapsed {
+		if r.AccountID == account.ID {
+			receivables = append(receivables, r.ID)
+		}
+	}
+	if len(receivables) != 2 {
+		t.Fatalf("Expected 2 lapsed receivables, got %d", len(receivables))
+	}
+
+	// The balance covers the first but not the second
+	for i, want := range []bool{true, false} {
+		ok, err := db.SettleReceivableFromBalance(ctx, receivables[i])
+		if err != nil {
+			t.Fatalf("Failed to settle receivable: %v", err)
+		}
+		if ok != want {
+			t.Errorf("Receivable %d: expected settled=%v, got %v", i, want, ok)
+		}
+	}
+
+	standing, err = db.GetCreditStanding(ctx, payer)
+	if err != nil {
+		t.Fatalf("Failed to get credit standing: %v", err)
+	}
+	if standing.Outstanding != 1000 || standing.Balance != 500 {
+		t.Errorf("Expected 1000 outstanding and 500 balance, got %+v", standing)
+	}
+
+	// A settled receivable is not settled twice
+	ok, err := db.SettleReceivableFromBalance(ctx, receivables[0])
+	if err != nil || ok {
+		t.Errorf("Expected settled receivable to be skipped, got ok=%v err=%v", ok, err)
+	}
+
+	if _, err := db.GetCreditStanding(ctx, "0xnot-linked"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// replicatedUsageMetadata lists the usage log metadata keys that may leave
+// the region. Anything else could describe scanned content and is dropped.
+var replicatedUsageMetadata = map[string]bool{
+	"auth_method":    true,
+	"payment_method": true,
+	"account_type":   true,
+	"actual_cost":    true,
+	"api_key_id":     true,
+	"sub_account_id": true,
+	"decision":       true,
+}
+
+// ReplicatedUsage is a usage log record stripped to metadata that can be
+// replicated across regions. It never carries scanned content.
+type ReplicatedUsage struct {
+	ID               uuid.UUID      `json:"id"`
+	AccountID        uuid.UUID      `json:"account_id"`
+	Endpoint         string         `json:"endpoint"`
+	Method           string         `json:"method"`
+	CostUSDC         usdc.MicroUSDC `json:"cost_usdc"`
+	Status           string         `json:"status"`
+	ThreatDetected   bool           `json:"threat_detected"`
+	ThreatType       *string        `json:"threat_type,omitempty"`
+	LatencyMs        *int           `json:"latency_ms,omitempty"`
+	DetectionVersion *string        `json:"detection_version,omitempty"`
+	Metadata         map[string]any `json:"metadata,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// SetAccountRegion pins an account's scans to region. An empty region
+// removes the pin.
+func (db *DB) SetAccountRegion(ctx context.Context, accountID uuid.UUID, region string) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE accounts SET region = $1, updated_at = $2 WHERE id = $3
+	`, labelOrNull(region), time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to set account region: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// ListReplicatedUsage returns up to limit usage records created after the
+// (since, afterID) cursor, oldest first, for replication to other regions
+func (db *DB) ListReplicatedUsage(ctx context.Context, since time.Time, afterID uuid.UUID, limit int) ([]*ReplicatedUsage, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, account_id, endpoint, method, cost_usdc, status, threat_detected,
+		       threat_type, latency_ms, detection_version, metadata, created_at
+		FROM usage_logs
+		WHERE (created_at, id) > ($1, $2)
+		ORDER BY created_at, id
+		LIMIT $3
+	`, since, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage for replication: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*ReplicatedUsage{}
+	for rows.Next() {
+		r := &ReplicatedUsage{}
+		var metadata map[string]any
+		if err := rows.Scan(&r.ID, &r.AccountID, &r.Endpoint, &r.Method, &r.CostUSDC, &r.Status, &r.ThreatDetected,
+			&r.ThreatType, &r.LatencyMs, &r.DetectionVersion, &metadata, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage for replication: %w", err)
+		}
+		r.Metadata = replicableMetadata(metadata)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate usage for replication: %w", err)
+	}
+	return records, nil
+}
+
+// replicableMetadata keeps only the allowlisted metadata keys
+func replicableMetadata(metadata map[string]any) map[string]any {
+	var out map[string]any
+	for k, v := range metadata {
+		if !replicatedUsageMetadata[k] {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]any)
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicableMetadata(t *testing.T) {
+	got := replicableMetadata(map[string]any{
+		"auth_method":    "api_key",
+		"actual_cost":    float64(2000),
+		"service_result": map[string]any{"reason": "prompt injection"},
+		"payment_nonce":  "abc",
+	})
+	assert.Equal(t, map[string]any{"auth_method": "api_key", "actual_cost": float64(2000)}, got)
+	assert.Nil(t, replicableMetadata(map[string]any{"service_result": "x"}))
+	assert.Nil(t, replicableMetadata(nil))
+}
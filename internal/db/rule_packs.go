@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MaxRulePacks is the most rule pack versions an account can store
+const MaxRulePacks = 50
+
+// maxPublicRulePacks bounds the public catalogue listing
+const maxPublicRulePacks = 500
+
+var (
+	// ErrRulePackNotFound is returned when an account has no rule pack with
+	// the given ID, or a public pack doesn't exist
+	ErrRulePackNotFound = errors.New("rule pack not found")
+	// ErrRulePackExists is returned when the account already has the pack's
+	// name and version
+	ErrRulePackExists = errors.New("rule pack version already imported")
+	// ErrTooManyRulePacks is returned when the account has MaxRulePacks already
+	ErrTooManyRulePacks = errors.New("too many rule packs")
+)
+
+// RulePack is a stored rule pack version
+type RulePack struct {
+	ID          uuid.UUID `json:"id"`
+	AccountID   uuid.UUID `json:"-"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	Rules       int       `json:"rules"`
+	Source      []byte    `json:"-"`
+	Enabled     bool      `json:"enabled"`
+	Public      bool      `json:"public"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateRulePack stores a validated rule pack version for the account,
+// enabling it (and disabling its other versions) when pack.Enabled is set
+func (db *DB) CreateRulePack(ctx context.Context, pack *RulePack) error {
+	now := time.Now().UTC()
+	pack.ID = uuid.New()
+	pack.CreatedAt, pack.UpdatedAt = now, now
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the account row to serialize imports against the cap
+	var lockedID uuid.UUID
+	if err := tx.QueryRow(ctx, `SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, pack.AccountID).Scan(&lockedID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM rule_packs WHERE account_id = $1`, pack.AccountID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count rule packs: %w", err)
+	}
+	if count >= MaxRulePacks {
+		return ErrTooManyRulePacks
+	}
+
+	if pack.Enabled {
+		if _, err := tx.Exec(ctx, `
+			UPDATE rule_packs SET enabled = FALSE, updated_at = $3
+			WHERE account_id = $1 AND name = $2 AND enabled
+		`, pack.AccountID, pack.Name, now); err != nil {
+			return fmt.Errorf("failed to disable other rule pack versions: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO rule_packs (id, account_id, name, version, description, rules, source, enabled, public, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+	`, pack.ID, pack.AccountID, pack.Name, pack.Version, pack.Description, pack.Rules, string(pack.Source), pack.Enabled, pack.Public, now); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrRulePackExists
+		}
+		return fmt.Errorf("failed to create rule pack: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// ListRulePacks returns the account's rule packs by name, newest version
+// first. Sources are not loaded.
+func (db *DB) ListRulePacks(ctx context.Context, accountID uuid.UUID) ([]*RulePack, error) {
+	return db.queryRulePacks(ctx, `
+		SELECT id, account_id, name, version, description, rules, ''::text, enabled, public, created_at, updated_at
+		FROM rule_packs
+		WHERE account_id = $1
+		ORDER BY name, created_at DESC
+	`, accountID)
+}
+
+// ListEnabledRulePacks returns the account's enabled rule packs with their
+// sources, for scanning
+func (db *DB) ListEnabledRulePacks(ctx context.Context, accountID uuid.UUID) ([]*RulePack, error) {
+	return db.queryRulePacks(ctx, `
+		SELECT id, account_id, name, version, description, rules, source, enabled, public, created_at, updated_at
+		FROM rule_packs
+		WHERE account_id = $1 AND enabled
+		ORDER BY name
+	`, accountID)
+}
+
+// ListPublicRulePacks returns the public catalogue by name, newest version
+// first. Sources are not loaded.
+func (db *DB) ListPublicRulePacks(ctx context.Context) ([]*RulePack, error) {
+	return db.queryRulePacks(ctx, `
+		SELECT id, account_id, name, version, description, rules, ''::text, enabled, public, created_at, updated_at
+		FROM rule_packs
+		WHERE public
+		ORDER BY name, created_at DESC
+		LIMIT $1
+	`, maxPublicRulePacks)
+}
+
+// GetRulePack returns one of the account's rule packs with its source
+func (db *DB) GetRulePack(ctx context.Context, accountID, id uuid.UUID) (*RulePack, error) {
+	packs, err := db.queryRulePacks(ctx, `
+		SELECT id, account_id, name, version, description, rules, source, enabled, public, created_at, updated_at
+		FROM rule_packs
+		WHERE account_id = $1 AND id = $2
+	`, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(packs) == 0 {
+		return nil, ErrRulePackNotFound
+	}
+	return packs[0], nil
+}
+
+// GetPublicRulePack returns a public rule pack with its source
+func (db *DB) GetPublicRulePack(ctx context.Context, id uuid.UUID) (*RulePack, error) {
+	packs, err := db.queryRulePacks(ctx, `
+		SELECT id, account_id, name, version, description, rules, source, enabled, public, created_at, updated_at
+		FROM rule_packs
+		WHERE id = $1 AND public
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(packs) == 0 {
+		return nil, ErrRulePackNotFound
+	}
+	return packs[0], nil
+}
+
+// UpdateRulePack sets whether one of the account's rule packs is enabled and
+// public. Enabling a version disables the pack's other versions.
+func (db *DB) UpdateRulePack(ctx context.Context, accountID, id uuid.UUID, enabled, public bool) error {
+	now := time.Now().UTC()
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if enabled {
+		if _, err := tx.Exec(ctx, `
+			UPDATE rule_packs SET enabled = FALSE, updated_at = $3
+			WHERE account_id = $1 AND enabled AND id <> $2
+			  AND name = (SELECT name FROM rule_packs WHERE account_id = $1 AND id = $2)
+		`, accountID, id, now); err != nil {
+			return fmt.Errorf("failed to disable other rule pack versions: %w", err)
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE rule_packs SET enabled = $3, public = $4, updated_at = $5
+		WHERE account_id = $1 AND id = $2
+	`, accountID, id, enabled, public, now)
+	if err != nil {
+		return fmt.Errorf("failed to update rule pack: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRulePackNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// DeleteRulePack removes one of the account's rule pack versions
+func (db *DB) DeleteRulePack(ctx context.Context, accountID, id uuid.UUID) error {
+	tag, err := db.pool.Exec(ctx, `
+		DELETE FROM rule_packs WHERE account_id = $1 AND id = $2
+	`, accountID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete rule pack: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRulePackNotFound
+	}
+	return nil
+}
+
+func (db *DB) queryRulePacks(ctx context.Context, query string, args ...any) ([]*RulePack, error) {
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule packs: %w", err)
+	}
+	defer rows.Close()
+
+	packs := []*RulePack{}
+	for rows.Next() {
+		p := &RulePack{}
+		var source string
+		if err := rows.Scan(&p.ID, &p.AccountID, &p.Name, &p.Version, &p.Description, &p.Rules, &source, &p.Enabled, &p.Public, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rule pack: %w", err)
+		}
+		if source != "" {
+			p.Source = []byte(source)
+		}
+		packs = append(packs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rule packs: %w", err)
+	}
+	return packs, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulePacks(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	pack := func(version string, enabled bool) *RulePack {
+		return &RulePack{AccountID: account.ID, Name: "acme", Version: version, Rules: 2, Source: []byte("name: acme\nversion: " + version + "\n"), Enabled: enabled}
+	}
+	v1, v2 := pack("1.0.0", true), pack("2.0.0", false)
+	require.NoError(t, db.CreateRulePack(ctx, v1))
+	require.NoError(t, db.CreateRulePack(ctx, v2))
+	assert.ErrorIs(t, db.CreateRulePack(ctx, pack("1.0.0", false)), ErrRulePackExists)
+
+	enabled, err := db.ListEnabledRulePacks(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, v1.ID, enabled[0].ID)
+	assert.Equal(t, v1.Source, enabled[0].Source)
+
+	// Enabling a version disables the others
+	require.NoError(t, db.UpdateRulePack(ctx, account.ID, v2.ID, true, true))
+	enabled, err = db.ListEnabledRulePacks(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, v2.ID, enabled[0].ID)
+
+	// Importing an enabled version does too
+	v3 := pack("3.0.0", true)
+	require.NoError(t, db.CreateRulePack(ctx, v3))
+	enabled, err = db.ListEnabledRulePacks(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, v3.ID, enabled[0].ID)
+
+	packs, err := db.ListRulePacks(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, packs, 3)
+	assert.Equal(t, "3.0.0", packs[0].Version)
+	assert.Nil(t, packs[0].Source, "listings don't load sources")
+
+	// Only published packs are in the catalogue, to any account
+	public, err := db.ListPublicRulePacks(ctx)
+	require.NoError(t, err)
+	require.Len(t, public, 1)
+	assert.Equal(t, v2.ID, public[0].ID)
+	got, err := db.GetPublicRulePack(ctx, v2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, v2.Source, got.Source)
+	_, err = db.GetPublicRulePack(ctx, v1.ID)
+	assert.ErrorIs(t, err, ErrRulePackNotFound)
+
+	// Other accounts can't see or change the account's packs
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	_, err = db.GetRulePack(ctx, other.ID, v1.ID)
+	assert.ErrorIs(t, err, ErrRulePackNotFound)
+	assert.ErrorIs(t, db.UpdateRulePack(ctx, other.ID, v1.ID, true, false), ErrRulePackNotFound)
+	assert.ErrorIs(t, db.DeleteRulePack(ctx, other.ID, v1.ID), ErrRulePackNotFound)
+
+	require.NoError(t, db.DeleteRulePack(ctx, account.ID, v3.ID))
+	enabled, err = db.ListEnabledRulePacks(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Empty(t, enabled)
+	assert.ErrorIs(t, db.DeleteRulePack(ctx, account.ID, uuid.New()), ErrRulePackNotFound)
+}
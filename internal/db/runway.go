@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// LowBalanceEventType is the event name of low balance webhook payloads
+const LowBalanceEventType = "balance.low"
+
+// RunwayWindowDays is how many days of spend the runway estimate averages
+const RunwayWindowDays = 7
+
+// AccountRunway estimates how long an account's balance lasts at its recent
+// spend rate
+type AccountRunway struct {
+	DailySpendUSDC usdc.MicroUSDC `json:"daily_spend_usdc"` // Average over the window
+	DaysRemaining  *float64       `json:"days_remaining"`   // nil when nothing was spent in the window
+	WindowDays     int            `json:"window_days"`
+	LowBalance     bool           `json:"low_balance"` // DaysRemaining is below the alert threshold
+}
+
+// LowBalanceEvent is the body of a low balance webhook delivery
+type LowBalanceEvent struct {
+	Event          string         `json:"event"` // always "balance.low"
+	AccountID      uuid.UUID      `json:"account_id"`
+	BalanceUSDC    usdc.MicroUSDC `json:"balance_usdc"`
+	DailySpendUSDC usdc.MicroUSDC `json:"daily_spend_usdc"`
+	DaysRemaining  float64        `json:"days_remaining"`
+	ThresholdDays  float64        `json:"threshold_days"`
+	DetectedAt     time.Time      `json:"detected_at"`
+}
+
+// EstimateRunway divides a balance by the average daily spend over the
+// runway window. An account that spent nothing has no estimate and is never
+// low; alertDays of 0 disables the low balance flag.
+func EstimateRunway(balance, windowSpend usdc.MicroUSDC, alertDays float64) *AccountRunway {
+	runway := &AccountRunway{
+		DailySpendUSDC: windowSpend / RunwayWindowDays,
+		WindowDays:     RunwayWindowDays,
+	}
+	if windowSpend <= 0 {
+		return runway
+	}
+	days := max(float64(balance)*RunwayWindowDays/float64(windowSpend), 0)
+	days = math.Round(days*10) / 10
+	runway.DaysRemaining = &days
+	runway.LowBalance = alertDays > 0 && days < alertDays
+	return runway
+}
+
+// runwayWindowStart is the first hour of spend counted towards the runway
+func runwayWindowStart(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Hour).Add(-(RunwayWindowDays*24 - 1) * time.Hour)
+}
+
+// GetAccountRunway estimates an account's runway from its balance and its
+// spend over the last RunwayWindowDays in the hourly rollups
+func (db *DB) GetAccountRunway(ctx context.Context, accountID uuid.UUID, balance usdc.MicroUSDC, alertDays float64, now time.Time) (*AccountRunway, error) {
+	var spend usdc.MicroUSDC
+	err := db.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(spend_usdc), 0)::bigint
+		FROM usage_rollups_hourly
+		WHERE account_id = $1 AND hour >= $2
+	`, accountID, runwayWindowStart(now)).Scan(&spend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account spend: %w", err)
+	}
+	return EstimateRunway(balance, spend, alertDays), nil
+}
+
+// QueueLowBalanceNotifications queues a balance.low delivery to the webhook
+// of every account whose runway has dropped below alertDays since it was
+// last notified, and re-arms accounts whose runway has recovered. Returns
+// how many deliveries were queued.
+func (db *DB) QueueLowBalanceNotifications(ctx context.Context, alertDays float64, now time.Time) (int, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT a.id, a.balance_usdc, a.low_balance_notified_at IS NOT NULL,
+			COALESCE((SELECT SUM(r.spend_usdc) FROM usage_rollups_hourly r
+				WHERE r.account_id = a.id AND r.hour >= $1), 0)::bigint
+		FROM accounts a
+		JOIN account_webhooks w ON w.account_id = a.id
+	`, runwayWindowStart(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get account runways: %w", err)
+	}
+	var notify []*LowBalanceEvent
+	var rearm []uuid.UUID
+	for rows.Next() {
+		var accountID uuid.UUID
+		var balance, spend usdc.MicroUSDC
+		var notified bool
+		if err := rows.Scan(&accountID, &balance, &notified, &spend); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan account runway: %w", err)
+		}
+		runway := EstimateRunway(balance, spend, alertDays)
+		switch {
+		case runway.LowBalance && !notified:
+			notify = append(notify, &LowBalanceEvent{
+				Event:          LowBalanceEventType,
+				AccountID:      accountID,
+				BalanceUSDC:    balance,
+				DailySpendUSDC: runway.DailySpendUSDC,
+				DaysRemaining:  *runway.DaysRemaining,
+				ThresholdDays:  alertDays,
+				DetectedAt:     now.UTC(),
+			})
+		case !runway.LowBalance && notified:
+			rearm = append(rearm, accountID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate account runways: %w", err)
+	}
+
+	queued := 0
+	for _, event := range notify {
+		// Claim the notification first, so concurrent sweeps queue it once
+		tag, err := tx.Exec(ctx, `
+			UPDATE accounts SET low_balance_notified_at = $2
+			WHERE id = $1 AND low_balance_notified_at IS NULL
+		`, event.AccountID, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record low balance notification: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode low balance event: %w", err)
+		}
+		if err := queueAccountEvent(ctx, tx, event.AccountID, LowBalanceEventType, payload); err != nil {
+			return 0, fmt.Errorf("failed to queue low balance webhook: %w", err)
+		}
+		queued++
+	}
+	if len(rearm) > 0 {
+		_, err = tx.Exec(ctx, `UPDATE accounts SET low_balance_notified_at = NULL WHERE id = ANY($1)`, rearm)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-arm low balance notifications: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return queued, nil
+}
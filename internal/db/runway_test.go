@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateRunway(t *testing.T) {
+	t.Run("no spend has no estimate", func(t *testing.T) {
+		runway := EstimateRunway(5_000_000, 0, 3)
+		assert.Nil(t, runway.DaysRemaining)
+		assert.False(t, runway.LowBalance)
+		assert.Equal(t, RunwayWindowDays, runway.WindowDays)
+	})
+
+	t.Run("divides by the daily average", func(t *testing.T) {
+		// 7 USDC over the week is 1 USDC a day
+		runway := EstimateRunway(5_000_000, 7_000_000, 3)
+		assert.Equal(t, usdc.MicroUSDC(1_000_000), runway.DailySpendUSDC)
+		require.NotNil(t, runway.DaysRemaining)
+		assert.Equal(t, 5.0, *runway.DaysRemaining)
+		assert.False(t, runway.LowBalance)
+	})
+
+	t.Run("below the threshold is low", func(t *testing.T) {
+		runway := EstimateRunway(2_500_000, 7_000_000, 3)
+		assert.Equal(t, 2.5, *runway.DaysRemaining)
+		assert.True(t, runway.LowBalance)
+		assert.False(t, EstimateRunway(2_500_000, 7_000_000, 0).LowBalance, "0 disables the flag")
+	})
+
+	t.Run("an empty balance has no days left", func(t *testing.T) {
+		runway := EstimateRunway(-100, 7_000_000, 3)
+		assert.Equal(t, 0.0, *runway.DaysRemaining)
+		assert.True(t, runway.LowBalance)
+	})
+}
+
+func TestQueueLowBalanceNotifications(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+	fixtures := NewFixtures(t, db)
+
+	low := fixtures.CreateTestAccount(nil).Account
+	fixtures.CreateCompletedDeposit(low.ID, 2_000_000)
+	healthy := fixtures.CreateTestAccount(nil).Account
+	fixtures.CreateCompletedDeposit(healthy.ID, 50_000_000)
+	for _, id := range []any{low.ID, healthy.ID} {
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO account_webhooks (account_id, url, secret) VALUES ($1, 'https://example.com/hook', 'whsec_test')
+		`, id)
+		require.NoError(t, err)
+		// 7 USDC spent over the week is 1 USDC a day
+		_, err = testDB.Pool.Exec(ctx, `
+			INSERT INTO usage_rollups_hourly (account_id, hour, spend_usdc) VALUES ($1, date_trunc('hour', NOW()), 7000000)
+		`, id)
+		require.NoError(t, err)
+	}
+
+	queued, err := db.QueueLowBalanceNotifications(ctx, 3, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+
+	var event, payload string
+	err = testDB.Pool.QueryRow(ctx, `
+		SELECT event, payload::text FROM account_webhook_deliveries WHERE account_id = $1
+	`, low.ID).Scan(&event, &payload)
+	require.NoError(t, err)
+	assert.Equal(t, LowBalanceEventType, event)
+	var body LowBalanceEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &body))
+	assert.Equal(t, 2.0, body.DaysRemaining)
+	assert.Equal(t, usdc.MicroUSDC(1_000_000), body.DailySpendUSDC)
+
+	// Notified once until the runway recovers
+	queued, err = db.QueueLowBalanceNotifications(ctx, 3, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, queued)
+
+	require.NoError(t, db.UpdateBalance(ctx, low.ID, usdc.MicroUSDC(10_000_000)))
+	_, err = db.QueueLowBalanceNotifications(ctx, 3, time.Now())
+	require.NoError(t, err)
+	var notified bool
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `
+		SELECT low_balance_notified_at IS NOT NULL FROM accounts WHERE id = $1
+	`, low.ID).Scan(&notified))
+	assert.False(t, notified, "re-armed after a top-up")
+}
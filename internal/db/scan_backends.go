@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ScanBackend is an account's detection backend selection. Name is empty
+// when the account uses the default backend.
+type ScanBackend struct {
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"` // Account's own endpoint, for the custom backend
+	Token string `json:"-"`             // Bearer token for the account's own endpoint
+}
+
+// GetAccountScanBackend returns the account's detection backend selection
+func (db *DB) GetAccountScanBackend(ctx context.Context, accountID uuid.UUID) (*ScanBackend, error) {
+	var name, url, token *string
+	err := db.pool.QueryRow(ctx, `
+		SELECT scan_backend, scan_backend_url, scan_backend_token FROM accounts WHERE id = $1
+	`, accountID).Scan(&name, &url, &token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account scan backend: %w", err)
+	}
+
+	backend := &ScanBackend{}
+	if name != nil {
+		backend.Name = *name
+	}
+	if url != nil {
+		backend.URL = *url
+	}
+	if token != nil {
+		backend.Token = *token
+	}
+	return backend, nil
+}
+
+// SetAccountScanBackend routes the account's scans to backend. An empty
+// name returns the account to the default backend.
+func (db *DB) SetAccountScanBackend(ctx context.Context, accountID uuid.UUID, backend ScanBackend) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET scan_backend = $1, scan_backend_url = $2, scan_backend_token = $3, updated_at = $4
+		WHERE id = $5
+	`, labelOrNull(backend.Name), labelOrNull(backend.URL), labelOrNull(backend.Token), time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to set account scan backend: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountScanBackend(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	backend, err := db.GetAccountScanBackend(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &ScanBackend{}, backend)
+
+	custom := ScanBackend{Name: "custom", URL: "https://detect.example.com", Token: "tok"}
+	require.NoError(t, db.SetAccountScanBackend(ctx, account.ID, custom))
+	backend, err = db.GetAccountScanBackend(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &custom, backend)
+
+	require.NoError(t, db.SetAccountScanBackend(ctx, account.ID, ScanBackend{}))
+	backend, err = db.GetAccountScanBackend(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Empty(t, backend.Name)
+	assert.Empty(t, backend.URL)
+
+	_, err = db.GetAccountScanBackend(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.ErrorIs(t, db.SetAccountScanBackend(ctx, uuid.New(), custom), ErrAccountNotFound)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScanSample is a scanned payload kept for replaying against new detection
+// versions. Text is stored with PII scrubbed. Samples of accounts with a
+// customer-managed key are stored sealed: Text, Scores and ThreatCategories
+// are empty until opened with that key.
+type ScanSample struct {
+	ID               uuid.UUID          `json:"id"`
+	AccountID        *uuid.UUID         `json:"account_id,omitempty"`
+	RequestID        string             `json:"request_id"`
+	Endpoint         string             `json:"endpoint"`
+	Text             string             `json:"text"`
+	Truncated        bool               `json:"truncated"`
+	SourceType       string             `json:"source_type,omitempty"`
+	ContentType      string             `json:"content_type,omitempty"`
+	Decision         string             `json:"decision"`
+	Scores           map[string]float64 `json:"scores"`
+	ThreatCategories []string           `json:"threat_categories"`
+	DetectionVersion string             `json:"detection_version,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+
+	EncryptionKeyARN string `json:"encryption_key_arn,omitempty"`
+	WrappedKey       []byte `json:"-"`
+	Ciphertext       []byte `json:"-"`
+}
+
+// Sealed reports whether the sample is stored under a customer-managed key
+func (s *ScanSample) Sealed() bool {
+	return s.EncryptionKeyARN != ""
+}
+
+// CreateScanSample stores a scan sample
+func (db *DB) CreateScanSample(ctx context.Context, sample *ScanSample) error {
+	sample.ID = uuid.New()
+	sample.CreatedAt = time.Now().UTC()
+	if sample.Scores == nil {
+		sample.Scores = map[string]float64{}
+	}
+	if sample.ThreatCategories == nil {
+		sample.ThreatCategories = []string{}
+	}
+
+	_, err := db.pool.Exec(ctx, `
+		INSERT INTO scan_samples (
+			id, request_id, endpoint, text, truncated, source_type, content_type,
+			decision, scores, threat_categories, detection_version, created_at,
+			account_id, encryption_key_arn, wrapped_key, ciphertext
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, NULLIF($11, ''), $12,
+			$13, NULLIF($14, ''), $15, $16)
+	`, sample.ID, sample.RequestID, sample.Endpoint, sample.Text, sample.Truncated,
+		sample.SourceType, sample.ContentType, sample.Decision, sample.Scores,
+		sample.ThreatCategories, sample.DetectionVersion, sample.CreatedAt,
+		sample.AccountID, sample.EncryptionKeyARN, sample.WrappedKey, sample.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to create scan sample: %w", err)
+	}
+	return nil
+}
+
+// ListScanSamples returns samples taken at or after since, oldest first. An
+// empty endpoint matches all endpoints.
+func (db *DB) ListScanSamples(ctx context.Context, endpoint string, since time.Time, limit int) ([]*ScanSample, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, request_id, endpoint, text, truncated, COALESCE(source_type, ''),
+			COALESCE(content_type, ''), decision, scores, threat_categories,
+			COALESCE(detection_version, ''), created_at,
+			account_id, COALESCE(encryption_key_arn, ''), wrapped_key, ciphertext
+		FROM scan_samples
+		WHERE created_at >= $1 AND ($2 = '' OR endpoint = $2)
+		ORDER BY created_at ASC
+		LIMIT $3
+	`, since, endpoint, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scan samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []*ScanSample
+	for rows.Next() {
+		s := &ScanSample{}
+		if err := rows.Scan(
+			&s.ID, &s.RequestID, &s.Endpoint, &s.Text, &s.Truncated, &s.SourceType,
+			&s.ContentType, &s.Decision, &s.Scores, &s.ThreatCategories,
+			&s.DetectionVersion, &s.CreatedAt,
+			&s.AccountID, &s.EncryptionKeyARN, &s.WrappedKey, &s.Ciphertext,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan sample: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate scan samples: %w", err)
+	}
+	return samples, nil
+}
+
+// CleanupOldScanSamples removes samples older than the given number of days
+func (db *DB) CleanupOldScanSamples(ctx context.Context, retentionDays int) (int64, error) {
+	result, err := db.pool.Exec(ctx, `
+		DELETE FROM scan_samples
+		WHERE created_at < NOW() - make_interval(days => $1)
+	`, retentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup old scan samples: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
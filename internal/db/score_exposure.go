@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Score exposure levels: how much of a scan's scoring API key responses reveal
+const (
+	ScoreExposureExact     = "exact"     // Scores as computed
+	ScoreExposureQuantized = "quantized" // Scores rounded to coarse steps
+	ScoreExposureOmitted   = "omitted"   // Decision and threat categories only
+)
+
+// PolicySourceAPIKey reports a setting made on the API key that made the scan
+const PolicySourceAPIKey = "api_key"
+
+// ErrInvalidScoreExposure is returned when a score exposure is not exact, quantized or omitted
+var ErrInvalidScoreExposure = errors.New("invalid score exposure")
+
+// ScoreExposure is how much scoring detail scan responses carry, and which
+// level set it
+type ScoreExposure struct {
+	Exposure string `json:"exposure"` // "exact", "quantized" or "omitted"
+	Source   string `json:"source"`   // "default", "account" or "api_key"
+}
+
+// IsValidScoreExposure reports whether exposure is a supported score exposure.
+func IsValidScoreExposure(exposure string) bool {
+	return exposure == ScoreExposureExact || exposure == ScoreExposureQuantized || exposure == ScoreExposureOmitted
+}
+
+// GetScoreExposure reads the account-level score exposure from the account's
+// metadata JSONB. Accounts that never set one get exact scores.
+func (db *DB) GetScoreExposure(ctx context.Context, accountID uuid.UUID) (*ScoreExposure, error) {
+	var exposure *string
+	err := db.QueryRow(ctx, `
+		SELECT metadata->>'score_exposure' FROM accounts WHERE id = $1
+	`, accountID).Scan(&exposure)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get score exposure: %w", err)
+	}
+	return resolveScoreExposure(nil, exposure), nil
+}
+
+// SetScoreExposure stores the account-level score exposure in the account's metadata JSONB.
+func (db *DB) SetScoreExposure(ctx context.Context, accountID uuid.UUID, exposure string) error {
+	if !IsValidScoreExposure(exposure) {
+		return ErrInvalidScoreExposure
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('score_exposure', $1::text),
+		    updated_at = $2
+		WHERE id = $3
+	`, exposure, time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update score exposure: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// SetAPIKeyScoreExposure overrides the score exposure for scans made with one
+// of the account's active keys. A nil exposure clears the override so the key
+// follows the account setting.
+func (db *DB) SetAPIKeyScoreExposure(ctx context.Context, keyID, accountID uuid.UUID, exposure *string) error {
+	if exposure != nil && !IsValidScoreExposure(*exposure) {
+		return ErrInvalidScoreExposure
+	}
+
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE api_keys SET score_exposure = $1
+		WHERE id = $2 AND account_id = $3 AND revoked_at IS NULL
+	`, exposure, keyID, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update API key score exposure: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// GetEffectiveScoreExposure resolves the score exposure for a scan made with
+// an account's API key. The key's override takes precedence over the
+// account's setting.
+func (db *DB) GetEffectiveScoreExposure(ctx context.Context, accountID, keyID uuid.UUID) (*ScoreExposure, error) {
+	var keyExposure, accountExposure *string
+	err := db.QueryRow(ctx, `
+		SELECT k.score_exposure, a.metadata->>'score_exposure'
+		FROM accounts a
+		LEFT JOIN api_keys k ON k.id = $2 AND k.account_id = a.id
+		WHERE a.id = $1
+	`, accountID, keyID).Scan(&keyExposure, &accountExposure)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get score exposure: %w", err)
+	}
+	return resolveScoreExposure(keyExposure, accountExposure), nil
+}
+
+// resolveScoreExposure picks the key's exposure, then the account's, then
+// exact scores. Unknown stored values are skipped.
+func resolveScoreExposure(key, account *string) *ScoreExposure {
+	switch {
+	case key != nil && IsValidScoreExposure(*key):
+		return &ScoreExposure{Exposure: *key, Source: PolicySourceAPIKey}
+	case account != nil && IsValidScoreExposure(*account):
+		return &ScoreExposure{Exposure: *account, Source: PolicySourceAccount}
+	}
+	return &ScoreExposure{Exposure: ScoreExposureExact, Source: PolicySourceDefault}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreExposure(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	key, err := db.CreateAPIKey(ctx, account.ID, "sk_live_abc", "hash-score-exposure", "ci", 10)
+	require.NoError(t, err)
+
+	exposure, err := db.GetEffectiveScoreExposure(ctx, account.ID, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &ScoreExposure{Exposure: ScoreExposureExact, Source: PolicySourceDefault}, exposure)
+
+	// The account setting applies to its keys
+	require.NoError(t, db.SetScoreExposure(ctx, account.ID, ScoreExposureQuantized))
+	exposure, err = db.GetEffectiveScoreExposure(ctx, account.ID, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &ScoreExposure{Exposure: ScoreExposureQuantized, Source: PolicySourceAccount}, exposure)
+
+	// A key's override wins, until cleared
+	omitted := ScoreExposureOmitted
+	require.NoError(t, db.SetAPIKeyScoreExposure(ctx, key.ID, account.ID, &omitted))
+	exposure, err = db.GetEffectiveScoreExposure(ctx, account.ID, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &ScoreExposure{Exposure: ScoreExposureOmitted, Source: PolicySourceAPIKey}, exposure)
+
+	keys, err := db.ListAPIKeys(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, &omitted, keys[0].ScoreExposure)
+
+	require.NoError(t, db.SetAPIKeyScoreExposure(ctx, key.ID, account.ID, nil))
+	exposure, err = db.GetEffectiveScoreExposure(ctx, account.ID, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScoreExposureQuantized, exposure.Exposure)
+
+	// Keys of other accounts can't be changed
+	other, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.SetAPIKeyScoreExposure(ctx, key.ID, other.ID, &omitted), ErrAPIKeyNotFound)
+	assert.ErrorIs(t, db.SetAPIKeyScoreExposure(ctx, uuid.New(), account.ID, nil), ErrAPIKeyNotFound)
+
+	bogus := "noisy"
+	assert.ErrorIs(t, db.SetScoreExposure(ctx, account.ID, bogus), ErrInvalidScoreExposure)
+	assert.ErrorIs(t, db.SetAPIKeyScoreExposure(ctx, key.ID, account.ID, &bogus), ErrInvalidScoreExposure)
+}
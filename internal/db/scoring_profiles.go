@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ScoringModeDefault is the profile mode used for scanning modes without
+// their own profile
+const ScoringModeDefault = "default"
+
+// ErrScoringProfileNotFound is returned when an account has no scoring
+// profile for a mode
+var ErrScoringProfileNotFound = errors.New("scoring profile not found")
+
+// ScoringProfile is an account's layer weights and threshold curve for a
+// scanning mode. Profile is the JSON-encoded stronghold.ScoringProfile.
+type ScoringProfile struct {
+	AccountID uuid.UUID       `json:"account_id"`
+	Mode      string          `json:"mode"`
+	Profile   json.RawMessage `json:"profile"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// GetScoringProfile returns the profile applied to the account's scans in
+// mode: the mode's own profile, otherwise the account's default profile
+func (db *DB) GetScoringProfile(ctx context.Context, accountID uuid.UUID, mode string) (*ScoringProfile, error) {
+	p := &ScoringProfile{AccountID: accountID}
+	err := db.pool.QueryRow(ctx, `
+		SELECT mode, profile, updated_at
+		FROM scoring_profiles
+		WHERE account_id = $1 AND mode IN ($2, $3)
+		ORDER BY mode = $3
+		LIMIT 1
+	`, accountID, mode, ScoringModeDefault).Scan(&p.Mode, &p.Profile, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrScoringProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scoring profile: %w", err)
+	}
+	return p, nil
+}
+
+// ListScoringProfiles returns the account's scoring profiles by mode
+func (db *DB) ListScoringProfiles(ctx context.Context, accountID uuid.UUID) ([]*ScoringProfile, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT mode, profile, updated_at
+		FROM scoring_profiles
+		WHERE account_id = $1
+		ORDER BY mode
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scoring profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []*ScoringProfile{}
+	for rows.Next() {
+		p := &ScoringProfile{AccountID: accountID}
+		if err := rows.Scan(&p.Mode, &p.Profile, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scoring profile: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate scoring profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// SetScoringProfile creates or replaces the account's profile for mode
+func (db *DB) SetScoringProfile(ctx context.Context, accountID uuid.UUID, mode string, profile json.RawMessage) error {
+	now := time.Now().UTC()
+	_, err := db.pool.Exec(ctx, `
+		INSERT INTO scoring_profiles (account_id, mode, profile, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (account_id, mode) DO UPDATE SET profile = EXCLUDED.profile, updated_at = EXCLUDED.updated_at
+	`, accountID, mode, profile, now)
+	if err != nil {
+		return fmt.Errorf("failed to set scoring profile: %w", err)
+	}
+	return nil
+}
+
+// DeleteScoringProfile removes the account's profile for mode
+func (db *DB) DeleteScoringProfile(ctx context.Context, accountID uuid.UUID, mode string) error {
+	result, err := db.pool.Exec(ctx, `
+		DELETE FROM scoring_profiles WHERE account_id = $1 AND mode = $2
+	`, accountID, mode)
+	if err != nil {
+		return fmt.Errorf("failed to delete scoring profile: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrScoringProfileNotFound
+	}
+	return nil
+}
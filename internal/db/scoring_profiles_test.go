@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoringProfiles(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+
+	_, err = db.GetScoringProfile(ctx, account.ID, "strict")
+	assert.ErrorIs(t, err, ErrScoringProfileNotFound)
+
+	defaults := json.RawMessage(`{"weights": {"heuristic": 1}}`)
+	strict := json.RawMessage(`{"weights": {"heuristic": 1, "ml": 2}}`)
+	require.NoError(t, db.SetScoringProfile(ctx, account.ID, ScoringModeDefault, defaults))
+	require.NoError(t, db.SetScoringProfile(ctx, account.ID, "strict", strict))
+
+	// A mode's own profile wins over the default
+	p, err := db.GetScoringProfile(ctx, account.ID, "strict")
+	require.NoError(t, err)
+	assert.Equal(t, "strict", p.Mode)
+	assert.JSONEq(t, string(strict), string(p.Profile))
+
+	p, err = db.GetScoringProfile(ctx, account.ID, "permissive")
+	require.NoError(t, err)
+	assert.Equal(t, ScoringModeDefault, p.Mode)
+
+	profiles, err := db.ListScoringProfiles(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, ScoringModeDefault, profiles[0].Mode)
+
+	require.NoError(t, db.DeleteScoringProfile(ctx, account.ID, "strict"))
+	assert.ErrorIs(t, db.DeleteScoringProfile(ctx, account.ID, "strict"), ErrScoringProfileNotFound)
+	p, err = db.GetScoringProfile(ctx, account.ID, "strict")
+	require.NoError(t, err)
+	assert.Equal(t, ScoringModeDefault, p.Mode)
+}
@@ -23,6 +23,7 @@ type Session struct {
 	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
 	IPAddress        *net.IP    `json:"ip_address,omitempty"`
 	UserAgent        *string    `json:"user_agent,omitempty"`
+	DeviceTokenHash  *string    `json:"-"` // Trusted device the session is bound to
 }
 
 // GenerateRefreshToken creates a cryptographically secure refresh token
@@ -83,13 +84,13 @@ func (db *DB) GetSessionByRefreshToken(ctx context.Context, refreshToken string)
 
 	session := &Session{}
 	err := db.QueryRow(ctx, `
-		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent
+		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent, device_token_hash
 		FROM sessions
 		WHERE refresh_token_hash = $1
 	`, tokenHash).Scan(
 		&session.ID, &session.AccountID, &session.RefreshTokenHash,
 		&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt,
-		&session.IPAddress, &session.UserAgent,
+		&session.IPAddress, &session.UserAgent, &session.DeviceTokenHash,
 	)
 
 	if err != nil {
@@ -123,6 +124,22 @@ func (db *DB) UpdateSessionLastUsed(ctx context.Context, sessionID uuid.UUID) er
 	return nil
 }
 
+// BindSessionDevice binds a session to a trusted device token. A session
+// already bound to a device keeps its binding.
+func (db *DB) BindSessionDevice(ctx context.Context, sessionID uuid.UUID, deviceToken string) error {
+	_, err := db.pool.Exec(ctx, `
+		UPDATE sessions
+		SET device_token_hash = $1
+		WHERE id = $2 AND device_token_hash IS NULL
+	`, HashToken(deviceToken), sessionID)
+
+	if err != nil {
+		return fmt.Errorf("failed to bind session device: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteSession deletes a session by its ID
 func (db *DB) DeleteSession(ctx context.Context, sessionID uuid.UUID) error {
 	_, err := db.pool.Exec(ctx, `
@@ -169,7 +186,7 @@ func (db *DB) DeleteAllAccountSessions(ctx context.Context, accountID uuid.UUID)
 // GetAccountSessions retrieves all active sessions for an account
 func (db *DB) GetAccountSessions(ctx context.Context, accountID uuid.UUID) ([]*Session, error) {
 	rows, err := db.pool.Query(ctx, `
-		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent
+		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent, device_token_hash
 		FROM sessions
 		WHERE account_id = $1 AND expires_at > $2
 		ORDER BY last_used_at DESC
@@ -186,7 +203,7 @@ func (db *DB) GetAccountSessions(ctx context.Context, accountID uuid.UUID) ([]*S
 		err := rows.Scan(
 			&session.ID, &session.AccountID, &session.RefreshTokenHash,
 			&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt,
-			&session.IPAddress, &session.UserAgent,
+			&session.IPAddress, &session.UserAgent, &session.DeviceTokenHash,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
@@ -229,14 +246,14 @@ func (db *DB) RotateRefreshToken(ctx context.Context, oldRefreshToken string, du
 	// SELECT ... FOR UPDATE to lock the session row and prevent concurrent rotation
 	session := &Session{}
 	err = tx.QueryRow(ctx, `
-		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent
+		SELECT id, account_id, refresh_token_hash, expires_at, created_at, last_used_at, ip_address, user_agent, device_token_hash
 		FROM sessions
 		WHERE refresh_token_hash = $1
 		FOR UPDATE
 	`, oldTokenHash).Scan(
 		&session.ID, &session.AccountID, &session.RefreshTokenHash,
 		&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt,
-		&session.IPAddress, &session.UserAgent,
+		&session.IPAddress, &session.UserAgent, &session.DeviceTokenHash,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
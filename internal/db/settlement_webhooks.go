@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// SettlementEventType is the event name of settlement webhook payloads
+const SettlementEventType = "payment.settled"
+
+// ErrSettlementWebhookNotFound is returned when an account has no settlement webhook
+var ErrSettlementWebhookNotFound = errors.New("settlement webhook not found")
+
+// SettlementWebhook is an account's settlement notification endpoint
+type SettlementWebhook struct {
+	AccountID uuid.UUID `json:"account_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"` // HMAC key, returned only when the webhook is set
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SettlementEvent is the body of a settlement webhook delivery
+type SettlementEvent struct {
+	Event           string         `json:"event"` // always "payment.settled"
+	PaymentID       uuid.UUID      `json:"payment_id"`
+	Nonce           string         `json:"nonce"`
+	TxHash          string         `json:"tx_hash"`
+	Network         string         `json:"network"`
+	Payer           string         `json:"payer"`
+	Receiver        string         `json:"receiver"`
+	Endpoint        string         `json:"endpoint"`
+	AmountUSDC      usdc.MicroUSDC `json:"amount_usdc"`
+	OverpaymentUSDC usdc.MicroUSDC `json:"overpayment_usdc"`
+	SettledAt       time.Time      `json:"settled_at"`
+}
+
+// WebhookDelivery is a queued account webhook event with its destination
+type WebhookDelivery struct {
+	ID        uuid.UUID
+	AccountID uuid.UUID
+	Event     string // Stronghold-Event header value
+	URL       string
+	Secret    string
+	Payload   []byte
+	Attempts  int // including the one in progress
+}
+
+// generateWebhookSecret creates a random HMAC key for signing deliveries
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// SetSettlementWebhook creates or updates an account's settlement webhook.
+// A new webhook gets a fresh signing secret; changing the URL keeps it.
+func (db *DB) SetSettlementWebhook(ctx context.Context, accountID uuid.UUID, url string) (*SettlementWebhook, error) {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SettlementWebhook{}
+	err = db.QueryRow(ctx, `
+		INSERT INTO account_webhooks (account_id, url, secret)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id) DO UPDATE SET url = EXCLUDED.url, updated_at = NOW()
+		RETURNING account_id, url, secret, created_at, updated_at
+	`, accountID, url, secret).Scan(&w.AccountID, &w.URL, &w.Secret, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set settlement webhook: %w", err)
+	}
+	return w, nil
+}
+
+// GetSettlementWebhook returns an account's settlement webhook
+func (db *DB) GetSettlementWebhook(ctx context.Context, accountID uuid.UUID) (*SettlementWebhook, error) {
+	w := &SettlementWebhook{}
+	err := db.QueryRow(ctx, `
+		SELECT account_id, url, secret, created_at, updated_at
+		FROM account_webhooks WHERE account_id = $1
+	`, accountID).Scan(&w.AccountID, &w.URL, &w.Secret, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSettlementWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settlement webhook: %w", err)
+	}
+	return w, nil
+}
+
+// DeleteSettlementWebhook removes an account's settlement webhook and its
+// undelivered notifications. Returns false if there was none.
+func (db *DB) DeleteSettlementWebhook(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	result, err := db.ExecResult(ctx, `DELETE FROM account_webhooks WHERE account_id = $1`, accountID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete settlement webhook: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// queueSettlementEvent queues a delivery to the webhook of the account linked
+// to the payer's wallet, if it has one. Called in the transaction that
+// completes the payment, so a settled payment is always notified once.
+func queueSettlementEvent(ctx context.Context, tx pgx.Tx, event *SettlementEvent) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO account_webhook_deliveries (account_id, payment_transaction_id, payload)
+		SELECT w.account_id, $2, $3
+		FROM account_webhooks w
+		JOIN accounts a ON a.id = w.account_id
+		WHERE LOWER(a.evm_wallet_address) = LOWER($1) OR a.solana_wallet_address = $1
+		ON CONFLICT (account_id, payment_transaction_id) DO NOTHING
+	`, event.Payer, event.PaymentID, event)
+	if err != nil {
+		return fmt.Errorf("failed to queue settlement webhook: %w", err)
+	}
+	return nil
+}
+
+// queueAccountEvent queues an event for the account's webhook, if it has
+// one. Called in the transaction that produces the event.
+func queueAccountEvent(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, event string, payload []byte) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO account_webhook_deliveries (account_id, event, payload)
+		SELECT account_id, $2, $3 FROM account_webhooks WHERE account_id = $1
+	`, accountID, event, payload)
+	return err
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries that are due,
+// counting the attempt and deferring the next one by lease so other
+// instances don't send them concurrently.
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	rows, err := db.Query(ctx, `
+		WITH claimed AS (
+			UPDATE account_webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+			WHERE id IN (
+				SELECT id FROM account_webhook_deliveries
+				WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, account_id, event, payload, attempts
+		)
+		SELECT c.id, c.account_id, c.event, w.url, w.secret, c.payload, c.attempts
+		FROM claimed c
+		JOIN account_webhooks w ON w.account_id = c.account_id
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d := &WebhookDelivery{}
+		if err := rows.Scan(&d.ID, &d.AccountID, &d.Event, &d.URL, &d.Secret, &d.Payload, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// MarkWebhookDelivered records a successful delivery
+func (db *DB) MarkWebhookDelivered(ctx context.Context, id uuid.UUID) error {
+	err := db.Exec(ctx, `
+		UPDATE account_webhook_deliveries SET delivered_at = NOW(), last_error = NULL WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivered: %w", err)
+	}
+	return nil
+}
+
+// FailWebhookDelivery records a failed delivery attempt. A nil retryAt
+// gives up on the delivery.
+func (db *DB) FailWebhookDelivery(ctx context.Context, id uuid.UUID, errorMsg string, retryAt *time.Time) error {
+	err := db.Exec(ctx, `
+		UPDATE account_webhook_deliveries
+		SET last_error = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			failed_at = CASE WHEN $3::timestamptz IS NULL THEN NOW() END
+		WHERE id = $1
+	`, id, errorMsg, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return nil
+}
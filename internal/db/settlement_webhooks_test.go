@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+)
+
+// TestSettlementWebhookQueuedOnSettlement tests that completing a payment
+// queues a delivery to the payer account's webhook
+func TestSettlementWebhookQueuedOnSettlement(t *testing.T) {
+	pool := getTestPool(t)
+	if pool == nil {
+		t.Skip("No database connection available")
+	}
+	db := &DB{pool: pool}
+	ctx := context.Background()
+
+	payer := "0xAbCdEf" + uuid.New().String()[:8] + "00000000000000000000000000"
+	account, err := db.CreateAccount(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	defer func() {
+		_, _ = db.pool.Exec(ctx, "DELETE FROM accounts WHERE id = $1", account.ID)
+	}()
+	if err := db.LinkEVMWallet(ctx, account.ID, strings.ToLower(payer)); err != nil {
+		t.Fatalf("Failed to link wallet: %v", err)
+	}
+
+	webhook, err := db.SetSettlementWebhook(ctx, account.ID, "https://example.com/hooks")
+	if err != nil {
+		t.Fatalf("Failed to set webhook: %v", err)
+	}
+	if !strings.HasPrefix(webhook.Secret, "whsec_") {
+		t.Errorf("Expected whsec_ secret, got %q", webhook.Secret)
+	}
+	// Changing the URL keeps the secret
+	updated, err := db.SetSettlementWebhook(ctx, account.ID, "https://example.com/hooks/v2")
+	if err != nil {
+		t.Fatalf("Failed to update webhook: %v", err)
+	}
+	if updated.Secret != webhook.Secret || updated.URL != "https://example.com/hooks/v2" {
+		t.Errorf("Unexpected updated webhook: %+v", updated)
+	}
+
+	tx := &PaymentTransaction{
+		PaymentNonce:    "webhook-" + uuid.New().String(),
+		PaymentHeader:   "x402;test-header",
+		PayerAddress:    payer,
+		ReceiverAddress: "0x0987654321098765432109876543210987654321",
+		Endpoint:        "/v1/scan/content",
+		AmountUSDC:      usdc.MicroUSDC(1000),
+		Network:         "base-sepolia",
+		ExpiresAt:       time.Now().Add(5 * time.Minute),
+	}
+	if err := db.CreatePaymentTransaction(ctx, tx); err != nil {
+		t.Fatalf("Failed to create payment: %v", err)
+	}
+	defer func() {
+		_, _ = db.pool.Exec(ctx, "DELETE FROM payment_transactions WHERE id = $1", tx.ID)
+	}()
+	if err := db.TransitionStatus(ctx, tx.ID, PaymentStatusReserved, PaymentStatusSettling); err != nil {
+		t.Fatalf("Failed to transition to settling: %v", err)
+	}
+	if err := db.CompleteSettlement(ctx, tx.ID, "0xdeadbeef"); err != nil {
+		t.Fatalf("Failed to complete settlement: %v", err)
+	}
+
+	deliveries, err := db.ClaimWebhookDeliveries(ctx, 100, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim deliveries: %v", err)
+	}
+	var delivery *WebhookDelivery
+	for _, d := range deliveries {
+		if d.AccountID == account.ID {
+			delivery = d
+		}
+	}
+	if delivery == nil {
+		t.Fatal("Expected a delivery for the payer's account")
+	}
+	if delivery.URL != "https://example.com/hooks/v2" || delivery.Secret != webhook.Secret || delivery.Attempts != 1 {
+		t.Errorf("Unexpected delivery: %+v", delivery)
+	}
+
+	var event SettlementEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if event.Event != SettlementEventType || event.Nonce != tx.PaymentNonce || event.TxHash != "0xdeadbeef" ||
+		event.AmountUSDC != 1000 || event.Endpoint != "/v1/scan/content" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	// A claimed delivery is leased and not handed out again
+	again, err := db.ClaimWebhookDeliveries(ctx, 100, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim deliveries: %v", err)
+	}
+	for _, d := range again {
+		if d.ID == delivery.ID {
+			t.Error("Expected leased delivery not to be claimed again")
+		}
+	}
+
+	retryAt := time.Now().Add(-time.Second)
+	if err := db.FailWebhookDelivery(ctx, delivery.ID, "webhook returned 500", &retryAt); err != nil {
+		t.Fatalf("Failed to record failure: %v", err)
+	}
+	if err := db.MarkWebhookDelivered(ctx, delivery.ID); err != nil {
+		t.Fatalf("Failed to mark delivered: %v", err)
+	}
+
+	deleted, err := db.DeleteSettlementWebhook(ctx, account.ID)
+	if err != nil || !deleted {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if _, err := db.GetSettlementWebhook(ctx, account.ID); err != ErrSettlementWebhookNotFound {
+		t.Errorf("Expected ErrSettlementWebhookNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrSubAccountNotFound is returned when no open sub-account matches
+	ErrSubAccountNotFound = errors.New("sub-account not found")
+	// ErrSubAccountLimitReached is returned when the account already has as
+	// many open sub-accounts as it may
+	ErrSubAccountLimitReached = errors.New("sub-account limit reached")
+	// ErrSubAccountNameTaken is returned when another open sub-account of the
+	// account has the same name
+	ErrSubAccountNameTaken = errors.New("sub-account name already in use")
+	// ErrSubAccountBudgetExceeded is returned when a charge would take the
+	// sub-account past its budget
+	ErrSubAccountBudgetExceeded = errors.New("sub-account budget exceeded")
+)
+
+// SubAccount is a per-project or per-agent slice of an account. Its API
+// keys spend the parent account's balance, up to the budget when one is set.
+type SubAccount struct {
+	ID         uuid.UUID       `json:"id"`
+	AccountID  uuid.UUID       `json:"account_id"`
+	Name       string          `json:"name"`
+	BudgetUSDC *usdc.MicroUSDC `json:"budget_usdc,omitempty"` // nil means no cap beyond the parent's balance
+	SpentUSDC  usdc.MicroUSDC  `json:"spent_usdc"`
+	ClosedAt   *time.Time      `json:"closed_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// RemainingUSDC is what the sub-account may still spend, or nil without a
+// budget
+func (s *SubAccount) RemainingUSDC() *usdc.MicroUSDC {
+	if s.BudgetUSDC == nil {
+		return nil
+	}
+	remaining := max(*s.BudgetUSDC-s.SpentUSDC, 0)
+	return &remaining
+}
+
+// CanSpend reports whether amount fits in the sub-account's budget
+func (s *SubAccount) CanSpend(amount usdc.MicroUSDC) bool {
+	return s.BudgetUSDC == nil || s.SpentUSDC+amount <= *s.BudgetUSDC
+}
+
+const subAccountColumns = `id, account_id, name, budget_usdc, spent_usdc, closed_at, created_at, updated_at`
+
+func scanSubAccount(row pgx.Row) (*SubAccount, error) {
+	s := &SubAccount{}
+	err := row.Scan(&s.ID, &s.AccountID, &s.Name, &s.BudgetUSDC, &s.SpentUSDC,
+		&s.ClosedAt, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSubAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan sub-account: %w", err)
+	}
+	return s, nil
+}
+
+// CreateSubAccount creates an open sub-account under accountID. The account
+// row is locked so concurrent requests can't exceed maxOpen.
+func (db *DB) CreateSubAccount(ctx context.Context, accountID uuid.UUID, name string, budget *usdc.MicroUSDC, maxOpen int) (*SubAccount, error) {
+	now := time.Now().UTC()
+	s := &SubAccount{
+		ID:         uuid.New(),
+		AccountID:  accountID,
+		Name:       name,
+		BudgetUSDC: budget,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lockedID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, accountID).Scan(&lockedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	var open int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM sub_accounts WHERE account_id = $1 AND closed_at IS NULL
+	`, accountID).Scan(&open); err != nil {
+		return nil, fmt.Errorf("failed to count sub-accounts: %w", err)
+	}
+	if open >= maxOpen {
+		return nil, ErrSubAccountLimitReached
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO sub_accounts (id, account_id, name, budget_usdc, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, s.ID, s.AccountID, s.Name, s.BudgetUSDC, s.CreatedAt, s.UpdatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrSubAccountNameTaken
+		}
+		return nil, fmt.Errorf("failed to create sub-account: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s, nil
+}
+
+// GetSubAccount returns an open sub-account of accountID
+func (db *DB) GetSubAccount(ctx context.Context, accountID, id uuid.UUID) (*SubAccount, error) {
+	return scanSubAccount(db.QueryRow(ctx, `
+		SELECT `+subAccountColumns+` FROM sub_accounts
+		WHERE id = $1 AND account_id = $2 AND closed_at IS NULL
+	`, id, accountID))
+}
+
+// ListSubAccounts returns the account's open sub-accounts by name
+func (db *DB) ListSubAccounts(ctx context.Context, accountID uuid.UUID) ([]*SubAccount, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT `+subAccountColumns+` FROM sub_accounts
+		WHERE account_id = $1 AND closed_at IS NULL
+		ORDER BY LOWER(name), id
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sub-accounts: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*SubAccount{}
+	for rows.Next() {
+		s, err := scanSubAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sub-accounts: %w", err)
+	}
+	return subs, nil
+}
+
+// SetSubAccountBudget replaces an open sub-account's budget. nil removes
+// the cap. Spending so far is kept, so a budget below it stops the
+// sub-account until raised.
+func (db *DB) SetSubAccountBudget(ctx context.Context, accountID, id uuid.UUID, budget *usdc.MicroUSDC) (*SubAccount, error) {
+	return scanSubAccount(db.QueryRow(ctx, `
+		UPDATE sub_accounts SET budget_usdc = $1, updated_at = $2
+		WHERE id = $3 AND account_id = $4 AND closed_at IS NULL
+		RETURNING `+subAccountColumns,
+		budget, time.Now().UTC(), id, accountID))
+}
+
+// CloseSubAccount closes an open sub-account and revokes its API keys. Its
+// usage stays attributed to it.
+func (db *DB) CloseSubAccount(ctx context.Context, accountID, id uuid.UUID) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	tag, err := tx.Exec(ctx, `
+		UPDATE sub_accounts SET closed_at = $1, updated_at = $1
+		WHERE id = $2 AND account_id = $3 AND closed_at IS NULL
+	`, now, id, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to close sub-account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSubAccountNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = $1
+		WHERE sub_account_id = $2 AND revoked_at IS NULL
+	`, now, id); err != nil {
+		return fmt.Errorf("failed to revoke sub-account API keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ChargeSubAccount deducts amount from the parent account's balance and
+// counts it against the sub-account's budget, atomically. It returns false
+// when the parent's balance is insufficient, and ErrSubAccountBudgetExceeded
+// when the budget is.
+func (db *DB) ChargeSubAccount(ctx context.Context, subAccountID uuid.UUID, amount usdc.MicroUSDC) (bool, error) {
+	if amount <= 0 {
+		return false, errors.New("amount must be positive")
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the sub-account so concurrent charges see each other's spending
+	s, err := scanSubAccount(tx.QueryRow(ctx, `
+		SELECT `+subAccountColumns+` FROM sub_accounts
+		WHERE id = $1 AND closed_at IS NULL
+		FOR UPDATE
+	`, subAccountID))
+	if err != nil {
+		return false, err
+	}
+	if !s.CanSpend(amount) {
+		return false, ErrSubAccountBudgetExceeded
+	}
+
+	now := time.Now().UTC()
+	tag, err := tx.Exec(ctx, `
+		UPDATE accounts SET balance_usdc = balance_usdc - $1, updated_at = $2
+		WHERE id = $3 AND balance_usdc >= $1
+	`, amount, now, s.AccountID)
+	if err != nil {
+		return false, fmt.Errorf("failed to deduct balance: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE sub_accounts SET spent_usdc = spent_usdc + $1, updated_at = $2 WHERE id = $3
+	`, amount, now, subAccountID); err != nil {
+		return false, fmt.Errorf("failed to record sub-account spending: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RecordSubAccountSpend counts amount billed some other way, such as
+// metered billing, against the sub-account's budget
+func (db *DB) RecordSubAccountSpend(ctx context.Context, subAccountID uuid.UUID, amount usdc.MicroUSDC) error {
+	if _, err := db.pool.Exec(ctx, `
+		UPDATE sub_accounts SET spent_usdc = spent_usdc + $1, updated_at = $2 WHERE id = $3
+	`, amount, time.Now().UTC(), subAccountID); err != nil {
+		return fmt.Errorf("failed to record sub-account spending: %w", err)
+	}
+	return nil
+}
+
+// SubAccountUsage is one sub-account's usage over a window
+type SubAccountUsage struct {
+	SubAccountID uuid.UUID  `json:"sub_account_id"`
+	Name         string     `json:"name"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	UsageAggregate
+}
+
+// GetSubAccountUsage aggregates scans, blocks and spend by sub-account for
+// the account between start and end. Closed sub-accounts are included when
+// they have usage in the window.
+func (db *DB) GetSubAccountUsage(ctx context.Context, accountID uuid.UUID, start, end time.Time) ([]*SubAccountUsage, error) {
+	rows, err := db.pool.Query(ctx, `
+		WITH sub_usage AS (
+			SELECT NULLIF(metadata->>'sub_account_id', '')::uuid AS sub_account_id,
+			       (metadata ? 'auth_method') AS is_scan,
+			       threat_detected,
+			       metadata->>'decision' = 'BLOCK' AS blocked,
+			       COALESCE((metadata->>'actual_cost')::bigint, 0) AS spend
+			FROM usage_logs
+			WHERE account_id = $1 AND metadata ? 'sub_account_id'
+			  AND created_at >= $2 AND created_at <= $3
+		)
+		SELECT s.id, s.name, s.closed_at,
+		       COUNT(u.sub_account_id) FILTER (WHERE u.is_scan),
+		       COUNT(u.sub_account_id) FILTER (WHERE u.is_scan AND u.blocked),
+		       COUNT(u.sub_account_id) FILTER (WHERE u.is_scan AND u.threat_detected),
+		       COALESCE(SUM(u.spend), 0)
+		FROM sub_accounts s
+		LEFT JOIN sub_usage u ON u.sub_account_id = s.id
+		WHERE s.account_id = $1
+		GROUP BY s.id, s.name, s.closed_at
+		HAVING s.closed_at IS NULL OR COUNT(u.sub_account_id) > 0
+		ORDER BY 7 DESC, LOWER(s.name)
+	`, accountID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sub-account usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []*SubAccountUsage{}
+	for rows.Next() {
+		u := &SubAccountUsage{}
+		if err := rows.Scan(append([]any{&u.SubAccountID, &u.Name, &u.ClosedAt}, u.scanDest()...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan sub-account usage: %w", err)
+		}
+		u.computeBlockRate()
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sub-account usage: %w", err)
+	}
+	return usage, nil
+}
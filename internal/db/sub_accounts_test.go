@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubAccountLifecycle(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account := createTestB2BAccount(t, db, "sub-lifecycle@example.com")
+	other := createTestB2BAccount(t, db, "sub-lifecycle-other@example.com")
+
+	budget := usdc.MicroUSDC(5_000_000)
+	sub, err := db.CreateSubAccount(ctx, account.ID, "Research", &budget, 2)
+	require.NoError(t, err)
+	assert.Equal(t, &budget, sub.BudgetUSDC)
+
+	// Names are unique per account, ignoring case
+	_, err = db.CreateSubAccount(ctx, account.ID, "research", nil, 2)
+	assert.ErrorIs(t, err, ErrSubAccountNameTaken)
+	_, err = db.CreateSubAccount(ctx, other.ID, "Research", nil, 2)
+	require.NoError(t, err)
+
+	_, err = db.CreateSubAccount(ctx, account.ID, "Support", nil, 2)
+	require.NoError(t, err)
+	_, err = db.CreateSubAccount(ctx, account.ID, "Billing", nil, 2)
+	assert.ErrorIs(t, err, ErrSubAccountLimitReached)
+
+	subs, err := db.ListSubAccounts(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+	assert.Equal(t, "Research", subs[0].Name)
+	assert.Equal(t, "Support", subs[1].Name)
+
+	// Other accounts can't see or change the sub-account
+	_, err = db.GetSubAccount(ctx, other.ID, sub.ID)
+	assert.ErrorIs(t, err, ErrSubAccountNotFound)
+	_, err = db.SetSubAccountBudget(ctx, other.ID, sub.ID, nil)
+	assert.ErrorIs(t, err, ErrSubAccountNotFound)
+
+	updated, err := db.SetSubAccountBudget(ctx, account.ID, sub.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, updated.BudgetUSDC)
+
+	// Closing revokes the sub-account's keys but not the account's own
+	_, err = db.CreateSubAccountAPIKey(ctx, account.ID, sub.ID, "sk_live_sub1", testHash("sub1"), "Agent", 10)
+	require.NoError(t, err)
+	_, err = db.CreateAPIKey(ctx, account.ID, "sk_live_own1", testHash("own1"), "Own", 10)
+	require.NoError(t, err)
+
+	require.NoError(t, db.CloseSubAccount(ctx, account.ID, sub.ID))
+	assert.ErrorIs(t, db.CloseSubAccount(ctx, account.ID, sub.ID), ErrSubAccountNotFound)
+	_, err = db.GetSubAccount(ctx, account.ID, sub.ID)
+	assert.ErrorIs(t, err, ErrSubAccountNotFound)
+
+	keys, err := db.ListAPIKeys(ctx, account.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "Own", keys[0].Name)
+
+	// A closed sub-account's name can be reused
+	_, err = db.CreateSubAccount(ctx, account.ID, "Research", nil, 2)
+	require.NoError(t, err)
+}
+
+func TestCreateSubAccountAPIKey(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account := createTestB2BAccount(t, db, "sub-keys@example.com")
+	other := createTestB2BAccount(t, db, "sub-keys-other@example.com")
+
+	sub, err := db.CreateSubAccount(ctx, account.ID, "Agent", nil, 10)
+	require.NoError(t, err)
+
+	key, err := db.CreateSubAccountAPIKey(ctx, account.ID, sub.ID, "sk_live_sk01", testHash("sk01"), "Agent key", 1)
+	require.NoError(t, err)
+	require.NotNil(t, key.SubAccountID)
+	assert.Equal(t, sub.ID, *key.SubAccountID)
+
+	got, err := db.GetAPIKeyByHash(ctx, testHash("sk01"))
+	require.NoError(t, err)
+	require.NotNil(t, got.SubAccountID)
+	assert.Equal(t, sub.ID, *got.SubAccountID)
+
+	// The key limit applies to the sub-account and the account separately
+	_, err = db.CreateSubAccountAPIKey(ctx, account.ID, sub.ID, "sk_live_sk02", testHash("sk02"), "Second", 1)
+	assert.ErrorIs(t, err, ErrAPIKeyLimitReached)
+	_, err = db.CreateAPIKey(ctx, account.ID, "sk_live_sk03", testHash("sk03"), "Own", 1)
+	require.NoError(t, err)
+
+	_, err = db.CreateSubAccountAPIKey(ctx, other.ID, sub.ID, "sk_live_sk04", testHash("sk04"), "Stolen", 10)
+	assert.ErrorIs(t, err, ErrSubAccountNotFound)
+	_, err = db.CreateSubAccountAPIKey(ctx, account.ID, uuid.New(), "sk_live_sk05", testHash("sk05"), "Missing", 10)
+	assert.ErrorIs(t, err, ErrSubAccountNotFound)
+}
+
+func TestChargeSubAccount(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account := createTestB2BAccount(t, db, "sub-charge@example.com")
+	_, err := db.pool.Exec(ctx, `UPDATE accounts SET balance_usdc = 3000 WHERE id = $1`, account.ID)
+	require.NoError(t, err)
+
+	budget := usdc.MicroUSDC(2000)
+	sub, err := db.CreateSubAccount(ctx, account.ID, "Agent", &budget, 10)
+	require.NoError(t, err)
+
+	ok, err := db.ChargeSubAccount(ctx, sub.ID, 1500)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The budget stops the sub-account before the parent's balance does
+	_, err = db.ChargeSubAccount(ctx, sub.ID, 1000)
+	assert.ErrorIs(t, err, ErrSubAccountBudgetExceeded)
+
+	got, err := db.GetAccountByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(1500), got.BalanceUSDC)
+
+	// Without a budget, only the parent's balance limits it
+	_, err = db.SetSubAccountBudget(ctx, account.ID, sub.ID, nil)
+	require.NoError(t, err)
+	ok, err = db.ChargeSubAccount(ctx, sub.ID, 2000)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = db.ChargeSubAccount(ctx, sub.ID, 1500)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, db.RecordSubAccountSpend(ctx, sub.ID, 250))
+	sub, err = db.GetSubAccount(ctx, account.ID, sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(3250), sub.SpentUSDC)
+
+	got, err = db.GetAccountByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(0), got.BalanceUSDC)
+}
+
+func TestGetSubAccountUsage(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account := createTestB2BAccount(t, db, "sub-usage@example.com")
+	agent, err := db.CreateSubAccount(ctx, account.ID, "Agent", nil, 10)
+	require.NoError(t, err)
+	idle, err := db.CreateSubAccount(ctx, account.ID, "Idle", nil, 10)
+	require.NoError(t, err)
+	closed, err := db.CreateSubAccount(ctx, account.ID, "Closed", nil, 10)
+	require.NoError(t, err)
+
+	logs := []map[string]any{
+		{"auth_method": "api_key", "sub_account_id": agent.ID.String(), "decision": "BLOCK"},
+		{"auth_method": "api_key", "sub_account_id": agent.ID.String(), "decision": "ALLOW"},
+		{"payment_method": "credits", "actual_cost": usdc.MicroUSDC(1000), "sub_account_id": agent.ID.String()},
+		{"payment_method": "credits", "actual_cost": usdc.MicroUSDC(1000), "sub_account_id": agent.ID.String()},
+		{"auth_method": "api_key", "sub_account_id": closed.ID.String(), "decision": "ALLOW"},
+		{"auth_method": "api_key", "decision": "ALLOW"},
+	}
+	for _, metadata := range logs {
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: account.ID,
+			RequestID: uuid.New().String(),
+			Endpoint:  "/v1/scan/content",
+			Method:    "POST",
+			Status:    "success",
+			Metadata:  metadata,
+		}))
+	}
+	require.NoError(t, db.CloseSubAccount(ctx, account.ID, closed.ID))
+	require.NoError(t, db.CloseSubAccount(ctx, account.ID, idle.ID))
+
+	now := time.Now().UTC()
+	usage, err := db.GetSubAccountUsage(ctx, account.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	byName := make(map[string]*SubAccountUsage)
+	for _, u := range usage {
+		byName[u.Name] = u
+	}
+	require.Len(t, byName, 2, "an idle closed sub-account is left out")
+	assert.Equal(t, int64(2), byName["Agent"].Scans)
+	assert.Equal(t, int64(1), byName["Agent"].Blocked)
+	assert.Equal(t, usdc.MicroUSDC(2000), byName["Agent"].SpendUSDC)
+	assert.Equal(t, int64(1), byName["Closed"].Scans)
+	assert.NotNil(t, byName["Closed"].ClosedAt)
+}
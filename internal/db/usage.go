@@ -2,12 +2,16 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"stronghold/internal/hashchain"
 	"stronghold/internal/usdc"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // UsageLog represents a single API usage record
@@ -24,8 +28,13 @@ type UsageLog struct {
 	RequestSizeBytes  *int           `json:"request_size_bytes,omitempty"`
 	ResponseSizeBytes *int           `json:"response_size_bytes,omitempty"`
 	LatencyMs         *int           `json:"latency_ms,omitempty"`
+	DetectionVersion  string         `json:"detection_version,omitempty"`
+	InstallID         *uuid.UUID     `json:"install_id,omitempty"` // Proxy install that signed the request
 	Metadata          map[string]any `json:"metadata,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
+	ChainSeq          int64          `json:"chain_seq,omitempty"`
+	PrevHash          string         `json:"prev_hash,omitempty"`
+	Hash              string         `json:"hash,omitempty"`
 }
 
 // UsageStats represents aggregated usage statistics
@@ -36,29 +45,154 @@ type UsageStats struct {
 	AvgLatencyMs    float64        `json:"avg_latency_ms"`
 }
 
-// CreateUsageLog creates a new usage log entry
+// CreateUsageLog creates a new usage log entry, appending it to the
+// account's usage log hash chain
 func (db *DB) CreateUsageLog(ctx context.Context, log *UsageLog) error {
 	log.ID = uuid.New()
-	log.CreatedAt = time.Now().UTC()
+	// Postgres keeps microseconds; hash the value that will be read back
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
 
-	_, err := db.pool.Exec(ctx, `
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize appends to the account's chain
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, log.AccountID); err != nil {
+		return fmt.Errorf("failed to lock usage log chain: %w", err)
+	}
+
+	var lastSeq *int64
+	var lastHash *string
+	err = tx.QueryRow(ctx, `
+		SELECT chain_seq, hash FROM usage_logs
+		WHERE account_id = $1 AND chain_seq IS NOT NULL
+		ORDER BY chain_seq DESC
+		LIMIT 1
+	`, log.AccountID).Scan(&lastSeq, &lastHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to read usage log chain: %w", err)
+	}
+	log.ChainSeq = 1
+	log.PrevHash = hashchain.Genesis
+	if lastSeq != nil && lastHash != nil {
+		log.ChainSeq = *lastSeq + 1
+		log.PrevHash = *lastHash
+	}
+	log.Hash = usageLogHash(log)
+
+	_, err = tx.Exec(ctx, `
 		INSERT INTO usage_logs (
 			id, account_id, request_id, endpoint, method, cost_usdc, status,
 			threat_detected, threat_type, request_size_bytes, response_size_bytes,
-			latency_ms, metadata, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			latency_ms, metadata, created_at, chain_seq, prev_hash, hash,
+			detection_version, install_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''), $19)
 	`, log.ID, log.AccountID, log.RequestID, log.Endpoint, log.Method,
 		log.CostUSDC, log.Status, log.ThreatDetected, log.ThreatType,
 		log.RequestSizeBytes, log.ResponseSizeBytes, log.LatencyMs,
-		log.Metadata, log.CreatedAt)
+		log.Metadata, log.CreatedAt, log.ChainSeq, log.PrevHash, log.Hash,
+		log.DetectionVersion, log.InstallID)
 
 	if err != nil {
 		return fmt.Errorf("failed to create usage log: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
 	return nil
 }
 
+// usageLogHash hashes a usage log's billing fields onto its previous hash.
+// Metadata and sizes are informational and not covered.
+func usageLogHash(log *UsageLog) string {
+	threatType := ""
+	if log.ThreatType != nil {
+		threatType = *log.ThreatType
+	}
+	record, _ := json.Marshal([]any{
+		log.ChainSeq, log.ID.String(), log.AccountID.String(), log.RequestID,
+		log.Endpoint, log.Method, int64(log.CostUSDC), log.Status,
+		log.ThreatDetected, threatType, log.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	return hashchain.Next(log.PrevHash, record)
+}
+
+// UsageChainResult reports the outcome of verifying an account's usage log
+// hash chain
+type UsageChainResult struct {
+	Valid     bool       `json:"valid"`
+	Records   int        `json:"records"`   // Chained records verified
+	Unchained int        `json:"unchained"` // Records written before chaining was enabled
+	Head      string     `json:"head"`      // Hash of the last record
+	BrokenAt  *uuid.UUID `json:"broken_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// VerifyUsageLogChain recomputes an account's usage log hash chain and
+// reports the first record that does not match
+func (db *DB) VerifyUsageLogChain(ctx context.Context, accountID uuid.UUID) (*UsageChainResult, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, account_id, request_id, endpoint, method, cost_usdc, status,
+		       threat_detected, threat_type, created_at, chain_seq, prev_hash, hash
+		FROM usage_logs
+		WHERE account_id = $1
+		ORDER BY chain_seq ASC NULLS FIRST, created_at ASC
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage logs: %w", err)
+	}
+	defer rows.Close()
+
+	res := &UsageChainResult{Valid: true, Head: hashchain.Genesis}
+	fail := func(id uuid.UUID, reason string) {
+		res.Valid = false
+		res.BrokenAt = &id
+		res.Reason = reason
+	}
+	for rows.Next() && res.Valid {
+		log := &UsageLog{}
+		var seq *int64
+		var prevHash, hash *string
+		if err := rows.Scan(
+			&log.ID, &log.AccountID, &log.RequestID, &log.Endpoint, &log.Method,
+			&log.CostUSDC, &log.Status, &log.ThreatDetected, &log.ThreatType,
+			&log.CreatedAt, &seq, &prevHash, &hash,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan usage log: %w", err)
+		}
+
+		if seq == nil || prevHash == nil || hash == nil {
+			if res.Records > 0 {
+				fail(log.ID, "record is not chained")
+			} else {
+				res.Unchained++
+			}
+			continue
+		}
+		log.ChainSeq, log.PrevHash = *seq, *prevHash
+		switch {
+		case log.ChainSeq != int64(res.Records)+1:
+			fail(log.ID, fmt.Sprintf("expected chain position %d, found %d; a record was removed", res.Records+1, log.ChainSeq))
+		case log.PrevHash != res.Head:
+			fail(log.ID, "previous hash does not match")
+		case usageLogHash(log) != *hash:
+			fail(log.ID, "hash does not match; the record was modified")
+		default:
+			res.Head = *hash
+			res.Records++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage logs: %w", err)
+	}
+
+	return res, nil
+}
+
 // GetUsageLogs retrieves usage logs for an account with pagination
 func (db *DB) GetUsageLogs(ctx context.Context, accountID uuid.UUID, limit, offset int) ([]*UsageLog, error) {
 	if limit <= 0 {
@@ -71,7 +205,7 @@ func (db *DB) GetUsageLogs(ctx context.Context, accountID uuid.UUID, limit, offs
 	rows, err := db.pool.Query(ctx, `
 		SELECT id, account_id, request_id, endpoint, method, cost_usdc, status,
 		       threat_detected, threat_type, request_size_bytes, response_size_bytes,
-		       latency_ms, metadata, created_at
+		       latency_ms, metadata, created_at, COALESCE(detection_version, '')
 		FROM usage_logs
 		WHERE account_id = $1
 		ORDER BY created_at DESC
@@ -90,7 +224,7 @@ func (db *DB) GetUsageLogs(ctx context.Context, accountID uuid.UUID, limit, offs
 			&log.ID, &log.AccountID, &log.RequestID, &log.Endpoint, &log.Method,
 			&log.CostUSDC, &log.Status, &log.ThreatDetected, &log.ThreatType,
 			&log.RequestSizeBytes, &log.ResponseSizeBytes, &log.LatencyMs,
-			&log.Metadata, &log.CreatedAt,
+			&log.Metadata, &log.CreatedAt, &log.DetectionVersion,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan usage log: %w", err)
@@ -118,7 +252,7 @@ func (db *DB) GetUsageLogsByDateRange(ctx context.Context, accountID uuid.UUID,
 	rows, err := db.pool.Query(ctx, `
 		SELECT id, account_id, request_id, endpoint, method, cost_usdc, status,
 		       threat_detected, threat_type, request_size_bytes, response_size_bytes,
-		       latency_ms, metadata, created_at
+		       latency_ms, metadata, created_at, COALESCE(detection_version, '')
 		FROM usage_logs
 		WHERE account_id = $1 AND created_at >= $2 AND created_at <= $3
 		ORDER BY created_at DESC
@@ -137,7 +271,7 @@ func (db *DB) GetUsageLogsByDateRange(ctx context.Context, accountID uuid.UUID,
 			&log.ID, &log.AccountID, &log.RequestID, &log.Endpoint, &log.Method,
 			&log.CostUSDC, &log.Status, &log.ThreatDetected, &log.ThreatType,
 			&log.RequestSizeBytes, &log.ResponseSizeBytes, &log.LatencyMs,
-			&log.Metadata, &log.CreatedAt,
+			&log.Metadata, &log.CreatedAt, &log.DetectionVersion,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan usage log: %w", err)
@@ -178,7 +312,10 @@ func (db *DB) GetUsageStats(ctx context.Context, accountID uuid.UUID, start, end
 	return stats, nil
 }
 
-// GetDailyUsageStats retrieves daily usage statistics for an account
+// GetDailyUsageStats retrieves daily usage statistics for an account over
+// the last days UTC days, newest first. Days the rollup worker has finished
+// are read from usage_rollups_daily; from the first day it has not, usage is
+// aggregated from usage_logs.
 func (db *DB) GetDailyUsageStats(ctx context.Context, accountID uuid.UUID, days int) ([]*DailyUsageStats, error) {
 	if days <= 0 {
 		days = 30
@@ -187,19 +324,31 @@ func (db *DB) GetDailyUsageStats(ctx context.Context, accountID uuid.UUID, days
 		days = 365
 	}
 
-	startDate := time.Now().UTC().AddDate(0, 0, -days)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	startDate := today.AddDate(0, 0, -days)
+
+	var liveFrom time.Time
+	err := db.pool.QueryRow(ctx, `
+		SELECT COALESCE(MIN(d::date), $2::date)
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+		WHERE NOT EXISTS (SELECT 1 FROM usage_rollup_days r WHERE r.day = d::date)
+	`, startDate, today).Scan(&liveFrom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find daily usage rollups: %w", err)
+	}
 
 	rows, err := db.pool.Query(ctx, `
-		SELECT
-			DATE(created_at) as date,
-			COUNT(*) as request_count,
-			SUM(cost_usdc) as total_cost_usdc,
-			SUM(CASE WHEN threat_detected THEN 1 ELSE 0 END) as threats_detected
+		SELECT day, requests, cost_usdc, threats_detected, blocked, spend_usdc,
+			latency_p50_ms, latency_p95_ms, latency_p99_ms
+		FROM usage_rollups_daily
+		WHERE account_id = $1 AND day >= $2::date AND day < $3::date
+		UNION ALL
+		SELECT `+usageDay+`,`+dailyUsageColumns+`
 		FROM usage_logs
-		WHERE account_id = $1 AND created_at >= $2
-		GROUP BY DATE(created_at)
-		ORDER BY date DESC
-	`, accountID, startDate)
+		WHERE account_id = $1 AND created_at >= $4
+		GROUP BY 1
+		ORDER BY 1 DESC
+	`, accountID, startDate, liveFrom, liveFrom)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get daily usage stats: %w", err)
@@ -210,7 +359,8 @@ func (db *DB) GetDailyUsageStats(ctx context.Context, accountID uuid.UUID, days
 	for rows.Next() {
 		stat := &DailyUsageStats{}
 		var date time.Time
-		err := rows.Scan(&date, &stat.RequestCount, &stat.TotalCostUSDC, &stat.ThreatsDetected)
+		err := rows.Scan(&date, &stat.RequestCount, &stat.TotalCostUSDC, &stat.ThreatsDetected,
+			&stat.Blocked, &stat.SpendUSDC, &stat.LatencyP50Ms, &stat.LatencyP95Ms, &stat.LatencyP99Ms)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan daily usage stats: %w", err)
 		}
@@ -231,6 +381,11 @@ type DailyUsageStats struct {
 	RequestCount    int64          `json:"request_count"`
 	TotalCostUSDC   usdc.MicroUSDC `json:"total_cost_usdc"`
 	ThreatsDetected int64          `json:"threats_detected"`
+	Blocked         int64          `json:"blocked"`
+	SpendUSDC       usdc.MicroUSDC `json:"spend_usdc"`
+	LatencyP50Ms    int            `json:"latency_p50_ms"`
+	LatencyP95Ms    int            `json:"latency_p95_ms"`
+	LatencyP99Ms    int            `json:"latency_p99_ms"`
 }
 
 // GetEndpointUsageStats retrieves usage statistics grouped by endpoint
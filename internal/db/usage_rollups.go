@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Account event types
+const (
+	AccountEventScan    = "scan"
+	AccountEventDeposit = "deposit"
+)
+
+// recentAccountEvents is how many recent events an overview lists
+const recentAccountEvents = 10
+
+// AccountEvent is a recent scan or deposit on an account
+type AccountEvent struct {
+	Type       string         `json:"type"`                  // "scan" or "deposit"
+	Endpoint   string         `json:"endpoint,omitempty"`    // scans only
+	Decision   string         `json:"decision,omitempty"`    // scans only
+	ThreatType *string        `json:"threat_type,omitempty"` // scans only
+	AmountUSDC usdc.MicroUSDC `json:"amount_usdc,omitempty"` // net amount of deposits
+	Status     string         `json:"status,omitempty"`      // deposits only
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// AccountOverview is what the dashboard shows on load: the balance, usage
+// over the last day, week and month, and the latest scans and deposits
+type AccountOverview struct {
+	BalanceUSDC  usdc.MicroUSDC  `json:"balance_usdc"`
+	Last24h      UsageAggregate  `json:"last_24h"`
+	Last7d       UsageAggregate  `json:"last_7d"`
+	Last30d      UsageAggregate  `json:"last_30d"`
+	RecentEvents []*AccountEvent `json:"recent_events"`
+	GeneratedAt  time.Time       `json:"generated_at"`
+}
+
+// rollupWindow returns the aggregate columns of usage_rollups_hourly rows at
+// or after the hour in the given query parameter
+func rollupWindow(param string) string {
+	return `
+		COALESCE(SUM(scans) FILTER (WHERE hour >= ` + param + `), 0),
+		COALESCE(SUM(blocked) FILTER (WHERE hour >= ` + param + `), 0),
+		COALESCE(SUM(threats_detected) FILTER (WHERE hour >= ` + param + `), 0),
+		COALESCE(SUM(spend_usdc) FILTER (WHERE hour >= ` + param + `), 0)`
+}
+
+// GetAccountOverview reads an account's balance, its usage over the last
+// 24 hours, 7 days and 30 days from the hourly rollups, and its most recent
+// scans and deposits. Windows are whole UTC hours, ending with the current one.
+func (db *DB) GetAccountOverview(ctx context.Context, accountID uuid.UUID, now time.Time) (*AccountOverview, error) {
+	overview := &AccountOverview{
+		RecentEvents: []*AccountEvent{},
+		GeneratedAt:  now.UTC(),
+	}
+
+	err := db.pool.QueryRow(ctx, `SELECT balance_usdc FROM accounts WHERE id = $1`, accountID).Scan(&overview.BalanceUSDC)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account balance: %w", err)
+	}
+
+	hour := now.UTC().Truncate(time.Hour)
+	dest := append(overview.Last24h.scanDest(), overview.Last7d.scanDest()...)
+	dest = append(dest, overview.Last30d.scanDest()...)
+	err = db.pool.QueryRow(ctx, `
+		SELECT`+rollupWindow("$2")+`,`+rollupWindow("$3")+`,`+rollupWindow("$4")+`
+		FROM usage_rollups_hourly
+		WHERE account_id = $1 AND hour >= $4
+	`, accountID, hour.Add(-23*time.Hour), hour.Add(-(7*24-1)*time.Hour), hour.Add(-(30*24-1)*time.Hour)).Scan(dest...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage rollups: %w", err)
+	}
+	overview.Last24h.computeBlockRate()
+	overview.Last7d.computeBlockRate()
+	overview.Last30d.computeBlockRate()
+
+	rows, err := db.pool.Query(ctx, `
+		(SELECT 'scan', endpoint, COALESCE(metadata->>'decision', ''), threat_type, 0::bigint, '', created_at
+		 FROM usage_logs
+		 WHERE account_id = $1 AND metadata ? 'auth_method'
+		 ORDER BY created_at DESC
+		 LIMIT $2)
+		UNION ALL
+		(SELECT 'deposit', '', '', NULL, net_amount_usdc, status::text, created_at
+		 FROM deposits
+		 WHERE account_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2)
+		ORDER BY 7 DESC
+		LIMIT $2
+	`, accountID, recentAccountEvents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent account events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := &AccountEvent{}
+		if err := rows.Scan(&e.Type, &e.Endpoint, &e.Decision, &e.ThreatType, &e.AmountUSDC, &e.Status, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account event: %w", err)
+		}
+		overview.RecentEvents = append(overview.RecentEvents, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate account events: %w", err)
+	}
+
+	return overview, nil
+}
+
+// dailyUsageColumns aggregates usage_logs rows into the columns of
+// usage_rollups_daily after account_id and day. Requests and cost count every
+// usage log; blocks and spend follow the organization usage report.
+const dailyUsageColumns = `
+	COUNT(*),
+	COALESCE(SUM(cost_usdc), 0)::bigint,
+	COUNT(*) FILTER (WHERE threat_detected),
+	COUNT(*) FILTER (WHERE metadata ? 'auth_method' AND metadata->>'decision' = 'BLOCK'),
+	COALESCE(SUM(COALESCE((metadata->>'actual_cost')::bigint, cost_usdc::bigint)), 0)::bigint,
+	COALESCE(ROUND(percentile_cont(0.5) WITHIN GROUP (ORDER BY latency_ms)), 0)::int,
+	COALESCE(ROUND(percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms)), 0)::int,
+	COALESCE(ROUND(percentile_cont(0.99) WITHIN GROUP (ORDER BY latency_ms)), 0)::int`
+
+// usageDay is the UTC day of a usage log
+const usageDay = `(created_at AT TIME ZONE 'UTC')::date`
+
+// RollupDailyUsage replaces every account's totals for a UTC day with ones
+// aggregated from usage_logs, and records the day as rolled up so reads stop
+// aggregating it. Only days that have ended should be rolled up. Returns how
+// many accounts had usage on the day.
+func (db *DB) RollupDailyUsage(ctx context.Context, day time.Time) (int64, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM usage_rollups_daily WHERE day = $1::date`, day); err != nil {
+		return 0, fmt.Errorf("failed to clear daily usage rollups: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO usage_rollups_daily (
+			account_id, day, requests, cost_usdc, threats_detected, blocked, spend_usdc,
+			latency_p50_ms, latency_p95_ms, latency_p99_ms
+		)
+		SELECT account_id, $1::date,`+dailyUsageColumns+`
+		FROM usage_logs
+		WHERE created_at >= $2 AND created_at < $3
+		GROUP BY account_id
+	`, day, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up daily usage: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO usage_rollup_days (day) VALUES ($1::date)
+		ON CONFLICT (day) DO UPDATE SET rolled_up_at = NOW()
+	`, day)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record daily usage rollup: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PendingUsageRollupDays returns the UTC days from one day to another,
+// inclusive, that have not been rolled up, oldest first
+func (db *DB) PendingUsageRollupDays(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT d::date
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+		WHERE NOT EXISTS (SELECT 1 FROM usage_rollup_days r WHERE r.day = d::date)
+		ORDER BY 1
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending usage rollup days: %w", err)
+	}
+	defer rows.Close()
+
+	days := []time.Time{}
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan usage rollup day: %w", err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate usage rollup days: %w", err)
+	}
+	return days, nil
+}
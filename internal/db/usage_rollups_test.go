@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountOverview(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+	fixtures := NewFixtures(t, db)
+
+	account := fixtures.CreateTestAccount(nil).Account
+	fixtures.CreateCompletedDeposit(account.ID, 5_000_000)
+
+	logScan := func(decision string, threat bool) {
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: account.ID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+			Status: "success", ThreatDetected: threat,
+			Metadata: map[string]any{"auth_method": "api_key", "decision": decision},
+		}))
+		require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+			AccountID: account.ID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+			Status:   "success",
+			Metadata: map[string]any{"payment_method": "credits", "account_type": "b2b", "actual_cost": usdc.MicroUSDC(1000)},
+		}))
+	}
+	logScan("BLOCK", true)
+	logScan("ALLOW", false)
+
+	// A scan from ten days ago only counts towards the 30 day window
+	_, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO usage_logs (account_id, request_id, endpoint, method, status, metadata, created_at)
+		VALUES ($1, 'old', '/v1/scan/output', 'POST', 'success', '{"auth_method": "api_key", "decision": "WARN"}', NOW() - INTERVAL '10 days')
+	`, account.ID)
+	require.NoError(t, err)
+
+	overview, err := db.GetAccountOverview(ctx, account.ID, time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), overview.BalanceUSDC)
+	assert.Equal(t, int64(2), overview.Last24h.Scans)
+	assert.Equal(t, int64(1), overview.Last24h.Blocked)
+	assert.Equal(t, int64(1), overview.Last24h.ThreatsDetected)
+	assert.Equal(t, usdc.MicroUSDC(2000), overview.Last24h.SpendUSDC)
+	assert.InDelta(t, 0.5, overview.Last24h.BlockRate, 0.001)
+	assert.Equal(t, overview.Last24h, overview.Last7d)
+	assert.Equal(t, int64(3), overview.Last30d.Scans)
+	assert.InDelta(t, 1.0/3, overview.Last30d.BlockRate, 0.001)
+
+	require.Len(t, overview.RecentEvents, 4, "billing rows aren't listed")
+	assert.Equal(t, AccountEventScan, overview.RecentEvents[0].Type)
+	assert.Equal(t, "ALLOW", overview.RecentEvents[0].Decision)
+	assert.Equal(t, AccountEventScan, overview.RecentEvents[1].Type)
+	assert.Equal(t, AccountEventDeposit, overview.RecentEvents[2].Type)
+	assert.Equal(t, usdc.MicroUSDC(5_000_000), overview.RecentEvents[2].AmountUSDC)
+	assert.Equal(t, "WARN", overview.RecentEvents[3].Decision)
+
+	_, err = db.GetAccountOverview(ctx, uuid.New(), time.Now())
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestRollupDailyUsage(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+	fixtures := NewFixtures(t, db)
+
+	account := fixtures.CreateTestAccount(nil).Account
+	fixtures.CreateCompletedDeposit(account.ID, 5_000_000)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := today.AddDate(0, 0, -3)
+	for i, latency := range []int{10, 20, 30, 110} {
+		decision := "ALLOW"
+		if i == 0 {
+			decision = "BLOCK"
+		}
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO usage_logs (account_id, request_id, endpoint, method, status, cost_usdc, latency_ms, threat_detected, metadata, created_at)
+			VALUES ($1, $2, '/v1/scan/content', 'POST', 'success', 500, $3, $4, jsonb_build_object('auth_method', 'api_key', 'decision', $5::text), $6)
+		`, account.ID, uuid.NewString(), latency, i == 0, decision, day.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.CreateUsageLog(ctx, &UsageLog{
+		AccountID: account.ID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST", Status: "success",
+	}))
+
+	pending, err := db.PendingUsageRollupDays(ctx, today.AddDate(0, 0, -7), today.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, pending, 7)
+	for _, d := range pending {
+		_, err := db.RollupDailyUsage(ctx, d)
+		require.NoError(t, err)
+	}
+	pending, err = db.PendingUsageRollupDays(ctx, today.AddDate(0, 0, -7), today)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{today}, pending)
+
+	// Rolling up again replaces the day rather than adding to it
+	accounts, err := db.RollupDailyUsage(ctx, day)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), accounts)
+
+	stats, err := db.GetDailyUsageStats(ctx, account.ID, 7)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, today.Format("2006-01-02"), stats[0].Date, "today is read from usage_logs")
+	assert.Equal(t, int64(1), stats[0].RequestCount)
+
+	rolled := stats[1]
+	assert.Equal(t, day.Format("2006-01-02"), rolled.Date)
+	assert.Equal(t, int64(4), rolled.RequestCount)
+	assert.Equal(t, usdc.MicroUSDC(2000), rolled.TotalCostUSDC)
+	assert.Equal(t, usdc.MicroUSDC(2000), rolled.SpendUSDC)
+	assert.Equal(t, int64(1), rolled.ThreatsDetected)
+	assert.Equal(t, int64(1), rolled.Blocked)
+	assert.Equal(t, 25, rolled.LatencyP50Ms)
+	assert.Equal(t, 98, rolled.LatencyP95Ms)
+	assert.Equal(t, 108, rolled.LatencyP99Ms)
+
+	// Rolled up days are read from the rollup, not the usage log
+	_, err = testDB.Pool.Exec(ctx, `UPDATE usage_rollups_daily SET requests = 99 WHERE account_id = $1`, account.ID)
+	require.NoError(t, err)
+	stats, err = db.GetDailyUsageStats(ctx, account.ID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(99), stats[1].RequestCount)
+}
@@ -437,3 +437,52 @@ func TestGetUsageLogs_OrderedByCreatedAtDesc(t *testing.T) {
 			"Logs should be ordered by created_at descending")
 	}
 }
+
+func TestUsageLogChain_VerifiesAndDetectsTampering(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	account, err := db.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	err = db.UpdateBalance(ctx, account.ID, usdc.FromFloat(100.0))
+	require.NoError(t, err)
+
+	var logs []*UsageLog
+	for i := 0; i < 3; i++ {
+		log := &UsageLog{
+			AccountID: account.ID,
+			RequestID: uuid.NewString(),
+			Endpoint:  "/v1/scan/content",
+			Method:    "POST",
+			CostUSDC:  usdc.MicroUSDC(1000),
+			Status:    "success",
+		}
+		require.NoError(t, db.CreateUsageLog(ctx, log))
+		logs = append(logs, log)
+	}
+	assert.Equal(t, int64(3), logs[2].ChainSeq)
+	assert.Equal(t, logs[1].Hash, logs[2].PrevHash)
+
+	result, err := db.VerifyUsageLogChain(ctx, account.ID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.Records)
+	assert.Equal(t, logs[2].Hash, result.Head)
+
+	// Chained columns are write-once
+	_, err = testDB.Pool.Exec(ctx, `UPDATE usage_logs SET cost_usdc = 0 WHERE id = $1`, logs[1].ID)
+	require.Error(t, err)
+
+	// Deleting a record breaks the chain at the next one
+	_, err = testDB.Pool.Exec(ctx, `DELETE FROM usage_logs WHERE id = $1`, logs[1].ID)
+	require.NoError(t, err)
+
+	result, err = db.VerifyUsageLogChain(ctx, account.ID)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	require.NotNil(t, result.BrokenAt)
+	assert.Equal(t, logs[2].ID, *result.BrokenAt)
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrDirectoryLinkUnverified means a directory user's email matches an
+	// existing account, which is only linked once the email's domain is known
+	// to be a verified domain of the user's organization
+	ErrDirectoryLinkUnverified = errors.New("email domain not verified for the directory's organization")
+	// ErrDirectoryLinkRefused means a directory user's email matches an
+	// account that belongs to another organization
+	ErrDirectoryLinkRefused = errors.New("email belongs to an account in another organization")
+)
+
+// DirectoryUser is a user synced from an organization's identity provider
+// through WorkOS Directory Sync
+type DirectoryUser struct {
+	DirectoryUserID string
+	OrganizationID  string
+	Email           string
+	Role            string
+	DomainVerified  bool // Email's domain is a verified domain of OrganizationID
+}
+
+// OrganizationMember is a B2B account belonging to a WorkOS organization
+type OrganizationMember struct {
+	AccountID        uuid.UUID     `json:"account_id"`
+	Email            *string       `json:"email,omitempty"`
+	Role             *string       `json:"role,omitempty"`
+	Status           AccountStatus `json:"status"`
+	DirectoryManaged bool          `json:"directory_managed"`
+	CreatedAt        time.Time     `json:"created_at"`
+	LastLoginAt      *time.Time    `json:"last_login_at,omitempty"`
+	DeprovisionedAt  *time.Time    `json:"deprovisioned_at,omitempty"`
+}
+
+// SetWorkOSMembership records the WorkOS organization an account belongs to
+// and its role there. An empty role clears it. No-op when unchanged.
+func (db *DB) SetWorkOSMembership(ctx context.Context, accountID uuid.UUID, organizationID, role string) error {
+	_, err := db.pool.Exec(ctx, `
+		UPDATE accounts
+		SET workos_organization_id = $1, workos_role = $2, updated_at = $3
+		WHERE id = $4
+		  AND (workos_organization_id IS DISTINCT FROM $1 OR workos_role IS DISTINCT FROM $2)
+	`, labelOrNull(organizationID), labelOrNull(role), time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update organization membership: %w", err)
+	}
+	return nil
+}
+
+// ProvisionDirectoryUser creates or updates the B2B account for a directory
+// user. An existing account is matched by directory user ID, then by email
+// for a B2B account not yet linked to a directory. An email match is only
+// linked when the account has no organization or the user's, and the email's
+// domain is verified for that organization: ErrDirectoryLinkRefused and
+// ErrDirectoryLinkUnverified are returned otherwise. Accounts suspended by
+// DeprovisionDirectoryUser are reactivated; accounts suspended for any other
+// reason stay suspended. Returns the account ID and whether it was created.
+func (db *DB) ProvisionDirectoryUser(ctx context.Context, u DirectoryUser) (uuid.UUID, bool, error) {
+	if u.DirectoryUserID == "" || u.Email == "" {
+		return uuid.Nil, false, errors.New("directory user ID and email must not be empty")
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var accountID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM accounts WHERE workos_directory_user_id = $1 FOR UPDATE
+	`, u.DirectoryUserID).Scan(&accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var organizationID *string
+		err = tx.QueryRow(ctx, `
+			SELECT id, workos_organization_id FROM accounts
+			WHERE email = $1 AND account_type = $2 AND workos_directory_user_id IS NULL
+			FOR UPDATE
+		`, u.Email, AccountTypeB2B).Scan(&accountID, &organizationID)
+		if err == nil {
+			// Any organization can send a directory user with any email
+			if organizationID != nil && *organizationID != u.OrganizationID {
+				return uuid.Nil, false, ErrDirectoryLinkRefused
+			}
+			if !u.DomainVerified {
+				return uuid.Nil, false, ErrDirectoryLinkUnverified
+			}
+		}
+	}
+
+	created := false
+	now := time.Now().UTC()
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		accountID = uuid.New()
+		created = true
+		_, err = tx.Exec(ctx, `
+			INSERT INTO accounts (id, account_type, email, workos_directory_user_id, workos_organization_id, workos_role,
+				balance_usdc, status, created_at, updated_at, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8, $8, '{}')
+		`, accountID, AccountTypeB2B, u.Email, u.DirectoryUserID, labelOrNull(u.OrganizationID), labelOrNull(u.Role),
+			AccountStatusActive, now)
+	case err == nil:
+		_, err = tx.Exec(ctx, `
+			UPDATE accounts
+			SET email = $1, workos_directory_user_id = $2, workos_organization_id = $3, workos_role = $4,
+				status = CASE WHEN deprovisioned_at IS NOT NULL THEN $5 ELSE status END,
+				deprovisioned_at = NULL, updated_at = $6
+			WHERE id = $7
+		`, u.Email, u.DirectoryUserID, labelOrNull(u.OrganizationID), labelOrNull(u.Role),
+			AccountStatusActive, now, accountID)
+	default:
+		return uuid.Nil, false, fmt.Errorf("failed to look up directory user: %w", err)
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && strings.Contains(pgErr.ConstraintName, "email") {
+			return uuid.Nil, false, ErrEmailAlreadyExists
+		}
+		return uuid.Nil, false, fmt.Errorf("failed to provision directory user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return accountID, created, nil
+}
+
+// DeprovisionDirectoryUser suspends the account of a user removed from the
+// organization's directory, ends its sessions and revokes its API keys.
+// Returns ErrAccountNotFound if no active provisioned account matches.
+func (db *DB) DeprovisionDirectoryUser(ctx context.Context, directoryUserID string) (uuid.UUID, error) {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	var accountID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		UPDATE accounts
+		SET status = $1, deprovisioned_at = $2, updated_at = $2
+		WHERE workos_directory_user_id = $3 AND deprovisioned_at IS NULL
+		RETURNING id
+	`, AccountStatusSuspended, now, directoryUserID).Scan(&accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrAccountNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to suspend account: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE account_id = $1`, accountID); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = $1 WHERE account_id = $2 AND revoked_at IS NULL
+	`, now, accountID); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to revoke API keys: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return accountID, nil
+}
+
+// GetDirectoryAccountByEmail returns the directory-provisioned account for
+// email that no WorkOS user has signed in to yet
+func (db *DB) GetDirectoryAccountByEmail(ctx context.Context, email string) (*Account, error) {
+	return scanAccount(db.QueryRow(ctx, `
+		SELECT `+accountSelectColumns+` FROM accounts
+		WHERE email = $1 AND workos_directory_user_id IS NOT NULL AND workos_user_id IS NULL
+	`, email))
+}
+
+// LinkWorkOSUser links a WorkOS user to an account that has none, on the
+// user's first sign-in to a directory-provisioned account
+func (db *DB) LinkWorkOSUser(ctx context.Context, accountID uuid.UUID, workosUserID string) error {
+	result, err := db.pool.Exec(ctx, `
+		UPDATE accounts SET workos_user_id = $1, updated_at = $2
+		WHERE id = $3 AND workos_user_id IS NULL
+	`, workosUserID, time.Now().UTC(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to link WorkOS user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// ListOrganizationMembers returns the accounts belonging to a WorkOS
+// organization, ordered by email
+func (db *DB) ListOrganizationMembers(ctx context.Context, organizationID string) ([]*OrganizationMember, error) {
+	rows, err := db.pool.Query(ctx, `
+		SELECT id, email, workos_role, status, workos_directory_user_id IS NOT NULL,
+		       created_at, last_login_at, deprovisioned_at
+		FROM accounts
+		WHERE workos_organization_id = $1
+		ORDER BY email, created_at
+	`, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*OrganizationMember{}
+	for rows.Next() {
+		var m OrganizationMember
+		if err := rows.Scan(&m.AccountID, &m.Email, &m.Role, &m.Status, &m.DirectoryManaged,
+			&m.CreatedAt, &m.LastLoginAt, &m.DeprovisionedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organization members: %w", err)
+	}
+	return members, nil
+}
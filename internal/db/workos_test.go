@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"stronghold/internal/db/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionDirectoryUser_Lifecycle(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	fixtures := NewFixtures(t, db)
+	ctx := context.Background()
+
+	user := DirectoryUser{
+		DirectoryUserID: "directory_user_01",
+		OrganizationID:  "org_01",
+		Email:           "alice@example.com",
+		Role:            "member",
+	}
+	accountID, created, err := db.ProvisionDirectoryUser(ctx, user)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Updates match by directory user ID, even when the email changes
+	user.Email = "alice.smith@example.com"
+	user.Role = "admin"
+	again, created, err := db.ProvisionDirectoryUser(ctx, user)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, accountID, again)
+
+	members, err := db.ListOrganizationMembers(ctx, "org_01")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "alice.smith@example.com", *members[0].Email)
+	assert.Equal(t, "admin", *members[0].Role)
+	assert.True(t, members[0].DirectoryManaged)
+
+	session := fixtures.CreateTestSession(accountID)
+	_, err = db.CreateAPIKey(ctx, accountID, "sk_live_abc", "hash-directory-user", "ci", 10)
+	require.NoError(t, err)
+
+	deprovisioned, err := db.DeprovisionDirectoryUser(ctx, user.DirectoryUserID)
+	require.NoError(t, err)
+	assert.Equal(t, accountID, deprovisioned)
+
+	account, err := db.GetAccountByID(ctx, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, AccountStatusSuspended, account.Status)
+	_, err = db.GetSessionByRefreshToken(ctx, session.RefreshToken)
+	assert.Error(t, err, "sessions should be ended")
+	_, err = db.GetAPIKeyByHash(ctx, "hash-directory-user")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	_, err = db.DeprovisionDirectoryUser(ctx, user.DirectoryUserID)
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	// Reprovisioning reactivates the account
+	_, _, err = db.ProvisionDirectoryUser(ctx, user)
+	require.NoError(t, err)
+	account, err = db.GetAccountByID(ctx, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, AccountStatusActive, account.Status)
+}
+
+func TestProvisionDirectoryUser_LinksExistingAccount(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	existing, err := db.CreateB2BAccount(ctx, "user_01", "bob@example.com", "Example")
+	require.NoError(t, err)
+	require.NoError(t, db.SuspendAccount(ctx, existing.ID))
+
+	user := DirectoryUser{
+		DirectoryUserID: "directory_user_02",
+		OrganizationID:  "org_01",
+		Email:           "bob@example.com",
+	}
+	_, _, err = db.ProvisionDirectoryUser(ctx, user)
+	assert.ErrorIs(t, err, ErrDirectoryLinkUnverified, "linking needs the email's domain verified for the organization")
+
+	user.DomainVerified = true
+	accountID, created, err := db.ProvisionDirectoryUser(ctx, user)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, existing.ID, accountID)
+
+	// A suspension the directory didn't make is left alone
+	account, err := db.GetAccountByID(ctx, accountID)
+	require.NoError(t, err)
+	assert.Equal(t, AccountStatusSuspended, account.Status)
+
+	// Already signed in, so it is not offered for linking
+	_, err = db.GetDirectoryAccountByEmail(ctx, "bob@example.com")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestProvisionDirectoryUser_RefusesAnotherOrganizationsAccount(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	db := &DB{pool: testDB.Pool}
+	ctx := context.Background()
+
+	existing, err := db.CreateB2BAccount(ctx, "user_01", "carol@example.com", "Example")
+	require.NoError(t, err)
+	require.NoError(t, db.SetWorkOSMembership(ctx, existing.ID, "org_01", "admin"))
+
+	// Another organization pushes a directory user with the same email
+	_, _, err = db.ProvisionDirectoryUser(ctx, DirectoryUser{
+		DirectoryUserID: "directory_user_evil",
+		OrganizationID:  "org_02",
+		Email:           "carol@example.com",
+		Role:            "member",
+		DomainVerified:  true,
+	})
+	assert.ErrorIs(t, err, ErrDirectoryLinkRefused)
+
+	members, err := db.ListOrganizationMembers(ctx, "org_01")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "admin", *members[0].Role)
+	assert.False(t, members[0].DirectoryManaged)
+
+	// Deleting that directory user can't suspend the account
+	_, err = db.DeprovisionDirectoryUser(ctx, "directory_user_evil")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	account, err := db.GetAccountByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, AccountStatusActive, account.Status)
+
+	// The account's own organization can link it
+	accountID, created, err := db.ProvisionDirectoryUser(ctx, DirectoryUser{
+		DirectoryUserID: "directory_user_03",
+		OrganizationID:  "org_01",
+		Email:           "carol@example.com",
+		Role:            "admin",
+		DomainVerified:  true,
+	})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, existing.ID, accountID)
+}
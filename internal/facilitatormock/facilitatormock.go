@@ -0,0 +1,380 @@
+// Package facilitatormock is a stand-in x402 facilitator for local
+// development and integration tests. It answers /verify and /settle in the
+// x402 v2 wire format the API uses, tracks nonces so a payment settles only
+// once, and can inject latency and failures per endpoint.
+//
+// It never touches a chain: signatures are not checked against the token
+// contract and settled transactions are fake hashes. Protocol compliance
+// still has to be tested against a real facilitator.
+package facilitatormock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+// Failure modes injected into an endpoint
+const (
+	FailNone    = ""
+	FailReject  = "reject"  // Answer 200 with isValid/success false
+	FailError   = "error"   // Answer 500
+	FailTimeout = "timeout" // Hold the request until the client gives up
+)
+
+// Reasons returned for rejected payments
+const (
+	ReasonInvalidPayload = "invalid_payload"
+	ReasonAmountMismatch = "amount_mismatch"
+	ReasonRecipient      = "recipient_mismatch"
+	ReasonExpired        = "authorization_expired"
+	ReasonNonceUsed      = "nonce_already_used"
+	ReasonInjected       = "injected_failure"
+)
+
+// maxTimeoutHold bounds how long a timeout failure holds a request
+const maxTimeoutHold = 2 * time.Minute
+
+// EndpointConfig controls one facilitator endpoint
+type EndpointConfig struct {
+	LatencyMs   int     `json:"latency_ms"`   // Added to every request
+	Failure     string  `json:"failure"`      // "", "reject", "error" or "timeout"
+	FailureRate float64 `json:"failure_rate"` // Share of requests that fail; 0 with a failure mode means all
+}
+
+// Config controls the mock's behavior. It can be changed while running with
+// PUT /config.
+type Config struct {
+	Verify EndpointConfig `json:"verify"`
+	Settle EndpointConfig `json:"settle"`
+}
+
+// Stats counts requests since startup or the last reset
+type Stats struct {
+	Verified         int64 `json:"verified"`
+	Rejected         int64 `json:"rejected"`
+	Settled          int64 `json:"settled"`
+	InjectedFailures int64 `json:"injected_failures"`
+	Nonces           int   `json:"nonces"` // Settled nonces being tracked
+}
+
+// Server is the mock facilitator
+type Server struct {
+	mu     sync.Mutex
+	config Config
+	nonces map[string]string // settled nonce key -> transaction
+	stats  Stats
+	now    func() time.Time
+}
+
+// New creates a mock facilitator
+func New(cfg Config) *Server {
+	return &Server{
+		config: cfg,
+		nonces: make(map[string]string),
+		now:    time.Now,
+	}
+}
+
+// Handler returns the facilitator's HTTP routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /verify", s.handleVerify)
+	mux.HandleFunc("POST /settle", s.handleSettle)
+	mux.HandleFunc("GET /supported", s.handleSupported)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /config", s.handleGetConfig)
+	mux.HandleFunc("PUT /config", s.handlePutConfig)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("POST /reset", s.handleReset)
+	return mux
+}
+
+// request is the x402 v2 body sent to /verify and /settle
+type request struct {
+	X402Version    int `json:"x402Version"`
+	PaymentPayload struct {
+		Payload struct {
+			Signature     string                       `json:"signature"`
+			Authorization *wallet.EIP3009Authorization `json:"authorization"`
+			Transaction   string                       `json:"transaction"`
+		} `json:"payload"`
+	} `json:"paymentPayload"`
+	PaymentRequirements wallet.PaymentRequirementsV2 `json:"paymentRequirements"`
+}
+
+// payer returns the paying address, if the payload names one
+func (r *request) payer() string {
+	if auth := r.PaymentPayload.Payload.Authorization; auth != nil {
+		return auth.From
+	}
+	return ""
+}
+
+// nonceKey identifies the payment for replay tracking: the EIP-3009 nonce
+// for EVM payments, the transaction itself for Solana
+func (r *request) nonceKey() string {
+	network := r.PaymentRequirements.Network
+	if auth := r.PaymentPayload.Payload.Authorization; auth != nil {
+		return network + "|" + strings.ToLower(auth.From) + "|" + strings.ToLower(auth.Nonce)
+	}
+	sum := sha256.Sum256([]byte(r.PaymentPayload.Payload.Transaction))
+	return network + "|tx|" + hex.EncodeToString(sum[:])
+}
+
+// check validates the payment against its requirements and the settled
+// nonces. Callers hold s.mu.
+func (s *Server) check(r *request) string {
+	p := r.PaymentPayload.Payload
+	reqs := r.PaymentRequirements
+	if r.X402Version != 2 || reqs.Network == "" {
+		return ReasonInvalidPayload
+	}
+
+	switch {
+	case p.Authorization != nil:
+		auth := p.Authorization
+		if p.Signature == "" || auth.Nonce == "" {
+			return ReasonInvalidPayload
+		}
+		if auth.Value != reqs.Amount {
+			return ReasonAmountMismatch
+		}
+		if !strings.EqualFold(auth.To, reqs.PayTo) {
+			return ReasonRecipient
+		}
+		validBefore, err := strconv.ParseInt(auth.ValidBefore, 10, 64)
+		if err != nil {
+			return ReasonInvalidPayload
+		}
+		if s.now().Unix() >= validBefore {
+			return ReasonExpired
+		}
+	case p.Transaction != "":
+		// Solana transactions carry their own transfer; nothing to compare
+	default:
+		return ReasonInvalidPayload
+	}
+
+	if _, used := s.nonces[r.nonceKey()]; used {
+		return ReasonNonceUsed
+	}
+	return ""
+}
+
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.begin(w, r, func(c Config) EndpointConfig { return c.Verify })
+	if !ok {
+		return
+	}
+
+	resp := struct {
+		IsValid       bool   `json:"isValid"`
+		InvalidReason string `json:"invalidReason,omitempty"`
+		Payer         string `json:"payer,omitempty"`
+	}{Payer: req.payer()}
+
+	s.mu.Lock()
+	if req.injected {
+		resp.InvalidReason = ReasonInjected
+	} else {
+		resp.InvalidReason = s.check(&req.request)
+	}
+	resp.IsValid = resp.InvalidReason == ""
+	if resp.IsValid {
+		s.stats.Verified++
+	} else {
+		s.stats.Rejected++
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSettle(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.begin(w, r, func(c Config) EndpointConfig { return c.Settle })
+	if !ok {
+		return
+	}
+
+	// Settle responses use snake_case, matching x402-rs
+	resp := struct {
+		Success     bool   `json:"success"`
+		Transaction string `json:"transaction,omitempty"`
+		Network     string `json:"network,omitempty"`
+		Payer       string `json:"payer,omitempty"`
+		ErrorReason string `json:"error_reason,omitempty"`
+	}{Network: req.PaymentRequirements.Network, Payer: req.payer()}
+
+	s.mu.Lock()
+	if req.injected {
+		resp.ErrorReason = ReasonInjected
+	} else {
+		resp.ErrorReason = s.check(&req.request)
+	}
+	if resp.ErrorReason == "" {
+		key := req.nonceKey()
+		sum := sha256.Sum256([]byte(key))
+		resp.Success = true
+		resp.Transaction = "0x" + hex.EncodeToString(sum[:])
+		s.nonces[key] = resp.Transaction
+		s.stats.Settled++
+	} else {
+		s.stats.Rejected++
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// pendingRequest is a decoded request and whether a reject failure was
+// injected into it
+type pendingRequest struct {
+	request
+	injected bool
+}
+
+// begin applies the endpoint's latency and failure injection and decodes the
+// body. It returns false once a response has been written.
+func (s *Server) begin(w http.ResponseWriter, r *http.Request, endpoint func(Config) EndpointConfig) (*pendingRequest, bool) {
+	s.mu.Lock()
+	cfg := endpoint(s.config)
+	s.mu.Unlock()
+
+	if cfg.LatencyMs > 0 {
+		select {
+		case <-time.After(time.Duration(cfg.LatencyMs) * time.Millisecond):
+		case <-r.Context().Done():
+			return nil, false
+		}
+	}
+
+	var req pendingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req.request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return nil, false
+	}
+
+	if cfg.Failure == FailNone || (cfg.FailureRate > 0 && rand.Float64() >= cfg.FailureRate) {
+		return &req, true
+	}
+
+	s.mu.Lock()
+	s.stats.InjectedFailures++
+	s.mu.Unlock()
+	slog.Info("injecting facilitator failure", "path", r.URL.Path, "failure", cfg.Failure)
+
+	switch cfg.Failure {
+	case FailError:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "injected failure"})
+		return nil, false
+	case FailTimeout:
+		select {
+		case <-r.Context().Done():
+		case <-time.After(maxTimeoutHold):
+			writeJSON(w, http.StatusGatewayTimeout, map[string]string{"error": "injected timeout"})
+		}
+		return nil, false
+	default:
+		req.injected = true
+		return &req, true
+	}
+}
+
+func (s *Server) handleSupported(w http.ResponseWriter, r *http.Request) {
+	type kind struct {
+		X402Version int    `json:"x402Version"`
+		Scheme      string `json:"scheme"`
+		Network     string `json:"network"`
+	}
+	var kinds []kind
+	for _, network := range []string{"eip155:8453", "eip155:84532", "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1"} {
+		kinds = append(kinds, kind{X402Version: 2, Scheme: "exact", Network: network})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"kinds": kinds})
+}
+
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	cfg := s.config
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+func (s *Server) handlePutConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid config"})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.mu.Lock()
+	s.config = cfg
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Stats())
+}
+
+// handleReset forgets settled nonces and counters, keeping the config
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.nonces = make(map[string]string)
+	s.stats = Stats{}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+}
+
+// Stats returns request counts since startup or the last reset
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Nonces = len(s.nonces)
+	return stats
+}
+
+// Validate checks the failure modes and rates
+func (c Config) Validate() error {
+	endpoints := []struct {
+		name string
+		cfg  EndpointConfig
+	}{{"verify", c.Verify}, {"settle", c.Settle}}
+	for _, e := range endpoints {
+		switch e.cfg.Failure {
+		case FailNone, FailReject, FailError, FailTimeout:
+		default:
+			return fmt.Errorf("%s: unknown failure mode %q (want reject, error or timeout)", e.name, e.cfg.Failure)
+		}
+		if e.cfg.FailureRate < 0 || e.cfg.FailureRate > 1 {
+			return fmt.Errorf("%s: failure_rate must be between 0 and 1", e.name)
+		}
+		if e.cfg.LatencyMs < 0 {
+			return fmt.Errorf("%s: latency_ms must not be negative", e.name)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
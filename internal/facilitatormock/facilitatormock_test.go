@@ -0,0 +1,132 @@
+package facilitatormock
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPayer = "0x1111111111111111111111111111111111111111"
+	testPayTo = "0x2222222222222222222222222222222222222222"
+)
+
+func paymentBody(nonce, amount string, validBefore time.Time) map[string]any {
+	return map[string]any{
+		"x402Version": 2,
+		"paymentPayload": map[string]any{
+			"x402Version": 2,
+			"payload": map[string]any{
+				"signature": "0xsig",
+				"authorization": map[string]any{
+					"from":        testPayer,
+					"to":          testPayTo,
+					"value":       amount,
+					"validAfter":  "0",
+					"validBefore": strconv.FormatInt(validBefore.Unix(), 10),
+					"nonce":       nonce,
+				},
+			},
+		},
+		"paymentRequirements": map[string]any{
+			"scheme":  "exact",
+			"network": "eip155:84532",
+			"amount":  "1000",
+			"payTo":   testPayTo,
+		},
+	}
+}
+
+func post(t *testing.T, h http.Handler, path string, body any) (int, map[string]any) {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+func TestVerifyAndSettle(t *testing.T) {
+	s := New(Config{})
+	h := s.Handler()
+	valid := time.Now().Add(time.Minute)
+
+	code, resp := post(t, h, "/verify", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["isValid"])
+	assert.Equal(t, testPayer, resp["payer"])
+
+	code, resp = post(t, h, "/settle", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["success"])
+	assert.NotEmpty(t, resp["transaction"])
+	assert.Equal(t, "eip155:84532", resp["network"])
+
+	_, resp = post(t, h, "/settle", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, false, resp["success"])
+	assert.Equal(t, ReasonNonceUsed, resp["error_reason"], "a nonce settles only once")
+
+	_, resp = post(t, h, "/verify", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, ReasonNonceUsed, resp["invalidReason"])
+
+	_, resp = post(t, h, "/verify", paymentBody("0x02", "999", valid))
+	assert.Equal(t, ReasonAmountMismatch, resp["invalidReason"])
+
+	_, resp = post(t, h, "/verify", paymentBody("0x03", "1000", time.Now().Add(-time.Minute)))
+	assert.Equal(t, ReasonExpired, resp["invalidReason"])
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Verified)
+	assert.Equal(t, int64(1), stats.Settled)
+	assert.Equal(t, int64(4), stats.Rejected)
+	assert.Equal(t, 1, stats.Nonces)
+
+	code, _ = post(t, h, "/reset", nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, resp = post(t, h, "/settle", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, true, resp["success"], "reset forgets settled nonces")
+}
+
+func TestInjectedFailures(t *testing.T) {
+	s := New(Config{
+		Verify: EndpointConfig{Failure: FailReject},
+		Settle: EndpointConfig{Failure: FailError},
+	})
+	h := s.Handler()
+	valid := time.Now().Add(time.Minute)
+
+	code, resp := post(t, h, "/verify", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["isValid"])
+	assert.Equal(t, ReasonInjected, resp["invalidReason"])
+
+	code, _ = post(t, h, "/settle", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, int64(2), s.Stats().InjectedFailures)
+	assert.Equal(t, 0, s.Stats().Nonces, "failed settlements don't consume the nonce")
+
+	// Reconfigure at runtime
+	b, _ := json.Marshal(Config{Settle: EndpointConfig{LatencyMs: 20}})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/config", bytes.NewReader(b)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	start := time.Now()
+	_, resp = post(t, h, "/settle", paymentBody("0x01", "1000", valid))
+	assert.Equal(t, true, resp["success"])
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	b, _ = json.Marshal(Config{Verify: EndpointConfig{Failure: "explode"}})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/config", bytes.NewReader(b)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
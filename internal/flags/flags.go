@@ -0,0 +1,159 @@
+// Package flags serves operator feature flags from a cached snapshot of the
+// feature_flags table, so flags can be flipped without a redeploy and checked
+// on every request without a database query.
+package flags
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Well-known flags
+const (
+	// MaintenancePrefix followed by a route path ("maintenance:/v1/scan/content")
+	// answers that endpoint with 503 while the flag is on
+	MaintenancePrefix = "maintenance:"
+	// MaintenanceAll puts every endpoint except health checks and the admin API
+	// into maintenance
+	MaintenanceAll = MaintenancePrefix + "*"
+	// PaymentsLogOnly lets requests without a valid payment through, logging
+	// what would have been charged instead of answering 402
+	PaymentsLogOnly = "payments.log_only"
+	// RejectNumericAmounts answers 400 to request amounts sent as JSON numbers
+	// rather than decimal strings ("10.50"). Scope it to accounts to roll the
+	// deprecation out gradually.
+	RejectNumericAmounts = "amounts.reject_numeric"
+)
+
+// defaultRefreshInterval is how often the snapshot is reloaded
+const defaultRefreshInterval = 15 * time.Second
+
+// Store loads feature flags
+type Store interface {
+	ListFeatureFlags(ctx context.Context) ([]*db.FeatureFlag, error)
+}
+
+// Flags holds the current flag snapshot. A nil Flags has every flag off.
+type Flags struct {
+	store    Store
+	interval time.Duration
+
+	mu    sync.RWMutex
+	flags map[string]*db.FeatureFlag
+}
+
+// New creates a flag cache and loads the current flags. A failed load is
+// logged and leaves every flag off until the next refresh succeeds.
+func New(ctx context.Context, store Store, interval time.Duration) *Flags {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	f := &Flags{
+		store:    store,
+		interval: interval,
+		flags:    make(map[string]*db.FeatureFlag),
+	}
+	if err := f.Refresh(ctx); err != nil {
+		slog.Warn("failed to load feature flags", "error", err)
+	}
+	return f
+}
+
+// Refresh reloads the flag snapshot
+func (f *Flags) Refresh(ctx context.Context) error {
+	if f == nil {
+		return nil
+	}
+	list, err := f.store.ListFeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+	next := make(map[string]*db.FeatureFlag, len(list))
+	for _, flag := range list {
+		next[flag.Name] = flag
+	}
+	f.mu.Lock()
+	f.flags = next
+	f.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the snapshot every interval until ctx is done. The last good
+// snapshot is kept while the database is unreachable.
+func (f *Flags) Run(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(ctx); err != nil {
+				slog.Warn("failed to refresh feature flags", "error", err)
+			}
+		}
+	}
+}
+
+// Get returns a flag from the snapshot
+func (f *Flags) Get(name string) (*db.FeatureFlag, bool) {
+	if f == nil {
+		return nil, false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	flag, ok := f.flags[name]
+	return flag, ok
+}
+
+// Enabled reports whether a flag is on for an account. With a nil accountID
+// (unauthenticated traffic) a flag is on only when enabled for 100%.
+func (f *Flags) Enabled(name string, accountID *uuid.UUID) bool {
+	flag, ok := f.Get(name)
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if flag.Percent >= 100 {
+		return true
+	}
+	if accountID == nil {
+		return false
+	}
+	if slices.Contains(flag.AccountIDs, *accountID) {
+		return true
+	}
+	return bucket(name, *accountID) < flag.Percent
+}
+
+// Maintenance returns the maintenance flag covering a route path, if one is on
+func (f *Flags) Maintenance(path string) (*db.FeatureFlag, bool) {
+	for _, name := range []string{MaintenancePrefix + path, MaintenanceAll} {
+		if f.Enabled(name, nil) {
+			flag, _ := f.Get(name)
+			return flag, true
+		}
+	}
+	return nil, false
+}
+
+// bucket places an account in [0, 100) for a flag. The same account lands in
+// the same bucket for a flag every time, so raising the percentage only adds
+// accounts; different flags use independent buckets.
+func bucket(name string, accountID uuid.UUID) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	h.Write(accountID[:])
+	return float64(binary.BigEndian.Uint64(h.Sum(nil))%10000) / 100
+}
@@ -0,0 +1,112 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stronghold/internal/db"
+
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	flags []*db.FeatureFlag
+	err   error
+}
+
+func (f *fakeStore) ListFeatureFlags(context.Context) ([]*db.FeatureFlag, error) {
+	return f.flags, f.err
+}
+
+func TestEnabled(t *testing.T) {
+	listed := uuid.New()
+	store := &fakeStore{flags: []*db.FeatureFlag{
+		{Name: "all", Enabled: true, Percent: 100},
+		{Name: "off", Enabled: false, Percent: 100},
+		{Name: "listed", Enabled: true, Percent: 0, AccountIDs: []uuid.UUID{listed}},
+	}}
+	f := New(context.Background(), store, 0)
+
+	if !f.Enabled("all", nil) || !f.Enabled("all", &listed) {
+		t.Error("expected flag at 100% to be on for everyone")
+	}
+	if f.Enabled("off", &listed) {
+		t.Error("expected disabled flag to be off")
+	}
+	if f.Enabled("missing", &listed) {
+		t.Error("expected unknown flag to be off")
+	}
+	if !f.Enabled("listed", &listed) {
+		t.Error("expected flag to be on for a listed account")
+	}
+	other := uuid.New()
+	if f.Enabled("listed", &other) || f.Enabled("listed", nil) {
+		t.Error("expected flag at 0% to be off for other accounts")
+	}
+
+	var nilFlags *Flags
+	if nilFlags.Enabled("all", nil) {
+		t.Error("expected nil Flags to have every flag off")
+	}
+}
+
+func TestEnabled_PercentIsStableAndMonotonic(t *testing.T) {
+	store := &fakeStore{flags: []*db.FeatureFlag{{Name: "cohort", Enabled: true, Percent: 30}}}
+	f := New(context.Background(), store, 0)
+
+	accounts := make([]uuid.UUID, 2000)
+	on := map[uuid.UUID]bool{}
+	for i := range accounts {
+		accounts[i] = uuid.New()
+		on[accounts[i]] = f.Enabled("cohort", &accounts[i])
+	}
+	count := 0
+	for _, v := range on {
+		if v {
+			count++
+		}
+	}
+	if count < 450 || count > 750 {
+		t.Errorf("expected about 30%% of accounts, got %d of %d", count, len(accounts))
+	}
+
+	// Raising the percentage only adds accounts
+	store.flags[0] = &db.FeatureFlag{Name: "cohort", Enabled: true, Percent: 60}
+	if err := f.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range accounts {
+		if on[id] && !f.Enabled("cohort", &id) {
+			t.Fatal("account dropped out of the cohort when the percentage was raised")
+		}
+	}
+}
+
+func TestRefresh_KeepsSnapshotOnError(t *testing.T) {
+	store := &fakeStore{flags: []*db.FeatureFlag{{Name: PaymentsLogOnly, Enabled: true, Percent: 100}}}
+	f := New(context.Background(), store, 0)
+
+	store.err = errors.New("database unavailable")
+	if err := f.Refresh(context.Background()); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if !f.Enabled(PaymentsLogOnly, nil) {
+		t.Error("expected last good snapshot to be kept")
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	store := &fakeStore{flags: []*db.FeatureFlag{
+		{Name: MaintenancePrefix + "/v1/scan/output", Enabled: true, Percent: 100, Message: "upgrading"},
+	}}
+	f := New(context.Background(), store, 0)
+
+	flag, ok := f.Maintenance("/v1/scan/output")
+	if !ok || flag.Message != "upgrading" {
+		t.Errorf("expected output endpoint in maintenance, got %v %v", flag, ok)
+	}
+	if _, ok := f.Maintenance("/v1/scan/content"); ok {
+		t.Error("expected content endpoint to be available")
+	}
+}
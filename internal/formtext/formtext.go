@@ -0,0 +1,270 @@
+// Package formtext extracts scannable text from multipart/form-data and
+// application/x-www-form-urlencoded bodies. Text fields, text file contents,
+// and file names are returned; binary parts are skipped without being read
+// into memory. Raw text/plain and application/octet-stream bodies are decoded
+// whole.
+package formtext
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	mediaMultipart   = "multipart/form-data"
+	mediaURLEncoded  = "application/x-www-form-urlencoded"
+	mediaPlainText   = "text/plain"
+	mediaOctetStream = "application/octet-stream"
+
+	// sniffLen is how much of an untyped file part is inspected to decide
+	// whether it is text, matching http.DetectContentType
+	sniffLen = 512
+)
+
+// ErrNotForm is returned when the content type is not a supported form encoding
+var ErrNotForm = errors.New("not a form content type")
+
+// ErrNotRaw is returned when the content type is not a raw text body
+var ErrNotRaw = errors.New("not a raw text content type")
+
+// ErrCharset is returned for raw bodies without a supported charset, or that
+// aren't valid in it
+var ErrCharset = errors.New("raw body must be UTF-8, US-ASCII or ISO-8859-1 text with a declared charset")
+
+// Field is one extracted form part
+type Field struct {
+	Name     string // Form field name
+	FileName string // Set for file uploads
+	Value    string // Text content; empty for binary files
+	Binary   bool   // True when the part was skipped as binary
+}
+
+// Result holds the parts extracted from a form body
+type Result struct {
+	Fields    []Field
+	Truncated bool // The body ended mid-form (e.g. it was cut at a size limit)
+}
+
+// IsForm reports whether contentType is multipart/form-data or
+// application/x-www-form-urlencoded
+func IsForm(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == mediaMultipart || mediaType == mediaURLEncoded
+}
+
+// Extract parses a form body. A body that ends early is not an error: the
+// parts read so far are returned with Truncated set.
+func Extract(body []byte, contentType string) (*Result, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrNotForm
+	}
+
+	switch mediaType {
+	case mediaURLEncoded:
+		return extractURLEncoded(body), nil
+	case mediaMultipart:
+		boundary := params["boundary"]
+		if boundary == "" {
+			return nil, errors.New("multipart body has no boundary")
+		}
+		res := &Result{}
+		extractMultipart(bytes.NewReader(body), boundary, res)
+		return res, nil
+	default:
+		return nil, ErrNotForm
+	}
+}
+
+// IsRaw reports whether contentType is text/plain or application/octet-stream,
+// whose whole body is the text
+func IsRaw(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == mediaPlainText || mediaType == mediaOctetStream
+}
+
+// Raw decodes a text/plain or application/octet-stream body as text. Plain
+// text defaults to UTF-8; octet streams must name their charset, as they could
+// otherwise hold anything. UTF-8, US-ASCII and ISO-8859-1 are supported.
+func Raw(body []byte, contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != mediaPlainText && mediaType != mediaOctetStream) {
+		return "", ErrNotRaw
+	}
+
+	charset := strings.ToLower(params["charset"])
+	if charset == "" && mediaType == mediaPlainText {
+		charset = "utf-8"
+	}
+	switch charset {
+	case "utf-8", "utf8", "us-ascii":
+		if !utf8.Valid(body) {
+			return "", ErrCharset
+		}
+		return string(body), nil
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(body))
+		for i, b := range body {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	default:
+		return "", ErrCharset
+	}
+}
+
+func extractURLEncoded(body []byte) *Result {
+	res := &Result{}
+	for _, pair := range strings.Split(string(body), "&") {
+		if pair == "" {
+			continue
+		}
+		rawName, rawValue, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue // Still scan text with malformed escapes
+		}
+		res.Fields = append(res.Fields, Field{Name: name, Value: value})
+	}
+	return res
+}
+
+func extractMultipart(r io.Reader, boundary string, res *Result) {
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			res.Truncated = true
+			return
+		}
+
+		field := Field{Name: part.FormName(), FileName: part.FileName()}
+		partType := part.Header.Get("Content-Type")
+
+		// multipart/mixed inside a form field carries multiple files
+		if mediaType, params, err := mime.ParseMediaType(partType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+			extractMultipart(part, params["boundary"], res)
+			continue
+		}
+
+		text, truncated := readTextPart(part, partType, field.FileName != "")
+		if text == nil {
+			field.Binary = true
+		} else {
+			field.Value = string(text)
+		}
+		res.Fields = append(res.Fields, field)
+		if truncated {
+			res.Truncated = true
+			return
+		}
+	}
+}
+
+// readTextPart returns the part's content if it is text, or nil for binary
+// parts, which are drained without buffering
+func readTextPart(part *multipart.Part, partType string, isFile bool) ([]byte, bool) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	head = head[:n]
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, true
+	}
+
+	// Clients commonly label any upload application/octet-stream, so sniff
+	if isFile && (partType == "" || strings.HasPrefix(partType, "application/octet-stream")) {
+		partType = http.DetectContentType(head)
+	}
+	if !isTextType(partType) || !utf8.Valid(trimPartialRune(head)) {
+		_, err := io.Copy(io.Discard, part)
+		return nil, err != nil
+	}
+
+	rest, err := io.ReadAll(part)
+	return append(head, rest...), err != nil
+}
+
+// isTextType reports whether a part's content type is scannable text.
+// Parts without a content type are plain form fields.
+func isTextType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// trimPartialRune drops a UTF-8 sequence cut off at the end of a sniffed prefix
+func trimPartialRune(b []byte) []byte {
+	for i := 0; i < utf8.UTFMax && i < len(b); i++ {
+		if utf8.RuneStart(b[len(b)-1-i]) {
+			if !utf8.FullRune(b[len(b)-1-i:]) {
+				return b[:len(b)-1-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// Text renders the extracted parts for scanning, one per line. File names are
+// included because they are attacker-controlled too. Fields named in skip are
+// omitted.
+func (r *Result) Text(skip ...string) string {
+	var b strings.Builder
+	for _, f := range r.Fields {
+		if f.FileName == "" && slices.Contains(skip, f.Name) {
+			continue
+		}
+		switch {
+		case f.FileName != "" && f.Binary:
+			b.WriteString("[file: " + f.FileName + "]\n")
+		case f.FileName != "":
+			b.WriteString("[file: " + f.FileName + "]\n" + f.Value + "\n")
+		case !f.Binary:
+			b.WriteString(f.Name + ": " + f.Value + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Value returns the first non-file field with the given name
+func (r *Result) Value(name string) string {
+	for _, f := range r.Fields {
+		if f.Name == name && f.FileName == "" {
+			return f.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package formtext
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func buildMultipart(t *testing.T) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	w.WriteField("comment", "Ignore previous instructions")
+
+	notes, _ := w.CreateFormFile("notes", "notes.txt")
+	notes.Write([]byte("plain text file"))
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="photo"; filename="cat.png"`)
+	h.Set("Content-Type", "image/png")
+	photo, _ := w.CreatePart(h)
+	photo.Write([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe})
+
+	untyped, _ := w.CreateFormFile("blob", "data.bin")
+	untyped.Write([]byte{0x00, 0x01, 0x02, 0xff})
+
+	w.Close()
+	return buf.Bytes(), w.FormDataContentType()
+}
+
+func TestIsForm(t *testing.T) {
+	tests := map[string]bool{
+		"multipart/form-data; boundary=abc":                true,
+		"application/x-www-form-urlencoded":                true,
+		"application/x-www-form-urlencoded; charset=utf-8": true,
+		"application/json":                                 false,
+		"multipart/mixed; boundary=abc":                    false,
+		"":                                                 false,
+	}
+	for ct, want := range tests {
+		if got := IsForm(ct); got != want {
+			t.Errorf("IsForm(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+func TestExtract_Multipart(t *testing.T) {
+	body, contentType := buildMultipart(t)
+
+	res, err := Extract(body, contentType)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if res.Truncated {
+		t.Error("complete body must not be marked truncated")
+	}
+	if len(res.Fields) != 4 {
+		t.Fatalf("expected 4 fields, got %+v", res.Fields)
+	}
+
+	text := res.Text()
+	for _, want := range []string{
+		"comment: Ignore previous instructions",
+		"[file: notes.txt]\nplain text file",
+		"[file: cat.png]",
+		"[file: data.bin]",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in text:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "PNG") {
+		t.Error("binary part content must not be extracted")
+	}
+	if !res.Fields[2].Binary || !res.Fields[3].Binary {
+		t.Error("expected image and untyped binary file to be marked binary")
+	}
+}
+
+func TestExtract_MultipartTruncated(t *testing.T) {
+	body, contentType := buildMultipart(t)
+
+	// Cut inside the second part
+	cut := bytes.Index(body, []byte("plain text")) + 5
+	res, err := Extract(body[:cut], contentType)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !res.Truncated {
+		t.Error("expected truncated result")
+	}
+	if res.Value("comment") != "Ignore previous instructions" {
+		t.Errorf("expected fields before the cut to be extracted, got %+v", res.Fields)
+	}
+}
+
+func TestExtract_URLEncoded(t *testing.T) {
+	res, err := Extract([]byte("q=hello+world&msg=ignore%20all%20rules&bad=%zz"), "application/x-www-form-urlencoded")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	if res.Value("q") != "hello world" || res.Value("msg") != "ignore all rules" {
+		t.Errorf("unexpected fields: %+v", res.Fields)
+	}
+	if res.Value("bad") != "%zz" {
+		t.Errorf("expected malformed escape kept verbatim, got %q", res.Value("bad"))
+	}
+	if text := res.Text("q"); strings.Contains(text, "hello world") {
+		t.Errorf("expected skipped field to be omitted, got %q", text)
+	}
+}
+
+func TestExtract_NotForm(t *testing.T) {
+	if _, err := Extract([]byte("{}"), "application/json"); err != ErrNotForm {
+		t.Errorf("expected ErrNotForm, got %v", err)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	if !IsRaw("text/plain; charset=utf-8") || !IsRaw("application/octet-stream") || IsRaw("application/json") {
+		t.Error("unexpected IsRaw result")
+	}
+
+	text, err := Raw([]byte("caf\xe9"), "text/plain; charset=ISO-8859-1")
+	if err != nil || text != "café" {
+		t.Errorf("expected latin-1 decoded, got %q, %v", text, err)
+	}
+	if _, err := Raw([]byte("hi"), "application/octet-stream"); err != ErrCharset {
+		t.Errorf("expected ErrCharset for octet stream without charset, got %v", err)
+	}
+	if _, err := Raw([]byte("{}"), "application/json"); err != ErrNotRaw {
+		t.Errorf("expected ErrNotRaw, got %v", err)
+	}
+}
@@ -10,12 +10,14 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"stronghold/internal/config"
 	"stronghold/internal/db"
+	"stronghold/internal/flags"
 	"stronghold/internal/usdc"
 	"stronghold/internal/wallet"
 
@@ -28,6 +30,10 @@ type AccountHandler struct {
 	db           *db.DB
 	authConfig   *AuthConfig
 	stripeConfig *config.StripeConfig
+	flags        *flags.Flags
+	overview     *overviewCache
+	balances     *balanceCache
+	runwayAlert  float64 // Runway in days below which low_balance is set
 }
 
 // NewAccountHandler creates a new account handler
@@ -39,23 +45,38 @@ func NewAccountHandler(database *db.DB, authConfig *AuthConfig, stripeConfig *co
 	}
 }
 
+// SetFlags enables per-account rollout of the numeric amount deprecation
+func (h *AccountHandler) SetFlags(f *flags.Flags) {
+	h.flags = f
+}
+
+// SetRunwayAlert flags account runways shorter than days as a low balance;
+// 0 never flags them
+func (h *AccountHandler) SetRunwayAlert(days float64) {
+	h.runwayAlert = days
+}
+
 // RegisterRoutes registers account routes
 func (h *AccountHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
 	group := app.Group("/v1/account")
 
 	// All account routes require authentication
 	group.Get("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetAccount)
+	group.Get("/overview", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetOverview)
 	group.Get("/usage", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetUsage)
 	group.Get("/usage/stats", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetUsageStats)
+	group.Get("/usage/export", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ExportUsage)
+	group.Get("/usage/verify", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.VerifyUsage)
 	group.Post("/deposit", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.InitiateDeposit)
 	group.Get("/deposits", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetDeposits)
 	group.Put("/wallets", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.UpdateWallets)
 	group.Get("/balances", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetBalances)
+	group.Get("/balances/history", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetBalanceHistory)
 }
 
 // GetAccount returns the current account details
 // @Summary Get account details
-// @Description Returns the authenticated user's account details including balance and deposit stats
+// @Description Returns the authenticated user's account details including balance and deposit stats, and a runway estimate: the average daily spend over the last 7 days and how many days the balance lasts at that rate. runway.low_balance is set when it is below the low balance alert threshold, 3 days by default. The runway is omitted if it could not be estimated.
 // @Tags account
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Account details"
@@ -108,6 +129,14 @@ func (h *AccountHandler) GetAccount(c fiber.Ctx) error {
 		"last_login_at":         account.LastLoginAt,
 		"deposit_stats":         depositStats,
 	}
+
+	// Estimate how long the balance lasts at the recent spend rate
+	runway, err := h.db.GetAccountRunway(ctx, accountID, account.BalanceUSDC, h.runwayAlert, time.Now())
+	if err != nil {
+		slog.Warn("failed to estimate account runway", "account_id", accountID.String(), "error", err)
+	} else {
+		resp["runway"] = runway
+	}
 	if account.EVMWalletAddress != nil {
 		resp["wallet_address"] = account.EVMWalletAddress
 	}
@@ -171,6 +200,41 @@ func (h *AccountHandler) GetUsage(c fiber.Ctx) error {
 	})
 }
 
+// VerifyUsage checks the account's usage log hash chain for tampering
+// @Summary Verify usage log integrity
+// @Description Recomputes the hash chain over the account's usage logs and reports the first record that does not match
+// @Tags account
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Verification result"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security CookieAuth
+// @Router /v1/account/usage/verify [get]
+func (h *AccountHandler) VerifyUsage(c fiber.Ctx) error {
+	accountIDStr := c.Locals("account_id")
+	if accountIDStr == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Not authenticated",
+		})
+	}
+
+	accountID, err := uuid.Parse(accountIDStr.(string))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	result, err := h.db.VerifyUsageLogChain(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify usage logs",
+		})
+	}
+
+	return c.JSON(result)
+}
+
 // GetUsageStatsRequest represents the query parameters for usage stats
 type GetUsageStatsRequest struct {
 	Days int `query:"days"`
@@ -253,9 +317,9 @@ func (h *AccountHandler) GetUsageStats(c fiber.Ctx) error {
 
 // InitiateDepositRequest represents a request to initiate a deposit
 type InitiateDepositRequest struct {
-	AmountUSDC float64 `json:"amount_usdc"`
-	Provider   string  `json:"provider"`
-	Network    string  `json:"network"` // "base" (default) or "solana"
+	AmountUSDC usdc.DecimalAmount `json:"amount_usdc" swaggertype:"string" example:"10.50"` // Decimal string; JSON numbers are deprecated
+	Provider   string             `json:"provider"`
+	Network    string             `json:"network"` // "base" (default) or "solana"
 }
 
 // InitiateDepositResponse represents the response after initiating a deposit
@@ -302,20 +366,19 @@ func (h *AccountHandler) InitiateDeposit(c fiber.Ctx) error {
 
 	var req InitiateDepositRequest
 	if err := c.Bind().Body(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return invalidBody(c, err)
+	}
+	if numericAmountRefused(c, h.flags, accountID, req.AmountUSDC) {
+		return numericAmountResponse(c)
 	}
 
-	if req.AmountUSDC <= 0 {
+	amountMicro := req.AmountUSDC.Micro
+	if amountMicro <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Amount must be greater than 0",
 		})
 	}
 
-	// Convert float64 request amount to MicroUSDC immediately
-	amountMicro := usdc.FromFloat(req.AmountUSDC)
-
 	// Validate provider
 	var provider db.DepositProvider
 	switch req.Provider {
@@ -644,6 +707,8 @@ type WalletBalanceInfo struct {
 	Address     string         `json:"address"`
 	BalanceUSDC usdc.MicroUSDC `json:"balance_usdc"`
 	Network     string         `json:"network"`
+	UpdatedAt   *time.Time     `json:"updated_at,omitempty"` // When the balance was read on-chain
+	Stale       bool           `json:"stale,omitempty"`      // Served from cache while a refresh runs
 	Error       string         `json:"error,omitempty"`
 }
 
@@ -656,9 +721,10 @@ type GetBalancesResponse struct {
 
 // GetBalances returns on-chain USDC balances for the account's wallets
 // @Summary Get wallet balances
-// @Description Returns on-chain USDC balances for the account's EVM and Solana wallets
+// @Description Returns on-chain USDC balances for the account's EVM and Solana wallets. Balances are cached for 30 seconds by default and served stale for up to 5 minutes more while they refresh in the background; updated_at tells when each was read on-chain. Pass fresh=true to bypass the cache, though a balance read within the last 5 seconds is still reused.
 // @Tags account
 // @Produce json
+// @Param fresh query bool false "Read balances on-chain instead of from the cache"
 // @Success 200 {object} GetBalancesResponse
 // @Failure 401 {object} map[string]string "Not authenticated"
 // @Failure 404 {object} map[string]string "Account not found"
@@ -686,11 +752,13 @@ func (h *AccountHandler) GetBalances(c fiber.Ctx) error {
 		})
 	}
 
+	fresh, _ := strconv.ParseBool(c.Query("fresh"))
+
 	resp := GetBalancesResponse{}
 	var totalUSDC usdc.MicroUSDC
 
 	// Use a single request-level timeout budget and query both chains in parallel.
-	ctx, cancel := context.WithTimeout(c.Context(), 28*time.Second)
+	ctx, cancel := context.WithTimeout(c.Context(), balanceQueryTimeout)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -708,7 +776,7 @@ func (h *AccountHandler) GetBalances(c fiber.Ctx) error {
 				Network: "base",
 			}
 
-			balance, err := queryEVMBalance(ctx, address, "base")
+			err := h.walletBalance(ctx, info, queryEVMBalance, fresh)
 			if err != nil {
 				slog.Error("failed to query EVM balance",
 					"account_id", accountID,
@@ -716,8 +784,6 @@ func (h *AccountHandler) GetBalances(c fiber.Ctx) error {
 					"error", err,
 				)
 				info.Error = "Failed to query balance"
-			} else {
-				info.BalanceUSDC = usdc.FromFloat(balance)
 			}
 
 			mu.Lock()
@@ -741,7 +807,7 @@ func (h *AccountHandler) GetBalances(c fiber.Ctx) error {
 				Network: "solana",
 			}
 
-			balance, err := querySolanaBalance(ctx, address, "solana")
+			err := h.walletBalance(ctx, info, querySolanaBalance, fresh)
 			if err != nil {
 				slog.Error("failed to query Solana balance",
 					"account_id", accountID,
@@ -749,8 +815,6 @@ func (h *AccountHandler) GetBalances(c fiber.Ctx) error {
 					"error", err,
 				)
 				info.Error = "Failed to query balance"
-			} else {
-				info.BalanceUSDC = usdc.FromFloat(balance)
 			}
 
 			mu.Lock()
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// balanceQueryTimeout bounds the on-chain lookups for one request
+	balanceQueryTimeout = 28 * time.Second
+	// balanceMinRefresh is how long a fetched balance is served even to
+	// requests asking for a fresh one, so refreshes can't hammer RPC providers
+	balanceMinRefresh = 5 * time.Second
+	// balanceCachePruneSize is how many cached balances trigger a sweep of
+	// expired ones
+	balanceCachePruneSize = 4096
+)
+
+// balanceQuery looks up a wallet's USDC balance on a network
+type balanceQuery func(ctx context.Context, address, network string) (float64, error)
+
+// cachedBalance is a wallet balance and when it was read on-chain
+type cachedBalance struct {
+	balance   usdc.MicroUSDC
+	fetchedAt time.Time
+}
+
+// balanceFetch is an on-chain lookup that concurrent requests for the same
+// wallet wait on instead of querying again
+type balanceFetch struct {
+	done    chan struct{}
+	balance cachedBalance
+	err     error
+}
+
+// balanceCache keeps on-chain wallet balances for a short TTL, so dashboard
+// loads don't each query RPC providers. Balances older than the TTL are still
+// served for staleTTL while they are refreshed in the background, and
+// concurrent lookups of one wallet share a single query.
+type balanceCache struct {
+	ttl      time.Duration
+	staleTTL time.Duration
+	mu       sync.Mutex
+	entries  map[string]cachedBalance
+	inflight map[string]*balanceFetch
+	now      func() time.Time
+}
+
+func newBalanceCache(ttl, staleTTL time.Duration) *balanceCache {
+	return &balanceCache{
+		ttl:      ttl,
+		staleTTL: staleTTL,
+		entries:  make(map[string]cachedBalance),
+		inflight: make(map[string]*balanceFetch),
+		now:      time.Now,
+	}
+}
+
+// get returns a wallet's balance and whether it is stale. A cached balance is
+// served within the TTL, or within the stale window while a refresh runs in
+// the background; fresh skips the cache unless the balance was read within
+// balanceMinRefresh.
+func (c *balanceCache) get(ctx context.Context, network, address string, fresh bool, query balanceQuery) (cachedBalance, bool, error) {
+	key := network + ":" + address
+	now := c.now()
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		age := now.Sub(cached.fetchedAt)
+		switch {
+		case age < balanceMinRefresh, !fresh && age < c.ttl:
+			return cached, false, nil
+		case !fresh && age < c.ttl+c.staleTTL:
+			c.start(key, network, address, query)
+			return cached, true, nil
+		}
+	}
+
+	f := c.start(key, network, address, query)
+	select {
+	case <-f.done:
+		return f.balance, false, f.err
+	case <-ctx.Done():
+		return cachedBalance{}, false, ctx.Err()
+	}
+}
+
+// start queries a wallet's balance unless a query is already in flight, and
+// returns the in-flight query. The query outlives the request that started
+// it, so the requests waiting on it and the cache still get its result.
+func (c *balanceCache) start(key, network, address string, query balanceQuery) *balanceFetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.inflight[key]; ok {
+		return f
+	}
+	f := &balanceFetch{done: make(chan struct{})}
+	c.inflight[key] = f
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), balanceQueryTimeout)
+		defer cancel()
+		balance, err := query(ctx, address, network)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.inflight, key)
+		f.err = err
+		if err == nil {
+			f.balance = cachedBalance{balance: usdc.FromFloat(balance), fetchedAt: c.now()}
+			c.put(key, f.balance)
+		}
+		close(f.done)
+	}()
+	return f
+}
+
+// put caches a balance, first dropping expired ones once the cache grows.
+// Callers must hold c.mu.
+func (c *balanceCache) put(key string, balance cachedBalance) {
+	if len(c.entries) >= balanceCachePruneSize {
+		now := c.now()
+		for k, cached := range c.entries {
+			if !now.Before(cached.fetchedAt.Add(c.ttl + c.staleTTL)) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = balance
+}
+
+// SetBalanceCache serves wallet balances from memory for ttl after they are
+// read on-chain, and for staleTTL beyond that while they are refreshed in the
+// background; a ttl of 0 queries the chain on every request
+func (h *AccountHandler) SetBalanceCache(ttl, staleTTL time.Duration) {
+	if ttl <= 0 {
+		h.balances = nil
+		return
+	}
+	h.balances = newBalanceCache(ttl, max(staleTTL, 0))
+}
+
+// walletBalance fills in a wallet's balance, from the cache when enabled
+func (h *AccountHandler) walletBalance(ctx context.Context, info *WalletBalanceInfo, query balanceQuery, fresh bool) error {
+	if h.balances == nil {
+		balance, err := query(ctx, info.Address, info.Network)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		info.BalanceUSDC = usdc.FromFloat(balance)
+		info.UpdatedAt = &now
+		return nil
+	}
+
+	cached, stale, err := h.balances.get(ctx, info.Network, info.Address, fresh, query)
+	if err != nil {
+		return err
+	}
+	updatedAt := cached.fetchedAt.UTC()
+	info.BalanceUSDC = cached.balance
+	info.UpdatedAt = &updatedAt
+	info.Stale = stale
+	return nil
+}
+
+// GetBalanceHistoryRequest represents the query parameters for balance history
+type GetBalanceHistoryRequest struct {
+	Days int `query:"days"`
+}
+
+// GetBalanceHistory returns the account's daily balances for charting
+// @Summary Get balance history
+// @Description Returns one point per UTC day with the on-platform balance at the end of the day, and the on-chain USDC balances of the account's Base and Solana wallets when they were read that day. The current day's point is the latest snapshot, taken hourly by default. Deposits and withdrawals over the same period are listed as events for markers; withdrawals are balance debits that settle payments owed for scans served on credit. Days before the account's first snapshot are omitted.
+// @Tags account
+// @Produce json
+// @Param days query int false "Number of days to include, ending today (default 30, max 365)"
+// @Success 200 {object} db.BalanceHistory
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security CookieAuth
+// @Router /v1/account/balances/history [get]
+func (h *AccountHandler) GetBalanceHistory(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req GetBalanceHistoryRequest
+	if err := c.Bind().Query(&req); err != nil {
+		req.Days = 30
+	}
+
+	history, err := h.db.GetBalanceHistory(c.Context(), accountID, req.Days, time.Now())
+	if err != nil {
+		slog.Error("failed to get balance history", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get balance history",
+		})
+	}
+	return c.JSON(history)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceCache(t *testing.T) {
+	cache := newBalanceCache(30*time.Second, 5*time.Minute)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	var queries atomic.Int32
+	balance := 1.5
+	query := func(ctx context.Context, address, network string) (float64, error) {
+		queries.Add(1)
+		return balance, nil
+	}
+	get := func(fresh bool) (cachedBalance, bool) {
+		t.Helper()
+		cached, stale, err := cache.get(t.Context(), "base", "0xabc", fresh, query)
+		require.NoError(t, err)
+		return cached, stale
+	}
+	// Waits for a background refresh to land in the cache
+	settle := func() {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			return len(cache.inflight) == 0
+		}, time.Second, time.Millisecond)
+	}
+
+	cached, stale := get(false)
+	assert.Equal(t, usdc.FromFloat(1.5), cached.balance)
+	assert.Equal(t, now, cached.fetchedAt)
+	assert.False(t, stale)
+	assert.EqualValues(t, 1, queries.Load())
+
+	// Within the TTL the cached balance is served, even when asked to be
+	// fresh within balanceMinRefresh
+	balance = 2
+	now = now.Add(time.Second)
+	get(true)
+	now = now.Add(20 * time.Second)
+	cached, _ = get(false)
+	assert.Equal(t, usdc.FromFloat(1.5), cached.balance)
+	assert.EqualValues(t, 1, queries.Load())
+
+	// fresh reads on-chain once the balance is older than balanceMinRefresh
+	cached, stale = get(true)
+	assert.Equal(t, usdc.FromFloat(2), cached.balance)
+	assert.False(t, stale)
+	assert.EqualValues(t, 2, queries.Load())
+
+	// Past the TTL the stale balance is served while it refreshes
+	balance = 3
+	now = now.Add(time.Minute)
+	cached, stale = get(false)
+	assert.Equal(t, usdc.FromFloat(2), cached.balance)
+	assert.True(t, stale)
+	settle()
+	assert.EqualValues(t, 3, queries.Load())
+	cached, stale = get(false)
+	assert.Equal(t, usdc.FromFloat(3), cached.balance)
+	assert.False(t, stale)
+
+	// Past the stale window the balance is read before responding
+	balance = 4
+	now = now.Add(time.Hour)
+	cached, stale = get(false)
+	assert.Equal(t, usdc.FromFloat(4), cached.balance)
+	assert.False(t, stale)
+}
+
+func TestBalanceCache_SharesQueries(t *testing.T) {
+	cache := newBalanceCache(30*time.Second, 0)
+
+	var queries atomic.Int32
+	release := make(chan struct{})
+	query := func(ctx context.Context, address, network string) (float64, error) {
+		queries.Add(1)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cached, _, err := cache.get(context.Background(), "solana", "9Wz", false, query)
+			assert.NoError(t, err)
+			assert.Equal(t, usdc.FromFloat(1), cached.balance)
+		}()
+	}
+	require.Eventually(t, func() bool { return queries.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, queries.Load())
+}
+
+func TestBalanceCache_ErrorsAreNotCached(t *testing.T) {
+	cache := newBalanceCache(30*time.Second, time.Minute)
+
+	fail := true
+	query := func(ctx context.Context, address, network string) (float64, error) {
+		if fail {
+			return 0, errors.New("429 Too Many Requests")
+		}
+		return 1, nil
+	}
+
+	_, _, err := cache.get(t.Context(), "base", "0xabc", false, query)
+	require.Error(t, err)
+
+	fail = false
+	cached, _, err := cache.get(t.Context(), "base", "0xabc", false, query)
+	require.NoError(t, err)
+	assert.Equal(t, usdc.FromFloat(1), cached.balance)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// overviewCachePruneSize is how many cached overviews trigger a sweep of
+// expired ones
+const overviewCachePruneSize = 1024
+
+// overviewCache keeps account overviews for a short TTL, so a dashboard that
+// reloads or polls is served from memory
+type overviewCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uuid.UUID]*db.AccountOverview
+}
+
+func newOverviewCache(ttl time.Duration) *overviewCache {
+	return &overviewCache{ttl: ttl, entries: make(map[uuid.UUID]*db.AccountOverview)}
+}
+
+// get returns the account's overview if it was generated within the TTL
+func (c *overviewCache) get(accountID uuid.UUID, now time.Time) *db.AccountOverview {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	overview := c.entries[accountID]
+	if overview == nil || !now.Before(overview.GeneratedAt.Add(c.ttl)) {
+		return nil
+	}
+	return overview
+}
+
+// put caches an overview, first dropping expired ones once the cache grows
+func (c *overviewCache) put(accountID uuid.UUID, overview *db.AccountOverview, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= overviewCachePruneSize {
+		for id, cached := range c.entries {
+			if !now.Before(cached.GeneratedAt.Add(c.ttl)) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[accountID] = overview
+}
+
+// SetOverviewCache serves account overviews from memory for ttl after they
+// are read; 0 reads every request from the database
+func (h *AccountHandler) SetOverviewCache(ttl time.Duration) {
+	if ttl <= 0 {
+		h.overview = nil
+		return
+	}
+	h.overview = newOverviewCache(ttl)
+}
+
+// GetOverview returns the dashboard's account summary
+// @Summary Get account overview
+// @Description Returns everything the dashboard shows on load in one request: the balance, scans, blocks, block rate and spend over the last 24 hours, 7 days and 30 days, and the 10 most recent scans and deposits. Usage comes from hourly rollups, so windows are whole UTC hours ending with the current one. Responses may be cached for up to 30 seconds by default; generated_at tells when the overview was read.
+// @Tags account
+// @Produce json
+// @Success 200 {object} db.AccountOverview
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security CookieAuth
+// @Router /v1/account/overview [get]
+func (h *AccountHandler) GetOverview(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var overview *db.AccountOverview
+	if h.overview != nil {
+		overview = h.overview.get(accountID, now)
+	}
+	if overview == nil {
+		overview, err = h.db.GetAccountOverview(c.Context(), accountID, now)
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Account not found",
+			})
+		}
+		if err != nil {
+			slog.Error("failed to get account overview", "account_id", accountID.String(), "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get account overview",
+			})
+		}
+		if h.overview != nil {
+			h.overview.put(accountID, overview, now)
+		}
+	}
+
+	if h.overview != nil {
+		maxAge := int(overview.GeneratedAt.Add(h.overview.ttl).Sub(now).Seconds())
+		c.Set(fiber.HeaderCacheControl, "private, max-age="+strconv.Itoa(max(maxAge, 0)))
+	}
+	return c.JSON(overview)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverviewCache(t *testing.T) {
+	cache := newOverviewCache(30 * time.Second)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	accountID := uuid.New()
+
+	assert.Nil(t, cache.get(accountID, now))
+
+	overview := &db.AccountOverview{GeneratedAt: now}
+	cache.put(accountID, overview, now)
+	assert.Same(t, overview, cache.get(accountID, now.Add(29*time.Second)))
+	assert.Nil(t, cache.get(accountID, now.Add(30*time.Second)), "expires after the TTL")
+	assert.Nil(t, cache.get(uuid.New(), now))
+
+	// Expired overviews are swept once the cache fills up
+	for range overviewCachePruneSize {
+		cache.put(uuid.New(), &db.AccountOverview{GeneratedAt: now}, now)
+	}
+	later := now.Add(time.Minute)
+	cache.put(accountID, &db.AccountOverview{GeneratedAt: later}, later)
+	assert.Len(t, cache.entries, 1)
+}
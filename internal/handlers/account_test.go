@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http/httptest"
 	"strconv"
 	"strings"
@@ -14,9 +15,11 @@ import (
 
 	"stronghold/internal/db"
 	"stronghold/internal/db/testutil"
+	"stronghold/internal/secure"
 	"stronghold/internal/usdc"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,7 +40,7 @@ func setupAccountTest(t *testing.T) (*fiber.App, *AuthHandler, *AccountHandler,
 	require.NoError(t, err)
 
 	authConfig := &AuthConfig{
-		JWTSecret:       "test-secret-key-for-testing",
+		JWTSecret:       secure.NewSecretString("test-secret-key-for-testing"),
 		AccessTokenTTL:  15 * time.Minute,
 		RefreshTokenTTL: 90 * 24 * time.Hour,
 		DashboardURL:    "http://localhost:3000",
@@ -122,6 +125,11 @@ func TestGetAccount_ReturnsStats(t *testing.T) {
 	stats := body["deposit_stats"].(map[string]interface{})
 	assert.Contains(t, stats, "total_deposits")
 	assert.Contains(t, stats, "total_deposited_usdc")
+
+	// A new account has spent nothing, so there is no runway estimate yet
+	runway := body["runway"].(map[string]interface{})
+	assert.Nil(t, runway["days_remaining"])
+	assert.Equal(t, false, runway["low_balance"])
 }
 
 func TestGetAccount_SolanaOnly_OmitsLegacyWalletAddress(t *testing.T) {
@@ -293,6 +301,52 @@ func TestGetBalances_QueriesWalletsInParallel(t *testing.T) {
 	assert.GreaterOrEqual(t, atomic.LoadInt32(&maxActive), int32(2), "wallet balance lookups should overlap")
 }
 
+func TestGetBalances_Cached(t *testing.T) {
+	app, _, accountHandler, testDB, database := setupAccountTest(t)
+	defer testDB.Close(t)
+	defer database.Close()
+	accountHandler.SetBalanceCache(time.Minute, time.Minute)
+
+	accountNumber, accessToken := createAuthenticatedAccount(t, app)
+	account, err := database.GetAccountByNumber(t.Context(), accountNumber)
+	require.NoError(t, err)
+	evmAddr := "0x1234567890abcdef1234567890abcdef12345678"
+	require.NoError(t, database.UpdateWalletAddresses(t.Context(), account.ID, &evmAddr, nil))
+
+	origQueryEVM := queryEVMBalance
+	defer func() { queryEVMBalance = origQueryEVM }()
+	var queries int32
+	queryEVMBalance = func(ctx context.Context, address, network string) (float64, error) {
+		atomic.AddInt32(&queries, 1)
+		return 1.25, nil
+	}
+
+	get := func(url string) GetBalancesResponse {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Cookie", AccessTokenCookie+"="+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, 200, resp.StatusCode)
+		var body GetBalancesResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	first := get("/v1/account/balances")
+	require.NotNil(t, first.EVM)
+	require.NotNil(t, first.EVM.UpdatedAt)
+	assert.Equal(t, usdc.FromFloat(1.25), first.TotalUSDC)
+
+	cached := get("/v1/account/balances")
+	assert.Equal(t, first.EVM.UpdatedAt, cached.EVM.UpdatedAt, "served from the cache")
+	assert.False(t, cached.EVM.Stale)
+
+	// A balance read moments ago is reused even when asked to be fresh
+	get("/v1/account/balances?fresh=true")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&queries))
+}
+
 func TestGetUsageStats_DateRange(t *testing.T) {
 	app, _, _, testDB, database := setupAccountTest(t)
 	defer testDB.Close(t)
@@ -371,6 +425,29 @@ func TestGetUsage_Pagination(t *testing.T) {
 	assert.Contains(t, body, "offset")
 }
 
+func TestVerifyUsage_EmptyChain(t *testing.T) {
+	app, _, _, testDB, database := setupAccountTest(t)
+	defer testDB.Close(t)
+	defer database.Close()
+
+	_, accessToken := createAuthenticatedAccount(t, app)
+
+	req := httptest.NewRequest("GET", "/v1/account/usage/verify", nil)
+	req.Header.Set("Cookie", AccessTokenCookie+"="+accessToken)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	assert.Equal(t, true, body["valid"])
+	assert.Equal(t, float64(0), body["records"])
+}
+
 func TestInitiateDeposit_Stripe_RequiresWallet(t *testing.T) {
 	app, _, _, testDB, database := setupAccountTest(t)
 	defer testDB.Close(t)
@@ -712,3 +789,81 @@ func TestGetAccount_WithDeposits(t *testing.T) {
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, totalDeposited, int64(100_000_000)) // 100 USDC in microUSDC
 }
+
+func TestGetOverview_Cached(t *testing.T) {
+	app, _, accountHandler, testDB, database := setupAccountTest(t)
+	defer testDB.Close(t)
+	defer database.Close()
+	accountHandler.SetOverviewCache(time.Minute)
+
+	_, accessToken := createAuthenticatedAccount(t, app)
+
+	get := func() (db.AccountOverview, string) {
+		req := httptest.NewRequest("GET", "/v1/account/overview", nil)
+		req.Header.Set("Cookie", AccessTokenCookie+"="+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, 200, resp.StatusCode)
+		var body db.AccountOverview
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body, resp.Header.Get("Cache-Control")
+	}
+
+	first, cacheControl := get()
+	assert.Equal(t, "private, max-age=60", cacheControl)
+	assert.Equal(t, int64(0), first.Last30d.Scans)
+	assert.Empty(t, first.RecentEvents)
+
+	var accountID uuid.UUID
+	require.NoError(t, testDB.Pool.QueryRow(context.Background(), `SELECT id FROM accounts LIMIT 1`).Scan(&accountID))
+	require.NoError(t, database.CreateUsageLog(context.Background(), &db.UsageLog{
+		AccountID: accountID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+		Status: "success", Metadata: map[string]any{"auth_method": "api_key", "decision": "ALLOW"},
+	}))
+
+	cached, _ := get()
+	assert.Equal(t, first.GeneratedAt, cached.GeneratedAt, "served from the cache")
+	assert.Equal(t, int64(0), cached.Last24h.Scans)
+
+	accountHandler.SetOverviewCache(0)
+	fresh, cacheControl := get()
+	assert.Empty(t, cacheControl)
+	assert.Equal(t, int64(1), fresh.Last24h.Scans)
+	assert.Len(t, fresh.RecentEvents, 1)
+}
+
+func TestExportUsage(t *testing.T) {
+	app, _, _, testDB, database := setupAccountTest(t)
+	defer testDB.Close(t)
+	defer database.Close()
+
+	_, accessToken := createAuthenticatedAccount(t, app)
+
+	var accountID uuid.UUID
+	require.NoError(t, testDB.Pool.QueryRow(context.Background(), `SELECT id FROM accounts LIMIT 1`).Scan(&accountID))
+	require.NoError(t, database.UpdateBalance(context.Background(), accountID, usdc.FromFloat(1.0)))
+	latency := 40
+	require.NoError(t, database.CreateUsageLog(context.Background(), &db.UsageLog{
+		AccountID: accountID, RequestID: uuid.NewString(), Endpoint: "/v1/scan/content", Method: "POST",
+		CostUSDC: usdc.MicroUSDC(1000), Status: "success", LatencyMs: &latency,
+		Metadata: map[string]any{"auth_method": "api_key", "decision": "BLOCK"},
+	}))
+
+	req := httptest.NewRequest("GET", "/v1/account/usage/export?days=7", nil)
+	req.Header.Set("Cookie", AccessTokenCookie+"="+accessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	today := time.Now().UTC().Format("2006-01-02")
+	assert.Equal(t,
+		"date,requests,cost_usdc,threats_detected,blocked,spend_usdc,latency_p50_ms,latency_p95_ms,latency_p99_ms\n"+
+			today+",1,0.001,0,1,0.001,40,40,40\n",
+		string(body))
+}
@@ -0,0 +1,551 @@
+package handlers
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"stronghold/internal/abuse"
+	"stronghold/internal/backends"
+	"stronghold/internal/canary"
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/flags"
+	"stronghold/internal/middleware/ratelimit"
+	"stronghold/internal/settlement"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// defaultCanaryWindow is how far back canary results are summarized by default
+const defaultCanaryWindow = 7 * 24 * time.Hour
+
+// AdminHandler handles operator endpoints
+type AdminHandler struct {
+	db         *db.DB
+	scanner    *stronghold.Scanner
+	canary     *canary.Canary
+	flags      *flags.Flags
+	rateLimits RateLimitStats
+	retention  *config.PaymentRetentionConfig
+	pruner     PaymentPruner
+	region     *config.RegionConfig
+	keys       KeyChecker
+	backends   *backends.Router
+	abuse      *abuse.Detector
+	prepaid    *config.PrepaidCodeConfig
+}
+
+// RateLimitStats reports rate limiter counters
+type RateLimitStats interface {
+	Stats() []ratelimit.Stats
+}
+
+// PaymentPruner reports the payment retention worker's last pass
+type PaymentPruner interface {
+	LastRun() *settlement.RetentionRun
+}
+
+// NewAdminHandler creates a new admin handler. canary may be nil when the
+// canary is disabled.
+func NewAdminHandler(database *db.DB, scanner *stronghold.Scanner, c *canary.Canary, f *flags.Flags) *AdminHandler {
+	return &AdminHandler{
+		db:      database,
+		scanner: scanner,
+		canary:  c,
+		flags:   f,
+	}
+}
+
+// SetRateLimits exposes rate limiter counters at /v1/admin/ratelimit
+func (h *AdminHandler) SetRateLimits(r RateLimitStats) {
+	h.rateLimits = r
+}
+
+// SetPaymentRetention exposes payment table size and pruning at
+// /v1/admin/payments/retention. p may be nil when retention is disabled.
+func (h *AdminHandler) SetPaymentRetention(cfg *config.PaymentRetentionConfig, p PaymentPruner) {
+	h.retention = cfg
+	h.pruner = p
+}
+
+// SetRegion enables account region pinning against this instance's region
+// and the configured regional endpoints
+func (h *AdminHandler) SetRegion(r *config.RegionConfig) {
+	h.region = r
+}
+
+// SetKeyChecker enables customer-managed encryption keys for scan samples
+func (h *AdminHandler) SetKeyChecker(k KeyChecker) {
+	h.keys = k
+}
+
+// SetBackends enables per-account detection backend selection
+func (h *AdminHandler) SetBackends(r *backends.Router) {
+	h.backends = r
+}
+
+// SetAbuse exposes abuse scores at /v1/admin/abuse
+func (h *AdminHandler) SetAbuse(d *abuse.Detector) {
+	h.abuse = d
+}
+
+// RegisterRoutes registers admin routes behind adminAuth
+func (h *AdminHandler) RegisterRoutes(app *fiber.App, adminAuth fiber.Handler) {
+	admin := app.Group("/v1/admin", adminAuth)
+	admin.Get("/canary", h.GetCanary)
+	admin.Get("/canary/results", h.GetCanaryResults)
+	admin.Put("/canary/accounts/:account_id", h.SetCanaryEnrollment)
+	admin.Delete("/canary/accounts/:account_id", h.DeleteCanaryEnrollment)
+	admin.Get("/flags", h.ListFlags)
+	// Flag names may contain slashes ("maintenance:/v1/scan/content")
+	admin.Put("/flags/*", h.SetFlag)
+	admin.Delete("/flags/*", h.DeleteFlag)
+	admin.Get("/ratelimit", h.GetRateLimits)
+	admin.Get("/payments/retention", h.GetPaymentRetention)
+	admin.Get("/detection/latency", h.GetDetectionLatency)
+	admin.Put("/accounts/:account_id/region", h.SetAccountRegion)
+	admin.Get("/replication/usage", h.GetReplicatedUsage)
+	admin.Put("/accounts/:account_id/encryption-key", h.SetEncryptionKey)
+	admin.Delete("/accounts/:account_id/encryption-key", h.DeleteEncryptionKey)
+	admin.Put("/accounts/:account_id/scan-backend", h.SetScanBackend)
+	admin.Get("/chaos", h.GetChaos)
+	admin.Put("/chaos/:point", h.SetChaosFault)
+	admin.Delete("/chaos/:point", h.DeleteChaosFault)
+	admin.Get("/abuse", h.GetAbuse)
+	admin.Delete("/abuse/*", h.ResetAbuse)
+	admin.Post("/prepaid-codes", h.CreatePrepaidCodes)
+	admin.Get("/prepaid-codes", h.ListPrepaidCodes)
+	admin.Delete("/prepaid-codes/:batch_id", h.VoidPrepaidCodes)
+}
+
+// CanaryStatusResponse describes the canary and its enrolled accounts
+type CanaryStatusResponse struct {
+	Enabled        bool                   `json:"enabled"`
+	StableVersion  string                 `json:"stable_version"`
+	CanaryVersion  string                 `json:"canary_version,omitempty"`
+	DefaultPercent float64                `json:"default_percent"`
+	Enrollments    []*db.CanaryEnrollment `json:"enrollments"`
+}
+
+// RateLimitStatsResponse reports rate limiter counters since startup
+type RateLimitStatsResponse struct {
+	Limiters []ratelimit.Stats `json:"limiters"`
+}
+
+// PaymentRetentionResponse reports payment table size and pruning
+type PaymentRetentionResponse struct {
+	RetentionDays int                      `json:"retention_days"` // 0 when payments are kept forever
+	Archive       bool                     `json:"archive"`
+	Tables        *db.PaymentTableStats    `json:"tables"`
+	LastRun       *settlement.RetentionRun `json:"last_run,omitempty"`
+}
+
+// DetectionLatencyResponse reports detection stage latency since startup
+type DetectionLatencyResponse struct {
+	Stages     []stronghold.StageLatency `json:"stages"`
+	MLWarmupMs int64                     `json:"ml_warmup_ms"` // 0 when the ml layer isn't loaded
+}
+
+// CanaryEnrollmentRequest sets the share of an account's scans shadow-scored by the canary
+type CanaryEnrollmentRequest struct {
+	Percent float64 `json:"percent"` // 0-100
+}
+
+// CanaryResultsRequest represents the query parameters for canary results
+type CanaryResultsRequest struct {
+	AccountID string `query:"account_id"`
+	Since     string `query:"since"`
+	Limit     int    `query:"limit"`
+}
+
+// CanaryResultsResponse summarizes canary comparisons
+type CanaryResultsResponse struct {
+	Since         time.Time          `json:"since"`
+	AccountID     *uuid.UUID         `json:"account_id,omitempty"`
+	Summary       *db.CanarySummary  `json:"summary"`
+	AgreementRate float64            `json:"agreement_rate"` // 0-1; 1 when there are no results
+	Disagreements []*db.CanaryResult `json:"disagreements"`
+}
+
+// GetCanary returns the canary status and enrolled accounts
+// @Summary Get canary status
+// @Description Returns the stable and canary detection versions, the default canary percentage, and accounts enrolled with their own percentage
+// @Tags admin
+// @Produce json
+// @Success 200 {object} CanaryStatusResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/canary [get]
+func (h *AdminHandler) GetCanary(c fiber.Ctx) error {
+	enrollments, err := h.db.ListCanaryEnrollments(c.Context())
+	if err != nil {
+		slog.Error("failed to list canary enrollments", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get canary enrollments",
+		})
+	}
+
+	return c.JSON(CanaryStatusResponse{
+		Enabled:        h.canary != nil,
+		StableVersion:  h.scanner.Version().Version,
+		CanaryVersion:  h.canary.Version(),
+		DefaultPercent: h.canary.DefaultPercent(),
+		Enrollments:    enrollments,
+	})
+}
+
+// SetCanaryEnrollment enrolls an account in the canary
+// @Summary Enroll account in canary
+// @Description Shadow-scores the given percentage of the account's scans with the canary detection configuration. The stable decision is always enforced.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param account_id path string true "Account ID"
+// @Param request body CanaryEnrollmentRequest true "Canary percentage"
+// @Success 200 {object} db.CanaryEnrollment
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/canary/accounts/{account_id} [put]
+func (h *AdminHandler) SetCanaryEnrollment(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	var req CanaryEnrollmentRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Percent < 0 || req.Percent > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "percent must be between 0 and 100",
+		})
+	}
+
+	if _, err := h.db.GetAccountByID(c.Context(), accountID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Account not found",
+		})
+	}
+
+	enrollment, err := h.db.SetCanaryEnrollment(c.Context(), accountID, req.Percent)
+	if err != nil {
+		slog.Error("failed to set canary enrollment", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set canary enrollment",
+		})
+	}
+	h.canary.Forget(accountID)
+
+	slog.Info("canary enrollment set", "account_id", accountID.String(), "percent", req.Percent)
+	return c.JSON(enrollment)
+}
+
+// DeleteCanaryEnrollment removes an account from the canary
+// @Summary Remove account from canary
+// @Description Removes the account's canary enrollment; its scans fall back to the default canary percentage
+// @Tags admin
+// @Param account_id path string true "Account ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "Invalid account ID"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/canary/accounts/{account_id} [delete]
+func (h *AdminHandler) DeleteCanaryEnrollment(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	if err := h.db.DeleteCanaryEnrollment(c.Context(), accountID); err != nil {
+		slog.Error("failed to delete canary enrollment", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete canary enrollment",
+		})
+	}
+	h.canary.Forget(accountID)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetCanaryResults summarizes canary comparisons
+// @Summary Get canary results
+// @Description Returns how often the canary agreed with the stable decision, counts per decision transition, and the most recent disagreements
+// @Tags admin
+// @Produce json
+// @Param account_id query string false "Only results for this account"
+// @Param since query string false "RFC 3339 start time (default 7 days ago)"
+// @Param limit query int false "Maximum disagreements returned (default 100, max 1000)"
+// @Success 200 {object} CanaryResultsResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/canary/results [get]
+func (h *AdminHandler) GetCanaryResults(c fiber.Ctx) error {
+	var req CanaryResultsRequest
+	if err := c.Bind().Query(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+
+	var accountID *uuid.UUID
+	if req.AccountID != "" {
+		id, err := uuid.Parse(req.AccountID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid account ID",
+			})
+		}
+		accountID = &id
+	}
+
+	since := time.Now().UTC().Add(-defaultCanaryWindow)
+	if req.Since != "" {
+		t, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "since must be an RFC 3339 time",
+			})
+		}
+		since = t
+	}
+
+	summary, err := h.db.GetCanarySummary(c.Context(), accountID, since)
+	if err != nil {
+		slog.Error("failed to get canary summary", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get canary results",
+		})
+	}
+	disagreements, err := h.db.ListCanaryDisagreements(c.Context(), accountID, since, req.Limit)
+	if err != nil {
+		slog.Error("failed to list canary disagreements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get canary results",
+		})
+	}
+
+	rate := 1.0
+	if summary.Total > 0 {
+		rate = float64(summary.Agreed) / float64(summary.Total)
+	}
+	return c.JSON(CanaryResultsResponse{
+		Since:         since,
+		AccountID:     accountID,
+		Summary:       summary,
+		AgreementRate: rate,
+		Disagreements: disagreements,
+	})
+}
+
+// FeatureFlagRequest sets a feature flag. Percent defaults to 100.
+type FeatureFlagRequest struct {
+	Enabled    bool        `json:"enabled"`
+	Percent    *float64    `json:"percent,omitempty"`     // Share of accounts the flag is on for, 0-100
+	AccountIDs []uuid.UUID `json:"account_ids,omitempty"` // Accounts the flag is always on for while enabled
+	Message    string      `json:"message,omitempty"`     // Shown to callers, e.g. during maintenance
+}
+
+// ListFlags returns all feature flags
+// @Summary List feature flags
+// @Description Returns all feature flags as stored. API instances pick up changes within FEATURE_FLAGS_REFRESH_INTERVAL.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} db.FeatureFlag
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/flags [get]
+func (h *AdminHandler) ListFlags(c fiber.Ctx) error {
+	list, err := h.db.ListFeatureFlags(c.Context())
+	if err != nil {
+		slog.Error("failed to list feature flags", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list feature flags",
+		})
+	}
+	return c.JSON(list)
+}
+
+// SetFlag creates or replaces a feature flag
+// @Summary Set feature flag
+// @Description Creates or replaces a feature flag. Well-known flags: "maintenance:<path>" and "maintenance:*" answer 503 for an endpoint or all endpoints, and "payments.log_only" serves requests that would get 402 without charging them.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Flag name"
+// @Param request body FeatureFlagRequest true "Flag settings"
+// @Success 200 {object} db.FeatureFlag
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/flags/{name} [put]
+func (h *AdminHandler) SetFlag(c fiber.Ctx) error {
+	name, ok := flagName(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid flag name",
+		})
+	}
+
+	var req FeatureFlagRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	percent := 100.0
+	if req.Percent != nil {
+		percent = *req.Percent
+	}
+	if percent < 0 || percent > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "percent must be between 0 and 100",
+		})
+	}
+
+	flag := &db.FeatureFlag{
+		Name:       name,
+		Enabled:    req.Enabled,
+		Percent:    percent,
+		AccountIDs: req.AccountIDs,
+		Message:    req.Message,
+	}
+	if err := h.db.UpsertFeatureFlag(c.Context(), flag); err != nil {
+		slog.Error("failed to save feature flag", "name", name, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save feature flag",
+		})
+	}
+	h.refreshFlags(c)
+
+	slog.Info("feature flag set", "name", name, "enabled", flag.Enabled, "percent", flag.Percent, "accounts", len(flag.AccountIDs))
+	return c.JSON(flag)
+}
+
+// DeleteFlag removes a feature flag, turning it off
+// @Summary Delete feature flag
+// @Description Removes a feature flag; a missing flag is off
+// @Tags admin
+// @Param name path string true "Flag name"
+// @Success 204
+// @Failure 400 {object} map[string]string "Invalid flag name"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Flag not found"
+// @Security BearerAuth
+// @Router /v1/admin/flags/{name} [delete]
+func (h *AdminHandler) DeleteFlag(c fiber.Ctx) error {
+	name, ok := flagName(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid flag name",
+		})
+	}
+
+	deleted, err := h.db.DeleteFeatureFlag(c.Context(), name)
+	if err != nil {
+		slog.Error("failed to delete feature flag", "name", name, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete feature flag",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Flag not found",
+		})
+	}
+	h.refreshFlags(c)
+
+	slog.Info("feature flag deleted", "name", name)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// flagName returns the flag name from the wildcard path segment
+func flagName(c fiber.Ctx) (string, bool) {
+	name := c.Params("*")
+	if name == "" || len(name) > 255 || strings.ContainsAny(name, " \t\r\n") {
+		return "", false
+	}
+	return name, true
+}
+
+// refreshFlags applies a flag change on this instance immediately; other
+// instances pick it up on their next refresh
+func (h *AdminHandler) refreshFlags(c fiber.Ctx) {
+	if err := h.flags.Refresh(c.Context()); err != nil {
+		slog.Warn("failed to refresh feature flags", "error", err)
+	}
+}
+
+// GetRateLimits returns rate limiter counters
+// @Summary Rate limiter stats
+// @Description Returns allowed and limited request counts for each rate limiter on this API instance since startup, and how many requests were let through because the rate limit store was unavailable.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} RateLimitStatsResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/ratelimit [get]
+func (h *AdminHandler) GetRateLimits(c fiber.Ctx) error {
+	resp := RateLimitStatsResponse{Limiters: []ratelimit.Stats{}}
+	if h.rateLimits != nil {
+		resp.Limiters = h.rateLimits.Stats()
+	}
+	return c.JSON(resp)
+}
+
+// GetPaymentRetention returns payment table size and the last pruning pass
+// @Summary Payment retention
+// @Description Returns payment transaction counts by status, the oldest prunable payment, on-disk sizes of the payment and archive tables, the retention settings, and the last pruning pass on this API instance.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} PaymentRetentionResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security BearerAuth
+// @Router /v1/admin/payments/retention [get]
+func (h *AdminHandler) GetPaymentRetention(c fiber.Ctx) error {
+	stats, err := h.db.GetPaymentTableStats(c.Context())
+	if err != nil {
+		slog.Error("failed to get payment table stats", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get payment table stats",
+		})
+	}
+
+	resp := PaymentRetentionResponse{Tables: stats}
+	if h.retention != nil {
+		resp.RetentionDays = h.retention.RetentionDays
+		resp.Archive = h.retention.Archive
+	}
+	if h.pruner != nil {
+		resp.LastRun = h.pruner.LastRun()
+	}
+	return c.JSON(resp)
+}
+
+// GetDetectionLatency returns per-stage scan latency
+// @Summary Detection stage latency
+// @Description Returns scan count, error count, mean and maximum latency for each detection stage on this API instance since startup: engine (heuristic, semantic and LLM layers), ml (local ONNX classifier) and output (credential scan), plus how long the ml layer's warm-up took.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} DetectionLatencyResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/detection/latency [get]
+func (h *AdminHandler) GetDetectionLatency(c fiber.Ctx) error {
+	return c.JSON(DetectionLatencyResponse{
+		Stages:     h.scanner.StageLatency(),
+		MLWarmupMs: h.scanner.MLWarmup().Milliseconds(),
+	})
+}
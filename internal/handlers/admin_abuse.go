@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"log/slog"
+	"strings"
+
+	"stronghold/internal/abuse"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// AbuseResponse lists the scan callers with an abuse score on this instance
+type AbuseResponse struct {
+	Enabled bool           `json:"enabled"`
+	Callers []abuse.Caller `json:"callers"`
+}
+
+// GetAbuse lists callers scored by abuse detection
+// @Summary Abusive scan callers
+// @Description Lists the accounts and anonymous addresses this API instance has scored for probing the detection engine, highest score first, with the signals seen, the response level applied, and whether the account was suspended.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} AbuseResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/abuse [get]
+func (h *AdminHandler) GetAbuse(c fiber.Ctx) error {
+	resp := AbuseResponse{Enabled: h.abuse != nil, Callers: []abuse.Caller{}}
+	if h.abuse != nil {
+		resp.Callers = h.abuse.Callers()
+	}
+	return c.JSON(resp)
+}
+
+// ResetAbuse clears a caller's abuse score
+// @Summary Clear an abuse score
+// @Description Forgets a caller's abuse score and history on this API instance, lifting jitter and throttling. An account the detector suspended is reactivated.
+// @Tags admin
+// @Produce json
+// @Param key path string true "Caller key: account:<id> or ip:<address>"
+// @Success 200 {object} abuse.Caller "Standing before the reset"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Caller not scored"
+// @Failure 500 {object} map[string]string "Failed to reactivate account"
+// @Security BearerAuth
+// @Router /v1/admin/abuse/{key} [delete]
+func (h *AdminHandler) ResetAbuse(c fiber.Ctx) error {
+	key := c.Params("*")
+	if h.abuse == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Abuse detection is disabled",
+		})
+	}
+
+	caller, ok := h.abuse.Reset(key)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Caller not scored",
+		})
+	}
+
+	if id, err := uuid.Parse(strings.TrimPrefix(key, "account:")); caller.Suspended && err == nil {
+		if err := h.db.ReactivateAccount(c.Context(), id); err != nil {
+			slog.Error("failed to reactivate account", "account_id", id, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to reactivate account",
+			})
+		}
+	}
+
+	slog.Info("abuse score reset", "caller", key, "score", caller.Score, "reactivated", caller.Suspended)
+	return c.JSON(caller)
+}
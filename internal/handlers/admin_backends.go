@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// ScanBackendRequest selects an account's detection backend
+type ScanBackendRequest struct {
+	Backend string `json:"backend"`         // internal, ensemble, custom or a SCAN_BACKENDS name; empty for the default
+	URL     string `json:"url,omitempty"`   // Base URL of the account's own endpoint, for custom
+	Token   string `json:"token,omitempty"` // Bearer token for the account's own endpoint
+}
+
+// ScanBackendResponse reports an account's detection backend
+type ScanBackendResponse struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Backend   string    `json:"backend"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// SetScanBackend selects the detection backend for an account's scans
+// @Summary Set account scan backend
+// @Description Routes the account's scans to a detection backend: the built-in engine (internal), the weighted ensemble (ensemble), an external service from SCAN_BACKENDS, or the account's own HTTPS endpoint (custom, with url and an optional bearer token). An empty backend returns the account to SCAN_DEFAULT_BACKEND.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param account_id path string true "Account ID"
+// @Param request body ScanBackendRequest true "Backend selection"
+// @Success 200 {object} ScanBackendResponse
+// @Failure 400 {object} map[string]string "Invalid request or unknown backend"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/scan-backend [put]
+func (h *AdminHandler) SetScanBackend(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	var req ScanBackendRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	selection := db.ScanBackend{Name: strings.ToLower(strings.TrimSpace(req.Backend))}
+
+	switch {
+	case selection.Name == "":
+	case selection.Name == config.BackendCustom:
+		selection.URL = strings.TrimRight(strings.TrimSpace(req.URL), "/")
+		selection.Token = req.Token
+		if !strings.HasPrefix(selection.URL, "https://") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "A custom backend needs an https:// url",
+			})
+		}
+	case h.backends == nil || !h.backends.Has(selection.Name):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown backend; use internal, ensemble, custom or a SCAN_BACKENDS name",
+		})
+	}
+
+	if err := h.db.SetAccountScanBackend(c.Context(), accountID, selection); err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Account not found",
+			})
+		}
+		slog.Error("failed to set account scan backend", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set scan backend",
+		})
+	}
+
+	slog.Info("account scan backend set", "account_id", accountID.String(), "backend", selection.Name)
+	backend := selection.Name
+	if backend == "" && h.backends != nil {
+		backend = h.backends.Default()
+	}
+	return c.JSON(ScanBackendResponse{AccountID: accountID, Backend: backend, URL: selection.URL})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"stronghold/internal/db"
+	"stronghold/internal/kms"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// KeyChecker verifies that a customer-managed KMS key can be used.
+// *kms.DataKeys implements it.
+type KeyChecker interface {
+	Check(ctx context.Context, keyARN string) error
+}
+
+// EncryptionKeyRequest sets an account's customer-managed key
+type EncryptionKeyRequest struct {
+	KeyARN string `json:"key_arn"` // arn:aws:kms:<region>:<account>:key/<id> or alias/<name>
+}
+
+// EncryptionKeyResponse reports an account's customer-managed key
+type EncryptionKeyResponse struct {
+	AccountID uuid.UUID `json:"account_id"`
+	KeyARN    string    `json:"key_arn"`
+}
+
+// DeleteEncryptionKeyResponse reports the samples deleted with the key
+type DeleteEncryptionKeyResponse struct {
+	AccountID      uuid.UUID `json:"account_id"`
+	DeletedSamples int64     `json:"deleted_samples"`
+}
+
+// SetEncryptionKey sets a business account's customer-managed key
+// @Summary Set customer-managed encryption key
+// @Description Seals the account's stored scan samples under a data key wrapped by the customer's AWS KMS key. The key must grant Stronghold kms:GenerateDataKey and kms:Decrypt, and is checked before it is saved. If the customer later disables or revokes the key, samples sealed under it become unreadable and new samples are not stored. Only business accounts can use customer-managed keys.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param account_id path string true "Account ID"
+// @Param request body EncryptionKeyRequest true "KMS key ARN"
+// @Success 200 {object} EncryptionKeyResponse
+// @Failure 400 {object} map[string]string "Invalid request, key unusable, or not a business account"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 502 {object} map[string]string "KMS unavailable"
+// @Failure 503 {object} map[string]string "Customer-managed keys not available"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/encryption-key [put]
+func (h *AdminHandler) SetEncryptionKey(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	var req EncryptionKeyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	keyARN := strings.TrimSpace(req.KeyARN)
+	if _, err := kms.KeyRegion(keyARN); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "key_arn must be a KMS key or alias ARN",
+		})
+	}
+
+	account, err := h.db.GetAccountByID(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Account not found",
+		})
+	}
+	if account.AccountType != db.AccountTypeB2B {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Customer-managed keys are only available to business accounts",
+		})
+	}
+
+	if h.keys == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Customer-managed keys are not available on this instance",
+		})
+	}
+	if err := h.keys.Check(c.Context(), keyARN); err != nil {
+		slog.Warn("customer-managed key check failed", "account_id", accountID.String(), "key_arn", keyARN, "error", err)
+		if errors.Is(err, kms.ErrKeyRevoked) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Stronghold cannot use this key; grant it kms:GenerateDataKey and kms:Decrypt",
+			})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to verify key with KMS",
+		})
+	}
+
+	if err := h.db.SetAccountEncryptionKey(c.Context(), accountID, keyARN); err != nil {
+		slog.Error("failed to set account encryption key", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set encryption key",
+		})
+	}
+
+	slog.Info("customer-managed key set", "account_id", accountID.String(), "key_arn", keyARN)
+	return c.JSON(EncryptionKeyResponse{AccountID: accountID, KeyARN: keyARN})
+}
+
+// DeleteEncryptionKey removes an account's customer-managed key
+// @Summary Remove customer-managed encryption key
+// @Description Removes the account's customer-managed key and deletes the scan samples sealed under it. New samples are stored like other accounts' samples.
+// @Tags admin
+// @Produce json
+// @Param account_id path string true "Account ID"
+// @Success 200 {object} DeleteEncryptionKeyResponse
+// @Failure 400 {object} map[string]string "Invalid account ID"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/encryption-key [delete]
+func (h *AdminHandler) DeleteEncryptionKey(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	deleted, err := h.db.RemoveAccountEncryptionKey(c.Context(), accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Account not found",
+			})
+		}
+		slog.Error("failed to remove account encryption key", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove encryption key",
+		})
+	}
+
+	slog.Info("customer-managed key removed", "account_id", accountID.String(), "deleted_samples", deleted)
+	return c.JSON(DeleteEncryptionKeyResponse{AccountID: accountID, DeletedSamples: deleted})
+}
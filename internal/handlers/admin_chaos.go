@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"stronghold/internal/chaos"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ChaosFaultRequest sets a fault at an injection point
+type ChaosFaultRequest struct {
+	Action  string  `json:"action"`             // "delay", "error" or "drop"
+	DelayMs int64   `json:"delay_ms,omitempty"` // Required for delay
+	Rate    float64 `json:"rate,omitempty"`     // Share of calls affected, 0-1; 0 means all
+}
+
+// ChaosResponse lists the injected faults
+type ChaosResponse struct {
+	Enabled bool          `json:"enabled"` // Whether this build can inject faults
+	Points  []string      `json:"points"`
+	Faults  []chaos.Fault `json:"faults"`
+}
+
+// GetChaos lists injected faults
+// @Summary List injected faults
+// @Description Lists the faults injected into facilitator calls and database connections. Fault injection is only available in API builds made with -tags chaos.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ChaosResponse
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/chaos [get]
+func (h *AdminHandler) GetChaos(c fiber.Ctx) error {
+	faults := chaos.Faults()
+	if faults == nil {
+		faults = []chaos.Fault{}
+	}
+	return c.JSON(ChaosResponse{
+		Enabled: chaos.Enabled,
+		Points:  chaos.Points,
+		Faults:  faults,
+	})
+}
+
+// SetChaosFault injects a fault at a point
+// @Summary Inject a fault
+// @Description Injects a fault at a point, replacing any already there. "facilitator" affects every facilitator request, including retries. "db" affects database connection checkouts; drop and error kill the connection and fail the query. Only available in API builds made with -tags chaos.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param point path string true "Injection point: facilitator or db"
+// @Param request body ChaosFaultRequest true "Fault"
+// @Success 200 {object} chaos.Fault
+// @Failure 400 {object} map[string]string "Invalid fault"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Fault injection not compiled in"
+// @Security BearerAuth
+// @Router /v1/admin/chaos/{point} [put]
+func (h *AdminHandler) SetChaosFault(c fiber.Ctx) error {
+	var req ChaosFaultRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	fault := chaos.Fault{
+		Point:   c.Params("point"),
+		Action:  req.Action,
+		DelayMs: req.DelayMs,
+		Rate:    req.Rate,
+	}
+	if err := chaos.Set(fault); err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, chaos.ErrDisabled) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	slog.Warn("admin injected fault", "point", fault.Point, "action", fault.Action)
+	return c.JSON(fault)
+}
+
+// DeleteChaosFault removes the fault at a point
+// @Summary Remove a fault
+// @Description Removes the fault injected at a point
+// @Tags admin
+// @Param point path string true "Injection point"
+// @Success 204
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/chaos/{point} [delete]
+func (h *AdminHandler) DeleteChaosFault(c fiber.Ctx) error {
+	chaos.Clear(c.Params("point"))
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxPrepaidCodeBatch bounds the codes generated in one request
+	maxPrepaidCodeBatch = 1000
+	// maxPrepaidCodeLabelLength bounds a batch's label
+	maxPrepaidCodeLabelLength = 100
+)
+
+// SetPrepaidCodes enables prepaid code generation at /v1/admin/prepaid-codes
+func (h *AdminHandler) SetPrepaidCodes(cfg *config.PrepaidCodeConfig) {
+	h.prepaid = cfg
+}
+
+// CreatePrepaidCodesRequest generates a batch of prepaid codes
+type CreatePrepaidCodesRequest struct {
+	Label           string             `json:"label" example:"Conference trial"`
+	Count           int                `json:"count" example:"100"`
+	ValueUSDC       usdc.DecimalAmount `json:"value_usdc" swaggertype:"string" example:"5.00"`
+	PerAccountLimit int                `json:"per_account_limit,omitempty" example:"1"` // Codes from the batch one account may redeem; defaults to 1
+	ExpiresAt       *time.Time         `json:"expires_at,omitempty"`
+}
+
+// CreatePrepaidCodesResponse is a new batch with its codes, which are not
+// stored and can't be shown again
+type CreatePrepaidCodesResponse struct {
+	Batch *db.PrepaidCodeBatch `json:"batch"`
+	Codes []string             `json:"codes"`
+}
+
+// CreatePrepaidCodes generates a batch of prepaid codes
+// @Summary Generate prepaid codes
+// @Description Generates single-use codes that each credit a fixed USDC value to the account that redeems them, for trials and offline sales. Only hashes are stored, so the codes are returned once and can't be listed again. per_account_limit caps how many codes from the batch one account may redeem.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CreatePrepaidCodesRequest true "Batch terms"
+// @Success 201 {object} CreatePrepaidCodesResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Prepaid codes disabled"
+// @Failure 500 {object} map[string]string "Failed to generate codes"
+// @Security BearerAuth
+// @Router /v1/admin/prepaid-codes [post]
+func (h *AdminHandler) CreatePrepaidCodes(c fiber.Ctx) error {
+	if h.prepaid == nil || h.prepaid.MaxValue <= 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Prepaid codes are disabled",
+		})
+	}
+
+	var req CreatePrepaidCodesRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	if msg := validatePrepaidCodeBatch(&req, h.prepaid.MaxValue, time.Now()); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	// Same format as recovery codes: 80 random bits, too many to guess
+	codes, err := generateRecoveryCodes(req.Count)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate codes",
+		})
+	}
+	hashes := make([]string, len(codes))
+	hints := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = db.HashToken(code)
+		hints[i] = prepaidCodeHint(code)
+	}
+
+	batch := &db.PrepaidCodeBatch{
+		Label:           req.Label,
+		ValueUSDC:       req.ValueUSDC.Micro,
+		PerAccountLimit: req.PerAccountLimit,
+		ExpiresAt:       req.ExpiresAt,
+	}
+	if err := h.db.CreatePrepaidCodeBatch(c.Context(), batch, hashes, hints); err != nil {
+		slog.Error("failed to create prepaid code batch", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate codes",
+		})
+	}
+
+	slog.Info("prepaid codes generated",
+		"batch_id", batch.ID, "label", batch.Label, "count", batch.CodeCount, "value_usdc", batch.ValueUSDC)
+	return c.Status(fiber.StatusCreated).JSON(CreatePrepaidCodesResponse{Batch: batch, Codes: codes})
+}
+
+// ListPrepaidCodesRequest pages through prepaid code batches
+type ListPrepaidCodesRequest struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+// ListPrepaidCodes lists prepaid code batches
+// @Summary List prepaid code batches
+// @Description Lists prepaid code batches, newest first, with how many of their codes have been redeemed.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Number of batches to return (default 50)"
+// @Param offset query int false "Number of batches to skip (default 0)"
+// @Success 200 {object} map[string]interface{} "Batches with pagination"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security BearerAuth
+// @Router /v1/admin/prepaid-codes [get]
+func (h *AdminHandler) ListPrepaidCodes(c fiber.Ctx) error {
+	var req ListPrepaidCodesRequest
+	if err := c.Bind().Query(&req); err != nil {
+		req.Limit = 50
+		req.Offset = 0
+	}
+
+	batches, err := h.db.ListPrepaidCodeBatches(c.Context(), req.Limit, req.Offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list prepaid codes",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"batches": batches,
+		"limit":   req.Limit,
+		"offset":  req.Offset,
+	})
+}
+
+// VoidPrepaidCodes voids a batch of prepaid codes
+// @Summary Void prepaid codes
+// @Description Stops the batch's unredeemed codes from being redeemed, for example when codes leak. Balance already credited from the batch is kept.
+// @Tags admin
+// @Produce json
+// @Param batch_id path string true "Batch ID"
+// @Success 204 "Voided"
+// @Failure 400 {object} map[string]string "Invalid batch ID"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Batch not found"
+// @Security BearerAuth
+// @Router /v1/admin/prepaid-codes/{batch_id} [delete]
+func (h *AdminHandler) VoidPrepaidCodes(c fiber.Ctx) error {
+	batchID, err := uuid.Parse(c.Params("batch_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid batch ID",
+		})
+	}
+
+	err = h.db.VoidPrepaidCodeBatch(c.Context(), batchID)
+	if errors.Is(err, db.ErrPrepaidCodeBatchNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Batch not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to void prepaid codes",
+		})
+	}
+
+	slog.Info("prepaid code batch voided", "batch_id", batchID)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// validatePrepaidCodeBatch normalizes req and returns why it is rejected,
+// or "". A code may carry at most maxValue.
+func validatePrepaidCodeBatch(req *CreatePrepaidCodesRequest, maxValue usdc.MicroUSDC, now time.Time) string {
+	req.Label = strings.TrimSpace(req.Label)
+	if req.PerAccountLimit == 0 {
+		req.PerAccountLimit = 1
+	}
+
+	switch {
+	case req.Label == "":
+		return "label is required"
+	case utf8.RuneCountInString(req.Label) > maxPrepaidCodeLabelLength:
+		return fmt.Sprintf("label must be at most %d characters", maxPrepaidCodeLabelLength)
+	case req.Count < 1 || req.Count > maxPrepaidCodeBatch:
+		return fmt.Sprintf("count must be between 1 and %d", maxPrepaidCodeBatch)
+	case req.ValueUSDC.Legacy:
+		return "value_usdc must be a decimal string, e.g. \"5.00\""
+	case req.ValueUSDC.Micro <= 0:
+		return "value_usdc must be greater than 0"
+	case req.ValueUSDC.Micro > maxValue:
+		return fmt.Sprintf("value_usdc must be at most %s", maxValue)
+	case req.PerAccountLimit < 0:
+		return "per_account_limit must be positive"
+	case req.ExpiresAt != nil && !req.ExpiresAt.After(now):
+		return "expires_at must be in the future"
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// maxReplicationBatch bounds how many usage records one replication pull returns
+const maxReplicationBatch = 1000
+
+// AccountRegionRequest pins an account to a region. An empty region removes the pin.
+type AccountRegionRequest struct {
+	Region string `json:"region"`
+}
+
+// AccountRegionResponse reports an account's region pin
+type AccountRegionResponse struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Region    string    `json:"region,omitempty"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+}
+
+// ReplicationUsageRequest represents the query parameters for a replication pull
+type ReplicationUsageRequest struct {
+	Since   string `query:"since"`
+	AfterID string `query:"after_id"`
+	Limit   int    `query:"limit"`
+}
+
+// ReplicationUsageResponse is a page of replicable usage records. Pass the
+// next cursor back as since and after_id to continue.
+type ReplicationUsageResponse struct {
+	Region      string                `json:"region,omitempty"`
+	Records     []*db.ReplicatedUsage `json:"records"`
+	NextSince   *time.Time            `json:"next_since,omitempty"`
+	NextAfterID *uuid.UUID            `json:"next_after_id,omitempty"`
+}
+
+// SetAccountRegion pins an account to a region
+// @Summary Set account region
+// @Description Pins the account's scans to a region. Instances in other regions answer the account's API key requests with 421 and the regional endpoint, so scan content is only processed where the account is pinned. The region must be one of REGION_ENDPOINTS; an empty region removes the pin.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param account_id path string true "Account ID"
+// @Param request body AccountRegionRequest true "Region"
+// @Success 200 {object} AccountRegionResponse
+// @Failure 400 {object} map[string]string "Invalid request or unknown region"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Security BearerAuth
+// @Router /v1/admin/accounts/{account_id}/region [put]
+func (h *AdminHandler) SetAccountRegion(c fiber.Ctx) error {
+	accountID, err := uuid.Parse(c.Params("account_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid account ID",
+		})
+	}
+
+	var req AccountRegionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	region := strings.ToLower(strings.TrimSpace(req.Region))
+
+	var endpoint string
+	if region != "" {
+		if h.region != nil {
+			endpoint = h.region.Endpoints[region]
+		}
+		if endpoint == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown region; add it to REGION_ENDPOINTS first",
+			})
+		}
+	}
+
+	if err := h.db.SetAccountRegion(c.Context(), accountID, region); err != nil {
+		if errors.Is(err, db.ErrAccountNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Account not found",
+			})
+		}
+		slog.Error("failed to set account region", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set account region",
+		})
+	}
+
+	slog.Info("account region set", "account_id", accountID.String(), "region", region)
+	return c.JSON(AccountRegionResponse{
+		AccountID: accountID,
+		Region:    region,
+		Endpoint:  endpoint,
+	})
+}
+
+// GetReplicatedUsage exports usage metadata for cross-region replication
+// @Summary Export usage for replication
+// @Description Returns usage records created after the since/after_id cursor, oldest first, for replication to other regions. Only non-content metadata leaves the region: scanned text, threat reasons and payment payloads are never included.
+// @Tags admin
+// @Produce json
+// @Param since query string false "RFC 3339 cursor time (default: beginning)"
+// @Param after_id query string false "Cursor record ID at since"
+// @Param limit query int false "Maximum records returned (default and max 1000)"
+// @Success 200 {object} ReplicationUsageResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Invalid admin token"
+// @Security BearerAuth
+// @Router /v1/admin/replication/usage [get]
+func (h *AdminHandler) GetReplicatedUsage(c fiber.Ctx) error {
+	var req ReplicationUsageRequest
+	if err := c.Bind().Query(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+		})
+	}
+
+	var since time.Time
+	if req.Since != "" {
+		t, err := time.Parse(time.RFC3339Nano, req.Since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "since must be an RFC 3339 time",
+			})
+		}
+		since = t
+	}
+	var afterID uuid.UUID
+	if req.AfterID != "" {
+		id, err := uuid.Parse(req.AfterID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid after_id",
+			})
+		}
+		afterID = id
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > maxReplicationBatch {
+		limit = maxReplicationBatch
+	}
+
+	records, err := h.db.ListReplicatedUsage(c.Context(), since, afterID, limit)
+	if err != nil {
+		slog.Error("failed to list usage for replication", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export usage",
+		})
+	}
+
+	resp := ReplicationUsageResponse{Records: records}
+	if h.region != nil {
+		resp.Region = h.region.Name
+	}
+	if n := len(records); n > 0 {
+		resp.NextSince = &records[n-1].CreatedAt
+		resp.NextAfterID = &records[n-1].ID
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+
+	"stronghold/internal/flags"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// invalidBody answers a request whose body failed to bind, naming the
+// problem when it was a malformed amount
+func invalidBody(c fiber.Ctx, err error) error {
+	msg := "Invalid request body"
+	if errors.Is(err, usdc.ErrInvalidAmount) {
+		msg = "Invalid amount_usdc: use a decimal string with at most 6 decimal places, e.g. \"10.50\""
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": msg,
+	})
+}
+
+// numericAmountRefused reports whether an amount sent as a JSON number must
+// be refused. Numeric amounts are deprecated: they are accepted with a
+// Deprecation header until the RejectNumericAmounts flag is on for the
+// account.
+func numericAmountRefused(c fiber.Ctx, f *flags.Flags, accountID uuid.UUID, amount usdc.DecimalAmount) bool {
+	if !amount.Legacy {
+		return false
+	}
+	if f.Enabled(flags.RejectNumericAmounts, &accountID) {
+		return true
+	}
+	c.Set("Deprecation", "true")
+	slog.Info("deprecated numeric amount in request", "path", c.Path(), "account_id", accountID.String())
+	return false
+}
+
+// numericAmountResponse answers a request refused by numericAmountRefused
+func numericAmountResponse(c fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "amount_usdc must be a decimal string, e.g. \"10.50\"; numeric amounts are no longer accepted",
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/flags"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticFlags []*db.FeatureFlag
+
+func (s staticFlags) ListFeatureFlags(context.Context) ([]*db.FeatureFlag, error) {
+	return s, nil
+}
+
+// setupAmountApp serves the deposit request binding and numeric amount check
+// on their own, without the database the full handler needs
+func setupAmountApp(t *testing.T, accountID uuid.UUID, list ...*db.FeatureFlag) *fiber.App {
+	t.Helper()
+	f := flags.New(t.Context(), staticFlags(list), 0)
+	app := fiber.New()
+	app.Post("/deposit", func(c fiber.Ctx) error {
+		var req InitiateDepositRequest
+		if err := c.Bind().Body(&req); err != nil {
+			return invalidBody(c, err)
+		}
+		if numericAmountRefused(c, f, accountID, req.AmountUSDC) {
+			return numericAmountResponse(c)
+		}
+		return c.JSON(fiber.Map{"amount_usdc": req.AmountUSDC.Micro})
+	})
+	return app
+}
+
+func postAmount(t *testing.T, app *fiber.App, body string) (int, string, map[string]string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/deposit", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return resp.StatusCode, resp.Header.Get("Deprecation"), result
+}
+
+func TestAmount_DecimalString(t *testing.T) {
+	app := setupAmountApp(t, uuid.New())
+
+	status, deprecation, result := postAmount(t, app, `{"amount_usdc":"10.50","provider":"direct"}`)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, deprecation)
+	assert.Equal(t, "10500000", result["amount_usdc"])
+}
+
+func TestAmount_InvalidDecimal(t *testing.T) {
+	app := setupAmountApp(t, uuid.New())
+
+	for _, body := range []string{
+		`{"amount_usdc":"10.1234567"}`,
+		`{"amount_usdc":"-5"}`,
+		`{"amount_usdc":"1e3"}`,
+	} {
+		status, _, result := postAmount(t, app, body)
+		assert.Equal(t, fiber.StatusBadRequest, status, body)
+		assert.Contains(t, result["error"], "Invalid amount_usdc", body)
+	}
+}
+
+func TestAmount_NumericDeprecated(t *testing.T) {
+	app := setupAmountApp(t, uuid.New())
+
+	status, deprecation, result := postAmount(t, app, `{"amount_usdc":10.5}`)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "true", deprecation)
+	assert.Equal(t, "10500000", result["amount_usdc"])
+}
+
+func TestAmount_NumericRejectedByFlag(t *testing.T) {
+	accountID := uuid.New()
+	app := setupAmountApp(t, accountID, &db.FeatureFlag{
+		Name:       flags.RejectNumericAmounts,
+		Enabled:    true,
+		AccountIDs: []uuid.UUID{accountID},
+	})
+
+	status, _, result := postAmount(t, app, `{"amount_usdc":10.5}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Contains(t, result["error"], "decimal string")
+
+	status, _, _ = postAmount(t, app, `{"amount_usdc":"10.50"}`)
+	assert.Equal(t, fiber.StatusOK, status)
+}
+
+func TestAmount_NumericFlagScopedToAccount(t *testing.T) {
+	app := setupAmountApp(t, uuid.New(), &db.FeatureFlag{
+		Name:       flags.RejectNumericAmounts,
+		Enabled:    true,
+		AccountIDs: []uuid.UUID{uuid.New()},
+	})
+
+	status, deprecation, _ := postAmount(t, app, `{"amount_usdc":10.5}`)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "true", deprecation)
+}
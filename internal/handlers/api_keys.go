@@ -71,19 +71,12 @@ func (h *APIKeyHandler) Create(c fiber.Ctx) error {
 		})
 	}
 
-	// Generate key: sk_live_ + 32 random hex chars
-	randomBytes := make([]byte, 16)
-	if _, err := rand.Read(randomBytes); err != nil {
+	fullKey, keyPrefix, keyHash, err := generateAPIKey()
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate API key",
 		})
 	}
-	fullKey := "sk_live_" + hex.EncodeToString(randomBytes)
-	keyPrefix := fullKey[:12] // "sk_live_xxxx"
-
-	// Hash for storage
-	hash := sha256.Sum256([]byte(fullKey))
-	keyHash := hex.EncodeToString(hash[:])
 
 	// Atomically check key cap and insert (serialized via account row lock)
 	apiKey, err := h.db.CreateAPIKey(c.Context(), accountID, keyPrefix, keyHash, req.Name, maxAPIKeysPerAccount)
@@ -114,13 +107,27 @@ func (h *APIKeyHandler) Create(c fiber.Ctx) error {
 	})
 }
 
+// generateAPIKey returns a new key (sk_live_ + 32 random hex chars), its
+// displayed prefix and the hash it is stored as
+func generateAPIKey() (fullKey, keyPrefix, keyHash string, err error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", "", "", err
+	}
+	fullKey = "sk_live_" + hex.EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(fullKey))
+	return fullKey, fullKey[:12], hex.EncodeToString(hash[:]), nil
+}
+
 // APIKeyListItem represents a key in list responses (no full key)
 type APIKeyListItem struct {
-	ID         string  `json:"id"`
-	KeyPrefix  string  `json:"key_prefix"`
-	Name       string  `json:"label"`
-	CreatedAt  string  `json:"created_at"`
-	LastUsedAt *string `json:"last_used_at,omitempty"`
+	ID            string  `json:"id"`
+	KeyPrefix     string  `json:"key_prefix"`
+	Name          string  `json:"label"`
+	CreatedAt     string  `json:"created_at"`
+	LastUsedAt    *string `json:"last_used_at,omitempty"`
+	ScoreExposure *string `json:"score_exposure,omitempty"` // Set when the key overrides the account's score exposure
+	SubAccountID  *string `json:"sub_account_id,omitempty"` // Set when the key belongs to a sub-account
 }
 
 // List returns all active API keys for the authenticated B2B account
@@ -140,16 +147,7 @@ func (h *APIKeyHandler) List(c fiber.Ctx) error {
 
 	items := make([]APIKeyListItem, len(keys))
 	for i, k := range keys {
-		items[i] = APIKeyListItem{
-			ID:        k.ID.String(),
-			KeyPrefix: k.KeyPrefix,
-			Name:      k.Name,
-			CreatedAt: k.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		}
-		if k.LastUsedAt != nil {
-			t := k.LastUsedAt.Format("2006-01-02T15:04:05Z")
-			items[i].LastUsedAt = &t
-		}
+		items[i] = newAPIKeyListItem(k)
 	}
 
 	return c.JSON(fiber.Map{
@@ -157,6 +155,26 @@ func (h *APIKeyHandler) List(c fiber.Ctx) error {
 	})
 }
 
+// newAPIKeyListItem converts a key for list responses
+func newAPIKeyListItem(k db.APIKey) APIKeyListItem {
+	item := APIKeyListItem{
+		ID:            k.ID.String(),
+		KeyPrefix:     k.KeyPrefix,
+		Name:          k.Name,
+		CreatedAt:     k.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ScoreExposure: k.ScoreExposure,
+	}
+	if k.LastUsedAt != nil {
+		t := k.LastUsedAt.Format("2006-01-02T15:04:05Z")
+		item.LastUsedAt = &t
+	}
+	if k.SubAccountID != nil {
+		id := k.SubAccountID.String()
+		item.SubAccountID = &id
+	}
+	return item
+}
+
 // Revoke revokes an API key by ID
 func (h *APIKeyHandler) Revoke(c fiber.Ctx) error {
 	accountID, err := h.getB2BAccountID(c)
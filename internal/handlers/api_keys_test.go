@@ -12,6 +12,7 @@ import (
 
 	"stronghold/internal/db"
 	"stronghold/internal/db/testutil"
+	"stronghold/internal/secure"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -35,7 +36,7 @@ func setupAPIKeyTest(t *testing.T) (*fiber.App, *AuthHandler, *APIKeyHandler, *t
 	require.NoError(t, err)
 
 	authConfig := &AuthConfig{
-		JWTSecret:       "test-secret-key-for-testing",
+		JWTSecret:       secure.NewSecretString("test-secret-key-for-testing"),
 		AccessTokenTTL:  15 * time.Minute,
 		RefreshTokenTTL: 90 * 24 * time.Hour,
 		DashboardURL:    "http://localhost:3000",
@@ -11,6 +11,7 @@ import (
 
 	"stronghold/internal/db"
 	"stronghold/internal/kms"
+	"stronghold/internal/secure"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gofiber/fiber/v3"
@@ -32,12 +33,14 @@ type CookieConfig struct {
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	JWTSecret       string
+	JWTSecret       *secure.SecretString
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
 	DashboardURL    string
 	AllowedOrigins  []string
 	Cookie          CookieConfig
+	// SessionBinding is the session binding mode (see SessionBindingEnforce)
+	SessionBinding string
 }
 
 // AuthHandler handles authentication endpoints
@@ -159,7 +162,13 @@ func (h *AuthHandler) RegisterRoutesWithMiddleware(app *fiber.App, middlewares .
 	group.Post("/totp/setup", h.AuthMiddleware(), h.SetupTOTP)
 	group.Post("/totp/verify", h.AuthMiddleware(), h.VerifyTOTP)
 	group.Get("/devices", h.AuthMiddleware(), h.RequireTrustedDevice(), h.ListDevices)
+	group.Post("/devices/rename", h.AuthMiddleware(), h.RequireTrustedDevice(), h.RenameDevice)
 	group.Post("/devices/revoke", h.AuthMiddleware(), h.RequireTrustedDevice(), h.RevokeDevice)
+	group.Post("/devices/approvals", h.AuthMiddleware(), h.RequestDeviceApproval)
+	group.Get("/devices/approvals", h.AuthMiddleware(), h.ListDeviceApprovals)
+	group.Get("/devices/approvals/:id", h.AuthMiddleware(), h.GetDeviceApproval)
+	group.Post("/devices/approvals/:id/approve", h.AuthMiddleware(), h.ApproveDeviceApproval)
+	group.Post("/devices/approvals/:id/deny", h.AuthMiddleware(), h.DenyDeviceApproval)
 }
 
 // JWTClaims represents JWT claims
@@ -358,12 +367,13 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 	// Create session
 	ip := c.IP()
 	userAgent := string(c.Request().Header.UserAgent())
-	_, refreshToken, err := h.db.CreateSession(ctx, account.ID, net.ParseIP(ip), userAgent, h.config.RefreshTokenTTL)
+	session, refreshToken, err := h.db.CreateSession(ctx, account.ID, net.ParseIP(ip), userAgent, h.config.RefreshTokenTTL)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create session",
 		})
 	}
+	h.bindSessionDevice(c, session)
 
 	// Generate access token
 	accessToken, expiresAt, err := h.generateAccessToken(account.ID.String(), account.AccountNumber)
@@ -420,7 +430,7 @@ type RefreshTokenResponse struct {
 // @Tags auth
 // @Produce json
 // @Success 200 {object} RefreshTokenResponse
-// @Failure 401 {object} map[string]string "Invalid or expired refresh token"
+// @Failure 401 {object} map[string]string "Invalid or expired refresh token, or session bound to another device"
 // @Failure 403 {object} map[string]string "Account not active"
 // @Router /v1/auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c fiber.Ctx) error {
@@ -434,6 +444,14 @@ func (h *AuthHandler) RefreshToken(c fiber.Ctx) error {
 
 	ctx := c.Context()
 
+	// Reject refreshes from a device other than the one the session is bound to
+	if bound, err := h.db.GetSessionByRefreshToken(ctx, refreshToken); err == nil && !h.checkSessionBinding(c, bound) {
+		h.clearAuthCookies(c)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Session is bound to another device, log in again",
+		})
+	}
+
 	// Rotate refresh token
 	session, newRefreshToken, err := h.db.RotateRefreshToken(ctx, refreshToken, h.config.RefreshTokenTTL)
 	if err != nil {
@@ -678,7 +696,7 @@ func (h *AuthHandler) GetWalletKey(c fiber.Ctx) error {
 		})
 	}
 
-	privateKeyHex, err := h.kmsClient.Decrypt(ctx, encryptedKey)
+	privateKey, err := h.kmsClient.DecryptSecret(ctx, encryptedKey)
 	if err != nil {
 		slog.Error("failed to decrypt wallet key", "account_id", accountID, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -691,8 +709,11 @@ func (h *AuthHandler) GetWalletKey(c fiber.Ctx) error {
 		"ip", c.IP(),
 	)
 
+	// c.JSON encodes into the response buffer before returning, so the key
+	// can be zeroed as soon as it has been written.
+	defer privateKey.Zero()
 	return c.JSON(GetWalletKeyResponse{
-		PrivateKey: privateKeyHex,
+		PrivateKey: privateKey.Reveal(),
 	})
 }
 
@@ -715,7 +736,7 @@ func (h *AuthHandler) generateAccessToken(accountID, accountNumber string) (stri
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(h.config.JWTSecret))
+	tokenString, err := token.SignedString(h.config.JWTSecret.Bytes())
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -758,7 +779,7 @@ func (h *AuthHandler) AuthMiddleware() fiber.Handler {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte(h.config.JWTSecret), nil
+			return h.config.JWTSecret.Bytes(), nil
 		},
 			jwt.WithIssuer("stronghold-api"),
 			jwt.WithAudience("stronghold-api"),
@@ -880,7 +901,7 @@ func (h *AuthHandler) UpdateWallet(c fiber.Ctx) error {
 			"totp_required": true,
 		})
 	}
-	_ = h.db.TouchDevice(ctx, accountID, deviceToken)
+	_ = h.db.TouchDevice(ctx, accountID, deviceToken, c.IP())
 
 	// Validate and parse the private key
 	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(req.PrivateKey, "0x"))
@@ -4,10 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math"
 
 	"stronghold/internal/config"
 	"stronghold/internal/db"
+	"stronghold/internal/flags"
 	"stronghold/internal/usdc"
 
 	"github.com/gofiber/fiber/v3"
@@ -22,6 +22,7 @@ type B2BBillingHandler struct {
 	db           *db.DB
 	stripeConfig *config.StripeConfig
 	dashboardURL string
+	flags        *flags.Flags
 }
 
 // NewB2BBillingHandler creates a new B2B billing handler
@@ -33,6 +34,11 @@ func NewB2BBillingHandler(database *db.DB, stripeConfig *config.StripeConfig, da
 	}
 }
 
+// SetFlags enables per-account rollout of the numeric amount deprecation
+func (h *B2BBillingHandler) SetFlags(f *flags.Flags) {
+	h.flags = f
+}
+
 // RegisterRoutes registers B2B billing routes (all require JWT auth)
 func (h *B2BBillingHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler) {
 	group := app.Group("/v1/billing", authMiddleware)
@@ -43,7 +49,7 @@ func (h *B2BBillingHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.
 
 // PurchaseCreditsRequest represents a credit purchase request
 type PurchaseCreditsRequest struct {
-	AmountUSDC float64 `json:"amount_usdc"`
+	AmountUSDC usdc.DecimalAmount `json:"amount_usdc" swaggertype:"string" example:"25.00"` // Decimal string; JSON numbers are deprecated
 }
 
 // PurchaseCredits creates a Stripe Checkout session for credit purchase
@@ -55,18 +61,19 @@ func (h *B2BBillingHandler) PurchaseCredits(c fiber.Ctx) error {
 
 	var req PurchaseCreditsRequest
 	if err := c.Bind().Body(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return invalidBody(c, err)
+	}
+	if numericAmountRefused(c, h.flags, account.ID, req.AmountUSDC) {
+		return numericAmountResponse(c)
 	}
 
 	// Validate amount ($10 min, $10,000 max)
-	if req.AmountUSDC < 10.0 {
+	if req.AmountUSDC.Micro < 10*usdc.Scale {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Minimum credit purchase is $10.00",
 		})
 	}
-	if req.AmountUSDC > 10000.0 {
+	if req.AmountUSDC.Micro > 10_000*usdc.Scale {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Maximum credit purchase is $10,000.00",
 		})
@@ -80,7 +87,7 @@ func (h *B2BBillingHandler) PurchaseCredits(c fiber.Ctx) error {
 
 	// Round to whole cents and derive both values from that to prevent
 	// charging less in Stripe than credited in microUSDC (e.g. 10.009)
-	amountCents := int64(math.Round(req.AmountUSDC * 100))
+	amountCents := (int64(req.AmountUSDC.Micro) + 5000) / 10000
 	microUSDCAmount := usdc.MicroUSDC(amountCents * 10000) // 1 cent = 10,000 microUSDC
 
 	// Create Stripe Checkout session
@@ -114,7 +121,7 @@ func (h *B2BBillingHandler) PurchaseCredits(c fiber.Ctx) error {
 					UnitAmount: stripe.Int64(amountCents),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name:        stripe.String("Stronghold API Credits"),
-						Description: stripe.String(fmt.Sprintf("$%.2f in API credits", req.AmountUSDC)),
+						Description: stripe.String(fmt.Sprintf("$%s in API credits", microUSDCAmount)),
 					},
 				},
 				Quantity: stripe.Int64(1),
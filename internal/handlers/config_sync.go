@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxConfigSyncBytes bounds the encrypted configuration an account stores
+const maxConfigSyncBytes = 64 * 1024
+
+// ConfigSyncHandler stores the CLI configuration an account's machines
+// share. The CLI encrypts it before upload; the API never sees the key.
+type ConfigSyncHandler struct {
+	db *db.DB
+}
+
+// NewConfigSyncHandler creates a new config sync handler
+func NewConfigSyncHandler(database *db.DB) *ConfigSyncHandler {
+	return &ConfigSyncHandler{db: database}
+}
+
+// PutConfigSyncRequest uploads a new version of the encrypted configuration
+type PutConfigSyncRequest struct {
+	BaseVersion int64  `json:"base_version"`       // Version the change was made on; 0 for the first upload
+	Data        string `json:"data"`               // Base64 ciphertext
+	Hostname    string `json:"hostname,omitempty"` // Machine uploading it
+}
+
+// RegisterRoutes registers config sync routes. Uploads require a trusted
+// device, since every machine applies what was uploaded.
+func (h *ConfigSyncHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
+	group := app.Group("/v1/account/config-sync")
+	group.Get("/", authHandler.AuthMiddleware(), h.GetConfigSync)
+	group.Put("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.PutConfigSync)
+}
+
+// GetConfigSync returns the account's encrypted CLI configuration
+// @Summary Get synced CLI configuration
+// @Description Returns the latest encrypted CLI configuration uploaded by one of the account's machines, with its version.
+// @Tags account
+// @Produce json
+// @Success 200 {object} db.ConfigSync
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Nothing synced yet"
+// @Security CookieAuth
+// @Router /v1/account/config-sync [get]
+func (h *ConfigSyncHandler) GetConfigSync(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	sync, err := h.db.GetConfigSync(c.Context(), accountID)
+	if errors.Is(err, db.ErrConfigSyncNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No configuration has been synced",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get synced configuration",
+		})
+	}
+	c.Set("Cache-Control", "no-store")
+	return c.JSON(sync)
+}
+
+// PutConfigSync stores a new version of the account's encrypted CLI
+// configuration
+// @Summary Upload synced CLI configuration
+// @Description Stores a new version of the account's encrypted CLI configuration. The upload must name the version it was based on; if another machine uploaded since, it is refused with 409 and the current version so the caller can pull first.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body PutConfigSyncRequest true "Base version and base64 ciphertext"
+// @Success 200 {object} db.ConfigSync
+// @Failure 400 {object} map[string]string "Invalid data"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Trusted device required"
+// @Failure 409 {object} map[string]interface{} "Changed since base_version"
+// @Failure 413 {object} map[string]string "Configuration too large"
+// @Security CookieAuth
+// @Router /v1/account/config-sync [put]
+func (h *ConfigSyncHandler) PutConfigSync(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req PutConfigSyncRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if status, msg := validateConfigSync(&req); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	sync, err := h.db.PutConfigSync(c.Context(), accountID, req.BaseVersion, req.Data, deviceMetadata("", req.Hostname).Hostname)
+	if errors.Is(err, db.ErrConfigSyncConflict) {
+		resp := fiber.Map{"error": "Configuration was changed by another machine; pull it first"}
+		if current, err := h.db.GetConfigSync(c.Context(), accountID); err == nil {
+			resp["version"] = current.Version
+		}
+		return c.Status(fiber.StatusConflict).JSON(resp)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store synced configuration",
+		})
+	}
+	return c.JSON(sync)
+}
+
+// validateConfigSync returns the status and message rejecting req, or 0
+func validateConfigSync(req *PutConfigSyncRequest) (int, string) {
+	if req.BaseVersion < 0 {
+		return fiber.StatusBadRequest, "base_version must not be negative"
+	}
+	if base64.StdEncoding.DecodedLen(len(req.Data)) > maxConfigSyncBytes {
+		return fiber.StatusRequestEntityTooLarge, "Configuration too large"
+	}
+	raw, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil || len(raw) == 0 {
+		return fiber.StatusBadRequest, "data must be non-empty base64"
+	}
+	return 0, ""
+}
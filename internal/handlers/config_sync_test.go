@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfigSync(t *testing.T) {
+	sealed := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+	tests := []struct {
+		name string
+		req  PutConfigSyncRequest
+		want int
+	}{
+		{"valid", PutConfigSyncRequest{BaseVersion: 2, Data: sealed}, 0},
+		{"negative version", PutConfigSyncRequest{BaseVersion: -1, Data: sealed}, fiber.StatusBadRequest},
+		{"empty", PutConfigSyncRequest{}, fiber.StatusBadRequest},
+		{"not base64", PutConfigSyncRequest{Data: "not base64!"}, fiber.StatusBadRequest},
+		{"too large", PutConfigSyncRequest{Data: strings.Repeat("A", maxConfigSyncBytes*2)}, fiber.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := validateConfigSync(&tt.req)
+			assert.Equal(t, tt.want, status)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// DetectionHandler reports the detection engine version
+type DetectionHandler struct {
+	scanner *stronghold.Scanner
+}
+
+// DetectionVersionResponse describes the running detection configuration
+// and the detection release history
+type DetectionVersionResponse struct {
+	stronghold.DetectionVersion
+	Changelog []stronghold.ChangelogEntry `json:"changelog"`
+}
+
+// NewDetectionHandler creates a new detection handler
+func NewDetectionHandler(scanner *stronghold.Scanner) *DetectionHandler {
+	return &DetectionHandler{
+		scanner: scanner,
+	}
+}
+
+// RegisterRoutes registers detection routes
+func (h *DetectionHandler) RegisterRoutes(app *fiber.App) {
+	app.Get("/v1/detection/version", h.GetVersion)
+}
+
+// GetVersion returns the detection version reported in scan results
+// @Summary Get detection version
+// @Description Returns the detection rules version, engine version, enabled layers and thresholds, with a changelog of detection releases. The version field matches detection_version in scan results and usage logs.
+// @Tags scan
+// @Produce json
+// @Success 200 {object} DetectionVersionResponse
+// @Router /v1/detection/version [get]
+func (h *DetectionHandler) GetVersion(c fiber.Ctx) error {
+	return c.JSON(DetectionVersionResponse{
+		DetectionVersion: h.scanner.Version(),
+		Changelog:        stronghold.Changelog,
+	})
+}
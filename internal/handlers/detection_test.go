@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stronghold/internal/config"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDetectionVersion(t *testing.T) {
+	scanner, err := stronghold.NewScanner(&config.StrongholdConfig{BlockThreshold: 0.55, WarnThreshold: 0.35})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	NewDetectionHandler(scanner).RegisterRoutes(app)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/detection/version", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body DetectionVersionResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	assert.Equal(t, stronghold.RulesVersion, body.RulesVersion)
+	assert.True(t, strings.HasPrefix(body.Version, stronghold.RulesVersion+"+"))
+	assert.Equal(t, []string{"heuristic"}, body.Layers)
+	assert.Equal(t, 0.55, body.BlockThreshold)
+	require.NotEmpty(t, body.Changelog)
+	assert.Equal(t, stronghold.RulesVersion, body.Changelog[0].Version)
+
+	// Scan results carry the same version
+	result, err := scanner.ScanContent(t.Context(), "hello", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, body.Version, result.DetectionVersion)
+}
+
+func TestDetectionVersion_ChangesWithThresholds(t *testing.T) {
+	a, err := stronghold.NewScanner(&config.StrongholdConfig{BlockThreshold: 0.55, WarnThreshold: 0.35})
+	require.NoError(t, err)
+	b, err := stronghold.NewScanner(&config.StrongholdConfig{BlockThreshold: 0.6, WarnThreshold: 0.35})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a.Version().Version, b.Version().Version)
+	assert.Equal(t, a.Version().EngineVersion, b.Version().EngineVersion)
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+const (
+	// deviceApprovalTTL is how long a request waits for a decision
+	deviceApprovalTTL = 10 * time.Minute
+	// deviceApprovalPollInterval is how often the requesting machine should poll
+	deviceApprovalPollInterval = 3
+	// maxPendingDeviceApprovals bounds the requests an account can have waiting
+	maxPendingDeviceApprovals = 5
+)
+
+// DeviceApprovalRequest asks for this machine to be trusted by approval from
+// an existing trusted device instead of a TOTP code
+type DeviceApprovalRequest struct {
+	DeviceLabel    string `json:"device_label,omitempty"`
+	DeviceTTLDays  int    `json:"device_ttl_days,omitempty"`
+	DeviceOS       string `json:"device_os,omitempty"`
+	DeviceHostname string `json:"device_hostname,omitempty"`
+}
+
+// DeviceApprovalResponse is returned to the requesting machine. DeviceToken
+// becomes a trusted device token once the request is approved.
+type DeviceApprovalResponse struct {
+	ID                  uuid.UUID `json:"id"`
+	DeviceToken         string    `json:"device_token"`
+	Status              string    `json:"status"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	PollIntervalSeconds int       `json:"poll_interval_seconds"`
+}
+
+// DeviceApprovalStatusResponse reports the state of a request
+type DeviceApprovalStatusResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RequestDeviceApproval creates a pending request to trust this machine
+// @Summary Request device approval
+// @Description Starts trusting a new machine without a TOTP code. The response carries a device token that becomes trusted once an existing trusted device approves the request. The account's webhook, if set, receives a device.approval_requested event so the owner knows to decide. Poll GET /v1/auth/devices/approvals/{id} with the token in X-Stronghold-Device until the status is approved, denied or expired.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body DeviceApprovalRequest false "Device label and trust duration"
+// @Success 201 {object} DeviceApprovalResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 409 {object} map[string]string "No trusted device can approve the request"
+// @Failure 429 {object} map[string]string "Too many pending requests"
+// @Security CookieAuth
+// @Router /v1/auth/devices/approvals [post]
+func (h *AuthHandler) RequestDeviceApproval(c fiber.Ctx) error {
+	accountID, err := h.requireAccountID(c)
+	if err != nil || accountID == uuid.Nil {
+		return err
+	}
+
+	var req DeviceApprovalRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if _, err := parseDeviceTTL(req.DeviceTTLDays); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Context()
+	devices, err := h.db.ListDevices(ctx, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check trusted devices",
+		})
+	}
+	if len(devices) == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "No trusted device can approve this request, verify with TOTP instead",
+		})
+	}
+
+	deviceToken, err := generateDeviceToken()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate device token",
+		})
+	}
+
+	label := strings.TrimSpace(req.DeviceLabel)
+	if label == "" {
+		label = defaultDeviceLabel(c)
+	}
+
+	approval, err := h.db.CreateDeviceApproval(ctx, accountID, deviceToken, label,
+		deviceMetadata(req.DeviceOS, req.DeviceHostname), req.DeviceTTLDays,
+		c.IP(), defaultDeviceLabel(c), time.Now().UTC().Add(deviceApprovalTTL), maxPendingDeviceApprovals)
+	if err != nil {
+		if errors.Is(err, db.ErrTooManyDeviceApprovals) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many pending device approvals, approve or deny them first",
+			})
+		}
+		slog.Error("failed to create device approval", "account_id", accountID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to request approval",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(DeviceApprovalResponse{
+		ID:                  approval.ID,
+		DeviceToken:         deviceToken,
+		Status:              approval.Status,
+		ExpiresAt:           approval.ExpiresAt,
+		PollIntervalSeconds: deviceApprovalPollInterval,
+	})
+}
+
+// GetDeviceApproval reports a request's status to the machine that made it
+// @Summary Poll device approval
+// @Description Returns the status of a device approval request: pending, approved, denied or expired. Only the requesting machine can poll, by sending the device token from the request in X-Stronghold-Device. Once approved, that token is a trusted device token.
+// @Tags auth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} DeviceApprovalStatusResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Approval not found"
+// @Security CookieAuth
+// @Router /v1/auth/devices/approvals/{id} [get]
+func (h *AuthHandler) GetDeviceApproval(c fiber.Ctx) error {
+	accountID, err := h.requireAccountID(c)
+	if err != nil || accountID == uuid.Nil {
+		return err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	deviceToken := getDeviceToken(c)
+	if err != nil || deviceToken == "" {
+		return deviceApprovalNotFound(c)
+	}
+
+	approval, err := h.db.GetDeviceApprovalByToken(c.Context(), accountID, id, deviceToken)
+	if err != nil {
+		return deviceApprovalNotFound(c)
+	}
+	return c.JSON(DeviceApprovalStatusResponse{
+		ID:        approval.ID,
+		Status:    approval.Status,
+		ExpiresAt: approval.ExpiresAt,
+	})
+}
+
+// ListDeviceApprovals lists pending requests for a trusted device to decide
+// @Summary List pending device approvals
+// @Description Lists requests from new machines waiting to be trusted. Must be called from a trusted device.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string][]db.DeviceApproval
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Not a trusted device"
+// @Security CookieAuth
+// @Router /v1/auth/devices/approvals [get]
+func (h *AuthHandler) ListDeviceApprovals(c fiber.Ctx) error {
+	accountID, _, ok := h.requireApprovingDevice(c)
+	if !ok {
+		return nil
+	}
+
+	approvals, err := h.db.ListPendingDeviceApprovals(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list device approvals",
+		})
+	}
+	return c.JSON(fiber.Map{"approvals": approvals})
+}
+
+// ApproveDeviceApproval trusts the machine that made a request
+// @Summary Approve a device
+// @Description Trusts the machine that made a pending request. Must be called from a trusted device; a machine can't approve its own request.
+// @Tags auth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} db.Device
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Not a trusted device"
+// @Failure 404 {object} map[string]string "No pending approval"
+// @Security CookieAuth
+// @Router /v1/auth/devices/approvals/{id}/approve [post]
+func (h *AuthHandler) ApproveDeviceApproval(c fiber.Ctx) error {
+	accountID, approverID, ok := h.requireApprovingDevice(c)
+	if !ok {
+		return nil
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return deviceApprovalNotFound(c)
+	}
+
+	device, err := h.db.ApproveDeviceApproval(c.Context(), accountID, id, approverID)
+	if err != nil {
+		if errors.Is(err, db.ErrDeviceApprovalNotPending) {
+			return deviceApprovalNotFound(c)
+		}
+		slog.Error("failed to approve device", "account_id", accountID, "approval_id", id, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to approve device",
+		})
+	}
+	slog.Info("device approved", "account_id", accountID, "approval_id", id, "device_id", device.ID, "approved_by", approverID)
+	return c.JSON(device)
+}
+
+// DenyDeviceApproval rejects a pending request
+// @Summary Deny a device
+// @Description Rejects a pending request from a new machine. Must be called from a trusted device.
+// @Tags auth
+// @Produce json
+// @Param id path string true "Approval ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Not a trusted device"
+// @Failure 404 {object} map[string]string "No pending approval"
+// @Security CookieAuth
+// @Router /v1/auth/devices/approvals/{id}/deny [post]
+func (h *AuthHandler) DenyDeviceApproval(c fiber.Ctx) error {
+	accountID, approverID, ok := h.requireApprovingDevice(c)
+	if !ok {
+		return nil
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return deviceApprovalNotFound(c)
+	}
+
+	if err := h.db.DenyDeviceApproval(c.Context(), accountID, id, approverID); err != nil {
+		if errors.Is(err, db.ErrDeviceApprovalNotPending) {
+			return deviceApprovalNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to deny device",
+		})
+	}
+	slog.Info("device denied", "account_id", accountID, "approval_id", id, "denied_by", approverID)
+	return c.JSON(fiber.Map{"denied": id.String()})
+}
+
+// requireApprovingDevice returns the account and the trusted device making
+// the call, or writes an error response and returns false. Unlike
+// RequireTrustedDevice this applies whether or not wallet escrow is enabled:
+// a session alone must never be able to approve a device, or a stolen
+// session could trust its own machine.
+func (h *AuthHandler) requireApprovingDevice(c fiber.Ctx) (uuid.UUID, uuid.UUID, bool) {
+	accountID, err := h.requireAccountID(c)
+	if err != nil || accountID == uuid.Nil {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	deviceToken := getDeviceToken(c)
+	device, err := h.db.GetDeviceByToken(c.Context(), accountID, deviceToken)
+	if deviceToken == "" || err != nil {
+		_ = c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":         "Trusted device required",
+			"totp_required": true,
+		})
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+	_ = h.db.TouchDevice(c.Context(), accountID, deviceToken, c.IP())
+	return accountID, device.ID, true
+}
+
+func deviceApprovalNotFound(c fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Device approval not found",
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestAccount creates an account and returns its number and access token
+func createTestAccount(t *testing.T, app *fiber.App) (string, string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/v1/auth/account", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var created CreateAccountResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	var accessToken string
+	for _, cookie := range resp.Header.Values("Set-Cookie") {
+		for _, part := range strings.Split(cookie, ";") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, AccessTokenCookie+"=") {
+				accessToken = strings.TrimPrefix(part, AccessTokenCookie+"=")
+			}
+		}
+	}
+	require.NotEmpty(t, accessToken)
+	return created.AccountNumber, accessToken
+}
+
+func TestDeviceApproval_Flow(t *testing.T) {
+	app, handler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	accountNumber, accessToken := createTestAccount(t, app)
+
+	call := func(method, path, deviceToken string, out interface{}) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		if deviceToken != "" {
+			req.Header.Set(deviceTokenHeader, deviceToken)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	// Without a trusted device nobody could approve
+	assert.Equal(t, fiber.StatusConflict, call("POST", "/v1/auth/devices/approvals", "", nil))
+
+	account, err := handler.db.GetAccountByNumber(ctx, accountNumber)
+	require.NoError(t, err)
+	trustedToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	_, err = handler.db.CreateDeviceToken(ctx, account.ID, trustedToken, "laptop", db.DeviceMetadata{}, nil)
+	require.NoError(t, err)
+	_, err = testDB.Pool.Exec(ctx, `
+		INSERT INTO account_webhooks (account_id, url, secret) VALUES ($1, 'https://example.com/hook', 'whsec_test')
+	`, account.ID)
+	require.NoError(t, err)
+
+	var requested DeviceApprovalResponse
+	require.Equal(t, fiber.StatusCreated, call("POST", "/v1/auth/devices/approvals", "", &requested))
+	assert.Equal(t, "pending", requested.Status)
+	require.NotEmpty(t, requested.DeviceToken)
+	approvalPath := "/v1/auth/devices/approvals/" + requested.ID.String()
+
+	// The account's webhook is told, so a trusted device can decide
+	var payload string
+	require.NoError(t, testDB.Pool.QueryRow(ctx, `
+		SELECT payload::text FROM account_webhook_deliveries WHERE account_id = $1 AND event = $2
+	`, account.ID, db.DeviceApprovalEventType).Scan(&payload))
+	var event db.DeviceApprovalEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	assert.Equal(t, requested.ID, event.ApprovalID)
+
+	// Only the requesting machine can poll
+	assert.Equal(t, fiber.StatusNotFound, call("GET", approvalPath, trustedToken, nil))
+	var status DeviceApprovalStatusResponse
+	require.Equal(t, fiber.StatusOK, call("GET", approvalPath, requested.DeviceToken, &status))
+	assert.Equal(t, "pending", status.Status)
+
+	// The pending machine can't list or approve its own request
+	assert.Equal(t, fiber.StatusForbidden, call("GET", "/v1/auth/devices/approvals", requested.DeviceToken, nil))
+	assert.Equal(t, fiber.StatusForbidden, call("POST", approvalPath+"/approve", requested.DeviceToken, nil))
+	assert.Equal(t, fiber.StatusForbidden, call("POST", approvalPath+"/approve", "", nil))
+
+	var listed struct {
+		Approvals []map[string]interface{} `json:"approvals"`
+	}
+	require.Equal(t, fiber.StatusOK, call("GET", "/v1/auth/devices/approvals", trustedToken, &listed))
+	require.Len(t, listed.Approvals, 1)
+	assert.Equal(t, requested.ID.String(), listed.Approvals[0]["id"])
+
+	require.Equal(t, fiber.StatusOK, call("POST", approvalPath+"/approve", trustedToken, nil))
+	require.Equal(t, fiber.StatusOK, call("GET", approvalPath, requested.DeviceToken, &status))
+	assert.Equal(t, "approved", status.Status)
+
+	_, err = handler.db.GetDeviceByToken(ctx, account.ID, requested.DeviceToken)
+	assert.NoError(t, err, "the requested token should now be trusted")
+
+	// A decided request can't be decided again
+	assert.Equal(t, fiber.StatusNotFound, call("POST", approvalPath+"/deny", trustedToken, nil))
+}
+
+func TestDeviceApproval_Deny(t *testing.T) {
+	app, handler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	accountNumber, accessToken := createTestAccount(t, app)
+	account, err := handler.db.GetAccountByNumber(ctx, accountNumber)
+	require.NoError(t, err)
+	trustedToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	_, err = handler.db.CreateDeviceToken(ctx, account.ID, trustedToken, "laptop", db.DeviceMetadata{}, nil)
+	require.NoError(t, err)
+
+	pendingToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	approval, err := handler.db.CreateDeviceApproval(ctx, account.ID, pendingToken, "ci-runner", db.DeviceMetadata{OS: "linux", Hostname: "ci-runner-7"}, 30, "10.0.0.1", "stronghold-cli", time.Now().Add(time.Minute), 5)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/v1/auth/devices/approvals/"+approval.ID.String()+"/deny", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set(deviceTokenHeader, trustedToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	got, err := handler.db.GetDeviceApprovalByToken(ctx, account.ID, approval.ID, pendingToken)
+	require.NoError(t, err)
+	assert.Equal(t, "denied", got.Status)
+	_, err = handler.db.GetDeviceByToken(ctx, account.ID, pendingToken)
+	assert.Error(t, err, "a denied token must not be trusted")
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"stronghold/internal/middleware"
+	"stronghold/pkg/e2escan"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ScanKeyResponse is a key for sealing end-to-end encrypted scans
+type ScanKeyResponse = e2escan.Key
+
+// SetE2EScan enables end-to-end encrypted scans with keys from keyring
+func (h *ScanHandler) SetE2EScan(keyring *e2escan.Keyring) {
+	h.e2eKeys = keyring
+}
+
+// IssueScanKey issues a key for end-to-end encrypted scans
+// @Summary Get a key for end-to-end encrypted scans
+// @Description Issues a short-lived X25519 key. Scan requests sealed to it with the e2escan protocol and sent with Content-Type application/vnd.stronghold.e2e+json are only decrypted by the scanning backend, and their responses come back sealed. Any scan or ingest endpoint accepts them. The key is free and needs no authentication; fetch a new one when it expires or a scan answers 400 with code e2e_key_rejected.
+// @Tags scan
+// @Produce json
+// @Success 200 {object} ScanKeyResponse
+// @Failure 500 {object} map[string]string
+// @Router /v1/scan/keys [post]
+func (h *ScanHandler) IssueScanKey(c fiber.Ctx) error {
+	key, err := h.e2eKeys.Issue()
+	if err != nil {
+		slog.Error("failed to issue scan key", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Failed to issue scan key",
+			"request_id": middleware.GetRequestID(c),
+		})
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(key)
+}
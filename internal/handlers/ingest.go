@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/middleware"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// IngestStore records the documents checked by the RAG ingestion gateway.
+// *db.DB implements it.
+type IngestStore interface {
+	SaveIngestFingerprints(ctx context.Context, owner string, prints []*db.IngestFingerprint) error
+	GetIngestFingerprints(ctx context.Context, owner string, fingerprints []string) (map[string]*db.IngestFingerprint, error)
+}
+
+// Recheck statuses of a document
+const (
+	RecheckStatusRescanned = "rescanned" // Checked before and scanned again
+	RecheckStatusUnknown   = "unknown"   // Never checked by this caller; not scanned
+)
+
+// IngestVerdict is the check result for one document and the fingerprint
+// it was recorded under
+type IngestVerdict struct {
+	ID          string                 `json:"id"`
+	Fingerprint string                 `json:"fingerprint"` // Hex SHA-256 of the document text
+	Result      *stronghold.ScanResult `json:"result"`
+}
+
+// IngestCheckResponse holds a verdict per document and the roll-up decision
+type IngestCheckResponse struct {
+	Decision         stronghold.Decision `json:"decision"`                 // Most severe decision across the documents
+	WorstDocument    string              `json:"worst_document,omitempty"` // ID of the highest-scoring document, unless all are allowed
+	Documents        []IngestVerdict     `json:"documents"`                // In request order
+	RequestID        string              `json:"request_id"`
+	LatencyMs        int64               `json:"latency_ms"`
+	DetectionVersion string              `json:"detection_version,omitempty"`
+}
+
+// RecheckVerdict compares a document's current verdict with the one it was
+// last checked under
+type RecheckVerdict struct {
+	ID                       string                 `json:"id"`
+	Fingerprint              string                 `json:"fingerprint"`
+	Status                   string                 `json:"status"` // "rescanned" or "unknown"
+	PreviousDecision         stronghold.Decision    `json:"previous_decision,omitempty"`
+	PreviousDetectionVersion string                 `json:"previous_detection_version,omitempty"`
+	Changed                  bool                   `json:"changed"` // The decision differs from the previous one
+	Result                   *stronghold.ScanResult `json:"result,omitempty"`
+}
+
+// IngestRecheckResponse holds the rechecked verdicts and how many changed
+type IngestRecheckResponse struct {
+	Decision         stronghold.Decision `json:"decision"` // Most severe decision across the rescanned documents
+	WorstDocument    string              `json:"worst_document,omitempty"`
+	Changed          int                 `json:"changed"` // Rescanned documents whose decision changed
+	Unknown          int                 `json:"unknown"` // Documents never checked, which weren't scanned
+	Documents        []RecheckVerdict    `json:"documents"`
+	RequestID        string              `json:"request_id"`
+	LatencyMs        int64               `json:"latency_ms"`
+	DetectionVersion string              `json:"detection_version,omitempty"`
+}
+
+// SetIngest enables the RAG ingestion gateway at /v1/ingest
+func (h *ScanHandler) SetIngest(store IngestStore) {
+	h.ingest = store
+}
+
+// registerIngestRoutes registers the RAG ingestion gateway, priced and
+// limited like the scan routes
+func (h *ScanHandler) registerIngestRoutes(app *fiber.App) {
+	group := app.Group("/v1/ingest")
+	h.useScanMiddleware(group)
+
+	if h.paymentRouter != nil {
+		group.Post("/check", h.paymentRouter.Route(h.pricing.Ingest), h.IngestCheck)
+		group.Post("/recheck", h.paymentRouter.Route(h.pricing.Ingest), h.IngestRecheck)
+	} else {
+		group.Post("/check", h.x402.AtomicPayment(h.pricing.Ingest), h.IngestCheck)
+		group.Post("/recheck", h.x402.AtomicPayment(h.pricing.Ingest), h.IngestRecheck)
+	}
+}
+
+// IngestCheck scans documents before they enter a vector store
+// @Summary Check documents before ingestion
+// @Description Scans up to 64 documents bound for a vector store, like /v1/scan/documents, and records each document's SHA-256 fingerprint with its verdict so the documents can be rechecked with /v1/ingest/recheck when detection improves. Document text is never stored. Fingerprints are kept per account for API key requests and per payer wallet for x402 requests.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Param request body ScanDocumentsRequest true "Documents to check"
+// @Success 200 {object} IngestCheckResponse
+// @Failure 400 {object} map[string]string
+// @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
+// @Router /v1/ingest/check [post]
+func (h *ScanHandler) IngestCheck(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	var req ScanDocumentsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Invalid request body",
+			"request_id": requestID,
+		})
+	}
+	if err := h.checkScanDocuments(&req); err != nil {
+		return h.scanDocumentsError(c, requestID, err)
+	}
+
+	start := time.Now()
+	verdicts, worst, err := h.scanDocuments(c, requestID, "/v1/ingest/check", req.Mode, req.Documents)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Scan failed",
+			"request_id": requestID,
+		})
+	}
+
+	resp := &IngestCheckResponse{
+		Decision:         verdicts[worst].Result.Decision,
+		WorstDocument:    verdicts[worst].ID,
+		Documents:        make([]IngestVerdict, len(verdicts)),
+		RequestID:        requestID,
+		DetectionVersion: verdicts[worst].Result.DetectionVersion,
+	}
+	if resp.Decision == stronghold.DecisionAllow {
+		resp.WorstDocument = ""
+	}
+	prints := make([]*db.IngestFingerprint, len(verdicts))
+	for i, v := range verdicts {
+		fingerprint := ingestFingerprint(req.Documents[i].Text)
+		resp.Documents[i] = IngestVerdict{ID: v.ID, Fingerprint: fingerprint, Result: v.Result}
+		prints[i] = &db.IngestFingerprint{
+			Fingerprint:      fingerprint,
+			DocumentID:       v.ID,
+			Decision:         string(v.Result.Decision),
+			DetectionVersion: v.Result.DetectionVersion,
+		}
+	}
+
+	if err := h.ingest.SaveIngestFingerprints(c.Context(), ingestOwner(c), prints); err != nil {
+		slog.Error("failed to save ingest fingerprints", "request_id", requestID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Failed to record document fingerprints",
+			"request_id": requestID,
+		})
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	c.Locals(middleware.DetectionVersionKey, resp.DetectionVersion)
+
+	// Record execution result in payment transaction for idempotent replay
+	h.recordExecution(c, requestID, map[string]interface{}{
+		"request_id":        resp.RequestID,
+		"decision":          resp.Decision,
+		"worst_document":    resp.WorstDocument,
+		"documents":         resp.Documents,
+		"latency_ms":        resp.LatencyMs,
+		"detection_version": resp.DetectionVersion,
+	})
+
+	h.logUsage(c, documentsUsage(requestID, resp.LatencyMs, verdicts[worst].Result), "/v1/ingest/check", h.pricing.Ingest)
+
+	return c.JSON(resp)
+}
+
+// IngestRecheck rescans previously checked documents
+// @Summary Recheck ingested documents
+// @Description Rescans documents previously checked with /v1/ingest/check, for use when detection improves (see /v1/detection/version). Send the documents' text again, since only fingerprints are stored. Documents whose fingerprint the caller never checked are reported as unknown and not scanned. Each rescanned document's verdict is compared with the one it was last checked under, and the recorded verdict is updated.
+// @Tags ingest
+// @Accept json
+// @Produce json
+// @Param request body ScanDocumentsRequest true "Documents to recheck"
+// @Success 200 {object} IngestRecheckResponse
+// @Failure 400 {object} map[string]string
+// @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
+// @Router /v1/ingest/recheck [post]
+func (h *ScanHandler) IngestRecheck(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	var req ScanDocumentsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Invalid request body",
+			"request_id": requestID,
+		})
+	}
+	if err := h.checkScanDocuments(&req); err != nil {
+		return h.scanDocumentsError(c, requestID, err)
+	}
+
+	start := time.Now()
+	owner := ingestOwner(c)
+	fingerprints := make([]string, len(req.Documents))
+	for i, doc := range req.Documents {
+		fingerprints[i] = ingestFingerprint(doc.Text)
+	}
+	previous, err := h.ingest.GetIngestFingerprints(c.Context(), owner, fingerprints)
+	if err != nil {
+		slog.Error("failed to get ingest fingerprints", "request_id", requestID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Failed to look up document fingerprints",
+			"request_id": requestID,
+		})
+	}
+
+	resp := &IngestRecheckResponse{
+		Decision:  stronghold.DecisionAllow,
+		Documents: make([]RecheckVerdict, len(req.Documents)),
+		RequestID: requestID,
+	}
+	var known []ScanDocument
+	var knownAt []int
+	for i, doc := range req.Documents {
+		resp.Documents[i] = RecheckVerdict{ID: doc.ID, Fingerprint: fingerprints[i], Status: RecheckStatusUnknown}
+		if prev, ok := previous[fingerprints[i]]; ok {
+			resp.Documents[i].Status = RecheckStatusRescanned
+			resp.Documents[i].PreviousDecision = stronghold.Decision(prev.Decision)
+			resp.Documents[i].PreviousDetectionVersion = prev.DetectionVersion
+			known = append(known, doc)
+			knownAt = append(knownAt, i)
+		} else {
+			resp.Unknown++
+		}
+	}
+
+	usage := &stronghold.ScanResult{Decision: stronghold.DecisionAllow}
+	if len(known) > 0 {
+		verdicts, worst, err := h.scanDocuments(c, requestID, "/v1/ingest/recheck", req.Mode, known)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":      "Scan failed",
+				"request_id": requestID,
+			})
+		}
+
+		prints := make([]*db.IngestFingerprint, len(verdicts))
+		for j, v := range verdicts {
+			doc := &resp.Documents[knownAt[j]]
+			doc.Result = v.Result
+			doc.Changed = v.Result.Decision != doc.PreviousDecision
+			if doc.Changed {
+				resp.Changed++
+			}
+			prints[j] = &db.IngestFingerprint{
+				Fingerprint:      doc.Fingerprint,
+				DocumentID:       doc.ID,
+				Decision:         string(v.Result.Decision),
+				DetectionVersion: v.Result.DetectionVersion,
+			}
+		}
+		if err := h.ingest.SaveIngestFingerprints(c.Context(), owner, prints); err != nil {
+			slog.Error("failed to save ingest fingerprints", "request_id", requestID, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":      "Failed to record document fingerprints",
+				"request_id": requestID,
+			})
+		}
+
+		usage = verdicts[worst].Result
+		resp.Decision = usage.Decision
+		resp.DetectionVersion = usage.DetectionVersion
+		if resp.Decision != stronghold.DecisionAllow {
+			resp.WorstDocument = verdicts[worst].ID
+		}
+	}
+
+	resp.LatencyMs = time.Since(start).Milliseconds()
+	c.Locals(middleware.DetectionVersionKey, resp.DetectionVersion)
+
+	// Record execution result in payment transaction for idempotent replay
+	h.recordExecution(c, requestID, map[string]interface{}{
+		"request_id":        resp.RequestID,
+		"decision":          resp.Decision,
+		"worst_document":    resp.WorstDocument,
+		"changed":           resp.Changed,
+		"unknown":           resp.Unknown,
+		"documents":         resp.Documents,
+		"latency_ms":        resp.LatencyMs,
+		"detection_version": resp.DetectionVersion,
+	})
+
+	h.logUsage(c, documentsUsage(requestID, resp.LatencyMs, usage), "/v1/ingest/recheck", h.pricing.Ingest)
+
+	return c.JSON(resp)
+}
+
+// ingestFingerprint is the hex SHA-256 of a document's text
+func ingestFingerprint(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// ingestOwner scopes ingest fingerprints to the caller: the account for API
+// key requests, the payer's wallet for x402 requests
+func ingestOwner(c fiber.Ctx) string {
+	if id := scanAccountID(c); id != nil {
+		return id.String()
+	}
+	if tx := middleware.GetPaymentTransaction(c); tx != nil {
+		if strings.HasPrefix(tx.PayerAddress, "0x") {
+			return strings.ToLower(tx.PayerAddress)
+		}
+		return tx.PayerAddress
+	}
+	return ""
+}
+
+// compile-time check that *db.DB can back the ingestion gateway
+var _ IngestStore = (*db.DB)(nil)
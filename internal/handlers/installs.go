@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/identity"
+	"stronghold/internal/middleware"
+	"stronghold/internal/proxyversion"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// machineDarkAfter is how long an install may go without a heartbeat before
+// the fleet view reports its protection as dark. Proxies report every five
+// minutes by default, so this allows for two missed heartbeats.
+const machineDarkAfter = 15 * time.Minute
+
+// Protection statuses a proxy install reports in its heartbeat
+const (
+	ProtectionProtected = "protected" // Scanning and enforcing decisions
+	ProtectionShadow    = "shadow"    // Scanning, but decisions are only logged
+	ProtectionDegraded  = "degraded"  // Scans are failing; traffic passes or is blocked per fail_open
+)
+
+// Machine states in the fleet view: a reported protection status, or one of
+const (
+	MachineDark      = "dark"       // No heartbeat within machineDarkAfter
+	MachineNeverSeen = "never_seen" // Registered but never sent a heartbeat
+	MachineRevoked   = "revoked"
+)
+
+// InstallHandler registers proxy installs, revokes them and tracks their
+// heartbeats
+type InstallHandler struct {
+	db       *db.DB
+	versions fiber.Handler
+	now      func() time.Time
+}
+
+// NewInstallHandler creates a new install handler
+func NewInstallHandler(database *db.DB) *InstallHandler {
+	return &InstallHandler{db: database, now: time.Now}
+}
+
+// RegisterInstallRequest registers a proxy install's public key
+type RegisterInstallRequest struct {
+	PublicKey string `json:"public_key"`
+	Hostname  string `json:"hostname,omitempty"`
+	OS        string `json:"os,omitempty"`
+}
+
+// InstallResponse describes a registered proxy install
+type InstallResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Hostname  *string    `json:"hostname,omitempty"`
+	OS        *string    `json:"os,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// SetProxyVersion checks the version reported with heartbeats against the
+// supported version policy
+func (h *InstallHandler) SetProxyVersion(handler fiber.Handler) {
+	h.versions = handler
+}
+
+// HeartbeatRequest reports a proxy install's version and protection status
+type HeartbeatRequest struct {
+	Version          string `json:"version"`
+	ProtectionStatus string `json:"protection_status"` // "protected", "shadow" or "degraded"
+}
+
+// HeartbeatResponse acknowledges a heartbeat. Update is set when the proxy
+// reported its version.
+type HeartbeatResponse struct {
+	Status string               `json:"status"`
+	Update *proxyversion.Update `json:"update,omitempty"`
+}
+
+// MachineResponse describes a proxy install in the fleet view
+type MachineResponse struct {
+	ID               uuid.UUID         `json:"id"`
+	Hostname         *string           `json:"hostname,omitempty"`
+	OS               *string           `json:"os,omitempty"`
+	Version          *string           `json:"version,omitempty"`
+	ProtectionStatus *string           `json:"protection_status,omitempty"` // As last reported by the install
+	State            string            `json:"state"`                       // Reported status, or "dark", "never_seen" or "revoked"
+	LastSeenAt       *time.Time        `json:"last_seen_at,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	RevokedAt        *time.Time        `json:"revoked_at,omitempty"`
+	Usage            db.UsageAggregate `json:"usage"` // Scans the install signed in the window
+}
+
+// ListMachinesResponse lists the account's proxy installs and their usage
+// between Start and End
+type ListMachinesResponse struct {
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Machines []MachineResponse `json:"machines"`
+	Dark     int               `json:"dark"` // Active installs whose protection went dark or never reported
+}
+
+// RegisterRoutes registers install routes. The heartbeat route is
+// authenticated by the install's request signature.
+func (h *InstallHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler, installSignature fiber.Handler) {
+	group := app.Group("/v1/account/installs")
+	group.Post("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.RegisterInstall)
+	group.Delete("/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.RevokeInstall)
+
+	app.Get("/v1/account/machines", authHandler.AuthMiddleware(), h.ListMachines)
+	var heartbeat []any
+	if h.versions != nil {
+		heartbeat = append(heartbeat, h.versions)
+	}
+	app.Post("/v1/installs/heartbeat", installSignature, append(heartbeat, h.Heartbeat)...)
+}
+
+// RegisterInstall registers a proxy install's signing key with the account
+// @Summary Register a proxy install
+// @Description Registers the Ed25519 public key a proxy install signs scan requests with. Signed scans are attributed to the install until it is revoked.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body RegisterInstallRequest true "Base64 public key and machine details"
+// @Success 201 {object} InstallResponse
+// @Failure 400 {object} map[string]string "Invalid public key"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 409 {object} map[string]string "Public key already registered"
+// @Security CookieAuth
+// @Router /v1/account/installs [post]
+func (h *InstallHandler) RegisterInstall(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req RegisterInstallRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	pub, err := identity.DecodePublicKey(req.PublicKey)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	install, err := h.db.CreateProxyInstall(c.Context(), accountID, identity.EncodePublicKey(pub), deviceMetadata(req.OS, req.Hostname))
+	if errors.Is(err, db.ErrProxyInstallKeyInUse) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This public key is already registered",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register install",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(installResponse(install))
+}
+
+// RevokeInstall revokes a proxy install so its signed requests are refused
+// @Summary Revoke a proxy install
+// @Description Revokes a proxy install, e.g. a lost or compromised machine. Scan requests it signs are rejected with 403 from then on.
+// @Tags account
+// @Produce json
+// @Param id path string true "Install ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "Invalid install ID"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Install not found"
+// @Security CookieAuth
+// @Router /v1/account/installs/{id} [delete]
+func (h *InstallHandler) RevokeInstall(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	installID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid install ID",
+		})
+	}
+
+	if err := h.db.RevokeProxyInstall(c.Context(), accountID, installID); err != nil {
+		if errors.Is(err, db.ErrProxyInstallNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Install not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke install",
+		})
+	}
+	return c.JSON(fiber.Map{"revoked": installID.String()})
+}
+
+// ListMachines lists the account's proxy installs with their last heartbeat
+// and usage
+// @Summary List machines
+// @Description Lists the account's registered proxy installs with the version and protection status from their latest heartbeat. An active install that hasn't reported in 15 minutes is shown with state "dark". Each machine's usage counts the scans it signed over the last `days` days, or `start` to `end` when both are given; its spend is the price of its x402 scans.
+// @Tags account
+// @Produce json
+// @Param days query int false "Number of days of usage to include (default 30, max 365)"
+// @Param start query string false "Usage window start (RFC 3339)"
+// @Param end query string false "Usage window end (RFC 3339)"
+// @Success 200 {object} ListMachinesResponse
+// @Failure 400 {object} map[string]string "Invalid window"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/machines [get]
+func (h *InstallHandler) ListMachines(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	now := h.now()
+	start, end, err := parseUsageWindow(c.Query("days"), c.Query("start"), c.Query("end"), now.UTC())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	installs, err := h.db.ListProxyInstalls(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list machines",
+		})
+	}
+
+	usage, err := h.db.GetInstallUsage(c.Context(), accountID, start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get machine usage",
+		})
+	}
+	usageByInstall := make(map[uuid.UUID]db.UsageAggregate, len(usage))
+	for _, u := range usage {
+		usageByInstall[u.InstallID] = u.UsageAggregate
+	}
+
+	resp := ListMachinesResponse{Start: start, End: end, Machines: make([]MachineResponse, 0, len(installs))}
+	for i := range installs {
+		machine := machineResponse(&installs[i], now)
+		machine.Usage = usageByInstall[installs[i].ID]
+		if machine.State == MachineDark || machine.State == MachineNeverSeen {
+			resp.Dark++
+		}
+		resp.Machines = append(resp.Machines, machine)
+	}
+	return c.JSON(resp)
+}
+
+// Heartbeat records a proxy install's version and protection status
+// @Summary Proxy install heartbeat
+// @Description Called periodically by a registered proxy install, signed with its install key, to report its version and protection status.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body HeartbeatRequest true "Version and protection status"
+// @Success 200 {object} HeartbeatResponse
+// @Failure 400 {object} map[string]string "Invalid protection status"
+// @Failure 401 {object} map[string]string "Missing or invalid install signature"
+// @Failure 403 {object} map[string]string "Install revoked"
+// @Failure 426 {object} map[string]interface{} "Proxy version refused"
+// @Router /v1/installs/heartbeat [post]
+func (h *InstallHandler) Heartbeat(c fiber.Ctx) error {
+	installID, _ := middleware.GetInstall(c)
+	if installID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Install signature required",
+		})
+	}
+
+	var req HeartbeatRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	switch req.ProtectionStatus {
+	case ProtectionProtected, ProtectionShadow, ProtectionDegraded:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "protection_status must be protected, shadow or degraded",
+		})
+	}
+	if len(req.Version) > 64 {
+		req.Version = req.Version[:64]
+	}
+
+	if err := h.db.RecordProxyInstallHeartbeat(c.Context(), *installID, req.Version, req.ProtectionStatus); err != nil {
+		if errors.Is(err, db.ErrProxyInstallNotFound) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "This install has been revoked",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record heartbeat",
+		})
+	}
+	return c.JSON(HeartbeatResponse{Status: "ok", Update: middleware.GetProxyUpdate(c)})
+}
+
+// machineResponse derives the install's state in the fleet view
+func machineResponse(install *db.ProxyInstall, now time.Time) MachineResponse {
+	m := MachineResponse{
+		ID:               install.ID,
+		Hostname:         install.Hostname,
+		OS:               install.OS,
+		Version:          install.Version,
+		ProtectionStatus: install.ProtectionStatus,
+		LastSeenAt:       install.LastSeenAt,
+		CreatedAt:        install.CreatedAt,
+		RevokedAt:        install.RevokedAt,
+	}
+	switch {
+	case install.Revoked():
+		m.State = MachineRevoked
+	case install.LastSeenAt == nil:
+		m.State = MachineNeverSeen
+	case now.Sub(*install.LastSeenAt) > machineDarkAfter:
+		m.State = MachineDark
+	case install.ProtectionStatus != nil:
+		m.State = *install.ProtectionStatus
+	default:
+		m.State = ProtectionProtected
+	}
+	return m
+}
+
+func installResponse(install *db.ProxyInstall) InstallResponse {
+	return InstallResponse{
+		ID:        install.ID,
+		Hostname:  install.Hostname,
+		OS:        install.OS,
+		CreatedAt: install.CreatedAt,
+		RevokedAt: install.RevokedAt,
+	}
+}
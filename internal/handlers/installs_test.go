@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineResponse_State(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	stale := now.Add(-machineDarkAfter - time.Minute)
+	shadow := ProtectionShadow
+
+	tests := []struct {
+		name    string
+		install db.ProxyInstall
+		want    string
+	}{
+		{"never reported", db.ProxyInstall{}, MachineNeverSeen},
+		{"reporting", db.ProxyInstall{LastSeenAt: &recent, ProtectionStatus: &shadow}, ProtectionShadow},
+		{"went dark", db.ProxyInstall{LastSeenAt: &stale, ProtectionStatus: &shadow}, MachineDark},
+		{"revoked", db.ProxyInstall{LastSeenAt: &recent, RevokedAt: &recent}, MachineRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, machineResponse(&tt.install, now).State)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxOrgUsageWindow bounds the window a single usage query can aggregate
+const maxOrgUsageWindow = 366 * 24 * time.Hour
+
+// OrgUsageHandler serves usage aggregated across an organization's members
+type OrgUsageHandler struct {
+	db *db.DB
+}
+
+// NewOrgUsageHandler creates a new organization usage handler
+func NewOrgUsageHandler(database *db.DB) *OrgUsageHandler {
+	return &OrgUsageHandler{db: database}
+}
+
+// RegisterRoutes registers organization usage routes
+func (h *OrgUsageHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
+	app.Get("/v1/org/usage", authHandler.AuthMiddleware(), h.GetUsage)
+}
+
+// GetUsage aggregates usage across the caller's organization
+// @Summary Get organization usage
+// @Description Aggregates scans, spend, blocks and block rate across every account in the caller's WorkOS organization, broken down by member, by API key and by day (UTC). The window is the last `days` days, or `start` to `end` when both are given. Requires the admin role.
+// @Tags policy
+// @Produce json
+// @Param days query int false "Number of days to include (default 30, max 365)"
+// @Param start query string false "Window start (RFC 3339)"
+// @Param end query string false "Window end (RFC 3339)"
+// @Success 200 {object} db.OrganizationUsage
+// @Failure 400 {object} map[string]string "Invalid window"
+// @Failure 403 {object} map[string]string "Not an organization admin"
+// @Security BearerAuth
+// @Router /v1/org/usage [get]
+func (h *OrgUsageHandler) GetUsage(c fiber.Ctx) error {
+	orgID, err := requireOrgAdmin(c)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := parseUsageWindow(c.Query("days"), c.Query("start"), c.Query("end"), time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	usage, err := h.db.GetOrganizationUsage(c.Context(), orgID, start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get organization usage",
+		})
+	}
+	return c.JSON(usage)
+}
+
+// parseUsageWindow returns the [start, end] window for a usage query: start
+// and end when both are given, otherwise the last days days (default 30)
+func parseUsageWindow(days, start, end string, now time.Time) (time.Time, time.Time, error) {
+	if start != "" || end != "" {
+		if start == "" || end == "" {
+			return time.Time{}, time.Time{}, errors.New("start and end must be given together")
+		}
+		s, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("start must be an RFC 3339 time")
+		}
+		e, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("end must be an RFC 3339 time")
+		}
+		if !e.After(s) {
+			return time.Time{}, time.Time{}, errors.New("end must be after start")
+		}
+		if e.Sub(s) > maxOrgUsageWindow {
+			return time.Time{}, time.Time{}, errors.New("window must not exceed 366 days")
+		}
+		return s.UTC(), e.UTC(), nil
+	}
+
+	n := 30
+	if days != "" {
+		var err error
+		n, err = strconv.Atoi(days)
+		if err != nil || n < 1 || n > 365 {
+			return time.Time{}, time.Time{}, errors.New("days must be between 1 and 365")
+		}
+	}
+	return now.AddDate(0, 0, -n), now, nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUsageWindow(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	start, end, err := parseUsageWindow("", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -30), start)
+	assert.Equal(t, now, end)
+
+	start, _, err = parseUsageWindow("7", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), start)
+
+	start, end, err = parseUsageWindow("", "2026-03-01T00:00:00Z", "2026-03-02T00:00:00+01:00", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC), end)
+
+	for _, tc := range [][3]string{
+		{"0", "", ""},
+		{"366", "", ""},
+		{"abc", "", ""},
+		{"", "2026-03-01T00:00:00Z", ""},
+		{"", "yesterday", "2026-03-02T00:00:00Z"},
+		{"", "2026-03-02T00:00:00Z", "2026-03-01T00:00:00Z"},
+		{"", "2024-01-01T00:00:00Z", "2026-01-01T00:00:00Z"},
+	} {
+		_, _, err := parseUsageWindow(tc[0], tc[1], tc[2], now)
+		assert.Error(t, err, "days=%q start=%q end=%q", tc[0], tc[1], tc[2])
+	}
+}
+
+func TestGetOrgUsage_RequiresOrgAdmin(t *testing.T) {
+	h := NewOrgUsageHandler(nil)
+	app := fiber.New()
+	app.Get("/v1/org/usage", func(c fiber.Ctx) error {
+		c.Locals("workos_org_id", "org_01")
+		c.Locals("workos_role", "member")
+		return c.Next()
+	}, h.GetUsage)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/org/usage", nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
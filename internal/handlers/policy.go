@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// orgAdminRole is the WorkOS role slug allowed to manage organization policies
+const orgAdminRole = "admin"
+
+// PolicyHandler handles scan policy endpoints for accounts and organizations
+type PolicyHandler struct {
+	db *db.DB
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(database *db.DB) *PolicyHandler {
+	return &PolicyHandler{db: database}
+}
+
+// RegisterRoutes registers policy routes
+func (h *PolicyHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
+	account := app.Group("/v1/account/policy")
+	account.Get("/jailbreak", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetJailbreakPolicy)
+	account.Put("/jailbreak", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.UpdateJailbreakPolicy)
+	account.Get("/scoring", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ListScoringProfiles)
+	account.Put("/scoring/:mode", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.SetScoringProfile)
+	account.Delete("/scoring/:mode", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.DeleteScoringProfile)
+	account.Get("/scores", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetScoreExposure)
+	account.Put("/scores", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.UpdateScoreExposure)
+	account.Put("/scores/keys/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.UpdateAPIKeyScoreExposure)
+	account.Get("/prompts", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ListPromptFingerprints)
+	account.Post("/prompts", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.RegisterPrompt)
+	account.Delete("/prompts/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.DeletePromptFingerprint)
+	account.Get("/rule-packs", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ListRulePacks)
+	account.Post("/rule-packs", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ImportRulePack)
+	account.Get("/rule-packs/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.ExportRulePack)
+	account.Put("/rule-packs/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.UpdateRulePack)
+	account.Delete("/rule-packs/:id", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.DeleteRulePack)
+
+	org := app.Group("/v1/org/policy")
+	org.Get("/jailbreak", authHandler.AuthMiddleware(), h.GetOrgJailbreakPolicy)
+	org.Put("/jailbreak", authHandler.AuthMiddleware(), h.UpdateOrgJailbreakPolicy)
+	org.Delete("/jailbreak", authHandler.AuthMiddleware(), h.DeleteOrgJailbreakPolicy)
+
+	catalogue := app.Group("/v1/rule-packs")
+	catalogue.Get("/", authHandler.AuthMiddleware(), h.ListPublicRulePacks)
+	catalogue.Get("/:id", authHandler.AuthMiddleware(), h.ExportPublicRulePack)
+}
+
+// JailbreakPolicyRequest represents a request to update a jailbreak policy.
+// Omitted fields keep their current value.
+type JailbreakPolicyRequest struct {
+	Enabled *bool   `json:"enabled"`
+	Action  *string `json:"action"` // "warn" or "block"
+}
+
+// JailbreakPolicyResponse describes the account's own policy and the policy actually applied to scans
+type JailbreakPolicyResponse struct {
+	Account   *db.JailbreakPolicy `json:"account"`
+	Effective *db.JailbreakPolicy `json:"effective"`
+}
+
+// GetJailbreakPolicy returns the account and effective jailbreak policy
+// @Summary Get jailbreak detection policy
+// @Description Returns the account-level jailbreak policy and the effective policy after organization overrides
+// @Tags policy
+// @Produce json
+// @Success 200 {object} JailbreakPolicyResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/jailbreak [get]
+func (h *PolicyHandler) GetJailbreakPolicy(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.loadJailbreakPolicies(c, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get policy",
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// UpdateJailbreakPolicy updates the account-level jailbreak policy
+// @Summary Update jailbreak detection policy
+// @Description Toggles jailbreak detection and chooses whether jailbreak-only detections warn or block
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param request body JailbreakPolicyRequest true "Policy update"
+// @Success 200 {object} JailbreakPolicyResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/jailbreak [put]
+func (h *PolicyHandler) UpdateJailbreakPolicy(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req JailbreakPolicyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Action != nil && !db.IsValidJailbreakAction(*req.Action) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action must be 'warn' or 'block'",
+		})
+	}
+
+	defaultEnabled, err := h.db.HasActiveAPIKeys(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check account status",
+		})
+	}
+
+	current, err := h.db.GetJailbreakPolicy(c.Context(), accountID, defaultEnabled)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get policy",
+		})
+	}
+
+	enabled, action := mergeJailbreakPolicy(current, &req)
+	if err := h.db.SetJailbreakPolicy(c.Context(), accountID, enabled, action); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update policy",
+		})
+	}
+
+	resp, err := h.loadJailbreakPolicies(c, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read updated policy",
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// GetOrgJailbreakPolicy returns the organization-level jailbreak override
+// @Summary Get organization jailbreak policy
+// @Description Returns the jailbreak policy override for the caller's WorkOS organization, or null if none is set
+// @Tags policy
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string "Not an organization session"
+// @Security BearerAuth
+// @Router /v1/org/policy/jailbreak [get]
+func (h *PolicyHandler) GetOrgJailbreakPolicy(c fiber.Ctx) error {
+	orgID, _ := c.Locals("workos_org_id").(string)
+	if orgID == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Organization session required",
+		})
+	}
+
+	policy, err := h.db.GetOrganizationJailbreakPolicy(c.Context(), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get organization policy",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"organization_id": orgID,
+		"policy":          policy,
+	})
+}
+
+// UpdateOrgJailbreakPolicy sets the organization-level jailbreak override
+// @Summary Update organization jailbreak policy
+// @Description Sets a jailbreak policy that overrides the settings of every account in the organization. Requires the admin role.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param request body JailbreakPolicyRequest true "Policy update"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 403 {object} map[string]string "Not an organization admin"
+// @Security BearerAuth
+// @Router /v1/org/policy/jailbreak [put]
+func (h *PolicyHandler) UpdateOrgJailbreakPolicy(c fiber.Ctx) error {
+	orgID, err := requireOrgAdmin(c)
+	if err != nil {
+		return err
+	}
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req JailbreakPolicyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Action != nil && !db.IsValidJailbreakAction(*req.Action) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action must be 'warn' or 'block'",
+		})
+	}
+
+	current, err := h.db.GetOrganizationJailbreakPolicy(c.Context(), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get organization policy",
+		})
+	}
+	if current == nil {
+		current = &db.JailbreakPolicy{Enabled: true, Action: db.JailbreakActionBlock}
+	}
+
+	enabled, action := mergeJailbreakPolicy(current, &req)
+	if err := h.db.SetOrganizationJailbreakPolicy(c.Context(), orgID, enabled, action, accountID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update organization policy",
+		})
+	}
+
+	slog.Info("organization jailbreak policy updated",
+		"org_id", orgID, "account_id", accountID, "enabled", enabled, "action", action)
+
+	return c.JSON(fiber.Map{
+		"organization_id": orgID,
+		"policy": &db.JailbreakPolicy{
+			Enabled: enabled,
+			Action:  action,
+			Source:  db.PolicySourceOrganization,
+		},
+	})
+}
+
+// DeleteOrgJailbreakPolicy removes the organization-level jailbreak override
+// @Summary Remove organization jailbreak policy
+// @Description Removes the organization override so member accounts use their own settings. Requires the admin role.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string "Not an organization admin"
+// @Security BearerAuth
+// @Router /v1/org/policy/jailbreak [delete]
+func (h *PolicyHandler) DeleteOrgJailbreakPolicy(c fiber.Ctx) error {
+	orgID, err := requireOrgAdmin(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.DeleteOrganizationJailbreakPolicy(c.Context(), orgID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete organization policy",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"organization_id": orgID,
+		"policy":          nil,
+	})
+}
+
+// loadJailbreakPolicies reads both the account's own policy and the effective one
+func (h *PolicyHandler) loadJailbreakPolicies(c fiber.Ctx, accountID uuid.UUID) (*JailbreakPolicyResponse, error) {
+	defaultEnabled, err := h.db.HasActiveAPIKeys(c.Context(), accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := h.db.GetJailbreakPolicy(c.Context(), accountID, defaultEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	effective, err := h.db.GetEffectiveJailbreakPolicy(c.Context(), accountID, defaultEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JailbreakPolicyResponse{Account: account, Effective: effective}, nil
+}
+
+// mergeJailbreakPolicy applies the non-nil fields of req on top of current
+func mergeJailbreakPolicy(current *db.JailbreakPolicy, req *JailbreakPolicyRequest) (bool, string) {
+	enabled := current.Enabled
+	action := current.Action
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	if req.Action != nil {
+		action = *req.Action
+	}
+	if !db.IsValidJailbreakAction(action) {
+		action = db.JailbreakActionBlock
+	}
+	return enabled, action
+}
+
+// policyAccountID extracts and parses the account_id from request context.
+// Returns fiber.NewError so callers always get a non-nil error on failure.
+func policyAccountID(c fiber.Ctx) (uuid.UUID, error) {
+	accountIDStr, _ := c.Locals("account_id").(string)
+	if accountIDStr == "" {
+		return uuid.UUID{}, fiber.NewError(fiber.StatusUnauthorized, "Not authenticated")
+	}
+
+	accountID, err := uuid.Parse(accountIDStr)
+	if err != nil {
+		return uuid.UUID{}, fiber.NewError(fiber.StatusInternalServerError, "Invalid account ID")
+	}
+
+	return accountID, nil
+}
+
+// requireOrgAdmin returns the caller's WorkOS organization ID if they hold the admin role
+func requireOrgAdmin(c fiber.Ctx) (string, error) {
+	orgID, _ := c.Locals("workos_org_id").(string)
+	role, _ := c.Locals("workos_role").(string)
+	if orgID == "" || role != orgAdminRole {
+		return "", fiber.NewError(fiber.StatusForbidden, "Organization admin role required")
+	}
+	return orgID, nil
+}
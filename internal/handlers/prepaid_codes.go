@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// prepaidCodeLength is the length of a prepaid code without dashes: 80
+// random bits in base32, formatted like a recovery code
+const prepaidCodeLength = 16
+
+// PrepaidCodeHandler redeems prepaid codes into account balance
+type PrepaidCodeHandler struct {
+	db     *db.DB
+	config *config.PrepaidCodeConfig
+}
+
+// NewPrepaidCodeHandler creates a new prepaid code handler
+func NewPrepaidCodeHandler(database *db.DB, cfg *config.PrepaidCodeConfig) *PrepaidCodeHandler {
+	return &PrepaidCodeHandler{db: database, config: cfg}
+}
+
+// RedeemCodeRequest redeems a prepaid code
+type RedeemCodeRequest struct {
+	Code string `json:"code" example:"ABCD-EFGH-IJKL-MNOP"`
+}
+
+// RedeemCodeResponse reports the deposit a redeemed code made
+type RedeemCodeResponse struct {
+	Deposit     *db.Deposit    `json:"deposit"`
+	AmountUSDC  usdc.MicroUSDC `json:"amount_usdc"`
+	BalanceUSDC usdc.MicroUSDC `json:"balance_usdc"`
+}
+
+// RegisterRoutes registers prepaid code routes. limiter is the auth rate
+// limiter, applied on top of the failed redemption lockout.
+func (h *PrepaidCodeHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler, limiter fiber.Handler) {
+	app.Post("/v1/account/redeem", limiter, authHandler.AuthMiddleware(), h.Redeem)
+}
+
+// Redeem credits a prepaid code's value to the caller's balance
+// @Summary Redeem a prepaid code
+// @Description Credits the value of a single-use prepaid code to the account balance and records it as a deposit with provider prepaid_code. Codes may expire, and each batch limits how many of its codes one account may redeem. After too many failed attempts from the account or IP address, redemption is locked for a while.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body RedeemCodeRequest true "Prepaid code"
+// @Success 200 {object} RedeemCodeResponse
+// @Failure 400 {object} map[string]string "Malformed code"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 403 {object} map[string]string "Account not active"
+// @Failure 404 {object} map[string]string "Code not found"
+// @Failure 409 {object} map[string]string "Code already redeemed or batch limit reached"
+// @Failure 410 {object} map[string]string "Code expired"
+// @Failure 429 {object} map[string]string "Too many failed attempts"
+// @Security CookieAuth
+// @Router /v1/account/redeem [post]
+func (h *PrepaidCodeHandler) Redeem(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req RedeemCodeRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	code, ok := normalizePrepaidCode(req.Code)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid code format",
+		})
+	}
+
+	ctx := c.Context()
+	ip := c.IP()
+	lockout := h.config.MaxFailures > 0
+	if lockout {
+		byAccount, byIP, err := h.db.CountPrepaidCodeFailures(ctx, accountID, ip, time.Now().Add(-h.config.FailureWindow))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to redeem code",
+			})
+		}
+		if byAccount >= h.config.MaxFailures || byIP >= h.config.MaxFailures {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(h.config.FailureWindow.Seconds())))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many failed attempts, try again later",
+			})
+		}
+	}
+
+	deposit, err := h.db.RedeemPrepaidCode(ctx, accountID, db.HashToken(code))
+	if err != nil {
+		status, msg := prepaidCodeError(err)
+		if status == fiber.StatusInternalServerError {
+			slog.Error("failed to redeem prepaid code", "account_id", accountID, "error", err)
+		} else if status != fiber.StatusForbidden {
+			slog.Warn("prepaid code redemption refused",
+				"account_id", accountID, "ip", ip, "hint", prepaidCodeHint(code), "reason", err)
+			if lockout {
+				if err := h.db.RecordPrepaidCodeFailure(ctx, accountID, ip, h.config.FailureWindow); err != nil {
+					slog.Error("failed to record prepaid code failure", "account_id", accountID, "error", err)
+				}
+			}
+		}
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	slog.Info("prepaid code redeemed",
+		"account_id", accountID, "deposit_id", deposit.ID, "amount_usdc", deposit.AmountUSDC)
+
+	resp := RedeemCodeResponse{Deposit: deposit, AmountUSDC: deposit.AmountUSDC}
+	if account, err := h.db.GetAccountByID(ctx, accountID); err == nil {
+		resp.BalanceUSDC = account.BalanceUSDC
+	}
+	return c.JSON(resp)
+}
+
+// normalizePrepaidCode returns code in the form it was generated in,
+// ignoring case, spaces and dashes, and reading the digits base32 lacks as
+// the letters they are mistaken for
+func normalizePrepaidCode(code string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case r == '0':
+			r = 'O'
+		case r == '1':
+			r = 'I'
+		case r == '8':
+			r = 'B'
+		case (r < 'A' || r > 'Z') && (r < '2' || r > '7'):
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != prepaidCodeLength {
+		return "", false
+	}
+	return formatRecoveryCode(b.String()), true
+}
+
+// prepaidCodeHint is the part of a code kept to identify it for support
+func prepaidCodeHint(code string) string {
+	return code[len(code)-4:]
+}
+
+// prepaidCodeError returns the status and message for a failed redemption
+func prepaidCodeError(err error) (int, string) {
+	switch {
+	case errors.Is(err, db.ErrPrepaidCodeNotFound):
+		return fiber.StatusNotFound, "Code not found"
+	case errors.Is(err, db.ErrPrepaidCodeRedeemed):
+		return fiber.StatusConflict, "Code has already been redeemed"
+	case errors.Is(err, db.ErrPrepaidCodeLimit):
+		return fiber.StatusConflict, "This account has already redeemed its codes from this batch"
+	case errors.Is(err, db.ErrPrepaidCodeExpired):
+		return fiber.StatusGone, "Code has expired"
+	case errors.Is(err, db.ErrPrepaidCodeAccountInactive), errors.Is(err, db.ErrAccountNotFound):
+		return fiber.StatusForbidden, "Account is not active"
+	default:
+		return fiber.StatusInternalServerError, "Failed to redeem code"
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePrepaidCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+		ok   bool
+	}{
+		{"formatted", "ABCD-EFGH-IJKL-MNOP", "ABCD-EFGH-IJKL-MNOP", true},
+		{"lowercase with spaces", " abcd efgh ijkl mnop ", "ABCD-EFGH-IJKL-MNOP", true},
+		{"no dashes", "ABCDEFGH23456777", "ABCD-EFGH-2345-6777", true},
+		{"misread digits", "0BCD-1FGH-8JKL-MNOP", "OBCD-IFGH-BJKL-MNOP", true},
+		{"too short", "ABCD-EFGH-IJKL", "", false},
+		{"too long", "ABCD-EFGH-IJKL-MNOP-Q", "", false},
+		{"not base32", "ABCD-EFGH-IJKL-MN9P", "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := normalizePrepaidCode(tt.code)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizePrepaidCode_GeneratedCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes(20)
+	require.NoError(t, err)
+	for _, code := range codes {
+		got, ok := normalizePrepaidCode(strings.ToLower(code))
+		assert.True(t, ok, code)
+		assert.Equal(t, code, got)
+	}
+}
+
+func TestValidatePrepaidCodeBatch(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(24*time.Hour)
+	value := usdc.DecimalAmount{Micro: 5_000_000}
+	maxValue := usdc.MicroUSDC(100_000_000)
+	tests := []struct {
+		name  string
+		req   CreatePrepaidCodesRequest
+		valid bool
+	}{
+		{"valid", CreatePrepaidCodesRequest{Label: "Trial", Count: 10, ValueUSDC: value, ExpiresAt: &future}, true},
+		{"no label", CreatePrepaidCodesRequest{Label: " ", Count: 10, ValueUSDC: value}, false},
+		{"long label", CreatePrepaidCodesRequest{Label: strings.Repeat("a", maxPrepaidCodeLabelLength+1), Count: 10, ValueUSDC: value}, false},
+		{"no codes", CreatePrepaidCodesRequest{Label: "Trial", ValueUSDC: value}, false},
+		{"too many codes", CreatePrepaidCodesRequest{Label: "Trial", Count: maxPrepaidCodeBatch + 1, ValueUSDC: value}, false},
+		{"numeric value", CreatePrepaidCodesRequest{Label: "Trial", Count: 10, ValueUSDC: usdc.DecimalAmount{Micro: 5_000_000, Legacy: true}}, false},
+		{"zero value", CreatePrepaidCodesRequest{Label: "Trial", Count: 10}, false},
+		{"over max value", CreatePrepaidCodesRequest{Label: "Trial", Count: 10, ValueUSDC: usdc.DecimalAmount{Micro: maxValue + 1}}, false},
+		{"negative limit", CreatePrepaidCodesRequest{Label: "Trial", Count: 10, ValueUSDC: value, PerAccountLimit: -1}, false},
+		{"expired", CreatePrepaidCodesRequest{Label: "Trial", Count: 10, ValueUSDC: value, ExpiresAt: &past}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := validatePrepaidCodeBatch(&tt.req, maxValue, now)
+			assert.Equal(t, tt.valid, msg == "", msg)
+		})
+	}
+
+	req := CreatePrepaidCodesRequest{Label: " Trial ", Count: 1, ValueUSDC: value}
+	require.Empty(t, validatePrepaidCodeBatch(&req, maxValue, now))
+	assert.Equal(t, "Trial", req.Label)
+	assert.Equal(t, 1, req.PerAccountLimit)
+}
+
+func TestPrepaidCodeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{db.ErrPrepaidCodeNotFound, fiber.StatusNotFound},
+		{db.ErrPrepaidCodeRedeemed, fiber.StatusConflict},
+		{db.ErrPrepaidCodeLimit, fiber.StatusConflict},
+		{db.ErrPrepaidCodeExpired, fiber.StatusGone},
+		{db.ErrPrepaidCodeAccountInactive, fiber.StatusForbidden},
+		{fmt.Errorf("wrapped: %w", db.ErrPrepaidCodeRedeemed), fiber.StatusConflict},
+		{errors.New("connection reset"), fiber.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		status, _ := prepaidCodeError(tt.err)
+		assert.Equal(t, tt.want, status, tt.err.Error())
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"stronghold/internal/db"
 	"stronghold/internal/middleware"
 	"stronghold/internal/usdc"
 
@@ -9,7 +10,10 @@ import (
 
 // PricingHandler handles pricing-related endpoints
 type PricingHandler struct {
-	x402 *middleware.X402Middleware
+	x402         *middleware.X402Middleware
+	apiKeys      *middleware.APIKeyMiddleware // Optional; identifies the caller for estimates
+	db           *db.DB
+	maxTextBytes int
 }
 
 // PricingResponse represents the pricing information response
@@ -25,8 +29,12 @@ type RoutePrice struct {
 	Path           string         `json:"path"`
 	Method         string         `json:"method"`
 	PriceMicroUSDC usdc.MicroUSDC `json:"price_micro_usdc"`
-	PriceUSD       float64        `json:"price_usd"`
+	PriceUSDC      string         `json:"price_usdc"` // Decimal string, e.g. "0.001"
+	PriceUSD       float64        `json:"price_usd"`  // Deprecated: float form of price_usdc
 	Description    string         `json:"description"`
+	// Accepts lists every way to pay for the route, with the chain parameters
+	// needed to build the payment
+	Accepts []middleware.NetworkPrice `json:"accepts"`
 }
 
 // NewPricingHandler creates a new pricing handler
@@ -39,11 +47,12 @@ func NewPricingHandler(x402 *middleware.X402Middleware) *PricingHandler {
 // RegisterRoutes registers pricing routes
 func (h *PricingHandler) RegisterRoutes(app *fiber.App) {
 	app.Get("/v1/pricing", h.GetPricing)
+	app.Post("/v1/pricing/estimate", h.EstimateCost)
 }
 
 // GetPricing returns pricing information for all endpoints
 // @Summary Get pricing information
-// @Description Returns the pricing for all protected endpoints
+// @Description Returns the pricing for all protected endpoints, with per-network payment parameters
 // @Tags pricing
 // @Produce json
 // @Success 200 {object} PricingResponse
@@ -59,14 +68,26 @@ func (h *PricingHandler) GetPricing(c fiber.Ctx) error {
 			description = "Content scanning for prompt injection detection"
 		case "/v1/scan/output":
 			description = "Output scanning for credential leak detection"
+		case "/v1/scan/tool-call":
+			description = "Tool-call argument scanning for dangerous commands, paths and URLs"
+		case "/v1/scan/session/message":
+			description = "Conversation message scanning for multi-turn prompt injection, per message"
+		case "/v1/scan/documents":
+			description = "Multi-document prompt injection scanning with per-document verdicts"
+		case "/v1/ingest/check":
+			description = "RAG ingestion check of a document batch, recording fingerprints for rechecks"
+		case "/v1/ingest/recheck":
+			description = "RAG ingestion recheck of previously checked documents against current detection"
 		}
 
 		routePrices = append(routePrices, RoutePrice{
 			Path:           route.Path,
 			Method:         route.Method,
 			PriceMicroUSDC: route.Price,
+			PriceUSDC:      route.Price.String(),
 			PriceUSD:       route.Price.Float(),
 			Description:    description,
+			Accepts:        h.x402.NetworkPrices(route.Price),
 		})
 	}
 
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/middleware"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// maxEstimateWorkloads bounds the workloads in one estimate
+	maxEstimateWorkloads = 20
+	// maxEstimateRequests bounds the monthly requests an estimate covers
+	maxEstimateRequests = 1_000_000_000
+)
+
+// batchEndpoints scan several documents per request
+var batchEndpoints = map[string]bool{
+	"/v1/scan/documents": true,
+	"/v1/ingest/check":   true,
+	"/v1/ingest/recheck": true,
+}
+
+// EstimateWorkload is one planned use of a priced endpoint
+type EstimateWorkload struct {
+	Endpoint            string `json:"endpoint"`                        // Priced path, e.g. "/v1/scan/content"
+	Documents           int64  `json:"documents"`                       // Documents, messages or tool calls scanned per month
+	AvgDocumentBytes    int    `json:"avg_document_bytes,omitempty"`    // Documents over the per-request text limit take several requests
+	DocumentsPerRequest int    `json:"documents_per_request,omitempty"` // Batch size for /v1/scan/documents and /v1/ingest (default and max 64)
+}
+
+// EstimateRequest describes a planned monthly workload
+type EstimateRequest struct {
+	Workloads []EstimateWorkload `json:"workloads"`
+}
+
+// WorkloadEstimate is the estimated monthly cost of one workload
+type WorkloadEstimate struct {
+	Endpoint          string         `json:"endpoint"`
+	Documents         int64          `json:"documents"`
+	Requests          int64          `json:"requests"`         // Billed requests the documents take
+	PriceMicroUSDC    usdc.MicroUSDC `json:"price_micro_usdc"` // List price per request
+	ListCostMicroUSDC usdc.MicroUSDC `json:"list_cost_micro_usdc"`
+	CostMicroUSDC     usdc.MicroUSDC `json:"cost_micro_usdc"`
+	CostUSDC          string         `json:"cost_usdc"`
+}
+
+// EstimateResponse is the estimated monthly cost of a workload. The list
+// cost is what x402 payments would cost; the cost is what account billing
+// would charge after volume discounts.
+type EstimateResponse struct {
+	Currency               string             `json:"currency"`
+	Workloads              []WorkloadEstimate `json:"workloads"`
+	MonthlyRequests        int64              `json:"monthly_requests"`
+	ListCostMicroUSDC      usdc.MicroUSDC     `json:"list_cost_micro_usdc"`
+	ListCostUSDC           string             `json:"list_cost_usdc"`
+	CostMicroUSDC          usdc.MicroUSDC     `json:"cost_micro_usdc"`
+	CostUSDC               string             `json:"cost_usdc"`
+	VolumeTier             int                `json:"volume_tier"`              // Tier reached by the end of the month; 0 is list price
+	CurrentMonthlyRequests int64              `json:"current_monthly_requests"` // Billed requests the caller's account made this month, counted toward tiers
+	CurrentVolumeTier      int                `json:"current_volume_tier"`
+}
+
+// SetAccounts lets API-key callers have their estimate start from the volume
+// tier their account has reached this month
+func (h *PricingHandler) SetAccounts(apiKeys *middleware.APIKeyMiddleware, database *db.DB) {
+	h.apiKeys = apiKeys
+	h.db = database
+}
+
+// SetMaxTextBytes sets the text limit of a single scan request, which
+// decides how many requests large documents take
+func (h *PricingHandler) SetMaxTextBytes(n int) {
+	h.maxTextBytes = n
+}
+
+// textLimit returns the largest text a scan request may contain
+func (h *PricingHandler) textLimit() int {
+	if h.maxTextBytes > 0 {
+		return h.maxTextBytes
+	}
+	return defaultMaxTextBytes
+}
+
+// EstimateCost estimates the monthly cost of a planned workload
+// @Summary Estimate monthly cost
+// @Description Estimates the monthly cost of a planned workload under current pricing. Each workload names a priced endpoint and the documents it scans a month; documents larger than the per-request text limit, and batches for /v1/scan/documents and /v1/ingest, are converted into billed requests. Volume discounts are applied as the month's requests reach each tier. Requests with an API key start from the requests the account has already made this month.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param request body EstimateRequest true "Planned workloads"
+// @Success 200 {object} EstimateResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/pricing/estimate [post]
+func (h *PricingHandler) EstimateCost(c fiber.Ctx) error {
+	var req EstimateRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var current int64
+	if h.apiKeys != nil && len(c.Request().Header.Peek("Authorization")) > 0 {
+		account, _, err := h.apiKeys.Authenticate(c)
+		if err != nil {
+			return err
+		}
+		current = h.monthlyRequests(c, account)
+	}
+
+	resp, err := estimateWorkloads(h.x402.GetPricing(), h.x402.GetRoutes(), h.textLimit(), current, req.Workloads)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(resp)
+}
+
+// monthlyRequests returns the billed requests an account has made this
+// calendar month, or zero when no volume tiers are configured
+func (h *PricingHandler) monthlyRequests(c fiber.Ctx, account *db.Account) int64 {
+	if len(h.x402.GetPricing().VolumeTiers) == 0 {
+		return 0
+	}
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	stats, err := h.db.GetUsageStats(c.Context(), account.ID, monthStart, now)
+	if err != nil {
+		// Estimate from the start of the month rather than failing
+		slog.Warn("failed to load monthly usage for cost estimate", "account_id", account.ID, "error", err)
+		return 0
+	}
+	return stats.TotalRequests
+}
+
+// workloadRequests converts a workload's documents into billed requests
+func workloadRequests(w EstimateWorkload, maxTextBytes int) (int64, error) {
+	if w.Documents <= 0 || w.Documents > maxEstimateRequests {
+		return 0, fmt.Errorf("%s: documents must be between 1 and %d", w.Endpoint, maxEstimateRequests)
+	}
+	if w.AvgDocumentBytes < 0 {
+		return 0, fmt.Errorf("%s: avg_document_bytes must not be negative", w.Endpoint)
+	}
+
+	if !batchEndpoints[w.Endpoint] {
+		if w.DocumentsPerRequest != 0 {
+			return 0, fmt.Errorf("%s: documents_per_request only applies to batch endpoints", w.Endpoint)
+		}
+		// Larger documents are scanned in windows of the text limit
+		windows := int64(1)
+		if w.AvgDocumentBytes > maxTextBytes {
+			windows = int64((w.AvgDocumentBytes + maxTextBytes - 1) / maxTextBytes)
+		}
+		if w.Documents > maxEstimateRequests/windows {
+			return 0, fmt.Errorf("%s: workload exceeds %d requests a month", w.Endpoint, maxEstimateRequests)
+		}
+		return w.Documents * windows, nil
+	}
+
+	if w.DocumentsPerRequest < 0 || w.DocumentsPerRequest > maxScanDocuments {
+		return 0, fmt.Errorf("%s: documents_per_request must be between 1 and %d", w.Endpoint, maxScanDocuments)
+	}
+	perRequest := maxScanDocuments
+	if w.DocumentsPerRequest > 0 {
+		perRequest = w.DocumentsPerRequest
+	}
+	// A batch's combined text is held to the same limit as a single scan
+	if w.AvgDocumentBytes > 0 {
+		fit := maxTextBytes / w.AvgDocumentBytes
+		if fit == 0 {
+			return 0, fmt.Errorf("%s: documents over %d bytes don't fit in a batch; scan them with /v1/scan/content", w.Endpoint, maxTextBytes)
+		}
+		perRequest = min(perRequest, fit)
+	}
+	return (w.Documents + int64(perRequest) - 1) / int64(perRequest), nil
+}
+
+// estimateWorkloads prices workloads for a month that starts current billed
+// requests in. The workloads are assumed to be spread evenly over the month,
+// so each has its share of requests in every volume tier reached.
+func estimateWorkloads(pricing *config.PricingConfig, routes []middleware.PriceRoute, maxTextBytes int, current int64, workloads []EstimateWorkload) (*EstimateResponse, error) {
+	if len(workloads) == 0 || len(workloads) > maxEstimateWorkloads {
+		return nil, fmt.Errorf("workloads must hold between 1 and %d workloads", maxEstimateWorkloads)
+	}
+
+	prices := make(map[string]usdc.MicroUSDC, len(routes))
+	for _, route := range routes {
+		prices[route.Path] = route.Price
+	}
+
+	resp := &EstimateResponse{
+		Currency:               "USDC",
+		Workloads:              make([]WorkloadEstimate, 0, len(workloads)),
+		CurrentMonthlyRequests: current,
+	}
+	for _, w := range workloads {
+		price, ok := prices[w.Endpoint]
+		if !ok {
+			return nil, fmt.Errorf("unknown endpoint %q; see GET /v1/pricing for priced endpoints", w.Endpoint)
+		}
+		requests, err := workloadRequests(w, maxTextBytes)
+		if err != nil {
+			return nil, err
+		}
+		resp.MonthlyRequests += requests
+		if resp.MonthlyRequests > maxEstimateRequests {
+			return nil, errors.New("workloads exceed the requests an estimate covers")
+		}
+		resp.Workloads = append(resp.Workloads, WorkloadEstimate{
+			Endpoint:          w.Endpoint,
+			Documents:         w.Documents,
+			Requests:          requests,
+			PriceMicroUSDC:    price,
+			ListCostMicroUSDC: price * usdc.MicroUSDC(requests),
+		})
+	}
+
+	runs := volumeRuns(pricing, current, resp.MonthlyRequests)
+	for i := range resp.Workloads {
+		w := &resp.Workloads[i]
+		var done, allocated int64
+		for _, run := range runs {
+			// The workload's requests up to the end of the run, in proportion
+			done += run.requests
+			share := w.Requests*done/resp.MonthlyRequests - allocated
+			allocated += share
+			unit, _ := pricing.PriceForVolume(w.PriceMicroUSDC, run.start)
+			w.CostMicroUSDC += unit * usdc.MicroUSDC(share)
+		}
+		w.CostUSDC = w.CostMicroUSDC.String()
+		resp.ListCostMicroUSDC += w.ListCostMicroUSDC
+		resp.CostMicroUSDC += w.CostMicroUSDC
+	}
+	resp.ListCostUSDC = resp.ListCostMicroUSDC.String()
+	resp.CostUSDC = resp.CostMicroUSDC.String()
+
+	_, tier := pricing.PriceForVolume(0, current)
+	resp.CurrentVolumeTier = tier + 1
+	_, tier = pricing.PriceForVolume(0, current+max(resp.MonthlyRequests-1, 0))
+	resp.VolumeTier = tier + 1
+	return resp, nil
+}
+
+// volumeRun is a stretch of consecutive requests billed at one volume tier
+type volumeRun struct {
+	start    int64 // Billed requests made before the run's first
+	requests int64
+}
+
+// volumeRuns splits n requests made after start into runs that each stay
+// within one volume tier
+func volumeRuns(pricing *config.PricingConfig, start, n int64) []volumeRun {
+	var runs []volumeRun
+	end := start + n
+	for pos := start; pos < end; {
+		next := end
+		for _, t := range pricing.VolumeTiers {
+			if t.MinRequests > pos && t.MinRequests < next {
+				next = t.MinRequests
+			}
+		}
+		runs = append(runs, volumeRun{start: pos, requests: next - pos})
+		pos = next
+	}
+	return runs
+}
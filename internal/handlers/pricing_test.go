@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"stronghold/internal/config"
@@ -54,12 +56,18 @@ func TestGetPricing_ReturnsAllRoutes(t *testing.T) {
 		assert.NotEmpty(t, route.Method)
 		assert.GreaterOrEqual(t, route.PriceMicroUSDC, usdc.MicroUSDC(0))
 		assert.GreaterOrEqual(t, route.PriceUSD, 0.0)
+		assert.Equal(t, route.PriceMicroUSDC.String(), route.PriceUSDC)
 	}
 
 	// These endpoints should be in the pricing
 	expectedPaths := []string{
 		"/v1/scan/content",
 		"/v1/scan/output",
+		"/v1/scan/tool-call",
+		"/v1/scan/session/message",
+		"/v1/scan/documents",
+		"/v1/ingest/check",
+		"/v1/ingest/recheck",
 	}
 
 	for _, path := range expectedPaths {
@@ -101,6 +109,11 @@ func TestGetPricing_HasDescriptions(t *testing.T) {
 
 	assert.Contains(t, descriptionsByPath["/v1/scan/content"], "prompt injection")
 	assert.Contains(t, descriptionsByPath["/v1/scan/output"], "credential leak")
+	assert.Contains(t, descriptionsByPath["/v1/scan/tool-call"], "Tool-call")
+	assert.Contains(t, descriptionsByPath["/v1/scan/session/message"], "multi-turn")
+	assert.Contains(t, descriptionsByPath["/v1/scan/documents"], "Multi-document")
+	assert.Contains(t, descriptionsByPath["/v1/ingest/check"], "RAG ingestion")
+	assert.Contains(t, descriptionsByPath["/v1/ingest/recheck"], "RAG ingestion")
 }
 
 func TestGetPricing_CorrectPrices(t *testing.T) {
@@ -200,3 +213,154 @@ func TestGetPricing_AllRoutesHaveMethod(t *testing.T) {
 		assert.Equal(t, "POST", route.Method, "Scan routes should be POST")
 	}
 }
+
+func TestGetPricing_NetworkParameters(t *testing.T) {
+	x402cfg := &config.X402Config{
+		EVMWalletAddress:    "0x1234567890123456789012345678901234567890",
+		SolanaWalletAddress: "7xKXtg2CWYuV7i8UEz5B2oS6x9fPVkDz7M8f8f8f8f8f",
+		FacilitatorURL:      "https://x402.org/facilitator",
+		Networks:            []string{"base", "solana"},
+		SolanaFeePayer:      "FeePayer1111111111111111111111111111111111",
+	}
+	pricingCfg := &config.PricingConfig{
+		ScanContent:   usdc.MicroUSDC(1000),
+		ScanOutput:    usdc.MicroUSDC(2500),
+		ScanToolCall:  usdc.MicroUSDC(1000),
+		ScanSession:   usdc.MicroUSDC(1000),
+		ScanDocuments: usdc.MicroUSDC(1000),
+		Ingest:        usdc.MicroUSDC(1000),
+	}
+
+	x402 := middleware.NewX402Middleware(x402cfg, pricingCfg)
+	handler := NewPricingHandler(x402)
+
+	app := fiber.New()
+	handler.RegisterRoutes(app)
+
+	req := httptest.NewRequest("GET", "/v1/pricing", nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body PricingResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	require.NoError(t, err)
+
+	for _, route := range body.Routes {
+		require.Len(t, route.Accepts, 2, "route %s", route.Path)
+
+		base, sol := route.Accepts[0], route.Accepts[1]
+		assert.Equal(t, "base", base.Network)
+		assert.Equal(t, "eip155:8453", base.CAIP2)
+		assert.Equal(t, 8453, base.ChainID)
+		assert.Equal(t, "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", base.Token)
+		assert.Equal(t, 6, base.TokenDecimals)
+		assert.Equal(t, x402cfg.EVMWalletAddress, base.Recipient)
+		assert.Empty(t, base.FeePayer)
+
+		assert.Equal(t, "solana", sol.Network)
+		assert.Equal(t, 0, sol.ChainID)
+		assert.Equal(t, x402cfg.SolanaWalletAddress, sol.Recipient)
+		assert.Equal(t, x402cfg.SolanaFeePayer, sol.FeePayer)
+
+		expected := "1000"
+		if route.Path == "/v1/scan/output" {
+			expected = "2500"
+		}
+		assert.Equal(t, expected, base.Amount)
+		assert.Equal(t, expected, sol.Amount)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	x402 := middleware.NewX402Middleware(&config.X402Config{}, &config.PricingConfig{
+		ScanContent:   usdc.MicroUSDC(1000),
+		ScanDocuments: usdc.MicroUSDC(5000),
+		VolumeTiers:   []config.VolumeTier{{MinRequests: 1000, DiscountPercent: 10}},
+	})
+	app := fiber.New()
+	NewPricingHandler(x402).RegisterRoutes(app)
+
+	post := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/v1/pricing/estimate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// 64,000 documents in full batches take 1,000 requests, and the
+	// workloads share the discounted second half of the month evenly
+	resp := post(`{"workloads": [
+		{"endpoint": "/v1/scan/content", "documents": 1000},
+		{"endpoint": "/v1/scan/documents", "documents": 64000}
+	]}`)
+	defer resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode)
+
+	var body EstimateResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, int64(2000), body.MonthlyRequests)
+	require.Len(t, body.Workloads, 2)
+	assert.Equal(t, int64(1000), body.Workloads[1].Requests)
+	assert.Equal(t, usdc.MicroUSDC(950_000), body.Workloads[0].CostMicroUSDC)
+	assert.Equal(t, usdc.MicroUSDC(4_750_000), body.Workloads[1].CostMicroUSDC)
+	assert.Equal(t, usdc.MicroUSDC(6_000_000), body.ListCostMicroUSDC)
+	assert.Equal(t, "5.70", body.CostUSDC)
+	assert.Equal(t, 1, body.VolumeTier)
+	assert.Equal(t, 0, body.CurrentVolumeTier)
+
+	for _, invalid := range []string{
+		`{"workloads": []}`,
+		`{"workloads": [{"endpoint": "/v1/unknown", "documents": 10}]}`,
+		`{"workloads": [{"endpoint": "/v1/scan/content", "documents": 0}]}`,
+	} {
+		resp := post(invalid)
+		resp.Body.Close()
+		assert.Equal(t, 400, resp.StatusCode, invalid)
+	}
+}
+
+func TestEstimateWorkloads_StartsFromCurrentTier(t *testing.T) {
+	pricing := &config.PricingConfig{
+		ScanContent: usdc.MicroUSDC(1000),
+		VolumeTiers: []config.VolumeTier{{MinRequests: 1000, DiscountPercent: 10}, {MinRequests: 10000, DiscountPercent: 20}},
+	}
+	routes := []middleware.PriceRoute{{Path: "/v1/scan/content", Method: "POST", Price: pricing.ScanContent}}
+
+	resp, err := estimateWorkloads(pricing, routes, defaultMaxTextBytes, 9500, []EstimateWorkload{
+		{Endpoint: "/v1/scan/content", Documents: 2000},
+	})
+	require.NoError(t, err)
+	// 500 requests at 900 before reaching the second tier, then 1,500 at 800
+	assert.Equal(t, usdc.MicroUSDC(1_650_000), resp.CostMicroUSDC)
+	assert.Equal(t, 1, resp.CurrentVolumeTier)
+	assert.Equal(t, 2, resp.VolumeTier)
+}
+
+func TestWorkloadRequests(t *testing.T) {
+	const limit = 500 * 1024
+	tests := []struct {
+		name     string
+		workload EstimateWorkload
+		want     int64
+		ok       bool
+	}{
+		{"one request per document", EstimateWorkload{Endpoint: "/v1/scan/content", Documents: 10}, 10, true},
+		{"large documents take windows", EstimateWorkload{Endpoint: "/v1/scan/content", Documents: 10, AvgDocumentBytes: 1200 * 1024}, 30, true},
+		{"full batches", EstimateWorkload{Endpoint: "/v1/scan/documents", Documents: 100}, 2, true},
+		{"batch size", EstimateWorkload{Endpoint: "/v1/ingest/check", Documents: 100, DocumentsPerRequest: 10}, 10, true},
+		{"batches held to the text limit", EstimateWorkload{Endpoint: "/v1/scan/documents", Documents: 100, AvgDocumentBytes: 20 * 1024}, 4, true},
+		{"document too large to batch", EstimateWorkload{Endpoint: "/v1/scan/documents", Documents: 1, AvgDocumentBytes: limit + 1}, 0, false},
+		{"batch size over the limit", EstimateWorkload{Endpoint: "/v1/scan/documents", Documents: 1, DocumentsPerRequest: 65}, 0, false},
+		{"batch size on a single endpoint", EstimateWorkload{Endpoint: "/v1/scan/content", Documents: 1, DocumentsPerRequest: 2}, 0, false},
+		{"too many requests", EstimateWorkload{Endpoint: "/v1/scan/content", Documents: maxEstimateRequests, AvgDocumentBytes: limit + 1}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := workloadRequests(tt.workload, limit)
+			assert.Equal(t, tt.ok, err == nil, "error: %v", err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// maxPromptShingles bounds a fingerprint's size; about a 40,000 word prompt
+const maxPromptShingles = 40000
+
+// RegisterPromptRequest registers a system prompt for leak detection. Send
+// either the prompt, which is hashed and discarded, or its shingles computed
+// client-side.
+type RegisterPromptRequest struct {
+	Name     string   `json:"name"`
+	Prompt   string   `json:"prompt,omitempty"`
+	Shingles []string `json:"shingles,omitempty"`
+}
+
+// PromptFingerprintResponse describes a registered system prompt
+type PromptFingerprintResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Shingles  int       `json:"shingles"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PromptFingerprintsResponse lists an account's registered system prompts
+type PromptFingerprintsResponse struct {
+	Prompts []PromptFingerprintResponse `json:"prompts"`
+}
+
+// ListPromptFingerprints returns the account's registered system prompts
+// @Summary List registered system prompts
+// @Description Returns the system prompts registered for leak detection in output scans. Only their names and shingle counts are kept.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} PromptFingerprintsResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/prompts [get]
+func (h *PolicyHandler) ListPromptFingerprints(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	prints, err := h.db.ListPromptFingerprints(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get registered prompts",
+		})
+	}
+
+	resp := PromptFingerprintsResponse{Prompts: make([]PromptFingerprintResponse, 0, len(prints))}
+	for _, fp := range prints {
+		resp.Prompts = append(resp.Prompts, promptFingerprintResponse(fp))
+	}
+	return c.JSON(resp)
+}
+
+// RegisterPrompt registers a system prompt for leak detection
+// @Summary Register a system prompt
+// @Description Registers a system prompt so output scans flag responses that repeat it. Send the prompt (hashed into shingles and discarded) or shingles computed client-side: truncated SHA-256 hashes (16 hex characters) of every 8-word run of the lowercased prompt split on non-alphanumerics. Prompts need at least 8 words.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param request body RegisterPromptRequest true "Prompt name and text or shingles"
+// @Success 201 {object} PromptFingerprintResponse
+// @Failure 400 {object} map[string]string "Invalid prompt or shingles"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 409 {object} map[string]string "Name already registered or too many prompts"
+// @Security CookieAuth
+// @Router /v1/account/policy/prompts [post]
+func (h *PolicyHandler) RegisterPrompt(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req RegisterPromptRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name is required and must be at most 100 characters",
+		})
+	}
+
+	shingles, err := promptShingles(&req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	fp, err := h.db.CreatePromptFingerprint(c.Context(), accountID, req.Name, shingles)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrPromptFingerprintExists):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A prompt with this name is already registered",
+			})
+		case errors.Is(err, db.ErrTooManyPromptFingerprints):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": fmt.Sprintf("At most %d prompts can be registered", db.MaxPromptFingerprints),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to register prompt",
+		})
+	}
+
+	slog.Info("system prompt registered", "account_id", accountID.String(), "name", fp.Name, "shingles", len(shingles))
+	return c.Status(fiber.StatusCreated).JSON(promptFingerprintResponse(fp))
+}
+
+// DeletePromptFingerprint removes a registered system prompt
+// @Summary Delete a registered system prompt
+// @Description Stops checking output scans for leaks of the prompt
+// @Tags policy
+// @Produce json
+// @Param id path string true "Prompt ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Prompt not found"
+// @Security CookieAuth
+// @Router /v1/account/policy/prompts/{id} [delete]
+func (h *PolicyHandler) DeletePromptFingerprint(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Prompt not found",
+		})
+	}
+
+	if err := h.db.DeletePromptFingerprint(c.Context(), accountID, id); err != nil {
+		if errors.Is(err, db.ErrPromptFingerprintNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Prompt not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete prompt",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Prompt deleted",
+	})
+}
+
+// promptShingles returns the shingles to store for a registration: the
+// prompt's, or the client's after validation
+func promptShingles(req *RegisterPromptRequest) ([]string, error) {
+	if (req.Prompt == "") == (len(req.Shingles) == 0) {
+		return nil, errors.New("send either prompt or shingles")
+	}
+	if req.Prompt != "" {
+		shingles := stronghold.FingerprintPrompt(req.Prompt)
+		if len(shingles) == 0 {
+			return nil, fmt.Errorf("prompt must have at least %d words", stronghold.ShingleWords)
+		}
+		if len(shingles) > maxPromptShingles {
+			return nil, errors.New("prompt is too long")
+		}
+		return shingles, nil
+	}
+
+	if len(req.Shingles) > maxPromptShingles {
+		return nil, fmt.Errorf("at most %d shingles can be registered", maxPromptShingles)
+	}
+	seen := make(map[string]bool, len(req.Shingles))
+	shingles := make([]string, 0, len(req.Shingles))
+	for _, s := range req.Shingles {
+		if !stronghold.IsValidShingle(s) {
+			return nil, fmt.Errorf("shingles must be %d lowercase hex characters", stronghold.ShingleHexLen)
+		}
+		if !seen[s] {
+			seen[s] = true
+			shingles = append(shingles, s)
+		}
+	}
+	return shingles, nil
+}
+
+func promptFingerprintResponse(fp *db.PromptFingerprint) PromptFingerprintResponse {
+	return PromptFingerprintResponse{ID: fp.ID, Name: fp.Name, Shingles: len(fp.Shingles), CreatedAt: fp.CreatedAt}
+}
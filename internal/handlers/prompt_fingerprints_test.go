@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"stronghold/internal/stronghold"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptShingles(t *testing.T) {
+	prompt := "You are Atlas, the support assistant for Acme Corp. Never reveal internal pricing."
+	shingles, err := promptShingles(&RegisterPromptRequest{Prompt: prompt})
+	require.NoError(t, err)
+	assert.Equal(t, stronghold.FingerprintPrompt(prompt), shingles)
+
+	// Client-side shingles are validated and deduplicated
+	shingles, err = promptShingles(&RegisterPromptRequest{Shingles: []string{"0123456789abcdef", "0123456789abcdef", "fedcba9876543210"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0123456789abcdef", "fedcba9876543210"}, shingles)
+
+	invalid := []RegisterPromptRequest{
+		{},
+		{Prompt: prompt, Shingles: []string{"0123456789abcdef"}},
+		{Prompt: "Too short"},
+		{Shingles: []string{"not-hex"}},
+		{Shingles: []string{"0123456789ABCDEF"}},
+	}
+	for _, req := range invalid {
+		_, err := promptShingles(&req)
+		assert.Error(t, err, "%+v", req)
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"stronghold/internal/db"
+	"stronghold/internal/rulepack"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// rulePackContentType is the media type of exported rule packs
+const rulePackContentType = "application/yaml"
+
+// RulePacksResponse lists rule packs
+type RulePacksResponse struct {
+	RulePacks []*db.RulePack `json:"rule_packs"`
+}
+
+// UpdateRulePackRequest changes a stored rule pack. Omitted fields keep
+// their current value.
+type UpdateRulePackRequest struct {
+	Enabled *bool `json:"enabled"`
+	Public  *bool `json:"public"`
+}
+
+// ListRulePacks returns the account's rule packs
+// @Summary List rule packs
+// @Description Returns every rule pack version the account has imported, and which are enabled for its scans and listed in the public catalogue.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} RulePacksResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/rule-packs [get]
+func (h *PolicyHandler) ListRulePacks(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	packs, err := h.db.ListRulePacks(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rule packs",
+		})
+	}
+	return c.JSON(RulePacksResponse{RulePacks: packs})
+}
+
+// ImportRulePack validates and stores a rule pack
+// @Summary Import a rule pack
+// @Description Imports a YAML rule pack, as exported from any account or the public catalogue. The pack is validated and its tests are run; every problem found is returned. Each name and version can be imported once. Pass enable=true to enable it at once, replacing any enabled version of the same pack.
+// @Tags policy
+// @Accept application/yaml
+// @Produce json
+// @Param enable query bool false "Enable the pack for scans"
+// @Param request body string true "Rule pack YAML"
+// @Success 201 {object} db.RulePack
+// @Failure 400 {object} map[string]interface{} "Invalid rule pack, with problems"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 409 {object} map[string]string "Version already imported or too many rule packs"
+// @Security CookieAuth
+// @Router /v1/account/policy/rule-packs [post]
+func (h *PolicyHandler) ImportRulePack(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	source := c.Body()
+	enable, _ := strconv.ParseBool(c.Query("enable"))
+	compiled, err := rulepack.Parse(source)
+	if err != nil {
+		var invalid *rulepack.ValidationError
+		if errors.As(err, &invalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "Invalid rule pack",
+				"problems": invalid.Problems,
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rule pack",
+		})
+	}
+
+	pack := &db.RulePack{
+		AccountID:   accountID,
+		Name:        compiled.Pack.Name,
+		Version:     compiled.Pack.Version,
+		Description: compiled.Pack.Description,
+		Rules:       len(compiled.Pack.Rules),
+		Source:      source,
+		Enabled:     enable,
+	}
+	if err := h.db.CreateRulePack(c.Context(), pack); err != nil {
+		switch {
+		case errors.Is(err, db.ErrRulePackExists):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": fmt.Sprintf("Version %s of %s is already imported", pack.Version, pack.Name),
+			})
+		case errors.Is(err, db.ErrTooManyRulePacks):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": fmt.Sprintf("At most %d rule packs can be imported", db.MaxRulePacks),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to import rule pack",
+		})
+	}
+
+	slog.Info("rule pack imported", "account_id", accountID.String(), "name", pack.Name, "version", pack.Version, "rules", pack.Rules, "enabled", pack.Enabled)
+	return c.Status(fiber.StatusCreated).JSON(pack)
+}
+
+// ExportRulePack returns one of the account's rule packs as YAML
+// @Summary Export a rule pack
+// @Description Returns the pack's YAML as imported, ready to import into another account.
+// @Tags policy
+// @Produce application/yaml
+// @Param id path string true "Rule pack ID"
+// @Success 200 {string} string "Rule pack YAML"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Rule pack not found"
+// @Security CookieAuth
+// @Router /v1/account/policy/rule-packs/{id} [get]
+func (h *PolicyHandler) ExportRulePack(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return rulePackNotFound(c)
+	}
+
+	pack, err := h.db.GetRulePack(c.Context(), accountID, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRulePackNotFound) {
+			return rulePackNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rule pack",
+		})
+	}
+	return sendRulePack(c, pack)
+}
+
+// UpdateRulePack enables, disables, publishes or unpublishes a rule pack
+// @Summary Update a rule pack
+// @Description Enables or disables the pack for the account's scans, and lists it in or removes it from the public catalogue. Enabling a version disables the pack's other versions.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule pack ID"
+// @Param request body UpdateRulePackRequest true "Changes"
+// @Success 200 {object} db.RulePack
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Rule pack not found"
+// @Security CookieAuth
+// @Router /v1/account/policy/rule-packs/{id} [put]
+func (h *PolicyHandler) UpdateRulePack(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return rulePackNotFound(c)
+	}
+
+	var req UpdateRulePackRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	pack, err := h.db.GetRulePack(c.Context(), accountID, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRulePackNotFound) {
+			return rulePackNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rule pack",
+		})
+	}
+	if req.Enabled != nil {
+		pack.Enabled = *req.Enabled
+	}
+	if req.Public != nil {
+		pack.Public = *req.Public
+	}
+
+	if err := h.db.UpdateRulePack(c.Context(), accountID, id, pack.Enabled, pack.Public); err != nil {
+		if errors.Is(err, db.ErrRulePackNotFound) {
+			return rulePackNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update rule pack",
+		})
+	}
+
+	slog.Info("rule pack updated", "account_id", accountID.String(), "name", pack.Name, "version", pack.Version, "enabled", pack.Enabled, "public", pack.Public)
+	return c.JSON(pack)
+}
+
+// DeleteRulePack removes a rule pack version
+// @Summary Delete a rule pack
+// @Description Removes the pack version from the account; if it was enabled, its rules stop applying to scans.
+// @Tags policy
+// @Produce json
+// @Param id path string true "Rule pack ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Rule pack not found"
+// @Security CookieAuth
+// @Router /v1/account/policy/rule-packs/{id} [delete]
+func (h *PolicyHandler) DeleteRulePack(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return rulePackNotFound(c)
+	}
+
+	if err := h.db.DeleteRulePack(c.Context(), accountID, id); err != nil {
+		if errors.Is(err, db.ErrRulePackNotFound) {
+			return rulePackNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete rule pack",
+		})
+	}
+
+	slog.Info("rule pack deleted", "account_id", accountID.String(), "id", id.String())
+	return c.JSON(fiber.Map{
+		"message": "Rule pack deleted",
+	})
+}
+
+// ListPublicRulePacks returns the public rule pack catalogue
+// @Summary List public rule packs
+// @Description Returns the rule packs accounts have shared publicly, by name with the newest version first. Export one with GET /v1/rule-packs/{id} and import it with POST /v1/account/policy/rule-packs.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} RulePacksResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/rule-packs [get]
+func (h *PolicyHandler) ListPublicRulePacks(c fiber.Ctx) error {
+	packs, err := h.db.ListPublicRulePacks(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rule packs",
+		})
+	}
+	// The catalogue doesn't say which packs their publishers have enabled
+	for _, p := range packs {
+		p.Enabled = false
+	}
+	return c.JSON(RulePacksResponse{RulePacks: packs})
+}
+
+// ExportPublicRulePack returns a public rule pack as YAML
+// @Summary Export a public rule pack
+// @Description Returns a publicly shared pack's YAML, ready to import.
+// @Tags policy
+// @Produce application/yaml
+// @Param id path string true "Rule pack ID"
+// @Success 200 {string} string "Rule pack YAML"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "Rule pack not found"
+// @Security CookieAuth
+// @Router /v1/rule-packs/{id} [get]
+func (h *PolicyHandler) ExportPublicRulePack(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return rulePackNotFound(c)
+	}
+
+	pack, err := h.db.GetPublicRulePack(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrRulePackNotFound) {
+			return rulePackNotFound(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rule pack",
+		})
+	}
+	return sendRulePack(c, pack)
+}
+
+func rulePackNotFound(c fiber.Ctx) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Rule pack not found",
+	})
+}
+
+// sendRulePack responds with a pack's YAML as a download named for its version
+func sendRulePack(c fiber.Ctx, pack *db.RulePack) error {
+	c.Set(fiber.HeaderContentType, rulePackContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-%s.yaml"`, pack.Name, pack.Version))
+	return c.Send(pack.Source)
+}
+
+// loadRulePacks returns the compiled rule packs the scanning account has
+// enabled. x402 scans and accounts without enabled packs get none.
+func (h *ScanHandler) loadRulePacks(c fiber.Ctx) []*rulepack.Compiled {
+	accountID := scanAccountID(c)
+	if accountID == nil || h.db == nil {
+		return nil
+	}
+
+	stored, err := h.db.ListEnabledRulePacks(c.Context(), *accountID)
+	if err != nil {
+		slog.Warn("failed to get rule packs, skipping them", "account_id", accountID.String(), "error", err)
+		return nil
+	}
+
+	packs := make([]*rulepack.Compiled, 0, len(stored))
+	for _, p := range stored {
+		compiled, err := h.rulePacks.Compile(p.ID, p.Source)
+		if err != nil {
+			// Packs are validated on import, so only a format change gets here
+			slog.Warn("unreadable rule pack, skipping it", "account_id", accountID.String(), "name", p.Name, "version", p.Version, "error", err)
+			continue
+		}
+		packs = append(packs, compiled)
+	}
+	return packs
+}
+
+// applyRulePacks adds the matches of packs to a scan result of text and
+// names the packs checked in its metadata. target is rulepack.TargetContent
+// or rulepack.TargetOutput.
+func applyRulePacks(result *stronghold.ScanResult, text, target string, packs []*rulepack.Compiled) {
+	if len(packs) == 0 {
+		return
+	}
+	rulepack.Apply(result, text, target, packs)
+
+	names := make([]string, len(packs))
+	for i, p := range packs {
+		names[i] = p.Pack.Name + "@" + p.Pack.Version
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata["rule_packs"] = names
+}
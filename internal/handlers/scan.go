@@ -1,13 +1,24 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"strconv"
+	"strings"
 
+	"stronghold/internal/backends"
+	"stronghold/internal/canary"
 	"stronghold/internal/config"
 	"stronghold/internal/db"
+	"stronghold/internal/formtext"
 	"stronghold/internal/middleware"
+	"stronghold/internal/rulepack"
+	"stronghold/internal/sampling"
+	"stronghold/internal/sessions"
 	"stronghold/internal/stronghold"
 	"stronghold/internal/usdc"
+	"stronghold/pkg/e2escan"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -20,15 +31,31 @@ type ScanHandler struct {
 	db            *db.DB
 	pricing       *config.PricingConfig
 	paymentRouter *middleware.PaymentRouter
+	sampler       *sampling.Sampler
+	canary        *canary.Canary
+	backends      *backends.Router
+	sessions      *sessions.Manager
+	rulePacks     *rulepack.Cache
+	e2eKeys       *e2escan.Keyring
+	ingest        IngestStore
+	limiter       fiber.Handler
+	bodyLimiter   fiber.Handler
+	installs      fiber.Handler
+	versions      fiber.Handler
+	maxTextBytes  int
 }
 
+// defaultMaxTextBytes bounds the scanned text when SetBodyLimits isn't used
+const defaultMaxTextBytes = 500 * 1024
+
 // NewScanHandlerWithDB creates a new scan handler with database support
 func NewScanHandlerWithDB(scanner *stronghold.Scanner, x402 *middleware.X402Middleware, database *db.DB, pricing *config.PricingConfig) *ScanHandler {
 	return &ScanHandler{
-		scanner: scanner,
-		x402:    x402,
-		db:      database,
-		pricing: pricing,
+		scanner:   scanner,
+		x402:      x402,
+		db:        database,
+		pricing:   pricing,
+		rulePacks: rulepack.NewCache(),
 	}
 }
 
@@ -40,7 +67,55 @@ func NewScanHandlerWithPaymentRouter(scanner *stronghold.Scanner, x402 *middlewa
 		db:            database,
 		pricing:       pricing,
 		paymentRouter: router,
+		rulePacks:     rulepack.NewCache(),
+	}
+}
+
+// SetSampler enables storing a sample of scans for replay
+func (h *ScanHandler) SetSampler(sampler *sampling.Sampler) {
+	h.sampler = sampler
+}
+
+// SetRateLimiter limits scan requests before payment is processed
+func (h *ScanHandler) SetRateLimiter(limiter fiber.Handler) {
+	h.limiter = limiter
+}
+
+// SetBodyLimits rejects oversized scan bodies before payment is processed
+// and bounds the text a single request may scan
+func (h *ScanHandler) SetBodyLimits(limiter fiber.Handler, maxTextBytes int) {
+	h.bodyLimiter = limiter
+	h.maxTextBytes = maxTextBytes
+}
+
+// SetInstallSignature attributes scans signed by a registered proxy install
+// to that install
+func (h *ScanHandler) SetInstallSignature(verifier fiber.Handler) {
+	h.installs = verifier
+}
+
+// SetProxyVersion checks the version proxies report with their scans
+// against the supported version policy
+func (h *ScanHandler) SetProxyVersion(handler fiber.Handler) {
+	h.versions = handler
+}
+
+// SetCanary enables shadow-scoring a share of scans with the canary detection configuration
+func (h *ScanHandler) SetCanary(c *canary.Canary) {
+	h.canary = c
+}
+
+// SetBackends routes each account's scans to its selected detection backend
+func (h *ScanHandler) SetBackends(r *backends.Router) {
+	h.backends = r
+}
+
+// scannerFor returns the detection backend for this request's account and its name
+func (h *ScanHandler) scannerFor(c fiber.Ctx) (backends.Backend, string) {
+	if h.backends == nil {
+		return h.scanner, config.BackendInternal
 	}
+	return h.backends.For(c.Context(), scanAccountID(c))
 }
 
 // ScanContentRequest represents a request to scan external content for prompt injection
@@ -50,6 +125,7 @@ type ScanContentRequest struct {
 	SourceType  string `json:"source_type,omitempty"`  // "web_page", "file", "api_response", "code_repo"
 	ContentType string `json:"content_type,omitempty"` // "html", "markdown", "json", "text", "code"
 	FilePath    string `json:"file_path,omitempty"`    // For file reads, e.g., "README.md"
+	Mode        string `json:"mode,omitempty"`         // "smart" (default), "strict" or "permissive"; selects the account's scoring profile
 }
 
 // ScanOutputRequest represents a request to scan LLM/agent output for credential leaks
@@ -57,6 +133,12 @@ type ScanOutputRequest struct {
 	Text string `json:"text"`
 }
 
+// ScanToolCallRequest represents a tool call to check before an agent framework executes it
+type ScanToolCallRequest struct {
+	Tool      string          `json:"tool"`                           // Tool name, e.g. "bash", "read_file", "web_fetch"
+	Arguments json.RawMessage `json:"arguments" swaggertype:"object"` // JSON arguments, or a string holding them
+}
+
 // RegisterRoutes registers all scan routes
 func (h *ScanHandler) RegisterRoutes(app *fiber.App) {
 	if h.db == nil {
@@ -73,15 +155,57 @@ func (h *ScanHandler) RegisterRoutes(app *fiber.App) {
 	}
 
 	group := app.Group("/v1/scan")
+	h.useScanMiddleware(group)
+
+	if h.e2eKeys != nil {
+		group.Post("/keys", h.IssueScanKey)
+	}
 
 	// Use PaymentRouter if available (supports both x402 and API key auth),
 	// otherwise fall back to x402-only middleware
 	if h.paymentRouter != nil {
 		group.Post("/content", h.paymentRouter.Route(h.pricing.ScanContent), h.ScanContent)
 		group.Post("/output", h.paymentRouter.Route(h.pricing.ScanOutput), h.ScanOutput)
+		group.Post("/tool-call", h.paymentRouter.Route(h.pricing.ScanToolCall), h.ScanToolCall)
+		group.Post("/documents", h.paymentRouter.Route(h.pricing.ScanDocuments), h.ScanDocuments)
+		if h.sessions != nil {
+			group.Post("/session", h.paymentRouter.Identify(), h.CreateScanSession)
+			group.Post("/session/message", h.paymentRouter.Route(h.pricing.ScanSession), h.ScanSessionMessage)
+		}
 	} else {
 		group.Post("/content", h.x402.AtomicPayment(h.pricing.ScanContent), h.ScanContent)
 		group.Post("/output", h.x402.AtomicPayment(h.pricing.ScanOutput), h.ScanOutput)
+		group.Post("/tool-call", h.x402.AtomicPayment(h.pricing.ScanToolCall), h.ScanToolCall)
+		group.Post("/documents", h.x402.AtomicPayment(h.pricing.ScanDocuments), h.ScanDocuments)
+		if h.sessions != nil {
+			group.Post("/session", h.CreateScanSession)
+			group.Post("/session/message", h.x402.AtomicPayment(h.pricing.ScanSession), h.ScanSessionMessage)
+		}
+	}
+
+	if h.ingest != nil {
+		h.registerIngestRoutes(app)
+	}
+}
+
+// useScanMiddleware applies the rate, body size, end-to-end encryption,
+// proxy version and install checks shared by the scan routes. Encrypted
+// bodies are opened after the size checks and before anything reads them.
+func (h *ScanHandler) useScanMiddleware(group fiber.Router) {
+	if h.limiter != nil {
+		group.Use(h.limiter)
+	}
+	if h.bodyLimiter != nil {
+		group.Use(h.bodyLimiter)
+	}
+	if h.e2eKeys != nil {
+		group.Use(middleware.E2EScan(h.e2eKeys))
+	}
+	if h.versions != nil {
+		group.Use(h.versions)
+	}
+	if h.installs != nil {
+		group.Use(h.installs)
 	}
 }
 
@@ -89,18 +213,27 @@ func (h *ScanHandler) RegisterRoutes(app *fiber.App) {
 // @Summary Scan external content for prompt injection
 // @Description Scans content from external sources (websites, files, APIs) for prompt injection attacks before passing to LLM
 // @Tags scan
-// @Accept json
+// @Accept json,mpfd,x-www-form-urlencoded,plain,octet-stream
 // @Produce json
 // @Param request body ScanContentRequest true "Content scan request"
 // @Success 200 {object} stronghold.ScanResult
 // @Failure 400 {object} map[string]string
 // @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string "Raw body without a supported charset"
 // @Router /v1/scan/content [post]
 func (h *ScanHandler) ScanContent(c fiber.Ctx) error {
 	requestID := middleware.GetRequestID(c)
 
 	var req ScanContentRequest
-	if err := c.Bind().Body(&req); err != nil {
+	err := bindScanContentRequest(c, &req)
+	if errors.Is(err, formtext.ErrCharset) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error":      "Raw bodies must be UTF-8, US-ASCII or ISO-8859-1 text; application/octet-stream needs an explicit charset",
+			"request_id": requestID,
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":      "Invalid request body",
 			"request_id": requestID,
@@ -114,17 +247,24 @@ func (h *ScanHandler) ScanContent(c fiber.Ctx) error {
 		})
 	}
 
-	// Reject oversized payloads (500KB limit)
-	if len(req.Text) > 500*1024 {
+	if len(req.Text) > h.textLimit() {
+		return h.textTooLarge(c, requestID)
+	}
+
+	if req.Mode == "" {
+		req.Mode = stronghold.ScanModeSmart
+	}
+	if !stronghold.IsValidScanMode(req.Mode) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":      "Text too large, maximum size is 500KB",
+			"error":      "mode must be smart, strict or permissive",
 			"request_id": requestID,
 		})
 	}
 
-	result, err := h.scanner.ScanContent(c.Context(), req.Text, req.SourceURL, req.SourceType, req.ContentType)
+	scanner, backend := h.scannerFor(c)
+	result, err := scanner.ScanContent(c.Context(), req.Text, req.SourceURL, req.SourceType, req.ContentType)
 	if err != nil {
-		slog.Error("scan content failed", "request_id", requestID, "error", err)
+		slog.Error("scan content failed", "request_id", requestID, "backend", backend, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":      "Scan failed",
 			"request_id": requestID,
@@ -139,21 +279,96 @@ func (h *ScanHandler) ScanContent(c fiber.Ctx) error {
 	result.Metadata["source_type"] = req.SourceType
 	result.Metadata["content_type"] = req.ContentType
 	result.Metadata["file_path"] = req.FilePath
+	result.Metadata["backend"] = backend
 
 	result.RequestID = requestID
+	c.Locals(middleware.DetectionVersionKey, result.DetectionVersion)
+
+	// Sample the scanner's own verdict, before account policies change it.
+	// Replay and the canary compare against the built-in engine only.
+	// Scans sent end-to-end encrypted are never sampled.
+	if backend == config.BackendInternal {
+		if !middleware.IsE2EScan(c) {
+			h.sampler.Record(scanAccountID(c), requestID, "/v1/scan/content", req.Text, req.SourceType, req.ContentType, result)
+		}
+		h.canary.Compare(scanAccountID(c), requestID, "/v1/scan/content", req.Text, req.SourceType, req.ContentType, result)
+
+		// Apply the account's scoring profile for the requested mode
+		h.applyScoringProfile(c, result, req.Mode)
+	}
 
 	// Filter jailbreak threats based on auth method and settings
 	h.filterJailbreakThreats(c, result)
 
+	// Add matches of the account's rule packs
+	applyRulePacks(result, req.Text, rulepack.TargetContent, h.loadRulePacks(c))
+
+	// Strip scoring detail the account or API key doesn't want returned
+	h.applyScoreExposure(c, result)
+
 	// Record execution result in payment transaction for idempotent replay
 	h.recordExecutionResult(c, result)
 
 	// Log usage for B2B requests (x402 handles its own logging)
-	h.logB2BUsage(c, result, "/v1/scan/content", h.pricing.ScanContent)
+	h.logUsage(c, result, "/v1/scan/content", h.pricing.ScanContent)
 
 	return c.JSON(result)
 }
 
+// scanContentFormMetadata are form fields that describe the content rather than
+// being part of it
+var scanContentFormMetadata = []string{"text", "source_url", "source_type", "content_type", "file_path", "mode"}
+
+// bindScanContentRequest reads a scan request from a JSON body, from a
+// multipart/form-data or x-www-form-urlencoded upload, or from a raw
+// text/plain or application/octet-stream body. For forms, the "text" field
+// plus any other text fields, text files, and file names are scanned; binary
+// files are skipped. A raw body is the text, with the other fields taken from
+// the query string.
+func bindScanContentRequest(c fiber.Ctx, req *ScanContentRequest) error {
+	contentType := c.Get(fiber.HeaderContentType)
+	if formtext.IsRaw(contentType) {
+		text, err := formtext.Raw(c.Body(), contentType)
+		if err != nil {
+			return err
+		}
+		req.Text = text
+		req.SourceURL = c.Query("source_url")
+		req.SourceType = c.Query("source_type")
+		req.ContentType = c.Query("content_type")
+		req.FilePath = c.Query("file_path")
+		req.Mode = c.Query("mode")
+		return nil
+	}
+	if !formtext.IsForm(contentType) {
+		return c.Bind().Body(req)
+	}
+
+	form, err := formtext.Extract(c.Body(), contentType)
+	if err != nil {
+		return err
+	}
+	if form.Truncated {
+		return errors.New("incomplete form body")
+	}
+
+	req.SourceURL = form.Value("source_url")
+	req.SourceType = form.Value("source_type")
+	req.ContentType = form.Value("content_type")
+	req.FilePath = form.Value("file_path")
+	req.Mode = form.Value("mode")
+
+	parts := []string{}
+	if text := form.Value("text"); text != "" {
+		parts = append(parts, text)
+	}
+	if rest := form.Text(scanContentFormMetadata...); rest != "" {
+		parts = append(parts, rest)
+	}
+	req.Text = strings.Join(parts, "\n")
+	return nil
+}
+
 // ScanOutput handles output scanning
 // @Summary Scan LLM output for credential leaks
 // @Description Scans LLM output text for credential leaks and sensitive data exposure
@@ -164,6 +379,7 @@ func (h *ScanHandler) ScanContent(c fiber.Ctx) error {
 // @Success 200 {object} stronghold.ScanResult
 // @Failure 400 {object} map[string]string
 // @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
 // @Router /v1/scan/output [post]
 func (h *ScanHandler) ScanOutput(c fiber.Ctx) error {
 	requestID := middleware.GetRequestID(c)
@@ -183,17 +399,98 @@ func (h *ScanHandler) ScanOutput(c fiber.Ctx) error {
 		})
 	}
 
-	// Reject oversized payloads (500KB limit)
-	if len(req.Text) > 500*1024 {
+	if len(req.Text) > h.textLimit() {
+		return h.textTooLarge(c, requestID)
+	}
+
+	scanner, backend := h.scannerFor(c)
+	result, err := scanner.ScanOutput(c.Context(), req.Text)
+	if err != nil {
+		slog.Error("scan output failed", "request_id", requestID, "backend", backend, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Scan failed",
+			"request_id": requestID,
+		})
+	}
+
+	result.RequestID = requestID
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata["backend"] = backend
+	c.Locals(middleware.DetectionVersionKey, result.DetectionVersion)
+
+	if backend == config.BackendInternal {
+		if !middleware.IsE2EScan(c) {
+			h.sampler.Record(scanAccountID(c), requestID, "/v1/scan/output", req.Text, "", "", result)
+		}
+		h.canary.Compare(scanAccountID(c), requestID, "/v1/scan/output", req.Text, "", "", result)
+	}
+
+	// Flag outputs that repeat the account's registered system prompts
+	h.applyPromptLeakage(c, result, req.Text)
+
+	// Add matches of the account's rule packs
+	applyRulePacks(result, req.Text, rulepack.TargetOutput, h.loadRulePacks(c))
+
+	// Strip scoring detail the account or API key doesn't want returned
+	h.applyScoreExposure(c, result)
+
+	// Record execution result in payment transaction for idempotent replay
+	h.recordExecutionResult(c, result)
+
+	// Log usage for B2B requests
+	h.logUsage(c, result, "/v1/scan/output", h.pricing.ScanOutput)
+
+	return c.JSON(result)
+}
+
+// ScanToolCall handles tool-call argument scanning
+// @Summary Scan a tool call before execution
+// @Description Checks a tool call's arguments for dangerous shell commands, paths to credentials and startup files, and URLs pointing at internal networks, cloud metadata or exfiltration services. Rules follow the tool's kind (shell, file, http) and argument names. Each finding is located at its argument's JSON path. Critical and high findings block; medium findings warn. Arguments may be a JSON value or a string holding one, as LLM APIs return them.
+// @Tags scan
+// @Accept json
+// @Produce json
+// @Param request body ScanToolCallRequest true "Tool call scan request"
+// @Success 200 {object} stronghold.ScanResult
+// @Failure 400 {object} map[string]string
+// @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
+// @Router /v1/scan/tool-call [post]
+func (h *ScanHandler) ScanToolCall(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	var req ScanToolCallRequest
+	if err := c.Bind().Body(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":      "Text too large, maximum size is 500KB",
+			"error":      "Invalid request body",
 			"request_id": requestID,
 		})
 	}
 
-	result, err := h.scanner.ScanOutput(c.Context(), req.Text)
+	if req.Tool == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Tool is required",
+			"request_id": requestID,
+		})
+	}
+
+	if len(req.Arguments) > h.textLimit() {
+		return h.textTooLarge(c, requestID)
+	}
+
+	args, err := decodeToolArguments(req.Arguments)
 	if err != nil {
-		slog.Error("scan output failed", "request_id", requestID, "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Arguments must be JSON",
+			"request_id": requestID,
+		})
+	}
+
+	// Tool-call rules are Stronghold's own; they always run on the built-in engine
+	result, err := h.scanner.ScanToolCall(c.Context(), req.Tool, args)
+	if err != nil {
+		slog.Error("scan tool call failed", "request_id", requestID, "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":      "Scan failed",
 			"request_id": requestID,
@@ -201,85 +498,233 @@ func (h *ScanHandler) ScanOutput(c fiber.Ctx) error {
 	}
 
 	result.RequestID = requestID
+	c.Locals(middleware.DetectionVersionKey, result.DetectionVersion)
+
+	// Strip scoring detail the account or API key doesn't want returned
+	h.applyScoreExposure(c, result)
 
 	// Record execution result in payment transaction for idempotent replay
 	h.recordExecutionResult(c, result)
 
 	// Log usage for B2B requests
-	h.logB2BUsage(c, result, "/v1/scan/output", h.pricing.ScanOutput)
+	h.logUsage(c, result, "/v1/scan/tool-call", h.pricing.ScanToolCall)
 
 	return c.JSON(result)
 }
 
-// filterJailbreakThreats removes jailbreak threats from results based on auth method and settings.
+// decodeToolArguments decodes tool-call arguments. LLM APIs return them as a
+// string holding JSON, so a string that parses as JSON is decoded again;
+// other strings are scanned as a single argument.
+func decodeToolArguments(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var args any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if s, ok := args.(string); ok {
+		var inner any
+		if json.Unmarshal([]byte(s), &inner) == nil {
+			return inner, nil
+		}
+	}
+	return args, nil
+}
+
+// textLimit returns the largest text a scan request may contain
+func (h *ScanHandler) textLimit() int {
+	if h.maxTextBytes > 0 {
+		return h.maxTextBytes
+	}
+	return defaultMaxTextBytes
+}
+
+// textTooLarge returns a 413 response for text over the limit
+func (h *ScanHandler) textTooLarge(c fiber.Ctx, requestID string) error {
+	limit := h.textLimit()
+	size := strconv.Itoa(limit) + " bytes"
+	if limit%1024 == 0 {
+		size = strconv.Itoa(limit/1024) + "KB"
+	}
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error":      "Text too large, maximum size is " + size,
+		"request_id": requestID,
+	})
+}
+
+// scanAccountID returns the account behind an API key scan, or nil
+func scanAccountID(c fiber.Ctx) *uuid.UUID {
+	accountIDStr, _ := c.Locals("account_id").(string)
+	if accountIDStr == "" {
+		return nil
+	}
+	accountID, err := uuid.Parse(accountIDStr)
+	if err != nil {
+		return nil
+	}
+	return &accountID
+}
+
+// applyScoringProfile rescores a content scan with the account's scoring
+// profile for mode. x402 scans and accounts without a profile keep the
+// engine's scoring.
+func (h *ScanHandler) applyScoringProfile(c fiber.Ctx, result *stronghold.ScanResult, mode string) {
+	accountID := scanAccountID(c)
+	if accountID == nil || h.db == nil || h.scanner == nil {
+		return
+	}
+
+	stored, err := h.db.GetScoringProfile(c.Context(), *accountID, mode)
+	if errors.Is(err, db.ErrScoringProfileNotFound) {
+		return
+	}
+	if err != nil {
+		slog.Warn("failed to get scoring profile, using engine scoring", "account_id", accountID.String(), "mode", mode, "error", err)
+		return
+	}
+	profile, err := decodeScoringProfile(stored)
+	if err != nil {
+		slog.Warn("unreadable scoring profile, using engine scoring", "account_id", accountID.String(), "mode", stored.Mode, "error", err)
+		return
+	}
+
+	h.scanner.Rescore(result, profile)
+	result.Metadata["scoring_profile"] = stored.Mode
+}
+
+// applyPromptLeakage checks an output scan against the account's registered
+// system prompts, whichever backend produced the result. x402 scans and
+// accounts without registered prompts are left unchanged.
+func (h *ScanHandler) applyPromptLeakage(c fiber.Ctx, result *stronghold.ScanResult, output string) {
+	accountID := scanAccountID(c)
+	if accountID == nil || h.db == nil {
+		return
+	}
+
+	stored, err := h.db.ListPromptFingerprints(c.Context(), *accountID)
+	if err != nil {
+		slog.Warn("failed to get registered prompts, skipping prompt leak check", "account_id", accountID.String(), "error", err)
+		return
+	}
+	if len(stored) == 0 {
+		return
+	}
+
+	prints := make([]stronghold.PromptFingerprint, 0, len(stored))
+	for _, fp := range stored {
+		prints = append(prints, stronghold.PromptFingerprint{Name: fp.Name, Shingles: fp.Shingles})
+	}
+	stronghold.ApplyPromptLeakage(result, output, prints)
+}
+
+// filterJailbreakThreats applies the jailbreak policy to results based on auth method and settings.
 // B2C (x402): always filters out jailbreak threats.
-// B2B (API key): filters only if jailbreak detection is explicitly disabled in settings.
+// B2B (API key): uses the effective account/organization policy (default: enabled, block).
 func (h *ScanHandler) filterJailbreakThreats(c fiber.Ctx, result *stronghold.ScanResult) {
 	authMethod, _ := c.Locals("auth_method").(string)
 
-	shouldFilter := false
+	policy := &db.JailbreakPolicy{Enabled: false, Action: db.JailbreakActionBlock, Source: db.PolicySourceDefault}
 
 	if authMethod == "api_key" {
-		// B2B: check account settings (default: enabled)
+		// B2B: resolve account settings and organization overrides (default: enabled)
+		policy.Enabled = true
 		accountIDStr, _ := c.Locals("account_id").(string)
 		if accountIDStr != "" {
 			accountID, err := uuid.Parse(accountIDStr)
 			if err == nil {
-				enabled, err := h.db.GetJailbreakDetectionEnabled(c.Context(), accountID, true)
+				effective, err := h.db.GetEffectiveJailbreakPolicy(c.Context(), accountID, true)
 				if err != nil {
-					slog.Warn("failed to get jailbreak detection setting, defaulting to enabled",
+					slog.Warn("failed to get jailbreak policy, defaulting to enabled",
 						"account_id", accountIDStr, "error", err)
-					enabled = true
+				} else {
+					policy = effective
 				}
-				shouldFilter = !enabled
 			}
 		}
-	} else {
-		// B2C (x402 path): always filter jailbreak threats
-		shouldFilter = true
 	}
 
-	if !shouldFilter {
+	applyJailbreakPolicy(result, policy)
+}
+
+// applyJailbreakPolicy removes jailbreak threats when detection is disabled, or
+// downgrades a jailbreak-only BLOCK to WARN when the policy action is "warn".
+func applyJailbreakPolicy(result *stronghold.ScanResult, policy *db.JailbreakPolicy) {
+	jailbreakCount := 0
+	for _, t := range result.ThreatsFound {
+		if t.Category == "jailbreak" {
+			jailbreakCount++
+		}
+	}
+	if jailbreakCount == 0 {
+		return
+	}
+	onlyJailbreak := jailbreakCount == len(result.ThreatsFound)
+
+	if policy.Enabled {
+		if policy.Action == db.JailbreakActionWarn && onlyJailbreak && result.Decision == stronghold.DecisionBlock {
+			result.Decision = stronghold.DecisionWarn
+			result.RecommendedAction = "warn"
+			result.Reason = "Jailbreak attempt detected (policy: warn)"
+		}
 		return
 	}
 
 	// Remove jailbreak-category threats
-	filtered := make([]stronghold.Threat, 0, len(result.ThreatsFound))
-	removedCount := 0
+	filtered := make([]stronghold.Threat, 0, len(result.ThreatsFound)-jailbreakCount)
 	for _, t := range result.ThreatsFound {
-		if t.Category == "jailbreak" {
-			removedCount++
-		} else {
+		if t.Category != "jailbreak" {
 			filtered = append(filtered, t)
 		}
 	}
 	result.ThreatsFound = filtered
 
-	// Only reset decision if jailbreak threats were actually removed and no other threats remain
-	if removedCount > 0 && len(result.ThreatsFound) == 0 && result.Decision != stronghold.DecisionAllow {
+	// Only reset decision if no other threats remain
+	if len(result.ThreatsFound) == 0 && result.Decision != stronghold.DecisionAllow {
 		result.Decision = stronghold.DecisionAllow
 		result.RecommendedAction = "allow"
 		result.Reason = "No actionable threats detected"
 	}
 }
 
-// logB2BUsage creates a usage log entry for B2B (API key) requests.
-// x402 payment requests already have their own logging via the payment transaction.
-func (h *ScanHandler) logB2BUsage(c fiber.Ctx, result *stronghold.ScanResult, endpoint string, cost usdc.MicroUSDC) {
-	authMethod, _ := c.Locals("auth_method").(string)
-	if authMethod != "api_key" {
-		return
-	}
+// logUsage creates a usage log entry for B2B (API key) requests and for
+// x402 requests signed by a registered proxy install. Other x402 requests
+// already have their own logging via the payment transaction. Install-signed
+// x402 scans are paid on-chain, so their row has CostUSDC 0 to keep the
+// deduct_account_balance_on_usage trigger from charging the install's
+// account again; the price is recorded in metadata.
+func (h *ScanHandler) logUsage(c fiber.Ctx, result *stronghold.ScanResult, endpoint string, cost usdc.MicroUSDC) {
+	installID, installAccountID := middleware.GetInstall(c)
 
-	accountIDStr, _ := c.Locals("account_id").(string)
-	if accountIDStr == "" {
-		return
+	authMethod, _ := c.Locals("auth_method").(string)
+	metadata := map[string]any{
+		"auth_method": authMethod,
+		"decision":    result.Decision,
 	}
 
-	accountID, err := uuid.Parse(accountIDStr)
-	if err != nil {
+	var accountID uuid.UUID
+	switch {
+	case authMethod == "api_key":
+		accountIDStr, _ := c.Locals("account_id").(string)
+		parsed, err := uuid.Parse(accountIDStr)
+		if err != nil {
+			return
+		}
+		accountID = parsed
+		metadata["api_key_id"] = c.Locals("api_key_id")
+		if subAccountID := c.Locals("sub_account_id"); subAccountID != nil {
+			metadata["sub_account_id"] = subAccountID
+		}
+	case installID != nil:
+		// x402 requests have no account of their own; the install's
+		// signature attributes them to the account that registered it
+		accountID = *installAccountID
+		metadata["auth_method"] = "x402"
+		metadata["actual_cost"] = cost
+	default:
 		return
 	}
+	accountIDStr := accountID.String()
 
 	threatDetected := len(result.ThreatsFound) > 0
 	var threatType *string
@@ -290,22 +735,22 @@ func (h *ScanHandler) logB2BUsage(c fiber.Ctx, result *stronghold.ScanResult, en
 
 	latency := int(result.LatencyMs)
 	usageLog := &db.UsageLog{
-		AccountID:      accountID,
-		RequestID:      result.RequestID,
-		Endpoint:       endpoint,
-		Method:         "POST",
-		CostUSDC:       0,
-		Status:         "success",
-		ThreatDetected: threatDetected,
-		ThreatType:     threatType,
-		LatencyMs:      &latency,
-		Metadata: map[string]any{
-			"auth_method": "api_key",
-		},
+		AccountID:        accountID,
+		RequestID:        result.RequestID,
+		Endpoint:         endpoint,
+		Method:           "POST",
+		CostUSDC:         0, // trigger-safe: x402 scans are paid on-chain
+		Status:           "success",
+		ThreatDetected:   threatDetected,
+		ThreatType:       threatType,
+		LatencyMs:        &latency,
+		DetectionVersion: result.DetectionVersion,
+		InstallID:        installID,
+		Metadata:         metadata,
 	}
 
 	if err := h.db.CreateUsageLog(c.Context(), usageLog); err != nil {
-		slog.Error("failed to log B2B usage",
+		slog.Error("failed to log scan usage",
 			"account_id", accountIDStr,
 			"request_id", result.RequestID,
 			"error", err,
@@ -315,17 +760,8 @@ func (h *ScanHandler) logB2BUsage(c fiber.Ctx, result *stronghold.ScanResult, en
 
 // recordExecutionResult stores the scan result in the payment transaction for idempotent replay
 func (h *ScanHandler) recordExecutionResult(c fiber.Ctx, result *stronghold.ScanResult) {
-	if h.db == nil {
-		return
-	}
-
-	tx := middleware.GetPaymentTransaction(c)
-	if tx == nil {
-		return
-	}
-
 	// Convert result to map for storage
-	resultMap := map[string]interface{}{
+	h.recordExecution(c, result.RequestID, map[string]interface{}{
 		"request_id":         result.RequestID,
 		"decision":           result.Decision,
 		"scores":             result.Scores,
@@ -335,6 +771,19 @@ func (h *ScanHandler) recordExecutionResult(c fiber.Ctx, result *stronghold.Scan
 		"sanitized_text":     result.SanitizedText,
 		"threats_found":      result.ThreatsFound,
 		"recommended_action": result.RecommendedAction,
+		"detection_version":  result.DetectionVersion,
+	})
+}
+
+// recordExecution stores a response in the payment transaction for idempotent replay
+func (h *ScanHandler) recordExecution(c fiber.Ctx, requestID string, resultMap map[string]interface{}) {
+	if h.db == nil {
+		return
+	}
+
+	tx := middleware.GetPaymentTransaction(c)
+	if tx == nil {
+		return
 	}
 
 	if err := h.db.RecordExecution(c.Context(), tx.ID, resultMap); err != nil {
@@ -342,7 +791,7 @@ func (h *ScanHandler) recordExecutionResult(c fiber.Ctx, result *stronghold.Scan
 		// The middleware will still attempt settlement
 		slog.Error("failed to record execution result",
 			"payment_id", tx.ID,
-			"request_id", result.RequestID,
+			"request_id", requestID,
 			"error", err,
 		)
 	}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/middleware"
+	"stronghold/internal/rulepack"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxScanDocuments bounds the documents in one multi-document scan
+const maxScanDocuments = 64
+
+// ScanDocument is one named document in a multi-document scan, such as a
+// retrieved RAG chunk
+type ScanDocument struct {
+	ID          string `json:"id,omitempty"` // Caller's name for the document; defaults to its index
+	Text        string `json:"text"`
+	SourceURL   string `json:"source_url,omitempty"`
+	SourceType  string `json:"source_type,omitempty"`  // "web_page", "file", "api_response", "code_repo"
+	ContentType string `json:"content_type,omitempty"` // "html", "markdown", "json", "text", "code"
+}
+
+// ScanDocumentsRequest represents a request to scan several documents at once
+type ScanDocumentsRequest struct {
+	Documents []ScanDocument `json:"documents"`
+	Mode      string         `json:"mode,omitempty"` // "smart" (default), "strict" or "permissive"; selects the account's scoring profile
+}
+
+// DocumentVerdict is the scan result for one document
+type DocumentVerdict struct {
+	ID     string                 `json:"id"`
+	Result *stronghold.ScanResult `json:"result"`
+}
+
+// ScanDocumentsResponse holds a verdict per document and the roll-up decision
+type ScanDocumentsResponse struct {
+	Decision         stronghold.Decision `json:"decision"`                 // Most severe decision across the documents
+	WorstDocument    string              `json:"worst_document,omitempty"` // ID of the highest-scoring document, unless all are allowed
+	Documents        []DocumentVerdict   `json:"documents"`                // In request order
+	RequestID        string              `json:"request_id"`
+	LatencyMs        int64               `json:"latency_ms"`
+	DetectionVersion string              `json:"detection_version,omitempty"`
+}
+
+// ScanDocuments handles scanning several documents in one request
+// @Summary Scan multiple documents for prompt injection
+// @Description Scans up to 64 named documents, such as retrieved RAG chunks, returning a verdict for each plus an overall decision and the worst offender, so pipelines can drop only the poisoned documents. The combined text is subject to the same size limit as /v1/scan/content.
+// @Tags scan
+// @Accept json
+// @Produce json
+// @Param request body ScanDocumentsRequest true "Documents to scan"
+// @Success 200 {object} ScanDocumentsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 402 {object} map[string]interface{}
+// @Failure 413 {object} map[string]string
+// @Router /v1/scan/documents [post]
+func (h *ScanHandler) ScanDocuments(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	var req ScanDocumentsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Invalid request body",
+			"request_id": requestID,
+		})
+	}
+	if err := h.checkScanDocuments(&req); err != nil {
+		return h.scanDocumentsError(c, requestID, err)
+	}
+
+	start := time.Now()
+	verdicts, worst, err := h.scanDocuments(c, requestID, "/v1/scan/documents", req.Mode, req.Documents)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Scan failed",
+			"request_id": requestID,
+		})
+	}
+
+	resp := &ScanDocumentsResponse{
+		Decision:         verdicts[worst].Result.Decision,
+		WorstDocument:    verdicts[worst].ID,
+		Documents:        verdicts,
+		RequestID:        requestID,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		DetectionVersion: verdicts[worst].Result.DetectionVersion,
+	}
+	if resp.Decision == stronghold.DecisionAllow {
+		resp.WorstDocument = ""
+	}
+	c.Locals(middleware.DetectionVersionKey, resp.DetectionVersion)
+
+	// Record execution result in payment transaction for idempotent replay
+	h.recordExecution(c, requestID, map[string]interface{}{
+		"request_id":        resp.RequestID,
+		"decision":          resp.Decision,
+		"worst_document":    resp.WorstDocument,
+		"documents":         resp.Documents,
+		"latency_ms":        resp.LatencyMs,
+		"detection_version": resp.DetectionVersion,
+	})
+
+	// Log usage for B2B requests, attributed to the worst document's threats
+	h.logUsage(c, documentsUsage(requestID, resp.LatencyMs, verdicts[worst].Result), "/v1/scan/documents", h.pricing.ScanDocuments)
+
+	return c.JSON(resp)
+}
+
+// errDocumentsTooLarge is returned by checkScanDocuments when the combined
+// text is over the scan size limit
+var errDocumentsTooLarge = errors.New("documents too large")
+
+// checkScanDocuments validates a multi-document request, naming unnamed
+// documents by their index and defaulting the mode
+func (h *ScanHandler) checkScanDocuments(req *ScanDocumentsRequest) error {
+	if len(req.Documents) == 0 || len(req.Documents) > maxScanDocuments {
+		return fmt.Errorf("documents must hold between 1 and %d documents", maxScanDocuments)
+	}
+
+	seen := make(map[string]bool, len(req.Documents))
+	total := 0
+	for i := range req.Documents {
+		doc := &req.Documents[i]
+		if doc.ID == "" {
+			doc.ID = strconv.Itoa(i)
+		}
+		if seen[doc.ID] {
+			return fmt.Errorf("duplicate document id: %s", doc.ID)
+		}
+		seen[doc.ID] = true
+		if doc.Text == "" {
+			return fmt.Errorf("text is required for document %s", doc.ID)
+		}
+		total += len(doc.Text)
+	}
+	if total > h.textLimit() {
+		return errDocumentsTooLarge
+	}
+
+	if req.Mode == "" {
+		req.Mode = stronghold.ScanModeSmart
+	}
+	if !stronghold.IsValidScanMode(req.Mode) {
+		return errors.New("mode must be smart, strict or permissive")
+	}
+	return nil
+}
+
+// scanDocumentsError responds to a request checkScanDocuments rejected
+func (h *ScanHandler) scanDocumentsError(c fiber.Ctx, requestID string, err error) error {
+	if errors.Is(err, errDocumentsTooLarge) {
+		return h.textTooLarge(c, requestID)
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":      err.Error(),
+		"request_id": requestID,
+	})
+}
+
+// scanDocuments scans each document as a content scan, returning the
+// verdicts in order and the index of the worst
+func (h *ScanHandler) scanDocuments(c fiber.Ctx, requestID, endpoint, mode string, docs []ScanDocument) ([]DocumentVerdict, int, error) {
+	scanner, backend := h.scannerFor(c)
+	verdicts := make([]DocumentVerdict, len(docs))
+	packs := h.loadRulePacks(c)
+	worst := 0
+	for i, doc := range docs {
+		result, err := scanner.ScanContent(c.Context(), doc.Text, doc.SourceURL, doc.SourceType, doc.ContentType)
+		if err != nil {
+			slog.Error("scan documents failed", "request_id", requestID, "backend", backend, "document", doc.ID, "error", err)
+			return nil, 0, err
+		}
+
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]interface{})
+		}
+		result.Metadata["source_url"] = doc.SourceURL
+		result.Metadata["source_type"] = doc.SourceType
+		result.Metadata["content_type"] = doc.ContentType
+		result.Metadata["backend"] = backend
+		result.RequestID = requestID
+
+		if backend == config.BackendInternal {
+			if !middleware.IsE2EScan(c) {
+				h.sampler.Record(scanAccountID(c), requestID, endpoint, doc.Text, doc.SourceType, doc.ContentType, result)
+			}
+			h.canary.Compare(scanAccountID(c), requestID, endpoint, doc.Text, doc.SourceType, doc.ContentType, result)
+			h.applyScoringProfile(c, result, mode)
+		}
+		h.filterJailbreakThreats(c, result)
+		applyRulePacks(result, doc.Text, rulepack.TargetContent, packs)
+
+		verdicts[i] = DocumentVerdict{ID: doc.ID, Result: result}
+		if worseResult(result, verdicts[worst].Result) {
+			worst = i
+		}
+	}
+
+	// Strip scoring detail once the worst document is picked by score
+	results := make([]*stronghold.ScanResult, len(verdicts))
+	for i, v := range verdicts {
+		results[i] = v.Result
+	}
+	h.applyScoreExposure(c, results...)
+	return verdicts, worst, nil
+}
+
+// documentsUsage summarizes a multi-document scan for usage logging by its
+// worst document's verdict
+func documentsUsage(requestID string, latencyMs int64, worst *stronghold.ScanResult) *stronghold.ScanResult {
+	return &stronghold.ScanResult{
+		Decision:         worst.Decision,
+		ThreatsFound:     worst.ThreatsFound,
+		RequestID:        requestID,
+		LatencyMs:        latencyMs,
+		DetectionVersion: worst.DetectionVersion,
+	}
+}
+
+// decisionRank orders decisions by severity
+func decisionRank(d stronghold.Decision) int {
+	switch d {
+	case stronghold.DecisionBlock:
+		return 2
+	case stronghold.DecisionWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// worseResult reports whether a is a worse verdict than b: a more severe
+// decision, or the same decision with a higher score
+func worseResult(a, b *stronghold.ScanResult) bool {
+	if ra, rb := decisionRank(a.Decision), decisionRank(b.Decision); ra != rb {
+		return ra > rb
+	}
+	return resultScore(a) > resultScore(b)
+}
+
+// resultScore is the combined score, or the heuristic score when only the
+// heuristic layer ran
+func resultScore(r *stronghold.ScanResult) float64 {
+	if score, ok := r.Scores["combined"]; ok {
+		return score
+	}
+	return r.Scores["heuristic"]
+}
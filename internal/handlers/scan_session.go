@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/middleware"
+	"stronghold/internal/rulepack"
+	"stronghold/internal/sessions"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ScanSessionResponse describes a new conversation scan session
+type ScanSessionResponse struct {
+	SessionID  string    `json:"session_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	TTLSeconds int       `json:"ttl_seconds"` // Idle time before the session expires; every message restarts it
+}
+
+// ScanSessionMessageRequest represents a conversation message to scan in its session
+type ScanSessionMessageRequest struct {
+	SessionID string `json:"session_id"`
+	Text      string `json:"text"`
+	Role      string `json:"role,omitempty"` // "user" (default), "assistant" or "tool"
+	Mode      string `json:"mode,omitempty"` // "smart" (default), "strict" or "permissive"; selects the account's scoring profile
+}
+
+// SetSessions enables conversation scan sessions
+func (h *ScanHandler) SetSessions(m *sessions.Manager) {
+	h.sessions = m
+}
+
+// sessionOwner identifies the caller a session belongs to: the account for
+// API key requests, nobody for x402 requests
+func sessionOwner(c fiber.Ctx) string {
+	if id := scanAccountID(c); id != nil {
+		return id.String()
+	}
+	return ""
+}
+
+// CreateScanSession opens a conversation scan session
+// @Summary Start a conversation scan session
+// @Description Opens a session for scanning a conversation's messages one at a time with POST /v1/scan/session/message. Each message is scanned on its own and together with the conversation's recent messages, so injection built up across turns is caught. Sessions expire after ttl_seconds without messages. Opening a session is free; sessions opened with an API key can only be used by that account.
+// @Tags scan
+// @Produce json
+// @Success 201 {object} ScanSessionResponse
+// @Failure 401 {object} map[string]string "Invalid API key"
+// @Failure 503 {object} map[string]string "Session store unavailable"
+// @Router /v1/scan/session [post]
+func (h *ScanHandler) CreateScanSession(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	sess, err := h.sessions.Create(c.Context(), sessionOwner(c))
+	if err != nil {
+		slog.Error("failed to create scan session", "request_id", requestID, "error", err)
+		message := "Scan sessions are unavailable"
+		if errors.Is(err, sessions.ErrStoreFull) {
+			message = "Too many open scan sessions, try again later"
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":      message,
+			"request_id": requestID,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(ScanSessionResponse{
+		SessionID:  sess.ID,
+		ExpiresAt:  sess.ExpiresAt,
+		TTLSeconds: int(h.sessions.TTL().Seconds()),
+	})
+}
+
+// ScanSessionMessage scans a conversation message in its session
+// @Summary Scan a conversation message
+// @Description Scans a message for prompt injection on its own and together with the session's recent messages. When the conversation reads as an injection but the message alone doesn't, the decision is raised with a multi_turn_injection threat; scores.context holds the conversation's score. The message is then added to the session and the session's TTL restarts. Charged per message.
+// @Tags scan
+// @Accept json
+// @Produce json
+// @Param request body ScanSessionMessageRequest true "Session message scan request"
+// @Success 200 {object} stronghold.ScanResult
+// @Failure 400 {object} map[string]string
+// @Failure 402 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string "Session not found or expired"
+// @Failure 413 {object} map[string]string
+// @Router /v1/scan/session/message [post]
+func (h *ScanHandler) ScanSessionMessage(c fiber.Ctx) error {
+	requestID := middleware.GetRequestID(c)
+
+	var req ScanSessionMessageRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Invalid request body",
+			"request_id": requestID,
+		})
+	}
+
+	if req.SessionID == "" || req.Text == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "session_id and text are required",
+			"request_id": requestID,
+		})
+	}
+
+	if len(req.Text) > h.textLimit() {
+		return h.textTooLarge(c, requestID)
+	}
+
+	switch req.Role {
+	case "":
+		req.Role = "user"
+	case "user", "assistant", "tool":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "role must be user, assistant or tool",
+			"request_id": requestID,
+		})
+	}
+
+	if req.Mode == "" {
+		req.Mode = stronghold.ScanModeSmart
+	}
+	if !stronghold.IsValidScanMode(req.Mode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "mode must be smart, strict or permissive",
+			"request_id": requestID,
+		})
+	}
+
+	sess, err := h.sessions.Get(c.Context(), req.SessionID, sessionOwner(c))
+	if err != nil {
+		if errors.Is(err, sessions.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":      "Scan session not found or expired",
+				"request_id": requestID,
+			})
+		}
+		slog.Error("failed to load scan session", "request_id", requestID, "error", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":      "Scan sessions are unavailable",
+			"request_id": requestID,
+		})
+	}
+
+	scanner, backend := h.scannerFor(c)
+	result, err := scanner.ScanContent(c.Context(), req.Text, "", "", "")
+	if err != nil {
+		slog.Error("scan session message failed", "request_id", requestID, "backend", backend, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":      "Scan failed",
+			"request_id": requestID,
+		})
+	}
+	if backend == config.BackendInternal {
+		h.applyScoringProfile(c, result, req.Mode)
+	}
+	h.filterJailbreakThreats(c, result)
+	applyRulePacks(result, req.Text, rulepack.TargetContent, h.loadRulePacks(c))
+
+	// Read the message with the conversation's recent messages
+	contextMessages := 1
+	if len(sess.Messages) > 0 {
+		history := make([]string, len(sess.Messages))
+		for i, m := range sess.Messages {
+			history[i] = m.Text
+		}
+		var text string
+		text, contextMessages = stronghold.ConversationContext(history, req.Text, h.sessions.MaxContextBytes())
+		contextResult, err := scanner.ScanContent(c.Context(), text, "", "", "")
+		if err != nil {
+			slog.Error("scan session context failed", "request_id", requestID, "backend", backend, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":      "Scan failed",
+				"request_id": requestID,
+			})
+		}
+		if backend == config.BackendInternal {
+			h.applyScoringProfile(c, contextResult, req.Mode)
+		}
+		h.filterJailbreakThreats(c, contextResult)
+		stronghold.ApplyConversationContext(result, contextResult, contextMessages)
+	}
+
+	// A session that expired mid-request still gets its verdict
+	if err := h.sessions.Append(c.Context(), sess.ID, sessions.Message{Role: req.Role, Text: req.Text}); err != nil {
+		slog.Warn("failed to record scan session message", "request_id", requestID, "error", err)
+	}
+
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata["session_id"] = sess.ID
+	result.Metadata["turn"] = sess.Turns + 1
+	result.Metadata["role"] = req.Role
+	result.Metadata["context_messages"] = contextMessages
+	result.Metadata["backend"] = backend
+
+	result.RequestID = requestID
+	c.Locals(middleware.DetectionVersionKey, result.DetectionVersion)
+
+	// Strip scoring detail the account or API key doesn't want returned
+	h.applyScoreExposure(c, result)
+
+	// Record execution result in payment transaction for idempotent replay
+	h.recordExecutionResult(c, result)
+
+	// Log usage for B2B requests
+	h.logUsage(c, result, "/v1/scan/session/message", h.pricing.ScanSession)
+
+	return c.JSON(result)
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// scoreSteps is how many steps quantized scores are rounded to per unit
+const scoreSteps = 10
+
+// reasonScore matches the score engines quote in a result's reason
+var reasonScore = regexp.MustCompile(` ?\(Score: ([0-9.]+)\)`)
+
+// ScoreExposureRequest sets a score exposure. For an API key, null clears its
+// override.
+type ScoreExposureRequest struct {
+	Exposure *string `json:"exposure"` // "exact", "quantized" or "omitted"
+}
+
+// APIKeyScoreExposure is the score exposure of one of the account's API keys
+type APIKeyScoreExposure struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"label"`
+	KeyPrefix string            `json:"key_prefix"`
+	Override  *string           `json:"override"` // null when the key follows the account
+	Effective *db.ScoreExposure `json:"effective"`
+}
+
+// ScoreExposureResponse describes the account's score exposure and that of each active API key
+type ScoreExposureResponse struct {
+	Account *db.ScoreExposure     `json:"account"`
+	APIKeys []APIKeyScoreExposure `json:"api_keys"`
+}
+
+// GetScoreExposure returns the account's score exposure and its API keys' overrides
+// @Summary Get score exposure policy
+// @Description Returns how much scoring detail API key scan responses carry: exact scores, scores quantized to steps of 0.1, or none (decision and threat categories only). Shown for the account and for each active API key, which may override it.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} ScoreExposureResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/scores [get]
+func (h *PolicyHandler) GetScoreExposure(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.loadScoreExposure(c, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get policy",
+		})
+	}
+	return c.JSON(resp)
+}
+
+// UpdateScoreExposure sets the account's score exposure
+// @Summary Update score exposure policy
+// @Description Sets how much scoring detail API key scan responses carry, for keys without an override. Quantized rounds scores to steps of 0.1; omitted leaves only the decision and threat categories, so exact scores can't be used to tune payloads.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param request body ScoreExposureRequest true "Score exposure"
+// @Success 200 {object} ScoreExposureResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/scores [put]
+func (h *PolicyHandler) UpdateScoreExposure(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req ScoreExposureRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Exposure == nil || !db.IsValidScoreExposure(*req.Exposure) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "exposure must be 'exact', 'quantized' or 'omitted'",
+		})
+	}
+
+	if err := h.db.SetScoreExposure(c.Context(), accountID, *req.Exposure); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update policy",
+		})
+	}
+	slog.Info("score exposure updated", "account_id", accountID.String(), "exposure", *req.Exposure)
+
+	resp, err := h.loadScoreExposure(c, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read updated policy",
+		})
+	}
+	return c.JSON(resp)
+}
+
+// UpdateAPIKeyScoreExposure overrides the score exposure of one API key
+// @Summary Override score exposure for an API key
+// @Description Sets how much scoring detail scans made with this key return, whatever the account setting. Send null to clear the override.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param id path string true "API key ID"
+// @Param request body ScoreExposureRequest true "Score exposure, or null"
+// @Success 200 {object} ScoreExposureResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "API key not found"
+// @Security CookieAuth
+// @Router /v1/account/policy/scores/keys/{id} [put]
+func (h *PolicyHandler) UpdateAPIKeyScoreExposure(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	var req ScoreExposureRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Exposure != nil && !db.IsValidScoreExposure(*req.Exposure) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "exposure must be 'exact', 'quantized', 'omitted' or null",
+		})
+	}
+
+	if err := h.db.SetAPIKeyScoreExposure(c.Context(), keyID, accountID, req.Exposure); err != nil {
+		if errors.Is(err, db.ErrAPIKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "API key not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update policy",
+		})
+	}
+	slog.Info("API key score exposure updated", "account_id", accountID.String(), "api_key_id", keyID.String(), "exposure", req.Exposure)
+
+	resp, err := h.loadScoreExposure(c, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read updated policy",
+		})
+	}
+	return c.JSON(resp)
+}
+
+// loadScoreExposure reads the account's score exposure and resolves it for each active key
+func (h *PolicyHandler) loadScoreExposure(c fiber.Ctx, accountID uuid.UUID) (*ScoreExposureResponse, error) {
+	account, err := h.db.GetScoreExposure(c.Context(), accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := h.db.ListAPIKeys(c.Context(), accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ScoreExposureResponse{Account: account, APIKeys: make([]APIKeyScoreExposure, 0, len(keys))}
+	for _, k := range keys {
+		effective := account
+		if k.ScoreExposure != nil && db.IsValidScoreExposure(*k.ScoreExposure) {
+			effective = &db.ScoreExposure{Exposure: *k.ScoreExposure, Source: db.PolicySourceAPIKey}
+		}
+		resp.APIKeys = append(resp.APIKeys, APIKeyScoreExposure{
+			ID:        k.ID,
+			Name:      k.Name,
+			KeyPrefix: k.KeyPrefix,
+			Override:  k.ScoreExposure,
+			Effective: effective,
+		})
+	}
+	return resp, nil
+}
+
+// applyScoreExposure strips scoring detail from API key scan results as the
+// key's or account's score exposure requires. x402 scans return exact scores.
+func (h *ScanHandler) applyScoreExposure(c fiber.Ctx, results ...*stronghold.ScanResult) {
+	authMethod, _ := c.Locals("auth_method").(string)
+	accountID := scanAccountID(c)
+	if authMethod != "api_key" || accountID == nil || h.db == nil {
+		return
+	}
+	keyIDStr, _ := c.Locals("api_key_id").(string)
+	keyID, err := uuid.Parse(keyIDStr)
+	if err != nil {
+		return
+	}
+
+	exposure, err := h.db.GetEffectiveScoreExposure(c.Context(), *accountID, keyID)
+	if err != nil {
+		// Fail closed: a caller that asked for no scores must not get them
+		slog.Warn("failed to get score exposure, omitting scores", "account_id", accountID.String(), "error", err)
+		exposure = &db.ScoreExposure{Exposure: db.ScoreExposureOmitted}
+	}
+	for _, result := range results {
+		redactScores(result, exposure.Exposure)
+	}
+}
+
+// redactScores quantizes or omits a result's scores and the details that
+// would let a caller reconstruct them. Omitting keeps the decision and the
+// category and severity of each threat.
+func redactScores(result *stronghold.ScanResult, exposure string) {
+	switch exposure {
+	case db.ScoreExposureQuantized:
+		for k, v := range result.Scores {
+			result.Scores[k] = quantizeScore(v)
+		}
+		result.Reason = reasonScore.ReplaceAllStringFunc(result.Reason, func(s string) string {
+			v, err := strconv.ParseFloat(reasonScore.FindStringSubmatch(s)[1], 64)
+			if err != nil {
+				return ""
+			}
+			return " (Score: " + strconv.FormatFloat(quantizeScore(v), 'f', 1, 64) + ")"
+		})
+	case db.ScoreExposureOmitted:
+		result.Scores = map[string]float64{}
+		result.Reason = reasonScore.ReplaceAllString(result.Reason, "")
+		result.SanitizedText = ""
+		for i, t := range result.ThreatsFound {
+			result.ThreatsFound[i] = stronghold.Threat{Category: t.Category, Severity: t.Severity}
+		}
+	}
+}
+
+// quantizeScore rounds a score to the nearest 1/scoreSteps
+func quantizeScore(v float64) float64 {
+	return math.Round(v*scoreSteps) / scoreSteps
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exposureResult() *stronghold.ScanResult {
+	return &stronghold.ScanResult{
+		Decision:      stronghold.DecisionWarn,
+		Scores:        map[string]float64{"heuristic": 0.63, "ml": 0.4481, "combined": 0.5723},
+		Reason:        "Warning: weighted score above the warn threshold (Score: 0.57)",
+		SanitizedText: "Please summarize [REDACTED]",
+		ThreatsFound: []stronghold.Threat{
+			{Category: "prompt_injection", Pattern: "ignore previous", Location: "offset 16", Severity: "medium", Description: "Instruction override"},
+		},
+	}
+}
+
+func TestRedactScores_Quantized(t *testing.T) {
+	result := exposureResult()
+	redactScores(result, db.ScoreExposureQuantized)
+
+	assert.Equal(t, map[string]float64{"heuristic": 0.6, "ml": 0.4, "combined": 0.6}, result.Scores)
+	assert.Equal(t, "Warning: weighted score above the warn threshold (Score: 0.6)", result.Reason)
+	assert.Equal(t, "ignore previous", result.ThreatsFound[0].Pattern, "quantizing keeps threat details")
+	assert.NotEmpty(t, result.SanitizedText)
+}
+
+func TestRedactScores_Omitted(t *testing.T) {
+	result := exposureResult()
+	redactScores(result, db.ScoreExposureOmitted)
+
+	assert.Equal(t, stronghold.DecisionWarn, result.Decision)
+	assert.Empty(t, result.Scores)
+	assert.NotNil(t, result.Scores, "scores stay an object on the wire")
+	assert.Equal(t, "Warning: weighted score above the warn threshold", result.Reason)
+	assert.Empty(t, result.SanitizedText)
+	assert.Equal(t, []stronghold.Threat{{Category: "prompt_injection", Severity: "medium"}}, result.ThreatsFound)
+}
+
+func TestRedactScores_Exact(t *testing.T) {
+	result := exposureResult()
+	redactScores(result, db.ScoreExposureExact)
+	assert.Equal(t, exposureResult(), result)
+}
+
+func TestApplyScoreExposure_PerAPIKey(t *testing.T) {
+	tDB := testutil.NewTestDB(t)
+	defer tDB.Close(t)
+
+	database := db.NewFromPool(tDB.Pool)
+	ctx := context.Background()
+
+	account, err := database.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	quiet, err := database.CreateAPIKey(ctx, account.ID, "sk_live_qui", "hash-quiet", "quiet", 10)
+	require.NoError(t, err)
+	loud, err := database.CreateAPIKey(ctx, account.ID, "sk_live_lou", "hash-loud", "loud", 10)
+	require.NoError(t, err)
+
+	require.NoError(t, database.SetScoreExposure(ctx, account.ID, db.ScoreExposureOmitted))
+	exact := db.ScoreExposureExact
+	require.NoError(t, database.SetAPIKeyScoreExposure(ctx, loud.ID, account.ID, &exact))
+
+	handler := &ScanHandler{db: database}
+	scan := func(authMethod, keyID string) *stronghold.ScanResult {
+		result := exposureResult()
+		app := fiber.New()
+		app.Post("/test", func(c fiber.Ctx) error {
+			c.Locals("auth_method", authMethod)
+			c.Locals("account_id", account.ID.String())
+			c.Locals("api_key_id", keyID)
+			handler.applyScoreExposure(c, result)
+			return c.JSON(result)
+		})
+		resp, err := app.Test(httptest.NewRequest("POST", "/test", bytes.NewBufferString(`{}`)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return result
+	}
+
+	assert.Empty(t, scan("api_key", quiet.ID.String()).Scores, "keys follow the account")
+	assert.Equal(t, exposureResult().Scores, scan("api_key", loud.ID.String()).Scores, "a key override wins")
+	assert.Equal(t, exposureResult().Scores, scan("x402", "").Scores, "x402 scans keep exact scores")
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ScoringProfileResponse is an account's scoring profile for a mode
+type ScoringProfileResponse struct {
+	Mode string `json:"mode"` // default, smart, strict or permissive
+	stronghold.ScoringProfile
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScoringProfilesResponse lists an account's scoring profiles
+type ScoringProfilesResponse struct {
+	Profiles []ScoringProfileResponse `json:"profiles"`
+	Layers   []string                 `json:"layers"` // Layers that can be weighted
+}
+
+// ListScoringProfiles returns the account's scoring profiles
+// @Summary List scoring profiles
+// @Description Returns the account's scoring profiles by scanning mode. A profile replaces the engine's combination of layer scores with a weighted mean and maps it to a decision with a threshold curve. Scans in a mode without a profile use the default profile, or the engine's own scoring when there is none.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} ScoringProfilesResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/scoring [get]
+func (h *PolicyHandler) ListScoringProfiles(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	stored, err := h.db.ListScoringProfiles(c.Context(), accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get scoring profiles",
+		})
+	}
+
+	resp := ScoringProfilesResponse{
+		Profiles: make([]ScoringProfileResponse, 0, len(stored)),
+		Layers:   stronghold.ScoringLayers(),
+	}
+	for _, p := range stored {
+		profile, err := decodeScoringProfile(p)
+		if err != nil {
+			slog.Warn("skipping unreadable scoring profile", "account_id", accountID.String(), "mode", p.Mode, "error", err)
+			continue
+		}
+		resp.Profiles = append(resp.Profiles, ScoringProfileResponse{Mode: p.Mode, ScoringProfile: *profile, UpdatedAt: p.UpdatedAt})
+	}
+	return c.JSON(resp)
+}
+
+// SetScoringProfile creates or replaces the account's scoring profile for a mode
+// @Summary Set scoring profile
+// @Description Sets the layer weights (heuristic, semantic, ml, llm) and threshold curve applied to the account's content scans in a scanning mode, or in every mode without its own profile when mode is default. Weights must be non-negative with at least one positive; curve points must ascend through (0, 1] with WARN before BLOCK. Without a curve the scanner's warn and block thresholds are used.
+// @Tags policy
+// @Accept json
+// @Produce json
+// @Param mode path string true "default, smart, strict or permissive"
+// @Param request body stronghold.ScoringProfile true "Weights and threshold curve"
+// @Success 200 {object} ScoringProfileResponse
+// @Failure 400 {object} map[string]string "Invalid mode or profile"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/policy/scoring/{mode} [put]
+func (h *PolicyHandler) SetScoringProfile(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	mode := c.Params("mode")
+	if !stronghold.IsValidProfileMode(mode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "mode must be default, smart, strict or permissive",
+		})
+	}
+
+	var profile stronghold.ScoringProfile
+	if err := c.Bind().Body(&profile); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := profile.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	encoded, err := json.Marshal(profile)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to encode scoring profile",
+		})
+	}
+	if err := h.db.SetScoringProfile(c.Context(), accountID, mode, encoded); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update scoring profile",
+		})
+	}
+
+	return c.JSON(ScoringProfileResponse{Mode: mode, ScoringProfile: profile, UpdatedAt: time.Now().UTC()})
+}
+
+// DeleteScoringProfile removes the account's scoring profile for a mode
+// @Summary Delete scoring profile
+// @Description Removes the account's scoring profile for a mode. Scans in that mode fall back to the default profile, or to the engine's own scoring.
+// @Tags policy
+// @Produce json
+// @Param mode path string true "default, smart, strict or permissive"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "No profile for this mode"
+// @Security CookieAuth
+// @Router /v1/account/policy/scoring/{mode} [delete]
+func (h *PolicyHandler) DeleteScoringProfile(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	mode := c.Params("mode")
+	if err := h.db.DeleteScoringProfile(c.Context(), accountID, mode); err != nil {
+		if errors.Is(err, db.ErrScoringProfileNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No scoring profile for this mode",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete scoring profile",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Scoring profile deleted",
+	})
+}
+
+// decodeScoringProfile decodes a stored scoring profile
+func decodeScoringProfile(p *db.ScoringProfile) (*stronghold.ScoringProfile, error) {
+	var profile stronghold.ScoringProfile
+	if err := json.Unmarshal(p.Profile, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
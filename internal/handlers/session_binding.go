@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log/slog"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Session binding modes, set with SESSION_BINDING
+const (
+	// SessionBindingOff skips binding checks
+	SessionBindingOff = "off"
+	// SessionBindingLog logs mismatches without rejecting the refresh
+	SessionBindingLog = "log"
+	// SessionBindingEnforce rejects refreshes from a different device token
+	// and logs User-Agent changes
+	SessionBindingEnforce = "enforce"
+	// SessionBindingStrict also rejects refreshes whose User-Agent changed
+	SessionBindingStrict = "strict"
+)
+
+// sessionBindingMode returns the configured mode, enforce by default
+func (h *AuthHandler) sessionBindingMode() string {
+	if h.config.SessionBinding == "" {
+		return SessionBindingEnforce
+	}
+	return h.config.SessionBinding
+}
+
+// bindSessionDevice binds a new session to the request's device token if it
+// belongs to one of the account's trusted devices
+func (h *AuthHandler) bindSessionDevice(c fiber.Ctx, session *db.Session) {
+	deviceToken := getDeviceToken(c)
+	if deviceToken == "" || h.sessionBindingMode() == SessionBindingOff {
+		return
+	}
+	ctx := c.Context()
+	if _, err := h.db.GetDeviceByToken(ctx, session.AccountID, deviceToken); err != nil {
+		return
+	}
+	if err := h.db.BindSessionDevice(ctx, session.ID, deviceToken); err != nil {
+		slog.Warn("failed to bind session to device", "session_id", session.ID, "error", err)
+		return
+	}
+	hash := db.HashToken(deviceToken)
+	session.DeviceTokenHash = &hash
+}
+
+// checkSessionBinding compares a refresh request with the device the
+// session is bound to. It returns false if the refresh must be rejected.
+// Sessions created before the device was trusted are bound on their first
+// refresh from it.
+func (h *AuthHandler) checkSessionBinding(c fiber.Ctx, session *db.Session) bool {
+	mode := h.sessionBindingMode()
+	if mode == SessionBindingOff {
+		return true
+	}
+
+	deviceToken := getDeviceToken(c)
+	if session.DeviceTokenHash == nil {
+		if deviceToken != "" {
+			h.bindSessionDevice(c, session)
+		}
+	} else if deviceToken == "" || subtle.ConstantTimeCompare([]byte(db.HashToken(deviceToken)), []byte(*session.DeviceTokenHash)) != 1 {
+		return h.sessionBindingMismatch(c, session, mode, "device_token", mode != SessionBindingLog)
+	}
+
+	userAgent := string(c.Request().Header.UserAgent())
+	if session.UserAgent != nil && *session.UserAgent != userAgent {
+		return h.sessionBindingMismatch(c, session, mode, "user_agent", mode == SessionBindingStrict)
+	}
+	return true
+}
+
+// sessionBindingMismatch records a mismatch as a security event and, when
+// reject is set, revokes the session so the token can't be retried
+func (h *AuthHandler) sessionBindingMismatch(c fiber.Ctx, session *db.Session, mode, reason string, reject bool) bool {
+	action := "allowed"
+	if reject {
+		action = "revoked"
+	}
+	logSecurityEvent(c, "session_binding_mismatch",
+		"account_id", session.AccountID,
+		"session_id", session.ID,
+		"reason", reason,
+		"mode", mode,
+		"action", action,
+	)
+	if !reject {
+		return true
+	}
+	if err := h.db.DeleteSession(c.Context(), session.ID); err != nil {
+		slog.Error("failed to revoke session after binding mismatch", "session_id", session.ID, "error", err)
+	}
+	return false
+}
+
+// logSecurityEvent logs an event worth alerting on. Entries carry a
+// security_event attribute so they can be filtered from the rest of the log.
+func logSecurityEvent(c fiber.Ctx, event string, attrs ...any) {
+	attrs = append([]any{
+		"security_event", event,
+		"ip", c.IP(),
+		"user_agent", string(c.Request().Header.UserAgent()),
+	}, attrs...)
+	slog.Warn("security event", attrs...)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loginWithDevice logs in with a trusted device token and returns the refresh token
+func loginWithDevice(t *testing.T, app *fiber.App, accountNumber, deviceToken string) string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"account_number": accountNumber})
+	req := httptest.NewRequest("POST", "/v1/auth/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stronghold-cli")
+	req.Header.Set(deviceTokenHeader, deviceToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	return refreshCookie(t, resp.Header.Values("Set-Cookie"))
+}
+
+func refreshCookie(t *testing.T, cookies []string) string {
+	t.Helper()
+	for _, cookie := range cookies {
+		for _, part := range strings.Split(cookie, ";") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, RefreshTokenCookie+"=") {
+				return strings.TrimPrefix(part, RefreshTokenCookie+"=")
+			}
+		}
+	}
+	t.Fatal("no refresh token cookie")
+	return ""
+}
+
+func refreshWith(t *testing.T, app *fiber.App, refreshToken, deviceToken, userAgent string) int {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/v1/auth/refresh", nil)
+	req.Header.Set("Cookie", RefreshTokenCookie+"="+refreshToken)
+	req.Header.Set("User-Agent", userAgent)
+	if deviceToken != "" {
+		req.Header.Set(deviceTokenHeader, deviceToken)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRefreshToken_BoundToDevice(t *testing.T) {
+	app, handler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	accountNumber, _ := createTestAccount(t, app)
+	account, err := handler.db.GetAccountByNumber(ctx, accountNumber)
+	require.NoError(t, err)
+	deviceToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	_, err = handler.db.CreateDeviceToken(ctx, account.ID, deviceToken, "laptop", db.DeviceMetadata{}, nil)
+	require.NoError(t, err)
+
+	// The bound device can refresh
+	refreshToken := loginWithDevice(t, app, accountNumber, deviceToken)
+	assert.Equal(t, fiber.StatusOK, refreshWith(t, app, refreshToken, deviceToken, "stronghold-cli"))
+
+	// A stolen cookie replayed elsewhere is rejected and the session revoked
+	refreshToken = loginWithDevice(t, app, accountNumber, deviceToken)
+	assert.Equal(t, fiber.StatusUnauthorized, refreshWith(t, app, refreshToken, "", "stronghold-cli"))
+	assert.Equal(t, fiber.StatusUnauthorized, refreshWith(t, app, refreshToken, deviceToken, "stronghold-cli"),
+		"the session should be revoked after a mismatch")
+
+	// A changed User-Agent is only rejected in strict mode
+	refreshToken = loginWithDevice(t, app, accountNumber, deviceToken)
+	assert.Equal(t, fiber.StatusOK, refreshWith(t, app, refreshToken, deviceToken, "other-agent"))
+	handler.config.SessionBinding = SessionBindingStrict
+	refreshToken = loginWithDevice(t, app, accountNumber, deviceToken)
+	assert.Equal(t, fiber.StatusUnauthorized, refreshWith(t, app, refreshToken, deviceToken, "other-agent"))
+}
+
+func TestRefreshToken_BindingLogMode(t *testing.T) {
+	app, handler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+	handler.config.SessionBinding = SessionBindingLog
+
+	accountNumber, _ := createTestAccount(t, app)
+	account, err := handler.db.GetAccountByNumber(ctx, accountNumber)
+	require.NoError(t, err)
+	deviceToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	_, err = handler.db.CreateDeviceToken(ctx, account.ID, deviceToken, "laptop", db.DeviceMetadata{}, nil)
+	require.NoError(t, err)
+
+	refreshToken := loginWithDevice(t, app, accountNumber, deviceToken)
+	assert.Equal(t, fiber.StatusOK, refreshWith(t, app, refreshToken, "", "stronghold-cli"))
+}
@@ -13,6 +13,7 @@ import (
 
 	"stronghold/internal/db"
 	"stronghold/internal/db/testutil"
+	"stronghold/internal/secure"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/stretchr/testify/assert"
@@ -35,7 +36,7 @@ func setupSettingsTest(t *testing.T) (*fiber.App, *AuthHandler, *SettingsHandler
 	require.NoError(t, err)
 
 	authConfig := &AuthConfig{
-		JWTSecret:       "test-secret-key-for-testing",
+		JWTSecret:       secure.NewSecretString("test-secret-key-for-testing"),
 		AccessTokenTTL:  15 * time.Minute,
 		RefreshTokenTTL: 90 * 24 * time.Hour,
 		DashboardURL:    "http://localhost:3000",
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/settlement"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxWebhookURLLength bounds settlement webhook URLs
+const maxWebhookURLLength = 2048
+
+// SettlementWebhookHandler manages an account's settlement webhook
+type SettlementWebhookHandler struct {
+	db     *db.DB
+	sender *settlement.Webhooks
+}
+
+// NewSettlementWebhookHandler creates a new settlement webhook handler
+func NewSettlementWebhookHandler(database *db.DB) *SettlementWebhookHandler {
+	return &SettlementWebhookHandler{db: database}
+}
+
+// SetSender enables test deliveries and publishes the sender's signing keys
+func (h *SettlementWebhookHandler) SetSender(w *settlement.Webhooks) {
+	h.sender = w
+}
+
+// RegisterRoutes registers settlement webhook routes
+func (h *SettlementWebhookHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
+	app.Get("/v1/webhooks/signing-keys", h.SigningKeys)
+
+	group := app.Group("/v1/account/webhooks/settlement")
+	group.Get("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetWebhook)
+	group.Put("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.SetWebhook)
+	group.Delete("/", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.DeleteWebhook)
+	group.Post("/test", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.TestWebhook)
+}
+
+// SettlementWebhookRequest sets the URL notified when the account's payments settle
+type SettlementWebhookRequest struct {
+	URL string `json:"url"` // must be https
+}
+
+// SettlementWebhookResponse describes the account's settlement webhook
+type SettlementWebhookResponse struct {
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // signing secret, returned only by PUT
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetWebhook returns the account's settlement webhook
+// @Summary Get settlement webhook
+// @Description Returns the URL notified when an x402 payment from one of the account's wallets settles. The signing secret is not returned.
+// @Tags account
+// @Produce json
+// @Success 200 {object} SettlementWebhookResponse
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "No settlement webhook"
+// @Security CookieAuth
+// @Router /v1/account/webhooks/settlement [get]
+func (h *SettlementWebhookHandler) GetWebhook(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.db.GetSettlementWebhook(c.Context(), accountID)
+	if errors.Is(err, db.ErrSettlementWebhookNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No settlement webhook",
+		})
+	}
+	if err != nil {
+		slog.Error("failed to get settlement webhook", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get settlement webhook",
+		})
+	}
+
+	return c.JSON(SettlementWebhookResponse{
+		URL:       webhook.URL,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	})
+}
+
+// SetWebhook creates or updates the account's settlement webhook
+// @Summary Set settlement webhook
+// @Description Sets the https URL notified when an x402 payment from one of the account's wallets settles. Returns the secret used to sign deliveries; it is kept when the URL changes and replaced only by deleting and recreating the webhook.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body SettlementWebhookRequest true "Webhook URL"
+// @Success 200 {object} SettlementWebhookResponse
+// @Failure 400 {object} map[string]string "Invalid URL"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Security CookieAuth
+// @Router /v1/account/webhooks/settlement [put]
+func (h *SettlementWebhookHandler) SetWebhook(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req SettlementWebhookRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if !validWebhookURL(req.URL) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "url must be an absolute https URL",
+		})
+	}
+
+	webhook, err := h.db.SetSettlementWebhook(c.Context(), accountID, req.URL)
+	if err != nil {
+		slog.Error("failed to set settlement webhook", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to set settlement webhook",
+		})
+	}
+
+	return c.JSON(SettlementWebhookResponse{
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	})
+}
+
+// DeleteWebhook removes the account's settlement webhook
+// @Summary Delete settlement webhook
+// @Description Stops settlement notifications and drops any not yet delivered
+// @Tags account
+// @Success 204
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "No settlement webhook"
+// @Security CookieAuth
+// @Router /v1/account/webhooks/settlement [delete]
+func (h *SettlementWebhookHandler) DeleteWebhook(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := h.db.DeleteSettlementWebhook(c.Context(), accountID)
+	if err != nil {
+		slog.Error("failed to delete settlement webhook", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete settlement webhook",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No settlement webhook",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SigningKeysResponse lists the keys that verify webhook signatures
+type SigningKeysResponse struct {
+	Keys             []settlement.WebhookSigningKey `json:"keys"`
+	ToleranceSeconds int                            `json:"tolerance_seconds"`
+}
+
+// SigningKeys returns the public keys that verify webhook signatures
+// @Summary Webhook signing keys
+// @Description Returns the Ed25519 public keys, in JWK form, that verify the Stronghold-Signature-Ed25519 header of webhook deliveries. The active key signs new deliveries; retired keys stay listed while deliveries signed with them may still arrive. Match a signature to its key by kid, and reject deliveries whose timestamp is more than tolerance_seconds from your clock. The list is empty when the server has no signing key.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} SigningKeysResponse
+// @Router /v1/webhooks/signing-keys [get]
+func (h *SettlementWebhookHandler) SigningKeys(c fiber.Ctx) error {
+	keys := []settlement.WebhookSigningKey{}
+	if h.sender != nil {
+		keys = h.sender.SigningKeys()
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(SigningKeysResponse{
+		Keys:             keys,
+		ToleranceSeconds: int(settlement.WebhookTolerance.Seconds()),
+	})
+}
+
+// TestWebhook sends a test event to the account's settlement webhook
+// @Summary Send test webhook
+// @Description Immediately sends a signed webhook.test event to the account's settlement webhook and reports how the receiver answered. Test deliveries are signed like settlement deliveries but aren't retried.
+// @Tags account
+// @Produce json
+// @Success 200 {object} settlement.TestDelivery
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 404 {object} map[string]string "No settlement webhook"
+// @Failure 503 {object} map[string]string "Webhook delivery unavailable"
+// @Security CookieAuth
+// @Router /v1/account/webhooks/settlement/test [post]
+func (h *SettlementWebhookHandler) TestWebhook(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+	if h.sender == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Webhook delivery unavailable",
+		})
+	}
+
+	webhook, err := h.db.GetSettlementWebhook(c.Context(), accountID)
+	if errors.Is(err, db.ErrSettlementWebhookNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No settlement webhook",
+		})
+	}
+	if err != nil {
+		slog.Error("failed to get settlement webhook", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get settlement webhook",
+		})
+	}
+
+	result, err := h.sender.SendTest(c.Context(), webhook)
+	if err != nil {
+		slog.Error("failed to send test webhook", "account_id", accountID.String(), "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to send test webhook",
+		})
+	}
+	return c.JSON(result)
+}
+
+// validWebhookURL reports whether raw is an absolute https URL without credentials
+func validWebhookURL(raw string) bool {
+	if raw == "" || len(raw) > maxWebhookURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != "" && u.User == nil
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSubAccountsPerAccount = 50
+	maxSubAccountNameLength  = 64
+)
+
+// SubAccountHandler handles sub-account management endpoints
+type SubAccountHandler struct {
+	db   *db.DB
+	keys *APIKeyHandler
+}
+
+// NewSubAccountHandler creates a new sub-account handler
+func NewSubAccountHandler(database *db.DB) *SubAccountHandler {
+	return &SubAccountHandler{db: database, keys: NewAPIKeyHandler(database)}
+}
+
+// RegisterRoutes registers sub-account routes (all require JWT auth)
+func (h *SubAccountHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler) {
+	group := app.Group("/v1/sub-accounts", authMiddleware)
+	group.Post("/", h.Create)
+	group.Get("/", h.List)
+	group.Get("/usage", h.GetUsage)
+	group.Put("/:id/budget", h.SetBudget)
+	group.Delete("/:id", h.Close)
+	group.Post("/:id/api-keys", h.CreateAPIKey)
+	group.Get("/:id/api-keys", h.ListAPIKeys)
+}
+
+// CreateSubAccountRequest creates a sub-account
+type CreateSubAccountRequest struct {
+	Name       string              `json:"name" example:"research-agent"`
+	BudgetUSDC *usdc.DecimalAmount `json:"budget_usdc,omitempty" swaggertype:"string" example:"50.00"` // Omit for no cap beyond the account balance
+}
+
+// SetSubAccountBudgetRequest replaces a sub-account's budget
+type SetSubAccountBudgetRequest struct {
+	BudgetUSDC *usdc.DecimalAmount `json:"budget_usdc" swaggertype:"string" example:"100.00"` // null removes the cap
+}
+
+// SubAccountResponse is a sub-account with what is left of its budget
+type SubAccountResponse struct {
+	*db.SubAccount
+	RemainingUSDC *usdc.MicroUSDC `json:"remaining_usdc,omitempty"`
+}
+
+func newSubAccountResponse(sub *db.SubAccount) SubAccountResponse {
+	return SubAccountResponse{SubAccount: sub, RemainingUSDC: sub.RemainingUSDC()}
+}
+
+// Create creates a sub-account
+// @Summary Create a sub-account
+// @Description Creates a sub-account for a project or agent. It gets its own API keys and usage, and spends the account's balance up to budget_usdc when one is set. Business accounts only.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body CreateSubAccountRequest true "Sub-account"
+// @Success 201 {object} SubAccountResponse
+// @Failure 400 {object} map[string]string "Invalid request or sub-account limit reached"
+// @Failure 403 {object} map[string]string "Not a business account"
+// @Failure 409 {object} map[string]string "Name already in use"
+// @Security CookieAuth
+// @Router /v1/sub-accounts [post]
+func (h *SubAccountHandler) Create(c fiber.Ctx) error {
+	accountID, err := h.keys.getB2BAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateSubAccountRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if msg := validateSubAccountName(req.Name); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	budget, msg := subAccountBudget(req.BudgetUSDC)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	sub, err := h.db.CreateSubAccount(c.Context(), accountID, req.Name, budget, maxSubAccountsPerAccount)
+	if err != nil {
+		return subAccountError(c, err, "Failed to create sub-account")
+	}
+
+	slog.Info("sub-account created", "account_id", accountID, "sub_account_id", sub.ID, "name", sub.Name)
+	return c.Status(fiber.StatusCreated).JSON(newSubAccountResponse(sub))
+}
+
+// List returns the account's open sub-accounts
+// @Summary List sub-accounts
+// @Description Lists the account's open sub-accounts by name, with their budget, spend and what is left of the budget.
+// @Tags account
+// @Produce json
+// @Success 200 {object} map[string][]SubAccountResponse
+// @Failure 403 {object} map[string]string "Not a business account"
+// @Security CookieAuth
+// @Router /v1/sub-accounts [get]
+func (h *SubAccountHandler) List(c fiber.Ctx) error {
+	accountID, err := h.keys.getB2BAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	subs, err := h.db.ListSubAccounts(c.Context(), accountID)
+	if err != nil {
+		slog.Error("failed to list sub-accounts", "account_id", accountID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list sub-accounts",
+		})
+	}
+
+	items := make([]SubAccountResponse, len(subs))
+	for i, sub := range subs {
+		items[i] = newSubAccountResponse(sub)
+	}
+	return c.JSON(fiber.Map{
+		"sub_accounts": items,
+	})
+}
+
+// SetBudget replaces a sub-account's budget
+// @Summary Set a sub-account's budget
+// @Description Replaces the most the sub-account may spend in total, counting what it has already spent. A null budget_usdc removes the cap, leaving only the account balance as a limit.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param id path string true "Sub-account ID"
+// @Param request body SetSubAccountBudgetRequest true "Budget"
+// @Success 200 {object} SubAccountResponse
+// @Failure 400 {object} map[string]string "Invalid budget"
+// @Failure 404 {object} map[string]string "Sub-account not found"
+// @Security CookieAuth
+// @Router /v1/sub-accounts/{id}/budget [put]
+func (h *SubAccountHandler) SetBudget(c fiber.Ctx) error {
+	accountID, id, err := h.subAccountParams(c)
+	if err != nil {
+		return err
+	}
+
+	var req SetSubAccountBudgetRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	budget, msg := subAccountBudget(req.BudgetUSDC)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	sub, err := h.db.SetSubAccountBudget(c.Context(), accountID, id, budget)
+	if err != nil {
+		return subAccountError(c, err, "Failed to set sub-account budget")
+	}
+
+	slog.Info("sub-account budget set", "account_id", accountID, "sub_account_id", id, "budget_usdc", sub.BudgetUSDC)
+	return c.JSON(newSubAccountResponse(sub))
+}
+
+// Close closes a sub-account
+// @Summary Close a sub-account
+// @Description Closes the sub-account and revokes its API keys. Its past usage stays attributed to it.
+// @Tags account
+// @Produce json
+// @Param id path string true "Sub-account ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string "Sub-account not found"
+// @Security CookieAuth
+// @Router /v1/sub-accounts/{id} [delete]
+func (h *SubAccountHandler) Close(c fiber.Ctx) error {
+	accountID, id, err := h.subAccountParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.CloseSubAccount(c.Context(), accountID, id); err != nil {
+		return subAccountError(c, err, "Failed to close sub-account")
+	}
+
+	slog.Info("sub-account closed", "account_id", accountID, "sub_account_id", id)
+	return c.JSON(fiber.Map{
+		"message": "Sub-account closed",
+	})
+}
+
+// CreateAPIKey creates an API key for a sub-account
+// @Summary Create a sub-account API key
+// @Description Creates an API key that bills and attributes its usage to the sub-account. The full key is returned once.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param id path string true "Sub-account ID"
+// @Param request body CreateAPIKeyRequest true "Key label"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} map[string]string "Missing label or key limit reached"
+// @Failure 404 {object} map[string]string "Sub-account not found"
+// @Security CookieAuth
+// @Router /v1/sub-accounts/{id}/api-keys [post]
+func (h *SubAccountHandler) CreateAPIKey(c fiber.Ctx) error {
+	accountID, id, err := h.subAccountParams(c)
+	if err != nil {
+		return err
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Key label is required",
+		})
+	}
+
+	fullKey, keyPrefix, keyHash, err := generateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate API key",
+		})
+	}
+
+	apiKey, err := h.db.CreateSubAccountAPIKey(c.Context(), accountID, id, keyPrefix, keyHash, req.Name, maxAPIKeysPerAccount)
+	if err != nil {
+		if errors.Is(err, db.ErrAPIKeyLimitReached) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Maximum of %d active API keys per sub-account", maxAPIKeysPerAccount),
+			})
+		}
+		return subAccountError(c, err, "Failed to create API key")
+	}
+
+	slog.Info("API key created",
+		"account_id", accountID,
+		"sub_account_id", id,
+		"key_id", apiKey.ID,
+		"key_prefix", keyPrefix,
+	)
+
+	return c.Status(fiber.StatusCreated).JSON(CreateAPIKeyResponse{
+		ID:        apiKey.ID.String(),
+		Key:       fullKey,
+		KeyPrefix: keyPrefix,
+		Name:      req.Name,
+		CreatedAt: apiKey.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+// ListAPIKeys lists a sub-account's active API keys
+// @Summary List sub-account API keys
+// @Description Lists the sub-account's active API keys. Keys are revoked with DELETE /v1/api-keys/{id}.
+// @Tags account
+// @Produce json
+// @Param id path string true "Sub-account ID"
+// @Success 200 {object} map[string][]APIKeyListItem
+// @Failure 404 {object} map[string]string "Sub-account not found"
+// @Security CookieAuth
+// @Router /v1/sub-accounts/{id}/api-keys [get]
+func (h *SubAccountHandler) ListAPIKeys(c fiber.Ctx) error {
+	accountID, id, err := h.subAccountParams(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.db.GetSubAccount(c.Context(), accountID, id); err != nil {
+		return subAccountError(c, err, "Failed to list API keys")
+	}
+	keys, err := h.db.ListAPIKeys(c.Context(), accountID)
+	if err != nil {
+		slog.Error("failed to list API keys", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list API keys",
+		})
+	}
+
+	items := []APIKeyListItem{}
+	for _, k := range keys {
+		if k.SubAccountID != nil && *k.SubAccountID == id {
+			items = append(items, newAPIKeyListItem(k))
+		}
+	}
+	return c.JSON(fiber.Map{
+		"api_keys": items,
+	})
+}
+
+// GetUsage breaks down the account's usage by sub-account
+// @Summary Get sub-account usage
+// @Description Aggregates scans, blocks, threats and spend by sub-account over the last `days` days, or `start` to `end` when both are given. Closed sub-accounts are included when they have usage in the window.
+// @Tags account
+// @Produce json
+// @Param days query int false "Number of days to include (default 30, max 365)"
+// @Param start query string false "Window start (RFC 3339)"
+// @Param end query string false "Window end (RFC 3339)"
+// @Success 200 {object} map[string][]db.SubAccountUsage
+// @Failure 400 {object} map[string]string "Invalid window"
+// @Security CookieAuth
+// @Router /v1/sub-accounts/usage [get]
+func (h *SubAccountHandler) GetUsage(c fiber.Ctx) error {
+	accountID, err := h.keys.getB2BAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := parseUsageWindow(c.Query("days"), c.Query("start"), c.Query("end"), time.Now().UTC())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	usage, err := h.db.GetSubAccountUsage(c.Context(), accountID, start, end)
+	if err != nil {
+		slog.Error("failed to get sub-account usage", "account_id", accountID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get sub-account usage",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"start":        start,
+		"end":          end,
+		"sub_accounts": usage,
+	})
+}
+
+// subAccountParams returns the caller's business account and the
+// sub-account ID from the path
+func (h *SubAccountHandler) subAccountParams(c fiber.Ctx) (uuid.UUID, uuid.UUID, error) {
+	accountID, err := h.keys.getB2BAccountID(c)
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, err
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fiber.NewError(fiber.StatusBadRequest, "Invalid sub-account ID")
+	}
+	return accountID, id, nil
+}
+
+// validateSubAccountName returns why a (trimmed) sub-account name is
+// unacceptable, or "" when it is fine
+func validateSubAccountName(name string) string {
+	if name == "" {
+		return "Sub-account name is required"
+	}
+	if utf8.RuneCountInString(name) > maxSubAccountNameLength {
+		return fmt.Sprintf("Sub-account name must be at most %d characters", maxSubAccountNameLength)
+	}
+	return ""
+}
+
+// subAccountBudget converts a requested budget, returning a message when it
+// is unacceptable. A missing or null budget means no cap.
+func subAccountBudget(amount *usdc.DecimalAmount) (*usdc.MicroUSDC, string) {
+	if amount == nil {
+		return nil, ""
+	}
+	if amount.Legacy {
+		return nil, "budget_usdc must be a decimal string, e.g. \"50.00\""
+	}
+	if amount.Micro < 0 {
+		return nil, "budget_usdc must not be negative"
+	}
+	budget := amount.Micro
+	return &budget, ""
+}
+
+// subAccountError answers a failed sub-account operation
+func subAccountError(c fiber.Ctx, err error, msg string) error {
+	switch {
+	case errors.Is(err, db.ErrSubAccountNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sub-account not found"})
+	case errors.Is(err, db.ErrSubAccountNameTaken):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A sub-account with this name already exists"})
+	case errors.Is(err, db.ErrSubAccountLimitReached):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Maximum of %d sub-accounts per account", maxSubAccountsPerAccount),
+		})
+	}
+	slog.Error("sub-account operation failed", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSubAccountName(t *testing.T) {
+	assert.Empty(t, validateSubAccountName("research-agent"))
+	assert.Empty(t, validateSubAccountName(strings.Repeat("é", maxSubAccountNameLength)))
+	assert.NotEmpty(t, validateSubAccountName(""))
+	assert.NotEmpty(t, validateSubAccountName(strings.Repeat("a", maxSubAccountNameLength+1)))
+}
+
+func TestSubAccountBudget(t *testing.T) {
+	budget := func(m usdc.MicroUSDC) *usdc.MicroUSDC { return &m }
+	tests := []struct {
+		name string
+		body string
+		want *usdc.MicroUSDC
+		ok   bool
+	}{
+		{"omitted", `{}`, nil, true},
+		{"null", `{"budget_usdc": null}`, nil, true},
+		{"decimal", `{"budget_usdc": "12.50"}`, budget(12_500_000), true},
+		{"zero", `{"budget_usdc": "0"}`, budget(0), true},
+		{"number", `{"budget_usdc": 12.5}`, nil, false},
+		{"negative", `{"budget_usdc": "-1.00"}`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req SetSubAccountBudgetRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				assert.False(t, tt.ok, "unexpected error: %v", err)
+				return
+			}
+			got, msg := subAccountBudget(req.BudgetUSDC)
+			assert.Equal(t, tt.ok, msg == "", msg)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSubAccountResponse_JSON(t *testing.T) {
+	budget := usdc.MicroUSDC(5_000_000)
+	sub := &db.SubAccount{ID: uuid.New(), Name: "Agent", BudgetUSDC: &budget, SpentUSDC: 6_000_000}
+
+	out, err := json.Marshal(newSubAccountResponse(sub))
+	require.NoError(t, err)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(out, &got))
+	assert.Equal(t, "Agent", got["name"])
+	assert.Equal(t, "5000000", got["budget_usdc"])
+	assert.Equal(t, "0", got["remaining_usdc"], "overspent budgets have nothing left")
+
+	out, err = json.Marshal(newSubAccountResponse(&db.SubAccount{ID: uuid.New(), Name: "Open"}))
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "remaining_usdc")
+}
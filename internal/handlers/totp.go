@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base32"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"stronghold/internal/db"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -17,6 +22,9 @@ import (
 const (
 	deviceTokenCookie = "stronghold_device"
 	deviceTokenHeader = "X-Stronghold-Device"
+
+	maxDeviceNameLength     = 64
+	maxDeviceMetadataLength = 128
 )
 
 // TOTPSetupResponse contains enrollment details.
@@ -28,10 +36,12 @@ type TOTPSetupResponse struct {
 
 // TOTPVerifyRequest verifies a TOTP or recovery code and trusts the device.
 type TOTPVerifyRequest struct {
-	Code          string `json:"code,omitempty"`
-	RecoveryCode  string `json:"recovery_code,omitempty"`
-	DeviceLabel   string `json:"device_label,omitempty"`
-	DeviceTTLDays int    `json:"device_ttl_days,omitempty"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+	DeviceLabel    string `json:"device_label,omitempty"`
+	DeviceTTLDays  int    `json:"device_ttl_days,omitempty"`
+	DeviceOS       string `json:"device_os,omitempty"`
+	DeviceHostname string `json:"device_hostname,omitempty"`
 }
 
 // TOTPVerifyResponse returns the trusted device token.
@@ -41,6 +51,12 @@ type TOTPVerifyResponse struct {
 	RecoveryCodeUsed bool       `json:"recovery_code_used"`
 }
 
+// DeviceRenameRequest names a trusted device. An empty name clears it.
+type DeviceRenameRequest struct {
+	DeviceID string `json:"device_id"`
+	Name     string `json:"name"`
+}
+
 // DeviceRevokeRequest revokes device trust.
 type DeviceRevokeRequest struct {
 	DeviceID string `json:"device_id,omitempty"`
@@ -132,48 +148,12 @@ func (h *AuthHandler) VerifyTOTP(c fiber.Ctx) error {
 	}
 	req.Code = strings.TrimSpace(req.Code)
 	req.RecoveryCode = strings.TrimSpace(req.RecoveryCode)
-	if req.Code == "" && req.RecoveryCode == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "TOTP code or recovery code is required",
-		})
-	}
 
 	ctx := c.Context()
-	encrypted, err := h.db.GetTOTPSecret(ctx, accountID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "TOTP not configured",
-		})
-	}
-
-	secret, err := h.kmsClient.Decrypt(ctx, encrypted)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to decrypt TOTP secret",
-		})
-	}
-
-	recoveryUsed := false
-	if req.RecoveryCode != "" {
-		ok, err := h.db.UseRecoveryCode(ctx, accountID, normalizeRecoveryCode(req.RecoveryCode))
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to verify recovery code",
-			})
-		}
-		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid recovery code",
-			})
-		}
-		recoveryUsed = true
-	} else {
-		if !totp.Validate(req.Code, secret) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid TOTP code",
-			})
-		}
+	if status, msg := h.checkSecondFactor(ctx, accountID, req.Code, req.RecoveryCode); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
 	}
+	recoveryUsed := req.RecoveryCode != ""
 
 	// Enable TOTP on successful verification.
 	if err := h.db.SetTOTPEnabled(ctx, accountID, true); err != nil {
@@ -201,7 +181,8 @@ func (h *AuthHandler) VerifyTOTP(c fiber.Ctx) error {
 		label = defaultDeviceLabel(c)
 	}
 
-	if _, err := h.db.CreateDeviceToken(ctx, accountID, deviceToken, label, expiresAt); err != nil {
+	meta := deviceMetadata(req.DeviceOS, req.DeviceHostname)
+	if _, err := h.db.CreateDeviceToken(ctx, accountID, deviceToken, label, meta, expiresAt); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to trust device",
 		})
@@ -223,7 +204,17 @@ func (h *AuthHandler) ListDevices(c fiber.Ctx) error {
 		return err
 	}
 
-	devices, err := h.db.ListDevices(c.Context(), accountID)
+	// Mark the device making the request so it can be told apart
+	ctx := c.Context()
+	var currentID *uuid.UUID
+	if token := getDeviceToken(c); token != "" {
+		if device, err := h.db.GetDeviceByToken(ctx, accountID, token); err == nil {
+			currentID = &device.ID
+			_ = h.db.TouchDevice(ctx, accountID, token, c.IP())
+		}
+	}
+
+	devices, err := h.db.ListDevices(ctx, accountID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to list devices",
@@ -231,10 +222,51 @@ func (h *AuthHandler) ListDevices(c fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"devices": devices,
+		"devices":           devices,
+		"current_device_id": currentID,
 	})
 }
 
+// RenameDevice sets the user-assigned name of a trusted device.
+func (h *AuthHandler) RenameDevice(c fiber.Ctx) error {
+	accountID, err := h.requireAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeviceRenameRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	deviceID, err := uuid.Parse(req.DeviceID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid device_id",
+		})
+	}
+	name := strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(name) > maxDeviceNameLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("name must be at most %d characters", maxDeviceNameLength),
+		})
+	}
+
+	if err := h.db.RenameDevice(c.Context(), accountID, deviceID, name); err != nil {
+		if errors.Is(err, db.ErrDeviceNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Device not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to rename device",
+		})
+	}
+	return c.JSON(fiber.Map{"renamed": req.DeviceID, "name": name})
+}
+
 // RevokeDevice revokes a single device or all devices.
 func (h *AuthHandler) RevokeDevice(c fiber.Ctx) error {
 	accountID, err := h.requireAccountID(c)
@@ -278,6 +310,43 @@ func (h *AuthHandler) RevokeDevice(c fiber.Ctx) error {
 	return c.JSON(fiber.Map{"revoked": req.DeviceID})
 }
 
+// checkSecondFactor verifies a TOTP code, or consumes a recovery code when
+// one is given, for accountID. It returns the status and message refusing
+// the request, or 0.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, accountID uuid.UUID, code, recoveryCode string) (int, string) {
+	if code == "" && recoveryCode == "" {
+		return fiber.StatusBadRequest, "TOTP code or recovery code is required"
+	}
+	if h.kmsClient == nil {
+		return fiber.StatusNotFound, "KMS not configured"
+	}
+
+	encrypted, err := h.db.GetTOTPSecret(ctx, accountID)
+	if err != nil {
+		return fiber.StatusBadRequest, "TOTP not configured"
+	}
+	secret, err := h.kmsClient.DecryptSecret(ctx, encrypted)
+	if err != nil {
+		return fiber.StatusInternalServerError, "Failed to decrypt TOTP secret"
+	}
+	defer secret.Zero()
+
+	if recoveryCode != "" {
+		ok, err := h.db.UseRecoveryCode(ctx, accountID, normalizeRecoveryCode(recoveryCode))
+		if err != nil {
+			return fiber.StatusInternalServerError, "Failed to verify recovery code"
+		}
+		if !ok {
+			return fiber.StatusUnauthorized, "Invalid recovery code"
+		}
+		return 0, ""
+	}
+	if !totp.Validate(code, secret.Reveal()) {
+		return fiber.StatusUnauthorized, "Invalid TOTP code"
+	}
+	return 0, ""
+}
+
 // RequireTrustedDevice enforces device trust when wallet escrow is enabled.
 func (h *AuthHandler) RequireTrustedDevice() fiber.Handler {
 	return func(c fiber.Ctx) error {
@@ -313,7 +382,7 @@ func (h *AuthHandler) RequireTrustedDevice() fiber.Handler {
 			})
 		}
 
-		_ = h.db.TouchDevice(ctx, accountID, deviceToken)
+		_ = h.db.TouchDevice(ctx, accountID, deviceToken, c.IP())
 		return c.Next()
 	}
 }
@@ -414,6 +483,21 @@ func normalizeRecoveryCodes(codes []string) []string {
 	return out
 }
 
+// deviceMetadata trims client-reported machine details and caps their length
+func deviceMetadata(os, hostname string) db.DeviceMetadata {
+	return db.DeviceMetadata{
+		OS:       truncateRunes(strings.TrimSpace(os), maxDeviceMetadataLength),
+		Hostname: truncateRunes(strings.TrimSpace(hostname), maxDeviceMetadataLength),
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 func defaultDeviceLabel(c fiber.Ctx) string {
 	ua := strings.TrimSpace(string(c.Request().Header.UserAgent()))
 	if ua == "" {
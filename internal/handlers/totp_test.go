@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevices_MetadataAndRename(t *testing.T) {
+	app, handler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	accountNumber, accessToken := createTestAccount(t, app)
+	account, err := handler.db.GetAccountByNumber(ctx, accountNumber)
+	require.NoError(t, err)
+
+	laptopToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	laptop, err := handler.db.CreateDeviceToken(ctx, account.ID, laptopToken, "stronghold-cli",
+		db.DeviceMetadata{OS: "darwin", Hostname: "work-laptop"}, nil)
+	require.NoError(t, err)
+	serverToken, err := generateDeviceToken()
+	require.NoError(t, err)
+	_, err = handler.db.CreateDeviceToken(ctx, account.ID, serverToken, "stronghold-cli",
+		db.DeviceMetadata{OS: "linux", Hostname: "build-01"}, nil)
+	require.NoError(t, err)
+
+	call := func(method, path, body string, out interface{}) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		req.Header.Set(deviceTokenHeader, laptopToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	rename := `{"device_id":"` + laptop.ID.String() + `","name":"Work laptop"}`
+	require.Equal(t, fiber.StatusOK, call("POST", "/v1/auth/devices/rename", rename, nil))
+	assert.Equal(t, fiber.StatusNotFound, call("POST", "/v1/auth/devices/rename",
+		`{"device_id":"00000000-0000-0000-0000-000000000000","name":"x"}`, nil))
+
+	var listed struct {
+		Devices         []db.Device `json:"devices"`
+		CurrentDeviceID string      `json:"current_device_id"`
+	}
+	require.Equal(t, fiber.StatusOK, call("GET", "/v1/auth/devices", "", &listed))
+	require.Len(t, listed.Devices, 2)
+	assert.Equal(t, laptop.ID.String(), listed.CurrentDeviceID)
+
+	for _, d := range listed.Devices {
+		require.NotNil(t, d.Hostname)
+		if d.ID != laptop.ID {
+			assert.Nil(t, d.Name)
+			assert.Nil(t, d.LastSeenAt, "only the calling device should be touched")
+			continue
+		}
+		require.NotNil(t, d.Name)
+		assert.Equal(t, "Work laptop", *d.Name)
+		assert.Equal(t, "work-laptop", *d.Hostname)
+		require.NotNil(t, d.OS)
+		assert.Equal(t, "darwin", *d.OS)
+		assert.NotNil(t, d.LastSeenAt)
+		assert.NotNil(t, d.LastSeenIP)
+	}
+}
+
+func TestDeviceMetadata_TrimsAndTruncates(t *testing.T) {
+	meta := deviceMetadata("  linux  ", strings.Repeat("é", maxDeviceMetadataLength+10))
+	assert.Equal(t, "linux", meta.OS)
+	assert.Equal(t, maxDeviceMetadataLength, utf8.RuneCountInString(meta.Hostname))
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxTransferMemoLength bounds the note recorded with a transfer
+const maxTransferMemoLength = 140
+
+// TransferHandler moves on-platform balance between accounts, for example
+// from an organization's treasury account to its members'
+type TransferHandler struct {
+	db         *db.DB
+	auth       *AuthHandler
+	dailyLimit usdc.MicroUSDC
+}
+
+// NewTransferHandler creates a new transfer handler. An account may transfer
+// out at most dailyLimit per UTC day; 0 disables transfers.
+func NewTransferHandler(database *db.DB, authHandler *AuthHandler, dailyLimit usdc.MicroUSDC) *TransferHandler {
+	return &TransferHandler{db: database, auth: authHandler, dailyLimit: dailyLimit}
+}
+
+// TransferRequest moves balance to another account. Every transfer must
+// carry a TOTP code, or a recovery code, even from a trusted device.
+type TransferRequest struct {
+	ToAccountNumber string             `json:"to_account_number"`
+	AmountUSDC      usdc.DecimalAmount `json:"amount_usdc" swaggertype:"string" example:"25.00"`
+	Memo            string             `json:"memo,omitempty"`
+	Code            string             `json:"code,omitempty"`
+	RecoveryCode    string             `json:"recovery_code,omitempty"`
+}
+
+// TransferResponse is the sender's ledger entry for a transfer and what is
+// left of its daily limit
+type TransferResponse struct {
+	Entry              *db.LedgerEntry `json:"entry"`
+	DailyLimitUSDC     usdc.MicroUSDC  `json:"daily_limit_usdc"`
+	RemainingTodayUSDC usdc.MicroUSDC  `json:"remaining_today_usdc"`
+}
+
+// RegisterRoutes registers transfer routes
+func (h *TransferHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler) {
+	group := app.Group("/v1/account")
+	group.Post("/transfer", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.Transfer)
+	group.Get("/transfers", authHandler.AuthMiddleware(), authHandler.RequireTrustedDevice(), h.GetTransfers)
+}
+
+// Transfer moves balance from the caller's account to another account
+// @Summary Transfer balance to another account
+// @Description Moves on-platform USDC balance to another account by account number and records a ledger entry on both accounts. Requires TOTP to be enabled and a current TOTP code or an unused recovery code with every transfer. An account may transfer out a limited amount per UTC day.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body TransferRequest true "Recipient, amount and TOTP code"
+// @Success 201 {object} TransferResponse
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Not authenticated or invalid code"
+// @Failure 402 {object} map[string]string "Insufficient balance"
+// @Failure 403 {object} map[string]string "TOTP not enabled"
+// @Failure 404 {object} map[string]string "Recipient not found or transfers disabled"
+// @Failure 409 {object} map[string]string "Recipient account not active"
+// @Failure 429 {object} map[string]interface{} "Daily transfer limit reached"
+// @Security CookieAuth
+// @Router /v1/account/transfer [post]
+func (h *TransferHandler) Transfer(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+	if h.dailyLimit <= 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Balance transfers are disabled",
+		})
+	}
+
+	var req TransferRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return invalidBody(c, err)
+	}
+	if status, msg := validateTransfer(&req); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	ctx := c.Context()
+	account, err := h.db.GetAccountByID(ctx, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Account not found",
+		})
+	}
+	if !account.TOTPEnabled {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Enable TOTP to transfer balance",
+		})
+	}
+	if status, msg := h.auth.checkSecondFactor(ctx, accountID, req.Code, req.RecoveryCode); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+
+	recipient, err := h.db.GetAccountByNumber(ctx, req.ToAccountNumber)
+	if errors.Is(err, db.ErrAccountNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Recipient account not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to look up recipient account",
+		})
+	}
+
+	entry, err := h.db.TransferBalance(ctx, accountID, recipient.ID, req.AmountUSDC.Micro, h.dailyLimit, req.Memo)
+	if err != nil {
+		status, msg := transferError(err)
+		resp := fiber.Map{"error": msg}
+		if errors.Is(err, db.ErrTransferLimitExceeded) {
+			resp["daily_limit_usdc"] = h.dailyLimit
+			if sent, err := h.db.TransferredToday(ctx, accountID); err == nil {
+				resp["remaining_today_usdc"] = max(h.dailyLimit-sent, 0)
+			}
+		}
+		return c.Status(status).JSON(resp)
+	}
+
+	sent, err := h.db.TransferredToday(ctx, accountID)
+	if err != nil {
+		sent = h.dailyLimit
+	}
+	return c.Status(fiber.StatusCreated).JSON(TransferResponse{
+		Entry:              entry,
+		DailyLimitUSDC:     h.dailyLimit,
+		RemainingTodayUSDC: max(h.dailyLimit-sent, 0),
+	})
+}
+
+// GetTransfersRequest pages through the account's ledger
+type GetTransfersRequest struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+// GetTransfers lists the balance transfers into and out of the account
+// @Summary List balance transfers
+// @Description Returns the account's ledger entries for transfers to and from other accounts, newest first, with the daily limit and how much of it has been used today.
+// @Tags account
+// @Produce json
+// @Param limit query int false "Number of entries to return (default 50)"
+// @Param offset query int false "Number of entries to skip (default 0)"
+// @Success 200 {object} map[string]interface{} "Ledger entries with pagination"
+// @Failure 401 {object} map[string]string "Not authenticated"
+// @Failure 500 {object} map[string]string "Server error"
+// @Security CookieAuth
+// @Router /v1/account/transfers [get]
+func (h *TransferHandler) GetTransfers(c fiber.Ctx) error {
+	accountID, err := policyAccountID(c)
+	if err != nil {
+		return err
+	}
+
+	var req GetTransfersRequest
+	if err := c.Bind().Query(&req); err != nil {
+		req.Limit = 50
+		req.Offset = 0
+	}
+
+	ctx := c.Context()
+	entries, err := h.db.GetLedgerEntries(ctx, accountID, req.Limit, req.Offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get transfers",
+		})
+	}
+	sent, err := h.db.TransferredToday(ctx, accountID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get transfers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"entries":          entries,
+		"daily_limit_usdc": h.dailyLimit,
+		"sent_today_usdc":  sent,
+		"limit":            req.Limit,
+		"offset":           req.Offset,
+	})
+}
+
+// validateTransfer normalizes req and returns the status and message
+// rejecting it, or 0
+func validateTransfer(req *TransferRequest) (int, string) {
+	req.ToAccountNumber = strings.TrimSpace(req.ToAccountNumber)
+	req.Memo = strings.TrimSpace(req.Memo)
+	req.Code = strings.TrimSpace(req.Code)
+	req.RecoveryCode = strings.TrimSpace(req.RecoveryCode)
+
+	if req.AmountUSDC.Legacy {
+		return fiber.StatusBadRequest, "amount_usdc must be a decimal string, e.g. \"10.50\""
+	}
+	if req.AmountUSDC.Micro <= 0 {
+		return fiber.StatusBadRequest, "Amount must be greater than 0"
+	}
+	if req.ToAccountNumber == "" {
+		return fiber.StatusBadRequest, "to_account_number is required"
+	}
+	if utf8.RuneCountInString(req.Memo) > maxTransferMemoLength {
+		return fiber.StatusBadRequest, fmt.Sprintf("memo must be at most %d characters", maxTransferMemoLength)
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return fiber.StatusBadRequest, "TOTP code or recovery code is required"
+	}
+	return 0, ""
+}
+
+// transferError returns the status and message for a failed transfer
+func transferError(err error) (int, string) {
+	switch {
+	case errors.Is(err, db.ErrTransferToSelf):
+		return fiber.StatusBadRequest, "Cannot transfer to your own account"
+	case errors.Is(err, db.ErrAccountNotFound):
+		return fiber.StatusNotFound, "Recipient account not found"
+	case errors.Is(err, db.ErrTransferRecipientInactive):
+		return fiber.StatusConflict, "Recipient account is not active"
+	case errors.Is(err, db.ErrTransferInsufficientBalance):
+		return fiber.StatusPaymentRequired, "Insufficient balance"
+	case errors.Is(err, db.ErrTransferLimitExceeded):
+		return fiber.StatusTooManyRequests, "Transfer would exceed the daily transfer limit"
+	default:
+		return fiber.StatusInternalServerError, "Failed to transfer balance"
+	}
+}
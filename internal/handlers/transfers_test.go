@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransfer(t *testing.T) {
+	amount := usdc.DecimalAmount{Micro: 25_000_000}
+	tests := []struct {
+		name string
+		req  TransferRequest
+		want int
+	}{
+		{"valid", TransferRequest{ToAccountNumber: "1234-5678-9012-3456", AmountUSDC: amount, Code: "123456"}, 0},
+		{"recovery code", TransferRequest{ToAccountNumber: "1234567890123456", AmountUSDC: amount, RecoveryCode: "ABCD-EFGH"}, 0},
+		{"numeric amount", TransferRequest{ToAccountNumber: "1234567890123456", AmountUSDC: usdc.DecimalAmount{Micro: 1, Legacy: true}, Code: "123456"}, fiber.StatusBadRequest},
+		{"zero amount", TransferRequest{ToAccountNumber: "1234567890123456", Code: "123456"}, fiber.StatusBadRequest},
+		{"no recipient", TransferRequest{ToAccountNumber: "  ", AmountUSDC: amount, Code: "123456"}, fiber.StatusBadRequest},
+		{"no code", TransferRequest{ToAccountNumber: "1234567890123456", AmountUSDC: amount, Code: " "}, fiber.StatusBadRequest},
+		{"long memo", TransferRequest{ToAccountNumber: "1234567890123456", AmountUSDC: amount, Code: "123456", Memo: strings.Repeat("é", maxTransferMemoLength+1)}, fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := validateTransfer(&tt.req)
+			assert.Equal(t, tt.want, status)
+		})
+	}
+}
+
+func TestTransferError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{db.ErrTransferToSelf, fiber.StatusBadRequest},
+		{db.ErrAccountNotFound, fiber.StatusNotFound},
+		{db.ErrTransferRecipientInactive, fiber.StatusConflict},
+		{db.ErrTransferInsufficientBalance, fiber.StatusPaymentRequired},
+		{fmt.Errorf("wrapped: %w", db.ErrTransferLimitExceeded), fiber.StatusTooManyRequests},
+		{errors.New("connection reset"), fiber.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		status, _ := transferError(tt.err)
+		assert.Equal(t, tt.want, status, tt.err.Error())
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Directory Sync events handled by the WorkOS webhook
+const (
+	workosEventUserCreated = "dsync.user.created"
+	workosEventUserUpdated = "dsync.user.updated"
+	workosEventUserDeleted = "dsync.user.deleted"
+)
+
+// WorkOSDirectoryHandler connects B2B organizations to Stronghold through
+// WorkOS: it resolves which organization's SSO connection a work email
+// should sign in with, and applies Directory Sync (SCIM) events so accounts
+// are provisioned and deprovisioned by the organization's identity provider.
+type WorkOSDirectoryHandler struct {
+	db           *db.DB
+	workosConfig *config.WorkOSConfig
+	client       *http.Client
+	apiBase      string
+}
+
+// NewWorkOSDirectoryHandler creates a new WorkOS directory handler
+func NewWorkOSDirectoryHandler(database *db.DB, workosConfig *config.WorkOSConfig) *WorkOSDirectoryHandler {
+	return &WorkOSDirectoryHandler{
+		db:           database,
+		workosConfig: workosConfig,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		apiBase:      workosAPIBase,
+	}
+}
+
+// RegisterRoutes registers the SSO lookup, directory webhook and member list routes
+func (h *WorkOSDirectoryHandler) RegisterRoutes(app *fiber.App, authHandler *AuthHandler, authLimiter fiber.Handler) {
+	app.Get("/v1/auth/sso/lookup", authLimiter, h.LookupSSO)
+	app.Post("/webhooks/workos", h.HandleWebhook)
+	app.Get("/v1/org/members", authHandler.AuthMiddleware(), h.ListMembers)
+}
+
+// SSOLookupResponse identifies the organization to sign in with
+type SSOLookupResponse struct {
+	OrganizationID string `json:"organization_id"`
+}
+
+// LookupSSO finds the organization whose SSO connection covers an email
+// @Summary Find SSO organization
+// @Description Resolves a work email to the WorkOS organization that owns its verified domain. Pass the organization ID to AuthKit sign-in so the user is sent straight to their identity provider.
+// @Tags auth
+// @Produce json
+// @Param email query string true "Work email address"
+// @Success 200 {object} SSOLookupResponse
+// @Failure 400 {object} map[string]string "Invalid email"
+// @Failure 404 {object} map[string]string "No organization uses SSO for this domain"
+// @Failure 503 {object} map[string]string "SSO is not configured"
+// @Router /v1/auth/sso/lookup [get]
+func (h *WorkOSDirectoryHandler) LookupSSO(c fiber.Ctx) error {
+	if h.workosConfig.APIKey == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "SSO is not configured",
+		})
+	}
+
+	email := strings.TrimSpace(c.Query("email"))
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A valid email address is required",
+		})
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	orgID, err := h.findOrganizationByDomain(c, domain)
+	if err != nil {
+		slog.Error("workos organization lookup failed", "domain", domain, "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to look up SSO organization",
+		})
+	}
+	if orgID == "" {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No organization uses single sign-on for this email domain",
+		})
+	}
+	return c.JSON(SSOLookupResponse{OrganizationID: orgID})
+}
+
+// workOSOrganizationList is the subset of the WorkOS list organizations response we need
+type workOSOrganizationList struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Domains []struct {
+			Domain string `json:"domain"`
+			State  string `json:"state"`
+		} `json:"domains"`
+	} `json:"data"`
+}
+
+// findOrganizationByDomain returns the organization that has verified domain,
+// or an empty string if there is none
+func (h *WorkOSDirectoryHandler) findOrganizationByDomain(c fiber.Ctx, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(c.Context(), http.MethodGet,
+		h.apiBase+"/organizations?domains="+url.QueryEscape(domain), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.workosConfig.APIKey)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("WorkOS API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("WorkOS API returned %d", resp.StatusCode)
+	}
+
+	var orgs workOSOrganizationList
+	if err := json.NewDecoder(resp.Body).Decode(&orgs); err != nil {
+		return "", fmt.Errorf("failed to decode WorkOS organizations: %w", err)
+	}
+	for _, org := range orgs.Data {
+		for _, d := range org.Domains {
+			// Unverified domains could be claimed by anyone
+			if strings.EqualFold(d.Domain, domain) && d.State == "verified" {
+				return org.ID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// workOSEvent is a WorkOS webhook delivery
+type workOSEvent struct {
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// workOSDirectoryUser is the subset of a Directory Sync user we need
+type workOSDirectoryUser struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id"`
+	State          string `json:"state"`
+	Email          string `json:"email"`
+	Emails         []struct {
+		Primary bool   `json:"primary"`
+		Value   string `json:"value"`
+	} `json:"emails"`
+	Role *struct {
+		Slug string `json:"slug"`
+	} `json:"role"`
+}
+
+// primaryEmail returns the user's email, falling back to the primary entry
+// of the SCIM emails list sent by older directories
+func (u *workOSDirectoryUser) primaryEmail() string {
+	if u.Email != "" {
+		return strings.ToLower(u.Email)
+	}
+	for _, e := range u.Emails {
+		if e.Primary {
+			return strings.ToLower(e.Value)
+		}
+	}
+	if len(u.Emails) > 0 {
+		return strings.ToLower(u.Emails[0].Value)
+	}
+	return ""
+}
+
+// HandleWebhook applies WorkOS Directory Sync events, verified with the
+// WorkOS-Signature header. Users created or reactivated in the organization's
+// identity provider get a B2B account; users deactivated or deleted there have
+// their account suspended, sessions ended and API keys revoked.
+func (h *WorkOSDirectoryHandler) HandleWebhook(c fiber.Ctx) error {
+	if h.workosConfig.WebhookSecret == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "WorkOS webhooks are not configured",
+		})
+	}
+
+	body := c.Body()
+	if err := verifyWorkOSSignature(c.Get("WorkOS-Signature"), body, h.workosConfig.WebhookSecret, time.Now()); err != nil {
+		slog.Warn("workos webhook signature verification failed", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid signature",
+		})
+	}
+
+	var event workOSEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event payload",
+		})
+	}
+
+	switch event.Event {
+	case workosEventUserCreated, workosEventUserUpdated, workosEventUserDeleted:
+	default:
+		slog.Debug("unhandled workos webhook event", "type", event.Event)
+		return c.JSON(fiber.Map{"received": true})
+	}
+
+	// WorkOS retries until it gets a 2xx, so the same event can arrive twice
+	claimed, err := h.db.ClaimWebhookEvent(c.Context(), event.ID, event.Event)
+	if err != nil {
+		slog.Error("failed to claim webhook event", "event_id", event.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Internal error",
+		})
+	}
+	if !claimed {
+		return c.JSON(fiber.Map{"received": true, "duplicate": true})
+	}
+
+	var user workOSDirectoryUser
+	if err := json.Unmarshal(event.Data, &user); err != nil || user.ID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid directory user",
+		})
+	}
+
+	if event.Event == workosEventUserDeleted || user.State == "inactive" {
+		err = h.deprovision(c, &user)
+	} else {
+		err = h.provision(c, &user)
+	}
+	if err != nil {
+		// Let WorkOS retry the delivery
+		if unclaimErr := h.db.UnclaimWebhookEvent(c.Context(), event.ID); unclaimErr != nil {
+			slog.Error("failed to unclaim webhook event", "event_id", event.ID, "error", unclaimErr)
+		}
+		slog.Error("failed to apply directory event", "event_id", event.ID, "type", event.Event,
+			"directory_user_id", user.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to apply event",
+		})
+	}
+	return c.JSON(fiber.Map{"received": true})
+}
+
+func (h *WorkOSDirectoryHandler) provision(c fiber.Ctx, user *workOSDirectoryUser) error {
+	email := user.primaryEmail()
+	if email == "" {
+		slog.Warn("directory user has no email, skipping", "directory_user_id", user.ID)
+		return nil
+	}
+	role := ""
+	if user.Role != nil {
+		role = user.Role.Slug
+	}
+
+	directoryUser := db.DirectoryUser{
+		DirectoryUserID: user.ID,
+		OrganizationID:  user.OrganizationID,
+		Email:           email,
+		Role:            role,
+	}
+	accountID, created, err := h.db.ProvisionDirectoryUser(c.Context(), directoryUser)
+	if errors.Is(err, db.ErrDirectoryLinkUnverified) {
+		// Only the organization that verified the email's domain may take
+		// over an existing account with that email
+		orgID, lookupErr := h.findOrganizationByDomain(c, email[strings.LastIndex(email, "@")+1:])
+		if lookupErr != nil {
+			return lookupErr
+		}
+		if orgID == "" || orgID != user.OrganizationID {
+			slog.Warn("directory user email domain is not verified for its organization, not linking existing account",
+				"directory_user_id", user.ID, "organization_id", user.OrganizationID)
+			return nil
+		}
+		directoryUser.DomainVerified = true
+		accountID, created, err = h.db.ProvisionDirectoryUser(c.Context(), directoryUser)
+	}
+	if errors.Is(err, db.ErrDirectoryLinkRefused) {
+		slog.Warn("directory user email belongs to an account in another organization, not linking",
+			"directory_user_id", user.ID, "organization_id", user.OrganizationID)
+		return nil
+	}
+	if errors.Is(err, db.ErrEmailAlreadyExists) {
+		// Retrying won't help; an operator has to resolve the clash
+		slog.Warn("directory user email belongs to another account",
+			"directory_user_id", user.ID, "organization_id", user.OrganizationID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("directory user provisioned", "account_id", accountID, "directory_user_id", user.ID,
+		"organization_id", user.OrganizationID, "role", role, "created", created)
+	return nil
+}
+
+func (h *WorkOSDirectoryHandler) deprovision(c fiber.Ctx, user *workOSDirectoryUser) error {
+	accountID, err := h.db.DeprovisionDirectoryUser(c.Context(), user.ID)
+	if errors.Is(err, db.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("directory user deprovisioned", "account_id", accountID, "directory_user_id", user.ID,
+		"organization_id", user.OrganizationID)
+	return nil
+}
+
+// verifyWorkOSSignature checks a WorkOS-Signature header of the form
+// "t=<unix ms>, v1=<hex hmac>", where the HMAC-SHA256 covers "<t>.<body>"
+func verifyWorkOSSignature(header string, body []byte, secret string, now time.Time) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid signature timestamp")
+	}
+	if age := now.Sub(time.UnixMilli(ms)); age > webhookTimestampTolerance || age < -webhookTimestampTolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// ListMembers lists the accounts in the caller's organization
+// @Summary List organization members
+// @Description Lists the B2B accounts in the caller's WorkOS organization with their role and status, including accounts provisioned or deprovisioned by the organization's directory. Requires the admin role.
+// @Tags policy
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string "Not an organization admin"
+// @Security BearerAuth
+// @Router /v1/org/members [get]
+func (h *WorkOSDirectoryHandler) ListMembers(c fiber.Ctx) error {
+	orgID, err := requireOrgAdmin(c)
+	if err != nil {
+		return err
+	}
+
+	members, err := h.db.ListOrganizationMembers(c.Context(), orgID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list organization members",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"organization_id": orgID,
+		"members":         members,
+	})
+}
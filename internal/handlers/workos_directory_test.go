@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWorkOSWebhookSecret = "whsec_test"
+
+func signWorkOSPayload(body []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s, v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifyWorkOSSignature(t *testing.T) {
+	body := []byte(`{"id":"event_01","event":"dsync.user.created"}`)
+	now := time.Now()
+
+	assert.NoError(t, verifyWorkOSSignature(signWorkOSPayload(body, "secret", now), body, "secret", now))
+	assert.Error(t, verifyWorkOSSignature(signWorkOSPayload(body, "other", now), body, "secret", now))
+	assert.Error(t, verifyWorkOSSignature(signWorkOSPayload(body, "secret", now), []byte(`{}`), "secret", now))
+	assert.Error(t, verifyWorkOSSignature(signWorkOSPayload(body, "secret", now.Add(-10*time.Minute)), body, "secret", now))
+	assert.Error(t, verifyWorkOSSignature("", body, "secret", now))
+	assert.Error(t, verifyWorkOSSignature("t=abc, v1=00", body, "secret", now))
+}
+
+func TestLookupSSO(t *testing.T) {
+	workos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		switch r.URL.Query().Get("domains") {
+		case "example.com":
+			fmt.Fprint(w, `{"data":[{"id":"org_01","domains":[{"domain":"example.com","state":"verified"}]}]}`)
+		case "pending.com":
+			fmt.Fprint(w, `{"data":[{"id":"org_02","domains":[{"domain":"pending.com","state":"pending"}]}]}`)
+		default:
+			fmt.Fprint(w, `{"data":[]}`)
+		}
+	}))
+	defer workos.Close()
+
+	h := NewWorkOSDirectoryHandler(nil, &config.WorkOSConfig{APIKey: "sk_test"})
+	h.apiBase = workos.URL
+	app := fiber.New()
+	app.Get("/v1/auth/sso/lookup", h.LookupSSO)
+
+	lookup := func(email string) (int, SSOLookupResponse) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/v1/auth/sso/lookup?email="+email, nil))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out SSOLookupResponse
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, out := lookup("alice@Example.com")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "org_01", out.OrganizationID)
+
+	status, _ = lookup("bob@pending.com")
+	assert.Equal(t, fiber.StatusNotFound, status, "unverified domains must not route to SSO")
+	status, _ = lookup("carol@unknown.com")
+	assert.Equal(t, fiber.StatusNotFound, status)
+	status, _ = lookup("not-an-email")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestWorkOSWebhook_DirectorySync(t *testing.T) {
+	_, authHandler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	h := NewWorkOSDirectoryHandler(authHandler.db, &config.WorkOSConfig{WebhookSecret: testWorkOSWebhookSecret})
+	app := fiber.New()
+	app.Post("/webhooks/workos", h.HandleWebhook)
+
+	send := func(id, event, state string) int {
+		body, err := json.Marshal(map[string]interface{}{
+			"id":    id,
+			"event": event,
+			"data": map[string]interface{}{
+				"id":              "directory_user_01",
+				"organization_id": "org_01",
+				"state":           state,
+				"emails":          []map[string]interface{}{{"primary": true, "value": "Dana@Example.com"}},
+				"role":            map[string]string{"slug": "admin"},
+			},
+		})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/webhooks/workos", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("WorkOS-Signature", signWorkOSPayload(body, testWorkOSWebhookSecret, time.Now()))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, fiber.StatusOK, send("event_01", workosEventUserCreated, "active"))
+	account, err := authHandler.db.GetAccountByEmail(ctx, "dana@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, db.AccountTypeB2B, account.AccountType)
+	assert.Equal(t, db.AccountStatusActive, account.Status)
+
+	members, err := authHandler.db.ListOrganizationMembers(ctx, "org_01")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "admin", *members[0].Role)
+
+	// Deactivation in the identity provider suspends the account
+	require.Equal(t, fiber.StatusOK, send("event_02", workosEventUserUpdated, "inactive"))
+	account, err = authHandler.db.GetAccountByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.AccountStatusSuspended, account.Status)
+
+	// Replays are acknowledged without being applied again
+	require.Equal(t, fiber.StatusOK, send("event_01", workosEventUserCreated, "active"))
+	account, err = authHandler.db.GetAccountByID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.AccountStatusSuspended, account.Status)
+
+	// Unsigned deliveries are rejected
+	req := httptest.NewRequest("POST", "/webhooks/workos", bytes.NewBufferString(`{"id":"event_03"}`))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestWorkOSWebhook_DirectorySyncTwoOrganizations(t *testing.T) {
+	_, authHandler, testDB := setupAuthTest(t)
+	defer testDB.Close(t)
+	ctx := context.Background()
+
+	// org_01 has verified example.com; org_02 has not
+	workos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("domains") == "example.com" {
+			fmt.Fprint(w, `{"data":[{"id":"org_01","domains":[{"domain":"example.com","state":"verified"}]}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer workos.Close()
+
+	h := NewWorkOSDirectoryHandler(authHandler.db, &config.WorkOSConfig{APIKey: "sk_test", WebhookSecret: testWorkOSWebhookSecret})
+	h.apiBase = workos.URL
+	app := fiber.New()
+	app.Post("/webhooks/workos", h.HandleWebhook)
+
+	send := func(id, event, directoryUserID, orgID string) int {
+		body, err := json.Marshal(map[string]interface{}{
+			"id":    id,
+			"event": event,
+			"data": map[string]interface{}{
+				"id":              directoryUserID,
+				"organization_id": orgID,
+				"state":           "active",
+				"email":           "erin@example.com",
+			},
+		})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/webhooks/workos", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("WorkOS-Signature", signWorkOSPayload(body, testWorkOSWebhookSecret, time.Now()))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	existing, err := authHandler.db.CreateB2BAccount(ctx, "user_01", "erin@example.com", "Example")
+	require.NoError(t, err)
+
+	// org_02 can't take over the account by pushing a user with its email
+	require.Equal(t, fiber.StatusOK, send("event_01", workosEventUserCreated, "directory_user_evil", "org_02"))
+	members, err := authHandler.db.ListOrganizationMembers(ctx, "org_02")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	require.Equal(t, fiber.StatusOK, send("event_02", workosEventUserDeleted, "directory_user_evil", "org_02"))
+	account, err := authHandler.db.GetAccountByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.AccountStatusActive, account.Status)
+
+	// org_01 verified the domain, so it links the account
+	require.Equal(t, fiber.StatusOK, send("event_03", workosEventUserCreated, "directory_user_01", "org_01"))
+	members, err = authHandler.db.ListOrganizationMembers(ctx, "org_01")
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, existing.ID, members[0].AccountID)
+	assert.True(t, members[0].DirectoryManaged)
+}
@@ -0,0 +1,166 @@
+// Package hashchain makes append-only logs tamper-evident. Each record
+// carries the hash of the record before it, so editing, reordering, or
+// deleting a record breaks every hash that follows.
+//
+// JSON-lines logs are sealed by appending "prev_hash" and "hash" fields to
+// each object. The hash covers the exact bytes written, so verification does
+// not depend on how the record is later parsed.
+package hashchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Genesis is the previous hash of the first record in a chain
+const Genesis = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// maxLine bounds a single log record during verification
+const maxLine = 4 * 1024 * 1024
+
+// hashSuffix matches the hash field Seal appends to a record
+var hashSuffix = regexp.MustCompile(`,"hash":"([0-9a-f]{64})"}$`)
+
+// Next returns the hash of a record that follows prev
+func Next(prev string, record []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Seal links a JSON object to prev. It returns the line to write (without a
+// trailing newline) and the record's hash, which is the next record's prev.
+func Seal(prev string, object []byte) ([]byte, string, error) {
+	object = bytes.TrimSpace(object)
+	if len(object) < 2 || object[0] != '{' || object[len(object)-1] != '}' {
+		return nil, "", errors.New("record is not a JSON object")
+	}
+
+	var line bytes.Buffer
+	line.Write(object[:len(object)-1])
+	if len(object) > 2 {
+		line.WriteByte(',')
+	}
+	fmt.Fprintf(&line, `"prev_hash":%q}`, prev)
+
+	hash := Next(prev, line.Bytes())
+	line.Truncate(line.Len() - 1)
+	fmt.Fprintf(&line, `,"hash":%q}`, hash)
+	return line.Bytes(), hash, nil
+}
+
+// Result summarizes a verified log
+type Result struct {
+	Start    string // Previous hash of the first record; Genesis unless earlier records were rotated away
+	Records  int    // Chained records verified
+	Unsealed int    // Records written before chaining was enabled
+	Head     string // Hash of the last record; record it elsewhere to detect truncation
+}
+
+// BreakError reports the first record where the chain does not hold
+type BreakError struct {
+	Line   int
+	Reason string
+}
+
+func (e *BreakError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verify checks a JSON-lines log written with Seal. Unsealed records are
+// accepted only before the first sealed one, as left by an older version.
+// A *BreakError is returned for the first record that fails.
+func Verify(r io.Reader) (*Result, error) {
+	return VerifyFrom(r, Genesis)
+}
+
+// VerifyFrom checks a log that continues a chain whose head was prev, such
+// as the next file of a rotated log. An empty prev accepts whatever the
+// first record links to, for a log whose earlier files were deleted. Only a
+// chain starting at Genesis may begin with unsealed records.
+func VerifyFrom(r io.Reader, prev string) (*Result, error) {
+	res := &Result{Start: prev, Head: prev}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		m := hashSuffix.FindSubmatchIndex(raw)
+		if m == nil {
+			if res.Records > 0 || prev != Genesis && prev != "" {
+				return res, &BreakError{Line: line, Reason: "record is not sealed"}
+			}
+			res.Unsealed++
+			continue
+		}
+		hash := string(raw[m[2]:m[3]])
+		body := append(append([]byte(nil), raw[:m[0]]...), '}')
+
+		var fields struct {
+			PrevHash *string `json:"prev_hash"`
+		}
+		if err := json.Unmarshal(body, &fields); err != nil || fields.PrevHash == nil {
+			return res, &BreakError{Line: line, Reason: "record is malformed"}
+		}
+		if res.Head == "" {
+			res.Start, res.Head = *fields.PrevHash, *fields.PrevHash
+		}
+		if *fields.PrevHash != res.Head {
+			return res, &BreakError{Line: line, Reason: "previous hash does not match; a record was removed, reordered, or inserted"}
+		}
+		if Next(res.Head, body) != hash {
+			return res, &BreakError{Line: line, Reason: "hash does not match; the record was modified"}
+		}
+		res.Head = hash
+		res.Records++
+	}
+	if err := scanner.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Head returns the hash of the last sealed record in a JSON-lines log, or
+// Genesis if it has none, so a writer can continue the chain after restart
+func Head(r io.ReadSeeker) (string, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+
+	// Read back far enough to hold the last complete line
+	for window := int64(64 * 1024); ; window *= 4 {
+		start := max(size-window, 0)
+		if _, err := r.Seek(start, io.SeekStart); err != nil {
+			return "", err
+		}
+		buf, err := io.ReadAll(io.LimitReader(r, size-start))
+		if err != nil {
+			return "", err
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		i := bytes.LastIndexByte(buf, '\n')
+		if i < 0 && start > 0 && window < maxLine {
+			continue
+		}
+		last := buf[i+1:]
+		if m := hashSuffix.FindSubmatch(last); m != nil {
+			return string(m[1]), nil
+		}
+		return Genesis, nil
+	}
+}
@@ -0,0 +1,120 @@
+package hashchain
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sealLines builds a chained log from JSON objects
+func sealLines(t *testing.T, objects ...string) ([]string, string) {
+	t.Helper()
+	head := Genesis
+	var lines []string
+	for _, obj := range objects {
+		line, hash, err := Seal(head, []byte(obj))
+		if err != nil {
+			t.Fatalf("Seal(%s): %v", obj, err)
+		}
+		lines = append(lines, string(line))
+		head = hash
+	}
+	return lines, head
+}
+
+func TestSealAndVerify(t *testing.T) {
+	lines, head := sealLines(t, `{"a":1}`, `{"b":"two"}`, `{}`)
+
+	res, err := Verify(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if res.Records != 3 || res.Head != head || res.Unsealed != 0 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if !strings.HasPrefix(lines[0], `{"a":1,"prev_hash":"`+Genesis+`","hash":"`) {
+		t.Errorf("unexpected sealed line %s", lines[0])
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	lines, _ := sealLines(t, `{"n":1}`, `{"n":2}`, `{"n":3}`)
+
+	tests := []struct {
+		name  string
+		lines []string
+		line  int
+	}{
+		{"modified", []string{lines[0], strings.Replace(lines[1], `"n":2`, `"n":9`, 1), lines[2]}, 2},
+		{"deleted", []string{lines[0], lines[2]}, 2},
+		{"reordered", []string{lines[1], lines[0], lines[2]}, 1},
+		{"unsealed insert", []string{lines[0], `{"n":1.5}`, lines[1]}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n")))
+			var brk *BreakError
+			if !errors.As(err, &brk) || brk.Line != tt.line {
+				t.Errorf("expected break at line %d, got %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func TestVerify_AcceptsUnsealedPrefix(t *testing.T) {
+	lines, _ := sealLines(t, `{"n":1}`)
+	res, err := Verify(strings.NewReader(`{"old":true}` + "\n" + lines[0]))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if res.Unsealed != 1 || res.Records != 1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestVerifyFrom_RotatedLog(t *testing.T) {
+	lines, head := sealLines(t, `{"n":1}`, `{"n":2}`, `{"n":3}`)
+	first, second := strings.Join(lines[:2], "\n"), lines[2]
+
+	res, err := Verify(strings.NewReader(first))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	mid := res.Head
+	res, err = VerifyFrom(strings.NewReader(second), mid)
+	if err != nil || res.Records != 1 || res.Head != head {
+		t.Fatalf("expected the second file to continue the chain, got %+v, %v", res, err)
+	}
+
+	// The first file was deleted: the chain is taken from the second
+	res, err = VerifyFrom(strings.NewReader(second), "")
+	if err != nil || res.Start == Genesis || res.Head != head {
+		t.Fatalf("expected the chain to be verified from the second file, got %+v, %v", res, err)
+	}
+
+	if _, err := VerifyFrom(strings.NewReader(second), Genesis); err == nil {
+		t.Error("expected a continuation verified from Genesis to break")
+	}
+	if _, err := VerifyFrom(strings.NewReader(`{"old":true}`+"\n"+second), mid); err == nil {
+		t.Error("expected unsealed records in a continuation to break")
+	}
+}
+
+func TestHead(t *testing.T) {
+	if head, err := Head(bytes.NewReader(nil)); err != nil || head != Genesis {
+		t.Errorf("expected Genesis for an empty log, got %q, %v", head, err)
+	}
+
+	lines, want := sealLines(t, `{"n":1}`, `{"n":2}`)
+	head, err := Head(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	if err != nil || head != want {
+		t.Errorf("expected head %s, got %s (%v)", want, head, err)
+	}
+}
+
+func TestSeal_RejectsNonObjects(t *testing.T) {
+	if _, _, err := Seal(Genesis, []byte(`[1,2]`)); err == nil {
+		t.Error("expected an error for a JSON array")
+	}
+}
@@ -0,0 +1,123 @@
+// Package idempotency deduplicates retried scans. A scan sent with
+// "Idempotent-Scan: true" is keyed on its account, endpoint and body
+// checksum; a retry with the same key within the window gets the first
+// response back instead of being scanned and charged again. Responses live
+// in a Store: in memory for a single instance, or in Redis so a retry that
+// lands on another API instance is deduplicated too.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"stronghold/internal/config"
+)
+
+const (
+	// claimHold is how long a claim lasts without a response, so a request
+	// that dies mid-scan doesn't block its retries for the whole window
+	claimHold = 30 * time.Second
+	// pollInterval is how often a retry checks on the request it waits for
+	pollInterval = 25 * time.Millisecond
+)
+
+var (
+	// ErrInProgress is returned when the first request is still running after
+	// the wait for it
+	ErrInProgress = errors.New("an identical scan is still in progress")
+	// ErrStoreFull is returned when the memory store holds too many responses
+	ErrStoreFull = errors.New("too many remembered scan responses")
+)
+
+// Response is a stored scan response
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Store keeps responses until the window passes
+type Store interface {
+	// Claim returns the response stored for key. When there is none and no
+	// other request holds key, it claims key for hold and returns claimed.
+	// While another request holds key it returns neither.
+	Claim(ctx context.Context, key string, hold time.Duration) (resp *Response, claimed bool, err error)
+	// Complete stores the response of a claimed key for ttl
+	Complete(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+	// Release drops a claim that produced nothing to replay
+	Release(ctx context.Context, key string) error
+}
+
+// Dedup hands out claims on scan keys and replays completed ones
+type Dedup struct {
+	store  Store
+	window time.Duration
+	wait   time.Duration
+}
+
+// New returns a Dedup keeping responses in store, or nil when the window is
+// zero and deduplication is off
+func New(cfg *config.ScanIdempotencyConfig, store Store) *Dedup {
+	if cfg.Window <= 0 {
+		return nil
+	}
+	return &Dedup{store: store, window: cfg.Window, wait: claimHold}
+}
+
+// Key identifies a scan by the account sending it, its endpoint and a
+// SHA-256 checksum of its body
+func Key(account, method, path string, body []byte) string {
+	sum := sha256.Sum256(body)
+	h := sha256.New()
+	for _, part := range []string{account, method, path, hex.EncodeToString(sum[:])} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin returns the stored response for key, or claims key so the caller
+// runs the scan. A request arriving while an identical one runs waits for
+// its response, and gets ErrInProgress if it takes too long.
+func (d *Dedup) Begin(ctx context.Context, key string) (*Response, bool, error) {
+	deadline := time.Now().Add(d.wait)
+	for {
+		resp, claimed, err := d.store.Claim(ctx, key, claimHold)
+		if err != nil || resp != nil || claimed {
+			return resp, claimed, err
+		}
+		if time.Now().After(deadline) {
+			return nil, false, ErrInProgress
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Complete stores the response of a claimed key for the window
+func (d *Dedup) Complete(ctx context.Context, key string, resp *Response) error {
+	return d.store.Complete(ctx, key, resp, d.window)
+}
+
+// Release gives up a claimed key, so a retry runs the scan again
+func (d *Dedup) Release(ctx context.Context, key string) error {
+	return d.store.Release(ctx, key)
+}
+
+// Close closes the store if it holds a connection
+func (d *Dedup) Close() error {
+	if d == nil {
+		return nil
+	}
+	if closer, ok := d.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a memory store whose time is advanced by the test
+func fakeClock() (*MemoryStore, *time.Time) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestKey(t *testing.T) {
+	body := []byte(`{"text":"hello"}`)
+	key := Key("account-a", "POST", "/v1/scan/content", body)
+
+	assert.Equal(t, key, Key("account-a", "POST", "/v1/scan/content", []byte(`{"text":"hello"}`)))
+	assert.NotEqual(t, key, Key("account-b", "POST", "/v1/scan/content", body), "accounts never share verdicts")
+	assert.NotEqual(t, key, Key("account-a", "POST", "/v1/scan/output", body))
+	assert.NotEqual(t, key, Key("account-a", "POST", "/v1/scan/content", []byte(`{"text":"hello!"}`)))
+}
+
+func TestMemoryStore_ClaimCompleteRelease(t *testing.T) {
+	s, now := fakeClock()
+	ctx := context.Background()
+
+	resp, claimed, err := s.Claim(ctx, "k", time.Second)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Nil(t, resp)
+
+	_, claimed, _ = s.Claim(ctx, "k", time.Second)
+	assert.False(t, claimed, "a held key is not claimed twice")
+
+	require.NoError(t, s.Release(ctx, "k"))
+	_, claimed, _ = s.Claim(ctx, "k", time.Second)
+	assert.True(t, claimed, "a released key can be claimed again")
+
+	stored := &Response{Status: 200, ContentType: "application/json", Body: []byte(`{"decision":"ALLOW"}`)}
+	require.NoError(t, s.Complete(ctx, "k", stored, time.Minute))
+	require.NoError(t, s.Release(ctx, "k"), "releasing a completed key keeps its response")
+	resp, claimed, _ = s.Claim(ctx, "k", time.Second)
+	assert.False(t, claimed)
+	assert.Equal(t, stored, resp)
+
+	*now = now.Add(2 * time.Minute)
+	resp, claimed, _ = s.Claim(ctx, "k", time.Second)
+	assert.True(t, claimed, "responses expire after the window")
+	assert.Nil(t, resp)
+}
+
+func TestMemoryStore_AbandonedClaimExpires(t *testing.T) {
+	s, now := fakeClock()
+	ctx := context.Background()
+	_, claimed, _ := s.Claim(ctx, "k", claimHold)
+	require.True(t, claimed)
+
+	*now = now.Add(claimHold)
+	_, claimed, _ = s.Claim(ctx, "k", claimHold)
+	assert.True(t, claimed)
+}
+
+func TestDedup_WaitsForFirstRequest(t *testing.T) {
+	d := New(&config.ScanIdempotencyConfig{Window: time.Minute}, NewMemoryStore())
+	ctx := context.Background()
+
+	_, claimed, err := d.Begin(ctx, "k")
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	stored := &Response{Status: 200, Body: []byte("ok")}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		d.Complete(ctx, "k", stored)
+	}()
+	resp, claimed, err := d.Begin(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, stored, resp)
+
+	d.Begin(ctx, "other")
+	d.wait = 30 * time.Millisecond
+	_, _, err = d.Begin(ctx, "other")
+	assert.ErrorIs(t, err, ErrInProgress)
+
+	assert.Nil(t, New(&config.ScanIdempotencyConfig{}, NewMemoryStore()), "a zero window turns deduplication off")
+}
+
+// TestRedisStore runs against a real Redis when TEST_REDIS_URL is set
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	s, err := NewRedisStoreFromURL(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	key := Key("account-a", "POST", "/v1/scan/content", []byte(t.Name()))
+	t.Cleanup(func() { s.client.Del(ctx, s.prefix+key) })
+
+	_, claimed, err := s.Claim(ctx, key, time.Second)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	_, claimed, err = s.Claim(ctx, key, time.Second)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	stored := &Response{Status: 200, ContentType: "application/json", Body: []byte(`{"decision":"ALLOW"}`)}
+	require.NoError(t, s.Complete(ctx, key, stored, time.Minute))
+	resp, _, err := s.Claim(ctx, key, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, stored, resp)
+
+	_, err = NewRedisStoreFromURL("not a url")
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired entries are dropped from memory
+const sweepInterval = time.Minute
+
+// maxMemoryEntries bounds the claims and responses held by one instance
+const maxMemoryEntries = 100000
+
+// entry is a claim, or a completed response once resp is set
+type entry struct {
+	resp      *Response
+	expiresAt time.Time
+}
+
+// MemoryStore keeps responses in process memory. They are per instance, so
+// a retry only deduplicates when it reaches the instance that served it.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Claim returns the stored response, or claims key when there is none
+func (s *MemoryStore) Claim(_ context.Context, key string, hold time.Duration) (*Response, bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.resp, false, nil
+	}
+
+	s.sweep(now, false)
+	if len(s.entries) >= maxMemoryEntries {
+		s.sweep(now, true)
+		if len(s.entries) >= maxMemoryEntries {
+			return nil, false, ErrStoreFull
+		}
+	}
+	s.entries[key] = &entry{expiresAt: now.Add(hold)}
+	return nil, true, nil
+}
+
+// Complete stores the response of a claimed key
+func (s *MemoryStore) Complete(_ context.Context, key string, resp *Response, ttl time.Duration) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &entry{resp: resp, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Release drops a claim
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired entries, at most once per sweepInterval unless
+// forced. Callers hold s.mu.
+func (s *MemoryStore) sweep(now time.Time, force bool) {
+	if !force && now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds a single store round trip
+const redisTimeout = 250 * time.Millisecond
+
+// RedisStore keeps responses in Redis, shared by every API instance. A
+// claim is an empty value; a completed key holds the response as JSON.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store using client. Keys are prefixed with
+// "stronghold:idempotency:".
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "stronghold:idempotency:"}
+}
+
+// NewRedisStoreFromURL connects to a redis:// or rediss:// URL
+func NewRedisStoreFromURL(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return NewRedisStore(redis.NewClient(opts)), nil
+}
+
+// Claim returns the stored response, or claims key when there is none
+func (s *RedisStore) Claim(ctx context.Context, key string, hold time.Duration) (*Response, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	claimed, err := s.client.SetNX(ctx, s.prefix+key, "", hold).Result()
+	if err != nil || claimed {
+		return nil, claimed, err
+	}
+
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) || (err == nil && len(data) == 0) {
+		// Held by another request, or expired since; either way, ask again
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false, fmt.Errorf("corrupt stored response: %w", err)
+	}
+	return &resp, false, nil
+}
+
+// Complete stores the response of a claimed key
+func (s *RedisStore) Complete(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, data, ttl).Err()
+}
+
+// Release drops a claim
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
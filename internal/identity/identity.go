@@ -0,0 +1,151 @@
+// Package identity gives each proxy install its own Ed25519 keypair and signs
+// the proxy's scan requests with it. The public key is registered with the
+// account at init, so the API can attribute scans to the machine that sent
+// them and refuse requests from an install that has been revoked.
+//
+// A signature covers the method, path, a Unix timestamp and the SHA-256 of
+// the body. The API rejects timestamps outside MaxSkew; payments carry their
+// own nonces, so a replay inside the window cannot be charged twice.
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request headers carrying an install signature
+const (
+	HeaderInstall   = "X-Stronghold-Install"
+	HeaderTimestamp = "X-Stronghold-Timestamp"
+	HeaderSignature = "X-Stronghold-Signature"
+)
+
+// MaxSkew is how far a signature's timestamp may be from the server clock
+const MaxSkew = 5 * time.Minute
+
+// signaturePrefix versions the signed message format
+const signaturePrefix = "shi1"
+
+var (
+	// ErrInvalidSignature is returned for malformed or incorrectly signed requests
+	ErrInvalidSignature = errors.New("invalid install signature")
+	// ErrStale is returned for signatures whose timestamp is outside MaxSkew
+	ErrStale = errors.New("install signature timestamp out of range")
+)
+
+// Signature holds the header values for a signed request
+type Signature struct {
+	Timestamp string
+	Value     string
+}
+
+// GenerateKey creates a new install keypair
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate install key: %w", err)
+	}
+	return priv, nil
+}
+
+// EncodePublicKey returns the base64 form of pub sent at registration
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// DecodePublicKey parses a public key produced by EncodePublicKey
+func DecodePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d base64-encoded bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// Sign signs a request made at now
+func Sign(key ed25519.PrivateKey, method, path string, body []byte, now time.Time) Signature {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := ed25519.Sign(key, message(method, path, ts, body))
+	return Signature{Timestamp: ts, Value: base64.RawURLEncoding.EncodeToString(sig)}
+}
+
+// Verify checks a request signature against pub and the timestamp against now
+func Verify(pub ed25519.PublicKey, method, path string, body []byte, timestamp, signature string, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pub, message(method, path, timestamp, body), sig) {
+		return ErrInvalidSignature
+	}
+
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > MaxSkew || skew < -MaxSkew {
+		return ErrStale
+	}
+	return nil
+}
+
+// message builds the signed bytes for a request
+func message(method, path, timestamp string, body []byte) []byte {
+	digest := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		signaturePrefix, strings.ToUpper(method), path, timestamp, hex.EncodeToString(digest[:]),
+	}, "\n"))
+}
+
+// LoadKey reads a hex-encoded Ed25519 seed from path
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read install key: %w", err)
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid install key in %s", path)
+	}
+	defer clear(seed)
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// LoadOrCreateKey reads the install key from path, generating one if it does
+// not exist
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	key, err := LoadKey(path)
+	if err == nil {
+		return key, nil
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		return nil, err
+	}
+
+	key, err = GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write install key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,85 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	pub, err := DecodePublicKey(EncodePublicKey(key.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatalf("DecodePublicKey: %v", err)
+	}
+
+	now := time.Now()
+	body := []byte(`{"text":"hello"}`)
+	sig := Sign(key, "post", "/v1/scan/content", body, now)
+
+	if err := Verify(pub, "POST", "/v1/scan/content", body, sig.Timestamp, sig.Value, now); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		path string
+		body []byte
+		sig  string
+	}{
+		"other path":    {"/v1/scan/output", body, sig.Value},
+		"modified body": {"/v1/scan/content", []byte(`{"text":"hellO"}`), sig.Value},
+		"garbage":       {"/v1/scan/content", body, "not-a-signature"},
+		"empty":         {"/v1/scan/content", body, ""},
+	} {
+		err := Verify(pub, "POST", tc.path, tc.body, sig.Timestamp, tc.sig, now)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: got %v, want ErrInvalidSignature", name, err)
+		}
+	}
+
+	other, _ := GenerateKey()
+	if err := Verify(other.Public().(ed25519.PublicKey), "POST", "/v1/scan/content", body, sig.Timestamp, sig.Value, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("other key: got %v, want ErrInvalidSignature", err)
+	}
+
+	if err := Verify(pub, "POST", "/v1/scan/content", body, sig.Timestamp, sig.Value, now.Add(MaxSkew+time.Minute)); !errors.Is(err, ErrStale) {
+		t.Errorf("stale: got %v, want ErrStale", err)
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "install.key")
+
+	created, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("key file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey (existing): %v", err)
+	}
+	if !created.Equal(loaded) {
+		t.Error("reloaded key differs from created key")
+	}
+
+	if err := os.WriteFile(path, []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateKey(path); err == nil {
+		t.Error("expected error for corrupt key file, got nil")
+	}
+}
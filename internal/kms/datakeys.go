@@ -0,0 +1,191 @@
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrKeyRevoked is returned when a customer-managed key is disabled, pending
+// deletion, deleted, or no longer grants Stronghold access. Data sealed under
+// it can't be read until the customer restores access.
+var ErrKeyRevoked = errors.New("customer-managed key is revoked or inaccessible")
+
+// revokedErrorCodes are KMS error codes meaning the customer has taken the key away
+var revokedErrorCodes = map[string]bool{
+	"AccessDeniedException":    true,
+	"DisabledException":        true,
+	"KMSInvalidStateException": true,
+	"NotFoundException":        true,
+	"IncorrectKeyException":    true,
+}
+
+// Sealed is data encrypted with a one-time data key. The data key is stored
+// only wrapped by the customer's KMS key, so revoking that key makes the
+// data unreadable.
+type Sealed struct {
+	KeyARN     string
+	WrappedKey []byte
+	Ciphertext []byte // AES-256-GCM nonce followed by the sealed data
+}
+
+// dataKeyAPI is the part of the KMS client used for envelope encryption
+type dataKeyAPI interface {
+	GenerateDataKey(ctx context.Context, in *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, in *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// DataKeys seals data under customer-managed KMS keys (BYOK). Keys may live
+// in any AWS region; a client is kept per region.
+type DataKeys struct {
+	newClient func(region string) dataKeyAPI
+
+	mu      sync.Mutex
+	clients map[string]dataKeyAPI
+}
+
+// NewDataKeys creates a DataKeys using the AWS SDK's default credential chain.
+// Customers grant that principal kms:GenerateDataKey and kms:Decrypt on their key.
+func NewDataKeys(ctx context.Context) (*DataKeys, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &DataKeys{
+		newClient: func(region string) dataKeyAPI {
+			return kms.NewFromConfig(awsCfg, func(o *kms.Options) { o.Region = region })
+		},
+		clients: make(map[string]dataKeyAPI),
+	}, nil
+}
+
+// KeyRegion returns the region of a KMS key ARN
+// (arn:aws:kms:<region>:<account>:key/<id> or :alias/<name>)
+func KeyRegion(keyARN string) (string, error) {
+	parts := strings.SplitN(keyARN, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || !strings.HasPrefix(parts[1], "aws") || parts[2] != "kms" ||
+		parts[3] == "" || parts[4] == "" ||
+		!(strings.HasPrefix(parts[5], "key/") || strings.HasPrefix(parts[5], "alias/")) {
+		return "", fmt.Errorf("invalid KMS key ARN %q", keyARN)
+	}
+	return parts[3], nil
+}
+
+func (d *DataKeys) client(keyARN string) (dataKeyAPI, error) {
+	region, err := KeyRegion(keyARN)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c, ok := d.clients[region]
+	if !ok {
+		c = d.newClient(region)
+		d.clients[region] = c
+	}
+	return c, nil
+}
+
+// Seal encrypts plaintext with a new data key wrapped by keyARN. The
+// encryption context is bound to the wrapped key and must be given again to
+// Open; customers can reference it in their key policy.
+func (d *DataKeys) Seal(ctx context.Context, keyARN string, encContext map[string]string, plaintext []byte) (*Sealed, error) {
+	c, err := d.client(keyARN)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(keyARN),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: encContext,
+	})
+	if err != nil {
+		return nil, kmsError("generate data key", err)
+	}
+	defer clear(out.Plaintext)
+
+	gcm, err := newGCM(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &Sealed{
+		KeyARN:     keyARN,
+		WrappedKey: out.CiphertextBlob,
+		Ciphertext: gcm.Seal(nonce, nonce, plaintext, nil),
+	}, nil
+}
+
+// Open decrypts sealed data. It returns ErrKeyRevoked when the customer has
+// disabled, deleted or withdrawn access to the key.
+func (d *DataKeys) Open(ctx context.Context, sealed *Sealed, encContext map[string]string) ([]byte, error) {
+	c, err := d.client(sealed.KeyARN)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(sealed.KeyARN),
+		CiphertextBlob:    sealed.WrappedKey,
+		EncryptionContext: encContext,
+	})
+	if err != nil {
+		return nil, kmsError("decrypt data key", err)
+	}
+	defer clear(out.Plaintext)
+
+	gcm, err := newGCM(out.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("sealed data is too short")
+	}
+	nonce, ciphertext := sealed.Ciphertext[:gcm.NonceSize()], sealed.Ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sealed data: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Check verifies that Stronghold can seal and open data under keyARN
+func (d *DataKeys) Check(ctx context.Context, keyARN string) error {
+	probe := map[string]string{"stronghold:purpose": "key-check"}
+	sealed, err := d.Seal(ctx, keyARN, probe, []byte("ok"))
+	if err != nil {
+		return err
+	}
+	_, err = d.Open(ctx, sealed, probe)
+	return err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// kmsError wraps a KMS failure, mapping revocation to ErrKeyRevoked
+func kmsError(op string, err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && revokedErrorCodes[apiErr.ErrorCode()] {
+		return fmt.Errorf("KMS %s failed: %w: %s", op, ErrKeyRevoked, apiErr.ErrorCode())
+	}
+	return fmt.Errorf("KMS %s failed: %w", op, err)
+}
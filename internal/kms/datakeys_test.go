@@ -0,0 +1,104 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKMS wraps data keys by prefixing them with the encryption context
+type fakeKMS struct {
+	revoked bool
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, in *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	if f.revoked {
+		return nil, &smithy.GenericAPIError{Code: "DisabledException"}
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	wrapped := append([]byte(fmt.Sprint(in.EncryptionContext)), key...)
+	return &kms.GenerateDataKeyOutput{Plaintext: bytes.Clone(key), CiphertextBlob: wrapped}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	if f.revoked {
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+	}
+	prefix := []byte(fmt.Sprint(in.EncryptionContext))
+	if !bytes.HasPrefix(in.CiphertextBlob, prefix) {
+		return nil, &smithy.GenericAPIError{Code: "InvalidCiphertextException"}
+	}
+	return &kms.DecryptOutput{Plaintext: bytes.Clone(in.CiphertextBlob[len(prefix):])}, nil
+}
+
+const testKeyARN = "arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func newTestDataKeys(fake *fakeKMS) (*DataKeys, *[]string) {
+	var regions []string
+	return &DataKeys{
+		newClient: func(region string) dataKeyAPI {
+			regions = append(regions, region)
+			return fake
+		},
+		clients: make(map[string]dataKeyAPI),
+	}, &regions
+}
+
+func TestDataKeys_SealOpen(t *testing.T) {
+	fake := &fakeKMS{}
+	keys, regions := newTestDataKeys(fake)
+	ctx := context.Background()
+	encContext := map[string]string{"stronghold:account_id": "acct-1"}
+
+	sealed, err := keys.Seal(ctx, testKeyARN, encContext, []byte("scanned text"))
+	require.NoError(t, err)
+	assert.Equal(t, testKeyARN, sealed.KeyARN)
+	assert.NotContains(t, string(sealed.Ciphertext), "scanned text")
+
+	plaintext, err := keys.Open(ctx, sealed, maps.Clone(encContext))
+	require.NoError(t, err)
+	assert.Equal(t, "scanned text", string(plaintext))
+	assert.Equal(t, []string{"eu-west-1"}, *regions, "one client per key region")
+
+	_, err = keys.Open(ctx, sealed, map[string]string{"stronghold:account_id": "acct-2"})
+	assert.Error(t, err, "a different encryption context must not open the data")
+	assert.NotErrorIs(t, err, ErrKeyRevoked)
+
+	tampered := *sealed
+	tampered.Ciphertext = bytes.Clone(sealed.Ciphertext)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	_, err = keys.Open(ctx, &tampered, encContext)
+	assert.Error(t, err)
+
+	fake.revoked = true
+	_, err = keys.Open(ctx, sealed, encContext)
+	assert.True(t, errors.Is(err, ErrKeyRevoked), "got %v", err)
+	_, err = keys.Seal(ctx, testKeyARN, encContext, []byte("more"))
+	assert.ErrorIs(t, err, ErrKeyRevoked)
+	assert.ErrorIs(t, keys.Check(ctx, testKeyARN), ErrKeyRevoked)
+}
+
+func TestKeyRegion(t *testing.T) {
+	region, err := KeyRegion(testKeyARN)
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+
+	region, err = KeyRegion("arn:aws-us-gov:kms:us-gov-west-1:111122223333:alias/scan-history")
+	require.NoError(t, err)
+	assert.Equal(t, "us-gov-west-1", region)
+
+	for _, bad := range []string{"", "alias/scan-history", "1234abcd-12ab", "arn:aws:s3:::bucket", "arn:aws:kms::111122223333:key/abc"} {
+		_, err := KeyRegion(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -1,4 +1,5 @@
-// Package kms provides AWS KMS encryption for wallet private keys
+// Package kms provides AWS KMS encryption for wallet private keys and
+// customer-managed (BYOK) keys for stored scan data
 package kms
 
 import (
@@ -6,6 +7,8 @@ import (
 	"encoding/base64"
 	"fmt"
 
+	"stronghold/internal/secure"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
@@ -90,6 +93,29 @@ func (c *Client) Decrypt(ctx context.Context, encryptedKey string) (string, erro
 	return string(result.Plaintext), nil
 }
 
+// DecryptSecret decrypts like Decrypt but returns the plaintext in a
+// zeroable SecretString that never reaches an immutable string. The caller
+// must Zero it when done.
+func (c *Client) DecryptSecret(ctx context.Context, encryptedKey string) (*secure.SecretString, error) {
+	if encryptedKey == "" {
+		return nil, fmt.Errorf("encrypted key cannot be empty")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 encoding: %w", err)
+	}
+
+	result, err := c.kms.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMS decrypt failed: %w", err)
+	}
+
+	return secure.NewSecretStringFromBytes(result.Plaintext), nil
+}
+
 // KeyID returns the KMS key ID/ARN being used
 func (c *Client) KeyID() string {
 	return c.keyID
@@ -0,0 +1,265 @@
+// Package loadtest drives synthetic scan traffic at a fixed request rate and
+// reports latency percentiles, a latency histogram and the error rate, so
+// deployments can be sized before production rollout.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of one request
+type Result struct {
+	Latency  time.Duration
+	Status   int    // HTTP status, 0 if the request failed before a response
+	Decision string // Scan decision, if the response reported one
+	Err      error  // Transport errors and responses the target treats as failures
+}
+
+// Target sends one synthetic request. injection asks for a payload that
+// should be flagged rather than a benign one.
+type Target interface {
+	Do(ctx context.Context, injection bool) Result
+}
+
+// Config controls the request rate and traffic mix
+type Config struct {
+	RPS           int           // Requests started per second
+	Duration      time.Duration // How long to send traffic for
+	Concurrency   int           // Maximum requests in flight
+	InjectionRate float64       // Share of requests carrying an injection payload
+}
+
+// Budget is the error budget a run must stay within. Zero fields aren't
+// checked.
+type Budget struct {
+	MaxErrorRate float64
+	MaxP99       time.Duration
+}
+
+// Bucket counts requests that completed within UpperMs (and above the
+// previous bucket). The last bucket has no upper bound.
+type Bucket struct {
+	UpperMs int   `json:"upper_ms,omitempty"`
+	Count   int64 `json:"count"`
+}
+
+// bucketBounds are the histogram's upper bounds in milliseconds
+var bucketBounds = []int{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// Report summarizes a run
+type Report struct {
+	Requests   int64            `json:"requests"`
+	Errors     int64            `json:"errors"`
+	Dropped    int64            `json:"dropped"` // Ticks skipped because Concurrency requests were already in flight
+	ErrorRate  float64          `json:"error_rate"`
+	Elapsed    time.Duration    `json:"elapsed_ns"`
+	RPS        float64          `json:"rps"` // Achieved completion rate
+	Mean       time.Duration    `json:"mean_ns"`
+	P50        time.Duration    `json:"p50_ns"`
+	P90        time.Duration    `json:"p90_ns"`
+	P99        time.Duration    `json:"p99_ns"`
+	Max        time.Duration    `json:"max_ns"`
+	Histogram  []Bucket         `json:"histogram"`
+	Statuses   map[int]int64    `json:"statuses"`
+	Decisions  map[string]int64 `json:"decisions"`
+	ErrorTypes map[string]int64 `json:"error_types,omitempty"`
+}
+
+// Run sends traffic to target at cfg.RPS until cfg.Duration has passed or
+// ctx is done, then waits for requests in flight to finish
+func Run(ctx context.Context, cfg Config, target Target) *Report {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = cfg.RPS
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	results := make(chan Result, cfg.Concurrency)
+	var latencies []time.Duration
+	report := &Report{
+		Histogram:  make([]Bucket, len(bucketBounds)+1),
+		Statuses:   make(map[int]int64),
+		Decisions:  make(map[string]int64),
+		ErrorTypes: make(map[string]int64),
+	}
+	for i, upper := range bucketBounds {
+		report.Histogram[i].UpperMs = upper
+	}
+
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range results {
+			latencies = append(latencies, r.Latency)
+			report.add(r)
+		}
+	}()
+
+	// Slots bound the requests in flight. Ticks that find no free slot are
+	// dropped and reported rather than queued, so a saturated target shows up
+	// as a rate shortfall instead of hidden queueing delay.
+	slots := make(chan struct{}, cfg.Concurrency)
+	var wg sync.WaitGroup
+	interval := time.Second / time.Duration(max(cfg.RPS, 1))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var sent int64
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			report.Dropped++
+			continue
+		}
+		// Injections are spread evenly through the run
+		injection := int64(float64(sent+1)*cfg.InjectionRate) > int64(float64(sent)*cfg.InjectionRate)
+		sent++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// Requests started before the deadline are allowed to finish
+			results <- target.Do(context.WithoutCancel(ctx), injection)
+		}()
+	}
+	wg.Wait()
+	close(results)
+	<-collected
+
+	report.Elapsed = time.Since(start)
+	report.summarize(latencies)
+	return report
+}
+
+func (r *Report) add(res Result) {
+	r.Requests++
+	if res.Status != 0 {
+		r.Statuses[res.Status]++
+	}
+	if res.Decision != "" {
+		r.Decisions[res.Decision]++
+	}
+	if res.Err != nil {
+		r.Errors++
+		r.ErrorTypes[errorType(res)]++
+	}
+	ms := res.Latency.Milliseconds()
+	i := sort.SearchInts(bucketBounds, int(ms))
+	r.Histogram[i].Count++
+}
+
+// errorType groups errors for the report: by status when the target
+// answered, otherwise as a transport failure
+func errorType(res Result) string {
+	if res.Status != 0 {
+		return fmt.Sprintf("status %d", res.Status)
+	}
+	if errors.Is(res.Err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return "transport"
+}
+
+func (r *Report) summarize(latencies []time.Duration) {
+	if r.Elapsed > 0 {
+		r.RPS = float64(r.Requests) / r.Elapsed.Seconds()
+	}
+	if len(latencies) == 0 {
+		return
+	}
+	r.ErrorRate = float64(r.Errors) / float64(r.Requests)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	r.Mean = total / time.Duration(len(latencies))
+	r.P50 = percentile(latencies, 0.50)
+	r.P90 = percentile(latencies, 0.90)
+	r.P99 = percentile(latencies, 0.99)
+	r.Max = latencies[len(latencies)-1]
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Check returns the ways the run exceeded the budget
+func (r *Report) Check(b Budget) []string {
+	var violations []string
+	if b.MaxErrorRate > 0 && r.ErrorRate > b.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% exceeds budget of %.2f%%", r.ErrorRate*100, b.MaxErrorRate*100))
+	}
+	if b.MaxP99 > 0 && r.P99 > b.MaxP99 {
+		violations = append(violations, fmt.Sprintf("p99 latency %s exceeds budget of %s", r.P99, b.MaxP99))
+	}
+	return violations
+}
+
+// WriteText writes a human-readable report
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Requests:     %d in %s (%.1f/s)\n", r.Requests, r.Elapsed.Round(time.Millisecond), r.RPS)
+	fmt.Fprintf(w, "Errors:       %d (%.2f%%)\n", r.Errors, r.ErrorRate*100)
+	if r.Dropped > 0 {
+		fmt.Fprintf(w, "Dropped:      %d (concurrency limit reached; raise -concurrency or lower -rps)\n", r.Dropped)
+	}
+	fmt.Fprintf(w, "Latency:      mean %s  p50 %s  p90 %s  p99 %s  max %s\n",
+		round(r.Mean), round(r.P50), round(r.P90), round(r.P99), round(r.Max))
+
+	fmt.Fprintln(w, "\nHistogram:")
+	var peak int64
+	for _, b := range r.Histogram {
+		peak = max(peak, b.Count)
+	}
+	for _, b := range r.Histogram {
+		label := "> " + fmt.Sprint(bucketBounds[len(bucketBounds)-1]) + "ms"
+		if b.UpperMs > 0 {
+			label = "<= " + fmt.Sprint(b.UpperMs) + "ms"
+		}
+		bar := 0
+		if peak > 0 {
+			bar = int(b.Count * 40 / peak)
+		}
+		fmt.Fprintf(w, "  %-10s %8d %s\n", label, b.Count, strings.Repeat("#", bar))
+	}
+
+	writeCounts(w, "Decisions", r.Decisions)
+	writeCounts(w, "Errors by type", r.ErrorTypes)
+}
+
+func writeCounts(w io.Writer, title string, counts map[string]int64) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %-16s %d\n", k, counts[k])
+	}
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(100 * time.Microsecond)
+}
@@ -0,0 +1,105 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTarget struct {
+	latency    time.Duration
+	injections atomic.Int64
+	calls      atomic.Int64
+	failEvery  int64
+}
+
+func (f *fakeTarget) Do(ctx context.Context, injection bool) Result {
+	n := f.calls.Add(1)
+	if injection {
+		f.injections.Add(1)
+	}
+	time.Sleep(f.latency)
+	if f.failEvery > 0 && n%f.failEvery == 0 {
+		return Result{Latency: f.latency, Status: 503, Err: errors.New("unavailable")}
+	}
+	return Result{Latency: f.latency, Status: 200, Decision: "ALLOW"}
+}
+
+func TestRun(t *testing.T) {
+	target := &fakeTarget{latency: 2 * time.Millisecond, failEvery: 4}
+	report := Run(context.Background(), Config{
+		RPS:           200,
+		Duration:      250 * time.Millisecond,
+		InjectionRate: 0.5,
+	}, target)
+
+	require.Positive(t, report.Requests)
+	assert.Equal(t, target.calls.Load(), report.Requests)
+	assert.InDelta(t, report.Requests/2, target.injections.Load(), 1, "injections are spread through the run")
+	assert.Equal(t, report.Requests/4, report.Errors)
+	assert.InDelta(t, 0.25, report.ErrorRate, 0.02)
+	assert.Equal(t, report.Errors, report.ErrorTypes["status 503"])
+	assert.Equal(t, 2*time.Millisecond, report.P99)
+
+	var histogram int64
+	for _, b := range report.Histogram {
+		histogram += b.Count
+	}
+	assert.Equal(t, report.Requests, histogram)
+	assert.Equal(t, report.Requests, report.Histogram[0].Count, "2ms requests land in the first bucket")
+
+	assert.Len(t, report.Check(Budget{MaxErrorRate: 0.01}), 1)
+	assert.Empty(t, report.Check(Budget{MaxErrorRate: 0.5, MaxP99: time.Second}))
+
+	var out strings.Builder
+	report.WriteText(&out)
+	assert.Contains(t, out.String(), "p99")
+}
+
+func TestRunDropsTicksAtConcurrencyLimit(t *testing.T) {
+	report := Run(context.Background(), Config{
+		RPS:         200,
+		Duration:    200 * time.Millisecond,
+		Concurrency: 1,
+	}, &fakeTarget{latency: 50 * time.Millisecond})
+
+	assert.Positive(t, report.Dropped)
+	assert.LessOrEqual(t, report.Requests, int64(5))
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 0.50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 0.99))
+	assert.Equal(t, time.Millisecond, percentile(sorted[:1], 0.99))
+}
+
+func TestAPITarget(t *testing.T) {
+	var auth, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		b := make([]byte, 512)
+		n, _ := r.Body.Read(b)
+		body = string(b[:n])
+		w.Write([]byte(`{"decision":"BLOCK"}`))
+	}))
+	defer srv.Close()
+
+	target := &APITarget{URL: srv.URL + "/v1/scan/content", APIKey: "sk_test", Client: srv.Client()}
+	res := target.Do(context.Background(), true)
+	require.NoError(t, res.Err)
+	assert.Equal(t, "BLOCK", res.Decision)
+	assert.Equal(t, "Bearer sk_test", auth)
+	assert.Contains(t, body, `"source_type":"web_page"`)
+}
@@ -0,0 +1,347 @@
+// Package logrotate writes append-only log files that rotate by size and
+// age. A rotated file is renamed with the time it was rotated, optionally
+// gzipped, and deleted once it falls outside the retention limits.
+//
+// Rotated files sit next to the log as <name>.<timestamp>[.gz]; Segments
+// lists them with the live file, oldest first, so readers can treat a
+// rotated log as one stream.
+package logrotate
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeFormat names rotated files; it sorts chronologically and keeps
+// milliseconds so rotations in quick succession do not collide
+const timeFormat = "20060102T150405.000Z"
+
+// Options controls when a log rotates and how many rotated files are kept.
+// Zero values disable the corresponding limit.
+type Options struct {
+	MaxBytes   int64         // Rotate before the file would exceed this size
+	Interval   time.Duration // Rotate when a write falls in a later interval than the file's last, e.g. daily at 24h
+	MaxBackups int           // Rotated files to keep
+	MaxAge     time.Duration // Delete rotated files older than this
+	Compress   bool          // gzip rotated files
+	Perm       os.FileMode   // Mode of new log files; 0600 if unset
+}
+
+// Writer is an io.WriteCloser appending to a rotating log file. It is safe
+// for concurrent use.
+type Writer struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time // Interval the last write fell in
+
+	background sync.WaitGroup // Compression and pruning of rotated files
+}
+
+// Open opens the log at path for appending, creating it if needed, and
+// prunes rotated files outside the retention limits
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.Perm == 0 {
+		opts.Perm = 0600
+	}
+	w := &Writer{path: path, opts: opts, now: time.Now}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	w.prune()
+	return w, nil
+}
+
+// openFile opens the live log file and picks up its size and the interval
+// it was last written in
+func (w *Writer) openFile() error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, w.opts.Perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	w.period = w.intervalOf(w.now())
+	if w.size > 0 {
+		w.period = w.intervalOf(info.ModTime())
+	}
+	return nil
+}
+
+// intervalOf returns the start of the rotation interval t falls in.
+// Intervals are aligned to UTC, so 24h rotates at midnight UTC.
+func (w *Writer) intervalOf(t time.Time) time.Time {
+	if w.opts.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(w.opts.Interval)
+}
+
+// Write appends p to the log, rotating first if p would take the file past
+// MaxBytes or the rotation interval has moved on. A single write is never
+// split across files.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	period := w.intervalOf(w.now())
+	if w.size > 0 && (period.After(w.period) || w.opts.MaxBytes > 0 && w.size+int64(len(p)) > w.opts.MaxBytes) {
+		// A failed rotation keeps appending to the current file if it can
+		if err := w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+	w.period = period
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current log aside and starts a new one, whatever its size
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes the log and opens its path again, for when another process
+// has rotated the file this writer was appending to
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	w.file.Close()
+	if err := w.openFile(); err != nil {
+		w.file = nil
+		return err
+	}
+	return nil
+}
+
+// rotate renames the live file to a timestamped backup and reopens it. The
+// backup is compressed and old backups pruned in the background.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	backup := w.backupName(w.now())
+	if err := os.Rename(w.path, backup); err != nil {
+		if reopenErr := w.openFile(); reopenErr != nil {
+			w.file = nil
+			return errors.Join(err, reopenErr)
+		}
+		return fmt.Errorf("failed to rotate %s: %w", w.path, err)
+	}
+	if err := w.openFile(); err != nil {
+		w.file = nil
+		return err
+	}
+
+	w.background.Add(1)
+	go func() {
+		defer w.background.Done()
+		if w.opts.Compress {
+			compressFile(backup)
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// backupName names a file rotated at t, moving t on by a millisecond at a
+// time until the name is free so a rotation never overwrites another
+func (w *Writer) backupName(t time.Time) string {
+	for {
+		name := w.path + "." + t.UTC().Format(timeFormat)
+		_, err := os.Lstat(name)
+		_, gzErr := os.Lstat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// Close closes the log, waiting for any rotated file still being compressed
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.background.Wait()
+	return err
+}
+
+// prune deletes rotated files beyond MaxBackups or older than MaxAge
+func (w *Writer) prune() {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+	backups := Backups(w.path)
+	cutoff := w.now().Add(-w.opts.MaxAge)
+	for i, backup := range backups {
+		expired := w.opts.MaxAge > 0 && rotatedAt(backup).Before(cutoff)
+		surplus := w.opts.MaxBackups > 0 && i < len(backups)-w.opts.MaxBackups
+		if expired || surplus {
+			os.Remove(backup)
+		}
+	}
+}
+
+// compressFile gzips path to path.gz and removes the original. A failure
+// leaves the uncompressed backup in place.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	err = errors.Join(err, zw.Close(), dst.Close())
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Backups returns the rotated files of the log at path, oldest first. A
+// backup still being compressed is listed once, uncompressed.
+func Backups(path string) []string {
+	matches, _ := filepath.Glob(globEscape(path) + ".*")
+	seen := make(map[string]bool)
+	var backups []string
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".tmp") {
+			continue
+		}
+		name := strings.TrimSuffix(m, ".gz")
+		if len(name) != len(path)+1+len(timeFormat) || rotatedAt(name).IsZero() || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, err := os.Stat(name); err == nil {
+			m = name
+		}
+		backups = append(backups, m)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+	return backups
+}
+
+// Segments returns the rotated files of the log at path followed by the
+// live file, if it exists: the whole log, oldest first
+func Segments(path string) []string {
+	segments := Backups(path)
+	if _, err := os.Stat(path); err == nil {
+		segments = append(segments, path)
+	}
+	return segments
+}
+
+// OpenSegment opens a file returned by Segments for reading, decompressing
+// rotated files that were gzipped
+func OpenSegment(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !rotatedAt(path).IsZero() {
+		// Compressed since it was listed
+		path += ".gz"
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return &gzipFile{Reader: zr, file: f}, nil
+}
+
+// gzipFile closes both the decompressor and the file under it
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.file.Close())
+}
+
+// rotatedAt returns when a rotated file was rotated, from its name, or the
+// zero time if name is not a rotated file
+func rotatedAt(name string) time.Time {
+	name = strings.TrimSuffix(name, ".gz")
+	i := len(name) - len(timeFormat)
+	if i < 1 || name[i-1] != '.' {
+		return time.Time{}
+	}
+	t, err := time.Parse(timeFormat, name[i:])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// globEscape quotes the glob metacharacters in path
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package logrotate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll concatenates every segment of the log at path
+func readAll(t *testing.T, path string) string {
+	t.Helper()
+	var b strings.Builder
+	for _, segment := range Segments(path) {
+		r, err := OpenSegment(segment)
+		if err != nil {
+			t.Fatalf("OpenSegment(%s): %v", segment, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", segment, err)
+		}
+		b.Write(data)
+	}
+	return b.String()
+}
+
+// clock returns a now func the test can advance
+func clock(start time.Time) (func() time.Time, func(time.Duration)) {
+	now := start
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestWriter_RotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w, err := Open(path, Options{MaxBytes: 10, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, advance := clock(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	w.now = now
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write(%q): %v", line, err)
+		}
+		advance(time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := Backups(path)
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", backups)
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b, ".gz") {
+			t.Errorf("expected %s to be compressed", b)
+		}
+	}
+	if got := readAll(t, path); got != "first\nsecond\nthird\n" {
+		t.Errorf("segments read back %q", got)
+	}
+}
+
+func TestWriter_RotatesByInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spend.log")
+	w, err := Open(path, Options{Interval: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, advance := clock(time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC))
+	w.now = now
+	w.period = w.intervalOf(now())
+	defer w.Close()
+
+	w.Write([]byte("before midnight\n"))
+	advance(30 * time.Minute)
+	w.Write([]byte("still the same day\n"))
+	if backups := Backups(path); len(backups) != 0 {
+		t.Fatalf("expected no rotation within the day, got %v", backups)
+	}
+
+	advance(time.Hour)
+	w.Write([]byte("next day\n"))
+	backups := Backups(path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".20261018T003000.000Z") {
+		t.Fatalf("expected one rotation at the day boundary, got %v", backups)
+	}
+	live, _ := os.ReadFile(path)
+	if string(live) != "next day\n" {
+		t.Errorf("live file holds %q", live)
+	}
+}
+
+func TestWriter_PrunesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	// Rotated files left by an earlier run, one well past max_age
+	for _, stamp := range []time.Time{start.Add(-30 * 24 * time.Hour), start.Add(-2 * time.Hour), start.Add(-time.Hour)} {
+		name := path + "." + stamp.Format(timeFormat)
+		if err := os.WriteFile(name, []byte("old\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := Open(path, Options{MaxBytes: 4, MaxBackups: 2, MaxAge: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backups := Backups(path); len(backups) != 2 {
+		t.Fatalf("expected the expired file to be pruned on open, got %v", backups)
+	}
+
+	now, _ := clock(start)
+	w.now = now
+	w.Write([]byte("new\n"))
+	w.Write([]byte("new\n")) // Rotates; the oldest backup goes over max_backups
+	w.Close()
+
+	backups := Backups(path)
+	if len(backups) != 2 || !strings.HasSuffix(backups[1], start.Format(timeFormat)) {
+		t.Fatalf("expected the 2 newest rotated files, got %v", backups)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"stronghold/internal/abuse"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// AbuseGuard applies the abuse detector's response to each paid scan and
+// scores the scan once it completes
+type AbuseGuard struct {
+	detector *abuse.Detector
+}
+
+// NewAbuseGuard guards scans with d, or returns nil when d is nil because
+// abuse detection is off
+func NewAbuseGuard(d *abuse.Detector) *AbuseGuard {
+	if d == nil {
+		return nil
+	}
+	return &AbuseGuard{detector: d}
+}
+
+// Guard runs next for the caller identified by key unless it is throttled
+// or suspended, delaying it first when its responses are jittered. A
+// successful scan is then scored. account is nil for anonymous callers.
+func (g *AbuseGuard) Guard(c fiber.Ctx, key string, account *uuid.UUID, next func() error) error {
+	if g == nil {
+		return next()
+	}
+
+	action := g.detector.Admit(key)
+	switch {
+	case action.Refuse && action.Level == abuse.LevelSuspend:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":      "Scanning is suspended for this caller",
+			"request_id": GetRequestID(c),
+		})
+	case action.Refuse:
+		retryAfter := max(int(action.RetryAfter.Round(time.Second)/time.Second), 1)
+		c.Set("Retry-After", strconv.Itoa(retryAfter))
+		return rateLimitResponse(c)
+	case action.Delay > 0:
+		select {
+		case <-time.After(action.Delay):
+		case <-c.Context().Done():
+		}
+	}
+
+	if err := next(); err != nil {
+		return err
+	}
+	if status := c.Response().StatusCode(); status >= 200 && status < 300 {
+		var result struct {
+			Decision string `json:"decision"`
+		}
+		_ = json.Unmarshal(c.Response().Body(), &result)
+		g.detector.Observe(c.Context(), key, account, c.Body(), result.Decision)
+	}
+	return nil
+}
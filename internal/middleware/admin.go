@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// AdminAuth protects operator endpoints with a static bearer token. With no
+// token configured the admin API is disabled and its routes answer 404.
+func AdminAuth(token string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if token == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Not found",
+			})
+		}
+
+		authHeader := c.Get(fiber.HeaderAuthorization)
+		scheme, provided, ok := strings.Cut(authHeader, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid admin token",
+			})
+		}
+
+		c.Locals("admin", true)
+		return c.Next()
+	}
+}
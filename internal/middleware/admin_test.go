@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuth(t *testing.T) {
+	newApp := func(token string) *fiber.App {
+		app := fiber.New()
+		app.Get("/admin", AdminAuth(token), func(c fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+		return app
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{"disabled without token", "", "Bearer anything", fiber.StatusNotFound},
+		{"missing header", "secret-token", "", fiber.StatusUnauthorized},
+		{"wrong token", "secret-token", "Bearer other", fiber.StatusUnauthorized},
+		{"wrong scheme", "secret-token", "Basic secret-token", fiber.StatusUnauthorized},
+		{"valid token", "secret-token", "Bearer secret-token", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := newApp(tt.token).Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
@@ -14,6 +14,10 @@ import (
 	"github.com/gofiber/fiber/v3"
 )
 
+// SubAccountKey is the Locals key holding the *db.SubAccount an API key
+// bills to, when it belongs to one
+const SubAccountKey = "sub_account"
+
 // APIKeyMiddleware handles API key authentication for B2B accounts
 type APIKeyMiddleware struct {
 	db *db.DB
@@ -76,6 +80,20 @@ func (m *APIKeyMiddleware) Authenticate(c fiber.Ctx) (*db.Account, *db.APIKey, e
 		return nil, nil, fiber.NewError(fiber.StatusForbidden, "Account is not active")
 	}
 
+	// Sub-account keys stop working when the sub-account is closed
+	if apiKey.SubAccountID != nil {
+		sub, err := m.db.GetSubAccount(c.Context(), account.ID, *apiKey.SubAccountID)
+		if err != nil {
+			if errors.Is(err, db.ErrSubAccountNotFound) {
+				return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "Sub-account is closed")
+			}
+			slog.Error("sub-account lookup failed for API key", "sub_account_id", *apiKey.SubAccountID, "error", err)
+			return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+		}
+		c.Locals(SubAccountKey, sub)
+		c.Locals("sub_account_id", sub.ID.String())
+	}
+
 	// Store in context
 	c.Locals("account_id", account.ID.String())
 	c.Locals("api_key_id", apiKey.ID.String())
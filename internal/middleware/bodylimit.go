@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// bodyBudgetRetryAfter is the Retry-After hint, in seconds, when the in-flight
+// byte budget is exhausted
+const bodyBudgetRetryAfter = "1"
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// BodyLimiter bounds the size of request bodies and the total bytes held by
+// requests being processed at once, so neither one large request nor many
+// concurrent ones can exhaust the API's memory.
+//
+// Compressed bodies are decompressed here with the limit applied to the
+// decoded size; Fiber's own decoding in c.Body() is unbounded, so a small
+// gzip bomb would otherwise expand in full.
+type BodyLimiter struct {
+	maxBytes int64
+	budget   int64
+	inFlight atomic.Int64
+}
+
+// NewBodyLimiter creates a limiter rejecting bodies over maxBytes and
+// answering 503 while more than budget bytes are in flight. A budget of zero
+// leaves in-flight bytes unbounded.
+func NewBodyLimiter(maxBytes, budget int64) *BodyLimiter {
+	return &BodyLimiter{maxBytes: maxBytes, budget: budget}
+}
+
+// Middleware returns the handler. Oversized bodies get 413.
+func (b *BodyLimiter) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		req := c.Request()
+		if n := int64(req.Header.ContentLength()); n > b.maxBytes {
+			return bodyTooLarge(c, b.maxBytes)
+		}
+
+		body := req.Body()
+		if encoding := strings.ToLower(strings.TrimSpace(string(req.Header.ContentEncoding()))); encoding != "" && encoding != "identity" {
+			decoded, err := decodeBody(body, encoding, b.maxBytes)
+			switch {
+			case errors.Is(err, errBodyTooLarge):
+				return bodyTooLarge(c, b.maxBytes)
+			case errors.Is(err, fiber.ErrUnsupportedMediaType):
+				return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+					"error":      "Unsupported Content-Encoding, use gzip or deflate",
+					"request_id": GetRequestID(c),
+				})
+			case err != nil:
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":      "Invalid compressed body",
+					"request_id": GetRequestID(c),
+				})
+			}
+			req.SetBodyRaw(decoded)
+			req.Header.Del(fiber.HeaderContentEncoding)
+			body = decoded
+		}
+		if int64(len(body)) > b.maxBytes {
+			return bodyTooLarge(c, b.maxBytes)
+		}
+
+		size := int64(len(body))
+		if !b.reserve(size) {
+			slog.Warn("request body budget exhausted", "path", c.Path(), "bytes", size, "in_flight", b.inFlight.Load())
+			c.Set(fiber.HeaderRetryAfter, bodyBudgetRetryAfter)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":      "Service busy",
+				"message":    "Too much data is being processed, please retry shortly",
+				"request_id": GetRequestID(c),
+			})
+		}
+		defer b.inFlight.Add(-size)
+		return c.Next()
+	}
+}
+
+// InFlight returns the body bytes held by requests being processed
+func (b *BodyLimiter) InFlight() int64 {
+	return b.inFlight.Load()
+}
+
+// reserve accounts size bytes against the budget. A request is always
+// admitted when nothing else is in flight.
+func (b *BodyLimiter) reserve(size int64) bool {
+	for {
+		cur := b.inFlight.Load()
+		if b.budget > 0 && cur > 0 && cur+size > b.budget {
+			return false
+		}
+		if b.inFlight.CompareAndSwap(cur, cur+size) {
+			return true
+		}
+	}
+}
+
+// decodeBody decompresses body, failing with errBodyTooLarge as soon as the
+// output passes limit
+func decodeBody(body []byte, encoding string, limit int64) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch encoding {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fiber.ErrUnsupportedMediaType
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(decoded)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return decoded, nil
+}
+
+// bodyTooLarge returns a 413 Request Entity Too Large response
+func bodyTooLarge(c fiber.Ctx, limit int64) error {
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error":      "Request body too large",
+		"max_bytes":  limit,
+		"request_id": GetRequestID(c),
+	})
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBodyLimitApp(b *BodyLimiter, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Use(b.Middleware())
+	app.Post("/v1/scan/content", handler)
+	return app
+}
+
+func echoBody(c fiber.Ctx) error {
+	return c.Send(c.Body())
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestBodyLimiter_RejectsLargeBody(t *testing.T) {
+	app := setupBodyLimitApp(NewBodyLimiter(100, 0), echoBody)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(strings.Repeat("a", 100))))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(strings.Repeat("a", 101))))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `"max_bytes":100`)
+}
+
+func TestBodyLimiter_DecodesGzipWithinLimit(t *testing.T) {
+	app := setupBodyLimitApp(NewBodyLimiter(1024, 0), echoBody)
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewReader(gzipped(t, `{"text":"hello"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"text":"hello"}`, string(body))
+}
+
+func TestBodyLimiter_RejectsGzipBomb(t *testing.T) {
+	app := setupBodyLimitApp(NewBodyLimiter(1024, 0), echoBody)
+
+	// Compresses to well under the limit but expands far past it
+	bomb := gzipped(t, strings.Repeat("a", 10*1024*1024))
+	require.Less(t, len(bomb), 1024*1024)
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestBodyLimiter_UnsupportedEncoding(t *testing.T) {
+	app := setupBodyLimitApp(NewBodyLimiter(1024, 0), echoBody)
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader("x"))
+	req.Header.Set("Content-Encoding", "br")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestBodyLimiter_InFlightBudget(t *testing.T) {
+	b := NewBodyLimiter(100, 150)
+	var inner int
+	app := setupBodyLimitApp(b, func(c fiber.Ctx) error {
+		// A second 100 byte request arriving now would exceed the budget
+		inner = len(c.Body())
+		assert.False(t, b.reserve(100))
+		assert.True(t, b.reserve(50))
+		b.inFlight.Add(-50)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(strings.Repeat("a", 100))))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, 100, inner)
+	assert.Equal(t, int64(0), b.InFlight(), "bytes should be released after the request")
+
+	// A body larger than the budget is still admitted when nothing else is in flight
+	assert.True(t, NewBodyLimiter(100, 10).reserve(100))
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"stronghold/pkg/e2escan"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// E2EScanKey is the Fiber locals key set on end-to-end encrypted scans
+const E2EScanKey = "e2e_scan"
+
+// E2EScan opens scan requests sealed with the e2escan protocol and seals
+// their responses. It must run before anything reading the body, so
+// signature checks, deduplication and handlers see the plaintext request;
+// requests that aren't sealed pass through unchanged.
+func E2EScan(keyring *e2escan.Keyring) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !strings.HasPrefix(c.Get(fiber.HeaderContentType), e2escan.ContentType) {
+			return c.Next()
+		}
+
+		var env e2escan.Envelope
+		if err := json.Unmarshal(c.Body(), &env); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "Invalid encrypted scan envelope",
+				"request_id": GetRequestID(c),
+			})
+		}
+		body, session, err := keyring.Open(c.Method(), c.Path(), &env)
+		switch {
+		case errors.Is(err, e2escan.ErrKeyRejected):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "Scan key is expired or unknown, fetch a new one from /v1/scan/keys",
+				"code":       e2escan.CodeKeyRejected,
+				"request_id": GetRequestID(c),
+			})
+		case err != nil:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "Encrypted scan body failed to decrypt",
+				"request_id": GetRequestID(c),
+			})
+		}
+		c.Request().SetBodyRaw(body)
+		c.Request().Header.SetContentType(fiber.MIMEApplicationJSON)
+		c.Locals(E2EScanKey, true)
+
+		// Errors are rendered here rather than by the app, so their
+		// responses are sealed like any other
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		resp := c.Response()
+		sealed, err := session.SealResponse(resp.StatusCode(), resp.Body())
+		if err != nil {
+			return err
+		}
+		out, err := json.Marshal(sealed)
+		if err != nil {
+			return err
+		}
+		resp.SetBodyRaw(out)
+		c.Set(fiber.HeaderContentType, e2escan.ContentType)
+		return nil
+	}
+}
+
+// IsE2EScan reports whether the request was sent end-to-end encrypted
+func IsE2EScan(c fiber.Ctx) bool {
+	e2e, _ := c.Locals(E2EScanKey).(bool)
+	return e2e
+}
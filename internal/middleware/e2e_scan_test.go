@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/pkg/e2escan"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupE2EScanApp(t *testing.T) (*fiber.App, *e2escan.Key) {
+	t.Helper()
+	keyring := e2escan.NewKeyring([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	key, err := keyring.Issue()
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(E2EScan(keyring))
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		assert.True(t, IsE2EScan(c))
+		assert.Equal(t, fiber.MIMEApplicationJSON, c.Get(fiber.HeaderContentType))
+		return c.Status(fiber.StatusAccepted).Send(c.Body())
+	})
+	app.Post("/v1/scan/output", func(c fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusTeapot, "handler failed")
+	})
+	return app, key
+}
+
+func sealedRequest(t *testing.T, key *e2escan.Key, path, body string) (*e2escan.Session, []byte) {
+	t.Helper()
+	env, session, err := e2escan.Seal(key, "POST", path, []byte(body))
+	require.NoError(t, err)
+	out, err := json.Marshal(env)
+	require.NoError(t, err)
+	return session, out
+}
+
+func openResponse(t *testing.T, session *e2escan.Session, status int, body io.Reader) string {
+	t.Helper()
+	var env e2escan.Envelope
+	require.NoError(t, json.NewDecoder(body).Decode(&env))
+	plain, err := session.OpenResponse(status, &env)
+	require.NoError(t, err)
+	return string(plain)
+}
+
+func TestE2EScan_OpensRequestAndSealsResponse(t *testing.T) {
+	app, key := setupE2EScanApp(t)
+	session, body := sealedRequest(t, key, "/v1/scan/content", `{"text":"secret plans"}`)
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", e2escan.ContentType)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, e2escan.ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"text":"secret plans"}`, openResponse(t, session, resp.StatusCode, resp.Body))
+}
+
+func TestE2EScan_SealsHandlerErrors(t *testing.T) {
+	app, key := setupE2EScanApp(t)
+	session, body := sealedRequest(t, key, "/v1/scan/output", `{"text":"x"}`)
+
+	req := httptest.NewRequest("POST", "/v1/scan/output", bytes.NewReader(body))
+	req.Header.Set("Content-Type", e2escan.ContentType)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, fiber.StatusTeapot, resp.StatusCode)
+	assert.Equal(t, "handler failed", openResponse(t, session, resp.StatusCode, resp.Body))
+}
+
+func TestE2EScan_RejectsBadEnvelopes(t *testing.T) {
+	app, key := setupE2EScanApp(t)
+
+	// Sealed for another endpoint
+	_, body := sealedRequest(t, key, "/v1/scan/output", `{"text":"x"}`)
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", e2escan.ContentType)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Unknown key
+	req = httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(`{"key_id":"x","nonce":"","ciphertext":""}`))
+	req.Header.Set("Content-Type", e2escan.ContentType)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	out, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(out), e2escan.CodeKeyRejected)
+}
+
+func TestE2EScan_PassesPlainRequests(t *testing.T) {
+	app := fiber.New()
+	app.Use(E2EScan(e2escan.NewKeyring([]byte("0123456789abcdef0123456789abcdef"), time.Minute)))
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		assert.False(t, IsE2EScan(c))
+		return c.Send(c.Body())
+	})
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"text":"hello"}`, string(out))
+}
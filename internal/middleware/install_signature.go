@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/identity"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+)
+
+// Locals set for requests signed by a registered proxy install
+const (
+	InstallIDKey        = "install_id"
+	InstallAccountIDKey = "install_account_id"
+)
+
+// InstallSignature verifies scan requests signed by a proxy install and
+// attributes them to it. Unsigned requests pass through unchanged, so older
+// proxies and direct API callers keep working; a request that claims an
+// install must carry a valid, current signature from an install that has not
+// been revoked.
+type InstallSignature struct {
+	db  *db.DB
+	now func() time.Time
+}
+
+// NewInstallSignature creates install signature verification middleware
+func NewInstallSignature(database *db.DB) *InstallSignature {
+	return &InstallSignature{db: database, now: time.Now}
+}
+
+// Middleware returns the fiber handler
+func (m *InstallSignature) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		header := c.Get(identity.HeaderInstall)
+		if header == "" {
+			return c.Next()
+		}
+
+		installID, err := uuid.Parse(header)
+		if err != nil {
+			return installRejected(c, fiber.StatusUnauthorized, "Invalid install ID")
+		}
+
+		install, err := m.db.GetProxyInstall(c.Context(), installID)
+		if errors.Is(err, db.ErrProxyInstallNotFound) {
+			return installRejected(c, fiber.StatusUnauthorized, "Unknown install")
+		}
+		if err != nil {
+			slog.Error("install lookup failed", "install_id", installID.String(), "error", err)
+			return installRejected(c, fiber.StatusInternalServerError, "Internal server error")
+		}
+		if install.Revoked() {
+			slog.Warn("request from revoked install", "install_id", installID.String(), "account_id", install.AccountID.String())
+			return installRejected(c, fiber.StatusForbidden, "This install has been revoked. Run 'stronghold init' to register it again")
+		}
+
+		pub, err := identity.DecodePublicKey(install.PublicKey)
+		if err != nil {
+			slog.Error("stored install key is invalid", "install_id", installID.String(), "error", err)
+			return installRejected(c, fiber.StatusInternalServerError, "Internal server error")
+		}
+
+		err = identity.Verify(pub, c.Method(), c.Path(), c.Body(),
+			c.Get(identity.HeaderTimestamp), c.Get(identity.HeaderSignature), m.now())
+		if errors.Is(err, identity.ErrStale) {
+			return installRejected(c, fiber.StatusUnauthorized, "Install signature expired; check the machine's clock")
+		}
+		if err != nil {
+			return installRejected(c, fiber.StatusUnauthorized, "Invalid install signature")
+		}
+
+		c.Locals(InstallIDKey, install.ID.String())
+		c.Locals(InstallAccountIDKey, install.AccountID.String())
+		return c.Next()
+	}
+}
+
+// GetInstall returns the verified install and its account for the request,
+// or nil values for unsigned requests
+func GetInstall(c fiber.Ctx) (installID, accountID *uuid.UUID) {
+	installStr, _ := c.Locals(InstallIDKey).(string)
+	accountStr, _ := c.Locals(InstallAccountIDKey).(string)
+	install, err := uuid.Parse(installStr)
+	if err != nil {
+		return nil, nil
+	}
+	account, err := uuid.Parse(accountStr)
+	if err != nil {
+		return nil, nil
+	}
+	return &install, &account
+}
+
+func installRejected(c fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error":      message,
+		"request_id": GetRequestID(c),
+	})
+}
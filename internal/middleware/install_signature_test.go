@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/db/testutil"
+	"stronghold/internal/identity"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallSignature(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	database := db.NewFromPool(testDB.Pool)
+	ctx := context.Background()
+
+	account, err := database.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	key, err := identity.GenerateKey()
+	require.NoError(t, err)
+	install, err := database.CreateProxyInstall(ctx, account.ID,
+		identity.EncodePublicKey(key.Public().(ed25519.PublicKey)), db.DeviceMetadata{Hostname: "laptop"})
+	require.NoError(t, err)
+
+	m := NewInstallSignature(database)
+
+	var gotInstall, gotAccount string
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.Middleware(), func(c fiber.Ctx) error {
+		gotInstall, gotAccount = "", ""
+		if installID, accountID := GetInstall(c); installID != nil {
+			gotInstall, gotAccount = installID.String(), accountID.String()
+		}
+		return c.SendString("ok")
+	})
+
+	body := []byte(`{"text":"hello"}`)
+	send := func(installID string, sig identity.Signature, payload []byte) int {
+		req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewReader(payload))
+		if installID != "" {
+			req.Header.Set(identity.HeaderInstall, installID)
+			req.Header.Set(identity.HeaderTimestamp, sig.Timestamp)
+			req.Header.Set(identity.HeaderSignature, sig.Value)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, send("", identity.Signature{}, body), "unsigned requests pass through")
+	assert.Empty(t, gotInstall)
+
+	sig := identity.Sign(key, "POST", "/v1/scan/content", body, time.Now())
+	assert.Equal(t, fiber.StatusOK, send(install.ID.String(), sig, body))
+	assert.Equal(t, install.ID.String(), gotInstall)
+	assert.Equal(t, account.ID.String(), gotAccount)
+
+	assert.Equal(t, fiber.StatusUnauthorized, send(install.ID.String(), sig, []byte(`{"text":"other"}`)), "body is covered by the signature")
+
+	stale := identity.Sign(key, "POST", "/v1/scan/content", body, time.Now().Add(-identity.MaxSkew-time.Minute))
+	assert.Equal(t, fiber.StatusUnauthorized, send(install.ID.String(), stale, body))
+
+	other, err := identity.GenerateKey()
+	require.NoError(t, err)
+	forged := identity.Sign(other, "POST", "/v1/scan/content", body, time.Now())
+	assert.Equal(t, fiber.StatusUnauthorized, send(install.ID.String(), forged, body))
+
+	require.NoError(t, database.RevokeProxyInstall(ctx, account.ID, install.ID))
+	assert.Equal(t, fiber.StatusForbidden, send(install.ID.String(), sig, body), "revoked installs are refused")
+}
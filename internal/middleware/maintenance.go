@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strings"
+
+	"stronghold/internal/flags"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// maintenanceRetryAfter is the Retry-After hint, in seconds, for endpoints in maintenance
+const maintenanceRetryAfter = "300"
+
+// Maintenance answers 503 for endpoints whose maintenance flag is on. Health
+// checks and the admin API stay reachable so operators can turn it off again.
+func Maintenance(f *flags.Flags) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		path := c.Path()
+		if c.Method() == fiber.MethodOptions || strings.HasPrefix(path, "/health") || strings.HasPrefix(path, "/v1/admin") {
+			return c.Next()
+		}
+
+		flag, ok := f.Maintenance(path)
+		if !ok {
+			return c.Next()
+		}
+
+		message := flag.Message
+		if message == "" {
+			message = "This endpoint is temporarily unavailable for maintenance"
+		}
+		c.Set(fiber.HeaderRetryAfter, maintenanceRetryAfter)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":      "Service unavailable",
+			"message":    message,
+			"request_id": GetRequestID(c),
+		})
+	}
+}
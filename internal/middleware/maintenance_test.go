@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/flags"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticFlags []*db.FeatureFlag
+
+func (s staticFlags) ListFeatureFlags(context.Context) ([]*db.FeatureFlag, error) {
+	return s, nil
+}
+
+func setupMaintenanceApp(t *testing.T, list ...*db.FeatureFlag) *fiber.App {
+	t.Helper()
+	app := fiber.New()
+	app.Use(Maintenance(flags.New(t.Context(), staticFlags(list), 0)))
+	ok := func(c fiber.Ctx) error { return c.SendString("ok") }
+	app.Post("/v1/scan/content", ok)
+	app.Post("/v1/scan/output", ok)
+	app.Get("/health", ok)
+	app.Get("/v1/admin/flags", ok)
+	return app
+}
+
+func maintenanceStatus(t *testing.T, app *fiber.App, method, path string) int {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest(method, path, nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestMaintenance_SingleEndpoint(t *testing.T) {
+	app := setupMaintenanceApp(t, &db.FeatureFlag{Name: flags.MaintenancePrefix + "/v1/scan/content", Enabled: true, Percent: 100, Message: "Back soon"})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, maintenanceRetryAfter, resp.Header.Get(fiber.HeaderRetryAfter))
+
+	assert.Equal(t, fiber.StatusOK, maintenanceStatus(t, app, "POST", "/v1/scan/output"))
+}
+
+func TestMaintenance_AllKeepsHealthAndAdmin(t *testing.T) {
+	app := setupMaintenanceApp(t, &db.FeatureFlag{Name: flags.MaintenanceAll, Enabled: true, Percent: 100})
+
+	assert.Equal(t, fiber.StatusServiceUnavailable, maintenanceStatus(t, app, "POST", "/v1/scan/output"))
+	assert.Equal(t, fiber.StatusOK, maintenanceStatus(t, app, "GET", "/health"))
+	assert.Equal(t, fiber.StatusOK, maintenanceStatus(t, app, "GET", "/v1/admin/flags"))
+}
+
+func TestMaintenance_DisabledFlagPasses(t *testing.T) {
+	app := setupMaintenanceApp(t, &db.FeatureFlag{Name: flags.MaintenanceAll, Enabled: false, Percent: 100})
+
+	assert.Equal(t, fiber.StatusOK, maintenanceStatus(t, app, "POST", "/v1/scan/content"))
+}
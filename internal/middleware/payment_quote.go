@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+	"stronghold/internal/wallet"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// AccountQuote describes what an authenticated caller would pay for a request,
+// so clients can pick the cheapest valid payment path from a 402 response
+type AccountQuote struct {
+	ListPrice        usdc.MicroUSDC `json:"list_price"`
+	Price            usdc.MicroUSDC `json:"price"`       // Volume-tier price for credits and metered billing
+	VolumeTier       int            `json:"volume_tier"` // 0 is list price, 1 the first discount tier, ...
+	MonthlyRequests  int64          `json:"monthly_requests"`
+	CreditsRemaining usdc.MicroUSDC `json:"credits_remaining"`
+	PreferredNetwork string         `json:"preferred_network,omitempty"`
+	PaymentMethods   []string       `json:"payment_methods"` // Paths currently usable: "credits", "metered", "x402"
+}
+
+// accountPrice returns the volume-tier price for an account and the number of
+// billed requests it has made this month. The usage lookup is skipped when no
+// tiers are configured.
+func (pr *PaymentRouter) accountPrice(ctx context.Context, account *db.Account, price usdc.MicroUSDC) (usdc.MicroUSDC, int, int64) {
+	if len(pr.x402.pricing.VolumeTiers) == 0 {
+		return price, -1, 0
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	stats, err := pr.db.GetUsageStats(ctx, account.ID, monthStart, now)
+	if err != nil {
+		// Fall back to list price rather than failing the request
+		slog.Warn("failed to load monthly usage for volume pricing", "account_id", account.ID, "error", err)
+		return price, -1, 0
+	}
+
+	tierPrice, tier := pr.x402.pricing.PriceForVolume(price, stats.TotalRequests)
+	return tierPrice, tier, stats.TotalRequests
+}
+
+// quote builds the account-specific part of a 402 response
+func (pr *PaymentRouter) quote(account *db.Account, listPrice, price usdc.MicroUSDC, tier int, monthlyRequests int64, hasMetered bool) *AccountQuote {
+	q := &AccountQuote{
+		ListPrice:        listPrice,
+		Price:            price,
+		VolumeTier:       tier + 1,
+		MonthlyRequests:  monthlyRequests,
+		CreditsRemaining: account.BalanceUSDC,
+		PreferredNetwork: preferredNetwork(account, pr.x402.GetNetworks(), pr.x402.config.WalletForNetwork),
+		PaymentMethods:   []string{},
+	}
+	if account.BalanceUSDC >= price {
+		q.PaymentMethods = append(q.PaymentMethods, "credits")
+	}
+	if hasMetered {
+		q.PaymentMethods = append(q.PaymentMethods, "metered")
+	}
+	if pr.x402.config.HasPayments() {
+		q.PaymentMethods = append(q.PaymentMethods, "x402")
+	}
+	return q
+}
+
+// preferredNetwork returns the first configured payment network the account
+// has a linked wallet for, or "" if it has none. receiving filters out
+// networks the server cannot be paid on.
+func preferredNetwork(account *db.Account, networks []string, receiving func(string) string) string {
+	for _, network := range networks {
+		if receiving(network) == "" {
+			continue
+		}
+		if wallet.IsSolanaNetwork(network) {
+			if account.SolanaWalletAddress != nil && *account.SolanaWalletAddress != "" {
+				return network
+			}
+		} else if account.EVMWalletAddress != nil && *account.EVMWalletAddress != "" {
+			return network
+		}
+	}
+	return ""
+}
+
+// accountPaymentRequired returns a 402 for an authenticated account that
+// cannot cover the request from credits or metered billing. Alongside the x402
+// options (preferred network first) it reports the account's price and
+// remaining credits.
+func (pr *PaymentRouter) accountPaymentRequired(c fiber.Ctx, quote *AccountQuote) error {
+	body := fiber.Map{
+		"error":   "Insufficient credits",
+		"message": "Your credit balance is insufficient. Purchase credits at /v1/billing/credits.",
+		"account": quote,
+	}
+	if accepts := pr.x402.paymentOptions(quote.ListPrice, quote.PreferredNetwork); len(accepts) > 0 {
+		body["payment_requirements"] = accepts[0]
+		body["accepts"] = accepts
+	}
+	return c.Status(fiber.StatusPaymentRequired).JSON(body)
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quoteTestX402() *X402Middleware {
+	return NewX402Middleware(&config.X402Config{
+		EVMWalletAddress:    "0x1234567890123456789012345678901234567890",
+		SolanaWalletAddress: "7xKXtg2CWYuV7i8UEz5B2oS6x9fPVkDz7M8f8f8f8f8f",
+		FacilitatorURL:      "https://x402.org/facilitator",
+		Networks:            []string{"base", "solana"},
+	}, &config.PricingConfig{ScanContent: 1000, ScanOutput: 1000})
+}
+
+func TestPreferredNetwork(t *testing.T) {
+	m := quoteTestX402()
+	evm := "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"
+	sol := "9xKXtg2CWYuV7i8UEz5B2oS6x9fPVkDz7M8f8f8f8f8f"
+
+	tests := []struct {
+		name    string
+		account *db.Account
+		want    string
+	}{
+		{"no wallets", &db.Account{}, ""},
+		{"solana only", &db.Account{SolanaWalletAddress: &sol}, "solana"},
+		{"both uses config order", &db.Account{EVMWalletAddress: &evm, SolanaWalletAddress: &sol}, "base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := preferredNetwork(tt.account, m.GetNetworks(), m.config.WalletForNetwork)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAccountPaymentRequired_PreferredNetworkFirst(t *testing.T) {
+	m := quoteTestX402()
+	pr := NewPaymentRouter(m, nil, nil, nil)
+	sol := "9xKXtg2CWYuV7i8UEz5B2oS6x9fPVkDz7M8f8f8f8f8f"
+	account := &db.Account{SolanaWalletAddress: &sol, BalanceUSDC: 200}
+
+	app := fiber.New()
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		return pr.accountPaymentRequired(c, pr.quote(account, 1000, 900, 0, 12000, false))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusPaymentRequired, resp.StatusCode)
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var body struct {
+		PaymentRequirements map[string]any   `json:"payment_requirements"`
+		Accepts             []map[string]any `json:"accepts"`
+		Account             AccountQuote     `json:"account"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &body))
+
+	assert.Equal(t, "solana", body.PaymentRequirements["network"])
+	require.Len(t, body.Accepts, 2)
+	assert.Equal(t, "solana", body.Accepts[0]["network"])
+	assert.Equal(t, "base", body.Accepts[1]["network"])
+
+	assert.Equal(t, usdc.MicroUSDC(900), body.Account.Price)
+	assert.Equal(t, usdc.MicroUSDC(1000), body.Account.ListPrice)
+	assert.Equal(t, 1, body.Account.VolumeTier)
+	assert.Equal(t, usdc.MicroUSDC(200), body.Account.CreditsRemaining)
+	assert.Equal(t, "solana", body.Account.PreferredNetwork)
+	assert.Equal(t, []string{"x402"}, body.Account.PaymentMethods)
+}
@@ -3,10 +3,13 @@ package middleware
 import (
 	"errors"
 	"log/slog"
+	"strconv"
 	"strings"
 
+	"stronghold/internal/abuse"
 	"stronghold/internal/billing"
 	"stronghold/internal/db"
+	"stronghold/internal/flags"
 	"stronghold/internal/usdc"
 
 	"github.com/gofiber/fiber/v3"
@@ -23,6 +26,10 @@ type PaymentRouter struct {
 	apiKey *APIKeyMiddleware
 	meter  *billing.MeterReporter
 	db     *db.DB
+	flags  *flags.Flags
+	region *RegionPin
+	dedup  *ScanDedup
+	abuse  *AbuseGuard
 }
 
 // NewPaymentRouter creates a new payment router
@@ -35,16 +42,43 @@ func NewPaymentRouter(x402 *X402Middleware, apiKey *APIKeyMiddleware, meter *bil
 	}
 }
 
+// SetFlags lets feature flags dial payment enforcement down to log-only
+func (pr *PaymentRouter) SetFlags(f *flags.Flags) {
+	pr.flags = f
+}
+
+// SetRegionPin refuses scans from accounts pinned to another region
+func (pr *PaymentRouter) SetRegionPin(p *RegionPin) {
+	pr.region = p
+}
+
+// SetScanDedup replays opted-in scans retried by the same account instead of
+// charging for them again
+func (pr *PaymentRouter) SetScanDedup(d *ScanDedup) {
+	pr.dedup = d
+}
+
+// SetAbuseGuard jitters, throttles or refuses callers probing the detection
+// engine, and scores every scan
+func (pr *PaymentRouter) SetAbuseGuard(g *AbuseGuard) {
+	pr.abuse = g
+}
+
 // Route returns middleware that handles payment for the given price.
 // It accepts either x402 crypto payment OR B2B API key authentication.
 func (pr *PaymentRouter) Route(price usdc.MicroUSDC) fiber.Handler {
 	// Pre-build the x402 handler for this price
 	x402Handler := pr.x402.AtomicPayment(price)
+	// x402 callers have no account until the payment is verified, so they
+	// are scored by address
+	guarded := func(c fiber.Ctx) error {
+		return pr.abuse.Guard(c, abuse.AddressKey(c.IP()), nil, func() error { return x402Handler(c) })
+	}
 
 	return func(c fiber.Ctx) error {
 		// Path 1: x402 crypto payment (X-PAYMENT header present)
 		if c.Get("X-Payment") != "" {
-			return x402Handler(c)
+			return guarded(c)
 		}
 
 		// Path 2: API key authentication (Bearer sk_live_...)
@@ -60,7 +94,26 @@ func (pr *PaymentRouter) Route(price usdc.MicroUSDC) fiber.Handler {
 		// Path 3: Neither header present → delegate to x402 handler.
 		// In dev mode (no payment networks configured), x402 passes through.
 		// In production, x402 returns its own 402 with payment instructions.
-		return x402Handler(c)
+		if pr.x402.config.HasPayments() && pr.flags.Enabled(flags.PaymentsLogOnly, nil) {
+			return pr.unenforced(c, nil, price, "no payment")
+		}
+		return guarded(c)
+	}
+}
+
+// Identify authenticates an API key when one is sent, without charging, for
+// free endpoints that behave differently per account. Requests without an
+// API key pass through anonymously.
+func (pr *PaymentRouter) Identify() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		authHeader := string(c.Request().Header.Peek("Authorization"))
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") && strings.HasPrefix(parts[1], "sk_live_") {
+			if _, _, err := pr.apiKey.Authenticate(c); err != nil {
+				return err
+			}
+		}
+		return c.Next()
 	}
 }
 
@@ -68,24 +121,47 @@ func (pr *PaymentRouter) Route(price usdc.MicroUSDC) fiber.Handler {
 // Billing is deferred until after the handler succeeds to avoid charging for failed requests.
 func (pr *PaymentRouter) handleAPIKeyPayment(c fiber.Ctx, price usdc.MicroUSDC) error {
 	// Authenticate API key
-	account, _, err := pr.apiKey.Authenticate(c)
+	account, apiKey, err := pr.apiKey.Authenticate(c)
 	if err != nil {
 		return err
 	}
 
+	// Pinned accounts are only scanned in their own region
+	if handled, err := pr.region.Check(c, account); handled {
+		return err
+	}
+
+	// A retried scan that opted in gets its first verdict, uncharged
+	key, handled, err := pr.dedup.Begin(c, account, apiKey)
+	if handled {
+		return err
+	}
+	charged := false
+	defer func() { pr.dedup.Finish(c, key, charged) }()
+
+	// Account billing is charged at the account's volume-tier price
+	listPrice := price
+	price, tier, monthlyRequests := pr.accountPrice(c.Context(), account, listPrice)
+
+	// Sub-account keys spend the parent's balance, up to the sub-account's budget
+	sub, _ := c.Locals(SubAccountKey).(*db.SubAccount)
+	if sub != nil && !sub.CanSpend(price) {
+		return subAccountBudgetExhausted(c, sub)
+	}
+
 	// Pre-check: verify the account has a way to pay before running the handler
 	hasCredits := account.BalanceUSDC >= price
 	hasMetered := pr.meter != nil && pr.meter.IsConfigured() && account.StripeCustomerID != nil && *account.StripeCustomerID != ""
 
 	if !hasCredits && !hasMetered {
-		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
-			"error":   "Insufficient credits",
-			"message": "Your credit balance is insufficient. Purchase credits at /v1/billing/credits.",
-		})
+		if pr.flags.Enabled(flags.PaymentsLogOnly, &account.ID) {
+			return pr.unenforced(c, &account.ID, price, "insufficient balance")
+		}
+		return pr.accountPaymentRequired(c, pr.quote(account, listPrice, price, tier, monthlyRequests, hasMetered))
 	}
 
 	// Execute the handler BEFORE charging
-	if err := c.Next(); err != nil {
+	if err := pr.abuse.Guard(c, abuse.AccountKey(account.ID), &account.ID, c.Next); err != nil {
 		return err
 	}
 
@@ -96,7 +172,17 @@ func (pr *PaymentRouter) handleAPIKeyPayment(c fiber.Ctx, price usdc.MicroUSDC)
 	}
 
 	// Try deducting from credit balance (atomic SQL)
-	deducted, err := pr.db.DeductBalance(c.Context(), account.ID, price)
+	var deducted bool
+	if sub != nil {
+		deducted, err = pr.db.ChargeSubAccount(c.Context(), sub.ID, price)
+	} else {
+		deducted, err = pr.db.DeductBalance(c.Context(), account.ID, price)
+	}
+	if errors.Is(err, db.ErrSubAccountBudgetExceeded) {
+		// Concurrent scans used up the budget after the pre-check
+		c.Response().Reset()
+		return subAccountBudgetExhausted(c, sub)
+	}
 	if err != nil {
 		slog.Error("failed to deduct balance", "account_id", account.ID, "error", err)
 		c.Response().Reset()
@@ -106,14 +192,16 @@ func (pr *PaymentRouter) handleAPIKeyPayment(c fiber.Ctx, price usdc.MicroUSDC)
 	}
 
 	if deducted {
+		charged = true
+		setCost(c, price)
 		pr.logUsage(c, account.ID, price, "credits")
 		return nil
 	}
 
 	// Fall back to metered billing.
-	// Each request gets a unique server-issued identifier. No deduplication is
-	// intended — the scan runs on every request (including retries), so each
-	// execution is a distinct billable event.
+	// Each request gets a unique server-issued identifier: the scan ran, so each
+	// execution is a distinct billable event. Retries sent with Idempotent-Scan
+	// were answered before reaching here.
 	if hasMetered {
 		meterKey := uuid.New().String()
 		if err := pr.meter.ReportUsage(c.Context(), account.ID, *account.StripeCustomerID, c.Path(), price, meterKey); err != nil {
@@ -128,6 +216,13 @@ func (pr *PaymentRouter) handleAPIKeyPayment(c fiber.Ctx, price usdc.MicroUSDC)
 				"error": "Billing service temporarily unavailable. Please try again.",
 			})
 		}
+		charged = true
+		setCost(c, price)
+		if sub != nil {
+			if err := pr.db.RecordSubAccountSpend(c.Context(), sub.ID, price); err != nil {
+				slog.Error("failed to record sub-account spending", "sub_account_id", sub.ID, "error", err)
+			}
+		}
 		pr.logUsage(c, account.ID, price, "metered")
 		return nil
 	}
@@ -142,25 +237,69 @@ func (pr *PaymentRouter) handleAPIKeyPayment(c fiber.Ctx, price usdc.MicroUSDC)
 	})
 }
 
+// subAccountBudgetExhausted refuses a scan the sub-account's budget can't
+// cover. Raising the budget is up to the parent account, so no payment
+// requirements are offered.
+func subAccountBudgetExhausted(c fiber.Ctx, sub *db.SubAccount) error {
+	return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+		"error":          "Sub-account budget exhausted",
+		"message":        "This API key's sub-account has spent its budget. The parent account can raise it.",
+		"sub_account_id": sub.ID,
+		"budget_usdc":    sub.BudgetUSDC,
+		"spent_usdc":     sub.SpentUSDC,
+	})
+}
+
+// unenforced serves a request that would have been refused with 402 while
+// payments are log-only. Nothing is charged; the header tells the caller.
+func (pr *PaymentRouter) unenforced(c fiber.Ctx, accountID *uuid.UUID, price usdc.MicroUSDC, reason string) error {
+	attrs := []any{"path", c.Path(), "price", price.String(), "reason", reason, "request_id", GetRequestID(c)}
+	if accountID != nil {
+		attrs = append(attrs, "account_id", accountID.String())
+	}
+	slog.Warn("payment not enforced (log-only)", attrs...)
+	c.Set("X-Stronghold-Payment", "not-enforced")
+	return c.Next()
+}
+
+// CostHeader reports what a billed request cost, in microUSDC, so clients
+// such as the proxy can track spend as it happens
+const CostHeader = "X-Stronghold-Cost"
+
+// setCost reports the price charged for the request in CostHeader
+func setCost(c fiber.Ctx, price usdc.MicroUSDC) {
+	c.Set(CostHeader, strconv.FormatInt(int64(price), 10))
+}
+
+// DetectionVersionKey is the Locals key scan handlers use to pass the
+// detection version of a verdict to usage logging
+const DetectionVersionKey = "detection_version"
+
 // logUsage creates a usage log entry for a B2B API request.
 // CostUSDC is set to 0 in the DB row to prevent the deduct_account_balance_on_usage
 // trigger from subtracting again — B2B balance changes are handled by DeductBalance
 // (credits) or Stripe (metered). The actual cost is recorded in metadata for auditing.
 func (pr *PaymentRouter) logUsage(c fiber.Ctx, accountID uuid.UUID, price usdc.MicroUSDC, paymentMethod string) {
 	requestID := GetRequestID(c)
+	detectionVersion, _ := c.Locals(DetectionVersionKey).(string)
 	usageLog := &db.UsageLog{
-		AccountID: accountID,
-		RequestID: requestID,
-		Endpoint:  c.Path(),
-		Method:    c.Method(),
-		CostUSDC:  0, // trigger-safe: DeductBalance or Stripe already handled billing
-		Status:    "success",
+		AccountID:        accountID,
+		RequestID:        requestID,
+		Endpoint:         c.Path(),
+		Method:           c.Method(),
+		CostUSDC:         0, // trigger-safe: DeductBalance or Stripe already handled billing
+		Status:           "success",
+		DetectionVersion: detectionVersion,
 		Metadata: map[string]any{
 			"payment_method": paymentMethod,
 			"account_type":   "b2b",
 			"actual_cost":    price,
+			"api_key_id":     c.Locals("api_key_id"),
 		},
 	}
+	if subAccountID := c.Locals("sub_account_id"); subAccountID != nil {
+		usageLog.Metadata["sub_account_id"] = subAccountID
+	}
 
 	if err := pr.db.CreateUsageLog(c.Context(), usageLog); err != nil {
 		slog.Error("failed to create usage log", "account_id", accountID, "error", err)
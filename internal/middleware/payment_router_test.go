@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/db"
+	"stronghold/internal/flags"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute_PaymentsLogOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     *db.FeatureFlag
+		wantPaid bool
+	}{
+		{"enforced without flag", nil, true},
+		{"log-only for all traffic", &db.FeatureFlag{Name: flags.PaymentsLogOnly, Enabled: true, Percent: 100}, false},
+		{"log-only for a cohort only", &db.FeatureFlag{Name: flags.PaymentsLogOnly, Enabled: true, Percent: 50}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list staticFlags
+			if tt.flag != nil {
+				list = append(list, tt.flag)
+			}
+			pr := NewPaymentRouter(quoteTestX402(), nil, nil, nil)
+			pr.SetFlags(flags.New(t.Context(), list, 0))
+
+			app := fiber.New()
+			app.Post("/v1/scan/content", pr.Route(1000), func(c fiber.Ctx) error {
+				return c.SendString("scanned")
+			})
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", nil))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			if tt.wantPaid {
+				assert.NotEqual(t, fiber.StatusOK, resp.StatusCode)
+				assert.Empty(t, resp.Header.Get("X-Stronghold-Payment"))
+			} else {
+				assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+				assert.Equal(t, "not-enforced", resp.Header.Get("X-Stronghold-Payment"))
+			}
+		})
+	}
+}
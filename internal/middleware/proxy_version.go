@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"stronghold/internal/proxyversion"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ProxyUpdateKey is the local holding the update signal for the request's
+// proxy version
+const ProxyUpdateKey = "proxy_update"
+
+// ProxyVersion checks the version a proxy reports against the supported
+// version policy. Outdated proxies get the update status in a response
+// header; vulnerable ones are refused with 426 when the policy enforces it.
+// Requests that don't report a version pass through unchanged.
+type ProxyVersion struct {
+	policy proxyversion.Policy
+}
+
+// NewProxyVersion creates proxy version checking middleware
+func NewProxyVersion(policy proxyversion.Policy) *ProxyVersion {
+	return &ProxyVersion{policy: policy}
+}
+
+// Middleware returns the fiber handler
+func (m *ProxyVersion) Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		version := c.Get(proxyversion.HeaderVersion)
+		if version == "" {
+			return c.Next()
+		}
+
+		update := m.policy.Check(version)
+		if update.Enforced {
+			slog.Warn("refused request from vulnerable proxy version", "version", version, "path", c.Path())
+			return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+				"error":      "This proxy version is no longer accepted. Upgrade Stronghold to continue scanning",
+				"update":     update,
+				"request_id": GetRequestID(c),
+			})
+		}
+		if update.Status != proxyversion.StatusCurrent {
+			c.Set(proxyversion.HeaderUpdate, update.Status)
+		}
+		c.Locals(ProxyUpdateKey, &update)
+		return c.Next()
+	}
+}
+
+// GetProxyUpdate returns the update signal for the request's proxy version,
+// or nil if the request didn't report one
+func GetProxyUpdate(c fiber.Ctx) *proxyversion.Update {
+	update, _ := c.Locals(ProxyUpdateKey).(*proxyversion.Update)
+	return update
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/proxyversion"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyVersion(t *testing.T) {
+	m := NewProxyVersion(proxyversion.Policy{
+		Recommended: "1.4.0",
+		Vulnerable:  []string{"1.3.2"},
+		Enforce:     true,
+	})
+
+	var got *proxyversion.Update
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.Middleware(), func(c fiber.Ctx) error {
+		got = GetProxyUpdate(c)
+		return c.SendString("ok")
+	})
+
+	send := func(version string) (int, string) {
+		req := httptest.NewRequest("POST", "/v1/scan/content", nil)
+		if version != "" {
+			req.Header.Set(proxyversion.HeaderVersion, version)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode, resp.Header.Get(proxyversion.HeaderUpdate)
+	}
+
+	status, header := send("")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, header)
+	assert.Nil(t, got, "requests without a version pass through")
+
+	status, header = send("1.4.0")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, header)
+	require.NotNil(t, got)
+	assert.Equal(t, proxyversion.StatusCurrent, got.Status)
+
+	status, header = send("1.3.9")
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, proxyversion.StatusRecommended, header)
+
+	status, header = send("1.3.2")
+	assert.Equal(t, fiber.StatusUpgradeRequired, status, "vulnerable versions are refused when enforcing")
+	assert.Empty(t, header)
+}
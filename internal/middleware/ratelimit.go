@@ -2,23 +2,37 @@ package middleware
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"stronghold/internal/config"
+	"stronghold/internal/middleware/ratelimit"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/limiter"
 )
 
-// RateLimitMiddleware provides rate limiting for the API
+// RateLimitMiddleware provides rate limiting for the API. The general, auth,
+// and scan limiters share one store, so with Redis every API instance counts
+// against the same limits.
 type RateLimitMiddleware struct {
 	config *config.RateLimitConfig
+	store  ratelimit.Store
+
+	mu       sync.Mutex
+	limiters []*ratelimit.Limiter
 }
 
-// NewRateLimitMiddleware creates a new rate limit middleware instance
+// NewRateLimitMiddleware creates a new rate limit middleware instance that
+// counts in memory
 func NewRateLimitMiddleware(cfg *config.RateLimitConfig) *RateLimitMiddleware {
+	return NewRateLimitMiddlewareWithStore(cfg, ratelimit.NewMemoryStore())
+}
+
+// NewRateLimitMiddlewareWithStore creates a rate limit middleware counting in store
+func NewRateLimitMiddlewareWithStore(cfg *config.RateLimitConfig, store ratelimit.Store) *RateLimitMiddleware {
 	return &RateLimitMiddleware{
 		config: cfg,
+		store:  store,
 	}
 }
 
@@ -30,15 +44,11 @@ func (m *RateLimitMiddleware) Middleware() fiber.Handler {
 		}
 	}
 
-	return limiter.New(limiter.Config{
-		Max:        m.config.MaxRequests,
-		Expiration: time.Duration(m.config.WindowSeconds) * time.Second,
-		KeyGenerator: func(c fiber.Ctx) string {
-			return c.IP()
-		},
+	return m.limiter(ratelimit.Config{
+		Name:         "general",
+		Rule:         m.rule(m.config.MaxRequests, m.config.Burst),
+		Key:          ratelimit.ByIP,
 		LimitReached: rateLimitResponse,
-		SkipSuccessfulRequests: false,
-		SkipFailedRequests:     false,
 		Next: func(c fiber.Ctx) bool {
 			// Skip rate limiting for health endpoints
 			return isHealthEndpoint(c.Path())
@@ -54,19 +64,73 @@ func (m *RateLimitMiddleware) AuthLimiter() fiber.Handler {
 		}
 	}
 
-	return limiter.New(limiter.Config{
-		MaxFunc:    m.getAuthLimit,
-		Expiration: time.Duration(m.config.WindowSeconds) * time.Second,
-		KeyGenerator: func(c fiber.Ctx) string {
+	return m.limiter(ratelimit.Config{
+		Name: "auth",
+		Rule: m.rule(m.config.MaxRequests, 0),
+		RuleFunc: func(c fiber.Ctx) ratelimit.Rule {
+			return m.rule(m.getAuthLimit(c), 0)
+		},
+		Key: func(c fiber.Ctx) string {
 			// Key by IP + endpoint for per-endpoint limits
-			return c.IP() + ":" + c.Path()
+			return ratelimit.ByIP(c) + ":" + c.Path()
 		},
-		LimitReached:           rateLimitResponse,
-		SkipSuccessfulRequests: false,
-		SkipFailedRequests:     false,
+		LimitReached: rateLimitResponse,
+	})
+}
+
+// ScanLimiter returns the per-account rate limiter for scan endpoints.
+// Requests are keyed by account when authenticated, by API key when one is
+// sent, and by IP otherwise. It runs before payment so rejected requests
+// aren't charged.
+func (m *RateLimitMiddleware) ScanLimiter() fiber.Handler {
+	if !m.config.Enabled || m.config.ScanMax <= 0 {
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return m.limiter(ratelimit.Config{
+		Name:         "scan",
+		Rule:         m.rule(m.config.ScanMax, m.config.ScanBurst),
+		Key:          ratelimit.ByAccount,
+		LimitReached: rateLimitResponse,
 	})
 }
 
+// Stats returns counters for the limiters created so far
+func (m *RateLimitMiddleware) Stats() []ratelimit.Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]ratelimit.Stats, 0, len(m.limiters))
+	for _, l := range m.limiters {
+		stats = append(stats, l.Stats())
+	}
+	return stats
+}
+
+// limiter creates a limiter and keeps it for Stats
+func (m *RateLimitMiddleware) limiter(cfg ratelimit.Config) fiber.Handler {
+	l := ratelimit.New(m.store, cfg)
+	m.mu.Lock()
+	m.limiters = append(m.limiters, l)
+	m.mu.Unlock()
+	return l.Handler()
+}
+
+// rule builds a rule with the configured strategy and window
+func (m *RateLimitMiddleware) rule(limit, burst int) ratelimit.Rule {
+	strategy, err := ratelimit.ParseStrategy(m.config.Strategy)
+	if err != nil {
+		strategy = ratelimit.FixedWindow
+	}
+	return ratelimit.Rule{
+		Strategy: strategy,
+		Limit:    limit,
+		Window:   time.Duration(m.config.WindowSeconds) * time.Second,
+		Burst:    burst,
+	}
+}
+
 // getAuthLimit returns the appropriate limit based on the endpoint
 func (m *RateLimitMiddleware) getAuthLimit(c fiber.Ctx) int {
 	path := c.Path()
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired counters are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps counters in process memory. Limits are per instance.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	count   int       // Fixed window: requests in the window
+	tokens  float64   // Token bucket: tokens left at updated
+	updated time.Time // Fixed window: window start; token bucket: last refill
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]*memoryEntry)}
+}
+
+// Allow counts one request for key
+func (s *MemoryStore) Allow(_ context.Context, key string, rule Rule) (Decision, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	e := s.entries[key]
+	if e != nil && !now.Before(e.expires) {
+		e = nil
+	}
+
+	if rule.Strategy == TokenBucket {
+		capacity := float64(rule.capacity())
+		rate := float64(rule.Limit) / rule.Window.Seconds() // Tokens per second
+		if e == nil {
+			e = &memoryEntry{tokens: capacity, updated: now}
+			s.entries[key] = e
+		}
+		e.tokens = min(capacity, e.tokens+now.Sub(e.updated).Seconds()*rate)
+		e.updated = now
+		// Idle buckets are full again once this has passed
+		e.expires = now.Add(seconds((capacity - e.tokens) / rate)).Add(rule.Window)
+		return takeToken(&e.tokens, rule, rate), nil
+	}
+
+	if e == nil {
+		e = &memoryEntry{updated: now, expires: now.Add(rule.Window)}
+		s.entries[key] = e
+	}
+	e.count++
+	return windowDecision(e.count, rule, e.expires.Sub(now)), nil
+}
+
+// sweep drops expired entries. Callers hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// takeToken spends one token if available. rate is in tokens per second.
+func takeToken(tokens *float64, rule Rule, rate float64) Decision {
+	d := Decision{Limit: rule.capacity()}
+	if *tokens >= 1 {
+		*tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - *tokens) / rate)
+	}
+	d.Remaining = int(*tokens)
+	return d
+}
+
+// seconds converts fractional seconds to a duration, rounded to the millisecond
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s*1000)) * time.Millisecond
+}
+
+// windowDecision turns a fixed-window count into a decision
+func windowDecision(count int, rule Rule, resetIn time.Duration) Decision {
+	d := Decision{Limit: rule.Limit, Allowed: count <= rule.Limit, Remaining: max(rule.Limit-count, 0)}
+	if !d.Allowed {
+		d.RetryAfter = resetIn
+	}
+	return d
+}
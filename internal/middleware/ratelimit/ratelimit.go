@@ -0,0 +1,189 @@
+// Package ratelimit limits request rates with fixed-window or token-bucket
+// rules. Counters live in a Store: in memory for a single instance, or in
+// Redis so every API instance shares the same limits.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Strategy selects how a rule counts requests
+type Strategy string
+
+const (
+	// FixedWindow allows Limit requests per Window, counted from the first
+	// request in the window
+	FixedWindow Strategy = "fixed_window"
+	// TokenBucket refills Limit tokens per Window up to Burst, so short
+	// bursts are absorbed while the long-run rate stays at Limit per Window
+	TokenBucket Strategy = "token_bucket"
+)
+
+// ParseStrategy validates a strategy name; empty means FixedWindow
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "", FixedWindow:
+		return FixedWindow, nil
+	case TokenBucket:
+		return TokenBucket, nil
+	default:
+		return "", fmt.Errorf("unknown rate limit strategy %q (use %q or %q)", s, FixedWindow, TokenBucket)
+	}
+}
+
+// Rule is one rate limit
+type Rule struct {
+	Strategy Strategy
+	Limit    int           // Requests per Window
+	Window   time.Duration // Counting window, or the time to refill Limit tokens
+	Burst    int           // Token bucket capacity; defaults to Limit
+}
+
+func (r Rule) capacity() int {
+	if r.Strategy == TokenBucket && r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// Decision is the outcome of counting one request
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Set when the request is not allowed
+}
+
+// Store counts requests for a key under a rule
+type Store interface {
+	Allow(ctx context.Context, key string, rule Rule) (Decision, error)
+}
+
+// KeyFunc returns the key a request is counted under
+type KeyFunc func(c fiber.Ctx) string
+
+// ByIP counts requests per client IP
+func ByIP(c fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// ByAccount counts requests per account when the caller is authenticated,
+// per API key when one is sent (before it has been verified), and per IP
+// otherwise
+func ByAccount(c fiber.Ctx) string {
+	if id, ok := c.Locals("account_id").(string); ok && id != "" {
+		return "account:" + id
+	}
+	if key := c.Get("X-API-Key"); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return ByIP(c)
+}
+
+// Config configures a Limiter
+type Config struct {
+	Name     string                 // Identifies the limiter in keys and stats
+	Rule     Rule                   // Used when RuleFunc is nil
+	RuleFunc func(c fiber.Ctx) Rule // Picks a rule per request, e.g. by path
+	Key      KeyFunc                // Defaults to ByIP
+	Next     func(c fiber.Ctx) bool // Skips limiting when it returns true
+	// LimitReached responds to limited requests; Retry-After is already set
+	LimitReached fiber.Handler
+}
+
+// Stats reports a limiter's counters since startup
+type Stats struct {
+	Name        string `json:"name"`
+	Strategy    string `json:"strategy"`
+	Allowed     int64  `json:"allowed"`
+	Limited     int64  `json:"limited"`
+	StoreErrors int64  `json:"store_errors"` // Requests let through because the store failed
+}
+
+// Limiter is rate limiting middleware
+type Limiter struct {
+	cfg   Config
+	store Store
+
+	allowed     atomic.Int64
+	limited     atomic.Int64
+	storeErrors atomic.Int64
+}
+
+// New creates a limiter counting in store
+func New(store Store, cfg Config) *Limiter {
+	if cfg.Key == nil {
+		cfg.Key = ByIP
+	}
+	if cfg.LimitReached == nil {
+		cfg.LimitReached = func(c fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusTooManyRequests)
+		}
+	}
+	return &Limiter{cfg: cfg, store: store}
+}
+
+// Handler returns the middleware. Requests are let through if the store
+// fails, so an unavailable Redis degrades to no limiting rather than an
+// outage.
+func (l *Limiter) Handler() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if l.cfg.Next != nil && l.cfg.Next(c) {
+			return c.Next()
+		}
+		rule := l.cfg.Rule
+		if l.cfg.RuleFunc != nil {
+			rule = l.cfg.RuleFunc(c)
+		}
+		if rule.Limit <= 0 || rule.Window <= 0 {
+			return c.Next()
+		}
+		if rule.Strategy == "" {
+			rule.Strategy = FixedWindow
+		}
+
+		key := l.cfg.Name + ":" + string(rule.Strategy) + ":" + l.cfg.Key(c)
+		d, err := l.store.Allow(c.Context(), key, rule)
+		if err != nil {
+			l.storeErrors.Add(1)
+			slog.Warn("rate limit store unavailable, allowing request", "limiter", l.cfg.Name, "error", err)
+			return c.Next()
+		}
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+		if !d.Allowed {
+			l.limited.Add(1)
+			c.Set("Retry-After", strconv.Itoa(max(int(math.Ceil(d.RetryAfter.Seconds())), 1)))
+			return l.cfg.LimitReached(c)
+		}
+		l.allowed.Add(1)
+		return c.Next()
+	}
+}
+
+// Stats returns the limiter's counters
+func (l *Limiter) Stats() Stats {
+	strategy := l.cfg.Rule.Strategy
+	if strategy == "" {
+		strategy = FixedWindow
+	}
+	return Stats{
+		Name:        l.cfg.Name,
+		Strategy:    string(strategy),
+		Allowed:     l.allowed.Load(),
+		Limited:     l.limited.Load(),
+		StoreErrors: l.storeErrors.Load(),
+	}
+}
@@ -0,0 +1,237 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a memory store whose time is advanced by the test
+func fakeClock() (*MemoryStore, *time.Time) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestMemoryStore_FixedWindow(t *testing.T) {
+	s, now := fakeClock()
+	rule := Rule{Strategy: FixedWindow, Limit: 3, Window: time.Minute}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		d, err := s.Allow(ctx, "k", rule)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed, "request %d", i+1)
+		assert.Equal(t, 2-i, d.Remaining)
+	}
+
+	*now = now.Add(20 * time.Second)
+	d, err := s.Allow(ctx, "k", rule)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 40*time.Second, d.RetryAfter)
+
+	// Other keys are counted separately
+	d, _ = s.Allow(ctx, "other", rule)
+	assert.True(t, d.Allowed)
+
+	*now = now.Add(40 * time.Second)
+	d, _ = s.Allow(ctx, "k", rule)
+	assert.True(t, d.Allowed, "a new window should start")
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	s, now := fakeClock()
+	// 60 per minute (one per second) with bursts of 5
+	rule := Rule{Strategy: TokenBucket, Limit: 60, Window: time.Minute, Burst: 5}
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		d, err := s.Allow(ctx, "k", rule)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed, "burst request %d", i+1)
+	}
+	d, _ := s.Allow(ctx, "k", rule)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 5, d.Limit)
+	assert.Equal(t, time.Second, d.RetryAfter)
+
+	// One token refills per second
+	*now = now.Add(time.Second)
+	d, _ = s.Allow(ctx, "k", rule)
+	assert.True(t, d.Allowed)
+	d, _ = s.Allow(ctx, "k", rule)
+	assert.False(t, d.Allowed)
+
+	// The bucket never holds more than the burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		d, _ = s.Allow(ctx, "k", rule)
+		assert.True(t, d.Allowed)
+	}
+	d, _ = s.Allow(ctx, "k", rule)
+	assert.False(t, d.Allowed)
+}
+
+func TestMemoryStore_SweepsExpired(t *testing.T) {
+	s, now := fakeClock()
+	rule := Rule{Strategy: FixedWindow, Limit: 1, Window: time.Second}
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := s.Allow(context.Background(), key, rule)
+		require.NoError(t, err)
+	}
+	*now = now.Add(2 * time.Minute)
+	_, err := s.Allow(context.Background(), "d", rule)
+	require.NoError(t, err)
+	assert.Len(t, s.entries, 1)
+}
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, Rule) (Decision, error) {
+	return Decision{}, errors.New("connection refused")
+}
+
+func limiterApp(l *Limiter) *fiber.App {
+	app := fiber.New()
+	app.Use(l.Handler())
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestLimiter_Handler(t *testing.T) {
+	l := New(NewMemoryStore(), Config{
+		Name: "test",
+		Rule: Rule{Strategy: TokenBucket, Limit: 2, Window: time.Minute},
+	})
+	app := limiterApp(l)
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-RateLimit-Limit"))
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+
+	assert.Equal(t, Stats{Name: "test", Strategy: "token_bucket", Allowed: 2, Limited: 1}, l.Stats())
+}
+
+func TestLimiter_StoreFailureAllows(t *testing.T) {
+	l := New(failingStore{}, Config{Name: "test", Rule: Rule{Limit: 1, Window: time.Minute}})
+	app := limiterApp(l)
+
+	for i := 0; i < 3; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, int64(3), l.Stats().StoreErrors)
+}
+
+func TestByAccount(t *testing.T) {
+	accountID := uuid.New().String()
+	var keys []string
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		if c.Get("X-Test-Account") != "" {
+			c.Locals("account_id", c.Get("X-Test-Account"))
+		}
+		keys = append(keys, ByAccount(c))
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	requests := []map[string]string{
+		{"X-Test-Account": accountID, "X-API-Key": "sk_live_abc"},
+		{"X-API-Key": "sk_live_abc"},
+		{},
+	}
+	for _, headers := range requests {
+		req := httptest.NewRequest("GET", "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	require.Len(t, keys, 3)
+	assert.Equal(t, "account:"+accountID, keys[0])
+	assert.Regexp(t, `^key:[0-9a-f]{16}$`, keys[1])
+	assert.NotContains(t, keys[1], "sk_live_abc")
+	assert.Regexp(t, `^ip:`, keys[2])
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, FixedWindow, s)
+
+	s, err = ParseStrategy("token_bucket")
+	require.NoError(t, err)
+	assert.Equal(t, TokenBucket, s)
+
+	_, err = ParseStrategy("sliding_log")
+	assert.Error(t, err)
+}
+
+// TestRedisStore runs against a real Redis when TEST_REDIS_URL is set
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	s, err := NewRedisStoreFromURL(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	prefix := "test:" + uuid.NewString() + ":"
+	t.Cleanup(func() {
+		s.client.Del(ctx, s.prefix+prefix+"fw", s.prefix+prefix+"tb")
+	})
+
+	fw := Rule{Strategy: FixedWindow, Limit: 2, Window: time.Minute}
+	for i := 0; i < 2; i++ {
+		d, err := s.Allow(ctx, prefix+"fw", fw)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	}
+	d, err := s.Allow(ctx, prefix+"fw", fw)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Greater(t, d.RetryAfter, 50*time.Second)
+
+	tb := Rule{Strategy: TokenBucket, Limit: 60, Window: time.Minute, Burst: 3}
+	for i := 0; i < 3; i++ {
+		d, err := s.Allow(ctx, prefix+"tb", tb)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	}
+	d, err = s.Allow(ctx, prefix+"tb", tb)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.LessOrEqual(t, d.RetryAfter, time.Second)
+
+	_, err = NewRedisStoreFromURL("not a url")
+	assert.Error(t, err)
+}
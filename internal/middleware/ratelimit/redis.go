@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds a single store round trip so a slow Redis doesn't
+// stall requests; on timeout the request is let through
+const redisTimeout = 100 * time.Millisecond
+
+// fixedWindowScript counts a request and returns the count and the window's
+// remaining milliseconds
+var fixedWindowScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// tokenBucketScript refills and takes a token using the Redis clock, so
+// instances with skewed clocks share one bucket consistently. It returns
+// whether a token was taken and the tokens left.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps counters in Redis, shared by every API instance
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store using client. Keys are prefixed with
+// "stronghold:ratelimit:".
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "stronghold:ratelimit:"}
+}
+
+// NewRedisStoreFromURL connects to a redis:// or rediss:// URL
+func NewRedisStoreFromURL(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return NewRedisStore(redis.NewClient(opts)), nil
+}
+
+// Allow counts one request for key
+func (s *RedisStore) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	key = s.prefix + key
+
+	if rule.Strategy == TokenBucket {
+		capacity := float64(rule.capacity())
+		rate := float64(rule.Limit) / float64(rule.Window.Milliseconds()) // Tokens per millisecond
+		ttl := int64(capacity/rate) + rule.Window.Milliseconds()
+		res, err := tokenBucketScript.Run(ctx, s.client, []string{key}, capacity, rate, ttl).Slice()
+		if err != nil {
+			return Decision{}, err
+		}
+		if len(res) != 2 {
+			return Decision{}, fmt.Errorf("unexpected token bucket reply %v", res)
+		}
+		tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+		if err != nil {
+			return Decision{}, fmt.Errorf("unexpected token bucket reply %v", res)
+		}
+		d := Decision{Allowed: res[0] == int64(1), Limit: rule.capacity(), Remaining: int(tokens)}
+		if !d.Allowed {
+			d.RetryAfter = seconds((1 - tokens) / rate / 1000)
+		}
+		return d, nil
+	}
+
+	res, err := fixedWindowScript.Run(ctx, s.client, []string{key}, rule.Window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	if len(res) != 2 {
+		return Decision{}, fmt.Errorf("unexpected fixed window reply %v", res)
+	}
+	return windowDecision(int(res[0]), rule, time.Duration(res[1])*time.Millisecond), nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -439,3 +439,72 @@ func TestRateLimit_WindowExpiry(t *testing.T) {
 	resp.Body.Close()
 	assert.Equal(t, 200, resp.StatusCode)
 }
+
+func TestRateLimit_ScanLimiterPerAccount(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:       true,
+		WindowSeconds: 60,
+		MaxRequests:   100,
+		ScanMax:       2,
+		Strategy:      "token_bucket",
+	}
+
+	rlm := NewRateLimitMiddleware(cfg)
+
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) error {
+		c.Locals("account_id", c.Get("X-Test-Account"))
+		return c.Next()
+	})
+	app.Use(rlm.ScanLimiter())
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	scan := func(account string) int {
+		req := httptest.NewRequest("POST", "/v1/scan/content", nil)
+		req.Header.Set("X-Test-Account", account)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Both accounts share one IP but are limited separately
+	assert.Equal(t, 200, scan("account-a"))
+	assert.Equal(t, 200, scan("account-a"))
+	assert.Equal(t, 429, scan("account-a"))
+	assert.Equal(t, 200, scan("account-b"))
+
+	stats := rlm.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "scan", stats[0].Name)
+	assert.Equal(t, "token_bucket", stats[0].Strategy)
+	assert.Equal(t, int64(3), stats[0].Allowed)
+	assert.Equal(t, int64(1), stats[0].Limited)
+}
+
+func TestRateLimit_ScanLimiterDisabled(t *testing.T) {
+	cfg := &config.RateLimitConfig{
+		Enabled:       true,
+		WindowSeconds: 60,
+		MaxRequests:   100,
+		ScanMax:       0,
+	}
+
+	rlm := NewRateLimitMiddleware(cfg)
+
+	app := fiber.New()
+	app.Use(rlm.ScanLimiter())
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	for i := 0; i < 5; i++ {
+		resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+	assert.Empty(t, rlm.Stats())
+}
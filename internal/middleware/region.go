@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// RegionPin keeps an account's scan content inside the region it is pinned
+// to. Requests that reach another region are refused before any content is
+// scanned or logged, pointing the caller at the account's regional endpoint.
+type RegionPin struct {
+	config *config.RegionConfig
+}
+
+// NewRegionPin creates a region pin for this instance's region
+func NewRegionPin(cfg *config.RegionConfig) *RegionPin {
+	return &RegionPin{config: cfg}
+}
+
+// Check answers 421 Misdirected Request when account is pinned to a region
+// other than this instance's. It returns handled=false when the request may
+// be processed here. Accounts without a region are served anywhere.
+func (p *RegionPin) Check(c fiber.Ctx, account *db.Account) (bool, error) {
+	if p == nil || account.Region == nil || *account.Region == "" || *account.Region == p.config.Name {
+		return false, nil
+	}
+
+	resp := fiber.Map{
+		"error":      "Account is pinned to another region",
+		"region":     *account.Region,
+		"request_id": GetRequestID(c),
+	}
+	if endpoint, ok := p.config.Endpoints[*account.Region]; ok {
+		resp["endpoint"] = endpoint
+	}
+	return true, c.Status(fiber.StatusMisdirectedRequest).JSON(resp)
+}
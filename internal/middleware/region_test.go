@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionPin(t *testing.T) {
+	pin := NewRegionPin(&config.RegionConfig{
+		Name:      "us",
+		Endpoints: map[string]string{"us": "https://us.api.example.com", "eu": "https://eu.api.example.com"},
+	})
+
+	check := func(region *string) (int, map[string]string) {
+		app := fiber.New()
+		app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+			if handled, err := pin.Check(c, &db.Account{Region: region}); handled {
+				return err
+			}
+			return c.SendString("ok")
+		})
+		resp, err := app.Test(httptest.NewRequest("POST", "/v1/scan/content", nil))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	us, eu, ap := "us", "eu", "ap"
+
+	status, _ := check(nil)
+	assert.Equal(t, fiber.StatusOK, status, "unpinned accounts are served anywhere")
+	status, _ = check(&us)
+	assert.Equal(t, fiber.StatusOK, status)
+
+	status, body := check(&eu)
+	assert.Equal(t, fiber.StatusMisdirectedRequest, status)
+	assert.Equal(t, "eu", body["region"])
+	assert.Equal(t, "https://eu.api.example.com", body["endpoint"])
+
+	status, body = check(&ap)
+	assert.Equal(t, fiber.StatusMisdirectedRequest, status)
+	assert.Empty(t, body["endpoint"])
+}
+
+func TestRegionPin_NilAllowsAll(t *testing.T) {
+	var pin *RegionPin
+	eu := "eu"
+	handled, err := pin.Check(nil, &db.Account{Region: &eu})
+	assert.False(t, handled)
+	assert.NoError(t, err)
+}
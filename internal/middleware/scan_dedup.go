@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"stronghold/internal/db"
+	"stronghold/internal/idempotency"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const (
+	// IdempotentScanHeader opts an API-key scan into deduplication
+	IdempotentScanHeader = "Idempotent-Scan"
+	// IdempotentReplayHeader marks a response replayed for a retried scan
+	IdempotentReplayHeader = "Idempotent-Replay"
+)
+
+// ScanDedup answers a retried scan with the verdict of the first one, so
+// retry storms don't pay for the same scan twice. Only requests sent with
+// "Idempotent-Scan: true" are deduplicated; x402 payments already replay by
+// payment nonce.
+type ScanDedup struct {
+	dedup *idempotency.Dedup
+}
+
+// NewScanDedup creates scan deduplication on d, or returns nil when d is
+// nil because deduplication is off
+func NewScanDedup(d *idempotency.Dedup) *ScanDedup {
+	if d == nil {
+		return nil
+	}
+	return &ScanDedup{dedup: d}
+}
+
+// Begin replays the stored response when the same API key sent the same
+// scan within the window, returning handled=true. Otherwise it returns the
+// key the request claimed, to be settled with Finish, or "" when the
+// request is not deduplicated. If the store is unavailable the scan runs
+// and is charged as usual.
+func (s *ScanDedup) Begin(c fiber.Ctx, account *db.Account, apiKey *db.APIKey) (string, bool, error) {
+	if s == nil || !strings.EqualFold(c.Get(IdempotentScanHeader), "true") {
+		return "", false, nil
+	}
+
+	// Keys can override the account's score exposure, and sub-account keys
+	// are charged and attributed to their sub-account, so neither may replay
+	// another's verdict
+	scope := account.ID.String() + "/" + apiKey.ID.String()
+	if apiKey.SubAccountID != nil {
+		scope += "/" + apiKey.SubAccountID.String()
+	}
+	key := idempotency.Key(scope, c.Method(), c.OriginalURL(), c.Body())
+	resp, _, err := s.dedup.Begin(c.Context(), key)
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		return "", true, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":      "An identical scan is still in progress. Please try again.",
+			"request_id": GetRequestID(c),
+		})
+	case err != nil:
+		slog.Warn("scan deduplication unavailable", "account_id", account.ID, "error", err)
+		return "", false, nil
+	case resp != nil:
+		c.Set(IdempotentReplayHeader, "true")
+		c.Set(fiber.HeaderContentType, resp.ContentType)
+		return "", true, c.Status(resp.Status).Send(resp.Body)
+	}
+	return key, false, nil
+}
+
+// Finish stores the response of a charged scan for replay, or releases the
+// claim so a retry of an uncharged one runs the scan again
+func (s *ScanDedup) Finish(c fiber.Ctx, key string, charged bool) {
+	if s == nil || key == "" {
+		return
+	}
+	if !charged {
+		if err := s.dedup.Release(c.Context(), key); err != nil {
+			slog.Warn("failed to release scan claim", "error", err)
+		}
+		return
+	}
+	resp := &idempotency.Response{
+		Status:      c.Response().StatusCode(),
+		ContentType: string(c.Response().Header.ContentType()),
+		Body:        append([]byte(nil), c.Response().Body()...),
+	}
+	if err := s.dedup.Complete(c.Context(), key, resp); err != nil {
+		slog.Warn("failed to store scan response for replay", "error", err)
+	}
+}
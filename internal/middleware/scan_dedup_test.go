@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/idempotency"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanDedup(t *testing.T) {
+	dedup := NewScanDedup(idempotency.New(&config.ScanIdempotencyConfig{Window: time.Minute}, idempotency.NewMemoryStore()))
+	accountA, accountB := &db.Account{ID: uuid.New()}, &db.Account{ID: uuid.New()}
+	keyA, keyA2, keyB := &db.APIKey{ID: uuid.New()}, &db.APIKey{ID: uuid.New()}, &db.APIKey{ID: uuid.New()}
+	subID := uuid.New()
+	keySub := &db.APIKey{ID: uuid.New(), SubAccountID: &subID}
+
+	scans, charges := 0, 0
+	app := fiber.New()
+	app.Post("/v1/scan/content", func(c fiber.Ctx) error {
+		account, apiKey := accountA, keyA
+		switch c.Get("X-Test-Key") {
+		case "a2":
+			apiKey = keyA2
+		case "sub":
+			apiKey = keySub
+		case "b":
+			account, apiKey = accountB, keyB
+		}
+		key, handled, err := dedup.Begin(c, account, apiKey)
+		if handled {
+			return err
+		}
+		charged := false
+		defer func() { dedup.Finish(c, key, charged) }()
+
+		scans++
+		if strings.Contains(string(c.Body()), "fail") {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "scanner down"})
+		}
+		charges++
+		charged = true
+		return c.JSON(fiber.Map{"decision": "ALLOW", "scan": scans})
+	})
+
+	scan := func(body string, headers map[string]string) (int, string, string) {
+		req := httptest.NewRequest("POST", "/v1/scan/content", strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), resp.Header.Get(IdempotentReplayHeader)
+	}
+	optIn := map[string]string{IdempotentScanHeader: "true"}
+
+	status, first, replayed := scan(`{"text":"a"}`, optIn)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Empty(t, replayed)
+
+	status, retry, replayed := scan(`{"text":"a"}`, optIn)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, first, retry, "a retry gets the first verdict")
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, 1, charges)
+
+	scan(`{"text":"a"}`, nil)
+	assert.Equal(t, 2, charges, "scans without the header are never deduplicated")
+
+	scan(`{"text":"b"}`, optIn)
+	scan(`{"text":"a"}`, map[string]string{IdempotentScanHeader: "true", "X-Test-Key": "b"})
+	assert.Equal(t, 4, charges, "other content and other accounts are scanned")
+
+	scan(`{"text":"a"}`, map[string]string{IdempotentScanHeader: "true", "X-Test-Key": "a2"})
+	assert.Equal(t, 5, charges, "another key may have another score exposure, so it is scanned")
+
+	scan(`{"text":"a"}`, map[string]string{IdempotentScanHeader: "true", "X-Test-Key": "sub"})
+	assert.Equal(t, 6, charges, "a sub-account key is charged to its sub-account, not replayed the parent's verdict")
+
+	scan(`{"text":"fail"}`, optIn)
+	status, _, replayed = scan(`{"text":"fail"}`, optIn)
+	assert.Equal(t, fiber.StatusBadGateway, status)
+	assert.Empty(t, replayed, "uncharged failures are not replayed")
+	assert.Equal(t, 8, scans)
+}
+
+func TestScanDedup_NilIsOff(t *testing.T) {
+	var dedup *ScanDedup
+	key, handled, err := dedup.Begin(nil, nil, nil)
+	assert.Empty(t, key)
+	assert.False(t, handled)
+	assert.NoError(t, err)
+	dedup.Finish(nil, "", true)
+
+	assert.Nil(t, NewScanDedup(nil))
+}
@@ -66,6 +66,8 @@ func (m *WorkOSAuthMiddleware) initJWKS(ctx context.Context) (keyfunc.Keyfunc, e
 // workOSClaims represents the JWT claims from a WorkOS access token.
 type workOSClaims struct {
 	jwt.RegisteredClaims
+	OrgID string `json:"org_id,omitempty"` // Present when the session is scoped to an organization
+	Role  string `json:"role,omitempty"`   // Organization membership role slug (e.g. "admin", "member")
 }
 
 // Handler returns a Fiber middleware handler that validates WorkOS JWTs.
@@ -167,15 +169,28 @@ func (m *WorkOSAuthMiddleware) Handler() fiber.Handler {
 			})
 		}
 
+		// Keep organization membership and role in sync so org-level policies
+		// also apply to API key requests, which carry no JWT claims.
+		if claims.OrgID != "" {
+			if err := m.db.SetWorkOSMembership(c.Context(), account.ID, claims.OrgID, claims.Role); err != nil {
+				slog.Warn("failed to record WorkOS organization",
+					"account_id", account.ID, "org_id", claims.OrgID, "error", err)
+			}
+		}
+
 		// Set context — same keys as existing AuthMiddleware
 		c.Locals("account_id", account.ID.String())
 		c.Locals("account_number", account.AccountNumber)
+		c.Locals("workos_org_id", claims.OrgID)
+		c.Locals("workos_role", claims.Role)
 
 		return c.Next()
 	}
 }
 
 // jitProvision creates a new B2B account + Stripe customer for a WorkOS user.
+// If the organization's directory already provisioned an account for the
+// user's email, that account is linked instead.
 func (m *WorkOSAuthMiddleware) jitProvision(ctx context.Context, workosUserID string) (*db.Account, error) {
 	// Fetch user details from WorkOS to get email
 	email, err := m.fetchWorkOSUserEmail(ctx, workosUserID)
@@ -183,6 +198,14 @@ func (m *WorkOSAuthMiddleware) jitProvision(ctx context.Context, workosUserID st
 		return nil, fmt.Errorf("failed to fetch WorkOS user: %w", err)
 	}
 
+	provisioned, err := m.db.GetDirectoryAccountByEmail(ctx, email)
+	if err == nil {
+		return m.linkDirectoryAccount(ctx, provisioned, workosUserID, email)
+	}
+	if !errors.Is(err, db.ErrAccountNotFound) {
+		return nil, fmt.Errorf("failed to look up directory account: %w", err)
+	}
+
 	// Create B2B account (no company name yet — collected during onboarding)
 	account, err := m.db.CreateB2BAccount(ctx, workosUserID, email, "")
 	if err != nil {
@@ -191,13 +214,7 @@ func (m *WorkOSAuthMiddleware) jitProvision(ctx context.Context, workosUserID st
 
 	// Create Stripe customer if Stripe is configured
 	if m.stripeConfig.SecretKey != "" {
-		params := &stripe.CustomerParams{
-			Email: stripe.String(email),
-		}
-		params.AddMetadata("account_id", account.ID.String())
-		params.AddMetadata("account_type", "b2b")
-
-		cust, err := customer.New(params)
+		cust, err := newB2BStripeCustomer(account.ID.String(), email)
 		if err != nil {
 			slog.Error("failed to create Stripe customer during JIT provision, rolling back",
 				"account_id", account.ID, "error", err)
@@ -225,6 +242,46 @@ func (m *WorkOSAuthMiddleware) jitProvision(ctx context.Context, workosUserID st
 	return account, nil
 }
 
+// linkDirectoryAccount links a WorkOS user to the account the directory
+// provisioned for them on their first sign-in, creating the Stripe customer
+// that directory provisioning skips. The account is kept on failure since
+// the directory, not the sign-in, owns it.
+func (m *WorkOSAuthMiddleware) linkDirectoryAccount(ctx context.Context, account *db.Account, workosUserID, email string) (*db.Account, error) {
+	if m.stripeConfig.SecretKey != "" && account.StripeCustomerID == nil {
+		cust, err := newB2BStripeCustomer(account.ID.String(), email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Stripe customer: %w", err)
+		}
+		if err := m.db.UpdateStripeCustomerID(ctx, account.ID, cust.ID); err != nil {
+			if _, delErr := customer.Del(cust.ID, nil); delErr != nil {
+				slog.Error("failed to delete Stripe customer during rollback",
+					"stripe_customer_id", cust.ID, "error", delErr)
+			}
+			return nil, fmt.Errorf("failed to store Stripe customer ID: %w", err)
+		}
+		account.StripeCustomerID = &cust.ID
+	}
+
+	if err := m.db.LinkWorkOSUser(ctx, account.ID, workosUserID); err != nil {
+		return nil, fmt.Errorf("failed to link directory account: %w", err)
+	}
+	account.WorkOSUserID = &workosUserID
+
+	slog.Info("linked WorkOS user to directory-provisioned account",
+		"account_id", account.ID, "workos_user_id", workosUserID)
+	return account, nil
+}
+
+// newB2BStripeCustomer creates the Stripe customer for a B2B account
+func newB2BStripeCustomer(accountID, email string) (*stripe.Customer, error) {
+	params := &stripe.CustomerParams{
+		Email: stripe.String(email),
+	}
+	params.AddMetadata("account_id", accountID)
+	params.AddMetadata("account_type", "b2b")
+	return customer.New(params)
+}
+
 // workOSUserResponse is the subset of WorkOS User Management API response we need.
 type workOSUserResponse struct {
 	ID    string `json:"id"`
@@ -3,13 +3,16 @@ package middleware
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"stronghold/internal/chaos"
 	"stronghold/internal/config"
 	"stronghold/internal/db"
 	"stronghold/internal/usdc"
@@ -34,7 +37,8 @@ func NewX402Middleware(cfg *config.X402Config, pricing *config.PricingConfig) *X
 		config:  cfg,
 		pricing: pricing,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: chaos.Transport(chaos.Facilitator, nil),
 		},
 	}
 }
@@ -45,7 +49,8 @@ func NewX402MiddlewareWithDB(cfg *config.X402Config, pricing *config.PricingConf
 		config:  cfg,
 		pricing: pricing,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: chaos.Transport(chaos.Facilitator, nil),
 		},
 		db: database,
 	}
@@ -75,6 +80,11 @@ func (m *X402Middleware) GetRoutes() []PriceRoute {
 	return []PriceRoute{
 		{Path: "/v1/scan/content", Method: "POST", Price: m.pricing.ScanContent},
 		{Path: "/v1/scan/output", Method: "POST", Price: m.pricing.ScanOutput},
+		{Path: "/v1/scan/tool-call", Method: "POST", Price: m.pricing.ScanToolCall},
+		{Path: "/v1/scan/session/message", Method: "POST", Price: m.pricing.ScanSession},
+		{Path: "/v1/scan/documents", Method: "POST", Price: m.pricing.ScanDocuments},
+		{Path: "/v1/ingest/check", Method: "POST", Price: m.pricing.Ingest},
+		{Path: "/v1/ingest/recheck", Method: "POST", Price: m.pricing.Ingest},
 	}
 }
 
@@ -91,6 +101,56 @@ func (m *X402Middleware) GetNetworks() []string {
 	return m.config.Networks
 }
 
+// GetPricing returns the endpoint prices and volume tiers
+func (m *X402Middleware) GetPricing() *config.PricingConfig {
+	return m.pricing
+}
+
+// NetworkPrice describes how to pay a price on one network
+type NetworkPrice struct {
+	Network        string `json:"network"`
+	CAIP2          string `json:"caip2"`
+	ChainID        int    `json:"chain_id,omitempty"` // EVM networks only
+	Scheme         string `json:"scheme"`
+	Recipient      string `json:"recipient"`
+	Token          string `json:"token"`          // Token contract (EVM) or mint (Solana) address
+	TokenSymbol    string `json:"token_symbol"`   // Always "USDC"
+	TokenDecimals  int    `json:"token_decimals"` // Atomic units per token: 10^decimals
+	Amount         string `json:"amount"`         // Price in atomic token units
+	FacilitatorURL string `json:"facilitator_url"`
+	FeePayer       string `json:"fee_payer,omitempty"` // Solana only
+}
+
+// NetworkPrices returns the payment parameters for price on every configured
+// network that has a wallet and a known token
+func (m *X402Middleware) NetworkPrices(price usdc.MicroUSDC) []NetworkPrice {
+	prices := []NetworkPrice{}
+	for _, network := range m.config.Networks {
+		recipient := m.config.WalletForNetwork(network)
+		netCfg, known := wallet.NetworkConfig(network)
+		if recipient == "" || !known {
+			continue
+		}
+		np := NetworkPrice{
+			Network:        network,
+			CAIP2:          wallet.NetworkCAIP2(network),
+			ChainID:        netCfg.ChainID,
+			Scheme:         "x402",
+			Recipient:      recipient,
+			Token:          netCfg.TokenAddress,
+			TokenSymbol:    "USDC",
+			TokenDecimals:  usdc.DecimalsForChain(network),
+			Amount:         m.priceToAtomicUnits(price, network).String(),
+			FacilitatorURL: m.config.FacilitatorURL,
+		}
+		if wallet.IsSolanaNetwork(network) {
+			np.FeePayer = m.config.SolanaFeePayer
+		}
+		prices = append(prices, np)
+	}
+	return prices
+}
+
 // AtomicPayment returns middleware that implements the reserve-commit pattern for atomic payments.
 // It ensures that either both service execution and payment settlement succeed, or neither does.
 // If settlement fails, a 503 is returned and the service result is not delivered.
@@ -120,9 +180,35 @@ func (m *X402Middleware) AtomicPayment(price usdc.MicroUSDC) fiber.Handler {
 			return m.requirePaymentResponse(c, price)
 		}
 
+		// Payments past their validity window are refused outright, so replay
+		// protection does not depend on remembering old nonces
+		if payload.Expired(time.Now()) {
+			// Completed payments still replay their cached result
+			if paymentTx, err := m.db.GetPaymentByNonce(c.Context(), payload.Nonce); err == nil && paymentTx.Status == db.PaymentStatusCompleted {
+				return m.replay(c, paymentTx)
+			}
+			return m.expiredPaymentResponse(c, price, payload.Network)
+		}
+
+		// Check the amount first so underpayments get an explicit error
+		overpayment, err := m.checkAmount(payload, price)
+		var amountErr *AmountError
+		if errors.As(err, &amountErr) {
+			return m.amountErrorResponse(c, price, amountErr)
+		}
+		if err != nil {
+			return m.requirePaymentResponse(c, price)
+		}
+
 		// Verify payment with facilitator first (before database operations)
 		valid, err := m.verifyPayment(paymentHeader, price)
-		if err != nil || !valid {
+		var credit *db.CreditStanding
+		if errors.Is(err, ErrFacilitatorUnavailable) && !wallet.IsSolanaNetwork(payload.Network) {
+			// EVM signatures were checked locally, so trusted payers can be
+			// served on credit. Solana payments are only checked by the facilitator.
+			credit = m.outageCredit(c, payload.Payer, price)
+		}
+		if credit == nil && (err != nil || !valid) {
 			return m.requirePaymentResponse(c, price)
 		}
 
@@ -134,6 +220,7 @@ func (m *X402Middleware) AtomicPayment(price usdc.MicroUSDC) fiber.Handler {
 			ReceiverAddress: m.config.WalletForNetwork(payload.Network),
 			Endpoint:        c.Path(),
 			AmountUSDC:      price,
+			OverpaymentUSDC: overpayment,
 			Network:         payload.Network,
 			ExpiresAt:       time.Now().Add(5 * time.Minute),
 		}
@@ -150,16 +237,7 @@ func (m *X402Middleware) AtomicPayment(price usdc.MicroUSDC) fiber.Handler {
 		if !wasCreated {
 			// Transaction already exists - handle based on status
 			if paymentTx.Status == db.PaymentStatusCompleted {
-				// Return cached result (idempotent replay)
-				if paymentTx.FacilitatorPaymentID != nil {
-					m.PaymentResponse(c, *paymentTx.FacilitatorPaymentID)
-				}
-				if paymentTx.ServiceResult != nil {
-					return c.JSON(paymentTx.ServiceResult)
-				}
-				// Settlement completed but service result was not stored;
-				// return a minimal success response so the caller is not charged twice.
-				return c.JSON(fiber.Map{"status": "already_settled"})
+				return m.replay(c, paymentTx)
 			}
 			// If payment is in another state (reserved, executing, settling, failed),
 			// treat as conflict - another request is processing this payment
@@ -218,13 +296,32 @@ func (m *X402Middleware) AtomicPayment(price usdc.MicroUSDC) fiber.Handler {
 			})
 		}
 
-		// Settle payment (blocking)
-		paymentID, err := m.settlePayment(paymentHeader)
+		// Settle payment (blocking), unless the facilitator was already found down
+		var paymentID string
+		if credit != nil {
+			err = ErrFacilitatorUnavailable
+		} else {
+			paymentID, err = m.settlePayment(paymentHeader)
+			if errors.Is(err, ErrFacilitatorUnavailable) {
+				credit = m.outageCredit(c, payload.Payer, price)
+			}
+		}
 		if err != nil {
-			slog.Error("failed to settle payment", "error", err)
+			slog.Error("failed to settle payment", "error", err, "on_credit", credit != nil)
 			if failErr := m.db.FailSettlement(c.Context(), paymentTx.ID, err.Error()); failErr != nil {
 				slog.Warn("failed to record settlement failure", "payment_id", paymentTx.ID, "error", failErr)
 			}
+			// Serve trusted payers on credit; the settlement worker settles
+			// the payment once the facilitator recovers
+			if credit != nil {
+				err := m.db.CreateReceivable(c.Context(), credit.AccountID, paymentTx.ID, price)
+				if err == nil {
+					m.deferredPaymentResponse(c, payload.Nonce)
+					setCost(c, price)
+					return nil
+				}
+				slog.Error("failed to record receivable", "payment_id", paymentTx.ID, "error", err)
+			}
 			// Return 503 - payment not settled, service result not returned
 			// Clear the response body that was set by the handler
 			c.Response().ResetBody()
@@ -240,11 +337,26 @@ func (m *X402Middleware) AtomicPayment(price usdc.MicroUSDC) fiber.Handler {
 			slog.Error("failed to mark payment as completed", "error", err)
 		}
 
-		m.PaymentResponse(c, paymentID)
+		m.paymentResponse(c, paymentID, overpayment)
+		setCost(c, price)
 		return nil
 	}
 }
 
+// replay answers a request carrying an already completed payment with its
+// cached result (idempotent replay)
+func (m *X402Middleware) replay(c fiber.Ctx, paymentTx *db.PaymentTransaction) error {
+	if paymentTx.FacilitatorPaymentID != nil {
+		m.paymentResponse(c, *paymentTx.FacilitatorPaymentID, paymentTx.OverpaymentUSDC)
+	}
+	if paymentTx.ServiceResult != nil {
+		return c.JSON(paymentTx.ServiceResult)
+	}
+	// Settlement completed but service result was not stored;
+	// return a minimal success response so the caller is not charged twice.
+	return c.JSON(fiber.Map{"status": "already_settled"})
+}
+
 // GetPaymentTransaction retrieves the payment transaction from the request context
 func GetPaymentTransaction(c fiber.Ctx) *db.PaymentTransaction {
 	if tx, ok := c.Locals("payment_tx").(*db.PaymentTransaction); ok {
@@ -265,11 +377,25 @@ func (m *X402Middleware) priceToAtomicUnits(price usdc.MicroUSDC, network string
 
 // requirePaymentResponse returns a 402 Payment Required response
 func (m *X402Middleware) requirePaymentResponse(c fiber.Ctx, price usdc.MicroUSDC) error {
-	c.Status(fiber.StatusPaymentRequired)
+	accepts := m.paymentOptions(price, "")
+	if len(accepts) == 0 {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "No payment networks configured",
+		})
+	}
 
+	return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+		"error":                "Payment required",
+		"payment_requirements": accepts[0], // backward compat: primary option
+		"accepts":              accepts,    // multi-chain: all options
+	})
+}
+
+// paymentOptions builds the x402 accepts array from the configured networks.
+// The preferred network, if configured, is listed first.
+func (m *X402Middleware) paymentOptions(price usdc.MicroUSDC, preferred string) []map[string]interface{} {
 	accepts := []map[string]interface{}{}
 
-	// Build accepts array from configured networks
 	for _, network := range m.config.Networks {
 		recipient := m.config.WalletForNetwork(network)
 		if recipient == "" {
@@ -288,22 +414,133 @@ func (m *X402Middleware) requirePaymentResponse(c fiber.Ctx, price usdc.MicroUSD
 		if wallet.IsSolanaNetwork(network) && m.config.SolanaFeePayer != "" {
 			option["fee_payer"] = m.config.SolanaFeePayer
 		}
-		accepts = append(accepts, option)
+		if network == preferred {
+			accepts = append([]map[string]interface{}{option}, accepts...)
+		} else {
+			accepts = append(accepts, option)
+		}
 	}
 
-	if len(accepts) == 0 {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "No payment networks configured",
-		})
+	return accepts
+}
+
+// ErrFacilitatorUnavailable is returned when the facilitator can't be reached
+// or answers with a server error, as opposed to rejecting the payment
+var ErrFacilitatorUnavailable = errors.New("facilitator unavailable")
+
+// outageCredit returns the standing of the payer's account if it may be
+// served on credit while the facilitator is unavailable, or nil
+func (m *X402Middleware) outageCredit(c fiber.Ctx, payer string, price usdc.MicroUSDC) *db.CreditStanding {
+	cfg := &m.config.OutageCredit
+	if !cfg.Enabled {
+		return nil
+	}
+	standing, err := m.db.GetCreditStanding(c.Context(), payer)
+	if err != nil {
+		if !errors.Is(err, db.ErrAccountNotFound) {
+			slog.Warn("failed to get credit standing", "payer", payer, "error", err)
+		}
+		return nil
 	}
+	if !creditEligible(cfg, standing, price) {
+		return nil
+	}
+	return standing
+}
 
-	response := map[string]interface{}{
-		"error":                "Payment required",
-		"payment_requirements": accepts[0], // backward compat: primary option
-		"accepts":              accepts,    // multi-chain: all options
+// creditEligible reports whether an account may owe price on top of its
+// outstanding credit: it must be active, stay under the credit cap, and
+// either have enough settled payments or a balance covering what it owes
+func creditEligible(cfg *config.OutageCreditConfig, s *db.CreditStanding, price usdc.MicroUSDC) bool {
+	owed := s.Outstanding + price
+	if s.Status != db.AccountStatusActive || owed > cfg.MaxOutstanding {
+		return false
 	}
+	return s.SettledPayments >= cfg.MinSettledPayments || s.Balance >= owed
+}
+
+// Payment error codes, returned with 402 responses
+const (
+	CodeUnderpayment       = "underpayment"
+	CodeOverpaymentTooHigh = "overpayment_exceeds_tolerance"
+	CodePaymentExpired     = "payment_expired"
+)
 
-	return c.JSON(response)
+// AmountError reports a payment whose amount is below the price, or above it
+// by more than the configured overpayment tolerance
+type AmountError struct {
+	Code     string
+	Network  string
+	Expected *big.Int // Price in atomic token units
+	Maximum  *big.Int // Largest accepted amount in atomic token units
+	Received *big.Int
+}
+
+func (e *AmountError) Error() string {
+	return fmt.Sprintf("%s: expected %s (up to %s), got %s", e.Code, e.Expected, e.Maximum, e.Received)
+}
+
+// checkAmount accepts a payment of at least the price and at most the price
+// plus the overpayment tolerance, returning the overpayment
+func (m *X402Middleware) checkAmount(payload *wallet.X402Payload, price usdc.MicroUSDC) (usdc.MicroUSDC, error) {
+	amount := new(big.Int)
+	if _, ok := amount.SetString(payload.Amount, 10); !ok {
+		return 0, fmt.Errorf("invalid amount format: %s", payload.Amount)
+	}
+	expected := m.priceToAtomicUnits(price, payload.Network)
+	maximum := m.priceToAtomicUnits(price+m.config.OverpaymentTolerance, payload.Network)
+
+	code := ""
+	switch {
+	case amount.Cmp(expected) < 0:
+		code = CodeUnderpayment
+	case amount.Cmp(maximum) > 0:
+		code = CodeOverpaymentTooHigh
+	}
+	if code != "" {
+		return 0, &AmountError{Code: code, Network: payload.Network, Expected: expected, Maximum: maximum, Received: amount}
+	}
+	return usdc.FromBigInt(new(big.Int).Sub(amount, expected), payload.Network), nil
+}
+
+// amountErrorResponse answers a payment with the wrong amount with 402,
+// echoing the current price so the client can pay again without refetching it
+func (m *X402Middleware) amountErrorResponse(c fiber.Ctx, price usdc.MicroUSDC, e *AmountError) error {
+	msg := "Payment amount is below the current price"
+	if e.Code == CodeOverpaymentTooHigh {
+		msg = "Payment amount exceeds the current price by more than the accepted tolerance"
+	}
+	accepts := m.paymentOptions(price, e.Network)
+	resp := fiber.Map{
+		"error":            msg,
+		"code":             e.Code,
+		"price_micro_usdc": price,
+		"network":          e.Network,
+		"expected_amount":  e.Expected.String(),
+		"maximum_amount":   e.Maximum.String(),
+		"received_amount":  e.Received.String(),
+		"accepts":          accepts,
+	}
+	if len(accepts) > 0 {
+		resp["payment_requirements"] = accepts[0]
+	}
+	return c.Status(fiber.StatusPaymentRequired).JSON(resp)
+}
+
+// expiredPaymentResponse answers a payment past its validity window with 402
+// and fresh payment options
+func (m *X402Middleware) expiredPaymentResponse(c fiber.Ctx, price usdc.MicroUSDC, network string) error {
+	accepts := m.paymentOptions(price, network)
+	resp := fiber.Map{
+		"error":            "Payment authorization has expired, please generate a new payment",
+		"code":             CodePaymentExpired,
+		"price_micro_usdc": price,
+		"accepts":          accepts,
+	}
+	if len(accepts) > 0 {
+		resp["payment_requirements"] = accepts[0]
+	}
+	return c.Status(fiber.StatusPaymentRequired).JSON(resp)
 }
 
 // verifyPayment verifies the x402 payment header via the facilitator.
@@ -314,14 +551,9 @@ func (m *X402Middleware) verifyPayment(paymentHeader string, price usdc.MicroUSD
 		return false, fmt.Errorf("failed to parse payment: %w", err)
 	}
 
-	// Verify the amount matches
-	amount := new(big.Int)
-	if _, ok := amount.SetString(payload.Amount, 10); !ok {
-		return false, fmt.Errorf("invalid amount format: %s", payload.Amount)
-	}
-	expectedAmount := m.priceToAtomicUnits(price, payload.Network)
-	if amount.Cmp(expectedAmount) != 0 {
-		return false, fmt.Errorf("amount mismatch: expected %s, got %s", expectedAmount.String(), payload.Amount)
+	// Verify the amount covers the price, within the overpayment tolerance
+	if _, err := m.checkAmount(payload, price); err != nil {
+		return false, err
 	}
 
 	// Verify the payment network is one we support
@@ -363,12 +595,13 @@ func (m *X402Middleware) verifyPayment(paymentHeader string, price usdc.MicroUSD
 		}
 	}
 
-	// Build the original payment requirements for facilitator
+	// Build the original payment requirements for facilitator. The amount is
+	// what was paid, as settlement uses it too.
 	originalReq := &wallet.PaymentRequirements{
 		Scheme:    "x402",
 		Network:   payload.Network,
 		Recipient: expectedWallet,
-		Amount:    expectedAmount.String(),
+		Amount:    payload.Amount,
 		Currency:  "USDC",
 	}
 
@@ -408,10 +641,13 @@ func (m *X402Middleware) verifyPayment(paymentHeader string, price usdc.MicroUSD
 	}
 
 	if err != nil {
-		return false, fmt.Errorf("failed to call facilitator: %w", err)
+		return false, fmt.Errorf("%w: %v", ErrFacilitatorUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		return false, fmt.Errorf("%w: verify returned %s", ErrFacilitatorUnavailable, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return false, fmt.Errorf("facilitator verification failed: %s", resp.Status)
 	}
@@ -490,10 +726,13 @@ func (m *X402Middleware) settlePayment(paymentHeader string) (string, error) {
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("failed to call facilitator: %w", err)
+		return "", fmt.Errorf("%w: %v", ErrFacilitatorUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		return "", fmt.Errorf("%w: settle returned %s", ErrFacilitatorUnavailable, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("facilitator settlement failed: %s", resp.Status)
 	}
@@ -523,6 +762,12 @@ func (m *X402Middleware) settlePayment(paymentHeader string) (string, error) {
 
 // PaymentResponse adds payment response header after successful processing
 func (m *X402Middleware) PaymentResponse(c fiber.Ctx, paymentID string) {
+	m.paymentResponse(c, paymentID, 0)
+}
+
+// paymentResponse adds the payment response header, reporting any
+// overpayment credited to the payer
+func (m *X402Middleware) paymentResponse(c fiber.Ctx, paymentID string, overpayment usdc.MicroUSDC) {
 	if !m.config.HasPayments() {
 		return
 	}
@@ -531,6 +776,9 @@ func (m *X402Middleware) PaymentResponse(c fiber.Ctx, paymentID string) {
 		"payment_id": paymentID,
 		"status":     "settled",
 	}
+	if overpayment > 0 {
+		response["overpayment_micro_usdc"] = strconv.FormatInt(int64(overpayment), 10)
+	}
 
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
@@ -540,6 +788,21 @@ func (m *X402Middleware) PaymentResponse(c fiber.Ctx, paymentID string) {
 	c.Set("X-Payment-Response", string(responseJSON))
 }
 
+// deferredPaymentResponse adds the payment response header for a request
+// served on credit. The payment settles later; the nonce identifies it in
+// settlement webhooks.
+func (m *X402Middleware) deferredPaymentResponse(c fiber.Ctx, nonce string) {
+	responseJSON, err := json.Marshal(map[string]string{
+		"nonce":  nonce,
+		"status": "deferred",
+	})
+	if err != nil {
+		slog.Error("failed to marshal payment response", "error", err)
+		return
+	}
+	c.Set("X-Payment-Response", string(responseJSON))
+}
+
 // IsFreeRoute checks if a route doesn't require payment
 func (m *X402Middleware) IsFreeRoute(path string) bool {
 	freeRoutes := []string{
@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -186,14 +189,18 @@ func TestGetRoutes(t *testing.T) {
 		Networks:         []string{"base-sepolia"},
 	}
 	pricing := &config.PricingConfig{
-		ScanContent: usdc.MicroUSDC(1000),
-		ScanOutput:  usdc.MicroUSDC(1000),
+		ScanContent:   usdc.MicroUSDC(1000),
+		ScanOutput:    usdc.MicroUSDC(1000),
+		ScanToolCall:  usdc.MicroUSDC(2000),
+		ScanSession:   usdc.MicroUSDC(3000),
+		ScanDocuments: usdc.MicroUSDC(5000),
+		Ingest:        usdc.MicroUSDC(4000),
 	}
 
 	m := NewX402Middleware(cfg, pricing)
 	routes := m.GetRoutes()
 
-	assert.Len(t, routes, 2)
+	assert.Len(t, routes, 7)
 
 	// Verify route pricing
 	routeMap := make(map[string]usdc.MicroUSDC)
@@ -203,6 +210,11 @@ func TestGetRoutes(t *testing.T) {
 
 	assert.Equal(t, usdc.MicroUSDC(1000), routeMap["/v1/scan/content"])
 	assert.Equal(t, usdc.MicroUSDC(1000), routeMap["/v1/scan/output"])
+	assert.Equal(t, usdc.MicroUSDC(2000), routeMap["/v1/scan/tool-call"])
+	assert.Equal(t, usdc.MicroUSDC(3000), routeMap["/v1/scan/session/message"])
+	assert.Equal(t, usdc.MicroUSDC(5000), routeMap["/v1/scan/documents"])
+	assert.Equal(t, usdc.MicroUSDC(4000), routeMap["/v1/ingest/check"])
+	assert.Equal(t, usdc.MicroUSDC(4000), routeMap["/v1/ingest/recheck"])
 }
 
 func TestMicroUSDCToBigInt(t *testing.T) {
@@ -717,6 +729,7 @@ func TestAtomicPayment_FullFlow_Integration(t *testing.T) {
 	err = json.Unmarshal([]byte(paymentResp), &paymentData)
 	require.NoError(t, err)
 	assert.Equal(t, "test-payment-456", paymentData["payment_id"])
+	assert.Equal(t, "1000", resp.Header.Get(CostHeader))
 
 	// Verify the payment was recorded in the database
 	payment, err := database.GetPaymentByNonce(context.Background(), body["nonce"].(string))
@@ -1130,3 +1143,301 @@ func TestAtomicPayment_SettlingTransitionFailure(t *testing.T) {
 	assert.False(t, settleCalled, "Settlement should not be called when settling transition fails")
 }
 
+
+func TestCheckAmount(t *testing.T) {
+	cfg := &config.X402Config{
+		EVMWalletAddress:     "0x1234567890123456789012345678901234567890",
+		Networks:             []string{"base-sepolia"},
+		OverpaymentTolerance: usdc.MicroUSDC(500),
+	}
+	m := NewX402Middleware(cfg, &config.PricingConfig{})
+
+	tests := []struct {
+		name        string
+		amount      string
+		overpayment usdc.MicroUSDC
+		code        string
+	}{
+		{"exact", "1000", 0, ""},
+		{"overpaid within tolerance", "1300", 300, ""},
+		{"overpaid at tolerance", "1500", 500, ""},
+		{"underpaid", "999", 0, CodeUnderpayment},
+		{"overpaid beyond tolerance", "1501", 0, CodeOverpaymentTooHigh},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := &wallet.X402Payload{Network: "base-sepolia", Amount: tc.amount}
+			overpayment, err := m.checkAmount(payload, usdc.MicroUSDC(1000))
+			if tc.code == "" {
+				require.NoError(t, err)
+				assert.Equal(t, tc.overpayment, overpayment)
+				return
+			}
+			var amountErr *AmountError
+			require.ErrorAs(t, err, &amountErr)
+			assert.Equal(t, tc.code, amountErr.Code)
+			assert.Equal(t, "1000", amountErr.Expected.String())
+			assert.Equal(t, "1500", amountErr.Maximum.String())
+		})
+	}
+
+	_, err := m.checkAmount(&wallet.X402Payload{Network: "base-sepolia", Amount: "lots"}, usdc.MicroUSDC(1000))
+	var amountErr *AmountError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &amountErr))
+}
+
+func TestCheckAmount_ZeroToleranceIsExact(t *testing.T) {
+	m := NewX402Middleware(&config.X402Config{Networks: []string{"base-sepolia"}}, &config.PricingConfig{})
+
+	_, err := m.checkAmount(&wallet.X402Payload{Network: "base-sepolia", Amount: "1000"}, usdc.MicroUSDC(1000))
+	assert.NoError(t, err)
+
+	_, err = m.checkAmount(&wallet.X402Payload{Network: "base-sepolia", Amount: "1001"}, usdc.MicroUSDC(1000))
+	var amountErr *AmountError
+	require.ErrorAs(t, err, &amountErr)
+	assert.Equal(t, CodeOverpaymentTooHigh, amountErr.Code)
+}
+
+func TestAtomicPayment_Underpayment(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	testWallet, err := wallet.NewTestWallet()
+	require.NoError(t, err)
+
+	receiverAddress := "0x1234567890123456789012345678901234567890"
+	cfg := &config.X402Config{
+		EVMWalletAddress:     receiverAddress,
+		FacilitatorURL:       "https://x402.org/facilitator",
+		Networks:             []string{"base-sepolia"},
+		OverpaymentTolerance: usdc.MicroUSDC(10000),
+	}
+	m := NewX402MiddlewareWithDB(cfg, &config.PricingConfig{ScanContent: usdc.MicroUSDC(2000)}, db.NewFromPool(testDB.Pool))
+
+	// The facilitator must not be asked to verify an underpayment
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Paid a stale, lower price
+	paymentHeader, err := testWallet.CreateTestPaymentHeader(receiverAddress, "1000", "base-sepolia")
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.AtomicPayment(usdc.MicroUSDC(2000)), func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewBufferString(`{"text":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Payment", paymentHeader)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 402, resp.StatusCode)
+	assert.Zero(t, httpmock.GetTotalCallCount())
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, CodeUnderpayment, body["code"])
+	assert.Equal(t, "2000", body["price_micro_usdc"])
+	assert.Equal(t, "2000", body["expected_amount"])
+	assert.Equal(t, "1000", body["received_amount"])
+	assert.Contains(t, body, "accepts")
+}
+
+func TestAtomicPayment_OverpaymentRecorded(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	testWallet, err := wallet.NewTestWallet()
+	require.NoError(t, err)
+
+	receiverAddress := "0x1234567890123456789012345678901234567890"
+	cfg := &config.X402Config{
+		EVMWalletAddress:     receiverAddress,
+		FacilitatorURL:       "https://x402.org/facilitator",
+		Networks:             []string{"base-sepolia"},
+		OverpaymentTolerance: usdc.MicroUSDC(10000),
+	}
+	database := db.NewFromPool(testDB.Pool)
+	m := NewX402MiddlewareWithDB(cfg, &config.PricingConfig{ScanContent: usdc.MicroUSDC(1000)}, database)
+
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://x402.org/facilitator/verify",
+		httpmock.NewJsonResponderOrPanic(200, map[string]interface{}{"isValid": true}))
+	httpmock.RegisterResponder("POST", "https://x402.org/facilitator/settle",
+		httpmock.NewJsonResponderOrPanic(200, map[string]interface{}{"success": true, "transaction": "overpaid-tx"}))
+
+	// Paid a cached, higher price
+	paymentHeader, err := testWallet.CreateTestPaymentHeader(receiverAddress, "1500", "base-sepolia")
+	require.NoError(t, err)
+	payload, err := wallet.ParseX402Payment(paymentHeader)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.AtomicPayment(usdc.MicroUSDC(1000)), func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewBufferString(`{"text":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Payment", paymentHeader)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var paymentData map[string]string
+	require.NoError(t, json.Unmarshal([]byte(resp.Header.Get("X-Payment-Response")), &paymentData))
+	assert.Equal(t, "500", paymentData["overpayment_micro_usdc"])
+
+	payment, err := database.GetPaymentByNonce(context.Background(), payload.Nonce)
+	require.NoError(t, err)
+	assert.Equal(t, db.PaymentStatusCompleted, payment.Status)
+	assert.Equal(t, usdc.MicroUSDC(1000), payment.AmountUSDC)
+	assert.Equal(t, usdc.MicroUSDC(500), payment.OverpaymentUSDC)
+}
+
+func TestAtomicPayment_ExpiredPayment(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	receiverAddress := "0x1234567890123456789012345678901234567890"
+	cfg := &config.X402Config{
+		EVMWalletAddress: receiverAddress,
+		FacilitatorURL:   "https://x402.org/facilitator",
+		Networks:         []string{"base-sepolia"},
+	}
+	m := NewX402MiddlewareWithDB(cfg, &config.PricingConfig{ScanContent: usdc.MicroUSDC(1000)}, db.NewFromPool(testDB.Pool))
+
+	// The facilitator must not be asked to verify an expired payment
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	payloadJSON, err := json.Marshal(wallet.X402Payload{
+		Network:   "base-sepolia",
+		Scheme:    "x402",
+		Payer:     "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+		Receiver:  receiverAddress,
+		Amount:    "1000",
+		Timestamp: time.Now().Add(-wallet.PaymentValidity - time.Minute).Unix(),
+		Nonce:     fmt.Sprintf("expired-%d", time.Now().UnixNano()),
+		Signature: "0x00",
+	})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.AtomicPayment(usdc.MicroUSDC(1000)), func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewBufferString(`{"text":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Payment", "x402;"+base64.StdEncoding.EncodeToString(payloadJSON))
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 402, resp.StatusCode)
+	assert.Zero(t, httpmock.GetTotalCallCount())
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, CodePaymentExpired, body["code"])
+	assert.Contains(t, body, "accepts")
+}
+
+func TestCreditEligible(t *testing.T) {
+	cfg := &config.OutageCreditConfig{Enabled: true, MinSettledPayments: 10, MaxOutstanding: 5000}
+	active := func(settled int, balance, outstanding usdc.MicroUSDC) *db.CreditStanding {
+		return &db.CreditStanding{Status: db.AccountStatusActive, SettledPayments: settled, Balance: balance, Outstanding: outstanding}
+	}
+
+	assert.True(t, creditEligible(cfg, active(10, 0, 0), 1000), "payment history earns credit")
+	assert.True(t, creditEligible(cfg, active(0, 2000, 1000), 1000), "balance covering what is owed earns credit")
+	assert.False(t, creditEligible(cfg, active(0, 1999, 1000), 1000), "balance must cover outstanding credit too")
+	assert.False(t, creditEligible(cfg, active(9, 0, 0), 1000), "new accounts without balance")
+	assert.False(t, creditEligible(cfg, active(100, 1_000_000, 4500), 1000), "outstanding credit is capped")
+
+	suspended := active(100, 1_000_000, 0)
+	suspended.Status = db.AccountStatusSuspended
+	assert.False(t, creditEligible(cfg, suspended, 1000))
+}
+
+func TestAtomicPayment_OutageCredit(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close(t)
+
+	testWallet, err := wallet.NewTestWallet()
+	require.NoError(t, err)
+
+	receiverAddress := "0x1234567890123456789012345678901234567890"
+	cfg := &config.X402Config{
+		EVMWalletAddress: receiverAddress,
+		FacilitatorURL:   "https://x402.org/facilitator",
+		Networks:         []string{"base-sepolia"},
+		OutageCredit:     config.OutageCreditConfig{Enabled: true, MinSettledPayments: 100, MaxOutstanding: 1_000_000},
+	}
+	database := db.NewFromPool(testDB.Pool)
+	m := NewX402MiddlewareWithDB(cfg, &config.PricingConfig{ScanContent: usdc.MicroUSDC(1000)}, database)
+
+	ctx := context.Background()
+	account, err := database.CreateAccount(ctx, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, database.LinkEVMWallet(ctx, account.ID, strings.ToLower(testWallet.AddressString())))
+	_, err = testDB.Pool.Exec(ctx, `UPDATE accounts SET balance_usdc = 5000 WHERE id = $1`, account.ID)
+	require.NoError(t, err)
+
+	// The facilitator is down
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://x402.org/facilitator/verify",
+		httpmock.NewStringResponder(502, "bad gateway"))
+
+	paymentHeader, err := testWallet.CreateTestPaymentHeader(receiverAddress, "1000", "base-sepolia")
+	require.NoError(t, err)
+	payload, err := wallet.ParseX402Payment(paymentHeader)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Post("/v1/scan/content", m.AtomicPayment(usdc.MicroUSDC(1000)), func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "ok"})
+	})
+
+	req := httptest.NewRequest("POST", "/v1/scan/content", bytes.NewBufferString(`{"text":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Payment", paymentHeader)
+
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 200, resp.StatusCode)
+	var paymentResp map[string]string
+	require.NoError(t, json.Unmarshal([]byte(resp.Header.Get("X-Payment-Response")), &paymentResp))
+	assert.Equal(t, "deferred", paymentResp["status"])
+	assert.Equal(t, payload.Nonce, paymentResp["nonce"])
+
+	// The payment is left for the settlement worker, and the credit is owed
+	paymentTx, err := database.GetPaymentByNonce(ctx, payload.Nonce)
+	require.NoError(t, err)
+	assert.Equal(t, db.PaymentStatusFailed, paymentTx.Status)
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["POST https://x402.org/facilitator/settle"])
+
+	standing, err := database.GetCreditStanding(ctx, testWallet.AddressString())
+	require.NoError(t, err)
+	assert.Equal(t, usdc.MicroUSDC(1000), standing.Outstanding)
+
+	// Settling the payment closes the receivable
+	require.NoError(t, database.MarkSettling(ctx, paymentTx.ID))
+	require.NoError(t, database.CompleteSettlement(ctx, paymentTx.ID, "0xsettled"))
+	standing, err = database.GetCreditStanding(ctx, testWallet.AddressString())
+	require.NoError(t, err)
+	assert.Zero(t, standing.Outstanding)
+}
@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"stronghold/internal/hashchain"
+	"stronghold/internal/logrotate"
+)
+
+// AuditEntry records a flagged scan decision so it can be reviewed or replayed later
+type AuditEntry struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	Direction    string    `json:"direction"` // "response" (incoming content), "request" (outgoing body), or "payment" (upstream payment)
+	Decision     Decision  `json:"decision"`
+	Action       string    `json:"action"` // What the proxy actually did
+	ShadowAction string    `json:"shadow_action,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Threats      []Threat  `json:"threats,omitempty"` // Findings behind the decision, e.g. flagged headers
+	Detection    string    `json:"detection_version,omitempty"`
+}
+
+// auditLog appends flagged decisions to a JSON-lines file. Entries are hash
+// chained so 'stronghold audit verify' can detect edits and deletions.
+type auditLog struct {
+	mu     sync.Mutex
+	file   *logrotate.Writer
+	path   string
+	head   string      // Hash of the last entry written
+	last   os.FileInfo // The live file as this process last left it
+	logger *slog.Logger
+}
+
+// AuditFilePath returns the audit log location: logging.audit_file if set,
+// otherwise audit.log next to the proxy log file. Empty means disabled.
+func (c *LoggingConfig) AuditFilePath() string {
+	if c.AuditFile != "" {
+		return c.AuditFile
+	}
+	if c.File != "" {
+		return filepath.Join(filepath.Dir(c.File), "audit.log")
+	}
+	return ""
+}
+
+// newAuditLog opens the audit log. It returns nil if auditing is disabled or
+// the file cannot be opened.
+func newAuditLog(cfg LoggingConfig, logger *slog.Logger) *auditLog {
+	path := cfg.AuditFilePath()
+	if path == "" {
+		return nil
+	}
+
+	// Continue the hash chain from the last entry of a previous run, which
+	// may have been rotated out of the live file
+	head, err := auditChainHead(path)
+	if err != nil {
+		logger.Warn("failed to read audit log head, starting a new hash chain", "path", path, "error", err)
+		head = hashchain.Genesis
+	}
+
+	f, err := logrotate.Open(path, cfg.Rotation.options(0600))
+	if err != nil {
+		logger.Warn("failed to open audit log, decision auditing disabled", "path", path, "error", err)
+		return nil
+	}
+
+	last, _ := os.Stat(path)
+	return &auditLog{file: f, path: path, head: head, last: last, logger: logger}
+}
+
+// auditChainHead returns the hash of the last entry in the newest segment of
+// the audit log that has one
+func auditChainHead(path string) (string, error) {
+	segments := logrotate.Segments(path)
+	for i := len(segments) - 1; i >= 0; i-- {
+		head, err := segmentHead(segments[i])
+		if err != nil || head != hashchain.Genesis {
+			return head, err
+		}
+	}
+	return hashchain.Genesis, nil
+}
+
+// segmentHead returns the hash chain head of one segment of a rotated log
+func segmentHead(segment string) (string, error) {
+	r, err := logrotate.OpenSegment(segment)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	if f, ok := r.(*os.File); ok {
+		return hashchain.Head(f)
+	}
+	// Rotated files are compressed and bounded by max_bytes
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return hashchain.Head(bytes.NewReader(data))
+}
+
+// record appends an entry to the audit log
+func (a *auditLog) record(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sync()
+	line, hash, err := hashchain.Seal(a.head, data)
+	if err != nil {
+		return
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		a.logger.Error("failed to write audit log", "error", err)
+		return
+	}
+	a.head = hash
+	a.last, _ = os.Stat(a.path)
+}
+
+// sync picks up entries another process appended since this one last wrote,
+// as the old and new proxy both do while one hands over to the other, so the
+// hash chain continues from them. Callers hold a.mu.
+func (a *auditLog) sync() {
+	current, err := os.Stat(a.path)
+	if err != nil || a.last == nil || os.SameFile(current, a.last) && current.Size() == a.last.Size() {
+		return
+	}
+	if !os.SameFile(current, a.last) {
+		// Rotated by the other process; this writer still holds the old file
+		if err := a.file.Reopen(); err != nil {
+			a.logger.Error("failed to reopen audit log", "error", err)
+		}
+	}
+	if head, err := auditChainHead(a.path); err == nil {
+		a.head = head
+	}
+}
+
+// Close closes the audit log file
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
@@ -0,0 +1,322 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"stronghold/internal/logrotate"
+	"stronghold/internal/usdc"
+	"stronghold/internal/wallet"
+)
+
+// autoPayMaxBody bounds request bodies buffered for a paid retry and 402
+// bodies read for payment requirements
+const autoPayMaxBody = 1024 * 1024
+
+// AutoPayConfig lets the proxy pay x402-protected upstream APIs on the agent's
+// behalf with the local wallet. How much it may spend is set by
+// payments.policy.
+type AutoPayConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Hosts   []string `yaml:"hosts,omitempty"` // Hosts that may be paid ("api.example.com", "*.example.com"); none if empty
+}
+
+// SpendFilePath returns the spend ledger location: logging.spend_file if set,
+// otherwise spend.log next to the proxy log file. Empty means no ledger.
+func (c *LoggingConfig) SpendFilePath() string {
+	if c.SpendFile != "" {
+		return c.SpendFile
+	}
+	if c.File != "" {
+		return filepath.Join(filepath.Dir(c.File), "spend.log")
+	}
+	return ""
+}
+
+// SpendEntry records one payment made to an upstream API
+type SpendEntry struct {
+	Time      time.Time      `json:"time"`
+	RequestID string         `json:"request_id,omitempty"`
+	Host      string         `json:"host"`
+	URL       string         `json:"url"`
+	Network   string         `json:"network"`
+	Recipient string         `json:"recipient"`
+	Amount    usdc.MicroUSDC `json:"amount"`
+	Status    int            `json:"status"`   // Upstream status of the paid retry
+	Accepted  bool           `json:"accepted"` // False if the upstream rejected the payment
+}
+
+// AutoPayStats reports upstream spending
+type AutoPayStats struct {
+	Payments      int64          `json:"payments"`
+	Denied        int64          `json:"denied"`
+	SpentLastHour usdc.MicroUSDC `json:"spent_last_hour"`
+	SpentLastDay  usdc.MicroUSDC `json:"spent_last_day"`
+	LimitHour     usdc.MicroUSDC `json:"limit_hour,omitempty"`
+	LimitDay      usdc.MicroUSDC `json:"limit_day"`
+}
+
+// roundTripperFunc adapts a function such as (*http.Client).Do to an
+// http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// autoPayer retries upstream 402 responses with an x402 payment from the
+// scanner client's wallets, within the spend policy. Payments are appended to
+// a JSON-lines spend ledger.
+type autoPayer struct {
+	hosts   []string
+	policy  *spendPolicy
+	wallets *ScannerClient
+	audit   *auditLog
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	payments int64
+	denied   int64
+	ledger   *logrotate.Writer
+}
+
+// newAutoPayer returns nil when auto-payment is disabled. Recent spending is
+// restored from the ledger so a restart does not reset the spend limits.
+func newAutoPayer(cfg AutoPayConfig, policy SpendPolicyConfig, logging LoggingConfig, wallets *ScannerClient, logger *slog.Logger) *autoPayer {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Hosts) == 0 {
+		logger.Warn("wallet.autopay is enabled but lists no hosts; no upstream payments will be made")
+	}
+
+	p := &autoPayer{
+		policy:  newSpendPolicy(policy, logger),
+		wallets: wallets,
+		logger:  logger,
+	}
+	for _, h := range cfg.Hosts {
+		p.hosts = append(p.hosts, strings.ToLower(h))
+	}
+
+	if path := logging.SpendFilePath(); path != "" {
+		p.policy.restore(readSpendLedger(path))
+		f, err := logrotate.Open(path, logging.Rotation.options(0600))
+		if err != nil {
+			logger.Warn("failed to open spend ledger, payments will not be recorded", "path", path, "error", err)
+		} else {
+			p.ledger = f
+		}
+	}
+	return p
+}
+
+// readSpendLedger returns the entries in a spend ledger and the files rotated
+// out of it, skipping lines that don't parse
+func readSpendLedger(path string) []SpendEntry {
+	var entries []SpendEntry
+	for _, segment := range logrotate.Segments(path) {
+		r, err := logrotate.OpenSegment(segment)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var e SpendEntry
+			if json.Unmarshal(scanner.Bytes(), &e) == nil {
+				entries = append(entries, e)
+			}
+		}
+		r.Close()
+	}
+	return entries
+}
+
+// allowed reports whether host may be paid
+func (p *autoPayer) allowed(host string) bool {
+	host = strings.ToLower(host)
+	return slices.ContainsFunc(p.hosts, func(pattern string) bool {
+		return matchHostPattern(pattern, host)
+	})
+}
+
+// roundTrip sends req through rt. If the upstream answers 402 and the host may
+// be paid, the request is retried once with a payment attached. A 402 that is
+// not paid is returned to the caller unchanged, with X-Stronghold-Autopay
+// explaining why. A nil payer forwards req as-is.
+func (p *autoPayer) roundTrip(rt http.RoundTripper, req *http.Request, requestID string) (*http.Response, error) {
+	if p == nil || !p.allowed(req.URL.Hostname()) {
+		return rt.RoundTrip(req)
+	}
+
+	// Keep the body so it can be sent again with the payment
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength < 0 || req.ContentLength > autoPayMaxBody {
+			return rt.RoundTrip(req)
+		}
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusPaymentRequired {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, autoPayMaxBody))
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 402 response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+
+	requirements, amount, reservation, reason := p.authorize(req.URL.Hostname(), respBody)
+	if reason != "" {
+		p.mu.Lock()
+		p.denied++
+		p.mu.Unlock()
+		p.logger.Warn("upstream payment not made", "url", req.URL.String(), "reason", reason, "requestID", requestID)
+		p.audit.record(AuditEntry{
+			RequestID: requestID,
+			Method:    req.Method,
+			URL:       req.URL.String(),
+			Direction: "payment",
+			Decision:  DecisionBlock,
+			Action:    "block",
+			Reason:    reason,
+		})
+		resp.Header.Set("X-Stronghold-Autopay", "declined: "+reason)
+		return resp, nil
+	}
+
+	paymentHeader, err := p.wallets.createPayment(requirements)
+	if err != nil {
+		p.policy.release(reservation)
+		p.logger.Warn("upstream payment not made", "url", req.URL.String(), "error", err, "requestID", requestID)
+		resp.Header.Set("X-Stronghold-Autopay", "declined: payment could not be created")
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if body != nil {
+		retry.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	retry.Header.Set("X-Payment", paymentHeader)
+	paid, err := rt.RoundTrip(retry)
+	if err != nil {
+		// The outcome is unknown, so the amount stays counted against the limit
+		p.record(requestID, req, requirements, amount, 0, true)
+		return nil, err
+	}
+
+	accepted := paid.StatusCode != http.StatusPaymentRequired
+	if !accepted {
+		p.policy.release(reservation)
+	}
+	p.record(requestID, req, requirements, amount, paid.StatusCode, accepted)
+	if accepted {
+		paid.Header.Set("X-Stronghold-Autopay", fmt.Sprintf("paid %s USDC on %s", amount, requirements.Network))
+	} else {
+		paid.Header.Set("X-Stronghold-Autopay", "declined: upstream rejected the payment")
+	}
+	return paid, nil
+}
+
+// authorize selects payment requirements from a 402 body and reserves the
+// amount under the spend policy. It returns a reason when payment is refused.
+func (p *autoPayer) authorize(host string, body []byte) (*wallet.PaymentRequirements, usdc.MicroUSDC, uint64, string) {
+	requirements, err := p.wallets.selectPaymentOption(body)
+	if err != nil {
+		return nil, 0, 0, "unrecognized payment requirements"
+	}
+	if !p.wallets.hasWalletForNetwork(requirements.Network) {
+		return nil, 0, 0, fmt.Sprintf("no wallet for network %q", requirements.Network)
+	}
+	atomic, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok || atomic.Sign() <= 0 {
+		return nil, 0, 0, "invalid payment amount"
+	}
+	amount := usdc.FromBigInt(atomic, requirements.Network)
+
+	reservation, hit := p.policy.reserve(host, amount)
+	if hit != nil {
+		return nil, 0, 0, hit.Error()
+	}
+	return requirements, amount, reservation, ""
+}
+
+// record counts a payment and appends it to the spend ledger
+func (p *autoPayer) record(requestID string, req *http.Request, requirements *wallet.PaymentRequirements, amount usdc.MicroUSDC, status int, accepted bool) {
+	entry := SpendEntry{
+		Time:      time.Now().UTC(),
+		RequestID: requestID,
+		Host:      req.URL.Hostname(),
+		URL:       req.URL.String(),
+		Network:   requirements.Network,
+		Recipient: requirements.Recipient,
+		Amount:    amount,
+		Status:    status,
+		Accepted:  accepted,
+	}
+	p.logger.Info("sent upstream payment", "url", entry.URL, "amount", amount.String(), "network", entry.Network, "accepted", accepted, "requestID", requestID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if accepted {
+		p.payments++
+	}
+	if p.ledger == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := p.ledger.Write(append(line, '\n')); err != nil {
+		p.logger.Error("failed to write spend ledger", "error", err)
+	}
+}
+
+// stats returns spending counters; nil when auto-payment is disabled
+func (p *autoPayer) stats() *AutoPayStats {
+	if p == nil {
+		return nil
+	}
+	hour, day := p.policy.totals()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &AutoPayStats{
+		Payments:      p.payments,
+		Denied:        p.denied,
+		SpentLastHour: hour,
+		SpentLastDay:  day,
+		LimitHour:     p.policy.global.perHour,
+		LimitDay:      p.policy.global.perDay,
+	}
+}
+
+// Close closes the spend ledger
+func (p *autoPayer) Close() error {
+	if p == nil || p.ledger == nil {
+		return nil
+	}
+	return p.ledger.Close()
+}
@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+	"stronghold/internal/wallet"
+)
+
+// paidAPI returns a server that answers 402 for amount atomic units until a
+// request carries X-Payment, counting requests and echoing the request body
+func paidAPI(t *testing.T, amount string, requests *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Header.Get("X-Payment") == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPaymentRequired)
+			fmt.Fprintf(w, `{"accepts":[{"scheme":"x402","network":"base-sepolia","payTo":"0x1234567890123456789012345678901234567890","maxAmountRequired":%q}]}`, amount)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("paid:"), body...))
+	}))
+}
+
+func newTestAutoPayer(t *testing.T, cfg AutoPayConfig, policy SpendPolicyConfig, spendFile string) *autoPayer {
+	t.Helper()
+	testWallet, err := wallet.NewTestWallet()
+	if err != nil {
+		t.Fatalf("failed to create test wallet: %v", err)
+	}
+	client := NewScannerClient("http://scanner.invalid", "")
+	client.SetWallet(testWallet)
+
+	cfg.Enabled = true
+	p := newAutoPayer(cfg, policy, LoggingConfig{SpendFile: spendFile}, client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestAutoPay_PaysAndRetries(t *testing.T) {
+	var requests int32
+	api := paidAPI(t, "5000", &requests)
+	defer api.Close()
+
+	ledger := filepath.Join(t.TempDir(), "spend.log")
+	p := newTestAutoPayer(t, AutoPayConfig{Hosts: []string{"127.0.0.1"}}, SpendPolicyConfig{}, ledger)
+
+	req, _ := http.NewRequest("POST", api.URL+"/v1/data", strings.NewReader("query"))
+	resp, err := p.roundTrip(http.DefaultTransport, req, "req-1")
+	if err != nil {
+		t.Fatalf("roundTrip failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "paid:query" {
+		t.Fatalf("expected paid retry with original body, got %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("X-Stronghold-Autopay"); !strings.HasPrefix(got, "paid 0.005") {
+		t.Errorf("unexpected X-Stronghold-Autopay header %q", got)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 upstream requests, got %d", requests)
+	}
+
+	stats := p.stats()
+	if stats.Payments != 1 || stats.SpentLastDay != 5000 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	data, err := os.ReadFile(ledger)
+	if err != nil {
+		t.Fatalf("failed to read ledger: %v", err)
+	}
+	var entry SpendEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("invalid ledger entry %q: %v", data, err)
+	}
+	if entry.Amount != 5000 || !entry.Accepted || entry.RequestID != "req-1" || entry.Network != "base-sepolia" {
+		t.Errorf("unexpected ledger entry %+v", entry)
+	}
+}
+
+func TestAutoPay_DeclinesOverLimit(t *testing.T) {
+	var requests int32
+	api := paidAPI(t, "50000", &requests) // 0.05 USDC
+	defer api.Close()
+
+	p := newTestAutoPayer(t, AutoPayConfig{Hosts: []string{"127.0.0.1"}}, SpendPolicyConfig{MaxPerRequest: 0.01}, "")
+
+	req, _ := http.NewRequest("GET", api.URL, nil)
+	resp, err := p.roundTrip(http.DefaultTransport, req, "")
+	if err != nil {
+		t.Fatalf("roundTrip failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPaymentRequired {
+		t.Fatalf("expected the 402 to be passed through, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Stronghold-Autopay"); !strings.Contains(got, "per-request limit") {
+		t.Errorf("unexpected X-Stronghold-Autopay header %q", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "maxAmountRequired") {
+		t.Errorf("expected original 402 body, got %q", body)
+	}
+	if requests != 1 || p.stats().Denied != 1 {
+		t.Errorf("expected no paid retry, got %d requests and %+v", requests, p.stats())
+	}
+}
+
+func TestAutoPay_DailyLimitSurvivesRestart(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "spend.log")
+	recent, _ := json.Marshal(SpendEntry{Time: time.Now().UTC().Add(-time.Hour), Amount: usdc.FromFloat(0.99), Accepted: true})
+	yesterday, _ := json.Marshal(SpendEntry{Time: time.Now().UTC().Add(-48 * time.Hour), Amount: usdc.FromFloat(0.5), Accepted: true})
+	os.WriteFile(ledger, []byte(string(recent)+"\n"+string(yesterday)+"\n"), 0600)
+
+	var requests int32
+	api := paidAPI(t, "20000", &requests)
+	defer api.Close()
+
+	p := newTestAutoPayer(t, AutoPayConfig{Hosts: []string{"127.0.0.1"}}, SpendPolicyConfig{MaxPerRequest: 0.05, MaxPerDay: 1}, ledger)
+	if got := p.stats().SpentLastDay; got != usdc.FromFloat(0.99) {
+		t.Fatalf("expected the last day's spend restored from ledger, got %s", got)
+	}
+
+	req, _ := http.NewRequest("GET", api.URL, nil)
+	resp, err := p.roundTrip(http.DefaultTransport, req, "")
+	if err != nil {
+		t.Fatalf("roundTrip failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPaymentRequired || !strings.Contains(resp.Header.Get("X-Stronghold-Autopay"), "daily limit") {
+		t.Errorf("expected daily limit to decline payment, got %d %q", resp.StatusCode, resp.Header.Get("X-Stronghold-Autopay"))
+	}
+}
+
+func TestAutoPay_OnlyListedHosts(t *testing.T) {
+	var requests int32
+	api := paidAPI(t, "1000", &requests)
+	defer api.Close()
+
+	p := newTestAutoPayer(t, AutoPayConfig{Hosts: []string{"*.example.com"}}, SpendPolicyConfig{}, "")
+
+	req, _ := http.NewRequest("GET", api.URL, nil)
+	resp, err := p.roundTrip(http.DefaultTransport, req, "")
+	if err != nil {
+		t.Fatalf("roundTrip failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPaymentRequired || resp.Header.Get("X-Stronghold-Autopay") != "" {
+		t.Errorf("expected unlisted host to be left alone, got %d %q", resp.StatusCode, resp.Header.Get("X-Stronghold-Autopay"))
+	}
+
+	u, _ := url.Parse("https://api.example.com/x")
+	if !p.allowed(u.Hostname()) {
+		t.Error("expected subdomain to match *.example.com")
+	}
+}
+
+func TestHandleHTTP_AutoPay(t *testing.T) {
+	var requests int32
+	api := paidAPI(t, "1000", &requests)
+	defer api.Close()
+
+	config := newTestConfig("")
+	config.Scanning.Content.Enabled = false
+	config.Wallet.AutoPay = AutoPayConfig{Enabled: true, Hosts: []string{"127.0.0.1"}}
+	s := newTestServer(t, config)
+
+	testWallet, err := wallet.NewTestWallet()
+	if err != nil {
+		t.Fatalf("failed to create test wallet: %v", err)
+	}
+	s.scanner.SetWallet(testWallet)
+	s.autopay = newAutoPayer(config.Wallet.AutoPay, config.Payments.Policy, config.Logging, s.scanner, s.logger)
+
+	req := httptest.NewRequest("GET", api.URL+"/resource", nil)
+	w := httptest.NewRecorder()
+	s.handleHTTP(w, req, time.Now())
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "paid:") {
+		t.Fatalf("expected paid response, got %d %q", w.Code, w.Body.String())
+	}
+	if got := s.autopay.stats().Payments; got != 1 {
+		t.Errorf("expected 1 payment, got %d", got)
+	}
+}
@@ -0,0 +1,278 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Block response formats
+const (
+	BlockFormatJSON  = "json"  // 403 with a JSON body (default)
+	BlockFormatHTML  = "html"  // 403 with an HTML page
+	BlockFormatAuto  = "auto"  // HTML for browser traffic, JSON for everything else
+	BlockFormatRetry = "retry" // 409 Conflict with Retry-After, for agents that implement backoff
+)
+
+// defaultRetryAfter is used by the "retry" format when no retry_after is configured
+const defaultRetryAfter = 60 * time.Second
+
+// BlockPageConfig controls the response sent to the client when content is blocked
+type BlockPageConfig struct {
+	Format       string        `yaml:"format,omitempty"`        // "json", "html", "auto", or "retry"
+	JSONTemplate string        `yaml:"json_template,omitempty"` // Go text/template producing the JSON body
+	HTMLFile     string        `yaml:"html_file,omitempty"`     // Path to a Go html/template file for HTML pages
+	RetryAfter   time.Duration `yaml:"retry_after,omitempty"`   // Retry-After for the "retry" format
+}
+
+// BlockResponseRule overrides the block response for destinations matching a host pattern.
+// Unset fields inherit from the top-level block_response settings.
+type BlockResponseRule struct {
+	Match           string `yaml:"match"` // "api.example.com" or "*.example.com"
+	BlockPageConfig `yaml:",inline"`
+}
+
+// BlockResponseConfig holds the default block response and per-destination overrides
+type BlockResponseConfig struct {
+	BlockPageConfig `yaml:",inline"`
+	Rules           []BlockResponseRule `yaml:"rules,omitempty"` // First match wins
+}
+
+// BlockInfo is the data available to block response templates
+type BlockInfo struct {
+	Error             string
+	Reason            string
+	Decision          Decision
+	RequestID         string
+	URL               string
+	Host              string
+	RecommendedAction string
+}
+
+// blockPage is a compiled BlockPageConfig
+type blockPage struct {
+	format     string
+	jsonTmpl   *texttemplate.Template
+	htmlTmpl   *htmltemplate.Template
+	retryAfter time.Duration
+}
+
+// blockRule pairs a host pattern with its compiled block page
+type blockRule struct {
+	match string
+	page  *blockPage
+}
+
+// blockResponder renders block responses according to BlockResponseConfig
+type blockResponder struct {
+	def   *blockPage
+	rules []blockRule
+}
+
+// defaultBlockHTML is the built-in page shown to browsers
+var defaultBlockHTML = htmltemplate.Must(htmltemplate.New("block").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Blocked by Stronghold</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto;">
+<h1>Content blocked</h1>
+<p>Stronghold blocked this page because it may contain a prompt injection or other threat to AI agents.</p>
+{{if .Reason}}<p><strong>Reason:</strong> {{.Reason}}</p>{{end}}
+{{if .URL}}<p><strong>URL:</strong> {{.URL}}</p>{{end}}
+{{if .RequestID}}<p><small>Request ID: {{.RequestID}}</small></p>{{end}}
+</body>
+</html>
+`))
+
+// jsonTemplateFuncs are available to custom JSON templates.
+// {{json .Reason}} renders a value as a quoted, escaped JSON literal.
+var jsonTemplateFuncs = texttemplate.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// newBlockResponder validates the block response config and compiles its templates
+func newBlockResponder(cfg BlockResponseConfig) (*blockResponder, error) {
+	def, err := compileBlockPage(cfg.BlockPageConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &blockResponder{def: def}
+	for i, rule := range cfg.Rules {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("block_response.rules[%d]: match is required", i)
+		}
+		page, err := compileBlockPage(mergeBlockPageConfig(cfg.BlockPageConfig, rule.BlockPageConfig))
+		if err != nil {
+			return nil, fmt.Errorf("block_response.rules[%d]: %w", i, err)
+		}
+		r.rules = append(r.rules, blockRule{match: strings.ToLower(rule.Match), page: page})
+	}
+
+	return r, nil
+}
+
+// mergeBlockPageConfig returns override with unset fields taken from base
+func mergeBlockPageConfig(base, override BlockPageConfig) BlockPageConfig {
+	if override.Format == "" {
+		override.Format = base.Format
+	}
+	if override.JSONTemplate == "" {
+		override.JSONTemplate = base.JSONTemplate
+	}
+	if override.HTMLFile == "" {
+		override.HTMLFile = base.HTMLFile
+	}
+	if override.RetryAfter == 0 {
+		override.RetryAfter = base.RetryAfter
+	}
+	return override
+}
+
+// compileBlockPage parses the templates referenced by a BlockPageConfig
+func compileBlockPage(cfg BlockPageConfig) (*blockPage, error) {
+	page := &blockPage{
+		format:     cfg.Format,
+		htmlTmpl:   defaultBlockHTML,
+		retryAfter: cfg.RetryAfter,
+	}
+
+	switch page.format {
+	case "":
+		page.format = BlockFormatJSON
+	case BlockFormatJSON, BlockFormatHTML, BlockFormatAuto, BlockFormatRetry:
+	default:
+		return nil, fmt.Errorf("invalid block response format: %s (must be json, html, auto, or retry)", cfg.Format)
+	}
+
+	if page.retryAfter < 0 {
+		return nil, fmt.Errorf("retry_after must not be negative")
+	}
+	if page.retryAfter == 0 {
+		page.retryAfter = defaultRetryAfter
+	}
+
+	if cfg.JSONTemplate != "" {
+		tmpl, err := texttemplate.New("block_json").Funcs(jsonTemplateFuncs).Parse(cfg.JSONTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json_template: %w", err)
+		}
+		page.jsonTmpl = tmpl
+	}
+
+	if cfg.HTMLFile != "" {
+		data, err := os.ReadFile(cfg.HTMLFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read html_file: %w", err)
+		}
+		tmpl, err := htmltemplate.New("block_html").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid html_file template: %w", err)
+		}
+		page.htmlTmpl = tmpl
+	}
+
+	return page, nil
+}
+
+// pageFor returns the block page for the destination host
+func (r *blockResponder) pageFor(host string) *blockPage {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	for _, rule := range r.rules {
+		if matchHostPattern(rule.match, host) {
+			return rule.page
+		}
+	}
+	return r.def
+}
+
+// matchHostPattern reports whether host matches pattern.
+// A leading "*." matches the domain itself and any subdomain.
+func matchHostPattern(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// render builds the status code, headers, and body for a block response.
+// req is the client's request and is used to pick a destination rule and
+// to detect browser traffic.
+func (r *blockResponder) render(req *http.Request, info BlockInfo) (int, http.Header, []byte) {
+	if info.Error == "" {
+		info.Error = "Content blocked by Stronghold security scan"
+	}
+	if info.Host == "" {
+		info.Host = req.Host
+	}
+
+	page := r.pageFor(info.Host)
+	header := make(http.Header)
+
+	format := page.format
+	if format == BlockFormatAuto {
+		format = BlockFormatJSON
+		if strings.Contains(req.Header.Get("Accept"), "text/html") {
+			format = BlockFormatHTML
+		}
+	}
+
+	switch format {
+	case BlockFormatHTML:
+		var buf bytes.Buffer
+		if err := page.htmlTmpl.Execute(&buf, info); err == nil {
+			header.Set("Content-Type", "text/html; charset=utf-8")
+			return http.StatusForbidden, header, buf.Bytes()
+		}
+		// Fall back to JSON if the custom page fails to render
+		header.Set("Content-Type", "application/json")
+		return http.StatusForbidden, header, page.renderJSON(info)
+	case BlockFormatRetry:
+		header.Set("Content-Type", "application/json")
+		header.Set("Retry-After", strconv.Itoa(int(page.retryAfter.Round(time.Second)/time.Second)))
+		return http.StatusConflict, header, page.renderJSON(info)
+	default:
+		header.Set("Content-Type", "application/json")
+		return http.StatusForbidden, header, page.renderJSON(info)
+	}
+}
+
+// renderJSON renders the JSON body, falling back to the built-in schema if
+// the custom template fails or produces invalid JSON
+func (p *blockPage) renderJSON(info BlockInfo) []byte {
+	if p.jsonTmpl != nil {
+		var buf bytes.Buffer
+		if err := p.jsonTmpl.Execute(&buf, info); err == nil && json.Valid(buf.Bytes()) {
+			return buf.Bytes()
+		}
+	}
+
+	body, _ := json.Marshal(struct {
+		Error             string `json:"error"`
+		Reason            string `json:"reason"`
+		RequestID         string `json:"request_id,omitempty"`
+		URL               string `json:"url,omitempty"`
+		RecommendedAction string `json:"recommended_action,omitempty"`
+	}{
+		Error:             info.Error,
+		Reason:            info.Reason,
+		RequestID:         info.RequestID,
+		URL:               info.URL,
+		RecommendedAction: info.RecommendedAction,
+	})
+	return body
+}
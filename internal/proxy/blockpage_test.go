@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockResponder_DefaultJSON(t *testing.T) {
+	r, err := newBlockResponder(BlockResponseConfig{})
+	if err != nil {
+		t.Fatalf("newBlockResponder: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/page", nil)
+	status, header, body := r.render(req, BlockInfo{Reason: "Prompt injection detected", RequestID: "req-1"})
+
+	if status != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", status)
+	}
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected application/json, got %q", header.Get("Content-Type"))
+	}
+
+	var parsed map[string]string
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("failed to parse body: %v", err)
+	}
+	if parsed["reason"] != "Prompt injection detected" || parsed["request_id"] != "req-1" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestBlockResponder_RetryRuleForHost(t *testing.T) {
+	r, err := newBlockResponder(BlockResponseConfig{
+		Rules: []BlockResponseRule{{
+			Match:           "*.agents.example.com",
+			BlockPageConfig: BlockPageConfig{Format: BlockFormatRetry, RetryAfter: 30 * time.Second},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("newBlockResponder: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://api.agents.example.com/v1", nil)
+	status, header, _ := r.render(req, BlockInfo{Reason: "blocked"})
+	if status != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", status)
+	}
+	if header.Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After=30, got %q", header.Get("Retry-After"))
+	}
+
+	// Non-matching hosts use the default format
+	req = httptest.NewRequest("GET", "http://other.example.com/", nil)
+	status, _, _ = r.render(req, BlockInfo{Reason: "blocked"})
+	if status != http.StatusForbidden {
+		t.Errorf("expected status 403 for non-matching host, got %d", status)
+	}
+}
+
+func TestBlockResponder_AutoServesHTMLToBrowsers(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "block.html")
+	if err := os.WriteFile(htmlPath, []byte("<p>Blocked: {{.Reason}}</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newBlockResponder(BlockResponseConfig{
+		BlockPageConfig: BlockPageConfig{Format: BlockFormatAuto, HTMLFile: htmlPath},
+	})
+	if err != nil {
+		t.Fatalf("newBlockResponder: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	_, header, body := r.render(req, BlockInfo{Reason: "<script>"})
+	if !strings.HasPrefix(header.Get("Content-Type"), "text/html") {
+		t.Errorf("expected text/html, got %q", header.Get("Content-Type"))
+	}
+	if string(body) != "<p>Blocked: &lt;script&gt;</p>" {
+		t.Errorf("unexpected HTML body: %s", body)
+	}
+
+	req = httptest.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set("Accept", "application/json")
+	_, header, _ = r.render(req, BlockInfo{Reason: "blocked"})
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected application/json for non-browser client, got %q", header.Get("Content-Type"))
+	}
+}
+
+func TestBlockResponder_CustomJSONTemplate(t *testing.T) {
+	r, err := newBlockResponder(BlockResponseConfig{
+		BlockPageConfig: BlockPageConfig{
+			JSONTemplate: `{"code": "blocked", "detail": {{json .Reason}}}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("newBlockResponder: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	_, _, body := r.render(req, BlockInfo{Reason: `say "hi"`})
+
+	var parsed map[string]string
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("custom template produced invalid JSON: %v (%s)", err, body)
+	}
+	if parsed["code"] != "blocked" || parsed["detail"] != `say "hi"` {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestBlockResponder_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  BlockResponseConfig
+	}{
+		{"unknown format", BlockResponseConfig{BlockPageConfig: BlockPageConfig{Format: "xml"}}},
+		{"bad json template", BlockResponseConfig{BlockPageConfig: BlockPageConfig{JSONTemplate: "{{.Reason"}}},
+		{"missing html file", BlockResponseConfig{BlockPageConfig: BlockPageConfig{HTMLFile: "/nonexistent/block.html"}}},
+		{"rule without match", BlockResponseConfig{Rules: []BlockResponseRule{{}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newBlockResponder(tt.cfg); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"stronghold/internal/bypass"
+)
+
+// BypassConfig configures signed bypass tokens for trusted internal tooling
+type BypassConfig struct {
+	KeyPath string `yaml:"key_path"` // HMAC key used to verify tokens; bypass is disabled if unset
+}
+
+// bypassVerifier checks bypass tokens attached to client requests
+type bypassVerifier struct {
+	key    []byte
+	logger *slog.Logger
+	onUse  func() // called each time a valid token skips scanning
+}
+
+// newBypassVerifier loads the signing key. It returns nil when bypass tokens
+// are not configured or the key cannot be read.
+func newBypassVerifier(cfg BypassConfig, logger *slog.Logger) *bypassVerifier {
+	if cfg.KeyPath == "" {
+		return nil
+	}
+
+	key, err := bypass.LoadKey(cfg.KeyPath)
+	if err != nil {
+		logger.Warn("failed to load bypass key, bypass tokens disabled", "error", err)
+		return nil
+	}
+
+	logger.Info("bypass tokens enabled")
+	return &bypassVerifier{key: key, logger: logger}
+}
+
+// check removes the bypass header from req and returns the token claims if
+// the token is valid for host. Every attempt is logged for audit.
+func (v *bypassVerifier) check(req *http.Request, host string) *bypass.Claims {
+	token := req.Header.Get(bypass.HeaderName)
+	req.Header.Del(bypass.HeaderName) // Never forward tokens upstream
+	if token == "" {
+		return nil
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	claims, err := bypass.Verify(v.key, token, time.Now())
+	if err != nil {
+		v.logger.Warn("bypass token rejected", "host", host, "url", req.URL.String(), "error", err)
+		return nil
+	}
+	if !claims.AllowsHost(host) {
+		v.logger.Warn("bypass token rejected", "host", host, "url", req.URL.String(),
+			"token_id", claims.ID, "error", "host not allowed")
+		return nil
+	}
+
+	v.logger.Warn("bypass token used, scanning skipped",
+		"token_id", claims.ID,
+		"client", claims.Client,
+		"host", host,
+		"method", req.Method,
+		"url", req.URL.String(),
+		"expires_at", claims.Expiry().Format(time.RFC3339),
+	)
+	if v.onUse != nil {
+		v.onUse()
+	}
+	return claims
+}
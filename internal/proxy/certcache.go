@@ -3,7 +3,9 @@ package proxy
 import (
 	"crypto/tls"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +31,20 @@ type CertCache struct {
 	ttl     time.Duration
 	mu      sync.RWMutex
 	stopCh  chan struct{}
+
+	hits         atomic.Int64
+	misses       atomic.Int64
+	evictions    atomic.Int64
+	pregenerated atomic.Int64
+}
+
+// CertCacheStats reports certificate cache effectiveness
+type CertCacheStats struct {
+	Size         int   `json:"size"`
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"` // Certificates generated during a handshake
+	Evictions    int64 `json:"evictions"`
+	Pregenerated int64 `json:"pregenerated"`
 }
 
 // NewCertCache creates a new certificate cache with TTL-based eviction
@@ -54,10 +70,17 @@ func (c *CertCache) GetCert(host string) (*tls.Certificate, error) {
 		c.mu.Lock()
 		entry.lastUsed = time.Now()
 		c.mu.Unlock()
+		c.hits.Add(1)
 		return entry.cert, nil
 	}
 	c.mu.RUnlock()
 
+	c.misses.Add(1)
+	return c.generate(host)
+}
+
+// generate creates and caches a certificate for host
+func (c *CertCache) generate(host string) (*tls.Certificate, error) {
 	// Generate new certificate
 	cert, err := c.ca.GenerateCert(host)
 	if err != nil {
@@ -81,6 +104,43 @@ func (c *CertCache) GetCert(host string) (*tls.Certificate, error) {
 	return cert, nil
 }
 
+// Pregenerate creates certificates for hosts ahead of their first connection,
+// so those handshakes don't pay for key generation. Wildcard patterns and
+// hosts already cached are skipped. It returns the number generated.
+func (c *CertCache) Pregenerate(hosts []string) (int, error) {
+	generated := 0
+	for _, host := range hosts {
+		// Clients send lowercase SNI names, so cache under the same key
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || strings.Contains(host, "*") {
+			continue
+		}
+		c.mu.RLock()
+		_, cached := c.certs[host]
+		c.mu.RUnlock()
+		if cached {
+			continue
+		}
+		if _, err := c.generate(host); err != nil {
+			return generated, err
+		}
+		generated++
+		c.pregenerated.Add(1)
+	}
+	return generated, nil
+}
+
+// Stats returns cache counters
+func (c *CertCache) Stats() CertCacheStats {
+	return CertCacheStats{
+		Size:         c.Size(),
+		Hits:         c.hits.Load(),
+		Misses:       c.misses.Load(),
+		Evictions:    c.evictions.Load(),
+		Pregenerated: c.pregenerated.Load(),
+	}
+}
+
 // GetCertificate returns a function suitable for tls.Config.GetCertificate
 func (c *CertCache) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	return c.GetCert(hello.ServerName)
@@ -131,6 +191,7 @@ func (c *CertCache) evict() {
 	for host, entry := range c.certs {
 		if now.Sub(entry.lastUsed) > c.ttl {
 			delete(c.certs, host)
+			c.evictions.Add(1)
 		}
 	}
 
@@ -155,6 +216,7 @@ func (c *CertCache) evict() {
 				break
 			}
 			delete(c.certs, e.host)
+			c.evictions.Add(1)
 		}
 	}
 }
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"stronghold/internal/chaos"
+)
+
+// handleChaos lists (GET), sets (PUT) and clears (DELETE ?point=) injected
+// faults. It is only routed in builds made with -tags chaos; proxied requests
+// that happen to have the same path are forwarded as usual.
+func (s *Server) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Host != "" || r.Method == http.MethodConnect {
+		s.handleRequest(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var f chaos.Fault
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, "invalid fault", http.StatusBadRequest)
+			return
+		}
+		if err := chaos.Set(f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		chaos.Clear(r.URL.Query().Get("point"))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chaos.Faults())
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"mime"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// minScriptShare is the fraction of non-ASCII characters that must fall in a
+// candidate's script for the heuristic detector to pick it
+const minScriptShare = 0.9
+
+// charsetCandidate is a legacy encoding tried by the heuristic detector
+type charsetCandidate struct {
+	name  string
+	enc   encoding.Encoding
+	match func(r rune) bool // Characters expected in text using this charset
+	kana  bool              // Japanese text: kana must be present
+}
+
+// heuristicCharsets are tried in order when a non-UTF-8 body declares no
+// charset. Multi-byte encodings reject most byte sequences from other
+// encodings, so strict decodability plus script coverage picks the right one
+// for typical pages.
+var heuristicCharsets = []charsetCandidate{
+	{name: "shift_jis", enc: japanese.ShiftJIS, match: isJapanese, kana: true},
+	{name: "euc-jp", enc: japanese.EUCJP, match: isJapanese, kana: true},
+	{name: "euc-kr", enc: korean.EUCKR, match: isHangul},
+	{name: "gbk", enc: simplifiedchinese.GBK, match: isHan},
+	{name: "big5", enc: traditionalchinese.Big5, match: isHan},
+}
+
+// decodeToUTF8 transcodes body to UTF-8 for scanning. The charset comes from a
+// BOM, the Content-Type header, or an HTML <meta> tag; bodies without one that
+// are not valid UTF-8 go through a heuristic detector, falling back to
+// windows-1252. It returns the decoded body and the charset name, or the body
+// unchanged with "utf-8" when no transcoding is needed.
+func decodeToUTF8(body []byte, contentType string) ([]byte, string) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+
+	if !certain && utf8.Valid(body) {
+		return body, "utf-8"
+	}
+	if enc == encoding.Nop || name == "utf-8" {
+		return body, "utf-8"
+	}
+
+	// windows-1252 without certainty is DetermineEncoding's default guess
+	if !certain && name == "windows-1252" {
+		enc, name = detectCharset(body)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, "utf-8"
+	}
+	return decoded, name
+}
+
+// detectCharset guesses the legacy charset of body
+func detectCharset(body []byte) (encoding.Encoding, string) {
+	for _, c := range heuristicCharsets {
+		if matchesCharset(body, c) {
+			return c.enc, c.name
+		}
+	}
+	return charmap.Windows1252, "windows-1252"
+}
+
+// matchesCharset reports whether body decodes cleanly with c and the decoded
+// non-ASCII text is predominantly in c's script
+func matchesCharset(body []byte, c charsetCandidate) bool {
+	decoded, err := c.enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return false
+	}
+
+	var nonASCII, inScript, kana int
+	for _, r := range string(decoded) {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		if r == utf8.RuneError {
+			return false
+		}
+		nonASCII++
+		if c.match(r) {
+			inScript++
+		}
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			kana++
+		}
+	}
+
+	if nonASCII == 0 || float64(inScript)/float64(nonASCII) < minScriptShare {
+		return false
+	}
+	// Kana are essentially always present in Japanese text and absent otherwise
+	if c.kana {
+		return kana > 0
+	}
+	return kana == 0
+}
+
+func isJapanese(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) || isCJKPunct(r)
+}
+
+func isHangul(r rune) bool {
+	return unicode.Is(unicode.Hangul, r) || isCJKPunct(r)
+}
+
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r) || isCJKPunct(r)
+}
+
+// isCJKPunct matches CJK symbols/punctuation and fullwidth forms
+func isCJKPunct(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// utf8ContentType rewrites a Content-Type's charset parameter to utf-8 after
+// the body has been transcoded
+func utf8ContentType(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	params["charset"] = "utf-8"
+	return mime.FormatMediaType(mediaType, params)
+}
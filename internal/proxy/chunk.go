@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"stronghold/internal/usdc"
+)
+
+const (
+	defaultMaxBodySize  = 1024 * 1024
+	defaultChunkSize    = 256 * 1024 // Below the API's 500KB text limit
+	defaultChunkOverlap = 4 * 1024
+	scanTimeout         = 10 * time.Second
+)
+
+// ScanLimitsConfig controls how much of a body is scanned
+type ScanLimitsConfig struct {
+	MaxBodySize  int  `yaml:"max_body_size,omitempty"` // Bodies larger than this are not scanned (default 1MB)
+	Chunked      bool `yaml:"chunked,omitempty"`       // Scan text larger than ChunkSize in overlapping windows
+	ChunkSize    int  `yaml:"chunk_size,omitempty"`    // Bytes per window (default 256KB)
+	ChunkOverlap int  `yaml:"chunk_overlap,omitempty"` // Bytes shared by adjacent windows (default 4KB)
+}
+
+// maxBodySize returns the largest body that is buffered and scanned
+func (c ScanLimitsConfig) maxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return defaultMaxBodySize
+	}
+	return c.MaxBodySize
+}
+
+// chunkSize returns the window size for chunked scanning
+func (c ScanLimitsConfig) chunkSize() int {
+	if c.ChunkSize <= 0 {
+		return defaultChunkSize
+	}
+	return c.ChunkSize
+}
+
+// chunkOverlap returns the window overlap, kept below half the window size so
+// every window makes progress
+func (c ScanLimitsConfig) chunkOverlap() int {
+	overlap := c.ChunkOverlap
+	if overlap <= 0 {
+		overlap = defaultChunkOverlap
+	}
+	if overlap > c.chunkSize()/2 {
+		overlap = c.chunkSize() / 2
+	}
+	return overlap
+}
+
+// scanText sends a scan payload to the scanner once sched grants a slot for
+// the source host. With chunked scanning enabled, payloads larger than the
+// chunk size are split into overlapping windows and the verdicts merged, so an
+// attack straddling a boundary is seen whole by at least one window.
+func scanText(scanner *ScannerClient, sched *scanScheduler, limits ScanLimitsConfig, payload []byte, sourceURL, payloadType string) (*ScanResult, error) {
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), scanTimeout)
+	release, err := sched.acquire(waitCtx, scanHost(sourceURL))
+	cancelWait()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if !limits.Chunked || len(payload) <= limits.chunkSize() {
+		ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+		defer cancel()
+		return scanner.ScanContent(ctx, payload, sourceURL, payloadType)
+	}
+
+	chunks := splitWindows(payload, limits.chunkSize(), limits.chunkOverlap())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(chunks))*scanTimeout)
+	defer cancel()
+
+	results, err := scanner.ScanContentChunks(ctx, chunks, sourceURL, payloadType)
+	if err != nil {
+		return nil, err
+	}
+	return mergeResults(results, len(chunks)), nil
+}
+
+// splitWindows splits text into windows of at most size bytes, each starting
+// overlap bytes before the previous one ended. Boundaries are moved to rune
+// starts so no window holds a partial UTF-8 sequence.
+func splitWindows(text []byte, size, overlap int) [][]byte {
+	var windows [][]byte
+	start := 0
+	for {
+		end := min(start+size, len(text))
+		if end < len(text) {
+			for end > start+1 && !utf8.RuneStart(text[end]) {
+				end--
+			}
+		}
+		windows = append(windows, text[start:end])
+		if end == len(text) {
+			return windows
+		}
+
+		next := end - overlap
+		for next < end && !utf8.RuneStart(text[next]) {
+			next++
+		}
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
+
+// mergeResults combines per-window verdicts: the most severe decision wins
+// and carries its reason, scores are the per-key maximum, and threats from
+// every window are kept with the window noted in their location. The cost
+// is what all the windows cost.
+func mergeResults(results []*ScanResult, total int) *ScanResult {
+	merged := &ScanResult{
+		Decision: DecisionAllow,
+		Scores:   make(map[string]float64),
+		Metadata: map[string]interface{}{
+			"chunks":         total,
+			"chunks_scanned": len(results),
+		},
+	}
+
+	var cost usdc.MicroUSDC
+	worst := -1
+	for i, r := range results {
+		merged.LatencyMs += r.LatencyMs
+		cost += resultCost(r)
+		for k, v := range r.Scores {
+			if v > merged.Scores[k] {
+				merged.Scores[k] = v
+			}
+		}
+		for _, t := range r.ThreatsFound {
+			t.Location = fmt.Sprintf("chunk %d: %s", i+1, t.Location)
+			merged.ThreatsFound = append(merged.ThreatsFound, t)
+		}
+		if worst < 0 || decisionRank(r.Decision) > decisionRank(results[worst].Decision) {
+			worst = i
+		}
+	}
+
+	if worst >= 0 {
+		w := results[worst]
+		merged.Decision = w.Decision
+		merged.Reason = w.Reason
+		merged.RequestID = w.RequestID
+		merged.RecommendedAction = w.RecommendedAction
+		if w.Decision != DecisionAllow {
+			merged.Reason = fmt.Sprintf("%s (chunk %d of %d)", w.Reason, worst+1, total)
+		}
+	}
+	if cost > 0 {
+		setResultCost(merged, cost)
+	}
+	return merged
+}
+
+// decisionRank orders decisions by severity
+func decisionRank(d Decision) int {
+	switch d {
+	case DecisionBlock:
+		return 2
+	case DecisionWarn:
+		return 1
+	default:
+		return 0
+	}
+}
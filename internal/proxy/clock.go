@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+// ClockStats reports the local clock's skew from the API's, measured from
+// the Date header of scan responses, and the correction applied to payment
+// timestamps for it
+type ClockStats struct {
+	SkewMs       int64     `json:"skew_ms"`       // Local clock minus the API's
+	CorrectionMs int64     `json:"correction_ms"` // Added to payment timestamps
+	MeasuredAt   time.Time `json:"measured_at"`
+}
+
+// Clock returns the latest clock skew measured against the API, or nil if
+// no response has carried a Date header yet
+func (c *ScannerClient) Clock() *ClockStats {
+	return c.clock.Load()
+}
+
+// noteServerDate measures the clock skew from an API response and corrects
+// payment timestamps for it, so a 402 answered by a server whose clock
+// differs is paid with a payment inside its validity window
+func (c *ScannerClient) noteServerDate(date string, sent, received time.Time) {
+	skew, ok := wallet.ObserveServerDate(date, sent, received)
+	if !ok {
+		return
+	}
+	c.clock.Store(&ClockStats{
+		SkewMs:       skew.Milliseconds(),
+		CorrectionMs: wallet.ClockOffset().Milliseconds(),
+		MeasuredAt:   received,
+	})
+}
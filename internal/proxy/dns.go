@@ -0,0 +1,343 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	defaultDNSListen       = "127.0.0.1:5353"
+	dnsUpstreamTimeout     = 5 * time.Second
+	domainAgeLookupTimeout = 3 * time.Second
+	domainAgeCacheTTL      = 24 * time.Hour
+	maxDNSPacketSize       = 4096
+)
+
+// defaultDNSUpstreams are used when dns.upstream is not configured
+var defaultDNSUpstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// DNSConfig configures the optional local DNS forwarder
+type DNSConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Listen         string        `yaml:"listen,omitempty"`           // UDP address, default 127.0.0.1:5353
+	Upstream       []string      `yaml:"upstream,omitempty"`         // Upstream resolvers, tried in order
+	Denylist       []string      `yaml:"denylist,omitempty"`         // "evil.example" or "*.evil.example"
+	BlockNewerThan time.Duration `yaml:"block_newer_than,omitempty"` // Block domains registered more recently than this (e.g. 720h); 0 disables
+}
+
+// domainAgeFunc returns when the registrable domain was registered
+type domainAgeFunc func(ctx context.Context, domain string) (time.Time, error)
+
+// dnsServer forwards DNS queries upstream, answering NXDOMAIN for blocked names
+type dnsServer struct {
+	config   DNSConfig
+	scanning *ScanningConfig
+	policies *policyEngine
+	audit    *auditLog
+	logger   *slog.Logger
+
+	domainAge domainAgeFunc
+	ageMu     sync.Mutex
+	ageCache  map[string]domainAgeResult
+
+	conn net.PacketConn
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+}
+
+type domainAgeResult struct {
+	registered time.Time
+	fetchedAt  time.Time
+}
+
+// newDNSServer creates a DNS forwarder from config
+func newDNSServer(cfg DNSConfig, scanning *ScanningConfig, policies *policyEngine, audit *auditLog, logger *slog.Logger) *dnsServer {
+	if cfg.Listen == "" {
+		cfg.Listen = defaultDNSListen
+	}
+	if len(cfg.Upstream) == 0 {
+		cfg.Upstream = defaultDNSUpstreams
+	}
+	for i, d := range cfg.Denylist {
+		cfg.Denylist[i] = strings.ToLower(strings.TrimSuffix(d, "."))
+	}
+
+	client := &http.Client{Timeout: domainAgeLookupTimeout}
+	return &dnsServer{
+		config:   cfg,
+		scanning: scanning,
+		policies: policies,
+		audit:    audit,
+		logger:   logger,
+		domainAge: func(ctx context.Context, domain string) (time.Time, error) {
+			return rdapRegistrationDate(ctx, client, domain)
+		},
+		ageCache: make(map[string]domainAgeResult),
+	}
+}
+
+// Start begins serving DNS on the configured UDP address
+func (d *dnsServer) Start() error {
+	conn, err := net.ListenPacket("udp", d.config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for DNS on %s: %w", d.config.Listen, err)
+	}
+	d.StartOn(conn)
+	return nil
+}
+
+// StartOn begins serving DNS on conn, such as a socket handed over by the
+// proxy this one replaces
+func (d *dnsServer) StartOn(conn net.PacketConn) {
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+
+	d.logger.Info("DNS protection listening", "addr", conn.LocalAddr().String(), "upstream", d.config.Upstream)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.serve(conn)
+	}()
+}
+
+// socket returns the UDP socket being served, nil before Start
+func (d *dnsServer) socket() net.PacketConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conn
+}
+
+// Stop closes the listener and waits for in-flight queries
+func (d *dnsServer) Stop() {
+	d.mu.Lock()
+	conn := d.conn
+	d.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+	d.wg.Wait()
+}
+
+func (d *dnsServer) serve(conn net.PacketConn) {
+	for {
+		buf := make([]byte, maxDNSPacketSize)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			d.logger.Debug("DNS read error", "error", err)
+			continue
+		}
+
+		d.wg.Add(1)
+		go func(query []byte, addr net.Addr) {
+			defer d.wg.Done()
+			if resp := d.handleQuery(query); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}(buf[:n], addr)
+	}
+}
+
+// handleQuery returns the response packet for a raw DNS query
+func (d *dnsServer) handleQuery(query []byte) []byte {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil {
+		return nil
+	}
+	question, err := p.Question()
+	if err != nil {
+		return nil
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	if reason := d.blockReason(name); reason != "" {
+		shadowed := d.scanning.IsShadow()
+		action := "block"
+		entry := AuditEntry{Method: "DNS", URL: name, Direction: "dns", Decision: DecisionBlock, Reason: reason}
+		if shadowed {
+			entry.Action = "allow"
+			entry.ShadowAction = action
+			d.logger.Info("shadow mode: DNS block not enforced", "name", name, "reason", reason)
+		} else {
+			entry.Action = action
+			d.logger.Warn("DNS resolution blocked", "name", name, "reason", reason)
+		}
+		d.audit.record(entry)
+
+		if !shadowed {
+			return nxdomain(header, question)
+		}
+	}
+
+	resp, err := d.forward(query)
+	if err != nil {
+		d.logger.Warn("DNS upstream failed", "name", name, "error", err)
+		return servfail(header, question)
+	}
+	return resp
+}
+
+// blockReason returns why name must not resolve, or "" if it is allowed
+func (d *dnsServer) blockReason(name string) string {
+	for _, pattern := range d.config.Denylist {
+		if matchHostPattern(pattern, name) {
+			return "domain is on the DNS denylist"
+		}
+	}
+
+	if v := d.policies.checkWindows(name, time.Now()); v != nil {
+		return fmt.Sprintf("policy %s: %s", v.Rule, v.Reason)
+	}
+
+	if d.config.BlockNewerThan > 0 {
+		if registered, ok := d.registrationDate(name); ok && time.Since(registered) < d.config.BlockNewerThan {
+			return fmt.Sprintf("domain registered %s, newer than %s", registered.Format("2006-01-02"), d.config.BlockNewerThan)
+		}
+	}
+
+	return ""
+}
+
+// registrationDate returns the cached registration date of name's registrable
+// domain. Lookups that fail are treated as unknown so DNS fails open.
+func (d *dnsServer) registrationDate(name string) (time.Time, bool) {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	d.ageMu.Lock()
+	cached, ok := d.ageCache[domain]
+	d.ageMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < domainAgeCacheTTL {
+		return cached.registered, !cached.registered.IsZero()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), domainAgeLookupTimeout)
+	defer cancel()
+
+	registered, err := d.domainAge(ctx, domain)
+	if err != nil {
+		d.logger.Debug("domain age lookup failed", "domain", domain, "error", err)
+		registered = time.Time{}
+	}
+
+	d.ageMu.Lock()
+	d.ageCache[domain] = domainAgeResult{registered: registered, fetchedAt: time.Now()}
+	d.ageMu.Unlock()
+
+	return registered, !registered.IsZero()
+}
+
+// forward sends the query to each upstream in turn until one answers
+func (d *dnsServer) forward(query []byte) ([]byte, error) {
+	var lastErr error
+	for _, upstream := range d.config.Upstream {
+		conn, err := net.DialTimeout("udp", upstream, dnsUpstreamTimeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		conn.SetDeadline(time.Now().Add(dnsUpstreamTimeout))
+		if _, err := conn.Write(query); err != nil {
+			conn.Close()
+			lastErr = err
+			continue
+		}
+
+		buf := make([]byte, maxDNSPacketSize)
+		n, err := conn.Read(buf)
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return buf[:n], nil
+	}
+	return nil, fmt.Errorf("all upstream resolvers failed: %w", lastErr)
+}
+
+// nxdomain builds a name-error response for the query
+func nxdomain(query dnsmessage.Header, question dnsmessage.Question) []byte {
+	return dnsErrorResponse(query, question, dnsmessage.RCodeNameError)
+}
+
+// servfail builds a server-failure response for the query
+func servfail(query dnsmessage.Header, question dnsmessage.Question) []byte {
+	return dnsErrorResponse(query, question, dnsmessage.RCodeServerFailure)
+}
+
+func dnsErrorResponse(query dnsmessage.Header, question dnsmessage.Question, rcode dnsmessage.RCode) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 query.ID,
+		Response:           true,
+		OpCode:             query.OpCode,
+		RecursionDesired:   query.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	if err := b.StartQuestions(); err != nil {
+		return nil
+	}
+	if err := b.Question(question); err != nil {
+		return nil
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// rdapRegistrationDate looks up a domain's registration date via RDAP
+func rdapRegistrationDate(ctx context.Context, client *http.Client, domain string) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://rdap.org/domain/"+domain, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("RDAP returned %d", resp.StatusCode)
+	}
+
+	var rdap struct {
+		Events []struct {
+			Action string    `json:"eventAction"`
+			Date   time.Time `json:"eventDate"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rdap); err != nil {
+		return time.Time{}, fmt.Errorf("invalid RDAP response: %w", err)
+	}
+
+	for _, e := range rdap.Events {
+		if e.Action == "registration" {
+			return e.Date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no registration event for %s", domain)
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startFakeResolver answers every query with a single A record of 192.0.2.1
+func startFakeResolver(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true})
+			b.StartQuestions()
+			b.Question(q)
+			b.StartAnswers()
+			b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}, dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+			msg, _ := b.Finish()
+			conn.WriteTo(msg, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func buildDNSQuery(t *testing.T, name string) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	b.StartQuestions()
+	if err := b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name + "."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		t.Fatalf("build question: %v", err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("build query: %v", err)
+	}
+	return msg
+}
+
+func dnsRCode(t *testing.T, resp []byte) dnsmessage.RCode {
+	t.Helper()
+	var p dnsmessage.Parser
+	h, err := p.Start(resp)
+	if err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+	if h.ID != 42 {
+		t.Errorf("expected response ID 42, got %d", h.ID)
+	}
+	return h.RCode
+}
+
+func newTestDNSServer(t *testing.T, cfg DNSConfig, scanning *ScanningConfig) (*dnsServer, string) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	audit := newAuditLog(LoggingConfig{AuditFile: auditPath}, logger)
+	t.Cleanup(func() { audit.Close() })
+
+	cfg.Upstream = []string{startFakeResolver(t)}
+	policies, _ := newPolicyEngine(PolicyConfig{})
+	d := newDNSServer(cfg, scanning, policies, audit, logger)
+	d.domainAge = func(ctx context.Context, domain string) (time.Time, error) {
+		if domain == "fresh.example" {
+			return time.Now().Add(-48 * time.Hour), nil
+		}
+		return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return d, auditPath
+}
+
+func TestDNSServer_Denylist(t *testing.T) {
+	d, auditPath := newTestDNSServer(t, DNSConfig{Denylist: []string{"*.evil.example"}}, &ScanningConfig{})
+
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "exfil.evil.example"))); rcode != dnsmessage.RCodeNameError {
+		t.Errorf("expected NXDOMAIN for denylisted name, got %s", rcode)
+	}
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "api.example.com"))); rcode != dnsmessage.RCodeSuccess {
+		t.Errorf("expected allowed name to resolve, got %s", rcode)
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("read audit log: %v", err)
+	}
+	if !strings.Contains(string(data), `"direction":"dns"`) || !strings.Contains(string(data), "exfil.evil.example") {
+		t.Errorf("expected DNS block in audit log, got %s", data)
+	}
+	if strings.Contains(string(data), "api.example.com") {
+		t.Error("allowed lookups must not be audited")
+	}
+}
+
+func TestDNSServer_NewlyRegistered(t *testing.T) {
+	d, _ := newTestDNSServer(t, DNSConfig{BlockNewerThan: 30 * 24 * time.Hour}, &ScanningConfig{})
+
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "login.fresh.example"))); rcode != dnsmessage.RCodeNameError {
+		t.Errorf("expected NXDOMAIN for newly registered domain, got %s", rcode)
+	}
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "www.old.example"))); rcode != dnsmessage.RCodeSuccess {
+		t.Errorf("expected established domain to resolve, got %s", rcode)
+	}
+}
+
+func TestDNSServer_ShadowModeForwards(t *testing.T) {
+	d, _ := newTestDNSServer(t, DNSConfig{Denylist: []string{"evil.example"}}, &ScanningConfig{Mode: ScanModeShadow})
+
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "evil.example"))); rcode != dnsmessage.RCodeSuccess {
+		t.Errorf("shadow mode must not block DNS, got %s", rcode)
+	}
+}
+
+func TestDNSServer_PolicyWindow(t *testing.T) {
+	d, _ := newTestDNSServer(t, DNSConfig{}, &ScanningConfig{})
+	// Egress is only allowed tomorrow
+	tomorrow := strings.ToLower(time.Now().Add(24 * time.Hour).Weekday().String()[:3])
+	d.policies = mustPolicyEngine(t, PolicyRule{Name: "off-hours", Hosts: []string{"*.example.com"}, Days: []string{tomorrow}, Hours: "00:00-23:59"})
+
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "api.example.com"))); rcode != dnsmessage.RCodeNameError {
+		t.Errorf("expected NXDOMAIN outside policy window, got %s", rcode)
+	}
+	if rcode := dnsRCode(t, d.handleQuery(buildDNSQuery(t, "other.test"))); rcode != dnsmessage.RCodeSuccess {
+		t.Errorf("expected unmatched host to resolve, got %s", rcode)
+	}
+}
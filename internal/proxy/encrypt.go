@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"stronghold/internal/proxyversion"
+	"stronghold/pkg/e2escan"
+)
+
+// scanKeyRefresh is how long before it expires a scan key is replaced, so
+// a request isn't sealed to a key that expires in flight
+const scanKeyRefresh = time.Minute
+
+// scanKeys caches the current key for end-to-end encrypted scans. Regions
+// may not share keys, so it is only used with the endpoint that issued it.
+type scanKeys struct {
+	mu       sync.Mutex
+	key      *e2escan.Key
+	endpoint string
+}
+
+// SetEncryption seals scan requests end to end, so content is only readable
+// by the scanning backend and not by load balancers or proxies in between
+func (c *ScannerClient) SetEncryption(enabled bool) {
+	if enabled {
+		c.e2e = &scanKeys{}
+	} else {
+		c.e2e = nil
+	}
+}
+
+// scanKey returns a key to seal scans to base with, fetching a new one when
+// there is none for base or it is about to expire
+func (c *ScannerClient) scanKey(ctx context.Context, base string) (*e2escan.Key, error) {
+	c.e2e.mu.Lock()
+	defer c.e2e.mu.Unlock()
+	if k := c.e2e.key; k != nil && c.e2e.endpoint == base && time.Until(k.ExpiresAt) > scanKeyRefresh {
+		return k, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", base+"/v1/scan/keys", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan key request: %w", err)
+	}
+	req.Header.Set(proxyversion.HeaderVersion, Version)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch scan key: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("failed to fetch scan key: %s - %s", resp.Status, string(body))
+	}
+	var key e2escan.Key
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return nil, fmt.Errorf("failed to decode scan key: %w", err)
+	}
+	if key.Algorithm != e2escan.Algorithm {
+		return nil, fmt.Errorf("unsupported scan key algorithm %q", key.Algorithm)
+	}
+	c.e2e.key, c.e2e.endpoint = &key, base
+	return &key, nil
+}
+
+// forgetScanKey drops the cached key after the API rejected it
+func (c *ScannerClient) forgetScanKey() {
+	c.e2e.mu.Lock()
+	c.e2e.key = nil
+	c.e2e.mu.Unlock()
+}
+
+// sendSealed seals body to the API's scan key, sends the request and
+// replaces a sealed response body with its plaintext. A request sealed to a
+// key the API no longer accepts is sent once more with a new key. Verdicts
+// are always sealed by the backend, so an unsealed 2xx response is an error:
+// something in between answered in its place.
+func (c *ScannerClient) sendSealed(ctx context.Context, base string, req *http.Request, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		key, err := c.scanKey(ctx, base)
+		if err != nil {
+			return nil, err
+		}
+		env, session, err := e2escan.Seal(key, req.Method, req.URL.Path, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt scan: %w", err)
+		}
+		sealed, err := json.Marshal(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt scan: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(sealed))
+		req.ContentLength = int64(len(sealed))
+		req.Header.Set("Content-Type", e2escan.ContentType)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), e2escan.ContentType) {
+			var sealedResp e2escan.Envelope
+			err := json.NewDecoder(resp.Body).Decode(&sealedResp)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode encrypted response: %w", err)
+			}
+			plain, err := session.OpenResponse(resp.StatusCode, &sealedResp)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt response: %w", err)
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(plain))
+			resp.Header.Set("Content-Type", "application/json")
+			return resp, nil
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unsealed %s response to an encrypted scan", resp.Status)
+		}
+		if resp.StatusCode != http.StatusBadRequest || attempt > 0 {
+			return resp, nil
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		var rejected struct {
+			Code string `json:"code"`
+		}
+		if json.Unmarshal(data, &rejected) != nil || rejected.Code != e2escan.CodeKeyRejected {
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			return resp, nil
+		}
+		c.forgetScanKey()
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts every config override variable
+const envPrefix = "STRONGHOLD_"
+
+// EnvVar describes an environment variable that overrides a config field
+type EnvVar struct {
+	Name string // e.g. STRONGHOLD_SCANNING_CONTENT_ACTION_ON_WARN
+	Path string // YAML path, e.g. scanning.content.action_on_warn
+	Type string // Go type of the field
+}
+
+// ConfigEnvVars lists the override variable for every config field, sorted
+// by name. Each field's variable is STRONGHOLD_ followed by its YAML path in
+// upper case with dots replaced by underscores.
+func ConfigEnvVars() []EnvVar {
+	var vars []EnvVar
+	walkConfig(reflect.ValueOf(&Config{}).Elem(), "", func(path string, v reflect.Value) {
+		vars = append(vars, EnvVar{Name: envName(path), Path: path, Type: v.Type().String()})
+	})
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// applyEnvOverrides sets config fields from STRONGHOLD_* variables; empty
+// variables are ignored. Strings are used as-is and string lists may be
+// comma-separated; every other value, including lists of rules, is parsed as
+// YAML (JSON works too), so
+// STRONGHOLD_POLICIES_RULES='[{"name": "openai", "hosts": ["api.openai.com"]}]'
+// replaces the policy rules.
+func applyEnvOverrides(config *Config) error {
+	var errs []string
+	walkConfig(reflect.ValueOf(config).Elem(), "", func(path string, v reflect.Value) {
+		name := envName(path)
+		raw := os.Getenv(name)
+		if raw == "" {
+			return
+		}
+		if err := setFromEnv(v, raw); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment overrides: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// walkConfig calls fn for each settable leaf field under v with its YAML
+// path. Nested structs are walked; inline structs share their parent's path.
+func walkConfig(v reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "inline") {
+			walkConfig(fv, prefix, fn)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if fv.Kind() == reflect.Struct {
+			walkConfig(fv, path, fn)
+			continue
+		}
+		fn(path, fv)
+	}
+}
+
+func envName(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// setFromEnv parses raw into a leaf field
+func setFromEnv(v reflect.Value, raw string) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(list)
+		return nil
+	}
+
+	// Decode into a fresh value so a bad override leaves the field untouched
+	ptr := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+		return fmt.Errorf("cannot parse %q as %s", raw, v.Type())
+	}
+	v.Set(ptr.Elem())
+	return nil
+}
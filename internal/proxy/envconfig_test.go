@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadConfigWithEnv(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	t.Setenv("STRONGHOLD_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	return LoadConfig()
+}
+
+func TestLoadConfig_EnvOverrides(t *testing.T) {
+	config, err := loadConfigWithEnv(t, map[string]string{
+		"STRONGHOLD_PROXY_PORT":                      "9090",
+		"STRONGHOLD_PROXY_IPV6":                      "false",
+		"STRONGHOLD_API_ENDPOINT":                    "http://localhost:8080",
+		"STRONGHOLD_API_TIMEOUT":                     "45s",
+		"STRONGHOLD_SCANNING_CONTENT_ACTION_ON_WARN": "block",
+		"STRONGHOLD_WALLET_AUTOPAY_HOSTS":            "api.example.com, *.paid.dev",
+		"STRONGHOLD_BLOCK_RESPONSE_FORMAT":           "html",
+		"STRONGHOLD_POLICIES_RULES":                  `[{"name": "openai", "hosts": ["api.openai.com"], "max_requests": 10}]`,
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if config.Proxy.Port != 9090 {
+		t.Errorf("proxy.port = %d, want 9090", config.Proxy.Port)
+	}
+	if config.Proxy.IPv6 == nil || *config.Proxy.IPv6 {
+		t.Errorf("proxy.ipv6 = %v, want false", config.Proxy.IPv6)
+	}
+	if config.API.Endpoint.String() != "http://localhost:8080" {
+		t.Errorf("api.endpoint = %q", config.API.Endpoint)
+	}
+	if config.API.Timeout != 45*time.Second {
+		t.Errorf("api.timeout = %v, want 45s", config.API.Timeout)
+	}
+	if config.Scanning.Content.ActionOnWarn != "block" {
+		t.Errorf("scanning.content.action_on_warn = %q, want block", config.Scanning.Content.ActionOnWarn)
+	}
+	if config.Scanning.Content.ActionOnBlock != "block" {
+		t.Errorf("scanning.content.action_on_block = %q, default should be kept", config.Scanning.Content.ActionOnBlock)
+	}
+	if hosts := config.Wallet.AutoPay.Hosts; len(hosts) != 2 || hosts[0] != "api.example.com" || hosts[1] != "*.paid.dev" {
+		t.Errorf("wallet.autopay.hosts = %v", hosts)
+	}
+	if config.BlockResponse.Format != "html" {
+		t.Errorf("block_response.format = %q, want html", config.BlockResponse.Format)
+	}
+	rules := config.Policies.Rules
+	if len(rules) != 1 || rules[0].Name != "openai" || rules[0].MaxRequests != 10 || len(rules[0].Hosts) != 1 {
+		t.Errorf("policies.rules = %+v", rules)
+	}
+}
+
+func TestLoadConfig_InvalidEnvOverride(t *testing.T) {
+	_, err := loadConfigWithEnv(t, map[string]string{
+		"STRONGHOLD_PROXY_PORT":  "eighty",
+		"STRONGHOLD_API_TIMEOUT": "soon",
+	})
+	if err == nil {
+		t.Fatal("expected an error for unparseable overrides")
+	}
+	for _, name := range []string{"STRONGHOLD_PROXY_PORT", "STRONGHOLD_API_TIMEOUT"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
+
+func TestConfigEnvVars(t *testing.T) {
+	seen := make(map[string]string)
+	for _, v := range ConfigEnvVars() {
+		if prev, ok := seen[v.Name]; ok {
+			t.Errorf("%s overrides both %s and %s", v.Name, prev, v.Path)
+		}
+		seen[v.Name] = v.Path
+	}
+
+	for name, path := range map[string]string{
+		"STRONGHOLD_PROXY_PORT":                      "proxy.port",
+		"STRONGHOLD_API_ENDPOINT":                    "api.endpoint",
+		"STRONGHOLD_SCANNING_OUTPUT_ACTION_ON_BLOCK": "scanning.output.action_on_block",
+		"STRONGHOLD_BLOCK_RESPONSE_FORMAT":           "block_response.format",
+		"STRONGHOLD_POLICIES_RULES":                  "policies.rules",
+	} {
+		if seen[name] != path {
+			t.Errorf("%s = %q, want %q", name, seen[name], path)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Listener handoff upgrades the proxy in place. On UpgradeSignal the running
+// proxy starts its binary again, passing it the sockets it listens on, and
+// only stops accepting and drains once the new process reports it is ready.
+// The firewall keeps redirecting to a socket that never closes, so nothing
+// is refused while the binary is replaced.
+
+const (
+	// handoffEnv names the sockets a successor inherits, in order from fd 4.
+	// fd 3 is the pipe it reports ready on.
+	handoffEnv     = "STRONGHOLD_HANDOFF"
+	handoffTimeout = 30 * time.Second // How long a successor has to report ready
+)
+
+// Sockets handed over to a successor
+const (
+	socketProxy  = "proxy"
+	socketProxy6 = "proxy6"
+	socketDNS    = "dns"
+)
+
+var errHandoffUnsupported = errors.New("listener handoff is not supported on this platform")
+
+// Successor is a proxy process started on this one's sockets
+type Successor struct {
+	Process *os.Process
+	exited  chan struct{}
+	err     error
+}
+
+// Exited is closed when the successor exits
+func (s *Successor) Exited() <-chan struct{} {
+	return s.exited
+}
+
+// Err returns how the successor exited, once Exited is closed
+func (s *Successor) Err() error {
+	return s.err
+}
+
+// Stop asks the successor to drain and exit, killing it if it has not exited
+// within timeout
+func (s *Successor) Stop(timeout time.Duration) {
+	s.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-s.exited:
+	case <-time.After(timeout):
+		s.Process.Kill()
+		<-s.exited
+	}
+}
+
+// inheritedSockets returns the sockets handed over by the proxy this one
+// replaces, by name, and the pipe to report ready on. Both are nil when the
+// proxy was started fresh.
+func inheritedSockets() (map[string]*os.File, *os.File) {
+	names := os.Getenv(handoffEnv)
+	if names == "" {
+		return nil, nil
+	}
+	os.Unsetenv(handoffEnv)
+
+	sockets := make(map[string]*os.File)
+	for i, name := range strings.Split(names, ",") {
+		sockets[name] = os.NewFile(uintptr(4+i), name)
+	}
+	return sockets, os.NewFile(3, "handoff-ready")
+}
+
+// IsSuccessor reports whether this proxy took over from a previous one. A
+// successor leaves further handoffs to the process that started it, which is
+// the one its service manager watches.
+func (s *Server) IsSuccessor() bool {
+	return s.inherited != nil
+}
+
+// inheritedListener returns the listener handed over under name, or nil if
+// there is none
+func (s *Server) inheritedListener(name string) (net.Listener, error) {
+	f := s.inherited[name]
+	if f == nil {
+		return nil, nil
+	}
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use inherited %s listener: %w", name, err)
+	}
+	return l, nil
+}
+
+// reportReady tells the proxy being replaced that this one is accepting
+// connections
+func (s *Server) reportReady() {
+	if s.ready == nil {
+		return
+	}
+	s.ready.Write([]byte{1})
+	s.ready.Close()
+	s.ready = nil
+	s.logger.Info("took over listeners from previous proxy", "ppid", os.Getppid())
+}
+
+// Handoff starts binary as a successor on this proxy's sockets and waits for
+// it to report ready. The caller then shuts this proxy down, or stops the
+// previous successor. The sockets are kept open so later handoffs can pass
+// them on after this proxy stops listening.
+func (s *Server) Handoff(binary string) (*Successor, error) {
+	s.handoffMu.Lock()
+	defer s.handoffMu.Unlock()
+	if UpgradeSignal == nil {
+		return nil, errHandoffUnsupported
+	}
+
+	if s.sockets == nil {
+		names, files, err := s.socketFiles()
+		if err != nil {
+			return nil, err
+		}
+		s.socketNames, s.sockets = names, files
+	}
+
+	successor, err := startSuccessor(binary, s.socketNames, s.sockets)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("handed listeners to new proxy", "pid", successor.Process.Pid, "binary", binary)
+	return successor, nil
+}
+
+// socketFiles duplicates the sockets this proxy listens on, in handoff order
+func (s *Server) socketFiles() ([]string, []*os.File, error) {
+	type filer interface{ File() (*os.File, error) }
+	var names []string
+	var conns []filer
+	add := func(name string, conn any) {
+		if c, ok := conn.(filer); ok {
+			names = append(names, name)
+			conns = append(conns, c)
+		}
+	}
+	add(socketProxy, s.listener)
+	add(socketProxy6, s.listener6)
+	if s.dns != nil {
+		add(socketDNS, s.dns.socket())
+	}
+	if len(names) == 0 || names[0] != socketProxy {
+		return nil, nil, errors.New("proxy is not listening")
+	}
+
+	files := make([]*os.File, 0, len(conns))
+	for i, c := range conns {
+		f, err := c.File()
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, nil, fmt.Errorf("failed to hand over %s socket: %w", names[i], err)
+		}
+		files = append(files, f)
+	}
+	return names, files, nil
+}
+
+// waitReady waits for a successor to report ready on r, failing if it exits
+// first or takes longer than handoffTimeout
+func waitReady(r *os.File, successor *Successor) error {
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := r.Read(buf)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			return nil
+		}
+		// The pipe closes without a byte when the successor exits
+		<-successor.exited
+		return fmt.Errorf("new proxy exited before it was ready: %v", successor.err)
+	case <-successor.exited:
+		return fmt.Errorf("new proxy exited before it was ready: %v", successor.err)
+	case <-time.After(handoffTimeout):
+		successor.Process.Kill()
+		<-successor.exited
+		return fmt.Errorf("new proxy was not ready within %s", handoffTimeout)
+	}
+}
+
+// startDNS serves DNS on the inherited socket if there is one, otherwise on
+// the configured address
+func (s *Server) startDNS() error {
+	f := s.inherited[socketDNS]
+	if f == nil {
+		return s.dns.Start()
+	}
+	defer f.Close()
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return fmt.Errorf("failed to use inherited DNS socket: %w", err)
+	}
+	s.dns.StartOn(conn)
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package proxy
+
+import "os"
+
+// UpgradeSignal is nil where listener handoff is not supported
+var UpgradeSignal os.Signal
+
+func startSuccessor(binary string, names []string, sockets []*os.File) (*Successor, error) {
+	return nil, errHandoffUnsupported
+}
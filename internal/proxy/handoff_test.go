@@ -0,0 +1,125 @@
+//go:build unix
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script standing in for the proxy
+// binary
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stronghold-proxy")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newListeningServer(t *testing.T) *Server {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return &Server{listener: l, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func TestHandoff_SuccessorInheritsSockets(t *testing.T) {
+	s := newListeningServer(t)
+
+	// Reports ready only if it was handed the listener as fd 4
+	binary := writeScript(t, `[ "$STRONGHOLD_HANDOFF" = "proxy" ] || exit 2
+[ -e /dev/fd/4 ] || exit 3
+printf x >&3
+exec sleep 30
+`)
+	successor, err := s.Handoff(binary)
+	if err != nil {
+		t.Fatalf("Handoff: %v", err)
+	}
+	successor.Stop(time.Second)
+	select {
+	case <-successor.Exited():
+	default:
+		t.Error("expected the successor to have exited after Stop")
+	}
+
+	// The sockets are kept for the next handoff
+	if len(s.sockets) != 1 || s.socketNames[0] != socketProxy {
+		t.Errorf("expected the proxy socket kept, got %v", s.socketNames)
+	}
+}
+
+func TestHandoff_SuccessorFailsToStart(t *testing.T) {
+	s := newListeningServer(t)
+
+	_, err := s.Handoff(writeScript(t, "exit 3\n"))
+	if err == nil || !strings.Contains(err.Error(), "exited before it was ready") {
+		t.Fatalf("expected an error for a successor that exits, got %v", err)
+	}
+
+	// The listener still accepts
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("expected the old listener to keep accepting: %v", err)
+	}
+	conn.Close()
+}
+
+func TestServer_StartsOnInheritedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	config := newTestConfig("http://127.0.0.1:1")
+	config.Proxy.Port = 1 // Ignored: the inherited socket decides the port
+	s := newTestServer(t, config)
+	s.inherited = map[string]*os.File{socketProxy: f}
+	s.ready = w
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+	defer s.Shutdown(context.Background())
+
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("expected the server to report ready: %v", err)
+	}
+	if !s.IsSuccessor() {
+		t.Error("expected IsSuccessor after inheriting sockets")
+	}
+	if s.config.Proxy.Port != port {
+		t.Errorf("expected the inherited port %d, got %d", port, s.config.Proxy.Port)
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("expected the inherited listener to accept: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// UpgradeSignal asks a running proxy to hand its sockets to a fresh start of
+// its binary and drain; see Server.Handoff
+var UpgradeSignal os.Signal = syscall.SIGUSR2
+
+// startSuccessor runs binary with the sockets as extra files and waits for
+// it to report ready. Its output goes to this process's.
+func startSuccessor(binary string, names []string, sockets []*os.File) (*Successor, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(binary)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, handoffEnv+"=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Env = append(cmd.Env, handoffEnv+"="+strings.Join(names, ","))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append([]*os.File{w}, sockets...)
+
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+
+	successor := &Successor{Process: cmd.Process, exited: make(chan struct{})}
+	go func() {
+		successor.err = cmd.Wait()
+		close(successor.exited)
+	}()
+
+	if err := waitReady(r, successor); err != nil {
+		return nil, err
+	}
+	return successor, nil
+}
@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ThreatCredentialExfiltration is the threat category for credentials in
+// outbound request headers or bodies bound for a non-allowlisted host
+const ThreatCredentialExfiltration = "credential_exfiltration"
+
+// Header scan actions
+const (
+	HeaderActionBlock  = "block"  // Reject the request
+	HeaderActionRedact = "redact" // Strip the credentials and forward
+	HeaderActionWarn   = "warn"   // Log and audit, forward unchanged
+)
+
+// HeaderScanConfig configures scanning of outbound request headers and
+// cookies for credentials
+type HeaderScanConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Action    string   `yaml:"action,omitempty"`    // "block" (default), "redact", or "warn"
+	Allowlist []string `yaml:"allowlist,omitempty"` // Hosts credentials may be sent to: "api.github.com" or "*.amazonaws.com"
+}
+
+// action returns the configured action, defaulting to block
+func (c HeaderScanConfig) action() string {
+	switch c.Action {
+	case HeaderActionRedact, HeaderActionWarn:
+		return c.Action
+	default:
+		return HeaderActionBlock
+	}
+}
+
+// headerFinding is a credential found in an outbound header
+type headerFinding struct {
+	Header string // Canonical header name
+	Cookie string // Cookie name, for session cookies
+	Kind   string // "bearer_token", "session_cookie", "aws_access_key", ...
+}
+
+// authHeaders always carry credentials when present
+var authHeaders = map[string]string{
+	"Authorization":        "authorization",
+	"X-Api-Key":            "api_key",
+	"Api-Key":              "api_key",
+	"X-Auth-Token":         "auth_token",
+	"X-Amz-Security-Token": "aws_session_token",
+	"X-Goog-Api-Key":       "gcp_api_key",
+	"Private-Token":        "gitlab_token",
+}
+
+// credentialPatterns match well-known credential formats in any header value
+var credentialPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"aws_access_key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"gcp_oauth_token", regexp.MustCompile(`\bya29\.[0-9A-Za-z_-]{20,}`)},
+	{"gcp_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"github_token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z-]{10,}`)},
+	{"stripe_key", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{20,}`)},
+	{"api_secret_key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`)},
+}
+
+// sessionCookieNames are substrings of cookie names that hold sessions
+var sessionCookieNames = []string{"session", "sess", "sid", "token", "auth", "jwt", "login"}
+
+// headerScanner checks outbound request headers for credentials
+type headerScanner struct {
+	config HeaderScanConfig
+}
+
+// newHeaderScanner returns nil when header scanning is disabled
+func newHeaderScanner(cfg HeaderScanConfig) *headerScanner {
+	if !cfg.Enabled {
+		return nil
+	}
+	for i, h := range cfg.Allowlist {
+		cfg.Allowlist[i] = strings.ToLower(h)
+	}
+	return &headerScanner{config: cfg}
+}
+
+// allowed reports whether credentials may be sent to host
+func (s *headerScanner) allowed(host string) bool {
+	host = strings.ToLower(host)
+	return slices.ContainsFunc(s.config.Allowlist, func(p string) bool {
+		return matchHostPattern(p, host)
+	})
+}
+
+// scan inspects header for credentials bound for host. It returns nil when
+// nothing was found or host is allowlisted; otherwise a result describing the
+// findings and the configured action. Headers are not modified.
+func (s *headerScanner) scan(header http.Header, host string) (*ScanResult, []headerFinding, string) {
+	if s == nil || s.allowed(host) {
+		return nil, nil, ""
+	}
+
+	findings := findCredentials(header)
+	if len(findings) == 0 {
+		return nil, nil, ""
+	}
+
+	action := s.config.action()
+	decision := DecisionBlock
+	if action != HeaderActionBlock {
+		decision = DecisionWarn
+	}
+
+	kinds := make([]string, 0, len(findings))
+	threats := make([]Threat, 0, len(findings))
+	for _, f := range findings {
+		location := "header " + f.Header
+		if f.Cookie != "" {
+			location = "cookie " + f.Cookie
+		}
+		if !slices.Contains(kinds, f.Kind) {
+			kinds = append(kinds, f.Kind)
+		}
+		threats = append(threats, Threat{
+			Category:    ThreatCredentialExfiltration,
+			Pattern:     f.Kind,
+			Location:    location,
+			Severity:    "high",
+			Description: fmt.Sprintf("Credential sent to non-allowlisted host %s", host),
+		})
+	}
+
+	return &ScanResult{
+		Decision:          decision,
+		Reason:            fmt.Sprintf("Credential exfiltration: %s sent to %s", strings.Join(kinds, ", "), host),
+		ThreatsFound:      threats,
+		RecommendedAction: "Add the host to scanning.headers.allowlist if it should receive these credentials",
+	}, findings, action
+}
+
+// findCredentials returns the credentials found in header
+func findCredentials(header http.Header) []headerFinding {
+	var findings []headerFinding
+	for name, values := range header {
+		name = http.CanonicalHeaderKey(name)
+		if name == "Cookie" {
+			for _, c := range sessionCookies(values) {
+				findings = append(findings, headerFinding{Header: name, Cookie: c, Kind: "session_cookie"})
+			}
+			continue
+		}
+		if kind, ok := authHeaders[name]; ok {
+			if name == "Authorization" {
+				kind = authorizationKind(strings.Join(values, ","))
+			}
+			findings = append(findings, headerFinding{Header: name, Kind: kind})
+			continue
+		}
+		for _, p := range credentialPatterns {
+			if slices.ContainsFunc(values, p.re.MatchString) {
+				findings = append(findings, headerFinding{Header: name, Kind: p.kind})
+				break
+			}
+		}
+	}
+	slices.SortFunc(findings, func(a, b headerFinding) int {
+		return strings.Compare(a.Header+a.Cookie, b.Header+b.Cookie)
+	})
+	return findings
+}
+
+// authorizationKind names the credential scheme of an Authorization value
+func authorizationKind(value string) string {
+	scheme, _, _ := strings.Cut(strings.TrimSpace(value), " ")
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		return "bearer_token"
+	case "basic":
+		return "basic_auth"
+	case "aws4-hmac-sha256":
+		return "aws_signature"
+	default:
+		return "authorization"
+	}
+}
+
+// sessionCookies returns the names of cookies that look like sessions
+func sessionCookies(values []string) []string {
+	var names []string
+	for _, c := range parseCookies(values) {
+		if isSessionCookie(c.Name) {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func isSessionCookie(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "__secure-") || strings.HasPrefix(lower, "__host-") {
+		return true
+	}
+	return slices.ContainsFunc(sessionCookieNames, func(s string) bool {
+		return strings.Contains(lower, s)
+	})
+}
+
+func parseCookies(values []string) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, v := range values {
+		parsed, err := http.ParseCookie(v)
+		if err != nil {
+			continue
+		}
+		cookies = append(cookies, parsed...)
+	}
+	return cookies
+}
+
+// redactHeaders removes the credentials in findings from header. Session
+// cookies are dropped individually; other cookies are kept.
+func redactHeaders(header http.Header, findings []headerFinding) {
+	var dropCookies []string
+	for _, f := range findings {
+		if f.Cookie != "" {
+			dropCookies = append(dropCookies, f.Cookie)
+			continue
+		}
+		header.Del(f.Header)
+	}
+	if len(dropCookies) == 0 {
+		return
+	}
+
+	var kept []string
+	for _, c := range parseCookies(header.Values("Cookie")) {
+		if !slices.Contains(dropCookies, c.Name) {
+			kept = append(kept, c.Name+"="+c.Value)
+		}
+	}
+	header.Del("Cookie")
+	if len(kept) > 0 {
+		header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
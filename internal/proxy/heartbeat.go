@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"stronghold/internal/proxyversion"
+)
+
+const defaultHeartbeatInterval = 5 * time.Minute
+
+// Protection statuses reported in heartbeats
+const (
+	ProtectionProtected = "protected" // Scanning and enforcing decisions
+	ProtectionShadow    = "shadow"    // Scanning, but decisions are only logged
+	ProtectionDegraded  = "degraded"  // Scans failed since the last heartbeat
+)
+
+// Heartbeat reports the install's version and protection status to the API
+type Heartbeat struct {
+	Version          string `json:"version"`
+	ProtectionStatus string `json:"protection_status"`
+}
+
+// SendHeartbeat reports in as the client's registered install. The request
+// is authenticated by the install signature alone.
+func (c *ScannerClient) SendHeartbeat(ctx context.Context, hb Heartbeat) error {
+	body, err := json.Marshal(hb)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL()+"/v1/installs/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(proxyversion.HeaderVersion, Version)
+	c.sign(req, body)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUpgradeRequired {
+		return c.upgradeRequired(resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("heartbeat failed: %s - %s", resp.Status, string(body))
+	}
+
+	var result struct {
+		Update *proxyversion.Update `json:"update"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode heartbeat response: %w", err)
+	}
+	if result.Update != nil {
+		c.update.Store(result.Update)
+	}
+	return nil
+}
+
+// heartbeater periodically reports the install's protection status so fleet
+// operators can spot machines whose protection went dark
+type heartbeater struct {
+	scanner  *ScannerClient
+	interval time.Duration
+	shadow   bool
+	logger   *slog.Logger
+}
+
+// newHeartbeater returns nil when the proxy has no registered install to
+// report as
+func newHeartbeater(api APIConfig, scanning ScanningConfig, scanner *ScannerClient, logger *slog.Logger) *heartbeater {
+	if scanner.installKey == nil {
+		return nil
+	}
+	interval := api.HeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	return &heartbeater{
+		scanner:  scanner,
+		interval: interval,
+		shadow:   scanning.IsShadow(),
+		logger:   logger,
+	}
+}
+
+// run reports in at startup and then every interval until ctx is done
+func (h *heartbeater) run(ctx context.Context) {
+	if h == nil {
+		return
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.beat(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *heartbeater) beat(ctx context.Context) {
+	hb := Heartbeat{Version: Version, ProtectionStatus: h.status()}
+	if err := h.scanner.SendHeartbeat(ctx, hb); err != nil && ctx.Err() == nil {
+		h.logger.Warn("heartbeat failed", "error", err)
+	}
+}
+
+// status returns the protection status since the last heartbeat. Failed scans
+// take precedence over shadow mode: either way the machine isn't enforcing.
+func (h *heartbeater) status() string {
+	if h.scanner.failures.Swap(0) > 0 {
+		return ProtectionDegraded
+	}
+	if h.shadow {
+		return ProtectionShadow
+	}
+	return ProtectionProtected
+}
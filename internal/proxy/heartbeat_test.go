@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stronghold/internal/identity"
+	"stronghold/internal/proxyversion"
+)
+
+func TestHeartbeater_ReportsSignedStatus(t *testing.T) {
+	key, err := identity.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+
+	var got []Heartbeat
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/v1/installs/heartbeat" {
+			if err := identity.Verify(pub, r.Method, r.URL.Path, body,
+				r.Header.Get(identity.HeaderTimestamp), r.Header.Get(identity.HeaderSignature), time.Now()); err != nil {
+				t.Errorf("heartbeat signature did not verify: %v", err)
+			}
+			var hb Heartbeat
+			json.Unmarshal(body, &hb)
+			got = append(got, hb)
+			if r.Header.Get(proxyversion.HeaderVersion) != Version {
+				t.Errorf("version header = %q", r.Header.Get(proxyversion.HeaderVersion))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"status": "ok",
+				"update": proxyversion.Update{Status: proxyversion.StatusRecommended, Version: Version, Recommended: "1.4.0"},
+			})
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	scanner := NewScannerClient(server.URL, "")
+	if newHeartbeater(APIConfig{}, ScanningConfig{}, scanner, slog.Default()) != nil {
+		t.Fatal("expected no heartbeater without an install identity")
+	}
+	scanner.SetIdentity("2b1f6c2e-8a57-4c59-9d1e-3f8c1f0f4a11", key)
+	h := newHeartbeater(APIConfig{}, ScanningConfig{Mode: ScanModeShadow}, scanner, slog.Default())
+	if h.interval != defaultHeartbeatInterval {
+		t.Errorf("interval = %v, want default", h.interval)
+	}
+
+	h.beat(context.Background())
+	if _, err := scanner.ScanContent(context.Background(), []byte("x"), "http://example.com", "text/plain"); err == nil {
+		t.Fatal("expected scan to fail")
+	}
+	h.beat(context.Background())
+	h.beat(context.Background())
+
+	if u := scanner.Update(); u == nil || u.Recommended != "1.4.0" {
+		t.Errorf("update signal from heartbeat not recorded: %+v", u)
+	}
+
+	want := []string{ProtectionShadow, ProtectionDegraded, ProtectionShadow}
+	if len(got) != len(want) {
+		t.Fatalf("got %d heartbeats, want %d", len(got), len(want))
+	}
+	for i, hb := range got {
+		if hb.ProtectionStatus != want[i] {
+			t.Errorf("heartbeat %d status = %q, want %q", i, hb.ProtectionStatus, want[i])
+		}
+		if hb.Version != Version {
+			t.Errorf("heartbeat %d version = %q", i, hb.Version)
+		}
+	}
+}
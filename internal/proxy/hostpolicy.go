@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"stronghold/internal/configschema"
+)
+
+// scanModes are the values accepted for a scan mode
+var scanModes = map[string]bool{"smart": true, "strict": true, "permissive": true, ScanModeShadow: true}
+
+// scanActions are the values accepted for a scan action
+var scanActions = map[string]bool{"allow": true, "warn": true, "block": true}
+
+// hostPolicy is a validated per-host scanning policy
+type hostPolicy struct {
+	pattern string
+	configschema.HostPolicy
+}
+
+// hostPolicies resolves the scanning policy for a destination host. An
+// exact hostname wins over wildcards, a longer wildcard over a shorter one,
+// and "*" applies only when nothing else matches.
+type hostPolicies struct {
+	exact     map[string]*hostPolicy
+	wildcards []*hostPolicy // Longest suffix first
+	fallback  *hostPolicy
+}
+
+// newHostPolicies validates the policies.hosts config. It returns nil when
+// no host policies are configured.
+func newHostPolicies(hosts map[string]configschema.HostPolicy) (*hostPolicies, error) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	h := &hostPolicies{exact: make(map[string]*hostPolicy)}
+	for pattern, policy := range hosts {
+		p := &hostPolicy{pattern: strings.ToLower(strings.TrimSpace(pattern)), HostPolicy: policy}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("host %q: %w", pattern, err)
+		}
+
+		suffix, wildcard := strings.CutPrefix(p.pattern, "*.")
+		switch {
+		case p.pattern == "*":
+			h.fallback = p
+		case wildcard && suffix != "" && !strings.Contains(suffix, "*"):
+			h.wildcards = append(h.wildcards, p)
+		case p.pattern != "" && !strings.Contains(p.pattern, "*"):
+			if _, dup := h.exact[p.pattern]; dup {
+				return nil, fmt.Errorf("host %q: configured more than once", pattern)
+			}
+			h.exact[p.pattern] = p
+		default:
+			return nil, fmt.Errorf("host %q: must be a hostname, \"*.domain\", or \"*\"", pattern)
+		}
+	}
+
+	sort.Slice(h.wildcards, func(i, j int) bool {
+		a, b := h.wildcards[i].pattern, h.wildcards[j].pattern
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	for i := 1; i < len(h.wildcards); i++ {
+		if h.wildcards[i].pattern == h.wildcards[i-1].pattern {
+			return nil, fmt.Errorf("host %q: configured more than once", h.wildcards[i].pattern)
+		}
+	}
+	return h, nil
+}
+
+// validate checks a policy's mode and action
+func (p *hostPolicy) validate() error {
+	if p.Mode != "" && !scanModes[p.Mode] {
+		return fmt.Errorf("invalid mode %q (must be smart, strict, permissive, or shadow)", p.Mode)
+	}
+	if p.ActionOnBlock != "" && !scanActions[p.ActionOnBlock] {
+		return fmt.Errorf("invalid action_on_block %q (must be allow, warn, or block)", p.ActionOnBlock)
+	}
+	if p.Skip && (p.Mode != "" || p.ActionOnBlock != "") {
+		return errors.New("skip can't be combined with mode or action_on_block")
+	}
+	if !p.Skip && p.Mode == "" && p.ActionOnBlock == "" {
+		return errors.New("must set skip, mode, or action_on_block")
+	}
+	return nil
+}
+
+// lookup returns the policy for host, or nil when none matches
+func (h *hostPolicies) lookup(host string) *hostPolicy {
+	if h == nil {
+		return nil
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if p, ok := h.exact[host]; ok {
+		return p
+	}
+	for _, p := range h.wildcards {
+		if matchHostPattern(p.pattern, host) {
+			return p
+		}
+	}
+	return h.fallback
+}
+
+// scanning returns the scanning config for content to and from host, and
+// whether its policy skips content scanning. base is returned unchanged
+// when no policy overrides it.
+func (h *hostPolicies) scanning(host string, base *ScanningConfig) (*ScanningConfig, bool) {
+	p := h.lookup(host)
+	if p == nil {
+		return base, false
+	}
+	if p.Skip {
+		return base, true
+	}
+
+	scanning := *base
+	if p.Mode != "" {
+		scanning.Mode = p.Mode
+	}
+	if p.ActionOnBlock != "" {
+		scanning.Content.ActionOnBlock = p.ActionOnBlock
+	}
+	return &scanning, false
+}
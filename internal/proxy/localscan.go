@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"stronghold/internal/rulepack"
+)
+
+// bundledRules is the rule pack the local scanner always matches
+//
+//go:embed localrules.yaml
+var bundledRules []byte
+
+// LocalScanConfig configures in-process scanning, used in place of the API
+// when scanning.local_only is set
+type LocalScanConfig struct {
+	RulePacks   []string `yaml:"rule_packs,omitempty"`   // Rule pack YAML files matched alongside the bundled rules
+	APIFallback bool     `yaml:"api_fallback,omitempty"` // Send content no local rule flagged to the API, keeping the local verdict when it is unreachable
+}
+
+// localScanner matches content against the bundled rule pack and any
+// configured ones, without network access. It only catches known patterns;
+// the API's detectors see far more.
+type localScanner struct {
+	packs   []*rulepack.Compiled
+	version string
+}
+
+// newLocalScanner compiles the bundled rules and the packs in cfg. A pack
+// whose tests fail is refused like an import through the API would be.
+func newLocalScanner(cfg LocalScanConfig) (*localScanner, error) {
+	bundled, err := rulepack.Parse(bundledRules)
+	if err != nil {
+		return nil, fmt.Errorf("bundled rules: %w", err)
+	}
+	l := &localScanner{packs: []*rulepack.Compiled{bundled}}
+	versions := []string{bundled.Pack.Name + "@" + bundled.Pack.Version}
+	for _, path := range cfg.RulePacks {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("rule pack %s: %w", path, err)
+		}
+		pack, err := rulepack.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("rule pack %s: %w", path, err)
+		}
+		l.packs = append(l.packs, pack)
+		versions = append(versions, pack.Pack.Name+"@"+pack.Pack.Version)
+	}
+	l.version = "local:" + strings.Join(versions, ",")
+	return l, nil
+}
+
+// scan returns the local verdict for content. High and critical matches
+// block, medium ones warn.
+func (l *localScanner) scan(content []byte) *ScanResult {
+	result := &ScanResult{
+		Decision:         DecisionAllow,
+		Scores:           map[string]float64{},
+		Reason:           "No local rule matched",
+		Metadata:         map[string]interface{}{"engine": "local"},
+		DetectionVersion: l.version,
+	}
+	rulepack.Apply(result, string(content), rulepack.TargetContent, l.packs)
+	if result.Decision == DecisionAllow {
+		return result
+	}
+
+	names := make([]string, 0, len(result.ThreatsFound))
+	for _, t := range result.ThreatsFound {
+		names = append(names, t.Pattern)
+	}
+	result.Reason = "Local rule match: " + strings.Join(names, ", ")
+	if result.Decision == DecisionBlock {
+		result.RecommendedAction = "DO NOT PROCEED - Content matches a known prompt injection or exfiltration pattern."
+	} else {
+		result.RecommendedAction = "Caution advised - Content matches a suspicious pattern."
+	}
+	return result
+}
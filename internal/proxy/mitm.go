@@ -3,9 +3,7 @@ package proxy
 import (
 	"bufio"
 	"bytes"
-	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -13,14 +11,30 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"stronghold/internal/formtext"
 )
 
 // MITMHandler handles transparent HTTPS interception (Man-In-The-Middle)
 type MITMHandler struct {
-	certCache *CertCache
-	scanner   *ScannerClient
-	config    *Config
-	logger    *slog.Logger
+	certCache   *CertCache
+	scanner     *ScannerClient
+	config      *Config
+	logger      *slog.Logger
+	onShadow    func()           // called when shadow mode suppresses an action
+	blocks      *blockResponder  // renders block responses; defaults to the built-in JSON response
+	bypass      *bypassVerifier  // verifies bypass tokens; nil disables them
+	policies    *policyEngine    // local egress policies; nil disables them
+	hosts       *hostPolicies    // per-host scanning policies; nil scans every host alike
+	audit       *auditLog        // records flagged decisions; nil disables auditing
+	headerScan  *headerScanner   // checks outbound headers for credentials; nil disables it
+	canaries    *canaryWatcher   // injects and watches for canary tokens; nil disables them
+	requestScan *requestScanner  // checks outbound bodies and headers for secrets; nil disables it
+	upstream    *upstreamPool    // forwards requests over pooled connections
+	scans       *scanScheduler   // bounds concurrent scans; nil means unlimited
+	autopay     *autoPayer       // pays upstream 402s; nil disables it
+	offline     *offlineQueue    // retro-scans content passed by fail_open; nil disables it
+	pressure    *pressureMonitor // sheds scans over the resource budget; nil never sheds
 }
 
 // NewMITMHandler creates a new MITM handler
@@ -30,6 +44,7 @@ func NewMITMHandler(certCache *CertCache, scanner *ScannerClient, config *Config
 		scanner:   scanner,
 		config:    config,
 		logger:    logger,
+		upstream:  newUpstreamPool(config.Proxy.Pool),
 	}
 }
 
@@ -71,25 +86,20 @@ func (m *MITMHandler) HandleTLS(clientConn net.Conn, originalDst string) error {
 	tlsClientConn.SetDeadline(time.Time{})
 	defer tlsClientConn.Close()
 
-	// Connect to actual server with TLS (with connection timeout)
-	serverConn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", originalDst, &tls.Config{
-		ServerName: host,
-		MinVersion: tls.VersionTLS12,
-	})
-	if err != nil {
-		m.logger.Error("failed to connect to server", "host", host, "error", err)
-		return fmt.Errorf("failed to connect to server: %w", err)
+	// Handle HTTP requests over the TLS connection; the upstream side uses
+	// pooled connections shared with other clients
+	upstreamHost := host
+	if port != "443" {
+		upstreamHost = net.JoinHostPort(host, port)
 	}
-	defer serverConn.Close()
-
-	// Handle HTTP requests over the TLS connection
-	return m.proxyHTTPS(tlsClientConn, serverConn, host)
+	return m.proxyHTTPS(tlsClientConn, host, upstreamHost)
 }
 
-// proxyHTTPS proxies HTTP requests over established TLS connections
-func (m *MITMHandler) proxyHTTPS(clientConn, serverConn net.Conn, host string) error {
+// proxyHTTPS proxies HTTP requests from an established client TLS connection.
+// host is the destination hostname and upstreamHost the host[:port] requests
+// are sent to.
+func (m *MITMHandler) proxyHTTPS(clientConn net.Conn, host, upstreamHost string) error {
 	clientReader := bufio.NewReader(clientConn)
-	serverReader := bufio.NewReader(serverConn)
 
 	for {
 		// Set read deadline to detect closed connections
@@ -106,80 +116,175 @@ func (m *MITMHandler) proxyHTTPS(clientConn, serverConn net.Conn, host string) e
 
 		// Fix up the request URL for proxying
 		req.URL.Scheme = "https"
-		req.URL.Host = host
+		req.URL.Host = upstreamHost
 		req.RequestURI = "" // Must be empty for client requests
 
-		m.logger.Debug("MITM request", "method", req.Method, "url", req.URL.String())
+		requestID := generateRequestID()
+		m.logger.Debug("MITM request", "method", req.Method, "url", req.URL.String(), "requestID", requestID)
+
+		if v := m.policies.enforce(host, &m.config.Scanning, m.logger); v != nil {
+			m.sendPolicyResponse(clientConn, v, req)
+			continue
+		}
+
+		// Per-host policies may skip content scanning or change how it is scanned
+		scanning, skipped := m.hosts.scanning(host, &m.config.Scanning)
+
+		bypassed := m.bypass.check(req, host) != nil
+
+		// Credentials in outbound headers are checked before they leave
+		if !bypassed && m.checkRequestHeaders(clientConn, req, host, requestID) {
+			continue
+		}
+		if !bypassed && m.checkCanaries(clientConn, req, host, requestID) {
+			continue
+		}
+		if !bypassed && m.checkRequestSecrets(clientConn, req, host, requestID) {
+			continue
+		}
+		m.canaries.inject(req, host, m.config.Scanning.Limits.maxBodySize())
 
 		// Scan request body if it exists (for prompt injection in POST data)
 		var requestBody []byte
-		if req.Body != nil && req.ContentLength != 0 && m.config.Scanning.Content.Enabled {
+		if req.Body != nil && req.ContentLength != 0 && scanning.Content.Enabled && !bypassed && !skipped {
 			var readErr error
-			requestBody, readErr = io.ReadAll(io.LimitReader(req.Body, 1024*1024+1))
-			req.Body.Close()
+			originalBody := req.Body
+			maxBody := m.config.Scanning.Limits.maxBodySize()
+			requestBody, readErr = io.ReadAll(io.LimitReader(originalBody, int64(maxBody)+1))
 			if readErr != nil {
 				m.logger.Error("failed to read request body", "url", req.URL.String(), "error", readErr)
 			}
 
-			// Scan the request content (skip if over 1MB)
-			if len(requestBody) > 0 && len(requestBody) <= 1024*1024 {
-				result := m.scanContent(requestBody, req.URL.String(), req.Header.Get("Content-Type"))
+			// Scan the request content (skip if over the scan limit). Form
+			// uploads are the exception: the text fields within the limit are
+			// still scanned, and the rest of the body streams through untouched.
+			reqContentType := req.Header.Get("Content-Type")
+			oversized := len(requestBody) > maxBody
+			if len(requestBody) > 0 && (!oversized || formtext.IsForm(reqContentType)) {
+				result := m.scanContent(requestBody, req.URL.String(), reqContentType)
 				if result != nil && result.Decision == DecisionBlock {
-					// Block the request
-					m.sendBlockResponse(clientConn, result, req)
-					continue
+					shadowed := m.shadowed(scanning, "block", result, req)
+					m.recordAudit(requestID, req, "request", result, "block", shadowed)
+					if !shadowed {
+						// Block the request
+						m.sendBlockResponse(clientConn, result, req, requestID)
+						if oversized {
+							// The unread body is still on the connection
+							return nil
+						}
+						continue
+					}
 				}
 			}
 
 			// Restore body for forwarding
-			req.Body = io.NopCloser(strings.NewReader(string(requestBody)))
+			if oversized {
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(requestBody), originalBody), originalBody}
+			} else {
+				originalBody.Close()
+				req.Body = io.NopCloser(bytes.NewReader(requestBody))
+			}
 		}
 
-		// Forward request to server
-		if err := req.Write(serverConn); err != nil {
+		// Forward request to server over a pooled connection
+		resp, err := m.autopay.roundTrip(m.upstream, req, requestID)
+		if err != nil {
+			m.logger.Error("failed to forward request", "host", host, "error", err)
 			return fmt.Errorf("failed to forward request: %w", err)
 		}
 
-		// Read response from server
-		resp, err := http.ReadResponse(serverReader, req)
-		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+		contentType := resp.Header.Get("Content-Type")
+
+		// Event streams are scanned window by window as they arrive
+		if scanning.Content.Enabled && scanning.Streaming.Enabled && !bypassed && !skipped && IsEventStreamContentType(contentType) {
+			if err := m.streamResponse(clientConn, resp, req, requestID, scanning); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// Check if response should be scanned before reading the full body
-		contentType := resp.Header.Get("Content-Type")
-		shouldScan := m.config.Scanning.Content.Enabled &&
+		shouldScan := scanning.Content.Enabled && !bypassed && !skipped &&
 			ShouldScanContentType(contentType) && !IsBinaryContentType(contentType)
 
+		// Over the resource budget, some scans are shed rather than buffered
+		shed := shedNone
 		if shouldScan {
-			// Read body for scanning (with 1MB limit + 1 byte to detect oversized)
-			responseBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024+1))
+			shed = m.pressure.shed(contentType)
+		}
+		switch shed {
+		case shedBlock:
+			m.logger.Warn("content blocked under resource pressure", "url", req.URL.String(), "content_type", contentType, "requestID", requestID)
 			resp.Body.Close()
+			m.sendBlockResponse(clientConn, pressureBlockResult(), req, requestID)
+			continue
+		case shedPass:
+			m.logger.Warn("scan skipped under resource pressure", "url", req.URL.String(), "content_type", contentType, "requestID", requestID)
+			shouldScan = false
+		}
+
+		if shouldScan {
+			// Read body for scanning (up to the scan limit + 1 byte to detect oversized)
+			maxBody := m.config.Scanning.Limits.maxBodySize()
+			responseBody, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)+1))
 			if err != nil {
+				resp.Body.Close()
 				return fmt.Errorf("failed to read response body: %w", err)
 			}
+			originalBody := resp.Body
+			oversized := len(responseBody) > maxBody
+			if !oversized {
+				originalBody.Close()
+			}
 
 			// Scan if within size limit
 			var scanResult *ScanResult
-			if len(responseBody) > 0 && len(responseBody) <= 1024*1024 {
+			if len(responseBody) > 0 && !oversized {
 				scanResult = m.scanContent(responseBody, req.URL.String(), contentType)
 			}
+			if !oversized {
+				scanResult = withHeaderFindings(scanResult, resp.Header)
+			}
 
 			// Add Stronghold headers
 			resp.Header.Set("X-Stronghold-Proxy", "mitm")
+			resp.Header.Set("X-Stronghold-Request-ID", requestID)
 			if scanResult != nil {
 				resp.Header.Set("X-Stronghold-Decision", string(scanResult.Decision))
 				resp.Header.Set("X-Stronghold-Reason", scanResult.Reason)
+				setCostHeader(resp.Header, scanResult)
 
 				// Block if needed
-				action := getAction(scanResult.Decision, m.config.Scanning.Content)
+				action := getAction(scanResult.Decision, scanning.Content)
+				shadowed := m.shadowed(scanning, action, scanResult, req)
+				if scanResult.Decision != DecisionAllow {
+					m.recordAudit(requestID, req, "response", scanResult, action, shadowed)
+				}
+				if shadowed {
+					resp.Header.Set("X-Stronghold-Shadow-Action", action)
+					action = "allow"
+				}
 				if action == "block" {
-					m.sendBlockResponse(clientConn, scanResult, req)
+					m.sendBlockResponse(clientConn, scanResult, req, requestID)
 					continue
 				}
 			}
 
-			// Forward response to client with the read body
+			// Forward response to client with the read body. Oversized
+			// bodies stream the unread remainder after it.
+			if oversized {
+				resp.Header.Set("X-Stronghold-Scan-Type", "skipped-oversized")
+				resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(responseBody), originalBody))
+				err := resp.Write(clientConn)
+				originalBody.Close()
+				if err != nil {
+					return fmt.Errorf("failed to forward response: %w", err)
+				}
+				continue
+			}
 			resp.Body = io.NopCloser(bytes.NewReader(responseBody))
 			resp.ContentLength = int64(len(responseBody))
 
@@ -189,6 +294,13 @@ func (m *MITMHandler) proxyHTTPS(clientConn, serverConn net.Conn, host string) e
 		} else {
 			// Non-scannable content: stream directly without buffering
 			resp.Header.Set("X-Stronghold-Proxy", "mitm")
+			if bypassed {
+				resp.Header.Set("X-Stronghold-Scan-Type", "bypassed")
+			} else if skipped {
+				resp.Header.Set("X-Stronghold-Scan-Type", "skipped-policy")
+			} else if shed == shedPass {
+				resp.Header.Set("X-Stronghold-Scan-Type", "skipped-pressure")
+			}
 			if err := resp.Write(clientConn); err != nil {
 				resp.Body.Close()
 				return fmt.Errorf("failed to forward response: %w", err)
@@ -200,13 +312,16 @@ func (m *MITMHandler) proxyHTTPS(clientConn, serverConn net.Conn, host string) e
 
 // scanContent scans content for threats
 func (m *MITMHandler) scanContent(body []byte, sourceURL, contentType string) *ScanResult {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	payload, payloadType, ok := scanPayload(body, contentType)
+	if !ok {
+		return nil
+	}
 
-	result, err := m.scanner.ScanContent(ctx, body, sourceURL, contentType)
+	result, err := scanText(m.scanner, m.scans, m.config.Scanning.Limits, payload, sourceURL, payloadType)
 	if err != nil {
 		m.logger.Error("scan error", "error", err)
 		if m.config.Scanning.FailOpen {
+			m.offline.enqueue(payload, sourceURL, payloadType)
 			return nil
 		}
 		return &ScanResult{
@@ -218,36 +333,180 @@ func (m *MITMHandler) scanContent(body []byte, sourceURL, contentType string) *S
 	return result
 }
 
+// checkRequestHeaders applies header scanning to an outbound request,
+// redacting credentials in place or sending a block response. It reports
+// whether the request was blocked.
+func (m *MITMHandler) checkRequestHeaders(conn net.Conn, req *http.Request, host, requestID string) bool {
+	result, findings, action := m.headerScan.scan(req.Header, host)
+	if result == nil {
+		return false
+	}
+
+	shadowed := m.shadowed(&m.config.Scanning, action, result, req)
+	m.recordAudit(requestID, req, "request", result, action, shadowed)
+	if shadowed {
+		return false
+	}
+
+	switch action {
+	case HeaderActionBlock:
+		if req.Body != nil {
+			io.Copy(io.Discard, req.Body)
+			req.Body.Close()
+		}
+		m.sendBlockResponse(conn, result, req, requestID)
+		return true
+	case HeaderActionRedact:
+		m.logger.Warn("credentials redacted from request", "url", req.URL.String(), "reason", result.Reason)
+		redactHeaders(req.Header, findings)
+	default:
+		m.logger.Warn("credential exfiltration detected", "url", req.URL.String(), "reason", result.Reason)
+	}
+	return false
+}
+
+// checkCanaries looks for canary tokens in an outbound request and raises a
+// critical alert when one is found, sending a block response unless the
+// canary action is warn. It reports whether the request was blocked.
+func (m *MITMHandler) checkCanaries(conn net.Conn, req *http.Request, host, requestID string) bool {
+	result, action := m.canaries.check(req, host, m.config.Scanning.Limits.maxBodySize())
+	if result == nil {
+		return false
+	}
+
+	m.logger.Error("CANARY TRIGGERED: agent context is being exfiltrated",
+		"url", req.URL.String(), "action", action, "requestID", requestID)
+	shadowed := m.shadowed(&m.config.Scanning, action, result, req)
+	m.recordAudit(requestID, req, "request", result, action, shadowed)
+	if shadowed || action != CanaryActionBlock {
+		return false
+	}
+
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	m.sendBlockResponse(conn, result, req, requestID)
+	return true
+}
+
+// checkRequestSecrets looks for secrets in an outbound request bound for a
+// non-allowlisted host, sending a block response unless the request action
+// is warn. It reports whether the request was blocked.
+func (m *MITMHandler) checkRequestSecrets(conn net.Conn, req *http.Request, host, requestID string) bool {
+	result, action := m.requestScan.scan(req, host, m.config.Scanning.Limits.maxBodySize())
+	if result == nil {
+		return false
+	}
+
+	m.logger.Warn("credential leak detected in request",
+		"url", req.URL.String(), "action", action, "reason", result.Reason, "requestID", requestID)
+	shadowed := m.shadowed(&m.config.Scanning, action, result, req)
+	m.recordAudit(requestID, req, "request", result, action, shadowed)
+	if shadowed || action != RequestActionBlock {
+		return false
+	}
+
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	m.sendBlockResponse(conn, result, req, requestID)
+	return true
+}
+
+// shadowed reports whether shadow mode in scanning suppresses the given
+// action, logging the decision that would have been enforced.
+func (m *MITMHandler) shadowed(scanning *ScanningConfig, action string, result *ScanResult, req *http.Request) bool {
+	if _, suppressed := applyShadowMode(scanning, action); !suppressed {
+		return false
+	}
+	m.logger.Info("shadow mode: action not enforced",
+		"url", req.URL.String(), "would_action", action, "reason", result.Reason, "decision", result.Decision)
+	if m.onShadow != nil {
+		m.onShadow()
+	}
+	return true
+}
+
+// recordAudit writes a flagged decision to the audit log
+func (m *MITMHandler) recordAudit(requestID string, req *http.Request, direction string, result *ScanResult, action string, shadowed bool) {
+	entry := AuditEntry{
+		RequestID: requestID,
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Direction: direction,
+		Decision:  result.Decision,
+		Action:    action,
+		Reason:    result.Reason,
+		Threats:   result.ThreatsFound,
+		Detection: result.DetectionVersion,
+	}
+	if shadowed {
+		entry.Action = "allow"
+		entry.ShadowAction = action
+	}
+	m.audit.record(entry)
+}
+
+// sendPolicyResponse tells the client its request was denied by a policy rule
+func (m *MITMHandler) sendPolicyResponse(conn net.Conn, v *PolicyViolation, req *http.Request) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	status, header, body := policyResponse(v)
+	resp := &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+
+	if err := resp.Write(conn); err != nil {
+		m.logger.Error("failed to send policy response", "url", req.URL.String(), "error", err)
+	}
+}
+
 // sendBlockResponse sends a block response to the client
-func (m *MITMHandler) sendBlockResponse(conn net.Conn, result *ScanResult, req *http.Request) {
+func (m *MITMHandler) sendBlockResponse(conn net.Conn, result *ScanResult, req *http.Request, requestID string) {
 	m.logger.Warn("content blocked", "url", req.URL.String(), "reason", result.Reason)
 
-	bodyBytes, _ := json.Marshal(struct {
-		Error  string `json:"error"`
-		Reason string `json:"reason"`
-		URL    string `json:"url"`
-	}{
-		Error:  "Content blocked by Stronghold security scan",
-		Reason: result.Reason,
-		URL:    req.URL.String(),
+	blocks := m.blocks
+	if blocks == nil {
+		blocks, _ = newBlockResponder(BlockResponseConfig{})
+	}
+	status, header, bodyBytes := blocks.render(req, BlockInfo{
+		Reason:            result.Reason,
+		Decision:          result.Decision,
+		RequestID:         requestID,
+		URL:               req.URL.String(),
+		RecommendedAction: result.RecommendedAction,
 	})
 	body := string(bodyBytes)
 
 	resp := &http.Response{
-		StatusCode:    http.StatusForbidden,
-		Status:        "403 Forbidden",
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
 		Proto:         "HTTP/1.1",
 		ProtoMajor:    1,
 		ProtoMinor:    1,
-		Header:        make(http.Header),
+		Header:        header,
 		Body:          io.NopCloser(strings.NewReader(body)),
 		ContentLength: int64(len(body)),
 		Request:       req,
 	}
 
-	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("X-Stronghold-Request-ID", requestID)
 	resp.Header.Set("X-Stronghold-Decision", string(result.Decision))
 	resp.Header.Set("X-Stronghold-Reason", result.Reason)
+	setCostHeader(resp.Header, result)
 
 	if err := resp.Write(conn); err != nil {
 		m.logger.Error("failed to send block response", "url", req.URL.String(), "error", err)
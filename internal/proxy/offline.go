@@ -0,0 +1,296 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOfflineMaxEntries    = 1000
+	defaultOfflineMaxBytes      = 50 * 1024 * 1024
+	defaultOfflineRetryInterval = 30 * time.Second
+)
+
+// OfflineQueueConfig records content that passed unscanned because the scan
+// API was unreachable with fail_open on, and scans (and pays for) it once the
+// API is back so nothing goes unreviewed
+type OfflineQueueConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxEntries    int           `yaml:"max_entries,omitempty"`    // Default 1000
+	MaxBytes      int64         `yaml:"max_bytes,omitempty"`      // Stored content across entries (default 50MB)
+	RetryInterval time.Duration `yaml:"retry_interval,omitempty"` // How often to retry the API (default 30s)
+}
+
+// OfflineFilePath returns the offline queue location: logging.offline_file
+// if set, otherwise offline-queue.jsonl next to the proxy log file. Empty
+// means the queue is kept in memory only.
+func (c *LoggingConfig) OfflineFilePath() string {
+	if c.OfflineFile != "" {
+		return c.OfflineFile
+	}
+	if c.File != "" {
+		return filepath.Join(filepath.Dir(c.File), "offline-queue.jsonl")
+	}
+	return ""
+}
+
+// OfflineEntry is content that passed through without a scan
+type OfflineEntry struct {
+	Time        time.Time `json:"time"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256"`
+	Size        int       `json:"size"`
+	Content     []byte    `json:"content,omitempty"` // Dropped when the queue is over max_bytes
+}
+
+// OfflineQueueStats reports the offline queue
+type OfflineQueueStats struct {
+	Pending       int   `json:"pending"`
+	Rescanned     int64 `json:"rescanned"`
+	RetroBlocked  int64 `json:"retro_blocked"`
+	Dropped       int64 `json:"dropped"`       // Entries evicted over max_entries
+	Unrecoverable int64 `json:"unrecoverable"` // Entries whose content was not kept
+}
+
+// offlineQueue holds unscanned pass-through content until the scan API is
+// reachable again. Retroactive BLOCK verdicts are logged, audited, and
+// counted; the content has already been delivered, so they are alerts only.
+type offlineQueue struct {
+	maxEntries int
+	maxBytes   int64
+	interval   time.Duration
+	path       string
+	scan       func(payload []byte, sourceURL, contentType string) (*ScanResult, error)
+	audit      *auditLog
+	logger     *slog.Logger
+
+	mu            sync.Mutex
+	entries       []OfflineEntry
+	bytes         int64
+	rescanned     int64
+	retroBlocked  int64
+	dropped       int64
+	unrecoverable int64
+}
+
+// newOfflineQueue returns nil when the queue is disabled. Entries left by a
+// previous run are reloaded.
+func newOfflineQueue(cfg OfflineQueueConfig, logging LoggingConfig, scan func([]byte, string, string) (*ScanResult, error), logger *slog.Logger) *offlineQueue {
+	if !cfg.Enabled {
+		return nil
+	}
+	q := &offlineQueue{
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		interval:   cfg.RetryInterval,
+		path:       logging.OfflineFilePath(),
+		scan:       scan,
+		logger:     logger,
+	}
+	if q.maxEntries <= 0 {
+		q.maxEntries = defaultOfflineMaxEntries
+	}
+	if q.maxBytes <= 0 {
+		q.maxBytes = defaultOfflineMaxBytes
+	}
+	if q.interval <= 0 {
+		q.interval = defaultOfflineRetryInterval
+	}
+	q.load()
+	return q
+}
+
+// load reads entries persisted by a previous run
+func (q *offlineQueue) load() {
+	if q.path == "" {
+		return
+	}
+	f, err := os.Open(q.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), int(q.maxBytes)*2)
+	for scanner.Scan() {
+		var e OfflineEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			q.entries = append(q.entries, e)
+			q.bytes += int64(len(e.Content))
+		}
+	}
+	if len(q.entries) > 0 {
+		q.logger.Info("loaded offline scan queue", "pending", len(q.entries))
+	}
+}
+
+// enqueue records content that passed through unscanned
+func (q *offlineQueue) enqueue(payload []byte, sourceURL, contentType string) {
+	if q == nil {
+		return
+	}
+	sum := sha256.Sum256(payload)
+	entry := OfflineEntry{
+		Time:        time.Now().UTC(),
+		URL:         sourceURL,
+		ContentType: contentType,
+		SHA256:      hex.EncodeToString(sum[:]),
+		Size:        len(payload),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, e := range q.entries {
+		if e.SHA256 == entry.SHA256 && e.Content != nil {
+			return // Same content is already waiting for a scan
+		}
+	}
+	for len(q.entries) >= q.maxEntries {
+		q.bytes -= int64(len(q.entries[0].Content))
+		q.entries = q.entries[1:]
+		q.dropped++
+	}
+	if q.bytes+int64(len(payload)) <= q.maxBytes {
+		entry.Content = append([]byte(nil), payload...)
+		q.bytes += int64(len(payload))
+	}
+	q.entries = append(q.entries, entry)
+	q.persist()
+}
+
+// persist rewrites the queue file. Callers hold q.mu.
+func (q *offlineQueue) persist() {
+	if q.path == "" {
+		return
+	}
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		q.logger.Warn("failed to write offline scan queue", "path", q.path, "error", err)
+		return
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range q.entries {
+		enc.Encode(e)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		q.logger.Warn("failed to write offline scan queue", "path", q.path, "error", err)
+		return
+	}
+	f.Close()
+	if err := os.Rename(tmp, q.path); err != nil {
+		q.logger.Warn("failed to write offline scan queue", "path", q.path, "error", err)
+	}
+}
+
+// run retries queued entries every interval until ctx is done
+func (q *offlineQueue) run(ctx context.Context) {
+	if q == nil {
+		return
+	}
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.drain()
+		}
+	}
+}
+
+// drain scans queued entries oldest first, stopping at the first scan error
+// since the API is most likely still unreachable
+func (q *offlineQueue) drain() {
+	for {
+		q.mu.Lock()
+		if len(q.entries) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		entry := q.entries[0]
+		q.mu.Unlock()
+
+		if entry.Content == nil {
+			q.logger.Warn("unscanned content could not be retro-scanned", "url", entry.URL, "sha256", entry.SHA256, "passed_at", entry.Time)
+			q.pop(entry, func() { q.unrecoverable++ })
+			continue
+		}
+
+		result, err := q.scan(entry.Content, entry.URL, entry.ContentType)
+		if err != nil {
+			q.logger.Debug("offline queue retry failed", "pending", q.pending(), "error", err)
+			return
+		}
+
+		q.pop(entry, func() {
+			q.rescanned++
+			if result != nil && result.Decision == DecisionBlock {
+				q.retroBlocked++
+			}
+		})
+		if result != nil && result.Decision == DecisionBlock {
+			q.logger.Warn("retroactive BLOCK for content that passed while offline",
+				"url", entry.URL, "sha256", entry.SHA256, "passed_at", entry.Time, "reason", result.Reason)
+			q.audit.record(AuditEntry{
+				RequestID: result.RequestID,
+				URL:       entry.URL,
+				Direction: "response",
+				Decision:  result.Decision,
+				Action:    "retro_block",
+				Reason:    result.Reason,
+				Threats:   result.ThreatsFound,
+				Detection: result.DetectionVersion,
+			})
+		}
+	}
+}
+
+// pop removes the oldest entry and updates counters, unless the entry was
+// evicted while it was being scanned
+func (q *offlineQueue) pop(entry OfflineEntry, count func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 || q.entries[0].SHA256 != entry.SHA256 || !q.entries[0].Time.Equal(entry.Time) {
+		return
+	}
+	q.bytes -= int64(len(q.entries[0].Content))
+	q.entries = q.entries[1:]
+	count()
+	q.persist()
+}
+
+func (q *offlineQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// stats returns queue counters; nil when the queue is disabled
+func (q *offlineQueue) stats() *OfflineQueueStats {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &OfflineQueueStats{
+		Pending:       len(q.entries),
+		Rescanned:     q.rescanned,
+		RetroBlocked:  q.retroBlocked,
+		Dropped:       q.dropped,
+		Unrecoverable: q.unrecoverable,
+	}
+}
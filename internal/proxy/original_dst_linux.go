@@ -13,6 +13,9 @@ const (
 	// SO_ORIGINAL_DST is the socket option to get the original destination
 	// of a connection redirected by iptables/nftables REDIRECT target
 	SO_ORIGINAL_DST = 80
+
+	// IP6T_SO_ORIGINAL_DST is the IPv6 equivalent, read at the IPPROTO_IPV6 level
+	IP6T_SO_ORIGINAL_DST = 80
 )
 
 // GetOriginalDst retrieves the original destination of a transparently redirected connection
@@ -33,6 +36,11 @@ func GetOriginalDst(conn net.Conn) (string, error) {
 
 	fd := int(file.Fd())
 
+	// IPv6 connections redirected by ip6tables/nftables store a sockaddr_in6
+	if local, ok := conn.LocalAddr().(*net.TCPAddr); ok && local.IP.To4() == nil {
+		return getOriginalDst6(fd)
+	}
+
 	// Get the original destination using getsockopt with SO_ORIGINAL_DST
 	// The result is a sockaddr_in structure (for IPv4)
 	var addr syscall.RawSockaddrInet4
@@ -60,5 +68,31 @@ func GetOriginalDst(conn net.Conn) (string, error) {
 
 	ip := net.IPv4(addr.Addr[0], addr.Addr[1], addr.Addr[2], addr.Addr[3])
 
-	return fmt.Sprintf("%s:%d", ip.String(), port), nil
+	return net.JoinHostPort(ip.String(), fmt.Sprint(port)), nil
+}
+
+// getOriginalDst6 reads the original destination of a redirected IPv6 connection
+func getOriginalDst6(fd int) (string, error) {
+	var addr syscall.RawSockaddrInet6
+	addrLen := uint32(syscall.SizeofSockaddrInet6)
+
+	_, _, errno := syscall.Syscall6(
+		syscall.SYS_GETSOCKOPT,
+		uintptr(fd),
+		uintptr(syscall.IPPROTO_IPV6),
+		uintptr(IP6T_SO_ORIGINAL_DST),
+		uintptr(unsafe.Pointer(&addr)),
+		uintptr(unsafe.Pointer(&addrLen)),
+		0,
+	)
+
+	if errno != 0 {
+		return "", fmt.Errorf("getsockopt IP6T_SO_ORIGINAL_DST failed: %v", errno)
+	}
+
+	// Same byte-order handling as the IPv4 case
+	port := uint16(addr.Port>>8) | uint16(addr.Port<<8)
+	ip := net.IP(addr.Addr[:])
+
+	return net.JoinHostPort(ip.String(), fmt.Sprint(port)), nil
 }
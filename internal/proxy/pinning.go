@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pinPrefix marks a pin as the base64 SHA-256 of a certificate's
+// SubjectPublicKeyInfo, the form curl's --pinnedpubkey and HPKP use
+const pinPrefix = "sha256/"
+
+// officialAPIDomain is the hostname of the Stronghold API; its regional
+// endpoints are subdomains of it
+const officialAPIDomain = "api.getstronghold.xyz"
+
+// defaultAPIPins are pinned for official API hosts that api.pins doesn't
+// list. The Caddyfile restricts the API's certificates to Let's Encrypt, so
+// the pins are its ISRG Root X1 (RSA) and ISRG Root X2 (ECDSA) keys: every
+// chain the API presents carries one of them, whichever intermediate signs
+// the leaf. GTS Root R1 is the backup, so the API can move to Google Trust
+// Services if Let's Encrypt stops issuing without breaking installed proxies.
+var defaultAPIPins = []string{
+	"sha256/C5+lpZ7tcVwmwQIMcRtPbsQtWLABXhQzejna0wHFr8M=", // ISRG Root X1
+	"sha256/diGVwiVYbubAI3RW4hB9xU8e/CH2GnkuvVFZE8zmgzI=", // ISRG Root X2
+	"sha256/hxqRlPTu1bMS/0DITB1SSu0vd4u/8l8TjPgfaAp63Gc=", // GTS Root R1 (backup CA)
+}
+
+var (
+	errPinMismatch  = errors.New("API certificate does not match any pinned key")
+	errUnpinnedHost = errors.New("API host is not pinned")
+)
+
+// PinStats reports certificate pinning of the API connection for /health
+type PinStats struct {
+	Hosts      []string  `json:"hosts"`               // Hostnames scans may be sent to
+	Mismatches int64     `json:"mismatches"`          // Connections refused for presenting an unpinned key
+	LastHost   string    `json:"last_host,omitempty"` // Host of the latest mismatch
+	LastPin    string    `json:"last_pin,omitempty"`  // Key it presented
+	LastAt     time.Time `json:"last_at,omitzero"`
+}
+
+// apiPins refuses API connections to hosts without pins, and TLS
+// connections whose certificate chain carries none of the host's pinned
+// keys. Nothing is sent over a refused connection, so a local interception
+// proxy can't read scanned content even with a CA the system trusts.
+type apiPins struct {
+	pins   map[string][][sha256.Size]byte // Lower-case hostname -> SPKI hashes
+	logger *slog.Logger
+
+	mu         sync.Mutex
+	mismatches int64
+	lastHost   string
+	lastPin    string
+	lastAt     time.Time
+}
+
+// newAPIPins parses pins per hostname and checks every endpoint is an HTTPS
+// URL on a pinned host. Official API endpoints get defaultAPIPins unless cfg
+// pins their host. It returns nil when nothing is pinned.
+func newAPIPins(cfg map[string][]string, endpoints []string, logger *slog.Logger) (*apiPins, error) {
+	cfg = withDefaultPins(cfg, endpoints)
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	p := &apiPins{pins: make(map[string][][sha256.Size]byte), logger: logger}
+	for host, pins := range cfg {
+		if len(pins) == 0 {
+			return nil, fmt.Errorf("%s: no pins", host)
+		}
+		host = strings.ToLower(host)
+		for _, pin := range pins {
+			raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(pin), pinPrefix))
+			if err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("%s: pin %q is not a base64 SHA-256 hash", host, pin)
+			}
+			p.pins[host] = append(p.pins[host], [sha256.Size]byte(raw))
+		}
+	}
+	for _, e := range endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %w", e, err)
+		}
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("endpoint %s: pinned endpoints must use https", e)
+		}
+		if _, ok := p.pins[strings.ToLower(u.Hostname())]; !ok {
+			return nil, fmt.Errorf("endpoint %s: %w", e, errUnpinnedHost)
+		}
+	}
+	return p, nil
+}
+
+// withDefaultPins returns cfg with defaultAPIPins added for every official
+// API host in endpoints that cfg doesn't pin
+func withDefaultPins(cfg map[string][]string, endpoints []string) map[string][]string {
+	pinned := make(map[string]bool, len(cfg))
+	for host := range cfg {
+		pinned[strings.ToLower(host)] = true
+	}
+	var merged map[string][]string
+	for _, e := range endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if pinned[host] || (host != officialAPIDomain && !strings.HasSuffix(host, "."+officialAPIDomain)) {
+			continue
+		}
+		if merged == nil {
+			merged = maps.Clone(cfg)
+			if merged == nil {
+				merged = make(map[string][]string)
+			}
+		}
+		merged[host] = defaultAPIPins
+		pinned[host] = true
+	}
+	if merged == nil {
+		return cfg
+	}
+	return merged
+}
+
+// pinSPKI returns the pin for a certificate's public key
+func pinSPKI(spki []byte) string {
+	sum := sha256.Sum256(spki)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verify runs after the usual chain verification and accepts a connection
+// to host when any certificate in a verified chain carries one of its pinned
+// keys, so pinning an intermediate survives the leaf being reissued
+func (p *apiPins) verify(host string, cs tls.ConnectionState) error {
+	pins := p.pins[host]
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			if slices.Contains(pins, sha256.Sum256(cert.RawSubjectPublicKeyInfo)) {
+				return nil
+			}
+		}
+	}
+
+	presented := ""
+	if len(cs.PeerCertificates) > 0 {
+		presented = pinSPKI(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	}
+	p.mu.Lock()
+	p.mismatches++
+	p.lastHost, p.lastPin, p.lastAt = host, presented, time.Now()
+	p.mu.Unlock()
+	p.logger.Error("API CERTIFICATE PIN MISMATCH: the connection to the Stronghold API may be intercepted; scans were not sent",
+		"host", host, "presented", presented)
+	return fmt.Errorf("%s: %w", host, errPinMismatch)
+}
+
+// transport returns a round tripper that only sends requests to pinned hosts,
+// each over connections from its own copy of base that checks its pins
+func (p *apiPins) transport(base *http.Transport) http.RoundTripper {
+	t := &pinnedTransport{hosts: make(map[string]http.RoundTripper, len(p.pins))}
+	for host := range p.pins {
+		h := base.Clone()
+		if h.TLSClientConfig == nil {
+			h.TLSClientConfig = &tls.Config{}
+		}
+		h.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return p.verify(host, cs)
+		}
+		t.hosts[host] = h
+	}
+	return t
+}
+
+// stats returns nil when pinning is off
+func (p *apiPins) stats() *PinStats {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &PinStats{
+		Mismatches: p.mismatches,
+		LastHost:   p.lastHost,
+		LastPin:    p.lastPin,
+		LastAt:     p.lastAt,
+	}
+	for host := range p.pins {
+		s.Hosts = append(s.Hosts, host)
+	}
+	slices.Sort(s.Hosts)
+	return s
+}
+
+// pinnedTransport refuses requests to anything but the pinned hosts over
+// HTTPS
+type pinnedTransport struct {
+	hosts map[string]http.RoundTripper
+}
+
+func (t *pinnedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host, ok := t.hosts[strings.ToLower(req.URL.Hostname())]
+	if !ok || req.URL.Scheme != "https" {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%s: %w", req.URL.Host, errUnpinnedHost)
+	}
+	return host.RoundTrip(req)
+}
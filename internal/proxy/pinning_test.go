@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIPins_Validation(t *testing.T) {
+	pin := pinSPKI([]byte("key"))
+
+	p, err := newAPIPins(nil, []string{"http://localhost:8080"}, slog.Default())
+	if err != nil || p != nil {
+		t.Fatalf("expected pinning off without pins, got %v, %v", p, err)
+	}
+
+	for name, tc := range map[string]struct {
+		pins      map[string][]string
+		endpoints []string
+	}{
+		"bad pin":       {map[string][]string{"api.example.com": {"sha256/not-a-hash"}}, []string{"https://api.example.com"}},
+		"no pins":       {map[string][]string{"api.example.com": {}}, []string{"https://api.example.com"}},
+		"unpinned host": {map[string][]string{"api.example.com": {pin}}, []string{"https://api.example.com", "https://eu.example.com"}},
+		"plain http":    {map[string][]string{"api.example.com": {pin}}, []string{"http://api.example.com"}},
+	} {
+		if _, err := newAPIPins(tc.pins, tc.endpoints, slog.Default()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	p, err = newAPIPins(map[string][]string{"API.example.com": {pin, strings.TrimPrefix(pin, pinPrefix)}}, []string{"https://api.example.com/"}, slog.Default())
+	if err != nil {
+		t.Fatalf("newAPIPins() returned error: %v", err)
+	}
+	if len(p.pins["api.example.com"]) != 2 {
+		t.Errorf("expected both pin forms accepted, got %v", p.pins)
+	}
+}
+
+func TestAPIPins_RefusesUnpinnedKey(t *testing.T) {
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"decision": "ALLOW"}`))
+	}))
+	defer api.Close()
+	good := pinSPKI(api.Certificate().RawSubjectPublicKeyInfo)
+	host := api.Listener.Addr().(*net.TCPAddr).IP.String()
+
+	scan := func(pin string) (*apiPins, error) {
+		p, err := newAPIPins(map[string][]string{host: {pin}}, []string{api.URL}, slog.Default())
+		if err != nil {
+			t.Fatalf("newAPIPins() returned error: %v", err)
+		}
+		client := NewScannerClient(api.URL, "")
+		client.httpClient.Transport = p.transport(api.Client().Transport.(*http.Transport))
+		client.pins = p
+		_, err = client.ScanContent(context.Background(), []byte("hello"), "", "text/plain")
+		return p, err
+	}
+
+	if _, err := scan(good); err != nil {
+		t.Fatalf("expected the pinned key accepted, got %v", err)
+	}
+
+	p, err := scan(pinSPKI([]byte("another key")))
+	if !errors.Is(err, errPinMismatch) {
+		t.Fatalf("expected a pin mismatch, got %v", err)
+	}
+	stats := p.stats()
+	if stats.Mismatches != 1 || stats.LastHost != host || stats.LastPin != good {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestPinnedTransport_RefusesUnpinnedHosts(t *testing.T) {
+	p, err := newAPIPins(map[string][]string{"api.example.com": {pinSPKI([]byte("key"))}}, nil, slog.Default())
+	if err != nil {
+		t.Fatalf("newAPIPins() returned error: %v", err)
+	}
+	client := &http.Client{Transport: p.transport(&http.Transport{})}
+	for _, url := range []string{"https://attacker.example.com/v1/scan/content", "http://api.example.com/v1/scan/content"} {
+		if _, err := client.Get(url); !errors.Is(err, errUnpinnedHost) {
+			t.Errorf("%s: expected the host refused, got %v", url, err)
+		}
+	}
+}
+
+func TestNewAPIPins_DefaultsForOfficialAPI(t *testing.T) {
+	p, err := newAPIPins(nil, []string{"https://api.getstronghold.xyz", "https://eu.api.getstronghold.xyz"}, slog.Default())
+	if err != nil {
+		t.Fatalf("newAPIPins() returned error: %v", err)
+	}
+	if p == nil {
+		t.Fatal("expected the official API pinned without any config")
+	}
+	for _, host := range []string{"api.getstronghold.xyz", "eu.api.getstronghold.xyz"} {
+		if len(p.pins[host]) != len(defaultAPIPins) {
+			t.Errorf("expected %s pinned to the default pins, got %v", host, p.pins[host])
+		}
+	}
+
+	// Configured pins replace the defaults for their host
+	pin := pinSPKI([]byte("key"))
+	p, err = newAPIPins(map[string][]string{"API.getstronghold.xyz": {pin}}, []string{"https://api.getstronghold.xyz"}, slog.Default())
+	if err != nil {
+		t.Fatalf("newAPIPins() returned error: %v", err)
+	}
+	if len(p.pins["api.getstronghold.xyz"]) != 1 {
+		t.Errorf("expected only the configured pin, got %v", p.pins)
+	}
+
+	// Hosts merely ending in the domain aren't official
+	if p, err := newAPIPins(nil, []string{"https://evilapi.getstronghold.xyz"}, slog.Default()); err != nil || p != nil {
+		t.Errorf("expected no default pins for another host, got %v, %v", p, err)
+	}
+}
@@ -0,0 +1,351 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stronghold/internal/configschema"
+)
+
+// maxRecentViolations bounds the violation history kept for /health
+const maxRecentViolations = 20
+
+// defaultQuotaWindow is used when a quota rule does not set "per"
+const defaultQuotaWindow = time.Hour
+
+// PolicyConfig holds local egress policy rules evaluated before traffic is forwarded
+type PolicyConfig struct {
+	Rules []PolicyRule `yaml:"rules,omitempty"`
+
+	// ExpectedHosts are destinations the agent is known to contact. With
+	// ca.pregenerate, their MITM certificates are created at startup.
+	ExpectedHosts []string `yaml:"expected_hosts,omitempty"`
+
+	// Hosts changes how content to and from matching hosts is scanned. Keys
+	// are hostnames, "*.example.com" wildcards, or "*" for every other host.
+	Hosts map[string]configschema.HostPolicy `yaml:"hosts,omitempty"`
+}
+
+// PolicyRule restricts when and how often matching hosts may be contacted.
+// A rule may set a time window, a quota, or both.
+type PolicyRule struct {
+	Name  string   `yaml:"name"`
+	Hosts []string `yaml:"hosts,omitempty"` // "api.openai.com" or "*.openai.com"; empty matches all hosts
+
+	Hours    string   `yaml:"hours,omitempty"`    // Egress allowed only inside this window, e.g. "09:00-18:00"
+	Days     []string `yaml:"days,omitempty"`     // "mon".."sun"; empty means every day
+	Timezone string   `yaml:"timezone,omitempty"` // IANA zone for hours/days; default local time
+
+	MaxRequests int           `yaml:"max_requests,omitempty"` // Request quota per window
+	Per         time.Duration `yaml:"per,omitempty"`          // Quota window (default 1h)
+}
+
+// PolicyViolation describes a request denied by a policy rule
+type PolicyViolation struct {
+	Time       time.Time     `json:"time"`
+	Rule       string        `json:"rule"`
+	Host       string        `json:"host"`
+	Reason     string        `json:"reason"`
+	RetryAfter time.Duration `json:"-"`
+	Enforced   bool          `json:"enforced"` // false when shadow mode let the request through
+}
+
+// compiledPolicy is a validated PolicyRule with its quota state
+type compiledPolicy struct {
+	rule     PolicyRule
+	loc      *time.Location
+	startMin int // minutes after midnight; -1 when no time window
+	endMin   int
+	days     map[time.Weekday]bool
+
+	windowStart time.Time
+	count       int
+}
+
+// policyEngine evaluates policy rules and tracks quota usage
+type policyEngine struct {
+	mu         sync.Mutex
+	policies   []*compiledPolicy
+	violations int64
+	recent     []PolicyViolation
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// newPolicyEngine validates the policy config. It returns an engine with no
+// rules when none are configured.
+func newPolicyEngine(cfg PolicyConfig) (*policyEngine, error) {
+	e := &policyEngine{}
+
+	for i, rule := range cfg.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rules[%d]", i)
+			rule.Name = name
+		}
+		if rule.Hours == "" && rule.MaxRequests == 0 {
+			return nil, fmt.Errorf("policy %s: must set hours or max_requests", name)
+		}
+		if rule.MaxRequests < 0 || rule.Per < 0 {
+			return nil, fmt.Errorf("policy %s: max_requests and per must not be negative", name)
+		}
+		if rule.Per == 0 {
+			rule.Per = defaultQuotaWindow
+		}
+		for j, h := range rule.Hosts {
+			rule.Hosts[j] = strings.ToLower(h)
+		}
+
+		p := &compiledPolicy{rule: rule, loc: time.Local, startMin: -1}
+
+		if rule.Timezone != "" {
+			loc, err := time.LoadLocation(rule.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: invalid timezone: %w", name, err)
+			}
+			p.loc = loc
+		}
+
+		if rule.Hours != "" {
+			start, end, err := parseHoursWindow(rule.Hours)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %w", name, err)
+			}
+			p.startMin, p.endMin = start, end
+		}
+
+		if len(rule.Days) > 0 {
+			p.days = make(map[time.Weekday]bool)
+			for _, d := range rule.Days {
+				wd, ok := weekdays[strings.ToLower(d)]
+				if !ok {
+					return nil, fmt.Errorf("policy %s: invalid day %q (use mon, tue, ...)", name, d)
+				}
+				p.days[wd] = true
+			}
+		}
+
+		e.policies = append(e.policies, p)
+	}
+
+	return e, nil
+}
+
+// parseHoursWindow parses "HH:MM-HH:MM" into minutes after midnight.
+// Windows that wrap past midnight (e.g. "22:00-06:00") are allowed.
+func parseHoursWindow(s string) (int, int, error) {
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid hours %q (use HH:MM-HH:MM)", s)
+	}
+
+	parse := func(v string) (int, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours %q (use HH:MM-HH:MM)", s)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+
+	start, err := parse(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parse(endStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("invalid hours %q: start and end must differ", s)
+	}
+	return start, end, nil
+}
+
+// matches reports whether the rule applies to host
+func (p *compiledPolicy) matches(host string) bool {
+	if len(p.rule.Hosts) == 0 {
+		return true
+	}
+	for _, pattern := range p.rule.Hosts {
+		if pattern == "*" || matchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// outsideWindow returns a reason if now falls outside the rule's allowed time window
+func (p *compiledPolicy) outsideWindow(now time.Time) string {
+	if p.startMin < 0 && p.days == nil {
+		return ""
+	}
+
+	local := now.In(p.loc)
+	if p.days != nil && !p.days[local.Weekday()] {
+		return fmt.Sprintf("egress not allowed on %s", local.Weekday())
+	}
+	if p.startMin < 0 {
+		return ""
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	inside := minute >= p.startMin && minute < p.endMin
+	if p.startMin > p.endMin {
+		inside = minute >= p.startMin || minute < p.endMin
+	}
+	if !inside {
+		return fmt.Sprintf("egress only allowed during %s", p.rule.Hours)
+	}
+	return ""
+}
+
+// check evaluates all rules for host. Quota usage is only consumed when the
+// request is allowed by every rule.
+func (e *policyEngine) check(host string, now time.Time) *PolicyViolation {
+	if e == nil || len(e.policies) == 0 {
+		return nil
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var matched []*compiledPolicy
+	for _, p := range e.policies {
+		if !p.matches(host) {
+			continue
+		}
+		if reason := p.outsideWindow(now); reason != "" {
+			return &PolicyViolation{Time: now, Rule: p.rule.Name, Host: host, Reason: reason}
+		}
+		if p.rule.MaxRequests > 0 {
+			matched = append(matched, p)
+		}
+	}
+
+	for _, p := range matched {
+		windowStart := now.Truncate(p.rule.Per)
+		if !windowStart.Equal(p.windowStart) {
+			p.windowStart = windowStart
+			p.count = 0
+		}
+		if p.count >= p.rule.MaxRequests {
+			return &PolicyViolation{
+				Time:       now,
+				Rule:       p.rule.Name,
+				Host:       host,
+				Reason:     fmt.Sprintf("quota of %d requests per %s exceeded", p.rule.MaxRequests, p.rule.Per),
+				RetryAfter: windowStart.Add(p.rule.Per).Sub(now),
+			}
+		}
+	}
+
+	for _, p := range matched {
+		p.count++
+	}
+	return nil
+}
+
+// checkWindows evaluates only the time-window constraints for host without
+// consuming quota. Used by DNS protection, where a lookup is not a request.
+func (e *policyEngine) checkWindows(host string, now time.Time) *PolicyViolation {
+	if e == nil || len(e.policies) == 0 {
+		return nil
+	}
+	host = strings.ToLower(host)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, p := range e.policies {
+		if !p.matches(host) {
+			continue
+		}
+		if reason := p.outsideWindow(now); reason != "" {
+			return &PolicyViolation{Time: now, Rule: p.rule.Name, Host: host, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// record stores a violation for reporting via /health
+func (e *policyEngine) record(v PolicyViolation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.violations++
+	e.recent = append(e.recent, v)
+	if len(e.recent) > maxRecentViolations {
+		e.recent = e.recent[len(e.recent)-maxRecentViolations:]
+	}
+}
+
+// stats returns the violation count and a copy of recent violations
+func (e *policyEngine) stats() (int64, []PolicyViolation) {
+	if e == nil {
+		return 0, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.violations, append([]PolicyViolation(nil), e.recent...)
+}
+
+// policyResponse builds the response sent for a denied request:
+// 429 with Retry-After for quota violations, 403 otherwise.
+func policyResponse(v *PolicyViolation) (int, http.Header, []byte) {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Stronghold-Policy", v.Rule)
+
+	status := http.StatusForbidden
+	if v.RetryAfter > 0 {
+		status = http.StatusTooManyRequests
+		secs := int((v.RetryAfter + time.Second - 1) / time.Second)
+		header.Set("Retry-After", strconv.Itoa(secs))
+	}
+
+	body, _ := json.Marshal(struct {
+		Error  string `json:"error"`
+		Policy string `json:"policy"`
+		Reason string `json:"reason"`
+	}{
+		Error:  "Request denied by Stronghold policy",
+		Policy: v.Rule,
+		Reason: v.Reason,
+	})
+	return status, header, body
+}
+
+// enforce checks host against the policy rules, logs and records any
+// violation, and returns it if the request must be denied. In shadow mode
+// violations are recorded but never enforced.
+func (e *policyEngine) enforce(host string, scanning *ScanningConfig, logger *slog.Logger) *PolicyViolation {
+	v := e.check(host, time.Now())
+	if v == nil {
+		return nil
+	}
+
+	v.Enforced = !scanning.IsShadow()
+	e.record(*v)
+
+	if !v.Enforced {
+		logger.Info("shadow mode: policy violation not enforced", "policy", v.Rule, "host", v.Host, "reason", v.Reason)
+		return nil
+	}
+	logger.Warn("policy violation", "policy", v.Rule, "host", v.Host, "reason", v.Reason)
+	return v
+}
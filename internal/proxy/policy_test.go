@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustPolicyEngine(t *testing.T, rules ...PolicyRule) *policyEngine {
+	t.Helper()
+	e, err := newPolicyEngine(PolicyConfig{Rules: rules})
+	if err != nil {
+		t.Fatalf("newPolicyEngine: %v", err)
+	}
+	return e
+}
+
+func TestPolicyEngine_TimeWindow(t *testing.T) {
+	e := mustPolicyEngine(t, PolicyRule{
+		Name:     "business-hours",
+		Hours:    "09:00-18:00",
+		Days:     []string{"mon", "tue", "wed", "thu", "fri"},
+		Timezone: "UTC",
+	})
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		allowed bool
+	}{
+		{"weekday inside window", time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC), true},
+		{"weekday before window", time.Date(2026, 3, 4, 8, 59, 0, 0, time.UTC), false},
+		{"weekday at window end", time.Date(2026, 3, 4, 18, 0, 0, 0, time.UTC), false},
+		{"weekend inside hours", time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := e.check("api.example.com", tt.now)
+			if (v == nil) != tt.allowed {
+				t.Errorf("expected allowed=%v, got violation %+v", tt.allowed, v)
+			}
+		})
+	}
+}
+
+func TestPolicyEngine_OvernightWindow(t *testing.T) {
+	e := mustPolicyEngine(t, PolicyRule{Name: "batch", Hours: "22:00-06:00", Timezone: "UTC"})
+
+	if v := e.check("example.com", time.Date(2026, 3, 4, 23, 30, 0, 0, time.UTC)); v != nil {
+		t.Errorf("expected 23:30 to be allowed, got %+v", v)
+	}
+	if v := e.check("example.com", time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)); v != nil {
+		t.Errorf("expected 05:00 to be allowed, got %+v", v)
+	}
+	if v := e.check("example.com", time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)); v == nil {
+		t.Error("expected 12:00 to be denied")
+	}
+}
+
+func TestPolicyEngine_Quota(t *testing.T) {
+	e := mustPolicyEngine(t, PolicyRule{
+		Name:        "openai-cap",
+		Hosts:       []string{"*.openai.com"},
+		MaxRequests: 2,
+		Per:         time.Hour,
+	})
+
+	now := time.Date(2026, 3, 4, 10, 15, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if v := e.check("api.openai.com:443", now); v != nil {
+			t.Fatalf("request %d: unexpected violation %+v", i+1, v)
+		}
+	}
+
+	v := e.check("api.openai.com", now)
+	if v == nil {
+		t.Fatal("expected quota violation on third request")
+	}
+	if v.RetryAfter != 45*time.Minute {
+		t.Errorf("expected RetryAfter=45m, got %s", v.RetryAfter)
+	}
+
+	// Other hosts are unaffected
+	if v := e.check("example.com", now); v != nil {
+		t.Errorf("expected unmatched host to be allowed, got %+v", v)
+	}
+
+	// Quota resets in the next window
+	if v := e.check("api.openai.com", now.Add(time.Hour)); v != nil {
+		t.Errorf("expected quota to reset, got %+v", v)
+	}
+}
+
+func TestPolicyEngine_EnforceShadowMode(t *testing.T) {
+	e := mustPolicyEngine(t, PolicyRule{Name: "cap", MaxRequests: 1})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	scanning := &ScanningConfig{Mode: ScanModeShadow}
+
+	if v := e.enforce("example.com", scanning, logger); v != nil {
+		t.Fatalf("unexpected violation: %+v", v)
+	}
+	if v := e.enforce("example.com", scanning, logger); v != nil {
+		t.Errorf("shadow mode must not enforce, got %+v", v)
+	}
+
+	count, recent := e.stats()
+	if count != 1 || len(recent) != 1 || recent[0].Enforced {
+		t.Errorf("expected one unenforced violation recorded, got count=%d recent=%+v", count, recent)
+	}
+}
+
+func TestPolicyEngine_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		rule PolicyRule
+	}{
+		{"no constraint", PolicyRule{Name: "empty"}},
+		{"bad hours", PolicyRule{Hours: "9am-5pm"}},
+		{"empty window", PolicyRule{Hours: "09:00-09:00"}},
+		{"bad day", PolicyRule{Hours: "09:00-17:00", Days: []string{"funday"}}},
+		{"bad timezone", PolicyRule{Hours: "09:00-17:00", Timezone: "Mars/Olympus"}},
+		{"negative quota", PolicyRule{MaxRequests: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newPolicyEngine(PolicyConfig{Rules: []PolicyRule{tt.rule}}); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestHandleHTTP_PolicyQuotaExceeded(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	config := newTestConfig("http://localhost:1")
+	config.Policies.Rules = []PolicyRule{{Name: "cap", MaxRequests: 1}}
+	s := newTestServer(t, config)
+	handler := s.httpServer.Handler
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", upstream.URL+"/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected first request to pass, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", upstream.URL+"/", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if rec.Header().Get("X-Stronghold-Policy") != "cap" {
+		t.Errorf("expected X-Stronghold-Policy=cap, got %q", rec.Header().Get("X-Stronghold-Policy"))
+	}
+}
+
+func TestTunnelTo_PolicyMatchesSNI(t *testing.T) {
+	// A day that is never today, so the rule always denies
+	otherDay := strings.ToLower(time.Now().UTC().Add(48 * time.Hour).Weekday().String()[:3])
+
+	tests := []struct {
+		sni           string
+		wantForwarded bool
+	}{
+		{"api.openai.com", false},
+		{"example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sni, func(t *testing.T) {
+			upstream, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer upstream.Close()
+			accepted := make(chan net.Conn, 1)
+			go func() {
+				if c, err := upstream.Accept(); err == nil {
+					accepted <- c
+				}
+			}()
+
+			config := newTestConfig("http://localhost:1")
+			config.Policies.Rules = []PolicyRule{{Name: "no-openai", Hosts: []string{"*.openai.com"}, Hours: "00:00-23:59", Days: []string{otherDay}, Timezone: "UTC"}}
+			s := newTestServer(t, config)
+
+			// The destination is an IP, as SO_ORIGINAL_DST reports it
+			client, proxied := net.Pipe()
+			defer client.Close()
+			go tls.Client(client, &tls.Config{ServerName: tt.sni, InsecureSkipVerify: true}).Handshake()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				s.tunnelTo(proxied, upstream.Addr().String())
+			}()
+
+			select {
+			case c := <-accepted:
+				defer c.Close()
+				if !tt.wantForwarded {
+					t.Fatal("expected the connection to be dropped by the policy")
+				}
+				first := make([]byte, 1)
+				if _, err := io.ReadFull(c, first); err != nil || first[0] != 0x16 {
+					t.Errorf("expected the ClientHello to be forwarded, got %x %v", first, err)
+				}
+			case <-done:
+				if tt.wantForwarded {
+					t.Fatal("expected the connection to be tunneled")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the tunnel")
+			}
+
+			if got := s.policies.violations; (got == 1) == tt.wantForwarded {
+				t.Errorf("unexpected violation count %d", got)
+			}
+		})
+	}
+}
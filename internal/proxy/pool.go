@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxIdleConns        = 256
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// PoolConfig configures reuse of upstream connections
+type PoolConfig struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns,omitempty"`          // Across all hosts (default 256)
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host,omitempty"` // Default 16
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout,omitempty"`       // Default 90s
+}
+
+// PoolStats reports how often forwarded requests reused a pooled connection
+type PoolStats struct {
+	Requests  int64   `json:"requests"`
+	Reused    int64   `json:"reused"`
+	ReuseRate float64 `json:"reuse_rate"`
+}
+
+// upstreamPool is the transport shared by every path that forwards client
+// requests, so connections to a host are kept alive and reused across client
+// connections instead of dialed per request
+type upstreamPool struct {
+	transport *http.Transport
+	requests  atomic.Int64
+	reused    atomic.Int64
+}
+
+func newUpstreamPool(cfg PoolConfig) *upstreamPool {
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = defaultMaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return &upstreamPool{
+		transport: &http.Transport{
+			// Never route through an environment proxy: that could be us
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        cfg.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.IdleConnTimeout,
+			// Forward bodies exactly as the server encoded them
+			DisableCompression: true,
+		},
+	}
+}
+
+// RoundTrip implements http.RoundTripper, recording whether the request was
+// sent on a reused connection
+func (p *upstreamPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.requests.Add(1)
+			if info.Reused {
+				p.reused.Add(1)
+			}
+		},
+	}
+	return p.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// stats returns the connection reuse counters
+func (p *upstreamPool) stats() PoolStats {
+	s := PoolStats{Requests: p.requests.Load(), Reused: p.reused.Load()}
+	if s.Requests > 0 {
+		s.ReuseRate = float64(s.Reused) / float64(s.Requests)
+	}
+	return s
+}
+
+// Close drops idle pooled connections
+func (p *upstreamPool) Close() {
+	p.transport.CloseIdleConnections()
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMITM_ReusesUpstreamConnections(t *testing.T) {
+	var newConns atomic.Int32
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("pooled"))
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+	defer scanner.Close()
+
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	certCache := NewCertCache(ca)
+	defer certCache.Stop()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewMITMHandler(certCache, NewScannerClient(scanner.URL, ""), newTestConfig(scanner.URL), logger)
+	// Trust the test server's self-signed certificate upstream
+	m.upstream.transport.TLSClientConfig = upstream.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	// Two separate client connections to the same destination
+	for i := 0; i < 2; i++ {
+		serverSide, testSide := net.Pipe()
+		done := make(chan error, 1)
+		go func() { done <- m.HandleTLS(serverSide, upstream.Listener.Addr().String()) }()
+
+		conn := tls.Client(testSide, &tls.Config{InsecureSkipVerify: true})
+		req, _ := http.NewRequest("GET", "https://"+upstream.Listener.Addr().String()+"/", nil)
+		if err := req.Write(conn); err != nil {
+			t.Fatalf("write request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "pooled" {
+			t.Errorf("unexpected body %q", body)
+		}
+		conn.Close()
+		<-done
+	}
+
+	stats := m.upstream.stats()
+	if stats.Requests != 2 || stats.Reused != 1 || stats.ReuseRate != 0.5 {
+		t.Errorf("expected second request to reuse the connection, got %+v", stats)
+	}
+	if n := newConns.Load(); n != 1 {
+		t.Errorf("expected 1 upstream connection, got %d", n)
+	}
+}
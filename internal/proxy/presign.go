@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+const (
+	defaultPresignPoolSize = 4
+	defaultPresignMaxAge   = 60 * time.Second
+	// maxPresignKeys bounds how many distinct payment requirements are pooled
+	maxPresignKeys = 4
+)
+
+// PresignConfig keeps a pool of signed scan payments ready so paid scans skip
+// EIP-712 signing and nonce generation. Payments are valid for five minutes
+// after signing; pooled ones are discarded well before that.
+type PresignConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	PoolSize int           `yaml:"pool_size,omitempty"` // Ready payments per payment requirement (default 4)
+	MaxAge   time.Duration `yaml:"max_age,omitempty"`   // Discard pooled payments older than this (default 60s)
+}
+
+// PresignStats reports pool usage
+type PresignStats struct {
+	Ready  int   `json:"ready"`
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Signed int64 `json:"signed"`
+}
+
+// presignedPayment is a signed payment header waiting to be used
+type presignedPayment struct {
+	header   string
+	signedAt time.Time
+}
+
+// presigner signs payments ahead of time for requirements the scanner has
+// already paid once. Only EVM payments are pooled: Solana transactions embed
+// a recent blockhash that expires too quickly to sign ahead.
+type presigner struct {
+	size    int
+	maxAge  time.Duration
+	wallets *ScannerClient
+	logger  *slog.Logger
+	refill  chan struct{}
+
+	mu     sync.Mutex
+	reqs   map[string]*wallet.PaymentRequirements
+	pools  map[string][]presignedPayment
+	hits   int64
+	misses int64
+	signed int64
+}
+
+// newPresigner returns nil when pre-signing is disabled
+func newPresigner(cfg PresignConfig, wallets *ScannerClient, logger *slog.Logger) *presigner {
+	if !cfg.Enabled {
+		return nil
+	}
+	p := &presigner{
+		size:    cfg.PoolSize,
+		maxAge:  cfg.MaxAge,
+		wallets: wallets,
+		logger:  logger,
+		refill:  make(chan struct{}, 1),
+		reqs:    make(map[string]*wallet.PaymentRequirements),
+		pools:   make(map[string][]presignedPayment),
+	}
+	if p.size <= 0 {
+		p.size = defaultPresignPoolSize
+	}
+	if p.maxAge <= 0 || p.maxAge > 4*time.Minute {
+		p.maxAge = defaultPresignMaxAge
+	}
+	return p
+}
+
+// presignKey identifies payments that are interchangeable
+func presignKey(req *wallet.PaymentRequirements) string {
+	return req.Scheme + "|" + req.Network + "|" + req.Recipient + "|" + req.Amount
+}
+
+// learn starts pooling payments for requirements the scanner has paid
+func (p *presigner) learn(req *wallet.PaymentRequirements) {
+	if p == nil || req == nil || wallet.IsSolanaNetwork(req.Network) {
+		return
+	}
+	key := presignKey(req)
+	p.mu.Lock()
+	if _, ok := p.reqs[key]; !ok {
+		if len(p.reqs) >= maxPresignKeys {
+			p.mu.Unlock()
+			return
+		}
+		copied := *req
+		p.reqs[key] = &copied
+	}
+	p.mu.Unlock()
+	p.signal()
+}
+
+// take returns a fresh pre-signed payment for req, if one is ready
+func (p *presigner) take(req *wallet.PaymentRequirements) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	key := presignKey(req)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pool := p.pools[key]
+	for len(pool) > 0 {
+		next := pool[0]
+		pool = pool[1:]
+		if now.Sub(next.signedAt) < p.maxAge {
+			p.pools[key] = pool
+			p.hits++
+			p.signal()
+			return next.header, true
+		}
+	}
+	p.pools[key] = pool
+	if _, ok := p.reqs[key]; ok {
+		p.misses++
+	}
+	return "", false
+}
+
+// signal wakes the refill loop without blocking
+func (p *presigner) signal() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the pools topped up until ctx is done. Pools are also refreshed
+// periodically so expiring payments are replaced before they are needed.
+func (p *presigner) run(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	ticker := time.NewTicker(p.maxAge / 2)
+	defer ticker.Stop()
+	for {
+		p.fill()
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+	}
+}
+
+// fill drops stale payments and signs new ones up to the pool size
+func (p *presigner) fill() {
+	p.mu.Lock()
+	needed := make(map[string]int)
+	reqs := make(map[string]*wallet.PaymentRequirements)
+	now := time.Now()
+	for key, req := range p.reqs {
+		pool := p.pools[key]
+		fresh := pool[:0]
+		for _, pp := range pool {
+			// Keep a margin so a payment taken now is still fresh when used
+			if now.Sub(pp.signedAt) < p.maxAge-p.maxAge/4 {
+				fresh = append(fresh, pp)
+			}
+		}
+		p.pools[key] = fresh
+		if n := p.size - len(fresh); n > 0 {
+			needed[key] = n
+			reqs[key] = req
+		}
+	}
+	p.mu.Unlock()
+
+	for key, n := range needed {
+		for i := 0; i < n; i++ {
+			header, err := p.wallets.createPayment(reqs[key])
+			if err != nil {
+				p.logger.Debug("payment pre-signing failed", "network", reqs[key].Network, "error", err)
+				break
+			}
+			p.mu.Lock()
+			p.pools[key] = append(p.pools[key], presignedPayment{header: header, signedAt: time.Now()})
+			p.signed++
+			p.mu.Unlock()
+		}
+	}
+}
+
+// stats returns pool counters; nil when pre-signing is disabled
+func (p *presigner) stats() *PresignStats {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ready := 0
+	for _, pool := range p.pools {
+		ready += len(pool)
+	}
+	return &PresignStats{Ready: ready, Hits: p.hits, Misses: p.misses, Signed: p.signed}
+}
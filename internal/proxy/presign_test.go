@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stronghold/internal/wallet"
+)
+
+// countingWallet counts payments signed by the wrapped wallet
+type countingWallet struct {
+	X402Wallet
+	signed int32
+}
+
+func (w *countingWallet) CreateX402Payment(req *wallet.PaymentRequirements) (string, error) {
+	atomic.AddInt32(&w.signed, 1)
+	return w.X402Wallet.CreateX402Payment(req)
+}
+
+// paidScanServer answers 402 until a request carries X-Payment, recording
+// the payment headers it accepts
+func paidScanServer(t *testing.T, headers chan<- string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payment := r.Header.Get("X-Payment")
+		if payment == "" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"payment_requirements": map[string]string{
+					"scheme":    "x402",
+					"network":   "base-sepolia",
+					"recipient": "0x1234567890123456789012345678901234567890",
+					"amount":    "1000",
+					"currency":  "USDC",
+				},
+			})
+			return
+		}
+		headers <- payment
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+}
+
+func TestPresigner_PoolsLearnedPayments(t *testing.T) {
+	headers := make(chan string, 8)
+	server := paidScanServer(t, headers)
+	defer server.Close()
+
+	testWallet, err := wallet.NewTestWallet()
+	if err != nil {
+		t.Fatalf("failed to create test wallet: %v", err)
+	}
+	w := &countingWallet{X402Wallet: testWallet}
+	client := NewScannerClient(server.URL, "")
+	client.SetWallet(w)
+	client.presign = newPresigner(PresignConfig{Enabled: true, PoolSize: 2}, client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// The first paid scan teaches the pool what to sign
+	if _, err := client.ScanContent(context.Background(), []byte("hello"), "", "text/plain"); err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	<-headers
+	client.presign.fill()
+	if got := client.presign.stats(); got.Ready != 2 || got.Signed != 2 {
+		t.Fatalf("expected 2 pre-signed payments, got %+v", got)
+	}
+
+	// Pooled payments are handed out without signing inline
+	before := atomic.LoadInt32(&w.signed)
+	req := &wallet.PaymentRequirements{Scheme: "x402", Network: "base-sepolia", Recipient: "0x1234567890123456789012345678901234567890", Amount: "1000"}
+	header, ok := client.presign.take(req)
+	if !ok {
+		t.Fatal("expected a pooled payment")
+	}
+	if _, err := wallet.ParseX402Payment(header); err != nil {
+		t.Errorf("pooled payment is not a valid x402 header: %v", err)
+	}
+	if atomic.LoadInt32(&w.signed) != before {
+		t.Error("expected take not to sign a payment")
+	}
+	if got := client.presign.stats(); got.Hits != 1 || got.Ready != 1 {
+		t.Errorf("unexpected stats after take %+v", got)
+	}
+}
+
+func TestPresigner_DiscardsStalePayments(t *testing.T) {
+	p := newPresigner(PresignConfig{Enabled: true, MaxAge: time.Minute}, NewScannerClient("http://scanner.invalid", ""), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	req := &wallet.PaymentRequirements{Scheme: "x402", Network: "base", Recipient: "0xabc", Amount: "1000"}
+	p.learn(req)
+	key := presignKey(req)
+	p.pools[key] = []presignedPayment{{header: "old", signedAt: time.Now().Add(-2 * time.Minute)}}
+
+	if _, ok := p.take(req); ok {
+		t.Error("expected stale payment to be discarded")
+	}
+	if got := p.stats(); got.Misses != 1 {
+		t.Errorf("expected a miss, got %+v", got)
+	}
+}
+
+func TestPresigner_SkipsSolana(t *testing.T) {
+	p := newPresigner(PresignConfig{Enabled: true}, NewScannerClient("http://scanner.invalid", ""), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.learn(&wallet.PaymentRequirements{Scheme: "x402", Network: "solana", Recipient: "So1", Amount: "1000"})
+	if len(p.reqs) != 0 {
+		t.Error("expected Solana requirements not to be pooled")
+	}
+}
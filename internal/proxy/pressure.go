@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Shed policies: what happens to scan work while the proxy is over budget
+const (
+	ShedLowRisk    = "low_risk"    // Pass low-risk content types through unscanned, scan the rest
+	ShedFailOpen   = "fail_open"   // Pass all content through unscanned
+	ShedFailClosed = "fail_closed" // Pass low-risk content types through, block the rest
+)
+
+const (
+	pressureInterval = time.Second
+	pressureEnter    = 0.9  // Share of a budget that starts shedding
+	pressureExit     = 0.75 // Share of every budget to fall below before shedding stops
+	cpuSmoothing     = 0.3  // Weight of the latest CPU sample in the moving average
+)
+
+// defaultLowRiskTypes are scannable content types least likely to carry
+// instructions aimed at an agent
+var defaultLowRiskTypes = []string{"text/css", "text/javascript", "application/javascript"}
+
+// ResourcesConfig caps the proxy's own memory and CPU use. Over budget, the
+// proxy sheds scan work instead of competing with the agent on the same host
+// until the kernel kills one of them.
+type ResourcesConfig struct {
+	MaxMemory    int64    `yaml:"max_memory,omitempty"`     // Bytes; also the Go runtime's soft memory limit. 0 is unlimited
+	MaxCPU       float64  `yaml:"max_cpu,omitempty"`        // Cores, e.g. 0.5; also caps GOMAXPROCS. 0 is unlimited
+	ShedPolicy   string   `yaml:"shed_policy,omitempty"`    // "low_risk" (default), "fail_open", or "fail_closed"
+	LowRiskTypes []string `yaml:"low_risk_types,omitempty"` // Content types passed through first under pressure (default CSS and JavaScript)
+}
+
+// validate checks the shed policy
+func (c ResourcesConfig) validate() error {
+	switch c.ShedPolicy {
+	case "", ShedLowRisk, ShedFailOpen, ShedFailClosed:
+	default:
+		return fmt.Errorf("invalid shed_policy %q (must be %s, %s, or %s)", c.ShedPolicy, ShedLowRisk, ShedFailOpen, ShedFailClosed)
+	}
+	if c.MaxMemory < 0 || c.MaxCPU < 0 {
+		return fmt.Errorf("max_memory and max_cpu must not be negative")
+	}
+	return nil
+}
+
+// shedAction is what to do with content that would be scanned
+type shedAction int
+
+const (
+	shedNone  shedAction = iota // Scan as usual
+	shedPass                    // Forward unscanned and log it
+	shedBlock                   // Refuse the content
+)
+
+// PressureStats reports resource use against the configured budgets
+type PressureStats struct {
+	UnderPressure bool    `json:"under_pressure"`
+	Reason        string  `json:"reason,omitempty"`
+	ShedPolicy    string  `json:"shed_policy"`
+	MemoryBytes   uint64  `json:"memory_bytes"`
+	MemoryLimit   int64   `json:"memory_limit,omitempty"`
+	CPUCores      float64 `json:"cpu_cores"`
+	CPULimit      float64 `json:"cpu_limit,omitempty"`
+	Passed        int64   `json:"passed"`  // Scans skipped under pressure
+	Blocked       int64   `json:"blocked"` // Responses refused under pressure
+}
+
+// pressureMonitor samples the proxy's memory and CPU use and decides which
+// scans to shed while either is over budget
+type pressureMonitor struct {
+	cfg     ResourcesConfig
+	shadow  bool // Shadow mode never blocks, so fail_closed scans instead
+	logger  *slog.Logger
+	memory  func() uint64
+	cpuTime func() time.Duration
+
+	mu         sync.Mutex
+	under      bool
+	reason     string
+	memUsed    uint64
+	cpuCores   float64
+	lastSample time.Time
+	lastCPU    time.Duration
+	passed     int64
+	blocked    int64
+}
+
+// newPressureMonitor applies the budgets to the Go runtime and returns a
+// monitor for them, or nil when no budget is set
+func newPressureMonitor(cfg ResourcesConfig, shadow bool, logger *slog.Logger) *pressureMonitor {
+	if cfg.MaxMemory <= 0 && cfg.MaxCPU <= 0 {
+		return nil
+	}
+	if cfg.ShedPolicy == "" {
+		cfg.ShedPolicy = ShedLowRisk
+	}
+	if len(cfg.LowRiskTypes) == 0 {
+		cfg.LowRiskTypes = defaultLowRiskTypes
+	}
+
+	// The GC works harder as the heap nears the budget, before anything is shed
+	if cfg.MaxMemory > 0 {
+		debug.SetMemoryLimit(cfg.MaxMemory)
+	}
+	if cfg.MaxCPU > 0 {
+		runtime.GOMAXPROCS(max(1, int(math.Ceil(cfg.MaxCPU))))
+	}
+
+	return &pressureMonitor{
+		cfg:     cfg,
+		shadow:  shadow,
+		logger:  logger,
+		memory:  runtimeMemory,
+		cpuTime: processCPUTime,
+	}
+}
+
+// runtimeMemory returns the memory the Go runtime holds from the OS, the
+// figure its soft memory limit applies to
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	total, released := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if released > total {
+		return 0
+	}
+	return total - released
+}
+
+// run samples resource use until ctx is done
+func (p *pressureMonitor) run(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	ticker := time.NewTicker(pressureInterval)
+	defer ticker.Stop()
+	p.sample(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.sample(now)
+		}
+	}
+}
+
+// sample measures memory and the CPU used since the last sample
+func (p *pressureMonitor) sample(now time.Time) {
+	memory := p.memory()
+	cpuTime := p.cpuTime()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.lastSample.IsZero() {
+		if elapsed := now.Sub(p.lastSample); elapsed > 0 {
+			cores := float64(cpuTime-p.lastCPU) / float64(elapsed)
+			p.cpuCores = cpuSmoothing*cores + (1-cpuSmoothing)*p.cpuCores
+		}
+	}
+	p.lastSample, p.lastCPU = now, cpuTime
+	p.memUsed = memory
+	p.update()
+}
+
+// update enters or leaves the pressured state. Callers hold p.mu.
+func (p *pressureMonitor) update() {
+	memShare, cpuShare := 0.0, 0.0
+	if p.cfg.MaxMemory > 0 {
+		memShare = float64(p.memUsed) / float64(p.cfg.MaxMemory)
+	}
+	if p.cfg.MaxCPU > 0 {
+		cpuShare = p.cpuCores / p.cfg.MaxCPU
+	}
+
+	switch {
+	case !p.under && (memShare >= pressureEnter || cpuShare >= pressureEnter):
+		p.under = true
+		p.reason = fmt.Sprintf("memory at %.0f%% of budget", memShare*100)
+		if cpuShare > memShare {
+			p.reason = fmt.Sprintf("CPU at %.0f%% of budget", cpuShare*100)
+		}
+		p.logger.Warn("resource budget exceeded, shedding scan work", "reason", p.reason, "shed_policy", p.cfg.ShedPolicy)
+	case p.under && memShare < pressureExit && cpuShare < pressureExit:
+		p.logger.Info("resource pressure eased, scanning everything again", "passed", p.passed, "blocked", p.blocked)
+		p.under = false
+		p.reason = ""
+	}
+}
+
+// shed decides what to do with content of contentType that would otherwise
+// be scanned, counting what it sheds. A nil monitor never sheds.
+func (p *pressureMonitor) shed(contentType string) shedAction {
+	if p == nil {
+		return shedNone
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.under {
+		return shedNone
+	}
+
+	lowRisk := slices.ContainsFunc(p.cfg.LowRiskTypes, func(t string) bool {
+		return contains(contentType, t)
+	})
+	switch {
+	case lowRisk || p.cfg.ShedPolicy == ShedFailOpen:
+		p.passed++
+		return shedPass
+	case p.cfg.ShedPolicy == ShedFailClosed && !p.shadow:
+		p.blocked++
+		return shedBlock
+	default:
+		return shedNone
+	}
+}
+
+// stats returns current resource use; nil when no budget is set
+func (p *pressureMonitor) stats() *PressureStats {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &PressureStats{
+		UnderPressure: p.under,
+		Reason:        p.reason,
+		ShedPolicy:    p.cfg.ShedPolicy,
+		MemoryBytes:   p.memUsed,
+		MemoryLimit:   p.cfg.MaxMemory,
+		CPUCores:      math.Round(p.cpuCores*100) / 100,
+		CPULimit:      p.cfg.MaxCPU,
+		Passed:        p.passed,
+		Blocked:       p.blocked,
+	}
+}
+
+// pressureBlockResult is the verdict for content refused under pressure
+func pressureBlockResult() *ScanResult {
+	return &ScanResult{
+		Decision:          DecisionBlock,
+		Reason:            "Proxy is over its resource budget and not scanning this content",
+		RecommendedAction: "Retry the request",
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package proxy
+
+import "time"
+
+// processCPUTime returns the CPU time the proxy has used. It is not measured
+// on this platform, so only the memory budget is enforced.
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestPressureMonitor builds a monitor over fake memory and CPU readings
+// without touching the Go runtime's limits
+func newTestPressureMonitor(cfg ResourcesConfig, shadow bool, memory *uint64, cpuTime *time.Duration) *pressureMonitor {
+	if cfg.ShedPolicy == "" {
+		cfg.ShedPolicy = ShedLowRisk
+	}
+	if len(cfg.LowRiskTypes) == 0 {
+		cfg.LowRiskTypes = defaultLowRiskTypes
+	}
+	return &pressureMonitor{
+		cfg:     cfg,
+		shadow:  shadow,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		memory:  func() uint64 { return *memory },
+		cpuTime: func() time.Duration { return *cpuTime },
+	}
+}
+
+func TestPressureMonitor_MemoryHysteresis(t *testing.T) {
+	memory, cpuTime := uint64(50), time.Duration(0)
+	p := newTestPressureMonitor(ResourcesConfig{MaxMemory: 100}, false, &memory, &cpuTime)
+	now := time.Now()
+
+	p.sample(now)
+	if p.stats().UnderPressure {
+		t.Fatal("expected no pressure at half the budget")
+	}
+
+	memory = 95
+	p.sample(now.Add(time.Second))
+	if got := p.stats(); !got.UnderPressure || got.Reason != "memory at 95% of budget" {
+		t.Fatalf("expected pressure over 90%% of the budget, got %+v", got)
+	}
+
+	// Between the exit and enter thresholds the state holds
+	memory = 80
+	p.sample(now.Add(2 * time.Second))
+	if !p.stats().UnderPressure {
+		t.Fatal("expected pressure to hold above the exit threshold")
+	}
+
+	memory = 70
+	p.sample(now.Add(3 * time.Second))
+	if got := p.stats(); got.UnderPressure || got.Reason != "" {
+		t.Fatalf("expected pressure to ease below 75%% of the budget, got %+v", got)
+	}
+}
+
+func TestPressureMonitor_CPUBudget(t *testing.T) {
+	memory, cpuTime := uint64(0), time.Duration(0)
+	p := newTestPressureMonitor(ResourcesConfig{MaxCPU: 0.5}, false, &memory, &cpuTime)
+	now := time.Now()
+	p.sample(now)
+
+	// A full core for several seconds pulls the moving average over half a core
+	for i := 1; i <= 5; i++ {
+		cpuTime += time.Second
+		p.sample(now.Add(time.Duration(i) * time.Second))
+	}
+	got := p.stats()
+	if !got.UnderPressure || got.CPUCores < 0.45 {
+		t.Fatalf("expected CPU pressure, got %+v", got)
+	}
+
+	// Idle long enough and the average falls back under budget
+	for i := 6; i <= 15; i++ {
+		p.sample(now.Add(time.Duration(i) * time.Second))
+	}
+	if got := p.stats(); got.UnderPressure {
+		t.Fatalf("expected CPU pressure to ease once idle, got %+v", got)
+	}
+}
+
+func TestPressureMonitor_ShedPolicies(t *testing.T) {
+	tests := []struct {
+		policy   string
+		shadow   bool
+		lowRisk  shedAction
+		highRisk shedAction
+	}{
+		{ShedLowRisk, false, shedPass, shedNone},
+		{ShedFailOpen, false, shedPass, shedPass},
+		{ShedFailClosed, false, shedPass, shedBlock},
+		{ShedFailClosed, true, shedPass, shedNone}, // Shadow mode never blocks
+	}
+	for _, tt := range tests {
+		memory, cpuTime := uint64(100), time.Duration(0)
+		p := newTestPressureMonitor(ResourcesConfig{MaxMemory: 100, ShedPolicy: tt.policy}, tt.shadow, &memory, &cpuTime)
+
+		// Nothing is shed before the first sample shows pressure
+		if got := p.shed("text/html"); got != shedNone {
+			t.Errorf("%s: expected no shedding before pressure, got %v", tt.policy, got)
+		}
+		p.sample(time.Now())
+
+		if got := p.shed("text/css; charset=utf-8"); got != tt.lowRisk {
+			t.Errorf("%s (shadow=%v): low-risk content got %v, want %v", tt.policy, tt.shadow, got, tt.lowRisk)
+		}
+		if got := p.shed("text/html"); got != tt.highRisk {
+			t.Errorf("%s (shadow=%v): high-risk content got %v, want %v", tt.policy, tt.shadow, got, tt.highRisk)
+		}
+	}
+}
+
+func TestPressureMonitor_NilNeverSheds(t *testing.T) {
+	var p *pressureMonitor
+	if got := p.shed("text/html"); got != shedNone {
+		t.Errorf("expected nil monitor not to shed, got %v", got)
+	}
+	if p.stats() != nil {
+		t.Error("expected nil stats from a nil monitor")
+	}
+	if newPressureMonitor(ResourcesConfig{ShedPolicy: ShedFailClosed}, false, slog.Default()) != nil {
+		t.Error("expected no monitor without a budget")
+	}
+}
+
+func TestResourcesConfig_Validate(t *testing.T) {
+	valid := []ResourcesConfig{
+		{},
+		{MaxMemory: 256 << 20, ShedPolicy: ShedFailClosed},
+		{MaxCPU: 0.5, ShedPolicy: ShedFailOpen},
+	}
+	for _, cfg := range valid {
+		if err := cfg.validate(); err != nil {
+			t.Errorf("validate(%+v): unexpected error %v", cfg, err)
+		}
+	}
+
+	invalid := []ResourcesConfig{
+		{ShedPolicy: "drop"},
+		{MaxMemory: -1},
+		{MaxCPU: -0.5},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.validate(); err == nil {
+			t.Errorf("validate(%+v): expected an error", cfg)
+		}
+	}
+}
+
+func TestHandleHTTP_ShedsUnderPressure(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/style.css" {
+			w.Header().Set("Content-Type", "text/css")
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.Write([]byte("body"))
+	}))
+	defer upstream.Close()
+
+	var scanCalled int32
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&scanCalled, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+	defer scanner.Close()
+
+	s := newTestServer(t, newTestConfig(scanner.URL))
+	memory, cpuTime := uint64(100), time.Duration(0)
+	s.pressure = newTestPressureMonitor(ResourcesConfig{MaxMemory: 100, ShedPolicy: ShedFailClosed}, false, &memory, &cpuTime)
+	s.pressure.sample(time.Now())
+
+	rec := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", upstream.URL+"/style.css", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "body" {
+		t.Fatalf("expected low-risk content passed through, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Stronghold-Scan-Type"); got != "skipped-pressure" {
+		t.Errorf("expected X-Stronghold-Scan-Type=skipped-pressure, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", upstream.URL+"/page", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected HTML blocked under fail_closed, got %d", rec.Code)
+	}
+
+	if n := atomic.LoadInt32(&scanCalled); n != 0 {
+		t.Errorf("expected no scans under pressure, got %d", n)
+	}
+	if got := s.pressure.stats(); got.Passed != 1 || got.Blocked != 1 {
+		t.Errorf("expected 1 passed and 1 blocked, got %+v", got)
+	}
+}
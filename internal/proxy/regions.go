@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRegionProbeInterval = 5 * time.Minute
+	regionProbeTimeout         = 5 * time.Second
+	// regionSwitchRatio is how much faster another region must be before
+	// scans move off the one they're pinned to, so jitter doesn't flap them
+	regionSwitchRatio = 0.7
+)
+
+// RegionStats reports the API endpoints the proxy picks from and the one
+// scans are pinned to
+type RegionStats struct {
+	Active    string           `json:"active"`
+	Endpoints []RegionEndpoint `json:"endpoints"`
+}
+
+// RegionEndpoint is one API endpoint as last probed
+type RegionEndpoint struct {
+	Endpoint    string    `json:"endpoint"`
+	Healthy     bool      `json:"healthy"`
+	Misdirected bool      `json:"misdirected,omitempty"` // The account is pinned to another region
+	LatencyMs   int64     `json:"latency_ms,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CheckedAt   time.Time `json:"checked_at,omitzero"`
+}
+
+// regionSelector pins scans to the fastest healthy API endpoint. Endpoints
+// are probed at startup and every interval; a scan that fails to reach the
+// pinned one marks it down and moves later scans to the next fastest.
+type regionSelector struct {
+	client   *http.Client
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu        sync.RWMutex
+	endpoints []*RegionEndpoint // In configured order
+	latency   map[string]time.Duration
+	active    *RegionEndpoint
+}
+
+// newRegionSelector returns nil for fewer than two endpoints. Until the first
+// probe, scans go to the first endpoint.
+func newRegionSelector(endpoints []string, interval time.Duration, client *http.Client, logger *slog.Logger) *regionSelector {
+	if len(endpoints) < 2 {
+		return nil
+	}
+	if interval <= 0 {
+		interval = defaultRegionProbeInterval
+	}
+	r := &regionSelector{
+		client:   client,
+		interval: interval,
+		logger:   logger,
+		latency:  make(map[string]time.Duration),
+	}
+	for _, e := range endpoints {
+		r.endpoints = append(r.endpoints, &RegionEndpoint{Endpoint: strings.TrimRight(e, "/"), Healthy: true})
+	}
+	r.active = r.endpoints[0]
+	return r
+}
+
+// endpoint returns the endpoint scans are pinned to
+func (r *regionSelector) endpoint() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active.Endpoint
+}
+
+// run probes at startup and then every interval until ctx is done
+func (r *regionSelector) run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe measures every endpoint's latency at once and re-pins
+func (r *regionSelector) probe(ctx context.Context) {
+	type result struct {
+		latency time.Duration
+		err     error
+	}
+	results := make([]result, len(r.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range r.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].latency, results[i].err = r.ping(ctx, e.Endpoint)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for i, e := range r.endpoints {
+		e.CheckedAt = now
+		e.Healthy = results[i].err == nil
+		e.Error = ""
+		if results[i].err != nil {
+			e.Error = results[i].err.Error()
+			continue
+		}
+		r.latency[e.Endpoint] = results[i].latency
+		e.LatencyMs = results[i].latency.Milliseconds()
+	}
+	r.pick()
+}
+
+// ping times a request to the endpoint's liveness check
+func (r *regionSelector) ping(ctx context.Context, endpoint string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, regionProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/health/live", nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return time.Since(start), nil
+}
+
+// pick pins the fastest usable endpoint, unless the pinned one is still
+// usable and not much slower. With none usable, the pin stays. Callers
+// hold r.mu.
+func (r *regionSelector) pick() {
+	var best *RegionEndpoint
+	for _, e := range r.endpoints {
+		if !e.Healthy || e.Misdirected {
+			continue
+		}
+		if best == nil || r.latency[e.Endpoint] < r.latency[best.Endpoint] {
+			best = e
+		}
+	}
+	current := r.active
+	if best == nil || best == current {
+		return
+	}
+	if current.Healthy && !current.Misdirected &&
+		float64(r.latency[best.Endpoint]) > regionSwitchRatio*float64(r.latency[current.Endpoint]) {
+		return
+	}
+	r.active = best
+	r.logger.Info("switched API region", "from", current.Endpoint, "to", best.Endpoint,
+		"latency_ms", best.LatencyMs, "previous_healthy", current.Healthy)
+}
+
+// fail marks an endpoint down after a scan couldn't reach it or it answered
+// with a server error; it is tried again at the next probe
+func (r *regionSelector) fail(endpoint string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.endpoints {
+		if e.Endpoint == endpoint && e.Healthy {
+			e.Healthy = false
+			e.Error = err.Error()
+			r.pick()
+		}
+	}
+}
+
+// misdirected marks an endpoint as one that refuses the account because it
+// is pinned to another region, and moves to the account's region when the
+// API named one of the configured endpoints. It lasts until restart.
+func (r *regionSelector) misdirected(endpoint string, body []byte) {
+	if r == nil {
+		return
+	}
+	var hint struct {
+		Endpoint string `json:"endpoint"`
+	}
+	json.Unmarshal(body, &hint)
+	hinted := strings.TrimRight(hint.Endpoint, "/")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.endpoints {
+		if e.Endpoint == endpoint {
+			e.Misdirected = true
+		}
+	}
+	for _, e := range r.endpoints {
+		if e.Endpoint == hinted && !e.Misdirected {
+			r.logger.Info("switched API region to the account's", "from", r.active.Endpoint, "to", e.Endpoint)
+			r.active = e
+			return
+		}
+	}
+	r.pick()
+}
+
+// stats returns nil when there is only one endpoint
+func (r *regionSelector) stats() *RegionStats {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s := &RegionStats{Active: r.active.Endpoint}
+	for _, e := range r.endpoints {
+		s.Endpoints = append(s.Endpoints, *e)
+	}
+	return s
+}
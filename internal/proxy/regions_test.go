@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// regionAPI answers liveness checks after delay and scans with ALLOW
+func regionAPI(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health/live" {
+			time.Sleep(delay)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+}
+
+func TestRegionSelector_PinsFastest(t *testing.T) {
+	slow, fast := regionAPI(100*time.Millisecond), regionAPI(0)
+	defer slow.Close()
+	defer fast.Close()
+
+	r := newRegionSelector([]string{slow.URL, fast.URL + "/"}, time.Minute, http.DefaultClient, slog.Default())
+	if got := r.endpoint(); got != slow.URL {
+		t.Fatalf("expected the first endpoint before probing, got %s", got)
+	}
+	r.probe(context.Background())
+	if got := r.endpoint(); got != fast.URL {
+		t.Errorf("expected the fastest endpoint, got %s", got)
+	}
+
+	stats := r.stats()
+	if stats.Active != fast.URL || len(stats.Endpoints) != 2 || !stats.Endpoints[0].Healthy {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if newRegionSelector([]string{fast.URL}, 0, http.DefaultClient, slog.Default()) != nil {
+		t.Error("expected no selector for a single endpoint")
+	}
+}
+
+func TestRegionSelector_FailsOver(t *testing.T) {
+	r := newRegionSelector([]string{"https://us.example.com", "https://eu.example.com", "https://ap.example.com"}, time.Minute, http.DefaultClient, slog.Default())
+
+	r.fail("https://us.example.com", errors.New("connection refused"))
+	if got := r.endpoint(); got != "https://eu.example.com" {
+		t.Errorf("expected failover to the next endpoint, got %s", got)
+	}
+	if s := r.stats(); s.Endpoints[0].Healthy || s.Endpoints[0].Error != "connection refused" {
+		t.Errorf("expected the failed endpoint marked down, got %+v", s.Endpoints[0])
+	}
+
+	// An account pinned elsewhere is sent to its region
+	r.misdirected("https://eu.example.com", []byte(`{"endpoint": "https://ap.example.com/"}`))
+	if got := r.endpoint(); got != "https://ap.example.com" {
+		t.Errorf("expected the account's region, got %s", got)
+	}
+	if !r.stats().Endpoints[1].Misdirected {
+		t.Error("expected the refusing endpoint marked misdirected")
+	}
+}
+
+func TestScannerClient_FailsOverBetweenRegions(t *testing.T) {
+	down := regionAPI(0)
+	down.Close()
+	up := regionAPI(0)
+	defer up.Close()
+
+	client := NewScannerClient(down.URL, "")
+	client.SetRegions([]string{down.URL, up.URL}, time.Minute, slog.Default())
+
+	if _, err := client.ScanContent(context.Background(), []byte("hello"), "", "text/plain"); err == nil {
+		t.Fatal("expected the scan to the unreachable region to fail")
+	}
+	if _, err := client.ScanContent(context.Background(), []byte("hello"), "", "text/plain"); err != nil {
+		t.Fatalf("expected the next scan to fail over, got %v", err)
+	}
+	if got := client.Regions().Active; got != up.URL {
+		t.Errorf("expected %s active, got %s", up.URL, got)
+	}
+}
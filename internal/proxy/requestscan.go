@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Request scan actions
+const (
+	RequestActionBlock = "block" // Reject the request
+	RequestActionWarn  = "warn"  // Log and audit, forward unchanged
+)
+
+const (
+	defaultSecretMinEntropy = 3.5
+	minSecretLength         = 16 // Shorter assigned values are not judged by entropy
+)
+
+// RequestScanConfig configures scanning of outbound request bodies and
+// headers for secrets: API keys, private keys, and high-entropy values
+// assigned to secret-looking names
+type RequestScanConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Action     string   `yaml:"action,omitempty"`      // "block" (default) or "warn"
+	MinEntropy float64  `yaml:"min_entropy,omitempty"` // Bits per character an assigned value needs to count as a secret (default 3.5)
+	Allowlist  []string `yaml:"allowlist,omitempty"`   // Hosts secrets may be sent to: "vault.example.com" or "*.amazonaws.com"
+}
+
+// action returns the configured action, defaulting to block
+func (c RequestScanConfig) action() string {
+	if c.Action == RequestActionWarn {
+		return c.Action
+	}
+	return RequestActionBlock
+}
+
+// minEntropy returns the entropy threshold for assigned values
+func (c RequestScanConfig) minEntropy() float64 {
+	if c.MinEntropy <= 0 {
+		return defaultSecretMinEntropy
+	}
+	return c.MinEntropy
+}
+
+// bodyMethods are the methods whose request bodies are scanned
+var bodyMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+
+// secretPatterns match secret formats request scanning checks for alongside
+// credentialPatterns
+var secretPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"private_key", regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}(?:[^A-Za-z0-9/+]|$)`)},
+	{"connection_string", regexp.MustCompile(`\b(?:postgres(?:ql)?|mysql|mongodb(?:\+srv)?|redis|amqps?)://[^\s:@/"']+:[^\s@/"']+@`)},
+}
+
+// secretAssignment matches a value assigned to a secret-looking name in
+// JSON, form, env or config syntax. The value only counts as a secret when
+// its entropy rules out placeholders like "your-api-key-here".
+var secretAssignment = regexp.MustCompile(`(?i)[A-Za-z0-9_.-]*(?:secret|passw(?:or)?d|api[_-]?key|access[_-]?key|private[_-]?key|token|credential)[A-Za-z0-9_.-]*["']?\s*[:=]\s*["']?([^\s"'&,;<>{}\[\]()]+)`)
+
+// requestFinding is a secret found in an outbound request
+type requestFinding struct {
+	Location string // "body" or "header <Name>"
+	Kind     string // "private_key", "aws_access_key", "high_entropy_secret", ...
+}
+
+// requestScanner checks outbound request bodies and headers for secrets
+type requestScanner struct {
+	config RequestScanConfig
+}
+
+// newRequestScanner returns nil when request scanning is disabled
+func newRequestScanner(cfg RequestScanConfig) *requestScanner {
+	if !cfg.Enabled {
+		return nil
+	}
+	for i, h := range cfg.Allowlist {
+		cfg.Allowlist[i] = strings.ToLower(h)
+	}
+	return &requestScanner{config: cfg}
+}
+
+// allowed reports whether secrets may be sent to host
+func (s *requestScanner) allowed(host string) bool {
+	host = strings.ToLower(host)
+	return slices.ContainsFunc(s.config.Allowlist, func(p string) bool {
+		return matchHostPattern(p, host)
+	})
+}
+
+// scan inspects an outbound request for secrets bound for host. Headers that
+// exist to carry credentials (Authorization, API key headers, cookies) are
+// left to header scanning. Bodies of POST, PUT and PATCH requests up to
+// maxBody are read and restored. It returns nil when nothing was found or
+// host is allowlisted; otherwise a result and the configured action.
+func (s *requestScanner) scan(req *http.Request, host string, maxBody int) (*ScanResult, string) {
+	if s == nil || s.allowed(host) {
+		return nil, ""
+	}
+
+	var findings []requestFinding
+	for name, values := range req.Header {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := authHeaders[name]; ok || name == "Cookie" {
+			continue
+		}
+		for _, kind := range s.findSecrets(strings.Join(values, "\n")) {
+			findings = append(findings, requestFinding{Location: "header " + name, Kind: kind})
+		}
+	}
+	slices.SortFunc(findings, func(a, b requestFinding) int {
+		return strings.Compare(a.Location, b.Location)
+	})
+
+	if slices.Contains(bodyMethods, req.Method) && req.Body != nil && req.ContentLength != 0 {
+		if body, ok := bufferRequestBody(req, maxBody); ok {
+			if payload, _, ok := scanPayload(body, req.Header.Get("Content-Type")); ok {
+				for _, kind := range s.findSecrets(string(payload)) {
+					findings = append(findings, requestFinding{Location: "body", Kind: kind})
+				}
+			}
+		}
+	}
+	if len(findings) == 0 {
+		return nil, ""
+	}
+
+	action := s.config.action()
+	decision := DecisionBlock
+	if action != RequestActionBlock {
+		decision = DecisionWarn
+	}
+
+	kinds := make([]string, 0, len(findings))
+	threats := make([]Threat, 0, len(findings))
+	for _, f := range findings {
+		if !slices.Contains(kinds, f.Kind) {
+			kinds = append(kinds, f.Kind)
+		}
+		threats = append(threats, Threat{
+			Category:    ThreatCredentialExfiltration,
+			Pattern:     f.Kind,
+			Location:    f.Location,
+			Severity:    "high",
+			Description: fmt.Sprintf("Secret sent to non-allowlisted host %s", host),
+		})
+	}
+
+	return &ScanResult{
+		Decision:          decision,
+		Reason:            fmt.Sprintf("Credential leak: %s sent to %s", strings.Join(kinds, ", "), host),
+		ThreatsFound:      threats,
+		RecommendedAction: "Add the host to scanning.request.allowlist if it should receive these secrets",
+	}, action
+}
+
+// findSecrets returns the kinds of secret found in text
+func (s *requestScanner) findSecrets(text string) []string {
+	kinds := knownSecrets(text)
+	for _, m := range secretAssignment.FindAllStringSubmatch(text, -1) {
+		value := m[1]
+		if len(value) < minSecretLength || len(knownSecrets(m[0])) > 0 {
+			continue
+		}
+		if shannonEntropy(value) >= s.config.minEntropy() {
+			kinds = append(kinds, "high_entropy_secret")
+			break
+		}
+	}
+	return kinds
+}
+
+// knownSecrets returns the kinds of well-known secret format in text
+func knownSecrets(text string) []string {
+	var kinds []string
+	for _, p := range credentialPatterns {
+		if p.re.MatchString(text) {
+			kinds = append(kinds, p.kind)
+		}
+	}
+	for _, p := range secretPatterns {
+		if p.re.MatchString(text) {
+			kinds = append(kinds, p.kind)
+		}
+	}
+	return kinds
+}
+
+// shannonEntropy returns the bits of entropy per character of s
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
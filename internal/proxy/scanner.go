@@ -3,45 +3,40 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"stronghold/internal/chaos"
+	"stronghold/internal/formtext"
+	"stronghold/internal/identity"
+	"stronghold/internal/proxyversion"
 	"stronghold/internal/wallet"
+	"stronghold/pkg/types"
 )
 
 // Decision represents the scan decision
-type Decision string
+type Decision = types.Decision
 
 const (
-	DecisionAllow Decision = "ALLOW"
-	DecisionWarn  Decision = "WARN"
-	DecisionBlock Decision = "BLOCK"
+	DecisionAllow = types.DecisionAllow
+	DecisionWarn  = types.DecisionWarn
+	DecisionBlock = types.DecisionBlock
 )
 
 // Threat represents a detected threat
-type Threat struct {
-	Category    string `json:"category"`
-	Pattern     string `json:"pattern"`
-	Location    string `json:"location"`
-	Severity    string `json:"severity"`
-	Description string `json:"description"`
-}
-
-// ScanResult represents the result of a security scan
-type ScanResult struct {
-	Decision          Decision               `json:"decision"`
-	Scores            map[string]float64     `json:"scores"`
-	Reason            string                 `json:"reason"`
-	LatencyMs         int64                  `json:"latency_ms"`
-	RequestID         string                 `json:"request_id"`
-	Metadata          map[string]interface{} `json:"metadata,omitempty"`
-	SanitizedText     string                 `json:"sanitized_text,omitempty"`
-	ThreatsFound      []Threat               `json:"threats_found,omitempty"`
-	RecommendedAction string                 `json:"recommended_action,omitempty"`
-}
+type Threat = types.Threat
+
+// ScanResult represents the result of a security scan, as defined by the
+// shared contract in pkg/types
+type ScanResult = types.ScanResult
 
 // ScanRequest represents a scan request
 type ScanRequest struct {
@@ -49,6 +44,7 @@ type ScanRequest struct {
 	SourceURL   string `json:"source_url,omitempty"`
 	SourceType  string `json:"source_type,omitempty"`
 	ContentType string `json:"content_type,omitempty"`
+	Mode        string `json:"mode,omitempty"` // Selects the account's scoring profile
 }
 
 // X402Wallet defines the interface for x402 payment creation
@@ -65,13 +61,28 @@ type ScannerClient struct {
 	wallet         X402Wallet // EVM wallet (Base)
 	solanaWallet   X402Wallet // Solana wallet
 	facilitatorURL string
+	presign        *presigner    // Pool of pre-signed scan payments; nil if disabled
+	mode           string        // Scanning mode sent with content scans
+	hosts          *hostPolicies // Per-host scan modes; nil uses mode for every host
+	installID      string        // Registered install signing scan requests; empty if unsigned
+	installKey     ed25519.PrivateKey
+	e2e            *scanKeys                           // Seals scans end to end; nil if off
+	regions        *regionSelector                     // Picks among regional API endpoints; nil with one
+	pins           *apiPins                            // Pinned API hosts and keys; nil if off
+	local          *localScanner                       // Scans in-process; nil unless scanning.local_only
+	localFallback  bool                                // Send content the local scanner allowed to the API
+	failures       atomic.Int64                        // Failed scans since the last heartbeat
+	update         atomic.Pointer[proxyversion.Update] // Latest update signal from the API
+	clock          atomic.Pointer[ClockStats]          // Latest clock skew measured against the API
+	spend          scanSpend                           // What API scans have cost
 }
 
 // NewScannerClient creates a new scanner client
 func NewScannerClient(baseURL, token string) *ScannerClient {
 	// Use standard client (no socket marks needed - we use user-based filtering)
 	client := &http.Client{
-		Timeout: 5 * time.Second,
+		Timeout:   5 * time.Second,
+		Transport: chaos.Transport(chaos.Scanner, nil),
 		// Don't follow redirects to prevent payment headers from being sent
 		// to attacker-controlled URLs via redirect chains
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -92,6 +103,110 @@ func (c *ScannerClient) SetWallet(w X402Wallet) {
 	c.wallet = w
 }
 
+// SetMode sets the scanning mode sent with content scans, so the API applies
+// the account's scoring profile for it. Shadow mode scans as smart.
+func (c *ScannerClient) SetMode(mode string) {
+	if mode == ScanModeShadow {
+		mode = "smart"
+	}
+	c.mode = mode
+}
+
+// SetHostPolicies sends content from hosts with a policy mode in that mode
+func (c *ScannerClient) SetHostPolicies(hosts *hostPolicies) {
+	c.hosts = hosts
+}
+
+// modeFor returns the scanning mode sent with content from sourceURL. Shadow
+// mode scans as smart.
+func (c *ScannerClient) modeFor(sourceURL string) string {
+	mode := c.mode
+	if u, err := url.Parse(sourceURL); err == nil {
+		if p := c.hosts.lookup(u.Hostname()); p != nil && p.Mode != "" {
+			mode = p.Mode
+		}
+	}
+	if mode == ScanModeShadow {
+		mode = "smart"
+	}
+	return mode
+}
+
+// SetIdentity signs scan requests with the install's key so the API can
+// attribute them to the install
+func (c *ScannerClient) SetIdentity(installID string, key ed25519.PrivateKey) {
+	c.installID = installID
+	c.installKey = key
+}
+
+// sign attaches the install's signature to an API request, if the client
+// has an install identity
+func (c *ScannerClient) sign(req *http.Request, body []byte) {
+	if c.installKey == nil {
+		return
+	}
+	sig := identity.Sign(c.installKey, req.Method, req.URL.Path, body, time.Now())
+	req.Header.Set(identity.HeaderInstall, c.installID)
+	req.Header.Set(identity.HeaderTimestamp, sig.Timestamp)
+	req.Header.Set(identity.HeaderSignature, sig.Value)
+}
+
+// SetRegions lets scans go to whichever of endpoints is fastest and healthy,
+// probing them every interval. It has no effect with a single endpoint.
+func (c *ScannerClient) SetRegions(endpoints []string, interval time.Duration, logger *slog.Logger) {
+	c.regions = newRegionSelector(endpoints, interval, c.httpClient, logger)
+}
+
+// SetPins only lets API requests go to hosts in pins, over TLS connections
+// whose certificate chain carries one of the host's pinned keys. Every
+// endpoint must be an HTTPS URL on a pinned host. Official API hosts are
+// pinned to defaultAPIPins unless pins lists them; with neither it is off.
+func (c *ScannerClient) SetPins(pins map[string][]string, endpoints []string, logger *slog.Logger) error {
+	p, err := newAPIPins(pins, endpoints, logger)
+	if err != nil || p == nil {
+		return err
+	}
+	c.pins = p
+	c.httpClient.Transport = chaos.Transport(chaos.Scanner, p.transport(http.DefaultTransport.(*http.Transport)))
+	return nil
+}
+
+// Pinning reports pin checks of the API connection; nil when pinning is off
+func (c *ScannerClient) Pinning() *PinStats {
+	return c.pins.stats()
+}
+
+// apiURL returns the API endpoint to send requests to
+func (c *ScannerClient) apiURL() string {
+	if c.regions != nil {
+		return c.regions.endpoint()
+	}
+	return c.baseURL
+}
+
+// Regions reports the regional endpoints and the active one, or nil with a
+// single endpoint
+func (c *ScannerClient) Regions() *RegionStats {
+	return c.regions.stats()
+}
+
+// SetLocal scans content in-process with local instead of the API. With
+// fallback, content no local rule flagged is also sent to the API, and the
+// local verdict stands when the API can't be reached.
+func (c *ScannerClient) SetLocal(local *localScanner, fallback bool) {
+	c.local = local
+	c.localFallback = fallback
+}
+
+// LocalRules names the rule packs scans are matched against in-process, or
+// is empty when scans go to the API
+func (c *ScannerClient) LocalRules() string {
+	if c.local == nil {
+		return ""
+	}
+	return c.local.version
+}
+
 // SetSolanaWallet sets the Solana wallet for x402 payments
 func (c *ScannerClient) SetSolanaWallet(w X402Wallet) {
 	c.solanaWallet = w
@@ -99,31 +214,163 @@ func (c *ScannerClient) SetSolanaWallet(w X402Wallet) {
 
 // ScanContent scans external content for prompt injection attacks
 func (c *ScannerClient) ScanContent(ctx context.Context, content []byte, sourceURL, contentType string) (*ScanResult, error) {
+	var local *ScanResult
+	if c.local != nil {
+		if local = c.local.scan(content); !c.localFallback || local.Decision != DecisionAllow {
+			return local, nil
+		}
+	}
+
 	req := ScanRequest{
 		Text:        string(content),
 		SourceURL:   sourceURL,
 		SourceType:  "http_proxy",
 		ContentType: contentType,
+		Mode:        c.modeFor(sourceURL),
 	}
 
-	return c.scanWithPayment(ctx, "/v1/scan/content", req)
+	result, err := c.scanWithPayment(ctx, "/v1/scan/content", req)
+	if err != nil && local != nil {
+		return local, nil
+	}
+	return result, err
+}
+
+// ScanContentChunks scans the windows of an oversized document in order,
+// stopping at the first BLOCK. Payment requirements learned from the first
+// window are reused to pay the rest upfront, saving a 402 round trip per
+// window. Results for the windows scanned so far are returned. The local
+// scanner, when set, handles every window as ScanContent does.
+func (c *ScannerClient) ScanContentChunks(ctx context.Context, chunks [][]byte, sourceURL, contentType string) ([]*ScanResult, error) {
+	var local []*ScanResult
+	if c.local != nil {
+		flagged := false
+		for _, chunk := range chunks {
+			result := c.local.scan(chunk)
+			local = append(local, result)
+			flagged = flagged || result.Decision != DecisionAllow
+			if result.Decision == DecisionBlock {
+				break
+			}
+		}
+		if !c.localFallback || flagged {
+			return local, nil
+		}
+	}
+
+	var prepaid *wallet.PaymentRequirements
+	results := make([]*ScanResult, 0, len(chunks))
+	for _, chunk := range chunks {
+		req := ScanRequest{
+			Text:        string(chunk),
+			SourceURL:   sourceURL,
+			SourceType:  "http_proxy",
+			ContentType: contentType,
+			Mode:        c.modeFor(sourceURL),
+		}
+		result, paid, err := c.scanPaying(ctx, "/v1/scan/content", req, prepaid)
+		if err != nil {
+			c.failures.Add(1)
+			if local != nil {
+				return local, nil
+			}
+			return results, err
+		}
+		prepaid = paid
+		results = append(results, result)
+		if result.Decision == DecisionBlock {
+			break
+		}
+	}
+	return results, nil
 }
 
 // scanWithPayment performs a scan request with automatic x402 payment handling
 func (c *ScannerClient) scanWithPayment(ctx context.Context, endpoint string, reqBody interface{}) (*ScanResult, error) {
+	result, _, err := c.scanPaying(ctx, endpoint, reqBody, nil)
+	if err != nil {
+		c.failures.Add(1)
+	}
+	return result, err
+}
+
+// scanPaying performs a scan request, paying when the server answers 402. If
+// prepaid is set, a payment for it is attached to the first attempt. It
+// returns the requirements that were paid, or nil if no payment was needed.
+func (c *ScannerClient) scanPaying(ctx context.Context, endpoint string, reqBody interface{}, prepaid *wallet.PaymentRequirements) (*ScanResult, *wallet.PaymentRequirements, error) {
+	paymentHeader := ""
+	if prepaid != nil {
+		var err error
+		if paymentHeader, err = c.scanPayment(prepaid); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Try the request first (might already have credit or in dev mode)
-	result, statusCode, paymentReq, err := c.scan(ctx, endpoint, reqBody, "")
+	result, statusCode, paymentReq, err := c.scan(ctx, endpoint, reqBody, paymentHeader)
 
 	// If successful or error other than 402, return immediately
 	if err != nil || statusCode != http.StatusPaymentRequired {
-		return result, err
+		if err == nil {
+			c.recordCost(result, prepaid)
+		}
+		return result, prepaid, err
 	}
 
-	// Handle 402 Payment Required
+	// Handle 402 Payment Required (including stale prepaid requirements)
 	if paymentReq == nil {
-		return nil, fmt.Errorf("payment required but no requirements received")
+		return nil, nil, fmt.Errorf("payment required but no requirements received")
+	}
+
+	paymentHeader, err = c.scanPayment(paymentReq)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// Retry with payment
+	result, statusCode, _, err = c.scan(ctx, endpoint, reqBody, paymentHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if statusCode == http.StatusPaymentRequired {
+		return nil, nil, fmt.Errorf("payment was rejected - insufficient funds or invalid payment. Check your balance with 'stronghold wallet balance'")
+	}
+
+	c.presign.learn(paymentReq)
+	c.recordCost(result, paymentReq)
+	return result, paymentReq, nil
+}
+
+// recordCost counts a scan in the spend totals. The API reports what a
+// billed scan cost; an x402 payment made for a scan it didn't report on is
+// taken as the cost instead.
+func (c *ScannerClient) recordCost(result *ScanResult, paid *wallet.PaymentRequirements) {
+	cost := resultCost(result)
+	if cost == 0 {
+		if cost = paidCost(paid); cost > 0 {
+			setResultCost(result, cost)
+		}
+	}
+	c.spend.add(time.Now(), cost)
+}
+
+// Spend returns what API scans have cost over the last hour and day
+func (c *ScannerClient) Spend() *ScanSpendStats {
+	return c.spend.stats(time.Now())
+}
+
+// scanPayment returns a pre-signed payment for a scan if one is ready,
+// otherwise signs one now
+func (c *ScannerClient) scanPayment(paymentReq *wallet.PaymentRequirements) (string, error) {
+	if header, ok := c.presign.take(paymentReq); ok {
+		return header, nil
+	}
+	return c.createPayment(paymentReq)
+}
+
+// createPayment signs an x402 payment with the wallet for the required network
+func (c *ScannerClient) createPayment(paymentReq *wallet.PaymentRequirements) (string, error) {
 	// Select wallet based on network
 	selectedWallet := c.wallet
 	if wallet.IsSolanaNetwork(paymentReq.Network) {
@@ -131,32 +378,22 @@ func (c *ScannerClient) scanWithPayment(ctx context.Context, endpoint string, re
 	}
 
 	if selectedWallet == nil {
-		return nil, fmt.Errorf("payment required but no wallet configured for network %s. Run 'stronghold wallet list' or 'stronghold wallet balance' to check wallet status, or visit https://getstronghold.xyz/dashboard to add funds", paymentReq.Network)
+		return "", fmt.Errorf("payment required but no wallet configured for network %s. Run 'stronghold wallet list' or 'stronghold wallet balance' to check wallet status, or visit https://getstronghold.xyz/dashboard to add funds", paymentReq.Network)
 	}
 
 	// Create x402 payment
 	paymentHeader, err := selectedWallet.CreateX402Payment(paymentReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create payment: %w", err)
-	}
-
-	// Retry with payment
-	result, statusCode, _, err = c.scan(ctx, endpoint, reqBody, paymentHeader)
-	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to create payment: %w", err)
 	}
-
-	if statusCode == http.StatusPaymentRequired {
-		return nil, fmt.Errorf("payment was rejected - insufficient funds or invalid payment. Check your balance with 'stronghold wallet balance'")
-	}
-
-	return result, nil
+	return paymentHeader, nil
 }
 
 // scan performs the actual scan request
 // Returns: result, statusCode, paymentRequirements (if 402), error
 func (c *ScannerClient) scan(ctx context.Context, endpoint string, reqBody interface{}, paymentHeader string) (*ScanResult, int, *wallet.PaymentRequirements, error) {
-	url := c.baseURL + endpoint
+	base := c.apiURL()
+	url := base + endpoint
 
 	body, err := json.Marshal(reqBody)
 	if err != nil {
@@ -169,18 +406,30 @@ func (c *ScannerClient) scan(ctx context.Context, endpoint string, reqBody inter
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(proxyversion.HeaderVersion, Version)
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
 	if paymentHeader != "" {
 		req.Header.Set("X-Payment", paymentHeader)
 	}
-
-	resp, err := c.httpClient.Do(req)
+	c.sign(req, body)
+
+	sent := time.Now()
+	var resp *http.Response
+	if c.e2e != nil {
+		resp, err = c.sendSealed(ctx, base, req, body)
+	} else {
+		resp, err = c.httpClient.Do(req)
+	}
 	if err != nil {
+		if ctx.Err() == nil {
+			c.regions.fail(base, err)
+		}
 		return nil, 0, nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	c.noteServerDate(resp.Header.Get("Date"), sent, time.Now())
 
 	// Handle 402 Payment Required
 	if resp.StatusCode == http.StatusPaymentRequired {
@@ -191,8 +440,19 @@ func (c *ScannerClient) scan(ctx context.Context, endpoint string, reqBody inter
 		return nil, resp.StatusCode, paymentReq, nil
 	}
 
+	if resp.StatusCode == http.StatusUpgradeRequired {
+		return nil, resp.StatusCode, nil, c.upgradeRequired(resp)
+	}
+	c.noteUpdateStatus(resp.Header.Get(proxyversion.HeaderUpdate))
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		switch {
+		case resp.StatusCode == http.StatusMisdirectedRequest:
+			c.regions.misdirected(base, body)
+		case resp.StatusCode >= http.StatusInternalServerError:
+			c.regions.fail(base, fmt.Errorf("scan failed: %s", resp.Status))
+		}
 		return nil, resp.StatusCode, nil, fmt.Errorf("scan failed: %s - %s", resp.Status, string(body))
 	}
 
@@ -201,30 +461,47 @@ func (c *ScannerClient) scan(ctx context.Context, endpoint string, reqBody inter
 		return nil, resp.StatusCode, nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	// A new major schema may change what fields mean; the result can't be
+	// trusted, so it is handled like any other failed scan
+	if !types.Compatible(result.SchemaVersion) {
+		return nil, resp.StatusCode, nil, fmt.Errorf("unsupported scan result schema %s (this proxy reads %s); upgrade Stronghold", result.SchemaVersion, types.SchemaVersion)
+	}
+	if cost := headerCost(resp.Header); cost > 0 {
+		setResultCost(&result, cost)
+	}
+
 	return &result, resp.StatusCode, nil, nil
 }
 
-// paymentOption represents a single payment option from the 402 response
+// paymentOption represents a single payment option from the 402 response.
+// PayTo and MaxAmountRequired are the x402 spec names some third-party
+// servers use in place of Recipient and Amount.
 type paymentOption struct {
-	Scheme         string `json:"scheme"`
-	Network        string `json:"network"`
-	Recipient      string `json:"recipient"`
-	Amount         string `json:"amount"`
-	Currency       string `json:"currency"`
-	FacilitatorURL string `json:"facilitator_url"`
-	Description    string `json:"description"`
-	FeePayer       string `json:"fee_payer,omitempty"`
+	Scheme            string `json:"scheme"`
+	Network           string `json:"network"`
+	Recipient         string `json:"recipient"`
+	Amount            string `json:"amount"`
+	Currency          string `json:"currency"`
+	FacilitatorURL    string `json:"facilitator_url"`
+	Description       string `json:"description"`
+	FeePayer          string `json:"fee_payer,omitempty"`
+	PayTo             string `json:"payTo,omitempty"`
+	MaxAmountRequired string `json:"maxAmountRequired,omitempty"`
 }
 
 // parsePaymentRequired parses a 402 response to extract payment requirements.
-// It inspects the "accepts" array and selects the first option the client has
-// a wallet for. Falls back to "payment_requirements" for older servers.
 func (c *ScannerClient) parsePaymentRequired(resp *http.Response) (*wallet.PaymentRequirements, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return c.selectPaymentOption(body)
+}
 
+// selectPaymentOption picks payment requirements from a 402 body. It inspects
+// the "accepts" array and selects the first option the client has a wallet
+// for. Falls back to "payment_requirements" for older servers.
+func (c *ScannerClient) selectPaymentOption(body []byte) (*wallet.PaymentRequirements, error) {
 	var response struct {
 		Error               string          `json:"error"`
 		PaymentRequirements paymentOption   `json:"payment_requirements"`
@@ -264,11 +541,18 @@ func (c *ScannerClient) hasWalletForNetwork(network string) bool {
 
 // optionToRequirements converts a paymentOption to wallet.PaymentRequirements
 func optionToRequirements(opt *paymentOption) *wallet.PaymentRequirements {
+	recipient, amount := opt.Recipient, opt.Amount
+	if recipient == "" {
+		recipient = opt.PayTo
+	}
+	if amount == "" {
+		amount = opt.MaxAmountRequired
+	}
 	return &wallet.PaymentRequirements{
 		Scheme:         opt.Scheme,
 		Network:        opt.Network,
-		Recipient:      opt.Recipient,
-		Amount:         opt.Amount,
+		Recipient:      recipient,
+		Amount:         amount,
 		Currency:       opt.Currency,
 		FacilitatorURL: opt.FacilitatorURL,
 		Description:    opt.Description,
@@ -289,6 +573,8 @@ func ShouldScanContentType(contentType string) bool {
 		"application/javascript",
 		"text/javascript",
 		"text/css",
+		"multipart/form-data",
+		"application/x-www-form-urlencoded",
 	}
 
 	for _, t := range scannableTypes {
@@ -302,6 +588,11 @@ func ShouldScanContentType(contentType string) bool {
 
 // IsBinaryContentType determines if a content type is binary
 func IsBinaryContentType(contentType string) bool {
+	// Form bodies are text-bearing even though they match "application/x-"
+	if formtext.IsForm(contentType) {
+		return false
+	}
+
 	binaryTypes := []string{
 		"image/",
 		"video/",
@@ -322,6 +613,34 @@ func IsBinaryContentType(contentType string) bool {
 	return false
 }
 
+// scanPayload returns the text to scan for a body. Form bodies are reduced to
+// their text fields, text files, and file names so binary uploads are never
+// sent to the scanner. Other bodies in legacy charsets are transcoded to
+// UTF-8. ok is false when there is nothing to scan.
+func scanPayload(body []byte, contentType string) (payload []byte, payloadType string, ok bool) {
+	if IsBinaryContentType(contentType) {
+		return nil, "", false
+	}
+	if !formtext.IsForm(contentType) {
+		decoded, name := decodeToUTF8(body, contentType)
+		if name != "utf-8" {
+			contentType = utf8ContentType(contentType)
+		}
+		return decoded, contentType, true
+	}
+
+	form, err := formtext.Extract(body, contentType)
+	if err != nil {
+		// Unparseable form: scan it raw only if it is text
+		return body, contentType, utf8.Valid(body)
+	}
+	text := form.Text()
+	if text == "" {
+		return nil, "", false
+	}
+	return []byte(text), "text/plain", true
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"stronghold/internal/usdc"
+	"stronghold/internal/wallet"
+)
+
+// costHeader carries what a scan cost, in microUSDC: the API sets it on
+// billed scans, and the proxy on every response it scanned
+const costHeader = "X-Stronghold-Cost"
+
+// costMetadata is the result metadata key the scanner client records a
+// scan's cost under
+const costMetadata = "cost_micro_usdc"
+
+// spendBuckets is the number of one-minute buckets kept, one day's worth
+const spendBuckets = 24 * 60
+
+// ScanSpendStats is what the proxy has paid for scans
+type ScanSpendStats struct {
+	LastHour      usdc.MicroUSDC `json:"last_hour"`
+	LastDay       usdc.MicroUSDC `json:"last_day"`
+	Total         usdc.MicroUSDC `json:"total"` // Since the proxy started
+	ScansLastHour int64          `json:"scans_last_hour"`
+	ScansLastDay  int64          `json:"scans_last_day"`
+}
+
+// spendBucket is the spend within one minute
+type spendBucket struct {
+	minute int64 // Unix minute the bucket holds; stale buckets are reused
+	amount usdc.MicroUSDC
+	scans  int64
+}
+
+// scanSpend keeps rolling hourly and daily totals of scan costs in
+// one-minute buckets, so memory stays fixed however many scans are made
+type scanSpend struct {
+	mu      sync.Mutex
+	buckets [spendBuckets]spendBucket
+	total   usdc.MicroUSDC
+}
+
+// add counts a scan that cost amount
+func (s *scanSpend) add(now time.Time, amount usdc.MicroUSDC) {
+	minute := now.Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[minute%spendBuckets]
+	if b.minute != minute {
+		*b = spendBucket{minute: minute}
+	}
+	b.amount += amount
+	b.scans++
+	s.total += amount
+}
+
+// stats sums the buckets within the last hour and day
+func (s *scanSpend) stats(now time.Time) *ScanSpendStats {
+	minute := now.Unix() / 60
+	stats := &ScanSpendStats{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.buckets {
+		age := minute - b.minute
+		if age < 0 || age >= spendBuckets {
+			continue
+		}
+		stats.LastDay += b.amount
+		stats.ScansLastDay += b.scans
+		if age < 60 {
+			stats.LastHour += b.amount
+			stats.ScansLastHour += b.scans
+		}
+	}
+	stats.Total = s.total
+	return stats
+}
+
+// resultCost returns the cost recorded on a scan result, zero if none
+func resultCost(result *ScanResult) usdc.MicroUSDC {
+	if result == nil {
+		return 0
+	}
+	cost, _ := result.Metadata[costMetadata].(usdc.MicroUSDC)
+	return cost
+}
+
+// setResultCost records a scan's cost on its result
+func setResultCost(result *ScanResult, cost usdc.MicroUSDC) {
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata[costMetadata] = cost
+}
+
+// headerCost parses the cost the API reported for a scan
+func headerCost(header http.Header) usdc.MicroUSDC {
+	cost, err := strconv.ParseInt(header.Get(costHeader), 10, 64)
+	if err != nil || cost < 0 {
+		return 0
+	}
+	return usdc.MicroUSDC(cost)
+}
+
+// paidCost is the amount of an x402 payment made for a scan
+func paidCost(paid *wallet.PaymentRequirements) usdc.MicroUSDC {
+	if paid == nil {
+		return 0
+	}
+	cost, err := strconv.ParseInt(paid.Amount, 10, 64)
+	if err != nil || cost < 0 {
+		return 0
+	}
+	return usdc.MicroUSDC(cost)
+}
+
+// setCostHeader reports what scanning a response cost
+func setCostHeader(header http.Header, result *ScanResult) {
+	header.Set(costHeader, strconv.FormatInt(int64(resultCost(result)), 10))
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+)
+
+func TestScanSpend_RollingWindows(t *testing.T) {
+	var s scanSpend
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	s.add(now.Add(-25*time.Hour), 5000) // Outside both windows
+	s.add(now.Add(-3*time.Hour), 2000)
+	s.add(now.Add(-30*time.Minute), 1000)
+	s.add(now.Add(-30*time.Minute), 0)
+	s.add(now, 500)
+
+	got := s.stats(now)
+	want := &ScanSpendStats{LastHour: 1500, LastDay: 3500, Total: 8500, ScansLastHour: 3, ScansLastDay: 4}
+	if *got != *want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// A day later the buckets are reused rather than added to
+	s.add(now.Add(24*time.Hour), 100)
+	got = s.stats(now.Add(24 * time.Hour))
+	if got.LastHour != 100 || got.LastDay != 100 || got.ScansLastDay != 1 {
+		t.Errorf("expected only the new scan a day later, got %+v", got)
+	}
+}
+
+func TestScannerClient_RecordsReportedCost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(costHeader, "900")
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+	defer server.Close()
+
+	client := NewScannerClient(server.URL, "sk_live_test")
+	for range 2 {
+		result, err := client.ScanContent(context.Background(), []byte("test content"), "https://example.com", "text/plain")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cost := resultCost(result); cost != 900 {
+			t.Errorf("expected cost 900, got %d", cost)
+		}
+	}
+
+	if spend := client.Spend(); spend.LastHour != 1800 || spend.ScansLastHour != 2 {
+		t.Errorf("unexpected spend: %+v", spend)
+	}
+}
+
+func TestMergeResults_SumsCost(t *testing.T) {
+	a := &ScanResult{Decision: DecisionAllow}
+	setResultCost(a, 1000)
+	b := &ScanResult{Decision: DecisionWarn}
+	setResultCost(b, 1000)
+
+	if cost := resultCost(mergeResults([]*ScanResult{a, b}, 2)); cost != 2000 {
+		t.Errorf("expected merged cost 2000, got %d", cost)
+	}
+	if cost := resultCost(mergeResults([]*ScanResult{{Decision: DecisionAllow}}, 1)); cost != 0 {
+		t.Errorf("expected no cost for free scans, got %d", cost)
+	}
+}
+
+func TestHandleHTTP_CostHeader(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>hello</body></html>"))
+	}))
+	defer upstream.Close()
+
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(costHeader, "1000")
+		json.NewEncoder(w).Encode(ScanResult{Decision: DecisionAllow})
+	}))
+	defer scanner.Close()
+
+	s := newTestServer(t, newTestConfig(scanner.URL))
+	rec := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", upstream.URL+"/", nil))
+
+	if got := rec.Header().Get(costHeader); got != "1000" {
+		t.Errorf("expected %s=1000, got %q", costHeader, got)
+	}
+
+	rec = httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	var health struct {
+		ScanSpend *ScanSpendStats `json:"scan_spend"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&health); err != nil {
+		t.Fatalf("failed to decode health: %v", err)
+	}
+	if health.ScanSpend == nil || health.ScanSpend.LastDay != usdc.MicroUSDC(1000) || health.ScanSpend.ScansLastDay != 1 {
+		t.Errorf("unexpected scan spend in health: %+v", health.ScanSpend)
+	}
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+const (
+	defaultMaxInflightScans   = 64
+	defaultMaxInflightPerHost = 8
+)
+
+// scanScheduler bounds concurrent scans globally and per destination host.
+// When the global limit is reached, freed slots are handed to waiting hosts
+// in round-robin order, so one chatty destination cannot starve the others.
+type scanScheduler struct {
+	mu      sync.Mutex
+	global  int
+	perHost int
+	running int
+	hosts   map[string]*hostScans
+	ring    []string // Hosts with waiters, in service order
+}
+
+// hostScans tracks one destination's running and queued scans
+type hostScans struct {
+	running int
+	waiters []chan struct{}
+}
+
+// ScanQueueStats reports scheduler occupancy
+type ScanQueueStats struct {
+	Inflight int `json:"inflight"`
+	Queued   int `json:"queued"`
+}
+
+func newScanScheduler(global, perHost int) *scanScheduler {
+	if global <= 0 {
+		global = defaultMaxInflightScans
+	}
+	if perHost <= 0 {
+		perHost = defaultMaxInflightPerHost
+	}
+	return &scanScheduler{
+		global:  global,
+		perHost: perHost,
+		hosts:   make(map[string]*hostScans),
+	}
+}
+
+// acquire waits for a scan slot for host. The returned release must be called
+// when the scan finishes. A nil scheduler imposes no limit.
+func (s *scanScheduler) acquire(ctx context.Context, host string) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	h := s.hosts[host]
+	if h == nil {
+		h = &hostScans{}
+		s.hosts[host] = h
+	}
+
+	// Run immediately only if nobody is queued ahead, to keep ordering fair
+	if s.running < s.global && h.running < s.perHost && len(s.ring) == 0 {
+		s.running++
+		h.running++
+		s.mu.Unlock()
+		return s.releaser(host), nil
+	}
+
+	ready := make(chan struct{})
+	h.waiters = append(h.waiters, ready)
+	if !slices.Contains(s.ring, host) {
+		s.ring = append(s.ring, host)
+	}
+	s.dispatch()
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return s.releaser(host), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if i := slices.Index(h.waiters, ready); i >= 0 {
+			h.waiters = slices.Delete(h.waiters, i, i+1)
+			if len(h.waiters) == 0 {
+				s.ring = slices.DeleteFunc(s.ring, func(x string) bool { return x == host })
+			}
+			s.cleanup(host)
+			return nil, fmt.Errorf("scan queue wait for %s: %w", host, ctx.Err())
+		}
+		// Granted while cancelling: hand the slot back
+		s.release(host)
+		return nil, fmt.Errorf("scan queue wait for %s: %w", host, ctx.Err())
+	}
+}
+
+func (s *scanScheduler) releaser(host string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.release(host)
+			s.mu.Unlock()
+		})
+	}
+}
+
+// release frees a slot held by host. Callers hold s.mu.
+func (s *scanScheduler) release(host string) {
+	s.running--
+	s.hosts[host].running--
+	s.dispatch()
+	s.cleanup(host)
+}
+
+// dispatch grants free slots to queued hosts round-robin. Callers hold s.mu.
+func (s *scanScheduler) dispatch() {
+	for s.running < s.global {
+		i := slices.IndexFunc(s.ring, func(host string) bool {
+			return s.hosts[host].running < s.perHost
+		})
+		if i < 0 {
+			return
+		}
+
+		host := s.ring[i]
+		h := s.hosts[host]
+		ready := h.waiters[0]
+		h.waiters = h.waiters[1:]
+		s.running++
+		h.running++
+		close(ready)
+
+		// Served hosts go to the back of the line
+		s.ring = slices.Delete(s.ring, i, i+1)
+		if len(h.waiters) > 0 {
+			s.ring = append(s.ring, host)
+		}
+	}
+}
+
+// cleanup forgets idle hosts. Callers hold s.mu.
+func (s *scanScheduler) cleanup(host string) {
+	if h := s.hosts[host]; h != nil && h.running == 0 && len(h.waiters) == 0 {
+		delete(s.hosts, host)
+	}
+}
+
+// stats returns current occupancy
+func (s *scanScheduler) stats() ScanQueueStats {
+	if s == nil {
+		return ScanQueueStats{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := ScanQueueStats{Inflight: s.running}
+	for _, h := range s.hosts {
+		stats.Queued += len(h.waiters)
+	}
+	return stats
+}
+
+// scanHost returns the host a scan is attributed to
+func scanHost(sourceURL string) string {
+	if u, err := url.Parse(sourceURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return sourceURL
+}
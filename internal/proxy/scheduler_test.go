@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanScheduler_PerHostLimit(t *testing.T) {
+	s := newScanScheduler(10, 1)
+
+	release, err := s.acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.acquire(ctx, "a.example"); err == nil {
+		t.Fatal("expected second scan for the same host to wait past the deadline")
+	}
+
+	// Another host is not held up by the saturated one
+	releaseB, err := s.acquire(context.Background(), "b.example")
+	if err != nil {
+		t.Fatalf("acquire other host: %v", err)
+	}
+	releaseB()
+	release()
+
+	if st := s.stats(); st.Inflight != 0 || st.Queued != 0 || len(s.hosts) != 0 {
+		t.Errorf("expected scheduler to be idle, got %+v with %d hosts", st, len(s.hosts))
+	}
+}
+
+func TestScanScheduler_RoundRobin(t *testing.T) {
+	s := newScanScheduler(1, 10)
+
+	hold, err := s.acquire(context.Background(), "busy.example")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	// A chatty host queues three scans before a quiet host queues one
+	for i, host := range []string{"chatty.example", "chatty.example", "chatty.example", "quiet.example"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.acquire(context.Background(), host)
+			if err != nil {
+				t.Errorf("acquire %s: %v", host, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, host)
+			mu.Unlock()
+			release()
+		}()
+		for s.stats().Queued != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	hold()
+	wg.Wait()
+
+	want := []string{"chatty.example", "quiet.example", "chatty.example", "chatty.example"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected round-robin order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestScanScheduler_Nil(t *testing.T) {
+	var s *scanScheduler
+	release, err := s.acquire(context.Background(), "a.example")
+	if err != nil {
+		t.Fatalf("nil scheduler must not limit: %v", err)
+	}
+	release()
+}
@@ -18,6 +18,13 @@ import (
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"stronghold/internal/chaos"
+	"stronghold/internal/configschema"
+	"stronghold/internal/configsecret"
+	"stronghold/internal/identity"
+	"stronghold/internal/logrotate"
+	"stronghold/internal/proxyversion"
+	"stronghold/internal/redact"
 	"stronghold/internal/wallet"
 )
 
@@ -30,40 +37,74 @@ type Config struct {
 	Scanning  ScanningConfig  `yaml:"scanning"`
 	Logging   LoggingConfig   `yaml:"logging"`
 	CA        CAConfig        `yaml:"ca"`
+
+	BlockResponse BlockResponseConfig `yaml:"block_response"`
+	Bypass        BypassConfig        `yaml:"bypass"`
+	Policies      PolicyConfig        `yaml:"policies"`
+	DNS           DNSConfig           `yaml:"dns"`
+	Payments      PaymentsConfig      `yaml:"payments"`
+	Resources     ResourcesConfig     `yaml:"resources,omitempty"`
 }
 
 // CAConfig holds CA certificate configuration for MITM
 type CAConfig struct {
-	CertPath string `yaml:"cert_path"`
-	KeyPath  string `yaml:"key_path"`
+	CertPath    string `yaml:"cert_path"`
+	KeyPath     string `yaml:"key_path"`
+	Pregenerate bool   `yaml:"pregenerate,omitempty"` // Generate certs for policies.expected_hosts at startup
 }
 
 // WalletConfig holds wallet configuration
 type WalletConfig struct {
-	Address       string `yaml:"address"`
-	Network       string `yaml:"network"`
-	SolanaAddress string `yaml:"solana_address"`
-	SolanaNetwork string `yaml:"solana_network"`
+	Address       string        `yaml:"address"`
+	Network       string        `yaml:"network"`
+	SolanaAddress string        `yaml:"solana_address"`
+	SolanaNetwork string        `yaml:"solana_network"`
+	AutoPay       AutoPayConfig `yaml:"autopay,omitempty"`
+	Presign       PresignConfig `yaml:"presign,omitempty"`
 }
 
 // ProxyConfig holds proxy-specific configuration
 type ProxyConfig struct {
-	Port int    `yaml:"port"`
-	Bind string `yaml:"bind"`
+	Port int        `yaml:"port"`
+	Bind string     `yaml:"bind"`
+	IPv6 *bool      `yaml:"ipv6,omitempty"` // Also listen on [::1] when bound to loopback (default true)
+	Pool PoolConfig `yaml:"pool,omitempty"` // Upstream connection reuse
+
+	MaxInflightScans   int `yaml:"max_inflight_scans,omitempty"`    // Concurrent scans across all hosts (default 64)
+	MaxInflightPerHost int `yaml:"max_inflight_per_host,omitempty"` // Concurrent scans per destination host (default 8)
+}
+
+// IPv6Enabled reports whether the proxy should accept IPv6 connections
+func (c ProxyConfig) IPv6Enabled() bool {
+	return c.IPv6 == nil || *c.IPv6
+}
+
+// isLoopbackBind reports whether bind is an IPv4 loopback address or localhost
+func isLoopbackBind(bind string) bool {
+	if bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback() && ip.To4() != nil
 }
 
 // APIConfig holds API configuration
 type APIConfig struct {
-	Endpoint string        `yaml:"endpoint"`
-	Timeout  time.Duration `yaml:"timeout"`
+	Endpoint          configschema.Endpoints `yaml:"endpoint"` // One URL, or one per region to pick the fastest from
+	Timeout           time.Duration          `yaml:"timeout"`
+	HeartbeatInterval time.Duration          `yaml:"heartbeat_interval,omitempty"` // How often a registered install reports in (default 5m)
+	ProbeInterval     time.Duration          `yaml:"probe_interval,omitempty"`     // How often regional endpoints are probed for latency (default 5m)
+	Pins              map[string][]string    `yaml:"pins,omitempty"`               // SPKI SHA-256 hashes per API hostname; scans only go to pinned hosts
 }
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	Token    string `yaml:"token"`
-	Email    string `yaml:"email"`
-	UserID   string `yaml:"user_id"`
-	LoggedIn bool   `yaml:"logged_in"`
+	Token          string `yaml:"token"`
+	Email          string `yaml:"email"`
+	UserID         string `yaml:"user_id"`
+	LoggedIn       bool   `yaml:"logged_in"`
+	InstallID      string `yaml:"install_id,omitempty"`       // Registered install that signs scan requests
+	InstallKeyPath string `yaml:"install_key_path,omitempty"` // Install's Ed25519 signing key
 }
 
 // ScanTypeConfig configures behavior for a specific scan type
@@ -73,34 +114,95 @@ type ScanTypeConfig struct {
 	ActionOnBlock string `yaml:"action_on_block"` // "allow", "warn", "block"
 }
 
+// ScanModeShadow scans all traffic and reports decisions without ever blocking
+const ScanModeShadow = "shadow"
+
 // ScanningConfig holds scanning configuration
 type ScanningConfig struct {
-	Mode           string         `yaml:"mode"`
-	BlockThreshold float64        `yaml:"block_threshold"`
-	FailOpen       bool           `yaml:"fail_open"`
-	Content        ScanTypeConfig `yaml:"content"` // Prompt injection scanning (incoming)
-	Output         ScanTypeConfig `yaml:"output"`  // Credential leak scanning (outgoing)
+	Mode           string             `yaml:"mode"` // "smart", "strict", "permissive", or "shadow"
+	BlockThreshold float64            `yaml:"block_threshold"`
+	FailOpen       bool               `yaml:"fail_open"`
+	Encrypt        bool               `yaml:"encrypt,omitempty"` // Seal scans end to end so only the scanning backend reads content
+	Content        ScanTypeConfig     `yaml:"content"`           // Prompt injection scanning (incoming)
+	Output         ScanTypeConfig     `yaml:"output"`            // Credential leak scanning (outgoing)
+	Limits         ScanLimitsConfig   `yaml:"limits,omitempty"`
+	Headers        HeaderScanConfig   `yaml:"headers,omitempty"`    // Credentials in outbound request headers
+	Canary         CanaryConfig       `yaml:"canary,omitempty"`     // Honeypot tokens that must never leave the agent's context
+	Request        RequestScanConfig  `yaml:"request,omitempty"`    // Secrets in outbound request bodies and headers
+	Offline        OfflineQueueConfig `yaml:"offline,omitempty"`    // Retro-scan content passed by fail_open
+	Streaming      StreamScanConfig   `yaml:"streaming,omitempty"`  // Scan text/event-stream responses as they arrive
+	LocalOnly      bool               `yaml:"local_only,omitempty"` // Scan in-process with bundled rules: no API calls, no scan payments
+	Local          LocalScanConfig    `yaml:"local,omitempty"`
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level string `yaml:"level"`
-	File  string `yaml:"file"`
+	Level     string `yaml:"level"`
+	File      string `yaml:"file"`
+	AuditFile string `yaml:"audit_file"` // Flagged decisions (JSON lines); defaults to audit.log next to File
+	SpendFile string `yaml:"spend_file"` // Upstream payments (JSON lines); defaults to spend.log next to File
+
+	OfflineFile string `yaml:"offline_file"` // Offline scan queue; defaults to offline-queue.jsonl next to File
+
+	// Unredacted logs wallet addresses, IPs, tokens and scanned content as
+	// they are. Debugging only; redaction is on by default.
+	Unredacted bool `yaml:"unredacted"`
+
+	Rotation LogRotationConfig `yaml:"rotation,omitempty"` // Applies to the proxy log, audit log and spend ledger
+}
+
+// Log rotation defaults
+const (
+	defaultLogMaxBytes   = 10 * 1024 * 1024
+	defaultLogMaxBackups = 10
+)
+
+// LogRotationConfig bounds the disk used by the proxy's logs. Zero values
+// take the defaults.
+type LogRotationConfig struct {
+	MaxBytes   int64         `yaml:"max_bytes,omitempty"`   // Rotate a log before it exceeds this size (default 10MB)
+	Interval   time.Duration `yaml:"interval,omitempty"`    // Also rotate every interval, e.g. 24h for daily; off by default
+	MaxBackups int           `yaml:"max_backups,omitempty"` // Rotated files kept per log (default 10)
+	MaxAge     time.Duration `yaml:"max_age,omitempty"`     // Delete rotated files older than this; off by default
+	Compress   *bool         `yaml:"compress,omitempty"`    // gzip rotated files (default true)
+}
+
+// options converts the rotation config for opening a log with perm
+func (c LogRotationConfig) options(perm os.FileMode) logrotate.Options {
+	opts := logrotate.Options{
+		MaxBytes:   c.MaxBytes,
+		Interval:   c.Interval,
+		MaxBackups: c.MaxBackups,
+		MaxAge:     c.MaxAge,
+		Compress:   c.Compress == nil || *c.Compress,
+		Perm:       perm,
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultLogMaxBytes
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = defaultLogMaxBackups
+	}
+	return opts
 }
 
 // GetProxyAddr returns the proxy address
 func (c *Config) GetProxyAddr() string {
-	return fmt.Sprintf("%s:%d", c.Proxy.Bind, c.Proxy.Port)
+	return net.JoinHostPort(c.Proxy.Bind, strconv.Itoa(c.Proxy.Port))
 }
 
-// applyDefaultScanTypeConfig sets default values for ScanTypeConfig if not already set
-func applyDefaultScanTypeConfig(cfg *ScanTypeConfig) {
-	// If ActionOnWarn is empty, this is an old config without these fields
-	if cfg.ActionOnWarn == "" {
-		cfg.Enabled = true
-		cfg.ActionOnWarn = "warn"
-		cfg.ActionOnBlock = "block"
+// IsShadow reports whether the proxy is in shadow (dry-run) mode
+func (c *ScanningConfig) IsShadow() bool {
+	return c.Mode == ScanModeShadow
+}
+
+// applyShadowMode downgrades an action to "allow" when running in shadow mode.
+// It returns the action to enforce and whether enforcement was suppressed.
+func applyShadowMode(scanning *ScanningConfig, action string) (string, bool) {
+	if scanning.IsShadow() && action != "allow" {
+		return "allow", true
 	}
+	return action, false
 }
 
 // getAction determines what action to take based on scan decision and config
@@ -128,29 +230,54 @@ type Server struct {
 	wallet         *wallet.Wallet
 	httpServer     *http.Server
 	listener       net.Listener
+	listener6      net.Listener // IPv6 loopback listener for redirected IPv6 traffic
 	logger         *slog.Logger
-	logFile        *os.File
+	logFile        *logrotate.Writer
 	httpClient     *http.Client
+	upstream       *upstreamPool
+	scans          *scanScheduler
 	ca             *CA
 	certCache      *CertCache
 	mitm           *MITMHandler
+	blocks         *blockResponder
+	bypass         *bypassVerifier
+	policies       *policyEngine
+	hosts          *hostPolicies
+	audit          *auditLog
+	headerScan     *headerScanner
+	canaries       *canaryWatcher
+	requestScan    *requestScanner
+	autopay        *autoPayer
+	presign        *presigner
+	offline        *offlineQueue
+	heartbeat      *heartbeater
+	pressure       *pressureMonitor
+	dns            *dnsServer
 	requestCount   int64
 	blockedCount   int64
 	warnedCount    int64
+	shadowedCount  int64 // actions suppressed by shadow mode
+	bypassedCount  int64 // requests that skipped scanning via a bypass token
 	mu             sync.RWMutex
 	connSem        chan struct{}   // semaphore to limit concurrent connections
 	connWg         sync.WaitGroup // tracks active connections for graceful drain
+
+	inherited   map[string]*os.File // sockets handed over by the proxy this one replaces
+	ready       *os.File            // pipe to report ready on after a handoff
+	handoffMu   sync.Mutex
+	socketNames []string   // sockets kept for successors, in handoff order
+	sockets     []*os.File
 }
 
 // NewServer creates a new proxy server
 func NewServer(config *Config) (*Server, error) {
 	// Setup logging
 	var handler slog.Handler
-	var logFile *os.File
-	output := os.Stdout
+	var logFile *logrotate.Writer
+	var output io.Writer = os.Stdout
 
 	if config.Logging.File != "" {
-		if f, err := os.OpenFile(config.Logging.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		if f, err := logrotate.Open(config.Logging.File, config.Logging.Rotation.options(0644)); err == nil {
 			output = f
 			logFile = f
 		}
@@ -160,14 +287,64 @@ func NewServer(config *Config) (*Server, error) {
 		Level:     slog.LevelDebug,
 		AddSource: true,
 	})
-	logger := slog.New(handler)
+	logger := slog.New(redact.Wrap(handler, config.Logging.Unredacted))
+	if config.Logging.Unredacted {
+		logger.Warn("log redaction is off (logging.unredacted); logs contain personal data and secrets")
+	}
+
+	blocks, err := newBlockResponder(config.BlockResponse)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block_response config: %w", err)
+	}
+
+	policies, err := newPolicyEngine(config.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policies config: %w", err)
+	}
+
+	hostPolicies, err := newHostPolicies(config.Policies.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policies.hosts config: %w", err)
+	}
 
-	// Create scanner client
-	scanner := NewScannerClient(config.API.Endpoint, config.Auth.Token)
+	if err := config.Resources.validate(); err != nil {
+		return nil, fmt.Errorf("invalid resources config: %w", err)
+	}
+
+	// Create scanner client. Local-only scanning without an API fallback never
+	// contacts the API, so there is nothing to probe, pin, sign or report to.
+	scanner := NewScannerClient(config.API.Endpoint.Primary(), config.Auth.Token)
+	scanner.SetMode(config.Scanning.Mode)
+	scanner.SetHostPolicies(hostPolicies)
+	if config.Scanning.LocalOnly {
+		local, err := newLocalScanner(config.Scanning.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scanning.local config: %w", err)
+		}
+		scanner.SetLocal(local, config.Scanning.Local.APIFallback)
+		logger.Info("scanning locally", "rules", local.version, "api_fallback", config.Scanning.Local.APIFallback)
+	}
+	if !config.Scanning.LocalOnly || config.Scanning.Local.APIFallback {
+		scanner.SetEncryption(config.Scanning.Encrypt)
+		scanner.SetRegions(config.API.Endpoint, config.API.ProbeInterval, logger)
+		if err := scanner.SetPins(config.API.Pins, config.API.Endpoint, logger); err != nil {
+			return nil, fmt.Errorf("invalid api.pins config: %w", err)
+		}
+		if config.Auth.InstallID != "" && config.Auth.InstallKeyPath != "" {
+			key, err := identity.LoadKey(config.Auth.InstallKeyPath)
+			if err != nil {
+				logger.Warn("failed to load install key; scan requests will not be signed", "error", err)
+			} else {
+				scanner.SetIdentity(config.Auth.InstallID, key)
+			}
+		}
+	}
 
 	// Create standard HTTP client (no socket marks needed - we use user-based filtering)
+	upstream := newUpstreamPool(config.Proxy.Pool)
 	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: upstream,
+		Timeout:   30 * time.Second,
 		// Don't follow redirects to prevent payment headers from being sent
 		// to attacker-controlled URLs via redirect chains
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -181,8 +358,29 @@ func NewServer(config *Config) (*Server, error) {
 		logger:     logger,
 		logFile:    logFile,
 		httpClient: httpClient,
+		upstream:   upstream,
+		scans:      newScanScheduler(config.Proxy.MaxInflightScans, config.Proxy.MaxInflightPerHost),
+		blocks:     blocks,
+		policies:   policies,
+		hosts:      hostPolicies,
+		audit:      newAuditLog(config.Logging, logger),
+		headerScan: newHeaderScanner(config.Scanning.Headers),
+		canaries:   newCanaryWatcher(config.Scanning.Canary),
 		connSem:    make(chan struct{}, 10000),
 	}
+	s.requestScan = newRequestScanner(config.Scanning.Request)
+	s.heartbeat = newHeartbeater(config.API, config.Scanning, scanner, logger)
+	s.pressure = newPressureMonitor(config.Resources, config.Scanning.IsShadow(), logger)
+	s.inherited, s.ready = inheritedSockets()
+
+	if config.DNS.Enabled {
+		s.dns = newDNSServer(config.DNS, &config.Scanning, policies, s.audit, logger)
+	}
+
+	s.bypass = newBypassVerifier(config.Bypass, logger)
+	if s.bypass != nil {
+		s.bypass.onUse = s.recordBypassed
+	}
 
 	// Load EVM wallet if configured
 	if config.Auth.UserID != "" && config.Wallet.Address != "" {
@@ -213,6 +411,23 @@ func NewServer(config *Config) (*Server, error) {
 		}
 	}
 
+	// Upstream x402 payments use the same wallets as scan payments
+	s.autopay = newAutoPayer(config.Wallet.AutoPay, config.Payments.Policy, config.Logging, scanner, logger)
+	if s.autopay != nil {
+		s.autopay.audit = s.audit
+	}
+	s.presign = newPresigner(config.Wallet.Presign, scanner, logger)
+	scanner.presign = s.presign
+
+	if config.Scanning.FailOpen {
+		s.offline = newOfflineQueue(config.Scanning.Offline, config.Logging, func(payload []byte, sourceURL, contentType string) (*ScanResult, error) {
+			return scanText(scanner, s.scans, config.Scanning.Limits, payload, sourceURL, contentType)
+		}, logger)
+		if s.offline != nil {
+			s.offline.audit = s.audit
+		}
+	}
+
 	// Load or create CA for MITM
 	if config.CA.CertPath != "" && config.CA.KeyPath != "" {
 		ca, err := LoadCA(config.CA.CertPath, config.CA.KeyPath)
@@ -222,6 +437,20 @@ func NewServer(config *Config) (*Server, error) {
 			s.ca = ca
 			s.certCache = NewCertCache(ca)
 			s.mitm = NewMITMHandler(s.certCache, scanner, config, logger)
+			s.mitm.onShadow = s.recordShadowed
+			s.mitm.blocks = blocks
+			s.mitm.bypass = s.bypass
+			s.mitm.policies = policies
+			s.mitm.hosts = hostPolicies
+			s.mitm.audit = s.audit
+			s.mitm.headerScan = s.headerScan
+			s.mitm.canaries = s.canaries
+			s.mitm.requestScan = s.requestScan
+			s.mitm.upstream = upstream
+			s.mitm.scans = s.scans
+			s.mitm.autopay = s.autopay
+			s.mitm.offline = s.offline
+			s.mitm.pressure = s.pressure
 			logger.Info("MITM enabled with CA certificate")
 		}
 	} else {
@@ -235,6 +464,20 @@ func NewServer(config *Config) (*Server, error) {
 			s.ca = ca
 			s.certCache = NewCertCache(ca)
 			s.mitm = NewMITMHandler(s.certCache, scanner, config, logger)
+			s.mitm.onShadow = s.recordShadowed
+			s.mitm.blocks = blocks
+			s.mitm.bypass = s.bypass
+			s.mitm.policies = policies
+			s.mitm.hosts = hostPolicies
+			s.mitm.audit = s.audit
+			s.mitm.headerScan = s.headerScan
+			s.mitm.canaries = s.canaries
+			s.mitm.requestScan = s.requestScan
+			s.mitm.upstream = upstream
+			s.mitm.scans = s.scans
+			s.mitm.autopay = s.autopay
+			s.mitm.offline = s.offline
+			s.mitm.pressure = s.pressure
 			logger.Info("MITM enabled with CA certificate", "ca_dir", caDir)
 		}
 	}
@@ -243,6 +486,9 @@ func NewServer(config *Config) (*Server, error) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleRequest)
 	mux.HandleFunc("/health", s.handleHealth)
+	if chaos.Enabled {
+		mux.HandleFunc("/chaos", s.handleChaos)
+	}
 
 	s.httpServer = &http.Server{
 		Handler:      mux,
@@ -262,7 +508,7 @@ func LoadConfig() (*Config, error) {
 			Bind: "127.0.0.1",
 		},
 		API: APIConfig{
-			Endpoint: "https://api.getstronghold.xyz",
+			Endpoint: configschema.Endpoints{"https://api.getstronghold.xyz"},
 			Timeout:  30 * time.Second,
 		},
 		Scanning: ScanningConfig{
@@ -293,27 +539,27 @@ func LoadConfig() (*Config, error) {
 	}
 
 	if data, err := os.ReadFile(configPath); err == nil {
+		// Upgrade older layouts before decoding so renamed keys aren't dropped
+		data, migrated, err := configschema.MigrateFile(configPath, data)
+		if err != nil {
+			return nil, err
+		}
+		if migrated != nil {
+			slog.Info("migrated config file", "path", configPath, "from_version", migrated.From, "to_version", migrated.To, "backup", migrated.Backup)
+		}
+		data, err = configsecret.Decrypt(data, configPath)
+		if err != nil {
+			return nil, err
+		}
 		if err := yaml.Unmarshal(data, config); err != nil {
 			return nil, fmt.Errorf("failed to parse config file: %w", err)
 		}
-		// Apply defaults for new ScanTypeConfig fields if not set (migration)
-		applyDefaultScanTypeConfig(&config.Scanning.Content)
-		applyDefaultScanTypeConfig(&config.Scanning.Output)
 	}
 
-	// Override with environment variables
-	if port := os.Getenv("STRONGHOLD_PROXY_PORT"); port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			config.Proxy.Port = p
-		}
-	}
-
-	if bind := os.Getenv("STRONGHOLD_PROXY_BIND"); bind != "" {
-		config.Proxy.Bind = bind
-	}
-
-	if endpoint := os.Getenv("STRONGHOLD_API_ENDPOINT"); endpoint != "" {
-		config.API.Endpoint = endpoint
+	// Override with environment variables (STRONGHOLD_<SECTION>_<KEY>), so
+	// containers can run without a config file
+	if err := applyEnvOverrides(config); err != nil {
+		return nil, err
 	}
 
 	return config, nil
@@ -323,7 +569,19 @@ func LoadConfig() (*Config, error) {
 func (s *Server) Start(ctx context.Context) error {
 	addr := s.config.GetProxyAddr()
 
-	listener, err := net.Listen("tcp", addr)
+	// A proxy replacing another keeps its sockets, and with them the port
+	listener, err := s.inheritedListener(socketProxy)
+	if err != nil {
+		return err
+	}
+	if listener != nil {
+		addr = listener.Addr().String()
+		if tcpAddr, ok := listener.Addr().(*net.TCPAddr); ok {
+			s.config.Proxy.Port = tcpAddr.Port
+		}
+	} else {
+		listener, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		// Port in use - try to find an available one
 		s.logger.Warn("configured port unavailable, searching for alternative",
@@ -348,16 +606,71 @@ func (s *Server) Start(ctx context.Context) error {
 	s.listener = listener
 	s.logger.Info("proxy listening", "addr", addr, "mitm_enabled", s.mitm != nil)
 
+	// ip6tables/nftables redirect IPv6 traffic to ::1, so a loopback-bound
+	// proxy must listen there too or IPv6 connections bypass scanning
+	if l6, err := s.inheritedListener(socketProxy6); err != nil {
+		s.logger.Warn("IPv6 loopback listener unavailable, IPv6 traffic will not be intercepted", "error", err)
+	} else if l6 != nil {
+		s.listener6 = l6
+		s.logger.Info("proxy listening", "addr", l6.Addr().String())
+	} else if s.config.Proxy.IPv6Enabled() && isLoopbackBind(s.config.Proxy.Bind) {
+		addr6 := net.JoinHostPort("::1", strconv.Itoa(s.config.Proxy.Port))
+		if l6, err := net.Listen("tcp6", addr6); err != nil {
+			s.logger.Warn("IPv6 loopback listener unavailable, IPv6 traffic will not be intercepted", "addr", addr6, "error", err)
+		} else {
+			s.listener6 = l6
+			s.logger.Info("proxy listening", "addr", addr6)
+		}
+	}
+	if s.config.Scanning.IsShadow() {
+		s.logger.Warn("shadow mode enabled: scan decisions are logged but never enforced")
+	}
+
+	if s.dns != nil {
+		if err := s.startDNS(); err != nil {
+			listener.Close()
+			if s.listener6 != nil {
+				s.listener6.Close()
+			}
+			return err
+		}
+	}
+
+	if s.certCache != nil && s.config.CA.Pregenerate {
+		go s.pregenerateCerts()
+	}
+	go s.presign.run(ctx)
+	go s.offline.run(ctx)
+	go s.heartbeat.run(ctx)
+	go s.scanner.regions.run(ctx)
+	go s.pressure.run(ctx)
+
 	// Start accepting raw connections for transparent proxy mode
-	go s.acceptConnections(ctx)
+	go s.acceptConnections(ctx, listener)
+	if s.listener6 != nil {
+		go s.acceptConnections(ctx, s.listener6)
+	}
+	s.reportReady()
 
 	// Wait for context cancellation
 	<-ctx.Done()
 	return nil
 }
 
+// pregenerateCerts fills the cert cache for the expected hosts in the
+// background so their first handshakes don't wait on key generation
+func (s *Server) pregenerateCerts() {
+	start := time.Now()
+	n, err := s.certCache.Pregenerate(s.config.Policies.ExpectedHosts)
+	if err != nil {
+		s.logger.Warn("certificate pre-generation stopped", "generated", n, "error", err)
+		return
+	}
+	s.logger.Info("pre-generated certificates", "count", n, "duration", time.Since(start).Round(time.Millisecond))
+}
+
 // acceptConnections handles incoming TCP connections
-func (s *Server) acceptConnections(ctx context.Context) {
+func (s *Server) acceptConnections(ctx context.Context, listener net.Listener) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -365,10 +678,10 @@ func (s *Server) acceptConnections(ctx context.Context) {
 		default:
 		}
 
-		conn, err := s.listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
-			if ctx.Err() != nil {
-				return // Context cancelled
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return // Context cancelled or listener closed
 			}
 			s.logger.Error("accept error", "error", err)
 			continue
@@ -469,42 +782,56 @@ func (s *Server) handleHTTPConnection(conn net.Conn) {
 
 // tunnelConnection tunnels a TLS connection without MITM
 func (s *Server) tunnelConnection(conn net.Conn) {
-	defer conn.Close()
-
-	var originalDst string
-	var tunnelConn net.Conn = conn
-
-	// Try to get underlying TCP connection for SO_ORIGINAL_DST
 	underlyingConn := conn
 	if pc, ok := conn.(*prefixedConn); ok {
 		underlyingConn = pc.Conn
 	}
 
-	// Try SO_ORIGINAL_DST first (Linux)
-	var err error
-	originalDst, err = GetOriginalDst(underlyingConn)
+	// Try SO_ORIGINAL_DST first (Linux); otherwise the SNI names the destination
+	originalDst, err := GetOriginalDst(underlyingConn)
 	if err != nil {
-		// SO_ORIGINAL_DST failed - try SNI extraction
-		s.logger.Debug("SO_ORIGINAL_DST failed for tunnel, extracting SNI", "error", err)
+		s.logger.Debug("SO_ORIGINAL_DST failed for tunnel, using SNI", "error", err)
+		originalDst = ""
+	}
 
-		// Get the prefix data if this is a prefixedConn
-		var prefix []byte
-		if pc, ok := conn.(*prefixedConn); ok {
-			prefix = pc.prefix
-		}
+	s.tunnelTo(conn, originalDst)
+}
 
-		// We need to read the TLS ClientHello for SNI
-		sni, fullClientHello, sniErr := ExtractSNI(underlyingConn, prefix)
-		if sniErr != nil {
+// tunnelTo tunnels a TLS connection to originalDst, or to the SNI host when
+// originalDst is empty. Policy rules are matched against the SNI because
+// SO_ORIGINAL_DST only gives the destination IP.
+func (s *Server) tunnelTo(conn net.Conn, originalDst string) {
+	defer conn.Close()
+
+	underlyingConn := conn
+	var prefix []byte
+	if pc, ok := conn.(*prefixedConn); ok {
+		underlyingConn = pc.Conn
+		prefix = pc.prefix
+	}
+
+	// Read the TLS ClientHello for SNI; it is replayed to the destination
+	sni, fullClientHello, sniErr := ExtractSNI(underlyingConn, prefix)
+	var tunnelConn net.Conn = newPrefixedConn(underlyingConn, fullClientHello)
+
+	host := sni
+	if sniErr != nil {
+		if originalDst == "" {
 			s.logger.Error("failed to extract SNI for tunnel", "error", sniErr)
 			return
 		}
-
+		// Clients that send no SNI are matched on the destination address
+		s.logger.Debug("no SNI for tunnel, matching policies on the destination", "dst", originalDst, "error", sniErr)
+		host = originalDst
+	}
+	if originalDst == "" {
 		originalDst = sni + ":443"
 		s.logger.Debug("extracted SNI for tunnel", "sni", sni, "dst", originalDst)
+	}
 
-		// Create new prefixed connection with full ClientHello for tunneling
-		tunnelConn = newPrefixedConn(underlyingConn, fullClientHello)
+	// Without MITM there is no HTTP response to send; just drop the connection
+	if v := s.policies.enforce(host, &s.config.Scanning, s.logger); v != nil {
+		return
 	}
 
 	// Connect to destination
@@ -610,6 +937,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.listener6 != nil {
+		s.listener6.Close()
+	}
+	if s.dns != nil {
+		s.dns.Stop()
+	}
 
 	// Wait for active connections to drain with a 30s timeout
 	drainDone := make(chan struct{})
@@ -634,7 +967,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
-	// Close log file handle if we opened one
+	s.upstream.Close()
+
+	// Close log file handles if we opened them
+	s.audit.Close()
+	s.autopay.Close()
 	if s.logFile != nil {
 		s.logFile.Close()
 	}
@@ -645,7 +982,7 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // findAvailablePort searches for an available port starting from startPort
 func (s *Server) findAvailablePort(startPort int) int {
 	for port := startPort; port < startPort+100; port++ {
-		addr := fmt.Sprintf("%s:%d", s.config.Proxy.Bind, port)
+		addr := net.JoinHostPort(s.config.Proxy.Bind, strconv.Itoa(port))
 		listener, err := net.Listen("tcp", addr)
 		if err == nil {
 			listener.Close()
@@ -685,13 +1022,43 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 		targetURL = "http://" + r.Host + r.URL.String()
 	}
 
-	_, err := url.Parse(targetURL)
+	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
 		s.logger.Error("error parsing URL", "error", err)
 		http.Error(w, "Invalid URL", http.StatusBadRequest)
 		return
 	}
 
+	// Local egress policies are evaluated before anything is sent upstream
+	if v := s.policies.enforce(parsedURL.Hostname(), &s.config.Scanning, s.logger); v != nil {
+		writePolicyResponse(w, v)
+		return
+	}
+
+	// Per-host policies may skip content scanning or change how it is scanned
+	scanning, skipped := s.hosts.scanning(parsedURL.Hostname(), &s.config.Scanning)
+
+	// Strip and verify any bypass token before headers are copied upstream
+	bypassed := s.bypass.check(r, parsedURL.Hostname()) != nil
+	requestID := generateRequestID()
+
+	// Credentials in outbound headers are checked before they leave
+	if !bypassed && s.checkRequestHeaders(w, r, targetURL, parsedURL.Hostname(), requestID) {
+		return
+	}
+
+	// Canaries leaving the agent's context mean exfiltration is under way;
+	// requests to LLM APIs get a fresh one instead
+	if !bypassed && s.checkCanaries(w, r, targetURL, parsedURL.Hostname(), requestID) {
+		return
+	}
+
+	// Secrets in outbound bodies and headers must not leave for unknown hosts
+	if !bypassed && s.checkRequestSecrets(w, r, targetURL, parsedURL.Hostname(), requestID) {
+		return
+	}
+	s.canaries.inject(r, parsedURL.Hostname(), s.config.Scanning.Limits.maxBodySize())
+
 	// Create the outgoing request
 	outReq, err := http.NewRequest(r.Method, targetURL, r.Body)
 	if err != nil {
@@ -712,8 +1079,9 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 	outReq.Header.Del("Proxy-Authenticate")
 
 	// Perform the request using standard client
-	// (no socket marks needed - we use user-based filtering via nftables/pf)
-	resp, err := s.httpClient.Do(outReq)
+	// (no socket marks needed - we use user-based filtering via nftables/pf).
+	// Upstream 402s are paid here when auto-payment allows it.
+	resp, err := s.autopay.roundTrip(roundTripperFunc(s.httpClient.Do), outReq, requestID)
 	if err != nil {
 		s.logger.Error("error forwarding request", "error", err)
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
@@ -722,21 +1090,54 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 	defer resp.Body.Close()
 
 	// Add base Stronghold headers
-	requestID := generateRequestID()
 	w.Header().Set("X-Stronghold-Request-ID", requestID)
 	w.Header().Set("X-Stronghold-Scan-Latency", fmt.Sprintf("%dms", time.Since(start).Milliseconds()))
 
 	// Check content type BEFORE reading the body to avoid buffering large binaries
 	contentType := resp.Header.Get("Content-Type")
-	shouldScan := s.config.Scanning.Content.Enabled &&
+	shouldScan := scanning.Content.Enabled && !bypassed && !skipped &&
 		ShouldScanContentType(contentType) && !IsBinaryContentType(contentType)
 
+	// Over the resource budget, some scans are shed rather than buffered
+	shed := shedNone
+	if shouldScan {
+		shed = s.pressure.shed(contentType)
+	}
+	switch shed {
+	case shedBlock:
+		result := pressureBlockResult()
+		s.logger.Warn("content blocked under resource pressure", "url", targetURL, "content_type", contentType, "requestID", requestID)
+		status, header, blockBody := s.blocks.render(r, BlockInfo{
+			Reason:            result.Reason,
+			Decision:          result.Decision,
+			RequestID:         requestID,
+			URL:               targetURL,
+			RecommendedAction: result.RecommendedAction,
+		})
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("X-Stronghold-Scan-Type", "shed")
+		w.WriteHeader(status)
+		w.Write(blockBody)
+		return
+	case shedPass:
+		s.logger.Warn("scan skipped under resource pressure", "url", targetURL, "content_type", contentType, "requestID", requestID)
+		shouldScan = false
+	}
+
 	if !shouldScan {
 		// Non-scannable content: stream directly without buffering
 		w.Header().Set("X-Stronghold-Decision", "ALLOW")
 		w.Header().Set("X-Stronghold-Action", "allow")
-		if !s.config.Scanning.Content.Enabled {
+		if bypassed {
+			w.Header().Set("X-Stronghold-Scan-Type", "bypassed")
+		} else if skipped {
+			w.Header().Set("X-Stronghold-Scan-Type", "skipped-policy")
+		} else if !s.config.Scanning.Content.Enabled {
 			w.Header().Set("X-Stronghold-Scan-Type", "disabled")
+		} else if shed == shedPass {
+			w.Header().Set("X-Stronghold-Scan-Type", "skipped-pressure")
 		} else {
 			w.Header().Set("X-Stronghold-Scan-Type", "skipped-unscannable")
 		}
@@ -751,16 +1152,17 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 		return
 	}
 
-	// Scannable content: read with 1MB limit (+ 1 byte to detect oversized)
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024+1))
+	// Scannable content: read up to the scan limit (+ 1 byte to detect oversized)
+	maxBody := s.config.Scanning.Limits.maxBodySize()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)+1))
 	if err != nil {
 		s.logger.Error("error reading response body", "error", err)
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 		return
 	}
 
-	// If body exceeds 1MB, skip scanning and forward as-is
-	if len(body) > 1024*1024 {
+	// If body exceeds the scan limit, skip scanning and forward as-is
+	if len(body) > maxBody {
 		w.Header().Set("X-Stronghold-Decision", "ALLOW")
 		w.Header().Set("X-Stronghold-Action", "allow")
 		w.Header().Set("X-Stronghold-Scan-Type", "skipped-oversized")
@@ -780,19 +1182,20 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 		return
 	}
 
-	// Scan the response body
-	scanResult := s.scanResponse(body, targetURL, contentType)
+	// Scan the response body; suspicious response headers are part of the verdict
+	scanResult := withHeaderFindings(s.scanResponse(body, targetURL, contentType), resp.Header)
 
 	// Determine action based on scan result and config
 	var action string
 	if scanResult != nil {
-		action = getAction(scanResult.Decision, s.config.Scanning.Content)
+		action = getAction(scanResult.Decision, scanning.Content)
 
 		// Always add scan result headers (even when not blocking)
 		w.Header().Set("X-Stronghold-Decision", string(scanResult.Decision))
 		w.Header().Set("X-Stronghold-Reason", scanResult.Reason)
 		w.Header().Set("X-Stronghold-Action", action)
 		w.Header().Set("X-Stronghold-Scan-Type", "content")
+		setCostHeader(w.Header(), scanResult)
 		if score, ok := scanResult.Scores["combined"]; ok {
 			w.Header().Set("X-Stronghold-Score", fmt.Sprintf("%.2f", score))
 		} else if score, ok := scanResult.Scores["heuristic"]; ok {
@@ -810,22 +1213,45 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 			s.mu.Unlock()
 		}
 
+		// In shadow mode, report what would have happened and let the response through
+		if enforced, shadowed := applyShadowMode(scanning, action); shadowed {
+			s.logger.Info("shadow mode: action not enforced",
+				"url", targetURL, "would_action", action, "reason", scanResult.Reason, "decision", scanResult.Decision)
+			w.Header().Set("X-Stronghold-Shadow-Action", action)
+			w.Header().Set("X-Stronghold-Action", enforced)
+			s.recordShadowed()
+			action = enforced
+		}
+
+		if scanResult.Decision != DecisionAllow {
+			s.audit.record(AuditEntry{
+				RequestID:    requestID,
+				Method:       r.Method,
+				URL:          targetURL,
+				Direction:    "response",
+				Decision:     scanResult.Decision,
+				Action:       action,
+				ShadowAction: w.Header().Get("X-Stronghold-Shadow-Action"),
+				Reason:       scanResult.Reason,
+				Threats:      scanResult.ThreatsFound,
+			})
+		}
+
 		// Handle action
 		switch action {
 		case "block":
 			s.logger.Warn("content blocked", "url", targetURL, "reason", scanResult.Reason, "decision", scanResult.Decision)
-			blockBody, _ := json.Marshal(struct {
-				Error             string `json:"error"`
-				Reason            string `json:"reason"`
-				RequestID         string `json:"request_id"`
-				RecommendedAction string `json:"recommended_action"`
-			}{
-				Error:             "Content blocked by Stronghold security scan",
+			status, header, blockBody := s.blocks.render(r, BlockInfo{
 				Reason:            scanResult.Reason,
+				Decision:          scanResult.Decision,
 				RequestID:         requestID,
+				URL:               targetURL,
 				RecommendedAction: scanResult.RecommendedAction,
 			})
-			w.WriteHeader(http.StatusForbidden)
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
 			w.Write(blockBody)
 			return
 		case "warn":
@@ -853,6 +1279,14 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request, start time.T
 
 // handleConnect handles HTTPS CONNECT requests (explicit proxy mode)
 func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
+	// With MITM enabled, policies are evaluated per request inside the tunnel
+	if s.mitm == nil {
+		if v := s.policies.enforce(r.Host, &s.config.Scanning, s.logger); v != nil {
+			writePolicyResponse(w, v)
+			return
+		}
+	}
+
 	// Use standard dialer (no socket marks needed - we use user-based filtering)
 	dialer := &net.Dialer{Timeout: 10 * time.Second}
 	destConn, err := dialer.Dial("tcp", r.Host)
@@ -905,6 +1339,179 @@ func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 	<-done
 }
 
+// checkRequestHeaders applies header scanning to an outbound request,
+// redacting credentials in place or writing a block response. It reports
+// whether the request was blocked.
+func (s *Server) checkRequestHeaders(w http.ResponseWriter, r *http.Request, targetURL, host, requestID string) bool {
+	result, findings, action := s.headerScan.scan(r.Header, host)
+	if result == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	if result.Decision == DecisionBlock {
+		s.blockedCount++
+	} else {
+		s.warnedCount++
+	}
+	s.mu.Unlock()
+
+	entry := AuditEntry{
+		RequestID: requestID,
+		Method:    r.Method,
+		URL:       targetURL,
+		Direction: "request",
+		Decision:  result.Decision,
+		Action:    action,
+		Reason:    result.Reason,
+		Threats:   result.ThreatsFound,
+		Detection: result.DetectionVersion,
+	}
+	if enforced, shadowed := applyShadowMode(&s.config.Scanning, action); shadowed {
+		s.logger.Info("shadow mode: action not enforced",
+			"url", targetURL, "would_action", action, "reason", result.Reason, "decision", result.Decision)
+		s.recordShadowed()
+		entry.ShadowAction = action
+		entry.Action = enforced
+		action = enforced
+	}
+	s.audit.record(entry)
+
+	switch action {
+	case HeaderActionBlock:
+		s.logger.Warn("credential exfiltration blocked", "url", targetURL, "reason", result.Reason)
+		status, header, body := s.blocks.render(r, BlockInfo{
+			Reason:            result.Reason,
+			Decision:          result.Decision,
+			RequestID:         requestID,
+			URL:               targetURL,
+			RecommendedAction: result.RecommendedAction,
+		})
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		w.Write(body)
+		return true
+	case HeaderActionRedact:
+		s.logger.Warn("credentials redacted from request", "url", targetURL, "reason", result.Reason)
+		redactHeaders(r.Header, findings)
+	default:
+		s.logger.Warn("credential exfiltration detected", "url", targetURL, "reason", result.Reason)
+	}
+	return false
+}
+
+// checkCanaries looks for canary tokens in an outbound request and raises a
+// critical alert when one is found, writing a block response unless the
+// canary action is warn. It reports whether the request was blocked.
+func (s *Server) checkCanaries(w http.ResponseWriter, r *http.Request, targetURL, host, requestID string) bool {
+	result, action := s.canaries.check(r, host, s.config.Scanning.Limits.maxBodySize())
+	if result == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	if result.Decision == DecisionBlock {
+		s.blockedCount++
+	} else {
+		s.warnedCount++
+	}
+	s.mu.Unlock()
+
+	s.logger.Error("CANARY TRIGGERED: agent context is being exfiltrated",
+		"url", targetURL, "action", action, "requestID", requestID)
+	entry := AuditEntry{
+		RequestID: requestID,
+		Method:    r.Method,
+		URL:       targetURL,
+		Direction: "request",
+		Decision:  result.Decision,
+		Action:    action,
+		Reason:    result.Reason,
+		Threats:   result.ThreatsFound,
+	}
+	if enforced, shadowed := applyShadowMode(&s.config.Scanning, action); shadowed {
+		s.recordShadowed()
+		entry.ShadowAction = action
+		entry.Action = enforced
+		action = enforced
+	}
+	s.audit.record(entry)
+
+	if action != CanaryActionBlock {
+		return false
+	}
+	status, header, body := s.blocks.render(r, BlockInfo{
+		Reason:            result.Reason,
+		Decision:          result.Decision,
+		RequestID:         requestID,
+		URL:               targetURL,
+		RecommendedAction: result.RecommendedAction,
+	})
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+	return true
+}
+
+// checkRequestSecrets looks for secrets in an outbound request bound for a
+// non-allowlisted host, writing a block response unless the request action
+// is warn. It reports whether the request was blocked.
+func (s *Server) checkRequestSecrets(w http.ResponseWriter, r *http.Request, targetURL, host, requestID string) bool {
+	result, action := s.requestScan.scan(r, host, s.config.Scanning.Limits.maxBodySize())
+	if result == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	if result.Decision == DecisionBlock {
+		s.blockedCount++
+	} else {
+		s.warnedCount++
+	}
+	s.mu.Unlock()
+
+	s.logger.Warn("credential leak detected in request",
+		"url", targetURL, "action", action, "reason", result.Reason, "requestID", requestID)
+	entry := AuditEntry{
+		RequestID: requestID,
+		Method:    r.Method,
+		URL:       targetURL,
+		Direction: "request",
+		Decision:  result.Decision,
+		Action:    action,
+		Reason:    result.Reason,
+		Threats:   result.ThreatsFound,
+	}
+	if enforced, shadowed := applyShadowMode(&s.config.Scanning, action); shadowed {
+		s.recordShadowed()
+		entry.ShadowAction = action
+		entry.Action = enforced
+		action = enforced
+	}
+	s.audit.record(entry)
+
+	if action != RequestActionBlock {
+		return false
+	}
+	status, header, body := s.blocks.render(r, BlockInfo{
+		Reason:            result.Reason,
+		Decision:          result.Decision,
+		RequestID:         requestID,
+		URL:               targetURL,
+		RecommendedAction: result.RecommendedAction,
+	})
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+	return true
+}
+
 // scanResponse scans the response content
 func (s *Server) scanResponse(body []byte, sourceURL, contentType string) *ScanResult {
 	// Skip binary content
@@ -912,8 +1519,8 @@ func (s *Server) scanResponse(body []byte, sourceURL, contentType string) *ScanR
 		return nil
 	}
 
-	// Skip if content is too large (> 1MB)
-	if len(body) > 1024*1024 {
+	// Skip if content is over the scan limit
+	if len(body) > s.config.Scanning.Limits.maxBodySize() {
 		s.logger.Debug("skipping scan: content too large", "bytes", len(body))
 		return nil
 	}
@@ -923,16 +1530,19 @@ func (s *Server) scanResponse(body []byte, sourceURL, contentType string) *ScanR
 		return nil
 	}
 
-	// Perform the scan
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	payload, payloadType, ok := scanPayload(body, contentType)
+	if !ok {
+		return nil
+	}
 
-	result, err := s.scanner.ScanContent(ctx, body, sourceURL, contentType)
+	// Perform the scan
+	result, err := scanText(s.scanner, s.scans, s.config.Scanning.Limits, payload, sourceURL, payloadType)
 	if err != nil {
 		s.logger.Error("scan error", "error", err)
 
 		// Fail open or closed based on configuration
 		if s.config.Scanning.FailOpen {
+			s.offline.enqueue(payload, sourceURL, payloadType)
 			return nil // Allow through
 		}
 
@@ -947,21 +1557,94 @@ func (s *Server) scanResponse(body []byte, sourceURL, contentType string) *ScanR
 	return result
 }
 
+// writePolicyResponse sends the response for a request denied by a policy rule
+func writePolicyResponse(w http.ResponseWriter, v *PolicyViolation) {
+	status, header, body := policyResponse(v)
+	for k, vals := range header {
+		w.Header()[k] = vals
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// recordBypassed counts a request that skipped scanning via a bypass token
+func (s *Server) recordBypassed() {
+	s.mu.Lock()
+	s.bypassedCount++
+	s.mu.Unlock()
+}
+
+// recordShadowed counts an action that shadow mode prevented from being enforced
+func (s *Server) recordShadowed() {
+	s.mu.Lock()
+	s.shadowedCount++
+	s.mu.Unlock()
+}
+
 // handleHealth handles health check requests
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	violations, recentViolations := s.policies.stats()
+
 	s.mu.RLock()
 	stats := struct {
-		Status        string `json:"status"`
+		Status        string               `json:"status"`
+		Version       string               `json:"version"`
+		PID           int                  `json:"pid"` // Changes when a handoff replaces the process serving
+		Update        *proxyversion.Update `json:"update,omitempty"` // Latest update signal from the API
+		Mode          string               `json:"mode,omitempty"`
+		LocalRules    string               `json:"local_rules,omitempty"` // Rule packs matched in-process in local-only mode
 		RequestsTotal int64  `json:"requests_total"`
 		Blocked       int64  `json:"blocked"`
 		Warned        int64  `json:"warned"`
+		Shadowed      int64  `json:"shadowed,omitempty"`
+		Bypassed      int64  `json:"bypassed,omitempty"`
+
+		PolicyViolations int64              `json:"policy_violations,omitempty"`
+		RecentViolations []PolicyViolation  `json:"recent_violations,omitempty"`
+		Upstream         PoolStats          `json:"upstream"`
+		ScanQueue        ScanQueueStats     `json:"scan_queue"`
+		CertCache        *CertCacheStats    `json:"cert_cache,omitempty"`
+		AutoPay          *AutoPayStats      `json:"autopay,omitempty"`
+		Presign          *PresignStats      `json:"presign,omitempty"`
+		OfflineQueue     *OfflineQueueStats `json:"offline_queue,omitempty"`
+		Canary           *CanaryStats       `json:"canary,omitempty"`
+		Clock            *ClockStats        `json:"clock,omitempty"`
+		Regions          *RegionStats       `json:"regions,omitempty"`
+		Pinning          *PinStats          `json:"pinning,omitempty"`
+		Pressure         *PressureStats     `json:"pressure,omitempty"`
+		ScanSpend        *ScanSpendStats    `json:"scan_spend,omitempty"`
 	}{
 		Status:        "healthy",
+		Version:       Version,
+		PID:           os.Getpid(),
+		Update:        s.scanner.Update(),
+		Mode:          s.config.Scanning.Mode,
+		LocalRules:    s.scanner.LocalRules(),
 		RequestsTotal: s.requestCount,
 		Blocked:       s.blockedCount,
 		Warned:        s.warnedCount,
+		Shadowed:      s.shadowedCount,
+		Bypassed:      s.bypassedCount,
+
+		PolicyViolations: violations,
+		RecentViolations: recentViolations,
+		Upstream:         s.upstream.stats(),
+		ScanQueue:        s.scans.stats(),
+		AutoPay:          s.autopay.stats(),
+		Presign:          s.presign.stats(),
+		OfflineQueue:     s.offline.stats(),
+		Canary:           s.canaries.stats(),
+		Clock:            s.scanner.Clock(),
+		Regions:          s.scanner.Regions(),
+		Pinning:          s.scanner.Pinning(),
+		Pressure:         s.pressure.stats(),
+		ScanSpend:        s.scanner.Spend(),
 	}
 	s.mu.RUnlock()
+	if s.certCache != nil {
+		certStats := s.certCache.Stats()
+		stats.CertCache = &certStats
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
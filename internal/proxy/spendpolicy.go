@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"stronghold/internal/usdc"
+)
+
+const (
+	defaultMaxPerRequest = 0.01
+	defaultMaxPerDay     = 1.00
+	// notifyInterval suppresses repeat notifications for the same limit
+	notifyInterval = time.Hour
+)
+
+// PaymentsConfig holds the proxy's payment settings
+type PaymentsConfig struct {
+	Policy SpendPolicyConfig `yaml:"policy,omitempty"`
+}
+
+// SpendPolicyConfig limits what the proxy may spend paying upstream APIs.
+// Limits are hard stops: a payment that would exceed any of them is refused.
+// Amounts are in USDC; hour and day limits are rolling windows.
+type SpendPolicyConfig struct {
+	MaxPerRequest float64            `yaml:"max_per_request,omitempty"` // Default 0.01
+	MaxPerHour    float64            `yaml:"max_per_hour,omitempty"`    // 0 means no hourly limit
+	MaxPerDay     float64            `yaml:"max_per_day,omitempty"`     // Default 1.00
+	Domains       []DomainSpendLimit `yaml:"domains,omitempty"`         // Tighter limits for specific hosts
+	NotifyURL     string             `yaml:"notify_url,omitempty"`      // Receives a JSON POST when a limit is hit
+}
+
+// DomainSpendLimit applies limits to payments for hosts matching Host
+// ("api.example.com" or "*.example.com"). Zero fields are not limited
+// beyond the global policy.
+type DomainSpendLimit struct {
+	Host          string  `yaml:"host"`
+	MaxPerRequest float64 `yaml:"max_per_request,omitempty"`
+	MaxPerHour    float64 `yaml:"max_per_hour,omitempty"`
+	MaxPerDay     float64 `yaml:"max_per_day,omitempty"`
+}
+
+// SpendLimitHit describes a payment refused by the spend policy
+type SpendLimitHit struct {
+	Time   time.Time      `json:"time"`
+	Scope  string         `json:"scope"` // "global" or the domain pattern
+	Limit  string         `json:"limit"` // "per_request", "per_hour", or "per_day"
+	Max    usdc.MicroUSDC `json:"max"`
+	Spent  usdc.MicroUSDC `json:"spent"` // Already spent in the window
+	Amount usdc.MicroUSDC `json:"amount"`
+	Host   string         `json:"host"`
+}
+
+func (h *SpendLimitHit) Error() string {
+	if h.Limit == "per_request" {
+		return fmt.Sprintf("%s USDC exceeds the %s per-request limit of %s", h.Amount, h.Scope, h.Max)
+	}
+	window := "daily"
+	if h.Limit == "per_hour" {
+		window = "hourly"
+	}
+	return fmt.Sprintf("%s %s limit of %s USDC reached (%s spent)", h.Scope, window, h.Max, h.Spent)
+}
+
+// spendLimits is a compiled set of limits; zero means unlimited
+type spendLimits struct {
+	scope      string
+	perRequest usdc.MicroUSDC
+	perHour    usdc.MicroUSDC
+	perDay     usdc.MicroUSDC
+}
+
+// spendEvent is a payment counted against the rolling windows
+type spendEvent struct {
+	id     uint64
+	at     time.Time
+	host   string
+	amount usdc.MicroUSDC
+}
+
+// spendPolicy enforces spend limits over payments made in the last 24 hours
+type spendPolicy struct {
+	global  spendLimits
+	domains []spendLimits
+	notify  *spendNotifier
+
+	mu     sync.Mutex
+	events []spendEvent
+	nextID uint64
+}
+
+func newSpendPolicy(cfg SpendPolicyConfig, logger *slog.Logger) *spendPolicy {
+	p := &spendPolicy{
+		global: spendLimits{
+			scope:      "global",
+			perRequest: usdc.FromFloat(cfg.MaxPerRequest),
+			perHour:    usdc.FromFloat(cfg.MaxPerHour),
+			perDay:     usdc.FromFloat(cfg.MaxPerDay),
+		},
+		notify: newSpendNotifier(cfg.NotifyURL, logger),
+	}
+	if cfg.MaxPerRequest <= 0 {
+		p.global.perRequest = usdc.FromFloat(defaultMaxPerRequest)
+	}
+	if cfg.MaxPerDay <= 0 {
+		p.global.perDay = usdc.FromFloat(defaultMaxPerDay)
+	}
+	for _, d := range cfg.Domains {
+		p.domains = append(p.domains, spendLimits{
+			scope:      strings.ToLower(d.Host),
+			perRequest: usdc.FromFloat(d.MaxPerRequest),
+			perHour:    usdc.FromFloat(d.MaxPerHour),
+			perDay:     usdc.FromFloat(d.MaxPerDay),
+		})
+	}
+	return p
+}
+
+// restore counts earlier accepted payments from the spend ledger
+func (p *spendPolicy) restore(entries []SpendEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cutoff := time.Now().Add(-24 * time.Hour)
+	for _, e := range entries {
+		if e.Accepted && e.Time.After(cutoff) {
+			p.nextID++
+			p.events = append(p.events, spendEvent{id: p.nextID, at: e.Time, host: strings.ToLower(e.Host), amount: e.Amount})
+		}
+	}
+}
+
+// reserve counts amount for host against every applicable limit. It returns
+// an id for release, or the first limit the payment would exceed; limit hits
+// are reported to the notifier.
+func (p *spendPolicy) reserve(host string, amount usdc.MicroUSDC) (uint64, *SpendLimitHit) {
+	host = strings.ToLower(host)
+	now := time.Now()
+
+	p.mu.Lock()
+	p.prune(now)
+	hit := p.check(p.global, host, amount, now)
+	for _, d := range p.domains {
+		if hit != nil {
+			break
+		}
+		if matchHostPattern(d.scope, host) {
+			hit = p.check(d, host, amount, now)
+		}
+	}
+	if hit != nil {
+		p.mu.Unlock()
+		p.notify.send(hit)
+		return 0, hit
+	}
+	p.nextID++
+	id := p.nextID
+	p.events = append(p.events, spendEvent{id: id, at: now, host: host, amount: amount})
+	p.mu.Unlock()
+	return id, nil
+}
+
+// check tests one set of limits. Callers hold p.mu.
+func (p *spendPolicy) check(l spendLimits, host string, amount usdc.MicroUSDC, now time.Time) *SpendLimitHit {
+	hit := func(limit string, max, spent usdc.MicroUSDC) *SpendLimitHit {
+		return &SpendLimitHit{Time: now, Scope: l.scope, Limit: limit, Max: max, Spent: spent, Amount: amount, Host: host}
+	}
+	if l.perRequest > 0 && amount > l.perRequest {
+		return hit("per_request", l.perRequest, 0)
+	}
+	if l.perHour > 0 {
+		if spent := p.spent(l.scope, now.Add(-time.Hour)); spent+amount > l.perHour {
+			return hit("per_hour", l.perHour, spent)
+		}
+	}
+	if l.perDay > 0 {
+		if spent := p.spent(l.scope, now.Add(-24*time.Hour)); spent+amount > l.perDay {
+			return hit("per_day", l.perDay, spent)
+		}
+	}
+	return nil
+}
+
+// spent sums payments since a time, for all hosts or one domain pattern.
+// Callers hold p.mu.
+func (p *spendPolicy) spent(scope string, since time.Time) usdc.MicroUSDC {
+	var total usdc.MicroUSDC
+	for _, e := range p.events {
+		if e.at.After(since) && (scope == "global" || matchHostPattern(scope, e.host)) {
+			total += e.amount
+		}
+	}
+	return total
+}
+
+// prune drops events older than the longest window. Callers hold p.mu.
+func (p *spendPolicy) prune(now time.Time) {
+	cutoff := now.Add(-24 * time.Hour)
+	i := 0
+	for i < len(p.events) && !p.events[i].at.After(cutoff) {
+		i++
+	}
+	p.events = p.events[i:]
+}
+
+// release uncounts a reservation whose payment was not spent
+func (p *spendPolicy) release(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, e := range p.events {
+		if e.id == id {
+			p.events = append(p.events[:i], p.events[i+1:]...)
+			return
+		}
+	}
+}
+
+// totals returns global spend over the last hour and day
+func (p *spendPolicy) totals() (hour, day usdc.MicroUSDC) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune(now)
+	return p.spent("global", now.Add(-time.Hour)), p.spent("global", now.Add(-24*time.Hour))
+}
+
+// spendNotifier reports limit hits in the log and, if configured, to a
+// webhook. Each limit is reported at most once per notifyInterval.
+type spendNotifier struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newSpendNotifier(url string, logger *slog.Logger) *spendNotifier {
+	return &spendNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+		last:   make(map[string]time.Time),
+	}
+}
+
+// send reports a limit hit unless the same limit was reported recently
+func (n *spendNotifier) send(hit *SpendLimitHit) {
+	key := hit.Scope + "/" + hit.Limit
+	n.mu.Lock()
+	if last, ok := n.last[key]; ok && hit.Time.Sub(last) < notifyInterval {
+		n.mu.Unlock()
+		return
+	}
+	n.last[key] = hit.Time
+	n.mu.Unlock()
+
+	n.logger.Warn("spend limit reached", "scope", hit.Scope, "limit", hit.Limit,
+		"max", hit.Max.String(), "spent", hit.Spent.String(), "host", hit.Host)
+	if n.url == "" {
+		return
+	}
+
+	body, err := json.Marshal(struct {
+		Event string `json:"event"`
+		*SpendLimitHit
+	}{"spend_limit_reached", hit})
+	if err != nil {
+		return
+	}
+	go func() {
+		resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			n.logger.Warn("failed to send spend limit notification", "error", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
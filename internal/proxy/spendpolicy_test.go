@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"stronghold/internal/usdc"
+)
+
+func newTestSpendPolicy(cfg SpendPolicyConfig) *spendPolicy {
+	return newSpendPolicy(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSpendPolicy_Defaults(t *testing.T) {
+	p := newTestSpendPolicy(SpendPolicyConfig{})
+	if p.global.perRequest != usdc.FromFloat(0.01) || p.global.perDay != usdc.FromFloat(1) || p.global.perHour != 0 {
+		t.Errorf("unexpected default limits %+v", p.global)
+	}
+}
+
+func TestSpendPolicy_HourlyLimit(t *testing.T) {
+	p := newTestSpendPolicy(SpendPolicyConfig{MaxPerRequest: 1, MaxPerHour: 0.10})
+
+	for i := 0; i < 2; i++ {
+		if _, hit := p.reserve("api.example.com", usdc.FromFloat(0.05)); hit != nil {
+			t.Fatalf("payment %d refused: %v", i, hit)
+		}
+	}
+	_, hit := p.reserve("other.example.net", usdc.FromFloat(0.01))
+	if hit == nil || hit.Scope != "global" || hit.Limit != "per_hour" || hit.Spent != usdc.FromFloat(0.10) {
+		t.Fatalf("expected global hourly limit hit, got %+v", hit)
+	}
+}
+
+func TestSpendPolicy_DomainLimits(t *testing.T) {
+	p := newTestSpendPolicy(SpendPolicyConfig{
+		MaxPerRequest: 0.05,
+		MaxPerDay:     1,
+		Domains: []DomainSpendLimit{
+			{Host: "*.cheap.example", MaxPerRequest: 0.001, MaxPerDay: 0.002},
+		},
+	})
+
+	if _, hit := p.reserve("api.cheap.example", usdc.FromFloat(0.002)); hit == nil || hit.Scope != "*.cheap.example" || hit.Limit != "per_request" {
+		t.Errorf("expected domain per-request limit hit, got %+v", hit)
+	}
+	for i := 0; i < 2; i++ {
+		if _, hit := p.reserve("api.cheap.example", usdc.FromFloat(0.001)); hit != nil {
+			t.Fatalf("payment %d refused: %v", i, hit)
+		}
+	}
+	if _, hit := p.reserve("API.cheap.example", usdc.FromFloat(0.001)); hit == nil || hit.Limit != "per_day" {
+		t.Errorf("expected domain daily limit hit, got %+v", hit)
+	}
+
+	// Other hosts only see the global limits
+	if _, hit := p.reserve("api.example.com", usdc.FromFloat(0.04)); hit != nil {
+		t.Errorf("unexpected limit hit for unlisted host: %v", hit)
+	}
+}
+
+func TestSpendPolicy_Release(t *testing.T) {
+	p := newTestSpendPolicy(SpendPolicyConfig{MaxPerRequest: 1, MaxPerDay: 1})
+
+	id, hit := p.reserve("api.example.com", usdc.FromFloat(0.8))
+	if hit != nil {
+		t.Fatalf("payment refused: %v", hit)
+	}
+	if _, hit := p.reserve("api.example.com", usdc.FromFloat(0.5)); hit == nil {
+		t.Fatal("expected daily limit hit")
+	}
+	p.release(id)
+	if _, hit := p.reserve("api.example.com", usdc.FromFloat(0.5)); hit != nil {
+		t.Errorf("expected released amount to be uncounted, got %v", hit)
+	}
+}
+
+func TestSpendPolicy_RestoreSkipsOldAndRejected(t *testing.T) {
+	p := newTestSpendPolicy(SpendPolicyConfig{})
+	now := time.Now()
+	p.restore([]SpendEntry{
+		{Time: now.Add(-30 * time.Minute), Host: "a.example", Amount: 3000, Accepted: true},
+		{Time: now.Add(-2 * time.Hour), Host: "a.example", Amount: 2000, Accepted: true},
+		{Time: now.Add(-10 * time.Minute), Host: "a.example", Amount: 9000, Accepted: false},
+		{Time: now.Add(-30 * time.Hour), Host: "a.example", Amount: 7000, Accepted: true},
+	})
+
+	hour, day := p.totals()
+	if hour != 3000 || day != 5000 {
+		t.Errorf("expected 3000/5000 spent, got %s/%s", hour, day)
+	}
+}
+
+func TestSpendNotifier_WebhookOncePerLimit(t *testing.T) {
+	events := make(chan map[string]interface{}, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		events <- body
+	}))
+	defer hook.Close()
+
+	p := newTestSpendPolicy(SpendPolicyConfig{MaxPerRequest: 0.01, NotifyURL: hook.URL})
+	for i := 0; i < 3; i++ {
+		if _, hit := p.reserve("api.example.com", usdc.FromFloat(0.02)); hit == nil {
+			t.Fatal("expected per-request limit hit")
+		}
+	}
+
+	select {
+	case body := <-events:
+		if body["event"] != "spend_limit_reached" || body["limit"] != "per_request" || body["host"] != "api.example.com" {
+			t.Errorf("unexpected notification %v", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a limit notification")
+	}
+	select {
+	case body := <-events:
+		t.Errorf("expected repeat hits to be suppressed, got %v", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
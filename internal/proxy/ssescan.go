@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultStreamWindow   = 4 * 1024
+	defaultStreamOverlap  = 512
+	defaultStreamMaxDelay = 500 * time.Millisecond
+)
+
+// streamTrailers are declared on streamed responses and filled in once the
+// stream ends, since the verdict isn't known when the headers are sent
+var streamTrailers = []string{"X-Stronghold-Decision", "X-Stronghold-Reason", costHeader}
+
+// StreamScanConfig controls incremental scanning of text/event-stream
+// responses. Events are held back until the window holding them is scanned,
+// so a BLOCK cuts the stream before the flagged event reaches the agent.
+type StreamScanConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Window   int           `yaml:"window,omitempty"`    // Event data bytes per scan (default 4KB)
+	Overlap  int           `yaml:"overlap,omitempty"`   // Data carried into the next window (default 512 bytes)
+	MaxDelay time.Duration `yaml:"max_delay,omitempty"` // Longest an event is held before a short window is scanned (default 500ms)
+}
+
+// window returns the event data scanned at a time
+func (c StreamScanConfig) window() int {
+	if c.Window <= 0 {
+		return defaultStreamWindow
+	}
+	return c.Window
+}
+
+// overlap returns the data repeated from the previous window, kept below half
+// the window so an attack split across events is seen whole
+func (c StreamScanConfig) overlap() int {
+	overlap := c.Overlap
+	if overlap <= 0 {
+		overlap = defaultStreamOverlap
+	}
+	if overlap > c.window()/2 {
+		overlap = c.window() / 2
+	}
+	return overlap
+}
+
+// maxDelay returns how long an event may wait for its window to fill
+func (c StreamScanConfig) maxDelay() time.Duration {
+	if c.MaxDelay <= 0 {
+		return defaultStreamMaxDelay
+	}
+	return c.MaxDelay
+}
+
+// IsEventStreamContentType reports whether a response is server-sent events
+func IsEventStreamContentType(contentType string) bool {
+	return contains(contentType, "text/event-stream")
+}
+
+// sseEvent is one server-sent event: its bytes as received and the text of
+// its data lines
+type sseEvent struct {
+	raw  []byte
+	data []byte
+}
+
+// readSSEEvent reads the next event, up to and including the blank line that
+// ends it. At the end of the stream the partial event read so far is
+// returned with the error.
+func readSSEEvent(r *bufio.Reader) (sseEvent, error) {
+	var ev sseEvent
+	for {
+		line, err := r.ReadBytes('\n')
+		ev.raw = append(ev.raw, line...)
+		if err != nil {
+			return ev, err
+		}
+		field := bytes.TrimRight(line, "\r\n")
+		if len(field) == 0 {
+			return ev, nil
+		}
+		if value, ok := bytes.CutPrefix(field, []byte("data:")); ok {
+			value = bytes.TrimPrefix(value, []byte(" "))
+			ev.data = append(append(ev.data, value...), '\n')
+		}
+	}
+}
+
+// sseScan forwards a stream's events once the window holding their data is
+// scanned
+type sseScan struct {
+	config  StreamScanConfig
+	scan    func(window []byte) *ScanResult
+	cut     func(result *ScanResult) bool // Reports whether the stream is stopped
+	out     io.Writer
+	pending [][]byte // Events held until their data is scanned
+	data    []byte   // Data of the pending events
+	tail    []byte   // End of the last window, scanned again with the next
+	results []*ScanResult
+	err     error // Set once the client can't be written to
+}
+
+// run copies events from body to the client until the stream ends or a
+// window is cut, which it reports with the window's result
+func (s *sseScan) run(body io.Reader) *ScanResult {
+	events := make(chan sseEvent)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(events)
+		r := bufio.NewReader(body)
+		for {
+			ev, err := readSSEEvent(r)
+			if len(ev.raw) > 0 {
+				select {
+				case events <- ev:
+				case <-stop:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return s.release()
+			}
+			s.pending = append(s.pending, ev.raw)
+			s.data = append(s.data, ev.data...)
+			if len(s.data) < s.config.window() {
+				if timeout == nil {
+					timer = time.NewTimer(s.config.maxDelay())
+					timeout = timer.C
+				}
+				continue
+			}
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if blocked := s.release(); blocked != nil || s.err != nil {
+			return blocked
+		}
+	}
+}
+
+// release scans the pending data and forwards the pending events, unless the
+// scan cuts the stream. Events without data are forwarded unscanned.
+func (s *sseScan) release() *ScanResult {
+	if len(s.data) > 0 {
+		window := append(s.tail, s.data...)
+		if result := s.scan(window); result != nil {
+			s.results = append(s.results, result)
+			if s.cut(result) {
+				return result
+			}
+		}
+
+		start := max(len(window)-s.config.overlap(), 0)
+		for start < len(window) && !utf8.RuneStart(window[start]) {
+			start++
+		}
+		s.tail = append([]byte(nil), window[start:]...)
+		s.data = s.data[:0]
+	}
+
+	for _, raw := range s.pending {
+		if _, s.err = s.out.Write(raw); s.err != nil {
+			return nil
+		}
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// streamResponse forwards a server-sent event stream while scanning it in
+// windows. A BLOCK ends the stream with a stronghold-block event; the
+// verdict for the whole stream is sent in the X-Stronghold-Decision trailer.
+func (m *MITMHandler) streamResponse(conn net.Conn, resp *http.Response, req *http.Request, requestID string, scanning *ScanningConfig) error {
+	cut := func(result *ScanResult) bool {
+		action, _ := applyShadowMode(scanning, getAction(result.Decision, scanning.Content))
+		return action == "block"
+	}
+
+	// Injected response headers are checked before anything is sent
+	var results []*ScanResult
+	if headerResult := withHeaderFindings(nil, resp.Header); headerResult != nil {
+		if cut(headerResult) {
+			resp.Body.Close()
+			m.recordAudit(requestID, req, "response", headerResult, "block", false)
+			m.sendBlockResponse(conn, headerResult, req, requestID)
+			return nil
+		}
+		results = append(results, headerResult)
+	}
+
+	resp.Header.Set("X-Stronghold-Proxy", "mitm")
+	resp.Header.Set("X-Stronghold-Request-ID", requestID)
+	resp.Header.Set("X-Stronghold-Scan-Type", "streaming")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.TransferEncoding = []string{"chunked"}
+	resp.Trailer = make(http.Header)
+	for _, name := range streamTrailers {
+		resp.Trailer[name] = nil
+	}
+
+	upstream := resp.Body
+	pr, pw := io.Pipe()
+	resp.Body = pr
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer upstream.Close()
+
+		stream := &sseScan{
+			config:  scanning.Streaming,
+			scan:    func(window []byte) *ScanResult { return m.scanContent(window, req.URL.String(), "text/plain") },
+			cut:     cut,
+			out:     pw,
+			results: results,
+		}
+		blocked := stream.run(upstream)
+		result := mergeResults(stream.results, len(stream.results))
+
+		action := getAction(result.Decision, scanning.Content)
+		if result.Decision != DecisionAllow {
+			m.recordAudit(requestID, req, "response", result, action, m.shadowed(scanning, action, result, req))
+		}
+		if blocked != nil {
+			m.logger.Warn("stream blocked", "url", req.URL.String(), "reason", blocked.Reason)
+			event, _ := json.Marshal(map[string]string{
+				"decision":   string(blocked.Decision),
+				"reason":     blocked.Reason,
+				"request_id": requestID,
+			})
+			fmt.Fprintf(pw, "event: stronghold-block\ndata: %s\n\n", event)
+		}
+
+		resp.Trailer.Set("X-Stronghold-Decision", string(result.Decision))
+		if result.Reason != "" {
+			resp.Trailer.Set("X-Stronghold-Reason", result.Reason)
+		}
+		setCostHeader(resp.Trailer, result)
+		pw.Close()
+	}()
+
+	err := resp.Write(conn)
+	pr.Close()
+	<-done
+	if err != nil {
+		return fmt.Errorf("failed to forward response: %w", err)
+	}
+	return nil
+}
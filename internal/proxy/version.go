@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"stronghold/internal/proxyversion"
+)
+
+// Version is the proxy version, set at build time via ldflags
+var Version = "dev"
+
+// Update returns the latest update signal the API sent for this proxy's
+// version, or nil if none has been received
+func (c *ScannerClient) Update() *proxyversion.Update {
+	return c.update.Load()
+}
+
+// noteUpdateStatus records the update status from an API response header.
+// Heartbeats carry the full signal; a header only replaces it when the
+// status changed.
+func (c *ScannerClient) noteUpdateStatus(status string) {
+	if status == "" {
+		return
+	}
+	if current := c.update.Load(); current != nil && current.Status == status {
+		return
+	}
+	c.update.Store(&proxyversion.Update{Status: status, Version: Version})
+}
+
+// upgradeRequired records the signal from a 426 response and returns the
+// error for the refused request
+func (c *ScannerClient) upgradeRequired(resp *http.Response) error {
+	var body struct {
+		Update *proxyversion.Update `json:"update"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err != nil || body.Update == nil {
+		body.Update = &proxyversion.Update{Status: proxyversion.StatusRequired, Version: Version, Enforced: true}
+	}
+	c.update.Store(body.Update)
+
+	reason := body.Update.Reason
+	if reason == "" {
+		reason = "version " + Version + " is no longer supported"
+	}
+	return fmt.Errorf("the API refused this proxy: %s. Upgrade Stronghold to resume scanning", reason)
+}
@@ -0,0 +1,127 @@
+// Package proxyversion decides whether a proxy release is still supported.
+// The proxy reports its version on scan and heartbeat requests; the API
+// compares it with the operator's policy and answers with an update signal
+// that `stronghold status` surfaces. Versions known to be vulnerable can
+// optionally be refused outright.
+package proxyversion
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// HeaderVersion carries the proxy version on requests to the API
+const HeaderVersion = "X-Stronghold-Proxy-Version"
+
+// HeaderUpdate carries the update status on API responses when the proxy
+// should be upgraded
+const HeaderUpdate = "X-Stronghold-Update"
+
+// Update statuses
+const (
+	StatusCurrent     = "current"
+	StatusRecommended = "upgrade_recommended"
+	StatusRequired    = "upgrade_required"
+)
+
+// Policy is the operator's supported-version policy. Empty fields are not
+// checked.
+type Policy struct {
+	Minimum     string   // Older versions must upgrade
+	Recommended string   // Older versions should upgrade
+	Vulnerable  []string // Versions with known vulnerabilities; always must upgrade
+	Enforce     bool     // Refuse requests from vulnerable versions
+}
+
+// Update is the signal returned to a proxy about its version
+type Update struct {
+	Status      string `json:"status"`
+	Version     string `json:"version"`
+	Minimum     string `json:"minimum_version,omitempty"`
+	Recommended string `json:"recommended_version,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Enforced    bool   `json:"enforced,omitempty"` // Requests from this version are refused
+}
+
+// Check returns the update signal for a proxy version. Versions that don't
+// parse, such as development builds, are treated as current.
+func (p Policy) Check(version string) Update {
+	u := Update{
+		Status:      StatusCurrent,
+		Version:     version,
+		Minimum:     p.Minimum,
+		Recommended: p.Recommended,
+	}
+	if _, ok := parse(version); !ok {
+		return u
+	}
+
+	switch {
+	case slices.ContainsFunc(p.Vulnerable, func(v string) bool { return equal(v, version) }):
+		u.Status = StatusRequired
+		u.Reason = fmt.Sprintf("version %s has a known vulnerability", version)
+		u.Enforced = p.Enforce
+	case p.Minimum != "" && less(version, p.Minimum):
+		u.Status = StatusRequired
+		u.Reason = fmt.Sprintf("version %s is older than the minimum supported version %s", version, p.Minimum)
+	case p.Recommended != "" && less(version, p.Recommended):
+		u.Status = StatusRecommended
+		u.Reason = fmt.Sprintf("version %s is older than the recommended version %s", version, p.Recommended)
+	}
+	return u
+}
+
+// Valid reports whether a version can be compared
+func Valid(version string) bool {
+	_, ok := parse(version)
+	return ok
+}
+
+// parse reads MAJOR.MINOR.PATCH with an optional "v" prefix. Pre-release and
+// build suffixes are ignored.
+func parse(version string) ([3]int, bool) {
+	var parts [3]int
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+func compare(a, b string) int {
+	pa, _ := parse(a)
+	pb, _ := parse(b)
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func less(a, b string) bool {
+	return compare(a, b) < 0
+}
+
+func equal(a, b string) bool {
+	if !Valid(a) || !Valid(b) {
+		return false
+	}
+	return compare(a, b) == 0
+}
@@ -0,0 +1,51 @@
+package proxyversion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{
+		Minimum:     "1.2.0",
+		Recommended: "1.4.0",
+		Vulnerable:  []string{"1.4.1"},
+		Enforce:     true,
+	}
+
+	tests := []struct {
+		version  string
+		status   string
+		enforced bool
+	}{
+		{"1.4.0", StatusCurrent, false},
+		{"v1.5.2", StatusCurrent, false},
+		{"1.3.9", StatusRecommended, false},
+		{"1.1.0", StatusRequired, false},
+		{"v1.4.1", StatusRequired, true},
+		{"1.4.1-rc.1", StatusRequired, true},
+		{"dev", StatusCurrent, false},
+		{"", StatusCurrent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			u := p.Check(tt.version)
+			assert.Equal(t, tt.status, u.Status)
+			assert.Equal(t, tt.enforced, u.Enforced)
+			if tt.status != StatusCurrent {
+				assert.NotEmpty(t, u.Reason)
+			}
+		})
+	}
+
+	assert.Equal(t, StatusCurrent, Policy{}.Check("0.0.1").Status, "an empty policy accepts every version")
+	assert.False(t, Policy{Vulnerable: []string{"1.4.1"}}.Check("1.4.1").Enforced, "vulnerable versions are only refused when enforcing")
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("1.2.3"))
+	assert.True(t, Valid("v2"))
+	assert.False(t, Valid("dev"))
+	assert.False(t, Valid("1.2.3.4"))
+}
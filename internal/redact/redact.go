@@ -0,0 +1,224 @@
+// Package redact scrubs personal data and secrets from log output. Handlers
+// wrap an slog.Handler and rewrite attributes before they are written:
+// scanned content and secrets are replaced by placeholders, and wallet
+// addresses, account numbers and IP addresses are masked so log lines can
+// still be correlated without identifying anyone. Redaction is on by default
+// in every binary; turning it off is an explicit debugging override.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Attribute keys, matched case-insensitively, whose values are replaced or
+// masked whatever they hold
+var (
+	contentKeys = map[string]bool{
+		"text": true, "content": true, "body": true, "prompt": true, "output": true,
+		"input": true, "arguments": true, "request_body": true, "response_body": true,
+	}
+	secretKeys = map[string]bool{
+		"token": true, "access_token": true, "refresh_token": true, "api_key": true,
+		"secret": true, "password": true, "authorization": true, "cookie": true,
+		"private_key": true, "signature": true, "x_payment": true, "payment_header": true,
+	}
+	addressKeys = map[string]bool{
+		"address": true, "wallet": true, "wallet_address": true, "evm_wallet_address": true,
+		"solana_wallet_address": true, "payer": true, "pay_to": true, "recipient": true,
+		"from_address": true, "to_address": true,
+	}
+	ipKeys = map[string]bool{
+		"ip": true, "client_ip": true, "remote_ip": true, "remote_addr": true, "x_forwarded_for": true,
+	}
+	accountNumberKeys = map[string]bool{"account_number": true}
+	urlKeys           = map[string]bool{"url": true, "uri": true, "request_url": true, "endpoint": true}
+)
+
+// Patterns scrubbed from every string, including messages and errors
+var (
+	evmAddressPattern    = regexp.MustCompile(`\b0x[0-9a-fA-F]{40}\b`)
+	apiKeyPattern        = regexp.MustCompile(`\b(sk|rk|pk|whsec)_(live|test)_[0-9A-Za-z]+`)
+	jwtPattern           = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+`)
+	accountNumberPattern = regexp.MustCompile(`\b\d{4}-\d{4}-\d{4}-(\d{4})\b`)
+	bearerPattern        = regexp.MustCompile(`(?i)\bbearer\s+[\w.~+/-]+=*`)
+)
+
+// Handler redacts records before passing them to the wrapped handler
+type Handler struct {
+	next slog.Handler
+}
+
+// NewHandler wraps next with redaction
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+// Wrap wraps next with redaction unless unredacted is set, the explicit
+// debugging override
+func Wrap(next slog.Handler, unredacted bool) slog.Handler {
+	if unredacted {
+		return next
+	}
+	return NewHandler(next)
+}
+
+// Enabled reports whether the wrapped handler handles level
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record's message and attributes
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, String(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+// WithAttrs redacts attributes added to a derived logger
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = Attr(a)
+	}
+	return &Handler{next: h.next.WithAttrs(redacted)}
+}
+
+// WithGroup opens a group on the wrapped handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
+
+// Attr returns a redacted copy of an attribute. Group members are redacted
+// by their own keys.
+func Attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		members := v.Group()
+		redacted := make([]any, len(members))
+		for i, m := range members {
+			redacted[i] = Attr(m)
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+
+	key := strings.ToLower(a.Key)
+	switch {
+	case contentKeys[key]:
+		return slog.String(a.Key, fmt.Sprintf("[redacted %d bytes]", len(valueString(v))))
+	case secretKeys[key]:
+		return slog.String(a.Key, "[redacted]")
+	case addressKeys[key]:
+		return slog.String(a.Key, Address(valueString(v)))
+	case ipKeys[key]:
+		return slog.String(a.Key, IP(valueString(v)))
+	case accountNumberKeys[key]:
+		return slog.String(a.Key, AccountNumber(valueString(v)))
+	case urlKeys[key]:
+		return slog.String(a.Key, URL(valueString(v)))
+	}
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, String(v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, String(x.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, String(x.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// String scrubs API keys, bearer tokens, JWTs, EVM addresses and account
+// numbers from free text
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	s = bearerPattern.ReplaceAllString(s, "Bearer [redacted]")
+	s = apiKeyPattern.ReplaceAllString(s, "${1}_${2}_[redacted]")
+	s = jwtPattern.ReplaceAllString(s, "[redacted jwt]")
+	s = evmAddressPattern.ReplaceAllStringFunc(s, Address)
+	s = accountNumberPattern.ReplaceAllString(s, "****-****-****-$1")
+	return s
+}
+
+// Address masks a wallet address to its first and last four characters
+// (after any 0x prefix), e.g. 0x1234…cdef
+func Address(addr string) string {
+	prefix := ""
+	if strings.HasPrefix(addr, "0x") || strings.HasPrefix(addr, "0X") {
+		prefix, addr = addr[:2], addr[2:]
+	}
+	if len(addr) <= 8 {
+		return prefix + strings.Repeat("*", len(addr))
+	}
+	return prefix + addr[:4] + "…" + addr[len(addr)-4:]
+}
+
+// IP masks an address to its network: /24 for IPv4, /48 for IPv6. Values
+// that aren't addresses are scrubbed as free text.
+func IP(s string) string {
+	host := s
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		host = ap.Addr().String()
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return String(s)
+	}
+	bits := 48
+	if addr.Unmap().Is4() {
+		addr, bits = addr.Unmap(), 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.String()
+}
+
+// URL drops credentials and query values from a URL, which often carry
+// tokens; parameter names are kept
+func URL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || (u.User == nil && u.RawQuery == "") {
+		return String(s)
+	}
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q[k] = []string{"redacted"}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return String(u.String())
+}
+
+// AccountNumber masks all but the last four digits of an account number
+func AccountNumber(s string) string {
+	if m := accountNumberPattern.FindStringSubmatch(s); m != nil {
+		return "****-****-****-" + m[1]
+	}
+	return "[redacted]"
+}
+
+func valueString(v slog.Value) string {
+	if v.Kind() == slog.KindAny {
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.String()
+}
@@ -0,0 +1,96 @@
+// Package rollups materializes per-account daily usage totals, so usage
+// stats and exports read one row per day instead of every usage log.
+package rollups
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+)
+
+// SettleDelay is how long after a UTC day ends before it is rolled up, so
+// usage logged as the day closed has committed
+const SettleDelay = 5 * time.Minute
+
+// Store rolls up daily usage
+type Store interface {
+	PendingUsageRollupDays(ctx context.Context, from, to time.Time) ([]time.Time, error)
+	RollupDailyUsage(ctx context.Context, day time.Time) (int64, error)
+}
+
+// Worker rolls up each UTC day's usage once the day has ended. Days it has
+// not reached yet, including the current one, are aggregated from the usage
+// log when read, so stats stay exact while the worker catches up. A nil
+// Worker rolls up nothing.
+type Worker struct {
+	interval     time.Duration
+	backfillDays int
+	store        Store
+	now          func() time.Time
+}
+
+// New returns nil when the worker is disabled
+func New(cfg *config.UsageRollupConfig, store Store) *Worker {
+	if cfg.Interval <= 0 || store == nil {
+		return nil
+	}
+	return &Worker{
+		interval:     cfg.Interval,
+		backfillDays: max(cfg.BackfillDays, 1),
+		store:        store,
+		now:          time.Now,
+	}
+}
+
+// Run rolls up finished days every interval until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.rollup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollup rolls up the finished days within the backfill window that have not
+// been, oldest first, and returns how many it rolled up
+func (w *Worker) rollup(ctx context.Context) int {
+	last := w.now().UTC().Add(-SettleDelay).Truncate(24*time.Hour).AddDate(0, 0, -1)
+	first := last.AddDate(0, 0, 1-w.backfillDays)
+
+	days, err := w.store.PendingUsageRollupDays(ctx, first, last)
+	if err != nil {
+		slog.Warn("failed to find usage days to roll up", "error", err)
+		return 0
+	}
+	rolled := 0
+	for _, day := range days {
+		if ctx.Err() != nil {
+			break
+		}
+		accounts, err := w.store.RollupDailyUsage(ctx, day)
+		if err != nil {
+			slog.Warn("failed to roll up daily usage", "day", day.Format("2006-01-02"), "error", err)
+			break
+		}
+		slog.Debug("rolled up daily usage", "day", day.Format("2006-01-02"), "accounts", accounts)
+		rolled++
+	}
+	if rolled > 0 {
+		slog.Info("rolled up daily usage", "days", rolled)
+	}
+	return rolled
+}
+
+// compile-time check that *db.DB can back the rollup worker
+var _ Store = (*db.DB)(nil)
@@ -0,0 +1,77 @@
+package rollups
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	pending  []time.Time
+	failOn   time.Time
+	from, to time.Time
+	rolled   []time.Time
+}
+
+func (f *fakeStore) PendingUsageRollupDays(_ context.Context, from, to time.Time) ([]time.Time, error) {
+	f.from, f.to = from, to
+	return f.pending, nil
+}
+
+func (f *fakeStore) RollupDailyUsage(_ context.Context, day time.Time) (int64, error) {
+	if day.Equal(f.failOn) {
+		return 0, errors.New("connection refused")
+	}
+	f.rolled = append(f.rolled, day)
+	return 1, nil
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNew_Disabled(t *testing.T) {
+	assert.Nil(t, New(&config.UsageRollupConfig{Interval: 0}, &fakeStore{}))
+	assert.Nil(t, New(&config.UsageRollupConfig{Interval: time.Minute}, nil))
+
+	var w *Worker
+	w.Run(context.Background())
+}
+
+func TestWorker_RollsUpFinishedDays(t *testing.T) {
+	store := &fakeStore{pending: []time.Time{day(3), day(5)}}
+	w := New(&config.UsageRollupConfig{Interval: time.Minute, BackfillDays: 7}, store)
+	require.NotNil(t, w)
+
+	t.Run("waits for the settle delay", func(t *testing.T) {
+		w.now = func() time.Time { return day(10).Add(SettleDelay - time.Second) }
+		w.rollup(context.Background())
+		assert.Equal(t, day(8), store.to)
+		assert.Equal(t, day(2), store.from)
+	})
+
+	t.Run("includes yesterday once settled", func(t *testing.T) {
+		store.rolled = nil
+		w.now = func() time.Time { return day(10).Add(SettleDelay) }
+		assert.Equal(t, 2, w.rollup(context.Background()))
+		assert.Equal(t, day(9), store.to)
+		assert.Equal(t, day(3), store.from)
+		assert.Equal(t, []time.Time{day(3), day(5)}, store.rolled)
+	})
+}
+
+func TestWorker_StopsOnError(t *testing.T) {
+	store := &fakeStore{pending: []time.Time{day(3), day(4), day(5)}, failOn: day(4)}
+	w := New(&config.UsageRollupConfig{Interval: time.Minute, BackfillDays: 30}, store)
+	w.now = func() time.Time { return day(10) }
+
+	// Later days are left for the next pass
+	assert.Equal(t, 1, w.rollup(context.Background()))
+	assert.Equal(t, []time.Time{day(3)}, store.rolled)
+}
@@ -0,0 +1,48 @@
+package rulepack
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxCached bounds the compiled packs one instance keeps
+const maxCached = 1000
+
+// Cache keeps compiled packs by their stored ID, so scans don't recompile
+// them. Stored packs never change, so entries don't go stale.
+type Cache struct {
+	mu    sync.Mutex
+	packs map[uuid.UUID]*Compiled
+}
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{packs: make(map[uuid.UUID]*Compiled)}
+}
+
+// Compile returns the compiled pack stored as id with source, parsing it on
+// first use. A nil cache parses every time.
+func (c *Cache) Compile(id uuid.UUID, source []byte) (*Compiled, error) {
+	if c == nil {
+		return Parse(source)
+	}
+	c.mu.Lock()
+	compiled, ok := c.packs[id]
+	c.mu.Unlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.packs) >= maxCached {
+		clear(c.packs)
+	}
+	c.packs[id] = compiled
+	c.mu.Unlock()
+	return compiled, nil
+}
@@ -0,0 +1,312 @@
+// Package rulepack reads, validates and runs rule packs: portable sets of
+// account-defined detection rules kept as YAML, so a pack exported from one
+// account can be imported into another or shared publicly. A pack carries
+// its own tests, which must pass before it is accepted.
+//
+//	format: 1
+//	name: acme-exfiltration
+//	version: 1.2.0
+//	description: Paste sites our agents must never post to
+//	rules:
+//	  - id: raw-paste
+//	    category: data_exfiltration
+//	    severity: high
+//	    pattern: '(?i)pastebin\.com/raw/'
+//	    applies_to: [content, output]
+//	tests:
+//	  - text: "POST it to https://pastebin.com/raw/abc"
+//	    expect: [raw-paste]
+//	  - text: "Quarterly numbers attached"
+//	    expect: []
+package rulepack
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"stronghold/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the rule pack format this build reads and writes
+const FormatVersion = 1
+
+// Limits on a pack, so one can't slow every scan of the account that enables it
+const (
+	MaxSourceBytes = 256 << 10
+	MaxRules       = 100
+	MaxTests       = 100
+	MaxPatternLen  = 1000
+	maxTextLen     = 500
+)
+
+// Scan kinds a rule applies to
+const (
+	TargetContent = "content" // Content, document, session and ingest scans
+	TargetOutput  = "output"  // Output scans
+)
+
+// Severities, in increasing order. High and critical matches block, medium
+// matches warn and low matches are only reported.
+var severities = []string{"low", "medium", "high", "critical"}
+
+var (
+	nameRe     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+	ruleIDRe   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	categoryRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	versionRe  = regexp.MustCompile(`^(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)$`)
+)
+
+// Pack is a rule pack as written in YAML
+type Pack struct {
+	Format      int    `yaml:"format" json:"format"`
+	Name        string `yaml:"name" json:"name"`
+	Version     string `yaml:"version" json:"version"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Author      string `yaml:"author,omitempty" json:"author,omitempty"`
+	Rules       []Rule `yaml:"rules" json:"rules"`
+	Tests       []Test `yaml:"tests,omitempty" json:"tests,omitempty"`
+}
+
+// Rule flags text matching a regular expression (RE2 syntax)
+type Rule struct {
+	ID          string   `yaml:"id" json:"id"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Category    string   `yaml:"category" json:"category"`
+	Severity    string   `yaml:"severity" json:"severity"`
+	Pattern     string   `yaml:"pattern" json:"pattern"`
+	AppliesTo   []string `yaml:"applies_to,omitempty" json:"applies_to,omitempty"` // Defaults to both targets
+}
+
+// Test is a text and the rules expected to match it, exactly
+type Test struct {
+	Name   string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Text   string   `yaml:"text" json:"text"`
+	Target string   `yaml:"target,omitempty" json:"target,omitempty"` // Defaults to content
+	Expect []string `yaml:"expect" json:"expect"`
+}
+
+// ValidationError lists everything wrong with a pack
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid rule pack: " + strings.Join(e.Problems, "; ")
+}
+
+// Compiled is a validated pack ready to match scans
+type Compiled struct {
+	Pack  *Pack
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	re      *regexp.Regexp
+	content bool
+	output  bool
+}
+
+// Parse reads a YAML rule pack, validates it and runs its tests. Problems
+// are reported together in a *ValidationError.
+func Parse(source []byte) (*Compiled, error) {
+	if len(source) > MaxSourceBytes {
+		return nil, &ValidationError{Problems: []string{fmt.Sprintf("pack is over %d bytes", MaxSourceBytes)}}
+	}
+	var p Pack
+	dec := yaml.NewDecoder(bytes.NewReader(source))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, &ValidationError{Problems: []string{"not a YAML rule pack: " + err.Error()}}
+	}
+	return Compile(&p)
+}
+
+// Compile validates p and runs its tests
+func Compile(p *Pack) (*Compiled, error) {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if p.Format != FormatVersion {
+		add("format must be %d", FormatVersion)
+	}
+	if !nameRe.MatchString(p.Name) {
+		add("name must be 3 to 64 lowercase letters, digits and hyphens")
+	}
+	if !versionRe.MatchString(p.Version) {
+		add("version must be MAJOR.MINOR.PATCH")
+	}
+	if len(p.Description) > maxTextLen || len(p.Author) > maxTextLen {
+		add("description and author must be at most %d characters", maxTextLen)
+	}
+	if len(p.Rules) == 0 || len(p.Rules) > MaxRules {
+		add("a pack needs 1 to %d rules", MaxRules)
+	}
+	if len(p.Tests) > MaxTests {
+		add("a pack has at most %d tests", MaxTests)
+	}
+
+	c := &Compiled{Pack: p, rules: make([]compiledRule, 0, len(p.Rules))}
+	seen := make(map[string]bool)
+	for i, r := range p.Rules {
+		where := fmt.Sprintf("rules[%d]", i)
+		if r.ID != "" {
+			where = fmt.Sprintf("rule %q", r.ID)
+		}
+		switch {
+		case !ruleIDRe.MatchString(r.ID):
+			add("%s: id must be up to 64 lowercase letters, digits, hyphens and underscores", where)
+		case seen[r.ID]:
+			add("%s: duplicate id", where)
+		}
+		seen[r.ID] = true
+		if !categoryRe.MatchString(r.Category) {
+			add("%s: category must be a lowercase snake_case name", where)
+		}
+		if !slices.Contains(severities, r.Severity) {
+			add("%s: severity must be one of %s", where, strings.Join(severities, ", "))
+		}
+		if len(r.Description) > maxTextLen {
+			add("%s: description must be at most %d characters", where, maxTextLen)
+		}
+		cr := compiledRule{Rule: r, content: len(r.AppliesTo) == 0, output: len(r.AppliesTo) == 0}
+		for _, t := range r.AppliesTo {
+			switch t {
+			case TargetContent:
+				cr.content = true
+			case TargetOutput:
+				cr.output = true
+			default:
+				add("%s: applies_to must list %s or %s", where, TargetContent, TargetOutput)
+			}
+		}
+		if r.Pattern == "" || len(r.Pattern) > MaxPatternLen {
+			add("%s: pattern must be 1 to %d characters", where, MaxPatternLen)
+			continue
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			add("%s: invalid pattern: %v", where, err)
+			continue
+		}
+		if re.MatchString("") {
+			add("%s: pattern matches empty text", where)
+			continue
+		}
+		cr.re = re
+		c.rules = append(c.rules, cr)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	for i, t := range p.Tests {
+		where := fmt.Sprintf("tests[%d]", i)
+		if t.Name != "" {
+			where = fmt.Sprintf("test %q", t.Name)
+		}
+		target := t.Target
+		if target == "" {
+			target = TargetContent
+		}
+		if target != TargetContent && target != TargetOutput {
+			add("%s: target must be %s or %s", where, TargetContent, TargetOutput)
+			continue
+		}
+		var got []string
+		for _, m := range c.match(t.Text, target) {
+			got = append(got, m.ID)
+		}
+		want := slices.Clone(t.Expect)
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			add("%s: expected [%s], matched [%s]", where, strings.Join(want, ", "), strings.Join(got, ", "))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return c, nil
+}
+
+// Match returns a threat for each of the pack's rules matching text in a
+// scan of the given target
+func (c *Compiled) Match(text, target string) []types.Threat {
+	var threats []types.Threat
+	for _, r := range c.match(text, target) {
+		loc := r.re.FindStringIndex(text)
+		desc := r.Description
+		if desc == "" {
+			desc = fmt.Sprintf("Matched rule %s of rule pack %s", r.ID, c.Pack.Name)
+		}
+		threats = append(threats, types.Threat{
+			Category:    r.Category,
+			Pattern:     c.Pack.Name + "/" + r.ID,
+			Location:    fmt.Sprintf("offset %d", loc[0]),
+			Severity:    r.Severity,
+			Description: desc,
+		})
+	}
+	return threats
+}
+
+func (c *Compiled) match(text, target string) []compiledRule {
+	var matched []compiledRule
+	for _, r := range c.rules {
+		if (target == TargetOutput && !r.output) || (target != TargetOutput && !r.content) {
+			continue
+		}
+		if r.re.MatchString(text) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Apply adds the matches of packs to a scan result. It only escalates: high
+// and critical matches raise the decision to BLOCK, medium ones to WARN,
+// and the decision is never lowered.
+func Apply(result *types.ScanResult, text, target string, packs []*Compiled) {
+	var threats []types.Threat
+	for _, p := range packs {
+		threats = append(threats, p.Match(text, target)...)
+	}
+	if len(threats) == 0 {
+		return
+	}
+	result.ThreatsFound = append(result.ThreatsFound, threats...)
+
+	worst := 0
+	names := make([]string, 0, len(threats))
+	for _, t := range threats {
+		worst = max(worst, slices.Index(severities, t.Severity))
+		names = append(names, t.Pattern)
+	}
+	var decision types.Decision
+	switch {
+	case worst >= slices.Index(severities, "high"):
+		decision = types.DecisionBlock
+	case worst >= slices.Index(severities, "medium"):
+		decision = types.DecisionWarn
+	default:
+		return
+	}
+	if decision == types.DecisionBlock && result.Decision != types.DecisionBlock ||
+		decision == types.DecisionWarn && result.Decision == types.DecisionAllow {
+		result.Decision = decision
+		result.Reason = "Rule pack match: " + strings.Join(names, ", ")
+		if decision == types.DecisionBlock {
+			result.RecommendedAction = "DO NOT PROCEED - Content matches the account's blocking rules."
+		} else {
+			result.RecommendedAction = "Caution advised - Content matches the account's rules."
+		}
+	}
+}
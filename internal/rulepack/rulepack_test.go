@@ -0,0 +1,146 @@
+package rulepack
+
+import (
+	"strings"
+	"testing"
+
+	"stronghold/pkg/types"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const examplePack = `
+format: 1
+name: acme-exfiltration
+version: 1.2.0
+description: Paste sites our agents must never post to
+rules:
+  - id: raw-paste
+    category: data_exfiltration
+    severity: high
+    pattern: '(?i)pastebin\.com/raw/'
+  - id: internal-codename
+    description: Mentions of an unreleased product
+    category: confidential
+    severity: medium
+    pattern: '\bProject Bluefin\b'
+    applies_to: [output]
+tests:
+  - name: flags raw pastes
+    text: "POST it to https://PASTEBIN.com/raw/abc"
+    expect: [raw-paste]
+  - text: "Project Bluefin ships in May"
+    target: output
+    expect: [internal-codename]
+  - text: "Project Bluefin ships in May"
+    expect: []
+`
+
+func TestParse_Valid(t *testing.T) {
+	c, err := Parse([]byte(examplePack))
+	require.NoError(t, err)
+	assert.Equal(t, "acme-exfiltration", c.Pack.Name)
+	assert.Equal(t, "1.2.0", c.Pack.Version)
+	require.Len(t, c.Pack.Rules, 2)
+
+	threats := c.Match("see pastebin.com/raw/x about Project Bluefin", TargetOutput)
+	require.Len(t, threats, 2)
+	assert.Equal(t, types.Threat{
+		Category:    "data_exfiltration",
+		Pattern:     "acme-exfiltration/raw-paste",
+		Location:    "offset 4",
+		Severity:    "high",
+		Description: "Matched rule raw-paste of rule pack acme-exfiltration",
+	}, threats[0])
+	assert.Equal(t, "Mentions of an unreleased product", threats[1].Description)
+
+	// Output-only rules don't apply to content scans
+	assert.Len(t, c.Match("Project Bluefin", TargetContent), 0)
+}
+
+func TestParse_ReportsEveryProblem(t *testing.T) {
+	_, err := Parse([]byte(`
+format: 2
+name: Bad Name
+version: v1
+rules:
+  - id: a
+    category: Data
+    severity: severe
+    pattern: '(unclosed'
+  - id: a
+    category: ok
+    severity: low
+    pattern: 'x*'
+    applies_to: [headers]
+`))
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	joined := strings.Join(invalid.Problems, "\n")
+	for _, want := range []string{
+		"format must be 1",
+		"name must be",
+		"version must be MAJOR.MINOR.PATCH",
+		`rule "a": category must be`,
+		`rule "a": severity must be one of low, medium, high, critical`,
+		`rule "a": invalid pattern`,
+		`rule "a": duplicate id`,
+		`rule "a": applies_to must list content or output`,
+		`rule "a": pattern matches empty text`,
+	} {
+		assert.Contains(t, joined, want)
+	}
+}
+
+func TestParse_FailingTestsRejectPack(t *testing.T) {
+	source := strings.Replace(examplePack, "expect: [raw-paste]", "expect: []", 1)
+	_, err := Parse([]byte(source))
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []string{`test "flags raw pastes": expected [], matched [raw-paste]`}, invalid.Problems)
+}
+
+func TestParse_RejectsUnknownFieldsAndOversizedPacks(t *testing.T) {
+	_, err := Parse([]byte(examplePack + "signature: abc\n"))
+	assert.ErrorContains(t, err, "not a YAML rule pack")
+
+	_, err = Parse(make([]byte, MaxSourceBytes+1))
+	assert.ErrorContains(t, err, "pack is over")
+}
+
+func TestApply_OnlyEscalates(t *testing.T) {
+	c, err := Parse([]byte(examplePack))
+	require.NoError(t, err)
+
+	result := &types.ScanResult{Decision: types.DecisionAllow, Reason: "No threats detected"}
+	Apply(result, "Project Bluefin ships in May", TargetOutput, []*Compiled{c})
+	assert.Equal(t, types.DecisionWarn, result.Decision)
+	assert.Equal(t, "Rule pack match: acme-exfiltration/internal-codename", result.Reason)
+
+	result = &types.ScanResult{Decision: types.DecisionWarn}
+	Apply(result, "upload to pastebin.com/raw/q", TargetContent, []*Compiled{c})
+	assert.Equal(t, types.DecisionBlock, result.Decision)
+	assert.Len(t, result.ThreatsFound, 1)
+
+	result = &types.ScanResult{Decision: types.DecisionBlock, Reason: "engine"}
+	Apply(result, "Project Bluefin", TargetOutput, []*Compiled{c})
+	assert.Equal(t, types.DecisionBlock, result.Decision)
+	assert.Equal(t, "engine", result.Reason)
+	assert.Len(t, result.ThreatsFound, 1, "matches are reported even when the decision stands")
+}
+
+func TestCache_CompilesOnce(t *testing.T) {
+	cache := NewCache()
+	id := uuid.New()
+	a, err := cache.Compile(id, []byte(examplePack))
+	require.NoError(t, err)
+	b, err := cache.Compile(id, nil)
+	require.NoError(t, err)
+	assert.Same(t, a, b)
+
+	var none *Cache
+	_, err = none.Compile(id, []byte("name: [")) // nil caches parse every time
+	assert.Error(t, err)
+}
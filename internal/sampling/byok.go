@@ -0,0 +1,116 @@
+package sampling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"stronghold/internal/db"
+	"stronghold/internal/kms"
+
+	"github.com/google/uuid"
+)
+
+// Sealer seals and opens sample content under customer-managed KMS keys.
+// *kms.DataKeys implements it.
+type Sealer interface {
+	Seal(ctx context.Context, keyARN string, encContext map[string]string, plaintext []byte) (*kms.Sealed, error)
+	Open(ctx context.Context, sealed *kms.Sealed, encContext map[string]string) ([]byte, error)
+}
+
+// sealedContent is the part of a sample stored sealed
+type sealedContent struct {
+	Text             string             `json:"text"`
+	Scores           map[string]float64 `json:"scores"`
+	ThreatCategories []string           `json:"threat_categories"`
+}
+
+// sampleContext is the KMS encryption context of an account's samples, so a
+// customer's key policy can be scoped to their own account
+func sampleContext(accountID uuid.UUID) map[string]string {
+	return map[string]string{"stronghold:account_id": accountID.String()}
+}
+
+// sealForAccount seals the sample's content when the account has a
+// customer-managed key. It never falls back to storing plaintext.
+func (s *Sampler) sealForAccount(ctx context.Context, accountID uuid.UUID, sample *db.ScanSample) error {
+	keyARN, err := s.store.GetAccountEncryptionKey(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to look up encryption key: %w", err)
+	}
+	if keyARN == "" {
+		return nil
+	}
+	if s.sealer == nil {
+		return errors.New("account has a customer-managed key but BYOK is not available")
+	}
+
+	plaintext, err := json.Marshal(sealedContent{
+		Text:             sample.Text,
+		Scores:           sample.Scores,
+		ThreatCategories: sample.ThreatCategories,
+	})
+	if err != nil {
+		return err
+	}
+	sealed, err := s.sealer.Seal(ctx, keyARN, sampleContext(accountID), plaintext)
+	if err != nil {
+		return err
+	}
+
+	sample.EncryptionKeyARN = sealed.KeyARN
+	sample.WrappedKey = sealed.WrappedKey
+	sample.Ciphertext = sealed.Ciphertext
+	sample.Text, sample.Scores, sample.ThreatCategories = "", nil, nil
+	return nil
+}
+
+// Open unseals samples stored under customer-managed keys and returns the
+// samples that can be replayed, with the number that could not be read.
+// Samples whose key the customer has revoked stay unreadable.
+func Open(ctx context.Context, samples []*db.ScanSample, sealer Sealer) ([]*db.ScanSample, int) {
+	readable := make([]*db.ScanSample, 0, len(samples))
+	unreadable := 0
+	for _, sample := range samples {
+		if !sample.Sealed() {
+			readable = append(readable, sample)
+			continue
+		}
+		if err := open(ctx, sample, sealer); err != nil {
+			if errors.Is(err, kms.ErrKeyRevoked) {
+				slog.Debug("scan sample key revoked", "sample_id", sample.ID, "key_arn", sample.EncryptionKeyARN)
+			} else {
+				slog.Warn("failed to open scan sample", "sample_id", sample.ID, "error", err)
+			}
+			unreadable++
+			continue
+		}
+		readable = append(readable, sample)
+	}
+	return readable, unreadable
+}
+
+func open(ctx context.Context, sample *db.ScanSample, sealer Sealer) error {
+	if sealer == nil {
+		return errors.New("no sealer configured")
+	}
+	if sample.AccountID == nil {
+		return errors.New("sealed sample has no account")
+	}
+	plaintext, err := sealer.Open(ctx, &kms.Sealed{
+		KeyARN:     sample.EncryptionKeyARN,
+		WrappedKey: sample.WrappedKey,
+		Ciphertext: sample.Ciphertext,
+	}, sampleContext(*sample.AccountID))
+	if err != nil {
+		return err
+	}
+	var content sealedContent
+	if err := json.Unmarshal(plaintext, &content); err != nil {
+		return fmt.Errorf("invalid sealed sample: %w", err)
+	}
+	sample.Text, sample.Scores, sample.ThreatCategories = content.Text, content.Scores, content.ThreatCategories
+	return nil
+}
@@ -0,0 +1,157 @@
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+)
+
+// Scanner is the part of stronghold.Scanner that samples are replayed against
+type Scanner interface {
+	ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*stronghold.ScanResult, error)
+	ScanOutput(ctx context.Context, text string) (*stronghold.ScanResult, error)
+}
+
+// Change is a sample whose decision differs on replay
+type Change struct {
+	SampleID    uuid.UUID `json:"sample_id"`
+	RequestID   string    `json:"request_id"`
+	Endpoint    string    `json:"endpoint"`
+	Before      string    `json:"before"`
+	After       string    `json:"after"`
+	ScoreBefore float64   `json:"score_before"`
+	ScoreAfter  float64   `json:"score_after"`
+}
+
+// Report compares replayed decisions with the ones recorded in the samples
+type Report struct {
+	DetectionVersion string         `json:"detection_version"` // Version the samples were replayed against
+	Total            int            `json:"total"`
+	Replayed         int            `json:"replayed"`
+	Errors           int            `json:"errors"`
+	Unreadable       int            `json:"unreadable"` // Sealed samples that could not be opened, e.g. the key was revoked
+	Unchanged        int            `json:"unchanged"`
+	Transitions      map[string]int `json:"transitions"` // "ALLOW->BLOCK": count
+	Escalated        []Change       `json:"escalated"`   // Stricter verdicts; possible new false positives
+	Relaxed          []Change       `json:"relaxed"`     // More lenient verdicts; possible missed detections
+	MeanScoreDelta   float64        `json:"mean_score_delta"`
+}
+
+// decisionRank orders decisions by strictness
+var decisionRank = map[string]int{
+	string(stronghold.DecisionAllow): 0,
+	string(stronghold.DecisionWarn):  1,
+	string(stronghold.DecisionBlock): 2,
+}
+
+// Replay scans each sample again and compares the result with the recorded
+// decision. Samples from /v1/scan/output are replayed through ScanOutput,
+// everything else through ScanContent.
+func Replay(ctx context.Context, samples []*db.ScanSample, scanner Scanner) *Report {
+	r := &Report{Total: len(samples), Transitions: make(map[string]int)}
+	var deltaSum float64
+
+	for _, s := range samples {
+		if ctx.Err() != nil {
+			break
+		}
+		var result *stronghold.ScanResult
+		var err error
+		if s.Endpoint == "/v1/scan/output" {
+			result, err = scanner.ScanOutput(ctx, s.Text)
+		} else {
+			result, err = scanner.ScanContent(ctx, s.Text, "", s.SourceType, s.ContentType)
+		}
+		if err != nil {
+			r.Errors++
+			continue
+		}
+		r.Replayed++
+		r.DetectionVersion = result.DetectionVersion
+
+		before, after := stronghold.PrimaryScore(s.Scores), stronghold.PrimaryScore(result.Scores)
+		deltaSum += after - before
+
+		decision := string(result.Decision)
+		r.Transitions[s.Decision+"->"+decision]++
+		if decision == s.Decision {
+			r.Unchanged++
+			continue
+		}
+		change := Change{
+			SampleID:    s.ID,
+			RequestID:   s.RequestID,
+			Endpoint:    s.Endpoint,
+			Before:      s.Decision,
+			After:       decision,
+			ScoreBefore: before,
+			ScoreAfter:  after,
+		}
+		if decisionRank[decision] > decisionRank[s.Decision] {
+			r.Escalated = append(r.Escalated, change)
+		} else {
+			r.Relaxed = append(r.Relaxed, change)
+		}
+	}
+
+	if r.Replayed > 0 {
+		r.MeanScoreDelta = deltaSum / float64(r.Replayed)
+	}
+	return r
+}
+
+// WriteText writes a human-readable report listing up to maxExamples changes
+// of each kind
+func (r *Report) WriteText(w io.Writer, maxExamples int) {
+	if r.DetectionVersion != "" {
+		fmt.Fprintf(w, "Detection:    %s\n", r.DetectionVersion)
+	}
+	fmt.Fprintf(w, "Samples:      %d\n", r.Total)
+	fmt.Fprintf(w, "Replayed:     %d\n", r.Replayed)
+	if r.Errors > 0 {
+		fmt.Fprintf(w, "Errors:       %d\n", r.Errors)
+	}
+	if r.Unreadable > 0 {
+		fmt.Fprintf(w, "Unreadable:   %d\n", r.Unreadable)
+	}
+	fmt.Fprintf(w, "Unchanged:    %d\n", r.Unchanged)
+	fmt.Fprintf(w, "Escalated:    %d\n", len(r.Escalated))
+	fmt.Fprintf(w, "Relaxed:      %d\n", len(r.Relaxed))
+	fmt.Fprintf(w, "Score delta:  %+.4f (mean)\n", r.MeanScoreDelta)
+
+	if len(r.Transitions) > 0 {
+		keys := make([]string, 0, len(r.Transitions))
+		for k := range r.Transitions {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(w, "\nTransitions:")
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %-16s %d\n", k, r.Transitions[k])
+		}
+	}
+
+	writeChanges(w, "Relaxed (possible missed detections)", r.Relaxed, maxExamples)
+	writeChanges(w, "Escalated (possible false positives)", r.Escalated, maxExamples)
+}
+
+func writeChanges(w io.Writer, title string, changes []Change, max int) {
+	if len(changes) == 0 || max <= 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for i, c := range changes {
+		if i == max {
+			fmt.Fprintf(w, "  ... and %d more\n", len(changes)-max)
+			break
+		}
+		fmt.Fprintf(w, "  %s  %s  %s -> %s  (%.2f -> %.2f)\n",
+			c.SampleID, c.Endpoint, c.Before, c.After, c.ScoreBefore, c.ScoreAfter)
+	}
+}
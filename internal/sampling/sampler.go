@@ -0,0 +1,135 @@
+// Package sampling stores a PII-scrubbed sample of scanned payloads and
+// replays them against the current scanner, so detection changes can be
+// evaluated on real traffic before they ship.
+package sampling
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+	"unicode/utf8"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/stronghold"
+
+	"github.com/google/uuid"
+)
+
+// storeTimeout bounds a single sample write
+const storeTimeout = 5 * time.Second
+
+// Store persists scan samples
+type Store interface {
+	CreateScanSample(ctx context.Context, sample *db.ScanSample) error
+	CleanupOldScanSamples(ctx context.Context, retentionDays int) (int64, error)
+	GetAccountEncryptionKey(ctx context.Context, accountID uuid.UUID) (string, error)
+}
+
+// Sampler stores a percentage of scans. A nil Sampler records nothing.
+type Sampler struct {
+	percent       float64
+	maxBytes      int
+	retentionDays int
+	store         Store
+	sealer        Sealer
+	roll          func() float64
+}
+
+// New returns nil when sampling is disabled
+func New(cfg *config.SamplingConfig, store Store) *Sampler {
+	if cfg.Percent <= 0 || store == nil {
+		return nil
+	}
+	return &Sampler{
+		percent:       min(cfg.Percent, 100),
+		maxBytes:      cfg.MaxBytes,
+		retentionDays: cfg.RetentionDays,
+		store:         store,
+		roll:          func() float64 { return rand.Float64() * 100 },
+	}
+}
+
+// SetSealer enables customer-managed keys. Without one, samples of accounts
+// with a key are not stored.
+func (s *Sampler) SetSealer(sealer Sealer) {
+	if s != nil {
+		s.sealer = sealer
+	}
+}
+
+// Record stores the scan in the background if it falls in the sample. The
+// result should be the scanner's own verdict, before account policies are
+// applied, so replays compare like with like. Samples of accounts with a
+// customer-managed key are sealed under it, or dropped if that fails.
+func (s *Sampler) Record(accountID *uuid.UUID, requestID, endpoint, text, sourceType, contentType string, result *stronghold.ScanResult) {
+	if s == nil || result == nil || s.roll() >= s.percent {
+		return
+	}
+
+	sample := &db.ScanSample{
+		AccountID:        accountID,
+		RequestID:        requestID,
+		Endpoint:         endpoint,
+		SourceType:       sourceType,
+		ContentType:      contentType,
+		Decision:         string(result.Decision),
+		Scores:           make(map[string]float64, len(result.Scores)),
+		DetectionVersion: result.DetectionVersion,
+	}
+	for k, v := range result.Scores {
+		sample.Scores[k] = v
+	}
+	for _, t := range result.ThreatsFound {
+		sample.ThreatCategories = append(sample.ThreatCategories, t.Category)
+	}
+	sample.Text, sample.Truncated = truncate(Scrub(text), s.maxBytes)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		if accountID != nil {
+			if err := s.sealForAccount(ctx, *accountID, sample); err != nil {
+				slog.Warn("scan sample not stored", "request_id", requestID, "account_id", accountID.String(), "error", err)
+				return
+			}
+		}
+		if err := s.store.CreateScanSample(ctx, sample); err != nil {
+			slog.Warn("failed to store scan sample", "request_id", requestID, "error", err)
+		}
+	}()
+}
+
+// Run deletes samples past the retention period once a day until ctx is done
+func (s *Sampler) Run(ctx context.Context) {
+	if s == nil || s.retentionDays <= 0 {
+		return
+	}
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := s.store.CleanupOldScanSamples(ctx, s.retentionDays); err != nil {
+			slog.Warn("failed to clean up scan samples", "error", err)
+		} else if n > 0 {
+			slog.Info("cleaned up scan samples", "deleted", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// truncate cuts text to at most maxBytes without splitting a UTF-8 sequence
+func truncate(text string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return text, false
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut], true
+}
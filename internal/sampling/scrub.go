@@ -0,0 +1,111 @@
+package sampling
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Personal data is replaced with a typed placeholder
+var piiPatterns = []struct {
+	re          *regexp.Regexp
+	placeholder string
+	valid       func(string) bool
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]", nil},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[CARD]", luhnValid},
+	{regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), "[SSN]", nil},
+	{regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`), "[PHONE]", nil},
+	{regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`), "[IP]", nil},
+}
+
+// Credentials keep their prefix and shape so credential-leak detection still
+// sees them on replay, but the secret characters are masked
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\b(?:sk|rk|pk)_(?:live|test)_[A-Za-z0-9]{16,}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`),
+	regexp.MustCompile(`\b0x[0-9a-fA-F]{64}\b`),
+	regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// secretPrefixLen characters of a credential are kept as-is
+const secretPrefixLen = 4
+
+// Scrub removes personal data and masks credentials in text before it is
+// stored. Emails, card numbers, SSNs, phone numbers and IP addresses become
+// placeholders such as [EMAIL]; credentials keep their first characters and
+// have the rest replaced by characters of the same class.
+func Scrub(text string) string {
+	for _, re := range secretPatterns {
+		text = re.ReplaceAllStringFunc(text, maskSecret)
+	}
+	for _, p := range piiPatterns {
+		text = p.re.ReplaceAllStringFunc(text, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			return p.placeholder
+		})
+	}
+	return text
+}
+
+// maskSecret replaces letters with x/X and digits with 0 after the prefix,
+// leaving separators and PEM armor intact
+func maskSecret(secret string) string {
+	if strings.HasPrefix(secret, "-----BEGIN") {
+		header, rest, _ := strings.Cut(secret, "\n")
+		body, footer := rest, ""
+		if i := strings.LastIndex(rest, "-----END"); i >= 0 {
+			body, footer = rest[:i], rest[i:]
+		}
+		return header + "\n" + maskChars(body) + footer
+	}
+	if strings.HasPrefix(strings.ToLower(secret), "bearer") {
+		i := strings.LastIndexAny(secret, " \t") + 1
+		return secret[:i] + maskChars(secret[i:])
+	}
+	n := min(secretPrefixLen, len(secret))
+	return secret[:n] + maskChars(secret[n:])
+}
+
+func maskChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'x'
+		case r >= 'A' && r <= 'Z':
+			return 'X'
+		case r >= '0' && r <= '9':
+			return '0'
+		}
+		return r
+	}, s)
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum, so order
+// numbers and other long digit runs are left alone
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
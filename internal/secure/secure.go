@@ -0,0 +1,218 @@
+// Package secure holds key material in wrappers that are zeroed when they are
+// no longer needed and that never print their contents. SecretBytes and
+// SecretString are used for wallet private keys, TOTP secrets and the JWT
+// signing secret: fmt, slog and encoding/json all see "[REDACTED]", callers
+// read the plaintext explicitly through Bytes or Reveal, and the backing
+// memory is cleared by Zero or, as a best-effort fallback, when the wrapper is
+// garbage collected.
+//
+// Zeroization is best effort. Go may have copied the data before it was
+// wrapped (string conversions, library internals), and the garbage collector
+// gives no timing guarantee, so callers should still defer Zero as soon as a
+// secret is created.
+package secure
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"unsafe"
+)
+
+// Redacted is what every formatting path prints in place of a secret
+const Redacted = "[REDACTED]"
+
+// SecretBytes wraps sensitive byte data with explicit zeroing capability.
+// The wrapper owns the slice it is given: the caller must not keep using it
+// after the wrapper is dropped, because it is zeroed then.
+type SecretBytes struct {
+	data []byte
+}
+
+// NewSecretBytes takes ownership of data. The caller should defer Zero.
+func NewSecretBytes(data []byte) *SecretBytes {
+	s := &SecretBytes{}
+	s.set(data)
+	return s
+}
+
+func (s *SecretBytes) set(data []byte) {
+	s.data = data
+	if len(data) > 0 {
+		runtime.AddCleanup(s, wipe, data)
+	}
+}
+
+// Bytes returns the underlying byte slice. The returned slice shares memory
+// with the SecretBytes, so zeroing the SecretBytes also zeroes it.
+func (s *SecretBytes) Bytes() []byte {
+	if s == nil {
+		return nil
+	}
+	return s.data
+}
+
+// Zero clears every byte of the underlying slice. It is safe to call more
+// than once.
+func (s *SecretBytes) Zero() {
+	if s == nil {
+		return
+	}
+	wipe(s.data)
+}
+
+// Len returns the length of the underlying data
+func (s *SecretBytes) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.data)
+}
+
+// IsEmpty returns true if the SecretBytes is nil or has no data
+func (s *SecretBytes) IsEmpty() bool {
+	return s.Len() == 0
+}
+
+// String returns Redacted so the secret cannot leak through fmt or logs
+func (s *SecretBytes) String() string { return Redacted }
+
+// GoString returns Redacted for %#v
+func (s *SecretBytes) GoString() string { return Redacted }
+
+// Format prints Redacted for every verb, including %x and %d
+func (s *SecretBytes) Format(f fmt.State, _ rune) { _, _ = f.Write([]byte(Redacted)) }
+
+// LogValue implements slog.LogValuer
+func (s *SecretBytes) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// MarshalJSON encodes Redacted; secrets are never serialized implicitly
+func (s *SecretBytes) MarshalJSON() ([]byte, error) { return json.Marshal(Redacted) }
+
+// SecretString holds text such as a hex or base58 private key, a TOTP secret
+// or a signing secret. It has the same zeroing and redaction behaviour as
+// SecretBytes.
+type SecretString struct {
+	b SecretBytes
+}
+
+// NewSecretString copies s into a zeroable buffer. The original string cannot
+// be cleared by the wrapper; use ZeroString on it if it is heap-allocated.
+func NewSecretString(s string) *SecretString {
+	return NewSecretStringFromBytes([]byte(s))
+}
+
+// NewSecretStringFromBytes takes ownership of b without copying it
+func NewSecretStringFromBytes(b []byte) *SecretString {
+	ss := &SecretString{}
+	ss.b.set(b)
+	return ss
+}
+
+// Reveal returns the plaintext without copying it. The result aliases the
+// secret's memory and reads as NUL bytes once Zero has run, so it must not be
+// retained past the wrapper's lifetime.
+func (s *SecretString) Reveal() string {
+	if s.IsEmpty() {
+		return ""
+	}
+	return unsafe.String(unsafe.SliceData(s.b.data), len(s.b.data))
+}
+
+// Bytes returns the plaintext as a slice sharing the secret's memory
+func (s *SecretString) Bytes() []byte {
+	if s == nil {
+		return nil
+	}
+	return s.b.Bytes()
+}
+
+// Zero clears the secret. It is safe to call more than once.
+func (s *SecretString) Zero() {
+	if s == nil {
+		return
+	}
+	s.b.Zero()
+}
+
+// Len returns the length of the secret in bytes
+func (s *SecretString) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.b.Len()
+}
+
+// IsEmpty returns true if the SecretString is nil or empty
+func (s *SecretString) IsEmpty() bool {
+	return s.Len() == 0
+}
+
+// String returns Redacted
+func (s *SecretString) String() string { return Redacted }
+
+// GoString returns Redacted for %#v
+func (s *SecretString) GoString() string { return Redacted }
+
+// Format prints Redacted for every verb
+func (s *SecretString) Format(f fmt.State, _ rune) { _, _ = f.Write([]byte(Redacted)) }
+
+// LogValue implements slog.LogValuer
+func (s *SecretString) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// MarshalJSON encodes Redacted
+func (s *SecretString) MarshalJSON() ([]byte, error) { return json.Marshal(Redacted) }
+
+// UnmarshalJSON decodes a JSON string into the secret so API responses
+// carrying key material never pass through a plain string field
+func (s *SecretString) UnmarshalJSON(data []byte) error {
+	var raw []byte
+	if err := json.Unmarshal(data, (*jsonText)(&raw)); err != nil {
+		return err
+	}
+	s.b.Zero()
+	s.b.set(raw)
+	return nil
+}
+
+// jsonText decodes a JSON string into a byte slice, unlike []byte which
+// json treats as base64
+type jsonText []byte
+
+func (t *jsonText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = []byte(s)
+	ZeroString(&s)
+	return nil
+}
+
+func wipe(b []byte) {
+	clear(b)
+}
+
+// ZeroString zeros the backing memory of a string in place using unsafe.StringData.
+// Go strings are normally immutable, so we use unsafe to access the underlying
+// byte array directly. This is necessary for security-sensitive data like private
+// keys where we need to ensure the plaintext is cleared from memory.
+//
+// IMPORTANT: This only works for heap-allocated strings (e.g., from API responses,
+// string([]byte{...}), fmt.Sprintf). Passing a string literal may cause a fault
+// because literals reside in read-only memory.
+// Use SecretString instead when possible for better guarantees.
+func ZeroString(s *string) {
+	if s == nil || len(*s) == 0 {
+		return
+	}
+	// unsafe.StringData returns a pointer to the string's underlying bytes.
+	// unsafe.Slice converts it to a mutable byte slice so we can zero each byte.
+	p := unsafe.StringData(*s)
+	b := unsafe.Slice(p, len(*s))
+	for i := range b {
+		b[i] = 0
+	}
+	*s = ""
+}
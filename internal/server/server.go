@@ -2,18 +2,36 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"log/slog"
 	"time"
 
+	"stronghold/internal/abuse"
 	"stronghold/internal/billing"
+	"stronghold/internal/backends"
+	"stronghold/internal/canary"
 	"stronghold/internal/config"
 	"stronghold/internal/db"
+	"stronghold/internal/flags"
 	"stronghold/internal/handlers"
+	"stronghold/internal/idempotency"
+	"stronghold/internal/identity"
 	"stronghold/internal/kms"
 	"stronghold/internal/middleware"
+	"stronghold/internal/middleware/ratelimit"
+	"stronghold/internal/proxyversion"
+	"stronghold/internal/sampling"
+	"stronghold/internal/secure"
+	"stronghold/internal/redact"
+	"stronghold/internal/rollups"
+	"stronghold/internal/snapshots"
+	"stronghold/internal/sessions"
 	"stronghold/internal/settlement"
 	"stronghold/internal/stronghold"
+	"stronghold/internal/wallet"
+	"stronghold/pkg/e2escan"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/stripe/stripe-go/v82"
@@ -30,6 +48,21 @@ type Server struct {
 	database         *db.DB
 	authHandler      *handlers.AuthHandler
 	settlementWorker *settlement.Worker
+	paymentRetention *settlement.Retention
+	usageRollups     *rollups.Worker
+	balanceSnapshots *snapshots.Worker
+	lowBalance       *settlement.LowBalance
+	webhooks         *settlement.Webhooks
+	receivables      *settlement.Receivables
+	sampler          *sampling.Sampler
+	dataKeys         *kms.DataKeys
+	canary           *canary.Canary
+	flags            *flags.Flags
+	rateLimiter      *middleware.RateLimitMiddleware
+	rateLimitStore   ratelimit.Store
+	scanSessions     *sessions.Manager
+	scanDedup        *idempotency.Dedup
+	abuse            *abuse.Detector
 }
 
 // New creates a new server instance
@@ -76,9 +109,10 @@ func New(cfg *config.Config) (*Server, error) {
 
 	// Initialize auth handler
 	authConfig := &handlers.AuthConfig{
-		JWTSecret:       cfg.Auth.JWTSecret,
+		JWTSecret:       secure.NewSecretString(cfg.Auth.JWTSecret),
 		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
 		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+		SessionBinding:  cfg.Auth.SessionBinding,
 		DashboardURL:    cfg.Dashboard.URL,
 		AllowedOrigins:  cfg.Dashboard.AllowedOrigins,
 		Cookie: handlers.CookieConfig{
@@ -91,10 +125,14 @@ func New(cfg *config.Config) (*Server, error) {
 
 	// Create Fiber app
 	fiberConfig := fiber.Config{
-		AppName:      "Stronghold API",
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		ErrorHandler: errorHandler,
+		AppName:          "Stronghold API",
+		ReadTimeout:      cfg.Server.ReadTimeout,
+		WriteTimeout:     cfg.Server.WriteTimeout,
+		IdleTimeout:      cfg.Server.IdleTimeout,
+		DisableKeepalive: !cfg.Server.KeepAlive,
+		Concurrency:      cfg.Server.MaxConns,
+		BodyLimit:        cfg.Limits.BodyLimit,
+		ErrorHandler:     errorHandler,
 	}
 
 	// Configure proxy header for correct client IP behind reverse proxy
@@ -109,10 +147,65 @@ func New(cfg *config.Config) (*Server, error) {
 	}
 
 	app := fiber.New(fiberConfig)
+	// Counted per IPv4 address; the server listens on IPv4 only
+	app.Server().MaxConnsPerIP = cfg.Server.MaxConnsPerIP
 
 	// Create settlement worker for background retry of failed settlements
 	settlementWorker := settlement.NewWorker(database, &cfg.X402, nil)
 
+	// Candidate detection configuration, shadow-scored against the stable one
+	canaryScanner, err := canary.New(&cfg.Canary, database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create canary scanner: %w", err)
+	}
+
+	// Rate limit counters, shared across instances when kept in Redis
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "redis" {
+		redisStore, err := ratelimit.NewRedisStoreFromURL(cfg.RateLimit.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rate limit store: %w", err)
+		}
+		rateLimitStore = redisStore
+		slog.Info("rate limits shared through redis", "strategy", cfg.RateLimit.Strategy)
+	}
+
+	// Conversation scan sessions, shared across instances when kept in Redis
+	var scanSessionStore sessions.Store = sessions.NewMemoryStore()
+	if cfg.Sessions.Store == "redis" {
+		redisStore, err := sessions.NewRedisStoreFromURL(cfg.Sessions.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scan session store: %w", err)
+		}
+		scanSessionStore = redisStore
+		slog.Info("scan sessions shared through redis")
+	}
+
+	// Replayed verdicts for retried scans, shared across instances when kept in Redis
+	var scanDedupStore idempotency.Store = idempotency.NewMemoryStore()
+	if cfg.Idempotency.Store == "redis" {
+		redisStore, err := idempotency.NewRedisStoreFromURL(cfg.Idempotency.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scan idempotency store: %w", err)
+		}
+		scanDedupStore = redisStore
+		slog.Info("scan idempotency shared through redis")
+	}
+
+	// Ed25519 keys that sign webhook deliveries, published for receivers
+	webhookKeys, err := settlement.NewWebhookKeys(&cfg.Webhooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook signing keys: %w", err)
+	}
+	webhooks := settlement.NewWebhooks(database, nil)
+	webhooks.SetSigningKeys(webhookKeys)
+
+	// RPC endpoints for on-chain balance lookups, tried healthiest first
+	for network, urls := range cfg.RPC.URLs {
+		wallet.Providers().SetURLs(network, urls)
+		slog.Info("rpc providers configured", "network", network, "endpoints", len(urls))
+	}
+
 	s := &Server{
 		app:              app,
 		config:           cfg,
@@ -120,6 +213,34 @@ func New(cfg *config.Config) (*Server, error) {
 		database:         database,
 		authHandler:      authHandler,
 		settlementWorker: settlementWorker,
+		paymentRetention: settlement.NewRetention(&cfg.Payments, database),
+		usageRollups:     rollups.New(&cfg.Rollups, database),
+		balanceSnapshots: snapshots.New(&cfg.Snapshots, database),
+		lowBalance:       settlement.NewLowBalance(&cfg.Runway, database),
+		webhooks:         webhooks,
+		receivables:      settlement.NewReceivables(&cfg.X402.OutageCredit, database),
+		sampler:          sampling.New(&cfg.Sampling, database),
+		canary:           canaryScanner,
+		flags:            flags.New(context.Background(), database, cfg.Flags.RefreshInterval),
+		rateLimiter:      middleware.NewRateLimitMiddlewareWithStore(&cfg.RateLimit, rateLimitStore),
+		rateLimitStore:   rateLimitStore,
+		scanSessions:     sessions.New(&cfg.Sessions, scanSessionStore),
+		scanDedup:        idempotency.New(&cfg.Idempotency, scanDedupStore),
+		abuse:            abuse.New(&cfg.Abuse, database.SuspendAccount),
+	}
+	if s.sampler != nil {
+		slog.Info("scan sampling enabled", "percent", cfg.Sampling.Percent)
+		// Customer-managed keys use the default AWS credential chain; without
+		// it, samples of accounts with a key are not stored
+		if dataKeys, err := kms.NewDataKeys(context.Background()); err != nil {
+			slog.Warn("customer-managed keys unavailable", "error", err)
+		} else {
+			s.dataKeys = dataKeys
+			s.sampler.SetSealer(dataKeys)
+		}
+	}
+	if s.canary != nil {
+		slog.Info("detection canary enabled", "version", s.canary.Version(), "default_percent", cfg.Canary.DefaultPercent)
 	}
 
 	// Setup middleware
@@ -146,7 +267,8 @@ func (s *Server) setupMiddleware() {
 	// Use JSON format in production for log aggregators, text format for development
 	if s.config.IsProduction() {
 		s.app.Use(logger.New(logger.Config{
-			Format: `{"time":"${time}","status":${status},"method":"${method}","path":"${path}","latency":"${latency}","ip":"${ip}","request_id":"${locals:request_id}"}` + "\n",
+			Format:     `{"time":"${time}","status":${status},"method":"${method}","path":"${path}","latency":"${latency}","ip":"${ip}","request_id":"${locals:request_id}"}` + "\n",
+			CustomTags: s.accessLogTags(),
 		}))
 	} else {
 		s.app.Use(logger.New(logger.Config{
@@ -155,19 +277,21 @@ func (s *Server) setupMiddleware() {
 	}
 
 	// Rate limiting middleware (general limits)
-	rateLimiter := middleware.NewRateLimitMiddleware(&s.config.RateLimit)
-	s.app.Use(rateLimiter.Middleware())
+	s.app.Use(s.rateLimiter.Middleware())
 
 	// CORS middleware - configured for dashboard, x402 headers, and request tracking
 	s.app.Use(cors.New(cors.Config{
 		AllowOrigins:     s.config.Dashboard.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-PAYMENT", "X-PAYMENT-RESPONSE", "Authorization", "X-API-Key", "X-Stronghold-Device", middleware.RequestIDHeader},
-		ExposeHeaders:    []string{"X-PAYMENT-RESPONSE", middleware.RequestIDHeader},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-PAYMENT", "X-PAYMENT-RESPONSE", "Authorization", "X-API-Key", "X-Stronghold-Device", identity.HeaderInstall, identity.HeaderTimestamp, identity.HeaderSignature, proxyversion.HeaderVersion, middleware.RequestIDHeader, middleware.IdempotentScanHeader},
+		ExposeHeaders:    []string{"X-PAYMENT-RESPONSE", "X-Stronghold-Payment", proxyversion.HeaderUpdate, middleware.RequestIDHeader, middleware.IdempotentReplayHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Maintenance mode for endpoints switched off with feature flags
+	s.app.Use(middleware.Maintenance(s.flags))
+
 	// Note: x402 payment middleware is now applied per-route via AtomicPayment
 	// for atomic settlement. This removes the global Middleware() and SettleAfterHandler()
 	// which had the race condition where settlement could fail after service delivery.
@@ -188,17 +312,20 @@ func (s *Server) setupRoutes() {
 		)
 	}
 
-	// Initialize rate limiter for auth routes
-	rateLimiter := middleware.NewRateLimitMiddleware(&s.config.RateLimit)
-
 	// Health handler (no payment required)
 	healthHandler := handlers.NewHealthHandler(s.database, s.config)
 	healthHandler.RegisterRoutes(s.app)
 
 	// Pricing handler (no payment required)
 	pricingHandler := handlers.NewPricingHandler(x402)
+	pricingHandler.SetAccounts(middleware.NewAPIKeyMiddleware(s.database), s.database)
+	pricingHandler.SetMaxTextBytes(s.config.Limits.ScanMaxTextBytes)
 	pricingHandler.RegisterRoutes(s.app)
 
+	// Detection version handler (no payment required)
+	detectionHandler := handlers.NewDetectionHandler(s.scanner)
+	detectionHandler.RegisterRoutes(s.app)
+
 	// WorkOS API proxy — forwards /user_management/* requests to api.workos.com.
 	// This works around a WorkOS CORS bug where actual responses (not just OPTIONS
 	// preflight) are missing Access-Control-Allow-Origin headers, breaking the
@@ -215,7 +342,7 @@ func (s *Server) setupRoutes() {
 	s.app.Use(workosAuth.Handler())
 
 	// Auth handlers with stricter rate limiting
-	s.authHandler.RegisterRoutesWithMiddleware(s.app, rateLimiter.AuthLimiter())
+	s.authHandler.RegisterRoutesWithMiddleware(s.app, s.rateLimiter.AuthLimiter())
 
 	// Initialize Stripe API key once — all handlers share the same key.
 	// Setting it per-request would be an unsynchronized write to a package global.
@@ -228,17 +355,56 @@ func (s *Server) setupRoutes() {
 	b2bAuthHandler := handlers.NewB2BAuthHandler(s.database)
 	b2bAuthHandler.RegisterRoutes(s.app, s.authHandler.AuthMiddleware())
 
+	// WorkOS SSO lookup, Directory Sync webhook (verified via signature) and
+	// organization member list
+	workosDirectoryHandler := handlers.NewWorkOSDirectoryHandler(s.database, &s.config.WorkOS)
+	workosDirectoryHandler.RegisterRoutes(s.app, s.authHandler, s.rateLimiter.AuthLimiter())
+
+	// Organization usage aggregated across members and API keys (WorkOS admin)
+	orgUsageHandler := handlers.NewOrgUsageHandler(s.database)
+	orgUsageHandler.RegisterRoutes(s.app, s.authHandler)
+
 	// Account handlers (no payment required for account management)
 	// Reuse authConfig from authHandler initialization
 	accountHandler := handlers.NewAccountHandler(s.database, s.authHandler.Config(), &s.config.Stripe)
+	accountHandler.SetFlags(s.flags)
+	accountHandler.SetOverviewCache(s.config.Dashboard.OverviewCacheTTL)
+	accountHandler.SetBalanceCache(s.config.Dashboard.BalanceCacheTTL, s.config.Dashboard.BalanceStaleTTL)
+	accountHandler.SetRunwayAlert(s.config.Runway.AlertDays)
 	accountHandler.RegisterRoutes(s.app, s.authHandler)
 
+	// Balance transfers between accounts (TOTP code required per transfer)
+	transferHandler := handlers.NewTransferHandler(s.database, s.authHandler, s.config.Transfers.DailyLimit)
+	transferHandler.RegisterRoutes(s.app, s.authHandler)
+
+	// Prepaid code redemption (session auth, failed attempts locked out)
+	prepaidCodeHandler := handlers.NewPrepaidCodeHandler(s.database, &s.config.PrepaidCodes)
+	prepaidCodeHandler.RegisterRoutes(s.app, s.authHandler, s.rateLimiter.AuthLimiter())
+
+	// Proxy installs that sign scan requests (session auth required) and
+	// their heartbeats (install signature required)
+	installSignature := middleware.NewInstallSignature(s.database).Middleware()
+	proxyVersion := middleware.NewProxyVersion(proxyversion.Policy{
+		Minimum:     s.config.Proxy.MinimumVersion,
+		Recommended: s.config.Proxy.RecommendedVersion,
+		Vulnerable:  s.config.Proxy.VulnerableVersions,
+		Enforce:     s.config.Proxy.EnforceVulnerable,
+	}).Middleware()
+	installHandler := handlers.NewInstallHandler(s.database)
+	installHandler.SetProxyVersion(proxyVersion)
+	installHandler.RegisterRoutes(s.app, s.authHandler, installSignature)
+
 	// API key management (JWT auth required)
 	apiKeyHandler := handlers.NewAPIKeyHandler(s.database)
 	apiKeyHandler.RegisterRoutes(s.app, s.authHandler.AuthMiddleware())
 
+	// Sub-account management (JWT auth required)
+	subAccountHandler := handlers.NewSubAccountHandler(s.database)
+	subAccountHandler.RegisterRoutes(s.app, s.authHandler.AuthMiddleware())
+
 	// B2B billing (JWT auth required)
 	billingHandler := handlers.NewB2BBillingHandler(s.database, &s.config.Stripe, s.config.Dashboard.URL)
+	billingHandler.SetFlags(s.flags)
 	billingHandler.RegisterRoutes(s.app, s.authHandler.AuthMiddleware())
 
 	// Stripe webhook handler (no auth required - verified via signature)
@@ -249,15 +415,72 @@ func (s *Server) setupRoutes() {
 	apiKeyMiddleware := middleware.NewAPIKeyMiddleware(s.database)
 	meterReporter := billing.NewMeterReporter(s.database, &s.config.Stripe)
 	paymentRouter := middleware.NewPaymentRouter(x402, apiKeyMiddleware, meterReporter, s.database)
+	paymentRouter.SetFlags(s.flags)
+	if s.config.Region.Name != "" {
+		paymentRouter.SetRegionPin(middleware.NewRegionPin(&s.config.Region))
+	}
+	paymentRouter.SetScanDedup(middleware.NewScanDedup(s.scanDedup))
+	paymentRouter.SetAbuseGuard(middleware.NewAbuseGuard(s.abuse))
+
+	// Detection backends: the built-in engine, external services and customer endpoints
+	backendRouter := backends.NewRouter(&s.config.Backends, s.scanner, &s.config.Stronghold, s.database)
 
 	// Scan handlers (payment required - uses PaymentRouter for x402 OR API key auth)
 	scanHandler := handlers.NewScanHandlerWithPaymentRouter(s.scanner, x402, s.database, &s.config.Pricing, paymentRouter)
+	scanHandler.SetBackends(backendRouter)
+	scanHandler.SetSampler(s.sampler)
+	scanHandler.SetCanary(s.canary)
+	scanHandler.SetSessions(s.scanSessions)
+	scanHandler.SetIngest(s.database)
+	scanHandler.SetRateLimiter(s.rateLimiter.ScanLimiter())
+	var bodyLimiter fiber.Handler
+	if limits := s.config.Limits; limits.ScanMaxBodyBytes > 0 {
+		bodyLimiter = middleware.NewBodyLimiter(int64(limits.ScanMaxBodyBytes), int64(limits.ScanInFlightBytes)).Middleware()
+	}
+	scanHandler.SetBodyLimits(bodyLimiter, s.config.Limits.ScanMaxTextBytes)
+	scanHandler.SetInstallSignature(installSignature)
+	scanHandler.SetProxyVersion(proxyVersion)
+	if e2e := s.config.E2EScan; e2e.Enabled {
+		secret := []byte(e2e.Secret)
+		if len(secret) == 0 {
+			slog.Warn("E2E_SCAN_SECRET not set; scan keys only work on this instance until it restarts")
+			secret = make([]byte, 32)
+			rand.Read(secret)
+		}
+		scanHandler.SetE2EScan(e2escan.NewKeyring(secret, e2e.KeyTTL))
+	}
 	scanHandler.RegisterRoutes(s.app)
 
 	// Account settings handlers (session auth required)
 	settingsHandler := handlers.NewSettingsHandler(s.database)
 	settingsHandler.RegisterRoutes(s.app, s.authHandler)
 
+	// Scan policy handlers (account policy: session auth; org policy: WorkOS admin)
+	policyHandler := handlers.NewPolicyHandler(s.database)
+	policyHandler.RegisterRoutes(s.app, s.authHandler)
+
+	// Encrypted CLI configuration shared by an account's machines (session auth required)
+	configSyncHandler := handlers.NewConfigSyncHandler(s.database)
+	configSyncHandler.RegisterRoutes(s.app, s.authHandler)
+
+	// Settlement webhook handlers (session auth required)
+	settlementWebhookHandler := handlers.NewSettlementWebhookHandler(s.database)
+	settlementWebhookHandler.SetSender(s.webhooks)
+	settlementWebhookHandler.RegisterRoutes(s.app, s.authHandler)
+
+	// Operator endpoints (static admin token; disabled without one)
+	adminHandler := handlers.NewAdminHandler(s.database, s.scanner, s.canary, s.flags)
+	adminHandler.SetRateLimits(s.rateLimiter)
+	adminHandler.SetPaymentRetention(&s.config.Payments, s.paymentRetention)
+	adminHandler.SetRegion(&s.config.Region)
+	adminHandler.SetBackends(backendRouter)
+	adminHandler.SetAbuse(s.abuse)
+	adminHandler.SetPrepaidCodes(&s.config.PrepaidCodes)
+	if s.dataKeys != nil {
+		adminHandler.SetKeyChecker(s.dataKeys)
+	}
+	adminHandler.RegisterRoutes(s.app, middleware.AdminAuth(s.config.Admin.APIToken))
+
 	// API documentation
 	docsHandler := handlers.NewDocsHandler()
 	docsHandler.RegisterRoutes(s.app)
@@ -280,6 +503,30 @@ func (s *Server) Start(ctx context.Context) error {
 		s.settlementWorker.Start(ctx)
 	}
 
+	// Notify account settlement webhooks
+	go s.webhooks.Run(ctx)
+
+	// Debit outage credit that can no longer settle on-chain from balances
+	go s.receivables.Run(ctx)
+
+	// Archive or delete settled payments past their retention period
+	go s.paymentRetention.Run(ctx)
+
+	// Roll up finished days of usage for stats and exports
+	go s.usageRollups.Run(ctx)
+
+	// Snapshot daily balances for balance history
+	go s.balanceSnapshots.Run(ctx)
+
+	// Notify account webhooks when a balance is about to run out
+	go s.lowBalance.Run(ctx)
+
+	// Delete scan samples past their retention period
+	go s.sampler.Run(ctx)
+
+	// Pick up feature flag changes made by operators
+	go s.flags.Run(ctx)
+
 	addr := fmt.Sprintf(":%s", s.config.Server.Port)
 	slog.Info("starting Stronghold API server", "addr", addr)
 	return s.app.Listen(addr)
@@ -299,6 +546,25 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.database.Close()
 	}
 
+	// Close the shared rate limit store
+	if closer, ok := s.rateLimitStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("error closing rate limit store", "error", err)
+		}
+	}
+
+	// Close the shared scan session store
+	if s.scanSessions != nil {
+		if err := s.scanSessions.Close(); err != nil {
+			slog.Error("error closing scan session store", "error", err)
+		}
+	}
+
+	// Close the shared scan idempotency store
+	if err := s.scanDedup.Close(); err != nil {
+		slog.Error("error closing scan idempotency store", "error", err)
+	}
+
 	// Close scanner
 	if err := s.scanner.Close(); err != nil {
 		slog.Error("error closing scanner", "error", err)
@@ -308,6 +574,19 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.app.ShutdownWithContext(ctx)
 }
 
+// accessLogTags masks client IPs in the access log to their network unless
+// log redaction is off
+func (s *Server) accessLogTags() map[string]logger.LogFunc {
+	if s.config.Logging.Unredacted {
+		return nil
+	}
+	return map[string]logger.LogFunc{
+		logger.TagIP: func(output logger.Buffer, c fiber.Ctx, _ *logger.Data, _ string) (int, error) {
+			return output.WriteString(redact.IP(c.IP()))
+		},
+	}
+}
+
 // errorHandler handles errors globally
 func errorHandler(c fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
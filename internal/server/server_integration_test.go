@@ -13,6 +13,7 @@ import (
 	"stronghold/internal/db/testutil"
 	"stronghold/internal/handlers"
 	"stronghold/internal/middleware"
+	"stronghold/internal/secure"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/recover"
@@ -56,7 +57,7 @@ func createTestApp(t *testing.T, testDB *testutil.TestDB) (*fiber.App, *db.DB) {
 
 	// Auth handler
 	authConfig := &handlers.AuthConfig{
-		JWTSecret:       "test-secret-key",
+		JWTSecret:       secure.NewSecretString("test-secret-key"),
 		AccessTokenTTL:  15 * time.Minute,
 		RefreshTokenTTL: 90 * 24 * time.Hour,
 		DashboardURL:    "http://localhost:3000",
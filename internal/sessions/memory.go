@@ -0,0 +1,96 @@
+package sessions
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired sessions are dropped from memory
+const sweepInterval = time.Minute
+
+// maxMemorySessions bounds the sessions held by one instance
+const maxMemorySessions = 100000
+
+// MemoryStore keeps sessions in process memory. Sessions are per instance,
+// so load balancers must route a session's messages to the instance that
+// created it.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, sessions: make(map[string]*Session)}
+}
+
+// Create stores a new session
+func (s *MemoryStore) Create(_ context.Context, sess *Session, ttl time.Duration) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now, false)
+	if len(s.sessions) >= maxMemorySessions {
+		s.sweep(now, true)
+		if len(s.sessions) >= maxMemorySessions {
+			return ErrStoreFull
+		}
+	}
+
+	stored := *sess
+	stored.ExpiresAt = now.Add(ttl)
+	s.sessions[sess.ID] = &stored
+	return nil
+}
+
+// Get returns a copy of a session
+func (s *MemoryStore) Get(_ context.Context, id string) (*Session, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || !now.Before(sess.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	out := *sess
+	out.Messages = append([]Message(nil), sess.Messages...)
+	return &out, nil
+}
+
+// Append adds a message to a session
+func (s *MemoryStore) Append(_ context.Context, id string, msg Message, keep int, ttl time.Duration) error {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || !now.Before(sess.ExpiresAt) {
+		return ErrNotFound
+	}
+	sess.Messages = append(sess.Messages, msg)
+	if len(sess.Messages) > keep {
+		sess.Messages = append([]Message(nil), sess.Messages[len(sess.Messages)-keep:]...)
+	}
+	sess.Turns++
+	sess.ExpiresAt = now.Add(ttl)
+	return nil
+}
+
+// sweep drops expired sessions, at most once per sweepInterval unless
+// forced. Callers hold s.mu.
+func (s *MemoryStore) sweep(now time.Time, force bool) {
+	if !force && now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, sess := range s.sessions {
+		if !now.Before(sess.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
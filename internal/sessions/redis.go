@@ -0,0 +1,144 @@
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds a single store round trip
+const redisTimeout = 250 * time.Millisecond
+
+// appendScript adds a message to an existing session, trims its messages
+// and restarts the TTL of both keys. It returns 0 when the session is gone,
+// so an expired session isn't recreated without its owner.
+var appendScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], 'turns', 1)
+redis.call('RPUSH', KEYS[2], ARGV[1])
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return 1
+`)
+
+// RedisStore keeps sessions in Redis, shared by every API instance. Each
+// session is a hash of its owner, creation time and turn count, and a list
+// of its messages as JSON.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore creates a store using client. Keys are prefixed with
+// "stronghold:scansession:".
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "stronghold:scansession:"}
+}
+
+// NewRedisStoreFromURL connects to a redis:// or rediss:// URL
+func NewRedisStoreFromURL(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return NewRedisStore(redis.NewClient(opts)), nil
+}
+
+func (s *RedisStore) keys(id string) (meta, messages string) {
+	return s.prefix + id, s.prefix + id + ":messages"
+}
+
+// Create stores a new session
+func (s *RedisStore) Create(ctx context.Context, sess *Session, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	meta, _ := s.keys(sess.ID)
+
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, meta, "owner", sess.Owner, "created", sess.CreatedAt.UnixMilli(), "turns", 0)
+		p.PExpire(ctx, meta, ttl)
+		return nil
+	})
+	return err
+}
+
+// Get loads a session and its messages
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	meta, messages := s.keys(id)
+
+	var fields *redis.MapStringStringCmd
+	var ttl *redis.DurationCmd
+	var raw *redis.StringSliceCmd
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		fields = p.HGetAll(ctx, meta)
+		ttl = p.PTTL(ctx, meta)
+		raw = p.LRange(ctx, messages, 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields.Val()) == 0 || ttl.Val() <= 0 {
+		return nil, ErrNotFound
+	}
+
+	created, err := strconv.ParseInt(fields.Val()["created"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt scan session %s: %w", id, err)
+	}
+	turns, _ := strconv.Atoi(fields.Val()["turns"])
+	sess := &Session{
+		ID:        id,
+		Owner:     fields.Val()["owner"],
+		CreatedAt: time.UnixMilli(created),
+		ExpiresAt: time.Now().Add(ttl.Val()),
+		Turns:     turns,
+		Messages:  make([]Message, 0, len(raw.Val())),
+	}
+	for _, r := range raw.Val() {
+		var msg Message
+		if err := json.Unmarshal([]byte(r), &msg); err != nil {
+			return nil, fmt.Errorf("corrupt scan session %s: %w", id, err)
+		}
+		sess.Messages = append(sess.Messages, msg)
+	}
+	return sess, nil
+}
+
+// Append adds a message to a session
+func (s *RedisStore) Append(ctx context.Context, id string, msg Message, keep int, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	meta, messages := s.keys(id)
+
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ok, err := appendScript.Run(ctx, s.client, []string{meta, messages}, encoded, keep, ttl.Milliseconds()).Int()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if ok == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
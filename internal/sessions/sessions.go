@@ -0,0 +1,151 @@
+// Package sessions keeps conversation scan sessions: the recent messages of
+// a conversation, so each new message can be scanned together with the ones
+// before it. Sessions live in a Store: in memory for a single instance, or in
+// Redis so any API instance can serve a session's messages.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+
+	"stronghold/internal/config"
+)
+
+// Defaults for unset configuration
+const (
+	defaultTTL             = 30 * time.Minute
+	defaultMaxMessages     = 20
+	defaultMaxContextBytes = 32 * 1024
+)
+
+var (
+	// ErrNotFound is returned for unknown, expired and other accounts' sessions
+	ErrNotFound = errors.New("scan session not found or expired")
+	// ErrStoreFull is returned when the memory store holds too many sessions
+	ErrStoreFull = errors.New("too many open scan sessions")
+)
+
+// idPattern matches session IDs, so malformed IDs never reach the store
+var idPattern = regexp.MustCompile(`^ss_[0-9a-f]{32}$`)
+
+// Message is one scanned message of a conversation
+type Message struct {
+	Role string `json:"role"` // "user", "assistant" or "tool"
+	Text string `json:"text"`
+}
+
+// Session is a conversation's scan state
+type Session struct {
+	ID        string
+	Owner     string // Account that created the session; empty for x402 sessions
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Turns     int       // Messages scanned so far, including ones no longer kept
+	Messages  []Message // Most recent last
+}
+
+// Store keeps sessions until they expire
+type Store interface {
+	Create(ctx context.Context, s *Session, ttl time.Duration) error
+	// Get returns ErrNotFound for unknown or expired sessions
+	Get(ctx context.Context, id string) (*Session, error)
+	// Append adds a message, keeps only the last keep messages and restarts
+	// the session's TTL. It returns ErrNotFound for unknown or expired sessions.
+	Append(ctx context.Context, id string, msg Message, keep int, ttl time.Duration) error
+}
+
+// Manager creates sessions and records their messages
+type Manager struct {
+	store           Store
+	ttl             time.Duration
+	maxMessages     int
+	maxContextBytes int
+}
+
+// New creates a manager keeping sessions in store
+func New(cfg *config.ScanSessionsConfig, store Store) *Manager {
+	m := &Manager{
+		store:           store,
+		ttl:             cfg.TTL,
+		maxMessages:     cfg.MaxMessages,
+		maxContextBytes: cfg.MaxContextBytes,
+	}
+	if m.ttl <= 0 {
+		m.ttl = defaultTTL
+	}
+	if m.maxMessages <= 0 {
+		m.maxMessages = defaultMaxMessages
+	}
+	if m.maxContextBytes <= 0 {
+		m.maxContextBytes = defaultMaxContextBytes
+	}
+	return m
+}
+
+// TTL is how long a session lasts without messages
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+// MaxContextBytes bounds the earlier messages scanned with a new one
+func (m *Manager) MaxContextBytes() int {
+	return m.maxContextBytes
+}
+
+// Create opens a session for owner
+func (m *Manager) Create(ctx context.Context, owner string) (*Session, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		ID:        "ss_" + hex.EncodeToString(b),
+		Owner:     owner,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+	if err := m.store.Create(ctx, s, m.ttl); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns owner's session. Sessions opened by an account are only
+// visible to that account; sessions opened with x402 to anyone holding the ID.
+func (m *Manager) Get(ctx context.Context, id, owner string) (*Session, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	s, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Owner != "" && s.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return s, nil
+}
+
+// Append records a scanned message and restarts the session's TTL. Only the
+// end of a message longer than MaxContextBytes is kept, since that's all a
+// later scan's context can hold.
+func (m *Manager) Append(ctx context.Context, id string, msg Message) error {
+	if len(msg.Text) > m.maxContextBytes {
+		msg.Text = msg.Text[len(msg.Text)-m.maxContextBytes:]
+	}
+	return m.store.Append(ctx, id, msg, m.maxMessages, m.ttl)
+}
+
+// Close closes the store if it holds a connection
+func (m *Manager) Close() error {
+	if closer, ok := m.store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
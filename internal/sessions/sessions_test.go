@@ -0,0 +1,121 @@
+package sessions
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a memory store whose time is advanced by the test
+func fakeClock() (*MemoryStore, *time.Time) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestManager_RollingMessages(t *testing.T) {
+	store, now := fakeClock()
+	m := New(&config.ScanSessionsConfig{TTL: time.Minute, MaxMessages: 2, MaxContextBytes: 10}, store)
+	ctx := context.Background()
+
+	sess, err := m.Create(ctx, "")
+	require.NoError(t, err)
+	assert.Regexp(t, idPattern, sess.ID)
+
+	for _, text := range []string{"one", "two", strings.Repeat("x", 15) + "three"} {
+		require.NoError(t, m.Append(ctx, sess.ID, Message{Role: "user", Text: text}))
+	}
+	got, err := m.Get(ctx, sess.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Turns)
+	assert.Equal(t, []Message{{Role: "user", Text: "two"}, {Role: "user", Text: "xxxxxthree"}}, got.Messages)
+
+	// Every message restarts the TTL
+	*now = now.Add(50 * time.Second)
+	require.NoError(t, m.Append(ctx, sess.ID, Message{Role: "user", Text: "four"}))
+	*now = now.Add(50 * time.Second)
+	_, err = m.Get(ctx, sess.ID, "")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+	_, err = m.Get(ctx, sess.ID, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, m.Append(ctx, sess.ID, Message{Text: "late"}), ErrNotFound)
+}
+
+func TestManager_Owner(t *testing.T) {
+	m := New(&config.ScanSessionsConfig{}, NewMemoryStore())
+	ctx := context.Background()
+
+	owned, err := m.Create(ctx, "account-a")
+	require.NoError(t, err)
+	_, err = m.Get(ctx, owned.ID, "account-a")
+	assert.NoError(t, err)
+	_, err = m.Get(ctx, owned.ID, "account-b")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = m.Get(ctx, owned.ID, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// x402 sessions belong to whoever holds the ID
+	open, err := m.Create(ctx, "")
+	require.NoError(t, err)
+	_, err = m.Get(ctx, open.ID, "account-b")
+	assert.NoError(t, err)
+
+	_, err = m.Get(ctx, "ss_../../etc", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStore_SweepsExpired(t *testing.T) {
+	s, now := fakeClock()
+	ctx := context.Background()
+	require.NoError(t, s.Create(ctx, &Session{ID: "a"}, time.Minute))
+
+	*now = now.Add(2 * time.Minute)
+	require.NoError(t, s.Create(ctx, &Session{ID: "b"}, time.Minute))
+	assert.Len(t, s.sessions, 1)
+}
+
+// TestRedisStore runs against a real Redis when TEST_REDIS_URL is set
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	s, err := NewRedisStoreFromURL(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	m := New(&config.ScanSessionsConfig{TTL: time.Minute, MaxMessages: 2}, s)
+	ctx := context.Background()
+
+	sess, err := m.Create(ctx, "account-a")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		meta, messages := s.keys(sess.ID)
+		s.client.Del(ctx, meta, messages)
+	})
+
+	for _, text := range []string{"one", "two", "three"} {
+		require.NoError(t, m.Append(ctx, sess.ID, Message{Role: "tool", Text: text}))
+	}
+	got, err := m.Get(ctx, sess.ID, "account-a")
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Turns)
+	assert.Equal(t, []Message{{Role: "tool", Text: "two"}, {Role: "tool", Text: "three"}}, got.Messages)
+	assert.WithinDuration(t, sess.CreatedAt, got.CreatedAt, time.Millisecond)
+
+	_, err = m.Get(ctx, "ss_00000000000000000000000000000000", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, m.Append(ctx, "ss_00000000000000000000000000000000", Message{Text: "x"}), ErrNotFound)
+
+	_, err = NewRedisStoreFromURL("not a url")
+	assert.Error(t, err)
+}
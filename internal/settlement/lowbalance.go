@@ -0,0 +1,74 @@
+package settlement
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+)
+
+// LowBalanceStore queues low balance notifications
+type LowBalanceStore interface {
+	QueueLowBalanceNotifications(ctx context.Context, alertDays float64, now time.Time) (int, error)
+}
+
+// lowBalanceInterval is how often runways are checked. Spend is read from
+// hourly rollups, so checking more often rarely changes the estimate.
+const lowBalanceInterval = 15 * time.Minute
+
+// LowBalance notifies account webhooks once an account's balance will run
+// out within the alert threshold at its recent spend rate. An account is
+// notified again only after its runway recovers, such as after a deposit.
+// A nil LowBalance notifies nothing.
+type LowBalance struct {
+	alertDays float64
+	store     LowBalanceStore
+	now       func() time.Time
+}
+
+// NewLowBalance returns nil when low balance alerts are disabled
+func NewLowBalance(cfg *config.RunwayConfig, store LowBalanceStore) *LowBalance {
+	if cfg.AlertDays <= 0 || store == nil {
+		return nil
+	}
+	return &LowBalance{
+		alertDays: cfg.AlertDays,
+		store:     store,
+		now:       time.Now,
+	}
+}
+
+// Run checks account runways every lowBalanceInterval until ctx is done
+func (l *LowBalance) Run(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	ticker := time.NewTicker(lowBalanceInterval)
+	defer ticker.Stop()
+	for {
+		l.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check queues notifications for accounts whose runway has run low
+func (l *LowBalance) check(ctx context.Context) int {
+	queued, err := l.store.QueueLowBalanceNotifications(ctx, l.alertDays, l.now())
+	if err != nil {
+		slog.Warn("failed to check account runways", "error", err)
+		return 0
+	}
+	if queued > 0 {
+		slog.Info("queued low balance notifications", "accounts", queued, "alert_days", l.alertDays)
+	}
+	return queued
+}
+
+// compile-time check that *db.DB can back the low balance worker
+var _ LowBalanceStore = (*db.DB)(nil)
@@ -0,0 +1,44 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLowBalanceStore struct {
+	queued    int
+	err       error
+	alertDays float64
+}
+
+func (f *fakeLowBalanceStore) QueueLowBalanceNotifications(_ context.Context, alertDays float64, _ time.Time) (int, error) {
+	f.alertDays = alertDays
+	return f.queued, f.err
+}
+
+func TestNewLowBalance_Disabled(t *testing.T) {
+	assert.Nil(t, NewLowBalance(&config.RunwayConfig{AlertDays: 0}, &fakeLowBalanceStore{}))
+	assert.Nil(t, NewLowBalance(&config.RunwayConfig{AlertDays: 3}, nil))
+
+	var l *LowBalance
+	l.Run(context.Background())
+}
+
+func TestLowBalance_Check(t *testing.T) {
+	store := &fakeLowBalanceStore{queued: 2}
+	l := NewLowBalance(&config.RunwayConfig{AlertDays: 2.5}, store)
+	require.NotNil(t, l)
+
+	assert.Equal(t, 2, l.check(context.Background()))
+	assert.Equal(t, 2.5, store.alertDays)
+
+	store.err = errors.New("connection refused")
+	assert.Equal(t, 0, l.check(context.Background()))
+}
@@ -0,0 +1,97 @@
+package settlement
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/wallet"
+
+	"github.com/google/uuid"
+)
+
+// ReceivablesStore settles credit extended during facilitator outages
+type ReceivablesStore interface {
+	GetLapsedReceivables(ctx context.Context, before time.Time, limit int) ([]*db.Receivable, error)
+	SettleReceivableFromBalance(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+// receivablesBatchSize bounds the receivables settled per pass
+const receivablesBatchSize = 100
+
+// Receivables debits outage credit from account balances once the payment
+// it was extended on can no longer settle. Payments that do settle when the
+// facilitator recovers close their receivable themselves. A nil Receivables
+// settles nothing.
+type Receivables struct {
+	store    ReceivablesStore
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewReceivables returns nil when outage credit is disabled
+func NewReceivables(cfg *config.OutageCreditConfig, store ReceivablesStore) *Receivables {
+	if !cfg.Enabled || store == nil {
+		return nil
+	}
+	return &Receivables{
+		store:    store,
+		interval: time.Minute,
+		now:      time.Now,
+	}
+}
+
+// Run settles lapsed receivables every interval until ctx is done
+func (r *Receivables) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.settleLapsed(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// settleLapsed debits one batch of lapsed receivables and returns how many
+// were settled. Receivables the balance can't cover stay open, counting
+// against the account's credit until it tops up.
+func (r *Receivables) settleLapsed(ctx context.Context) int {
+	// Allow a validity window of clock skew before giving up on a payment,
+	// so one the facilitator could still settle isn't also debited
+	before := r.now().Add(-wallet.PaymentValidity)
+	receivables, err := r.store.GetLapsedReceivables(ctx, before, receivablesBatchSize)
+	if err != nil {
+		slog.Warn("failed to get lapsed receivables", "error", err)
+		return 0
+	}
+
+	settled := 0
+	for _, rec := range receivables {
+		ok, err := r.store.SettleReceivableFromBalance(ctx, rec.ID)
+		if err != nil {
+			slog.Warn("failed to settle receivable from balance", "receivable_id", rec.ID, "error", err)
+			continue
+		}
+		if !ok {
+			slog.Warn("receivable left open, balance too low", "receivable_id", rec.ID,
+				"account_id", rec.AccountID, "amount", rec.AmountUSDC)
+			continue
+		}
+		settled++
+	}
+	if settled > 0 {
+		slog.Info("settled outage credit from balances", "settled", settled)
+	}
+	return settled
+}
+
+// compile-time check that *db.DB can back the receivables worker
+var _ ReceivablesStore = (*db.DB)(nil)
@@ -0,0 +1,61 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/wallet"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReceivablesStore struct {
+	lapsed  []*db.Receivable
+	covered map[uuid.UUID]bool
+	failing map[uuid.UUID]bool
+	before  time.Time
+}
+
+func (f *fakeReceivablesStore) GetLapsedReceivables(_ context.Context, before time.Time, limit int) ([]*db.Receivable, error) {
+	f.before = before
+	return f.lapsed[:min(limit, len(f.lapsed))], nil
+}
+
+func (f *fakeReceivablesStore) SettleReceivableFromBalance(_ context.Context, id uuid.UUID) (bool, error) {
+	if f.failing[id] {
+		return false, errors.New("connection refused")
+	}
+	return f.covered[id], nil
+}
+
+func TestNewReceivables_Disabled(t *testing.T) {
+	assert.Nil(t, NewReceivables(&config.OutageCreditConfig{}, &fakeReceivablesStore{}))
+	assert.Nil(t, NewReceivables(&config.OutageCreditConfig{Enabled: true}, nil))
+
+	var r *Receivables
+	r.Run(context.Background())
+}
+
+func TestReceivables_SettlesCoveredBalances(t *testing.T) {
+	covered := &db.Receivable{ID: uuid.New()}
+	short := &db.Receivable{ID: uuid.New()}
+	broken := &db.Receivable{ID: uuid.New()}
+	store := &fakeReceivablesStore{
+		lapsed:  []*db.Receivable{covered, short, broken},
+		covered: map[uuid.UUID]bool{covered.ID: true},
+		failing: map[uuid.UUID]bool{broken.ID: true},
+	}
+	r := NewReceivables(&config.OutageCreditConfig{Enabled: true}, store)
+	require.NotNil(t, r)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	assert.Equal(t, 1, r.settleLapsed(context.Background()))
+	assert.Equal(t, now.Add(-wallet.PaymentValidity), store.before, "waits out a validity window past expiry")
+}
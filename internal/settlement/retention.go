@@ -0,0 +1,124 @@
+package settlement
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/wallet"
+)
+
+// RetentionStore prunes payment transactions
+type RetentionStore interface {
+	PrunePayments(ctx context.Context, before time.Time, archive bool, limit int) (int64, error)
+}
+
+// RetentionRun describes the last pruning pass
+type RetentionRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Cutoff     time.Time `json:"cutoff"`
+	Pruned     int64     `json:"pruned"`
+	Archived   bool      `json:"archived"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Retention prunes completed and expired payments past the retention period
+// once a day. Payment nonces only need to be kept while the signed
+// authorization is valid, since the facilitator and the x402 middleware both
+// refuse payments past it. A nil Retention prunes nothing.
+type Retention struct {
+	days      int
+	archive   bool
+	batchSize int
+	store     RetentionStore
+	now       func() time.Time
+
+	mu      sync.Mutex
+	lastRun *RetentionRun
+}
+
+// NewRetention returns nil when retention is disabled
+func NewRetention(cfg *config.PaymentRetentionConfig, store RetentionStore) *Retention {
+	if cfg.RetentionDays <= 0 || store == nil {
+		return nil
+	}
+	return &Retention{
+		days:      cfg.RetentionDays,
+		archive:   cfg.Archive,
+		batchSize: max(cfg.BatchSize, 1),
+		store:     store,
+		now:       time.Now,
+	}
+}
+
+// Run prunes payments past the retention period once a day until ctx is done
+func (r *Retention) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		run := r.prune(ctx)
+		if run.Error != "" {
+			slog.Warn("failed to prune payments", "pruned", run.Pruned, "error", run.Error)
+		} else if run.Pruned > 0 {
+			slog.Info("pruned payments", "pruned", run.Pruned, "archived", run.Archived, "cutoff", run.Cutoff)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune removes payments in batches until one comes back short
+func (r *Retention) prune(ctx context.Context) RetentionRun {
+	now := r.now()
+	cutoff := now.AddDate(0, 0, -r.days)
+	// Never prune a nonce that could still be presented
+	if validFrom := now.Add(-wallet.PaymentValidity); cutoff.After(validFrom) {
+		cutoff = validFrom
+	}
+
+	run := RetentionRun{StartedAt: now, Cutoff: cutoff, Archived: r.archive}
+	for ctx.Err() == nil {
+		n, err := r.store.PrunePayments(ctx, cutoff, r.archive, r.batchSize)
+		if err != nil {
+			run.Error = err.Error()
+			break
+		}
+		run.Pruned += n
+		if n < int64(r.batchSize) {
+			break
+		}
+	}
+	run.DurationMs = r.now().Sub(now).Milliseconds()
+
+	r.mu.Lock()
+	r.lastRun = &run
+	r.mu.Unlock()
+	return run
+}
+
+// LastRun returns the last pruning pass, or nil before the first
+func (r *Retention) LastRun() *RetentionRun {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastRun == nil {
+		return nil
+	}
+	run := *r.lastRun
+	return &run
+}
+
+// compile-time check that *db.DB can back the retention worker
+var _ RetentionStore = (*db.DB)(nil)
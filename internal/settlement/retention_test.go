@@ -0,0 +1,74 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/wallet"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRetentionStore struct {
+	batches []int64
+	err     error
+	cutoffs []time.Time
+}
+
+func (f *fakeRetentionStore) PrunePayments(_ context.Context, before time.Time, _ bool, _ int) (int64, error) {
+	f.cutoffs = append(f.cutoffs, before)
+	if len(f.batches) == 0 {
+		return 0, f.err
+	}
+	n := f.batches[0]
+	f.batches = f.batches[1:]
+	return n, nil
+}
+
+func TestNewRetention_Disabled(t *testing.T) {
+	assert.Nil(t, NewRetention(&config.PaymentRetentionConfig{RetentionDays: 0}, &fakeRetentionStore{}))
+	assert.Nil(t, NewRetention(&config.PaymentRetentionConfig{RetentionDays: 30}, nil))
+
+	var r *Retention
+	r.Run(context.Background())
+	assert.Nil(t, r.LastRun())
+}
+
+func TestRetention_PrunesInBatches(t *testing.T) {
+	store := &fakeRetentionStore{batches: []int64{10, 10, 3}}
+	r := NewRetention(&config.PaymentRetentionConfig{RetentionDays: 30, Archive: true, BatchSize: 10}, store)
+	require.NotNil(t, r)
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	run := r.prune(context.Background())
+	assert.Equal(t, int64(23), run.Pruned)
+	assert.True(t, run.Archived)
+	assert.Empty(t, run.Error)
+	assert.Equal(t, now.AddDate(0, 0, -30), run.Cutoff)
+	assert.Len(t, store.cutoffs, 3)
+	assert.Equal(t, &run, r.LastRun())
+}
+
+func TestRetention_StopsOnError(t *testing.T) {
+	store := &fakeRetentionStore{batches: []int64{5}, err: errors.New("connection refused")}
+	r := NewRetention(&config.PaymentRetentionConfig{RetentionDays: 1, BatchSize: 5}, store)
+
+	run := r.prune(context.Background())
+	assert.Equal(t, int64(5), run.Pruned)
+	assert.Equal(t, "connection refused", run.Error)
+}
+
+func TestRetention_CutoffOutsideValidityWindow(t *testing.T) {
+	store := &fakeRetentionStore{}
+	r := NewRetention(&config.PaymentRetentionConfig{RetentionDays: 1, BatchSize: 5}, store)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	run := r.prune(context.Background())
+	assert.False(t, run.Cutoff.After(now.Add(-wallet.PaymentValidity)))
+}
@@ -0,0 +1,170 @@
+package settlement
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stronghold/internal/config"
+)
+
+// HeaderWebhookSignatureEd25519 carries the Ed25519 signature of a webhook
+// delivery, verifiable with the keys published at /v1/webhooks/signing-keys
+const HeaderWebhookSignatureEd25519 = "Stronghold-Signature-Ed25519"
+
+// WebhookTolerance is how far a delivery's signed timestamp may be from the
+// receiver's clock before it should be rejected as a replay
+const WebhookTolerance = 5 * time.Minute
+
+// Webhook signature verification errors
+var (
+	ErrWebhookSignatureMalformed = errors.New("malformed webhook signature")
+	ErrWebhookSignatureExpired   = errors.New("webhook timestamp outside tolerance")
+	ErrWebhookKeyUnknown         = errors.New("webhook signed with an unknown key")
+	ErrWebhookSignatureInvalid   = errors.New("webhook signature does not verify")
+)
+
+// Signing key statuses
+const (
+	KeyStatusActive  = "active"
+	KeyStatusRetired = "retired"
+)
+
+// WebhookSigningKey is a published webhook verification key, in JWK form
+type WebhookSigningKey struct {
+	KeyType string `json:"kty"` // always "OKP"
+	Curve   string `json:"crv"` // always "Ed25519"
+	KeyID   string `json:"kid"`
+	X       string `json:"x"`   // base64url public key
+	Use     string `json:"use"` // always "sig"
+	Status  string `json:"status"`
+}
+
+// WebhookKeys signs webhook deliveries with the active Ed25519 key and
+// publishes it with the retired keys receivers may still see signatures from
+type WebhookKeys struct {
+	key     ed25519.PrivateKey
+	keyID   string
+	retired []ed25519.PublicKey
+}
+
+// NewWebhookKeys loads the webhook signing keys, returning nil when no
+// signing key is configured
+func NewWebhookKeys(cfg *config.WebhookSigningConfig) (*WebhookKeys, error) {
+	if cfg == nil || cfg.SigningKey == "" {
+		return nil, nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.SigningKey))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("webhook signing key must be %d base64-encoded bytes", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+
+	k := &WebhookKeys{key: key, keyID: webhookKeyID(key.Public().(ed25519.PublicKey))}
+	for _, s := range cfg.RetiredKeys {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("retired webhook key %q must be %d base64-encoded bytes", s, ed25519.PublicKeySize)
+		}
+		k.retired = append(k.retired, ed25519.PublicKey(raw))
+	}
+	return k, nil
+}
+
+// webhookKeyID identifies a public key by the first 8 bytes of its SHA-256
+func webhookKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Published returns the active key followed by the retired ones
+func (k *WebhookKeys) Published() []WebhookSigningKey {
+	if k == nil {
+		return []WebhookSigningKey{}
+	}
+	keys := []WebhookSigningKey{publishedKey(k.key.Public().(ed25519.PublicKey), KeyStatusActive)}
+	for _, pub := range k.retired {
+		keys = append(keys, publishedKey(pub, KeyStatusRetired))
+	}
+	return keys
+}
+
+func publishedKey(pub ed25519.PublicKey, status string) WebhookSigningKey {
+	return WebhookSigningKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		KeyID:   webhookKeyID(pub),
+		X:       base64.RawURLEncoding.EncodeToString(pub),
+		Use:     "sig",
+		Status:  status,
+	}
+}
+
+// Sign returns the Stronghold-Signature-Ed25519 header value for a delivery
+// sent at timestamp: "t=<unix seconds>,kid=<key id>,sig=<base64url Ed25519
+// signature of "<t>.<delivery id>.<body>">". Signing the delivery ID lets
+// receivers drop replays by remembering the IDs seen within the tolerance.
+func (k *WebhookKeys) Sign(deliveryID string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	sig := ed25519.Sign(k.key, webhookMessage(t, deliveryID, body))
+	return "t=" + t + ",kid=" + k.keyID + ",sig=" + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// VerifyWebhookSignature checks a Stronghold-Signature-Ed25519 header against
+// the published keys, rejecting timestamps more than WebhookTolerance from now
+func VerifyWebhookSignature(keys []WebhookSigningKey, header, deliveryID string, body []byte, now time.Time) error {
+	var t, kid, sig string
+	for part := range strings.SplitSeq(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			t = value
+		case "kid":
+			kid = value
+		case "sig":
+			sig = value
+		}
+	}
+	ts, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || kid == "" || sig == "" {
+		return ErrWebhookSignatureMalformed
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > WebhookTolerance || skew < -WebhookTolerance {
+		return ErrWebhookSignatureExpired
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return ErrWebhookSignatureMalformed
+	}
+
+	for _, key := range keys {
+		if key.KeyID != kid {
+			continue
+		}
+		pub, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return ErrWebhookKeyUnknown
+		}
+		if !ed25519.Verify(ed25519.PublicKey(pub), webhookMessage(t, deliveryID, body), raw) {
+			return ErrWebhookSignatureInvalid
+		}
+		return nil
+	}
+	return ErrWebhookKeyUnknown
+}
+
+// webhookMessage is the byte string a webhook signature covers
+func webhookMessage(timestamp, deliveryID string, body []byte) []byte {
+	msg := make([]byte, 0, len(timestamp)+len(deliveryID)+len(body)+2)
+	msg = append(msg, timestamp...)
+	msg = append(msg, '.')
+	msg = append(msg, deliveryID...)
+	msg = append(msg, '.')
+	return append(msg, body...)
+}
@@ -0,0 +1,68 @@
+package settlement
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSigningSeed(b byte) string {
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = b
+	return base64.StdEncoding.EncodeToString(seed)
+}
+
+func TestNewWebhookKeys(t *testing.T) {
+	keys, err := NewWebhookKeys(&config.WebhookSigningConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, keys, "no signing key configured")
+	assert.Empty(t, keys.Published())
+
+	_, err = NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: "c2hvcnQ="})
+	assert.Error(t, err)
+
+	old, err := NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: testSigningSeed(1)})
+	require.NoError(t, err)
+	oldPub := base64.StdEncoding.EncodeToString(old.key.Public().(ed25519.PublicKey))
+
+	keys, err = NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: testSigningSeed(2), RetiredKeys: []string{oldPub}})
+	require.NoError(t, err)
+	published := keys.Published()
+	require.Len(t, published, 2)
+	assert.Equal(t, KeyStatusActive, published[0].Status)
+	assert.Equal(t, KeyStatusRetired, published[1].Status)
+	assert.Equal(t, old.Published()[0].KeyID, published[1].KeyID)
+	assert.NotEqual(t, published[0].KeyID, published[1].KeyID)
+	assert.Equal(t, "OKP", published[0].KeyType)
+	assert.Equal(t, "Ed25519", published[0].Curve)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	old, err := NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: testSigningSeed(1)})
+	require.NoError(t, err)
+	oldPub := base64.StdEncoding.EncodeToString(old.key.Public().(ed25519.PublicKey))
+	keys, err := NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: testSigningSeed(2), RetiredKeys: []string{oldPub}})
+	require.NoError(t, err)
+	published := keys.Published()
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"payment.settled"}`)
+	header := keys.Sign("delivery-1", now.Unix(), body)
+
+	assert.NoError(t, VerifyWebhookSignature(published, header, "delivery-1", body, now.Add(time.Minute)))
+	assert.NoError(t, VerifyWebhookSignature(published, old.Sign("delivery-1", now.Unix(), body), "delivery-1", body, now),
+		"signatures from a retired key still verify")
+
+	assert.ErrorIs(t, VerifyWebhookSignature(published, header, "delivery-2", body, now), ErrWebhookSignatureInvalid,
+		"the delivery ID is signed")
+	assert.ErrorIs(t, VerifyWebhookSignature(published, header, "delivery-1", []byte(`{}`), now), ErrWebhookSignatureInvalid)
+	assert.ErrorIs(t, VerifyWebhookSignature(published, header, "delivery-1", body, now.Add(WebhookTolerance+time.Second)), ErrWebhookSignatureExpired)
+	assert.ErrorIs(t, VerifyWebhookSignature(published[1:], header, "delivery-1", body, now), ErrWebhookKeyUnknown)
+	assert.ErrorIs(t, VerifyWebhookSignature(published, "t=abc", "delivery-1", body, now), ErrWebhookSignatureMalformed)
+}
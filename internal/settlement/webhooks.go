@@ -0,0 +1,275 @@
+package settlement
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"stronghold/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Settlement webhook request headers
+const (
+	HeaderWebhookEvent     = "Stronghold-Event"
+	HeaderWebhookDelivery  = "Stronghold-Delivery"
+	HeaderWebhookSignature = "Stronghold-Signature"
+)
+
+// errPrivateAddress is returned when a webhook URL resolves to an address
+// that isn't publicly routable
+var errPrivateAddress = errors.New("webhook address is not publicly routable")
+
+// WebhookConfig holds configuration for account webhook delivery
+type WebhookConfig struct {
+	// PollInterval is how often to check for due deliveries
+	PollInterval time.Duration
+	// BatchSize is the maximum number of deliveries sent per poll
+	BatchSize int
+	// Timeout bounds a single delivery request
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before giving up
+	MaxAttempts int
+}
+
+// DefaultWebhookConfig returns sensible defaults for webhook delivery
+func DefaultWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		PollInterval: 5 * time.Second,
+		BatchSize:    50,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+	}
+}
+
+// WebhookStore queues and records account webhook deliveries
+type WebhookStore interface {
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*db.WebhookDelivery, error)
+	MarkWebhookDelivered(ctx context.Context, id uuid.UUID) error
+	FailWebhookDelivery(ctx context.Context, id uuid.UUID, errorMsg string, retryAt *time.Time) error
+}
+
+// Webhooks sends queued account events (settlements, low balance and device
+// approval requests) to account webhooks, retrying failures with exponential
+// backoff
+type Webhooks struct {
+	store  WebhookStore
+	config *WebhookConfig
+	client *http.Client
+	keys   *WebhookKeys
+	now    func() time.Time
+}
+
+// NewWebhooks creates an account webhook sender
+func NewWebhooks(store WebhookStore, cfg *WebhookConfig) *Webhooks {
+	if cfg == nil {
+		cfg = DefaultWebhookConfig()
+	}
+	return &Webhooks{
+		store:  store,
+		config: cfg,
+		client: newWebhookClient(cfg.Timeout, false),
+		now:    time.Now,
+	}
+}
+
+// SetSigningKeys adds an Ed25519 signature to every delivery, alongside the
+// per-webhook HMAC signature
+func (w *Webhooks) SetSigningKeys(keys *WebhookKeys) {
+	w.keys = keys
+}
+
+// SigningKeys returns the published keys that verify delivery signatures
+func (w *Webhooks) SigningKeys() []WebhookSigningKey {
+	return w.keys.Published()
+}
+
+// newWebhookClient returns a client that, unless allowPrivate is set, refuses
+// to connect to loopback, private and link-local addresses, so customer URLs
+// can't reach internal services
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		// Redirects could lead anywhere; the customer's endpoint must answer directly
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run sends due deliveries every PollInterval until ctx is done
+func (w *Webhooks) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		w.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue claims and sends one batch of due deliveries
+func (w *Webhooks) deliverDue(ctx context.Context) {
+	// The lease outlasts a request, so a delivery in flight isn't claimed again
+	deliveries, err := w.store.ClaimWebhookDeliveries(ctx, w.config.BatchSize, 2*w.config.Timeout)
+	if err != nil {
+		slog.Warn("failed to claim webhook deliveries", "error", err)
+		return
+	}
+
+	for _, d := range deliveries {
+		if err := w.send(ctx, d); err != nil {
+			var retryAt *time.Time
+			if d.Attempts < w.config.MaxAttempts {
+				next := w.now().Add(webhookBackoff(d.Attempts))
+				retryAt = &next
+			}
+			slog.Warn("webhook delivery failed", "delivery_id", d.ID, "account_id", d.AccountID,
+				"attempt", d.Attempts, "retry", retryAt != nil, "error", err)
+			if err := w.store.FailWebhookDelivery(ctx, d.ID, err.Error(), retryAt); err != nil {
+				slog.Warn("failed to record webhook failure", "delivery_id", d.ID, "error", err)
+			}
+			continue
+		}
+		if err := w.store.MarkWebhookDelivered(ctx, d.ID); err != nil {
+			slog.Warn("failed to mark webhook delivered", "delivery_id", d.ID, "error", err)
+		}
+	}
+}
+
+// send posts a delivery and succeeds on any 2xx response
+func (w *Webhooks) send(ctx context.Context, d *db.WebhookDelivery) error {
+	event := d.Event
+	if event == "" {
+		event = db.SettlementEventType
+	}
+	_, err := w.post(ctx, d.URL, d.Secret, event, d.ID.String(), d.Payload)
+	return err
+}
+
+// post signs and sends one webhook request, returning the response status
+// code when the receiver answered
+func (w *Webhooks) post(ctx context.Context, url, secret, event, deliveryID string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	now := w.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, event)
+	req.Header.Set(HeaderWebhookDelivery, deliveryID)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(secret, now, payload))
+	if w.keys != nil {
+		req.Header.Set(HeaderWebhookSignatureEd25519, w.keys.Sign(deliveryID, now, payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// TestEventType is the event name of test deliveries
+const TestEventType = "webhook.test"
+
+// TestEvent is the body of a test delivery
+type TestEvent struct {
+	Event     string    `json:"event"` // always "webhook.test"
+	AccountID uuid.UUID `json:"account_id"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// TestDelivery reports how a receiver answered a test delivery
+type TestDelivery struct {
+	DeliveryID string `json:"delivery_id"`
+	Delivered  bool   `json:"delivered"`
+	StatusCode int    `json:"status_code,omitempty"` // omitted when the receiver couldn't be reached
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SendTest immediately sends a signed test event to a webhook, signed the
+// same way as settlement deliveries, so receivers can check their
+// verification. It isn't queued or retried.
+func (w *Webhooks) SendTest(ctx context.Context, webhook *db.SettlementWebhook) (*TestDelivery, error) {
+	payload, err := json.Marshal(TestEvent{
+		Event:     TestEventType,
+		AccountID: webhook.AccountID,
+		SentAt:    w.now().UTC(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode test event: %w", err)
+	}
+
+	result := &TestDelivery{DeliveryID: uuid.NewString()}
+	start := time.Now()
+	result.StatusCode, err = w.post(ctx, webhook.URL, webhook.Secret, TestEventType, result.DeliveryID, payload)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Delivered = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// SignWebhook returns the Stronghold-Signature header value for a body sent
+// at timestamp: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before retrying after the given number of
+// attempts: 30s doubling per attempt, capped at 6 hours
+func webhookBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < 6*time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, 6*time.Hour)
+}
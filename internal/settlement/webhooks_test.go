@@ -0,0 +1,177 @@
+package settlement
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookStore struct {
+	mu        sync.Mutex
+	due       []*db.WebhookDelivery
+	delivered []uuid.UUID
+	failed    map[uuid.UUID]string
+	retryAt   map[uuid.UUID]*time.Time
+}
+
+func (f *fakeWebhookStore) ClaimWebhookDeliveries(_ context.Context, limit int, _ time.Duration) ([]*db.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := min(limit, len(f.due))
+	claimed := f.due[:n]
+	f.due = f.due[n:]
+	return claimed, nil
+}
+
+func (f *fakeWebhookStore) MarkWebhookDelivered(_ context.Context, id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeWebhookStore) FailWebhookDelivery(_ context.Context, id uuid.UUID, errorMsg string, retryAt *time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failed == nil {
+		f.failed = make(map[uuid.UUID]string)
+		f.retryAt = make(map[uuid.UUID]*time.Time)
+	}
+	f.failed[id] = errorMsg
+	f.retryAt[id] = retryAt
+	return nil
+}
+
+func newTestWebhooks(store WebhookStore) *Webhooks {
+	w := NewWebhooks(store, nil)
+	w.client = newWebhookClient(time.Second, true)
+	return w
+}
+
+func TestWebhooks_DeliversSignedPayload(t *testing.T) {
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := &db.WebhookDelivery{
+		ID:       uuid.New(),
+		URL:      server.URL,
+		Secret:   "whsec_test",
+		Payload:  []byte(`{"event":"payment.settled","tx_hash":"0xabc"}`),
+		Attempts: 1,
+	}
+	store := &fakeWebhookStore{due: []*db.WebhookDelivery{d}}
+	newTestWebhooks(store).deliverDue(context.Background())
+
+	assert.Equal(t, []uuid.UUID{d.ID}, store.delivered)
+	assert.Empty(t, store.failed)
+	assert.Equal(t, d.Payload, gotBody)
+	assert.Equal(t, "payment.settled", gotHeaders.Get(HeaderWebhookEvent))
+	assert.Equal(t, d.ID.String(), gotHeaders.Get(HeaderWebhookDelivery))
+
+	sig := gotHeaders.Get(HeaderWebhookSignature)
+	require.True(t, strings.HasPrefix(sig, "t="))
+	ts, err := strconv.ParseInt(strings.SplitN(strings.TrimPrefix(sig, "t="), ",", 2)[0], 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, SignWebhook("whsec_test", ts, d.Payload), sig)
+	assert.NotEqual(t, SignWebhook("whsec_other", ts, d.Payload), sig)
+}
+
+func TestWebhooks_RetriesFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	retry := &db.WebhookDelivery{ID: uuid.New(), URL: server.URL, Payload: []byte(`{}`), Attempts: 2}
+	last := &db.WebhookDelivery{ID: uuid.New(), URL: server.URL, Payload: []byte(`{}`), Attempts: DefaultWebhookConfig().MaxAttempts}
+	store := &fakeWebhookStore{due: []*db.WebhookDelivery{retry, last}}
+	w := newTestWebhooks(store)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	w.deliverDue(context.Background())
+
+	assert.Empty(t, store.delivered)
+	assert.Contains(t, store.failed[retry.ID], "500")
+	require.NotNil(t, store.retryAt[retry.ID])
+	assert.Equal(t, now.Add(time.Minute), *store.retryAt[retry.ID])
+	assert.Contains(t, store.failed, last.ID)
+	assert.Nil(t, store.retryAt[last.ID], "gives up after MaxAttempts")
+}
+
+func TestWebhooks_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address should not be reached")
+	}))
+	defer server.Close()
+
+	d := &db.WebhookDelivery{ID: uuid.New(), URL: server.URL, Payload: []byte(`{}`), Attempts: 1}
+	store := &fakeWebhookStore{due: []*db.WebhookDelivery{d}}
+	NewWebhooks(store, nil).deliverDue(context.Background())
+
+	assert.Empty(t, store.delivered)
+	assert.Contains(t, store.failed[d.ID], errPrivateAddress.Error())
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhookBackoff(1))
+	assert.Equal(t, time.Minute, webhookBackoff(2))
+	assert.Equal(t, 4*time.Minute, webhookBackoff(4))
+	assert.Equal(t, 6*time.Hour, webhookBackoff(20))
+}
+
+func TestWebhooks_SendTest(t *testing.T) {
+	var gotBody []byte
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeaders = r.Header.Clone()
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	keys, err := NewWebhookKeys(&config.WebhookSigningConfig{SigningKey: testSigningSeed(1)})
+	require.NoError(t, err)
+	w := newTestWebhooks(&fakeWebhookStore{})
+	w.SetSigningKeys(keys)
+
+	webhook := &db.SettlementWebhook{AccountID: uuid.New(), URL: server.URL, Secret: "whsec_test"}
+	result, err := w.SendTest(context.Background(), webhook)
+	require.NoError(t, err)
+	assert.False(t, result.Delivered)
+	assert.Equal(t, http.StatusTeapot, result.StatusCode)
+	assert.Contains(t, result.Error, "418")
+
+	assert.Equal(t, TestEventType, gotHeaders.Get(HeaderWebhookEvent))
+	assert.Equal(t, result.DeliveryID, gotHeaders.Get(HeaderWebhookDelivery))
+	assert.Contains(t, string(gotBody), webhook.AccountID.String())
+	assert.NoError(t, VerifyWebhookSignature(keys.Published(), gotHeaders.Get(HeaderWebhookSignatureEd25519),
+		result.DeliveryID, gotBody, time.Now()))
+	assert.True(t, strings.HasPrefix(gotHeaders.Get(HeaderWebhookSignature), "t="))
+
+	webhook.URL = "http://127.0.0.1:1"
+	result, err = NewWebhooks(&fakeWebhookStore{}, nil).SendTest(context.Background(), webhook)
+	require.NoError(t, err)
+	assert.False(t, result.Delivered)
+	assert.Zero(t, result.StatusCode)
+	assert.Contains(t, result.Error, errPrivateAddress.Error())
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"stronghold/internal/chaos"
 	"stronghold/internal/config"
 	"stronghold/internal/db"
 	"stronghold/internal/wallet"
@@ -61,7 +62,8 @@ func NewWorker(database *db.DB, x402Config *config.X402Config, cfg *WorkerConfig
 		x402Config: x402Config,
 		config:     cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: chaos.Transport(chaos.Facilitator, nil),
 		},
 		stopCh: make(chan struct{}),
 		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
@@ -0,0 +1,144 @@
+// Package snapshots records each account's on-platform and on-chain balances
+// once per UTC day, so the dashboard can chart balance history.
+package snapshots
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+	"stronghold/internal/wallet"
+
+	"github.com/google/uuid"
+)
+
+// Networks whose wallet balances are snapshot, matching the balances shown
+// on the dashboard
+const (
+	evmNetwork    = "base"
+	solanaNetwork = "solana"
+)
+
+// walletQueryTimeout bounds each on-chain balance lookup
+const walletQueryTimeout = 15 * time.Second
+
+// Store records balance snapshots
+type Store interface {
+	SnapshotBalances(ctx context.Context, day time.Time) (int64, error)
+	PendingOnchainSnapshots(ctx context.Context, day time.Time, limit int) ([]*db.OnchainSnapshot, error)
+	RecordOnchainBalances(ctx context.Context, accountID uuid.UUID, day time.Time, evm, solana *usdc.MicroUSDC, complete bool) error
+}
+
+// balanceQuery looks up a wallet's USDC balance on a network
+type balanceQuery func(ctx context.Context, address, network string) (float64, error)
+
+// Worker snapshots every active account's balance each interval, so the
+// day's last snapshot holds its closing balance. Wallet balances are read
+// on-chain once per day, a batch of accounts per pass; wallets that fail are
+// retried on the next pass. A nil Worker snapshots nothing.
+type Worker struct {
+	interval     time.Duration
+	onchainBatch int
+	store        Store
+	queryEVM     balanceQuery
+	querySolana  balanceQuery
+	now          func() time.Time
+}
+
+// New returns nil when the worker is disabled
+func New(cfg *config.BalanceSnapshotConfig, store Store) *Worker {
+	if cfg.Interval <= 0 || store == nil {
+		return nil
+	}
+	return &Worker{
+		interval:     cfg.Interval,
+		onchainBatch: cfg.OnchainBatch,
+		store:        store,
+		queryEVM:     wallet.QueryEVMBalance,
+		querySolana:  wallet.QuerySolanaBalance,
+		now:          time.Now,
+	}
+}
+
+// Run snapshots balances every interval until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.snapshot(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshot records the current day's balances, then reads on-chain the
+// wallets of up to a batch of accounts not yet snapshot today. Returns how
+// many accounts' wallets were fully recorded.
+func (w *Worker) snapshot(ctx context.Context) int {
+	day := w.now().UTC().Truncate(24 * time.Hour)
+
+	accounts, err := w.store.SnapshotBalances(ctx, day)
+	if err != nil {
+		slog.Warn("failed to snapshot balances", "day", day.Format("2006-01-02"), "error", err)
+		return 0
+	}
+	slog.Debug("snapshot balances", "day", day.Format("2006-01-02"), "accounts", accounts)
+	if w.onchainBatch <= 0 {
+		return 0
+	}
+
+	pending, err := w.store.PendingOnchainSnapshots(ctx, day, w.onchainBatch)
+	if err != nil {
+		slog.Warn("failed to find wallets to snapshot", "error", err)
+		return 0
+	}
+	recorded := 0
+	for _, p := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		evm, evmOK := w.query(ctx, w.queryEVM, p.EVMWalletAddress, evmNetwork)
+		solana, solanaOK := w.query(ctx, w.querySolana, p.SolanaWalletAddress, solanaNetwork)
+		complete := evmOK && solanaOK
+		if err := w.store.RecordOnchainBalances(ctx, p.AccountID, day, evm, solana, complete); err != nil {
+			slog.Warn("failed to record wallet balances", "account_id", p.AccountID, "error", err)
+			continue
+		}
+		if complete {
+			recorded++
+		}
+	}
+	if recorded > 0 {
+		slog.Info("snapshot wallet balances", "accounts", recorded)
+	}
+	return recorded
+}
+
+// query reads a wallet's balance, reporting ok for wallets that are not
+// configured. The balance is nil unless it was read.
+func (w *Worker) query(ctx context.Context, query balanceQuery, address *string, network string) (*usdc.MicroUSDC, bool) {
+	if address == nil {
+		return nil, true
+	}
+	ctx, cancel := context.WithTimeout(ctx, walletQueryTimeout)
+	defer cancel()
+	balance, err := query(ctx, *address, network)
+	if err != nil {
+		slog.Warn("failed to read wallet balance", "network", network, "address", *address, "error", err)
+		return nil, false
+	}
+	micro := usdc.FromFloat(balance)
+	return &micro, true
+}
+
+// compile-time check that *db.DB can back the snapshot worker
+var _ Store = (*db.DB)(nil)
@@ -0,0 +1,100 @@
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stronghold/internal/config"
+	"stronghold/internal/db"
+	"stronghold/internal/usdc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorded struct {
+	evm, solana *usdc.MicroUSDC
+	complete    bool
+}
+
+type fakeStore struct {
+	day      time.Time
+	failOn   error
+	limit    int
+	pending  []*db.OnchainSnapshot
+	recorded map[uuid.UUID]recorded
+}
+
+func (f *fakeStore) SnapshotBalances(_ context.Context, day time.Time) (int64, error) {
+	if f.failOn != nil {
+		return 0, f.failOn
+	}
+	f.day = day
+	return 3, nil
+}
+
+func (f *fakeStore) PendingOnchainSnapshots(_ context.Context, day time.Time, limit int) ([]*db.OnchainSnapshot, error) {
+	f.limit = limit
+	return f.pending, nil
+}
+
+func (f *fakeStore) RecordOnchainBalances(_ context.Context, accountID uuid.UUID, day time.Time, evm, solana *usdc.MicroUSDC, complete bool) error {
+	if f.recorded == nil {
+		f.recorded = map[uuid.UUID]recorded{}
+	}
+	f.recorded[accountID] = recorded{evm: evm, solana: solana, complete: complete}
+	return nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestNew_Disabled(t *testing.T) {
+	assert.Nil(t, New(&config.BalanceSnapshotConfig{Interval: 0}, &fakeStore{}))
+	assert.Nil(t, New(&config.BalanceSnapshotConfig{Interval: time.Hour}, nil))
+
+	var w *Worker
+	w.Run(context.Background())
+}
+
+func TestWorker_Snapshot(t *testing.T) {
+	both := &db.OnchainSnapshot{AccountID: uuid.New(), EVMWalletAddress: ptr("0xabc"), SolanaWalletAddress: ptr("9Wz")}
+	evmOnly := &db.OnchainSnapshot{AccountID: uuid.New(), EVMWalletAddress: ptr("0xdef")}
+	failing := &db.OnchainSnapshot{AccountID: uuid.New(), EVMWalletAddress: ptr("0xbad"), SolanaWalletAddress: ptr("Sol")}
+	store := &fakeStore{pending: []*db.OnchainSnapshot{both, evmOnly, failing}}
+
+	w := New(&config.BalanceSnapshotConfig{Interval: time.Hour, OnchainBatch: 50}, store)
+	require.NotNil(t, w)
+	w.now = func() time.Time { return time.Date(2026, 10, 1, 23, 30, 0, 0, time.UTC) }
+	w.queryEVM = func(_ context.Context, address, network string) (float64, error) {
+		assert.Equal(t, "base", network)
+		if address == "0xbad" {
+			return 0, errors.New("429 Too Many Requests")
+		}
+		return 1.5, nil
+	}
+	w.querySolana = func(_ context.Context, address, network string) (float64, error) {
+		assert.Equal(t, "solana", network)
+		return 2, nil
+	}
+
+	assert.Equal(t, 2, w.snapshot(context.Background()))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), store.day)
+	assert.Equal(t, 50, store.limit)
+
+	assert.Equal(t, recorded{evm: ptr(usdc.FromFloat(1.5)), solana: ptr(usdc.FromFloat(2)), complete: true}, store.recorded[both.AccountID])
+	assert.Equal(t, recorded{evm: ptr(usdc.FromFloat(1.5)), complete: true}, store.recorded[evmOnly.AccountID])
+	// The balance that was read is kept, and the account is retried later
+	assert.Equal(t, recorded{solana: ptr(usdc.FromFloat(2))}, store.recorded[failing.AccountID])
+}
+
+func TestWorker_SnapshotFails(t *testing.T) {
+	store := &fakeStore{failOn: errors.New("connection refused"), pending: []*db.OnchainSnapshot{{AccountID: uuid.New()}}}
+	w := New(&config.BalanceSnapshotConfig{Interval: time.Hour, OnchainBatch: 50}, store)
+
+	// Wallets are not read until the day's snapshot exists
+	assert.Equal(t, 0, w.snapshot(context.Background()))
+	assert.Empty(t, store.recorded)
+}
@@ -11,38 +11,37 @@ import (
 	citadelConfig "github.com/TryMightyAI/citadel/pkg/config"
 	"github.com/TryMightyAI/citadel/pkg/ml"
 	"stronghold/internal/config"
+	"stronghold/pkg/types"
 )
 
 // Decision represents the scan decision
-type Decision string
+type Decision = types.Decision
 
 const (
-	DecisionAllow Decision = "ALLOW"
-	DecisionWarn  Decision = "WARN"
-	DecisionBlock Decision = "BLOCK"
+	DecisionAllow = types.DecisionAllow
+	DecisionWarn  = types.DecisionWarn
+	DecisionBlock = types.DecisionBlock
 )
 
-// ScanResult represents the result of a security scan
-type ScanResult struct {
-	Decision          Decision               `json:"decision"`
-	Scores            map[string]float64     `json:"scores"`
-	Reason            string                 `json:"reason"`
-	LatencyMs         int64                  `json:"latency_ms"`
-	RequestID         string                 `json:"request_id"`
-	Metadata          map[string]interface{} `json:"metadata,omitempty"`
-	SanitizedText     string                 `json:"sanitized_text,omitempty"`     // Clean version with threats removed
-	ThreatsFound      []Threat               `json:"threats_found,omitempty"`      // Detailed threat info
-	RecommendedAction string                 `json:"recommended_action,omitempty"` // What the agent should do
+// ScanResult represents the result of a security scan. It is the shared wire
+// contract in pkg/types.
+type ScanResult = types.ScanResult
+
+// PrimaryScore returns the score the decision was based on: the combined
+// score when the hybrid detector ran, the heuristic score otherwise, or the
+// credential score for output scans
+func PrimaryScore(scores map[string]float64) float64 {
+	if v, ok := scores["combined"]; ok {
+		return v
+	}
+	if v, ok := scores["credential_score"]; ok {
+		return v
+	}
+	return scores["heuristic"]
 }
 
 // Threat represents a detected threat with location info
-type Threat struct {
-	Category    string `json:"category"`    // Broad category: "prompt_injection", "credential_leak"
-	Pattern     string `json:"pattern"`     // What matched (the specific pattern)
-	Location    string `json:"location"`    // Where in text (line/offset if available)
-	Severity    string `json:"severity"`    // "high", "medium", "low"
-	Description string `json:"description"` // Human-readable explanation
-}
+type Threat = types.Threat
 
 // Scanner wraps the Citadel security scanner
 type Scanner struct {
@@ -50,9 +49,12 @@ type Scanner struct {
 	threatScorer    *ml.ThreatScorer
 	hybridDetector  *ml.HybridDetector
 	outputScanner   *ml.OutputScanner
+	classifier      *Classifier
+	latency         stageLatencies
 	semanticEnabled bool
 	hugotEnabled    bool
 	llmEnabled      bool
+	version         DetectionVersion
 }
 
 // NewScanner creates a new Scanner with Citadel integration
@@ -70,10 +72,21 @@ func NewScanner(cfg *config.StrongholdConfig) (*Scanner, error) {
 		config:          cfg,
 		threatScorer:    threatScorer,
 		semanticEnabled: cfg.EnableSemantics,
-		hugotEnabled:    cfg.EnableHugot,
 		llmEnabled:      cfg.LLMProvider != "",
 	}
 
+	// Load the local ML layer. Without a model it is left out, and the
+	// detection version reports that it didn't run.
+	if cfg.EnableHugot {
+		classifier, err := NewClassifier(cfg)
+		if err != nil {
+			slog.Warn("ml classifier unavailable, scanning without the ml layer", "model_path", cfg.HugotModelPath, "error", err)
+		} else {
+			s.classifier = classifier
+			s.hugotEnabled = true
+		}
+	}
+
 	// Initialize hybrid detector if semantic or LLM detection is enabled
 	if cfg.EnableSemantics || cfg.LLMProvider != "" {
 		ollamaURL := os.Getenv("OLLAMA_URL") // Optional: for local embeddings
@@ -92,32 +105,103 @@ func NewScanner(cfg *config.StrongholdConfig) (*Scanner, error) {
 	// Initialize output scanner for credential detection
 	s.outputScanner = ml.NewOutputScanner()
 
+	s.version = newDetectionVersion(s)
+
 	return s, nil
 }
 
 // ScanContent scans external content for prompt injection attacks using Citadel
 func (s *Scanner) ScanContent(ctx context.Context, text, sourceURL, sourceType, contentType string) (*ScanResult, error) {
 	start := time.Now()
+	stages := make(map[string]float64)
 
 	var result *ScanResult
-	var err error
-
-	// Use hybrid detector if available (semantic + LLM detection)
-	if s.hybridDetector != nil {
-		result, err = s.scanWithHybrid(ctx, text, sourceURL, sourceType, contentType)
-	} else {
-		// Fallback to threat scorer only (heuristics)
-		result, err = s.scanWithThreatScorer(text, sourceURL, sourceType, contentType)
-	}
-
+	err := s.timeStage(stages, StageEngine, func() error {
+		var err error
+		// Use hybrid detector if available (semantic + LLM detection)
+		if s.hybridDetector != nil {
+			result, err = s.scanWithHybrid(ctx, text, sourceURL, sourceType, contentType)
+		} else {
+			// Fallback to threat scorer only (heuristics)
+			result, err = s.scanWithThreatScorer(text, sourceURL, sourceType, contentType)
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if s.classifier != nil {
+		s.applyClassifier(ctx, text, result, stages)
+	}
+
 	result.LatencyMs = time.Since(start).Milliseconds()
+	result.DetectionVersion = s.version.Version
+	result.Metadata["stage_latency_ms"] = stages
 	return result, nil
 }
 
+// applyClassifier runs the ML layer and raises the decision when the model's
+// score crosses a threshold the other layers didn't. It never lowers a
+// decision, and a failed classification leaves the result unchanged.
+func (s *Scanner) applyClassifier(ctx context.Context, text string, result *ScanResult, stages map[string]float64) {
+	var score float64
+	err := s.timeStage(stages, StageML, func() error {
+		var err error
+		score, err = s.classifier.Score(ctx, text)
+		return err
+	})
+	if err != nil {
+		slog.Warn("ml classifier failed", "error", err)
+		return
+	}
+	result.Scores["ml"] = score
+
+	switch {
+	case score >= s.config.BlockThreshold && result.Decision != DecisionBlock:
+		result.Decision = DecisionBlock
+		result.Reason = fmt.Sprintf("Critical: prompt injection classified by the ml layer (Score: %.2f)", score)
+		result.RecommendedAction = "DO NOT PROCEED - Content contains active threats. Discard immediately."
+	case score >= s.config.WarnThreshold && result.Decision == DecisionAllow:
+		result.Decision = DecisionWarn
+		result.Reason = fmt.Sprintf("Warning: possible prompt injection classified by the ml layer (Score: %.2f)", score)
+		result.RecommendedAction = "Caution advised - Review content manually before processing."
+	default:
+		return
+	}
+	result.ThreatsFound = append(result.ThreatsFound, Threat{
+		Category:    "prompt_injection",
+		Pattern:     "ml_classifier",
+		Severity:    severityFromScore(score),
+		Description: fmt.Sprintf("Local ML classifier: prompt injection (confidence: %.0f%%)", score*100),
+	})
+}
+
+// timeStage runs a detection stage, recording its latency for the scan and
+// for StageLatency
+func (s *Scanner) timeStage(stages map[string]float64, stage string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	d := time.Since(start)
+	s.latency.record(stage, d, err != nil)
+	stages[stage] = durationMs(d)
+	return err
+}
+
+// StageLatency returns per-stage latency since startup
+func (s *Scanner) StageLatency() []StageLatency {
+	return s.latency.snapshot()
+}
+
+// MLWarmup returns how long the ML layer's warm-up took, or zero when the
+// layer isn't loaded
+func (s *Scanner) MLWarmup() time.Duration {
+	if s.classifier == nil {
+		return 0
+	}
+	return s.classifier.Warmup()
+}
+
 // scanWithHybrid uses the full Citadel hybrid detector (heuristic + semantic + LLM)
 func (s *Scanner) scanWithHybrid(ctx context.Context, text, sourceURL, sourceType, contentType string) (*ScanResult, error) {
 	hybridResult, err := s.hybridDetector.Detect(ctx, text)
@@ -210,9 +294,14 @@ func (s *Scanner) scanWithThreatScorer(text, sourceURL, sourceType, contentType
 // ScanOutput scans LLM output for credential leaks using Citadel
 func (s *Scanner) ScanOutput(ctx context.Context, text string) (*ScanResult, error) {
 	start := time.Now()
+	stages := make(map[string]float64)
 
 	// Use Citadel's output scanner for credential detection
-	result := s.outputScanner.ScanOutput(text)
+	var result *ml.OutputScanResult
+	s.timeStage(stages, StageOutput, func() error {
+		result = s.outputScanner.ScanOutput(text)
+		return nil
+	})
 	score := float64(result.RiskScore) / 100.0 // Convert 0-100 to 0.0-1.0
 
 	decision := DecisionAllow
@@ -242,11 +331,13 @@ func (s *Scanner) ScanOutput(ctx context.Context, text string) (*ScanResult, err
 		Reason:    reason,
 		LatencyMs: time.Since(start).Milliseconds(),
 		ThreatsFound: threats,
+		DetectionVersion: s.version.Version,
 		Metadata: map[string]interface{}{
-			"findings":     len(result.Details),
-			"risk_level":   result.RiskLevel,
-			"is_safe":      result.IsSafe,
-			"categories":   result.ThreatCategories,
+			"findings":         len(result.Details),
+			"risk_level":       result.RiskLevel,
+			"is_safe":          result.IsSafe,
+			"categories":       result.ThreatCategories,
+			"stage_latency_ms": stages,
 		},
 	}, nil
 }
@@ -277,8 +368,18 @@ func (s *Scanner) sanitizeText(text string, threats []Threat) string {
 	return sanitized
 }
 
+// Version returns the scanner's detection version
+func (s *Scanner) Version() DetectionVersion {
+	return s.version
+}
+
 // Close cleans up scanner resources
 func (s *Scanner) Close() error {
+	if s.classifier != nil {
+		if err := s.classifier.Close(); err != nil {
+			return err
+		}
+	}
 	if s.hybridDetector != nil {
 		// Hybrid detector doesn't have a Close method, but we could add cleanup here
 	}
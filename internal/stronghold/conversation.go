@@ -0,0 +1,73 @@
+package stronghold
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ThreatMultiTurnInjection is the threat category for injection that only
+// shows when a message is read with the conversation's earlier messages
+const ThreatMultiTurnInjection = "multi_turn_injection"
+
+// ConversationContext joins a conversation's earlier messages and a new one
+// into the text scanned for injection built up across turns, and returns how
+// many messages it holds. The new message is always included; earlier
+// messages are added newest first until maxBytes is reached, so the oldest
+// are dropped first.
+func ConversationContext(history []string, message string, maxBytes int) (string, int) {
+	parts := []string{message}
+	size := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		size += len(history[i])
+		if size > maxBytes {
+			break
+		}
+		parts = append(parts, history[i])
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "\n\n"), len(parts)
+}
+
+// ApplyConversationContext escalates a message's scan result with the scan
+// of the message in its conversation context. The context's score is kept
+// in Scores["context"]. When the context scan's decision is stricter, the
+// result takes it with a multi_turn_injection threat; decisions are never
+// lowered.
+func ApplyConversationContext(result, contextResult *ScanResult, turns int) {
+	if result.Scores == nil {
+		result.Scores = make(map[string]float64)
+	}
+	result.Scores["context"] = PrimaryScore(contextResult.Scores)
+
+	if decisionRank(contextResult.Decision) <= decisionRank(result.Decision) {
+		return
+	}
+	severity := "medium"
+	if contextResult.Decision == DecisionBlock {
+		severity = "high"
+	}
+	result.ThreatsFound = append(result.ThreatsFound, Threat{
+		Category:    ThreatMultiTurnInjection,
+		Pattern:     "conversation_context",
+		Location:    "session",
+		Severity:    severity,
+		Description: fmt.Sprintf("Injection builds up across the last %d messages of the conversation", turns),
+	})
+	result.Decision = contextResult.Decision
+	result.Reason = "Multi-turn injection: " + contextResult.Reason
+	result.RecommendedAction = contextResult.RecommendedAction
+}
+
+// decisionRank orders decisions from least to most strict
+func decisionRank(d Decision) int {
+	switch d {
+	case DecisionBlock:
+		return 2
+	case DecisionWarn:
+		return 1
+	default:
+		return 0
+	}
+}
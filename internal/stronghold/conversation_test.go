@@ -0,0 +1,43 @@
+package stronghold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationContext(t *testing.T) {
+	text, n := ConversationContext([]string{"first", "second", "third"}, "new", 11)
+	assert.Equal(t, "second\n\nthird\n\nnew", text)
+	assert.Equal(t, 3, n)
+
+	text, n = ConversationContext(nil, "new", 0)
+	assert.Equal(t, "new", text)
+	assert.Equal(t, 1, n)
+}
+
+func TestApplyConversationContext(t *testing.T) {
+	// The conversation is an injection the message alone isn't
+	result := &ScanResult{Decision: DecisionAllow, Reason: "No threats detected"}
+	ApplyConversationContext(result, &ScanResult{
+		Decision:          DecisionBlock,
+		Reason:            "Prompt injection detected",
+		RecommendedAction: "DO NOT PROCEED",
+		Scores:            map[string]float64{"combined": 0.8},
+	}, 4)
+	assert.Equal(t, DecisionBlock, result.Decision)
+	assert.Equal(t, "Multi-turn injection: Prompt injection detected", result.Reason)
+	assert.InDelta(t, 0.8, result.Scores["context"], 0.001)
+	require.Len(t, result.ThreatsFound, 1)
+	assert.Equal(t, ThreatMultiTurnInjection, result.ThreatsFound[0].Category)
+	assert.Equal(t, "high", result.ThreatsFound[0].Severity)
+
+	// The context never lowers a decision
+	blocked := &ScanResult{Decision: DecisionBlock, Reason: "Prompt injection detected", Scores: map[string]float64{}}
+	ApplyConversationContext(blocked, &ScanResult{Decision: DecisionWarn, Scores: map[string]float64{"combined": 0.4}}, 2)
+	assert.Equal(t, DecisionBlock, blocked.Decision)
+	assert.Equal(t, "Prompt injection detected", blocked.Reason)
+	assert.Empty(t, blocked.ThreatsFound)
+	assert.InDelta(t, 0.4, blocked.Scores["context"], 0.001)
+}
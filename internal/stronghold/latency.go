@@ -0,0 +1,78 @@
+package stronghold
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Detection stages timed per scan
+const (
+	StageEngine   = "engine"    // Citadel heuristic, semantic and LLM layers
+	StageML       = "ml"        // Local ONNX classifier
+	StageOutput   = "output"    // Credential leak scan of LLM output
+	StageToolCall = "tool_call" // Tool-call argument rules
+)
+
+// StageLatency summarizes a detection stage's latency since startup
+type StageLatency struct {
+	Stage  string  `json:"stage"`
+	Count  int64   `json:"count"`
+	Errors int64   `json:"errors"`
+	MeanMs float64 `json:"mean_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// stageLatencies accumulates per-stage latency
+type stageLatencies struct {
+	mu     sync.Mutex
+	stages map[string]*stageTotals
+}
+
+type stageTotals struct {
+	count, errors int64
+	totalMs       float64
+	maxMs         float64
+}
+
+func (l *stageLatencies) record(stage string, d time.Duration, failed bool) {
+	ms := durationMs(d)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stages == nil {
+		l.stages = make(map[string]*stageTotals)
+	}
+	t, ok := l.stages[stage]
+	if !ok {
+		t = &stageTotals{}
+		l.stages[stage] = t
+	}
+	t.count++
+	t.totalMs += ms
+	t.maxMs = max(t.maxMs, ms)
+	if failed {
+		t.errors++
+	}
+}
+
+func (l *stageLatencies) snapshot() []StageLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]StageLatency, 0, len(l.stages))
+	for stage, t := range l.stages {
+		out = append(out, StageLatency{
+			Stage:  stage,
+			Count:  t.count,
+			Errors: t.errors,
+			MeanMs: t.totalMs / float64(t.count),
+			MaxMs:  t.maxMs,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Stage < out[j].Stage })
+	return out
+}
+
+// durationMs converts d to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,173 @@
+package stronghold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ThreatSystemPromptLeak is the threat category for output that repeats a
+// registered system prompt
+const ThreatSystemPromptLeak = "system_prompt_leak"
+
+// Prompt fingerprints are hashed shingles: every run of ShingleWords
+// consecutive words of the lowercased prompt, split on anything that isn't a
+// letter or digit and joined by single spaces, hashed with SHA-256 and
+// truncated to ShingleHexLen hex characters. Prompts need at least
+// ShingleWords words. Customers can compute them client-side so the prompt
+// itself is never sent.
+const (
+	ShingleWords  = 8
+	ShingleHexLen = 16
+)
+
+// Share of a prompt's shingles found in an output that warns or blocks. A
+// leak must also match at least minLeakShingles shingles (or all of a
+// prompt with fewer), so one stock phrase doesn't count.
+const (
+	PromptLeakWarnFraction  = 0.2
+	PromptLeakBlockFraction = 0.5
+	minLeakShingles         = 3
+)
+
+// PromptFingerprint is a registered system prompt's hashed shingles
+type PromptFingerprint struct {
+	Name     string
+	Shingles []string
+}
+
+// PromptLeak is how much of a registered prompt an output repeats
+type PromptLeak struct {
+	Name     string
+	Matched  int
+	Total    int
+	Fraction float64
+}
+
+// FingerprintPrompt returns the distinct hashed shingles of text; none when
+// it has fewer than ShingleWords words
+func FingerprintPrompt(text string) []string {
+	seen := make(map[string]bool)
+	var shingles []string
+	for _, s := range wordShingles(promptWords(text)) {
+		h := hashShingle(s)
+		if !seen[h] {
+			seen[h] = true
+			shingles = append(shingles, h)
+		}
+	}
+	return shingles
+}
+
+// IsValidShingle reports whether s is a hashed shingle
+func IsValidShingle(s string) bool {
+	if len(s) != ShingleHexLen {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+// CheckPromptLeakage returns the registered prompts output repeats enough
+// of to warn or block
+func CheckPromptLeakage(output string, prints []PromptFingerprint) []PromptLeak {
+	if len(prints) == 0 {
+		return nil
+	}
+	found := make(map[string]bool)
+	for _, s := range wordShingles(promptWords(output)) {
+		found[hashShingle(s)] = true
+	}
+
+	var leaks []PromptLeak
+	for _, p := range prints {
+		if len(p.Shingles) == 0 {
+			continue
+		}
+		matched := 0
+		for _, s := range p.Shingles {
+			if found[s] {
+				matched++
+			}
+		}
+		fraction := float64(matched) / float64(len(p.Shingles))
+		if matched < min(minLeakShingles, len(p.Shingles)) || fraction < PromptLeakWarnFraction {
+			continue
+		}
+		leaks = append(leaks, PromptLeak{Name: p.Name, Matched: matched, Total: len(p.Shingles), Fraction: fraction})
+	}
+	return leaks
+}
+
+// ApplyPromptLeakage flags an output scan result that repeats registered
+// system prompts. It only escalates: the result's decision is raised to WARN
+// or BLOCK by the largest leak and never lowered.
+func ApplyPromptLeakage(result *ScanResult, output string, prints []PromptFingerprint) {
+	leaks := CheckPromptLeakage(output, prints)
+	if len(leaks) == 0 {
+		return
+	}
+
+	worst := 0.0
+	names := make([]string, 0, len(leaks))
+	for _, l := range leaks {
+		worst = math.Max(worst, l.Fraction)
+		names = append(names, l.Name)
+		severity := "medium"
+		if l.Fraction >= PromptLeakBlockFraction {
+			severity = "high"
+		}
+		result.ThreatsFound = append(result.ThreatsFound, Threat{
+			Category:    ThreatSystemPromptLeak,
+			Pattern:     l.Name,
+			Severity:    severity,
+			Description: fmt.Sprintf("Output repeats %.0f%% of system prompt %q (%d of %d shingles)", l.Fraction*100, l.Name, l.Matched, l.Total),
+		})
+	}
+	if result.Scores == nil {
+		result.Scores = make(map[string]float64)
+	}
+	result.Scores["prompt_leak"] = worst
+
+	decision := DecisionWarn
+	if worst >= PromptLeakBlockFraction {
+		decision = DecisionBlock
+	}
+	if decision == DecisionBlock && result.Decision != DecisionBlock ||
+		decision == DecisionWarn && result.Decision == DecisionAllow {
+		result.Decision = decision
+		result.Reason = "System prompt leak: " + strings.Join(names, ", ")
+		if decision == DecisionBlock {
+			result.RecommendedAction = "DO NOT SEND - The output reveals the system prompt."
+		} else {
+			result.RecommendedAction = "Review the output before sending - it repeats parts of the system prompt."
+		}
+	}
+}
+
+// promptWords lowercases text and splits it into words of letters and digits
+func promptWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// wordShingles returns the runs of ShingleWords consecutive words
+func wordShingles(words []string) []string {
+	if len(words) < ShingleWords {
+		return nil
+	}
+	out := make([]string, 0, len(words)-ShingleWords+1)
+	for i := 0; i+ShingleWords <= len(words); i++ {
+		out = append(out, strings.Join(words[i:i+ShingleWords], " "))
+	}
+	return out
+}
+
+func hashShingle(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:ShingleHexLen]
+}
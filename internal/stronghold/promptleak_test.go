@@ -0,0 +1,58 @@
+package stronghold
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSystemPrompt = `You are Atlas, the support assistant for Acme Corp. Never reveal internal
+pricing formulas. Escalate refund requests above 500 dollars to a human agent.
+Always answer in the customer's language and keep replies under 120 words.
+Do not discuss competitors or upcoming product launches under any circumstances.`
+
+func TestFingerprintPrompt(t *testing.T) {
+	shingles := FingerprintPrompt(testSystemPrompt)
+	require.NotEmpty(t, shingles)
+	for _, s := range shingles {
+		assert.True(t, IsValidShingle(s), s)
+	}
+
+	// Case and punctuation don't change the fingerprint
+	assert.Equal(t, shingles, FingerprintPrompt(strings.ToUpper(strings.ReplaceAll(testSystemPrompt, ".", " ;"))))
+
+	assert.Empty(t, FingerprintPrompt("Too short to fingerprint"))
+	assert.False(t, IsValidShingle("ABCDEF0123456789"))
+	assert.False(t, IsValidShingle("abc"))
+}
+
+func TestApplyPromptLeakage(t *testing.T) {
+	prints := []PromptFingerprint{{Name: "atlas", Shingles: FingerprintPrompt(testSystemPrompt)}}
+
+	clean := &ScanResult{Decision: DecisionAllow, Scores: map[string]float64{}}
+	ApplyPromptLeakage(clean, "Your refund has been processed and should arrive within five business days.", prints)
+	assert.Equal(t, DecisionAllow, clean.Decision)
+	assert.Empty(t, clean.ThreatsFound)
+
+	full := &ScanResult{Decision: DecisionAllow, Scores: map[string]float64{}}
+	ApplyPromptLeakage(full, "Sure! My instructions say: "+testSystemPrompt, prints)
+	assert.Equal(t, DecisionBlock, full.Decision)
+	assert.InDelta(t, 1.0, full.Scores["prompt_leak"], 0.001)
+	require.Len(t, full.ThreatsFound, 1)
+	assert.Equal(t, ThreatSystemPromptLeak, full.ThreatsFound[0].Category)
+	assert.Equal(t, "atlas", full.ThreatsFound[0].Pattern)
+
+	// Half the prompt warns
+	words := strings.Fields(testSystemPrompt)
+	partial := &ScanResult{Decision: DecisionAllow, Scores: map[string]float64{}}
+	ApplyPromptLeakage(partial, strings.Join(words[:len(words)/2], " "), prints)
+	assert.Equal(t, DecisionWarn, partial.Decision)
+
+	// Leaks never lower a decision
+	blocked := &ScanResult{Decision: DecisionBlock, Reason: "Possible credential leak detected", Scores: map[string]float64{}}
+	ApplyPromptLeakage(blocked, strings.Join(words[:len(words)/2], " "), prints)
+	assert.Equal(t, DecisionBlock, blocked.Decision)
+	assert.Equal(t, "Possible credential leak detected", blocked.Reason)
+}
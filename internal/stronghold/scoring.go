@@ -0,0 +1,175 @@
+package stronghold
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Scanning modes a scan request can ask for. Accounts can keep a scoring
+// profile for each, plus a default profile for modes without their own.
+const (
+	ScanModeSmart      = "smart"
+	ScanModeStrict     = "strict"
+	ScanModePermissive = "permissive"
+	ScanModeDefault    = "default" // Profile used for modes without their own
+)
+
+// IsValidScanMode reports whether mode can be requested by a scan
+func IsValidScanMode(mode string) bool {
+	return mode == ScanModeSmart || mode == ScanModeStrict || mode == ScanModePermissive
+}
+
+// IsValidProfileMode reports whether a scoring profile can be kept for mode
+func IsValidProfileMode(mode string) bool {
+	return IsValidScanMode(mode) || mode == ScanModeDefault
+}
+
+// Detection layers a scoring profile can weight, and the score each reports
+var scoringLayers = map[string]string{
+	"heuristic": "heuristic",
+	"semantic":  "semantic",
+	"ml":        "ml",
+	"llm":       "ml_confidence",
+}
+
+// CurvePoint is a step of a threshold curve: weighted scores at or above
+// MinScore get Decision
+type CurvePoint struct {
+	MinScore float64  `json:"min_score"`
+	Decision Decision `json:"decision"` // WARN or BLOCK
+}
+
+// ScoringProfile replaces the engine's combination of layer scores with a
+// weighted mean, and its thresholds with a curve. Layers that didn't run on
+// a scan are left out of the mean.
+type ScoringProfile struct {
+	Weights map[string]float64 `json:"weights"`         // heuristic, semantic, ml, llm
+	Curve   []CurvePoint       `json:"curve,omitempty"` // Ascending; the scanner's thresholds when empty
+}
+
+// Validate checks that weights name known layers and are non-negative with
+// at least one positive, and that the curve ascends through (0, 1] without
+// stepping down from BLOCK to WARN
+func (p *ScoringProfile) Validate() error {
+	if len(p.Weights) == 0 {
+		return errors.New("weights are required")
+	}
+	var total float64
+	for layer, w := range p.Weights {
+		if _, ok := scoringLayers[layer]; !ok {
+			return fmt.Errorf("unknown layer %q (must be one of %s)", layer, strings.Join(ScoringLayers(), ", "))
+		}
+		if math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+			return fmt.Errorf("weight for %s must be a non-negative number", layer)
+		}
+		total += w
+	}
+	if total == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+
+	for i, pt := range p.Curve {
+		if pt.Decision != DecisionWarn && pt.Decision != DecisionBlock {
+			return fmt.Errorf("curve point %d: decision must be WARN or BLOCK", i)
+		}
+		if math.IsNaN(pt.MinScore) || pt.MinScore <= 0 || pt.MinScore > 1 {
+			return fmt.Errorf("curve point %d: min_score must be above 0.0 and at most 1.0", i)
+		}
+		if i > 0 {
+			prev := p.Curve[i-1]
+			if pt.MinScore <= prev.MinScore {
+				return fmt.Errorf("curve point %d: min_score must be above the previous point's", i)
+			}
+			if prev.Decision == DecisionBlock && pt.Decision == DecisionWarn {
+				return fmt.Errorf("curve point %d: WARN can't follow BLOCK", i)
+			}
+		}
+	}
+	return nil
+}
+
+// ScoringLayers returns the layers a scoring profile can weight
+func ScoringLayers() []string {
+	layers := make([]string, 0, len(scoringLayers))
+	for layer := range scoringLayers {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+	return layers
+}
+
+// Rescore applies a scoring profile to a content scan result from this
+// scanner: the combined score becomes the weighted mean of the layers that
+// ran, and the decision comes from the profile's curve. Results without any
+// weighted layer are left unchanged.
+func (s *Scanner) Rescore(result *ScanResult, p *ScoringProfile) {
+	var sum, total float64
+	for layer, w := range p.Weights {
+		score, ok := s.layerScore(result, layer)
+		if !ok || w == 0 {
+			continue
+		}
+		sum += w * score
+		total += w
+	}
+	if total == 0 {
+		return
+	}
+	combined := sum / total
+	result.Scores["combined"] = combined
+
+	decision := s.decide(combined, p.Curve)
+	if decision == result.Decision {
+		return
+	}
+	result.Decision = decision
+	switch decision {
+	case DecisionBlock:
+		result.Reason = fmt.Sprintf("Critical: weighted score above the block threshold (Score: %.2f)", combined)
+		result.RecommendedAction = "DO NOT PROCEED - Content contains active threats. Discard immediately."
+	case DecisionWarn:
+		result.Reason = fmt.Sprintf("Warning: weighted score above the warn threshold (Score: %.2f)", combined)
+		result.RecommendedAction = "Caution advised - Review content manually before processing."
+	default:
+		result.Reason = "No threats detected"
+		result.RecommendedAction = "Content is safe to process"
+	}
+}
+
+// layerScore returns a layer's score on a result, and whether the layer ran
+func (s *Scanner) layerScore(result *ScanResult, layer string) (float64, bool) {
+	hybrid := result.Metadata["detection"] != "heuristic_only"
+	switch layer {
+	case "semantic":
+		if !hybrid || !s.semanticEnabled {
+			return 0, false
+		}
+	case "llm":
+		if !hybrid || !s.llmEnabled {
+			return 0, false
+		}
+	}
+	score, ok := result.Scores[scoringLayers[layer]]
+	return score, ok
+}
+
+// decide maps a weighted score to a decision with the curve, or with the
+// scanner's thresholds when the curve is empty
+func (s *Scanner) decide(score float64, curve []CurvePoint) Decision {
+	if len(curve) == 0 {
+		curve = []CurvePoint{
+			{MinScore: s.config.WarnThreshold, Decision: DecisionWarn},
+			{MinScore: s.config.BlockThreshold, Decision: DecisionBlock},
+		}
+	}
+	decision := DecisionAllow
+	for _, pt := range curve {
+		if score >= pt.MinScore {
+			decision = pt.Decision
+		}
+	}
+	return decision
+}
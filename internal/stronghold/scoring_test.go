@@ -0,0 +1,69 @@
+package stronghold
+
+import (
+	"testing"
+
+	"stronghold/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoringProfile_Validate(t *testing.T) {
+	valid := ScoringProfile{
+		Weights: map[string]float64{"heuristic": 1, "ml": 2},
+		Curve:   []CurvePoint{{MinScore: 0.3, Decision: DecisionWarn}, {MinScore: 0.6, Decision: DecisionBlock}},
+	}
+	require.NoError(t, valid.Validate())
+
+	tests := map[string]ScoringProfile{
+		"no weights":         {},
+		"unknown layer":      {Weights: map[string]float64{"vibes": 1}},
+		"negative weight":    {Weights: map[string]float64{"heuristic": -1}},
+		"all zero":           {Weights: map[string]float64{"heuristic": 0, "ml": 0}},
+		"allow in curve":     {Weights: map[string]float64{"heuristic": 1}, Curve: []CurvePoint{{MinScore: 0.5, Decision: DecisionAllow}}},
+		"score out of range": {Weights: map[string]float64{"heuristic": 1}, Curve: []CurvePoint{{MinScore: 1.5, Decision: DecisionBlock}}},
+		"not ascending": {Weights: map[string]float64{"heuristic": 1}, Curve: []CurvePoint{
+			{MinScore: 0.6, Decision: DecisionWarn}, {MinScore: 0.4, Decision: DecisionBlock},
+		}},
+		"warn after block": {Weights: map[string]float64{"heuristic": 1}, Curve: []CurvePoint{
+			{MinScore: 0.4, Decision: DecisionBlock}, {MinScore: 0.6, Decision: DecisionWarn},
+		}},
+	}
+	for name, p := range tests {
+		assert.Error(t, p.Validate(), name)
+	}
+}
+
+func TestScanner_Rescore(t *testing.T) {
+	s := &Scanner{config: &config.StrongholdConfig{BlockThreshold: 0.55, WarnThreshold: 0.35}}
+	heuristicOnly := func(heuristic float64) *ScanResult {
+		return &ScanResult{
+			Decision: DecisionAllow,
+			Scores:   map[string]float64{"heuristic": heuristic, "semantic": 0, "ml": 0.9},
+			Metadata: map[string]interface{}{"detection": "heuristic_only"},
+		}
+	}
+
+	// semantic didn't run, so only heuristic and ml are weighted
+	result := heuristicOnly(0.1)
+	s.Rescore(result, &ScoringProfile{Weights: map[string]float64{"heuristic": 1, "semantic": 5, "ml": 1}})
+	assert.InDelta(t, 0.5, result.Scores["combined"], 1e-9)
+	assert.Equal(t, DecisionWarn, result.Decision)
+
+	// A curve replaces the scanner's thresholds, and can lower a decision
+	result = heuristicOnly(0.1)
+	result.Decision = DecisionBlock
+	s.Rescore(result, &ScoringProfile{
+		Weights: map[string]float64{"heuristic": 1, "ml": 1},
+		Curve:   []CurvePoint{{MinScore: 0.8, Decision: DecisionBlock}},
+	})
+	assert.Equal(t, DecisionAllow, result.Decision)
+	assert.Equal(t, "No threats detected", result.Reason)
+
+	// No weighted layer ran: unchanged
+	result = heuristicOnly(0.1)
+	s.Rescore(result, &ScoringProfile{Weights: map[string]float64{"semantic": 1}})
+	assert.NotContains(t, result.Scores, "combined")
+	assert.Equal(t, DecisionAllow, result.Decision)
+}
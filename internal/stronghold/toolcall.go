@@ -0,0 +1,328 @@
+package stronghold
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tool-call threat categories
+const (
+	ThreatDangerousCommand = "dangerous_command"
+	ThreatSensitivePath    = "sensitive_path"
+	ThreatUnsafeURL        = "unsafe_url"
+)
+
+// Kinds of tools, which decide the rules applied to every argument. Rules
+// also apply to arguments whose names give them away (command, path, url)
+// whatever the tool.
+const (
+	ToolKindShell   = "shell"
+	ToolKindFile    = "file"
+	ToolKindHTTP    = "http"
+	ToolKindGeneric = "generic"
+)
+
+// toolKindNames are substrings of tool names, checked in order
+var toolKindNames = []struct {
+	kind  string
+	names []string
+}{
+	{ToolKindShell, []string{"bash", "shell", "exec", "terminal", "command", "powershell", "cmd", "run"}},
+	{ToolKindHTTP, []string{"fetch", "http", "url", "browse", "request", "download", "curl", "web"}},
+	{ToolKindFile, []string{"file", "read", "write", "edit", "path", "dir", "fs", "delete", "move", "copy"}},
+}
+
+// Argument names that mark an argument as a command, path or URL
+var (
+	commandArgNames = []string{"command", "cmd", "script", "shell"}
+	pathArgNames    = []string{"path", "file", "dir", "dest", "target", "source", "cwd"}
+	urlArgNames     = []string{"url", "uri", "endpoint", "href", "link"}
+)
+
+// toolRule matches a risky argument value
+type toolRule struct {
+	pattern  string
+	severity string
+	re       *regexp.Regexp
+	desc     string
+}
+
+// commandRules match dangerous shell commands
+var commandRules = []toolRule{
+	{"recursive_delete", "critical", regexp.MustCompile(`\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]*\s+)*(/|~|\$HOME|\*)(\s|$|/\*)`), "Recursive delete of a root, home or wildcard path"},
+	{"pipe_to_shell", "critical", regexp.MustCompile(`\b(curl|wget)\b[^;&]*\|\s*(sudo\s+)?(ba|z|da|k)?sh\b`), "Downloaded script piped to a shell"},
+	{"encoded_payload", "critical", regexp.MustCompile(`\bbase64\s+(-d|--decode)\b[^;&]*\|\s*(ba|z|da)?sh\b`), "Encoded payload decoded into a shell"},
+	{"reverse_shell", "critical", regexp.MustCompile(`/dev/(tcp|udp)/|\bnc(at)?\b[^;|&]*\s-[a-z]*e\s|\bbash\s+-i\b[^;]*>&|\bmkfifo\b`), "Reverse shell"},
+	{"fork_bomb", "critical", regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), "Fork bomb"},
+	{"disk_destruction", "critical", regexp.MustCompile(`\bmkfs(\.\w+)?\b|\bdd\b[^;]*\bof=/dev/(sd|hd|nvme|disk|xvd)`), "Writes over a disk or filesystem"},
+	{"persistence", "high", regexp.MustCompile(`\bcrontab\s+-?[^l\s]|>>?\s*\S*(\.bashrc|\.zshrc|\.profile|\.bash_profile|authorized_keys)\b`), "Installs a startup hook or login key"},
+	{"permission_change", "high", regexp.MustCompile(`\bchmod\s+(-R\s+)?(0?777|[ugoa]*\+s)\b`), "Makes files world-writable or setuid"},
+	{"data_upload", "medium", regexp.MustCompile(`\b(curl|wget)\b[^;|&]*\s(-d|--data[a-z-]*|-F|--form|-T|--upload-file|--post-file|--post-data)\b`), "Uploads data to a remote host"},
+	{"privilege_escalation", "medium", regexp.MustCompile(`\bsudo\b|\bsu\s+(-|root)`), "Runs with elevated privileges"},
+	{"environment_dump", "medium", regexp.MustCompile(`\bprintenv\b|(^|[;&|]\s*)env\s*($|[|>;])`), "Dumps environment variables, which often hold secrets"},
+	{"anti_forensics", "medium", regexp.MustCompile(`\bhistory\s+-c\b|\bunset\s+HISTFILE\b|\bshred\b`), "Erases shell history or files beyond recovery"},
+}
+
+// pathRules match paths to credentials, secrets and startup files
+var pathRules = []toolRule{
+	{"ssh_keys", "critical", regexp.MustCompile(`(^|[/\s"'])\.ssh(/|$)|\bid_(rsa|dsa|ecdsa|ed25519)\b`), "SSH keys"},
+	{"cloud_credentials", "critical", regexp.MustCompile(`\.aws/(credentials|config)|\.config/gcloud|\.azure/|\.kube/config|\.docker/config\.json`), "Cloud or cluster credentials"},
+	{"system_secrets", "critical", regexp.MustCompile(`/etc/(shadow|gshadow|sudoers)\b`), "System password or sudo files"},
+	{"secrets_file", "high", regexp.MustCompile(`(^|[/\s"'])\.env(\.[\w-]+)?($|[\s"'])|\.netrc\b|\.npmrc\b|\.pypirc\b|\.git-credentials\b`), "File that typically holds secrets"},
+	{"process_environment", "high", regexp.MustCompile(`/proc/(self|\d+)/environ`), "Process environment, which often holds secrets"},
+	{"wallet_keys", "high", regexp.MustCompile(`(?i)keystore|wallet\.dat|\.stronghold/`), "Wallet keys"},
+	{"startup_file", "high", regexp.MustCompile(`(^|/)(\.bashrc|\.zshrc|\.profile|\.bash_profile|authorized_keys)$|/etc/cron|LaunchAgents|/systemd/system`), "Startup file that can persist code"},
+	{"system_accounts", "medium", regexp.MustCompile(`/etc/passwd\b`), "System account list"},
+	{"path_traversal", "medium", regexp.MustCompile(`(\.\./){2,}|(\.\.\\){2,}`), "Path traversal"},
+}
+
+// exfiltrationHosts receive arbitrary data without an account
+var exfiltrationHosts = []string{
+	"pastebin.com", "transfer.sh", "webhook.site", "requestbin.com", "pipedream.net",
+	"ngrok.io", "ngrok-free.app", "burpcollaborator.net", "interact.sh", "oast.fun", "oast.pro",
+}
+
+// metadataHosts serve cloud instance credentials
+var metadataHosts = []string{"169.254.169.254", "metadata.google.internal", "100.100.100.200", "fd00:ec2::254"}
+
+// urlInText finds URLs inside commands and other free text
+var urlInText = regexp.MustCompile(`(?i)\b(https?|ftp|file)://[^\s"'<>|;]+`)
+
+// maxQueryValueLen is the longest query value not flagged as smuggled data
+const maxQueryValueLen = 256
+
+// ToolArgument is a string argument of a tool call at its JSON path
+type ToolArgument struct {
+	Path  string
+	Value string
+}
+
+// ScanToolCall checks a tool call's arguments against rules for the tool's
+// kind: dangerous shell commands, paths to credentials and startup files, and
+// URLs pointing at internal networks, cloud metadata or exfiltration
+// services. Each finding is located at its argument's JSON path. Critical and
+// high findings block; medium findings warn.
+func (s *Scanner) ScanToolCall(ctx context.Context, tool string, args any) (*ScanResult, error) {
+	start := time.Now()
+	stages := make(map[string]float64)
+	kind := ToolKind(tool)
+
+	var threats []Threat
+	arguments := FlattenToolArguments(args)
+	s.timeStage(stages, StageToolCall, func() error {
+		for _, arg := range arguments {
+			threats = append(threats, checkToolArgument(kind, arg)...)
+		}
+		return nil
+	})
+
+	decision, score := DecisionAllow, 0.0
+	for _, t := range threats {
+		score = max(score, severityScore(t.Severity))
+	}
+	reason, action := "No risky arguments found", "Tool call is safe to execute"
+	switch {
+	case score >= severityScore("high"):
+		decision = DecisionBlock
+		reason = toolCallReason("Dangerous tool call", tool, threats)
+		action = "DO NOT EXECUTE - The tool call's arguments are dangerous."
+	case score > 0:
+		decision = DecisionWarn
+		reason = toolCallReason("Risky tool call", tool, threats)
+		action = "Confirm with the user before executing the tool call."
+	}
+
+	return &ScanResult{
+		Decision: decision,
+		Scores: map[string]float64{
+			"tool_risk":      score,
+			"findings_count": float64(len(threats)),
+		},
+		Reason:            reason,
+		LatencyMs:         time.Since(start).Milliseconds(),
+		ThreatsFound:      threats,
+		RecommendedAction: action,
+		DetectionVersion:  s.version.Version,
+		Metadata: map[string]interface{}{
+			"tool":             tool,
+			"tool_kind":        kind,
+			"arguments":        len(arguments),
+			"stage_latency_ms": stages,
+		},
+	}, nil
+}
+
+// ToolKind classifies a tool by its name
+func ToolKind(tool string) string {
+	name := strings.ToLower(tool)
+	for _, k := range toolKindNames {
+		if slices.ContainsFunc(k.names, func(n string) bool { return strings.Contains(name, n) }) {
+			return k.kind
+		}
+	}
+	return ToolKindGeneric
+}
+
+// FlattenToolArguments returns the string arguments of decoded JSON
+// arguments with their paths ("command", "options.cwd", "files[0]"), sorted
+// by path. Numbers and booleans can't carry commands, paths or URLs and are
+// skipped.
+func FlattenToolArguments(args any) []ToolArgument {
+	var out []ToolArgument
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case string:
+			out = append(out, ToolArgument{Path: path, Value: v})
+		case map[string]any:
+			for key, child := range v {
+				p := key
+				if path != "" {
+					p = path + "." + key
+				}
+				walk(p, child)
+			}
+		case []any:
+			for i, child := range v {
+				walk(path+"["+strconv.Itoa(i)+"]", child)
+			}
+		}
+	}
+	walk("", args)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// checkToolArgument applies the rules for the tool's kind and the
+// argument's name
+func checkToolArgument(kind string, arg ToolArgument) []Threat {
+	name := strings.ToLower(arg.Path)
+	if i := strings.LastIndexAny(name, ".]"); i >= 0 {
+		name = name[i+1:]
+	}
+	location := "arguments." + arg.Path
+	if arg.Path == "" {
+		location = "arguments"
+	}
+	named := func(names []string) bool {
+		return slices.ContainsFunc(names, func(n string) bool { return strings.Contains(name, n) })
+	}
+
+	var threats []Threat
+	isCommand := kind == ToolKindShell || named(commandArgNames)
+	if isCommand {
+		threats = append(threats, matchToolRules(ThreatDangerousCommand, commandRules, arg.Value, location)...)
+	}
+	if isCommand || kind == ToolKindFile || named(pathArgNames) {
+		threats = append(threats, matchToolRules(ThreatSensitivePath, pathRules, arg.Value, location)...)
+	}
+
+	urls := urlInText.FindAllString(arg.Value, -1)
+	if len(urls) == 0 && (kind == ToolKindHTTP || named(urlArgNames)) {
+		urls = []string{strings.TrimSpace(arg.Value)}
+	}
+	for _, u := range urls {
+		threats = append(threats, checkToolURL(u, location)...)
+	}
+	return threats
+}
+
+// matchToolRules returns a threat for each rule matching value
+func matchToolRules(category string, rules []toolRule, value, location string) []Threat {
+	var threats []Threat
+	for _, r := range rules {
+		if r.re.MatchString(value) {
+			threats = append(threats, Threat{
+				Category:    category,
+				Pattern:     r.pattern,
+				Location:    location,
+				Severity:    r.severity,
+				Description: r.desc,
+			})
+		}
+	}
+	return threats
+}
+
+// checkToolURL flags URLs with unsafe schemes, internal or metadata hosts,
+// exfiltration services, or oversized query values
+func checkToolURL(raw, location string) []Threat {
+	threat := func(pattern, severity, desc string) Threat {
+		return Threat{Category: ThreatUnsafeURL, Pattern: pattern, Location: location, Severity: severity, Description: desc}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return nil
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "file":
+		return []Threat{threat("file_url", "high", "Local file URL")}
+	case "javascript", "data":
+		return []Threat{threat("script_url", "medium", "Script or inline data URL")}
+	case "http", "https", "ftp":
+	default:
+		return nil
+	}
+
+	var threats []Threat
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case slices.Contains(metadataHosts, host):
+		threats = append(threats, threat("cloud_metadata", "critical", "Cloud instance metadata service, which serves credentials"))
+	case isInternalHost(host):
+		threats = append(threats, threat("internal_network", "high", fmt.Sprintf("Internal address %s", host)))
+	case slices.ContainsFunc(exfiltrationHosts, func(h string) bool { return host == h || strings.HasSuffix(host, "."+h) }):
+		threats = append(threats, threat("exfiltration_endpoint", "high", fmt.Sprintf("%s accepts arbitrary uploads without an account", host)))
+	}
+	for _, values := range u.Query() {
+		if slices.ContainsFunc(values, func(v string) bool { return len(v) > maxQueryValueLen }) {
+			threats = append(threats, threat("encoded_query", "medium", "Oversized query value that may smuggle data out"))
+			break
+		}
+	}
+	return threats
+}
+
+// isInternalHost reports whether host is localhost or a loopback, private or
+// link-local address
+func isInternalHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified())
+}
+
+// severityScore maps a finding's severity to a risk score
+func severityScore(severity string) float64 {
+	switch severity {
+	case "critical":
+		return 1.0
+	case "high":
+		return 0.85
+	case "medium":
+		return 0.5
+	default:
+		return 0.2
+	}
+}
+
+// toolCallReason summarizes the patterns found in a tool call
+func toolCallReason(prefix, tool string, threats []Threat) string {
+	patterns := []string{}
+	for _, t := range threats {
+		if !slices.Contains(patterns, t.Pattern) {
+			patterns = append(patterns, t.Pattern)
+		}
+	}
+	return fmt.Sprintf("%s: %s in %s arguments", prefix, strings.Join(patterns, ", "), tool)
+}
@@ -0,0 +1,130 @@
+package stronghold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// RulesVersion identifies Stronghold's own detection logic: how engine
+// results are mapped to decisions, threats and categories. Bump it and add a
+// Changelog entry with every change that can alter verdicts.
+const RulesVersion = "2026.10.4"
+
+// citadelModule is the detection engine's module path
+const citadelModule = "github.com/TryMightyAI/citadel"
+
+// ChangelogEntry describes a detection release
+type ChangelogEntry struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date"`
+	Changes []string `json:"changes"`
+}
+
+// Changelog lists detection releases, newest first
+var Changelog = []ChangelogEntry{
+	{
+		Version: "2026.10.4",
+		Date:    "2026-10-16",
+		Changes: []string{
+			"Conversation scan sessions: a message is also scanned with the session's recent messages and raised to the conversation's decision as multi_turn_injection",
+		},
+	},
+	{
+		Version: "2026.10.3",
+		Date:    "2026-10-16",
+		Changes: []string{
+			"Output scans flag responses repeating an account's registered system prompts as system_prompt_leak: WARN from 20% of the prompt, BLOCK from 50%",
+		},
+	},
+	{
+		Version: "2026.10.2",
+		Date:    "2026-10-16",
+		Changes: []string{
+			"Tool-call scanning: rules for dangerous shell commands, sensitive paths and unsafe URLs in tool arguments",
+		},
+	},
+	{
+		Version: "2026.10.1",
+		Date:    "2026-10-16",
+		Changes: []string{
+			"ml layer runs a local ONNX prompt injection classifier from HUGOT_MODEL_PATH and can raise ALLOW to WARN or BLOCK",
+			"ml layer is reported only when a model is loaded",
+		},
+	},
+	{
+		Version: "2026.10.0",
+		Date:    "2026-10-16",
+		Changes: []string{
+			"Detection version reported in scan results, usage logs and GET /v1/detection/version",
+		},
+	},
+}
+
+// DetectionVersion describes the detection configuration that produced a
+// verdict. Version changes whenever the rules, the engine, the thresholds or
+// the enabled layers change.
+type DetectionVersion struct {
+	Version        string   `json:"version"` // "<rules>+<engine>.<config>", reported in every scan result
+	RulesVersion   string   `json:"rules_version"`
+	Engine         string   `json:"engine"`
+	EngineVersion  string   `json:"engine_version"`
+	Layers         []string `json:"layers"`
+	BlockThreshold float64  `json:"block_threshold"`
+	WarnThreshold  float64  `json:"warn_threshold"`
+}
+
+// newDetectionVersion describes a scanner's configuration
+func newDetectionVersion(s *Scanner) DetectionVersion {
+	layers := []string{"heuristic"}
+	if s.hugotEnabled {
+		layers = append(layers, "ml")
+	}
+	if s.hybridDetector != nil && s.semanticEnabled {
+		layers = append(layers, "semantic")
+	}
+	if s.hybridDetector != nil && s.llmEnabled {
+		layers = append(layers, "llm")
+	}
+
+	v := DetectionVersion{
+		RulesVersion:   RulesVersion,
+		Engine:         "citadel",
+		EngineVersion:  moduleVersion(citadelModule),
+		Layers:         layers,
+		BlockThreshold: s.config.BlockThreshold,
+		WarnThreshold:  s.config.WarnThreshold,
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%g|%g|%s", v.BlockThreshold, v.WarnThreshold, strings.Join(layers, ","))))
+	v.Version = fmt.Sprintf("%s+%s.%s", RulesVersion, shortModuleVersion(v.EngineVersion), hex.EncodeToString(sum[:4]))
+	return v
+}
+
+// moduleVersion returns the version of a dependency compiled into the binary
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// shortModuleVersion trims a pseudo-version to its commit hash
+// (v0.0.0-20260130015424-0bc706a84026 becomes 0bc706a84026)
+func shortModuleVersion(version string) string {
+	if parts := strings.Split(version, "-"); len(parts) >= 3 {
+		return parts[len(parts)-1]
+	}
+	return strings.TrimPrefix(version, "v")
+}
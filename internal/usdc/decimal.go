@@ -0,0 +1,87 @@
+package usdc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxDecimalPlaces is the finest precision a decimal amount may carry
+const maxDecimalPlaces = 6
+
+// ErrInvalidAmount is wrapped by every decimal amount parsing error
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseDecimal parses a human-readable USDC amount ("10.50") into MicroUSDC
+// exactly. Only digits with an optional fractional part of at most six
+// digits are accepted: signs, exponents, whitespace and finer precision are
+// rejected rather than rounded.
+func ParseDecimal(s string) (MicroUSDC, error) {
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasDot && !isDigits(frac)) {
+		return 0, fmt.Errorf("%w: %q is not a decimal like \"10.50\"", ErrInvalidAmount, s)
+	}
+	if len(frac) > maxDecimalPlaces {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, s, maxDecimalPlaces)
+	}
+
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || w > math.MaxInt64/Scale {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	var f int64
+	if frac != "" {
+		f, _ = strconv.ParseInt(frac+strings.Repeat("0", maxDecimalPlaces-len(frac)), 10, 64)
+	}
+	if w*Scale > math.MaxInt64-f {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	return MicroUSDC(w*Scale + f), nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// DecimalAmount is an amount in a request body, written in USDC as a
+// decimal string ("10.50"). For older clients it also accepts a JSON number
+// (10.5), which is parsed from its literal text rather than through float64
+// and marked Legacy so callers can deprecate it.
+type DecimalAmount struct {
+	Micro  MicroUSDC
+	Legacy bool // Sent as a JSON number
+}
+
+// UnmarshalJSON parses a decimal string or, as a legacy form, a JSON number
+func (a *DecimalAmount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	legacy := true
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+		legacy = false
+	}
+	m, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*a = DecimalAmount{Micro: m, Legacy: legacy}
+	return nil
+}
+
+// MarshalJSON writes the amount as a decimal string
+func (a DecimalAmount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.Micro.String() + `"`), nil
+}
@@ -256,3 +256,50 @@ func TestMicroUSDCScan_NilReceiver(t *testing.T) {
 	err := m.Scan(int64(1))
 	assert.Error(t, err)
 }
+
+func TestParseDecimal(t *testing.T) {
+	valid := map[string]MicroUSDC{
+		"10":                   10_000_000,
+		"10.5":                 10_500_000,
+		"10.50":                10_500_000,
+		"0.000001":             1,
+		"0.001":                1_000,
+		"00.10":                100_000,
+		"10000.99":             10_000_990_000,
+		"9223372036854.775807": math.MaxInt64,
+	}
+	for input, want := range valid {
+		got, err := ParseDecimal(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", ".5", "10.", "-1", "+1", "1e3", " 10", "10.0000001", "1,000", "NaN", "9223372036855"} {
+		_, err := ParseDecimal(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestDecimalAmount_UnmarshalJSON(t *testing.T) {
+	var req struct {
+		Amount DecimalAmount `json:"amount"`
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"10.50"}`), &req))
+	assert.Equal(t, MicroUSDC(10_500_000), req.Amount.Micro)
+	assert.False(t, req.Amount.Legacy)
+
+	// Numbers are parsed from their text, so 0.1 is exact
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.1}`), &req))
+	assert.Equal(t, MicroUSDC(100_000), req.Amount.Micro)
+	assert.True(t, req.Amount.Legacy)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":""}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"10.1234567"}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":1e2}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":-5}`), &req))
+
+	b, err := json.Marshal(DecimalAmount{Micro: 10_500_000})
+	require.NoError(t, err)
+	assert.Equal(t, `"10.50"`, string(b))
+}
@@ -19,35 +19,16 @@ import (
 //
 // Supported networks: "base", "base-sepolia".
 func QueryEVMBalance(ctx context.Context, address string, network string) (float64, error) {
-	rpcURL, usdcAddr, err := evmNetworkParams(network)
+	usdcAddr, err := evmUSDCAddress(network)
 	if err != nil {
 		return 0, err
 	}
 
-	client, err := ethclient.DialContext(ctx, rpcURL)
+	balance, err := evmUSDCBalance(ctx, network, usdcAddr, common.HexToAddress(address))
 	if err != nil {
-		return 0, fmt.Errorf("failed to connect to %s RPC: %w", network, err)
-	}
-	defer client.Close()
-
-	addr := common.HexToAddress(address)
-
-	// Build balanceOf(address) call data
-	data := append(usdcBalanceOfSelector, common.LeftPadBytes(addr.Bytes(), 32)...)
-
-	msg := map[string]interface{}{
-		"to":   usdcAddr,
-		"data": hex.EncodeToString(data),
-	}
-
-	var result string
-	if err := client.Client().CallContext(ctx, &result, "eth_call", msg, "latest"); err != nil {
-		return 0, fmt.Errorf("eth_call balanceOf failed: %w", err)
+		return 0, err
 	}
 
-	balance := new(big.Int)
-	balance.SetString(strings.TrimPrefix(result, "0x"), 16)
-
 	// USDC has 6 decimals
 	balanceFloat := new(big.Float).SetInt(balance)
 	divisor := big.NewFloat(1_000_000)
@@ -61,13 +42,11 @@ func QueryEVMBalance(ctx context.Context, address string, network string) (float
 //
 // Supported networks: "solana", "solana-devnet".
 func QuerySolanaBalance(ctx context.Context, address string, network string) (float64, error) {
-	rpcURL, usdcMint, err := solanaNetworkParams(network)
+	usdcMint, err := solanaUSDCMint(network)
 	if err != nil {
 		return 0, err
 	}
 
-	client := rpc.New(rpcURL)
-
 	ownerPubkey, err := solana.PublicKeyFromBase58(address)
 	if err != nil {
 		return 0, fmt.Errorf("invalid Solana address %q: %w", address, err)
@@ -83,19 +62,9 @@ func QuerySolanaBalance(ctx context.Context, address string, network string) (fl
 		return 0, fmt.Errorf("failed to derive ATA: %w", err)
 	}
 
-	result, err := client.GetTokenAccountBalance(ctx, ata, rpc.CommitmentFinalized)
+	balance, err := solanaTokenBalance(ctx, network, ata)
 	if err != nil {
-		// If the ATA doesn't exist yet the balance is zero.
-		if strings.Contains(err.Error(), "could not find account") ||
-			strings.Contains(err.Error(), "Invalid param") {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to get token balance: %w", err)
-	}
-
-	balance := new(big.Int)
-	if _, ok := balance.SetString(result.Value.Amount, 10); !ok {
-		return 0, fmt.Errorf("failed to parse balance: %s", result.Value.Amount)
+		return 0, err
 	}
 
 	// USDC has 6 decimals on Solana
@@ -106,28 +75,90 @@ func QuerySolanaBalance(ctx context.Context, address string, network string) (fl
 	return human, nil
 }
 
-// evmNetworkParams returns the RPC URL and USDC contract address for the given
-// EVM network name.
-func evmNetworkParams(network string) (rpcURL string, usdcAddr string, err error) {
+// evmUSDCBalance reads an address's USDC balance in base units through the
+// network's RPC providers
+func evmUSDCBalance(ctx context.Context, network, usdcAddr string, addr common.Address) (*big.Int, error) {
+	// Build balanceOf(address) call data
+	data := append(usdcBalanceOfSelector, common.LeftPadBytes(addr.Bytes(), 32)...)
+
+	msg := map[string]interface{}{
+		"to":   usdcAddr,
+		"data": hex.EncodeToString(data),
+	}
+
+	var result string
+	err := defaultProviders.Do(ctx, network, func(ctx context.Context, rpcURL string) error {
+		client, err := ethclient.DialContext(ctx, rpcURL)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s RPC: %w", network, err)
+		}
+		defer client.Close()
+
+		if err := client.Client().CallContext(ctx, &result, "eth_call", msg, "latest"); err != nil {
+			return fmt.Errorf("eth_call balanceOf failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	balance := new(big.Int)
+	balance.SetString(strings.TrimPrefix(result, "0x"), 16)
+	return balance, nil
+}
+
+// solanaTokenBalance reads a token account's balance in base units through
+// the network's RPC providers. A token account that doesn't exist yet holds
+// nothing.
+func solanaTokenBalance(ctx context.Context, network string, ata solana.PublicKey) (*big.Int, error) {
+	var amount string
+	err := defaultProviders.Do(ctx, network, func(ctx context.Context, rpcURL string) error {
+		result, err := rpc.New(rpcURL).GetTokenAccountBalance(ctx, ata, rpc.CommitmentFinalized)
+		if err != nil {
+			// If the ATA doesn't exist yet the balance is zero.
+			if strings.Contains(err.Error(), "could not find account") ||
+				strings.Contains(err.Error(), "Invalid param") {
+				amount = "0"
+				return nil
+			}
+			return fmt.Errorf("failed to get token balance: %w", err)
+		}
+		amount = result.Value.Amount
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	balance := new(big.Int)
+	if _, ok := balance.SetString(amount, 10); !ok {
+		return nil, fmt.Errorf("failed to parse balance: %s", amount)
+	}
+	return balance, nil
+}
+
+// evmUSDCAddress returns the USDC contract address for the given EVM network
+// name.
+func evmUSDCAddress(network string) (string, error) {
 	switch network {
 	case "base":
-		return BaseMainnetRPC, USDCBaseAddress, nil
+		return USDCBaseAddress, nil
 	case "base-sepolia":
-		return BaseSepoliaRPC, x402NetworkConfigs["base-sepolia"].TokenAddress, nil
+		return x402NetworkConfigs["base-sepolia"].TokenAddress, nil
 	default:
-		return "", "", fmt.Errorf("unsupported EVM network: %s", network)
+		return "", fmt.Errorf("unsupported EVM network: %s", network)
 	}
 }
 
-// solanaNetworkParams returns the RPC URL and USDC mint for the given Solana
-// network name.
-func solanaNetworkParams(network string) (rpcURL string, usdcMint string, err error) {
+// solanaUSDCMint returns the USDC mint for the given Solana network name.
+func solanaUSDCMint(network string) (string, error) {
 	switch network {
 	case "solana":
-		return SolanaMainnetRPC, USDCSolanaMint, nil
+		return USDCSolanaMint, nil
 	case "solana-devnet":
-		return SolanaDevnetRPC, USDCSolanaDevnetMint, nil
+		return USDCSolanaDevnetMint, nil
 	default:
-		return "", "", fmt.Errorf("unsupported Solana network: %s", network)
+		return "", fmt.Errorf("unsupported Solana network: %s", network)
 	}
 }
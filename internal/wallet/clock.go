@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// MaxClockCorrection bounds how far payment timestamps are shifted toward
+// the server's clock. A larger shift would let a payment stay valid for more
+// than PaymentValidity past the moment it was signed.
+const MaxClockCorrection = PaymentValidity
+
+// clockSkewTolerance is how far a measured skew may drift from the current
+// correction before the correction is changed. Date headers only carry whole
+// seconds, so smaller differences are noise.
+const clockSkewTolerance = 2 * time.Second
+
+// clockOffset is added to the local clock when stamping payments
+var clockOffset atomic.Int64
+
+// paymentTime is the time payments are stamped with: the local clock,
+// corrected toward the server's
+func paymentTime() time.Time {
+	return timeNow().Add(ClockOffset())
+}
+
+// ClockOffset returns how far payment timestamps are shifted from the local
+// clock
+func ClockOffset() time.Duration {
+	return time.Duration(clockOffset.Load())
+}
+
+// SetClockOffset shifts payment timestamps by offset, clamped to
+// MaxClockCorrection either way, and returns the offset applied
+func SetClockOffset(offset time.Duration) time.Duration {
+	offset = min(max(offset, -MaxClockCorrection), MaxClockCorrection)
+	clockOffset.Store(int64(offset))
+	return offset
+}
+
+// MeasureClockSkew estimates how far the local clock is ahead of (positive)
+// or behind (negative) a server's, from the Date header of its response to a
+// request sent at sent and answered at received. The server is taken to be
+// halfway through the second its header reports, at the middle of the round
+// trip.
+func MeasureClockSkew(date string, sent, received time.Time) (time.Duration, error) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", date, err)
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(serverTime.Add(500 * time.Millisecond)).Round(time.Second), nil
+}
+
+// ObserveServerDate corrects payment timestamps for the skew measured from
+// a server response, so payments signed on a clock that is off still fall in
+// the server's validity window. Skews within clockSkewTolerance of the
+// current correction leave it unchanged. Returns the measured skew, and
+// false when the response carried no usable Date header.
+func ObserveServerDate(date string, sent, received time.Time) (time.Duration, bool) {
+	if date == "" {
+		return 0, false
+	}
+	skew, err := MeasureClockSkew(date, sent, received)
+	if err != nil {
+		return 0, false
+	}
+	if (-skew - ClockOffset()).Abs() > clockSkewTolerance {
+		SetClockOffset(-skew)
+	}
+	return skew, true
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasureClockSkew(t *testing.T) {
+	server := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	date := server.Format(http.TimeFormat)
+
+	// A local clock two minutes behind, with a 200ms round trip
+	sent := server.Add(-2 * time.Minute).Add(400 * time.Millisecond)
+	skew, err := MeasureClockSkew(date, sent, sent.Add(200*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, -2*time.Minute, skew)
+
+	_, err = MeasureClockSkew("yesterday", sent, sent)
+	assert.Error(t, err)
+}
+
+func TestObserveServerDate(t *testing.T) {
+	defer SetClockOffset(0)
+	origNow := timeNow
+	defer func() { timeNow = origNow }()
+
+	local := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return local }
+	// Responses arrive mid-second, as the Date header is assumed to be
+	observe := func(server time.Time) (time.Duration, bool) {
+		received := local.Add(500 * time.Millisecond)
+		return ObserveServerDate(server.Format(http.TimeFormat), received, received)
+	}
+
+	// The local clock is three minutes behind, so payments are stamped ahead
+	skew, ok := observe(local.Add(3 * time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, -3*time.Minute, skew)
+	assert.Equal(t, 3*time.Minute, ClockOffset())
+	assert.Equal(t, local.Add(3*time.Minute), paymentTime())
+
+	// Jitter within the tolerance keeps the correction
+	observe(local.Add(3*time.Minute + time.Second))
+	assert.Equal(t, 3*time.Minute, ClockOffset())
+
+	// The correction is bounded
+	observe(local.Add(-time.Hour))
+	assert.Equal(t, -MaxClockCorrection, ClockOffset())
+
+	_, ok = ObserveServerDate("", local, local)
+	assert.False(t, ok)
+	assert.Equal(t, -MaxClockCorrection, ClockOffset())
+}
@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rpcFailureBackoff is how long an endpoint is skipped after a failure,
+	// doubled for each consecutive failure up to rpcMaxBackoff
+	rpcFailureBackoff = 5 * time.Second
+	// rpcRateLimitBackoff is how long a rate limited endpoint is skipped,
+	// doubled for each consecutive rate limit up to rpcMaxBackoff
+	rpcRateLimitBackoff = 2 * time.Second
+	rpcMaxBackoff       = time.Minute
+	// rpcLatencyWeight is the weight of the newest sample in an endpoint's
+	// moving average latency
+	rpcLatencyWeight = 0.3
+)
+
+// DefaultRPCURLs returns the public RPC endpoints used for a network when no
+// providers are configured
+func DefaultRPCURLs(network string) []string {
+	switch network {
+	case "base":
+		return []string{BaseMainnetRPC}
+	case "base-sepolia":
+		return []string{BaseSepoliaRPC}
+	case "solana":
+		return []string{SolanaMainnetRPC}
+	case "solana-devnet":
+		return []string{SolanaDevnetRPC}
+	default:
+		return nil
+	}
+}
+
+// RPCEndpointStatus is the health of one RPC endpoint
+type RPCEndpointStatus struct {
+	URL          string
+	Failures     int           // Consecutive failed calls
+	Latency      time.Duration // Moving average of successful calls
+	BackoffUntil time.Time     // Zero unless the endpoint is being skipped
+	LastError    string
+}
+
+type rpcEndpoint struct {
+	RPCEndpointStatus
+	rateLimits int
+}
+
+// RPCProviders holds the RPC endpoints of each network and scores their
+// health. Calls go to the healthiest endpoint first and fail over to the
+// next on error; endpoints that fail or rate limit are skipped for a
+// growing backoff. Safe for concurrent use.
+type RPCProviders struct {
+	mu        sync.Mutex
+	endpoints map[string][]*rpcEndpoint
+	now       func() time.Time
+}
+
+// NewRPCProviders returns providers using the default public endpoints
+func NewRPCProviders() *RPCProviders {
+	return &RPCProviders{
+		endpoints: make(map[string][]*rpcEndpoint),
+		now:       time.Now,
+	}
+}
+
+// defaultProviders serves the package's balance queries and wallets
+var defaultProviders = NewRPCProviders()
+
+// Providers returns the RPC providers used by balance queries and wallets
+func Providers() *RPCProviders {
+	return defaultProviders
+}
+
+// SetURLs replaces a network's endpoints, in order of preference. An empty
+// list restores the default public endpoint.
+func (p *RPCProviders) SetURLs(network string, urls []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(urls) == 0 {
+		delete(p.endpoints, network)
+		return
+	}
+	endpoints := make([]*rpcEndpoint, 0, len(urls))
+	for _, url := range urls {
+		if url = strings.TrimSpace(url); url != "" {
+			endpoints = append(endpoints, &rpcEndpoint{RPCEndpointStatus: RPCEndpointStatus{URL: url}})
+		}
+	}
+	p.endpoints[network] = endpoints
+}
+
+// network returns a network's endpoints, creating the defaults on first use.
+// Callers must hold p.mu.
+func (p *RPCProviders) network(network string) []*rpcEndpoint {
+	if endpoints, ok := p.endpoints[network]; ok {
+		return endpoints
+	}
+	var endpoints []*rpcEndpoint
+	for _, url := range DefaultRPCURLs(network) {
+		endpoints = append(endpoints, &rpcEndpoint{RPCEndpointStatus: RPCEndpointStatus{URL: url}})
+	}
+	if endpoints != nil {
+		p.endpoints[network] = endpoints
+	}
+	return endpoints
+}
+
+// ranked returns a network's endpoints healthiest first: endpoints not in
+// backoff, then fewer consecutive failures, then lower latency. Endpoints
+// in backoff are still returned last, so a call is attempted even when every
+// endpoint is struggling.
+func (p *RPCProviders) ranked(network string) []*rpcEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	endpoints := append([]*rpcEndpoint(nil), p.network(network)...)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		aBackoff, bBackoff := now.Before(a.BackoffUntil), now.Before(b.BackoffUntil)
+		if aBackoff != bBackoff {
+			return !aBackoff
+		}
+		if aBackoff {
+			return a.BackoffUntil.Before(b.BackoffUntil)
+		}
+		if a.Failures != b.Failures {
+			return a.Failures < b.Failures
+		}
+		return a.Latency < b.Latency
+	})
+	return endpoints
+}
+
+// URLs returns a network's endpoint URLs, healthiest first
+func (p *RPCProviders) URLs(network string) []string {
+	endpoints := p.ranked(network)
+	urls := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		urls[i] = e.URL
+	}
+	return urls
+}
+
+// Status returns the health of a network's endpoints in configured order
+func (p *RPCProviders) Status(network string) []RPCEndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	endpoints := p.network(network)
+	status := make([]RPCEndpointStatus, len(endpoints))
+	for i, e := range endpoints {
+		status[i] = e.RPCEndpointStatus
+	}
+	return status
+}
+
+// Do calls fn with each of a network's endpoints, healthiest first, until one
+// succeeds. It returns the last error when every endpoint fails. Errors
+// caused by ctx ending are returned without trying further endpoints or
+// counting against the endpoint.
+func (p *RPCProviders) Do(ctx context.Context, network string, fn func(ctx context.Context, url string) error) error {
+	endpoints := p.ranked(network)
+	if len(endpoints) == 0 {
+		return fmt.Errorf("no RPC endpoints configured for network: %s", network)
+	}
+	var lastErr error
+	for _, e := range endpoints {
+		start := p.now()
+		err := fn(ctx, e.URL)
+		if err == nil {
+			p.succeeded(e, p.now().Sub(start))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		p.failed(e, err)
+		lastErr = fmt.Errorf("%s: %w", e.URL, err)
+	}
+	return lastErr
+}
+
+func (p *RPCProviders) succeeded(e *rpcEndpoint, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e.Latency == 0 {
+		e.Latency = latency
+	} else {
+		e.Latency = time.Duration(rpcLatencyWeight*float64(latency) + (1-rpcLatencyWeight)*float64(e.Latency))
+	}
+	e.Failures = 0
+	e.rateLimits = 0
+	e.BackoffUntil = time.Time{}
+	e.LastError = ""
+}
+
+func (p *RPCProviders) failed(e *rpcEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.Failures++
+	e.LastError = err.Error()
+	backoff := rpcBackoff(rpcFailureBackoff, e.Failures)
+	if IsRateLimited(err) {
+		e.rateLimits++
+		backoff = rpcBackoff(rpcRateLimitBackoff, e.rateLimits)
+	}
+	e.BackoffUntil = p.now().Add(backoff)
+}
+
+// IsRateLimited reports whether an RPC error means the endpoint is rate
+// limiting us
+func IsRateLimited(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "429") ||
+		strings.Contains(msg, "too many requests") ||
+		strings.Contains(msg, "rate limit")
+}
+
+// rpcBackoff doubles base for each attempt after the first, up to rpcMaxBackoff
+func rpcBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < rpcMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, rpcMaxBackoff)
+}
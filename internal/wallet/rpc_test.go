@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProviders(urls ...string) (*RPCProviders, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := NewRPCProviders()
+	p.now = func() time.Time { return now }
+	p.SetURLs("base", urls)
+	return p, &now
+}
+
+func TestRPCProviders_Defaults(t *testing.T) {
+	p := NewRPCProviders()
+	assert.Equal(t, []string{BaseMainnetRPC}, p.URLs("base"))
+	assert.Equal(t, []string{SolanaDevnetRPC}, p.URLs("solana-devnet"))
+	assert.Empty(t, p.URLs("ethereum"))
+
+	p.SetURLs("base", []string{"https://a.example", " ", "https://b.example"})
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, p.URLs("base"))
+
+	p.SetURLs("base", nil)
+	assert.Equal(t, []string{BaseMainnetRPC}, p.URLs("base"))
+
+	err := p.Do(context.Background(), "ethereum", func(context.Context, string) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestRPCProviders_FailsOver(t *testing.T) {
+	p, now := newTestProviders("https://a.example", "https://b.example")
+
+	var tried []string
+	err := p.Do(context.Background(), "base", func(_ context.Context, url string) error {
+		tried = append(tried, url)
+		if url == "https://a.example" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, tried)
+
+	// The failed endpoint is skipped until its backoff ends
+	assert.Equal(t, []string{"https://b.example", "https://a.example"}, p.URLs("base"))
+	status := p.Status("base")
+	assert.Equal(t, 1, status[0].Failures)
+	assert.Equal(t, "connection refused", status[0].LastError)
+	assert.Equal(t, now.Add(rpcFailureBackoff), status[0].BackoffUntil)
+	assert.Zero(t, status[1].Failures)
+
+	// After its backoff it still ranks below the healthy endpoint
+	*now = now.Add(time.Minute)
+	assert.Equal(t, []string{"https://b.example", "https://a.example"}, p.URLs("base"))
+
+	// A success clears its failures
+	err = p.Do(context.Background(), "base", func(_ context.Context, url string) error {
+		if url == "https://b.example" {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	status = p.Status("base")
+	assert.Zero(t, status[0].Failures)
+	assert.True(t, status[0].BackoffUntil.IsZero())
+}
+
+func TestRPCProviders_AllFail(t *testing.T) {
+	p, _ := newTestProviders("https://a.example", "https://b.example")
+
+	calls := 0
+	err := p.Do(context.Background(), "base", func(context.Context, string) error {
+		calls++
+		return errors.New("boom")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "https://b.example")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRPCProviders_CanceledContext(t *testing.T) {
+	p, _ := newTestProviders("https://a.example", "https://b.example")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := p.Do(ctx, "base", func(ctx context.Context, _ string) error {
+		calls++
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+	assert.Zero(t, p.Status("base")[0].Failures)
+}
+
+func TestRPCProviders_RateLimitBackoff(t *testing.T) {
+	p, now := newTestProviders("https://a.example")
+
+	rateLimited := func(context.Context, string) error { return errors.New("429 Too Many Requests") }
+	_ = p.Do(context.Background(), "base", rateLimited)
+	assert.Equal(t, now.Add(rpcRateLimitBackoff), p.Status("base")[0].BackoffUntil)
+	_ = p.Do(context.Background(), "base", rateLimited)
+	assert.Equal(t, now.Add(2*rpcRateLimitBackoff), p.Status("base")[0].BackoffUntil)
+	for range 10 {
+		_ = p.Do(context.Background(), "base", rateLimited)
+	}
+	assert.Equal(t, now.Add(rpcMaxBackoff), p.Status("base")[0].BackoffUntil)
+}
+
+func TestRPCProviders_PrefersLowerLatency(t *testing.T) {
+	p, now := newTestProviders("https://slow.example", "https://fast.example")
+
+	latency := map[string]time.Duration{
+		"https://slow.example": 800 * time.Millisecond,
+		"https://fast.example": 100 * time.Millisecond,
+	}
+	var served []string
+	call := func(_ context.Context, url string) error {
+		*now = now.Add(latency[url])
+		served = append(served, url)
+		return nil
+	}
+	// Endpoints without a latency yet are tried before measured ones
+	for range 3 {
+		require.NoError(t, p.Do(context.Background(), "base", call))
+	}
+	assert.Equal(t, []string{"https://slow.example", "https://fast.example", "https://fast.example"}, served)
+
+	status := p.Status("base")
+	assert.Equal(t, 800*time.Millisecond, status[0].Latency)
+	assert.Equal(t, 100*time.Millisecond, status[1].Latency)
+}
+
+func TestIsRateLimited(t *testing.T) {
+	assert.True(t, IsRateLimited(errors.New("429 Too Many Requests: slow down")))
+	assert.True(t, IsRateLimited(errors.New("rate limit exceeded")))
+	assert.False(t, IsRateLimited(errors.New("connection refused")))
+	assert.False(t, IsRateLimited(context.Canceled))
+	assert.False(t, IsRateLimited(nil))
+}
+
+func TestQueryEVMBalance_FailsOverToHealthyEndpoint(t *testing.T) {
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		// 2.5 USDC in base units
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  "0x00000000000000000000000000000000000000000000000000000000002625a0",
+		})
+	}))
+	defer healthy.Close()
+
+	defaultProviders.SetURLs("base-sepolia", []string{limited.URL, healthy.URL})
+	defer defaultProviders.SetURLs("base-sepolia", nil)
+
+	balance, err := QueryEVMBalance(context.Background(), "0x000000000000000000000000000000000000dEaD", "base-sepolia")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, balance)
+
+	status := defaultProviders.Status("base-sepolia")
+	assert.Equal(t, 1, status[0].Failures)
+	assert.True(t, IsRateLimited(errors.New(status[0].LastError)))
+	assert.Zero(t, status[1].Failures)
+}
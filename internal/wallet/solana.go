@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"bytes"
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
@@ -8,7 +9,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"strings"
+
+	"stronghold/internal/secure"
 
 	"github.com/99designs/keyring"
 	"github.com/gagliardetto/solana-go"
@@ -36,11 +38,11 @@ const (
 // SolanaWallet represents a Solana wallet using Ed25519 keypair.
 // Private keys are stored in the OS keyring, same as the EVM wallet.
 type SolanaWallet struct {
-	PublicKey solana.PublicKey
-	userID    string
-	keyring   keyring.Keyring
-	network   string
-	rpcURL    string
+	PublicKey  solana.PublicKey
+	userID     string
+	keyring    keyring.Keyring
+	network    string
+	rpcNetwork string // Network whose RPC providers serve this wallet
 }
 
 // SolanaConfig holds Solana wallet configuration
@@ -51,9 +53,9 @@ type SolanaConfig struct {
 
 // NewSolana creates or loads a Solana wallet for the given user
 func NewSolana(cfg SolanaConfig) (*SolanaWallet, error) {
-	rpcURL := SolanaMainnetRPC
+	rpcNetwork := "solana"
 	if cfg.Network == "solana-devnet" {
-		rpcURL = SolanaDevnetRPC
+		rpcNetwork = "solana-devnet"
 	}
 
 	ring, err := openKeyring()
@@ -62,10 +64,10 @@ func NewSolana(cfg SolanaConfig) (*SolanaWallet, error) {
 	}
 
 	w := &SolanaWallet{
-		userID:  cfg.UserID,
-		keyring: ring,
-		network: cfg.Network,
-		rpcURL:  rpcURL,
+		userID:     cfg.UserID,
+		keyring:    ring,
+		network:    cfg.Network,
+		rpcNetwork: rpcNetwork,
 	}
 
 	// Try to load existing wallet
@@ -124,12 +126,13 @@ func (w *SolanaWallet) Import(privateKeyBase58 string) (*SolanaWallet, error) {
 }
 
 // Export returns the private key as a base58 string
-func (w *SolanaWallet) Export() (string, error) {
+// The caller must Zero the returned secret when done.
+func (w *SolanaWallet) Export() (*secure.SecretString, error) {
 	item, err := w.keyring.Get(w.keyID())
 	if err != nil {
-		return "", fmt.Errorf("wallet not found: %w", err)
+		return nil, fmt.Errorf("wallet not found: %w", err)
 	}
-	return string(item.Data), nil
+	return secure.NewSecretStringFromBytes(bytes.Clone(item.Data)), nil
 }
 
 // AddressString returns the base58-encoded public key
@@ -159,8 +162,6 @@ func (w *SolanaWallet) GetBalance(ctx context.Context) (*big.Int, error) {
 		return nil, fmt.Errorf("wallet not initialized")
 	}
 
-	client := rpc.New(w.rpcURL)
-
 	mintPubkey := solana.MustPublicKeyFromBase58(w.usdcMint())
 
 	// Find the associated token account for USDC
@@ -169,23 +170,7 @@ func (w *SolanaWallet) GetBalance(ctx context.Context) (*big.Int, error) {
 		return nil, fmt.Errorf("failed to derive ATA: %w", err)
 	}
 
-	// Get token account balance
-	result, err := client.GetTokenAccountBalance(ctx, ata, rpc.CommitmentFinalized)
-	if err != nil {
-		// If account doesn't exist, balance is 0
-		if strings.Contains(err.Error(), "could not find account") ||
-			strings.Contains(err.Error(), "Invalid param") {
-			return big.NewInt(0), nil
-		}
-		return nil, fmt.Errorf("failed to get token balance: %w", err)
-	}
-
-	balance := new(big.Int)
-	if _, ok := balance.SetString(result.Value.Amount, 10); !ok {
-		return nil, fmt.Errorf("failed to parse balance: %s", result.Value.Amount)
-	}
-
-	return balance, nil
+	return solanaTokenBalance(ctx, w.rpcNetwork, ata)
 }
 
 // GetBalanceHuman returns the USDC balance as a human-readable float
@@ -226,9 +211,10 @@ func (w *SolanaWallet) CreateX402Payment(req *PaymentRequirements) (string, erro
 	if err != nil {
 		return "", fmt.Errorf("failed to get private key: %w", err)
 	}
+	defer privKey.Zero()
 
 	// Build the Solana transaction
-	txBase64, err := w.buildTransferTransaction(req, x402Config, amount, privKey)
+	txBase64, err := w.buildTransferTransaction(req, x402Config, amount, ed25519.PrivateKey(privKey.Bytes()))
 	if err != nil {
 		return "", fmt.Errorf("failed to build transaction: %w", err)
 	}
@@ -247,7 +233,7 @@ func (w *SolanaWallet) CreateX402Payment(req *PaymentRequirements) (string, erro
 		Receiver:     req.Recipient,
 		TokenAddress: x402Config.TokenAddress,
 		Amount:       req.Amount,
-		Timestamp:    timeNow().Unix(),
+		Timestamp:    paymentTime().Unix(),
 		Nonce:        nonce,
 		Transaction:  txBase64,
 	}
@@ -264,8 +250,6 @@ func (w *SolanaWallet) CreateX402Payment(req *PaymentRequirements) (string, erro
 // buildTransferTransaction constructs a Solana SPL Token transfer transaction
 func (w *SolanaWallet) buildTransferTransaction(req *PaymentRequirements, x402Config X402Config, amount *big.Int, privKey ed25519.PrivateKey) (string, error) {
 	ctx := context.Background()
-	client := rpc.New(w.rpcURL)
-
 	mintPubkey := solana.MustPublicKeyFromBase58(x402Config.TokenAddress)
 	recipientPubkey := solana.MustPublicKeyFromBase58(req.Recipient)
 
@@ -282,7 +266,14 @@ func (w *SolanaWallet) buildTransferTransaction(req *PaymentRequirements, x402Co
 	}
 
 	// Get recent blockhash
-	recentBlockhash, err := client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// The rest of the transaction is built against the endpoint that served it
+	var client *rpc.Client
+	var recentBlockhash *rpc.GetLatestBlockhashResult
+	err = defaultProviders.Do(ctx, w.rpcNetwork, func(ctx context.Context, rpcURL string) error {
+		client = rpc.New(rpcURL)
+		recentBlockhash, err = client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to get blockhash: %w", err)
 	}
@@ -394,7 +385,8 @@ func (w *SolanaWallet) load() error {
 	return nil
 }
 
-func (w *SolanaWallet) getPrivateKey() (ed25519.PrivateKey, error) {
+// getPrivateKey decodes the stored key into a buffer the caller must Zero
+func (w *SolanaWallet) getPrivateKey() (*secure.SecretBytes, error) {
 	item, err := w.keyring.Get(w.keyID())
 	if err != nil {
 		return nil, fmt.Errorf("wallet not found: %w", err)
@@ -405,11 +397,13 @@ func (w *SolanaWallet) getPrivateKey() (ed25519.PrivateKey, error) {
 		return nil, fmt.Errorf("failed to decode key: %w", err)
 	}
 
-	if len(privKeyBytes) != ed25519.PrivateKeySize {
+	key := secure.NewSecretBytes(privKeyBytes)
+	if key.Len() != ed25519.PrivateKeySize {
+		key.Zero()
 		return nil, fmt.Errorf("invalid key length")
 	}
 
-	return ed25519.PrivateKey(privKeyBytes), nil
+	return key, nil
 }
 
 func (w *SolanaWallet) usdcMint() string {
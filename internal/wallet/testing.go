@@ -94,7 +94,7 @@ func (w *TestWallet) CreateX402Payment(req *PaymentRequirements) (string, error)
 
 	timestamp := time.Now().Unix()
 	validAfter := int64(0)
-	validBefore := timestamp + 300 // 5 minute validity window
+	validBefore := timestamp + int64(PaymentValidity/time.Second)
 
 	// Parse amount as big.Int
 	amount := new(big.Int)
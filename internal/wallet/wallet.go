@@ -3,8 +3,11 @@
 package wallet
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"math/big"
@@ -13,12 +16,13 @@ import (
 	"runtime"
 	"strings"
 
+	"stronghold/internal/secure"
+
 	"github.com/99designs/keyring"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
@@ -40,7 +44,7 @@ type Wallet struct {
 	userID     string
 	keyring    keyring.Keyring
 	network    string
-	rpcURL     string
+	rpcNetwork string // Network whose RPC providers serve this wallet
 }
 
 // Config holds wallet configuration
@@ -51,10 +55,10 @@ type Config struct {
 
 // New creates or loads a wallet for the given user
 func New(cfg Config) (*Wallet, error) {
-	// Determine RPC URL
-	rpcURL := BaseMainnetRPC
+	// Determine which network's RPC providers to use
+	rpcNetwork := "base"
 	if cfg.Network == "base-sepolia" {
-		rpcURL = BaseSepoliaRPC
+		rpcNetwork = "base-sepolia"
 	}
 
 	// Open keyring with platform-specific configuration
@@ -64,10 +68,10 @@ func New(cfg Config) (*Wallet, error) {
 	}
 
 	w := &Wallet{
-		userID:  cfg.UserID,
-		keyring: ring,
-		network: cfg.Network,
-		rpcURL:  rpcURL,
+		userID:     cfg.UserID,
+		keyring:    ring,
+		network:    cfg.Network,
+		rpcNetwork: rpcNetwork,
 	}
 
 	// Try to load existing wallet
@@ -79,6 +83,11 @@ func New(cfg Config) (*Wallet, error) {
 	return w, nil
 }
 
+// OpenKeyring opens the OS keyring Stronghold keeps its secrets in
+func OpenKeyring() (keyring.Keyring, error) {
+	return openKeyring()
+}
+
 // openKeyring opens the OS keyring with appropriate configuration
 func openKeyring() (keyring.Keyring, error) {
 	// On Linux, check what's available and provide explicit errors
@@ -170,12 +179,8 @@ func (w *Wallet) Create() (*Wallet, error) {
 	w.Address = crypto.PubkeyToAddress(*publicKey)
 
 	// Store private key in keyring
-	privateKeyHex := hex.EncodeToString(crypto.FromECDSA(privateKey))
-	if err := w.keyring.Set(keyring.Item{
-		Key:  w.keyID(),
-		Data: []byte(privateKeyHex),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to store key: %w", err)
+	if err := w.storeKey(privateKey); err != nil {
+		return nil, err
 	}
 
 	return w, nil
@@ -191,11 +196,8 @@ func (w *Wallet) Import(privateKeyHex string) (*Wallet, error) {
 	publicKey := privateKey.Public().(*ecdsa.PublicKey)
 	w.Address = crypto.PubkeyToAddress(*publicKey)
 
-	if err := w.keyring.Set(keyring.Item{
-		Key:  w.keyID(),
-		Data: []byte(hex.EncodeToString(crypto.FromECDSA(privateKey))),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to store key: %w", err)
+	if err := w.storeKey(privateKey); err != nil {
+		return nil, err
 	}
 
 	return w, nil
@@ -213,13 +215,14 @@ func (w *Wallet) Exists() bool {
 }
 
 // Export returns the private key as a hex string
-// WARNING: Handle with extreme care - this exposes sensitive key material
-func (w *Wallet) Export() (string, error) {
+// WARNING: Handle with extreme care - this exposes sensitive key material.
+// The caller must Zero the returned secret when done.
+func (w *Wallet) Export() (*secure.SecretString, error) {
 	item, err := w.keyring.Get(w.keyID())
 	if err != nil {
-		return "", fmt.Errorf("wallet not found: %w", err)
+		return nil, fmt.Errorf("wallet not found: %w", err)
 	}
-	return string(item.Data), nil
+	return secure.NewSecretStringFromBytes(bytes.Clone(item.Data)), nil
 }
 
 // GetBalance returns the USDC balance for this wallet
@@ -228,31 +231,7 @@ func (w *Wallet) GetBalance(ctx context.Context) (*big.Int, error) {
 		return nil, fmt.Errorf("wallet not initialized")
 	}
 
-	client, err := ethclient.DialContext(ctx, w.rpcURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to network: %w", err)
-	}
-	defer client.Close()
-
-	// Build balanceOf call data
-	data := append(usdcBalanceOfSelector, common.LeftPadBytes(w.Address.Bytes(), 32)...)
-
-	// Call the contract
-	msg := map[string]interface{}{
-		"to":   USDCBaseAddress,
-		"data": hex.EncodeToString(data),
-	}
-	
-	var result string
-	if err := client.Client().CallContext(ctx, &result, "eth_call", msg, "latest"); err != nil {
-		return nil, fmt.Errorf("failed to get balance: %w", err)
-	}
-
-	// Parse result
-	balance := new(big.Int)
-	balance.SetString(strings.TrimPrefix(result, "0x"), 16)
-	
-	return balance, nil
+	return evmUSDCBalance(ctx, w.rpcNetwork, USDCBaseAddress, w.Address)
 }
 
 // GetBalanceHuman returns the USDC balance as a human-readable float
@@ -286,6 +265,25 @@ func (w *Wallet) Sign(data []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// DeriveKey derives a 32-byte symmetric key for purpose from the wallet's
+// private key. Every machine holding the wallet derives the same key, and
+// the key reveals nothing about the wallet. The caller must Zero it.
+func (w *Wallet) DeriveKey(purpose string) (*secure.SecretBytes, error) {
+	privateKey, err := w.getPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer w.zeroKey(privateKey)
+
+	raw := secure.NewSecretBytes(crypto.FromECDSA(privateKey))
+	defer raw.Zero()
+	key, err := hkdf.Key(sha256.New, raw.Bytes(), nil, purpose, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return secure.NewSecretBytes(key), nil
+}
+
 // SignEIP3009 signs an EIP-3009 TransferWithAuthorization using proper EIP-712 encoding
 // Reference implementation: https://github.com/brtvcl/eip-3009-transferWithAuthorization-example
 func (w *Wallet) SignEIP3009(chainID int64, tokenAddress, from, to string, value *big.Int, validAfter, validBefore int64, nonce []byte) ([]byte, error) {
@@ -371,10 +369,11 @@ func (w *Wallet) load() error {
 		return err
 	}
 
-	privateKey, err := crypto.HexToECDSA(string(item.Data))
+	privateKey, err := parseStoredKey(item.Data)
 	if err != nil {
 		return fmt.Errorf("failed to parse stored key: %w", err)
 	}
+	defer w.zeroKey(privateKey)
 
 	publicKey := privateKey.Public().(*ecdsa.PublicKey)
 	w.Address = crypto.PubkeyToAddress(*publicKey)
@@ -388,7 +387,7 @@ func (w *Wallet) getPrivateKey() (*ecdsa.PrivateKey, error) {
 		return nil, fmt.Errorf("wallet not found: %w", err)
 	}
 
-	privateKey, err := crypto.HexToECDSA(string(item.Data))
+	privateKey, err := parseStoredKey(item.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse key: %w", err)
 	}
@@ -396,6 +395,36 @@ func (w *Wallet) getPrivateKey() (*ecdsa.PrivateKey, error) {
 	return privateKey, nil
 }
 
+// storeKey writes the hex-encoded key to the keyring without passing it
+// through an immutable string. The encoded buffer belongs to the keyring
+// afterwards (in-memory backends keep it), so only the raw bytes are zeroed.
+func (w *Wallet) storeKey(privateKey *ecdsa.PrivateKey) error {
+	raw := secure.NewSecretBytes(crypto.FromECDSA(privateKey))
+	defer raw.Zero()
+	encoded := make([]byte, hex.EncodedLen(raw.Len()))
+	hex.Encode(encoded, raw.Bytes())
+
+	if err := w.keyring.Set(keyring.Item{
+		Key:  w.keyID(),
+		Data: encoded,
+	}); err != nil {
+		return fmt.Errorf("failed to store key: %w", err)
+	}
+	return nil
+}
+
+// parseStoredKey decodes a hex key read from the keyring. The decoded bytes
+// are zeroed once the key has been parsed; data is left to the keyring.
+func parseStoredKey(data []byte) (*ecdsa.PrivateKey, error) {
+	raw := secure.NewSecretBytes(make([]byte, hex.DecodedLen(len(data))))
+	defer raw.Zero()
+
+	if _, err := hex.Decode(raw.Bytes(), data); err != nil {
+		return nil, fmt.Errorf("invalid hex data for private key")
+	}
+	return crypto.ToECDSA(raw.Bytes())
+}
+
 func (w *Wallet) zeroKey(key *ecdsa.PrivateKey) {
 	// Zero out the key data for security
 	if key != nil && key.D != nil {
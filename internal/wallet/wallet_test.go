@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/99designs/keyring"
+)
+
+func TestWallet_DeriveKey(t *testing.T) {
+	newWallet := func(privateKeyHex string) *Wallet {
+		w := &Wallet{userID: "user", keyring: keyring.NewArrayKeyring(nil)}
+		if _, err := w.Import(privateKeyHex); err != nil {
+			t.Fatalf("Import() returned error: %v", err)
+		}
+		return w
+	}
+	const keyHex = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	a, b := newWallet(keyHex), newWallet("0x"+keyHex)
+
+	k1, err := a.DeriveKey("purpose one")
+	if err != nil {
+		t.Fatalf("DeriveKey() returned error: %v", err)
+	}
+	k2, _ := b.DeriveKey("purpose one")
+	other, _ := a.DeriveKey("purpose two")
+
+	if k1.Len() != 32 {
+		t.Errorf("expected a 32-byte key, got %d", k1.Len())
+	}
+	if !bytes.Equal(k1.Bytes(), k2.Bytes()) {
+		t.Error("expected the same key from the same wallet on another machine")
+	}
+	if bytes.Equal(k1.Bytes(), other.Bytes()) {
+		t.Error("expected a different key for another purpose")
+	}
+}
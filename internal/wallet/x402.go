@@ -64,6 +64,21 @@ type X402Payload struct {
 	Transaction  string `json:"transaction,omitempty"` // Solana: base64 encoded partially-signed transaction
 }
 
+// PaymentValidity is how long a signed payment stays valid after its
+// timestamp. EVM authorizations carry it as validBefore; the server refuses
+// older payments, so their nonces need not be remembered past it.
+const PaymentValidity = 5 * time.Minute
+
+// validBefore returns the end of the payment's validity window in Unix seconds
+func (p *X402Payload) validBefore() int64 {
+	return p.Timestamp + int64(PaymentValidity/time.Second)
+}
+
+// Expired reports whether the payment's validity window has passed
+func (p *X402Payload) Expired(now time.Time) bool {
+	return now.Unix() > p.validBefore()
+}
+
 // PaymentRequirements represents the 402 response from the server
 type PaymentRequirements struct {
 	Scheme         string `json:"scheme"`
@@ -140,6 +155,18 @@ func IsNetworkSupported(network string) bool {
 	return ok
 }
 
+// NetworkConfig returns the x402 configuration for a network, including its
+// USDC token address and (for EVM networks) chain ID
+func NetworkConfig(network string) (X402Config, bool) {
+	cfg, ok := x402NetworkConfigs[network]
+	return cfg, ok
+}
+
+// NetworkCAIP2 returns the CAIP-2 chain identifier for a network
+func NetworkCAIP2(network string) string {
+	return networkToCAIP2(network)
+}
+
 // IsSolanaNetwork returns true if the network is a Solana network
 func IsSolanaNetwork(network string) bool {
 	return network == "solana" || network == "solana-devnet" || strings.HasPrefix(network, "solana:")
@@ -171,9 +198,9 @@ func (w *Wallet) CreateX402Payment(req *PaymentRequirements) (string, error) {
 		return "", fmt.Errorf("failed to generate payment nonce: %w", err)
 	}
 
-	timestamp := timeNow().Unix()
+	timestamp := paymentTime().Unix()
 	validAfter := int64(0)
-	validBefore := timestamp + 300 // 5 minute validity window
+	validBefore := timestamp + int64(PaymentValidity/time.Second)
 
 	// Parse amount as big.Int
 	amount := new(big.Int)
@@ -246,9 +273,8 @@ func BuildFacilitatorRequest(payload *X402Payload, originalReq *PaymentRequireme
 
 // buildEVMFacilitatorRequest builds the facilitator request for EVM (Base) payments
 func buildEVMFacilitatorRequest(payload *X402Payload, caip2Network string) *FacilitatorRequest {
-	// Calculate validity window (5 minutes from timestamp)
 	validAfter := "0"
-	validBefore := fmt.Sprintf("%d", payload.Timestamp+300)
+	validBefore := fmt.Sprintf("%d", payload.validBefore())
 
 	// Format nonce with 0x prefix for EIP-3009
 	nonce := hexutil.Encode(common.FromHex(payload.Nonce))
@@ -336,7 +362,7 @@ func BuildFacilitatorRequestFromHeader(paymentHeader string, originalReq *Paymen
 func VerifyPaymentSignature(payload *X402Payload, expectedPayer string) error {
 	// Calculate validity window from timestamp
 	validAfter := int64(0)
-	validBefore := payload.Timestamp + 300
+	validBefore := payload.validBefore()
 
 	// Parse the amount
 	amount := new(big.Int)
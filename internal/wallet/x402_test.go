@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -97,3 +98,12 @@ func TestGetTokenDecimals(t *testing.T) {
 	// Unknown tokens default to 18
 	assert.Equal(t, 18, GetTokenDecimals("0x0000000000000000000000000000000000000000"))
 }
+
+func TestX402Payload_Expired(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	payload := &X402Payload{Timestamp: now.Unix()}
+
+	assert.False(t, payload.Expired(now))
+	assert.False(t, payload.Expired(now.Add(PaymentValidity)))
+	assert.True(t, payload.Expired(now.Add(PaymentValidity+time.Second)))
+}
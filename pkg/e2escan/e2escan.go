@@ -0,0 +1,258 @@
+// Package e2escan is the end-to-end encrypted scan protocol shared by the
+// API, the proxy and client SDKs. It keeps scanned content unreadable to
+// everything between a client and the scanning backend, such as load
+// balancers, TLS-terminating proxies and their logs.
+//
+// A client fetches a short-lived key from POST /v1/scan/keys, then seals
+// each scan request to it with a fresh X25519 key pair of its own:
+//
+//  1. shared = X25519(client private key, server public key)
+//  2. HKDF-SHA256(shared, salt = client public key || server public key,
+//     info = "stronghold e2e scan v1") gives 64 bytes: an AES-256-GCM key
+//     for the request and one for the response
+//  3. The JSON scan body is sealed with the request key, authenticating
+//     "<METHOD> <path>" as additional data, and sent as an Envelope with
+//     Content-Type ContentType
+//
+// The response comes back as an Envelope sealed with the response key,
+// authenticating "<METHOD> <path> <status>". Responses the backend didn't
+// produce, such as rate limits in front of it, are plain JSON.
+package e2escan
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// ContentType marks a request or response body that is an Envelope
+	ContentType = "application/vnd.stronghold.e2e+json"
+	// Algorithm names the key agreement, key derivation and cipher in use
+	Algorithm = "X25519-HKDF-SHA256-AES256GCM"
+	// CodeKeyRejected is the error code of a request sealed to a key the
+	// server no longer accepts; the client fetches a new key and retries
+	CodeKeyRejected = "e2e_key_rejected"
+)
+
+const (
+	sessionInfo = "stronghold e2e scan v1"
+	keyInfo     = "stronghold e2e scan key v1"
+	keyIDLen    = 24 // 16 random bytes and an 8-byte expiry
+)
+
+var (
+	// ErrKeyRejected means the envelope names a key that is malformed,
+	// expired or wasn't issued by this server
+	ErrKeyRejected = errors.New("scan key rejected")
+	// ErrDecrypt means an envelope failed authentication: it was altered,
+	// sealed to another key or sent to another endpoint
+	ErrDecrypt = errors.New("envelope failed to decrypt")
+)
+
+// Key is a server key for sealing scan requests, as returned by the
+// handshake endpoint
+type Key struct {
+	KeyID     string    `json:"key_id"`
+	PublicKey []byte    `json:"public_key"` // X25519, base64 in JSON
+	Algorithm string    `json:"algorithm"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Envelope is a sealed request or response body. KeyID and
+// EphemeralPublicKey are only set on requests.
+type Envelope struct {
+	KeyID              string `json:"key_id,omitempty"`
+	EphemeralPublicKey []byte `json:"ephemeral_public_key,omitempty"`
+	Nonce              []byte `json:"nonce"`
+	Ciphertext         []byte `json:"ciphertext"`
+}
+
+// Session holds the keys of one sealed request, to seal or open its
+// response
+type Session struct {
+	method, path string
+	request      cipher.AEAD
+	response     cipher.AEAD
+}
+
+// Seal encrypts a request body for method and path to the server key. The
+// returned session opens the response.
+func Seal(key *Key, method, path string, body []byte) (*Envelope, *Session, error) {
+	serverPub, err := ecdh.X25519().NewPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid server key: %w", err)
+	}
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, err := priv.ECDH(serverPub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid server key: %w", err)
+	}
+	clientPub := priv.PublicKey().Bytes()
+	s, err := newSession(shared, clientPub, key.PublicKey, method, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, ciphertext, err := seal(s.request, body, s.requestAD())
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Envelope{KeyID: key.KeyID, EphemeralPublicKey: clientPub, Nonce: nonce, Ciphertext: ciphertext}, s, nil
+}
+
+// SealResponse encrypts the response body sent with status
+func (s *Session) SealResponse(status int, body []byte) (*Envelope, error) {
+	nonce, ciphertext, err := seal(s.response, body, s.responseAD(status))
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{Nonce: nonce, Ciphertext: ciphertext}, nil
+}
+
+// OpenResponse decrypts a response body received with status
+func (s *Session) OpenResponse(status int, env *Envelope) ([]byte, error) {
+	return open(s.response, env, s.responseAD(status))
+}
+
+func (s *Session) requestAD() []byte {
+	return []byte(s.method + " " + s.path)
+}
+
+func (s *Session) responseAD(status int) []byte {
+	return []byte(s.method + " " + s.path + " " + strconv.Itoa(status))
+}
+
+// Keyring issues and opens scan keys. Keys aren't stored: each private key
+// is derived from the secret and the key ID, which carries the expiry, so
+// every server sharing the secret accepts keys any of them issued.
+type Keyring struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewKeyring creates a keyring issuing keys valid for ttl
+func NewKeyring(secret []byte, ttl time.Duration) *Keyring {
+	return &Keyring{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// Issue creates a new key
+func (k *Keyring) Issue() (*Key, error) {
+	id := make([]byte, keyIDLen)
+	if _, err := rand.Read(id[:16]); err != nil {
+		return nil, err
+	}
+	expires := k.now().Add(k.ttl).Truncate(time.Second)
+	binary.BigEndian.PutUint64(id[16:], uint64(expires.Unix()))
+
+	priv, err := k.derive(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		KeyID:     base64.RawURLEncoding.EncodeToString(id),
+		PublicKey: priv.PublicKey().Bytes(),
+		Algorithm: Algorithm,
+		ExpiresAt: expires.UTC(),
+	}, nil
+}
+
+// Open decrypts a request body sent to method and path. The returned
+// session seals the response.
+func (k *Keyring) Open(method, path string, env *Envelope) ([]byte, *Session, error) {
+	id, err := base64.RawURLEncoding.DecodeString(env.KeyID)
+	if err != nil || len(id) != keyIDLen {
+		return nil, nil, ErrKeyRejected
+	}
+	if expires := time.Unix(int64(binary.BigEndian.Uint64(id[16:])), 0); !k.now().Before(expires) {
+		return nil, nil, ErrKeyRejected
+	}
+	priv, err := k.derive(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientPub, err := ecdh.X25519().NewPublicKey(env.EphemeralPublicKey)
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	shared, err := priv.ECDH(clientPub)
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	s, err := newSession(shared, env.EphemeralPublicKey, priv.PublicKey().Bytes(), method, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := open(s.request, env, s.requestAD())
+	if err != nil {
+		// A key ID altered to extend its expiry derives another key, so
+		// it fails here too
+		return nil, nil, err
+	}
+	return body, s, nil
+}
+
+func (k *Keyring) derive(id []byte) (*ecdh.PrivateKey, error) {
+	seed, err := hkdf.Key(sha256.New, k.secret, id, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPrivateKey(seed)
+}
+
+func newSession(shared, clientPub, serverPub []byte, method, path string) (*Session, error) {
+	salt := append(bytes.Clone(clientPub), serverPub...)
+	keys, err := hkdf.Key(sha256.New, shared, salt, sessionInfo, 64)
+	if err != nil {
+		return nil, err
+	}
+	request, err := newGCM(keys[:32])
+	if err != nil {
+		return nil, err
+	}
+	response, err := newGCM(keys[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &Session{method: method, path: path, request: request, response: response}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, ad []byte) (nonce, ciphertext []byte, err error) {
+	nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, aead.Seal(nil, nonce, plaintext, ad), nil
+}
+
+func open(aead cipher.AEAD, env *Envelope, ad []byte) ([]byte, error) {
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
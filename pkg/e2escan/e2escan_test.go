@@ -0,0 +1,88 @@
+package e2escan
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestRoundTrip(t *testing.T) {
+	ring := NewKeyring(testSecret, time.Minute)
+	key, err := ring.Issue()
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm, key.Algorithm)
+
+	body := []byte(`{"text":"confidential merger memo"}`)
+	env, client, err := Seal(key, "POST", "/v1/scan/content", body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(env.Ciphertext), "merger")
+
+	// Another server sharing the secret opens it
+	got, server, err := NewKeyring(testSecret, time.Minute).Open("POST", "/v1/scan/content", env)
+	require.NoError(t, err)
+	assert.Equal(t, body, got)
+
+	resp, err := server.SealResponse(200, []byte(`{"decision":"ALLOW"}`))
+	require.NoError(t, err)
+	plain, err := client.OpenResponse(200, resp)
+	require.NoError(t, err)
+	assert.Equal(t, `{"decision":"ALLOW"}`, string(plain))
+
+	// The status is authenticated
+	_, err = client.OpenResponse(403, resp)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestOpen_RejectsTampering(t *testing.T) {
+	ring := NewKeyring(testSecret, time.Minute)
+	key, err := ring.Issue()
+	require.NoError(t, err)
+	env, _, err := Seal(key, "POST", "/v1/scan/content", []byte(`{"text":"x"}`))
+	require.NoError(t, err)
+
+	// Replayed to another endpoint
+	_, _, err = ring.Open("POST", "/v1/scan/output", env)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Opened with another secret
+	_, _, err = NewKeyring([]byte("another secret of thirty-two chars"), time.Minute).Open("POST", "/v1/scan/content", env)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Altered ciphertext
+	altered := *env
+	altered.Ciphertext = append([]byte(nil), env.Ciphertext...)
+	altered.Ciphertext[0] ^= 1
+	_, _, err = ring.Open("POST", "/v1/scan/content", &altered)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Key ID altered to extend its expiry
+	id, _ := base64.RawURLEncoding.DecodeString(env.KeyID)
+	binary.BigEndian.PutUint64(id[16:], uint64(time.Now().Add(time.Hour).Unix()))
+	extended := *env
+	extended.KeyID = base64.RawURLEncoding.EncodeToString(id)
+	_, _, err = ring.Open("POST", "/v1/scan/content", &extended)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	malformed := *env
+	malformed.KeyID = "not-a-key"
+	_, _, err = ring.Open("POST", "/v1/scan/content", &malformed)
+	assert.ErrorIs(t, err, ErrKeyRejected)
+}
+
+func TestOpen_RejectsExpiredKeys(t *testing.T) {
+	ring := NewKeyring(testSecret, time.Minute)
+	key, err := ring.Issue()
+	require.NoError(t, err)
+	env, _, err := Seal(key, "POST", "/v1/scan/content", []byte(`{"text":"x"}`))
+	require.NoError(t, err)
+
+	ring.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, _, err = ring.Open("POST", "/v1/scan/content", env)
+	assert.ErrorIs(t, err, ErrKeyRejected)
+}
@@ -0,0 +1,83 @@
+// Package types holds the wire contract shared by the API, the proxy and
+// client SDKs. The scan result is versioned with SchemaVersion
+// ("major.minor") and evolves additively:
+//
+//   - Fields may be added in a minor version. Consumers must ignore fields
+//     they don't know.
+//   - Fields are never removed, renamed or retyped, and the meaning of an
+//     existing field or decision value never changes within a major version.
+//   - Anything else is a breaking change and bumps the major version.
+//
+// Results without a schema_version come from APIs that predate versioning and
+// match 1.0.
+package types
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the scan result schema this build produces
+const SchemaVersion = "1.0"
+
+// Decision represents the scan decision
+type Decision string
+
+const (
+	DecisionAllow Decision = "ALLOW"
+	DecisionWarn  Decision = "WARN"
+	DecisionBlock Decision = "BLOCK"
+)
+
+// Threat represents a detected threat with location info
+type Threat struct {
+	Category    string `json:"category"`    // Broad category: "prompt_injection", "credential_leak"
+	Pattern     string `json:"pattern"`     // What matched (the specific pattern)
+	Location    string `json:"location"`    // Where in text (line/offset if available)
+	Severity    string `json:"severity"`    // "high", "medium", "low"
+	Description string `json:"description"` // Human-readable explanation
+}
+
+// ScanResult represents the result of a security scan
+type ScanResult struct {
+	SchemaVersion     string                 `json:"schema_version"` // Set to SchemaVersion when marshaled, if empty
+	Decision          Decision               `json:"decision"`
+	Scores            map[string]float64     `json:"scores"`
+	Reason            string                 `json:"reason"`
+	LatencyMs         int64                  `json:"latency_ms"`
+	RequestID         string                 `json:"request_id"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	SanitizedText     string                 `json:"sanitized_text,omitempty"`     // Clean version with threats removed
+	ThreatsFound      []Threat               `json:"threats_found,omitempty"`      // Detailed threat info
+	RecommendedAction string                 `json:"recommended_action,omitempty"` // What the agent should do
+	DetectionVersion  string                 `json:"detection_version"`            // Detection configuration that produced the verdict
+}
+
+// MarshalJSON stamps the current schema version on results that don't carry
+// one, so every result leaving a component is versioned
+func (r ScanResult) MarshalJSON() ([]byte, error) {
+	type plain ScanResult
+	if r.SchemaVersion == "" {
+		r.SchemaVersion = SchemaVersion
+	}
+	return json.Marshal(plain(r))
+}
+
+// Compatible reports whether a result with schema version v can be read by
+// this build: same major version, or unversioned
+func Compatible(v string) bool {
+	if v == "" {
+		return true
+	}
+	return major(v) == major(SchemaVersion)
+}
+
+func major(v string) int {
+	m, _, _ := strings.Cut(v, ".")
+	n, err := strconv.Atoi(m)
+	if err != nil {
+		return -1
+	}
+	return n
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanResultSchemaVersion(t *testing.T) {
+	b, err := json.Marshal(ScanResult{Decision: DecisionAllow})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"schema_version":"`+SchemaVersion+`"`)
+
+	b, err = json.Marshal(&ScanResult{SchemaVersion: "1.3"})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"schema_version":"1.3"`, "a set version is kept")
+
+	// Unknown fields from newer minor versions are ignored
+	var r ScanResult
+	require.NoError(t, json.Unmarshal([]byte(`{"schema_version":"1.9","decision":"BLOCK","new_field":true}`), &r))
+	assert.Equal(t, DecisionBlock, r.Decision)
+	assert.True(t, Compatible(r.SchemaVersion))
+}
+
+func TestCompatible(t *testing.T) {
+	assert.True(t, Compatible(""))
+	assert.True(t, Compatible("1.0"))
+	assert.True(t, Compatible("1.7"))
+	assert.False(t, Compatible("2.0"))
+	assert.False(t, Compatible("garbage"))
+}
@@ -18,7 +18,8 @@ export default function LoginPage() {
   const [useRecovery, setUseRecovery] = useState(false);
   const [ttlDays, setTtlDays] = useState(0);
   const [isSubmitting, setIsSubmitting] = useState(false);
-  const { login, verifyTotp, resetTotp, b2bSignIn, b2bSignUp, totpRequired, isAuthenticated, isLoading, needsOnboarding } = useAuth();
+  const [ssoEmail, setSsoEmail] = useState('');
+  const { login, verifyTotp, resetTotp, b2bSignIn, b2bSSOSignIn, b2bSignUp, totpRequired, isAuthenticated, isLoading, needsOnboarding } = useAuth();
   const router = useRouter();
 
   useEffect(() => {
@@ -53,6 +54,22 @@ export default function LoginPage() {
     }
   };
 
+  const handleSSOSignIn = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    if (!ssoEmail.includes('@')) {
+      setError('Please enter your work email');
+      return;
+    }
+    setIsSubmitting(true);
+    try {
+      await b2bSSOSignIn(ssoEmail.trim());
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Single sign-on failed');
+      setIsSubmitting(false);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError('');
@@ -198,6 +215,30 @@ export default function LoginPage() {
                   </>
                 )}
               </button>
+
+              <form onSubmit={handleSSOSignIn} className="space-y-3 pt-4 border-t border-[#333]">
+                <label htmlFor="ssoEmail" className="block text-sm font-medium text-gray-300">
+                  Single sign-on
+                </label>
+                <input
+                  type="email"
+                  id="ssoEmail"
+                  value={ssoEmail}
+                  onChange={(e) => {
+                    setSsoEmail(e.target.value);
+                    setError('');
+                  }}
+                  placeholder="you@company.com"
+                  className="w-full px-4 py-3 bg-[#0a0a0a] border border-[#333] rounded-lg text-white placeholder-gray-600 focus:outline-none focus:border-[#00D4AA] focus:ring-1 focus:ring-[#00D4AA] transition-colors"
+                />
+                <button
+                  type="submit"
+                  disabled={isSubmitting}
+                  className="w-full py-3 px-4 bg-[#222] hover:bg-[#2a2a2a] disabled:cursor-not-allowed text-white font-semibold rounded-lg transition-colors"
+                >
+                  Continue with SSO
+                </button>
+              </form>
             </div>
           ) : (
             // B2C: Account number + TOTP form
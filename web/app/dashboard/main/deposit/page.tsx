@@ -81,8 +81,8 @@ export default function DepositPage() {
           'Content-Type': 'application/json',
         },
         body: JSON.stringify({
-          // Input remains human-readable USDC float; API responses use microUSDC strings.
-          amount_usdc: amountNum,
+          // Request amounts are USDC decimal strings; API responses use microUSDC strings.
+          amount_usdc: amountNum.toFixed(6),
           provider: provider,
           network: effectiveNetwork,
         }),
@@ -19,6 +19,11 @@ interface UsageTableProps {
 const endpointLabels: Record<string, string> = {
   '/v1/scan/content': 'Content Scan',
   '/v1/scan/output': 'Output Scan',
+  '/v1/scan/tool-call': 'Tool Call Scan',
+  '/v1/scan/session/message': 'Session Message Scan',
+  '/v1/scan/documents': 'Document Scan',
+  '/v1/ingest/check': 'Ingest Check',
+  '/v1/ingest/recheck': 'Ingest Recheck',
 };
 
 export function UsageTable({ logs, loading, hasMore, onLoadMore }: UsageTableProps) {
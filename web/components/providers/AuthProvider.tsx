@@ -32,6 +32,7 @@ interface AuthContextType {
   logout: () => Promise<void>;
   refreshAuth: () => Promise<boolean>;
   b2bSignIn: () => Promise<void>;
+  b2bSSOSignIn: (email: string) => Promise<void>;
   b2bSignUp: () => Promise<void>;
   b2bSignOut: () => void;
   onboardB2B: (companyName: string) => Promise<void>;
@@ -248,6 +249,19 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     await workosSignIn();
   };
 
+  // Send the user straight to their organization's identity provider
+  const b2bSSOSignIn = async (email: string) => {
+    const response = await fetch(
+      `${API_URL}/v1/auth/sso/lookup?email=${encodeURIComponent(email)}`,
+    );
+    if (!response.ok) {
+      const error = await response.json().catch(() => null);
+      throw new Error(error?.error || 'Single sign-on lookup failed');
+    }
+    const { organization_id: organizationId } = await response.json();
+    await workosSignIn({ organizationId, loginHint: email });
+  };
+
   const b2bSignUp = async () => {
     await workosSignUp();
   };
@@ -304,6 +318,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         logout,
         refreshAuth,
         b2bSignIn,
+        b2bSSOSignIn,
         b2bSignUp,
         b2bSignOut: b2bSignOutHandler,
         onboardB2B: onboardB2BHandler,
@@ -102,6 +102,10 @@ export interface WalletBalanceInfo {
   address: string;
   balance_usdc: string;
   network: string;
+  /** When the balance was read on-chain */
+  updated_at?: string;
+  /** Served from the server cache while it refreshes */
+  stale?: boolean;
   error?: string;
 }
 
@@ -114,15 +118,51 @@ export interface BalancesResponse {
 
 /**
  * Fetch on-chain USDC balances for the authenticated account's wallets.
+ * Balances are cached server-side for a short time; pass fresh to read them
+ * on-chain again.
  */
-export async function fetchBalances(): Promise<BalancesResponse> {
-  const response = await fetchWithAuth(`${API_URL}/v1/account/balances`);
+export async function fetchBalances(fresh = false): Promise<BalancesResponse> {
+  const query = fresh ? '?fresh=true' : '';
+  const response = await fetchWithAuth(`${API_URL}/v1/account/balances${query}`);
   if (!response.ok) {
     throw new Error('Failed to fetch balances');
   }
   return response.json();
 }
 
+/** A day's closing balances, in microUSDC */
+export interface BalanceSnapshot {
+  /** YYYY-MM-DD, UTC */
+  date: string;
+  balance_usdc: string;
+  /** Omitted when the wallet was not read that day */
+  evm_balance_usdc?: string;
+  solana_balance_usdc?: string;
+}
+
+/** A deposit or withdrawal marker on the balance history chart */
+export interface BalanceEvent {
+  type: 'deposit' | 'withdrawal';
+  amount_usdc: string;
+  provider?: string;
+  at: string;
+}
+
+/** Response from GET /v1/account/balances/history */
+export interface BalanceHistoryResponse {
+  days: BalanceSnapshot[];
+  events: BalanceEvent[];
+}
+
+/** Fetch the daily balance history for the last `days` days, ending today. */
+export async function fetchBalanceHistory(days = 30): Promise<BalanceHistoryResponse> {
+  const response = await fetchWithAuth(`${API_URL}/v1/account/balances/history?days=${days}`);
+  if (!response.ok) {
+    throw new Error('Failed to fetch balance history');
+  }
+  return response.json();
+}
+
 // --- B2B token provider ---
 
 // Module-level function that returns a fresh B2B access token (set by AuthProvider
@@ -189,7 +229,7 @@ export async function purchaseCredits(amountUSDC: number): Promise<{ checkout_ur
   const response = await fetchWithAuth(`${API_URL}/v1/billing/credits`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ amount_usdc: amountUSDC }),
+    body: JSON.stringify({ amount_usdc: amountUSDC.toFixed(2) }),
   });
   if (!response.ok) {
     const error = await response.json();